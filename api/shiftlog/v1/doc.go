@@ -0,0 +1,16 @@
+// Package shiftlogv1 holds the protobuf messages of the gRPC API of
+// 'shiftlog serve' and the Go stubs of its Shiftlog service, generated from
+// shiftlog.proto.
+//
+// The server answers gRPC on the same port as its HTTP API, over HTTP/2
+// without TLS, so a client connects with insecure credentials:
+//
+//	conn, err := grpc.NewClient("localhost:8080", grpc.WithTransportCredentials(insecure.NewCredentials()))
+//	client := shiftlogv1.NewShiftlogClient(conn)
+//	stream, err := client.ListCommits(ctx, &shiftlogv1.ListCommitsRequest{HasConversation: true})
+//
+// Within v1, fields and methods are only ever added, never removed or
+// renumbered.
+package shiftlogv1
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative shiftlog.proto
@@ -0,0 +1,1052 @@
+// The gRPC API of 'shiftlog serve', served on the same port as its HTTP API
+// for platforms embedding shiftlog. It reads the same commits and
+// conversations as the REST endpoints under /api/.
+
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.6
+// 	protoc        (unknown)
+// source: shiftlog.proto
+
+package shiftlogv1
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type ListCommitsRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Branch, tag or revision range to list, HEAD by default.
+	Revision string `protobuf:"bytes,1,opt,name=revision,proto3" json:"revision,omitempty"`
+	// Most commits to return, 100 by default.
+	Limit int32 `protobuf:"varint,2,opt,name=limit,proto3" json:"limit,omitempty"`
+	// Only return commits with a conversation.
+	HasConversation bool `protobuf:"varint,3,opt,name=has_conversation,json=hasConversation,proto3" json:"has_conversation,omitempty"`
+	// Part of the author's name or email.
+	Author string `protobuf:"bytes,4,opt,name=author,proto3" json:"author,omitempty"`
+	// Only return commits whose conversation has this label.
+	Tag           string `protobuf:"bytes,5,opt,name=tag,proto3" json:"tag,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListCommitsRequest) Reset() {
+	*x = ListCommitsRequest{}
+	mi := &file_shiftlog_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListCommitsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListCommitsRequest) ProtoMessage() {}
+
+func (x *ListCommitsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_shiftlog_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListCommitsRequest.ProtoReflect.Descriptor instead.
+func (*ListCommitsRequest) Descriptor() ([]byte, []int) {
+	return file_shiftlog_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *ListCommitsRequest) GetRevision() string {
+	if x != nil {
+		return x.Revision
+	}
+	return ""
+}
+
+func (x *ListCommitsRequest) GetLimit() int32 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
+
+func (x *ListCommitsRequest) GetHasConversation() bool {
+	if x != nil {
+		return x.HasConversation
+	}
+	return false
+}
+
+func (x *ListCommitsRequest) GetAuthor() string {
+	if x != nil {
+		return x.Author
+	}
+	return ""
+}
+
+func (x *ListCommitsRequest) GetTag() string {
+	if x != nil {
+		return x.Tag
+	}
+	return ""
+}
+
+type Commit struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Sha   string                 `protobuf:"bytes,1,opt,name=sha,proto3" json:"sha,omitempty"`
+	// Subject of the commit message.
+	Message string `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	Author  string `protobuf:"bytes,3,opt,name=author,proto3" json:"author,omitempty"`
+	// Committer date, as git prints it.
+	Date string `protobuf:"bytes,4,opt,name=date,proto3" json:"date,omitempty"`
+	// Git tags of the commit.
+	GitTags []string `protobuf:"bytes,5,rep,name=git_tags,json=gitTags,proto3" json:"git_tags,omitempty"`
+	// Conversations stored for the commit, without their transcripts.
+	Conversations []*Conversation `protobuf:"bytes,6,rep,name=conversations,proto3" json:"conversations,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Commit) Reset() {
+	*x = Commit{}
+	mi := &file_shiftlog_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Commit) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Commit) ProtoMessage() {}
+
+func (x *Commit) ProtoReflect() protoreflect.Message {
+	mi := &file_shiftlog_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Commit.ProtoReflect.Descriptor instead.
+func (*Commit) Descriptor() ([]byte, []int) {
+	return file_shiftlog_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *Commit) GetSha() string {
+	if x != nil {
+		return x.Sha
+	}
+	return ""
+}
+
+func (x *Commit) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+func (x *Commit) GetAuthor() string {
+	if x != nil {
+		return x.Author
+	}
+	return ""
+}
+
+func (x *Commit) GetDate() string {
+	if x != nil {
+		return x.Date
+	}
+	return ""
+}
+
+func (x *Commit) GetGitTags() []string {
+	if x != nil {
+		return x.GitTags
+	}
+	return nil
+}
+
+func (x *Commit) GetConversations() []*Conversation {
+	if x != nil {
+		return x.Conversations
+	}
+	return nil
+}
+
+type Conversation struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Index of the conversation among the commit's.
+	Index     int32  `protobuf:"varint,1,opt,name=index,proto3" json:"index,omitempty"`
+	SessionId string `protobuf:"bytes,2,opt,name=session_id,json=sessionId,proto3" json:"session_id,omitempty"`
+	Agent     string `protobuf:"bytes,3,opt,name=agent,proto3" json:"agent,omitempty"`
+	Model     string `protobuf:"bytes,4,opt,name=model,proto3" json:"model,omitempty"`
+	// When the conversation was stored, RFC 3339 in UTC.
+	Timestamp    string `protobuf:"bytes,5,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+	MessageCount int32  `protobuf:"varint,6,opt,name=message_count,json=messageCount,proto3" json:"message_count,omitempty"`
+	Summary      string `protobuf:"bytes,7,opt,name=summary,proto3" json:"summary,omitempty"`
+	// Labels of the conversation.
+	Tags []string `protobuf:"bytes,8,rep,name=tags,proto3" json:"tags,omitempty"`
+	// Kept in the serving clone only.
+	Private bool `protobuf:"varint,9,opt,name=private,proto3" json:"private,omitempty"`
+	// Contributor notes ref the conversation was read from, empty for the
+	// shared notes.
+	NotesRef string `protobuf:"bytes,10,opt,name=notes_ref,json=notesRef,proto3" json:"notes_ref,omitempty"`
+	// Entries of the transcript, left out of listings.
+	Transcript    []*TranscriptEntry `protobuf:"bytes,11,rep,name=transcript,proto3" json:"transcript,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Conversation) Reset() {
+	*x = Conversation{}
+	mi := &file_shiftlog_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Conversation) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Conversation) ProtoMessage() {}
+
+func (x *Conversation) ProtoReflect() protoreflect.Message {
+	mi := &file_shiftlog_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Conversation.ProtoReflect.Descriptor instead.
+func (*Conversation) Descriptor() ([]byte, []int) {
+	return file_shiftlog_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *Conversation) GetIndex() int32 {
+	if x != nil {
+		return x.Index
+	}
+	return 0
+}
+
+func (x *Conversation) GetSessionId() string {
+	if x != nil {
+		return x.SessionId
+	}
+	return ""
+}
+
+func (x *Conversation) GetAgent() string {
+	if x != nil {
+		return x.Agent
+	}
+	return ""
+}
+
+func (x *Conversation) GetModel() string {
+	if x != nil {
+		return x.Model
+	}
+	return ""
+}
+
+func (x *Conversation) GetTimestamp() string {
+	if x != nil {
+		return x.Timestamp
+	}
+	return ""
+}
+
+func (x *Conversation) GetMessageCount() int32 {
+	if x != nil {
+		return x.MessageCount
+	}
+	return 0
+}
+
+func (x *Conversation) GetSummary() string {
+	if x != nil {
+		return x.Summary
+	}
+	return ""
+}
+
+func (x *Conversation) GetTags() []string {
+	if x != nil {
+		return x.Tags
+	}
+	return nil
+}
+
+func (x *Conversation) GetPrivate() bool {
+	if x != nil {
+		return x.Private
+	}
+	return false
+}
+
+func (x *Conversation) GetNotesRef() string {
+	if x != nil {
+		return x.NotesRef
+	}
+	return ""
+}
+
+func (x *Conversation) GetTranscript() []*TranscriptEntry {
+	if x != nil {
+		return x.Transcript
+	}
+	return nil
+}
+
+type TranscriptEntry struct {
+	state      protoimpl.MessageState `protogen:"open.v1"`
+	Uuid       string                 `protobuf:"bytes,1,opt,name=uuid,proto3" json:"uuid,omitempty"`
+	ParentUuid string                 `protobuf:"bytes,2,opt,name=parent_uuid,json=parentUuid,proto3" json:"parent_uuid,omitempty"`
+	// "user", "assistant" or "system".
+	Type          string          `protobuf:"bytes,3,opt,name=type,proto3" json:"type,omitempty"`
+	Timestamp     string          `protobuf:"bytes,4,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+	Model         string          `protobuf:"bytes,5,opt,name=model,proto3" json:"model,omitempty"`
+	Content       []*ContentBlock `protobuf:"bytes,6,rep,name=content,proto3" json:"content,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TranscriptEntry) Reset() {
+	*x = TranscriptEntry{}
+	mi := &file_shiftlog_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TranscriptEntry) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TranscriptEntry) ProtoMessage() {}
+
+func (x *TranscriptEntry) ProtoReflect() protoreflect.Message {
+	mi := &file_shiftlog_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TranscriptEntry.ProtoReflect.Descriptor instead.
+func (*TranscriptEntry) Descriptor() ([]byte, []int) {
+	return file_shiftlog_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *TranscriptEntry) GetUuid() string {
+	if x != nil {
+		return x.Uuid
+	}
+	return ""
+}
+
+func (x *TranscriptEntry) GetParentUuid() string {
+	if x != nil {
+		return x.ParentUuid
+	}
+	return ""
+}
+
+func (x *TranscriptEntry) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *TranscriptEntry) GetTimestamp() string {
+	if x != nil {
+		return x.Timestamp
+	}
+	return ""
+}
+
+func (x *TranscriptEntry) GetModel() string {
+	if x != nil {
+		return x.Model
+	}
+	return ""
+}
+
+func (x *TranscriptEntry) GetContent() []*ContentBlock {
+	if x != nil {
+		return x.Content
+	}
+	return nil
+}
+
+type ContentBlock struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// "text", "thinking", "tool_use", "tool_result", "image" or "document".
+	Type string `protobuf:"bytes,1,opt,name=type,proto3" json:"type,omitempty"`
+	Text string `protobuf:"bytes,2,opt,name=text,proto3" json:"text,omitempty"`
+	// Name of the tool of a tool_use block.
+	Name string `protobuf:"bytes,3,opt,name=name,proto3" json:"name,omitempty"`
+	// ID of a tool_use block, and the one a tool_result block answers.
+	ToolUseId string `protobuf:"bytes,4,opt,name=tool_use_id,json=toolUseId,proto3" json:"tool_use_id,omitempty"`
+	// Input of a tool_use block and content of a tool_result block, as JSON.
+	Json          string `protobuf:"bytes,5,opt,name=json,proto3" json:"json,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ContentBlock) Reset() {
+	*x = ContentBlock{}
+	mi := &file_shiftlog_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ContentBlock) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ContentBlock) ProtoMessage() {}
+
+func (x *ContentBlock) ProtoReflect() protoreflect.Message {
+	mi := &file_shiftlog_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ContentBlock.ProtoReflect.Descriptor instead.
+func (*ContentBlock) Descriptor() ([]byte, []int) {
+	return file_shiftlog_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *ContentBlock) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *ContentBlock) GetText() string {
+	if x != nil {
+		return x.Text
+	}
+	return ""
+}
+
+func (x *ContentBlock) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *ContentBlock) GetToolUseId() string {
+	if x != nil {
+		return x.ToolUseId
+	}
+	return ""
+}
+
+func (x *ContentBlock) GetJson() string {
+	if x != nil {
+		return x.Json
+	}
+	return ""
+}
+
+type GetConversationsRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Commit SHA or any reference to a commit.
+	Commit string `protobuf:"bytes,1,opt,name=commit,proto3" json:"commit,omitempty"`
+	// Leave the transcripts out.
+	MetadataOnly  bool `protobuf:"varint,2,opt,name=metadata_only,json=metadataOnly,proto3" json:"metadata_only,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetConversationsRequest) Reset() {
+	*x = GetConversationsRequest{}
+	mi := &file_shiftlog_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetConversationsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetConversationsRequest) ProtoMessage() {}
+
+func (x *GetConversationsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_shiftlog_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetConversationsRequest.ProtoReflect.Descriptor instead.
+func (*GetConversationsRequest) Descriptor() ([]byte, []int) {
+	return file_shiftlog_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *GetConversationsRequest) GetCommit() string {
+	if x != nil {
+		return x.Commit
+	}
+	return ""
+}
+
+func (x *GetConversationsRequest) GetMetadataOnly() bool {
+	if x != nil {
+		return x.MetadataOnly
+	}
+	return false
+}
+
+type GetConversationsResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Full SHA of the commit.
+	Sha           string          `protobuf:"bytes,1,opt,name=sha,proto3" json:"sha,omitempty"`
+	Conversations []*Conversation `protobuf:"bytes,2,rep,name=conversations,proto3" json:"conversations,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetConversationsResponse) Reset() {
+	*x = GetConversationsResponse{}
+	mi := &file_shiftlog_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetConversationsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetConversationsResponse) ProtoMessage() {}
+
+func (x *GetConversationsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_shiftlog_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetConversationsResponse.ProtoReflect.Descriptor instead.
+func (*GetConversationsResponse) Descriptor() ([]byte, []int) {
+	return file_shiftlog_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *GetConversationsResponse) GetSha() string {
+	if x != nil {
+		return x.Sha
+	}
+	return ""
+}
+
+func (x *GetConversationsResponse) GetConversations() []*Conversation {
+	if x != nil {
+		return x.Conversations
+	}
+	return nil
+}
+
+type SearchRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Query         string                 `protobuf:"bytes,1,opt,name=query,proto3" json:"query,omitempty"`
+	Regex         bool                   `protobuf:"varint,2,opt,name=regex,proto3" json:"regex,omitempty"`
+	CaseSensitive bool                   `protobuf:"varint,3,opt,name=case_sensitive,json=caseSensitive,proto3" json:"case_sensitive,omitempty"`
+	// Only match the conversations' metadata, not their transcripts.
+	MetadataOnly bool   `protobuf:"varint,4,opt,name=metadata_only,json=metadataOnly,proto3" json:"metadata_only,omitempty"`
+	Agent        string `protobuf:"bytes,5,opt,name=agent,proto3" json:"agent,omitempty"`
+	Branch       string `protobuf:"bytes,6,opt,name=branch,proto3" json:"branch,omitempty"`
+	Model        string `protobuf:"bytes,7,opt,name=model,proto3" json:"model,omitempty"`
+	Tag          string `protobuf:"bytes,8,opt,name=tag,proto3" json:"tag,omitempty"`
+	// Most conversations to return, 20 by default.
+	Limit         int32 `protobuf:"varint,9,opt,name=limit,proto3" json:"limit,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SearchRequest) Reset() {
+	*x = SearchRequest{}
+	mi := &file_shiftlog_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SearchRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SearchRequest) ProtoMessage() {}
+
+func (x *SearchRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_shiftlog_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SearchRequest.ProtoReflect.Descriptor instead.
+func (*SearchRequest) Descriptor() ([]byte, []int) {
+	return file_shiftlog_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *SearchRequest) GetQuery() string {
+	if x != nil {
+		return x.Query
+	}
+	return ""
+}
+
+func (x *SearchRequest) GetRegex() bool {
+	if x != nil {
+		return x.Regex
+	}
+	return false
+}
+
+func (x *SearchRequest) GetCaseSensitive() bool {
+	if x != nil {
+		return x.CaseSensitive
+	}
+	return false
+}
+
+func (x *SearchRequest) GetMetadataOnly() bool {
+	if x != nil {
+		return x.MetadataOnly
+	}
+	return false
+}
+
+func (x *SearchRequest) GetAgent() string {
+	if x != nil {
+		return x.Agent
+	}
+	return ""
+}
+
+func (x *SearchRequest) GetBranch() string {
+	if x != nil {
+		return x.Branch
+	}
+	return ""
+}
+
+func (x *SearchRequest) GetModel() string {
+	if x != nil {
+		return x.Model
+	}
+	return ""
+}
+
+func (x *SearchRequest) GetTag() string {
+	if x != nil {
+		return x.Tag
+	}
+	return ""
+}
+
+func (x *SearchRequest) GetLimit() int32 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
+
+type SearchResult struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Sha           string                 `protobuf:"bytes,1,opt,name=sha,proto3" json:"sha,omitempty"`
+	CommitDate    string                 `protobuf:"bytes,2,opt,name=commit_date,json=commitDate,proto3" json:"commit_date,omitempty"`
+	CommitMessage string                 `protobuf:"bytes,3,opt,name=commit_message,json=commitMessage,proto3" json:"commit_message,omitempty"`
+	Agent         string                 `protobuf:"bytes,4,opt,name=agent,proto3" json:"agent,omitempty"`
+	Branch        string                 `protobuf:"bytes,5,opt,name=branch,proto3" json:"branch,omitempty"`
+	Model         string                 `protobuf:"bytes,6,opt,name=model,proto3" json:"model,omitempty"`
+	MessageCount  int32                  `protobuf:"varint,7,opt,name=message_count,json=messageCount,proto3" json:"message_count,omitempty"`
+	Summary       string                 `protobuf:"bytes,8,opt,name=summary,proto3" json:"summary,omitempty"`
+	Tags          []string               `protobuf:"bytes,9,rep,name=tags,proto3" json:"tags,omitempty"`
+	Matches       []*SearchMatch         `protobuf:"bytes,10,rep,name=matches,proto3" json:"matches,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SearchResult) Reset() {
+	*x = SearchResult{}
+	mi := &file_shiftlog_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SearchResult) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SearchResult) ProtoMessage() {}
+
+func (x *SearchResult) ProtoReflect() protoreflect.Message {
+	mi := &file_shiftlog_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SearchResult.ProtoReflect.Descriptor instead.
+func (*SearchResult) Descriptor() ([]byte, []int) {
+	return file_shiftlog_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *SearchResult) GetSha() string {
+	if x != nil {
+		return x.Sha
+	}
+	return ""
+}
+
+func (x *SearchResult) GetCommitDate() string {
+	if x != nil {
+		return x.CommitDate
+	}
+	return ""
+}
+
+func (x *SearchResult) GetCommitMessage() string {
+	if x != nil {
+		return x.CommitMessage
+	}
+	return ""
+}
+
+func (x *SearchResult) GetAgent() string {
+	if x != nil {
+		return x.Agent
+	}
+	return ""
+}
+
+func (x *SearchResult) GetBranch() string {
+	if x != nil {
+		return x.Branch
+	}
+	return ""
+}
+
+func (x *SearchResult) GetModel() string {
+	if x != nil {
+		return x.Model
+	}
+	return ""
+}
+
+func (x *SearchResult) GetMessageCount() int32 {
+	if x != nil {
+		return x.MessageCount
+	}
+	return 0
+}
+
+func (x *SearchResult) GetSummary() string {
+	if x != nil {
+		return x.Summary
+	}
+	return ""
+}
+
+func (x *SearchResult) GetTags() []string {
+	if x != nil {
+		return x.Tags
+	}
+	return nil
+}
+
+func (x *SearchResult) GetMatches() []*SearchMatch {
+	if x != nil {
+		return x.Matches
+	}
+	return nil
+}
+
+type SearchMatch struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// "user", "assistant" or "system".
+	EntryType string `protobuf:"bytes,1,opt,name=entry_type,json=entryType,proto3" json:"entry_type,omitempty"`
+	// "text", "tool_use", "tool_result" or "thinking".
+	BlockType string `protobuf:"bytes,2,opt,name=block_type,json=blockType,proto3" json:"block_type,omitempty"`
+	ToolName  string `protobuf:"bytes,3,opt,name=tool_name,json=toolName,proto3" json:"tool_name,omitempty"`
+	// The matched text with some context around it.
+	Snippet       string `protobuf:"bytes,4,opt,name=snippet,proto3" json:"snippet,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SearchMatch) Reset() {
+	*x = SearchMatch{}
+	mi := &file_shiftlog_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SearchMatch) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SearchMatch) ProtoMessage() {}
+
+func (x *SearchMatch) ProtoReflect() protoreflect.Message {
+	mi := &file_shiftlog_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SearchMatch.ProtoReflect.Descriptor instead.
+func (*SearchMatch) Descriptor() ([]byte, []int) {
+	return file_shiftlog_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *SearchMatch) GetEntryType() string {
+	if x != nil {
+		return x.EntryType
+	}
+	return ""
+}
+
+func (x *SearchMatch) GetBlockType() string {
+	if x != nil {
+		return x.BlockType
+	}
+	return ""
+}
+
+func (x *SearchMatch) GetToolName() string {
+	if x != nil {
+		return x.ToolName
+	}
+	return ""
+}
+
+func (x *SearchMatch) GetSnippet() string {
+	if x != nil {
+		return x.Snippet
+	}
+	return ""
+}
+
+var File_shiftlog_proto protoreflect.FileDescriptor
+
+const file_shiftlog_proto_rawDesc = "" +
+	"\n" +
+	"\x0eshiftlog.proto\x12\vshiftlog.v1\"\x9b\x01\n" +
+	"\x12ListCommitsRequest\x12\x1a\n" +
+	"\brevision\x18\x01 \x01(\tR\brevision\x12\x14\n" +
+	"\x05limit\x18\x02 \x01(\x05R\x05limit\x12)\n" +
+	"\x10has_conversation\x18\x03 \x01(\bR\x0fhasConversation\x12\x16\n" +
+	"\x06author\x18\x04 \x01(\tR\x06author\x12\x10\n" +
+	"\x03tag\x18\x05 \x01(\tR\x03tag\"\xbc\x01\n" +
+	"\x06Commit\x12\x10\n" +
+	"\x03sha\x18\x01 \x01(\tR\x03sha\x12\x18\n" +
+	"\amessage\x18\x02 \x01(\tR\amessage\x12\x16\n" +
+	"\x06author\x18\x03 \x01(\tR\x06author\x12\x12\n" +
+	"\x04date\x18\x04 \x01(\tR\x04date\x12\x19\n" +
+	"\bgit_tags\x18\x05 \x03(\tR\agitTags\x12?\n" +
+	"\rconversations\x18\x06 \x03(\v2\x19.shiftlog.v1.ConversationR\rconversations\"\xd5\x02\n" +
+	"\fConversation\x12\x14\n" +
+	"\x05index\x18\x01 \x01(\x05R\x05index\x12\x1d\n" +
+	"\n" +
+	"session_id\x18\x02 \x01(\tR\tsessionId\x12\x14\n" +
+	"\x05agent\x18\x03 \x01(\tR\x05agent\x12\x14\n" +
+	"\x05model\x18\x04 \x01(\tR\x05model\x12\x1c\n" +
+	"\ttimestamp\x18\x05 \x01(\tR\ttimestamp\x12#\n" +
+	"\rmessage_count\x18\x06 \x01(\x05R\fmessageCount\x12\x18\n" +
+	"\asummary\x18\a \x01(\tR\asummary\x12\x12\n" +
+	"\x04tags\x18\b \x03(\tR\x04tags\x12\x18\n" +
+	"\aprivate\x18\t \x01(\bR\aprivate\x12\x1b\n" +
+	"\tnotes_ref\x18\n" +
+	" \x01(\tR\bnotesRef\x12<\n" +
+	"\n" +
+	"transcript\x18\v \x03(\v2\x1c.shiftlog.v1.TranscriptEntryR\n" +
+	"transcript\"\xc3\x01\n" +
+	"\x0fTranscriptEntry\x12\x12\n" +
+	"\x04uuid\x18\x01 \x01(\tR\x04uuid\x12\x1f\n" +
+	"\vparent_uuid\x18\x02 \x01(\tR\n" +
+	"parentUuid\x12\x12\n" +
+	"\x04type\x18\x03 \x01(\tR\x04type\x12\x1c\n" +
+	"\ttimestamp\x18\x04 \x01(\tR\ttimestamp\x12\x14\n" +
+	"\x05model\x18\x05 \x01(\tR\x05model\x123\n" +
+	"\acontent\x18\x06 \x03(\v2\x19.shiftlog.v1.ContentBlockR\acontent\"~\n" +
+	"\fContentBlock\x12\x12\n" +
+	"\x04type\x18\x01 \x01(\tR\x04type\x12\x12\n" +
+	"\x04text\x18\x02 \x01(\tR\x04text\x12\x12\n" +
+	"\x04name\x18\x03 \x01(\tR\x04name\x12\x1e\n" +
+	"\vtool_use_id\x18\x04 \x01(\tR\ttoolUseId\x12\x12\n" +
+	"\x04json\x18\x05 \x01(\tR\x04json\"V\n" +
+	"\x17GetConversationsRequest\x12\x16\n" +
+	"\x06commit\x18\x01 \x01(\tR\x06commit\x12#\n" +
+	"\rmetadata_only\x18\x02 \x01(\bR\fmetadataOnly\"m\n" +
+	"\x18GetConversationsResponse\x12\x10\n" +
+	"\x03sha\x18\x01 \x01(\tR\x03sha\x12?\n" +
+	"\rconversations\x18\x02 \x03(\v2\x19.shiftlog.v1.ConversationR\rconversations\"\xf3\x01\n" +
+	"\rSearchRequest\x12\x14\n" +
+	"\x05query\x18\x01 \x01(\tR\x05query\x12\x14\n" +
+	"\x05regex\x18\x02 \x01(\bR\x05regex\x12%\n" +
+	"\x0ecase_sensitive\x18\x03 \x01(\bR\rcaseSensitive\x12#\n" +
+	"\rmetadata_only\x18\x04 \x01(\bR\fmetadataOnly\x12\x14\n" +
+	"\x05agent\x18\x05 \x01(\tR\x05agent\x12\x16\n" +
+	"\x06branch\x18\x06 \x01(\tR\x06branch\x12\x14\n" +
+	"\x05model\x18\a \x01(\tR\x05model\x12\x10\n" +
+	"\x03tag\x18\b \x01(\tR\x03tag\x12\x14\n" +
+	"\x05limit\x18\t \x01(\x05R\x05limit\"\xb3\x02\n" +
+	"\fSearchResult\x12\x10\n" +
+	"\x03sha\x18\x01 \x01(\tR\x03sha\x12\x1f\n" +
+	"\vcommit_date\x18\x02 \x01(\tR\n" +
+	"commitDate\x12%\n" +
+	"\x0ecommit_message\x18\x03 \x01(\tR\rcommitMessage\x12\x14\n" +
+	"\x05agent\x18\x04 \x01(\tR\x05agent\x12\x16\n" +
+	"\x06branch\x18\x05 \x01(\tR\x06branch\x12\x14\n" +
+	"\x05model\x18\x06 \x01(\tR\x05model\x12#\n" +
+	"\rmessage_count\x18\a \x01(\x05R\fmessageCount\x12\x18\n" +
+	"\asummary\x18\b \x01(\tR\asummary\x12\x12\n" +
+	"\x04tags\x18\t \x03(\tR\x04tags\x122\n" +
+	"\amatches\x18\n" +
+	" \x03(\v2\x18.shiftlog.v1.SearchMatchR\amatches\"\x82\x01\n" +
+	"\vSearchMatch\x12\x1d\n" +
+	"\n" +
+	"entry_type\x18\x01 \x01(\tR\tentryType\x12\x1d\n" +
+	"\n" +
+	"block_type\x18\x02 \x01(\tR\tblockType\x12\x1b\n" +
+	"\ttool_name\x18\x03 \x01(\tR\btoolName\x12\x18\n" +
+	"\asnippet\x18\x04 \x01(\tR\asnippet2\xf5\x01\n" +
+	"\bShiftlog\x12E\n" +
+	"\vListCommits\x12\x1f.shiftlog.v1.ListCommitsRequest\x1a\x13.shiftlog.v1.Commit0\x01\x12_\n" +
+	"\x10GetConversations\x12$.shiftlog.v1.GetConversationsRequest\x1a%.shiftlog.v1.GetConversationsResponse\x12A\n" +
+	"\x06Search\x12\x1a.shiftlog.v1.SearchRequest\x1a\x19.shiftlog.v1.SearchResult0\x01B9Z7github.com/re-cinq/shift-log/api/shiftlog/v1;shiftlogv1b\x06proto3"
+
+var (
+	file_shiftlog_proto_rawDescOnce sync.Once
+	file_shiftlog_proto_rawDescData []byte
+)
+
+func file_shiftlog_proto_rawDescGZIP() []byte {
+	file_shiftlog_proto_rawDescOnce.Do(func() {
+		file_shiftlog_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_shiftlog_proto_rawDesc), len(file_shiftlog_proto_rawDesc)))
+	})
+	return file_shiftlog_proto_rawDescData
+}
+
+var file_shiftlog_proto_msgTypes = make([]protoimpl.MessageInfo, 10)
+var file_shiftlog_proto_goTypes = []any{
+	(*ListCommitsRequest)(nil),       // 0: shiftlog.v1.ListCommitsRequest
+	(*Commit)(nil),                   // 1: shiftlog.v1.Commit
+	(*Conversation)(nil),             // 2: shiftlog.v1.Conversation
+	(*TranscriptEntry)(nil),          // 3: shiftlog.v1.TranscriptEntry
+	(*ContentBlock)(nil),             // 4: shiftlog.v1.ContentBlock
+	(*GetConversationsRequest)(nil),  // 5: shiftlog.v1.GetConversationsRequest
+	(*GetConversationsResponse)(nil), // 6: shiftlog.v1.GetConversationsResponse
+	(*SearchRequest)(nil),            // 7: shiftlog.v1.SearchRequest
+	(*SearchResult)(nil),             // 8: shiftlog.v1.SearchResult
+	(*SearchMatch)(nil),              // 9: shiftlog.v1.SearchMatch
+}
+var file_shiftlog_proto_depIdxs = []int32{
+	2, // 0: shiftlog.v1.Commit.conversations:type_name -> shiftlog.v1.Conversation
+	3, // 1: shiftlog.v1.Conversation.transcript:type_name -> shiftlog.v1.TranscriptEntry
+	4, // 2: shiftlog.v1.TranscriptEntry.content:type_name -> shiftlog.v1.ContentBlock
+	2, // 3: shiftlog.v1.GetConversationsResponse.conversations:type_name -> shiftlog.v1.Conversation
+	9, // 4: shiftlog.v1.SearchResult.matches:type_name -> shiftlog.v1.SearchMatch
+	0, // 5: shiftlog.v1.Shiftlog.ListCommits:input_type -> shiftlog.v1.ListCommitsRequest
+	5, // 6: shiftlog.v1.Shiftlog.GetConversations:input_type -> shiftlog.v1.GetConversationsRequest
+	7, // 7: shiftlog.v1.Shiftlog.Search:input_type -> shiftlog.v1.SearchRequest
+	1, // 8: shiftlog.v1.Shiftlog.ListCommits:output_type -> shiftlog.v1.Commit
+	6, // 9: shiftlog.v1.Shiftlog.GetConversations:output_type -> shiftlog.v1.GetConversationsResponse
+	8, // 10: shiftlog.v1.Shiftlog.Search:output_type -> shiftlog.v1.SearchResult
+	8, // [8:11] is the sub-list for method output_type
+	5, // [5:8] is the sub-list for method input_type
+	5, // [5:5] is the sub-list for extension type_name
+	5, // [5:5] is the sub-list for extension extendee
+	0, // [0:5] is the sub-list for field type_name
+}
+
+func init() { file_shiftlog_proto_init() }
+func file_shiftlog_proto_init() {
+	if File_shiftlog_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_shiftlog_proto_rawDesc), len(file_shiftlog_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   10,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_shiftlog_proto_goTypes,
+		DependencyIndexes: file_shiftlog_proto_depIdxs,
+		MessageInfos:      file_shiftlog_proto_msgTypes,
+	}.Build()
+	File_shiftlog_proto = out.File
+	file_shiftlog_proto_goTypes = nil
+	file_shiftlog_proto_depIdxs = nil
+}
@@ -0,0 +1,220 @@
+// The gRPC API of 'shiftlog serve', served on the same port as its HTTP API
+// for platforms embedding shiftlog. It reads the same commits and
+// conversations as the REST endpoints under /api/.
+
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: shiftlog.proto
+
+package shiftlogv1
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	Shiftlog_ListCommits_FullMethodName      = "/shiftlog.v1.Shiftlog/ListCommits"
+	Shiftlog_GetConversations_FullMethodName = "/shiftlog.v1.Shiftlog/GetConversations"
+	Shiftlog_Search_FullMethodName           = "/shiftlog.v1.Shiftlog/Search"
+)
+
+// ShiftlogClient is the client API for Shiftlog service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type ShiftlogClient interface {
+	// ListCommits streams commits, newest first, with the metadata of their
+	// conversations, as GET /api/commits lists them.
+	ListCommits(ctx context.Context, in *ListCommitsRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Commit], error)
+	// GetConversations returns the conversations of a commit, with their
+	// transcripts unless metadata_only is set.
+	GetConversations(ctx context.Context, in *GetConversationsRequest, opts ...grpc.CallOption) (*GetConversationsResponse, error)
+	// Search streams the conversations matching a query, as shiftlog search
+	// finds them.
+	Search(ctx context.Context, in *SearchRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[SearchResult], error)
+}
+
+type shiftlogClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewShiftlogClient(cc grpc.ClientConnInterface) ShiftlogClient {
+	return &shiftlogClient{cc}
+}
+
+func (c *shiftlogClient) ListCommits(ctx context.Context, in *ListCommitsRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Commit], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &Shiftlog_ServiceDesc.Streams[0], Shiftlog_ListCommits_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[ListCommitsRequest, Commit]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Shiftlog_ListCommitsClient = grpc.ServerStreamingClient[Commit]
+
+func (c *shiftlogClient) GetConversations(ctx context.Context, in *GetConversationsRequest, opts ...grpc.CallOption) (*GetConversationsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetConversationsResponse)
+	err := c.cc.Invoke(ctx, Shiftlog_GetConversations_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *shiftlogClient) Search(ctx context.Context, in *SearchRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[SearchResult], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &Shiftlog_ServiceDesc.Streams[1], Shiftlog_Search_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[SearchRequest, SearchResult]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Shiftlog_SearchClient = grpc.ServerStreamingClient[SearchResult]
+
+// ShiftlogServer is the server API for Shiftlog service.
+// All implementations must embed UnimplementedShiftlogServer
+// for forward compatibility.
+type ShiftlogServer interface {
+	// ListCommits streams commits, newest first, with the metadata of their
+	// conversations, as GET /api/commits lists them.
+	ListCommits(*ListCommitsRequest, grpc.ServerStreamingServer[Commit]) error
+	// GetConversations returns the conversations of a commit, with their
+	// transcripts unless metadata_only is set.
+	GetConversations(context.Context, *GetConversationsRequest) (*GetConversationsResponse, error)
+	// Search streams the conversations matching a query, as shiftlog search
+	// finds them.
+	Search(*SearchRequest, grpc.ServerStreamingServer[SearchResult]) error
+	mustEmbedUnimplementedShiftlogServer()
+}
+
+// UnimplementedShiftlogServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedShiftlogServer struct{}
+
+func (UnimplementedShiftlogServer) ListCommits(*ListCommitsRequest, grpc.ServerStreamingServer[Commit]) error {
+	return status.Errorf(codes.Unimplemented, "method ListCommits not implemented")
+}
+func (UnimplementedShiftlogServer) GetConversations(context.Context, *GetConversationsRequest) (*GetConversationsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetConversations not implemented")
+}
+func (UnimplementedShiftlogServer) Search(*SearchRequest, grpc.ServerStreamingServer[SearchResult]) error {
+	return status.Errorf(codes.Unimplemented, "method Search not implemented")
+}
+func (UnimplementedShiftlogServer) mustEmbedUnimplementedShiftlogServer() {}
+func (UnimplementedShiftlogServer) testEmbeddedByValue()                  {}
+
+// UnsafeShiftlogServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to ShiftlogServer will
+// result in compilation errors.
+type UnsafeShiftlogServer interface {
+	mustEmbedUnimplementedShiftlogServer()
+}
+
+func RegisterShiftlogServer(s grpc.ServiceRegistrar, srv ShiftlogServer) {
+	// If the following call pancis, it indicates UnimplementedShiftlogServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&Shiftlog_ServiceDesc, srv)
+}
+
+func _Shiftlog_ListCommits_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(ListCommitsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(ShiftlogServer).ListCommits(m, &grpc.GenericServerStream[ListCommitsRequest, Commit]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Shiftlog_ListCommitsServer = grpc.ServerStreamingServer[Commit]
+
+func _Shiftlog_GetConversations_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetConversationsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ShiftlogServer).GetConversations(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Shiftlog_GetConversations_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ShiftlogServer).GetConversations(ctx, req.(*GetConversationsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Shiftlog_Search_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(SearchRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(ShiftlogServer).Search(m, &grpc.GenericServerStream[SearchRequest, SearchResult]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Shiftlog_SearchServer = grpc.ServerStreamingServer[SearchResult]
+
+// Shiftlog_ServiceDesc is the grpc.ServiceDesc for Shiftlog service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Shiftlog_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "shiftlog.v1.Shiftlog",
+	HandlerType: (*ShiftlogServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "GetConversations",
+			Handler:    _Shiftlog_GetConversations_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "ListCommits",
+			Handler:       _Shiftlog_ListCommits_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "Search",
+			Handler:       _Shiftlog_Search_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "shiftlog.proto",
+}
@@ -0,0 +1,241 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/re-cinq/shift-log/internal/cli"
+	"github.com/re-cinq/shift-log/internal/git"
+	"github.com/re-cinq/shift-log/internal/report"
+	"github.com/re-cinq/shift-log/internal/storage"
+	"github.com/spf13/cobra"
+)
+
+var (
+	annotateRange       string
+	annotateSummaryFile string
+)
+
+var annotateCmd = &cobra.Command{
+	Use:     "annotate",
+	Short:   "Report the conversations of a range to GitHub Actions",
+	GroupID: "human",
+	Long: `Reports which commits of a range have a stored conversation, in the
+formats GitHub Actions displays.
+
+It prints a warning workflow command for each commit without a conversation,
+which GitHub shows as an annotation of the run, and a notice with the totals.
+It appends a Markdown table of the commits, with the agents, tokens,
+estimated cost and summary of their conversations, to the job summary
+($GITHUB_STEP_SUMMARY). Outside GitHub Actions, or with --summary-file -, the
+table is printed instead.
+
+Costs are estimated from the tokens recorded in the conversations at the
+list prices of well-known models; conversations with other models have no
+cost. Merge, revert and bot commits are not expected to have a
+conversation, as in 'shiftlog check'. The command never fails because of
+missing conversations; use 'shiftlog check' to gate on them.
+
+Without --range, a pull request build reports the commits of the pull
+request (origin/$GITHUB_BASE_REF..HEAD). Check out the full history and
+fetch the notes first:
+
+  - uses: actions/checkout@v4
+    with:
+      fetch-depth: 0
+  - run: git fetch origin refs/notes/shiftlog:refs/notes/shiftlog
+  - run: shiftlog annotate`,
+	RunE: runAnnotate,
+}
+
+func init() {
+	annotateCmd.Flags().StringVar(&annotateRange, "range", "", "commits to report, e.g. origin/main..HEAD (default: the pull request on GitHub Actions, else @{upstream}..HEAD)")
+	annotateCmd.Flags().StringVar(&annotateSummaryFile, "summary-file", "", "file to append the Markdown table to, - for stdout (default: $GITHUB_STEP_SUMMARY)")
+	rootCmd.AddCommand(annotateCmd)
+}
+
+// annotatedCommit is a commit of the reported range and its conversations.
+type annotatedCommit struct {
+	commit        git.LogCommit
+	conversations []*storage.StoredConversation
+	allowed       string // why the commit needs no conversation, if it does not
+	tokens        int64
+	cost          float64
+	priced        bool // cost covers at least one conversation
+	unpriced      bool // a conversation's model has no known price
+}
+
+func runAnnotate(cmd *cobra.Command, args []string) error {
+	if err := git.RequireGitRepo(); err != nil {
+		return err
+	}
+	rangeSpec, err := commitRange(annotateRange)
+	if err != nil {
+		return err
+	}
+	cmd.SilenceUsage = true
+
+	stored, err := storage.ListAllConversationCommits()
+	if err != nil {
+		return fmt.Errorf("could not list conversations: %w", err)
+	}
+
+	allow := &checkAllowlist{defaults: true}
+	var commits []*annotatedCommit
+	err = git.ListCommits(git.LogOptions{Ref: rangeSpec}, func(c git.LogCommit) bool {
+		commits = append(commits, &annotatedCommit{commit: c})
+		return true
+	})
+	if err != nil {
+		return fmt.Errorf("could not list commits in %s: %w", rangeSpec, err)
+	}
+
+	var totalTokens int64
+	var totalCost float64
+	withConversation, allowed, missing := 0, 0, 0
+	for _, a := range commits {
+		if stored[a.commit.SHA] {
+			conversations, err := storage.GetStoredConversations(a.commit.SHA)
+			if err != nil {
+				cli.LogWarning("could not read the conversation of %s: %v", a.commit.SHA[:7], err)
+			}
+			a.conversations = conversations
+		}
+		for _, sc := range a.conversations {
+			a.tokens += sc.Effort.TotalTokens()
+			if cost, ok := storage.EstimateCost(sc.Model, sc.Effort); ok {
+				a.cost += cost
+				a.priced = true
+			} else if sc.Effort.TotalTokens() > 0 {
+				a.unpriced = true
+			}
+		}
+		totalTokens += a.tokens
+		totalCost += a.cost
+
+		switch {
+		case len(a.conversations) > 0:
+			withConversation++
+		default:
+			if a.allowed = allow.reason(a.commit); a.allowed != "" {
+				allowed++
+			} else {
+				missing++
+				fmt.Println(workflowCommand("warning", "No shiftlog conversation",
+					fmt.Sprintf("%s %s (%s) has no stored conversation", a.commit.SHA[:7], a.commit.Subject, a.commit.Author)))
+			}
+		}
+	}
+
+	if len(stored) == 0 && missing > 0 {
+		cli.LogWarning("there are no conversation notes in this clone; fetch %s first", git.NotesRef)
+	}
+
+	totals := fmt.Sprintf("%d of %d commit(s) in %s have a conversation, %d allowlisted, %d missing; %d tokens",
+		withConversation, len(commits), rangeSpec, allowed, missing, totalTokens)
+	if totalCost > 0 {
+		totals += fmt.Sprintf(", about %s", report.FormatCost(totalCost))
+	}
+	fmt.Println(workflowCommand("notice", "shiftlog", totals))
+
+	summaryFile := annotateSummaryFile
+	if summaryFile == "" {
+		summaryFile = os.Getenv("GITHUB_STEP_SUMMARY")
+	}
+	if summaryFile == "" || summaryFile == "-" {
+		writeAnnotateSummary(os.Stdout, rangeSpec, commits, totals)
+		return nil
+	}
+	f, err := os.OpenFile(summaryFile, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return fmt.Errorf("could not open the job summary: %w", err)
+	}
+	writeAnnotateSummary(f, rangeSpec, commits, totals)
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("could not write the job summary: %w", err)
+	}
+	cli.RecordArtifact("file", summaryFile)
+	return nil
+}
+
+// writeAnnotateSummary writes the Markdown job summary of the commits.
+func writeAnnotateSummary(w io.Writer, rangeSpec string, commits []*annotatedCommit, totals string) {
+	fmt.Fprintf(w, "### shiftlog conversations\n\n")
+	if len(commits) == 0 {
+		fmt.Fprintf(w, "No commits in `%s`.\n\n", rangeSpec)
+		return
+	}
+	fmt.Fprintf(w, "%s.\n\n", totals)
+
+	commitURL := ""
+	if server, repo := os.Getenv("GITHUB_SERVER_URL"), os.Getenv("GITHUB_REPOSITORY"); server != "" && repo != "" {
+		commitURL = server + "/" + repo + "/commit/"
+	}
+
+	unpriced := false
+	fmt.Fprintln(w, "| Commit | Subject | Conversation | Tokens | Cost | Summary |")
+	fmt.Fprintln(w, "| --- | --- | --- | ---: | ---: | --- |")
+	for _, a := range commits {
+		sha := "`" + a.commit.SHA[:7] + "`"
+		if commitURL != "" {
+			sha = "[" + sha + "](" + commitURL + a.commit.SHA + ")"
+		}
+
+		conversation, tokens, cost := "", "", ""
+		var summaries []string
+		switch {
+		case len(a.conversations) > 0:
+			var agents []string
+			messages := 0
+			for _, sc := range a.conversations {
+				if !slices.Contains(agents, sc.AgentName()) {
+					agents = append(agents, sc.AgentName())
+				}
+				messages += sc.MessageCount
+				if sc.Summary != "" {
+					summaries = append(summaries, sc.Summary)
+				}
+			}
+			conversation = fmt.Sprintf("✅ %s, %d messages", strings.Join(agents, ", "), messages)
+			if a.tokens > 0 {
+				tokens = fmt.Sprintf("%d", a.tokens)
+			}
+			switch {
+			case a.priced && a.unpriced:
+				cost = report.FormatCost(a.cost) + "*"
+				unpriced = true
+			case a.priced:
+				cost = report.FormatCost(a.cost)
+			case a.unpriced:
+				cost = "*"
+				unpriced = true
+			}
+		case a.allowed != "":
+			conversation = "➖ " + a.allowed
+		default:
+			conversation = "❌ missing"
+		}
+
+		row := []string{sha, report.MarkdownCell(a.commit.Subject), conversation, tokens, cost,
+			report.MarkdownCell(report.Truncate(strings.Join(summaries, " "), 200))}
+		fmt.Fprintln(w, "| "+strings.Join(row, " | ")+" |")
+	}
+	fmt.Fprintln(w)
+	if unpriced {
+		fmt.Fprintln(w, "\\* Some conversations used models without a known price and are not included in the cost.")
+		fmt.Fprintln(w)
+	}
+	fmt.Fprintln(w, "Costs are estimates at list prices, from the tokens recorded in the conversations.")
+	fmt.Fprintln(w)
+}
+
+// workflowCommand formats a GitHub Actions workflow command, such as
+// ::warning title=...::message.
+func workflowCommand(command, title, message string) string {
+	escapeData := strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A")
+	escapeProperty := strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A", ":", "%3A", ",", "%2C")
+	return fmt.Sprintf("::%s title=%s::%s", command, escapeProperty.Replace(title), escapeData.Replace(message))
+}
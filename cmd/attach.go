@@ -0,0 +1,86 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/re-cinq/shift-log/internal/cli"
+	"github.com/re-cinq/shift-log/internal/git"
+	"github.com/re-cinq/shift-log/internal/storage"
+	"github.com/spf13/cobra"
+)
+
+var (
+	attachCommitFlag  string
+	attachAgentFlag   string
+	attachReplaceFlag bool
+	attachLastFlag    bool
+)
+
+var attachCmd = &cobra.Command{
+	Use:     "attach [session-id]",
+	Short:   "Store a specific agent session on a commit",
+	GroupID: "human",
+	Long: `Stores the conversation of the given session on a commit, HEAD unless
+--commit is given. Use it when automatic session discovery stored the wrong
+session, or none. 'shiftlog sessions' lists the session IDs. With --last,
+the most recent session is stored, however long ago it was active.
+
+The session is added next to the conversations already stored on the
+commit; with --replace it takes their place. With --private it is kept in
+this clone until 'shiftlog publish' shares it.
+
+Examples:
+  shiftlog attach 4f6e1c2a-90b3-4c8e-a1d2-7b5f3e9c0d11
+  shiftlog attach 4f6e1c2a-90b3-4c8e-a1d2-7b5f3e9c0d11 --commit abc1234 --replace
+  shiftlog attach --last`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runAttach,
+}
+
+func init() {
+	attachCmd.Flags().StringVar(&attachCommitFlag, "commit", "HEAD", "Commit to store the conversation on")
+	attachCmd.Flags().StringVar(&attachAgentFlag, "agent", "", "Coding agent of the session, when several agents have a session with this ID")
+	attachCmd.Flags().BoolVar(&attachLastFlag, "last", false, "Store the most recent session, regardless of its age")
+	attachCmd.Flags().BoolVar(&attachReplaceFlag, "replace", false, "Replace the conversations already stored on the commit")
+	attachCmd.Flags().BoolVar(&privateFlag, "private", false, "Keep the conversation in this clone until it is published")
+	rootCmd.AddCommand(attachCmd)
+}
+
+func runAttach(cmd *cobra.Command, args []string) error {
+	if err := git.RequireGitRepo(); err != nil {
+		return err
+	}
+	if attachLastFlag == (len(args) == 1) {
+		return fmt.Errorf("give either a session ID or --last")
+	}
+
+	commit, err := git.ResolveRef(attachCommitFlag)
+	if err != nil {
+		return fmt.Errorf("could not resolve reference '%s': not a valid commit", attachCommitFlag)
+	}
+
+	projectPath, err := git.GetRepoRoot()
+	if err != nil {
+		return fmt.Errorf("could not determine repository root: %w", err)
+	}
+
+	var session *projectSession
+	if attachLastFlag {
+		sessions, err := listProjectSessions(projectPath, attachAgentFlag)
+		if err != nil {
+			return err
+		}
+		if len(sessions) == 0 {
+			return fmt.Errorf("no sessions found for this project")
+		}
+		session = &sessions[0]
+		cli.LogInfo("attaching %s session %s from %s", session.Agent.DisplayName(), session.SessionID, formatAge(session.StartedAt))
+	} else {
+		session, err = findProjectSession(projectPath, attachAgentFlag, args[0])
+		if err != nil {
+			return err
+		}
+	}
+
+	return storeConversationFor(commit, session.Agent, session.SessionID, session.TranscriptPath, session.TranscriptData, storage.TriggerAttach, attachReplaceFlag)
+}
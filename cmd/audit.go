@@ -0,0 +1,142 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/re-cinq/shift-log/internal/audit"
+	"github.com/re-cinq/shift-log/internal/cli"
+	"github.com/re-cinq/shift-log/internal/git"
+	"github.com/spf13/cobra"
+)
+
+// auditShownArtifacts is the number of artifacts a record lists in text
+// output before summing up the rest.
+const auditShownArtifacts = 3
+
+var (
+	auditSince   string
+	auditUser    string
+	auditCommand string
+	auditLimit   int
+	auditFormat  string
+)
+
+var auditCmd = &cobra.Command{
+	Use:     "audit",
+	Short:   "Show who changed conversations with shiftlog, and when",
+	GroupID: "human",
+	Long: `Every run of a shiftlog command that changes something, storing,
+tagging, syncing, importing, recovering or removing conversations and their
+hooks and settings, is recorded in .shiftlog/audit.jsonl: when it ran, the
+git user and host that ran it, its arguments, whether a hook ran it, whether
+it failed, and what it changed. Runs that change nothing are not recorded.
+
+The log is local to the clone, only ever appended to, and not written in
+repositories without a .shiftlog directory.`,
+}
+
+var auditLogCmd = &cobra.Command{
+	Use:   "log",
+	Short: "List the recorded operations",
+	Long: `Lists the operations recorded in the audit log, oldest first.
+
+Examples:
+  shiftlog audit log
+  shiftlog audit log --since 1.week --command sync
+  shiftlog audit log --user ada@example.com --format json`,
+	Args: cobra.NoArgs,
+	RunE: runAuditLog,
+}
+
+func init() {
+	auditLogCmd.Flags().StringVar(&auditSince, "since", "", "only list operations more recent than this date (as git log --since)")
+	auditLogCmd.Flags().StringVar(&auditUser, "user", "", "only list operations of users whose name or email contains this")
+	auditLogCmd.Flags().StringVar(&auditCommand, "command", "", `only list runs of this command and its subcommands, e.g. "sync"`)
+	auditLogCmd.Flags().IntVarP(&auditLimit, "limit", "n", 0, "only list the most recent operations (0 for all)")
+	auditLogCmd.Flags().StringVar(&auditFormat, "format", "text", "output format: text or json")
+	rootCmd.AddCommand(auditCmd)
+	auditCmd.AddCommand(auditLogCmd)
+}
+
+func runAuditLog(cmd *cobra.Command, args []string) error {
+	if auditFormat != "text" && auditFormat != "json" {
+		return fmt.Errorf("invalid --format %q: must be text or json", auditFormat)
+	}
+	if err := git.RequireGitRepo(); err != nil {
+		return err
+	}
+
+	filter := audit.Filter{User: auditUser, Command: strings.TrimPrefix(auditCommand, "shiftlog ")}
+	if auditSince != "" {
+		since, err := git.ParseDate(auditSince)
+		if err != nil {
+			return fmt.Errorf("invalid --since %q: %w", auditSince, err)
+		}
+		filter.Since = since
+	}
+	records, invalid, err := audit.Read(filter)
+	if err != nil {
+		return fmt.Errorf("could not read audit log: %w", err)
+	}
+	if invalid > 0 {
+		cli.LogWarning("skipped %d unreadable line(s) of the audit log", invalid)
+	}
+	if auditLimit > 0 && len(records) > auditLimit {
+		records = records[len(records)-auditLimit:]
+	}
+
+	if auditFormat == "json" {
+		if records == nil {
+			records = []audit.Record{}
+		}
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetEscapeHTML(false)
+		enc.SetIndent("", "  ")
+		return enc.Encode(records)
+	}
+
+	if len(records) == 0 {
+		fmt.Println("no recorded operations")
+		return nil
+	}
+	for _, r := range records {
+		fmt.Println(formatAuditRecord(r))
+	}
+	return nil
+}
+
+// formatAuditRecord formats a record as a line of text output, e.g.
+// "2024-05-01 10:00:00  Ada <ada@example.com>  store (hook)  note 3f2a1b9c".
+func formatAuditRecord(r audit.Record) string {
+	when := r.Time
+	if t, err := time.Parse(time.RFC3339, r.Time); err == nil {
+		when = t.Local().Format("2006-01-02 15:04:05")
+	}
+	command := strings.TrimPrefix(r.Command, "shiftlog ")
+	if r.Hook {
+		command += " (hook)"
+	}
+
+	var changed []string
+	for i, a := range r.Artifacts {
+		if i == auditShownArtifacts {
+			changed = append(changed, fmt.Sprintf("and %d more", len(r.Artifacts)-i))
+			break
+		}
+		id := a.ID
+		if len(id) == 40 && strings.Trim(id, "0123456789abcdef") == "" {
+			id = id[:8]
+		}
+		changed = append(changed, a.Kind+" "+id)
+	}
+
+	line := fmt.Sprintf("%s  %s  %s  %s", when, r.User, command, strings.Join(changed, ", "))
+	if r.Status == "error" {
+		line += "  (failed: " + r.Error + ")"
+	}
+	return line
+}
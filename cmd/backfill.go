@@ -0,0 +1,283 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/re-cinq/shift-log/internal/agent"
+	"github.com/re-cinq/shift-log/internal/cli"
+	"github.com/re-cinq/shift-log/internal/git"
+	"github.com/re-cinq/shift-log/internal/storage"
+	"github.com/re-cinq/shift-log/internal/util"
+	"github.com/spf13/cobra"
+)
+
+var (
+	backfillAgentFlag  string
+	backfillSinceFlag  string
+	backfillWithinFlag time.Duration
+	backfillDryRunFlag bool
+	backfillYesFlag    bool
+)
+
+var backfillCmd = &cobra.Command{
+	Use:     "backfill",
+	Short:   "Attach old agent sessions to the commits they produced",
+	GroupID: "human",
+	Long: `Scans the coding agent's local session storage for the project's
+sessions and stores each on the commits made while it was active, for
+history that predates shiftlog or was committed without its hooks.
+
+A commit on a local branch without a conversation matches a session when it
+was committed between the session's first entry and --within after its
+last one. When several sessions match, the one recorded on a branch that
+contains the commit wins (agents that record the branch, like Claude Code),
+then the one started last. Each commit gets the session's transcript up to
+the time of the commit. Merge commits are skipped.
+
+The matches are listed first and stored only after confirmation, or with
+--yes. --dry-run only lists them.
+
+Examples:
+  shiftlog backfill --agent claude
+  shiftlog backfill --agent claude --since 3.months --dry-run
+  shiftlog backfill --within 2h --yes`,
+	Args: cobra.NoArgs,
+	RunE: runBackfill,
+}
+
+func init() {
+	backfillCmd.Flags().StringVar(&backfillAgentFlag, "agent", "", "Only backfill sessions of this coding agent")
+	backfillCmd.Flags().StringVar(&backfillSinceFlag, "since", "", "only consider commits more recent than this date (as git log --since)")
+	backfillCmd.Flags().DurationVar(&backfillWithinFlag, "within", 30*time.Minute, "how long after a session's last entry a commit still matches it")
+	backfillCmd.Flags().BoolVar(&backfillDryRunFlag, "dry-run", false, "list the matches without storing them")
+	backfillCmd.Flags().BoolVarP(&backfillYesFlag, "yes", "y", false, "store the matches without asking for confirmation")
+	backfillCmd.Flags().BoolVar(&privateFlag, "private", false, "keep the conversations in this clone until they are published")
+	rootCmd.AddCommand(backfillCmd)
+}
+
+// backfillCommitSlack is how long after a commit's time the entries of its
+// session still belong to it: the result of the git commit tool call is
+// recorded after the commit, and git dates have a resolution of a second.
+const backfillCommitSlack = time.Minute
+
+// backfillSession is a project session with the span of its entries and the
+// branch it was recorded on, if the agent records one.
+type backfillSession struct {
+	projectSession
+	data       []byte
+	start, end time.Time
+	branch     string
+}
+
+// backfillMatch is a commit and the session that produced it.
+type backfillMatch struct {
+	commit  git.LogCommit
+	at      time.Time
+	session *backfillSession
+}
+
+func runBackfill(cmd *cobra.Command, args []string) error {
+	if err := git.RequireGitRepo(); err != nil {
+		return err
+	}
+	projectPath, err := git.GetRepoRoot()
+	if err != nil {
+		return fmt.Errorf("could not determine repository root: %w", err)
+	}
+
+	sessions, err := loadBackfillSessions(projectPath, backfillAgentFlag)
+	if err != nil {
+		return err
+	}
+	if len(sessions) == 0 {
+		fmt.Println("no sessions found")
+		return nil
+	}
+
+	matches, err := matchBackfillSessions(sessions, backfillSinceFlag, backfillWithinFlag)
+	if err != nil {
+		return err
+	}
+	if len(matches) == 0 {
+		fmt.Println("no commits without a conversation match a session")
+		return nil
+	}
+
+	idWidth := 0
+	for _, m := range matches {
+		idWidth = max(idWidth, len(m.session.SessionID))
+	}
+	for _, m := range matches {
+		fmt.Printf("%s  %s  %-*s  %s\n", m.commit.SHA[:7], m.at.Local().Format("2006-01-02 15:04"),
+			idWidth, m.session.SessionID, m.commit.Subject)
+	}
+	if backfillDryRunFlag {
+		return nil
+	}
+	if !backfillYesFlag && !cli.Confirm(fmt.Sprintf("shiftlog: store %d conversations on these commits?", len(matches))) {
+		return fmt.Errorf("not storing conversations without confirmation; pass --yes to store them")
+	}
+
+	stored := 0
+	for _, m := range matches {
+		s := m.session
+		transcriptData := transcriptUntil(s.data, m.at.Add(backfillCommitSlack))
+		err := storeBuiltConversation(m.commit.SHA, s.Agent, s.SessionID, false, func() (*storage.StoredConversation, []agent.TranscriptEntry, error) {
+			return conversationFromTranscript(m.commit.SHA, s.Agent, s.SessionID, transcriptData, storage.TriggerBackfill, projectPath, s.branch)
+		})
+		if err != nil {
+			cli.LogWarning("could not store session %s on %s: %v", s.SessionID, m.commit.SHA[:8], err)
+			continue
+		}
+		stored++
+	}
+	fmt.Printf("Stored %d of %d conversations\n", stored, len(matches))
+	return nil
+}
+
+// loadBackfillSessions returns the project's sessions of every agent, or
+// only of the named one, with the span of their timestamped entries.
+// Sessions without timestamps cannot be matched and are left out.
+func loadBackfillSessions(projectPath, agentName string) ([]*backfillSession, error) {
+	found, err := listProjectSessions(projectPath, agentName)
+	if err != nil {
+		return nil, err
+	}
+
+	var sessions []*backfillSession
+	for _, ps := range found {
+		data := ps.TranscriptData
+		if len(data) == 0 && ps.TranscriptPath != "" {
+			if data, err = readTranscriptData(ps.TranscriptPath); err != nil {
+				cli.LogDebug("backfill: could not read session %s: %v", ps.SessionID, err)
+				continue
+			}
+		}
+		transcript, err := ps.Agent.ParseTranscript(bytes.NewReader(data))
+		if err != nil {
+			cli.LogDebug("backfill: could not parse session %s: %v", ps.SessionID, err)
+			continue
+		}
+
+		s := &backfillSession{projectSession: ps, data: data}
+		for _, entry := range transcript.Entries {
+			ts, err := util.ParseTimestamp(entry.Timestamp)
+			if entry.Timestamp == "" || err != nil {
+				continue
+			}
+			if s.start.IsZero() || ts.Before(s.start) {
+				s.start = ts
+			}
+			if ts.After(s.end) {
+				s.end = ts
+			}
+			var recorded struct {
+				GitBranch string `json:"gitBranch"`
+			}
+			if json.Unmarshal(entry.Raw, &recorded) == nil && recorded.GitBranch != "" {
+				s.branch = recorded.GitBranch
+			}
+		}
+		if s.start.IsZero() {
+			cli.LogDebug("backfill: session %s has no timestamps", ps.SessionID)
+			continue
+		}
+		sessions = append(sessions, s)
+	}
+	return sessions, nil
+}
+
+// matchBackfillSessions matches the commits of the local branches that have
+// no conversation to the sessions active when they were committed, oldest
+// commit first so that each stores only what its session added since the
+// previous one.
+func matchBackfillSessions(sessions []*backfillSession, since string, within time.Duration) ([]backfillMatch, error) {
+	noted, err := git.ListAllCommitsWithNotes("")
+	if err != nil {
+		return nil, fmt.Errorf("could not list conversations: %w", err)
+	}
+
+	// The commits of each branch sessions were recorded on, to prefer the
+	// session of a commit's own branch
+	onBranch := make(map[string]map[string]bool)
+	for _, s := range sessions {
+		if s.branch == "" || onBranch[s.branch] != nil {
+			continue
+		}
+		onBranch[s.branch] = map[string]bool{}
+		if commits, err := git.ListCommitsInRange("refs/heads/" + s.branch); err == nil {
+			for _, sha := range commits {
+				onBranch[s.branch][sha] = true
+			}
+		}
+	}
+
+	var matches []backfillMatch
+	err = git.ListCommits(git.LogOptions{Ref: "--branches", Since: since}, func(c git.LogCommit) bool {
+		if noted[c.SHA] || c.Parents > 1 {
+			return true
+		}
+		at, err := util.ParseTimestamp(c.Date)
+		if err != nil {
+			return true
+		}
+
+		var best *backfillSession
+		for _, s := range sessions {
+			if at.Before(s.start) || at.After(s.end.Add(within)) {
+				continue
+			}
+			if best == nil || betterBackfillSession(s, best, c.SHA, onBranch) {
+				best = s
+			}
+		}
+		if best != nil {
+			matches = append(matches, backfillMatch{commit: c, at: at, session: best})
+		}
+		return true
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not list commits: %w", err)
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool { return matches[i].at.Before(matches[j].at) })
+	return matches, nil
+}
+
+// betterBackfillSession reports whether session s is a better match for
+// commit sha than best: recorded on a branch containing the commit, or
+// else started later.
+func betterBackfillSession(s, best *backfillSession, sha string, onBranch map[string]map[string]bool) bool {
+	sOnBranch, bestOnBranch := onBranch[s.branch][sha], onBranch[best.branch][sha]
+	if sOnBranch != bestOnBranch {
+		return sOnBranch
+	}
+	return s.start.After(best.start)
+}
+
+// transcriptUntil returns the lines of a JSONL transcript up to the first
+// entry recorded after cutoff, the transcript as it was when a commit was
+// made. Lines without a timestamp are kept. Transcripts that are a single
+// JSON document are returned whole.
+func transcriptUntil(data []byte, cutoff time.Time) []byte {
+	if json.Valid(data) {
+		return data
+	}
+	lines := bytes.Split(data, []byte("\n"))
+	for i, line := range lines {
+		var entry struct {
+			Timestamp string `json:"timestamp"`
+		}
+		if json.Unmarshal(line, &entry) != nil || entry.Timestamp == "" {
+			continue
+		}
+		if ts, err := util.ParseTimestamp(entry.Timestamp); err == nil && ts.After(cutoff) {
+			return append(bytes.Join(lines[:i], []byte("\n")), '\n')
+		}
+	}
+	return data
+}
@@ -0,0 +1,101 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/re-cinq/shift-log/internal/backup"
+	"github.com/re-cinq/shift-log/internal/cli"
+	"github.com/re-cinq/shift-log/internal/git"
+	"github.com/spf13/cobra"
+)
+
+var backupSkipConfig bool
+
+var backupCmd = &cobra.Command{
+	Use:     "backup",
+	Short:   "Back up or restore conversation notes",
+	GroupID: "human",
+	Long: `Saves every stored conversation (the full notes ref history) and the
+local .shiftlog/config to a single file, and restores them from it.
+
+Take a backup before risky history surgery such as large rebases or
+filter-repo runs. Backups do not depend on any remote.
+
+The file name selects the compression: .tar.zst, .tar.gz, or .tar.
+
+Examples:
+  shiftlog backup create backup.tar.zst
+  shiftlog backup restore backup.tar.zst
+  shiftlog backup restore --no-config backup.tar.gz`,
+}
+
+var backupCreateCmd = &cobra.Command{
+	Use:   "create <file>",
+	Short: "Write conversation notes and config to a backup file",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runBackupCreate,
+}
+
+var backupRestoreCmd = &cobra.Command{
+	Use:   "restore <file>",
+	Short: "Replace conversation notes and config from a backup file",
+	Long: `Replaces the notes ref with the one saved in the backup. The notes
+being replaced are kept in ` + git.NotesPreRestoreRef + `, so a restore
+can be undone with:
+
+  git update-ref ` + git.NotesRef + ` ` + git.NotesPreRestoreRef,
+	Args: cobra.ExactArgs(1),
+	RunE: runBackupRestore,
+}
+
+func init() {
+	backupRestoreCmd.Flags().BoolVar(&backupSkipConfig, "no-config", false, "restore notes only, keeping the current .shiftlog/config")
+	rootCmd.AddCommand(backupCmd)
+	backupCmd.AddCommand(backupCreateCmd)
+	backupCmd.AddCommand(backupRestoreCmd)
+}
+
+func runBackupCreate(cmd *cobra.Command, args []string) error {
+	if err := git.RequireGitRepo(); err != nil {
+		return err
+	}
+
+	manifest, err := backup.Create(args[0])
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Backed up %d conversations to %s\n", manifest.Conversations, args[0])
+	cli.RecordArtifact("file", args[0])
+	if manifest.HasConfig {
+		fmt.Println("Included .shiftlog/config")
+	}
+	return nil
+}
+
+func runBackupRestore(cmd *cobra.Command, args []string) error {
+	if err := git.RequireGitRepo(); err != nil {
+		return err
+	}
+
+	previous, err := git.GetNotesCommit()
+	if err != nil {
+		return fmt.Errorf("could not read notes ref: %w", err)
+	}
+
+	manifest, err := backup.Restore(args[0], !backupSkipConfig)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Restored %d conversations from %s (created %s)\n", manifest.Conversations, args[0], manifest.CreatedAt)
+	cli.RecordArtifact("ref", git.NotesRef)
+	if manifest.HasConfig && !backupSkipConfig {
+		fmt.Println("Restored .shiftlog/config")
+		cli.RecordArtifact("config", ".shiftlog/config")
+	}
+	if previous != "" && previous != manifest.NotesCommit {
+		fmt.Printf("Previous notes saved to %s\n", git.NotesPreRestoreRef)
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/re-cinq/shift-log/internal/badge"
+	"github.com/re-cinq/shift-log/internal/cli"
+	"github.com/re-cinq/shift-log/internal/git"
+	"github.com/re-cinq/shift-log/internal/storage"
+	"github.com/spf13/cobra"
+)
+
+var (
+	badgeOut   string
+	badgeLimit int
+	badgeRef   string
+	badgeLabel string
+)
+
+var badgeCmd = &cobra.Command{
+	Use:     "badge",
+	Short:   "Render a badge of the commits with a conversation",
+	GroupID: "human",
+	Long: `Renders an SVG badge such as "AI-logged | 87% of last 100 commits", the
+share of the recent commits that have a stored conversation, for the README
+of a project that keeps the audit trail of its agents.
+
+Merge commits are not counted. The badge is written to stdout, or to the
+file given with --out, e.g. to commit it or publish it from CI.
+'shiftlog serve' serves the same badge at /badge.svg, with the limit, ref
+and label as query parameters.
+
+Examples:
+  shiftlog badge --out docs/ai-logged.svg
+  shiftlog badge --limit 50 --ref main --label "agent audit"`,
+	RunE: runBadge,
+}
+
+func init() {
+	badgeCmd.Flags().StringVarP(&badgeOut, "out", "o", "", "file to write the badge to (default: stdout)")
+	badgeCmd.Flags().IntVar(&badgeLimit, "limit", 100, "number of recent commits to count")
+	badgeCmd.Flags().StringVar(&badgeRef, "ref", "HEAD", "branch or commit to count the history of")
+	badgeCmd.Flags().StringVar(&badgeLabel, "label", badge.DefaultLabel, "label on the left of the badge")
+	rootCmd.AddCommand(badgeCmd)
+}
+
+func runBadge(cmd *cobra.Command, args []string) error {
+	if err := git.RequireGitRepo(); err != nil {
+		return err
+	}
+	if badgeLimit <= 0 {
+		return fmt.Errorf("--limit must be positive")
+	}
+	sha, err := git.ResolveRef(badgeRef + "^{commit}")
+	if err != nil {
+		return fmt.Errorf("unknown ref %q", badgeRef)
+	}
+
+	cov, err := storage.CommitCoverage(sha, badgeLimit)
+	if err != nil {
+		return err
+	}
+	svg := badge.Coverage(badgeLabel, cov)
+
+	if badgeOut == "" {
+		_, err := os.Stdout.Write(svg)
+		return err
+	}
+	if err := os.WriteFile(badgeOut, svg, 0644); err != nil {
+		return fmt.Errorf("failed to write badge: %w", err)
+	}
+	cli.RecordArtifact("file", badgeOut)
+	fmt.Printf("Wrote %s (%s)\n", badgeOut, badge.CoverageMessage(cov))
+	return nil
+}
@@ -0,0 +1,200 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/re-cinq/shift-log/internal/cli"
+	"github.com/re-cinq/shift-log/internal/daemon"
+	"github.com/re-cinq/shift-log/internal/git"
+	"github.com/re-cinq/shift-log/internal/storage"
+	"github.com/spf13/cobra"
+)
+
+var (
+	blameLines  string
+	blameFormat string
+)
+
+var blameCmd = &cobra.Command{
+	Use:     "blame <file>",
+	Short:   "Show which conversation produced each line of a file",
+	GroupID: "human",
+	Long: `Combines git blame with stored conversations: for each line of a file,
+shows the commit that last changed it, whether that commit has a stored
+conversation, and which agent and model produced it.
+
+Open the transcript behind a line with 'shiftlog show <sha>'.
+
+Output formats:
+  table   aligned terminal output (default)
+  json    one object per line, for editor integrations
+
+Examples:
+  shiftlog blame main.go                  # Attribute every line
+  shiftlog blame main.go -L 10,20         # Only lines 10 to 20
+  shiftlog blame main.go --format json    # Machine-readable output`,
+	Args: cobra.ExactArgs(1),
+	RunE: runBlame,
+}
+
+func init() {
+	blameCmd.Flags().StringVarP(&blameLines, "lines", "L", "", "line range to blame, as <start>,<end>")
+	blameCmd.Flags().StringVar(&blameFormat, "format", "table", "output format: table or json")
+	rootCmd.AddCommand(blameCmd)
+}
+
+// BlameEntry is the attribution of a single line.
+type BlameEntry struct {
+	Line            int    `json:"line"`
+	Commit          string `json:"commit,omitempty"`
+	HasConversation bool   `json:"has_conversation"`
+	Agent           string `json:"agent,omitempty"`
+	Model           string `json:"model,omitempty"`
+	SessionID       string `json:"session_id,omitempty"`
+	Content         string `json:"content"`
+}
+
+func runBlame(cmd *cobra.Command, args []string) error {
+	if err := git.RequireGitRepo(); err != nil {
+		return err
+	}
+
+	if blameFormat != "table" && blameFormat != "json" {
+		return fmt.Errorf("invalid --format %q: must be table or json", blameFormat)
+	}
+
+	start, end, err := parseLineRange(blameLines)
+	if err != nil {
+		return err
+	}
+
+	lines, err := git.Blame(args[0], start, end)
+	if err != nil {
+		return fmt.Errorf("could not blame %s: %w", args[0], err)
+	}
+
+	entries := attributeLines(lines)
+
+	if blameFormat == "json" {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(entries)
+	}
+
+	printBlameTable(entries)
+	return nil
+}
+
+// parseLineRange parses a "<start>,<end>" range. An empty range means the whole file.
+func parseLineRange(spec string) (int, int, error) {
+	if spec == "" {
+		return 0, 0, nil
+	}
+	startStr, endStr, ok := strings.Cut(spec, ",")
+	start, err1 := strconv.Atoi(strings.TrimSpace(startStr))
+	end, err2 := strconv.Atoi(strings.TrimSpace(endStr))
+	if !ok || err1 != nil || err2 != nil || start < 1 || end < start {
+		return 0, 0, fmt.Errorf("invalid line range %q: expected <start>,<end>", spec)
+	}
+	return start, end, nil
+}
+
+// attributeLines looks up the stored conversation for each blamed commit.
+func attributeLines(lines []git.BlameLine) []BlameEntry {
+	// Many lines share a commit; read each note once
+	cache := make(map[string]*storage.StoredConversation)
+	if c := connectDaemon(); c != nil {
+		cache = daemonAttribution(c, lines)
+		_ = c.Close()
+	}
+
+	entries := make([]BlameEntry, 0, len(lines))
+	for _, line := range lines {
+		entry := BlameEntry{Line: line.Line, Content: line.Content}
+		if line.IsCommitted() {
+			entry.Commit = line.CommitSHA
+
+			stored, ok := cache[line.CommitSHA]
+			if !ok {
+				stored, _ = storage.GetStoredConversation(line.CommitSHA)
+				cache[line.CommitSHA] = stored
+			}
+
+			if stored != nil {
+				entry.HasConversation = true
+				entry.Agent = stored.Agent
+				if entry.Agent == "" {
+					entry.Agent = "claude"
+				}
+				entry.Model = stored.Model
+				entry.SessionID = stored.SessionID
+			}
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+// daemonAttribution returns the conversation of each blamed commit from
+// the daemon's index, as GetStoredConversation would read it, with nil for
+// commits that have none. It returns an empty map when the daemon fails,
+// leaving every commit to be read.
+func daemonAttribution(c *daemon.Client, lines []git.BlameLine) map[string]*storage.StoredConversation {
+	var commits []string
+	seen := make(map[string]bool)
+	for _, line := range lines {
+		if line.IsCommitted() && !seen[line.CommitSHA] {
+			seen[line.CommitSHA] = true
+			commits = append(commits, line.CommitSHA)
+		}
+	}
+	attributed, err := c.Attribution(commits)
+	if err != nil {
+		cli.LogDebug("daemon: %v", err)
+		return make(map[string]*storage.StoredConversation)
+	}
+	cache := make(map[string]*storage.StoredConversation, len(commits))
+	for _, sha := range commits {
+		cache[sha] = attributed[sha]
+	}
+	return cache
+}
+
+func printBlameTable(entries []BlameEntry) {
+	useColor := os.Getenv("NO_COLOR") == ""
+
+	agentWidth, modelWidth, lineWidth := 1, 1, 1
+	for _, e := range entries {
+		agentWidth = max(agentWidth, len(e.Agent))
+		modelWidth = max(modelWidth, len(e.Model))
+		lineWidth = max(lineWidth, len(strconv.Itoa(e.Line)))
+	}
+
+	for _, e := range entries {
+		sha := "0000000"
+		if len(e.Commit) >= 7 {
+			sha = e.Commit[:7]
+		}
+		marker := " "
+		agentName, model := "-", "-"
+		if e.HasConversation {
+			marker = "*"
+			agentName = e.Agent
+			if e.Model != "" {
+				model = e.Model
+			}
+		}
+
+		prefix := fmt.Sprintf("%s %s %-*s %-*s %*d", sha, marker, agentWidth, agentName, modelWidth, model, lineWidth, e.Line)
+		if useColor && !e.HasConversation {
+			prefix = ansiDim + prefix + ansiReset
+		} else if useColor {
+			prefix = ansiBold + prefix + ansiReset
+		}
+		fmt.Printf("%s | %s\n", prefix, e.Content)
+	}
+}
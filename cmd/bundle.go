@@ -0,0 +1,115 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/re-cinq/shift-log/internal/cli"
+	"github.com/re-cinq/shift-log/internal/git"
+	"github.com/spf13/cobra"
+)
+
+var bundleSince string
+
+var bundleCmd = &cobra.Command{
+	Use:     "bundle",
+	Short:   "Move conversations between clones without a shared remote",
+	GroupID: "human",
+	Long: `Writes conversations and the commits they belong to into a single git
+bundle file, and imports them from it in another clone. Use it to carry
+conversations into and out of environments that cannot reach the remote
+'shiftlog sync' pushes to.
+
+Importing merges the bundled conversations into the local ones like
+'shiftlog sync pull', so a bundle can be imported into a clone that
+already has conversations of its own.
+
+Examples:
+  shiftlog bundle create conversations.bundle
+  shiftlog bundle create --since 2.weeks recent.bundle
+  shiftlog bundle import conversations.bundle`,
+}
+
+var bundleCreateCmd = &cobra.Command{
+	Use:   "create <file>",
+	Short: "Write conversations and their commits to a bundle file",
+	Long: `Writes the conversation notes and the commits they belong to, with
+their history, to a git bundle.
+
+With --since, only conversations of commits made after the date are
+bundled, and older history is left out: the importing clone must already
+have the commits the bundled ones build on.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runBundleCreate,
+}
+
+var bundleImportCmd = &cobra.Command{
+	Use:   "import <file>",
+	Short: "Import conversations and their commits from a bundle file",
+	Long: `Fetches the commits of a bundle written by 'shiftlog bundle create'
+and merges its conversations into the local notes.
+
+Imported commits that no branch contains are kept alive under
+` + git.ImportedCommitsRefPrefix + `<sha>, so their conversations can be
+read until the commits reach a branch.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runBundleImport,
+}
+
+func init() {
+	bundleCreateCmd.Flags().StringVar(&bundleSince, "since", "", "only bundle conversations of commits more recent than this date (as git log --since)")
+	rootCmd.AddCommand(bundleCmd)
+	bundleCmd.AddCommand(bundleCreateCmd)
+	bundleCmd.AddCommand(bundleImportCmd)
+}
+
+func runBundleCreate(cmd *cobra.Command, args []string) error {
+	if err := git.RequireGitRepo(); err != nil {
+		return err
+	}
+
+	noted, err := git.ListAllCommitsWithNotes("")
+	if err != nil {
+		return fmt.Errorf("could not list conversations: %w", err)
+	}
+	shas := make([]string, 0, len(noted))
+	for sha := range noted {
+		shas = append(shas, sha)
+	}
+	commits, err := git.ExistingCommitsSince(shas, bundleSince)
+	if err != nil {
+		return fmt.Errorf("could not select commits: %w", err)
+	}
+	if len(commits) == 0 {
+		if bundleSince != "" {
+			return fmt.Errorf("no conversations since %s", bundleSince)
+		}
+		return fmt.Errorf("no conversations to bundle")
+	}
+
+	if err := git.CreateConversationBundle(args[0], commits, bundleSince); err != nil {
+		return fmt.Errorf("could not create bundle: %w", err)
+	}
+
+	fmt.Printf("Bundled %d conversations to %s\n", len(commits), args[0])
+	cli.RecordArtifact("file", args[0])
+	return nil
+}
+
+func runBundleImport(cmd *cobra.Command, args []string) error {
+	if err := git.RequireGitRepo(); err != nil {
+		return err
+	}
+
+	result, err := git.ImportConversationBundle(args[0])
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Imported %d conversations from %s (%d new)\n", result.Conversations, args[0], result.New)
+	cli.RecordArtifact("ref", git.NotesRef)
+	if len(result.Detached) > 0 {
+		fmt.Printf("%d imported commits are on no branch; they are kept under %s\n",
+			len(result.Detached), git.ImportedCommitsRefPrefix)
+	}
+	return nil
+}
@@ -0,0 +1,224 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/re-cinq/shift-log/internal/cli"
+	"github.com/re-cinq/shift-log/internal/git"
+	"github.com/re-cinq/shift-log/internal/storage"
+	"github.com/spf13/cobra"
+)
+
+var (
+	checkRange              string
+	checkRequire            bool
+	checkMaxMissing         int
+	checkAllowAuthors       []string
+	checkAllowMessages      []string
+	checkNoDefaultAllowlist bool
+)
+
+var checkCmd = &cobra.Command{
+	Use:     "check",
+	Short:   "Check that the commits of a range have conversations",
+	GroupID: "human",
+	Long: `Lists the commits of a range that have no stored conversation, for use as
+a CI gate that agent-produced changes carry their audit trail.
+
+By default the command only reports. With --require-conversation it fails
+when any commit lacks a conversation, and with --max-missing N when more
+than N do.
+
+Some commits are not expected to have a conversation and are never counted
+as missing: commits whose author opted out of capture with shiftlog optout
+or SHIFTLOG_DISABLE, merge commits, reverts ("Revert ..." subjects) and commits by
+bots (authors with "[bot]" in their name or email). Add to the allowlist
+with --allow-author and --allow-message, regular expressions matched against
+"Name <email>" and the subject line; --no-default-allowlist drops the
+built-in rules.
+
+Without --range, a GitHub Actions pull request build checks the commits of
+the pull request (origin/$GITHUB_BASE_REF..HEAD) and other builds the
+commits not pushed to the upstream branch.
+
+CI clones do not fetch notes. Fetch them before checking, and when
+contributors opt out, the record of their commits too:
+  git fetch origin refs/notes/shiftlog:refs/notes/shiftlog
+  git fetch origin refs/notes/shiftlog-omissions:refs/notes/shiftlog-omissions
+
+Examples:
+  shiftlog check --range origin/main..HEAD --require-conversation
+  shiftlog check --range origin/main..HEAD --max-missing 2
+  shiftlog check --range v1.2.0..HEAD --allow-message '^chore\(deps\)'`,
+	RunE: runCheck,
+}
+
+func init() {
+	checkCmd.Flags().StringVar(&checkRange, "range", "", "commits to check, e.g. origin/main..HEAD (default: the pull request on GitHub Actions, else @{upstream}..HEAD)")
+	checkCmd.Flags().BoolVar(&checkRequire, "require-conversation", false, "fail when a commit has no conversation")
+	checkCmd.Flags().IntVar(&checkMaxMissing, "max-missing", 0, "fail when more than this many commits have no conversation")
+	checkCmd.Flags().StringArrayVar(&checkAllowAuthors, "allow-author", nil, "skip commits whose \"Name <email>\" matches this regular expression (repeatable)")
+	checkCmd.Flags().StringArrayVar(&checkAllowMessages, "allow-message", nil, "skip commits whose subject matches this regular expression (repeatable)")
+	checkCmd.Flags().BoolVar(&checkNoDefaultAllowlist, "no-default-allowlist", false, "also require conversations on merge, revert and bot commits")
+	rootCmd.AddCommand(checkCmd)
+}
+
+// checkAllowlist decides which commits need no conversation.
+type checkAllowlist struct {
+	defaults bool
+	authors  []*regexp.Regexp
+	messages []*regexp.Regexp
+}
+
+// reason returns why c needs no conversation, or "" when it needs one.
+func (a *checkAllowlist) reason(c git.LogCommit) string {
+	if a.defaults {
+		switch {
+		case c.Parents > 1:
+			return "merge"
+		case strings.HasPrefix(c.Subject, `Revert "`) || strings.HasPrefix(c.Subject, `Reapply "`):
+			return "revert"
+		case strings.Contains(c.Author, "[bot]") || strings.Contains(c.AuthorEmail, "[bot]"):
+			return "bot"
+		}
+	}
+	author := fmt.Sprintf("%s <%s>", c.Author, c.AuthorEmail)
+	for _, re := range a.authors {
+		if re.MatchString(author) {
+			return "allowed author"
+		}
+	}
+	for _, re := range a.messages {
+		if re.MatchString(c.Subject) {
+			return "allowed message"
+		}
+	}
+	return ""
+}
+
+// commitRange returns the range of commits to look at: flag when set, the
+// commits of the pull request in a GitHub Actions pull request build, and
+// otherwise the commits not yet pushed to the upstream branch.
+func commitRange(flag string) (string, error) {
+	if flag != "" {
+		return flag, nil
+	}
+	if base := os.Getenv("GITHUB_BASE_REF"); base != "" {
+		return "origin/" + base + "..HEAD", nil
+	}
+	if _, err := git.RunGitCommand("rev-parse", "--verify", "-q", "@{upstream}"); err != nil {
+		return "", fmt.Errorf("the current branch has no upstream; pass --range, e.g. --range origin/main..HEAD")
+	}
+	return "@{upstream}..HEAD", nil
+}
+
+// compilePatterns compiles the regular expressions of a flag.
+func compilePatterns(flag string, patterns []string) ([]*regexp.Regexp, error) {
+	var res []*regexp.Regexp
+	for _, p := range patterns {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --%s pattern %q: %w", flag, p, err)
+		}
+		res = append(res, re)
+	}
+	return res, nil
+}
+
+func runCheck(cmd *cobra.Command, args []string) error {
+	if err := git.RequireGitRepo(); err != nil {
+		return err
+	}
+
+	// A negative limit reports without failing
+	limit := -1
+	if checkRequire {
+		limit = 0
+	}
+	if cmd.Flags().Changed("max-missing") {
+		if checkMaxMissing < 0 {
+			return fmt.Errorf("--max-missing must not be negative")
+		}
+		limit = checkMaxMissing
+	}
+
+	allow := &checkAllowlist{defaults: !checkNoDefaultAllowlist}
+	var err error
+	if allow.authors, err = compilePatterns("allow-author", checkAllowAuthors); err != nil {
+		return err
+	}
+	if allow.messages, err = compilePatterns("allow-message", checkAllowMessages); err != nil {
+		return err
+	}
+
+	rangeSpec, err := commitRange(checkRange)
+	if err != nil {
+		return err
+	}
+
+	// From here on, failures are findings, not usage errors
+	cmd.SilenceUsage = true
+
+	stored, err := storage.ListAllConversationCommits()
+	if err != nil {
+		return fmt.Errorf("could not list conversations: %w", err)
+	}
+
+	omitted, err := git.ListOmittedCommits()
+	if err != nil {
+		return fmt.Errorf("could not list omitted conversations: %w", err)
+	}
+
+	var missing []git.LogCommit
+	total, withConversation, optedOut, allowed := 0, 0, 0, 0
+	err = git.ListCommits(git.LogOptions{Ref: rangeSpec}, func(c git.LogCommit) bool {
+		total++
+		switch {
+		case stored[c.SHA]:
+			withConversation++
+		case omitted[c.SHA]:
+			optedOut++
+		case allow.reason(c) != "":
+			allowed++
+		default:
+			missing = append(missing, c)
+		}
+		return true
+	})
+	if err != nil {
+		return fmt.Errorf("could not list commits in %s: %w", rangeSpec, err)
+	}
+
+	if total == 0 {
+		fmt.Printf("No commits in %s\n", rangeSpec)
+		return nil
+	}
+	if len(stored) == 0 && len(missing) > 0 {
+		cli.LogWarning("there are no conversation notes in this clone; fetch %s first", git.NotesRef)
+	}
+
+	if len(missing) > 0 {
+		fmt.Println("Commits without a conversation:")
+		for _, c := range missing {
+			fmt.Printf("  %s  %s  (%s)\n", c.SHA[:7], c.Subject, c.Author)
+		}
+		fmt.Println()
+	}
+	summary := fmt.Sprintf("%d with a conversation", withConversation)
+	if optedOut > 0 {
+		summary += fmt.Sprintf(", %d opted out", optedOut)
+	}
+	fmt.Printf("%d commit(s) in %s: %s, %d allowlisted, %d missing\n",
+		total, rangeSpec, summary, allowed, len(missing))
+
+	if limit >= 0 && len(missing) > limit {
+		if limit == 0 {
+			return fmt.Errorf("%d commit(s) without a conversation", len(missing))
+		}
+		return fmt.Errorf("%d commit(s) without a conversation, more than the %d allowed", len(missing), limit)
+	}
+	return nil
+}
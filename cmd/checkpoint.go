@@ -0,0 +1,277 @@
+package cmd
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/re-cinq/shift-log/internal/agent"
+	"github.com/re-cinq/shift-log/internal/cli"
+	"github.com/re-cinq/shift-log/internal/git"
+	"github.com/re-cinq/shift-log/internal/storage"
+	"github.com/spf13/cobra"
+)
+
+var (
+	checkpointStashFlag   bool
+	checkpointAgentFlag   string
+	checkpointSessionFlag string
+	promoteCommitFlag     string
+)
+
+var checkpointCmd = &cobra.Command{
+	Use:     "checkpoint",
+	Short:   "Save the active conversation without a commit",
+	GroupID: "human",
+	Long: `Saves a checkpoint of the active session's conversation, so its context
+is not lost when work is stashed or abandoned.
+
+The checkpoint is stored against a snapshot commit of your uncommitted
+changes, made like 'git stash create' without touching the working tree. You
+can get the changes back with 'git stash apply <snapshot>'. With --stash, the
+changes are stashed and the checkpoint is stored against the stash commit.
+Without changes, the checkpoint is stored against HEAD, and the next commit
+picks it up like one taken by 'shiftlog watch'.
+
+'shiftlog checkpoints' lists checkpoints, and 'shiftlog checkpoints promote'
+stores one on a commit.
+
+Examples:
+  shiftlog checkpoint                   # Snapshot changes and conversation
+  shiftlog checkpoint --stash           # Stash changes, keep the conversation with them
+  shiftlog checkpoint --session 4f6e1c2a-90b3-4c8e-a1d2-7b5f3e9c0d11`,
+	Args: cobra.NoArgs,
+	RunE: runCheckpoint,
+}
+
+var checkpointsCmd = &cobra.Command{
+	Use:     "checkpoints",
+	Short:   "List conversation checkpoints",
+	GroupID: "human",
+	Long: `Lists the conversation checkpoints taken by 'shiftlog checkpoint' and
+'shiftlog watch' that are not stored on a commit yet, newest first.
+
+The object is the snapshot, stash or commit the checkpoint was taken on.`,
+	Args: cobra.NoArgs,
+	RunE: runCheckpoints,
+}
+
+var checkpointsPromoteCmd = &cobra.Command{
+	Use:   "promote <object>",
+	Short: "Store a checkpoint on a commit",
+	Long: `Stores the conversations checkpointed on an object, as listed by
+'shiftlog checkpoints', on a commit, HEAD unless --commit is given. The
+checkpoint is then removed. Sessions already stored on the commit are kept.
+
+Examples:
+  shiftlog checkpoints promote abc1234
+  shiftlog checkpoints promote stash@{0} --commit HEAD~1`,
+	Args: cobra.ExactArgs(1),
+	RunE: runCheckpointsPromote,
+}
+
+func init() {
+	checkpointCmd.Flags().BoolVar(&checkpointStashFlag, "stash", false, "Stash uncommitted changes and store the checkpoint against the stash")
+	checkpointCmd.Flags().StringVar(&checkpointAgentFlag, "agent", "", "Coding agent of the session. Defaults to configured agent.")
+	checkpointCmd.Flags().StringVar(&checkpointSessionFlag, "session", "", "Checkpoint this session instead of the active one (see 'shiftlog sessions')")
+	checkpointsPromoteCmd.Flags().StringVar(&promoteCommitFlag, "commit", "HEAD", "Commit to store the conversation on")
+	checkpointsCmd.AddCommand(checkpointsPromoteCmd)
+	rootCmd.AddCommand(checkpointCmd)
+	rootCmd.AddCommand(checkpointsCmd)
+}
+
+func runCheckpoint(cmd *cobra.Command, args []string) error {
+	if err := git.RequireGitRepo(); err != nil {
+		return err
+	}
+	projectPath, err := git.GetRepoRoot()
+	if err != nil {
+		return fmt.Errorf("could not determine repository root: %w", err)
+	}
+
+	ag, session, err := checkpointTarget(projectPath)
+	if err != nil {
+		return err
+	}
+	transcriptData, err := readSessionTranscript(session)
+	if err != nil {
+		return err
+	}
+	stored, err := newCheckpoint(ag, session, transcriptData, projectPath, storage.TriggerCheckpoint)
+	if err != nil {
+		return err
+	}
+
+	message := "shiftlog checkpoint of " + ag.DisplayName() + " session " + session.SessionID
+	var object, label string
+	if checkpointStashFlag {
+		hasChanges, err := git.HasUncommittedChanges()
+		if err != nil {
+			return fmt.Errorf("could not check working directory status: %w", err)
+		}
+		if !hasChanges {
+			return fmt.Errorf("no local changes to stash")
+		}
+		if object, err = git.StashChanges(message); err != nil {
+			return fmt.Errorf("could not stash changes: %w", err)
+		}
+		label = "stash@{0}"
+	} else {
+		if object, err = git.CreateSnapshot(message); err != nil {
+			return fmt.Errorf("could not snapshot changes: %w", err)
+		}
+		label = "snapshot"
+		if object == "" {
+			if object, err = git.GetHeadCommit(); err != nil {
+				return fmt.Errorf("failed to get HEAD commit: %w", err)
+			}
+			label = "HEAD"
+		}
+	}
+
+	if err := storage.SaveCheckpoint(object, stored); err != nil {
+		return fmt.Errorf("failed to save checkpoint: %w", err)
+	}
+	fmt.Printf("checkpointed session %s (%d messages) on %s %s\n", session.SessionID, stored.MessageCount, label, object[:8])
+	cli.RecordArtifact("checkpoint", object)
+	return nil
+}
+
+// checkpointTarget returns the session to checkpoint: the one given with
+// --session, or the agent's active session.
+func checkpointTarget(projectPath string) (agent.Agent, *agent.SessionInfo, error) {
+	if checkpointSessionFlag != "" {
+		s, err := findProjectSession(projectPath, checkpointAgentFlag, checkpointSessionFlag)
+		if err != nil {
+			return nil, nil, err
+		}
+		return s.Agent, &s.SessionInfo, nil
+	}
+
+	ag, err := resolveAgent(checkpointAgentFlag)
+	if err != nil {
+		return nil, nil, err
+	}
+	applyGraceWindow()
+	session, err := ag.DiscoverSession(projectPath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("session discovery failed: %w", err)
+	}
+	if session == nil {
+		return nil, nil, fmt.Errorf("no active %s session found, pick one with --session (see 'shiftlog sessions')", ag.DisplayName())
+	}
+	return ag, session, nil
+}
+
+// checkpointEntry is a checkpoint with the object it was taken on.
+type checkpointEntry struct {
+	Object string
+	*storage.StoredConversation
+}
+
+func runCheckpoints(cmd *cobra.Command, args []string) error {
+	if err := git.RequireGitRepo(); err != nil {
+		return err
+	}
+
+	byObject, err := storage.ListCheckpoints()
+	if err != nil {
+		return err
+	}
+	var entries []checkpointEntry
+	for object, checkpoints := range byObject {
+		for _, sc := range checkpoints {
+			entries = append(entries, checkpointEntry{Object: object, StoredConversation: sc})
+		}
+	}
+	if len(entries) == 0 {
+		fmt.Println("no checkpoints found")
+		return nil
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		if entries[i].Timestamp != entries[j].Timestamp {
+			return entries[i].Timestamp > entries[j].Timestamp
+		}
+		return entries[i].Object < entries[j].Object
+	})
+
+	stashes, err := git.StashNames()
+	if err != nil {
+		stashes = nil
+	}
+	for _, e := range entries {
+		fmt.Printf("%s  %-10s %-8s %-10s %3d messages  %s\n",
+			e.Object[:7], checkpointKind(e.Object, stashes), formatAge(e.Timestamp), e.AgentName(), e.MessageCount, e.SessionID)
+	}
+	return nil
+}
+
+// checkpointKind describes the object a checkpoint was taken on.
+func checkpointKind(object string, stashes map[string]string) string {
+	if name, ok := stashes[object]; ok {
+		return name
+	}
+	if git.IsSnapshot(object) {
+		return "snapshot"
+	}
+	if git.CommitExists(object) {
+		return "commit"
+	}
+	return "missing"
+}
+
+func runCheckpointsPromote(cmd *cobra.Command, args []string) error {
+	if err := git.RequireGitRepo(); err != nil {
+		return err
+	}
+
+	object, err := resolveCheckpointObject(args[0])
+	if err != nil {
+		return err
+	}
+	commit, err := git.ResolveRef(promoteCommitFlag)
+	if err != nil {
+		return fmt.Errorf("could not resolve reference '%s': not a valid commit", promoteCommitFlag)
+	}
+
+	added, err := storage.PromoteCheckpoints(object, commit)
+	if err != nil {
+		return err
+	}
+	if len(added) == 0 {
+		fmt.Printf("sessions already stored on %s, removed checkpoint %s\n", commit[:8], object[:8])
+		return nil
+	}
+	for _, sc := range added {
+		fmt.Printf("stored checkpoint of session %s on %s\n", sc.SessionID, commit[:8])
+		cli.RecordArtifact("note", commit)
+	}
+	return nil
+}
+
+// resolveCheckpointObject resolves the object of a checkpoint from a prefix
+// of its SHA, or from a ref such as stash@{0}.
+func resolveCheckpointObject(arg string) (string, error) {
+	byObject, err := storage.ListCheckpoints()
+	if err != nil {
+		return "", err
+	}
+	var matches []string
+	for object := range byObject {
+		if strings.HasPrefix(object, arg) {
+			matches = append(matches, object)
+		}
+	}
+	if len(matches) == 1 {
+		return matches[0], nil
+	}
+	if len(matches) > 1 {
+		return "", fmt.Errorf("'%s' matches several checkpoints", arg)
+	}
+	if sha, err := git.ResolveRef(arg); err == nil {
+		if _, ok := byObject[sha]; ok {
+			return sha, nil
+		}
+	}
+	return "", fmt.Errorf("no checkpoint found for '%s' (see 'shiftlog checkpoints')", arg)
+}
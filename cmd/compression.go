@@ -0,0 +1,382 @@
+package cmd
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/re-cinq/shift-log/internal/cli"
+	"github.com/re-cinq/shift-log/internal/config"
+	"github.com/re-cinq/shift-log/internal/git"
+	"github.com/re-cinq/shift-log/internal/storage"
+	"github.com/spf13/cobra"
+)
+
+var (
+	compressionTrainSamples int
+	compressionTrainSize    int
+	compressionDryRun       bool
+)
+
+var compressionCmd = &cobra.Command{
+	Use:     "compression",
+	Short:   "Choose how transcripts are compressed, and train zstd dictionaries",
+	GroupID: "human",
+	Long: `Transcripts are gzip-compressed by default. Set "compression" to "zstd" in
+.shiftlog/config to compress new ones with zstd instead. Transcripts are
+read back whichever compression they were stored with.
+
+zstd compresses much better with a dictionary trained over the repository's
+own transcripts: 'shiftlog compression train' trains one, saves it in
+` + git.DictionariesRef + `, which sync shares with the other clones,
+and sets it as "compression_dictionary". 'shiftlog compression recompress'
+then rewrites the stored conversations with it.
+
+Set "transcript_chunks" to true to store new transcripts uncompressed
+instead, cut in content-defined chunks kept in ` + git.BlobsRef + `:
+the transcripts a session stores on consecutive commits share the chunks
+of their common prefix, which git stores, compresses and syncs once.
+
+Examples:
+  shiftlog compression status
+  shiftlog compression train
+  shiftlog compression recompress --dry-run`,
+}
+
+var compressionStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show the configured compression and the size of stored transcripts",
+	Args:  cobra.NoArgs,
+	RunE:  runCompressionStatus,
+}
+
+var compressionTrainCmd = &cobra.Command{
+	Use:   "train",
+	Short: "Train a zstd dictionary over stored transcripts and compress with it",
+	Long: `Trains a zstd dictionary over the transcripts of the most recent stored
+conversations, saves it in ` + git.DictionariesRef + `, and configures
+new transcripts to be compressed with zstd and the dictionary.
+
+Other clones read the transcripts it compressed once they have pulled it
+with 'shiftlog sync pull'. Retraining later keeps the earlier dictionaries,
+which older transcripts still need.`,
+	Args: cobra.NoArgs,
+	RunE: runCompressionTrain,
+}
+
+var compressionRecompressCmd = &cobra.Command{
+	Use:   "recompress",
+	Short: "Rewrite stored transcripts with the configured compression",
+	Long: `Rewrites every stored conversation whose transcript is not stored as
+configured: in content-defined chunks with "transcript_chunks", or else
+compressed with the configured compression and dictionary. Transcripts and
+their checksums are unchanged, so signatures stay valid.`,
+	Args: cobra.NoArgs,
+	RunE: runCompressionRecompress,
+}
+
+func init() {
+	compressionTrainCmd.Flags().IntVar(&compressionTrainSamples, "samples", 1000, "train over the transcripts of at most this many conversations, the most recent first")
+	compressionTrainCmd.Flags().IntVar(&compressionTrainSize, "size", storage.DefaultDictionarySize, "largest dictionary size, in bytes")
+	compressionRecompressCmd.Flags().BoolVar(&compressionDryRun, "dry-run", false, "report what would be rewritten without changing any note")
+	rootCmd.AddCommand(compressionCmd)
+	compressionCmd.AddCommand(compressionStatusCmd)
+	compressionCmd.AddCommand(compressionTrainCmd)
+	compressionCmd.AddCommand(compressionRecompressCmd)
+}
+
+// compressionName describes a compression for humans, e.g.
+// "zstd, dictionary 1234".
+func compressionName(algorithm string, dictID uint32) string {
+	if algorithm == "" {
+		algorithm = config.CompressionGzip
+	}
+	if dictID != 0 {
+		return fmt.Sprintf("%s, dictionary %d", algorithm, dictID)
+	}
+	return algorithm
+}
+
+// chunksName describes transcripts stored in content-defined chunks.
+const chunksName = "content-defined chunks"
+
+// storedTranscript describes how the transcript of sc is stored, as
+// compressionName does, and returns the bytes it is stored in: compressed,
+// or the sum of its chunks.
+func storedTranscript(sc *storage.StoredConversation) (string, int, error) {
+	if !sc.IsChunked() {
+		data, err := sc.CompressedTranscript()
+		if err != nil {
+			return "", 0, err
+		}
+		return compressionName(storage.Compression(data)), len(data), nil
+	}
+	sizes, err := git.BlobSizes(sc.TranscriptChunks)
+	if err != nil {
+		return "", 0, err
+	}
+	size := 0
+	for _, sha := range sc.TranscriptChunks {
+		size += sizes[sha]
+	}
+	return chunksName, size, nil
+}
+
+func runCompressionStatus(cmd *cobra.Command, args []string) error {
+	if err := git.RequireGitRepo(); err != nil {
+		return err
+	}
+	cfg, err := config.Read()
+	if err != nil {
+		return fmt.Errorf("could not read config: %w", err)
+	}
+	if cfg.TranscriptChunks {
+		fmt.Printf("New transcripts: %s\n", chunksName)
+	} else {
+		fmt.Printf("New transcripts: %s\n", compressionName(cfg.Compression, cfg.CompressionDictionary))
+	}
+
+	contents, err := storage.ReadAllConversations()
+	if err != nil {
+		return fmt.Errorf("could not read conversations: %w", err)
+	}
+	type usage struct{ conversations, bytes int }
+	byCompression := make(map[string]*usage)
+	chunks := make(map[string]bool)
+	for _, content := range contents {
+		conversations, err := storage.UnmarshalStoredConversations(content)
+		if err != nil {
+			continue
+		}
+		for _, sc := range conversations {
+			name, size, err := storedTranscript(sc)
+			if err != nil {
+				continue
+			}
+			if byCompression[name] == nil {
+				byCompression[name] = &usage{}
+			}
+			byCompression[name].conversations++
+			byCompression[name].bytes += size
+			for _, sha := range sc.TranscriptChunks {
+				chunks[sha] = true
+			}
+		}
+	}
+	names := make([]string, 0, len(byCompression))
+	for name := range byCompression {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	fmt.Println("Stored transcripts:")
+	if len(names) == 0 {
+		fmt.Println("  none")
+	}
+	for _, name := range names {
+		u := byCompression[name]
+		fmt.Printf("  %-28s %6d conversations %12d bytes\n", name, u.conversations, u.bytes)
+	}
+	if len(chunks) > 0 {
+		distinct := make([]string, 0, len(chunks))
+		for sha := range chunks {
+			distinct = append(distinct, sha)
+		}
+		sizes, err := git.BlobSizes(distinct)
+		if err != nil {
+			return fmt.Errorf("could not read transcript chunks: %w", err)
+		}
+		total := 0
+		for _, size := range sizes {
+			total += size
+		}
+		fmt.Printf("  %-28s %6d chunks        %12d bytes\n", "distinct chunks", len(chunks), total)
+	}
+
+	dictionaries, err := storage.Dictionaries()
+	if err != nil {
+		return fmt.Errorf("could not read dictionaries: %w", err)
+	}
+	ids := make([]uint32, 0, len(dictionaries))
+	for id := range dictionaries {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	fmt.Println("Dictionaries:")
+	if len(ids) == 0 {
+		fmt.Println("  none")
+	}
+	for _, id := range ids {
+		fmt.Printf("  %-28d %12d bytes\n", id, dictionaries[id])
+	}
+	return nil
+}
+
+func runCompressionTrain(cmd *cobra.Command, args []string) error {
+	if err := git.RequireGitRepo(); err != nil {
+		return err
+	}
+	if compressionTrainSamples < 1 {
+		return fmt.Errorf("--samples must be at least 1")
+	}
+
+	contents, err := storage.ReadAllConversations()
+	if err != nil {
+		return fmt.Errorf("could not read conversations: %w", err)
+	}
+	var conversations []*storage.StoredConversation
+	for _, content := range contents {
+		stored, err := storage.UnmarshalStoredConversations(content)
+		if err != nil {
+			continue
+		}
+		conversations = append(conversations, stored...)
+	}
+	if len(conversations) == 0 {
+		return fmt.Errorf("no stored conversations to train a dictionary over")
+	}
+	// RFC3339 timestamps in UTC sort chronologically
+	sort.Slice(conversations, func(i, j int) bool { return conversations[i].Timestamp > conversations[j].Timestamp })
+	if len(conversations) > compressionTrainSamples {
+		conversations = conversations[:compressionTrainSamples]
+	}
+
+	var samples [][]byte
+	for _, sc := range conversations {
+		transcript, err := sc.GetTranscript()
+		if err != nil {
+			cli.LogWarning("skipping the transcript of session %s: %v", sc.SessionID, err)
+			continue
+		}
+		samples = append(samples, transcript)
+	}
+	dictionary, err := storage.TrainDictionary(samples, compressionTrainSize)
+	if err != nil {
+		return fmt.Errorf("could not train dictionary over %d transcripts: %w", len(samples), err)
+	}
+	id, err := storage.SaveDictionary(dictionary)
+	if err != nil {
+		return err
+	}
+	cli.RecordArtifact("dictionary", fmt.Sprint(id))
+
+	cfg, err := config.Read()
+	if err != nil {
+		return fmt.Errorf("could not read config: %w", err)
+	}
+	cfg.Compression = config.CompressionZstd
+	cfg.CompressionDictionary = id
+	if err := config.Write(cfg); err != nil {
+		return fmt.Errorf("could not write config: %w", err)
+	}
+	cli.RecordArtifact("config", ".shiftlog/config")
+
+	fmt.Printf("Trained dictionary %d (%d bytes) over %d transcripts\n", id, len(dictionary), len(samples))
+	fmt.Println("New transcripts are compressed with zstd and this dictionary.")
+	fmt.Println("Run 'shiftlog compression recompress' to rewrite the stored ones, and 'shiftlog sync push' to share the dictionary.")
+	return nil
+}
+
+func runCompressionRecompress(cmd *cobra.Command, args []string) error {
+	if err := git.RequireGitRepo(); err != nil {
+		return err
+	}
+	cfg, err := config.Read()
+	if err != nil {
+		return fmt.Errorf("could not read config: %w", err)
+	}
+	algorithm := cfg.Compression
+	if algorithm == "" {
+		algorithm = config.CompressionGzip
+	}
+	dictID := cfg.CompressionDictionary
+	if algorithm != config.CompressionZstd {
+		dictID = 0
+	}
+	target := compressionName(algorithm, dictID)
+	if cfg.TranscriptChunks {
+		target = chunksName
+	}
+
+	contents, err := storage.ReadAllConversations()
+	if err != nil {
+		return fmt.Errorf("could not read conversations: %w", err)
+	}
+	commits := make([]string, 0, len(contents))
+	for commit := range contents {
+		commits = append(commits, commit)
+	}
+	sort.Strings(commits)
+
+	b, err := storage.ActiveBackend()
+	if err != nil {
+		return err
+	}
+	rewritten, before, after := 0, 0, 0
+	for _, commit := range commits {
+		conversations, err := storage.UnmarshalStoredConversations(contents[commit])
+		if err != nil {
+			cli.LogWarning("skipping %s: %v", commit[:7], err)
+			continue
+		}
+		changed := false
+		for _, sc := range conversations {
+			current, size, err := storedTranscript(sc)
+			if err != nil {
+				cli.LogWarning("skipping session %s on %s: %v", sc.SessionID, commit[:7], err)
+				continue
+			}
+			if current == target {
+				continue
+			}
+			transcript, err := sc.GetTranscript()
+			if err != nil {
+				cli.LogWarning("skipping session %s on %s: %v", sc.SessionID, commit[:7], err)
+				continue
+			}
+			if cfg.TranscriptChunks {
+				if len(transcript) == 0 {
+					continue
+				}
+				before += size
+				after += len(transcript)
+				if !compressionDryRun {
+					if err := sc.SetTranscriptChunks(transcript); err != nil {
+						return err
+					}
+				}
+			} else {
+				compressed, err := storage.CompressWith(algorithm, dictID, transcript)
+				if err != nil {
+					return err
+				}
+				before += size
+				after += len(compressed)
+				if !compressionDryRun {
+					if err := sc.SetCompressedTranscript(compressed); err != nil {
+						return err
+					}
+				}
+			}
+			changed = !compressionDryRun
+			rewritten++
+		}
+		if !changed {
+			continue
+		}
+		content, err := storage.MarshalStoredConversations(conversations)
+		if err != nil {
+			return fmt.Errorf("failed to marshal conversation: %w", err)
+		}
+		if err := b.Write(commit, content); err != nil {
+			return fmt.Errorf("could not rewrite the note of %s: %w", commit[:7], err)
+		}
+		cli.RecordArtifact("note", commit)
+	}
+
+	verb := "Recompressed"
+	if compressionDryRun {
+		verb = "Would recompress"
+	}
+	fmt.Printf("%s %d transcripts with %s: %d bytes to %d bytes\n",
+		verb, rewritten, target, before, after)
+	return nil
+}
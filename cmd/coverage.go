@@ -0,0 +1,152 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/re-cinq/shift-log/internal/git"
+	"github.com/re-cinq/shift-log/internal/storage"
+	"github.com/spf13/cobra"
+)
+
+var (
+	coverageFormat string
+	coverageSince  string
+	coverageRef    string
+	coverageDepth  int
+)
+
+var coverageCmd = &cobra.Command{
+	Use:     "coverage",
+	Short:   "Report the share of commits and lines with a conversation",
+	GroupID: "human",
+	Long: `Reports what fraction of the commits of the current branch, and of the
+lines they changed, have a stored conversation, broken down by author and by
+directory, to track the adoption of shiftlog across teams.
+
+Changed lines are the lines each commit added plus those it deleted; binary
+files count none. A commit is counted under every directory it changed, with
+the lines it changed there. Directories are the first --depth components of
+the files' paths, "." for files at the root. Merge commits are not counted,
+and commits whose author opted out of capture are left out of the ratios.
+Authors are grouped by the repository's .mailmap.
+
+Output formats:
+  table     aligned terminal output (default)
+  json      machine-readable output
+  markdown  tables for a report or a pull request
+
+Examples:
+  shiftlog coverage                          # Whole history of this branch
+  shiftlog coverage --since "3 months ago"   # Recent commits only
+  shiftlog coverage --depth 2 --format markdown > coverage.md`,
+	Args: cobra.NoArgs,
+	RunE: runCoverage,
+}
+
+func init() {
+	coverageCmd.Flags().StringVar(&coverageFormat, "format", "table", "output format: table, json or markdown")
+	coverageCmd.Flags().StringVar(&coverageSince, "since", "", `only count commits more recent than this, e.g. "2025-01-01" or "4 weeks ago"`)
+	coverageCmd.Flags().StringVar(&coverageRef, "ref", "HEAD", "branch or commit to count the history of")
+	coverageCmd.Flags().IntVar(&coverageDepth, "depth", 1, "number of path components that make a directory")
+	rootCmd.AddCommand(coverageCmd)
+}
+
+func runCoverage(cmd *cobra.Command, args []string) error {
+	if err := git.RequireGitRepo(); err != nil {
+		return err
+	}
+	switch coverageFormat {
+	case "table", "json", "markdown":
+	default:
+		return fmt.Errorf("invalid --format %q: must be table, json or markdown", coverageFormat)
+	}
+	if coverageDepth < 1 {
+		return fmt.Errorf("--depth must be positive")
+	}
+	sha, err := git.ResolveRef(coverageRef + "^{commit}")
+	if err != nil {
+		return fmt.Errorf("unknown ref %q", coverageRef)
+	}
+
+	report, err := storage.ConversationCoverage(sha, coverageSince, coverageDepth)
+	if err != nil {
+		return err
+	}
+	report.Ref = coverageRef
+
+	switch coverageFormat {
+	case "json":
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(report)
+	case "markdown":
+		printCoverageMarkdown(report)
+	default:
+		printCoverage(report)
+	}
+	return nil
+}
+
+// coverageCells formats a group's counts and ratios.
+func coverageCells(g *storage.CoverageGroup) (commits, lines string) {
+	commits = fmt.Sprintf("%d/%d (%s)", g.Covered, g.Commits-g.OptedOut, formatRatio(g.CommitRatio))
+	lines = fmt.Sprintf("%d/%d (%s)", g.CoveredLines, g.Lines-g.OptedOutLines, formatRatio(g.LineRatio))
+	return commits, lines
+}
+
+func printCoverage(r *storage.CoverageReport) {
+	if r.Total.Commits == 0 {
+		fmt.Println("No commits found.")
+		return
+	}
+	useColor := os.Getenv("NO_COLOR") == ""
+	heading := func(text string) {
+		if useColor {
+			text = ansiBold + text + ansiReset
+		}
+		fmt.Println(text)
+	}
+
+	commits, lines := coverageCells(&r.Total)
+	heading("Coverage")
+	fmt.Printf("  Commits:    %s\n", commits)
+	fmt.Printf("  Lines:      %s\n", lines)
+	if r.Total.OptedOut > 0 {
+		fmt.Printf("  Opted out:  %d\n", r.Total.OptedOut)
+	}
+
+	section := func(title string, names []string, groups map[string]*storage.CoverageGroup) {
+		fmt.Println()
+		heading(title)
+		width := 0
+		for _, name := range names {
+			width = max(width, len(name))
+		}
+		for _, name := range names {
+			commits, lines := coverageCells(groups[name])
+			fmt.Printf("  %-*s  %-20s  %s lines\n", width, name, commits, lines)
+		}
+	}
+	section("By author", r.AuthorNames(), r.Authors)
+	section("By directory", r.DirectoryNames(), r.Directories)
+}
+
+func printCoverageMarkdown(r *storage.CoverageReport) {
+	commits, lines := coverageCells(&r.Total)
+	fmt.Printf("**Conversation coverage of %s**: %s commits, %s lines\n", r.Ref, commits, lines)
+
+	table := func(column string, names []string, groups map[string]*storage.CoverageGroup) {
+		fmt.Println()
+		fmt.Printf("| %s | commits | lines |\n", column)
+		fmt.Println("| --- | --- | --- |")
+		for _, name := range names {
+			commits, lines := coverageCells(groups[name])
+			fmt.Printf("| %s | %s | %s |\n", strings.ReplaceAll(name, "|", `\|`), commits, lines)
+		}
+	}
+	table("author", r.AuthorNames(), r.Authors)
+	table("directory", r.DirectoryNames(), r.Directories)
+}
@@ -0,0 +1,203 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/re-cinq/shift-log/internal/cli"
+	"github.com/re-cinq/shift-log/internal/daemon"
+	"github.com/re-cinq/shift-log/internal/git"
+	"github.com/re-cinq/shift-log/internal/storage"
+	"github.com/spf13/cobra"
+)
+
+var (
+	daemonSocket      string
+	daemonIdleTimeout time.Duration
+)
+
+var daemonCmd = &cobra.Command{
+	Use:     "daemon",
+	Short:   "Keep the conversation index in memory for fast queries",
+	GroupID: "human",
+	Long: `Runs a local daemon for the current worktree that keeps the index of its
+conversations in memory, and answers queries over a unix socket in the git
+dir: the conversations of a commit, the conversation behind a line, and
+searches. Editor plugins can query it directly, and show, search and blame
+use it whenever it runs, instead of loading the index and reading notes
+themselves on every invocation.
+
+The daemon runs in the foreground until interrupted, stopped with
+'shiftlog daemon stop', or idle for --idle-timeout. Commands only use a
+daemon of their own shiftlog version; set ` + daemon.DisableEnv + ` to
+keep them from using it at all.
+
+Examples:
+  shiftlog daemon &                     # Run in the background
+  shiftlog daemon --idle-timeout 30m    # Stop after 30 minutes without queries
+  shiftlog daemon status
+  shiftlog daemon stop`,
+	Args: cobra.NoArgs,
+	RunE: runDaemon,
+}
+
+var daemonStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show whether a daemon runs for the current worktree",
+	Args:  cobra.NoArgs,
+	RunE:  runDaemonStatus,
+}
+
+var daemonStopCmd = &cobra.Command{
+	Use:   "stop",
+	Short: "Stop the daemon of the current worktree",
+	Args:  cobra.NoArgs,
+	RunE:  runDaemonStop,
+}
+
+func init() {
+	daemonCmd.PersistentFlags().StringVar(&daemonSocket, "socket", "", "path of the socket (default: shiftlog-daemon.sock in the git dir)")
+	daemonCmd.Flags().DurationVar(&daemonIdleTimeout, "idle-timeout", 0, "stop after this long without queries, e.g. 30m (default: never)")
+	rootCmd.AddCommand(daemonCmd)
+	daemonCmd.AddCommand(daemonStatusCmd)
+	daemonCmd.AddCommand(daemonStopCmd)
+}
+
+func runDaemon(cmd *cobra.Command, args []string) error {
+	if err := git.RequireGitRepo(); err != nil {
+		return err
+	}
+	path, err := daemonSocketPath()
+	if err != nil {
+		return err
+	}
+	// A bare repository has no working tree, so lines are blamed in its git dir
+	var repoDir string
+	if git.IsBareRepository() {
+		repoDir, err = git.GetGitDir()
+	} else {
+		repoDir, err = git.GetRepoRoot()
+	}
+	if err != nil {
+		return fmt.Errorf("could not determine repository root: %w", err)
+	}
+	cmd.SilenceUsage = true
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	fmt.Printf("shiftlog daemon listening on %s\n", path)
+	err = daemon.Serve(ctx, daemon.Options{Socket: path, RepoDir: repoDir, Version: version, IdleTimeout: daemonIdleTimeout})
+	if errors.Is(err, daemon.ErrRunning) {
+		return fmt.Errorf("%w on %s", err, path)
+	}
+	return err
+}
+
+func runDaemonStatus(cmd *cobra.Command, args []string) error {
+	c, path, err := dialDaemon()
+	if err != nil {
+		return err
+	}
+	if c == nil {
+		fmt.Println("no daemon running")
+		return nil
+	}
+	defer func() { _ = c.Close() }()
+
+	fmt.Printf("daemon running on %s\n", path)
+	fmt.Printf("  pid:      %d\n", c.Hello.PID)
+	fmt.Printf("  version:  %s\n", c.Hello.Version)
+	fmt.Printf("  started:  %s\n", c.Hello.Started.Format(time.RFC3339))
+	if c.Hello.Protocol != daemon.ProtocolVersion || c.Hello.Version != version {
+		fmt.Printf("  not used: this is shiftlog %s; restart the daemon\n", version)
+	}
+	return nil
+}
+
+func runDaemonStop(cmd *cobra.Command, args []string) error {
+	c, _, err := dialDaemon()
+	if err != nil {
+		return err
+	}
+	if c == nil {
+		fmt.Println("no daemon running")
+		return nil
+	}
+	defer func() { _ = c.Close() }()
+
+	if err := c.Stop(); err != nil {
+		return fmt.Errorf("could not stop the daemon: %w", err)
+	}
+	fmt.Printf("stopped daemon %d\n", c.Hello.PID)
+	return nil
+}
+
+// daemonSocketPath returns --socket, or the socket of the current worktree.
+func daemonSocketPath() (string, error) {
+	if daemonSocket != "" {
+		return daemonSocket, nil
+	}
+	return daemon.SocketPath()
+}
+
+// dialDaemon connects to the daemon at daemonSocketPath, whatever its
+// version, returning a nil client when none answers.
+func dialDaemon() (*daemon.Client, string, error) {
+	if err := git.RequireGitRepo(); err != nil {
+		return nil, "", err
+	}
+	path, err := daemonSocketPath()
+	if err != nil {
+		return nil, "", err
+	}
+	c, err := daemon.Dial(path)
+	if err != nil {
+		return nil, path, nil
+	}
+	return c, path, nil
+}
+
+// connectDaemon returns a connection to the daemon of the current worktree
+// for a command to query instead of reading notes itself, or nil when none
+// of this version runs. Callers fall back to reading notes when a query
+// fails.
+func connectDaemon() *daemon.Client {
+	c := daemon.Connect(version)
+	if c != nil {
+		cli.LogDebug("using the daemon on %s", c.Hello.Socket)
+	}
+	return c
+}
+
+// readConversations returns the conversations of a commit with their
+// transcripts, as storage.GetConversationsWithPrivate, from the daemon when
+// one runs.
+func readConversations(commitSHA string) ([]*storage.StoredConversation, error) {
+	if c := connectDaemon(); c != nil {
+		defer func() { _ = c.Close() }()
+		conversations, err := c.Conversations(commitSHA)
+		if err == nil {
+			return conversations, nil
+		}
+		cli.LogDebug("daemon: %v", err)
+	}
+	return storage.GetConversationsWithPrivate(commitSHA)
+}
+
+// searchConversations runs storage.Search, in the daemon when one runs.
+func searchConversations(ctx context.Context, params *storage.SearchParams) ([]storage.SearchResult, error) {
+	if c := connectDaemon(); c != nil {
+		defer func() { _ = c.Close() }()
+		results, err := c.Search(params)
+		if err == nil {
+			return results, nil
+		}
+		cli.LogDebug("daemon: %v", err)
+	}
+	return storage.Search(ctx, params)
+}
@@ -20,7 +20,8 @@ var debugCmd = &cobra.Command{
 	Long: `Controls debug logging output for shiftlog commands.
 
 When debug logging is enabled, shiftlog writes detailed diagnostic
-information to stderr during all operations.
+information to stderr during all operations, and to the trace log shown by
+'shiftlog logs'. For a single run, use --verbose or SHIFTLOG_DEBUG=1 instead.
 
 Examples:
   shiftlog debug          Show current debug state
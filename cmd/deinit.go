@@ -3,6 +3,7 @@ package cmd
 import (
 	"fmt"
 	"os/exec"
+	"path/filepath"
 	"strings"
 
 	"github.com/re-cinq/shift-log/internal/agent"
@@ -19,14 +20,19 @@ var deinitCmd = &cobra.Command{
 	Long: `Removes shiftlog's hooks and git configuration from the current repository.
 
 This command:
-- Removes agent-specific hooks/plugins (Claude, Gemini, Copilot, OpenCode)
-- Removes shiftlog-managed git hook sections (pre-push, post-merge, post-checkout, post-commit)
-- Unsets git config settings for notes visibility
+- Removes agent-specific hooks/plugins of the configured agents (Claude, Amazon Q,
+  Gemini, Copilot, OpenCode)
+- Removes shiftlog-managed git hook sections (pre-push, post-merge, post-checkout,
+  post-commit, prepare-commit-msg)
+- Unsets git config settings for notes visibility and reflog retention
 
 Does NOT remove:
 - The .shiftlog/ directory (contains session data; remove manually if desired)
 - The .gitignore entry for .shiftlog/
-- Git notes data (notes are committed data and preserved)`,
+- Git notes data (notes are committed data and preserved)
+
+Use 'shiftlog uninstall' to remove the hooks of every supported agent, and
+optionally the notes and .shiftlog/ too.`,
 	RunE: runDeinit,
 }
 
@@ -44,24 +50,27 @@ func runDeinit(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("failed to get repository root: %w", err)
 	}
 
-	// Read agent from config (default to "claude" if missing)
-	agentName := "claude"
+	// Read agents from config (default to "claude" if missing)
+	agentNames := []string{"claude"}
 	cfg, err := config.Read()
-	if err == nil && cfg.Agent != "" {
-		agentName = cfg.Agent
+	if err == nil {
+		agentNames = cfg.AgentNames()
 	}
 
-	ag, err := agent.Get(agent.Name(agentName))
-	if err != nil {
-		return fmt.Errorf("unsupported agent %q: %w", agentName, err)
-	}
+	for _, agentName := range agentNames {
+		ag, err := agent.Get(agent.Name(agentName))
+		if err != nil {
+			return fmt.Errorf("unsupported agent %q: %w", agentName, err)
+		}
 
-	// Remove agent-specific hooks
-	cli.LogDebug("deinit: removing %s hooks", ag.DisplayName())
-	if err := ag.RemoveHooks(repoRoot); err != nil {
-		return fmt.Errorf("failed to remove %s hooks: %w", ag.DisplayName(), err)
+		// Remove agent-specific hooks
+		cli.LogDebug("deinit: removing %s hooks", ag.DisplayName())
+		if err := ag.RemoveHooks(repoRoot); err != nil {
+			return fmt.Errorf("failed to remove %s hooks: %w", ag.DisplayName(), err)
+		}
+		fmt.Printf("Removed %s hooks\n", ag.DisplayName())
+		cli.RecordArtifact("agent-hooks", string(ag.Name()))
 	}
-	fmt.Printf("Removed %s hooks\n", ag.DisplayName())
 
 	// Remove git hooks
 	cli.LogDebug("deinit: removing git hooks")
@@ -73,14 +82,15 @@ func runDeinit(cmd *cobra.Command, args []string) error {
 	if err := git.RemoveAllHooks(gitDir); err != nil {
 		return fmt.Errorf("failed to remove git hooks: %w", err)
 	}
-	fmt.Println("Removed git hooks (pre-push, post-merge, post-checkout, post-commit)")
+	fmt.Println("Removed git hooks (pre-push, post-merge, post-checkout, post-commit, prepare-commit-msg)")
+	cli.RecordArtifact("hook", filepath.Join(gitDir, "hooks"))
 
 	// Remove git config settings
 	cli.LogDebug("deinit: removing git config settings")
 	if err := removeGitSettings(git.NotesRef); err != nil {
 		return fmt.Errorf("failed to remove git settings: %w", err)
 	}
-	fmt.Println("Removed git notes settings (displayRef, rewriteRef)")
+	fmt.Println("Removed git notes settings (displayRef, rewriteRef, reflog retention)")
 
 	fmt.Println()
 	fmt.Println("Shiftlog has been removed from this repository.")
@@ -90,7 +100,8 @@ func runDeinit(cmd *cobra.Command, args []string) error {
 }
 
 // removeGitSettings unsets notes.displayRef and notes.rewriteRef if they
-// match the shiftlog notes ref. Does not touch settings set to other values.
+// match the shiftlog notes ref, and the reflog retention set by init. Does
+// not touch notes settings set to other values.
 func removeGitSettings(notesRef string) error {
 	for _, key := range []string{"notes.displayRef", "notes.rewriteRef"} {
 		out, err := exec.Command("git", "config", key).Output()
@@ -104,5 +115,13 @@ func removeGitSettings(notesRef string) error {
 			}
 		}
 	}
+	for key := range git.NotesGCSettings {
+		// Exit status 5 means the key was not set
+		if err := exec.Command("git", "config", "--unset", key).Run(); err != nil {
+			if exitErr, ok := err.(*exec.ExitError); !ok || exitErr.ExitCode() != 5 {
+				return fmt.Errorf("failed to unset %s: %w", key, err)
+			}
+		}
+	}
 	return nil
 }
@@ -0,0 +1,123 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/re-cinq/shift-log/internal/agent"
+	"github.com/re-cinq/shift-log/internal/git"
+	"github.com/re-cinq/shift-log/internal/storage"
+	"github.com/spf13/cobra"
+)
+
+var (
+	diffConversationSession string
+	diffConversationContext int
+	diffConversationFull    bool
+)
+
+var diffConversationCmd = &cobra.Command{
+	Use:     "diff-conversation <commit1> <commit2>",
+	Short:   "Show how a session's conversation changed between two commits",
+	GroupID: "human",
+	Long: `Compares the transcripts of an agent session stored on two commits and
+prints the entries added since the first commit, like a unified diff:
+entries marked + are only in the second commit's transcript, entries
+marked - only in the first's, for instance when the agent compacted its
+context.
+
+'shiftlog show' shows the entries added since the parent commit;
+diff-conversation compares any two commits, such as the first and last
+commit of a feature.
+The session must be stored on both commits. By default it is the first
+session of the second commit that is also stored on the first; pick another
+with --session.
+
+Examples:
+  shiftlog diff-conversation HEAD~3 HEAD
+  shiftlog diff-conversation v1.2.0 main --context 0
+  shiftlog diff-conversation abc1234 def5678 --session 5d3c...`,
+	Args: cobra.ExactArgs(2),
+	RunE: runDiffConversation,
+}
+
+func init() {
+	diffConversationCmd.Flags().StringVar(&diffConversationSession, "session", "", "session to compare (default: the first one stored on both commits)")
+	diffConversationCmd.Flags().IntVarP(&diffConversationContext, "context", "U", 3, "unchanged entries to show around each change")
+	diffConversationCmd.Flags().BoolVar(&diffConversationFull, "full", false, "show every unchanged entry")
+	rootCmd.AddCommand(diffConversationCmd)
+}
+
+func runDiffConversation(cmd *cobra.Command, args []string) error {
+	if err := git.RequireGitRepo(); err != nil {
+		return err
+	}
+	if diffConversationContext < 0 {
+		return fmt.Errorf("--context must not be negative")
+	}
+	context := diffConversationContext
+	if diffConversationFull {
+		context = -1
+	}
+
+	var shas [2]string
+	for i, ref := range args {
+		sha, err := git.ResolveRef(ref + "^{commit}")
+		if err != nil {
+			return fmt.Errorf("could not resolve reference '%s': not a valid commit", ref)
+		}
+		shas[i] = sha
+	}
+	cmd.SilenceUsage = true
+
+	diff, err := storage.DiffConversations(shas[0], shas[1], diffConversationSession, context)
+	if err != nil {
+		return err
+	}
+
+	var toolAliases map[string]string
+	if ag, err := agent.Get(agent.Name(diff.Agent)); err == nil {
+		toolAliases = ag.ToolAliases()
+	}
+
+	for _, side := range []struct {
+		marker, sha string
+		entries     int
+	}{{"---", diff.From, diff.FromEntries}, {"+++", diff.To, diff.ToEntries}} {
+		message, _, _ := git.GetCommitInfo(side.sha)
+		fmt.Printf("%s %s  %s (%d entries)\n", side.marker, side.sha[:7], message, side.entries)
+	}
+	fmt.Printf("Session %s (%s): %d added, %d removed\n", diff.SessionID, diff.Agent, diff.Added, diff.Removed)
+
+	if len(diff.Hunks) == 0 {
+		fmt.Println()
+		fmt.Println("The transcripts are identical.")
+		return nil
+	}
+
+	var buf bytes.Buffer
+	renderer := agent.NewRenderer(&buf, toolAliases)
+	for _, hunk := range diff.Hunks {
+		fmt.Println()
+		fmt.Printf("@@ -%d,%d +%d,%d @@\n", hunk.FromStart, hunk.FromCount, hunk.ToStart, hunk.ToCount)
+		for _, line := range hunk.Lines {
+			buf.Reset()
+			renderer.RenderEntry(&line.Entry)
+			if buf.Len() == 0 {
+				continue
+			}
+			prefix := "  "
+			switch line.Op {
+			case agent.DiffAdded:
+				prefix = "+ "
+			case agent.DiffRemoved:
+				prefix = "- "
+			}
+			for _, l := range strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n") {
+				fmt.Println(prefix + l)
+			}
+		}
+	}
+	return nil
+}
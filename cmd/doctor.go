@@ -5,16 +5,21 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"sort"
 	"strings"
 
 	"github.com/re-cinq/shift-log/internal/agent"
+	_ "github.com/re-cinq/shift-log/internal/agent/amazonq"  // register Amazon Q agent
 	_ "github.com/re-cinq/shift-log/internal/agent/claude"   // register Claude agent
 	_ "github.com/re-cinq/shift-log/internal/agent/codex"    // register Codex agent
 	_ "github.com/re-cinq/shift-log/internal/agent/copilot"  // register Copilot agent
 	_ "github.com/re-cinq/shift-log/internal/agent/gemini"   // register Gemini agent
+	_ "github.com/re-cinq/shift-log/internal/agent/goose"    // register Goose agent
 	_ "github.com/re-cinq/shift-log/internal/agent/opencode" // register OpenCode agent
+	_ "github.com/re-cinq/shift-log/internal/agent/windsurf" // register Windsurf agent
 	"github.com/re-cinq/shift-log/internal/config"
 	"github.com/re-cinq/shift-log/internal/git"
+	"github.com/re-cinq/shift-log/internal/storage"
 	"github.com/spf13/cobra"
 )
 
@@ -29,6 +34,7 @@ This command checks:
 - Git repository status
 - Coding agent hook configuration
 - Git notes.rewriteRef config (for rebase support)
+- Notes reflog retention (for 'shiftlog recover')
 - Git hooks installation
 - PATH configuration`,
 	RunE: runDoctor,
@@ -73,53 +79,78 @@ func runDoctor(cmd *cobra.Command, args []string) error {
 	}
 	fmt.Println()
 
-	// Resolve configured agent
-	agentName := "claude"
+	// Resolve configured agents
 	cfg, err := config.Read()
-	if err == nil && cfg.Agent != "" {
-		agentName = cfg.Agent
+	agentNames := []string{"claude"}
+	if err == nil {
+		agentNames = cfg.AgentNames()
 	}
-	ag, agentErr := agent.Get(agent.Name(agentName))
 
-	// Check 3: Agent-specific hook configuration
+	// Check 3: Agent-specific hook configuration, for each configured agent
 	repoRoot, _ := git.GetRepoRoot()
-	if repoRoot == "" {
-		fmt.Print("Checking coding agent hook configuration... SKIP (not in git repo)\n")
-	} else if agentErr != nil {
-		fmt.Printf("Checking coding agent hook configuration... FAIL\n")
-		fmt.Printf("  Unknown agent %q configured\n", agentName)
-		hasErrors = true
-	} else {
-		fmt.Printf("Checking %s hook configuration... ", ag.DisplayName())
-		checks := ag.DiagnoseHooks(repoRoot)
-		if len(checks) == 0 {
-			fmt.Println("OK")
+	for _, agentName := range agentNames {
+		ag, agentErr := agent.Get(agent.Name(agentName))
+		if repoRoot == "" {
+			fmt.Print("Checking coding agent hook configuration... SKIP (not in git repo)\n")
+		} else if agentErr != nil {
+			fmt.Printf("Checking coding agent hook configuration... FAIL\n")
+			fmt.Printf("  Unknown agent %q configured\n", agentName)
+			hasErrors = true
 		} else {
-			allOK := true
-			for _, check := range checks {
-				if !check.OK {
-					allOK = false
-					break
-				}
-			}
-			if allOK {
+			fmt.Printf("Checking %s hook configuration... ", ag.DisplayName())
+			checks := ag.DiagnoseHooks(repoRoot)
+			if len(checks) == 0 {
 				fmt.Println("OK")
 			} else {
-				fmt.Println("FAIL")
-				hasErrors = true
-			}
-			for _, check := range checks {
-				if check.OK {
-					fmt.Printf("  %s\n", check.Message)
+				allOK := true
+				for _, check := range checks {
+					if !check.OK {
+						allOK = false
+						break
+					}
+				}
+				if allOK {
+					fmt.Println("OK")
 				} else {
-					fmt.Printf("  %s: %s\n", check.Name, check.Message)
+					fmt.Println("FAIL")
+					hasErrors = true
+				}
+				for _, check := range checks {
+					if check.OK {
+						fmt.Printf("  %s\n", check.Message)
+					} else {
+						fmt.Printf("  %s: %s\n", check.Name, check.Message)
+					}
 				}
 			}
 		}
+		fmt.Println()
+	}
+
+	// Check 4: Storage backend
+	fmt.Print("Checking storage backend... ")
+	if repoRoot == "" {
+		fmt.Println("SKIP (not in git repo)")
+	} else if cfg == nil {
+		fmt.Println("FAIL")
+		fmt.Printf("  Could not read config: %v\n", err)
+		hasErrors = true
+	} else if backend, backendErr := storage.NewBackend(cfg); backendErr != nil {
+		fmt.Println("FAIL")
+		fmt.Printf("  %v\n", backendErr)
+		hasErrors = true
+	} else {
+		fmt.Println("OK")
+		fmt.Printf("  Backend: %s (%s)\n", backend.Name(), backend.Location())
+		if cfg.TranscriptChunks {
+			fmt.Printf("  Compression: none, %s\n", chunksName)
+		} else {
+			fmt.Printf("  Compression: %s\n", compressionName(cfg.Compression, cfg.CompressionDictionary))
+		}
 	}
 	fmt.Println()
 
-	// Check 4: notes.rewriteRef config
+	// Check 5: notes.rewriteRef config
 	fmt.Print("Checking notes.rewriteRef config... ")
 	if repoRoot == "" {
 		fmt.Println("SKIP (not in git repo)")
@@ -140,7 +171,33 @@ func runDoctor(cmd *cobra.Command, args []string) error {
 	}
 	fmt.Println()
 
-	// Check 5: Git hooks
+	// Check 6: notes reflog retention
+	fmt.Print("Checking notes reflog retention... ")
+	if repoRoot == "" {
+		fmt.Println("SKIP (not in git repo)")
+	} else {
+		var unset []string
+		for key, value := range git.NotesGCSettings {
+			out, err := git.RunGitCommand("config", key)
+			if err != nil || out != value {
+				unset = append(unset, key)
+			}
+		}
+		if len(unset) > 0 {
+			sort.Strings(unset)
+			fmt.Println("FAIL")
+			fmt.Printf("  Not set to never: %s\n", strings.Join(unset, ", "))
+			fmt.Println("  git gc may prune old notes before 'shiftlog recover' can restore them")
+			fmt.Println("  Run 'shiftlog init' to fix")
+			hasErrors = true
+		} else {
+			fmt.Println("OK")
+			fmt.Println("  Old notes are kept for 'shiftlog recover'")
+		}
+	}
+	fmt.Println()
+
+	// Check 7: Git hooks
 	fmt.Print("Checking git hooks... ")
 	if repoRoot == "" {
 		fmt.Println("SKIP (not in git repo)")
@@ -150,30 +207,44 @@ func runDoctor(cmd *cobra.Command, args []string) error {
 			fmt.Println("FAIL")
 			fmt.Println("  Could not find .git directory")
 			hasErrors = true
+		} else if setup, err := git.DetectHookSetup(gitDir); err != nil {
+			fmt.Println("FAIL")
+			fmt.Printf("  Could not detect the git hook setup: %v\n", err)
+			hasErrors = true
 		} else {
-			missingHooks := []string{}
-			for _, hook := range []string{"pre-push", "post-merge", "post-checkout", "post-commit"} {
-				hookPath := filepath.Join(gitDir, "hooks", hook)
-				data, err := os.ReadFile(hookPath)
-				if err != nil {
-					missingHooks = append(missingHooks, hook)
-				} else if !strings.Contains(string(data), "shiftlog") {
-					missingHooks = append(missingHooks, hook+" (no shiftlog)")
-				}
-			}
-			if len(missingHooks) > 0 {
+			problems, warnings := diagnoseGitHooks(setup, gitDir)
+			if len(problems) > 0 {
 				fmt.Println("FAIL")
-				fmt.Printf("  Missing or incomplete hooks: %v\n", missingHooks)
+				for _, problem := range problems {
+					fmt.Printf("  %s\n", problem)
+				}
 				fmt.Println("  Run 'shiftlog init' to fix")
 				hasErrors = true
 			} else {
 				fmt.Println("OK")
-				fmt.Println("  All git hooks installed")
+				fmt.Printf("  All git hooks installed in %s\n", setup.Location())
+			}
+			for _, warning := range warnings {
+				fmt.Printf("  Warning: %s\n", warning)
 			}
 		}
 	}
 	fmt.Println()
 
+	// Check 8: Queued stores
+	fmt.Print("Checking queued conversations... ")
+	if repoRoot == "" {
+		fmt.Println("SKIP (not in git repo)")
+	} else if n := storage.CountPendingStores(); n > 0 {
+		fmt.Println("WARN")
+		fmt.Printf("  %d conversations failed to store and are queued in .shiftlog/pending\n", n)
+		fmt.Println("  Run 'shiftlog flush' to store them")
+	} else {
+		fmt.Println("OK")
+		fmt.Println("  No failed stores are waiting to be retried")
+	}
+	fmt.Println()
+
 	// Summary
 	if hasErrors {
 		fmt.Println("Issues found. Run 'shiftlog init' to fix configuration.")
@@ -183,3 +254,63 @@ func runDoctor(cmd *cobra.Command, args []string) error {
 	fmt.Println("All checks passed! Shiftlog is properly configured.")
 	return nil
 }
+
+// diagnoseGitHooks checks that the git hooks shiftlog needs are wired up in
+// setup, returning the problems that keep them from running and warnings
+// about leftovers.
+func diagnoseGitHooks(setup *git.HookSetup, gitDir string) (problems, warnings []string) {
+	required := []git.HookType{git.HookPrePush, git.HookPostMerge, git.HookPostCheckout, git.HookPostCommit}
+
+	if setup.Manager == git.HookManagerLefthook {
+		configured := make(map[git.HookType]bool)
+		for _, hook := range setup.LefthookHooks() {
+			configured[hook] = true
+		}
+		for _, hook := range required {
+			if !configured[hook] {
+				problems = append(problems, fmt.Sprintf("%s: no shiftlog command in the Lefthook configuration", hook))
+				continue
+			}
+			data, err := os.ReadFile(setup.HookPath(hook))
+			if err != nil || !strings.Contains(string(data), "lefthook") {
+				problems = append(problems, fmt.Sprintf("%s: not generated by Lefthook; run 'lefthook install'", hook))
+			}
+		}
+		return problems, warnings
+	}
+
+	for _, hook := range required {
+		path := setup.HookPath(hook)
+		data, err := os.ReadFile(path)
+		switch {
+		case err != nil:
+			problems = append(problems, fmt.Sprintf("%s: missing from %s", hook, setup.Location()))
+		case !strings.Contains(string(data), "shiftlog"):
+			problems = append(problems, fmt.Sprintf("%s: does not run shiftlog", hook))
+		case strings.Contains(string(data), string(hook)+git.ChainedSuffix):
+			if info, err := os.Stat(path + git.ChainedSuffix); err != nil || info.Mode()&0111 == 0 {
+				problems = append(problems, fmt.Sprintf("%s: the chained hook %s%s is missing or not executable", hook, hook, git.ChainedSuffix))
+			}
+		}
+		if info, err := os.Stat(path); err == nil && info.Mode()&0111 == 0 && setup.Manager != git.HookManagerHusky {
+			problems = append(problems, fmt.Sprintf("%s: not executable, so git skips it", hook))
+		}
+	}
+
+	if setup.Manager == git.HookManagerHusky {
+		if dir := setup.HooksPathDir(); dir == "" || !strings.HasPrefix(dir, setup.Dir) {
+			problems = append(problems, "core.hooksPath does not point into .husky, so Husky's hooks do not run; run 'npx husky' (or npm install)")
+		}
+	}
+
+	// Sections left in .git/hooks by an earlier init are not run any more
+	defaultDir := filepath.Join(gitDir, "hooks")
+	if setup.HooksPath != "" && setup.Dir != defaultDir {
+		for _, hook := range required {
+			if data, err := os.ReadFile(filepath.Join(defaultDir, string(hook))); err == nil && strings.Contains(string(data), "# shiftlog-managed") {
+				warnings = append(warnings, fmt.Sprintf("the shiftlog section in .git/hooks/%s is ignored because core.hooksPath is set", hook))
+			}
+		}
+	}
+	return problems, warnings
+}
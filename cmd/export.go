@@ -0,0 +1,269 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/mail"
+	"os"
+	"os/user"
+	"strings"
+
+	"github.com/re-cinq/shift-log/internal/agent"
+	"github.com/re-cinq/shift-log/internal/anonymize"
+	"github.com/re-cinq/shift-log/internal/cli"
+	"github.com/re-cinq/shift-log/internal/config"
+	"github.com/re-cinq/shift-log/internal/git"
+	"github.com/re-cinq/shift-log/internal/privacy"
+	"github.com/re-cinq/shift-log/internal/render"
+	"github.com/re-cinq/shift-log/internal/storage"
+	"github.com/spf13/cobra"
+)
+
+var (
+	exportFormat    string
+	exportAnonymize bool
+	exportScrub     bool
+)
+
+var exportCmd = &cobra.Command{
+	Use:     "export [ref]",
+	Short:   "Export the conversations of a commit to share them",
+	GroupID: "human",
+	Long: `Writes the conversations stored for a commit, HEAD unless a ref is
+given, with their full transcripts to stdout: as a JSON document, or with
+--format html as a standalone HTML page rendered like the web viewer.
+Private conversations of this clone are included, marked as private.
+
+With --anonymize, the export can be shared outside the team, e.g. in a bug
+report or a blog post:
+  - commit authors and co-authors, the git user and email addresses
+    are replaced with placeholders
+  - absolute paths in the repository become repo-relative, and home
+    directories become ~
+  - the user and host name are replaced with placeholders
+  - values of environment variables in tool outputs are removed
+  - session IDs are replaced with a hash of them
+Review the export before publishing it: names and secrets the transcript
+mentions in other ways are kept.
+
+With --scrub, the personal data found by the detectors of the privacy policy
+in ` + privacy.PolicyFile + ` is redacted, hashed or kept as the policy says,
+and what was found is reported. Without a policy, email addresses and phone
+numbers are redacted. A policy lists rules, applied in order:
+  scrub_on_store: true          # scrub conversations when they are stored, too
+  rules:
+    - detector: email           # email, phone, name or pattern
+      action: hash              # redact (default), hash or allow
+      allow: ["*@example.com"]  # matches to keep
+    - detector: name
+      values: ["Ada Lovelace"]
+    - detector: pattern
+      name: employee-id
+      pattern: 'EMP-[0-9]{6}'
+
+Examples:
+  shiftlog export > conversation.json
+  shiftlog export --anonymize abc1234 > conversation.json
+  shiftlog export --anonymize --format html > conversation.html
+  shiftlog export --scrub > conversation.json`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runExport,
+}
+
+func init() {
+	exportCmd.Flags().StringVar(&exportFormat, "format", "json", "output format: json or html")
+	exportCmd.Flags().BoolVar(&exportAnonymize, "anonymize", false, "remove names, emails, paths, environment details and session IDs")
+	exportCmd.Flags().BoolVar(&exportScrub, "scrub", false, "scrub personal data as the privacy policy in "+privacy.PolicyFile+" says")
+	rootCmd.AddCommand(exportCmd)
+}
+
+// exportDocument is the JSON document written by shiftlog export.
+type exportDocument struct {
+	Commit        string                 `json:"commit"`
+	Subject       string                 `json:"subject"`
+	Date          string                 `json:"date"`
+	Author        string                 `json:"author,omitempty"`
+	AuthorEmail   string                 `json:"author_email,omitempty"`
+	Conversations []exportedConversation `json:"conversations"`
+}
+
+// exportedConversation is a stored conversation with its transcript
+// decoded into normalized entries.
+type exportedConversation struct {
+	SessionID    string                  `json:"session_id"`
+	Agent        string                  `json:"agent"`
+	Model        string                  `json:"model,omitempty"`
+	GitBranch    string                  `json:"git_branch,omitempty"`
+	Timestamp    string                  `json:"timestamp"`
+	MessageCount int                     `json:"message_count"`
+	Effort       *storage.Effort         `json:"effort,omitempty"`
+	FilesTouched []string                `json:"files_touched,omitempty"`
+	Summary      string                  `json:"summary,omitempty"`
+	Tags         []string                `json:"tags,omitempty"`
+	Provenance   *storage.Provenance     `json:"provenance,omitempty"`
+	Visibility   string                  `json:"visibility"`          // "shared", or "private" when kept in the exporting clone only
+	NotesRef     string                  `json:"notes_ref,omitempty"` // contributor ref the conversation was read from
+	Entries      []agent.TranscriptEntry `json:"entries"`
+}
+
+func runExport(cmd *cobra.Command, args []string) error {
+	switch exportFormat {
+	case "json", "html":
+	default:
+		return fmt.Errorf("invalid --format %q: must be json or html", exportFormat)
+	}
+	if err := git.RequireGitRepo(); err != nil {
+		return err
+	}
+
+	ref := "HEAD"
+	if len(args) > 0 {
+		ref = args[0]
+	}
+	fullSHA, err := git.ResolveRef(ref)
+	if err != nil {
+		return fmt.Errorf("could not resolve reference '%s': not a valid commit", ref)
+	}
+	conversations, err := storage.GetConversationsWithPrivate(fullSHA)
+	if err != nil {
+		return fmt.Errorf("could not read conversation: %w", err)
+	}
+	if len(conversations) == 0 {
+		return fmt.Errorf("no conversation found for commit %s", fullSHA[:7])
+	}
+	details, err := git.GetCommitDetails(fullSHA)
+	if err != nil {
+		return fmt.Errorf("could not read commit %s: %w", fullSHA[:7], err)
+	}
+
+	doc := exportDocument{
+		Commit:      fullSHA,
+		Subject:     details.Subject,
+		Date:        details.Date,
+		Author:      details.Author,
+		AuthorEmail: details.AuthorEmail,
+	}
+	for _, sc := range conversations {
+		transcript, err := sc.ParseTranscript()
+		if err != nil {
+			return fmt.Errorf("could not parse transcript of session %s: %w", sc.SessionID, err)
+		}
+		visibility := "shared"
+		if sc.IsPrivate() {
+			visibility = config.VisibilityPrivate
+		}
+		doc.Conversations = append(doc.Conversations, exportedConversation{
+			SessionID:    sc.SessionID,
+			Agent:        sc.AgentName(),
+			Model:        sc.Model,
+			GitBranch:    sc.GitBranch,
+			Timestamp:    sc.Timestamp,
+			MessageCount: sc.MessageCount,
+			Effort:       sc.Effort,
+			FilesTouched: sc.FilesTouched,
+			Summary:      sc.Summary,
+			Tags:         sc.Tags,
+			Provenance:   sc.Provenance,
+			Visibility:   visibility,
+			NotesRef:     sc.ContributorRef,
+			Entries:      transcript.Entries,
+		})
+	}
+
+	var anonymizer *anonymize.Anonymizer
+	if exportAnonymize {
+		anonymizer = exportAnonymizer(details, conversations)
+		doc.Author, doc.AuthorEmail = "", ""
+		for i := range doc.Conversations {
+			doc.Conversations[i].SessionID = anonymizer.SessionID(doc.Conversations[i].SessionID)
+		}
+	}
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	data := buf.Bytes()
+
+	if exportScrub {
+		policy, err := privacy.LoadPolicy()
+		if err != nil {
+			return err
+		}
+		scrubber, err := privacy.NewScrubber(policy)
+		if err != nil {
+			return fmt.Errorf("invalid %s: %w", privacy.PolicyFile, err)
+		}
+		if data, err = scrubber.JSON(data); err != nil {
+			return fmt.Errorf("could not scrub export: %w", err)
+		}
+		cli.LogInfo("scrubbed: %s", scrubber.Report())
+	}
+	if anonymizer != nil {
+		if data, err = anonymizer.JSON(data); err != nil {
+			return fmt.Errorf("could not anonymize export: %w", err)
+		}
+	}
+
+	if exportFormat == "json" {
+		_, err = os.Stdout.Write(data)
+		return err
+	}
+
+	// The HTML page is rendered from the (anonymized) JSON document, so
+	// that both formats share what they leave out
+	var exported exportDocument
+	if err := json.Unmarshal(data, &exported); err != nil {
+		return err
+	}
+	var body strings.Builder
+	for _, c := range exported.Conversations {
+		switch {
+		case c.Visibility == config.VisibilityPrivate:
+			fmt.Fprintf(&body, "<h2>%s (%s, private: not shared with the repository)</h2>\n", render.EscapeHTML(c.SessionID), render.EscapeHTML(c.Agent))
+		case len(exported.Conversations) > 1:
+			fmt.Fprintf(&body, "<h2>%s (%s)</h2>\n", render.EscapeHTML(c.SessionID), render.EscapeHTML(c.Agent))
+		}
+		body.WriteString(render.Transcript(c.Entries))
+	}
+	title := fmt.Sprintf("Conversation for %s: %s", exported.Commit[:7], exported.Subject)
+	_, err = fmt.Print(render.Document(title, body.String()))
+	return err
+}
+
+// exportAnonymizer returns an Anonymizer for the conversations of a commit,
+// knowing the commit's author and co-authors, the git user, and the paths,
+// user and host of this machine and of the ones that stored them.
+func exportAnonymizer(details *git.CommitDetails, conversations []*storage.StoredConversation) *anonymize.Anonymizer {
+	opts := anonymize.Options{
+		People: []string{details.Author, git.GetUserName()},
+		Emails: []string{details.AuthorEmail},
+	}
+	for _, t := range details.Trailers {
+		if !strings.EqualFold(t.Key, "Co-Authored-By") && !strings.EqualFold(t.Key, "Signed-off-by") {
+			continue
+		}
+		// Agents co-author with a noreply address; their names stay
+		if addr, err := mail.ParseAddress(t.Value); err == nil && !strings.Contains(addr.Address, "noreply") {
+			opts.People = append(opts.People, addr.Name)
+			opts.Emails = append(opts.Emails, addr.Address)
+		}
+	}
+	if root, err := git.GetRepoRoot(); err == nil {
+		opts.ProjectPaths = append(opts.ProjectPaths, root)
+	}
+	for _, sc := range conversations {
+		opts.ProjectPaths = append(opts.ProjectPaths, sc.ProjectPath)
+	}
+	opts.HomeDir, _ = os.UserHomeDir()
+	if u, err := user.Current(); err == nil {
+		opts.Username = u.Username
+		opts.People = append(opts.People, u.Name)
+	}
+	opts.Hostname, _ = os.Hostname()
+	return anonymize.New(opts)
+}
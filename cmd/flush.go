@@ -0,0 +1,108 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/re-cinq/shift-log/internal/cli"
+	"github.com/re-cinq/shift-log/internal/git"
+	"github.com/re-cinq/shift-log/internal/storage"
+	"github.com/spf13/cobra"
+)
+
+var flushCmd = &cobra.Command{
+	Use:     "flush",
+	Short:   "Store the conversations queued by failed stores",
+	GroupID: "hooks",
+	Long: `Retries the stores that failed, e.g. on git lock contention or a transient
+filesystem error. When a store cannot write its note, the conversation is
+queued in .shiftlog/pending instead of being lost, and attached to the
+commit it was made for by the next store, or by this command.
+
+Conversations already stored on their commit are dropped from the queue,
+as are those whose commit no longer exists. 'shiftlog doctor' warns while
+the queue is not empty.
+
+Examples:
+  shiftlog flush`,
+	Args: cobra.NoArgs,
+	RunE: runFlush,
+}
+
+func init() {
+	rootCmd.AddCommand(flushCmd)
+}
+
+func runFlush(cmd *cobra.Command, args []string) error {
+	if err := git.RequireGitRepo(); err != nil {
+		return err
+	}
+	stored, failed := flushPendingStores()
+	if stored == 0 && failed == 0 {
+		fmt.Println("No queued conversations")
+		return nil
+	}
+	fmt.Printf("Stored %d queued conversations\n", stored)
+	if failed > 0 {
+		return fmt.Errorf("%d queued conversations could not be stored yet", failed)
+	}
+	return nil
+}
+
+// retryPendingStores stores the conversations queued by failed stores
+// before a store, so that they reach their commits without shiftlog flush.
+func retryPendingStores() {
+	if storage.CountPendingStores() == 0 {
+		return
+	}
+	stored, failed := flushPendingStores()
+	cli.LogDebug("store: stored %d queued conversations, %d still queued", stored, failed)
+}
+
+// flushPendingStores stores each queued conversation on its commit,
+// dropping it from the queue once stored, and returns how many were stored
+// and how many failed again.
+func flushPendingStores() (stored, failed int) {
+	pending, errs := storage.PendingStores()
+	for _, err := range errs {
+		cli.LogWarning("skipping queued conversation %v", err)
+		failed++
+	}
+	for _, p := range pending {
+		short := p.Commit[:min(len(p.Commit), 8)]
+		if !git.CommitExists(p.Commit) {
+			cli.LogWarning("dropping the queued conversation of %s: the commit no longer exists", short)
+			if err := p.Remove(); err != nil {
+				cli.LogWarning("could not remove it from the queue: %v", err)
+			}
+			continue
+		}
+		if err := storePending(p); err != nil {
+			cli.LogWarning("could not store the queued conversation of %s: %v", short, err)
+			if err := p.Retried(err); err != nil {
+				cli.LogDebug("store: could not update the queue: %v", err)
+			}
+			failed++
+			continue
+		}
+		if err := p.Remove(); err != nil {
+			cli.LogWarning("could not remove the queued conversation of %s from the queue: %v", short, err)
+		}
+		stored++
+	}
+	return stored, failed
+}
+
+// storePending adds a queued conversation to the conversations stored on
+// its commit since, unless its session is among them. A note that cannot be
+// parsed keeps it queued rather than being overwritten.
+func storePending(p *storage.PendingStore) error {
+	existingPrivate, err := storage.GetPrivateConversations(p.Commit)
+	if err != nil {
+		return err
+	}
+	if storage.IndexOfSession(existingPrivate, p.Conversation) >= 0 {
+		cli.LogInfo("conversation already stored for commit %s", p.Commit[:min(len(p.Commit), 8)])
+		return nil
+	}
+	return writeStoredConversation(p.Commit, p.Conversation, p.Private, addToParsedNote)
+}
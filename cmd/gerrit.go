@@ -0,0 +1,294 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/re-cinq/shift-log/internal/cli"
+	"github.com/re-cinq/shift-log/internal/config"
+	"github.com/re-cinq/shift-log/internal/gerrit"
+	"github.com/re-cinq/shift-log/internal/git"
+	"github.com/re-cinq/shift-log/internal/storage"
+	"github.com/spf13/cobra"
+)
+
+// Environment variables holding the Gerrit account shiftlog gerrit posts
+// as, and its HTTP password (generated in Gerrit's settings).
+const (
+	gerritUserEnv     = "SHIFTLOG_GERRIT_USER"
+	gerritPasswordEnv = "SHIFTLOG_GERRIT_PASSWORD"
+)
+
+// gerritMessageTag tags the messages shiftlog posts, so that Gerrit can
+// hide them with the other bots' and shiftlog can tell which patchsets it
+// commented on.
+const gerritMessageTag = "autogenerated:shiftlog"
+
+var (
+	gerritRange     string
+	gerritURL       string
+	gerritViewerURL string
+	gerritDryRun    bool
+	gerritForce     bool
+)
+
+var gerritCmd = &cobra.Command{
+	Use:     "gerrit",
+	Short:   "Link conversations to Gerrit changes",
+	GroupID: "human",
+}
+
+var gerritCommentCmd = &cobra.Command{
+	Use:   "comment [<commit>...]",
+	Short: "Post a summary of each patchset's conversations on its Gerrit change",
+	Long: `Posts a message on the Gerrit change of each commit with a stored
+conversation: the agents and number of messages of its conversations, their
+summaries, and a link to the conversation in the web viewer of
+'shiftlog serve' when viewer_url is set in .shiftlog/config.
+
+Each patchset is commented on once; --force comments again. The commits are
+those given, or else those of --range. A commit uploaded to Gerrit after
+its conversation was stored on an earlier patchset of the same change, such
+as one amended in Gerrit's web editor, gets the conversation of the newest
+such patchset first, found by its Change-Id trailer.
+
+The server is gerrit_url of .shiftlog/config, or --url. Messages are posted
+as the account of $` + gerritUserEnv + `, with its HTTP password in
+$` + gerritPasswordEnv + `.
+
+Examples:
+  shiftlog gerrit comment                        # The commits of @{upstream}..HEAD
+  shiftlog gerrit comment HEAD --dry-run         # Print the message instead
+  shiftlog gerrit comment --range origin/main..HEAD --url https://review.example.com`,
+	RunE: runGerritComment,
+}
+
+func init() {
+	gerritCommentCmd.Flags().StringVar(&gerritRange, "range", "", "commits to comment on, e.g. origin/main..HEAD (default: @{upstream}..HEAD)")
+	gerritCommentCmd.Flags().StringVar(&gerritURL, "url", "", "URL of the Gerrit server (default: gerrit_url)")
+	gerritCommentCmd.Flags().StringVar(&gerritViewerURL, "viewer-url", "", "URL of shiftlog serve to link conversations to (default: viewer_url)")
+	gerritCommentCmd.Flags().BoolVar(&gerritDryRun, "dry-run", false, "print the messages instead of posting them")
+	gerritCommentCmd.Flags().BoolVar(&gerritForce, "force", false, "comment on patchsets already commented on")
+	gerritCmd.AddCommand(gerritCommentCmd)
+	rootCmd.AddCommand(gerritCmd)
+}
+
+func runGerritComment(cmd *cobra.Command, args []string) error {
+	if err := git.RequireGitRepo(); err != nil {
+		return err
+	}
+	cfg, err := config.Read()
+	if err != nil {
+		return fmt.Errorf("could not read config: %w", err)
+	}
+	serverURL := gerritURL
+	if serverURL == "" {
+		serverURL = cfg.GerritURL
+	}
+	if serverURL == "" && !gerritDryRun {
+		return fmt.Errorf("no Gerrit server: set gerrit_url in .shiftlog/config or pass --url")
+	}
+	viewerURL := gerritViewerURL
+	if viewerURL == "" {
+		viewerURL = cfg.ViewerURL
+	}
+
+	commits, err := gerritCommits(args)
+	if err != nil {
+		return err
+	}
+	cmd.SilenceUsage = true
+	if len(commits) == 0 {
+		fmt.Println("No commits to comment on")
+		return nil
+	}
+	adopted, err := adoptPatchsetConversations(commits)
+	if err != nil {
+		return err
+	}
+
+	client := gerrit.NewClient(serverURL, os.Getenv(gerritUserEnv), os.Getenv(gerritPasswordEnv))
+	ctx := cmd.Context()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	var failed int
+	for _, sha := range commits {
+		source := sha
+		if from, ok := adopted[sha]; ok && gerritDryRun {
+			// Not copied on a dry run
+			source = from
+		}
+		conversations, err := storage.GetStoredConversations(source)
+		if err != nil {
+			cli.LogWarning("could not read the conversation of %s: %v", sha[:7], err)
+			continue
+		}
+		if len(conversations) == 0 {
+			fmt.Printf("%s: no conversation\n", sha[:7])
+			continue
+		}
+		message := gerritMessage(sha, conversations, viewerURL)
+		if gerritDryRun {
+			fmt.Printf("%s:\n%s\n\n", sha[:7], indent(message, "  "))
+			continue
+		}
+
+		change, err := client.ChangeOfCommit(ctx, sha)
+		if errors.Is(err, gerrit.ErrNotFound) {
+			fmt.Printf("%s: not uploaded to Gerrit\n", sha[:7])
+			continue
+		}
+		if err != nil {
+			cli.LogWarning("could not find the Gerrit change of %s: %v", sha[:7], err)
+			failed++
+			continue
+		}
+		patchset := change.Patchset(sha)
+		if !gerritForce && change.HasMessage(gerritMessageTag, patchset) {
+			fmt.Printf("%s: already commented on change %d, patchset %d\n", sha[:7], change.Number, patchset)
+			continue
+		}
+		if err := client.Review(ctx, change, sha, message, gerritMessageTag); err != nil {
+			cli.LogWarning("could not comment on change %d: %v", change.Number, err)
+			failed++
+			continue
+		}
+		fmt.Printf("%s: commented on change %d, patchset %d\n", sha[:7], change.Number, patchset)
+		cli.RecordArtifact("gerrit-comment", fmt.Sprintf("%d/%d", change.Number, patchset))
+	}
+	if failed > 0 {
+		return fmt.Errorf("could not comment on %d of %d commit(s)", failed, len(commits))
+	}
+	return nil
+}
+
+// gerritCommits returns the SHAs of the commits to comment on: those of
+// args, or else of --range.
+func gerritCommits(args []string) ([]string, error) {
+	var commits []string
+	if len(args) > 0 {
+		if gerritRange != "" {
+			return nil, fmt.Errorf("pass commits or --range, not both")
+		}
+		for _, ref := range args {
+			sha, err := git.ResolveRef(ref)
+			if err != nil {
+				return nil, fmt.Errorf("could not resolve %s: %w", ref, err)
+			}
+			commits = append(commits, sha)
+		}
+		return commits, nil
+	}
+	rangeSpec, err := commitRange(gerritRange)
+	if err != nil {
+		return nil, err
+	}
+	err = git.ListCommits(git.LogOptions{Ref: rangeSpec}, func(c git.LogCommit) bool {
+		commits = append(commits, c.SHA)
+		return true
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not list commits in %s: %w", rangeSpec, err)
+	}
+	return commits, nil
+}
+
+// adoptPatchsetConversations copies to each of commits without a
+// conversation the conversation of the newest other commit of the same
+// Change-Id, an earlier patchset of the change, as remap does for rebased
+// commits. It returns the commit each conversation was copied from, or
+// would be on a dry run, keyed by the commit it was copied to.
+func adoptPatchsetConversations(commits []string) (map[string]string, error) {
+	adopted := make(map[string]string)
+	stored, err := storage.ListAllConversationCommits()
+	if err != nil {
+		return nil, fmt.Errorf("could not list conversations: %w", err)
+	}
+	var missing []string
+	for _, sha := range commits {
+		if !stored[sha] {
+			missing = append(missing, sha)
+		}
+	}
+	if len(missing) == 0 || len(stored) == 0 {
+		return adopted, nil
+	}
+
+	shas := slices.Clone(missing)
+	for sha := range stored {
+		shas = append(shas, sha)
+	}
+	changeIDs, err := git.ChangeIDs(shas)
+	if err != nil {
+		return nil, fmt.Errorf("could not read Change-Ids: %w", err)
+	}
+	for _, sha := range missing {
+		changeID, ok := changeIDs[sha]
+		if !ok {
+			continue
+		}
+		var patchsets []string
+		for other := range stored {
+			if changeIDs[other] == changeID {
+				patchsets = append(patchsets, other)
+			}
+		}
+		from, err := git.NewestCommit(patchsets)
+		if err != nil || from == "" {
+			continue
+		}
+		if gerritDryRun {
+			adopted[sha] = from
+			fmt.Printf("%s: would copy the conversation of %s, an earlier patchset of %s\n", sha[:7], from[:7], changeID)
+			continue
+		}
+		if err := git.CopyNote(from, sha); err != nil {
+			cli.LogWarning("could not copy the conversation of %s to %s: %v", from[:7], sha[:7], err)
+			continue
+		}
+		adopted[sha] = from
+		fmt.Printf("%s: copied the conversation of %s, an earlier patchset of %s\n", sha[:7], from[:7], changeID)
+		cli.RecordArtifact("note", sha)
+	}
+	return adopted, nil
+}
+
+// gerritMessage returns the message posted for the conversations of a
+// commit: their agents and messages, summaries and a link to the viewer.
+func gerritMessage(sha string, conversations []*storage.StoredConversation, viewerURL string) string {
+	var agents, summaries []string
+	messages := 0
+	for _, sc := range conversations {
+		if !slices.Contains(agents, sc.AgentName()) {
+			agents = append(agents, sc.AgentName())
+		}
+		messages += sc.MessageCount
+		if sc.Summary != "" {
+			summaries = append(summaries, sc.Summary)
+		}
+	}
+
+	var b strings.Builder
+	noun := "conversation"
+	if len(conversations) > 1 {
+		noun = "conversations"
+	}
+	fmt.Fprintf(&b, "shiftlog: %d %s with %s, %d messages\n", len(conversations), noun, strings.Join(agents, ", "), messages)
+	for _, summary := range summaries {
+		fmt.Fprintf(&b, "\n%s\n", summary)
+	}
+	if viewerURL != "" {
+		fmt.Fprintf(&b, "\n%s/#/commit/%s\n", strings.TrimSuffix(viewerURL, "/"), sha)
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// indent prefixes every line of s.
+func indent(s, prefix string) string {
+	return prefix + strings.ReplaceAll(s, "\n", "\n"+prefix)
+}
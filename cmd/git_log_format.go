@@ -0,0 +1,101 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+
+	"github.com/re-cinq/shift-log/internal/decorate"
+	"github.com/re-cinq/shift-log/internal/git"
+	"github.com/re-cinq/shift-log/internal/storage"
+	"github.com/spf13/cobra"
+)
+
+var (
+	gitLogFormatInstall   bool
+	gitLogFormatPager     bool
+	gitLogFormatUninstall bool
+)
+
+var gitLogFormatCmd = &cobra.Command{
+	Use:     "git-log-format [git log args]",
+	Short:   "Mark the commits with a conversation in git log output",
+	GroupID: "human",
+	Long: `Adds a column to git log output: ` + decorate.Marker + ` and the message count for
+commits with a stored conversation, blank for the others.
+
+Pipe git log into it, or run it on its own to have it run
+'git log --oneline --decorate' itself, with the arguments given after --. The conversations are read with a
+single listing of the notes, so it keeps up with long histories.
+
+With --install, it adds a 'git shiftlog' alias to the repository's config
+that shows the marked log in your pager. With --install --pager, plain
+'git log' is marked too, by setting pager.log. --uninstall removes both.
+
+Examples:
+  git log --oneline | shiftlog git-log-format
+  shiftlog git-log-format -- -20 main
+  shiftlog git-log-format --install && git shiftlog --graph`,
+	RunE: runGitLogFormat,
+}
+
+func init() {
+	gitLogFormatCmd.Flags().BoolVar(&gitLogFormatInstall, "install", false, "add the 'git shiftlog' alias to the repository's git config")
+	gitLogFormatCmd.Flags().BoolVar(&gitLogFormatPager, "pager", false, "with --install, also mark plain 'git log' output by setting pager.log")
+	gitLogFormatCmd.Flags().BoolVar(&gitLogFormatUninstall, "uninstall", false, "remove the alias and pager set by --install")
+	rootCmd.AddCommand(gitLogFormatCmd)
+}
+
+func runGitLogFormat(cmd *cobra.Command, args []string) error {
+	if err := git.RequireGitRepo(); err != nil {
+		return err
+	}
+
+	switch {
+	case gitLogFormatUninstall:
+		if err := git.UninstallLogFormat(); err != nil {
+			return err
+		}
+		fmt.Println("Removed the git shiftlog alias and log pager")
+		return nil
+	case gitLogFormatInstall:
+		if err := git.InstallLogFormat(gitLogFormatPager); err != nil {
+			return err
+		}
+		fmt.Println("Added the git shiftlog alias: run 'git shiftlog' for a log with conversations marked")
+		if gitLogFormatPager {
+			fmt.Println("Set pager.log: 'git log' marks conversations too")
+		}
+		return nil
+	case gitLogFormatPager:
+		return fmt.Errorf("--pager needs --install")
+	}
+
+	counts, err := storage.MessageCounts()
+	if err != nil {
+		return fmt.Errorf("could not read conversations: %w", err)
+	}
+	d := decorate.New(counts)
+
+	// Decorate piped output, or run git log when there is none
+	if stat, err := os.Stdin.Stat(); err == nil && stat.Mode()&os.ModeCharDevice == 0 && len(args) == 0 {
+		return d.Copy(os.Stdout, os.Stdin)
+	}
+	gitLog := exec.Command("git", append([]string{"log", "--oneline", "--decorate"}, args...)...)
+	gitLog.Stderr = os.Stderr
+	out, err := gitLog.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err := gitLog.Start(); err != nil {
+		return fmt.Errorf("could not run git log: %w", err)
+	}
+	if err := d.Copy(os.Stdout, out); err != nil {
+		_ = gitLog.Wait()
+		return err
+	}
+	if err := gitLog.Wait(); err != nil {
+		return fmt.Errorf("git log failed: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,165 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/re-cinq/shift-log/internal/agent"
+	"github.com/re-cinq/shift-log/internal/git"
+	"github.com/re-cinq/shift-log/internal/storage"
+	"github.com/spf13/cobra"
+)
+
+var (
+	hookAgentFlag string
+	hookDryRun    bool
+)
+
+var hookCmd = &cobra.Command{
+	Use:     "hook",
+	Short:   "Run coding agent hook payloads",
+	GroupID: "hooks",
+}
+
+var hookRunCmd = &cobra.Command{
+	Use:   "run",
+	Short: "Handle a coding agent hook payload read from stdin",
+	Long: `Reads a coding agent's hook JSON from stdin and handles it like
+'shiftlog store' does.
+
+With --dry-run, nothing is written. Instead it reports each step: which
+agents' parsers accept the payload, and which of them see a git commit in
+it, which configured agent the payload is attributed to, which session and
+transcript it resolves, and the note it would write. Summaries and signatures are left out. Use it to check a payload
+captured from a new agent version.
+
+Examples:
+  shiftlog hook run --dry-run < payload.json
+  shiftlog hook run --dry-run --agent gemini < payload.json`,
+	RunE: runHookRun,
+}
+
+func init() {
+	hookRunCmd.Flags().StringVar(&hookAgentFlag, "agent", "", "Coding agent (amazonq, claude, codex, copilot, gemini, goose, opencode, windsurf). Defaults to the configured agent that recognises the payload.")
+	hookRunCmd.Flags().BoolVar(&hookDryRun, "dry-run", false, "report what would be stored without writing anything")
+	hookCmd.AddCommand(hookRunCmd)
+	rootCmd.AddCommand(hookCmd)
+}
+
+func runHookRun(cmd *cobra.Command, args []string) error {
+	raw, err := io.ReadAll(os.Stdin)
+	if err != nil {
+		return fmt.Errorf("failed to read stdin: %w", err)
+	}
+	ag, err := resolveHookAgent(hookAgentFlag, raw)
+	if err != nil {
+		return fmt.Errorf("unsupported agent %q (supported: %s)", hookAgentFlag, agent.SupportedNames())
+	}
+
+	if !hookDryRun {
+		return storeHookPayload(ag, raw)
+	}
+	return dryRunHook(ag, raw)
+}
+
+// dryRunHook walks through storeHookPayload for a payload, printing what
+// each step finds instead of storing the conversation.
+func dryRunHook(ag agent.Agent, raw []byte) error {
+	printStep := func(name, format string, args ...interface{}) {
+		fmt.Printf("%-12s %s\n", name+":", fmt.Sprintf(format, args...))
+	}
+
+	var matched []string
+	for _, a := range agent.All() {
+		hd, err := a.ParseHookInput(raw)
+		if err != nil || hd.SessionID == "" {
+			continue
+		}
+		if a.IsCommitCommand(hd.ToolName, hd.Command) {
+			matched = append(matched, string(a.Name())+" (commit)")
+		} else {
+			matched = append(matched, string(a.Name()))
+		}
+	}
+	if len(matched) == 0 {
+		printStep("Parsers", "none recognise the payload")
+	} else {
+		printStep("Parsers", "%s", strings.Join(matched, ", "))
+	}
+	printStep("Agent", "%s", ag.Name())
+
+	hookData, err := ag.ParseHookInput(raw)
+	if err != nil {
+		printStep("Result", "would skip: %s cannot parse the payload: %v", ag.Name(), err)
+		return nil
+	}
+	printStep("Tool", "%s", valueOrNone(hookData.ToolName))
+	printStep("Command", "%s", valueOrNone(hookData.Command))
+	printStep("Session", "%s", valueOrNone(hookData.SessionID))
+	switch {
+	case len(hookData.TranscriptData) > 0:
+		printStep("Transcript", "inline (%d bytes)", len(hookData.TranscriptData))
+	default:
+		printStep("Transcript", "%s", valueOrNone(hookData.TranscriptPath))
+	}
+
+	if ag.IsCommitCommand(hookData.ToolName, hookData.Command) {
+		printStep("Commit", "yes")
+	} else if agent.IsGitMergeCommand(hookData.Command) && git.IsInsideWorkTree() && git.IsHeadNewMerge() {
+		printStep("Commit", "no, but a merge created a merge commit")
+	} else {
+		printStep("Commit", "no")
+		printStep("Result", "would skip: not a git commit command")
+		return nil
+	}
+
+	if !git.IsInsideWorkTree() {
+		printStep("Result", "would skip: not inside a git repository")
+		return nil
+	}
+	head, err := git.GetHeadCommit()
+	if err != nil {
+		return fmt.Errorf("failed to get HEAD commit: %w", err)
+	}
+	printStep("HEAD", "%s", head[:8])
+
+	if reason := captureDisabled(); reason != "" {
+		printStep("Result", "would record the conversation as omitted: capture is off (%s)", reason)
+		return nil
+	}
+
+	existing, _ := storage.GetStoredConversations(head)
+	if storage.IndexOfSession(existing, &storage.StoredConversation{SessionID: hookData.SessionID, Agent: string(ag.Name())}) >= 0 {
+		printStep("Result", "would skip: conversation already stored for commit %s", head[:8])
+		return nil
+	}
+
+	stored, _, err := buildStoredConversation(head, ag, hookData.SessionID, hookData.TranscriptPath, hookData.TranscriptData, storage.TriggerAgentHook)
+	if err != nil {
+		printStep("Result", "would fail: %v", err)
+		return nil
+	}
+	note, err := storage.MarshalStoredConversations(append(existing, stored))
+	if err != nil {
+		return fmt.Errorf("failed to marshal conversation: %w", err)
+	}
+
+	printStep("Messages", "%d", stored.MessageCount)
+	printStep("Model", "%s", valueOrNone(stored.Model))
+	printStep("Files", "%s", valueOrNone(strings.Join(stored.FilesTouched, ", ")))
+	if stored.Authorship != nil {
+		printStep("Authorship", "%d of %d added lines by the agent", stored.Authorship.AILines, stored.Authorship.TotalLines)
+	}
+	printStep("Result", "would write a %d-byte note on %s with %d conversation(s)", len(note), head[:8], len(existing)+1)
+	return nil
+}
+
+// valueOrNone returns s, or "(none)" when it is empty.
+func valueOrNone(s string) string {
+	if s == "" {
+		return "(none)"
+	}
+	return s
+}
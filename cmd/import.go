@@ -0,0 +1,150 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/re-cinq/shift-log/internal/agent"
+	"github.com/re-cinq/shift-log/internal/cli"
+	"github.com/re-cinq/shift-log/internal/git"
+	"github.com/re-cinq/shift-log/internal/importer"
+	"github.com/re-cinq/shift-log/internal/storage"
+	"github.com/spf13/cobra"
+)
+
+var (
+	importFormatFlag  string
+	importCommitFlag  string
+	importAgentFlag   string
+	importSessionFlag string
+)
+
+var importCmd = &cobra.Command{
+	Use:     "import <file>",
+	Short:   "Store a conversation logged by another tool on a commit",
+	GroupID: "human",
+	Long: `Converts a conversation log of another tool into a shiftlog conversation
+and stores it on a commit, HEAD unless --commit is given, next to the
+conversations already there. Use it to keep the history of tools used before
+shiftlog. With "-" as the file, the log is read from stdin.
+
+Formats:
+` + importFormatList() + `
+
+A jsonl file holds one message per line:
+  {"role": "user", "content": "list the files", "timestamp": "2024-05-01T10:00:00Z"}
+  {"role": "tool", "name": "shell", "input": {"command": "ls"}, "output": "main.go"}
+  {"role": "assistant", "content": "There is one file.", "model": "gpt-4o"}
+with the roles user, assistant, system and tool; timestamp and model are
+optional.
+
+The agent recorded for the conversation is the tool that writes the format,
+or --agent. Importing the same file on the same commit twice stores it once.
+
+Examples:
+  shiftlog import --format aider --commit abc1234 .aider.chat.history.md
+  shiftlog import --format claude-export conversation.txt
+  shiftlog import --format jsonl --agent cursor chat.jsonl`,
+	Args: cobra.ExactArgs(1),
+	RunE: runImport,
+}
+
+func init() {
+	importCmd.Flags().StringVar(&importFormatFlag, "format", "", "format of the file: "+strings.Join(importer.Names(), ", "))
+	importCmd.Flags().StringVar(&importCommitFlag, "commit", "HEAD", "Commit to store the conversation on")
+	importCmd.Flags().StringVar(&importAgentFlag, "agent", "", "Tool that recorded the conversation, instead of the format's")
+	importCmd.Flags().BoolVar(&privateFlag, "private", false, "Keep the conversation in this clone until it is published")
+	importCmd.Flags().StringVar(&importSessionFlag, "session", "", "Session ID to store the conversation under (default: derived from the file's content)")
+	_ = importCmd.MarkFlagRequired("format")
+	rootCmd.AddCommand(importCmd)
+}
+
+// importFormatList describes the import formats for the command's help.
+func importFormatList() string {
+	var b strings.Builder
+	for _, name := range importer.Names() {
+		f, _ := importer.Get(name)
+		fmt.Fprintf(&b, "  %-15s %s\n", f.Name, f.Description)
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+func runImport(cmd *cobra.Command, args []string) error {
+	if err := git.RequireGitRepo(); err != nil {
+		return err
+	}
+	format, err := importer.Get(importFormatFlag)
+	if err != nil {
+		return err
+	}
+	commit, err := git.ResolveRef(importCommitFlag)
+	if err != nil {
+		return fmt.Errorf("could not resolve reference '%s': not a valid commit", importCommitFlag)
+	}
+
+	var data []byte
+	if args[0] == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(args[0])
+	}
+	if err != nil {
+		return fmt.Errorf("could not read %s: %w", args[0], err)
+	}
+	transcript, err := format.Parse(data)
+	if err != nil {
+		return fmt.Errorf("could not import %s: %w", args[0], err)
+	}
+
+	sessionID := importSessionFlag
+	if sessionID == "" {
+		sessionID = "import-" + strings.TrimPrefix(storage.Checksum(data), "sha256:")[:12]
+	}
+	agentName := importAgentFlag
+	if agentName == "" {
+		agentName = format.Agent
+	}
+	if agentName == "" {
+		agentName = format.Name
+	}
+
+	// Imported conversations are stored as Claude Code transcripts, which
+	// shiftlog reads for any agent it has no parser of its own for
+	claude, err := agent.Get(agent.Name("claude"))
+	if err != nil {
+		return err
+	}
+	encoder, ok := claude.(agent.TranscriptEncoder)
+	if !ok {
+		return fmt.Errorf("cannot encode imported transcripts")
+	}
+	projectPath, _ := git.GetRepoRoot()
+	transcriptData, err := encoder.EncodeTranscript(transcript, sessionID, projectPath)
+	if err != nil {
+		return fmt.Errorf("could not encode transcript: %w", err)
+	}
+
+	existing, err := storage.GetStoredConversations(commit)
+	if err != nil {
+		cli.LogDebug("import: could not read existing note, will overwrite it: %v", err)
+	}
+	for _, sc := range existing {
+		if sc.SessionID == sessionID {
+			cli.LogInfo("conversation %s already stored for commit %s", sessionID, commit[:8])
+			return nil
+		}
+	}
+
+	return storeBuiltConversation(commit, claude, sessionID, false, func() (*storage.StoredConversation, []agent.TranscriptEntry, error) {
+		stored, increment, err := conversationFromTranscript(commit, claude, sessionID, transcriptData, storage.TriggerImport, projectPath, "")
+		if err != nil {
+			return nil, nil, err
+		}
+		stored.Agent = agentName
+		stored.Provenance.Agent = agentName
+		stored.Provenance.AgentVersion = ""
+		return stored, increment, nil
+	})
+}
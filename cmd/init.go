@@ -2,6 +2,7 @@ package cmd
 
 import (
 	"bufio"
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
@@ -9,18 +10,25 @@ import (
 	"strings"
 
 	"github.com/re-cinq/shift-log/internal/agent"
+	_ "github.com/re-cinq/shift-log/internal/agent/amazonq"  // register Amazon Q agent
 	_ "github.com/re-cinq/shift-log/internal/agent/claude"   // register Claude agent
 	_ "github.com/re-cinq/shift-log/internal/agent/codex"    // register Codex agent
 	_ "github.com/re-cinq/shift-log/internal/agent/copilot"  // register Copilot agent
 	_ "github.com/re-cinq/shift-log/internal/agent/gemini"   // register Gemini agent
+	_ "github.com/re-cinq/shift-log/internal/agent/goose"    // register Goose agent
 	_ "github.com/re-cinq/shift-log/internal/agent/opencode" // register OpenCode agent
+	_ "github.com/re-cinq/shift-log/internal/agent/windsurf" // register Windsurf agent
 	"github.com/re-cinq/shift-log/internal/cli"
 	"github.com/re-cinq/shift-log/internal/config"
 	"github.com/re-cinq/shift-log/internal/git"
 	"github.com/spf13/cobra"
 )
 
-var agentFlag string
+var (
+	agentFlag            string
+	allAgentsFlag        bool
+	suggestCommitMsgFlag string
+)
 
 var initCmd = &cobra.Command{
 	Use:     "init",
@@ -31,13 +39,31 @@ var initCmd = &cobra.Command{
 This command:
 - Uses refs/notes/shiftlog for note storage
 - Configures hooks for the specified coding agent (default: claude)
-- Installs git hooks for automatic note syncing
-- Configures git settings for notes visibility`,
+- Installs git hooks for automatic note syncing, where the repository runs
+  them from: .git/hooks, core.hooksPath, .husky/ for Husky, or
+  lefthook-local.yml for Lefthook. Existing hooks are kept: shell scripts
+  get a shiftlog section, other hooks are chained and called first
+- Configures git settings for notes visibility
+- Keeps the notes reflog from expiring, so 'shiftlog recover' can restore
+  notes lost to force-pushes or git gc
+
+--agent takes a comma-separated list to configure several agents used in the
+same repository, e.g. --agent=claude,gemini,opencode; --all-agents configures
+every supported agent. Hook payloads are then attributed to the agent that
+sent them, and the post-commit hook looks for an active session of each.
+
+With --suggest-commit-msg, also installs a prepare-commit-msg hook that adds
+a commit message suggestion from the active conversation to the commit
+template, written by the agent ("agent") or from the last user request
+("heuristic").`,
 	RunE: runInit,
 }
 
 func init() {
-	initCmd.Flags().StringVar(&agentFlag, "agent", "claude", "Coding agent to configure (claude, codex, copilot, gemini, opencode)")
+	initCmd.Flags().StringVar(&agentFlag, "agent", "claude", "Coding agents to configure, comma-separated (amazonq, claude, codex, copilot, gemini, goose, opencode, windsurf)")
+	initCmd.Flags().BoolVar(&allAgentsFlag, "all-agents", false, "Configure every supported coding agent")
+	initCmd.MarkFlagsMutuallyExclusive("agent", "all-agents")
+	initCmd.Flags().StringVar(&suggestCommitMsgFlag, "suggest-commit-msg", "", "Suggest commit messages from the active conversation (agent, heuristic)")
 	rootCmd.AddCommand(initCmd)
 }
 
@@ -52,10 +78,15 @@ func runInit(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("failed to get repository root: %w", err)
 	}
 
-	// Resolve agent
-	ag, err := agent.Get(agent.Name(agentFlag))
+	// Resolve agents
+	agents, err := resolveInitAgents()
 	if err != nil {
-		return fmt.Errorf("unsupported agent %q (supported: %s)", agentFlag, agent.SupportedNames())
+		return err
+	}
+	switch suggestCommitMsgFlag {
+	case "", config.SummaryAgent, config.SummaryHeuristic:
+	default:
+		return fmt.Errorf("invalid --suggest-commit-msg %q (supported: agent, heuristic)", suggestCommitMsgFlag)
 	}
 
 	// Configure git settings for notes visibility
@@ -65,15 +96,21 @@ func runInit(cmd *cobra.Command, args []string) error {
 	}
 
 	fmt.Printf("✓ Configured notes ref: %s\n", git.NotesRef)
-	fmt.Println("✓ Configured git notes settings (displayRef, rewriteRef)")
+	fmt.Println("✓ Configured git notes settings (displayRef, rewriteRef, reflog retention)")
 
 	// Configure agent-specific hooks
-	cli.LogDebug("init: configuring %s hooks", ag.DisplayName())
-	if err := ag.ConfigureHooks(repoRoot); err != nil {
-		return fmt.Errorf("failed to configure %s hooks: %w", ag.DisplayName(), err)
-	}
+	var names, displayNames []string
+	for _, ag := range agents {
+		cli.LogDebug("init: configuring %s hooks", ag.DisplayName())
+		if err := ag.ConfigureHooks(repoRoot); err != nil {
+			return fmt.Errorf("failed to configure %s hooks: %w", ag.DisplayName(), err)
+		}
 
-	fmt.Printf("✓ Configured %s hooks\n", ag.DisplayName())
+		fmt.Printf("✓ Configured %s hooks\n", ag.DisplayName())
+		cli.RecordArtifact("agent-hooks", string(ag.Name()))
+		names = append(names, string(ag.Name()))
+		displayNames = append(displayNames, ag.DisplayName())
+	}
 
 	// Install git hooks
 	cli.LogDebug("init: installing git hooks")
@@ -82,11 +119,42 @@ func runInit(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("failed to find git directory: %w", err)
 	}
 
-	if err := git.InstallAllHooks(gitDir); err != nil {
-		return fmt.Errorf("failed to install git hooks: %w", err)
+	hookSetup, err := git.DetectHookSetup(gitDir)
+	if err != nil {
+		return fmt.Errorf("failed to detect git hook setup: %w", err)
 	}
+	cli.LogDebug("init: hooks are run by %s from %s", hookSetup.Manager, hookSetup.Dir)
 
-	fmt.Println("✓ Installed git hooks (pre-push, post-merge, post-checkout, post-commit)")
+	hooksInstalled := true
+	if err := git.InstallAllHooks(hookSetup); err != nil {
+		if !reportLefthookSnippet(err) {
+			return fmt.Errorf("failed to install git hooks: %w", err)
+		}
+		hooksInstalled = false
+	}
+
+	if hooksInstalled {
+		fmt.Printf("✓ Installed git hooks (pre-push, post-merge, post-checkout, post-commit) in %s\n", hookSetup.Location())
+		recordHookArtifacts(hookSetup, git.HookPrePush, git.HookPostMerge, git.HookPostCheckout, git.HookPostCommit)
+	}
+
+	if suggestCommitMsgFlag != "" {
+		if err := git.InstallCommitSuggestionHook(hookSetup); err != nil {
+			if !reportLefthookSnippet(err) {
+				return err
+			}
+		} else {
+			fmt.Printf("✓ Installed prepare-commit-msg hook (%s commit message suggestions)\n", suggestCommitMsgFlag)
+			recordHookArtifacts(hookSetup, git.HookPrepareCommitMsg)
+		}
+	}
+
+	switch {
+	case hookSetup.Manager == git.HookManagerLefthook && hooksInstalled:
+		runLefthookInstall()
+	case hookSetup.Shared:
+		fmt.Printf("  The hooks in %s run shiftlog only where it is installed; commit them to share them\n", hookSetup.Location())
+	}
 
 	// Add .shiftlog/ to .gitignore
 	cli.LogDebug("init: ensuring .shiftlog/ is in .gitignore")
@@ -101,9 +169,18 @@ func runInit(cmd *cobra.Command, args []string) error {
 	if err != nil {
 		cfg = &config.Config{}
 	}
-	cfg.Agent = string(ag.Name())
+	cfg.Agent = names[0]
+	cfg.Agents = nil
+	if len(names) > 1 {
+		cfg.Agents = names
+	}
+	if suggestCommitMsgFlag != "" {
+		cfg.CommitSuggestion = suggestCommitMsgFlag
+	}
 	if err := config.Write(cfg); err != nil {
 		cli.LogDebug("init: failed to write config: %v", err)
+	} else if path, err := config.Path(); err == nil {
+		cli.RecordArtifact("config", path)
 	}
 
 	// Check if shiftlog is in PATH
@@ -116,11 +193,76 @@ func runInit(cmd *cobra.Command, args []string) error {
 
 	fmt.Println()
 	fmt.Println("Shiftlog is now configured! Conversations will be stored")
-	fmt.Printf("as git notes on %s when commits are made via %s.\n", git.NotesRef, ag.DisplayName())
+	fmt.Printf("as git notes on %s when commits are made via %s.\n", git.NotesRef, strings.Join(displayNames, ", "))
 
 	return nil
 }
 
+// reportLefthookSnippet prints the Lefthook configuration to add by hand
+// when err says that shiftlog could not add it, returning false for other
+// errors.
+func reportLefthookSnippet(err error) bool {
+	var lefthookErr *git.LefthookConfigError
+	if !errors.As(err, &lefthookErr) {
+		return false
+	}
+	cli.LogWarning("%v:", lefthookErr)
+	fmt.Fprint(os.Stderr, lefthookErr.Snippet)
+	return true
+}
+
+// recordHookArtifacts records the hook scripts or Lefthook configuration
+// init wrote for hooks.
+func recordHookArtifacts(setup *git.HookSetup, hooks ...git.HookType) {
+	if setup.Manager == git.HookManagerLefthook {
+		cli.RecordArtifact("config", setup.LefthookLocalConfigPath())
+		return
+	}
+	for _, hook := range hooks {
+		cli.RecordArtifact("hook", setup.HookPath(hook))
+	}
+}
+
+// runLefthookInstall has Lefthook regenerate its git hooks so that they run
+// the commands init added, or says how to do it when lefthook is not on PATH.
+func runLefthookInstall() {
+	if _, err := exec.LookPath("lefthook"); err != nil {
+		fmt.Println("  Run 'lefthook install' to apply the shiftlog commands")
+		return
+	}
+	if out, err := exec.Command("lefthook", "install").CombinedOutput(); err != nil {
+		cli.LogWarning("lefthook install failed: %v\n%s", err, out)
+		return
+	}
+	fmt.Println("✓ Ran 'lefthook install'")
+}
+
+// resolveInitAgents returns the agents named by --agent, or every agent with
+// --all-agents.
+func resolveInitAgents() ([]agent.Agent, error) {
+	if allAgentsFlag {
+		return agent.All(), nil
+	}
+	var agents []agent.Agent
+	seen := make(map[string]bool)
+	for _, name := range strings.Split(agentFlag, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" || seen[name] {
+			continue
+		}
+		seen[name] = true
+		ag, err := agent.Get(agent.Name(name))
+		if err != nil {
+			return nil, fmt.Errorf("unsupported agent %q (supported: %s)", name, agent.SupportedNames())
+		}
+		agents = append(agents, ag)
+	}
+	if len(agents) == 0 {
+		return nil, fmt.Errorf("no agent given (supported: %s)", agent.SupportedNames())
+	}
+	return agents, nil
+}
+
 // ensureGitignoreEntry adds an entry to the repo's .gitignore if not already present.
 func ensureGitignoreEntry(repoRoot, entry string) error {
 	gitignorePath := filepath.Join(repoRoot, ".gitignore")
@@ -180,5 +322,12 @@ func configureGitSettings(notesRef string) error {
 		return fmt.Errorf("failed to set notes.rewriteRef: %w", err)
 	}
 
+	// Old notes commits stay reachable from the reflog, so gc never prunes them
+	for key, value := range git.NotesGCSettings {
+		if err := exec.Command("git", "config", key, value).Run(); err != nil {
+			return fmt.Errorf("failed to set %s: %w", key, err)
+		}
+	}
+
 	return nil
 }
@@ -0,0 +1,166 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/re-cinq/shift-log/internal/cli"
+	"github.com/re-cinq/shift-log/internal/config"
+	"github.com/re-cinq/shift-log/internal/git"
+	"github.com/re-cinq/shift-log/internal/storage"
+	"github.com/re-cinq/shift-log/internal/tracker"
+	"github.com/spf13/cobra"
+)
+
+// Environment variables holding the credentials shiftlog issues --comment
+// posts with: the Jira account and its API token, or a Linear API key.
+const (
+	issueUserEnv  = "SHIFTLOG_ISSUE_USER"
+	issueTokenEnv = "SHIFTLOG_ISSUE_TOKEN"
+)
+
+var (
+	issuesComment bool
+	issuesDryRun  bool
+)
+
+var issuesCmd = &cobra.Command{
+	Use:     "issues <key>",
+	Short:   "List the conversations related to an issue",
+	GroupID: "human",
+	Long: `Lists the commits whose conversations refer to an issue of Jira or
+Linear, newest first.
+
+When a conversation is stored, the issue keys of its commit's message are
+recorded with it: those matched by issue_pattern in .shiftlog/config, by
+default keys such as PROJ-123.
+
+With --comment, a summary of the conversations is posted on the issue, in
+the tracker set by issue_tracker (jira or linear) and issue_tracker_url of
+.shiftlog/config. Jira comments are posted as the account of
+$` + issueUserEnv + ` with its API token in $` + issueTokenEnv + `, or with
+a personal access token alone; Linear ones with the API key in
+$` + issueTokenEnv + `. The summary links to the web viewer of
+'shiftlog serve' when viewer_url is set.
+
+Examples:
+  shiftlog issues PROJ-123
+  shiftlog issues PROJ-123 --comment --dry-run   # Print the comment instead`,
+	Args: cobra.ExactArgs(1),
+	RunE: runIssues,
+}
+
+func init() {
+	issuesCmd.Flags().BoolVar(&issuesComment, "comment", false, "post a summary of the conversations on the issue")
+	issuesCmd.Flags().BoolVar(&issuesDryRun, "dry-run", false, "with --comment, print the comment instead of posting it")
+	rootCmd.AddCommand(issuesCmd)
+}
+
+func runIssues(cmd *cobra.Command, args []string) error {
+	if err := git.RequireGitRepo(); err != nil {
+		return err
+	}
+	key := args[0]
+	cfg, err := config.Read()
+	if err != nil {
+		return fmt.Errorf("could not read config: %w", err)
+	}
+	var issueTracker tracker.Tracker
+	if issuesComment && !issuesDryRun {
+		issueTracker, err = tracker.New(cfg.IssueTracker, cfg.IssueTrackerURL, os.Getenv(issueUserEnv), os.Getenv(issueTokenEnv))
+		if err != nil {
+			return err
+		}
+	}
+	cmd.SilenceUsage = true
+
+	ctx := cmd.Context()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	commits, err := storage.IssueCommits(ctx, key)
+	if err != nil {
+		return err
+	}
+	if len(commits) == 0 {
+		fmt.Printf("no conversations refer to %s\n", key)
+		return nil
+	}
+
+	if !issuesComment {
+		for _, c := range commits {
+			subject := c.Subject
+			if len(subject) > 50 {
+				subject = subject[:47] + "..."
+			}
+			fmt.Printf("%s %s %s (%s)\n", c.SHA[:7], shortDate(c.Date), subject, describeConversations(c.Conversations))
+			for _, sc := range c.Conversations {
+				if sc.Summary != "" {
+					fmt.Printf("  %s\n", sc.Summary)
+				}
+			}
+		}
+		return nil
+	}
+
+	comment := issueComment(commits, cfg.ViewerURL)
+	if issuesDryRun {
+		fmt.Println(comment)
+		return nil
+	}
+	if err := issueTracker.Comment(ctx, key, comment); err != nil {
+		return fmt.Errorf("could not comment on %s: %w", key, err)
+	}
+	fmt.Printf("commented on %s with %d commit(s)\n", key, len(commits))
+	cli.RecordArtifact("issue-comment", key)
+	return nil
+}
+
+// issueComment returns the comment posted on an issue for the commits of
+// its conversations: each commit with the agents and messages of its
+// conversations, their summaries and a link to the viewer.
+func issueComment(commits []storage.IssueCommit, viewerURL string) string {
+	var b strings.Builder
+	noun := "commit"
+	if len(commits) > 1 {
+		noun = "commits"
+	}
+	fmt.Fprintf(&b, "shiftlog: %d %s with AI conversations\n", len(commits), noun)
+	for _, c := range commits {
+		fmt.Fprintf(&b, "\n- %s %s (%s)", c.SHA[:7], c.Subject, describeConversations(c.Conversations))
+		if viewerURL != "" {
+			fmt.Fprintf(&b, "\n  %s/#/commit/%s", strings.TrimSuffix(viewerURL, "/"), c.SHA)
+		}
+		for _, sc := range c.Conversations {
+			if sc.Summary != "" {
+				fmt.Fprintf(&b, "\n  %s", sc.Summary)
+			}
+		}
+	}
+	return b.String()
+}
+
+// describeConversations describes conversations by their agents and number
+// of messages, e.g. "claude, 42 messages".
+func describeConversations(conversations []*storage.StoredConversation) string {
+	var agents []string
+	messages := 0
+	for _, sc := range conversations {
+		if !slices.Contains(agents, sc.AgentName()) {
+			agents = append(agents, sc.AgentName())
+		}
+		messages += sc.MessageCount
+	}
+	return fmt.Sprintf("%s, %d messages", strings.Join(agents, ", "), messages)
+}
+
+// shortDate returns the day of a git date.
+func shortDate(date string) string {
+	if len(date) >= 10 {
+		return date[:10]
+	}
+	return date
+}
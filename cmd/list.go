@@ -38,7 +38,7 @@ func runList(cmd *cobra.Command, args []string) error {
 	}
 
 	// Get list of commits with notes
-	commits, err := git.ListCommitsWithNotes()
+	commits, err := storage.ListConversationCommits()
 	if err != nil {
 		return fmt.Errorf("could not list conversations: %w", err)
 	}
@@ -80,6 +80,9 @@ func runList(cmd *cobra.Command, args []string) error {
 			message,
 			stored.MessageCount,
 		)
+		if stored.Summary != "" {
+			fmt.Printf("  %s\n", stored.Summary)
+		}
 	}
 
 	return nil
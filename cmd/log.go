@@ -0,0 +1,296 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/re-cinq/shift-log/internal/agent"
+	"github.com/re-cinq/shift-log/internal/git"
+	"github.com/re-cinq/shift-log/internal/storage"
+	"github.com/re-cinq/shift-log/internal/util"
+	"github.com/spf13/cobra"
+)
+
+var (
+	logFile            string
+	logTag             string
+	logContext         int
+	logLimit           int
+	logHasConversation bool
+	logBranch          string
+	logSince           string
+	logAuthor          string
+	logFormat          string
+)
+
+var logCmd = &cobra.Command{
+	Use:     "log",
+	Short:   "List commits with their conversations, or the history of a file",
+	GroupID: "human",
+	Long: `Lists commits like git log, one line per commit, with columns for the
+stored conversation: a marker for commits that have one, its message count,
+tokens and agent. When several agent sessions contributed to a commit, their
+messages and tokens are added up and every agent is listed.
+
+With --file, lists instead every commit touching a file that has a stored
+conversation, newest first, together with the excerpt of the conversation in
+which the agent edited the file (the messages around each edit tool call).
+Commits that touch the file without the agent having edited it are listed
+without an excerpt.
+
+Output formats (commit listing only):
+  text  aligned terminal output (default)
+  json  machine-readable output, like the web UI's /api/commits
+  tsv   tab-separated values with a header row
+
+Examples:
+  shiftlog log                                 # Recent commits
+  shiftlog log --has-conversation --since 1.week
+  shiftlog log --branch main --author alice --format tsv
+  shiftlog log --file cmd/root.go              # History of a file
+  shiftlog log --file README.md --context 4    # Show more surrounding messages
+  shiftlog log --file main.go --limit 5        # Only the 5 most recent commits
+  shiftlog log --file main.go --tag bugfix     # Only conversations tagged bugfix`,
+	Args: cobra.NoArgs,
+	RunE: runLog,
+}
+
+func init() {
+	logCmd.Flags().StringVar(&logFile, "file", "", "show the conversation history of this file")
+	logCmd.Flags().StringVar(&logTag, "tag", "", "only show conversations with this tag")
+	logCmd.Flags().IntVar(&logContext, "context", storage.DefaultExcerptContext, "entries of context around each edit")
+	logCmd.Flags().IntVar(&logLimit, "limit", 20, "max number of commits (0 for all)")
+	logCmd.Flags().BoolVar(&logHasConversation, "has-conversation", false, "only list commits with a stored conversation")
+	logCmd.Flags().StringVar(&logBranch, "branch", "", "list commits of this branch instead of HEAD")
+	logCmd.Flags().StringVar(&logSince, "since", "", "only list commits more recent than this date (as git log --since)")
+	logCmd.Flags().StringVar(&logAuthor, "author", "", "only list commits whose author matches this pattern, after .mailmap")
+	logCmd.Flags().StringVar(&logFormat, "format", "text", "output format: text, json or tsv")
+	addConcurrencyFlag(logCmd)
+	rootCmd.AddCommand(logCmd)
+}
+
+func runLog(cmd *cobra.Command, args []string) error {
+	if err := git.RequireGitRepo(); err != nil {
+		return err
+	}
+
+	if logFile == "" {
+		return runCommitLog(cmd.Context())
+	}
+
+	file, err := repoRelativeArg(logFile)
+	if err != nil {
+		return err
+	}
+
+	history, err := storage.FileHistory(file, logTag, logContext, logLimit)
+	if err != nil {
+		return err
+	}
+
+	if len(history) == 0 {
+		fmt.Printf("no conversations found for %s\n", file)
+		return nil
+	}
+
+	useColor := os.Getenv("NO_COLOR") == ""
+	for i, entry := range history {
+		if i > 0 {
+			fmt.Println()
+		}
+		printFileHistoryEntry(entry, useColor)
+	}
+
+	return nil
+}
+
+// logEntry is a commit listed by shiftlog log, with the fields of the web
+// UI's /api/commits it shows.
+type logEntry struct {
+	SHA             string   `json:"sha"`
+	Message         string   `json:"message"`
+	Author          string   `json:"author"`
+	Date            string   `json:"date"`
+	HasConversation bool     `json:"has_conversation"`
+	MessageCount    int      `json:"message_count,omitempty"`
+	Tokens          int64    `json:"tokens,omitempty"`
+	Agents          []string `json:"agents,omitempty"`
+	Tags            []string `json:"tags,omitempty"`
+}
+
+// runCommitLog lists commits with their conversation columns.
+func runCommitLog(ctx context.Context) error {
+	switch logFormat {
+	case "text", "json", "tsv":
+	default:
+		return fmt.Errorf("invalid --format %q: must be text, json or tsv", logFormat)
+	}
+
+	tag := ""
+	if logTag != "" {
+		t, err := storage.NormalizeTag(logTag)
+		if err != nil {
+			return err
+		}
+		tag = t
+	}
+
+	noted, err := storage.ListAllConversationCommits()
+	if err != nil {
+		return fmt.Errorf("could not list conversations: %w", err)
+	}
+	ix, err := storage.OpenIndex(ctx)
+	if err != nil {
+		return fmt.Errorf("could not read the index: %w", err)
+	}
+
+	entries := []logEntry{}
+	opts := git.LogOptions{Ref: logBranch, Since: logSince, Author: logAuthor}
+	err = git.ListCommits(opts, func(c git.LogCommit) bool {
+		entry := logEntry{
+			SHA:             c.SHA,
+			Message:         c.Subject,
+			Author:          c.Author,
+			Date:            util.NormalizeTimestamp(c.Date),
+			HasConversation: noted[c.SHA] || ix.Conversations(c.SHA) != nil,
+		}
+		if entry.HasConversation {
+			conversations := ix.Conversations(c.SHA)
+			if conversations == nil {
+				entry.HasConversation = false
+			}
+			for _, sc := range conversations {
+				entry.MessageCount += sc.MessageCount
+				entry.Tokens += sc.Effort.TotalTokens()
+				entry.Agents = append(entry.Agents, sc.AgentName())
+				entry.Tags = append(entry.Tags, sc.Tags...)
+			}
+		}
+		if (logHasConversation || tag != "") && !entry.HasConversation {
+			return true
+		}
+		if tag != "" && !slices.Contains(entry.Tags, tag) {
+			return true
+		}
+		entries = append(entries, entry)
+		return logLimit <= 0 || len(entries) < logLimit
+	})
+	if err != nil {
+		return err
+	}
+
+	switch logFormat {
+	case "json":
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(entries)
+	case "tsv":
+		printCommitLogTSV(entries)
+	default:
+		printCommitLog(entries)
+	}
+	return nil
+}
+
+func printCommitLog(entries []logEntry) {
+	if len(entries) == 0 {
+		fmt.Println("no commits found")
+		return
+	}
+	useColor := os.Getenv("NO_COLOR") == ""
+	for _, e := range entries {
+		sha := e.SHA[:7]
+		if useColor {
+			sha = ansiBold + sha + ansiReset
+		}
+		marker, messages, tokens := " ", "", ""
+		if e.HasConversation {
+			marker = "*"
+			messages = fmt.Sprintf("%d msgs", e.MessageCount)
+			if e.Tokens > 0 {
+				tokens = fmt.Sprintf("%d tok", e.Tokens)
+			}
+		}
+		fmt.Printf("%s %s %9s %11s  %-14s %s\n", sha, marker, messages, tokens, strings.Join(e.Agents, ","), e.Message)
+	}
+}
+
+func printCommitLogTSV(entries []logEntry) {
+	fmt.Println(strings.Join([]string{"sha", "message", "author", "date", "has_conversation", "message_count", "tokens", "agents"}, "\t"))
+	clean := strings.NewReplacer("\t", " ", "\n", " ")
+	for _, e := range entries {
+		fmt.Println(strings.Join([]string{
+			e.SHA, clean.Replace(e.Message), clean.Replace(e.Author), e.Date,
+			strconv.FormatBool(e.HasConversation), strconv.Itoa(e.MessageCount),
+			strconv.FormatInt(e.Tokens, 10), strings.Join(e.Agents, ","),
+		}, "\t"))
+	}
+}
+
+// repoRelativeArg converts a path given on the command line (relative to the
+// current directory, or absolute) into a path relative to the repository root.
+func repoRelativeArg(p string) (string, error) {
+	if filepath.IsAbs(p) {
+		root, err := git.GetRepoRoot()
+		if err != nil {
+			return "", fmt.Errorf("could not determine repository root: %w", err)
+		}
+		rel := storage.RepoRelativePath(p, root)
+		if rel == "" {
+			return "", fmt.Errorf("%s is outside the repository", p)
+		}
+		return rel, nil
+	}
+
+	prefix, err := git.GetPathPrefix()
+	if err != nil {
+		return "", fmt.Errorf("could not determine repository path: %w", err)
+	}
+	rel := path.Clean(prefix + filepath.ToSlash(p))
+	if rel == "." || strings.HasPrefix(rel, "../") || rel == ".." {
+		return "", fmt.Errorf("%s is outside the repository", p)
+	}
+	return rel, nil
+}
+
+func printFileHistoryEntry(entry storage.FileHistoryEntry, useColor bool) {
+	shortSHA := entry.CommitSHA
+	if len(shortSHA) > 7 {
+		shortSHA = shortSHA[:7]
+	}
+
+	shortDate := entry.CommitDate
+	if len(shortDate) >= 10 {
+		shortDate = shortDate[:10]
+	}
+
+	// Header line: abc1234 2024-01-15 feat: add auth (claude)
+	if useColor {
+		fmt.Printf("%s%s%s %s %s (%s)\n", ansiBold, shortSHA, ansiReset, shortDate, entry.CommitMsg, entry.Agent)
+	} else {
+		fmt.Printf("%s %s %s (%s)\n", shortSHA, shortDate, entry.CommitMsg, entry.Agent)
+	}
+	if len(entry.Tags) > 0 {
+		fmt.Printf("tags: %s\n", strings.Join(entry.Tags, ", "))
+	}
+	fmt.Println(strings.Repeat("─", 60))
+
+	if len(entry.Excerpt) == 0 {
+		fmt.Println("  (file not edited in this conversation)")
+		return
+	}
+
+	var toolAliases map[string]string
+	if ag, err := agent.Get(agent.Name(entry.Agent)); err == nil {
+		toolAliases = ag.ToolAliases()
+	}
+	renderer := agent.NewRenderer(os.Stdout, toolAliases)
+	_ = renderer.RenderEntries(entry.Excerpt)
+}
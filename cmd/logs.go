@@ -0,0 +1,128 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/re-cinq/shift-log/internal/cli"
+	"github.com/re-cinq/shift-log/internal/git"
+	"github.com/spf13/cobra"
+)
+
+// logsPollInterval is how often --tail checks the log for new lines.
+const logsPollInterval = 500 * time.Millisecond
+
+var (
+	logsTail  bool
+	logsLines int
+	logsPath  bool
+)
+
+var logsCmd = &cobra.Command{
+	Use:     "logs",
+	Short:   "Show the shiftlog trace log",
+	GroupID: "human",
+	Long: `Prints the end of the trace log in .shiftlog/logs/shiftlog.log.
+
+Every run of a command called by hooks (store, sync, session-start, ...) is
+recorded there with its arguments and outcome, so that hook failures can be
+found after the fact. With debug logging on, through --verbose,
+SHIFTLOG_DEBUG=1 or 'shiftlog debug --on', the log also records every
+command, the parsed hook input, the git commands run and the debug messages.
+
+The log is rotated at 1 MiB, keeping shiftlog.log.1 to shiftlog.log.3.
+
+Examples:
+  shiftlog logs              # Show the last 50 lines
+  shiftlog logs -n 0         # Show the whole log
+  shiftlog logs --tail       # Keep printing new lines as they are logged
+  SHIFTLOG_DEBUG=1 git commit  # Trace the hooks of one commit in detail`,
+	RunE: runLogs,
+}
+
+func init() {
+	logsCmd.Flags().BoolVarP(&logsTail, "tail", "f", false, "keep printing lines as they are logged")
+	logsCmd.Flags().IntVarP(&logsLines, "lines", "n", 50, "number of lines to show (0 for all)")
+	logsCmd.Flags().BoolVar(&logsPath, "path", false, "print the path of the log instead")
+	rootCmd.AddCommand(logsCmd)
+}
+
+func runLogs(cmd *cobra.Command, args []string) error {
+	if err := git.RequireGitRepo(); err != nil {
+		return err
+	}
+	if logsTail && cli.IsJSONOutput() {
+		return fmt.Errorf("--tail does not support --output json")
+	}
+
+	path, err := cli.LogPath()
+	if err != nil {
+		return err
+	}
+	if logsPath {
+		fmt.Println(path)
+		return nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("could not read log: %w", err)
+	}
+	if len(data) == 0 && !logsTail {
+		fmt.Println("no log entries yet")
+		return nil
+	}
+	fmt.Print(lastLines(string(data), logsLines))
+
+	if !logsTail {
+		return nil
+	}
+	return followLog(path, int64(len(data)))
+}
+
+// lastLines returns the last n lines of text, or all of it when n is 0.
+func lastLines(text string, n int) string {
+	if n <= 0 {
+		return text
+	}
+	lines := strings.SplitAfter(text, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return strings.Join(lines, "")
+}
+
+// followLog prints what is appended to the log after offset until
+// interrupted, starting over when the log is rotated.
+func followLog(path string, offset int64) error {
+	for {
+		time.Sleep(logsPollInterval)
+
+		info, err := os.Stat(path)
+		if err != nil {
+			continue
+		}
+		if info.Size() < offset {
+			offset = 0
+		}
+		if info.Size() == offset {
+			continue
+		}
+
+		f, err := os.Open(path)
+		if err != nil {
+			continue
+		}
+		if _, err := f.Seek(offset, io.SeekStart); err == nil {
+			n, _ := io.Copy(os.Stdout, f)
+			offset += n
+		}
+		_ = f.Close()
+	}
+}
@@ -62,6 +62,7 @@ func runMigrate(cmd *cobra.Command, args []string) error {
 	if err != nil {
 		return err
 	}
+	recordMigration(n, "file", filepath.Join(repoRoot, ".shiftlog"))
 	changed += n
 
 	// 2. Update .gitignore
@@ -69,6 +70,7 @@ func runMigrate(cmd *cobra.Command, args []string) error {
 	if err != nil {
 		return err
 	}
+	recordMigration(n, "file", filepath.Join(repoRoot, ".gitignore"))
 	changed += n
 
 	// 3. Upgrade git hooks (claudit-managed → shiftlog-managed)
@@ -76,6 +78,7 @@ func runMigrate(cmd *cobra.Command, args []string) error {
 	if err != nil {
 		return err
 	}
+	recordMigration(n, "hook", filepath.Join(gitDir, "hooks"))
 	changed += n
 
 	// 4. Rename Copilot hooks file
@@ -83,6 +86,7 @@ func runMigrate(cmd *cobra.Command, args []string) error {
 	if err != nil {
 		return err
 	}
+	recordMigration(n, "file", filepath.Join(repoRoot, ".github", "hooks", "shiftlog.json"))
 	changed += n
 
 	// 5. Migrate notes ref: refs/notes/claude-conversations → refs/notes/shiftlog
@@ -90,6 +94,7 @@ func runMigrate(cmd *cobra.Command, args []string) error {
 	if err != nil {
 		return err
 	}
+	recordMigration(n, "ref", git.NotesRef)
 	changed += n
 
 	fmt.Println()
@@ -103,6 +108,14 @@ func runMigrate(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+// recordMigration records the artifact of a migration step that changed
+// something, unless this is a dry run.
+func recordMigration(changed int, kind, id string) {
+	if changed > 0 && !migrateDryRun {
+		cli.RecordArtifact(kind, id)
+	}
+}
+
 // migrateConfigDir renames .claudit/ → .shiftlog/ if it exists and .shiftlog/ does not.
 func migrateConfigDir(repoRoot string) (int, error) {
 	oldDir := filepath.Join(repoRoot, ".claudit")
@@ -0,0 +1,111 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/re-cinq/shift-log/internal/agent"
+	"github.com/re-cinq/shift-log/internal/cli"
+	"github.com/re-cinq/shift-log/internal/git"
+	"github.com/re-cinq/shift-log/internal/storage"
+	"github.com/spf13/cobra"
+)
+
+// DisableEnv turns off capture in every shiftlog process started with it
+// set, like shiftlog optout does, e.g. SHIFTLOG_DISABLE=1.
+const DisableEnv = "SHIFTLOG_DISABLE"
+
+var (
+	optOutGlobal bool
+	optOutUndo   bool
+)
+
+var optOutCmd = &cobra.Command{
+	Use:     "optout",
+	Short:   "Stop storing your own sessions' conversations",
+	GroupID: "human",
+	Long: `Turns off the capture of your agent sessions in this repository, for
+contributors who do not want their conversations stored even where the team
+has set up shiftlog. It sets ` + git.OptOutKey + ` in the repository's git
+config, which is yours alone; with --global, in your user config for every
+repository. --undo turns capture back on.
+
+Setting ` + DisableEnv + `=1 in the environment of the agent or of git has the
+same effect for the processes it reaches.
+
+While capture is off, the hooks and shiftlog watch store nothing. Instead,
+the commit records that its conversation was intentionally omitted, without
+any detail of the session, so that 'shiftlog check', 'shiftlog stats' and
+coverage badges count it as opted out rather than missing. The record syncs
+with 'shiftlog sync' like the conversations. Commands you run yourself,
+such as attach or import, still store.
+
+Examples:
+  shiftlog optout
+  shiftlog optout --global
+  shiftlog optout --undo`,
+	Args: cobra.NoArgs,
+	RunE: runOptOut,
+}
+
+func init() {
+	optOutCmd.Flags().BoolVar(&optOutGlobal, "global", false, "opt out in every repository, in your user git config")
+	optOutCmd.Flags().BoolVar(&optOutUndo, "undo", false, "turn capture back on")
+	rootCmd.AddCommand(optOutCmd)
+}
+
+func runOptOut(cmd *cobra.Command, args []string) error {
+	if !optOutGlobal {
+		if err := git.RequireGitRepo(); err != nil {
+			return err
+		}
+	}
+	if err := git.SetOptOut(!optOutUndo, optOutGlobal); err != nil {
+		return fmt.Errorf("could not set %s: %w", git.OptOutKey, err)
+	}
+	cli.RecordArtifact("config", git.OptOutKey)
+
+	where := "this repository"
+	if optOutGlobal {
+		where = "every repository"
+	}
+	if !optOutUndo {
+		fmt.Printf("Capture of your sessions is off in %s\n", where)
+		return nil
+	}
+	fmt.Printf("Capture of your sessions is back on in %s\n", where)
+	// Undoing the repository's setting leaves a global one in effect, and
+	// the other way round
+	switch {
+	case !git.IsOptedOut():
+	case optOutGlobal:
+		cli.LogWarning("%s is still set in this repository's git config; run 'shiftlog optout --undo' here too", git.OptOutKey)
+	default:
+		cli.LogWarning("%s is still set in your global git config; run 'shiftlog optout --global --undo'", git.OptOutKey)
+	}
+	return nil
+}
+
+// captureDisabled returns why the current user's sessions must not be
+// stored automatically: storage.OmissionDisabled when DisableEnv is set,
+// storage.OmissionOptOut after shiftlog optout, or "" when they may be.
+func captureDisabled() string {
+	if v := os.Getenv(DisableEnv); v != "" && v != "0" && v != "false" {
+		return storage.OmissionDisabled
+	}
+	if git.IsOptedOut() {
+		return storage.OmissionOptOut
+	}
+	return ""
+}
+
+// omitConversation records on a commit that the agent's conversation was
+// intentionally not stored, instead of storing it.
+func omitConversation(commit string, ag agent.Agent, trigger, reason string) error {
+	if err := storage.RecordOmission(commit, reason, string(ag.Name()), trigger); err != nil {
+		return err
+	}
+	cli.RecordArtifact("omission", commit)
+	cli.LogInfo("capture is off (%s): recorded the conversation of %s as omitted", reason, commit[:8])
+	return nil
+}
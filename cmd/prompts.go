@@ -0,0 +1,78 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/re-cinq/shift-log/internal/git"
+	"github.com/re-cinq/shift-log/internal/report"
+	"github.com/re-cinq/shift-log/internal/storage"
+	"github.com/spf13/cobra"
+)
+
+var (
+	promptsAll       bool
+	promptsFormat    string
+	promptsCommitURL string
+)
+
+var promptsCmd = &cobra.Command{
+	Use:     "prompts",
+	Short:   "Export a library of the prompts of stored conversations",
+	GroupID: "human",
+	Long: `Collects the prompts the conversations stored on the current branch
+started with into a library to share effective prompts within a team, each
+with the commits of the sessions it was sent in.
+
+With --all, every message the user typed is collected, not only the first
+of each conversation. Tool results and the command output and reminders
+that agents wrap in tags are left out. Prompts that share most of their
+words, such as the same request with different punctuation or an extra
+word, are merged into one entry, shown in its most recent wording. Entries
+are ordered by how many sessions used them.
+
+Output formats:
+  markdown  a browsable library to commit or paste into a wiki (default)
+  json      machine-readable output
+
+Examples:
+  shiftlog prompts > PROMPTS.md
+  shiftlog prompts --all --format json
+  shiftlog prompts --commit-url https://github.com/org/repo/commit/`,
+	Args: cobra.NoArgs,
+	RunE: runPrompts,
+}
+
+func init() {
+	promptsCmd.Flags().BoolVar(&promptsAll, "all", false, "collect every user message, not only the first of each conversation")
+	promptsCmd.Flags().StringVar(&promptsFormat, "format", "markdown", "output format: markdown or json")
+	promptsCmd.Flags().StringVar(&promptsCommitURL, "commit-url", "", "URL to link commits to in Markdown, followed by the SHA")
+	addConcurrencyFlag(promptsCmd)
+	rootCmd.AddCommand(promptsCmd)
+}
+
+func runPrompts(cmd *cobra.Command, args []string) error {
+	if err := git.RequireGitRepo(); err != nil {
+		return err
+	}
+	if promptsFormat != "markdown" && promptsFormat != "json" {
+		return fmt.Errorf("invalid --format %q: must be markdown or json", promptsFormat)
+	}
+
+	library, err := storage.PromptLibrary(cmd.Context(), promptsAll)
+	if err != nil {
+		return err
+	}
+
+	if promptsFormat == "json" {
+		if library == nil {
+			library = []*storage.Prompt{}
+		}
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(library)
+	}
+	report.Prompts(os.Stdout, library, promptsCommitURL)
+	return nil
+}
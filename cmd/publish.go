@@ -0,0 +1,57 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/re-cinq/shift-log/internal/cli"
+	"github.com/re-cinq/shift-log/internal/git"
+	"github.com/re-cinq/shift-log/internal/storage"
+	"github.com/spf13/cobra"
+)
+
+var publishSessionFlag string
+
+var publishCmd = &cobra.Command{
+	Use:     "publish <ref>",
+	Short:   "Share the private conversations of a commit",
+	GroupID: "human",
+	Long: `Moves the private conversations of a commit from ` + git.PrivateRef + `,
+which stays in this clone, to the conversation notes 'shiftlog sync'
+pushes. With --session, only that session's conversation is published.
+
+Conversations are stored as private with --private on store, attach,
+backfill and import, or by the config: "visibility": "private" in
+.shiftlog/config keeps every conversation private, and "private_branches"
+those of the branches matching one of its patterns, e.g. ["spike/*"].
+
+Examples:
+  shiftlog publish HEAD
+  shiftlog publish abc1234 --session 4f6e1c2a-90b3-4c8e-a1d2-7b5f3e9c0d11`,
+	Args: cobra.ExactArgs(1),
+	RunE: runPublish,
+}
+
+func init() {
+	publishCmd.Flags().StringVar(&publishSessionFlag, "session", "", "Only publish the conversation of this session")
+	rootCmd.AddCommand(publishCmd)
+}
+
+func runPublish(cmd *cobra.Command, args []string) error {
+	if err := git.RequireGitRepo(); err != nil {
+		return err
+	}
+	commit, err := git.ResolveRef(args[0])
+	if err != nil {
+		return fmt.Errorf("could not resolve reference '%s': not a valid commit", args[0])
+	}
+
+	published, err := storage.PublishConversations(commit, publishSessionFlag)
+	if len(published) > 0 {
+		cli.RecordArtifact("note", commit)
+		cli.RecordArtifact("private-note", commit)
+	}
+	for _, sc := range published {
+		fmt.Printf("published conversation of session %s on %s\n", sc.SessionID, commit[:8])
+	}
+	return err
+}
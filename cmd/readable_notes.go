@@ -0,0 +1,65 @@
+package cmd
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/re-cinq/shift-log/internal/cli"
+	"github.com/re-cinq/shift-log/internal/git"
+	"github.com/re-cinq/shift-log/internal/storage"
+	"github.com/spf13/cobra"
+)
+
+var readableNotesCmd = &cobra.Command{
+	Use:     "readable-notes",
+	Short:   "Describe stored conversations in plain-text git notes",
+	GroupID: "human",
+	Long: `Writes a plain-text description of the conversations of every commit to
+the refs/notes/shiftlog-readable notes ref: the agent, model, branch and
+message count of each, with its summary and tags. Unlike the conversation
+notes, which are JSON, these read well in git log:
+
+  git log --notes=shiftlog-readable
+
+With "readable_notes" enabled in .shiftlog/config, they are kept up to date
+each time a conversation is stored; this command writes them for the
+conversations stored before, or again after summaries or tags changed.
+Unchanged notes are left as they are.
+
+Examples:
+  shiftlog readable-notes
+  git log -1 --notes=shiftlog-readable`,
+	Args: cobra.NoArgs,
+	RunE: runReadableNotes,
+}
+
+func init() {
+	rootCmd.AddCommand(readableNotesCmd)
+}
+
+func runReadableNotes(cmd *cobra.Command, args []string) error {
+	if err := git.RequireGitRepo(); err != nil {
+		return err
+	}
+
+	contents, err := storage.ReadAllConversations()
+	if err != nil {
+		return fmt.Errorf("could not read conversations: %w", err)
+	}
+	commits := make([]string, 0, len(contents))
+	for commit := range contents {
+		commits = append(commits, commit)
+	}
+	sort.Strings(commits)
+
+	written := 0
+	for _, commit := range commits {
+		if err := storage.WriteReadableNote(commit, contents[commit]); err != nil {
+			cli.LogWarning("skipping %s: %v", commit[:7], err)
+			continue
+		}
+		written++
+	}
+	fmt.Printf("Described the conversations of %d commits in %s\n", written, git.ReadableRef)
+	return nil
+}
@@ -0,0 +1,136 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/re-cinq/shift-log/internal/cli"
+	"github.com/re-cinq/shift-log/internal/git"
+	"github.com/re-cinq/shift-log/internal/storage"
+	"github.com/spf13/cobra"
+)
+
+var recoverDryRun bool
+
+var recoverCmd = &cobra.Command{
+	Use:     "recover",
+	Short:   "Restore conversation notes lost from the notes ref",
+	GroupID: "human",
+	Long: `Scans the reflogs of the shiftlog notes refs and the repository's
+unreachable commits for earlier versions of the notes, and re-attaches
+conversations that are missing from refs/notes/shiftlog or whose note no
+longer parses. Each is restored on the commit SHA it was stored for, using
+the newest intact version found.
+
+Use this after a force-push, a bad notes merge or a reset dropped
+conversations. 'shiftlog init' keeps the notes reflogs from expiring so
+that old notes commits survive git gc; unreachable commits are only found
+until git gc prunes them.
+
+Notes removed on purpose are restored too, so check the list with
+--dry-run first.`,
+	Args: cobra.NoArgs,
+	RunE: runRecover,
+}
+
+func init() {
+	recoverCmd.Flags().BoolVar(&recoverDryRun, "dry-run", false, "List the notes that would be restored without changing anything")
+	rootCmd.AddCommand(recoverCmd)
+}
+
+func runRecover(cmd *cobra.Command, args []string) error {
+	if err := git.RequireGitRepo(); err != nil {
+		return err
+	}
+
+	cli.LogDebug("recover: scanning reflogs and unreachable commits for notes")
+
+	versions, err := git.FindNoteVersions()
+	if err != nil {
+		return fmt.Errorf("failed to scan for lost notes: %w", err)
+	}
+	current, err := git.ListNoteBlobs(git.NotesRef)
+	if err != nil {
+		return fmt.Errorf("failed to list notes: %w", err)
+	}
+
+	// Versions come newest first, so the first intact one wins
+	done := map[string]bool{}
+	restored, repaired, unavailable := 0, 0, 0
+	for _, v := range versions {
+		if done[v.Commit] {
+			continue
+		}
+		currentBlob, hasNote := current[v.Commit]
+		if hasNote && isIntactNote(currentBlob) {
+			done[v.Commit] = true
+			continue
+		}
+		if hasNote && currentBlob == v.Blob {
+			// The corrupt note itself; look further back
+			continue
+		}
+
+		data, err := git.ReadBlob(v.Blob)
+		if err != nil || !isIntactConversation(data) {
+			// Try an older version
+			continue
+		}
+		done[v.Commit] = true
+
+		if !git.CommitExists(v.Commit) {
+			cli.LogDebug("recover: commit %s is not available locally", v.Commit[:7])
+			unavailable++
+			continue
+		}
+
+		action := "Restored"
+		if hasNote {
+			action = "Repaired"
+		}
+		if recoverDryRun {
+			action = "Would restore"
+			if hasNote {
+				action = "Would repair"
+			}
+		} else if err := git.AddNote(v.Commit, data); err != nil {
+			cli.LogWarning("failed to restore note for %s: %v", v.Commit[:7], err)
+			continue
+		}
+		fmt.Printf("%s note for %s (from notes commit %s)\n", action, v.Commit[:7], v.NotesCommit[:7])
+		if !recoverDryRun {
+			cli.RecordArtifact("note", v.Commit)
+		}
+		if hasNote {
+			repaired++
+		} else {
+			restored++
+		}
+	}
+
+	if restored+repaired == 0 {
+		fmt.Println("No lost notes found")
+	} else if !recoverDryRun {
+		fmt.Printf("Recovered %d note(s), repaired %d corrupt note(s)\n", restored, repaired)
+	}
+	if unavailable > 0 {
+		fmt.Printf("%d note(s) belong to commits not available locally; fetch them and run 'shiftlog recover' again\n", unavailable)
+	}
+	return nil
+}
+
+// isIntactNote reports whether the note blob holds a readable conversation.
+func isIntactNote(blob string) bool {
+	data, err := git.ReadBlob(blob)
+	return err == nil && isIntactConversation(data)
+}
+
+// isIntactConversation reports whether data is a conversation note whose
+// transcript matches its checksum.
+func isIntactConversation(data []byte) bool {
+	sc, err := storage.UnmarshalStoredConversation(data)
+	if err != nil || sc.SessionID == "" || (sc.Transcript == "" && sc.TranscriptBlob == "" && !sc.IsChunked()) {
+		return false
+	}
+	ok, err := sc.VerifyIntegrity()
+	return err == nil && ok
+}
@@ -0,0 +1,82 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/re-cinq/shift-log/internal/git"
+	"github.com/re-cinq/shift-log/internal/report"
+	"github.com/re-cinq/shift-log/internal/storage"
+	"github.com/spf13/cobra"
+)
+
+var (
+	releaseReportFormat    string
+	releaseReportCommitURL string
+)
+
+var releaseReportCmd = &cobra.Command{
+	Use:     "release-report [<from>] <to>",
+	Short:   "Summarize the conversations of a release",
+	GroupID: "human",
+	Long: `Summarizes the conversations stored on the commits between two tags, for
+release retrospectives: how many commits have a conversation, the turns,
+tokens, estimated cost and AI authorship of the release, and a table of its
+conversations with their summaries and tags.
+
+The release is the commits reachable from <to> but not from <from>. Without
+<from>, it starts after the tag before <to>, or covers the whole history of
+<to> for a first release.
+
+Output formats:
+  markdown  a report to paste into release notes or a retrospective (default)
+  json      machine-readable output
+
+Examples:
+  shiftlog release-report v1.2.0                  # Since the tag before v1.2.0
+  shiftlog release-report v1.1.0 v1.2.0 > retro.md
+  shiftlog release-report v1.2.0 HEAD --format json
+  shiftlog release-report v1.2.0 --commit-url https://github.com/org/repo/commit/`,
+	Args: cobra.RangeArgs(1, 2),
+	RunE: runReleaseReport,
+}
+
+func init() {
+	releaseReportCmd.Flags().StringVar(&releaseReportFormat, "format", "markdown", "output format: markdown or json")
+	releaseReportCmd.Flags().StringVar(&releaseReportCommitURL, "commit-url", "", "URL to link commits to in Markdown, followed by the SHA")
+	rootCmd.AddCommand(releaseReportCmd)
+}
+
+func runReleaseReport(cmd *cobra.Command, args []string) error {
+	if err := git.RequireGitRepo(); err != nil {
+		return err
+	}
+	if releaseReportFormat != "markdown" && releaseReportFormat != "json" {
+		return fmt.Errorf("invalid --format %q: must be markdown or json", releaseReportFormat)
+	}
+
+	from, to := "", args[0]
+	if len(args) == 2 {
+		from, to = args[0], args[1]
+	}
+	for _, ref := range args {
+		if _, err := git.ResolveRef(ref + "^{commit}"); err != nil {
+			return fmt.Errorf("could not resolve reference '%s': not a valid commit", ref)
+		}
+	}
+	cmd.SilenceUsage = true
+
+	r, err := storage.BuildReleaseReport(from, to)
+	if err != nil {
+		return err
+	}
+
+	if releaseReportFormat == "json" {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(r)
+	}
+	report.Release(os.Stdout, r, releaseReportCommitURL)
+	return nil
+}
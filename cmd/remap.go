@@ -14,7 +14,9 @@ var remapCmd = &cobra.Command{
 	GroupID: "hooks",
 	Long: `Detects conversation notes on commits that are no longer on any branch
 (orphaned after a GitHub rebase merge) and copies them to matching commits
-using git patch-id.
+using git patch-id. Orphans whose diff changed, such as patchsets amended
+after their upload to Gerrit, are matched by their Change-Id trailer instead,
+to commits without a conversation of their own.
 
 This runs automatically in the post-merge hook after 'shiftlog sync pull'.
 You can also run it manually after pulling a rebase-merged PR.`,
@@ -47,7 +49,9 @@ func runRemap(cmd *cobra.Command, args []string) error {
 
 	// Compute patch-ids for orphaned commits
 	orphanPatchIDs := make(map[string]string) // patch-id → commit SHA
+	orphanSHAs := make([]string, 0, len(orphaned))
 	for commitSHA := range orphaned {
+		orphanSHAs = append(orphanSHAs, commitSHA)
 		patchID, err := git.PatchID(commitSHA)
 		if err != nil || patchID == "" {
 			cli.LogDebug("remap: could not compute patch-id for %s: %v", commitSHA[:7], err)
@@ -56,8 +60,21 @@ func runRemap(cmd *cobra.Command, args []string) error {
 		orphanPatchIDs[patchID] = commitSHA
 		cli.LogDebug("remap: orphaned %s patch-id=%s", commitSHA[:7], patchID[:12])
 	}
+	orphanChangeIDs, err := git.ChangeIDs(orphanSHAs)
+	if err != nil {
+		cli.LogDebug("remap: could not read Change-Ids: %v", err)
+		orphanChangeIDs = nil
+	}
 
-	if len(orphanPatchIDs) == 0 {
+	// The orphans that patch-id or Change-Id may match
+	matchable := make(map[string]bool)
+	for _, orphanSHA := range orphanPatchIDs {
+		matchable[orphanSHA] = true
+	}
+	for orphanSHA := range orphanChangeIDs {
+		matchable[orphanSHA] = true
+	}
+	if len(matchable) == 0 {
 		fmt.Printf("Found %d orphaned notes but could not compute patch-ids (commits may have been garbage collected)\n", len(orphaned))
 		return nil
 	}
@@ -75,6 +92,7 @@ func runRemap(cmd *cobra.Command, args []string) error {
 
 	// Compute patch-ids for candidates and match
 	remapped := 0
+	matched := make(map[string]bool)
 	for _, candidateSHA := range candidates {
 		patchID, err := git.PatchID(candidateSHA)
 		if err != nil || patchID == "" {
@@ -93,13 +111,23 @@ func runRemap(cmd *cobra.Command, args []string) error {
 				cli.LogWarning("failed to copy note from %s to %s: %v", orphanSHA[:7], candidateSHA[:7], err)
 				continue
 			}
+			cli.RecordArtifact("note", candidateSHA)
 			remapped++
+			matched[orphanSHA] = true
 			delete(orphanPatchIDs, patchID)
+			delete(orphanChangeIDs, orphanSHA)
 		}
 	}
 
+	// Amending a patchset changes its diff but keeps its Change-Id
+	copied, err := remapByChangeID(orphanChangeIDs, candidates, matched)
+	if err != nil {
+		return err
+	}
+	remapped += copied
+
 	// Report results
-	unmatched := len(orphanPatchIDs)
+	unmatched := len(matchable) - len(matched)
 	if remapped > 0 {
 		fmt.Printf("Remapped %d note(s) to rebased commits\n", remapped)
 	}
@@ -112,3 +140,47 @@ func runRemap(cmd *cobra.Command, args []string) error {
 
 	return nil
 }
+
+// remapByChangeID copies the notes of orphans, keyed by their Change-Id, to
+// the candidates of the same Change-Id that have no note of their own, the
+// newest orphan of each Change-Id winning. It records the orphans of the
+// Change-Ids matched in matched, and returns the number of notes copied.
+func remapByChangeID(orphanChangeIDs map[string]string, candidates []string, matched map[string]bool) (int, error) {
+	if len(orphanChangeIDs) == 0 {
+		return 0, nil
+	}
+	candidateChangeIDs, err := git.ChangeIDs(candidates)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read Change-Ids: %w", err)
+	}
+	copied := 0
+	for _, candidateSHA := range candidates {
+		changeID, ok := candidateChangeIDs[candidateSHA]
+		if !ok || git.HasNote(candidateSHA) {
+			continue
+		}
+		var orphans []string
+		for orphanSHA, id := range orphanChangeIDs {
+			if id == changeID && orphanSHA != candidateSHA {
+				orphans = append(orphans, orphanSHA)
+			}
+		}
+		orphanSHA, err := git.NewestCommit(orphans)
+		if err != nil || orphanSHA == "" {
+			continue
+		}
+
+		cli.LogDebug("remap: matched %s → %s (Change-Id=%s)", orphanSHA[:7], candidateSHA[:7], changeID)
+
+		if err := git.CopyNote(orphanSHA, candidateSHA); err != nil {
+			cli.LogWarning("failed to copy note from %s to %s: %v", orphanSHA[:7], candidateSHA[:7], err)
+			continue
+		}
+		cli.RecordArtifact("note", candidateSHA)
+		copied++
+		for _, orphan := range orphans {
+			matched[orphan] = true
+		}
+	}
+	return copied, nil
+}
@@ -6,13 +6,17 @@ import (
 	"os"
 	"os/exec"
 	"strings"
+	"time"
 
 	"github.com/re-cinq/shift-log/internal/agent"
+	_ "github.com/re-cinq/shift-log/internal/agent/amazonq"  // register Amazon Q agent
 	_ "github.com/re-cinq/shift-log/internal/agent/claude"   // register Claude agent
 	_ "github.com/re-cinq/shift-log/internal/agent/codex"    // register Codex agent
 	_ "github.com/re-cinq/shift-log/internal/agent/copilot"  // register Copilot agent
 	_ "github.com/re-cinq/shift-log/internal/agent/gemini"   // register Gemini agent
+	_ "github.com/re-cinq/shift-log/internal/agent/goose"    // register Goose agent
 	_ "github.com/re-cinq/shift-log/internal/agent/opencode" // register OpenCode agent
+	_ "github.com/re-cinq/shift-log/internal/agent/windsurf" // register Windsurf agent
 	"github.com/re-cinq/shift-log/internal/cli"
 	"github.com/re-cinq/shift-log/internal/git"
 	"github.com/re-cinq/shift-log/internal/storage"
@@ -20,7 +24,11 @@ import (
 )
 
 var (
-	resumeForce bool
+	resumeForce      bool
+	resumeNoCheckout bool
+	resumeBranch     bool
+	resumeWorktree   bool
+	resumeAgent      string
 )
 
 var resumeCmd = &cobra.Command{
@@ -29,6 +37,23 @@ var resumeCmd = &cobra.Command{
 	GroupID: "human",
 	Long: `Restores a coding agent session from a commit with a stored conversation,
 checks out the commit, and launches the coding agent with the restored session.
+This is the command-line counterpart of the web UI's resume button.
+
+When several agent sessions are stored on the commit, the first one is
+resumed; --agent picks the session of another agent. If the commit has no
+session of that agent, the stored conversation is replayed into it: its
+messages are converted to the agent's session format as text, behind a
+context message naming the agent that recorded it. Tool calls are reduced to
+their names. Claude Code, Codex CLI and OpenCode can be resumed this way.
+
+With --branch, the commit is checked out on a new resume/<short-sha>-<date>
+branch instead of a detached HEAD. With --no-checkout, the session is resumed
+on the current working tree and uncommitted changes are left alone.
+
+With --worktree, the commit is checked out in a new worktree next to the
+repository (<repo>-resume-<short-sha>) and the agent is launched there, so the
+current checkout is not touched. Remove it with "git worktree remove" when
+done.
 
 Accepts various git references:
   - Full or short SHA: abc123def456
@@ -38,7 +63,11 @@ Accepts various git references:
 Examples:
   shiftlog resume abc123
   shiftlog resume feature-branch
-  shiftlog resume HEAD~1`,
+  shiftlog resume HEAD~1
+  shiftlog resume abc123 --agent opencode    # Continue a Claude session in OpenCode
+  shiftlog resume abc123 --branch
+  shiftlog resume abc123 --worktree
+  shiftlog resume abc123 --no-checkout`,
 	Args: cobra.ExactArgs(1),
 	RunE: runResume,
 }
@@ -46,6 +75,10 @@ Examples:
 func init() {
 	rootCmd.AddCommand(resumeCmd)
 	resumeCmd.Flags().BoolVarP(&resumeForce, "force", "f", false, "Skip confirmation for uncommitted changes")
+	resumeCmd.Flags().BoolVar(&resumeBranch, "branch", false, "Check out the commit on a new resume/<short-sha>-<date> branch")
+	resumeCmd.Flags().BoolVar(&resumeWorktree, "worktree", false, "Check out the commit in a new worktree and resume there")
+	resumeCmd.Flags().BoolVar(&resumeNoCheckout, "no-checkout", false, "Resume on the current working tree without checking out the commit")
+	resumeCmd.Flags().StringVar(&resumeAgent, "agent", "", "Agent to resume with (e.g. claude, codex). Defaults to the agent that recorded the session.")
 }
 
 func runResume(cmd *cobra.Command, args []string) error {
@@ -53,6 +86,10 @@ func runResume(cmd *cobra.Command, args []string) error {
 
 	cli.LogDebug("resume: resolving ref %s", ref)
 
+	if resumeNoCheckout && (resumeBranch || resumeWorktree) {
+		return fmt.Errorf("--no-checkout cannot be used with --branch or --worktree")
+	}
+
 	// Verify we're in a git repository
 	if err := git.RequireGitRepo(); err != nil {
 		return err
@@ -66,27 +103,39 @@ func runResume(cmd *cobra.Command, args []string) error {
 
 	cli.LogDebug("resume: resolved to commit %s", commitSHA[:8])
 
-	// Read the stored conversation
-	stored, err := storage.GetStoredConversation(commitSHA)
+	// Read the stored conversations
+	conversations, err := storage.GetStoredConversations(commitSHA)
 	if err != nil {
 		return fmt.Errorf("could not read conversation: %w", err)
 	}
-	if stored == nil {
+	if len(conversations) == 0 {
 		return fmt.Errorf("no conversation found for commit %s", commitSHA[:8])
 	}
+	stored := conversations[0]
 
-	cli.LogDebug("resume: session=%s branch=%s messages=%d", stored.SessionID, stored.GitBranch, stored.MessageCount)
-
-	// Resolve agent from stored conversation
-	agentName := stored.Agent
-	if agentName == "" {
-		agentName = "claude"
-	}
-	ag, err := agent.Get(agent.Name(agentName))
-	if err != nil {
-		return fmt.Errorf("unsupported agent %q in stored conversation", agentName)
+	// Resolve agent from the flag or the stored conversation
+	var ag agent.Agent
+	if resumeAgent != "" {
+		ag, err = agent.Get(agent.Name(resumeAgent))
+		if err != nil {
+			return err
+		}
+		for _, sc := range conversations {
+			if sc.AgentName() == resumeAgent {
+				stored = sc
+				break
+			}
+		}
+	} else {
+		agentName := stored.AgentName()
+		ag, err = agent.Get(agent.Name(agentName))
+		if err != nil {
+			return fmt.Errorf("unsupported agent %q in stored conversation", agentName)
+		}
 	}
 
+	cli.LogDebug("resume: agent=%s session=%s branch=%s messages=%d", ag.Name(), stored.SessionID, stored.GitBranch, stored.MessageCount)
+
 	// Verify integrity
 	valid, err := stored.VerifyIntegrity()
 	if err != nil {
@@ -101,10 +150,13 @@ func runResume(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("could not decompress transcript: %w", err)
 	}
 
-	// Check for uncommitted changes
-	hasChanges, err := git.HasUncommittedChanges()
-	if err != nil {
-		return fmt.Errorf("could not check working directory status: %w", err)
+	// Check for uncommitted changes the checkout would conflict with
+	hasChanges := false
+	if !resumeNoCheckout && !resumeWorktree {
+		hasChanges, err = git.HasUncommittedChanges()
+		if err != nil {
+			return fmt.Errorf("could not check working directory status: %w", err)
+		}
 	}
 
 	if hasChanges && !resumeForce {
@@ -126,36 +178,106 @@ func runResume(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("could not determine project path: %w", err)
 	}
 
+	// Check out a worktree first, so the session is restored for its path
+	if resumeWorktree {
+		var branch string
+		if resumeBranch {
+			branch = git.ResumeBranchName(commitSHA, time.Now())
+		}
+		worktree := git.ResumeWorktreePath(projectPath, commitSHA)
+		if err := git.AddWorktree(worktree, branch, commitSHA); err != nil {
+			return fmt.Errorf("could not create worktree: %w", err)
+		}
+		projectPath = worktree
+
+		if branch != "" {
+			fmt.Printf("checked out %s on new branch %s in worktree %s\n", commitSHA[:8], branch, worktree)
+		} else {
+			fmt.Printf("checked out %s in worktree %s\n", commitSHA[:8], worktree)
+		}
+	}
+
+	// Replay a conversation recorded by another agent in the target's format
+	messageCount := stored.MessageCount
+	if source := stored.AgentName(); source != string(ag.Name()) {
+		transcriptData, messageCount, err = replayTranscript(stored, source, ag, projectPath)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("replaying %s conversation into %s\n", source, ag.DisplayName())
+	}
+
 	// Restore the session files using the agent
 	err = ag.RestoreSession(
 		projectPath,
 		stored.SessionID,
 		stored.GitBranch,
 		transcriptData,
-		stored.MessageCount,
+		messageCount,
 		"Restored session",
 	)
 	if err != nil {
 		return fmt.Errorf("could not restore session: %w", err)
 	}
 
-	fmt.Printf("restored session %s (%d messages)\n", stored.SessionID, stored.MessageCount)
+	fmt.Printf("restored session %s (%d messages)\n", stored.SessionID, messageCount)
 
 	// Checkout the commit
-	if err := git.Checkout(commitSHA); err != nil {
-		return fmt.Errorf("could not checkout commit: %w", err)
-	}
+	switch {
+	case resumeWorktree:
+		// Checked out in the worktree above
+	case resumeBranch:
+		branch := git.ResumeBranchName(commitSHA, time.Now())
+		if err := git.CheckoutNewBranch(branch, commitSHA); err != nil {
+			return fmt.Errorf("could not create branch %s: %w", branch, err)
+		}
+
+		fmt.Printf("checked out %s on new branch %s\n", commitSHA[:8], branch)
+	case !resumeNoCheckout:
+		if err := git.Checkout(commitSHA); err != nil {
+			return fmt.Errorf("could not checkout commit: %w", err)
+		}
 
-	fmt.Printf("checked out %s\n", commitSHA[:8])
+		fmt.Printf("checked out %s\n", commitSHA[:8])
+	}
 
 	// Launch the coding agent with the session
 	binary, cmdArgs := ag.ResumeCommand(stored.SessionID)
+	if binary == "" {
+		fmt.Printf("%s has no resume command; start it and continue session %s\n", ag.DisplayName(), stored.SessionID)
+		return nil
+	}
 	fmt.Printf("launching %s %s\n", binary, strings.Join(cmdArgs, " "))
 
 	agentCmd := exec.Command(binary, cmdArgs...)
+	agentCmd.Dir = projectPath
 	agentCmd.Stdin = os.Stdin
 	agentCmd.Stdout = os.Stdout
 	agentCmd.Stderr = os.Stderr
 
 	return agentCmd.Run()
 }
+
+// replayTranscript converts a conversation recorded by the source agent into
+// the native session format of ag. Returns the session data and its number of
+// messages.
+func replayTranscript(stored *storage.StoredConversation, source string, ag agent.Agent, projectPath string) ([]byte, int, error) {
+	encoder, ok := ag.(agent.TranscriptEncoder)
+	if !ok {
+		return nil, 0, fmt.Errorf("%s cannot resume conversations of other agents", ag.DisplayName())
+	}
+	transcript, err := stored.ParseTranscript()
+	if err != nil {
+		return nil, 0, fmt.Errorf("could not parse transcript: %w", err)
+	}
+	sourceName := source
+	if sourceAgent, err := agent.Get(agent.Name(source)); err == nil {
+		sourceName = sourceAgent.DisplayName()
+	}
+	replay := agent.ReplayTranscript(transcript, sourceName)
+	data, err := encoder.EncodeTranscript(replay, stored.SessionID, projectPath)
+	if err != nil {
+		return nil, 0, fmt.Errorf("could not convert transcript for %s: %w", ag.DisplayName(), err)
+	}
+	return data, replay.MessageCount(), nil
+}
@@ -2,8 +2,18 @@ package cmd
 
 import (
 	"fmt"
+	"os"
 	"runtime/debug"
+	"strings"
+	"time"
 
+	"github.com/re-cinq/shift-log/internal/agent/custom"
+	"github.com/re-cinq/shift-log/internal/agent/external"
+	"github.com/re-cinq/shift-log/internal/audit"
+	"github.com/re-cinq/shift-log/internal/cli"
+	"github.com/re-cinq/shift-log/internal/git"
+	"github.com/re-cinq/shift-log/internal/storage"
+	"github.com/re-cinq/shift-log/internal/util"
 	"github.com/spf13/cobra"
 )
 
@@ -12,6 +22,25 @@ import (
 // embedded by the Go toolchain.
 var version = "dev"
 
+var (
+	// outputFlag is the global --output format: cli.OutputText or cli.OutputJSON.
+	outputFlag string
+	// verboseFlag turns on debug logging for one invocation.
+	verboseFlag bool
+	// concurrencyFlag is --concurrency of the commands that load many
+	// conversations; 0 loads one per CPU at once.
+	concurrencyFlag int
+)
+
+// textOnlyCommands run until interrupted or hand the terminal to another
+// program, so they have no result to report with --output json.
+var textOnlyCommands = map[string]bool{
+	"completion": true,
+	"resume":     true,
+	"serve":      true,
+	"watch":      true,
+}
+
 var rootCmd = &cobra.Command{
 	Use:   "shiftlog",
 	Short: "Store and resume AI coding conversations as Git Notes",
@@ -19,11 +48,97 @@ var rootCmd = &cobra.Command{
 attached to commits. This enables teams to preserve AI-assisted development
 context alongside their code and resume interrupted sessions.
 
-Supports Claude Code, Codex CLI, Copilot CLI, Gemini CLI, and OpenCode.`,
+Supports Claude Code, Amazon Q CLI, Codex CLI, Copilot CLI, Gemini CLI, Goose, OpenCode, and Windsurf Cascade,
+other agents described by manifests in .shiftlog/agents/ or ~/.config/shiftlog/agents/, and
+agent plugins: shiftlog-agent-<name> executables on PATH.`,
 }
 
 func Execute() error {
-	return rootCmd.Execute()
+	cmd, err := rootCmd.ExecuteC()
+	cli.StopTrace(err)
+	recordAudit(cmd, err)
+	if cli.IsJSONOutput() {
+		return cli.FinishJSONOutput(err)
+	}
+	return err
+}
+
+// beforeCommand sets up logging and the output format of the command.
+func beforeCommand(cmd *cobra.Command, args []string) error {
+	if verboseFlag {
+		cli.SetVerbose()
+	}
+	storage.SetConcurrency(concurrencyFlag)
+	cli.StartTrace(cmd.CommandPath(), os.Args[1:], isHookCommand(cmd))
+	return startOutput(cmd, args)
+}
+
+// addConcurrencyFlag adds --concurrency to a command that reads, decompresses
+// and parses many conversations.
+func addConcurrencyFlag(cmd *cobra.Command) {
+	cmd.Flags().IntVar(&concurrencyFlag, "concurrency", 0, "conversations to load at once (default: one per CPU)")
+}
+
+// recordAudit appends the run of cmd to the audit log when it changed
+// something, i.e. recorded artifacts.
+func recordAudit(cmd *cobra.Command, cmdErr error) {
+	artifacts := cli.Artifacts()
+	if cmd == nil || len(artifacts) == 0 {
+		return
+	}
+	r := audit.Record{
+		Time:      time.Now().UTC().Format(time.RFC3339),
+		User:      git.GetUserName(),
+		Command:   cmd.CommandPath(),
+		Args:      os.Args[1:],
+		Hook:      isHookCommand(cmd),
+		Status:    "ok",
+		Artifacts: artifacts,
+	}
+	if email := git.GetUserEmail(); email != "" {
+		r.User = strings.TrimSpace(r.User + " <" + email + ">")
+	}
+	r.Host, _ = os.Hostname()
+	if cmdErr != nil {
+		r.Status = "error"
+		r.Error = cmdErr.Error()
+	}
+	if err := audit.Append(r); err != nil {
+		cli.LogDebug("audit: could not record %s: %v", r.Command, err)
+	}
+}
+
+// isHookCommand reports whether cmd belongs to the commands run by hooks.
+func isHookCommand(cmd *cobra.Command) bool {
+	for cmd.HasParent() && cmd.Parent().HasParent() {
+		cmd = cmd.Parent()
+	}
+	return cmd.GroupID == "hooks"
+}
+
+// startOutput switches the command to JSON output when --output json is
+// given. Commands with a --format flag are switched to their own JSON format,
+// which becomes the result's data.
+func startOutput(cmd *cobra.Command, args []string) error {
+	switch outputFlag {
+	case cli.OutputText:
+		return nil
+	case cli.OutputJSON:
+	default:
+		return fmt.Errorf("invalid --output %q: must be text or json", outputFlag)
+	}
+	if textOnlyCommands[cmd.Name()] {
+		return fmt.Errorf("%s does not support --output json", cmd.Name())
+	}
+
+	if f := cmd.Flags().Lookup("format"); f != nil && !f.Changed {
+		if err := f.Value.Set(cli.OutputJSON); err != nil {
+			return err
+		}
+	}
+	cmd.SilenceErrors = true
+	cmd.SilenceUsage = true
+	return cli.StartJSONOutput(cmd.CommandPath())
 }
 
 func init() {
@@ -35,9 +150,33 @@ func init() {
 	rootCmd.Version = version
 	rootCmd.SetVersionTemplate(fmt.Sprintf("shiftlog version %s\n", version))
 
+	cobra.OnInitialize(loadCustomAgents)
+	rootCmd.PersistentPreRunE = beforeCommand
+	git.CommandTracer = func(args []string) {
+		cli.Trace("git", "args", args)
+	}
+
+	rootCmd.PersistentFlags().BoolVar(&verboseFlag, "verbose", false, "print debug logs and write them to .shiftlog/logs (also SHIFTLOG_DEBUG=1)")
+	rootCmd.PersistentFlags().StringVar(&outputFlag, "output", cli.OutputText, "output format: text, or json for a machine-readable result")
+
 	// Add command groups
 	rootCmd.AddGroup(
 		&cobra.Group{ID: "human", Title: "Commands for humans:"},
 		&cobra.Group{ID: "hooks", Title: "Commands mostly used by hooks:"},
 	)
 }
+
+// loadCustomAgents registers the agents described by manifest files and the
+// agent plugins on PATH.
+func loadCustomAgents() {
+	root, err := util.GetProjectRoot()
+	if err != nil {
+		root = ""
+	}
+	for _, err := range custom.Load(root) {
+		cli.LogWarning("skipping agent manifest: %v", err)
+	}
+	for _, err := range external.Load() {
+		cli.LogWarning("skipping agent plugin: %v", err)
+	}
+}
@@ -15,6 +15,7 @@ var (
 	searchAgent         string
 	searchBranch        string
 	searchModel         string
+	searchTag           string
 	searchBefore        string
 	searchAfter         string
 	searchLimit         int
@@ -22,6 +23,7 @@ var (
 	searchMetadataOnly  bool
 	searchCaseSensitive bool
 	searchRegex         bool
+	searchErrors        bool
 )
 
 // ANSI color codes (local to avoid coupling with agent/render.go)
@@ -39,7 +41,11 @@ var searchCmd = &cobra.Command{
 	Long: `Searches conversation transcripts stored as Git Notes.
 
 Supports text search through conversation content and metadata filtering.
-Text search is case-insensitive by default.
+Text search is case-insensitive by default. With --errors, only the lines
+of tool results that report an error are searched: non-zero exits,
+exceptions, stack traces, panics and failed tests, and the results the
+agent marked as errors. Without a query, it lists every conversation where
+a tool failed.
 
 Examples:
   shiftlog search "authentication"             # Find conversations mentioning auth
@@ -47,7 +53,9 @@ Examples:
   shiftlog search --branch main --limit 5       # Recent conversations on main
   shiftlog search "test" --regex --context 2    # Regex search with context lines
   shiftlog search --before 2025-01-01           # Conversations before a date
-  shiftlog search "bug" --metadata-only         # Only match metadata, not content`,
+  shiftlog search --tag bugfix                  # Conversations tagged bugfix
+  shiftlog search "bug" --metadata-only         # Only match metadata, not content
+  shiftlog search --errors "TypeError"          # Sessions where a tool hit a TypeError`,
 	Args: cobra.MaximumNArgs(1),
 	RunE: runSearch,
 }
@@ -56,6 +64,7 @@ func init() {
 	searchCmd.Flags().StringVar(&searchAgent, "agent", "", "filter by agent name (e.g. claude, copilot)")
 	searchCmd.Flags().StringVar(&searchBranch, "branch", "", "filter by git branch")
 	searchCmd.Flags().StringVar(&searchModel, "model", "", "filter by model (substring match)")
+	searchCmd.Flags().StringVar(&searchTag, "tag", "", "filter by tag")
 	searchCmd.Flags().StringVar(&searchBefore, "before", "", "filter by date (YYYY-MM-DD or RFC3339)")
 	searchCmd.Flags().StringVar(&searchAfter, "after", "", "filter by date (YYYY-MM-DD or RFC3339)")
 	searchCmd.Flags().IntVar(&searchLimit, "limit", 20, "max number of results")
@@ -63,6 +72,8 @@ func init() {
 	searchCmd.Flags().BoolVar(&searchMetadataOnly, "metadata-only", false, "skip transcript search, filter by metadata only")
 	searchCmd.Flags().BoolVar(&searchCaseSensitive, "case-sensitive", false, "case-sensitive matching (default: insensitive)")
 	searchCmd.Flags().BoolVar(&searchRegex, "regex", false, "treat query as a regular expression")
+	searchCmd.Flags().BoolVar(&searchErrors, "errors", false, "search the errors reported by tool results")
+	addConcurrencyFlag(searchCmd)
 	rootCmd.AddCommand(searchCmd)
 }
 
@@ -78,9 +89,9 @@ func runSearch(cmd *cobra.Command, args []string) error {
 
 	// Require at least a query or one filter flag
 	hasFilter := searchAgent != "" || searchBranch != "" || searchModel != "" ||
-		searchBefore != "" || searchAfter != ""
+		searchTag != "" || searchBefore != "" || searchAfter != "" || searchErrors
 	if query == "" && !hasFilter {
-		return fmt.Errorf("provide a search query or at least one filter flag (--agent, --branch, --model, --before, --after)")
+		return fmt.Errorf("provide a search query or at least one filter flag (--agent, --branch, --model, --tag, --before, --after, --errors)")
 	}
 
 	params := &storage.SearchParams{
@@ -88,11 +99,13 @@ func runSearch(cmd *cobra.Command, args []string) error {
 		Agent:         searchAgent,
 		Branch:        searchBranch,
 		Model:         searchModel,
+		Tag:           searchTag,
 		Limit:         searchLimit,
 		ContextLines:  searchContext,
 		MetadataOnly:  searchMetadataOnly,
 		CaseSensitive: searchCaseSensitive,
 		Regex:         searchRegex,
+		Errors:        searchErrors,
 	}
 
 	// Parse date flags
@@ -111,7 +124,7 @@ func runSearch(cmd *cobra.Command, args []string) error {
 		params.After = t
 	}
 
-	results, err := storage.Search(params)
+	results, err := searchConversations(cmd.Context(), params)
 	if err != nil {
 		return err
 	}
@@ -160,6 +173,18 @@ func printSearchResult(result storage.SearchResult, useColor bool) {
 			result.Agent, result.Branch, result.MsgCount)
 	}
 
+	if result.Summary != "" {
+		if useColor {
+			fmt.Printf("  %s%s%s\n", ansiDim, result.Summary, ansiReset)
+		} else {
+			fmt.Printf("  %s\n", result.Summary)
+		}
+	}
+
+	if len(result.Tags) > 0 {
+		fmt.Printf("  tags: %s\n", strings.Join(result.Tags, ", "))
+	}
+
 	// Match snippets
 	for _, m := range result.Matches {
 		label := formatMatchLabel(m)
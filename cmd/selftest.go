@@ -0,0 +1,324 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/re-cinq/shift-log/internal/config"
+	"github.com/re-cinq/shift-log/internal/git"
+	"github.com/re-cinq/shift-log/internal/storage"
+	"github.com/re-cinq/shift-log/internal/web"
+	"github.com/spf13/cobra"
+)
+
+var selftestKeep bool
+
+var selftestCmd = &cobra.Command{
+	Use:     "selftest",
+	Short:   "Check end to end that conversations are stored and read back",
+	GroupID: "human",
+	Long: `Runs an end-to-end smoke test of this shiftlog binary, meant for the CI
+of projects that use shiftlog.
+
+The test works in a scratch clone of the current repository, so your
+checkout, branches and notes are never touched. It creates a temporary
+branch and commit, feeds a simulated coding agent hook payload with a
+bundled transcript to 'shiftlog store', then reads the conversation back
+through show, list, search and the web API. The repository's
+.shiftlog/config is copied into the clone, so the configured storage
+backend is exercised.
+
+Exits non-zero if any step fails, so an upgrade of shiftlog or of its
+transcript handling that breaks storage is caught in CI.`,
+	RunE: runSelftest,
+}
+
+func init() {
+	selftestCmd.Flags().BoolVar(&selftestKeep, "keep", false, "Keep the scratch clone for inspection")
+	rootCmd.AddCommand(selftestCmd)
+}
+
+const (
+	selftestBranch    = "shiftlog-selftest"
+	selftestSessionID = "shiftlog-selftest-session"
+	// selftestMarker appears in the bundled transcript and is searched for
+	selftestMarker = "shiftlog-selftest-marker"
+)
+
+// selftestTranscript is a minimal Claude Code transcript ending in a commit.
+const selftestTranscript = `{"uuid":"selftest-user-1","parentUuid":"","type":"user","timestamp":"2025-01-01T00:00:00Z","message":{"role":"user","content":[{"type":"text","text":"Please commit the shiftlog-selftest-marker file."}]}}
+{"uuid":"selftest-assistant-1","parentUuid":"selftest-user-1","type":"assistant","timestamp":"2025-01-01T00:00:01Z","model":"claude-sonnet-4-5-20250514","message":{"role":"assistant","content":[{"type":"text","text":"Committing it now."},{"type":"tool_use","id":"selftest-tool-1","name":"Bash","input":{"command":"git commit -m 'shiftlog selftest'"}}],"usage":{"input_tokens":10,"output_tokens":5}}}
+`
+
+// selftestRun holds the state shared by the selftest steps.
+type selftestRun struct {
+	exe     string // shiftlog binary under test
+	source  string // repository the selftest was started in
+	dir     string // scratch clone
+	head    string // commit the conversation is stored for
+	session string // transcript path handed to the hook
+}
+
+func runSelftest(cmd *cobra.Command, args []string) error {
+	if err := git.RequireGitRepo(); err != nil {
+		return err
+	}
+
+	source, err := git.GetRepoRoot()
+	if err != nil {
+		return err
+	}
+	exe, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("could not locate shiftlog binary: %w", err)
+	}
+	tmp, err := os.MkdirTemp("", "shiftlog-selftest-*")
+	if err != nil {
+		return fmt.Errorf("could not create scratch directory: %w", err)
+	}
+
+	run := &selftestRun{
+		exe:     exe,
+		source:  source,
+		dir:     filepath.Join(tmp, "repo"),
+		session: filepath.Join(tmp, "transcript.jsonl"),
+	}
+	if selftestKeep {
+		defer fmt.Printf("Scratch clone kept at %s\n", run.dir)
+	} else {
+		defer os.RemoveAll(tmp)
+	}
+
+	fmt.Println("Shiftlog Selftest")
+	fmt.Println("=================")
+	fmt.Println()
+
+	steps := []struct {
+		name string
+		run  func() error
+	}{
+		{"Creating scratch clone", run.createClone},
+		{"Committing on a temporary branch", run.commit},
+		{"Storing conversation from hook payload", run.store},
+		{"Reading stored conversation", run.readBack},
+		{"Showing conversation", run.show},
+		{"Listing conversations", run.list},
+		{"Searching conversations", run.search},
+		{"Reading conversation from web API", run.webAPI},
+	}
+
+	for _, step := range steps {
+		fmt.Printf("%s... ", step.name)
+		if err := step.run(); err != nil {
+			fmt.Println("FAIL")
+			fmt.Printf("  %v\n", err)
+			fmt.Println()
+			// Later steps depend on earlier ones, so stop at the first failure
+			return fmt.Errorf("selftest failed: %s", strings.ToLower(step.name))
+		}
+		fmt.Println("OK")
+	}
+
+	fmt.Println()
+	fmt.Println("All checks passed! Conversations are stored and read back correctly.")
+	return nil
+}
+
+// git runs a git command in the scratch clone. Hooks are disabled so the
+// repository's own shiftlog hooks cannot interfere.
+func (r *selftestRun) git(args ...string) (string, error) {
+	full := append([]string{"-C", r.dir, "-c", "core.hooksPath=" + os.DevNull}, args...)
+	out, err := exec.Command("git", full...).CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("git %s: %w: %s", strings.Join(args, " "), err, strings.TrimSpace(string(out)))
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// shiftlog runs the binary under test in the scratch clone.
+func (r *selftestRun) shiftlog(stdin string, args ...string) (string, error) {
+	c := exec.Command(r.exe, args...)
+	c.Dir = r.dir
+	c.Stdin = strings.NewReader(stdin)
+	out, err := c.CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("shiftlog %s: %w: %s", args[0], err, strings.TrimSpace(string(out)))
+	}
+	return string(out), nil
+}
+
+func (r *selftestRun) createClone() error {
+	// --shared borrows objects from the source, so the clone is cheap even
+	// for large repositories
+	out, err := exec.Command("git", "clone", "-q", "--shared", "--no-checkout", r.source, r.dir).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("git clone: %w: %s", err, strings.TrimSpace(string(out)))
+	}
+	// Commits and notes need an identity, which CI machines often lack
+	if _, err := r.git("config", "user.name", "shiftlog selftest"); err != nil {
+		return err
+	}
+	if _, err := r.git("config", "user.email", "selftest@shiftlog.invalid"); err != nil {
+		return err
+	}
+
+	cfgPath, err := config.Path()
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(cfgPath)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("could not read config: %w", err)
+	}
+	rel, err := filepath.Rel(r.source, cfgPath)
+	if err != nil {
+		return err
+	}
+	dst := filepath.Join(r.dir, rel)
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(dst, data, 0644)
+}
+
+func (r *selftestRun) commit() error {
+	// An orphan branch needs no checkout of the project's files
+	if _, err := r.git("switch", "-q", "--orphan", selftestBranch); err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(r.dir, selftestMarker+".txt"), []byte("shiftlog selftest\n"), 0644); err != nil {
+		return err
+	}
+	if _, err := r.git("add", selftestMarker+".txt"); err != nil {
+		return err
+	}
+	if _, err := r.git("commit", "-q", "-m", "shiftlog selftest"); err != nil {
+		return err
+	}
+	head, err := r.git("rev-parse", "HEAD")
+	if err != nil {
+		return err
+	}
+	r.head = head
+	return nil
+}
+
+func (r *selftestRun) store() error {
+	if err := os.WriteFile(r.session, []byte(selftestTranscript), 0644); err != nil {
+		return err
+	}
+	payload, err := json.Marshal(map[string]interface{}{
+		"session_id":      selftestSessionID,
+		"transcript_path": r.session,
+		"tool_name":       "Bash",
+		"tool_input":      map[string]string{"command": "git commit -m 'shiftlog selftest'"},
+	})
+	if err != nil {
+		return err
+	}
+	// The hook never fails the agent, so success is checked by reading back
+	_, err = r.shiftlog(string(payload), "store", "--agent", "claude")
+	return err
+}
+
+func (r *selftestRun) readBack() error {
+	return r.inClone(func() error {
+		stored, err := storage.GetStoredConversation(r.head)
+		if err != nil {
+			return err
+		}
+		if stored == nil {
+			return fmt.Errorf("no conversation was stored for %s", r.head[:7])
+		}
+		if stored.SessionID != selftestSessionID {
+			return fmt.Errorf("stored session %q, expected %q", stored.SessionID, selftestSessionID)
+		}
+		if stored.Checksum != storage.Checksum([]byte(selftestTranscript)) {
+			return fmt.Errorf("stored transcript checksum does not match the original")
+		}
+		transcript, err := stored.ParseTranscript()
+		if err != nil {
+			return fmt.Errorf("could not parse stored transcript: %w", err)
+		}
+		if len(transcript.Entries) != 2 {
+			return fmt.Errorf("stored transcript has %d entries, expected 2", len(transcript.Entries))
+		}
+		return nil
+	})
+}
+
+func (r *selftestRun) show() error {
+	out, err := r.shiftlog("", "show", r.head)
+	if err != nil {
+		return err
+	}
+	if !strings.Contains(out, selftestMarker) {
+		return fmt.Errorf("show output does not contain the conversation")
+	}
+	return nil
+}
+
+func (r *selftestRun) list() error {
+	out, err := r.shiftlog("", "list")
+	if err != nil {
+		return err
+	}
+	if !strings.Contains(out, r.head[:7]) {
+		return fmt.Errorf("list output does not include %s", r.head[:7])
+	}
+	return nil
+}
+
+func (r *selftestRun) search() error {
+	out, err := r.shiftlog("", "search", selftestMarker)
+	if err != nil {
+		return err
+	}
+	if !strings.Contains(out, r.head[:7]) {
+		return fmt.Errorf("search for %q did not find %s", selftestMarker, r.head[:7])
+	}
+	return nil
+}
+
+func (r *selftestRun) webAPI() error {
+	return r.inClone(func() error {
+		srv := web.NewServer(0, r.dir)
+		rec := httptest.NewRecorder()
+		srv.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/commits/"+r.head, nil))
+		if rec.Code != http.StatusOK {
+			return fmt.Errorf("GET /api/commits/%s returned %d: %s", r.head[:7], rec.Code, strings.TrimSpace(rec.Body.String()))
+		}
+
+		var resp web.ConversationResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			return fmt.Errorf("invalid API response: %w", err)
+		}
+		if resp.SessionID != selftestSessionID || len(resp.Transcript) == 0 {
+			return fmt.Errorf("API response does not contain the stored conversation")
+		}
+		return nil
+	})
+}
+
+// inClone runs fn with the scratch clone as working directory, for steps
+// that use shiftlog's packages in-process.
+func (r *selftestRun) inClone(fn func() error) error {
+	wd, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+	if err := os.Chdir(r.dir); err != nil {
+		return err
+	}
+	defer func() { _ = os.Chdir(wd) }()
+	return fn()
+}
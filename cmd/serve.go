@@ -2,17 +2,31 @@ package cmd
 
 import (
 	"fmt"
+	"log/slog"
+	"os"
+	"strings"
 
+	"github.com/re-cinq/shift-log/internal/agent"
+	"github.com/re-cinq/shift-log/internal/cli"
 	"github.com/re-cinq/shift-log/internal/git"
+	"github.com/re-cinq/shift-log/internal/storage"
 	"github.com/re-cinq/shift-log/internal/web"
 	"github.com/spf13/cobra"
 )
 
 var (
-	servePort       int
-	serveNoBrowser  bool
+	servePort         int
+	serveNoBrowser    bool
+	serveRepo         string
+	serveHost         string
+	serveAPITokenFile string
+	serveAccessLog    string
 )
 
+// apiTokenEnv holds the token that authorizes POST /api/conversations, as an
+// alternative to --api-token-file.
+const apiTokenEnv = "SHIFTLOG_API_TOKEN"
+
 var serveCmd = &cobra.Command{
 	Use:     "serve",
 	Short:   "Start the web visualization server",
@@ -24,12 +38,37 @@ The web interface provides:
   - A conversation viewer for reading message history
   - The ability to resume sessions directly from the UI
 
-The server binds to localhost (127.0.0.1) for security.
+The server binds to localhost (127.0.0.1) for security; --host changes that.
+Listening on any other address, resuming sessions and adding annotations
+need the API token (see below), since anyone who can reach the server could
+otherwise check out commits and launch the agent.
+
+With --repo, it serves another repository than the current one. This can be
+a bare repository, such as a mirror on a build server that the whole team
+browses without local clones. Commits, notes and branches are read from it
+as usual, but resuming sessions is disabled, since there is no working tree
+to resume in. Fetch the notes into the mirror, e.g. with
+'git fetch origin "+refs/notes/*:refs/notes/*"', to keep it up to date.
+
+With an API token, from --api-token-file or the SHIFTLOG_API_TOKEN
+environment variable, it also accepts conversations from thin clients and CI
+agents that do not have the notes ref: POST /api/conversations with the
+token as a bearer token, and a JSON body holding the commit, the agent's hook
+payload and the transcript. The commit must already be pushed to the
+server's repository.
+
+The same port serves a gRPC API, over HTTP/2 without TLS, for platforms
+embedding shiftlog: its schema is api/shiftlog/v1/shiftlog.proto.
+
+Metrics for monitoring a long-running server, such as request latencies and
+the git commands run, are served at /metrics in the Prometheus text format.
+--access-log logs every request to standard error, as text or JSON.
 
 Examples:
   shiftlog serve                 # Start on default port 8080, open browser
   shiftlog serve --port 3000     # Start on custom port
-  shiftlog serve --no-browser    # Start without opening browser`,
+  shiftlog serve --no-browser    # Start without opening browser
+  shiftlog serve --repo /srv/git/project.git --host 0.0.0.0 --no-browser`,
 	RunE: runServe,
 }
 
@@ -37,19 +76,155 @@ func init() {
 	rootCmd.AddCommand(serveCmd)
 	serveCmd.Flags().IntVarP(&servePort, "port", "p", 8080, "Port to listen on")
 	serveCmd.Flags().BoolVar(&serveNoBrowser, "no-browser", false, "Don't open browser automatically")
+	serveCmd.Flags().StringVar(&serveRepo, "repo", "", "Repository to serve, bare or not (default: the current one)")
+	serveCmd.Flags().StringVar(&serveHost, "host", "127.0.0.1", "Address to listen on, e.g. 0.0.0.0 for every interface")
+	serveCmd.Flags().StringVar(&serveAccessLog, "access-log", "", "Log every request to stderr: text or json (default: none)")
+	addConcurrencyFlag(serveCmd)
+	serveCmd.Flags().StringVar(&serveAPITokenFile, "api-token-file", "", "File holding the token that authorizes POST /api/conversations (default: $"+apiTokenEnv+")")
 }
 
 func runServe(cmd *cobra.Command, args []string) error {
-	// Verify we're in a git repository
-	if err := git.RequireGitRepo(); err != nil {
+	var accessLog *slog.Logger
+	switch serveAccessLog {
+	case "":
+	case "text":
+		accessLog = slog.New(slog.NewTextHandler(os.Stderr, nil))
+	case "json":
+		accessLog = slog.New(slog.NewJSONHandler(os.Stderr, nil))
+	default:
+		return fmt.Errorf("invalid --access-log %q: must be text or json", serveAccessLog)
+	}
+
+	// Read before changing directory, so a relative path works
+	token, err := readAPIToken()
+	if err != nil {
 		return err
 	}
 
-	repoDir, err := git.GetRepoRoot()
+	if serveRepo != "" {
+		if err := os.Chdir(serveRepo); err != nil {
+			return fmt.Errorf("could not open repository %s: %w", serveRepo, err)
+		}
+	}
+
+	// A bare repository has no working tree, so it has no root to serve from
+	bare := git.IsBareRepository()
+	var repoDir string
+	if bare {
+		repoDir, err = git.GetGitDir()
+	} else {
+		if err := git.RequireGitRepo(); err != nil {
+			return err
+		}
+		repoDir, err = git.GetRepoRoot()
+	}
 	if err != nil {
 		return fmt.Errorf("could not determine repository root: %w", err)
 	}
 
 	server := web.NewServer(servePort, repoDir)
+	server.SetHost(serveHost)
+	server.SetAccessLog(accessLog)
+	if bare {
+		server.DisableResume("Resume is not available: the server runs on a bare repository")
+		fmt.Printf("Serving bare repository %s (resume disabled)\n", repoDir)
+	}
+	if token != "" {
+		server.EnableConversationAPI(token, storeAPIConversation)
+		fmt.Println("Accepting conversations at POST /api/conversations")
+	}
 	return server.Start(!serveNoBrowser)
 }
+
+// readAPIToken returns the token from --api-token-file, or from
+// SHIFTLOG_API_TOKEN, or "" when neither is set.
+func readAPIToken() (string, error) {
+	if serveAPITokenFile == "" {
+		return strings.TrimSpace(os.Getenv(apiTokenEnv)), nil
+	}
+	data, err := os.ReadFile(serveAPITokenFile)
+	if err != nil {
+		return "", fmt.Errorf("could not read API token: %w", err)
+	}
+	token := strings.TrimSpace(string(data))
+	if token == "" {
+		return "", fmt.Errorf("API token file %s is empty", serveAPITokenFile)
+	}
+	return token, nil
+}
+
+// storeAPIConversation stores a conversation posted to POST
+// /api/conversations on a commit of the served repository. Like store, it
+// scrubs personal data when privacy.yaml asks for it; unlike store, it
+// leaves out summaries and signatures, which belong to the client.
+func storeAPIConversation(req web.StoreRequest) (*web.StoreResponse, error) {
+	name := req.Agent
+	if name == "" {
+		name = string(agent.Claude)
+	}
+	ag, err := agent.Get(agent.Name(name))
+	if err != nil {
+		return nil, fmt.Errorf("%w: unsupported agent %q", web.ErrInvalidConversation, name)
+	}
+
+	commit, err := git.ResolveRef(req.Commit + "^{commit}")
+	if err != nil {
+		return nil, fmt.Errorf("%w: unknown commit %s; push it first", web.ErrInvalidConversation, req.Commit)
+	}
+
+	// Only the session is taken from the payload: the agents' own parsing
+	// may look for the session among the server's files or run a plugin
+	hookData, err := agent.ParseStandardHookInput(req.Hook)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid hook payload: %v", web.ErrInvalidConversation, err)
+	}
+	if hookData.SessionID == "" {
+		return nil, fmt.Errorf("%w: the hook payload has no session_id", web.ErrInvalidConversation)
+	}
+	transcriptData := []byte(req.Transcript)
+	if len(transcriptData) == 0 {
+		return nil, fmt.Errorf("%w: no transcript", web.ErrInvalidConversation)
+	}
+
+	resp := &web.StoreResponse{Commit: commit, SessionID: hookData.SessionID}
+	existing, err := storage.GetStoredConversations(commit)
+	if err != nil {
+		cli.LogDebug("serve: could not read existing note, will overwrite it: %v", err)
+		existing = nil
+	}
+	if i := storage.IndexOfSession(existing, &storage.StoredConversation{SessionID: hookData.SessionID, Agent: string(ag.Name())}); i >= 0 {
+		resp.Status = "exists"
+		resp.MessageCount = existing[i].MessageCount
+		return resp, nil
+	}
+
+	stored, _, err := conversationFromTranscript(commit, ag, hookData.SessionID, transcriptData, storage.TriggerAPI, req.ProjectPath, req.Branch)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", web.ErrInvalidConversation, err)
+	}
+	if err := scrubOnStore(commit, stored); err != nil {
+		return nil, err
+	}
+	if err := stored.StoreTranscriptApart(); err != nil {
+		return nil, err
+	}
+	backend, err := storage.ActiveBackend()
+	if err != nil {
+		return nil, err
+	}
+	added, err := storage.AddStoredConversation(commit, stored, false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to store conversation in %s: %w", backend.Name(), err)
+	}
+	if !added {
+		// Stored by another request since the note was read
+		resp.Status = "exists"
+		resp.MessageCount = stored.MessageCount
+		return resp, nil
+	}
+
+	cli.LogInfo("stored conversation %s from the API for commit %s", hookData.SessionID, commit[:8])
+	resp.Status = "stored"
+	resp.MessageCount = stored.MessageCount
+	return resp, nil
+}
@@ -0,0 +1,183 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/re-cinq/shift-log/internal/agent"
+	"github.com/re-cinq/shift-log/internal/cli"
+	"github.com/re-cinq/shift-log/internal/git"
+	"github.com/re-cinq/shift-log/internal/util"
+	"github.com/spf13/cobra"
+)
+
+var sessionsAgentFlag string
+
+var sessionsCmd = &cobra.Command{
+	Use:     "sessions",
+	Short:   "List agent sessions of this project",
+	GroupID: "human",
+	Long: `Lists the coding agent sessions that shiftlog can find for the
+current project, across all supported agents, newest first.
+
+Shows:
+  - Session ID
+  - Agent
+  - Age of the session's last activity
+  - Number of messages
+
+Agents that cannot enumerate their sessions list only the active one.
+Use a session ID with 'shiftlog attach' to store that session on a commit.
+
+Example output:
+  SESSION                               AGENT   AGE      MESSAGES
+  4f6e1c2a-90b3-4c8e-a1d2-7b5f3e9c0d11  claude  5m ago   42
+  session-2024-01-15T10-02-a1b2c3       gemini  2d ago   15`,
+	RunE: runSessions,
+}
+
+func init() {
+	sessionsCmd.Flags().StringVar(&sessionsAgentFlag, "agent", "", "Only list sessions of this coding agent")
+	rootCmd.AddCommand(sessionsCmd)
+}
+
+// projectSession is a session discovered for the project, with the agent
+// that owns it and its parsed message count.
+type projectSession struct {
+	agent.SessionInfo
+	Agent        agent.Agent
+	MessageCount int
+}
+
+func runSessions(cmd *cobra.Command, args []string) error {
+	if err := git.RequireGitRepo(); err != nil {
+		return err
+	}
+	projectPath, err := git.GetRepoRoot()
+	if err != nil {
+		return fmt.Errorf("could not determine repository root: %w", err)
+	}
+
+	sessions, err := listProjectSessions(projectPath, sessionsAgentFlag)
+	if err != nil {
+		return err
+	}
+	if len(sessions) == 0 {
+		fmt.Println("no sessions found")
+		return nil
+	}
+
+	idWidth, agentWidth := len("SESSION"), len("AGENT")
+	for _, s := range sessions {
+		idWidth = max(idWidth, len(s.SessionID))
+		agentWidth = max(agentWidth, len(s.Agent.Name()))
+	}
+	fmt.Printf("%-*s  %-*s  %-8s %s\n", idWidth, "SESSION", agentWidth, "AGENT", "AGE", "MESSAGES")
+	for _, s := range sessions {
+		fmt.Printf("%-*s  %-*s  %-8s %d\n", idWidth, s.SessionID, agentWidth, s.Agent.Name(), formatAge(s.StartedAt), s.MessageCount)
+	}
+	return nil
+}
+
+// listProjectSessions returns the sessions of the project of every agent,
+// or only of the named one, newest first.
+func listProjectSessions(projectPath, agentName string) ([]projectSession, error) {
+	agents := agent.All()
+	if agentName != "" {
+		ag, err := agent.Get(agent.Name(agentName))
+		if err != nil {
+			return nil, err
+		}
+		agents = []agent.Agent{ag}
+	}
+
+	var sessions []projectSession
+	for _, ag := range agents {
+		found, err := agent.ListSessions(ag, projectPath)
+		if err != nil {
+			cli.LogDebug("sessions: %s: %v", ag.Name(), err)
+			continue
+		}
+		for _, info := range found {
+			sessions = append(sessions, projectSession{
+				SessionInfo:  info,
+				Agent:        ag,
+				MessageCount: sessionMessageCount(ag, info),
+			})
+		}
+	}
+
+	sort.SliceStable(sessions, func(i, j int) bool {
+		ti, _ := util.ParseTimestamp(sessions[i].StartedAt)
+		tj, _ := util.ParseTimestamp(sessions[j].StartedAt)
+		return ti.After(tj)
+	})
+	return sessions, nil
+}
+
+// sessionMessageCount returns the number of messages of a session's
+// transcript, or 0 if it cannot be read.
+func sessionMessageCount(ag agent.Agent, info agent.SessionInfo) int {
+	data := info.TranscriptData
+	if len(data) == 0 {
+		if info.TranscriptPath == "" {
+			return 0
+		}
+		var err error
+		if data, err = readTranscriptData(info.TranscriptPath); err != nil {
+			return 0
+		}
+	}
+	transcript, err := ag.ParseTranscript(bytes.NewReader(data))
+	if err != nil {
+		return 0
+	}
+	return transcript.MessageCount()
+}
+
+// formatAge formats the time since an RFC 3339 timestamp, e.g. "5m ago".
+func formatAge(timestamp string) string {
+	t, err := util.ParseTimestamp(timestamp)
+	if err != nil {
+		return "-"
+	}
+	d := time.Since(t)
+	switch {
+	case d < time.Minute:
+		return "just now"
+	case d < time.Hour:
+		return fmt.Sprintf("%dm ago", int(d.Minutes()))
+	case d < 24*time.Hour:
+		return fmt.Sprintf("%dh ago", int(d.Hours()))
+	}
+	return fmt.Sprintf("%dd ago", int(d.Hours()/24))
+}
+
+// findProjectSession returns the project's session with the ID, of the
+// named agent or of any agent.
+func findProjectSession(projectPath, agentName, sessionID string) (*projectSession, error) {
+	sessions, err := listProjectSessions(projectPath, agentName)
+	if err != nil {
+		return nil, err
+	}
+	var matches []projectSession
+	for _, s := range sessions {
+		if s.SessionID == sessionID {
+			matches = append(matches, s)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return nil, fmt.Errorf("no session %s found for this project (see 'shiftlog sessions')", sessionID)
+	case 1:
+		return &matches[0], nil
+	}
+	names := make([]string, len(matches))
+	for i, m := range matches {
+		names[i] = string(m.Agent.Name())
+	}
+	return nil, fmt.Errorf("session %s exists for several agents (%s), choose one with --agent", sessionID, strings.Join(names, ", "))
+}
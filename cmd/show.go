@@ -1,22 +1,30 @@
 package cmd
 
 import (
+	"encoding/json"
 	"fmt"
 	"os"
 	"strings"
 
 	"github.com/re-cinq/shift-log/internal/agent"
+	_ "github.com/re-cinq/shift-log/internal/agent/amazonq"  // register Amazon Q agent
 	_ "github.com/re-cinq/shift-log/internal/agent/claude"   // register Claude agent
 	_ "github.com/re-cinq/shift-log/internal/agent/codex"    // register Codex agent
 	_ "github.com/re-cinq/shift-log/internal/agent/copilot"  // register Copilot agent
 	_ "github.com/re-cinq/shift-log/internal/agent/gemini"   // register Gemini agent
+	_ "github.com/re-cinq/shift-log/internal/agent/goose"    // register Goose agent
 	_ "github.com/re-cinq/shift-log/internal/agent/opencode" // register OpenCode agent
+	_ "github.com/re-cinq/shift-log/internal/agent/windsurf" // register Windsurf agent
 	"github.com/re-cinq/shift-log/internal/git"
+	"github.com/re-cinq/shift-log/internal/render"
 	"github.com/re-cinq/shift-log/internal/storage"
 	"github.com/spf13/cobra"
 )
 
-var showFull bool
+var (
+	showFull   bool
+	showFormat string
+)
 
 var showCmd = &cobra.Command{
 	Use:     "show [ref]",
@@ -29,21 +37,33 @@ Use --full to see the complete session history.
 
 If no ref is provided, shows the conversation for HEAD.
 
+With --format html, writes the conversation as a standalone HTML page,
+rendered exactly like the web viewer renders it. With --format json, writes
+each entry's HTML as JSON.
+
 Examples:
   shiftlog show           # Show conversation since last commit
   shiftlog show --full    # Show full session history
   shiftlog show abc1234   # Show conversation for specific commit
-  shiftlog show HEAD~1    # Show conversation for previous commit`,
+  shiftlog show HEAD~1    # Show conversation for previous commit
+  shiftlog show --format html > conversation.html`,
 	Args: cobra.MaximumNArgs(1),
 	RunE: runShow,
 }
 
 func init() {
 	showCmd.Flags().BoolVarP(&showFull, "full", "f", false, "Show full session history instead of incremental")
+	showCmd.Flags().StringVar(&showFormat, "format", "text", "output format: text, html or json")
 	rootCmd.AddCommand(showCmd)
 }
 
 func runShow(cmd *cobra.Command, args []string) error {
+	switch showFormat {
+	case "text", "html", "json":
+	default:
+		return fmt.Errorf("invalid --format %q: must be text, html or json", showFormat)
+	}
+
 	// Verify we're in a git repository
 	if err := git.RequireGitRepo(); err != nil {
 		return err
@@ -61,14 +81,15 @@ func runShow(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("could not resolve reference '%s': not a valid commit", ref)
 	}
 
-	// Get the stored conversation
-	stored, err := storage.GetStoredConversation(fullSHA)
+	// Get the stored conversation, or the private one when it has none
+	conversations, err := readConversations(fullSHA)
 	if err != nil {
 		return fmt.Errorf("could not read conversation: %w", err)
 	}
-	if stored == nil {
+	if len(conversations) == 0 {
 		return fmt.Errorf("no conversation found for commit %s", fullSHA[:7])
 	}
+	stored := conversations[0]
 
 	// Resolve agent for tool aliases
 	agentName := stored.Agent
@@ -104,10 +125,27 @@ func runShow(cmd *cobra.Command, args []string) error {
 		entries = transcript.Entries
 	}
 
-	// Print header
 	message, date, _ := git.GetCommitInfo(fullSHA)
+	switch showFormat {
+	case "html":
+		title := fmt.Sprintf("Conversation for %s: %s", fullSHA[:7], message)
+		_, err := fmt.Print(render.Document(title, render.Transcript(entries)))
+		return err
+	case "json":
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(render.Entries(entries))
+	}
+
+	// Print header
 	fmt.Printf("Conversation for %s (%s)\n", fullSHA[:7], date[:10])
 	fmt.Printf("Commit: %s\n", message)
+	if stored.IsPrivate() {
+		fmt.Printf("Private: kept in this clone until 'shiftlog publish %s'\n", fullSHA[:7])
+	}
+	if stored.ContributorRef != "" {
+		fmt.Printf("Contributed: read from %s\n", stored.ContributorRef)
+	}
 
 	if isIncremental {
 		fmt.Printf("Showing: %d entries since %s\n", len(entries), parentSHA[:7])
@@ -0,0 +1,606 @@
+package cmd
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/re-cinq/shift-log/internal/git"
+	"github.com/re-cinq/shift-log/internal/push"
+	"github.com/re-cinq/shift-log/internal/storage"
+	"github.com/spf13/cobra"
+)
+
+var (
+	statsFormat      string
+	statsAuthorship  bool
+	statsTurns       bool
+	statsLimit       int
+	statsTimeSeries  bool
+	statsInterval    string
+	statsPushURL     string
+	statsInfluxURL   string
+	statsBy          string
+	statsProblematic bool
+	statsTools       bool
+)
+
+// influxTokenEnv holds the token that authorizes writes to --influx.
+const influxTokenEnv = "SHIFTLOG_INFLUX_TOKEN"
+
+var statsCmd = &cobra.Command{
+	Use:     "stats",
+	Short:   "Summarize stored conversations and AI authorship",
+	GroupID: "human",
+	Long: `Summarizes the conversations stored on the current branch: how many
+commits have a conversation, how many were AI-assisted, what share of their
+added lines was written by the agent, and the effort spent per agent, per
+model and per author. A conversation that used several models counts
+towards the first. Authors are grouped by the identities of the
+repository's .mailmap, so a contributor committing under several names or
+emails is counted once.
+
+With --authorship, prints the per-commit AI authorship report instead, for
+export to auditors or spreadsheets. Lines are attributed to the agent when
+they match content written by its edit tool calls; all other added lines
+count as manual edits. Conversations stored before authorship was recorded
+show no line counts.
+
+With --turns, lists the assistant turns that used the most tokens instead,
+for agents whose transcripts record usage per message.
+
+With --tools, reports each agent's tool use instead: how often it called
+each tool and how often the call failed, the average length of its Bash
+commands, and the files it edited most. Only Claude Code and Goose record
+which tool results were errors, so other agents show no failures.
+
+With --problematic, lists the commits whose agent work was likely
+problematic instead: a later commit reverted it, or a commit whose subject
+mentions a fix changed one of its files within two days. The summary
+counts them.
+
+With --by model, compares the models instead: the turns, tokens and cost
+per commit and per changed line (added or deleted) of the commits each
+model worked on, to weigh model tiers against what they deliver. --by agent
+compares the agents. Each commit counts towards the primary model of its
+first conversation, and only commits whose conversation records its effort
+count towards the averages.
+
+With --timeseries, prints the commits, conversations, tokens and cost of
+each day or week (--interval) instead, oldest first, for import into
+Grafana or a spreadsheet. Commits are placed by their commit date in UTC,
+weeks start on Monday, and the cost is the list price of the tokens of
+models with a known price. --push-gateway pushes the current interval to a
+Prometheus Pushgateway, and --influx writes the whole series to an InfluxDB
+write endpoint, authorized by the token in SHIFTLOG_INFLUX_TOKEN.
+
+Output formats:
+  table     aligned terminal output (default)
+  json      machine-readable output
+  csv       authorship report, comparison and time series only
+  markdown  authorship report and comparison only
+
+Examples:
+  shiftlog stats                                  # Summary for this branch
+  shiftlog stats --format json                    # Summary as JSON
+  shiftlog stats --authorship --format csv > ai.csv  # Export the report
+  shiftlog stats --turns --limit 5                # Five most expensive turns
+  shiftlog stats --tools                          # Tool use per agent
+  shiftlog stats --by model                       # Compare models' efficiency
+  shiftlog stats --timeseries --interval week --format csv > effort.csv
+  shiftlog stats --timeseries --push-gateway http://pushgateway:9091`,
+	Args: cobra.NoArgs,
+	RunE: runStats,
+}
+
+func init() {
+	statsCmd.Flags().StringVar(&statsFormat, "format", "table", "output format: table, json, csv or markdown")
+	statsCmd.Flags().BoolVar(&statsAuthorship, "authorship", false, "print the per-commit AI authorship report")
+	statsCmd.Flags().BoolVar(&statsTurns, "turns", false, "print the assistant turns that used the most tokens")
+	statsCmd.Flags().IntVar(&statsLimit, "limit", 10, "max number of turns for --turns (0 for all)")
+	statsCmd.Flags().BoolVar(&statsTools, "tools", false, "report the tools each agent used, their failures and the files edited most")
+	statsCmd.Flags().BoolVar(&statsProblematic, "problematic", false, "list the commits whose agent work was reverted or quickly fixed")
+	statsCmd.Flags().StringVar(&statsBy, "by", "", "compare the efficiency of each model or agent")
+	statsCmd.Flags().BoolVar(&statsTimeSeries, "timeseries", false, "print the effort per day or week")
+	statsCmd.Flags().StringVar(&statsInterval, "interval", storage.IntervalDay, "interval of --timeseries: day or week")
+	statsCmd.Flags().StringVar(&statsPushURL, "push-gateway", "", "URL of a Prometheus Pushgateway to push the current interval of --timeseries to")
+	statsCmd.Flags().StringVar(&statsInfluxURL, "influx", "", "InfluxDB write URL to write --timeseries to, e.g. http://localhost:8086/api/v2/write?org=acme&bucket=shiftlog")
+	addConcurrencyFlag(statsCmd)
+	rootCmd.AddCommand(statsCmd)
+}
+
+func runStats(cmd *cobra.Command, args []string) error {
+	if err := git.RequireGitRepo(); err != nil {
+		return err
+	}
+
+	if statsAuthorship {
+		return runAuthorshipReport(cmd.Context())
+	}
+	if statsTurns {
+		return runExpensiveTurns(cmd.Context())
+	}
+	if statsTools {
+		return runToolUsage(cmd.Context())
+	}
+	if statsTimeSeries {
+		return runTimeSeries(cmd.Context())
+	}
+	if statsBy != "" {
+		return runEfficiency(cmd.Context())
+	}
+	if statsProblematic {
+		return runProblematic()
+	}
+	if statsPushURL != "" || statsInfluxURL != "" {
+		return fmt.Errorf("--push-gateway and --influx need --timeseries")
+	}
+
+	if statsFormat != "table" && statsFormat != "json" {
+		return fmt.Errorf("invalid --format %q: must be table or json", statsFormat)
+	}
+
+	stats, err := storage.ComputeStats(cmd.Context())
+	if err != nil {
+		return err
+	}
+
+	if statsFormat == "json" {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(stats)
+	}
+
+	printStats(stats)
+	return nil
+}
+
+func runAuthorshipReport(ctx context.Context) error {
+	switch statsFormat {
+	case "table", "json", "csv", "markdown":
+	default:
+		return fmt.Errorf("invalid --format %q: must be table, json, csv or markdown", statsFormat)
+	}
+
+	records, err := storage.AuthorshipReport(ctx)
+	if err != nil {
+		return err
+	}
+
+	switch statsFormat {
+	case "json":
+		if records == nil {
+			records = []storage.AuthorshipRecord{}
+		}
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(records)
+	case "csv":
+		return writeAuthorshipCSV(records)
+	case "markdown":
+		printAuthorshipMarkdown(records)
+	default:
+		printAuthorshipTable(records)
+	}
+	return nil
+}
+
+func runExpensiveTurns(ctx context.Context) error {
+	if statsFormat != "table" && statsFormat != "json" {
+		return fmt.Errorf("invalid --format %q: must be table or json", statsFormat)
+	}
+
+	turns, err := storage.ExpensiveTurns(ctx, statsLimit)
+	if err != nil {
+		return err
+	}
+
+	if statsFormat == "json" {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(turns)
+	}
+
+	if len(turns) == 0 {
+		fmt.Println("No per-turn token usage recorded.")
+		return nil
+	}
+	for _, t := range turns {
+		fmt.Printf("%s  %8d tokens  %7d in  %6d out  %s\n",
+			t.CommitSHA[:7], t.Tokens, t.Usage.InputTokens, t.Usage.OutputTokens, t.Preview)
+	}
+	return nil
+}
+
+func runToolUsage(ctx context.Context) error {
+	if statsFormat != "table" && statsFormat != "json" {
+		return fmt.Errorf("invalid --format %q: must be table or json", statsFormat)
+	}
+
+	usage, err := storage.ToolUsageReport(ctx)
+	if err != nil {
+		return err
+	}
+
+	if statsFormat == "json" {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(usage)
+	}
+
+	if len(usage) == 0 {
+		fmt.Println("No tool calls recorded.")
+		return nil
+	}
+	useColor := os.Getenv("NO_COLOR") == ""
+	for i, u := range usage {
+		if i > 0 {
+			fmt.Println()
+		}
+		name := u.Agent
+		if useColor {
+			name = ansiBold + name + ansiReset
+		}
+		fmt.Println(name)
+		fmt.Printf("  Sessions: %d  Calls: %d  Failed: %s\n", u.Sessions, u.Calls, formatRatio(u.FailureRate))
+		if u.BashCommands > 0 {
+			fmt.Printf("  Bash commands: %d, %.0f characters on average\n", u.BashCommands, u.AvgBashLength)
+		}
+		fmt.Println("  Tools:")
+		for _, t := range u.Tools {
+			fmt.Printf("    %-16s %6d calls  %s failed\n", t.Name, t.Calls, formatRatio(t.FailureRate))
+		}
+		if len(u.Files) > 0 {
+			fmt.Println("  Most edited:")
+			for _, f := range u.Files {
+				fmt.Printf("    %6d  %s\n", f.Edits, f.Path)
+			}
+		}
+	}
+	return nil
+}
+
+func runProblematic() error {
+	if statsFormat != "table" && statsFormat != "json" {
+		return fmt.Errorf("invalid --format %q: must be table or json", statsFormat)
+	}
+
+	commits, err := storage.ProblematicCommits()
+	if err != nil {
+		return err
+	}
+
+	if statsFormat == "json" {
+		if commits == nil {
+			commits = []storage.ProblematicCommit{}
+		}
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(commits)
+	}
+
+	if len(commits) == 0 {
+		fmt.Println("No reverted or quickly fixed agent work found.")
+		return nil
+	}
+	for _, c := range commits {
+		fmt.Printf("%s  %-10s %s\n", c.CommitSHA[:7], c.Agent, c.CommitMsg)
+		for _, signal := range c.Signals {
+			what := "fixed by"
+			if signal.Reason == storage.SignalReverted {
+				what = "reverted by"
+			}
+			fmt.Printf("         %s %s %s\n", what, signal.Commit[:7], signal.Message)
+		}
+	}
+	return nil
+}
+
+func runEfficiency(ctx context.Context) error {
+	switch statsFormat {
+	case "table", "json", "csv", "markdown":
+	default:
+		return fmt.Errorf("invalid --format %q: must be table, json, csv or markdown", statsFormat)
+	}
+
+	comparison, err := storage.CompareEfficiency(ctx, statsBy)
+	if err != nil {
+		return err
+	}
+
+	switch statsFormat {
+	case "json":
+		if comparison == nil {
+			comparison = []storage.Efficiency{}
+		}
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(comparison)
+	case "csv":
+		w := csv.NewWriter(os.Stdout)
+		if err := w.Write(efficiencyHeader()); err != nil {
+			return err
+		}
+		for _, e := range comparison {
+			if err := w.Write(efficiencyRow(e)); err != nil {
+				return err
+			}
+		}
+		w.Flush()
+		return w.Error()
+	case "markdown":
+		header := efficiencyHeader()
+		fmt.Println("| " + strings.Join(header, " | ") + " |")
+		fmt.Println("|" + strings.Repeat(" --- |", len(header)))
+		for _, e := range comparison {
+			fmt.Println("| " + strings.Join(efficiencyRow(e), " | ") + " |")
+		}
+		return nil
+	}
+
+	if len(comparison) == 0 {
+		fmt.Println("No conversations found.")
+		return nil
+	}
+	width := len(statsBy)
+	for _, e := range comparison {
+		width = max(width, len(e.Name))
+	}
+	fmt.Printf("%-*s  %7s  %8s  %12s  %13s  %11s  %11s  %10s\n", width, statsBy,
+		"commits", "measured", "turns/commit", "tokens/commit", "cost/commit", "tokens/line", "cost/line")
+	for _, e := range comparison {
+		fmt.Printf("%-*s  %7d  %8d  %12.1f  %13.0f  %11s  %11.1f  %10s\n", width, e.Name,
+			e.Commits, e.Measured, e.TurnsPerCommit, e.TokensPerCommit,
+			fmt.Sprintf("$%.2f", e.CostPerCommit), e.TokensPerLine, fmt.Sprintf("$%.4f", e.CostPerLine))
+	}
+	return nil
+}
+
+func efficiencyHeader() []string {
+	return []string{statsBy, "commits", "measured", "turns", "tokens", "cost", "lines",
+		"turns_per_commit", "tokens_per_commit", "cost_per_commit", "tokens_per_line", "cost_per_line"}
+}
+
+// efficiencyRow formats a comparison row for the tabular formats.
+func efficiencyRow(e storage.Efficiency) []string {
+	f := func(v float64, prec int) string { return strconv.FormatFloat(v, 'f', prec, 64) }
+	return []string{
+		e.Name, strconv.Itoa(e.Commits), strconv.Itoa(e.Measured), strconv.Itoa(e.Turns),
+		strconv.FormatInt(e.Tokens, 10), f(e.Cost, 4), strconv.Itoa(e.Lines),
+		f(e.TurnsPerCommit, 2), f(e.TokensPerCommit, 0), f(e.CostPerCommit, 4), f(e.TokensPerLine, 2), f(e.CostPerLine, 6),
+	}
+}
+
+func runTimeSeries(ctx context.Context) error {
+	switch statsFormat {
+	case "table", "json", "csv":
+	default:
+		return fmt.Errorf("invalid --format %q: must be table, json or csv", statsFormat)
+	}
+
+	series, err := storage.EffortTimeSeries(ctx, statsInterval)
+	if err != nil {
+		return err
+	}
+
+	if statsPushURL != "" || statsInfluxURL != "" {
+		root, err := git.GetRepoRoot()
+		if err != nil {
+			return fmt.Errorf("could not determine repository root: %w", err)
+		}
+		repo := filepath.Base(root)
+		if statsPushURL != "" {
+			if err := push.Prometheus(ctx, statsPushURL, repo, statsInterval, series); err != nil {
+				return fmt.Errorf("failed to push to the Pushgateway: %w", err)
+			}
+			fmt.Printf("Pushed the current %s to %s\n", statsInterval, statsPushURL)
+		}
+		if statsInfluxURL != "" {
+			if err := push.Influx(ctx, statsInfluxURL, os.Getenv(influxTokenEnv), repo, statsInterval, series); err != nil {
+				return fmt.Errorf("failed to write to InfluxDB: %w", err)
+			}
+			fmt.Printf("Wrote %d points to InfluxDB\n", len(series))
+		}
+		return nil
+	}
+
+	switch statsFormat {
+	case "json":
+		if series == nil {
+			series = []storage.EffortPoint{}
+		}
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(series)
+	case "csv":
+		return writeTimeSeriesCSV(series)
+	}
+
+	if len(series) == 0 {
+		fmt.Println("No commits found.")
+		return nil
+	}
+	fmt.Printf("%-10s  %7s  %13s  %10s  %8s\n", statsInterval, "commits", "conversations", "tokens", "cost")
+	for _, p := range series {
+		fmt.Printf("%-10s  %7d  %13d  %10d  %8s\n", p.Start.Format(time.DateOnly), p.Commits, p.Conversations, p.Tokens, fmt.Sprintf("$%.2f", p.Cost))
+	}
+	return nil
+}
+
+func writeTimeSeriesCSV(series []storage.EffortPoint) error {
+	w := csv.NewWriter(os.Stdout)
+	if err := w.Write([]string{"start", "commits", "conversations", "tokens", "cost"}); err != nil {
+		return err
+	}
+	for _, p := range series {
+		row := []string{
+			p.Start.Format(time.DateOnly), strconv.Itoa(p.Commits), strconv.Itoa(p.Conversations),
+			strconv.FormatInt(p.Tokens, 10), strconv.FormatFloat(p.Cost, 'f', 4, 64),
+		}
+		if err := w.Write(row); err != nil {
+			return err
+		}
+	}
+	w.Flush()
+	return w.Error()
+}
+
+func printStats(s *storage.Stats) {
+	useColor := os.Getenv("NO_COLOR") == ""
+	heading := func(text string) {
+		if useColor {
+			text = ansiBold + text + ansiReset
+		}
+		fmt.Println(text)
+	}
+
+	heading("Conversations")
+	fmt.Printf("  Commits:        %d\n", s.Commits)
+	fmt.Printf("  Conversations:  %d\n", s.Conversations)
+	if s.OptedOut > 0 {
+		fmt.Printf("  Opted out:      %d\n", s.OptedOut)
+	}
+	if s.Problematic > 0 {
+		fmt.Printf("  Problematic:    %d (reverted or quickly fixed; see --problematic)\n", s.Problematic)
+	}
+	fmt.Printf("  AI-assisted:    %d\n", s.AIAssisted)
+	if s.Turns > 0 || s.Tokens > 0 {
+		fmt.Printf("  Turns:          %d\n", s.Turns)
+		fmt.Printf("  Tokens:         %d\n", s.Tokens)
+	}
+	if s.DurationSeconds > 0 {
+		fmt.Printf("  Duration:       %s (%s running tools)\n", formatSeconds(s.DurationSeconds), formatSeconds(s.ToolSeconds))
+	}
+
+	if s.Measured > 0 {
+		fmt.Println()
+		heading("AI authorship")
+		fmt.Printf("  Added lines:    %d\n", s.TotalLines)
+		fmt.Printf("  By agent:       %d (%s)\n", s.AILines, formatRatio(s.AIRatio))
+		fmt.Printf("  Measured:       %d of %d conversations\n", s.Measured, s.Conversations)
+	}
+
+	if len(s.Agents) > 0 {
+		fmt.Println()
+		heading("By agent")
+		for _, name := range s.AgentNames() {
+			a := s.Agents[name]
+			fmt.Printf("  %-10s %d conversations, %d AI-assisted, %d/%d lines\n",
+				name, a.Conversations, a.AIAssisted, a.AILines, a.TotalLines)
+		}
+	}
+
+	if len(s.Authors) > 1 {
+		fmt.Println()
+		heading("By author")
+		width := 0
+		for name := range s.Authors {
+			width = max(width, len(name))
+		}
+		for _, name := range s.AuthorNames() {
+			a := s.Authors[name]
+			fmt.Printf("  %-*s  %d conversations, %d AI-assisted, %d/%d lines, %d tokens\n",
+				width, name, a.Conversations, a.AIAssisted, a.AILines, a.TotalLines, a.Tokens)
+		}
+	}
+
+	if len(s.Models) > 0 {
+		fmt.Println()
+		heading("By model")
+		width := 0
+		for name := range s.Models {
+			width = max(width, len(name))
+		}
+		for _, name := range s.ModelNames() {
+			m := s.Models[name]
+			fmt.Printf("  %-*s  %d conversations, %d AI-assisted, %d/%d lines, %d tokens\n",
+				width, name, m.Conversations, m.AIAssisted, m.AILines, m.TotalLines, m.Tokens)
+		}
+	}
+}
+
+// authorshipRow formats a record for the tabular report formats.
+func authorshipRow(r storage.AuthorshipRecord) []string {
+	aiLines, totalLines, ratio := "", "", ""
+	if r.Authorship != nil {
+		aiLines = strconv.Itoa(r.Authorship.AILines)
+		totalLines = strconv.Itoa(r.Authorship.TotalLines)
+		ratio = strconv.FormatFloat(r.Authorship.Ratio, 'f', 2, 64)
+	}
+	return []string{
+		r.CommitSHA, r.CommitDate, r.Agent, r.Model,
+		strconv.FormatBool(r.AIAssisted), aiLines, totalLines, ratio, r.CommitMsg,
+	}
+}
+
+var authorshipHeader = []string{"commit", "date", "agent", "model", "ai_assisted", "ai_lines", "total_lines", "ratio", "message"}
+
+func writeAuthorshipCSV(records []storage.AuthorshipRecord) error {
+	w := csv.NewWriter(os.Stdout)
+	if err := w.Write(authorshipHeader); err != nil {
+		return err
+	}
+	for _, r := range records {
+		if err := w.Write(authorshipRow(r)); err != nil {
+			return err
+		}
+	}
+	w.Flush()
+	return w.Error()
+}
+
+func printAuthorshipMarkdown(records []storage.AuthorshipRecord) {
+	fmt.Println("| " + strings.Join(authorshipHeader, " | ") + " |")
+	fmt.Println("|" + strings.Repeat(" --- |", len(authorshipHeader)))
+	for _, r := range records {
+		row := authorshipRow(r)
+		for i, cell := range row {
+			row[i] = strings.ReplaceAll(cell, "|", `\|`)
+		}
+		fmt.Println("| " + strings.Join(row, " | ") + " |")
+	}
+}
+
+func printAuthorshipTable(records []storage.AuthorshipRecord) {
+	if len(records) == 0 {
+		fmt.Println("No conversations found.")
+		return
+	}
+
+	agentWidth := len("agent")
+	for _, r := range records {
+		agentWidth = max(agentWidth, len(r.Agent))
+	}
+
+	for _, r := range records {
+		lines, ratio := "-", "-"
+		if r.Authorship != nil {
+			lines = fmt.Sprintf("%d/%d", r.Authorship.AILines, r.Authorship.TotalLines)
+			ratio = formatRatio(r.Authorship.Ratio)
+		}
+		fmt.Printf("%s  %-*s  %9s  %4s  %s\n", r.CommitSHA[:7], agentWidth, r.Agent, lines, ratio, r.CommitMsg)
+	}
+}
+
+// formatSeconds formats a duration in seconds, e.g. "1h 5m" or "42s".
+func formatSeconds(seconds int64) string {
+	d := time.Duration(seconds) * time.Second
+	switch {
+	case d >= time.Hour:
+		return fmt.Sprintf("%dh %dm", int(d.Hours()), int(d.Minutes())%60)
+	case d >= time.Minute:
+		return fmt.Sprintf("%dm %ds", int(d.Minutes()), int(d.Seconds())%60)
+	}
+	return fmt.Sprintf("%ds", int(d.Seconds()))
+}
+
+// formatRatio formats a 0..1 ratio as a whole percentage.
+func formatRatio(r float64) string {
+	return fmt.Sprintf("%.0f%%", r*100)
+}
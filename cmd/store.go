@@ -7,23 +7,33 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/re-cinq/shift-log/internal/agent"
+	_ "github.com/re-cinq/shift-log/internal/agent/amazonq"  // register Amazon Q agent
 	_ "github.com/re-cinq/shift-log/internal/agent/claude"   // register Claude agent
 	_ "github.com/re-cinq/shift-log/internal/agent/codex"    // register Codex agent
 	_ "github.com/re-cinq/shift-log/internal/agent/copilot"  // register Copilot agent
 	_ "github.com/re-cinq/shift-log/internal/agent/gemini"   // register Gemini agent
+	_ "github.com/re-cinq/shift-log/internal/agent/goose"    // register Goose agent
 	_ "github.com/re-cinq/shift-log/internal/agent/opencode" // register OpenCode agent
+	_ "github.com/re-cinq/shift-log/internal/agent/windsurf" // register Windsurf agent
 	"github.com/re-cinq/shift-log/internal/cli"
 	"github.com/re-cinq/shift-log/internal/config"
 	"github.com/re-cinq/shift-log/internal/git"
+	"github.com/re-cinq/shift-log/internal/privacy"
 	"github.com/re-cinq/shift-log/internal/storage"
 	"github.com/spf13/cobra"
 )
 
 var (
 	manualFlag     bool
+	mergeFlag      bool
 	storeAgentFlag string
+	graceFlag      time.Duration
+	// privateFlag stores the conversation as private; store, attach,
+	// backfill and import set it.
+	privateFlag bool
 )
 
 var storeCmd = &cobra.Command{
@@ -36,13 +46,29 @@ as a Git Note if a git commit was detected.
 This command is designed to be called by a coding agent's hook system.
 
 With --manual flag, discovers the active session and stores its conversation
-for the most recent commit. Used by the post-commit git hook.`,
+for the most recent commit. Used by the post-commit git hook. A session
+counts as active for 5 minutes after its last activity; --grace, or
+"session_grace" in .shiftlog/config, widens that window. When no session is
+active and the commit is made from a terminal, it offers to store the most
+recent session instead.
+
+With --merge flag, records on a HEAD merge commit the conversations of the
+commits it merged, so they can be found from the mainline. Used by the
+post-merge git hook; the other modes do this too when HEAD is a merge.
+
+With --private, or when "visibility" is "private" in .shiftlog/config or
+the branch matches one of its "private_branches", the conversation is stored
+in ` + git.PrivateRef + `, which is never synced, until 'shiftlog publish'
+shares it.`,
 	RunE: runStore,
 }
 
 func init() {
 	storeCmd.Flags().BoolVar(&manualFlag, "manual", false, "Manual mode: discover session from active session file or recent sessions")
-	storeCmd.Flags().StringVar(&storeAgentFlag, "agent", "", "Coding agent (claude, codex, copilot, gemini, opencode). Defaults to configured agent.")
+	storeCmd.Flags().BoolVar(&mergeFlag, "merge", false, "Merge mode: record the conversations merged by a HEAD merge commit")
+	storeCmd.Flags().DurationVar(&graceFlag, "grace", 0, "Manual mode: store sessions active within this window, e.g. 12h (default 5m)")
+	storeCmd.Flags().BoolVar(&privateFlag, "private", false, "Keep the conversation in this clone until it is published")
+	storeCmd.Flags().StringVar(&storeAgentFlag, "agent", "", "Coding agent (amazonq, claude, codex, copilot, gemini, goose, opencode, windsurf). Defaults to the configured agent that sent the payload, or that has an active session.")
 	rootCmd.AddCommand(storeCmd)
 }
 
@@ -60,7 +86,41 @@ func resolveAgent(flagValue string) (agent.Agent, error) {
 	return agent.Get(agent.Name(name))
 }
 
+// configuredAgents returns the agents configured by init, in the order
+// given there, skipping unknown names.
+func configuredAgents() []agent.Agent {
+	cfg, err := config.Read()
+	if err != nil {
+		cfg = &config.Config{}
+	}
+	var agents []agent.Agent
+	for _, name := range cfg.AgentNames() {
+		if ag, err := agent.Get(agent.Name(name)); err == nil {
+			agents = append(agents, ag)
+		}
+	}
+	return agents
+}
+
+// resolveHookAgent resolves the agent that sent a hook payload: the --agent
+// flag if given, otherwise whichever configured agent recognises raw.
+func resolveHookAgent(flagValue string, raw []byte) (agent.Agent, error) {
+	if flagValue != "" {
+		return resolveAgent(flagValue)
+	}
+	agents := configuredAgents()
+	if len(agents) < 2 {
+		return resolveAgent("")
+	}
+	ag := agent.DetectHookAgent(raw, agents)
+	cli.LogDebug("store: detected %s hook payload", ag.Name())
+	return ag, nil
+}
+
 func runStore(cmd *cobra.Command, args []string) error {
+	if mergeFlag {
+		return runMergeStore()
+	}
 	if manualFlag {
 		return runManualStore()
 	}
@@ -71,18 +131,26 @@ func runStore(cmd *cobra.Command, args []string) error {
 func runHookStore() error {
 	cli.LogDebug("store: reading hook input from stdin")
 
-	ag, err := resolveAgent(storeAgentFlag)
+	// Read raw stdin
+	raw, err := io.ReadAll(os.Stdin)
 	if err != nil {
-		cli.LogDebug("store: unknown agent: %v", err)
+		cli.LogDebug("store: failed to read stdin: %v", err)
 		return nil
 	}
 
-	// Read raw stdin
-	raw, err := io.ReadAll(os.Stdin)
+	ag, err := resolveHookAgent(storeAgentFlag, raw)
 	if err != nil {
-		cli.LogDebug("store: failed to read stdin: %v", err)
+		cli.LogDebug("store: unknown agent: %v", err)
 		return nil
 	}
+	return storeHookPayload(ag, raw)
+}
+
+// storeHookPayload stores the conversation of an agent hook payload if it
+// reports a git commit. Like the rest of the hook mode, it only fails when
+// the conversation cannot be written.
+func storeHookPayload(ag agent.Agent, raw []byte) error {
+	cli.Trace("hook input", "agent", ag.Name(), "input", string(raw))
 
 	hookData, err := ag.ParseHookInput(raw)
 	if err != nil {
@@ -92,10 +160,14 @@ func runHookStore() error {
 
 	cli.LogDebug("store: tool=%s command=%q session=%s", hookData.ToolName, hookData.Command, hookData.SessionID)
 
-	// Check if this is a git commit command
+	// Check if this is a git commit command, or a merge that created a
+	// merge commit
 	if !ag.IsCommitCommand(hookData.ToolName, hookData.Command) {
-		cli.LogDebug("store: not a git commit command, skipping")
-		return nil
+		if !agent.IsGitMergeCommand(hookData.Command) || !git.IsInsideWorkTree() || !git.IsHeadNewMerge() {
+			cli.LogDebug("store: not a git commit command, skipping")
+			return nil
+		}
+		cli.LogDebug("store: git merge created a merge commit")
 	}
 
 	// Verify we're in a git repository
@@ -104,7 +176,10 @@ func runHookStore() error {
 		return nil
 	}
 
-	return storeConversation(ag, hookData.SessionID, hookData.TranscriptPath, hookData.TranscriptData)
+	recordMergedConversations()
+	defer foldCheckpoints()
+
+	return storeConversation(ag, hookData.SessionID, hookData.TranscriptPath, hookData.TranscriptData, storage.TriggerAgentHook)
 }
 
 // runManualStore handles the manual (post-commit hook) mode.
@@ -122,65 +197,431 @@ func runManualStore() error {
 		return nil
 	}
 
+	// A merge concluded with git commit, e.g. after resolving conflicts
+	recordMergedConversations()
+	defer foldCheckpoints()
+
 	cli.LogDebug("store: discovering active session in %s", projectPath)
 
-	ag, err := resolveAgent(storeAgentFlag)
-	if err != nil {
-		cli.LogDebug("store: unknown agent: %v", err)
+	agents := configuredAgents()
+	if storeAgentFlag != "" {
+		ag, err := resolveAgent(storeAgentFlag)
+		if err != nil {
+			cli.LogDebug("store: unknown agent: %v", err)
+			return nil
+		}
+		agents = []agent.Agent{ag}
+	}
+	if len(agents) == 0 {
+		cli.LogDebug("store: no known agent configured")
 		return nil
 	}
 
-	// Use the agent's own session discovery (each agent knows where its sessions live)
-	agentSession, err := ag.DiscoverSession(projectPath)
-	if err != nil {
-		cli.LogDebug("store: session discovery error: %v", err)
-		return nil
+	applyGraceWindow()
+
+	// Use each agent's own session discovery (each agent knows where its
+	// sessions live), taking the first agent with an active session
+	ag := agents[0]
+	var agentSession *agent.SessionInfo
+	for _, candidate := range agents {
+		found, err := candidate.DiscoverSession(projectPath)
+		if err != nil {
+			cli.LogDebug("store: %s session discovery error: %v", candidate.Name(), err)
+			continue
+		}
+		if found != nil {
+			ag, agentSession = candidate, found
+			break
+		}
 	}
 
 	if agentSession == nil {
 		cli.LogDebug("store: no active session found")
-		return nil
+		if agentSession = offerLastSession(ag, projectPath); agentSession == nil {
+			return nil
+		}
 	}
 
 	cli.LogDebug("store: found session %s", agentSession.SessionID)
-	return storeConversation(ag, agentSession.SessionID, agentSession.TranscriptPath, agentSession.TranscriptData)
+	return storeConversation(ag, agentSession.SessionID, agentSession.TranscriptPath, agentSession.TranscriptData, storage.TriggerPostCommit)
+}
+
+// foldCheckpoints moves the checkpoints taken by shiftlog watch before HEAD
+// was committed into its conversation note. Like recordMergedConversations,
+// failures are logged, never returned.
+func foldCheckpoints() {
+	if captureDisabled() != "" {
+		return
+	}
+	head, err := git.GetHeadCommit()
+	if err != nil {
+		return
+	}
+	folded, err := storage.FoldCheckpoints(head)
+	if err != nil {
+		cli.LogWarning("could not fold checkpoints into %s: %v", head[:8], err)
+		return
+	}
+	for _, sc := range folded {
+		cli.LogInfo("stored checkpoint of session %s for commit %s", sc.SessionID, head[:8])
+	}
+}
+
+// applyGraceWindow widens session discovery's recency timeout to the window
+// given by --grace or the session_grace config.
+func applyGraceWindow() {
+	grace := graceFlag
+	if grace == 0 {
+		cfg, err := config.Read()
+		if err != nil {
+			return
+		}
+		if grace, err = cfg.GraceWindow(); err != nil {
+			cli.LogWarning("%v", err)
+			return
+		}
+	}
+	if grace > agent.RecentSessionTimeout {
+		agent.RecentSessionTimeout = grace
+		cli.LogDebug("store: session grace window is %s", grace)
+	}
+}
+
+// offerLastSession asks whether to store the agent's most recent session of
+// the project when none was recent enough to be found. It returns nil when
+// there is no session, no terminal to ask on, or the user declines.
+func offerLastSession(ag agent.Agent, projectPath string) *agent.SessionInfo {
+	if captureDisabled() != "" {
+		return nil
+	}
+	sessions, err := agent.ListSessions(ag, projectPath)
+	if err != nil || len(sessions) == 0 {
+		return nil
+	}
+	last := sessions[0]
+	if !cli.Confirm(fmt.Sprintf("shiftlog: attach %s session %s from %s?", ag.DisplayName(), last.SessionID, formatAge(last.StartedAt))) {
+		return nil
+	}
+	return &last
+}
+
+// runMergeStore handles the merge (post-merge hook) mode.
+func runMergeStore() error {
+	cli.LogDebug("store: merge mode")
+
+	if !git.IsInsideWorkTree() {
+		cli.LogDebug("store: not inside a git repository, skipping")
+		return nil
+	}
+	recordMergedConversations()
+	return nil
+}
+
+// recordMergedConversations records the conversations merged by HEAD when it
+// is a merge commit. Failures are logged, never returned, so that they do not
+// get in the way of the merge or of storing the conversation.
+func recordMergedConversations() {
+	head, err := git.GetHeadCommit()
+	if err != nil || !git.IsMergeCommit(head) {
+		return
+	}
+
+	merged, err := storage.RecordMergedConversations(head)
+	if err != nil {
+		cli.LogWarning("could not record merged conversations for %s: %v", head[:8], err)
+		return
+	}
+	cli.LogDebug("store: recorded %d merged conversation(s) for %s", len(merged), head[:8])
+	if len(merged) > 0 {
+		cli.RecordArtifact("merge", head)
+	}
 }
 
 // storeConversation stores a conversation for the HEAD commit with duplicate detection.
 // When transcriptData is non-empty, it is used directly instead of reading from transcriptPath.
-func storeConversation(ag agent.Agent, sessionID, transcriptPath string, transcriptData []byte) error {
+// The trigger is recorded in the conversation's provenance. While capture is
+// disabled, it records the conversation as omitted instead. The
+// conversations queued by earlier failed stores are retried first.
+func storeConversation(ag agent.Agent, sessionID, transcriptPath string, transcriptData []byte, trigger string) error {
 	headCommit, err := git.GetHeadCommit()
 	if err != nil {
 		return fmt.Errorf("failed to get HEAD commit: %w", err)
 	}
 
 	cli.LogDebug("store: HEAD commit is %s", headCommit[:8])
+	retryPendingStores()
+	if reason := captureDisabled(); reason != "" {
+		return omitConversation(headCommit, ag, trigger, reason)
+	}
+	return storeConversationFor(headCommit, ag, sessionID, transcriptPath, transcriptData, trigger, false)
+}
+
+// storeConversationFor stores a conversation for a commit like
+// storeConversation. With replace, the conversations already stored for the
+// commit are dropped instead of kept.
+func storeConversationFor(headCommit string, ag agent.Agent, sessionID, transcriptPath string, transcriptData []byte, trigger string, replace bool) error {
+	return storeBuiltConversation(headCommit, ag, sessionID, replace, func() (*storage.StoredConversation, []agent.TranscriptEntry, error) {
+		return buildStoredConversation(headCommit, ag, sessionID, transcriptPath, transcriptData, trigger)
+	})
+}
 
-	// Check for existing note (duplicate detection)
-	if git.HasNote(headCommit) {
+// storeBuiltConversation stores the conversation build creates for a commit,
+// unless the session is already stored on it, with the summary, signature
+// and visibility the config asks for.
+func storeBuiltConversation(headCommit string, ag agent.Agent, sessionID string, replace bool, build func() (*storage.StoredConversation, []agent.TranscriptEntry, error)) error {
+	// Check for existing notes (duplicate detection). Conversations of other
+	// agent sessions are kept, and this one is stored after them.
+	existing, err := storage.GetStoredConversations(headCommit)
+	if err != nil {
+		cli.LogDebug("store: could not read existing note, will overwrite it: %v", err)
+		existing = nil
+	}
+	existingPrivate, err := storage.GetPrivateConversations(headCommit)
+	if err != nil {
+		cli.LogDebug("store: could not read existing private note, will overwrite it: %v", err)
+		existingPrivate = nil
+	}
+	if replace {
+		existing, existingPrivate = nil, nil
+	}
+	if existing != nil || existingPrivate != nil {
 		cli.LogDebug("store: existing note found for %s, checking for duplicate", headCommit[:8])
-		existingNote, err := git.GetNote(headCommit)
-		if err == nil {
-			existing, err := storage.UnmarshalStoredConversation(existingNote)
-			if err == nil && existing.SessionID == sessionID {
-				cli.LogInfo("conversation already stored for commit %s", headCommit[:8])
-				return nil
-			}
-			cli.LogDebug("store: different session, will overwrite existing note")
+		session := &storage.StoredConversation{SessionID: sessionID, Agent: string(ag.Name())}
+		if storage.IndexOfSession(existing, session) >= 0 || storage.IndexOfSession(existingPrivate, session) >= 0 {
+			cli.LogInfo("conversation already stored for commit %s", headCommit[:8])
+			return nil
 		}
+		cli.LogDebug("store: different session, will add it to the existing note")
+	}
+
+	stored, increment, err := build()
+	if err != nil {
+		return err
 	}
 
+	cfg, err := config.Read()
+	if err != nil {
+		cfg = &config.Config{}
+	}
+	if cfg.Summary == config.SummaryAgent || cfg.Summary == config.SummaryHeuristic {
+		// The agent's summary replaces this one once the note is written
+		stored.Summary = generateSummary(increment, ag.ToolAliases(), string(ag.Name()), config.SummaryHeuristic)
+		cli.LogDebug("store: summary: %s", stored.Summary)
+	}
+
+	if err := scrubOnStore(headCommit, stored); err != nil {
+		return err
+	}
+
+	if cfg.Sign {
+		// An unsigned conversation is better than none
+		if err := stored.Sign(); err != nil {
+			cli.LogWarning("could not sign conversation for %s: %v", headCommit[:8], err)
+		} else {
+			cli.LogDebug("store: signed with %s key", stored.Signature.Format)
+		}
+	}
+
+	private := privateFlag || cfg.IsPrivate(stored.GitBranch)
+	if private {
+		stored.Visibility = config.VisibilityPrivate
+	}
+	mode := addToNote
+	if replace {
+		mode = replaceNote
+	}
+	if err := writeStoredConversation(headCommit, stored, private, mode); err != nil {
+		// Keep the conversation for a later store or shiftlog flush to
+		// attach, rather than lose it to a transient failure
+		if _, queueErr := storage.QueuePendingStore(headCommit, stored, private, err); queueErr != nil {
+			cli.LogDebug("store: could not queue the conversation: %v", queueErr)
+			return err
+		}
+		cli.LogWarning("%v; queued the conversation, 'shiftlog flush' retries it", err)
+		return nil
+	}
+	if cfg.Summary == config.SummaryAgent {
+		saveAgentSummary(headCommit, stored, increment, ag, private)
+	}
+	return nil
+}
+
+// saveAgentSummary replaces the summary of a conversation just stored with
+// one written by its coding agent. It runs once the note is written, so
+// that a slow or failing agent cannot hold up or lose the conversation; the
+// heuristic summary stays when the agent cannot summarise.
+func saveAgentSummary(headCommit string, stored *storage.StoredConversation, increment []agent.TranscriptEntry, ag agent.Agent, private bool) {
+	summary, err := agentSummary(increment, string(ag.Name()))
+	if err != nil {
+		cli.LogWarning("agent summary failed, keeping the heuristic summary: %v", err)
+		return
+	}
+	policy, err := privacy.LoadPolicy()
+	if err != nil {
+		cli.LogWarning("could not read the privacy policy, keeping the heuristic summary: %v", err)
+		return
+	}
+	if policy.ScrubOnStore {
+		scrubber, err := privacy.NewScrubber(policy)
+		if err != nil {
+			cli.LogWarning("invalid %s, keeping the heuristic summary: %v", privacy.PolicyFile, err)
+			return
+		}
+		summary = scrubber.Text(summary)
+	}
+	if err := storage.SetStoredSummary(headCommit, stored, summary, private); err != nil {
+		cli.LogWarning("could not save the agent summary of %s: %v", headCommit[:8], err)
+		return
+	}
+	cli.LogDebug("store: agent summary: %s", summary)
+}
+
+// noteMode is how writeStoredConversation treats the conversations stored
+// on a commit already.
+type noteMode int
+
+const (
+	// addToNote keeps them, overwriting a note that cannot be parsed.
+	addToNote noteMode = iota
+	// addToParsedNote keeps them, failing on a note that cannot be parsed,
+	// so that a retry does not overwrite a note it cannot read.
+	addToParsedNote
+	// replaceNote replaces them.
+	replaceNote
+)
+
+// writeStoredConversation adds a conversation ready to be stored to the
+// conversations of a commit, in its conversation note or, when private, in
+// its private note, unless its session is stored there already. With
+// replaceNote, it replaces those of that note instead.
+func writeStoredConversation(headCommit string, stored *storage.StoredConversation, private bool, mode noteMode) error {
+	strict := mode == addToParsedNote
+	if private {
+		added := true
+		var err error
+		if mode == replaceNote {
+			err = storage.SavePrivateConversations(headCommit, []*storage.StoredConversation{stored})
+		} else {
+			added, err = storage.AddPrivateConversation(headCommit, stored, strict)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to store private conversation: %w", err)
+		}
+		if !added {
+			cli.LogInfo("conversation already stored for commit %s", headCommit[:8])
+			return nil
+		}
+		cli.LogInfo("stored private conversation for commit %s; 'shiftlog publish %s' shares it", headCommit[:8], headCommit[:8])
+		cli.RecordArtifact("private-note", headCommit)
+		return nil
+	}
+
+	if err := stored.StoreTranscriptApart(); err != nil {
+		return err
+	}
+	if stored.TranscriptBlob != "" {
+		cli.LogDebug("store: transcript stored apart as blob %s", stored.TranscriptBlob)
+	}
+	if stored.IsChunked() {
+		cli.LogDebug("store: transcript stored apart in %d chunks", len(stored.TranscriptChunks))
+	}
+
+	backend, err := storage.ActiveBackend()
+	if err != nil {
+		return err
+	}
+	if mode == replaceNote {
+		noteContent, err := storage.MarshalStoredConversations([]*storage.StoredConversation{stored})
+		if err != nil {
+			return fmt.Errorf("failed to marshal conversation: %w", err)
+		}
+		cli.LogDebug("store: note size is %d bytes", len(noteContent))
+		if err := backend.Write(headCommit, noteContent); err != nil {
+			return fmt.Errorf("failed to store conversation in %s: %w", backend.Name(), err)
+		}
+	} else {
+		added, err := storage.AddStoredConversation(headCommit, stored, strict)
+		if err != nil {
+			return fmt.Errorf("failed to store conversation in %s: %w", backend.Name(), err)
+		}
+		if !added {
+			// Stored by another hook since the note was read
+			cli.LogInfo("conversation already stored for commit %s", headCommit[:8])
+			return nil
+		}
+	}
+
+	cli.LogInfo("stored conversation for commit %s", headCommit[:8])
+	cli.RecordArtifact("note", headCommit)
+	if err := storage.UpdateIndex(); err != nil {
+		cli.LogDebug("store: could not update the index: %v", err)
+	}
+	return nil
+}
+
+// scrubOnStore scrubs personal data from the transcript and summary of a
+// conversation when the privacy policy asks for it on store, and records
+// what was found in the audit log. A policy that cannot be read stops the
+// conversation from being stored, rather than storing what it would scrub.
+func scrubOnStore(headCommit string, stored *storage.StoredConversation) error {
+	policy, err := privacy.LoadPolicy()
+	if err != nil {
+		return err
+	}
+	if !policy.ScrubOnStore {
+		return nil
+	}
+	scrubber, err := privacy.NewScrubber(policy)
+	if err != nil {
+		return fmt.Errorf("invalid %s: %w", privacy.PolicyFile, err)
+	}
+
+	transcript, err := stored.GetTranscript()
+	if err != nil {
+		return fmt.Errorf("could not read transcript to scrub: %w", err)
+	}
+	scrubbed := scrubber.Transcript(transcript)
+	stored.Summary = scrubber.Text(stored.Summary)
+	findings := scrubber.Report().Findings()
+	if len(findings) == 0 {
+		return nil
+	}
+	encoded, err := storage.CompressAndEncode(scrubbed)
+	if err != nil {
+		return fmt.Errorf("could not encode scrubbed transcript: %w", err)
+	}
+	stored.Transcript = encoded
+	stored.Checksum = storage.Checksum(scrubbed)
+
+	cli.LogInfo("scrubbed personal data from the conversation: %s", scrubber.Report())
+	err = privacy.AppendAudit(privacy.AuditEntry{
+		Time:      time.Now().UTC().Format(time.RFC3339),
+		Commit:    headCommit,
+		SessionID: stored.SessionID,
+		Findings:  findings,
+	})
+	if err != nil {
+		cli.LogWarning("could not write privacy audit log: %v", err)
+	}
+	return nil
+}
+
+// buildStoredConversation creates the conversation that store would write
+// for a commit, without writing it. It also returns the transcript entries
+// added since the session was last stored on a parent commit.
+func buildStoredConversation(headCommit string, ag agent.Agent, sessionID, transcriptPath string, transcriptData []byte, trigger string) (*storage.StoredConversation, []agent.TranscriptEntry, error) {
 	// Use inline transcript data if provided, otherwise read from path
 	if len(transcriptData) == 0 {
 		if transcriptPath == "" {
-			return fmt.Errorf("no transcript path or inline data provided")
+			return nil, nil, fmt.Errorf("no transcript path or inline data provided")
 		}
 
 		cli.LogDebug("store: reading transcript from %s", transcriptPath)
 
+		var err error
 		transcriptData, err = readTranscriptData(transcriptPath)
 		if err != nil {
-			return fmt.Errorf("failed to read transcript: %w", err)
+			return nil, nil, fmt.Errorf("failed to read transcript: %w", err)
 		}
 	} else {
 		cli.LogDebug("store: using inline transcript data (%d bytes)", len(transcriptData))
@@ -188,16 +629,26 @@ func storeConversation(ag agent.Agent, sessionID, transcriptPath string, transcr
 
 	cli.LogDebug("store: transcript size is %d bytes", len(transcriptData))
 
+	projectPath, _ := git.GetRepoRoot()
+	branch, _ := git.GetCurrentBranch()
+	return conversationFromTranscript(headCommit, ag, sessionID, transcriptData, trigger, projectPath, branch)
+}
+
+// conversationFromTranscript creates the conversation for a commit from the
+// session's transcript, made in projectPath on branch.
+func conversationFromTranscript(headCommit string, ag agent.Agent, sessionID string, transcriptData []byte, trigger, projectPath, branch string) (*storage.StoredConversation, []agent.TranscriptEntry, error) {
 	transcript, err := ag.ParseTranscript(strings.NewReader(string(transcriptData)))
 	if err != nil {
-		return fmt.Errorf("failed to parse transcript: %w", err)
+		return nil, nil, fmt.Errorf("failed to parse transcript: %w", err)
 	}
 
-	projectPath, _ := git.GetRepoRoot()
-	branch, _ := git.GetCurrentBranch()
-
 	cli.LogDebug("store: project=%s branch=%s messages=%d", projectPath, branch, transcript.MessageCount())
 
+	transcriptData = omitLargeAttachments(transcriptData)
+	transcriptData, thinking, err := omitThinking(transcriptData)
+	if err != nil {
+		return nil, nil, err
+	}
 	stored, err := storage.NewStoredConversation(
 		sessionID,
 		projectPath,
@@ -206,37 +657,122 @@ func storeConversation(ag agent.Agent, sessionID, transcriptPath string, transcr
 		transcriptData,
 	)
 	if err != nil {
-		return fmt.Errorf("failed to create stored conversation: %w", err)
+		return nil, nil, fmt.Errorf("failed to create stored conversation: %w", err)
 	}
 
 	stored.Agent = string(ag.Name())
 	stored.Model = transcript.Model
-
-	// Populate effort metrics from transcript
+	stored.Thinking = thinking
+	stored.Provenance = buildProvenance(ag, transcript, trigger)
+	cli.LogDebug("store: provenance %s %s via %s", stored.Provenance.Agent, stored.Provenance.AgentVersion, trigger)
+	stored.Issues = commitIssues(headCommit)
+
+	// Record the files edited since the previous commit in this session
+	_, lastUUID := storage.FindParentConversationBoundary(headCommit, sessionID)
+	increment := transcript.GetEntriesSince(lastUUID)
+
+	// Populate effort metrics from transcript, timing the work since the
+	// previous commit
+	duration, toolTime := agent.Durations(increment)
 	stored.Effort = &storage.Effort{
 		Turns:                    transcript.Turns,
 		InputTokens:              transcript.Usage.InputTokens,
 		OutputTokens:             transcript.Usage.OutputTokens,
 		CacheCreationInputTokens: transcript.Usage.CacheCreationInputTokens,
 		CacheReadInputTokens:     transcript.Usage.CacheReadInputTokens,
+		DurationSeconds:          int64(duration.Seconds()),
+		ToolSeconds:              int64(toolTime.Seconds()),
 	}
-	if stored.Effort.Turns == 0 && stored.Effort.TotalTokens() == 0 {
+	if stored.Effort.Turns == 0 && stored.Effort.TotalTokens() == 0 && stored.Effort.DurationSeconds == 0 {
 		stored.Effort = nil
 	}
+	stored.FilesTouched = storage.FilesTouched(increment, ag.ToolAliases(), projectPath)
+	cli.LogDebug("store: files touched: %v", stored.FilesTouched)
 
-	noteContent, err := stored.Marshal()
-	if err != nil {
-		return fmt.Errorf("failed to marshal conversation: %w", err)
+	// Attribute the commit's added lines to the agent or to manual edits
+	if authorship, err := storage.ComputeAuthorship(headCommit, increment, ag.ToolAliases(), projectPath); err != nil {
+		cli.LogWarning("could not compute authorship for %s: %v", headCommit[:8], err)
+	} else {
+		stored.Authorship = authorship
+		stored.AIAssisted = authorship.IsAIAssisted()
+		cli.LogDebug("store: authorship %d/%d lines", authorship.AILines, authorship.TotalLines)
 	}
+	return stored, increment, nil
+}
 
-	cli.LogDebug("store: note size is %d bytes", len(noteContent))
+// omitLargeAttachments drops the attachments larger than the configured
+// attachment_max_bytes from transcript data.
+func omitLargeAttachments(transcriptData []byte) []byte {
+	cfg, err := config.Read()
+	if err != nil || cfg.AttachmentMaxBytes <= 0 {
+		return transcriptData
+	}
+	transcriptData, omitted := storage.OmitLargeAttachments(transcriptData, cfg.AttachmentMaxBytes)
+	if omitted > 0 {
+		cli.LogDebug("store: omitted %d attachments larger than %d bytes", omitted, cfg.AttachmentMaxBytes)
+	}
+	return transcriptData
+}
 
-	if err := git.AddNote(headCommit, noteContent); err != nil {
-		return fmt.Errorf("failed to add git note: %w", err)
+// omitThinking leaves the models' thinking out of transcript data as the
+// configured thinking policy says. It returns the policy when it omitted
+// any, empty otherwise. A policy that is not valid stops the conversation
+// from being stored, rather than storing the thinking it would omit.
+func omitThinking(transcriptData []byte) ([]byte, string, error) {
+	cfg, err := config.Read()
+	if err != nil || cfg.Thinking == "" {
+		return transcriptData, "", nil
+	}
+	transcriptData, omitted, err := storage.OmitThinking(transcriptData, cfg.Thinking)
+	if err != nil {
+		return nil, "", err
+	}
+	if omitted == 0 {
+		return transcriptData, "", nil
 	}
+	cli.LogDebug("store: thinking of %d blocks omitted (%s)", omitted, cfg.Thinking)
+	return transcriptData, cfg.Thinking, nil
+}
 
-	cli.LogInfo("stored conversation for commit %s", headCommit[:8])
-	return nil
+// commitIssues returns the issue keys of the commit's message, as matched
+// by the configured issue_pattern.
+func commitIssues(commitSHA string) []string {
+	cfg, err := config.Read()
+	if err != nil {
+		return nil
+	}
+	pattern, err := cfg.IssueRegexp()
+	if err != nil {
+		cli.LogWarning("%v", err)
+		return nil
+	}
+	message, err := git.CommitMessage(commitSHA)
+	if err != nil {
+		return nil
+	}
+	issues := storage.IssueKeys(message, pattern)
+	if len(issues) > 0 {
+		cli.LogDebug("store: issues %v", issues)
+	}
+	return issues
+}
+
+// buildProvenance records the agent, its version and the models of the
+// transcript. The version recorded in the transcript is preferred over
+// asking the installed CLI, which may have been upgraded since.
+func buildProvenance(ag agent.Agent, transcript *agent.Transcript, trigger string) *storage.Provenance {
+	p := &storage.Provenance{
+		Agent:        string(ag.Name()),
+		AgentVersion: transcript.AgentVersion,
+		Trigger:      trigger,
+	}
+	if p.AgentVersion == "" {
+		p.AgentVersion = agent.DetectVersion(ag)
+	}
+	for _, c := range transcript.ModelCounts() {
+		p.Models = append(p.Models, storage.ModelUsage{Model: c.Model, Messages: c.Messages})
+	}
+	return p
 }
 
 // readTranscriptData reads transcript data from a file or directory.
@@ -0,0 +1,131 @@
+package cmd
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/re-cinq/shift-log/internal/cli"
+	"github.com/re-cinq/shift-log/internal/config"
+	"github.com/re-cinq/shift-log/internal/git"
+	"github.com/re-cinq/shift-log/internal/storage"
+	"github.com/spf13/cobra"
+)
+
+var (
+	stripThinkingHash   bool
+	stripThinkingDryRun bool
+)
+
+var stripThinkingCmd = &cobra.Command{
+	Use:     "strip-thinking",
+	Short:   "Remove the models' thinking from stored conversations",
+	GroupID: "human",
+	Long: `Rewrites every stored conversation whose transcript holds the thinking of
+its models, dropping the text of each thinking block, or with --hash
+replacing it with its SHA-256, as the "thinking" policy in .shiftlog/config
+does for new conversations. Viewers show the blocks as omitted.
+
+It rewrites Claude Code and Goose thinking blocks, Claude Code's redacted
+thinking and Codex reasoning, along with their signatures and encrypted
+content. Rewritten conversations lose their own signature, which covers the
+old transcript.
+
+Only the current version of each note is rewritten: the old versions stay
+in the history of the notes ref, here and in the remotes it was pushed to,
+until that history is rewritten.
+
+Examples:
+  shiftlog strip-thinking --dry-run
+  shiftlog strip-thinking --hash`,
+	Args: cobra.NoArgs,
+	RunE: runStripThinking,
+}
+
+func init() {
+	stripThinkingCmd.Flags().BoolVar(&stripThinkingHash, "hash", false, "keep the SHA-256 of each thinking text instead of dropping it")
+	stripThinkingCmd.Flags().BoolVar(&stripThinkingDryRun, "dry-run", false, "report what would be rewritten without changing any note")
+	rootCmd.AddCommand(stripThinkingCmd)
+}
+
+func runStripThinking(cmd *cobra.Command, args []string) error {
+	if err := git.RequireGitRepo(); err != nil {
+		return err
+	}
+	policy := config.ThinkingStrip
+	if stripThinkingHash {
+		policy = config.ThinkingHash
+	}
+
+	contents, err := storage.ReadAllConversations()
+	if err != nil {
+		return fmt.Errorf("could not read conversations: %w", err)
+	}
+	commits := make([]string, 0, len(contents))
+	for commit := range contents {
+		commits = append(commits, commit)
+	}
+	sort.Strings(commits)
+
+	b, err := storage.ActiveBackend()
+	if err != nil {
+		return err
+	}
+	rewritten, blocks := 0, 0
+	for _, commit := range commits {
+		conversations, err := storage.UnmarshalStoredConversations(contents[commit])
+		if err != nil {
+			cli.LogWarning("skipping %s: %v", commit[:7], err)
+			continue
+		}
+		changed := false
+		for _, sc := range conversations {
+			transcript, err := sc.GetTranscript()
+			if err != nil {
+				cli.LogWarning("skipping session %s on %s: %v", sc.SessionID, commit[:7], err)
+				continue
+			}
+			omitted, n, err := storage.OmitThinking(transcript, policy)
+			if err != nil {
+				return err
+			}
+			if n == 0 {
+				continue
+			}
+			blocks += n
+			rewritten++
+			if stripThinkingDryRun {
+				continue
+			}
+			if err := sc.SetTranscript(omitted); err != nil {
+				return err
+			}
+			sc.Thinking = policy
+			sc.Signature = nil
+			changed = true
+		}
+		if !changed {
+			continue
+		}
+		content, err := storage.MarshalStoredConversations(conversations)
+		if err != nil {
+			return fmt.Errorf("failed to marshal conversation: %w", err)
+		}
+		if err := b.Write(commit, content); err != nil {
+			return fmt.Errorf("could not rewrite the note of %s: %w", commit[:7], err)
+		}
+		cli.RecordArtifact("note", commit)
+	}
+
+	verb := "Stripped"
+	if stripThinkingHash {
+		verb = "Hashed"
+	}
+	if stripThinkingDryRun {
+		verb = "Would strip"
+		if stripThinkingHash {
+			verb = "Would hash"
+		}
+	}
+	fmt.Printf("%s the thinking of %d blocks in %d conversations\n", verb, blocks, rewritten)
+	return nil
+}
@@ -0,0 +1,204 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/re-cinq/shift-log/internal/agent"
+	"github.com/re-cinq/shift-log/internal/cli"
+	"github.com/re-cinq/shift-log/internal/config"
+	"github.com/re-cinq/shift-log/internal/git"
+	"github.com/re-cinq/shift-log/internal/storage"
+	"github.com/spf13/cobra"
+)
+
+var suggestModeFlag string
+
+var suggestCommitMsgCmd = &cobra.Command{
+	Use:     "suggest-commit-msg <message-file> [source] [commit]",
+	Short:   "Suggest a commit message from the active conversation",
+	GroupID: "hooks",
+	Long: `Adds a commit message suggestion, written from the active coding agent
+session, as a comment to the commit message file.
+
+This command is designed to be called by the prepare-commit-msg git hook,
+which 'shiftlog init --suggest-commit-msg' installs. The suggestion covers the
+conversation since the session was last stored and is written by the coding
+agent ("agent") or taken from the last user request ("heuristic"), as set by
+--mode or "commit_suggestion" in .shiftlog/config.
+
+Nothing is suggested when the message comes from -m, -F, a merge, a squash or
+an existing commit, or when no session is active. Failures never stop the
+commit.`,
+	Args: cobra.RangeArgs(1, 3),
+	RunE: runSuggestCommitMsg,
+}
+
+func init() {
+	suggestCommitMsgCmd.Flags().StringVar(&suggestModeFlag, "mode", "", "how to suggest: agent or heuristic (default from config, else heuristic)")
+	rootCmd.AddCommand(suggestCommitMsgCmd)
+}
+
+func runSuggestCommitMsg(cmd *cobra.Command, args []string) error {
+	messageFile := args[0]
+	source := ""
+	if len(args) > 1 {
+		source = args[1]
+	}
+
+	// The message was given or is being reused: leave it alone
+	switch source {
+	case "message", "merge", "squash", "commit":
+		cli.LogDebug("suggest-commit-msg: message source is %s, skipping", source)
+		return nil
+	}
+
+	mode := suggestModeFlag
+	if mode == "" {
+		if cfg, err := config.Read(); err == nil {
+			mode = cfg.CommitSuggestion
+		}
+	}
+	if mode != config.SummaryAgent {
+		mode = config.SummaryHeuristic
+	}
+
+	if !git.IsInsideWorkTree() {
+		cli.LogDebug("suggest-commit-msg: not inside a git repository, skipping")
+		return nil
+	}
+	projectPath, err := git.GetRepoRoot()
+	if err != nil {
+		cli.LogDebug("suggest-commit-msg: failed to get repo root: %v", err)
+		return nil
+	}
+
+	ag, err := resolveAgent("")
+	if err != nil {
+		cli.LogDebug("suggest-commit-msg: unknown agent: %v", err)
+		return nil
+	}
+
+	applyGraceWindow()
+	session, err := ag.DiscoverSession(projectPath)
+	if err != nil || session == nil {
+		cli.LogDebug("suggest-commit-msg: no active session found")
+		return nil
+	}
+
+	suggestion := suggestCommitMessage(ag, session, mode)
+	if suggestion == "" {
+		cli.LogDebug("suggest-commit-msg: session %s has nothing to suggest", session.SessionID)
+		return nil
+	}
+
+	data, err := os.ReadFile(messageFile)
+	if err != nil {
+		cli.LogWarning("could not read commit message file: %v", err)
+		return nil
+	}
+	header := fmt.Sprintf("Suggested by shiftlog from %s session %s:", ag.DisplayName(), session.SessionID)
+	content := insertCommitSuggestion(string(data), header, suggestion, commentChar())
+	if err := os.WriteFile(messageFile, []byte(content), 0644); err != nil {
+		cli.LogWarning("could not write commit message file: %v", err)
+	}
+	return nil
+}
+
+// suggestCommitMessage suggests a commit message for the part of the
+// session's conversation not yet stored on HEAD. In SummaryAgent mode the
+// agent writes it, falling back to the heuristic if it cannot.
+func suggestCommitMessage(ag agent.Agent, session *agent.SessionInfo, mode string) string {
+	data, err := readSessionTranscript(session)
+	if err != nil {
+		cli.LogDebug("suggest-commit-msg: %v", err)
+		return ""
+	}
+	transcript, err := ag.ParseTranscript(strings.NewReader(string(data)))
+	if err != nil {
+		cli.LogDebug("suggest-commit-msg: failed to parse transcript: %v", err)
+		return ""
+	}
+	entries := transcript.GetEntriesSince(storedEntryUUID(session.SessionID))
+
+	if mode == config.SummaryAgent {
+		message, err := agentCommitMessage(ag, entries)
+		if err == nil {
+			return message
+		}
+		cli.LogWarning("agent commit message failed, using heuristic suggestion: %v", err)
+	}
+	return agent.HeuristicCommitMessage(entries)
+}
+
+func agentCommitMessage(ag agent.Agent, entries []agent.TranscriptEntry) (string, error) {
+	prompt := agent.BuildCommitMessagePrompt(entries, agent.DefaultMaxPromptChars)
+	if prompt == "" {
+		return "", fmt.Errorf("transcript has no summarisable content")
+	}
+	summariser, ok := ag.(agent.Summariser)
+	if !ok {
+		return "", fmt.Errorf("agent %q does not support summarisation", ag.Name())
+	}
+	return runSummariser(summariser, prompt)
+}
+
+// storedEntryUUID returns the last transcript entry of the session stored on
+// HEAD, so the suggestion only covers the work since that commit. Returns ""
+// when HEAD has no conversation of the session.
+func storedEntryUUID(sessionID string) string {
+	head, err := git.GetHeadCommit()
+	if err != nil {
+		return ""
+	}
+	conversations, err := storage.GetStoredConversations(head)
+	if err != nil {
+		return ""
+	}
+	for _, sc := range conversations {
+		if sc.SessionID != sessionID {
+			continue
+		}
+		transcript, err := sc.ParseTranscript()
+		if err != nil {
+			return ""
+		}
+		return transcript.GetLastEntryUUID()
+	}
+	return ""
+}
+
+// commentChar returns the character git strips comment lines with.
+func commentChar() string {
+	c, err := git.RunGitCommand("config", "core.commentChar")
+	if err != nil || c == "" || c == "auto" {
+		return "#"
+	}
+	return c
+}
+
+// insertCommitSuggestion adds the suggestion as comment lines before git's
+// own comments in a commit message, so that it is visible but dropped unless
+// the user uncomments it.
+func insertCommitSuggestion(message, header, suggestion, comment string) string {
+	var b strings.Builder
+	b.WriteString(comment + " " + header + "\n")
+	b.WriteString(comment + "\n")
+	for _, line := range strings.Split(strings.TrimSpace(suggestion), "\n") {
+		b.WriteString(strings.TrimRight(comment+" "+line, " ") + "\n")
+	}
+	b.WriteString(comment + "\n")
+	block := b.String()
+
+	lines := strings.SplitAfter(message, "\n")
+	for i, line := range lines {
+		if strings.HasPrefix(line, comment) {
+			return strings.Join(lines[:i], "") + block + strings.Join(lines[i:], "")
+		}
+	}
+	if message != "" && !strings.HasSuffix(message, "\n") {
+		message += "\n"
+	}
+	return message + block
+}
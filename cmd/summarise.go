@@ -9,12 +9,16 @@ import (
 	"time"
 
 	"github.com/re-cinq/shift-log/internal/agent"
+	_ "github.com/re-cinq/shift-log/internal/agent/amazonq"  // register Amazon Q agent
 	_ "github.com/re-cinq/shift-log/internal/agent/claude"   // register Claude agent
 	_ "github.com/re-cinq/shift-log/internal/agent/codex"    // register Codex agent
 	_ "github.com/re-cinq/shift-log/internal/agent/copilot"  // register Copilot agent
 	_ "github.com/re-cinq/shift-log/internal/agent/gemini"   // register Gemini agent
+	_ "github.com/re-cinq/shift-log/internal/agent/goose"    // register Goose agent
 	_ "github.com/re-cinq/shift-log/internal/agent/opencode" // register OpenCode agent
+	_ "github.com/re-cinq/shift-log/internal/agent/windsurf" // register Windsurf agent
 	"github.com/re-cinq/shift-log/internal/cli"
+	"github.com/re-cinq/shift-log/internal/config"
 	"github.com/re-cinq/shift-log/internal/git"
 	"github.com/re-cinq/shift-log/internal/storage"
 	"github.com/spf13/cobra"
@@ -23,12 +27,16 @@ import (
 const summariseTimeout = 120 * time.Second
 
 var (
-	summariseAgent string
-	summariseFocus string
+	summariseAgent     string
+	summariseFocus     string
+	summariseSave      bool
+	summariseAll       bool
+	summariseHeuristic bool
+	summariseForce     bool
 )
 
 var summariseCmd = &cobra.Command{
-	Use:     "summarise [ref]",
+	Use:     "summarise [ref...]",
 	Aliases: []string{"tldr"},
 	Short:   "Summarise a stored conversation using your coding agent",
 	GroupID: "human",
@@ -40,19 +48,34 @@ and Codex). Use --agent to override which agent performs the summarisation.
 
 If no ref is provided, summarises the conversation for HEAD.
 
+With --save, a 2-3 sentence summary of each conversation is saved in its
+note instead, where it is shown by list, search and the web viewer. The
+agent falls back to a summary built locally from the transcript when it
+cannot summarise, and --heuristic skips it. --all backfills the
+conversations of the current branch stored before summaries were enabled.
+Set "summary" in .shiftlog/config to "agent" or "heuristic" to save them
+automatically at store time.
+
 Examples:
   shiftlog summarise            # Summarise conversation for HEAD
   shiftlog tldr                 # Same, using the alias
   shiftlog summarise HEAD~1     # Summarise for previous commit
   shiftlog summarise --agent=claude abc123  # Use Claude Code explicitly
-  shiftlog tldr --focus="security changes"  # Prioritise security in summary`,
-	Args: cobra.MaximumNArgs(1),
+  shiftlog tldr --focus="security changes"  # Prioritise security in summary
+  shiftlog summarise --save abc123          # Save a short summary in the note
+  shiftlog summarise --all                  # Backfill every conversation on this branch
+  shiftlog summarise --all --heuristic      # Backfill without calling the agent
+  shiftlog summarise --save --force HEAD    # Replace an existing summary`,
 	RunE: runSummarise,
 }
 
 func init() {
 	summariseCmd.Flags().StringVar(&summariseAgent, "agent", "", "Agent to use for summarisation (e.g. claude, codex)")
 	summariseCmd.Flags().StringVarP(&summariseFocus, "focus", "f", "", "What to prioritise in the summary (e.g. \"security changes\", \"API design\")")
+	summariseCmd.Flags().BoolVar(&summariseSave, "save", false, "save a short summary in the note of each conversation")
+	summariseCmd.Flags().BoolVar(&summariseAll, "all", false, "save a summary for every conversation on the current branch that has none")
+	summariseCmd.Flags().BoolVar(&summariseHeuristic, "heuristic", false, "with --save, build summaries locally instead of calling the agent")
+	summariseCmd.Flags().BoolVar(&summariseForce, "force", false, "with --save, replace existing summaries")
 	rootCmd.AddCommand(summariseCmd)
 }
 
@@ -61,6 +84,19 @@ func runSummarise(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
+	if summariseSave || summariseAll {
+		if summariseFocus != "" {
+			return fmt.Errorf("--focus applies to printed summaries, not saved ones")
+		}
+		return saveSummaries(args)
+	}
+	if summariseHeuristic || summariseForce {
+		return fmt.Errorf("--heuristic and --force apply with --save or --all")
+	}
+	if len(args) > 1 {
+		return fmt.Errorf("summarise prints one conversation; use --save to summarise several")
+	}
+
 	// Resolve ref
 	ref := "HEAD"
 	if len(args) > 0 {
@@ -121,6 +157,140 @@ func runSummarise(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("agent %q does not support summarisation; try --agent=claude", agentName)
 	}
 
+	// Start spinner
+	spinner := cli.NewSpinner("Summarising conversation...")
+	spinner.Start()
+	summary, err := runSummariser(summariser, prompt)
+	spinner.Stop()
+	if err != nil {
+		return err
+	}
+
+	fmt.Println(summary)
+	return nil
+}
+
+// saveSummaries saves a short summary in the conversations of the commits
+// refs name, HEAD's by default, or with --all of those on the current branch
+// that have none.
+func saveSummaries(refs []string) error {
+	if summariseAll && len(refs) > 0 {
+		return fmt.Errorf("specify commits to summarise or --all, not both")
+	}
+
+	var commits []string
+	if summariseAll {
+		var err error
+		commits, err = storage.ListConversationCommits()
+		if err != nil {
+			return fmt.Errorf("could not list conversations: %w", err)
+		}
+	} else {
+		if len(refs) == 0 {
+			refs = []string{"HEAD"}
+		}
+		for _, ref := range refs {
+			sha, err := git.ResolveRef(ref)
+			if err != nil {
+				return fmt.Errorf("could not resolve reference '%s': not a valid commit", ref)
+			}
+			commits = append(commits, sha)
+		}
+	}
+
+	mode := config.SummaryAgent
+	if summariseHeuristic {
+		mode = config.SummaryHeuristic
+	}
+
+	summarised := 0
+	for _, sha := range commits {
+		stored, err := storage.GetStoredConversation(sha)
+		if err != nil {
+			return fmt.Errorf("could not read conversation for %s: %w", sha[:7], err)
+		}
+		if stored == nil {
+			if summariseAll {
+				continue
+			}
+			return fmt.Errorf("no conversation found for commit %s", sha[:7])
+		}
+		if stored.Summary != "" && !summariseForce {
+			cli.LogDebug("summarise: %s already has a summary", sha[:8])
+			continue
+		}
+
+		transcript, err := stored.ParseTranscript()
+		if err != nil {
+			cli.LogWarning("could not parse transcript for %s: %v", sha[:7], err)
+			continue
+		}
+
+		// Summarise only this commit's part of a session spanning several commits
+		_, lastUUID := storage.FindParentConversationBoundary(sha, stored.SessionID)
+		agentName := summariseAgent
+		if agentName == "" {
+			agentName = stored.Agent
+		}
+		if agentName == "" {
+			agentName = "claude"
+		}
+		summary := generateSummary(transcript.GetEntriesSince(lastUUID), stored.ToolAliases(), agentName, mode)
+		if summary == "" {
+			cli.LogWarning("conversation for %s has no summarisable content", sha[:7])
+			continue
+		}
+
+		stored.Summary = summary
+		if err := storage.SaveStoredConversation(sha, stored); err != nil {
+			return fmt.Errorf("failed to update conversation for %s: %w", sha[:7], err)
+		}
+
+		fmt.Printf("%s %s\n", sha[:7], summary)
+		cli.RecordArtifact("note", sha)
+		summarised++
+	}
+
+	if summariseAll && summarised == 0 {
+		fmt.Println("no conversations need a summary")
+	}
+	return nil
+}
+
+// generateSummary returns a short summary of entries. In SummaryAgent mode it
+// asks the named agent and falls back to the heuristic summary if the agent
+// cannot summarise or fails. Returns "" if there is nothing to summarise.
+func generateSummary(entries []agent.TranscriptEntry, aliases map[string]string, agentName, mode string) string {
+	if mode == config.SummaryAgent {
+		summary, err := agentSummary(entries, agentName)
+		if err == nil {
+			return summary
+		}
+		cli.LogWarning("agent summary failed, using heuristic summary: %v", err)
+	}
+	return agent.HeuristicSummary(entries, aliases)
+}
+
+func agentSummary(entries []agent.TranscriptEntry, agentName string) (string, error) {
+	prompt := agent.BuildShortSummaryPrompt(entries, agent.DefaultMaxPromptChars)
+	if prompt == "" {
+		return "", fmt.Errorf("transcript has no summarisable content")
+	}
+
+	ag, err := agent.Get(agent.Name(agentName))
+	if err != nil {
+		return "", fmt.Errorf("unknown agent %q", agentName)
+	}
+	summariser, ok := ag.(agent.Summariser)
+	if !ok {
+		return "", fmt.Errorf("agent %q does not support summarisation", agentName)
+	}
+	return runSummariser(summariser, prompt)
+}
+
+// runSummariser sends prompt to the agent in non-interactive mode and returns
+// its trimmed output.
+func runSummariser(summariser agent.Summariser, prompt string) (string, error) {
 	binary, cmdArgs := summariser.SummariseCommand()
 
 	// Pass prompt as a positional argument (not stdin) — Claude Code v2.1.49+
@@ -130,7 +300,7 @@ func runSummarise(cmd *cobra.Command, args []string) error {
 	// Check binary exists
 	binaryPath, err := exec.LookPath(binary)
 	if err != nil {
-		return fmt.Errorf("%s not found in PATH; install it or use --agent to specify a different agent", binary)
+		return "", fmt.Errorf("%s not found in PATH; install it or use --agent to specify a different agent", binary)
 	}
 
 	cli.LogDebug("summarise: using %s %s", binaryPath, strings.Join(cmdArgs, " "))
@@ -145,30 +315,22 @@ func runSummarise(cmd *cobra.Command, args []string) error {
 	agentCmd.Stdout = &stdout
 	agentCmd.Stderr = &stderr
 
-	// Start spinner
-	spinner := cli.NewSpinner("Summarising conversation...")
-	spinner.Start()
-
 	err = agentCmd.Run()
-	spinner.Stop()
 
 	if ctx.Err() == context.DeadlineExceeded {
-		return fmt.Errorf("agent timed out after %s", summariseTimeout)
+		return "", fmt.Errorf("agent timed out after %s", summariseTimeout)
 	}
 	if err != nil {
 		errMsg := fmt.Sprintf("agent failed: %v", err)
 		if stderrOut := strings.TrimSpace(stderr.String()); stderrOut != "" {
 			errMsg += "\nstderr: " + stderrOut
 		}
-		return fmt.Errorf("%s", errMsg)
+		return "", fmt.Errorf("%s", errMsg)
 	}
 
-	// Print summary
 	summary := strings.TrimSpace(stdout.String())
 	if summary == "" {
-		return fmt.Errorf("agent returned empty summary")
+		return "", fmt.Errorf("agent returned empty summary")
 	}
-
-	fmt.Println(summary)
-	return nil
+	return summary, nil
 }
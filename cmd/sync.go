@@ -1,11 +1,17 @@
 package cmd
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
+	"os"
+	"slices"
+	"strings"
 
 	"github.com/re-cinq/shift-log/internal/cli"
+	"github.com/re-cinq/shift-log/internal/config"
 	"github.com/re-cinq/shift-log/internal/git"
+	"github.com/re-cinq/shift-log/internal/storage"
 	"github.com/spf13/cobra"
 )
 
@@ -13,50 +19,296 @@ var syncCmd = &cobra.Command{
 	Use:     "sync",
 	Short:   "Sync conversation notes with remote",
 	GroupID: "hooks",
-	Long:    `Sync git notes containing conversations with the remote repository.`,
+	Long:    `Sync git notes containing conversations and their annotations with the remote repository.`,
 }
 
 var syncPushCmd = &cobra.Command{
 	Use:   "push",
 	Short: "Push conversation notes to remote",
-	RunE:  runSyncPush,
+	Long: `Pushes conversation notes and annotations to one or more remotes.
+
+With contributor_ref set in .shiftlog/config, or --notes-ref, the
+conversation notes are pushed to that notes ref of the remote instead of
+the shared one, for contributors who cannot write to the shared notes of
+the repository they fork.
+
+Examples:
+  shiftlog sync push                                # Push to origin
+  shiftlog sync push --remote origin --remote backup
+  shiftlog sync push --all-remotes
+  shiftlog sync push --remote fork --notes-ref refs/notes/shiftlog-alice`,
+	Args: cobra.NoArgs,
+	RunE: runSyncPush,
 }
 
 var syncPullCmd = &cobra.Command{
 	Use:   "pull",
 	Short: "Pull conversation notes from remote",
-	RunE:  runSyncPull,
+	Long: `Fetches conversation notes and annotations from one or more remotes and
+merges each into the local notes.
+
+With contributor_ref set in .shiftlog/config, or --notes-ref, that notes
+ref of the remote is pulled too. It is merged into the local notes, or,
+when it is one of the read_refs, into the local ref of the same name, to
+be shown with the shared conversations until it is merged into them.
+
+Examples:
+  shiftlog sync pull                                # Pull from origin
+  shiftlog sync pull --remote origin --remote backup
+  shiftlog sync pull --all-remotes
+  shiftlog sync pull --remote alice --notes-ref refs/notes/shiftlog-alice`,
+	Args: cobra.NoArgs,
+	RunE: runSyncPull,
 }
 
-var syncRemote string
+var syncStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show how local and remote conversation notes differ",
+	Long: `Compares the local notes ref with the remote's without changing either:
+how many commits have notes only locally, only on the remote, or different
+notes on each side, and whether a push would be rejected.
+
+Examples:
+  shiftlog sync status                  # Compare with origin
+  shiftlog sync status --remote upstream
+  shiftlog sync status --verbose        # List the commits in each group
+  shiftlog sync status --format json`,
+	Args: cobra.NoArgs,
+	RunE: runSyncStatus,
+}
+
+var (
+	syncRemotes       []string
+	syncAllRemotes    bool
+	syncStatusVerbose bool
+	syncStatusFormat  string
+	syncNotesRef      string
+)
 
 func init() {
 	rootCmd.AddCommand(syncCmd)
 	syncCmd.AddCommand(syncPushCmd)
 	syncCmd.AddCommand(syncPullCmd)
+	syncCmd.AddCommand(syncStatusCmd)
+
+	syncCmd.PersistentFlags().StringArrayVar(&syncRemotes, "remote", []string{"origin"}, "Remote to sync with (repeatable for push and pull)")
+	syncPushCmd.Flags().BoolVar(&syncAllRemotes, "all-remotes", false, "Push to every configured remote")
+	syncPullCmd.Flags().BoolVar(&syncAllRemotes, "all-remotes", false, "Pull from every configured remote")
+	syncPushCmd.Flags().StringVar(&syncNotesRef, "notes-ref", "", "Contributor notes ref to push to instead of the shared one (default: contributor_ref)")
+	syncPullCmd.Flags().StringVar(&syncNotesRef, "notes-ref", "", "Contributor notes ref to pull besides the shared one (default: contributor_ref)")
+	syncStatusCmd.Flags().BoolVarP(&syncStatusVerbose, "verbose", "v", false, "list the commits in each group")
+	syncStatusCmd.Flags().StringVar(&syncStatusFormat, "format", "text", "output format: text or json")
+}
 
-	syncCmd.PersistentFlags().StringVar(&syncRemote, "remote", "origin", "Remote to sync with")
+// resolveSyncRemotes returns the remotes selected by --remote or --all-remotes.
+func resolveSyncRemotes(cmd *cobra.Command) ([]string, error) {
+	if !syncAllRemotes {
+		return syncRemotes, nil
+	}
+	if cmd.Flags().Changed("remote") {
+		return nil, fmt.Errorf("--remote and --all-remotes cannot be used together")
+	}
+	remotes, err := git.ListRemotes()
+	if err != nil {
+		return nil, fmt.Errorf("could not list remotes: %w", err)
+	}
+	if len(remotes) == 0 {
+		return nil, fmt.Errorf("no remotes configured")
+	}
+	return remotes, nil
+}
+
+// syncContributorRef returns the contributor notes ref that sync pushes to
+// and pulls from: --notes-ref, or else contributor_ref, or "" for the
+// shared notes ref only.
+func syncContributorRef() (string, error) {
+	ref := syncNotesRef
+	if ref == "" {
+		cfg, err := config.Read()
+		if err != nil {
+			return "", fmt.Errorf("could not read config: %w", err)
+		}
+		ref = cfg.ContributorRef
+	}
+	if ref == "" || ref == git.NotesRef {
+		return "", nil
+	}
+	if err := git.ValidateContributorRef(ref); err != nil {
+		return "", err
+	}
+	return ref, nil
+}
+
+// syncEachRemote runs fn for every remote, carrying on past failures so each
+// remote reports its own result. With a single remote its error is returned
+// unchanged.
+func syncEachRemote(remotes []string, action string, fn func(remote string) error) error {
+	if len(remotes) == 1 {
+		return fn(remotes[0])
+	}
+
+	var failed []string
+	for _, remote := range remotes {
+		if err := fn(remote); err != nil {
+			fmt.Printf("%s failed for %s: %v\n", action, remote, err)
+			failed = append(failed, remote)
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("%s failed for %d of %d remotes: %s",
+			strings.ToLower(action), len(failed), len(remotes), strings.Join(failed, ", "))
+	}
+	return nil
 }
 
 func runSyncPush(cmd *cobra.Command, args []string) error {
 	if err := git.RequireGitRepo(); err != nil {
 		return err
 	}
+	remotes, err := resolveSyncRemotes(cmd)
+	if err != nil {
+		return err
+	}
+	contributorRef, err := syncContributorRef()
+	if err != nil {
+		return err
+	}
+	return syncEachRemote(remotes, "Push", func(remote string) error {
+		return pushToRemote(remote, contributorRef)
+	})
+}
 
-	cli.LogDebug("sync push: pushing notes to remote %s", syncRemote)
+// pushToRemote pushes the notes to remote, the conversation notes going to
+// contributorRef when it is set.
+func pushToRemote(remote, contributorRef string) error {
+	// Dictionaries go first, so that a server validating the notes can
+	// decompress the transcripts compressed with them
+	if git.HasDictionaries() {
+		if err := git.PushDictionaries(remote); err != nil {
+			if errors.Is(err, git.ErrNonFastForward) {
+				fmt.Println("Push rejected: remote compression dictionaries have diverged.")
+				fmt.Println("Run 'shiftlog sync pull' first to merge, then push again.")
+				return err
+			}
+			cli.LogWarning("could not push compression dictionaries to %s: %v", remote, err)
+		} else {
+			cli.LogDebug("sync push: pushed compression dictionaries to %s", remote)
+		}
+	}
 
-	if err := git.PushNotes(syncRemote); err != nil {
+	if err := pushTranscriptBlobs(remote); err != nil {
+		return err
+	}
+
+	cli.LogDebug("sync push: pushing notes to remote %s", remote)
+
+	remoteRef := git.NotesRef
+	push := git.PushNotes
+	if contributorRef != "" {
+		remoteRef = contributorRef
+		push = func(remote string) error { return git.PushNotesTo(remote, contributorRef) }
+	}
+	if err := push(remote); err != nil {
 		if errors.Is(err, git.ErrNonFastForward) {
 			fmt.Println("Push rejected: remote notes have diverged.")
 			fmt.Println("Run 'shiftlog sync pull' first to merge, then push again.")
 			return err
 		}
 		// Don't fail if there are no notes to push or remote doesn't exist
-		cli.LogWarning("could not push notes: %v", err)
+		cli.LogWarning("could not push notes to %s: %v", remote, err)
+		return nil
+	}
+
+	if contributorRef != "" {
+		fmt.Printf("Pushed conversation notes to %s of %s\n", contributorRef, remote)
+	} else {
+		fmt.Printf("Pushed conversation notes to %s\n", remote)
+	}
+	cli.RecordArtifact("ref", remote+":"+remoteRef)
+
+	if git.HasAnnotations() {
+		if err := git.PushAnnotations(remote); err != nil {
+			if errors.Is(err, git.ErrNonFastForward) {
+				fmt.Println("Push rejected: remote annotations have diverged.")
+				fmt.Println("Run 'shiftlog sync pull' first to merge, then push again.")
+				return err
+			}
+			cli.LogWarning("could not push annotations to %s: %v", remote, err)
+			return nil
+		}
+		fmt.Printf("Pushed annotations to %s\n", remote)
+	}
+
+	if git.HasMergeRecords() {
+		if err := git.PushMergeRecords(remote); err != nil {
+			if errors.Is(err, git.ErrNonFastForward) {
+				fmt.Println("Push rejected: remote merge records have diverged.")
+				fmt.Println("Run 'shiftlog sync pull' first to merge, then push again.")
+				return err
+			}
+			cli.LogWarning("could not push merge records to %s: %v", remote, err)
+			return nil
+		}
+		fmt.Printf("Pushed merge records to %s\n", remote)
+	}
+
+	if git.HasReadableNotes() {
+		if err := git.PushReadableNotes(remote); err != nil {
+			if errors.Is(err, git.ErrNonFastForward) {
+				fmt.Println("Push rejected: remote readable notes have diverged.")
+				fmt.Println("Run 'shiftlog sync pull' first to merge, then push again.")
+				return err
+			}
+			cli.LogWarning("could not push readable notes to %s: %v", remote, err)
+			return nil
+		}
+		fmt.Printf("Pushed readable notes to %s\n", remote)
+	}
+
+	if !git.HasOmissions() {
 		return nil
 	}
+	if err := git.PushOmissions(remote); err != nil {
+		if errors.Is(err, git.ErrNonFastForward) {
+			fmt.Println("Push rejected: remote omitted conversations have diverged.")
+			fmt.Println("Run 'shiftlog sync pull' first to merge, then push again.")
+			return err
+		}
+		cli.LogWarning("could not push omitted conversations to %s: %v", remote, err)
+		return nil
+	}
+	fmt.Printf("Pushed omitted conversations to %s\n", remote)
+	return nil
+}
 
-	fmt.Printf("Pushed conversation notes to %s\n", syncRemote)
+// pushTranscriptBlobs shares the transcripts stored apart from their notes
+// before the notes that reference them: it uploads them to the configured
+// HTTP blob store, or else pushes their ref to the remote.
+func pushTranscriptBlobs(remote string) error {
+	if !git.HasBlobs() {
+		return nil
+	}
+	if storage.UsesBlobStore() {
+		uploaded, err := storage.UploadTranscriptBlobs()
+		if err != nil {
+			// The notes would reference transcripts nobody else can read
+			return err
+		}
+		cli.LogDebug("sync push: uploaded %d transcript blobs", uploaded)
+		if uploaded > 0 {
+			fmt.Printf("Uploaded %d transcript blobs to the blob store\n", uploaded)
+		}
+		return nil
+	}
+	if err := git.PushBlobs(remote); err != nil {
+		if errors.Is(err, git.ErrNonFastForward) {
+			fmt.Println("Push rejected: remote transcript blobs have diverged.")
+			fmt.Println("Run 'shiftlog sync pull' first to merge, then push again.")
+			return err
+		}
+		return fmt.Errorf("could not push transcript blobs to %s: %w", remote, err)
+	}
+	cli.LogDebug("sync push: pushed transcript blobs to %s", remote)
 	return nil
 }
 
@@ -64,21 +316,212 @@ func runSyncPull(cmd *cobra.Command, args []string) error {
 	if err := git.RequireGitRepo(); err != nil {
 		return err
 	}
+	remotes, err := resolveSyncRemotes(cmd)
+	if err != nil {
+		return err
+	}
+	contributorRef, err := syncContributorRef()
+	if err != nil {
+		return err
+	}
+	return syncEachRemote(remotes, "Pull", func(remote string) error {
+		return pullFromRemote(remote, contributorRef)
+	})
+}
+
+// pullFromRemote pulls the notes of remote, with its contributorRef when
+// it is set.
+func pullFromRemote(remote, contributorRef string) error {
+	cli.LogDebug("sync pull: fetching notes from remote %s", remote)
 
-	cli.LogDebug("sync pull: fetching notes from remote %s", syncRemote)
+	if err := git.FetchDictionariesToTracking(remote); err != nil {
+		// The remote has no dictionaries until someone trains one
+		cli.LogDebug("sync pull: no compression dictionaries fetched: %v", err)
+	} else if err := git.MergeDictionaries(); err != nil {
+		return fmt.Errorf("failed to merge compression dictionaries: %w", err)
+	}
 
-	if err := git.FetchNotesToTracking(syncRemote); err != nil {
+	// Blobs kept in an HTTP blob store are fetched when they are read
+	if !storage.UsesBlobStore() {
+		if err := git.FetchBlobsToTracking(remote); err != nil {
+			// The remote has no blobs until an oversized transcript is pushed
+			cli.LogDebug("sync pull: no transcript blobs fetched: %v", err)
+		} else if err := git.MergeBlobs(); err != nil {
+			return fmt.Errorf("failed to merge transcript blobs: %w", err)
+		}
+	}
+
+	fetched, err := pullNotes(remote, git.NotesRef)
+	if err != nil {
+		return err
+	}
+	if contributorRef != "" {
+		fetchedContributed, err := pullNotes(remote, contributorRef)
+		if err != nil {
+			return err
+		}
+		fetched = fetched || fetchedContributed
+	}
+	if !fetched {
 		// Don't fail if there are no notes to fetch or remote doesn't exist
-		cli.LogWarning("could not fetch notes: %v", err)
 		return nil
 	}
+	if err := storage.UpdateIndex(); err != nil {
+		cli.LogDebug("sync pull: could not update the index: %v", err)
+	}
+
+	if err := git.FetchAnnotationsToTracking(remote); err != nil {
+		// The remote has no annotations until someone pushes one
+		cli.LogDebug("sync pull: no annotations fetched: %v", err)
+	} else {
+		if err := git.MergeAnnotations(); err != nil {
+			return fmt.Errorf("failed to merge annotations: %w", err)
+		}
+		fmt.Printf("Fetched and merged annotations from %s\n", remote)
+	}
+
+	if err := git.FetchMergeRecordsToTracking(remote); err != nil {
+		// The remote has no merge records until a merge is pushed
+		cli.LogDebug("sync pull: no merge records fetched: %v", err)
+	} else {
+		if err := git.MergeMergeRecords(); err != nil {
+			return fmt.Errorf("failed to merge merge records: %w", err)
+		}
+		fmt.Printf("Fetched and merged merge records from %s\n", remote)
+	}
+
+	if err := git.FetchReadableNotesToTracking(remote); err != nil {
+		// The remote has no readable notes until someone turns them on
+		cli.LogDebug("sync pull: no readable notes fetched: %v", err)
+	} else {
+		if err := git.MergeReadableNotes(); err != nil {
+			return fmt.Errorf("failed to merge readable notes: %w", err)
+		}
+		fmt.Printf("Fetched and merged readable notes from %s\n", remote)
+	}
+
+	if err := git.FetchOmissionsToTracking(remote); err != nil {
+		// The remote has no omissions until a contributor opts out
+		cli.LogDebug("sync pull: no omitted conversations fetched: %v", err)
+		return nil
+	}
+	if err := git.MergeOmissions(); err != nil {
+		return fmt.Errorf("failed to merge omitted conversations: %w", err)
+	}
+	fmt.Printf("Fetched and merged omitted conversations from %s\n", remote)
+	return nil
+}
+
+// pullNotes fetches the remote's notes ref remoteRef and merges it into the
+// local notes, or into the local ref of the same name when it is one of the
+// read_refs, kept apart from the shared notes. It reports whether the remote
+// had the ref.
+func pullNotes(remote, remoteRef string) (bool, error) {
+	fetch := git.FetchNotesToTracking(remote)
+	if remoteRef != git.NotesRef {
+		fetch = git.FetchNotesFromToTracking(remote, remoteRef)
+	}
+	if fetch != nil {
+		cli.LogWarning("could not fetch notes %s from %s: %v", remoteRef, remote, fetch)
+		return false, nil
+	}
+
+	readRefs, err := storage.ContributorRefs()
+	if err != nil {
+		return false, err
+	}
+	if remoteRef != git.NotesRef && slices.Contains(readRefs, remoteRef) {
+		cli.LogDebug("sync pull: merging remote notes into %s", remoteRef)
+		if err := git.MergeNotesInto(remoteRef); err != nil {
+			return false, fmt.Errorf("failed to merge notes into %s: %w", remoteRef, err)
+		}
+		fmt.Printf("Fetched and merged conversation notes of %s from %s\n", remoteRef, remote)
+		cli.RecordArtifact("ref", remoteRef)
+		return true, nil
+	}
+
+	if err := storage.ReconcileTrackingNotes(); err != nil {
+		cli.LogWarning("could not reconcile conversation metadata: %v", err)
+	}
 
 	cli.LogDebug("sync pull: merging remote notes into local ref")
 
 	if err := git.MergeNotes(); err != nil {
-		return fmt.Errorf("failed to merge notes: %w", err)
+		return false, fmt.Errorf("failed to merge notes: %w", err)
+	}
+
+	if remoteRef != git.NotesRef {
+		fmt.Printf("Fetched and merged conversation notes of %s from %s\n", remoteRef, remote)
+	} else {
+		fmt.Printf("Fetched and merged conversation notes from %s\n", remote)
+	}
+	cli.RecordArtifact("ref", git.NotesRef)
+	return true, nil
+}
+
+func runSyncStatus(cmd *cobra.Command, args []string) error {
+	if err := git.RequireGitRepo(); err != nil {
+		return err
+	}
+	if syncStatusFormat != "text" && syncStatusFormat != "json" {
+		return fmt.Errorf("invalid --format %q: must be text or json", syncStatusFormat)
+	}
+
+	if len(syncRemotes) != 1 {
+		return fmt.Errorf("sync status compares one remote at a time")
+	}
+
+	cli.LogDebug("sync status: comparing notes with remote %s", syncRemotes[0])
+
+	status, err := git.CompareNotes(syncRemotes[0])
+	if err != nil {
+		return err
+	}
+
+	if syncStatusFormat == "json" {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(status)
 	}
 
-	fmt.Printf("Fetched and merged conversation notes from %s\n", syncRemote)
+	switch {
+	case !status.RemoteExists && status.Ahead == 0:
+		fmt.Printf("No conversation notes locally or on %s\n", status.Remote)
+		return nil
+	case !status.RemoteExists:
+		fmt.Printf("%s has no conversation notes yet; 'shiftlog sync push' will publish them\n", status.Remote)
+	case status.InSync():
+		fmt.Printf("Conversation notes are in sync with %s\n", status.Remote)
+	case status.Behind == 0:
+		fmt.Printf("Ahead of %s by %d notes commits; 'shiftlog sync push' will publish them\n", status.Remote, status.Ahead)
+	case status.Ahead == 0:
+		fmt.Printf("Behind %s by %d notes commits; run 'shiftlog sync pull'\n", status.Remote, status.Behind)
+	default:
+		fmt.Printf("Diverged from %s (%d local, %d remote notes commits); run 'shiftlog sync pull' before pushing\n",
+			status.Remote, status.Ahead, status.Behind)
+	}
+
+	fmt.Printf("  Only local:   %d\n", len(status.LocalOnly))
+	printStatusCommits(status.LocalOnly)
+	fmt.Printf("  Only remote:  %d\n", len(status.RemoteOnly))
+	printStatusCommits(status.RemoteOnly)
+	fmt.Printf("  Conflicting:  %d\n", len(status.Conflicting))
+	printStatusCommits(status.Conflicting)
+	fmt.Printf("  Identical:    %d\n", status.Shared)
 	return nil
 }
+
+// printStatusCommits lists commits under a sync status count with --verbose.
+func printStatusCommits(commits []string) {
+	if !syncStatusVerbose {
+		return
+	}
+	for _, sha := range commits {
+		message, _, err := git.GetCommitInfo(sha)
+		if err != nil {
+			// The commit itself may not have been fetched yet
+			message = "(commit not available locally)"
+		}
+		fmt.Printf("    %s %s\n", sha[:7], message)
+	}
+}
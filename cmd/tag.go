@@ -0,0 +1,101 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/re-cinq/shift-log/internal/cli"
+	"github.com/re-cinq/shift-log/internal/git"
+	"github.com/re-cinq/shift-log/internal/storage"
+	"github.com/spf13/cobra"
+)
+
+var tagRemove bool
+
+var tagCmd = &cobra.Command{
+	Use:     "tag <ref> [tag...]",
+	Short:   "Label a stored conversation",
+	GroupID: "human",
+	Long: `Attaches labels to the conversation stored for a commit, for example to
+mark sessions for later review or as training material. Tags are saved in
+the conversation note, so they sync with it and follow it through rebases.
+When two clones tag the same conversation, sync pull keeps the tags from both.
+
+With no tags, prints the conversation's current tags.
+
+Tags are lowercase and may contain letters, digits, '.', '_' and '-'.
+Filter by tag with 'shiftlog log --tag', 'shiftlog search --tag' or the
+tag filter in the web viewer.
+
+Examples:
+  shiftlog tag HEAD bugfix prompt-engineering   # Add two tags
+  shiftlog tag abc123                           # Show tags
+  shiftlog tag --remove abc123 bugfix           # Remove a tag`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: runTag,
+}
+
+func init() {
+	tagCmd.Flags().BoolVar(&tagRemove, "remove", false, "remove the given tags instead of adding them")
+	rootCmd.AddCommand(tagCmd)
+}
+
+func runTag(cmd *cobra.Command, args []string) error {
+	if err := git.RequireGitRepo(); err != nil {
+		return err
+	}
+
+	ref := args[0]
+	sha, err := git.ResolveRef(ref)
+	if err != nil {
+		return fmt.Errorf("could not resolve reference '%s': not a valid commit", ref)
+	}
+
+	stored, err := storage.GetStoredConversation(sha)
+	if err != nil {
+		return fmt.Errorf("could not read conversation for %s: %w", sha[:7], err)
+	}
+	if stored == nil {
+		return fmt.Errorf("no conversation found for commit %s", sha[:7])
+	}
+
+	if len(args) == 1 {
+		if tagRemove {
+			return fmt.Errorf("specify the tags to remove")
+		}
+		if len(stored.Tags) > 0 {
+			fmt.Println(strings.Join(stored.Tags, " "))
+		}
+		return nil
+	}
+
+	var tags []string
+	for _, arg := range args[1:] {
+		tag, err := storage.NormalizeTag(arg)
+		if err != nil {
+			return err
+		}
+		tags = append(tags, tag)
+	}
+
+	var changed bool
+	if tagRemove {
+		changed = stored.RemoveTags(tags...)
+	} else {
+		changed = stored.AddTags(tags...)
+	}
+
+	if changed {
+		if err := storage.SaveStoredConversation(sha, stored); err != nil {
+			return fmt.Errorf("failed to update conversation for %s: %w", sha[:7], err)
+		}
+		cli.RecordArtifact("note", sha)
+	}
+
+	if len(stored.Tags) == 0 {
+		fmt.Printf("%s has no tags\n", sha[:7])
+	} else {
+		fmt.Printf("%s %s\n", sha[:7], strings.Join(stored.Tags, " "))
+	}
+	return nil
+}
@@ -0,0 +1,157 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/re-cinq/shift-log/internal/agent"
+	"github.com/re-cinq/shift-log/internal/cli"
+	"github.com/re-cinq/shift-log/internal/git"
+	"github.com/spf13/cobra"
+)
+
+var (
+	uninstallDeleteNotes bool
+	uninstallPurge       bool
+	uninstallYes         bool
+)
+
+var uninstallCmd = &cobra.Command{
+	Use:     "uninstall",
+	Short:   "Remove everything shiftlog set up in the current repository",
+	GroupID: "human",
+	Long: `Undoes 'shiftlog init' for every coding agent at once.
+
+This command:
+- Removes the hooks and plugins of every supported coding agent, not just
+  the configured one
+- Removes shiftlog-managed git hook sections, keeping anything else in
+  shared hook files
+- Unsets git config settings for notes visibility and reflog retention,
+  and the git shiftlog alias and log pager of 'shiftlog git-log-format'
+
+With --purge, it also deletes the .shiftlog/ directory and its .gitignore
+entry.
+
+With --delete-notes, it also deletes the local conversation notes and the
+other shiftlog refs (checkpoints, annotations, merge records). Notes already
+pushed stay on the remote. Deleted notes cannot be brought back with
+'shiftlog recover'; run 'shiftlog backup create <file>' first to keep a copy.
+It asks for confirmation unless --yes is given.
+
+Use 'shiftlog deinit' to remove only the configured agent's hooks.`,
+	RunE: runUninstall,
+}
+
+func init() {
+	uninstallCmd.Flags().BoolVar(&uninstallDeleteNotes, "delete-notes", false, "also delete the local conversation notes and other shiftlog refs")
+	uninstallCmd.Flags().BoolVar(&uninstallPurge, "purge", false, "also delete the .shiftlog/ directory and its .gitignore entry")
+	uninstallCmd.Flags().BoolVarP(&uninstallYes, "yes", "y", false, "delete notes without asking for confirmation")
+	rootCmd.AddCommand(uninstallCmd)
+}
+
+func runUninstall(cmd *cobra.Command, args []string) error {
+	if err := git.RequireGitRepo(); err != nil {
+		return err
+	}
+
+	repoRoot, err := git.GetRepoRoot()
+	if err != nil {
+		return fmt.Errorf("failed to get repository root: %w", err)
+	}
+
+	// Ask before changing anything, so that declining leaves shiftlog installed
+	var refs []string
+	if uninstallDeleteNotes {
+		refs, err = git.ShiftlogRefs()
+		if err != nil {
+			return fmt.Errorf("could not list shiftlog refs: %w", err)
+		}
+		if len(refs) > 0 && !uninstallYes &&
+			!cli.Confirm(fmt.Sprintf("shiftlog: delete %d local shiftlog refs, including %s?", len(refs), git.NotesRef)) {
+			return fmt.Errorf("not deleting notes without confirmation; pass --yes to delete them")
+		}
+	}
+
+	for _, ag := range agent.All() {
+		cli.LogDebug("uninstall: removing %s hooks", ag.DisplayName())
+		if err := ag.RemoveHooks(repoRoot); err != nil {
+			return fmt.Errorf("failed to remove %s hooks: %w", ag.DisplayName(), err)
+		}
+	}
+	fmt.Println("Removed coding agent hooks")
+
+	gitDir, err := git.EnsureGitDir()
+	if err != nil {
+		return fmt.Errorf("failed to find git directory: %w", err)
+	}
+	if err := git.RemoveAllHooks(gitDir); err != nil {
+		return fmt.Errorf("failed to remove git hooks: %w", err)
+	}
+	fmt.Println("Removed git hooks (pre-push, post-merge, post-checkout, post-commit, prepare-commit-msg)")
+	cli.RecordArtifact("hook", filepath.Join(gitDir, "hooks"))
+
+	if err := removeGitSettings(git.NotesRef); err != nil {
+		return fmt.Errorf("failed to remove git settings: %w", err)
+	}
+	fmt.Println("Removed git notes settings (displayRef, rewriteRef, reflog retention)")
+
+	if err := git.UninstallLogFormat(); err != nil {
+		return fmt.Errorf("failed to remove git log settings: %w", err)
+	}
+
+	if uninstallPurge {
+		if err := os.RemoveAll(filepath.Join(repoRoot, ".shiftlog")); err != nil {
+			return fmt.Errorf("failed to delete .shiftlog/: %w", err)
+		}
+		if err := removeGitignoreEntry(repoRoot, ".shiftlog/"); err != nil {
+			return fmt.Errorf("failed to update .gitignore: %w", err)
+		}
+		fmt.Println("Deleted .shiftlog/ and its .gitignore entry")
+	}
+
+	for _, ref := range refs {
+		if err := git.DeleteRef(ref); err != nil {
+			return fmt.Errorf("failed to delete %s: %w", ref, err)
+		}
+		cli.LogDebug("uninstall: deleted %s", ref)
+		cli.RecordArtifact("ref", ref)
+	}
+	if len(refs) > 0 {
+		fmt.Printf("Deleted %d shiftlog refs\n", len(refs))
+	}
+
+	fmt.Println()
+	fmt.Println("Shiftlog has been uninstalled from this repository.")
+	if !uninstallDeleteNotes {
+		fmt.Println("Git notes data has been preserved.")
+	}
+	return nil
+}
+
+// removeGitignoreEntry removes the lines equal to entry from the repo's
+// .gitignore. A missing .gitignore is not an error.
+func removeGitignoreEntry(repoRoot, entry string) error {
+	path := filepath.Join(repoRoot, ".gitignore")
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	lines := strings.SplitAfter(string(data), "\n")
+	kept := lines[:0]
+	for _, line := range lines {
+		if strings.TrimSpace(line) != entry {
+			kept = append(kept, line)
+		}
+	}
+	if len(kept) == len(lines) {
+		return nil
+	}
+	return os.WriteFile(path, []byte(strings.Join(kept, "")), 0644)
+}
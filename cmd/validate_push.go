@@ -0,0 +1,105 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/re-cinq/shift-log/internal/git"
+	"github.com/re-cinq/shift-log/internal/storage"
+	"github.com/spf13/cobra"
+)
+
+var validatePushMaxSize int
+
+var validatePushCmd = &cobra.Command{
+	Use:     "validate-push",
+	Short:   "Validate pushed conversation notes in a pre-receive hook",
+	GroupID: "hooks",
+	Long: `Checks the conversation notes and annotations in an incoming push and
+rejects it if any added or changed note is malformed, too large, or contains
+what looks like an unredacted secret such as an API key or private key.
+
+Meant to run on the server in a pre-receive hook (GitHub Enterprise, GitLab,
+Gitea or plain git). It reads the ref updates git passes on stdin; notes that
+were already on the server are not checked again. For example:
+
+  #!/bin/sh
+  exec shiftlog validate-push`,
+	Args: cobra.NoArgs,
+	RunE: runValidatePush,
+}
+
+func init() {
+	validatePushCmd.Flags().IntVar(&validatePushMaxSize, "max-size", storage.DefaultMaxNoteSize, "Largest note accepted, in bytes")
+	rootCmd.AddCommand(validatePushCmd)
+}
+
+// noteValidators maps each validated notes ref to the check for its notes.
+var noteValidators = map[string]func(data []byte, maxSize int) []string{
+	git.NotesRef:        storage.ValidateConversationNote,
+	git.AnnotationsRef:  storage.ValidateAnnotationsNote,
+	git.MergesRef:       storage.ValidateMergesNote,
+	git.OmissionsRef:    storage.ValidateOmissionsNote,
+	git.DictionariesRef: storage.ValidateDictionaryNote,
+	git.ReadableRef:     storage.ValidateReadableNote,
+}
+
+func runValidatePush(cmd *cobra.Command, args []string) error {
+	if !git.IsGitDir() {
+		return git.ErrNotGitRepo
+	}
+
+	updates, err := git.ParseRefUpdates(os.Stdin)
+	if err != nil {
+		return fmt.Errorf("could not read ref updates: %w", err)
+	}
+
+	rejected := 0
+	for _, u := range updates {
+		validate, ok := noteValidators[u.Ref]
+		if !ok {
+			continue
+		}
+		notes, err := git.PushedNotes(u)
+		if err != nil {
+			return fmt.Errorf("could not read notes pushed to %s: %w", u.Ref, err)
+		}
+		sort.Slice(notes, func(i, j int) bool { return notes[i].Commit < notes[j].Commit })
+
+		var invalid []string
+		for _, note := range notes {
+			data, err := git.ReadBlob(note.Blob)
+			if err != nil {
+				return fmt.Errorf("could not read note for %s: %w", note.Commit[:7], err)
+			}
+			problems := validate(data, validatePushMaxSize)
+			if len(problems) == 0 {
+				continue
+			}
+			if len(invalid) == 0 {
+				fmt.Printf("shiftlog: rejecting %s\n", u.Ref)
+			}
+			for _, problem := range problems {
+				fmt.Printf("  %s: %s\n", note.Commit[:7], problem)
+			}
+			invalid = append(invalid, note.Commit)
+		}
+		if len(invalid) == 0 {
+			continue
+		}
+
+		rejected += len(invalid)
+		fmt.Println()
+		fmt.Println("Fix or remove these notes locally, then push again. If a note was")
+		fmt.Println("already changed or removed, the old version is still in the pushed")
+		fmt.Println("notes history and the notes ref must be rewritten. To remove one:")
+		fmt.Printf("  git notes --ref %s remove %s\n", u.Ref, invalid[0])
+		fmt.Println()
+	}
+
+	if rejected > 0 {
+		return fmt.Errorf("push rejected: %d invalid notes", rejected)
+	}
+	return nil
+}
@@ -0,0 +1,132 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/re-cinq/shift-log/internal/git"
+	"github.com/re-cinq/shift-log/internal/storage"
+	"github.com/spf13/cobra"
+)
+
+var verifySignatures bool
+
+var verifyCmd = &cobra.Command{
+	Use:     "verify [ref...]",
+	Short:   "Check stored conversations for tampering",
+	GroupID: "human",
+	Long: `Checks that each stored conversation's transcript still matches the
+checksum recorded when it was stored. Without refs, checks every
+conversation on the current branch.
+
+With --signatures, also checks the signature made at store time when
+"sign" is enabled in .shiftlog/config. Signatures are verified with your
+git signing setup: GnuPG's keyring for openpgp, gpg.ssh.allowedSignersFile
+for ssh. A signature that cannot be checked, for example because the key is
+unknown, is reported but does not fail the command; a bad signature does.
+
+Examples:
+  shiftlog verify                    # Check every conversation on this branch
+  shiftlog verify --signatures       # Also check who stored them
+  shiftlog verify --signatures HEAD  # Check one commit`,
+	RunE: runVerify,
+}
+
+func init() {
+	verifyCmd.Flags().BoolVar(&verifySignatures, "signatures", false, "also verify conversation signatures")
+	rootCmd.AddCommand(verifyCmd)
+}
+
+func runVerify(cmd *cobra.Command, args []string) error {
+	if err := git.RequireGitRepo(); err != nil {
+		return err
+	}
+
+	var commits []string
+	if len(args) == 0 {
+		var err error
+		commits, err = storage.ListConversationCommits()
+		if err != nil {
+			return fmt.Errorf("could not list conversations: %w", err)
+		}
+	}
+	for _, ref := range args {
+		sha, err := git.ResolveRef(ref)
+		if err != nil {
+			return fmt.Errorf("could not resolve reference '%s': not a valid commit", ref)
+		}
+		commits = append(commits, sha)
+	}
+
+	if len(commits) == 0 {
+		fmt.Println("No conversations found")
+		return nil
+	}
+
+	failed, verified := 0, 0
+	for _, sha := range commits {
+		conversations, err := storage.GetStoredConversations(sha)
+		if err != nil {
+			fmt.Printf("%s  FAIL  note is unreadable: %v\n", sha[:7], err)
+			failed++
+			verified++
+			continue
+		}
+		if conversations == nil {
+			if len(args) > 0 {
+				return fmt.Errorf("no conversation found for commit %s", sha[:7])
+			}
+			continue
+		}
+
+		for _, stored := range conversations {
+			verified++
+			label := sha[:7]
+			if len(conversations) > 1 {
+				label += " " + stored.AgentName()
+			}
+
+			ok, err := stored.VerifyIntegrity()
+			if err != nil || !ok {
+				fmt.Printf("%s  FAIL  transcript does not match its checksum\n", label)
+				failed++
+				continue
+			}
+			if !verifySignatures {
+				fmt.Printf("%s  OK\n", label)
+				continue
+			}
+
+			sig := stored.VerifySignature()
+			result := "OK  "
+			if sig.Status == "bad" {
+				result = "FAIL"
+				failed++
+			}
+			fmt.Printf("%s  %s  %s\n", label, result, describeSignature(sig))
+		}
+	}
+
+	fmt.Println()
+	if failed > 0 {
+		fmt.Printf("%d of %d conversation(s) failed verification\n", failed, verified)
+		return fmt.Errorf("verification failed")
+	}
+	fmt.Printf("All %d conversation(s) verified\n", verified)
+	return nil
+}
+
+// describeSignature renders a signature status for humans.
+func describeSignature(sig storage.SignatureStatus) string {
+	switch sig.Status {
+	case "unsigned":
+		return "unsigned"
+	case "good":
+		return fmt.Sprintf("good %s signature by %s", sig.Format, sig.Signer)
+	case "bad":
+		return fmt.Sprintf("bad %s signature: metadata or checksum changed since signing", sig.Format)
+	case "unverifiable":
+		return fmt.Sprintf("%s signature from an unknown key", sig.Format)
+	default:
+		return fmt.Sprintf("%s signature (%s)", sig.Format, sig.Status)
+	}
+}
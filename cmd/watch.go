@@ -0,0 +1,162 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/re-cinq/shift-log/internal/agent"
+	"github.com/re-cinq/shift-log/internal/cli"
+	"github.com/re-cinq/shift-log/internal/git"
+	"github.com/re-cinq/shift-log/internal/storage"
+	"github.com/spf13/cobra"
+)
+
+var (
+	watchInterval  time.Duration
+	watchAgentFlag string
+	watchOnce      bool
+)
+
+var watchCmd = &cobra.Command{
+	Use:     "watch",
+	Short:   "Checkpoint the active conversation between commits",
+	GroupID: "human",
+	Long: `Watches the coding agent's active session and, whenever its transcript
+changes, saves a checkpoint of the conversation in ` + git.CheckpointsRef + `,
+keyed by the current HEAD. If the agent crashes after a long stretch of work
+without a commit, the conversation is not lost: the next commit stores the
+checkpoint, unless the session itself gets stored on it.
+
+Runs until interrupted. With --once, takes a single checkpoint and exits.
+
+Examples:
+  shiftlog watch                  # Checkpoint every 30 seconds
+  shiftlog watch --interval 5m    # Checkpoint less often
+  shiftlog watch --agent codex    # Watch a Codex CLI session`,
+	RunE: runWatch,
+}
+
+func init() {
+	watchCmd.Flags().DurationVar(&watchInterval, "interval", 30*time.Second, "How often to check the transcript for changes")
+	watchCmd.Flags().StringVar(&watchAgentFlag, "agent", "", "Coding agent to watch. Defaults to configured agent.")
+	watchCmd.Flags().BoolVar(&watchOnce, "once", false, "Take a single checkpoint and exit")
+	rootCmd.AddCommand(watchCmd)
+}
+
+func runWatch(cmd *cobra.Command, args []string) error {
+	if err := git.RequireGitRepo(); err != nil {
+		return err
+	}
+	if watchInterval <= 0 {
+		return fmt.Errorf("--interval must be positive")
+	}
+
+	ag, err := resolveAgent(watchAgentFlag)
+	if err != nil {
+		return err
+	}
+	projectPath, err := git.GetRepoRoot()
+	if err != nil {
+		return fmt.Errorf("could not determine repository root: %w", err)
+	}
+	applyGraceWindow()
+
+	if watchOnce {
+		if reason := captureDisabled(); reason != "" {
+			cli.LogInfo("capture is off (%s): not checkpointing", reason)
+			return nil
+		}
+		checkpoint, err := checkpointSession(ag, projectPath, "")
+		if err == nil && checkpoint == "" {
+			cli.LogInfo("no active %s session found", ag.DisplayName())
+		}
+		return err
+	}
+
+	cli.LogInfo("watching %s sessions, checkpointing every %s", ag.DisplayName(), watchInterval)
+	var last string
+	for {
+		if checkpoint, err := checkpointSession(ag, projectPath, last); err != nil {
+			cli.LogWarning("%v", err)
+		} else {
+			last = checkpoint
+		}
+		time.Sleep(watchInterval)
+	}
+}
+
+// checkpointSession saves a checkpoint of the agent's active session for
+// HEAD. It returns an identifier of the checkpoint, and skips saving when it
+// equals previous because neither HEAD nor the transcript changed.
+func checkpointSession(ag agent.Agent, projectPath, previous string) (string, error) {
+	if reason := captureDisabled(); reason != "" {
+		cli.LogDebug("watch: capture is off (%s)", reason)
+		return previous, nil
+	}
+	session, err := ag.DiscoverSession(projectPath)
+	if err != nil {
+		return previous, fmt.Errorf("session discovery failed: %w", err)
+	}
+	if session == nil {
+		cli.LogDebug("watch: no active session found")
+		return previous, nil
+	}
+
+	head, err := git.GetHeadCommit()
+	if err != nil {
+		return previous, fmt.Errorf("failed to get HEAD commit: %w", err)
+	}
+
+	transcriptData, err := readSessionTranscript(session)
+	if err != nil {
+		return previous, err
+	}
+
+	checkpoint := head + ":" + session.SessionID + ":" + storage.Checksum(transcriptData)
+	if checkpoint == previous {
+		cli.LogDebug("watch: session %s unchanged", session.SessionID)
+		return previous, nil
+	}
+
+	stored, err := newCheckpoint(ag, session, transcriptData, projectPath, storage.TriggerWatch)
+	if err != nil {
+		return previous, err
+	}
+	if err := storage.SaveCheckpoint(head, stored); err != nil {
+		return previous, fmt.Errorf("failed to save checkpoint: %w", err)
+	}
+	cli.LogInfo("checkpointed session %s (%d messages) on %s", session.SessionID, stored.MessageCount, head[:8])
+	return checkpoint, nil
+}
+
+// readSessionTranscript returns a discovered session's transcript data.
+func readSessionTranscript(session *agent.SessionInfo) ([]byte, error) {
+	if len(session.TranscriptData) > 0 {
+		return session.TranscriptData, nil
+	}
+	data, err := readTranscriptData(session.TranscriptPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read transcript: %w", err)
+	}
+	return data, nil
+}
+
+// newCheckpoint creates the checkpoint of a session from its transcript,
+// recording trigger as the way it will have been stored.
+func newCheckpoint(ag agent.Agent, session *agent.SessionInfo, transcriptData []byte, projectPath, trigger string) (*storage.StoredConversation, error) {
+	transcript, err := ag.ParseTranscript(strings.NewReader(string(transcriptData)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse transcript: %w", err)
+	}
+	branch, _ := git.GetCurrentBranch()
+
+	stored, err := storage.NewStoredConversation(session.SessionID, projectPath, branch, transcript.MessageCount(), omitLargeAttachments(transcriptData))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create checkpoint: %w", err)
+	}
+	stored.Agent = string(ag.Name())
+	stored.Model = transcript.Model
+	stored.Provenance = buildProvenance(ag, transcript, trigger)
+	return stored, nil
+}
@@ -0,0 +1,124 @@
+// Package editor is the contract of the context API of 'shiftlog serve',
+// which editor plugins query for the conversation behind a line of code,
+// and a reference client of it.
+//
+// GET /api/context?file=<path>&line=<n> blames the line of the file in the
+// served repository's working tree and returns a LineContext: the commit
+// that last changed it and the conversations stored for that commit, with
+// the part of each in which the agent edited the file. file is relative to
+// the repository root, or absolute within it; line starts at 1.
+//
+// The contract is stable: within a Version, fields are only ever added,
+// never removed or given another meaning, so clients must ignore the
+// fields they do not know. Errors are answered with a 4xx or 5xx status
+// and a body of {"error": "<message>"}: 400 for a missing or invalid
+// parameter, 404 for a file or line git cannot blame.
+package editor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Version is the version of the contract, LineContext.Version.
+const Version = 1
+
+// Path is the path of the context API.
+const Path = "/api/context"
+
+// LineContext is the response of the context API.
+type LineContext struct {
+	Version int    `json:"version"`
+	File    string `json:"file"` // relative to the repository root, slash-separated
+	Line    int    `json:"line"`
+	// Commit is the commit that last changed the line, empty when the line
+	// has uncommitted changes. Subject, Author and Date are its subject,
+	// author and committer date (RFC 3339, UTC).
+	Commit  string `json:"commit,omitempty"`
+	Subject string `json:"subject,omitempty"`
+	Author  string `json:"author,omitempty"`
+	Date    string `json:"date,omitempty"`
+	// Conversations are those stored for Commit, empty when it has none.
+	Conversations []Conversation `json:"conversations"`
+	// URL is the path of the commit in the server's web viewer, to be
+	// resolved against the server's URL.
+	URL string `json:"url,omitempty"`
+	// Markdown is a summary of the above ready to be shown in a hover.
+	Markdown string `json:"markdown"`
+}
+
+// Conversation is a conversation stored for the commit of a line.
+type Conversation struct {
+	// Index is the conversation's index among the commit's, as the
+	// viewer's and /api/commits/<sha>'s ?conversation= parameter takes it.
+	Index        int    `json:"index"`
+	SessionID    string `json:"session_id"`
+	Agent        string `json:"agent"`
+	Model        string `json:"model,omitempty"`
+	MessageCount int    `json:"message_count"`
+	Summary      string `json:"summary,omitempty"`
+	// Excerpt are the messages around the agent's edits of the file in the
+	// part of the conversation that led to the commit, empty if the agent
+	// did not edit it.
+	Excerpt []Message `json:"excerpt"`
+}
+
+// Message is a message of a conversation excerpt.
+type Message struct {
+	Role  string   `json:"role"` // "user" or "assistant"
+	Text  string   `json:"text,omitempty"`
+	Tools []string `json:"tools,omitempty"` // names of the tools the message called
+}
+
+// Client queries the context API of a shiftlog serve.
+type Client struct {
+	baseURL string
+	http    *http.Client
+}
+
+// NewClient returns a client of the server at baseURL, e.g.
+// http://localhost:8080.
+func NewClient(baseURL string) *Client {
+	return &Client{baseURL: strings.TrimSuffix(baseURL, "/"), http: &http.Client{Timeout: 10 * time.Second}}
+}
+
+// Context returns the context of a line of file.
+func (c *Client) Context(ctx context.Context, file string, line int) (*LineContext, error) {
+	query := url.Values{"file": {file}, "line": {strconv.Itoa(line)}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+Path+"?"+query.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		var body struct {
+			Error string `json:"error"`
+		}
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		if json.Unmarshal(data, &body) != nil || body.Error == "" {
+			body.Error = strings.TrimSpace(string(data))
+		}
+		return nil, fmt.Errorf("%s: %s", resp.Status, body.Error)
+	}
+	var lc LineContext
+	if err := json.NewDecoder(resp.Body).Decode(&lc); err != nil {
+		return nil, err
+	}
+	if lc.Version != Version {
+		return nil, fmt.Errorf("unsupported context API version %d, want %d", lc.Version, Version)
+	}
+	return &lc, nil
+}
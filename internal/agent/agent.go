@@ -9,9 +9,14 @@ import (
 	"time"
 )
 
-// RecentSessionTimeout is the default timeout for considering a session "recent"
-// during session discovery across all agents.
-const RecentSessionTimeout = 5 * time.Minute
+// DefaultRecentSessionTimeout is the default timeout for considering a
+// session "recent" during session discovery across all agents.
+const DefaultRecentSessionTimeout = 5 * time.Minute
+
+// RecentSessionTimeout is the timeout for considering a session "recent"
+// during session discovery across all agents. Store widens it to the
+// configured grace window.
+var RecentSessionTimeout = DefaultRecentSessionTimeout
 
 // IsGitCommitCommand checks whether a shell command string represents a git commit.
 func IsGitCommitCommand(command string) bool {
@@ -19,6 +24,12 @@ func IsGitCommitCommand(command string) bool {
 		strings.Contains(command, "git-commit")
 }
 
+// IsGitMergeCommand checks whether a shell command string represents a git merge.
+func IsGitMergeCommand(command string) bool {
+	return strings.Contains(command, "git merge") ||
+		strings.Contains(command, "git-merge")
+}
+
 // PathsEqual compares two filesystem paths after resolving symlinks.
 // Falls back to filepath.Clean comparison if symlink resolution fails.
 func PathsEqual(a, b string) bool {
@@ -177,6 +188,9 @@ const (
 	Copilot  Name = "copilot"
 	Gemini   Name = "gemini"
 	OpenCode Name = "opencode"
+	Goose    Name = "goose"
+	AmazonQ  Name = "amazonq"
+	Windsurf Name = "windsurf"
 )
 
 // DiagnosticCheck represents a single doctor check result.
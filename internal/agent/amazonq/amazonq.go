@@ -0,0 +1,408 @@
+package amazonq
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/re-cinq/shift-log/internal/agent"
+)
+
+func init() {
+	agent.Register(&Agent{})
+}
+
+// Agent implements the agent.Agent interface for Amazon Q Developer CLI.
+type Agent struct{}
+
+func (a *Agent) Name() agent.Name    { return agent.AmazonQ }
+func (a *Agent) DisplayName() string { return "Amazon Q CLI" }
+
+// ConfigureHooks sets up an Amazon Q CLI agent with a postToolUse hook in
+// .amazonq/cli-agents/shiftlog.json.
+func (a *Agent) ConfigureHooks(repoRoot string) error {
+	cfg, err := ReadAgentConfig(repoRoot)
+	if err != nil {
+		return fmt.Errorf("failed to read Amazon Q agent configuration: %w", err)
+	}
+
+	AddShiftlogHook(cfg)
+
+	if err := WriteAgentConfig(repoRoot, cfg); err != nil {
+		return fmt.Errorf("failed to write Amazon Q agent configuration: %w", err)
+	}
+	return nil
+}
+
+// RemoveHooks removes shiftlog hooks from the Amazon Q CLI agent. The agent
+// configuration is shiftlog-owned, so it is deleted once no hooks remain.
+func (a *Agent) RemoveHooks(repoRoot string) error {
+	cfg, err := ReadAgentConfig(repoRoot)
+	if err != nil {
+		return nil // unreadable configuration means nothing to remove
+	}
+
+	RemoveShiftlogHook(cfg)
+
+	if len(cfg.Hooks) == 0 {
+		path := agentConfigPath(repoRoot)
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+
+	return WriteAgentConfig(repoRoot, cfg)
+}
+
+// DiagnoseHooks validates the Amazon Q CLI agent configuration.
+func (a *Agent) DiagnoseHooks(repoRoot string) []agent.DiagnosticCheck {
+	var checks []agent.DiagnosticCheck
+
+	data, err := os.ReadFile(agentConfigPath(repoRoot))
+	if err != nil {
+		checks = append(checks, agent.DiagnosticCheck{
+			Name:    "Amazon Q CLI agent configuration",
+			OK:      false,
+			Message: "No .amazonq/cli-agents/shiftlog.json found. Run 'shiftlog init --agent=amazonq' to configure.",
+		})
+		return checks
+	}
+
+	var raw map[string]interface{}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		checks = append(checks, agent.DiagnosticCheck{
+			Name:    "Amazon Q CLI agent configuration",
+			OK:      false,
+			Message: fmt.Sprintf("Invalid JSON in .amazonq/cli-agents/shiftlog.json: %v", err),
+		})
+		return checks
+	}
+
+	hooks, _ := raw["hooks"].(map[string]interface{})
+	if !agent.HasFlatHookCommand(hooks["postToolUse"], "shiftlog store") {
+		checks = append(checks, agent.DiagnosticCheck{
+			Name:    "postToolUse hook",
+			OK:      false,
+			Message: "'shiftlog store' hook not found in postToolUse. Run 'shiftlog init --agent=amazonq' to fix.",
+		})
+	} else {
+		checks = append(checks, agent.DiagnosticCheck{
+			Name:    "postToolUse hook",
+			OK:      true,
+			Message: "Found postToolUse hook configuration (use it with 'q chat --agent shiftlog')",
+		})
+	}
+
+	return checks
+}
+
+// ParseHookInput parses Amazon Q CLI's postToolUse hook JSON:
+//
+//	{"hook_event_name":"postToolUse", "cwd":"...", "tool_name":"execute_bash", "tool_input":{"command":"..."}}
+//
+// The hook carries no session, so the conversation is looked up by the
+// working directory. Input with a session_id and transcript_path is used as is.
+func (a *Agent) ParseHookInput(raw []byte) (*agent.HookData, error) {
+	hook, err := agent.ParseStandardHookInput(raw)
+	if err != nil {
+		return nil, err
+	}
+	if hook.SessionID != "" {
+		return hook, nil
+	}
+
+	var native struct {
+		CWD string `json:"cwd"`
+	}
+	_ = json.Unmarshal(raw, &native)
+	if native.CWD != "" {
+		if si := discoverConversation(native.CWD, agent.RecentSessionTimeout); si != nil {
+			hook.SessionID = si.SessionID
+			hook.TranscriptData = si.TranscriptData
+		}
+	}
+	return hook, nil
+}
+
+// shellToolNames are the tool names Amazon Q CLI uses for shell execution.
+var shellToolNames = map[string]bool{
+	"execute_bash": true,
+	"execute_cmd":  true, // Windows
+}
+
+// IsCommitCommand checks if a tool invocation represents a git commit.
+func (a *Agent) IsCommitCommand(toolName, command string) bool {
+	if !shellToolNames[toolName] {
+		return false
+	}
+	return agent.IsGitCommitCommand(command)
+}
+
+// conversation is the conversation state Amazon Q CLI stores per directory.
+type conversation struct {
+	ConversationID string `json:"conversation_id"`
+	History        []turn `json:"history"`
+	Model          string `json:"-"`
+}
+
+// turn is a user message and the assistant's reply. Older versions store
+// it as a [user, assistant] pair, newer ones as an object.
+type turn struct {
+	User      userMessage
+	Assistant assistantMessage
+}
+
+func (t *turn) UnmarshalJSON(data []byte) error {
+	var pair []json.RawMessage
+	if err := json.Unmarshal(data, &pair); err == nil {
+		if len(pair) > 0 {
+			_ = json.Unmarshal(pair[0], &t.User)
+		}
+		if len(pair) > 1 {
+			_ = json.Unmarshal(pair[1], &t.Assistant)
+		}
+		return nil
+	}
+	var obj struct {
+		User      userMessage      `json:"user"`
+		Assistant assistantMessage `json:"assistant"`
+	}
+	if err := json.Unmarshal(data, &obj); err != nil {
+		return err
+	}
+	t.User, t.Assistant = obj.User, obj.Assistant
+	return nil
+}
+
+type userMessage struct {
+	Content struct {
+		Prompt *struct {
+			Prompt string `json:"prompt"`
+		} `json:"Prompt"`
+		ToolUseResults *struct {
+			ToolUseResults []toolUseResult `json:"tool_use_results"`
+		} `json:"ToolUseResults"`
+		CancelledToolUses *struct {
+			Prompt         string          `json:"prompt"`
+			ToolUseResults []toolUseResult `json:"tool_use_results"`
+		} `json:"CancelledToolUses"`
+	} `json:"content"`
+	Timestamp string `json:"timestamp"`
+}
+
+type toolUseResult struct {
+	ToolUseID string            `json:"tool_use_id"`
+	Content   []json.RawMessage `json:"content"`
+	Status    string            `json:"status"`
+}
+
+type assistantMessage struct {
+	Response *assistantContent `json:"Response"`
+	ToolUse  *assistantContent `json:"ToolUse"`
+}
+
+type assistantContent struct {
+	MessageID string `json:"message_id"`
+	Content   string `json:"content"`
+	ToolUses  []struct {
+		ID   string          `json:"id"`
+		Name string          `json:"name"`
+		Args json.RawMessage `json:"args"`
+	} `json:"tool_uses"`
+}
+
+// parseConversation decodes a stored conversation, reading the model from
+// "model" or, in newer versions, "model_info".
+func parseConversation(data []byte) (*conversation, error) {
+	var conv conversation
+	if err := json.Unmarshal(data, &conv); err != nil {
+		return nil, err
+	}
+	var model struct {
+		Model     json.RawMessage `json:"model"`
+		ModelInfo *struct {
+			ModelID string `json:"model_id"`
+		} `json:"model_info"`
+	}
+	if json.Unmarshal(data, &model) == nil {
+		_ = json.Unmarshal(model.Model, &conv.Model)
+		if conv.Model == "" && model.ModelInfo != nil {
+			conv.Model = model.ModelInfo.ModelID
+		}
+	}
+	return &conv, nil
+}
+
+// ParseTranscript parses an Amazon Q CLI conversation. Amazon Q does not
+// record token usage, so Usage is left empty.
+func (a *Agent) ParseTranscript(r io.Reader) (*agent.Transcript, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(string(data)) == "" {
+		return &agent.Transcript{}, nil
+	}
+
+	conv, err := parseConversation(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse Amazon Q conversation: %w", err)
+	}
+
+	var entries []agent.TranscriptEntry
+	add := func(msgType agent.MessageType, uuid, timestamp string, blocks []agent.ContentBlock) {
+		if len(blocks) == 0 {
+			return
+		}
+		if uuid == "" {
+			uuid = fmt.Sprintf("amazonq-%d", len(entries))
+		}
+		entries = append(entries, agent.TranscriptEntry{
+			Type:      msgType,
+			UUID:      uuid,
+			Timestamp: timestamp,
+			Message:   &agent.Message{Role: string(msgType), Content: blocks},
+		})
+	}
+
+	for _, t := range conv.History {
+		add(agent.MessageTypeUser, "", t.User.Timestamp, userBlocks(t.User))
+
+		reply := t.Assistant.Response
+		if reply == nil {
+			reply = t.Assistant.ToolUse
+		}
+		if reply != nil {
+			add(agent.MessageTypeAssistant, reply.MessageID, "", assistantBlocks(reply))
+		}
+	}
+
+	transcript := &agent.Transcript{Entries: entries, Model: conv.Model}
+	transcript.Turns = transcript.CountTurns()
+	return transcript, nil
+}
+
+// userBlocks converts a user message: a prompt becomes text, and tool use
+// results become tool_result blocks.
+func userBlocks(m userMessage) []agent.ContentBlock {
+	var blocks []agent.ContentBlock
+	var results []toolUseResult
+	switch c := m.Content; {
+	case c.Prompt != nil:
+		if c.Prompt.Prompt != "" {
+			blocks = append(blocks, agent.ContentBlock{Type: "text", Text: c.Prompt.Prompt})
+		}
+	case c.ToolUseResults != nil:
+		results = c.ToolUseResults.ToolUseResults
+	case c.CancelledToolUses != nil:
+		if c.CancelledToolUses.Prompt != "" {
+			blocks = append(blocks, agent.ContentBlock{Type: "text", Text: c.CancelledToolUses.Prompt})
+		}
+		results = c.CancelledToolUses.ToolUseResults
+	}
+
+	for _, r := range results {
+		content, _ := json.Marshal(toolResultText(r.Content))
+		blocks = append(blocks, agent.ContentBlock{
+			Type:      "tool_result",
+			ToolUseID: r.ToolUseID,
+			Content:   content,
+		})
+	}
+	return blocks
+}
+
+// toolResultText joins the items of a tool result, which are either
+// {"Text":"..."} or {"Json":{...}}.
+func toolResultText(items []json.RawMessage) string {
+	var parts []string
+	for _, raw := range items {
+		var item struct {
+			Text *string         `json:"Text"`
+			JSON json.RawMessage `json:"Json"`
+		}
+		if json.Unmarshal(raw, &item) != nil {
+			continue
+		}
+		switch {
+		case item.Text != nil:
+			parts = append(parts, *item.Text)
+		case len(item.JSON) > 0:
+			parts = append(parts, string(item.JSON))
+		}
+	}
+	return strings.Join(parts, "\n")
+}
+
+// assistantBlocks converts an assistant reply into text and tool_use blocks.
+func assistantBlocks(c *assistantContent) []agent.ContentBlock {
+	var blocks []agent.ContentBlock
+	if c.Content != "" {
+		blocks = append(blocks, agent.ContentBlock{Type: "text", Text: c.Content})
+	}
+	for _, tu := range c.ToolUses {
+		blocks = append(blocks, agent.ContentBlock{
+			Type:  "tool_use",
+			ID:    tu.ID,
+			Name:  tu.Name,
+			Input: tu.Args,
+		})
+	}
+	return blocks
+}
+
+// ParseTranscriptFile parses an Amazon Q CLI conversation exported to a file,
+// e.g. with /save.
+func (a *Agent) ParseTranscriptFile(path string) (*agent.Transcript, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+	return a.ParseTranscript(f)
+}
+
+// DiscoverSession finds the recent Amazon Q CLI conversation for the project.
+func (a *Agent) DiscoverSession(projectPath string) (*agent.SessionInfo, error) {
+	return discoverConversation(filepath.Clean(projectPath), agent.RecentSessionTimeout), nil
+}
+
+// RestoreSession makes the conversation the current one for the project in
+// the Amazon Q CLI database.
+func (a *Agent) RestoreSession(projectPath, sessionID, gitBranch string,
+	transcriptData []byte, messageCount int, summary string) error {
+
+	return WriteConversation(projectPath, sessionID, transcriptData)
+}
+
+// ResumeCommand returns the command to resume an Amazon Q CLI conversation.
+// Amazon Q resumes the conversation of the current directory, which
+// RestoreSession has set.
+func (a *Agent) ResumeCommand(sessionID string) (string, []string) {
+	return "q", []string{"chat", "--resume"}
+}
+
+// VersionCommand returns the command that prints the Amazon Q CLI version.
+func (a *Agent) VersionCommand() (string, []string) {
+	return "q", []string{"--version"}
+}
+
+// SummariseCommand returns the command to run Amazon Q CLI non-interactively.
+func (a *Agent) SummariseCommand() (string, []string) {
+	return "q", []string{"chat", "--no-interactive"}
+}
+
+// ToolAliases returns Amazon Q CLI's tool name mappings to canonical names.
+func (a *Agent) ToolAliases() map[string]string {
+	return map[string]string{
+		"execute_bash": "Bash",
+		"execute_cmd":  "Bash",
+		"fs_read":      "Read",
+		"fs_write":     "Edit",
+		"todo_list":    "TodoWrite",
+	}
+}
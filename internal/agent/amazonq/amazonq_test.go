@@ -0,0 +1,346 @@
+package amazonq
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/re-cinq/shift-log/internal/agent"
+)
+
+func TestAgentName(t *testing.T) {
+	a := &Agent{}
+	if a.Name() != agent.AmazonQ {
+		t.Errorf("Name() = %q, want %q", a.Name(), agent.AmazonQ)
+	}
+	if a.DisplayName() != "Amazon Q CLI" {
+		t.Errorf("DisplayName() = %q, want %q", a.DisplayName(), "Amazon Q CLI")
+	}
+}
+
+func TestIsCommitCommand(t *testing.T) {
+	a := &Agent{}
+	tests := []struct {
+		tool, cmd string
+		want      bool
+	}{
+		{"execute_bash", "git commit -m fix", true},
+		{"execute_cmd", "git commit -am msg", true},
+		{"execute_bash", "git status", false},
+		{"fs_write", "git commit -m test", false},
+	}
+	for _, tc := range tests {
+		if got := a.IsCommitCommand(tc.tool, tc.cmd); got != tc.want {
+			t.Errorf("IsCommitCommand(%q, %q) = %v, want %v", tc.tool, tc.cmd, got, tc.want)
+		}
+	}
+}
+
+const sampleConversation = `{
+  "conversation_id": "conv-1",
+  "history": [
+    {
+      "user": {"content": {"Prompt": {"prompt": "Add a greeting"}}, "timestamp": "2025-07-01T10:00:00.123+02:00"},
+      "assistant": {"ToolUse": {"message_id": "msg-1", "content": "Writing it.", "tool_uses": [
+        {"id": "tooluse_1", "name": "fs_write", "args": {"command": "create", "path": "/p/hello.txt", "file_text": "hi\n"}}
+      ]}}
+    },
+    {
+      "user": {"content": {"ToolUseResults": {"tool_use_results": [
+        {"tool_use_id": "tooluse_1", "content": [{"Text": "created"}, {"Json": {"ok": true}}], "status": "Success"}
+      ]}}, "timestamp": "2025-07-01T10:00:05+02:00"},
+      "assistant": {"Response": {"message_id": "msg-2", "content": "Done."}}
+    }
+  ],
+  "model_info": {"model_name": "claude-sonnet-4", "model_id": "claude-sonnet-4"}
+}`
+
+func TestParseTranscript(t *testing.T) {
+	a := &Agent{}
+	transcript, err := a.ParseTranscript(strings.NewReader(sampleConversation))
+	if err != nil {
+		t.Fatalf("ParseTranscript() error: %v", err)
+	}
+
+	if len(transcript.Entries) != 4 {
+		t.Fatalf("got %d entries, want 4", len(transcript.Entries))
+	}
+	if transcript.Model != "claude-sonnet-4" {
+		t.Errorf("Model = %q, want claude-sonnet-4", transcript.Model)
+	}
+	if transcript.Turns != 1 {
+		t.Errorf("Turns = %d, want 1", transcript.Turns)
+	}
+
+	prompt := transcript.Entries[0]
+	if prompt.Type != agent.MessageTypeUser || prompt.Message.Content[0].Text != "Add a greeting" {
+		t.Errorf("first entry = %+v", prompt)
+	}
+	if prompt.UUID != "amazonq-0" || prompt.Timestamp != "2025-07-01T10:00:00.123+02:00" {
+		t.Errorf("first entry UUID/Timestamp = %q/%q", prompt.UUID, prompt.Timestamp)
+	}
+
+	reply := transcript.Entries[1]
+	if reply.UUID != "msg-1" || len(reply.Message.Content) != 2 {
+		t.Fatalf("tool use entry = %+v", reply)
+	}
+	use := reply.Message.Content[1]
+	if use.Type != "tool_use" || use.ID != "tooluse_1" || use.Name != "fs_write" {
+		t.Errorf("tool_use block = %+v", use)
+	}
+
+	result := transcript.Entries[2].Message.Content[0]
+	if result.Type != "tool_result" || result.ToolUseID != "tooluse_1" || string(result.Content) != `"created\n{\"ok\": true}"` {
+		t.Errorf("tool_result block = %+v (content %s)", result, result.Content)
+	}
+
+	files := agent.EditedFiles(transcript.Entries, a.ToolAliases())
+	if len(files) != 1 || files[0] != "/p/hello.txt" {
+		t.Errorf("EditedFiles() = %v, want [/p/hello.txt]", files)
+	}
+}
+
+func TestParseTranscriptLegacyPairs(t *testing.T) {
+	a := &Agent{}
+	legacy := `{"conversation_id":"conv-old","model":"q-model","history":[[
+		{"content":{"Prompt":{"prompt":"hello"}}},
+		{"Response":{"message_id":"m1","content":"hi there"}}
+	]]}`
+
+	transcript, err := a.ParseTranscript(strings.NewReader(legacy))
+	if err != nil {
+		t.Fatalf("ParseTranscript() error: %v", err)
+	}
+	if len(transcript.Entries) != 2 {
+		t.Fatalf("got %d entries, want 2", len(transcript.Entries))
+	}
+	if transcript.Model != "q-model" {
+		t.Errorf("Model = %q, want q-model", transcript.Model)
+	}
+	if transcript.Entries[1].Message.Content[0].Text != "hi there" {
+		t.Errorf("assistant text = %q", transcript.Entries[1].Message.Content[0].Text)
+	}
+}
+
+func TestParseTranscriptEmpty(t *testing.T) {
+	a := &Agent{}
+	transcript, err := a.ParseTranscript(strings.NewReader(""))
+	if err != nil {
+		t.Fatalf("ParseTranscript() error: %v", err)
+	}
+	if len(transcript.Entries) != 0 {
+		t.Errorf("got %d entries, want 0", len(transcript.Entries))
+	}
+}
+
+func TestToolAliases(t *testing.T) {
+	aliases := (&Agent{}).ToolAliases()
+	for tool, want := range map[string]string{"execute_bash": "Bash", "fs_read": "Read", "fs_write": "Edit"} {
+		if aliases[tool] != want {
+			t.Errorf("ToolAliases()[%q] = %q, want %q", tool, aliases[tool], want)
+		}
+	}
+}
+
+func TestResumeCommand(t *testing.T) {
+	bin, args := (&Agent{}).ResumeCommand("conv-1")
+	if bin != "q" || strings.Join(args, " ") != "chat --resume" {
+		t.Errorf("ResumeCommand() = %s %v", bin, args)
+	}
+}
+
+func TestConfigureHooks(t *testing.T) {
+	a := &Agent{}
+	tmpDir := t.TempDir()
+
+	if err := a.ConfigureHooks(tmpDir); err != nil {
+		t.Fatalf("ConfigureHooks() error: %v", err)
+	}
+	// Idempotent
+	if err := a.ConfigureHooks(tmpDir); err != nil {
+		t.Fatalf("ConfigureHooks() second call error: %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(tmpDir, ".amazonq", "cli-agents", "shiftlog.json"))
+	if err != nil {
+		t.Fatalf("agent configuration not written: %v", err)
+	}
+	var cfg struct {
+		Name  string                 `json:"name"`
+		Tools []string               `json:"tools"`
+		Hooks map[string][]HookEntry `json:"hooks"`
+	}
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if cfg.Name != "shiftlog" || len(cfg.Tools) != 1 || cfg.Tools[0] != "*" {
+		t.Errorf("agent = %q with tools %v", cfg.Name, cfg.Tools)
+	}
+	hooks := cfg.Hooks["postToolUse"]
+	if len(hooks) != 1 {
+		t.Fatalf("got %d postToolUse hooks, want 1", len(hooks))
+	}
+	if hooks[0].Matcher != "execute_bash" || hooks[0].Command != "shiftlog store --agent=amazonq" {
+		t.Errorf("hook = %+v", hooks[0])
+	}
+
+	checks := a.DiagnoseHooks(tmpDir)
+	if len(checks) != 1 || !checks[0].OK {
+		t.Errorf("DiagnoseHooks() = %+v, want one passing check", checks)
+	}
+
+	if err := a.RemoveHooks(tmpDir); err != nil {
+		t.Fatalf("RemoveHooks() error: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(tmpDir, ".amazonq", "cli-agents", "shiftlog.json")); !os.IsNotExist(err) {
+		t.Error("RemoveHooks() left the shiftlog agent configuration behind")
+	}
+}
+
+func TestRemoveHooksKeepsOtherHooks(t *testing.T) {
+	a := &Agent{}
+	tmpDir := t.TempDir()
+
+	cfg, _ := ReadAgentConfig(tmpDir)
+	cfg.Hooks["agentSpawn"] = []HookEntry{{Command: "git status"}}
+	AddShiftlogHook(cfg)
+	if err := WriteAgentConfig(tmpDir, cfg); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := a.RemoveHooks(tmpDir); err != nil {
+		t.Fatalf("RemoveHooks() error: %v", err)
+	}
+	cfg, err := ReadAgentConfig(tmpDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cfg.Hooks["postToolUse"]) != 0 || len(cfg.Hooks["agentSpawn"]) != 1 {
+		t.Errorf("hooks after removal = %+v", cfg.Hooks)
+	}
+	if cfg.Other["name"] != "shiftlog" {
+		t.Errorf("agent name lost: %v", cfg.Other["name"])
+	}
+}
+
+func TestGetDataDir(t *testing.T) {
+	if runtime.GOOS == "darwin" {
+		t.Skip("XDG_DATA_HOME is not used on macOS")
+	}
+	t.Setenv("XDG_DATA_HOME", "/custom/data")
+	path, err := GetDatabasePath()
+	if err != nil {
+		t.Fatalf("GetDatabasePath() error: %v", err)
+	}
+	if path != "/custom/data/amazon-q/data.sqlite3" {
+		t.Errorf("GetDatabasePath() = %q", path)
+	}
+}
+
+// createDatabase creates an Amazon Q CLI database in a temporary data
+// directory with the given schema.
+func createDatabase(t *testing.T, schema string) string {
+	t.Helper()
+	if _, err := exec.LookPath("sqlite3"); err != nil {
+		t.Skip("sqlite3 not available")
+	}
+	if runtime.GOOS == "darwin" {
+		t.Setenv("HOME", t.TempDir())
+	} else {
+		t.Setenv("XDG_DATA_HOME", t.TempDir())
+	}
+	dbPath, _ := GetDatabasePath()
+	if err := os.MkdirAll(filepath.Dir(dbPath), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := runSQL(dbPath, schema); err != nil {
+		t.Fatalf("creating database: %v", err)
+	}
+	return dbPath
+}
+
+const schemaV2 = `CREATE TABLE conversations_v2 (key TEXT NOT NULL, conversation_id TEXT NOT NULL, value TEXT NOT NULL, created_at INTEGER NOT NULL, updated_at INTEGER NOT NULL, PRIMARY KEY (key, conversation_id));`
+
+func TestRestoreAndDiscoverV2(t *testing.T) {
+	createDatabase(t, schemaV2)
+	a := &Agent{}
+
+	if err := a.RestoreSession("/p/o'neil", "conv-1", "main", []byte(sampleConversation), 4, ""); err != nil {
+		t.Fatalf("RestoreSession() error: %v", err)
+	}
+
+	info, err := a.DiscoverSession("/p/o'neil")
+	if err != nil || info == nil {
+		t.Fatalf("DiscoverSession() = %v, %v", info, err)
+	}
+	if info.SessionID != "conv-1" {
+		t.Errorf("SessionID = %q, want conv-1", info.SessionID)
+	}
+	if string(info.TranscriptData) != sampleConversation {
+		t.Errorf("TranscriptData was not restored verbatim:\n%s", info.TranscriptData)
+	}
+
+	if info, _ := a.DiscoverSession("/elsewhere"); info != nil {
+		t.Errorf("DiscoverSession(/elsewhere) = %+v, want nil", info)
+	}
+}
+
+func TestDiscoverV2SkipsStaleConversation(t *testing.T) {
+	dbPath := createDatabase(t, schemaV2)
+	stale := time.Now().Add(-2 * agent.RecentSessionTimeout).UnixMilli()
+	if _, err := runSQL(dbPath, fmt.Sprintf(
+		"INSERT INTO conversations_v2 VALUES ('/p', 'conv-1', %s, %d, %d);",
+		quote(sampleConversation), stale, stale)); err != nil {
+		t.Fatal(err)
+	}
+
+	if info, _ := (&Agent{}).DiscoverSession("/p"); info != nil {
+		t.Errorf("DiscoverSession() = %+v, want nil for a stale conversation", info)
+	}
+}
+
+func TestRestoreAndDiscoverV1(t *testing.T) {
+	createDatabase(t, `CREATE TABLE conversations (key TEXT PRIMARY KEY, value TEXT);`)
+	a := &Agent{}
+
+	// The legacy table has no update time: recency comes from the messages
+	now := time.Now().Format(time.RFC3339Nano)
+	conversation := fmt.Sprintf(`{"conversation_id":"conv-2","history":[{"user":{"content":{"Prompt":{"prompt":"hi"}},"timestamp":%q},"assistant":{"Response":{"message_id":"m","content":"hello"}}}]}`, now)
+	if err := a.RestoreSession("/p", "conv-2", "main", []byte(conversation), 2, ""); err != nil {
+		t.Fatalf("RestoreSession() error: %v", err)
+	}
+
+	info, err := a.DiscoverSession("/p")
+	if err != nil || info == nil {
+		t.Fatalf("DiscoverSession() = %v, %v", info, err)
+	}
+	if info.SessionID != "conv-2" {
+		t.Errorf("SessionID = %q, want conv-2", info.SessionID)
+	}
+
+	hook, err := a.ParseHookInput([]byte(`{"hook_event_name":"postToolUse","cwd":"/p","tool_name":"execute_bash","tool_input":{"command":"git commit -m x"}}`))
+	if err != nil {
+		t.Fatalf("ParseHookInput() error: %v", err)
+	}
+	if hook.SessionID != "conv-2" || len(hook.TranscriptData) == 0 {
+		t.Errorf("hook session = %q with %d bytes, want conv-2 transcript", hook.SessionID, len(hook.TranscriptData))
+	}
+	if hook.ToolName != "execute_bash" || hook.Command != "git commit -m x" {
+		t.Errorf("hook tool = %q %q", hook.ToolName, hook.Command)
+	}
+}
+
+func TestRestoreWithoutDatabase(t *testing.T) {
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+	t.Setenv("HOME", t.TempDir())
+	err := (&Agent{}).RestoreSession("/p", "conv-1", "main", []byte(sampleConversation), 4, "")
+	if err == nil || !strings.Contains(err.Error(), "q chat") {
+		t.Errorf("RestoreSession() error = %v, want hint to run q chat", err)
+	}
+}
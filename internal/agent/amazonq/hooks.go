@@ -0,0 +1,126 @@
+package amazonq
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// AgentName is the name of the Amazon Q CLI agent that carries the shiftlog
+// hook. Use it with `q chat --agent shiftlog`, or make it the default with
+// `q settings chat.defaultAgent shiftlog`.
+const AgentName = "shiftlog"
+
+// AgentConfig represents an Amazon Q CLI agent configuration file.
+// Format: {"name":"shiftlog", "tools":["*"], "hooks": {"postToolUse": [{"matcher":"execute_bash","command":"...","timeout_ms":30000}]}}
+type AgentConfig struct {
+	Hooks map[string][]HookEntry `json:"hooks"`
+	Other map[string]interface{} `json:"-"`
+}
+
+// HookEntry represents a single hook entry in an agent configuration.
+type HookEntry struct {
+	Matcher   string `json:"matcher,omitempty"`
+	Command   string `json:"command"`
+	TimeoutMs int    `json:"timeout_ms,omitempty"`
+}
+
+// agentConfigPath returns the path to the shiftlog agent configuration.
+func agentConfigPath(repoRoot string) string {
+	return filepath.Join(repoRoot, ".amazonq", "cli-agents", AgentName+".json")
+}
+
+// ReadAgentConfig reads the shiftlog agent configuration. A missing file
+// yields a new configuration that keeps the default agent's tools and MCP
+// servers.
+func ReadAgentConfig(repoRoot string) (*AgentConfig, error) {
+	data, err := os.ReadFile(agentConfigPath(repoRoot))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return &AgentConfig{
+				Hooks: make(map[string][]HookEntry),
+				Other: map[string]interface{}{
+					"name":             AgentName,
+					"description":      "Default agent with shiftlog conversation capture",
+					"tools":            []interface{}{"*"},
+					"useLegacyMcpJson": true,
+				},
+			}, nil
+		}
+		return nil, err
+	}
+
+	var raw map[string]interface{}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+
+	cfg := &AgentConfig{Hooks: make(map[string][]HookEntry)}
+	if hooks, ok := raw["hooks"]; ok {
+		hookBytes, _ := json.Marshal(hooks)
+		_ = json.Unmarshal(hookBytes, &cfg.Hooks)
+		delete(raw, "hooks")
+	}
+	cfg.Other = raw
+
+	return cfg, nil
+}
+
+// WriteAgentConfig writes the shiftlog agent configuration.
+func WriteAgentConfig(repoRoot string, cfg *AgentConfig) error {
+	output := make(map[string]interface{})
+	for k, v := range cfg.Other {
+		output[k] = v
+	}
+	if len(cfg.Hooks) > 0 {
+		output["hooks"] = cfg.Hooks
+	}
+
+	data, err := json.MarshalIndent(output, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	path := agentConfigPath(repoRoot)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// AddShiftlogHook adds the shiftlog store postToolUse hook for the shell tool.
+func AddShiftlogHook(cfg *AgentConfig) {
+	entry := HookEntry{
+		Matcher:   "execute_bash",
+		Command:   "shiftlog store --agent=amazonq",
+		TimeoutMs: 30000,
+	}
+
+	entries := cfg.Hooks["postToolUse"]
+	for i, e := range entries {
+		if strings.Contains(e.Command, "shiftlog store") {
+			entries[i] = entry
+			cfg.Hooks["postToolUse"] = entries
+			return
+		}
+	}
+	cfg.Hooks["postToolUse"] = append(entries, entry)
+}
+
+// RemoveShiftlogHook removes shiftlog hook entries from the configuration.
+func RemoveShiftlogHook(cfg *AgentConfig) {
+	for key, entries := range cfg.Hooks {
+		filtered := entries[:0]
+		for _, e := range entries {
+			if !strings.Contains(e.Command, "shiftlog store") {
+				filtered = append(filtered, e)
+			}
+		}
+		if len(filtered) == 0 {
+			delete(cfg.Hooks, key)
+		} else {
+			cfg.Hooks[key] = filtered
+		}
+	}
+}
@@ -0,0 +1,198 @@
+package amazonq
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/re-cinq/shift-log/internal/agent"
+)
+
+// Amazon Q CLI keeps one conversation per working directory in its SQLite
+// database. Older versions store it in the conversations table, keyed by
+// directory; newer versions keep every conversation of a directory in
+// conversations_v2 with its id and update time.
+const (
+	conversationsTable   = "conversations"
+	conversationsV2Table = "conversations_v2"
+)
+
+// GetDataDir returns the Amazon Q CLI data directory: $XDG_DATA_HOME/amazon-q
+// (default ~/.local/share/amazon-q) on Linux and
+// ~/Library/Application Support/amazon-q on macOS.
+func GetDataDir() (string, error) {
+	if runtime.GOOS == "darwin" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("could not determine home directory: %w", err)
+		}
+		return filepath.Join(home, "Library", "Application Support", "amazon-q"), nil
+	}
+
+	if xdg := os.Getenv("XDG_DATA_HOME"); xdg != "" {
+		return filepath.Join(xdg, "amazon-q"), nil
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("could not determine home directory: %w", err)
+	}
+	return filepath.Join(home, ".local", "share", "amazon-q"), nil
+}
+
+// GetDatabasePath returns the path to the Amazon Q CLI database.
+func GetDatabasePath() (string, error) {
+	dataDir, err := GetDataDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dataDir, "data.sqlite3"), nil
+}
+
+// runSQL runs SQL against the database with the sqlite3 CLI. The SQL is passed
+// on stdin, as conversations are too large for a command-line argument.
+func runSQL(dbPath, sql string) (string, error) {
+	cmd := exec.Command("sqlite3", dbPath)
+	cmd.Stdin = strings.NewReader(sql)
+	output, err := cmd.Output()
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// quote returns s as an SQL string literal.
+func quote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+// hasTable reports whether the database has the named table.
+func hasTable(dbPath, table string) bool {
+	out, err := runSQL(dbPath, fmt.Sprintf(
+		"SELECT name FROM sqlite_master WHERE type='table' AND name=%s;", quote(table)))
+	return err == nil && out == table
+}
+
+// readConversation returns the most recent conversation for projectPath and
+// when it was last updated. The time is zero when the database does not record
+// it and the conversation has no timestamped messages.
+func readConversation(dbPath, projectPath string) ([]byte, time.Time, error) {
+	if hasTable(dbPath, conversationsV2Table) {
+		out, err := runSQL(dbPath, fmt.Sprintf(
+			"SELECT updated_at || char(9) || value FROM %s WHERE key=%s ORDER BY updated_at DESC LIMIT 1;",
+			conversationsV2Table, quote(projectPath)))
+		if err != nil || out == "" {
+			return nil, time.Time{}, err
+		}
+		updatedAt, value, _ := strings.Cut(out, "\t")
+		return []byte(value), parseUnixTime(updatedAt), nil
+	}
+
+	out, err := runSQL(dbPath, fmt.Sprintf(
+		"SELECT value FROM %s WHERE key=%s;", conversationsTable, quote(projectPath)))
+	if err != nil || out == "" {
+		return nil, time.Time{}, err
+	}
+	return []byte(out), lastMessageTime([]byte(out)), nil
+}
+
+// parseUnixTime parses a Unix time in seconds or milliseconds.
+func parseUnixTime(s string) time.Time {
+	n, err := strconv.ParseInt(strings.TrimSpace(s), 10, 64)
+	if err != nil || n <= 0 {
+		return time.Time{}
+	}
+	if n > 1e12 {
+		return time.UnixMilli(n)
+	}
+	return time.Unix(n, 0)
+}
+
+// lastMessageTime returns the timestamp of the last timestamped user message
+// of a conversation.
+func lastMessageTime(data []byte) time.Time {
+	conv, err := parseConversation(data)
+	if err != nil {
+		return time.Time{}
+	}
+	var last time.Time
+	for _, turn := range conv.History {
+		if t, err := time.Parse(time.RFC3339Nano, turn.User.Timestamp); err == nil && t.After(last) {
+			last = t
+		}
+	}
+	return last
+}
+
+// discoverConversation finds the conversation Amazon Q CLI holds for
+// projectPath, if it was updated within timeout. Conversations without a
+// known update time are skipped: one is kept per directory indefinitely, so
+// it may be long finished.
+func discoverConversation(projectPath string, timeout time.Duration) *agent.SessionInfo {
+	dbPath, err := GetDatabasePath()
+	if err != nil {
+		return nil
+	}
+	if _, err := os.Stat(dbPath); err != nil {
+		return nil
+	}
+	if _, err := exec.LookPath("sqlite3"); err != nil {
+		return nil
+	}
+
+	data, updated, err := readConversation(dbPath, projectPath)
+	if err != nil || len(data) == 0 {
+		return nil
+	}
+	if updated.IsZero() || time.Since(updated) > timeout {
+		return nil
+	}
+
+	conv, err := parseConversation(data)
+	if err != nil || conv.ConversationID == "" {
+		return nil
+	}
+	return &agent.SessionInfo{
+		SessionID:      conv.ConversationID,
+		StartedAt:      updated.Format(time.RFC3339),
+		ProjectPath:    projectPath,
+		TranscriptData: data,
+	}
+}
+
+// WriteConversation stores a conversation in the Amazon Q CLI database as the
+// conversation for projectPath, so that `q chat --resume` picks it up there.
+// The database must already exist: it is created by Amazon Q CLI on first run.
+func WriteConversation(projectPath, sessionID string, data []byte) error {
+	dbPath, err := GetDatabasePath()
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(dbPath); err != nil {
+		return fmt.Errorf("no Amazon Q CLI database at %s (run 'q chat' once first)", dbPath)
+	}
+	if _, err := exec.LookPath("sqlite3"); err != nil {
+		return fmt.Errorf("sqlite3 is required to restore Amazon Q CLI conversations")
+	}
+
+	var sql string
+	if hasTable(dbPath, conversationsV2Table) {
+		now := time.Now().UnixMilli()
+		sql = fmt.Sprintf(
+			"INSERT OR REPLACE INTO %s (key, conversation_id, value, created_at, updated_at) VALUES (%s, %s, %s, %d, %d);",
+			conversationsV2Table, quote(projectPath), quote(sessionID), quote(string(data)), now, now)
+	} else {
+		sql = fmt.Sprintf(
+			"INSERT OR REPLACE INTO %s (key, value) VALUES (%s, %s);",
+			conversationsTable, quote(projectPath), quote(string(data)))
+	}
+	if _, err := runSQL(dbPath, sql); err != nil {
+		return fmt.Errorf("failed to write conversation: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,126 @@
+package agent
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+)
+
+// Sources of attachment content blocks.
+const (
+	// SourceBase64 embeds the attachment's data in the transcript.
+	SourceBase64 = "base64"
+	// SourceOmitted marks an attachment left out when the conversation was
+	// stored, keeping only its media type and size.
+	SourceOmitted = "omitted"
+)
+
+// AttachmentSource is the source of an image or document content block.
+type AttachmentSource struct {
+	Type      string `json:"type"`
+	MediaType string `json:"media_type,omitempty"`
+	Data      string `json:"data,omitempty"`
+	Size      int64  `json:"size,omitempty"` // decoded size of an omitted attachment
+}
+
+// Attachment is an image or document pasted into a conversation or returned
+// by a tool.
+type Attachment struct {
+	ID        string // see AttachmentID
+	EntryUUID string
+	MediaType string
+	Data      []byte
+}
+
+// AttachmentID identifies an attachment by the hash of its data, so the
+// same image pasted twice has one ID.
+func AttachmentID(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:8])
+}
+
+// Attachments returns the attachments embedded in the transcript, in order
+// of first appearance, including those inside tool results.
+func (t *Transcript) Attachments() []Attachment {
+	var attachments []Attachment
+	seen := make(map[string]bool)
+	for _, entry := range t.Entries {
+		if entry.Message == nil || !bytes.Contains(entry.Message.RawContent, []byte(`"source"`)) {
+			continue
+		}
+		RewriteAttachments(entry.Message.RawContent, func(block map[string]any) bool {
+			source, _ := block["source"].(map[string]any)
+			if source["type"] != SourceBase64 {
+				return false
+			}
+			encoded, _ := source["data"].(string)
+			data, err := base64.StdEncoding.DecodeString(encoded)
+			if err != nil {
+				return false
+			}
+			id := AttachmentID(data)
+			if !seen[id] {
+				seen[id] = true
+				mediaType, _ := source["media_type"].(string)
+				attachments = append(attachments, Attachment{ID: id, EntryUUID: entry.UUID, MediaType: mediaType, Data: data})
+			}
+			return false
+		})
+	}
+	return attachments
+}
+
+// Attachment returns the transcript's attachment with the given ID.
+func (t *Transcript) Attachment(id string) (Attachment, bool) {
+	for _, a := range t.Attachments() {
+		if a.ID == id {
+			return a, true
+		}
+	}
+	return Attachment{}, false
+}
+
+// RewriteAttachments calls fn on each image and document content block of
+// the JSON document raw, at any depth, as decoded by encoding/json. fn may
+// modify the block and reports whether it did. The document is re-encoded
+// only when a block changed; otherwise raw is returned as is.
+func RewriteAttachments(raw []byte, fn func(block map[string]any) bool) []byte {
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil || !rewriteAttachments(v, fn) {
+		return raw
+	}
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return raw
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n"))
+}
+
+func rewriteAttachments(v any, fn func(block map[string]any) bool) bool {
+	changed := false
+	switch v := v.(type) {
+	case map[string]any:
+		if _, ok := v["source"].(map[string]any); ok && (v["type"] == "image" || v["type"] == "document") {
+			return fn(v)
+		}
+		for _, child := range v {
+			if rewriteAttachments(child, fn) {
+				changed = true
+			}
+		}
+	case []any:
+		for _, child := range v {
+			if rewriteAttachments(child, fn) {
+				changed = true
+			}
+		}
+	}
+	return changed
+}
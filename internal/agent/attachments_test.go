@@ -0,0 +1,62 @@
+package agent
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"testing"
+)
+
+func TestTranscriptAttachments(t *testing.T) {
+	png := []byte("\x89PNG pasted")
+	screenshot := []byte("\x89PNG read")
+	pngData := base64.StdEncoding.EncodeToString(png)
+	screenshotData := base64.StdEncoding.EncodeToString(screenshot)
+
+	var transcript Transcript
+	for _, line := range []string{
+		`{"uuid":"u1","type":"user","message":{"role":"user","content":[{"type":"text","text":"Fix this"},{"type":"image","source":{"type":"base64","media_type":"image/png","data":"` + pngData + `"}}]}}`,
+		`{"uuid":"u2","type":"user","message":{"role":"user","content":[{"type":"tool_result","tool_use_id":"t1","content":[{"type":"image","source":{"type":"base64","media_type":"image/jpeg","data":"` + screenshotData + `"}}]}]}}`,
+		`{"uuid":"u3","type":"user","message":{"role":"user","content":[{"type":"image","source":{"type":"base64","media_type":"image/png","data":"` + pngData + `"}},{"type":"image","source":{"type":"omitted","media_type":"image/png","size":9000000}}]}}`,
+	} {
+		var entry TranscriptEntry
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatal(err)
+		}
+		transcript.Entries = append(transcript.Entries, entry)
+	}
+
+	attachments := transcript.Attachments()
+	if len(attachments) != 2 {
+		t.Fatalf("attachments = %+v, want the pasted image once and the screenshot", attachments)
+	}
+	if a := attachments[0]; a.ID != AttachmentID(png) || a.EntryUUID != "u1" || a.MediaType != "image/png" || string(a.Data) != string(png) {
+		t.Errorf("first attachment = %+v", a)
+	}
+	if a := attachments[1]; a.EntryUUID != "u2" || a.MediaType != "image/jpeg" || string(a.Data) != string(screenshot) {
+		t.Errorf("second attachment = %+v", a)
+	}
+
+	if a, ok := transcript.Attachment(AttachmentID(screenshot)); !ok || a.EntryUUID != "u2" {
+		t.Errorf("Attachment(screenshot) = %+v, %v", a, ok)
+	}
+	if _, ok := transcript.Attachment("unknown"); ok {
+		t.Error("Attachment(unknown) found an attachment")
+	}
+}
+
+func TestRewriteAttachments(t *testing.T) {
+	raw := []byte(`[{"type":"text","text":"<b>"},{"type":"document","source":{"type":"base64","media_type":"application/pdf","data":"JVBERg=="}}]`)
+
+	if got := RewriteAttachments(raw, func(map[string]any) bool { return false }); string(got) != string(raw) {
+		t.Errorf("unchanged document was re-encoded: %s", got)
+	}
+
+	got := RewriteAttachments(raw, func(block map[string]any) bool {
+		delete(block["source"].(map[string]any), "data")
+		return true
+	})
+	want := `[{"text":"<b>","type":"text"},{"source":{"media_type":"application/pdf","type":"base64"},"type":"document"}]`
+	if string(got) != want {
+		t.Errorf("RewriteAttachments = %s, want %s", got, want)
+	}
+}
@@ -201,6 +201,15 @@ func (a *Agent) DiscoverSession(projectPath string) (*agent.SessionInfo, error)
 	return scanForRecentSession(projectPath)
 }
 
+// ListSessions returns every Claude Code session of the project, newest first.
+func (a *Agent) ListSessions(projectPath string) ([]agent.SessionInfo, error) {
+	sessionDir, err := GetSessionDir(projectPath)
+	if err != nil {
+		return nil, err
+	}
+	return agent.ListSessionFiles(sessionDir, ".jsonl", nil, projectPath), nil
+}
+
 // discoverFromActiveSession checks the .shiftlog/active-session.json file
 // written by the session-start hook for a direct pointer to the active session.
 func discoverFromActiveSession(projectPath string) (*agent.SessionInfo, error) {
@@ -293,11 +302,75 @@ func (a *Agent) RestoreSession(projectPath, sessionID, gitBranch string,
 	return nil
 }
 
+// sessionLine is a line of a Claude Code session JSONL file, as written by
+// EncodeTranscript.
+type sessionLine struct {
+	Type        agent.MessageType `json:"type"`
+	UUID        string            `json:"uuid"`
+	ParentUUID  *string           `json:"parentUuid"`
+	SessionID   string            `json:"sessionId"`
+	CWD         string            `json:"cwd"`
+	Timestamp   string            `json:"timestamp"`
+	IsSidechain bool              `json:"isSidechain"`
+	UserType    string            `json:"userType"`
+	Message     sessionMessage    `json:"message"`
+}
+
+type sessionMessage struct {
+	Role    string               `json:"role"`
+	Model   string               `json:"model,omitempty"`
+	Content []agent.ContentBlock `json:"content"`
+}
+
+// EncodeTranscript writes a transcript as a Claude Code session JSONL file.
+// Entries without a message are skipped.
+func (a *Agent) EncodeTranscript(t *agent.Transcript, sessionID, projectPath string) ([]byte, error) {
+	now := time.Now().UTC().Format(time.RFC3339Nano)
+	var buf strings.Builder
+	for _, entry := range t.Entries {
+		if entry.Message == nil {
+			continue
+		}
+		line := sessionLine{
+			Type:      entry.Type,
+			UUID:      entry.UUID,
+			SessionID: sessionID,
+			CWD:       projectPath,
+			Timestamp: entry.Timestamp,
+			UserType:  "external",
+			Message: sessionMessage{
+				Role:    string(entry.Type),
+				Model:   entry.Model,
+				Content: entry.Message.Content,
+			},
+		}
+		if entry.ParentUUID != "" {
+			parent := entry.ParentUUID
+			line.ParentUUID = &parent
+		}
+		if line.Timestamp == "" {
+			line.Timestamp = now
+		}
+		data, err := json.Marshal(line)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(data)
+		buf.WriteByte('\n')
+	}
+	return []byte(buf.String()), nil
+}
+
 // ResumeCommand returns the command to resume a Claude Code session.
 func (a *Agent) ResumeCommand(sessionID string) (string, []string) {
 	return "claude", []string{"--resume", sessionID}
 }
 
+// VersionCommand returns the command that prints the Claude Code version.
+func (a *Agent) VersionCommand() (string, []string) {
+	return "claude", []string{"--version"}
+}
+
 // SummariseCommand returns the command to run Claude Code in non-interactive mode.
 func (a *Agent) SummariseCommand() (string, []string) {
 	return "claude", []string{"-p", "--output-format", "text"}
@@ -312,8 +385,10 @@ func (a *Agent) ToolAliases() map[string]string {
 // lineMetadata holds fields extracted from each JSONL line during parsing.
 type lineMetadata struct {
 	Model   string `json:"model"`
+	Version string `json:"version"`
 	Message *struct {
 		Role  string `json:"role"`
+		Model string `json:"model"`
 		Usage *struct {
 			InputTokens              int64 `json:"input_tokens"`
 			OutputTokens             int64 `json:"output_tokens"`
@@ -330,7 +405,7 @@ func ParseJSONLTranscript(r io.Reader) (*agent.Transcript, error) {
 	scanner.Buffer(buf, 10*1024*1024)
 
 	var entries []agent.TranscriptEntry
-	var model string
+	var model, version string
 	var usage agent.UsageMetrics
 
 	for scanner.Scan() {
@@ -342,30 +417,42 @@ func ParseJSONLTranscript(r io.Reader) (*agent.Transcript, error) {
 		var entry agent.TranscriptEntry
 		_ = json.Unmarshal(line, &entry)
 		entry.Raw = json.RawMessage(line)
-		entries = append(entries, entry)
 
-		// Extract model and usage from each line
+		// Extract model, version and usage from each line
 		var meta lineMetadata
 		if json.Unmarshal(line, &meta) == nil {
-			if model == "" && meta.Model != "" {
-				model = meta.Model
+			if entry.Model == "" && meta.Message != nil {
+				entry.Model = meta.Message.Model
+			}
+			if model == "" && entry.Model != "" {
+				model = entry.Model
 			}
-			// Accumulate token usage from assistant message.usage
+			if meta.Version != "" {
+				version = meta.Version
+			}
+			// Keep and accumulate token usage from assistant message.usage
 			if meta.Message != nil && meta.Message.Usage != nil {
 				u := meta.Message.Usage
+				entry.Usage = &agent.UsageMetrics{
+					InputTokens:              u.InputTokens,
+					OutputTokens:             u.OutputTokens,
+					CacheCreationInputTokens: u.CacheCreationInputTokens,
+					CacheReadInputTokens:     u.CacheReadInputTokens,
+				}
 				usage.InputTokens += u.InputTokens
 				usage.OutputTokens += u.OutputTokens
 				usage.CacheCreationInputTokens += u.CacheCreationInputTokens
 				usage.CacheReadInputTokens += u.CacheReadInputTokens
 			}
 		}
+		entries = append(entries, entry)
 	}
 
 	if err := scanner.Err(); err != nil {
 		return nil, err
 	}
 
-	t := &agent.Transcript{Entries: entries, Model: model, Usage: usage}
+	t := &agent.Transcript{Entries: entries, Model: model, Usage: usage, AgentVersion: version}
 	t.Turns = t.CountTurns()
 	return t, nil
 }
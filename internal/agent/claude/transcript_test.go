@@ -3,6 +3,8 @@ package claude
 import (
 	"strings"
 	"testing"
+
+	"github.com/re-cinq/shift-log/internal/agent"
 )
 
 func TestGetLastEntryUUID(t *testing.T) {
@@ -199,6 +201,17 @@ func TestParseJSONLTranscriptExtractsUsage(t *testing.T) {
 	if transcript.Usage.TotalTokens() != 425 {
 		t.Errorf("TotalTokens() = %d, want 425", transcript.Usage.TotalTokens())
 	}
+
+	// Each assistant entry keeps its own usage
+	if u := transcript.Entries[1].Usage; u == nil || u.InputTokens != 100 || u.CacheReadInputTokens != 20 {
+		t.Errorf("assistant-1 Usage = %+v, want 100 input and 20 cache read tokens", u)
+	}
+	if u := transcript.Entries[3].Usage; u == nil || u.TotalTokens() != 275 {
+		t.Errorf("assistant-2 Usage = %+v, want 275 tokens", u)
+	}
+	if transcript.Entries[0].Usage != nil {
+		t.Errorf("user entry Usage = %+v, want nil", transcript.Entries[0].Usage)
+	}
 }
 
 func TestParseJSONLTranscriptCountsTurns(t *testing.T) {
@@ -238,3 +251,55 @@ func TestParseJSONLTranscriptNoUsage(t *testing.T) {
 		t.Errorf("Turns = %d, want 1", transcript.Turns)
 	}
 }
+
+func TestParseJSONLTranscriptExtractsProvenance(t *testing.T) {
+	jsonl := `{"uuid":"user-1","type":"user","version":"1.0.51","message":{"role":"user","content":[{"type":"text","text":"Hello"}]}}
+{"uuid":"assistant-1","type":"assistant","version":"1.0.51","message":{"role":"assistant","model":"claude-opus-4-1-20250805","content":[{"type":"text","text":"Hi!"}]}}
+{"uuid":"assistant-2","type":"assistant","version":"1.0.52","message":{"role":"assistant","model":"claude-sonnet-4-5-20250514","content":[{"type":"text","text":"Done."}]}}`
+
+	transcript, err := ParseJSONLTranscript(strings.NewReader(jsonl))
+	if err != nil {
+		t.Fatalf("ParseJSONLTranscript failed: %v", err)
+	}
+
+	if transcript.AgentVersion != "1.0.52" {
+		t.Errorf("AgentVersion = %q, want the latest 1.0.52", transcript.AgentVersion)
+	}
+	if transcript.Model != "claude-opus-4-1-20250805" {
+		t.Errorf("Model = %q, want the first model", transcript.Model)
+	}
+	if counts := transcript.ModelCounts(); len(counts) != 2 || counts[1].Model != "claude-sonnet-4-5-20250514" {
+		t.Errorf("ModelCounts() = %+v", counts)
+	}
+}
+
+func TestEncodeTranscriptRoundTrip(t *testing.T) {
+	transcript := agent.ReplayTranscript(&agent.Transcript{Entries: []agent.TranscriptEntry{
+		{Type: agent.MessageTypeUser, Message: &agent.Message{Content: []agent.ContentBlock{{Type: "text", Text: "Hello"}}}},
+		{Type: agent.MessageTypeAssistant, Message: &agent.Message{Content: []agent.ContentBlock{{Type: "text", Text: "Hi there"}}}},
+	}}, "Codex CLI")
+
+	a := &Agent{}
+	data, err := a.EncodeTranscript(transcript, "session-1", "/repo")
+	if err != nil {
+		t.Fatalf("EncodeTranscript: %v", err)
+	}
+	if !strings.Contains(string(data), `"sessionId":"session-1"`) || !strings.Contains(string(data), `"cwd":"/repo"`) {
+		t.Errorf("session lines should carry session ID and cwd:\n%s", data)
+	}
+
+	parsed, err := ParseJSONLTranscript(strings.NewReader(string(data)))
+	if err != nil {
+		t.Fatalf("ParseJSONLTranscript: %v", err)
+	}
+	if len(parsed.Entries) != 3 {
+		t.Fatalf("got %d entries, want 3", len(parsed.Entries))
+	}
+	last := parsed.Entries[2]
+	if last.Type != agent.MessageTypeAssistant || last.Message.Content[0].Text != "Hi there" {
+		t.Errorf("last entry = %+v", last)
+	}
+	if last.ParentUUID != parsed.Entries[1].UUID {
+		t.Errorf("parent = %q, want %q", last.ParentUUID, parsed.Entries[1].UUID)
+	}
+}
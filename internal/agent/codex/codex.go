@@ -2,6 +2,7 @@ package codex
 
 import (
 	"encoding/json"
+	"fmt"
 	"io"
 	"os"
 	"os/exec"
@@ -18,7 +19,7 @@ func init() {
 // Agent implements the agent.Agent interface for OpenAI Codex CLI.
 type Agent struct{}
 
-func (a *Agent) Name() agent.Name   { return agent.Codex }
+func (a *Agent) Name() agent.Name    { return agent.Codex }
 func (a *Agent) DisplayName() string { return "Codex CLI" }
 
 // ConfigureHooks is a no-op for Codex — it has no per-tool hook mechanism.
@@ -59,14 +60,17 @@ func (a *Agent) ParseHookInput(raw []byte) (*agent.HookData, error) {
 	return agent.ParseStandardHookInput(raw)
 }
 
+// shellTools are the Codex tool names that run shell commands.
+var shellTools = map[string]bool{
+	"shell":          true,
+	"container.exec": true,
+	"shell_command":  true,
+	"local_shell":    true,
+	"exec_command":   true,
+}
+
 // IsCommitCommand checks if a tool invocation represents a git commit.
 func (a *Agent) IsCommitCommand(toolName, command string) bool {
-	shellTools := map[string]bool{
-		"shell":          true,
-		"container.exec": true,
-		"shell_command":  true,
-	}
-
 	if !shellTools[toolName] {
 		return false
 	}
@@ -87,8 +91,12 @@ type responseItem struct {
 	Content   json.RawMessage `json:"content"`
 	Name      string          `json:"name"`
 	Arguments string          `json:"arguments"`
+	Input     string          `json:"input"` // custom_tool_call, e.g. an apply_patch body
 	CallID    string          `json:"call_id"`
-	Output    string          `json:"output"`
+	Output    json.RawMessage `json:"output"`
+	Summary   []contentPart   `json:"summary"` // reasoning
+	Action    json.RawMessage `json:"action"`  // local_shell_call and web_search_call
+	Omitted   string          `json:"omitted"` // reasoning left out by shiftlog's thinking policy
 }
 
 // contentPart represents a content part within a response_item message.
@@ -97,7 +105,41 @@ type contentPart struct {
 	Text string `json:"text"`
 }
 
+// tokenUsage is a token count reported by a Codex token_count event.
+type tokenUsage struct {
+	InputTokens       int64 `json:"input_tokens"`
+	CachedInputTokens int64 `json:"cached_input_tokens"`
+	OutputTokens      int64 `json:"output_tokens"`
+}
+
+// metrics converts Codex token usage, which counts cached input as part of
+// the input tokens.
+func (u *tokenUsage) metrics() agent.UsageMetrics {
+	return agent.UsageMetrics{
+		InputTokens:          u.InputTokens - u.CachedInputTokens,
+		OutputTokens:         u.OutputTokens,
+		CacheReadInputTokens: u.CachedInputTokens,
+	}
+}
+
+// eventMsg represents the event_msg payloads the parser reads.
+type eventMsg struct {
+	Type string `json:"type"`
+	Info *struct {
+		TotalTokenUsage *tokenUsage `json:"total_token_usage"`
+		LastTokenUsage  *tokenUsage `json:"last_token_usage"`
+	} `json:"info"`
+}
+
 // ParseTranscript parses a Codex CLI rollout JSONL transcript.
+//
+// Besides the conversation items, it extracts the model from turn_context
+// lines (falling back to the session's model provider) and the session's
+// token usage from the last token_count event, whose totals are cumulative.
+// The usage of each model response, reported by the token_count event that
+// follows it, is kept on the response's last assistant entry.
+// Rollouts written before Codex wrapped items in a type/payload envelope are
+// read too.
 func (a *Agent) ParseTranscript(r io.Reader) (*agent.Transcript, error) {
 	data, err := io.ReadAll(r)
 	if err != nil {
@@ -105,7 +147,9 @@ func (a *Agent) ParseTranscript(r io.Reader) (*agent.Transcript, error) {
 	}
 
 	var entries []agent.TranscriptEntry
-	var model string
+	var model, provider, version string
+	var usage agent.UsageMetrics
+	lastAssistant := -1 // assistant entry awaiting its response's usage
 
 	for _, line := range strings.Split(string(data), "\n") {
 		line = strings.TrimSpace(line)
@@ -118,37 +162,80 @@ func (a *Agent) ParseTranscript(r io.Reader) (*agent.Transcript, error) {
 			continue
 		}
 
-		// Extract model_provider from session_meta
-		if model == "" && rl.Type == "session_meta" {
-			var meta struct {
-				ModelProvider string `json:"model_provider"`
+		payload := rl.Payload
+		switch rl.Type {
+		case "session_meta":
+			var meta SessionMeta
+			if json.Unmarshal(payload, &meta) == nil {
+				if meta.ModelProvider != "" {
+					provider = meta.ModelProvider
+				}
+				if meta.CLIVersion != "" {
+					version = meta.CLIVersion
+				}
 			}
-			if json.Unmarshal(rl.Payload, &meta) == nil && meta.ModelProvider != "" {
-				model = meta.ModelProvider
+			continue
+
+		case "turn_context":
+			var ctx struct {
+				Model string `json:"model"`
 			}
-		}
+			if json.Unmarshal(payload, &ctx) == nil && ctx.Model != "" {
+				model = ctx.Model
+			}
+			continue
 
-		if rl.Type != "response_item" {
+		case "event_msg":
+			var ev eventMsg
+			if json.Unmarshal(payload, &ev) == nil && ev.Type == "token_count" && ev.Info != nil {
+				if ev.Info.TotalTokenUsage != nil {
+					usage = ev.Info.TotalTokenUsage.metrics()
+				}
+				if ev.Info.LastTokenUsage != nil && lastAssistant >= 0 {
+					last := ev.Info.LastTokenUsage.metrics()
+					entries[lastAssistant].Usage = &last
+					lastAssistant = -1
+				}
+			}
 			continue
+
+		case "response_item":
+			// Parsed below
+
+		default:
+			if len(payload) > 0 {
+				continue
+			}
+			// Legacy rollout: the line is the item itself
+			payload = json.RawMessage(line)
 		}
 
 		var item responseItem
-		if err := json.Unmarshal(rl.Payload, &item); err != nil {
+		if err := json.Unmarshal(payload, &item); err != nil {
 			continue
 		}
 
 		entry := a.parseResponseItem(item, rl.Timestamp, []byte(line))
 		if entry.Type != "" {
+			entry.UUID = fmt.Sprintf("codex-%d", len(entries))
+			if entry.Type == agent.MessageTypeAssistant {
+				entry.Model = model
+				lastAssistant = len(entries)
+			}
 			entries = append(entries, entry)
 		}
 	}
 
-	t := &agent.Transcript{Entries: entries, Model: model}
+	if model == "" {
+		model = provider
+	}
+	t := &agent.Transcript{Entries: entries, Model: model, Usage: usage, AgentVersion: version}
 	t.Turns = t.CountTurns()
 	return t, nil
 }
 
 // parseResponseItem converts a Codex response_item into a TranscriptEntry.
+// Returns an entry with an empty Type for items that are not shown.
 func (a *Agent) parseResponseItem(item responseItem, timestamp string, rawLine []byte) agent.TranscriptEntry {
 	entry := agent.TranscriptEntry{
 		Timestamp: timestamp,
@@ -160,26 +247,53 @@ func (a *Agent) parseResponseItem(item responseItem, timestamp string, rawLine [
 		entry.Type = agent.NormalizeRole(item.Role)
 		entry.Message = parseCodexMessage(item)
 
-	case "function_call":
+	case "reasoning":
+		var parts []string
+		for _, p := range item.Summary {
+			if p.Text != "" {
+				parts = append(parts, p.Text)
+			}
+		}
+		if len(parts) == 0 && item.Omitted == "" {
+			// Encrypted reasoning has nothing to show
+			return entry
+		}
 		entry.Type = agent.MessageTypeAssistant
 		entry.Message = &agent.Message{
-			Role: "assistant",
-			Content: []agent.ContentBlock{{
-				Type:      "tool_use",
-				ToolUseID: item.CallID,
-				Text:      item.Name,
-				Input:     json.RawMessage(item.Arguments),
-			}},
+			Role:    "assistant",
+			Content: []agent.ContentBlock{{Type: "thinking", Thinking: strings.Join(parts, "\n\n"), Omitted: item.Omitted}},
+		}
+
+	case "function_call":
+		entry.Type = agent.MessageTypeAssistant
+		entry.Message = toolUseMessage(item.CallID, item.Name, json.RawMessage(item.Arguments))
+
+	case "custom_tool_call":
+		input, _ := json.Marshal(item.Input)
+		entry.Type = agent.MessageTypeAssistant
+		entry.Message = toolUseMessage(item.CallID, item.Name, input)
+
+	case "local_shell_call":
+		var action struct {
+			Command []string `json:"command"`
 		}
+		_ = json.Unmarshal(item.Action, &action)
+		input, _ := json.Marshal(map[string][]string{"command": action.Command})
+		entry.Type = agent.MessageTypeAssistant
+		entry.Message = toolUseMessage(item.CallID, "local_shell", input)
 
-	case "function_call_output":
+	case "web_search_call":
+		entry.Type = agent.MessageTypeAssistant
+		entry.Message = toolUseMessage(item.CallID, "web_search", item.Action)
+
+	case "function_call_output", "custom_tool_call_output":
 		entry.Type = agent.MessageTypeUser
 		entry.Message = &agent.Message{
 			Role: "user",
 			Content: []agent.ContentBlock{{
 				Type:      "tool_result",
 				ToolUseID: item.CallID,
-				Content:   json.RawMessage(`"` + strings.ReplaceAll(item.Output, `"`, `\"`) + `"`),
+				Content:   toolOutput(item.Output),
 			}},
 		}
 	}
@@ -187,6 +301,40 @@ func (a *Agent) parseResponseItem(item responseItem, timestamp string, rawLine [
 	return entry
 }
 
+// toolUseMessage builds the assistant message for a tool call.
+func toolUseMessage(callID, name string, input json.RawMessage) *agent.Message {
+	if !json.Valid(input) {
+		// Arguments that are not JSON are kept as a string
+		input, _ = json.Marshal(string(input))
+	}
+	return &agent.Message{
+		Role: "assistant",
+		Content: []agent.ContentBlock{{
+			Type:      "tool_use",
+			ToolUseID: callID,
+			Name:      name,
+			Text:      name,
+			Input:     input,
+		}},
+	}
+}
+
+// toolOutput returns a tool call's output as a JSON string. Codex records it
+// either as a string or as an object with the text in its "content" field.
+func toolOutput(raw json.RawMessage) json.RawMessage {
+	var text string
+	if err := json.Unmarshal(raw, &text); err != nil {
+		var obj struct {
+			Content string `json:"content"`
+		}
+		if json.Unmarshal(raw, &obj) == nil {
+			text = obj.Content
+		}
+	}
+	out, _ := json.Marshal(text)
+	return out
+}
+
 // parseCodexMessage extracts message content from a response_item.
 func parseCodexMessage(item responseItem) *agent.Message {
 	msg := &agent.Message{}
@@ -223,7 +371,6 @@ func parseCodexMessage(item responseItem) *agent.Message {
 	return msg
 }
 
-
 // ParseTranscriptFile parses a Codex rollout JSONL file.
 func (a *Agent) ParseTranscriptFile(path string) (*agent.Transcript, error) {
 	f, err := os.Open(path)
@@ -267,14 +414,80 @@ func (a *Agent) RestoreSession(projectPath, sessionID, gitBranch string,
 	return err
 }
 
+// EncodeTranscript writes a transcript as a Codex rollout JSONL file: a
+// session_meta line followed by a message response_item per user or
+// assistant entry. Only text blocks are written; Codex cannot resume tool
+// calls it did not make itself.
+func (a *Agent) EncodeTranscript(t *agent.Transcript, sessionID, projectPath string) ([]byte, error) {
+	now := time.Now().UTC().Format(time.RFC3339Nano)
+	var buf strings.Builder
+	writeLine := func(timestamp, lineType string, payload interface{}) error {
+		data, err := json.Marshal(payload)
+		if err != nil {
+			return err
+		}
+		if timestamp == "" {
+			timestamp = now
+		}
+		line, err := json.Marshal(rolloutLine{Timestamp: timestamp, Type: lineType, Payload: data})
+		if err != nil {
+			return err
+		}
+		buf.Write(line)
+		buf.WriteByte('\n')
+		return nil
+	}
+
+	meta := SessionMeta{ID: sessionID, Timestamp: now, CWD: projectPath}
+	if err := writeLine(now, "session_meta", meta); err != nil {
+		return nil, err
+	}
+	for _, entry := range t.Entries {
+		if entry.Message == nil {
+			continue
+		}
+		partType := "input_text"
+		switch entry.Type {
+		case agent.MessageTypeUser:
+		case agent.MessageTypeAssistant:
+			partType = "output_text"
+		default:
+			continue
+		}
+		var parts []contentPart
+		for _, block := range entry.Message.Content {
+			if block.Type == "text" && block.Text != "" {
+				parts = append(parts, contentPart{Type: partType, Text: block.Text})
+			}
+		}
+		if len(parts) == 0 {
+			continue
+		}
+		item := struct {
+			Type    string        `json:"type"`
+			Role    string        `json:"role"`
+			Content []contentPart `json:"content"`
+		}{Type: "message", Role: string(entry.Type), Content: parts}
+		if err := writeLine(entry.Timestamp, "response_item", item); err != nil {
+			return nil, err
+		}
+	}
+	return []byte(buf.String()), nil
+}
+
 // ResumeCommand returns the command to resume a Codex CLI session.
 func (a *Agent) ResumeCommand(sessionID string) (string, []string) {
 	return "codex", []string{"resume", sessionID}
 }
 
+// VersionCommand returns the command that prints the Codex CLI version.
+func (a *Agent) VersionCommand() (string, []string) {
+	return "codex", []string{"--version"}
+}
+
 // SummariseCommand returns the command to run Codex in non-interactive mode.
 func (a *Agent) SummariseCommand() (string, []string) {
-	return "codex", []string{"-q"}
+	return "codex", []string{"exec"}
 }
 
 // ToolAliases returns Codex's tool name mappings to canonical names.
@@ -283,6 +496,15 @@ func (a *Agent) ToolAliases() map[string]string {
 		"shell":          "Bash",
 		"container.exec": "Bash",
 		"shell_command":  "Bash",
+		"local_shell":    "Bash",
+		"exec_command":   "Bash",
+		"apply_patch":    "Edit",
+		"read_file":      "Read",
+		"view_image":     "Read",
+		"list_dir":       "Glob",
+		"grep_files":     "Grep",
+		"web_search":     "WebSearch",
+		"update_plan":    "TodoWrite",
 	}
 }
 
@@ -304,3 +304,210 @@ func TestGetCodexHome(t *testing.T) {
 		}
 	})
 }
+
+func TestParseTranscriptUsageAndModel(t *testing.T) {
+	a := &Agent{}
+	rollout := strings.Join([]string{
+		`{"timestamp":"2025-01-01T00:00:00Z","type":"session_meta","payload":{"id":"sess-1","cwd":"/tmp","model_provider":"openai","cli_version":"0.46.0"}}`,
+		`{"timestamp":"2025-01-01T00:00:01Z","type":"turn_context","payload":{"cwd":"/tmp","model":"gpt-5-codex"}}`,
+		`{"timestamp":"2025-01-01T00:00:02Z","type":"response_item","payload":{"type":"message","role":"user","content":[{"type":"input_text","text":"Hello"}]}}`,
+		`{"timestamp":"2025-01-01T00:00:02Z","type":"response_item","payload":{"type":"message","role":"assistant","content":[{"type":"output_text","text":"Hi"}]}}`,
+		`{"timestamp":"2025-01-01T00:00:03Z","type":"event_msg","payload":{"type":"token_count","info":{"total_token_usage":{"input_tokens":1000,"cached_input_tokens":600,"output_tokens":50,"total_tokens":1050}}}}`,
+		`{"timestamp":"2025-01-01T00:00:04Z","type":"event_msg","payload":{"type":"token_count","info":null}}`,
+		`{"timestamp":"2025-01-01T00:00:05Z","type":"event_msg","payload":{"type":"token_count","info":{"total_token_usage":{"input_tokens":3000,"cached_input_tokens":2000,"output_tokens":200,"total_tokens":3200}}}}`,
+	}, "\n")
+
+	transcript, err := a.ParseTranscript(strings.NewReader(rollout))
+	if err != nil {
+		t.Fatalf("ParseTranscript() error: %v", err)
+	}
+
+	if transcript.Model != "gpt-5-codex" {
+		t.Errorf("Model = %q, want %q", transcript.Model, "gpt-5-codex")
+	}
+	want := agent.UsageMetrics{InputTokens: 1000, OutputTokens: 200, CacheReadInputTokens: 2000}
+	if transcript.Usage != want {
+		t.Errorf("Usage = %+v, want %+v", transcript.Usage, want)
+	}
+	if transcript.Turns != 1 {
+		t.Errorf("Turns = %d, want 1", transcript.Turns)
+	}
+	if transcript.AgentVersion != "0.46.0" {
+		t.Errorf("AgentVersion = %q, want 0.46.0", transcript.AgentVersion)
+	}
+	if got := transcript.Entries[1].Model; got != "gpt-5-codex" {
+		t.Errorf("assistant entry Model = %q, want gpt-5-codex", got)
+	}
+}
+
+func TestParseTranscriptPerEntryUsage(t *testing.T) {
+	a := &Agent{}
+	rollout := strings.Join([]string{
+		`{"timestamp":"2025-01-01T00:00:01Z","type":"response_item","payload":{"type":"message","role":"user","content":[{"type":"input_text","text":"Hello"}]}}`,
+		`{"timestamp":"2025-01-01T00:00:02Z","type":"response_item","payload":{"type":"message","role":"assistant","content":[{"type":"output_text","text":"Looking"}]}}`,
+		`{"timestamp":"2025-01-01T00:00:03Z","type":"response_item","payload":{"type":"function_call","name":"shell","arguments":"{\"command\":[\"ls\"]}","call_id":"c1"}}`,
+		`{"timestamp":"2025-01-01T00:00:04Z","type":"event_msg","payload":{"type":"token_count","info":{"total_token_usage":{"input_tokens":1000,"cached_input_tokens":600,"output_tokens":50},"last_token_usage":{"input_tokens":1000,"cached_input_tokens":600,"output_tokens":50}}}}`,
+		`{"timestamp":"2025-01-01T00:00:05Z","type":"event_msg","payload":{"type":"token_count","info":{"total_token_usage":{"input_tokens":1000,"cached_input_tokens":600,"output_tokens":50},"last_token_usage":{"input_tokens":1000,"cached_input_tokens":600,"output_tokens":50}}}}`,
+	}, "\n")
+
+	transcript, err := a.ParseTranscript(strings.NewReader(rollout))
+	if err != nil {
+		t.Fatalf("ParseTranscript() error: %v", err)
+	}
+
+	// The response's usage goes to its last entry, once
+	if transcript.Entries[1].Usage != nil {
+		t.Errorf("message entry Usage = %+v, want nil", transcript.Entries[1].Usage)
+	}
+	want := agent.UsageMetrics{InputTokens: 400, OutputTokens: 50, CacheReadInputTokens: 600}
+	if u := transcript.Entries[2].Usage; u == nil || *u != want {
+		t.Errorf("function call Usage = %+v, want %+v", u, want)
+	}
+}
+
+func TestParseTranscriptToolCalls(t *testing.T) {
+	a := &Agent{}
+	rollout := strings.Join([]string{
+		`{"timestamp":"2025-01-01T00:00:01Z","type":"response_item","payload":{"type":"reasoning","summary":[{"type":"summary_text","text":"Planning the edit"}],"encrypted_content":"x"}}`,
+		`{"timestamp":"2025-01-01T00:00:02Z","type":"response_item","payload":{"type":"reasoning","summary":[],"encrypted_content":"x"}}`,
+		`{"timestamp":"2025-01-01T00:00:03Z","type":"response_item","payload":{"type":"custom_tool_call","call_id":"call_1","name":"apply_patch","input":"*** Begin Patch\n*** Add File: hello.go\n+package main\n*** End Patch"}}`,
+		`{"timestamp":"2025-01-01T00:00:04Z","type":"response_item","payload":{"type":"custom_tool_call_output","call_id":"call_1","output":"{\"output\":\"Success\"}"}}`,
+		`{"timestamp":"2025-01-01T00:00:05Z","type":"response_item","payload":{"type":"local_shell_call","call_id":"call_2","action":{"type":"exec","command":["git","status"]}}}`,
+		`{"timestamp":"2025-01-01T00:00:06Z","type":"response_item","payload":{"type":"function_call_output","call_id":"call_2","output":{"content":"say \"hi\"","success":true}}}`,
+	}, "\n")
+
+	transcript, err := a.ParseTranscript(strings.NewReader(rollout))
+	if err != nil {
+		t.Fatalf("ParseTranscript() error: %v", err)
+	}
+	if len(transcript.Entries) != 5 {
+		t.Fatalf("Expected 5 entries (encrypted reasoning skipped), got %d", len(transcript.Entries))
+	}
+
+	if block := transcript.Entries[0].Message.Content[0]; block.Type != "thinking" || block.Thinking != "Planning the edit" {
+		t.Errorf("reasoning block = %+v, want thinking %q", block, "Planning the edit")
+	}
+
+	patch := transcript.Entries[1].Message.Content[0]
+	if patch.Type != "tool_use" || patch.Name != "apply_patch" {
+		t.Errorf("custom_tool_call block = %+v, want tool_use apply_patch", patch)
+	}
+	if files := agent.EditedFiles(transcript.Entries, a.ToolAliases()); len(files) != 1 || files[0] != "hello.go" {
+		t.Errorf("EditedFiles() = %v, want [hello.go]", files)
+	}
+
+	if shell := transcript.Entries[3].Message.Content[0]; shell.Name != "local_shell" || string(shell.Input) != `{"command":["git","status"]}` {
+		t.Errorf("local_shell_call block = %+v", shell)
+	}
+	if out := string(transcript.Entries[4].Message.Content[0].Content); out != `"say \"hi\""` {
+		t.Errorf("tool output = %s, want %q", out, `"say \"hi\""`)
+	}
+
+	seen := map[string]bool{}
+	for _, e := range transcript.Entries {
+		if e.UUID == "" || seen[e.UUID] {
+			t.Errorf("entry UUID %q is empty or duplicate", e.UUID)
+		}
+		seen[e.UUID] = true
+	}
+}
+
+func TestParseTranscriptLegacyRollout(t *testing.T) {
+	a := &Agent{}
+	rollout := strings.Join([]string{
+		`{"id":"sess-1","timestamp":"2025-01-01T00:00:00Z","instructions":null}`,
+		`{"record_type":"state"}`,
+		`{"type":"message","role":"user","content":[{"type":"input_text","text":"Hello"}]}`,
+		`{"type":"message","role":"assistant","content":[{"type":"output_text","text":"Hi"}]}`,
+	}, "\n")
+
+	transcript, err := a.ParseTranscript(strings.NewReader(rollout))
+	if err != nil {
+		t.Fatalf("ParseTranscript() error: %v", err)
+	}
+	if len(transcript.Entries) != 2 {
+		t.Fatalf("Expected 2 entries, got %d", len(transcript.Entries))
+	}
+	if transcript.Entries[1].Message.Content[0].Text != "Hi" {
+		t.Errorf("Entry 1 text = %q, want %q", transcript.Entries[1].Message.Content[0].Text, "Hi")
+	}
+}
+
+func TestDiscoverSession(t *testing.T) {
+	codexHome := t.TempDir()
+	t.Setenv("CODEX_HOME", codexHome)
+	project := t.TempDir()
+
+	dayDir := filepath.Join(codexHome, "sessions", "2025", "01", "01")
+	if err := os.MkdirAll(dayDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	// A long instructions field must not stop the session from being found
+	meta := `{"timestamp":"2025-01-01T00:00:00Z","type":"session_meta","payload":{"id":"sess-1","cwd":"` + project + `","instructions":"` + strings.Repeat("x", 100000) + `"}}` + "\n"
+	if err := os.WriteFile(filepath.Join(dayDir, "rollout-2025-01-01T00-00-00-sess-1.jsonl"), []byte(meta), 0644); err != nil {
+		t.Fatal(err)
+	}
+	other := `{"timestamp":"2025-01-01T00:00:00Z","type":"session_meta","payload":{"id":"sess-2","cwd":"/elsewhere"}}` + "\n"
+	if err := os.WriteFile(filepath.Join(dayDir, "rollout-2025-01-01T00-00-00-sess-2.jsonl"), []byte(other), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := (&Agent{}).DiscoverSession(project)
+	if err != nil {
+		t.Fatalf("DiscoverSession() error: %v", err)
+	}
+	if info == nil || info.SessionID != "sess-1" {
+		t.Fatalf("DiscoverSession() = %+v, want session sess-1", info)
+	}
+}
+
+func TestWriteSessionFileName(t *testing.T) {
+	t.Setenv("CODEX_HOME", t.TempDir())
+
+	path, err := WriteSessionFile("sess-1", []byte("{}\n"))
+	if err != nil {
+		t.Fatalf("WriteSessionFile() error: %v", err)
+	}
+	name := filepath.Base(path)
+	if !strings.HasPrefix(name, "rollout-") || !strings.HasSuffix(name, "-sess-1.jsonl") || len(name) != len("rollout-2025-01-01T00-00-00-sess-1.jsonl") {
+		t.Errorf("rollout file name = %q, want rollout-<YYYY-MM-DDThh-mm-ss>-sess-1.jsonl", name)
+	}
+}
+
+func TestEncodeTranscriptRoundTrip(t *testing.T) {
+	transcript := &agent.Transcript{Entries: []agent.TranscriptEntry{
+		{Type: agent.MessageTypeUser, Timestamp: "2026-01-01T10:00:00Z", Message: &agent.Message{Content: []agent.ContentBlock{{Type: "text", Text: "Hello"}}}},
+		{Type: agent.MessageTypeAssistant, Message: &agent.Message{Content: []agent.ContentBlock{
+			{Type: "text", Text: "Hi there"},
+			{Type: "tool_use", Name: "Bash"},
+		}}},
+		{Type: agent.MessageTypeUser, Message: &agent.Message{Content: []agent.ContentBlock{{Type: "tool_result"}}}},
+	}}
+
+	a := &Agent{}
+	data, err := a.EncodeTranscript(transcript, "session-1", "/repo")
+	if err != nil {
+		t.Fatalf("EncodeTranscript: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("got %d lines, want session_meta and 2 messages:\n%s", len(lines), data)
+	}
+	if !strings.Contains(lines[0], `"type":"session_meta"`) || !strings.Contains(lines[0], `"id":"session-1"`) {
+		t.Errorf("first line should be the session meta, got %s", lines[0])
+	}
+
+	parsed, err := a.ParseTranscript(strings.NewReader(string(data)))
+	if err != nil {
+		t.Fatalf("ParseTranscript: %v", err)
+	}
+	if len(parsed.Entries) != 2 {
+		t.Fatalf("got %d entries, want 2", len(parsed.Entries))
+	}
+	if parsed.Entries[0].Timestamp != "2026-01-01T10:00:00Z" || parsed.Entries[0].Message.Content[0].Text != "Hello" {
+		t.Errorf("entry 0 = %+v", parsed.Entries[0])
+	}
+	if parsed.Entries[1].Type != agent.MessageTypeAssistant || parsed.Entries[1].Message.Content[0].Text != "Hi there" {
+		t.Errorf("entry 1 = %+v", parsed.Entries[1])
+	}
+}
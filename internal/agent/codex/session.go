@@ -20,6 +20,10 @@ type SessionMeta struct {
 	ModelProvider string `json:"model_provider"`
 }
 
+// maxSessionMetaSize bounds the first line of a rollout file read by
+// ParseSessionMeta.
+const maxSessionMetaSize = 16 << 20
+
 // GetCodexHome returns the Codex home directory.
 // Respects $CODEX_HOME, defaulting to ~/.codex.
 func GetCodexHome() (string, error) {
@@ -51,7 +55,10 @@ func ParseSessionMeta(path string) (*SessionMeta, error) {
 	}
 	defer func() { _ = f.Close() }()
 
+	// The session_meta line embeds the session instructions, which can be
+	// far longer than bufio's default line limit
 	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxSessionMetaSize)
 	if !scanner.Scan() {
 		return nil, scanner.Err()
 	}
@@ -119,7 +126,8 @@ func FindRecentRollout(projectPath string, timeout time.Duration) (path string,
 }
 
 // WriteSessionFile writes transcript data to the Codex sessions directory,
-// organized by date as Codex expects.
+// organized by date and named as Codex names its rollouts, so that
+// `codex resume` finds it by session ID.
 func WriteSessionFile(sessionID string, data []byte) (string, error) {
 	sessionsDir, err := GetSessionsDir()
 	if err != nil {
@@ -132,8 +140,7 @@ func WriteSessionFile(sessionID string, data []byte) (string, error) {
 		return "", err
 	}
 
-	filename := "rollout-" + now.Format("20060102T150405") + "-" + sessionID + ".jsonl"
+	filename := "rollout-" + now.Format("2006-01-02T15-04-05") + "-" + sessionID + ".jsonl"
 	path := filepath.Join(dateDir, filename)
 	return path, os.WriteFile(path, data, 0644)
 }
-
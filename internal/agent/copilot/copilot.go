@@ -168,6 +168,15 @@ func (a *Agent) ParseHookInput(raw []byte) (*agent.HookData, error) {
 	}, nil
 }
 
+// MatchesHookInput claims payloads in Copilot's native format, which no
+// other agent sends.
+func (a *Agent) MatchesHookInput(raw []byte) bool {
+	var hook struct {
+		ToolName string `json:"toolName"`
+	}
+	return json.Unmarshal(raw, &hook) == nil && hook.ToolName != ""
+}
+
 // shellToolNames are the known tool names Copilot CLI uses for shell execution.
 var shellToolNames = map[string]bool{
 	"bash": true,
@@ -231,6 +240,44 @@ func (a *Agent) DiscoverSession(projectPath string) (*agent.SessionInfo, error)
 	return scanForRecentSession(projectPath)
 }
 
+// ListSessions returns the Copilot CLI sessions whose working directory is
+// the project, newest first.
+func (a *Agent) ListSessions(projectPath string) ([]agent.SessionInfo, error) {
+	sessionDir, err := GetSessionStateDir()
+	if err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(sessionDir)
+	if err != nil {
+		return nil, nil
+	}
+
+	var sessions []agent.SessionInfo
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		entryPath := filepath.Join(sessionDir, entry.Name())
+		meta, err := parseSessionMeta(entryPath)
+		if err != nil || meta == nil || !agent.PathsEqual(meta.CWD, projectPath) {
+			continue
+		}
+		sessions = append(sessions, agent.SessionInfo{
+			SessionID:      meta.ID,
+			TranscriptPath: GetTranscriptPath(entryPath),
+			StartedAt:      info.ModTime().Format(time.RFC3339),
+			ProjectPath:    projectPath,
+		})
+	}
+
+	agent.SortSessions(sessions)
+	return sessions, nil
+}
+
 // RestoreSession writes a transcript to Copilot CLI's expected location.
 func (a *Agent) RestoreSession(projectPath, sessionID, gitBranch string,
 	transcriptData []byte, messageCount int, summary string) error {
@@ -243,6 +290,11 @@ func (a *Agent) ResumeCommand(sessionID string) (string, []string) {
 	return "copilot", []string{"--resume", sessionID}
 }
 
+// VersionCommand returns the command that prints the Copilot CLI version.
+func (a *Agent) VersionCommand() (string, []string) {
+	return "copilot", []string{"--version"}
+}
+
 // ToolAliases returns Copilot CLI's tool name mappings to canonical names.
 func (a *Agent) ToolAliases() map[string]string {
 	return map[string]string{
@@ -325,8 +377,9 @@ func parseCopilotTranscript(r io.Reader) (*agent.Transcript, error) {
 			}
 
 			entries = append(entries, agent.TranscriptEntry{
-				UUID: fmt.Sprintf("copilot-%d", idx),
-				Type: agent.MessageTypeAssistant,
+				UUID:  fmt.Sprintf("copilot-%d", idx),
+				Type:  agent.MessageTypeAssistant,
+				Model: model,
 				Message: &agent.Message{
 					Role:    "assistant",
 					Content: content,
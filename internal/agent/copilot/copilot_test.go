@@ -58,6 +58,16 @@ func TestParseHookInputStringToolArgs(t *testing.T) {
 	}
 }
 
+func TestMatchesHookInput(t *testing.T) {
+	a := &Agent{}
+	if !a.MatchesHookInput([]byte(`{"timestamp":1700000000,"cwd":"/tmp/project","toolName":"bash","toolArgs":{}}`)) {
+		t.Error("MatchesHookInput() = false for native payload, want true")
+	}
+	if a.MatchesHookInput([]byte(`{"session_id":"s","tool_name":"bash","tool_input":{"command":"ls"}}`)) {
+		t.Error("MatchesHookInput() = true for standard payload, want false")
+	}
+}
+
 func TestIsCommitCommand(t *testing.T) {
 	a := &Agent{}
 	tests := []struct {
@@ -0,0 +1,447 @@
+// Package custom implements coding agents described by manifest files, for
+// agents shiftlog has no built-in support for.
+package custom
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/re-cinq/shift-log/internal/agent"
+	"github.com/re-cinq/shift-log/internal/util"
+)
+
+// Agent implements the agent.Agent interface from a Manifest.
+type Agent struct {
+	manifest *Manifest
+	queries  queries
+}
+
+// queries are the compiled queries of a TranscriptSpec and SessionSpec.
+type queries struct {
+	messages, id, role, text, thinking, timestamp, model *Query
+	inputTokens, outputTokens                            *Query
+	toolCalls, toolName, toolInput, toolID, toolResultID *Query
+	project                                              *Query
+}
+
+// summarisingAgent is an Agent whose manifest has a summarise command.
+type summarisingAgent struct {
+	*Agent
+}
+
+// New returns the agent a manifest describes.
+func New(m *Manifest) (*Agent, error) {
+	a := &Agent{manifest: m}
+	t := m.Transcript
+	for _, q := range []struct {
+		dst    **Query
+		source string
+	}{
+		{&a.queries.messages, t.Messages},
+		{&a.queries.id, t.ID},
+		{&a.queries.role, t.Role},
+		{&a.queries.text, t.Text},
+		{&a.queries.thinking, t.Thinking},
+		{&a.queries.timestamp, t.Timestamp},
+		{&a.queries.model, t.Model},
+		{&a.queries.inputTokens, t.InputTokens},
+		{&a.queries.outputTokens, t.OutputTokens},
+		{&a.queries.toolCalls, t.ToolCalls},
+		{&a.queries.toolName, t.ToolName},
+		{&a.queries.toolInput, t.ToolInput},
+		{&a.queries.toolID, t.ToolID},
+		{&a.queries.toolResultID, t.ToolResultID},
+		{&a.queries.project, m.Sessions.Project},
+	} {
+		compiled, err := ParseQuery(q.source)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", m.path, err)
+		}
+		*q.dst = compiled
+	}
+	return a, nil
+}
+
+func (a *Agent) Name() agent.Name    { return agent.Name(a.manifest.Name) }
+func (a *Agent) DisplayName() string { return a.manifest.DisplayName }
+
+// ConfigureHooks is a no-op — custom agents are captured by the post-commit
+// git hook, or by an agent hook that runs 'shiftlog store --agent=<name>'.
+func (a *Agent) ConfigureHooks(repoRoot string) error {
+	return nil
+}
+
+// RemoveHooks is a no-op, see ConfigureHooks.
+func (a *Agent) RemoveHooks(repoRoot string) error {
+	return nil
+}
+
+// DiagnoseHooks checks that the manifest's session directory exists.
+func (a *Agent) DiagnoseHooks(repoRoot string) []agent.DiagnosticCheck {
+	check := agent.DiagnosticCheck{Name: a.DisplayName() + " sessions"}
+	dir, err := expandDir(a.manifest.Sessions.Dir, repoRoot)
+	if err != nil {
+		check.Message = err.Error()
+		return []agent.DiagnosticCheck{check}
+	}
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		check.Message = fmt.Sprintf("Session directory %s not found (from %s)", dir, a.manifest.path)
+		return []agent.DiagnosticCheck{check}
+	}
+	check.OK = true
+	check.Message = fmt.Sprintf("Found session directory %s", dir)
+	return []agent.DiagnosticCheck{check}
+}
+
+// ParseHookInput parses hook JSON in the standard format.
+func (a *Agent) ParseHookInput(raw []byte) (*agent.HookData, error) {
+	return agent.ParseStandardHookInput(raw)
+}
+
+// IsCommitCommand checks if a tool invocation represents a git commit. Any
+// tool counts when the manifest lists no shell tools.
+func (a *Agent) IsCommitCommand(toolName, command string) bool {
+	if len(a.manifest.ShellTools) > 0 {
+		shell := false
+		for _, t := range a.manifest.ShellTools {
+			if t == toolName {
+				shell = true
+				break
+			}
+		}
+		if !shell {
+			return false
+		}
+	}
+	return agent.IsGitCommitCommand(command)
+}
+
+// ParseTranscript parses a transcript as the manifest describes it.
+func (a *Agent) ParseTranscript(r io.Reader) (*agent.Transcript, error) {
+	messages, err := a.readMessages(r)
+	if err != nil {
+		return nil, err
+	}
+
+	t := &agent.Transcript{}
+	for i, msg := range messages {
+		entry, ok := a.parseMessage(msg, i)
+		if !ok {
+			continue
+		}
+		if model := a.queries.model.Text(msg); model != "" {
+			t.Model = model
+			if entry.Type == agent.MessageTypeAssistant {
+				entry.Model = model
+			}
+		}
+		usage := agent.UsageMetrics{
+			InputTokens:  number(a.queries.inputTokens.First(msg)),
+			OutputTokens: number(a.queries.outputTokens.First(msg)),
+		}
+		if usage.TotalTokens() > 0 {
+			entry.Usage = &usage
+		}
+		t.Usage.InputTokens += usage.InputTokens
+		t.Usage.OutputTokens += usage.OutputTokens
+		t.Entries = append(t.Entries, entry)
+	}
+	t.Turns = t.CountTurns()
+	return t, nil
+}
+
+// readMessages decodes the transcript's messages: one per line for JSONL,
+// or the Messages query of a JSON document.
+func (a *Agent) readMessages(r io.Reader) ([]interface{}, error) {
+	if a.manifest.Transcript.Format == FormatJSON {
+		var doc interface{}
+		if err := json.NewDecoder(r).Decode(&doc); err != nil {
+			if err == io.EOF {
+				return nil, nil
+			}
+			return nil, err
+		}
+		return flatten(a.queries.messages.All(doc)), nil
+	}
+
+	var messages []interface{}
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 32*1024*1024)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var msg interface{}
+		if err := json.Unmarshal(line, &msg); err != nil {
+			continue
+		}
+		messages = append(messages, msg)
+	}
+	return messages, scanner.Err()
+}
+
+// parseMessage converts a message into a transcript entry. Messages with an
+// unknown role or no content are skipped.
+func (a *Agent) parseMessage(msg interface{}, index int) (agent.TranscriptEntry, bool) {
+	role := a.role(a.queries.role.Text(msg))
+	if role == "" {
+		return agent.TranscriptEntry{}, false
+	}
+
+	entry := agent.TranscriptEntry{
+		UUID:      a.queries.id.Text(msg),
+		Timestamp: timestamp(a.queries.timestamp.First(msg)),
+	}
+	if entry.UUID == "" {
+		entry.UUID = fmt.Sprintf("%s-%d", a.manifest.Name, index)
+	}
+
+	text := a.queries.text.Text(msg)
+	var blocks []agent.ContentBlock
+	if role == roleTool {
+		entry.Type = agent.MessageTypeUser
+		content, _ := json.Marshal(text)
+		blocks = append(blocks, agent.ContentBlock{
+			Type:      "tool_result",
+			ToolUseID: a.queries.toolResultID.Text(msg),
+			Content:   content,
+		})
+		entry.Message = &agent.Message{Role: "user", Content: blocks}
+		return entry, true
+	}
+
+	entry.Type = agent.NormalizeRole(role)
+	if thinking := a.queries.thinking.Text(msg); thinking != "" {
+		blocks = append(blocks, agent.ContentBlock{Type: "thinking", Thinking: thinking})
+	}
+	if text != "" {
+		blocks = append(blocks, agent.ContentBlock{Type: "text", Text: text})
+	}
+	for j, call := range flatten(a.queries.toolCalls.All(msg)) {
+		name := a.queries.toolName.Text(call)
+		if name == "" {
+			continue
+		}
+		id := a.queries.toolID.Text(call)
+		if id == "" {
+			id = fmt.Sprintf("%s-%d-%d", a.manifest.Name, index, j)
+		}
+		blocks = append(blocks, agent.ContentBlock{
+			Type:  "tool_use",
+			ID:    id,
+			Name:  name,
+			Input: rawInput(a.queries.toolInput.First(call)),
+		})
+	}
+	if len(blocks) == 0 {
+		return agent.TranscriptEntry{}, false
+	}
+	entry.Message = &agent.Message{Role: role, Content: blocks}
+	return entry, true
+}
+
+// flatten expands array results, so that "messages" and "messages[]" yield
+// the same values.
+func flatten(values []interface{}) []interface{} {
+	var out []interface{}
+	for _, v := range values {
+		if arr, ok := v.([]interface{}); ok {
+			out = append(out, arr...)
+		} else {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// role maps an agent role to user, assistant, system or tool, or "" if the
+// role is unknown.
+func (a *Agent) role(raw string) string {
+	if mapped, ok := a.manifest.Transcript.Roles[raw]; ok {
+		return mapped
+	}
+	if raw == roleTool {
+		return roleTool
+	}
+	return string(agent.NormalizeRole(raw))
+}
+
+// rawInput returns a tool input as JSON. Inputs given as a JSON string, as
+// in OpenAI-style function arguments, are decoded.
+func rawInput(v interface{}) json.RawMessage {
+	if v == nil {
+		return nil
+	}
+	if s, ok := v.(string); ok && json.Valid([]byte(s)) {
+		return json.RawMessage(s)
+	}
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return nil
+	}
+	return raw
+}
+
+// timestamp formats a string timestamp as RFC 3339, or a number as Unix
+// seconds or, when too large for seconds, milliseconds.
+func timestamp(v interface{}) string {
+	switch val := v.(type) {
+	case string:
+		return util.NormalizeTimestamp(val)
+	case float64:
+		if val > 1e11 {
+			return time.UnixMilli(int64(val)).UTC().Format(time.RFC3339)
+		}
+		return time.Unix(int64(val), 0).UTC().Format(time.RFC3339)
+	}
+	return ""
+}
+
+// number returns a JSON number as an int64, or 0.
+func number(v interface{}) int64 {
+	if f, ok := v.(float64); ok {
+		return int64(f)
+	}
+	return 0
+}
+
+// ParseTranscriptFile parses a transcript file.
+func (a *Agent) ParseTranscriptFile(path string) (*agent.Transcript, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+	return a.ParseTranscript(f)
+}
+
+// DiscoverSession finds the most recently modified transcript in the
+// session directory, skipping other projects' sessions when the manifest
+// says where transcripts record their project.
+func (a *Agent) DiscoverSession(projectPath string) (*agent.SessionInfo, error) {
+	dir, err := expandDir(a.manifest.Sessions.Dir, projectPath)
+	if err != nil {
+		return nil, err
+	}
+	matches, err := filepath.Glob(filepath.Join(dir, a.manifest.Sessions.Glob))
+	if err != nil {
+		return nil, nil
+	}
+
+	now := time.Now()
+	var best string
+	var bestModTime time.Time
+	for _, path := range matches {
+		info, err := os.Stat(path)
+		if err != nil || info.IsDir() || now.Sub(info.ModTime()) > agent.RecentSessionTimeout {
+			continue
+		}
+		if best != "" && !info.ModTime().After(bestModTime) {
+			continue
+		}
+		if !a.belongsTo(path, projectPath) {
+			continue
+		}
+		best = path
+		bestModTime = info.ModTime()
+	}
+
+	if best == "" {
+		return nil, nil
+	}
+	return &agent.SessionInfo{
+		SessionID:      strings.TrimSuffix(filepath.Base(best), filepath.Ext(best)),
+		TranscriptPath: best,
+		StartedAt:      bestModTime.Format(time.RFC3339),
+		ProjectPath:    projectPath,
+	}, nil
+}
+
+// belongsTo reports whether a transcript records the project as its working
+// directory. It is always true when the manifest has no project query.
+func (a *Agent) belongsTo(path, projectPath string) bool {
+	if a.queries.project.IsEmpty() {
+		return true
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer func() { _ = f.Close() }()
+
+	var doc interface{}
+	if err := json.NewDecoder(f).Decode(&doc); err != nil {
+		return false
+	}
+	dir := a.queries.project.Text(doc)
+	return dir != "" && agent.PathsEqual(dir, projectPath)
+}
+
+// RestoreSession writes the transcript back into the session directory.
+func (a *Agent) RestoreSession(projectPath, sessionID, gitBranch string,
+	transcriptData []byte, messageCount int, summary string) error {
+
+	path, err := a.sessionPath(projectPath, sessionID)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("could not create session directory: %w", err)
+	}
+	return os.WriteFile(path, transcriptData, 0600)
+}
+
+// sessionPath returns the transcript path of a session, named after the
+// extension of the manifest's glob.
+func (a *Agent) sessionPath(projectPath, sessionID string) (string, error) {
+	dir, err := expandDir(a.manifest.Sessions.Dir, projectPath)
+	if err != nil {
+		return "", err
+	}
+	ext := filepath.Ext(a.manifest.Sessions.Glob)
+	if ext == "" || strings.ContainsAny(ext, "*?[") {
+		ext = "." + a.manifest.Transcript.Format
+	}
+	return filepath.Join(dir, sessionID+ext), nil
+}
+
+// ResumeCommand returns the manifest's resume command for a session.
+func (a *Agent) ResumeCommand(sessionID string) (string, []string) {
+	path := ""
+	if cwd, err := util.GetProjectRoot(); err == nil {
+		path, _ = a.sessionPath(cwd, sessionID)
+	}
+	return a.manifest.Resume.Command, expandArgs(a.manifest.Resume.Args, sessionID, path)
+}
+
+func expandArgs(args []string, sessionID, transcriptPath string) []string {
+	r := strings.NewReplacer("{session_id}", sessionID, "{transcript_path}", transcriptPath)
+	out := make([]string, len(args))
+	for i, arg := range args {
+		out[i] = r.Replace(arg)
+	}
+	return out
+}
+
+// ToolAliases returns the manifest's tool name mappings.
+func (a *Agent) ToolAliases() map[string]string {
+	return a.manifest.ToolAliases
+}
+
+// VersionCommand returns the manifest's version command, if any.
+func (a *Agent) VersionCommand() (string, []string) {
+	return a.manifest.Version.Command, append([]string(nil), a.manifest.Version.Args...)
+}
+
+// SummariseCommand returns the manifest's summarise command.
+func (s *summarisingAgent) SummariseCommand() (string, []string) {
+	return s.manifest.Summarise.Command, append([]string(nil), s.manifest.Summarise.Args...)
+}
@@ -0,0 +1,339 @@
+package custom
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/re-cinq/shift-log/internal/agent"
+)
+
+func TestQuery(t *testing.T) {
+	doc := map[string]interface{}{
+		"message": map[string]interface{}{
+			"content": []interface{}{
+				map[string]interface{}{"type": "text", "text": "one"},
+				map[string]interface{}{"type": "text", "text": "two"},
+			},
+		},
+		"tokens": 42.0,
+	}
+
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{".message.content[0].text", "one"},
+		{"message.content[-1].text", "two"},
+		{".message.content[].text", "one\ntwo"},
+		{".missing // .message.content[1].text", "two"},
+		{".tokens", "42"},
+		{".missing", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		q, err := ParseQuery(tt.query)
+		if err != nil {
+			t.Fatalf("ParseQuery(%q) error: %v", tt.query, err)
+		}
+		if got := q.Text(doc); got != tt.want {
+			t.Errorf("Query(%q).Text() = %q, want %q", tt.query, got, tt.want)
+		}
+	}
+
+	for _, bad := range []string{".a[", ".a[x]", ".a // "} {
+		if _, err := ParseQuery(bad); err == nil {
+			t.Errorf("ParseQuery(%q) succeeded, want error", bad)
+		}
+	}
+}
+
+// openAIManifest describes transcripts in OpenAI chat message format.
+const openAIManifest = `
+name: acme
+display_name: Acme Agent
+sessions:
+  dir: "{sessions}"
+  project: .cwd
+transcript:
+  format: jsonl
+  id: .id
+  role: .role
+  text: .content
+  timestamp: .created
+  model: .model
+  input_tokens: .usage.prompt_tokens
+  output_tokens: .usage.completion_tokens
+  tool_calls: .tool_calls
+  tool_name: .function.name
+  tool_input: .function.arguments
+  tool_id: .id
+  tool_result_id: .tool_call_id
+  roles:
+    human: user
+shell_tools: [shell]
+tool_aliases:
+  shell: Bash
+resume:
+  command: acme
+  args: ["--resume", "{session_id}"]
+summarise:
+  command: acme
+  args: ["--print"]
+`
+
+const openAITranscript = `{"cwd":"{project}","role":"system","content":"You are Acme."}
+{"id":"m1","role":"human","content":"Commit the fix","created":1760000000}
+{"id":"m2","role":"assistant","content":"Committing.","model":"acme-1","usage":{"prompt_tokens":100,"completion_tokens":20},"tool_calls":[{"id":"call_1","function":{"name":"shell","arguments":"{\"command\":\"git commit -m fix\"}"}}]}
+{"id":"m3","role":"tool","tool_call_id":"call_1","content":"[main abc123] fix"}
+{"id":"m4","role":"assistant","content":"Done.","model":"acme-1","usage":{"prompt_tokens":150,"completion_tokens":5}}
+not json
+`
+
+func writeManifest(t *testing.T, dir, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, "acme.yaml")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func loadAgent(t *testing.T, sessions string) *Agent {
+	t.Helper()
+	m, err := LoadManifest(writeManifest(t, t.TempDir(), strings.ReplaceAll(openAIManifest, "{sessions}", sessions)))
+	if err != nil {
+		t.Fatalf("LoadManifest() error: %v", err)
+	}
+	a, err := New(m)
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+	return a
+}
+
+func TestParseTranscript(t *testing.T) {
+	a := loadAgent(t, "/sessions")
+	transcript, err := a.ParseTranscript(strings.NewReader(openAITranscript))
+	if err != nil {
+		t.Fatalf("ParseTranscript() error: %v", err)
+	}
+
+	if len(transcript.Entries) != 5 {
+		t.Fatalf("got %d entries, want 5", len(transcript.Entries))
+	}
+	if transcript.Model != "acme-1" {
+		t.Errorf("Model = %q, want acme-1", transcript.Model)
+	}
+	if transcript.Usage.InputTokens != 250 || transcript.Usage.OutputTokens != 25 {
+		t.Errorf("Usage = %+v, want 250/25", transcript.Usage)
+	}
+	if transcript.Turns != 1 {
+		t.Errorf("Turns = %d, want 1", transcript.Turns)
+	}
+
+	system := transcript.Entries[0]
+	if system.Type != agent.MessageTypeSystem || system.UUID != "acme-0" {
+		t.Errorf("system entry = %s/%s", system.Type, system.UUID)
+	}
+
+	user := transcript.Entries[1]
+	if user.Type != agent.MessageTypeUser || user.UUID != "m1" || user.Timestamp != "2025-10-09T08:53:20Z" {
+		t.Errorf("user entry = %s/%s/%s", user.Type, user.UUID, user.Timestamp)
+	}
+
+	blocks := transcript.Entries[2].Message.Content
+	if len(blocks) != 2 || blocks[1].Type != "tool_use" {
+		t.Fatalf("assistant blocks = %+v", blocks)
+	}
+	if blocks[1].ID != "call_1" || blocks[1].Name != "shell" || string(blocks[1].Input) != `{"command":"git commit -m fix"}` {
+		t.Errorf("tool_use = %s %s %s", blocks[1].ID, blocks[1].Name, blocks[1].Input)
+	}
+
+	result := transcript.Entries[3]
+	if result.Type != agent.MessageTypeUser || result.Message.Content[0].Type != "tool_result" ||
+		result.Message.Content[0].ToolUseID != "call_1" {
+		t.Errorf("tool result entry = %+v", result.Message.Content)
+	}
+
+	files := agent.EditedFiles(transcript.Entries, a.ToolAliases())
+	if len(files) != 0 {
+		t.Errorf("EditedFiles() = %v, want none", files)
+	}
+}
+
+func TestParseTranscriptJSON(t *testing.T) {
+	m := &Manifest{
+		Name:     "doc",
+		Sessions: SessionSpec{Dir: "/sessions"},
+		Transcript: TranscriptSpec{
+			Format:   FormatJSON,
+			Messages: ".conversation.turns",
+			Role:     ".author",
+			Text:     ".parts[].text",
+		},
+	}
+	if err := m.validate(); err != nil {
+		t.Fatalf("validate() error: %v", err)
+	}
+	a, err := New(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	doc := `{"conversation":{"turns":[{"author":"user","parts":[{"text":"Hi"}]},{"author":"model","parts":[{"text":"Hello"},{"text":"there"}]}]}}`
+	transcript, err := a.ParseTranscript(strings.NewReader(doc))
+	if err != nil {
+		t.Fatalf("ParseTranscript() error: %v", err)
+	}
+	if len(transcript.Entries) != 2 {
+		t.Fatalf("got %d entries, want 2", len(transcript.Entries))
+	}
+	if got := transcript.Entries[1].Message.Content[0].Text; got != "Hello\nthere" {
+		t.Errorf("assistant text = %q", got)
+	}
+	if m.Sessions.Glob != "*.json" {
+		t.Errorf("default glob = %q, want *.json", m.Sessions.Glob)
+	}
+}
+
+func TestLoadManifestValidation(t *testing.T) {
+	tests := map[string]string{
+		"name":     "name: Acme\nsessions: {dir: /s}\ntranscript: {role: .r, text: .t}\n",
+		"dir":      "name: acme\ntranscript: {role: .r, text: .t}\n",
+		"format":   "name: acme\nsessions: {dir: /s}\ntranscript: {format: xml, role: .r, text: .t}\n",
+		"messages": "name: acme\nsessions: {dir: /s}\ntranscript: {format: json, role: .r, text: .t}\n",
+		"text":     "name: acme\nsessions: {dir: /s}\ntranscript: {role: .r}\n",
+		"roles":    "name: acme\nsessions: {dir: /s}\ntranscript: {role: .r, text: .t, roles: {bot: robot}}\n",
+	}
+	for field, content := range tests {
+		if _, err := LoadManifest(writeManifest(t, t.TempDir(), content)); err == nil {
+			t.Errorf("LoadManifest() with invalid %s succeeded", field)
+		}
+	}
+
+	// JSON manifests are accepted too
+	m, err := LoadManifest(writeManifest(t, t.TempDir(), `{"name":"acme","sessions":{"dir":"/s"},"transcript":{"role":".r","text":".t"}}`))
+	if err != nil {
+		t.Fatalf("LoadManifest(json) error: %v", err)
+	}
+	if m.DisplayName != "acme" || m.Sessions.Glob != "*.jsonl" {
+		t.Errorf("defaults = %q %q", m.DisplayName, m.Sessions.Glob)
+	}
+}
+
+func TestDiscoverAndRestoreSession(t *testing.T) {
+	project := t.TempDir()
+	other := t.TempDir()
+	sessions := filepath.Join(t.TempDir(), "{project_slug}")
+	a := loadAgent(t, sessions)
+
+	dir, err := expandDir(sessions, project)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(dir, "{") {
+		t.Fatalf("expandDir() = %s, placeholders left", dir)
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	if info, _ := a.DiscoverSession(project); info != nil {
+		t.Fatalf("DiscoverSession() = %+v, want nil without sessions", info)
+	}
+
+	mine := filepath.Join(dir, "mine.jsonl")
+	if err := os.WriteFile(mine, []byte(strings.ReplaceAll(openAITranscript, "{project}", project)), 0644); err != nil {
+		t.Fatal(err)
+	}
+	past := time.Now().Add(-time.Minute)
+	if err := os.Chtimes(mine, past, past); err != nil {
+		t.Fatal(err)
+	}
+	theirs := filepath.Join(dir, "theirs.jsonl")
+	if err := os.WriteFile(theirs, []byte(strings.ReplaceAll(openAITranscript, "{project}", other)), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := a.DiscoverSession(project)
+	if err != nil || info == nil {
+		t.Fatalf("DiscoverSession() = %v, %v", info, err)
+	}
+	if info.SessionID != "mine" || info.TranscriptPath != mine {
+		t.Errorf("DiscoverSession() = %s at %s, want mine", info.SessionID, info.TranscriptPath)
+	}
+
+	if err := a.RestoreSession(project, "restored", "main", []byte(openAITranscript), 5, ""); err != nil {
+		t.Fatalf("RestoreSession() error: %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "restored.jsonl"))
+	if err != nil {
+		t.Fatalf("restored transcript not found: %v", err)
+	}
+	if string(data) != openAITranscript {
+		t.Error("restored transcript differs from the stored one")
+	}
+}
+
+func TestCommands(t *testing.T) {
+	a := loadAgent(t, "/sessions")
+
+	if !a.IsCommitCommand("shell", "git commit -m x") {
+		t.Error("IsCommitCommand(shell, git commit) = false, want true")
+	}
+	if a.IsCommitCommand("edit", "git commit -m x") {
+		t.Error("IsCommitCommand(edit, git commit) = true, want false")
+	}
+
+	bin, args := a.ResumeCommand("abc")
+	if bin != "acme" || strings.Join(args, " ") != "--resume abc" {
+		t.Errorf("ResumeCommand() = %s %v", bin, args)
+	}
+
+	var ag agent.Agent = &summarisingAgent{a}
+	s, ok := ag.(agent.Summariser)
+	if !ok {
+		t.Fatal("summarising agent does not implement Summariser")
+	}
+	if bin, args := s.SummariseCommand(); bin != "acme" || len(args) != 1 || args[0] != "--print" {
+		t.Errorf("SummariseCommand() = %s %v", bin, args)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	project := t.TempDir()
+	dir := filepath.Join(project, ".shiftlog", "agents")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	valid := "name: loadtest\nsessions: {dir: /s}\ntranscript: {role: .r, text: .t}\n"
+	builtin := "name: claude\nsessions: {dir: /s}\ntranscript: {role: .r, text: .t}\n"
+	for name, content := range map[string]string{"a.yaml": valid, "b.yml": builtin, "notes.txt": "ignored"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	agent.Register(builtinStub{})
+
+	errs := Load(project)
+	if len(errs) != 1 || !strings.Contains(errs[0].Error(), "built in") {
+		t.Errorf("Load() errors = %v, want one for the built-in name", errs)
+	}
+	if _, err := agent.Get("loadtest"); err != nil {
+		t.Errorf("custom agent not registered: %v", err)
+	}
+
+	// Loading again replaces the custom agent rather than failing
+	if errs := Load(project); len(errs) != 1 {
+		t.Errorf("second Load() errors = %v", errs)
+	}
+}
+
+// builtinStub stands in for a built-in agent in the registry.
+type builtinStub struct{ agent.Agent }
+
+func (builtinStub) Name() agent.Name { return agent.Claude }
@@ -0,0 +1,259 @@
+package custom
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/re-cinq/shift-log/internal/agent"
+)
+
+// Manifest describes a coding agent that shiftlog has no built-in support
+// for: where its transcripts live, how to read them, and how to resume them.
+// Manifests are YAML or JSON files.
+type Manifest struct {
+	Name        string `yaml:"name"`
+	DisplayName string `yaml:"display_name"`
+
+	Sessions   SessionSpec    `yaml:"sessions"`
+	Transcript TranscriptSpec `yaml:"transcript"`
+
+	// ShellTools are the tool names that run shell commands, used to detect
+	// commits from hook input.
+	ShellTools []string `yaml:"shell_tools"`
+	// ToolAliases maps the agent's tool names to canonical names (Bash,
+	// Read, Edit, ...).
+	ToolAliases map[string]string `yaml:"tool_aliases"`
+
+	Resume    CommandSpec `yaml:"resume"`
+	Summarise CommandSpec `yaml:"summarise"`
+	Version   CommandSpec `yaml:"version"`
+
+	path string
+}
+
+// SessionSpec says where the agent keeps its transcripts.
+type SessionSpec struct {
+	// Dir is the transcript directory. It may start with ~ and contain
+	// {project} (the project path) and {project_slug} (the project path
+	// with separators replaced by dashes).
+	Dir string `yaml:"dir"`
+	// Glob selects transcript files in Dir (default "*.jsonl" or "*.json"
+	// depending on the transcript format).
+	Glob string `yaml:"glob"`
+	// Project is a query for the working directory recorded in a
+	// transcript, used to skip sessions of other projects. For JSONL
+	// transcripts it is applied to the first line.
+	Project string `yaml:"project"`
+}
+
+// TranscriptSpec describes a transcript's layout as queries (see Query).
+type TranscriptSpec struct {
+	// Format is "jsonl" (one message per line, the default) or "json" (one
+	// document with the messages at Messages).
+	Format   string `yaml:"format"`
+	Messages string `yaml:"messages"`
+
+	// Queries applied to each message.
+	ID        string `yaml:"id"`
+	Role      string `yaml:"role"`
+	Text      string `yaml:"text"`
+	Thinking  string `yaml:"thinking"`
+	Timestamp string `yaml:"timestamp"`
+	Model     string `yaml:"model"`
+
+	InputTokens  string `yaml:"input_tokens"`
+	OutputTokens string `yaml:"output_tokens"`
+
+	// ToolCalls yields the tool calls of a message; ToolName, ToolInput and
+	// ToolID are applied to each call.
+	ToolCalls string `yaml:"tool_calls"`
+	ToolName  string `yaml:"tool_name"`
+	ToolInput string `yaml:"tool_input"`
+	ToolID    string `yaml:"tool_id"`
+
+	// ToolResultID is applied to messages with the "tool" role to link
+	// them to the call they answer; their Text becomes the result.
+	ToolResultID string `yaml:"tool_result_id"`
+
+	// Roles maps the agent's role names to user, assistant, system or
+	// tool. Unmapped roles fall back to the common names.
+	Roles map[string]string `yaml:"roles"`
+}
+
+// CommandSpec is a command line. Args may contain {session_id} and
+// {transcript_path}.
+type CommandSpec struct {
+	Command string   `yaml:"command"`
+	Args    []string `yaml:"args"`
+}
+
+// Transcript formats.
+const (
+	FormatJSONL = "jsonl"
+	FormatJSON  = "json"
+)
+
+// roleTool marks tool result messages in TranscriptSpec.Roles.
+const roleTool = "tool"
+
+var namePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+// LoadManifest reads and validates a manifest file.
+func LoadManifest(path string) (*Manifest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var m Manifest
+	if err := yaml.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("could not parse %s: %w", path, err)
+	}
+	m.path = path
+	if err := m.validate(); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &m, nil
+}
+
+func (m *Manifest) validate() error {
+	if !namePattern.MatchString(m.Name) {
+		return fmt.Errorf("name %q must be lowercase letters, digits, - or _", m.Name)
+	}
+	if m.Sessions.Dir == "" {
+		return fmt.Errorf("sessions.dir is required")
+	}
+	switch m.Transcript.Format {
+	case "":
+		m.Transcript.Format = FormatJSONL
+	case FormatJSONL, FormatJSON:
+	default:
+		return fmt.Errorf("transcript.format must be %s or %s", FormatJSONL, FormatJSON)
+	}
+	if m.Transcript.Format == FormatJSON && m.Transcript.Messages == "" {
+		return fmt.Errorf("transcript.messages is required for the json format")
+	}
+	if m.Transcript.Role == "" || m.Transcript.Text == "" {
+		return fmt.Errorf("transcript.role and transcript.text are required")
+	}
+	for role, canonical := range m.Transcript.Roles {
+		switch canonical {
+		case "user", "assistant", "system", roleTool:
+		default:
+			return fmt.Errorf("transcript.roles.%s must be user, assistant, system or tool", role)
+		}
+	}
+	if m.Sessions.Glob == "" {
+		m.Sessions.Glob = "*." + m.Transcript.Format
+	}
+	if _, err := filepath.Match(m.Sessions.Glob, ""); err != nil {
+		return fmt.Errorf("invalid sessions.glob %q: %w", m.Sessions.Glob, err)
+	}
+	if m.DisplayName == "" {
+		m.DisplayName = m.Name
+	}
+	return nil
+}
+
+// ManifestDirs returns the directories manifests are loaded from, in
+// load order: the user's ~/.config/shiftlog/agents, then the project's
+// .shiftlog/agents, whose manifests override the user's.
+func ManifestDirs(projectRoot string) []string {
+	var dirs []string
+	if configDir, err := userConfigDir(); err == nil {
+		dirs = append(dirs, filepath.Join(configDir, "shiftlog", "agents"))
+	}
+	if projectRoot != "" {
+		dirs = append(dirs, filepath.Join(projectRoot, ".shiftlog", "agents"))
+	}
+	return dirs
+}
+
+// userConfigDir returns $XDG_CONFIG_HOME, or ~/.config on every platform.
+func userConfigDir() (string, error) {
+	if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" {
+		return dir, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".config"), nil
+}
+
+// registered tracks the agents registered from manifests, which later
+// manifests may replace, unlike built-in agents.
+var registered = map[agent.Name]bool{}
+
+// Load registers an agent for every manifest in the project's and user's
+// manifest directories. Invalid manifests are skipped and reported in the
+// returned errors.
+func Load(projectRoot string) []error {
+	var errs []error
+	for _, dir := range ManifestDirs(projectRoot) {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		var names []string
+		for _, e := range entries {
+			switch filepath.Ext(e.Name()) {
+			case ".yaml", ".yml", ".json":
+				if !e.IsDir() {
+					names = append(names, e.Name())
+				}
+			}
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			m, err := LoadManifest(filepath.Join(dir, name))
+			if err == nil {
+				err = Register(m)
+			}
+			if err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errs
+}
+
+// Register registers an agent for the manifest. It refuses to replace a
+// built-in agent.
+func Register(m *Manifest) error {
+	name := agent.Name(m.Name)
+	if _, err := agent.Get(name); err == nil && !registered[name] {
+		return fmt.Errorf("%s: agent %q is built in", m.path, m.Name)
+	}
+	a, err := New(m)
+	if err != nil {
+		return err
+	}
+	registered[name] = true
+	if m.Summarise.Command != "" {
+		agent.Register(&summarisingAgent{a})
+	} else {
+		agent.Register(a)
+	}
+	return nil
+}
+
+// expandDir resolves ~ and the project placeholders of a session directory.
+func expandDir(dir, projectPath string) (string, error) {
+	if dir == "~" || strings.HasPrefix(dir, "~/") {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("could not determine home directory: %w", err)
+		}
+		dir = filepath.Join(home, strings.TrimPrefix(dir, "~"))
+	}
+	slug := strings.NewReplacer("/", "-", "\\", "-", ":", "-").Replace(projectPath)
+	dir = strings.ReplaceAll(dir, "{project_slug}", slug)
+	dir = strings.ReplaceAll(dir, "{project}", projectPath)
+	return filepath.Clean(dir), nil
+}
@@ -0,0 +1,176 @@
+package custom
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Query is a compiled extraction path, a small subset of jq path syntax:
+//
+//	.message.content     object fields
+//	.parts[0].text       array index
+//	.content[].text      every element of an array
+//	.text // .content    the first alternative that yields a value
+//
+// The leading dot is optional.
+type Query struct {
+	source       string
+	alternatives [][]step
+}
+
+// step is one path segment: an object field, an array index, or (iterate)
+// every element of an array.
+type step struct {
+	field   string
+	index   int
+	isIndex bool
+	iterate bool
+}
+
+// ParseQuery compiles a query. An empty query matches nothing.
+func ParseQuery(source string) (*Query, error) {
+	q := &Query{source: source}
+	if strings.TrimSpace(source) == "" {
+		return q, nil
+	}
+	for _, alt := range strings.Split(source, "//") {
+		steps, err := parseSteps(strings.TrimSpace(alt))
+		if err != nil {
+			return nil, fmt.Errorf("invalid query %q: %w", source, err)
+		}
+		q.alternatives = append(q.alternatives, steps)
+	}
+	return q, nil
+}
+
+func parseSteps(path string) ([]step, error) {
+	if path == "" {
+		return nil, fmt.Errorf("empty alternative")
+	}
+	path = strings.TrimPrefix(path, ".")
+
+	var steps []step
+	for path != "" {
+		switch {
+		case strings.HasPrefix(path, "["):
+			end := strings.Index(path, "]")
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated [")
+			}
+			inner := strings.TrimSpace(path[1:end])
+			if inner == "" {
+				steps = append(steps, step{iterate: true})
+			} else {
+				n, err := strconv.Atoi(inner)
+				if err != nil {
+					return nil, fmt.Errorf("invalid index [%s]", inner)
+				}
+				steps = append(steps, step{index: n, isIndex: true})
+			}
+			path = path[end+1:]
+		case strings.HasPrefix(path, "."):
+			path = path[1:]
+		default:
+			end := strings.IndexAny(path, ".[")
+			if end < 0 {
+				end = len(path)
+			}
+			steps = append(steps, step{field: path[:end]})
+			path = path[end:]
+		}
+	}
+	return steps, nil
+}
+
+// String returns the query source.
+func (q *Query) String() string {
+	return q.source
+}
+
+// IsEmpty reports whether the query matches nothing.
+func (q *Query) IsEmpty() bool {
+	return q == nil || len(q.alternatives) == 0
+}
+
+// All returns every value the query yields from v, using the first
+// alternative that yields any non-null value.
+func (q *Query) All(v interface{}) []interface{} {
+	if q.IsEmpty() {
+		return nil
+	}
+	for _, steps := range q.alternatives {
+		var out []interface{}
+		for _, r := range apply(v, steps) {
+			if r != nil {
+				out = append(out, r)
+			}
+		}
+		if len(out) > 0 {
+			return out
+		}
+	}
+	return nil
+}
+
+// First returns the first value the query yields from v, or nil.
+func (q *Query) First(v interface{}) interface{} {
+	if all := q.All(v); len(all) > 0 {
+		return all[0]
+	}
+	return nil
+}
+
+// Text returns the query result as text: strings are joined with
+// newlines, numbers are formatted, and anything else is ignored.
+func (q *Query) Text(v interface{}) string {
+	var parts []string
+	for _, r := range q.All(v) {
+		switch val := r.(type) {
+		case string:
+			if val != "" {
+				parts = append(parts, val)
+			}
+		case float64:
+			parts = append(parts, strconv.FormatFloat(val, 'f', -1, 64))
+		case int:
+			parts = append(parts, strconv.Itoa(val))
+		}
+	}
+	return strings.Join(parts, "\n")
+}
+
+func apply(v interface{}, steps []step) []interface{} {
+	current := []interface{}{v}
+	for _, s := range steps {
+		var next []interface{}
+		for _, c := range current {
+			switch {
+			case s.iterate:
+				if arr, ok := c.([]interface{}); ok {
+					next = append(next, arr...)
+				}
+			case s.isIndex:
+				arr, ok := c.([]interface{})
+				if !ok {
+					continue
+				}
+				i := s.index
+				if i < 0 {
+					i += len(arr)
+				}
+				if i >= 0 && i < len(arr) {
+					next = append(next, arr[i])
+				}
+			default:
+				if obj, ok := c.(map[string]interface{}); ok {
+					if val, ok := obj[s.field]; ok {
+						next = append(next, val)
+					}
+				}
+			}
+		}
+		current = next
+	}
+	return current
+}
@@ -0,0 +1,41 @@
+package agent
+
+// HookInputMatcher is an optional interface for agents whose hook payloads
+// have fields no other agent sends, so that they can claim them outright.
+// Checked via type assertion: if m, ok := ag.(HookInputMatcher); ok { ... }
+type HookInputMatcher interface {
+	// MatchesHookInput reports whether raw was sent by this agent's hooks.
+	MatchesHookInput(raw []byte) bool
+}
+
+// DetectHookAgent returns the candidate most likely to have sent a hook
+// payload, for repositories configured for several agents. An agent
+// claiming the payload as a HookInputMatcher wins; otherwise the payload
+// should parse with a session ID, and an agent recognising a git commit in
+// it, i.e. its own shell tool, beats one that does not. Ties go to the
+// earlier candidate. Returns nil when there are no candidates.
+func DetectHookAgent(raw []byte, candidates []Agent) Agent {
+	var best Agent
+	bestScore := -1
+	for _, ag := range candidates {
+		score := hookInputScore(ag, raw)
+		if score > bestScore {
+			best, bestScore = ag, score
+		}
+	}
+	return best
+}
+
+func hookInputScore(ag Agent, raw []byte) int {
+	if m, ok := ag.(HookInputMatcher); ok && m.MatchesHookInput(raw) {
+		return 4
+	}
+	hook, err := ag.ParseHookInput(raw)
+	if err != nil || hook.SessionID == "" {
+		return 0
+	}
+	if ag.IsCommitCommand(hook.ToolName, hook.Command) {
+		return 2
+	}
+	return 1
+}
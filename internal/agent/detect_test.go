@@ -0,0 +1,69 @@
+package agent
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+// fakeHookAgent parses standard hook payloads and recognises commits made
+// with its own shell tool.
+type fakeHookAgent struct {
+	Agent
+	name      Name
+	shellTool string
+	matchKey  string
+}
+
+func (a *fakeHookAgent) Name() Name { return a.name }
+
+func (a *fakeHookAgent) ParseHookInput(raw []byte) (*HookData, error) {
+	return ParseStandardHookInput(raw)
+}
+
+func (a *fakeHookAgent) IsCommitCommand(toolName, command string) bool {
+	return toolName == a.shellTool && IsGitCommitCommand(command)
+}
+
+type matchingHookAgent struct {
+	fakeHookAgent
+}
+
+func (a *matchingHookAgent) MatchesHookInput(raw []byte) bool {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		return false
+	}
+	_, ok := fields[a.matchKey]
+	return ok
+}
+
+func TestDetectHookAgent(t *testing.T) {
+	bash := &fakeHookAgent{name: "bash-agent", shellTool: "Bash"}
+	shell := &fakeHookAgent{name: "shell-agent", shellTool: "run_shell_command"}
+	native := &matchingHookAgent{fakeHookAgent{name: "native-agent", shellTool: "bash", matchKey: "data_dir"}}
+	candidates := []Agent{bash, shell, native}
+
+	tests := []struct {
+		name    string
+		payload string
+		want    Name
+	}{
+		{"own shell tool", `{"session_id":"s","tool_name":"run_shell_command","tool_input":{"command":"git commit -m x"}}`, "shell-agent"},
+		{"other shell tool", `{"session_id":"s","tool_name":"Bash","tool_input":{"command":"git commit -m x"}}`, "bash-agent"},
+		{"matcher claims payload", `{"session_id":"s","data_dir":"/d","tool_name":"bash","tool_input":{"command":"git commit"}}`, "native-agent"},
+		{"no commit, first candidate", `{"session_id":"s","tool_name":"Read"}`, "bash-agent"},
+		{"unparseable, first candidate", `not json`, "bash-agent"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := DetectHookAgent([]byte(tt.payload), candidates)
+			if got.Name() != tt.want {
+				t.Errorf("DetectHookAgent() = %s, want %s", got.Name(), tt.want)
+			}
+		})
+	}
+
+	if got := DetectHookAgent([]byte(`{}`), nil); got != nil {
+		t.Errorf("DetectHookAgent(no candidates) = %v, want nil", got)
+	}
+}
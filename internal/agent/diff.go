@@ -0,0 +1,169 @@
+package agent
+
+import (
+	"encoding/json"
+)
+
+// DiffOp says whether a transcript entry is in both transcripts of a diff,
+// or only in one of them.
+type DiffOp string
+
+const (
+	DiffContext DiffOp = "context" // in both transcripts
+	DiffAdded   DiffOp = "added"   // only in the newer transcript
+	DiffRemoved DiffOp = "removed" // only in the older transcript
+)
+
+// DiffLine is a transcript entry of a diff.
+type DiffLine struct {
+	Op    DiffOp          `json:"op"`
+	Entry TranscriptEntry `json:"entry"`
+	HTML  string          `json:"html,omitempty"` // the entry rendered by shiftlog serve
+}
+
+// DiffHunk is a run of changed entries with the unchanged entries around
+// them, like a hunk of a unified diff. Starts are 1-based entry positions
+// in each transcript; a count of 0 means the hunk has no entries there,
+// and the start is then the position it follows.
+type DiffHunk struct {
+	FromStart int        `json:"from_start"`
+	FromCount int        `json:"from_count"`
+	ToStart   int        `json:"to_start"`
+	ToCount   int        `json:"to_count"`
+	Lines     []DiffLine `json:"lines"`
+}
+
+// TranscriptDiff is the difference between two transcripts of a session.
+type TranscriptDiff struct {
+	Added   int        `json:"added"`
+	Removed int        `json:"removed"`
+	Hunks   []DiffHunk `json:"hunks"`
+}
+
+// DiffTranscripts compares two transcripts of a session, from being the
+// older. Entries are matched by UUID, so an agent that only appends to its
+// transcript yields the entries of to after the last entry of from, as
+// GetEntriesSince does; entries dropped or rewritten, e.g. by compaction,
+// show as removed. context is the number of unchanged entries kept around
+// each change; a negative context keeps them all in one hunk.
+func DiffTranscripts(from, to *Transcript, context int) *TranscriptDiff {
+	lines, fromPos, toPos := diffEntries(from.Entries, to.Entries)
+
+	diff := &TranscriptDiff{Hunks: []DiffHunk{}}
+	keep := make([]bool, len(lines))
+	for i, line := range lines {
+		switch line.Op {
+		case DiffAdded:
+			diff.Added++
+		case DiffRemoved:
+			diff.Removed++
+		default:
+			if context >= 0 {
+				continue
+			}
+		}
+		keep[i] = true
+		if context > 0 {
+			for k := max(0, i-context); k <= min(len(lines)-1, i+context); k++ {
+				keep[k] = true
+			}
+		}
+	}
+
+	var hunk *DiffHunk
+	for i, line := range lines {
+		if !keep[i] {
+			hunk = nil
+			continue
+		}
+		if hunk == nil {
+			diff.Hunks = append(diff.Hunks, DiffHunk{FromStart: fromPos[i], ToStart: toPos[i]})
+			hunk = &diff.Hunks[len(diff.Hunks)-1]
+		}
+		hunk.Lines = append(hunk.Lines, line)
+		if line.Op != DiffAdded {
+			hunk.FromCount++
+		}
+		if line.Op != DiffRemoved {
+			hunk.ToCount++
+		}
+	}
+	for i := range diff.Hunks {
+		// An empty side starts after the position it follows, as in diff -u
+		if diff.Hunks[i].FromCount == 0 {
+			diff.Hunks[i].FromStart--
+		}
+		if diff.Hunks[i].ToCount == 0 {
+			diff.Hunks[i].ToStart--
+		}
+	}
+	return diff
+}
+
+// diffEntries lines up the entries of two transcripts. For each line it
+// also returns the 1-based position in from and to of its entry, or of the
+// next entry on the side the line is missing from.
+func diffEntries(from, to []TranscriptEntry) (lines []DiffLine, fromPos, toPos []int) {
+	fromKeys := make([]string, len(from))
+	inFrom := make(map[string]bool, len(from))
+	for i := range from {
+		fromKeys[i] = entryKey(&from[i])
+		inFrom[fromKeys[i]] = true
+	}
+	toKeys := make([]string, len(to))
+	inTo := make(map[string]bool, len(to))
+	for j := range to {
+		toKeys[j] = entryKey(&to[j])
+		inTo[toKeys[j]] = true
+	}
+
+	emit := func(op DiffOp, entry TranscriptEntry, i, j int) {
+		lines = append(lines, DiffLine{Op: op, Entry: entry})
+		fromPos = append(fromPos, i+1)
+		toPos = append(toPos, j+1)
+	}
+
+	// Transcripts are mostly appended to, so a linear walk matching
+	// entries by key is enough; entries that moved are kept where the
+	// newer transcript has them.
+	emitted := make(map[string]bool, len(to))
+	i, j := 0, 0
+	for i < len(from) || j < len(to) {
+		switch {
+		case i < len(from) && emitted[fromKeys[i]]:
+			i++
+		case i < len(from) && j < len(to) && fromKeys[i] == toKeys[j]:
+			emit(DiffContext, to[j], i, j)
+			emitted[toKeys[j]] = true
+			i++
+			j++
+		case i < len(from) && !inTo[fromKeys[i]]:
+			emit(DiffRemoved, from[i], i, j)
+			i++
+		case j < len(to) && !inFrom[toKeys[j]]:
+			emit(DiffAdded, to[j], i, j)
+			emitted[toKeys[j]] = true
+			j++
+		case j < len(to):
+			emit(DiffContext, to[j], i, j)
+			emitted[toKeys[j]] = true
+			j++
+		default:
+			i++
+		}
+	}
+	return lines, fromPos, toPos
+}
+
+// entryKey identifies an entry across transcripts of its session: by UUID
+// when the agent records one, and otherwise by its content.
+func entryKey(entry *TranscriptEntry) string {
+	if entry.UUID != "" {
+		return "uuid:" + entry.UUID
+	}
+	if len(entry.Raw) > 0 {
+		return "raw:" + string(entry.Raw)
+	}
+	data, _ := json.Marshal(entry)
+	return "json:" + string(data)
+}
@@ -0,0 +1,95 @@
+package agent
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// diffTranscript builds a transcript of entries with the given UUIDs.
+func diffTranscript(uuids ...string) *Transcript {
+	t := &Transcript{}
+	for _, uuid := range uuids {
+		t.Entries = append(t.Entries, TranscriptEntry{UUID: uuid, Type: MessageTypeUser})
+	}
+	return t
+}
+
+// diffString renders a diff compactly, e.g. "@-1,2+1,3 =a =b +c".
+func diffString(d *TranscriptDiff) string {
+	var parts []string
+	for _, h := range d.Hunks {
+		parts = append(parts, "@-"+strconv.Itoa(h.FromStart)+","+strconv.Itoa(h.FromCount)+"+"+strconv.Itoa(h.ToStart)+","+strconv.Itoa(h.ToCount))
+		for _, l := range h.Lines {
+			prefix := map[DiffOp]string{DiffContext: "=", DiffAdded: "+", DiffRemoved: "-"}[l.Op]
+			parts = append(parts, prefix+l.Entry.UUID)
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+func TestDiffTranscripts(t *testing.T) {
+	tests := []struct {
+		name        string
+		from, to    []string
+		context     int
+		want        string
+		wantAdded   int
+		wantRemoved int
+	}{
+		{
+			name: "appended entries",
+			from: []string{"a", "b", "c"}, to: []string{"a", "b", "c", "d", "e"},
+			context: 1,
+			want:    "@-3,1+3,3 =c +d +e", wantAdded: 2,
+		},
+		{
+			name: "same transcript",
+			from: []string{"a", "b"}, to: []string{"a", "b"},
+			context: 3,
+			want:    "",
+		},
+		{
+			name: "compacted entries",
+			from: []string{"a", "b", "c", "d"}, to: []string{"a", "s", "d", "e"},
+			context: 0,
+			want:    "@-2,2+2,1 -b -c +s @-4,0+4,1 +e", wantAdded: 2, wantRemoved: 2,
+		},
+		{
+			name: "new session start",
+			from: []string{}, to: []string{"a", "b"},
+			context: 3,
+			want:    "@-0,0+1,2 +a +b", wantAdded: 2,
+		},
+		{
+			name: "all context",
+			from: []string{"a", "b"}, to: []string{"a", "b", "c"},
+			context: -1,
+			want:    "@-1,2+1,3 =a =b +c", wantAdded: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := DiffTranscripts(diffTranscript(tt.from...), diffTranscript(tt.to...), tt.context)
+			if got := diffString(d); got != tt.want {
+				t.Errorf("diff = %q, want %q", got, tt.want)
+			}
+			if d.Added != tt.wantAdded || d.Removed != tt.wantRemoved {
+				t.Errorf("added, removed = %d, %d, want %d, %d", d.Added, d.Removed, tt.wantAdded, tt.wantRemoved)
+			}
+		})
+	}
+}
+
+func TestDiffTranscriptsWithoutUUIDs(t *testing.T) {
+	text := func(s string) TranscriptEntry {
+		return TranscriptEntry{Type: MessageTypeUser, Message: &Message{Content: []ContentBlock{{Type: "text", Text: s}}, RawContent: []byte(`"` + s + `"`)}}
+	}
+	from := &Transcript{Entries: []TranscriptEntry{text("hello")}}
+	to := &Transcript{Entries: []TranscriptEntry{text("hello"), text("again")}}
+
+	d := DiffTranscripts(from, to, 0)
+	if d.Added != 1 || d.Removed != 0 || len(d.Hunks) != 1 || d.Hunks[0].ToStart != 2 {
+		t.Errorf("diff = %+v, want one added entry at 2", d)
+	}
+}
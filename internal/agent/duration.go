@@ -0,0 +1,72 @@
+package agent
+
+import (
+	"sort"
+	"time"
+
+	"github.com/re-cinq/shift-log/internal/util"
+)
+
+// Durations measures the wall-clock time spanned by entries: total runs from
+// the first to the last timestamped entry, and tools is the time spent
+// running tools, from each tool call to its result. Tool calls that overlap
+// are counted once. Entries without a parseable timestamp are ignored.
+func Durations(entries []TranscriptEntry) (total, tools time.Duration) {
+	var first, last time.Time
+	calls := make(map[string]time.Time)
+	var spans [][2]time.Time
+
+	for _, entry := range entries {
+		ts, err := util.ParseTimestamp(entry.Timestamp)
+		if entry.Timestamp == "" || err != nil {
+			continue
+		}
+		if first.IsZero() || ts.Before(first) {
+			first = ts
+		}
+		if ts.After(last) {
+			last = ts
+		}
+		if entry.Message == nil {
+			continue
+		}
+		for _, block := range entry.Message.Content {
+			switch block.Type {
+			case "tool_use":
+				id := block.ID
+				if id == "" {
+					id = block.ToolUseID
+				}
+				if id != "" {
+					calls[id] = ts
+				}
+			case "tool_result":
+				if start, ok := calls[block.ToolUseID]; ok && !ts.Before(start) {
+					spans = append(spans, [2]time.Time{start, ts})
+					delete(calls, block.ToolUseID)
+				}
+			}
+		}
+	}
+	if first.IsZero() {
+		return 0, 0
+	}
+	return last.Sub(first), unionLength(spans)
+}
+
+// unionLength returns the time covered by a set of intervals.
+func unionLength(spans [][2]time.Time) time.Duration {
+	sort.Slice(spans, func(i, j int) bool { return spans[i][0].Before(spans[j][0]) })
+	var total time.Duration
+	var end time.Time
+	for _, s := range spans {
+		if s[0].After(end) {
+			total += s[1].Sub(s[0])
+			end = s[1]
+		} else if s[1].After(end) {
+			total += s[1].Sub(end)
+			end = s[1]
+		}
+	}
+	return total
+}
@@ -0,0 +1,41 @@
+package agent
+
+import (
+	"testing"
+	"time"
+)
+
+func timedEntry(typ MessageType, ts string, blocks ...ContentBlock) TranscriptEntry {
+	return TranscriptEntry{Type: typ, Timestamp: ts, Message: &Message{Content: blocks}}
+}
+
+func TestDurations(t *testing.T) {
+	entries := []TranscriptEntry{
+		timedEntry(MessageTypeUser, "2025-01-01T10:00:00Z", ContentBlock{Type: "text", Text: "go"}),
+		// Two tool calls running in parallel from 10:00:10
+		timedEntry(MessageTypeAssistant, "2025-01-01T10:00:10Z",
+			ContentBlock{Type: "tool_use", ID: "t1"}, ContentBlock{Type: "tool_use", ID: "t2"}),
+		timedEntry(MessageTypeUser, "2025-01-01T10:00:40Z", ContentBlock{Type: "tool_result", ToolUseID: "t1"}),
+		timedEntry(MessageTypeUser, "2025-01-01T10:01:10Z", ContentBlock{Type: "tool_result", ToolUseID: "t2"}),
+		// Copilot puts the call ID in ToolUseID
+		timedEntry(MessageTypeAssistant, "2025-01-01T10:02:00.500Z", ContentBlock{Type: "tool_use", ToolUseID: "t3"}),
+		timedEntry(MessageTypeUser, "2025-01-01T10:02:05.500Z", ContentBlock{Type: "tool_result", ToolUseID: "t3"}),
+		{Type: MessageTypeAssistant}, // no timestamp
+		timedEntry(MessageTypeAssistant, "2025-01-01T10:05:00Z", ContentBlock{Type: "text", Text: "done"}),
+	}
+
+	total, tools := Durations(entries)
+	if total != 5*time.Minute {
+		t.Errorf("total = %v, want 5m", total)
+	}
+	if tools != 65*time.Second {
+		t.Errorf("tools = %v, want 1m5s", tools)
+	}
+}
+
+func TestDurationsWithoutTimestamps(t *testing.T) {
+	total, tools := Durations([]TranscriptEntry{{Type: MessageTypeUser}, {Type: MessageTypeAssistant, Timestamp: "garbage"}})
+	if total != 0 || tools != 0 {
+		t.Errorf("Durations() = %v, %v, want 0, 0", total, tools)
+	}
+}
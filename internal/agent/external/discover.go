@@ -0,0 +1,85 @@
+package external
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+
+	"github.com/re-cinq/shift-log/internal/agent"
+)
+
+var namePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+// Find returns the agent plugin executables on PATH, keyed by agent name.
+// Like the shell, the first match on PATH wins.
+func Find() map[string]string {
+	plugins := make(map[string]string)
+	for _, dir := range filepath.SplitList(os.Getenv("PATH")) {
+		if dir == "" {
+			continue
+		}
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, e := range entries {
+			name, ok := pluginName(e.Name())
+			if !ok {
+				continue
+			}
+			if _, seen := plugins[name]; seen {
+				continue
+			}
+			path := filepath.Join(dir, e.Name())
+			if isExecutable(path) {
+				plugins[name] = path
+			}
+		}
+	}
+	return plugins
+}
+
+// pluginName returns the agent name of a plugin executable's file name.
+func pluginName(file string) (string, bool) {
+	if !strings.HasPrefix(file, Prefix) {
+		return "", false
+	}
+	name := strings.TrimPrefix(file, Prefix)
+	if runtime.GOOS == "windows" {
+		name = strings.TrimSuffix(name, filepath.Ext(name))
+	}
+	return name, namePattern.MatchString(name)
+}
+
+func isExecutable(path string) bool {
+	info, err := os.Stat(path)
+	if err != nil || info.IsDir() {
+		return false
+	}
+	if runtime.GOOS == "windows" {
+		switch strings.ToLower(filepath.Ext(path)) {
+		case ".exe", ".bat", ".cmd":
+			return true
+		}
+		return false
+	}
+	return info.Mode()&0111 != 0
+}
+
+// Load registers an agent for every plugin executable on PATH. Plugins
+// named after an agent that is already registered, built in or described
+// by a manifest, are skipped and reported in the returned errors.
+func Load() []error {
+	var errs []error
+	for name, path := range Find() {
+		if _, err := agent.Get(agent.Name(name)); err == nil {
+			errs = append(errs, fmt.Errorf("%s: agent %q is already defined", path, name))
+			continue
+		}
+		agent.Register(New(name, path))
+	}
+	return errs
+}
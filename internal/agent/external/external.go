@@ -0,0 +1,333 @@
+// Package external implements coding agents provided by plugin executables:
+// any executable named shiftlog-agent-<name> on PATH is registered as agent
+// <name>. shiftlog runs it with an operation as its only argument, writes
+// the operation's JSON request to its stdin and reads a JSON response from
+// its stdout. A non-zero exit status fails the operation, with stderr as the
+// error message.
+//
+// Operations:
+//
+//	info              {}                                  -> {"display_name", "shell_tools", "tool_aliases", "operations"}
+//	parse-hook        <raw hook input>                    -> {"session_id", "transcript_path", "tool_name", "command"}
+//	parse-transcript  <raw transcript>                    -> {"entries", "model", "usage"}
+//	discover-session  {"project_path"}                    -> {"session_id", "transcript_path", "started_at"} or null
+//	resume-command    {"session_id"}                      -> {"command", "args"}
+//	restore-session   {"project_path", "session_id", ...} -> {}
+//	configure-hooks   {"repo_root"}                       -> {}
+//	remove-hooks      {"repo_root"}                       -> {}
+//	diagnose-hooks    {"repo_root"}                       -> [{"name", "ok", "message"}]
+//
+// The first five are required. A plugin lists the optional ones it supports
+// in the "operations" of its info response; info itself may fail, in which
+// case the plugin gets defaults.
+package external
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/re-cinq/shift-log/internal/agent"
+)
+
+// Prefix is the file name prefix of agent plugin executables.
+const Prefix = "shiftlog-agent-"
+
+// Timeout bounds a single plugin operation.
+const Timeout = 30 * time.Second
+
+// Operations of the plugin protocol.
+const (
+	OpInfo            = "info"
+	OpParseHook       = "parse-hook"
+	OpParseTranscript = "parse-transcript"
+	OpDiscoverSession = "discover-session"
+	OpResumeCommand   = "resume-command"
+	OpRestoreSession  = "restore-session"
+	OpConfigureHooks  = "configure-hooks"
+	OpRemoveHooks     = "remove-hooks"
+	OpDiagnoseHooks   = "diagnose-hooks"
+)
+
+// Agent implements the agent.Agent interface by running a plugin executable.
+type Agent struct {
+	name agent.Name
+	path string
+
+	infoOnce sync.Once
+	info     Info
+}
+
+// Info is the response to the info operation.
+type Info struct {
+	DisplayName string            `json:"display_name"`
+	ShellTools  []string          `json:"shell_tools"`
+	ToolAliases map[string]string `json:"tool_aliases"`
+	Operations  []string          `json:"operations"`
+}
+
+// HookData is the response to parse-hook.
+type HookData struct {
+	SessionID      string `json:"session_id"`
+	TranscriptPath string `json:"transcript_path"`
+	ToolName       string `json:"tool_name"`
+	Command        string `json:"command"`
+}
+
+// Transcript is the response to parse-transcript. Entries use the
+// transcript entry format of Claude Code.
+type Transcript struct {
+	Entries []agent.TranscriptEntry `json:"entries"`
+	Model   string                  `json:"model"`
+	Usage   agent.UsageMetrics      `json:"usage"`
+}
+
+// SessionInfo is the response to discover-session.
+type SessionInfo struct {
+	SessionID      string `json:"session_id"`
+	TranscriptPath string `json:"transcript_path"`
+	StartedAt      string `json:"started_at"`
+}
+
+// Command is the response to resume-command.
+type Command struct {
+	Command string   `json:"command"`
+	Args    []string `json:"args"`
+}
+
+// RestoreRequest is the request of restore-session. Transcript is the stored
+// transcript, base64-encoded in JSON.
+type RestoreRequest struct {
+	ProjectPath  string `json:"project_path"`
+	SessionID    string `json:"session_id"`
+	GitBranch    string `json:"git_branch"`
+	Transcript   []byte `json:"transcript"`
+	MessageCount int    `json:"message_count"`
+	Summary      string `json:"summary"`
+}
+
+// DiagnosticCheck is an element of the diagnose-hooks response.
+type DiagnosticCheck struct {
+	Name    string `json:"name"`
+	OK      bool   `json:"ok"`
+	Message string `json:"message"`
+}
+
+// New returns the agent of a plugin executable.
+func New(name, path string) *Agent {
+	return &Agent{name: agent.Name(name), path: path}
+}
+
+// run runs an operation, writing stdin to the plugin and decoding its
+// output into out.
+func (a *Agent) run(op string, stdin []byte, out interface{}) error {
+	ctx, cancel := context.WithTimeout(context.Background(), Timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, a.path, op)
+	cmd.Stdin = bytes.NewReader(stdin)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return fmt.Errorf("%s %s: %s", Prefix+string(a.name), op, msg)
+		}
+		return fmt.Errorf("%s %s: %w", Prefix+string(a.name), op, err)
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.Unmarshal(stdout.Bytes(), out); err != nil {
+		return fmt.Errorf("%s %s: invalid response: %w", Prefix+string(a.name), op, err)
+	}
+	return nil
+}
+
+// runJSON runs an operation with a JSON-encoded request.
+func (a *Agent) runJSON(op string, req, out interface{}) error {
+	data, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+	return a.run(op, data, out)
+}
+
+// Info returns the plugin's info, asking it once.
+func (a *Agent) Info() Info {
+	a.infoOnce.Do(func() {
+		if err := a.runJSON(OpInfo, struct{}{}, &a.info); err != nil {
+			a.info = Info{}
+		}
+	})
+	return a.info
+}
+
+// supports reports whether the plugin implements an optional operation.
+func (a *Agent) supports(op string) bool {
+	for _, o := range a.Info().Operations {
+		if o == op {
+			return true
+		}
+	}
+	return false
+}
+
+func (a *Agent) Name() agent.Name { return a.name }
+
+func (a *Agent) DisplayName() string {
+	if name := a.Info().DisplayName; name != "" {
+		return name
+	}
+	return string(a.name)
+}
+
+// ConfigureHooks runs configure-hooks if the plugin supports it.
+func (a *Agent) ConfigureHooks(repoRoot string) error {
+	if !a.supports(OpConfigureHooks) {
+		return nil
+	}
+	return a.runJSON(OpConfigureHooks, map[string]string{"repo_root": repoRoot}, nil)
+}
+
+// RemoveHooks runs remove-hooks if the plugin supports it.
+func (a *Agent) RemoveHooks(repoRoot string) error {
+	if !a.supports(OpRemoveHooks) {
+		return nil
+	}
+	return a.runJSON(OpRemoveHooks, map[string]string{"repo_root": repoRoot}, nil)
+}
+
+// DiagnoseHooks runs diagnose-hooks if the plugin supports it, and
+// otherwise reports the plugin executable.
+func (a *Agent) DiagnoseHooks(repoRoot string) []agent.DiagnosticCheck {
+	if !a.supports(OpDiagnoseHooks) {
+		return []agent.DiagnosticCheck{{
+			Name:    "Agent plugin",
+			OK:      true,
+			Message: fmt.Sprintf("Using %s", a.path),
+		}}
+	}
+	var checks []DiagnosticCheck
+	if err := a.runJSON(OpDiagnoseHooks, map[string]string{"repo_root": repoRoot}, &checks); err != nil {
+		return []agent.DiagnosticCheck{{Name: "Agent plugin", OK: false, Message: err.Error()}}
+	}
+	out := make([]agent.DiagnosticCheck, len(checks))
+	for i, c := range checks {
+		out[i] = agent.DiagnosticCheck{Name: c.Name, OK: c.OK, Message: c.Message}
+	}
+	return out
+}
+
+// ParseHookInput passes the raw hook input to parse-hook.
+func (a *Agent) ParseHookInput(raw []byte) (*agent.HookData, error) {
+	var hook HookData
+	if err := a.run(OpParseHook, raw, &hook); err != nil {
+		return nil, err
+	}
+	return &agent.HookData{
+		SessionID:      hook.SessionID,
+		TranscriptPath: hook.TranscriptPath,
+		ToolName:       hook.ToolName,
+		Command:        hook.Command,
+	}, nil
+}
+
+// IsCommitCommand checks if a tool invocation represents a git commit. Any
+// tool counts when the plugin lists no shell tools.
+func (a *Agent) IsCommitCommand(toolName, command string) bool {
+	if tools := a.Info().ShellTools; len(tools) > 0 {
+		shell := false
+		for _, t := range tools {
+			if t == toolName {
+				shell = true
+				break
+			}
+		}
+		if !shell {
+			return false
+		}
+	}
+	return agent.IsGitCommitCommand(command)
+}
+
+// ParseTranscript passes the raw transcript to parse-transcript.
+func (a *Agent) ParseTranscript(r io.Reader) (*agent.Transcript, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	var resp Transcript
+	if err := a.run(OpParseTranscript, data, &resp); err != nil {
+		return nil, err
+	}
+	t := &agent.Transcript{Entries: resp.Entries, Model: resp.Model, Usage: resp.Usage}
+	t.Turns = t.CountTurns()
+	return t, nil
+}
+
+// ParseTranscriptFile parses a transcript file.
+func (a *Agent) ParseTranscriptFile(path string) (*agent.Transcript, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+	return a.ParseTranscript(f)
+}
+
+// DiscoverSession asks discover-session for the project's active session.
+func (a *Agent) DiscoverSession(projectPath string) (*agent.SessionInfo, error) {
+	var info *SessionInfo
+	if err := a.runJSON(OpDiscoverSession, map[string]string{"project_path": projectPath}, &info); err != nil {
+		return nil, err
+	}
+	if info == nil || info.SessionID == "" {
+		return nil, nil
+	}
+	return &agent.SessionInfo{
+		SessionID:      info.SessionID,
+		TranscriptPath: info.TranscriptPath,
+		StartedAt:      info.StartedAt,
+		ProjectPath:    projectPath,
+	}, nil
+}
+
+// RestoreSession runs restore-session.
+func (a *Agent) RestoreSession(projectPath, sessionID, gitBranch string,
+	transcriptData []byte, messageCount int, summary string) error {
+
+	if !a.supports(OpRestoreSession) {
+		return fmt.Errorf("%s cannot restore sessions", a.DisplayName())
+	}
+	return a.runJSON(OpRestoreSession, RestoreRequest{
+		ProjectPath:  projectPath,
+		SessionID:    sessionID,
+		GitBranch:    gitBranch,
+		Transcript:   transcriptData,
+		MessageCount: messageCount,
+		Summary:      summary,
+	}, nil)
+}
+
+// ResumeCommand asks resume-command how to resume a session. It returns no
+// command if the plugin fails.
+func (a *Agent) ResumeCommand(sessionID string) (string, []string) {
+	var cmd Command
+	if err := a.runJSON(OpResumeCommand, map[string]string{"session_id": sessionID}, &cmd); err != nil {
+		return "", nil
+	}
+	return cmd.Command, cmd.Args
+}
+
+// ToolAliases returns the plugin's tool name mappings.
+func (a *Agent) ToolAliases() map[string]string {
+	return a.Info().ToolAliases
+}
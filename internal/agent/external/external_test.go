@@ -0,0 +1,199 @@
+package external
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/re-cinq/shift-log/internal/agent"
+)
+
+// testPlugin is a plugin that answers every operation with canned JSON and
+// records restore requests in $PLUGIN_LOG.
+const testPlugin = `#!/bin/sh
+case "$1" in
+info)
+  echo '{"display_name":"Test Plugin","shell_tools":["bash"],"tool_aliases":{"bash":"Bash"},"operations":["restore-session"]}' ;;
+parse-hook)
+  cat >/dev/null
+  echo '{"session_id":"s1","transcript_path":"/tmp/s1.jsonl","tool_name":"bash","command":"git commit -m x"}' ;;
+parse-transcript)
+  cat >/dev/null
+  echo '{"entries":[{"uuid":"u1","type":"user","message":{"role":"user","content":"hello"}},{"uuid":"a1","type":"assistant","message":{"role":"assistant","content":[{"type":"tool_use","id":"t1","name":"bash","input":{"command":"ls"}}]}}],"model":"test-model","usage":{"input_tokens":10,"output_tokens":5}}' ;;
+discover-session)
+  if grep -q '"/none"' ; then echo null; else echo '{"session_id":"s1","transcript_path":"/tmp/s1.jsonl","started_at":"2025-01-01T00:00:00Z"}'; fi ;;
+resume-command)
+  cat >/dev/null
+  echo '{"command":"test-agent","args":["--resume","s1"]}' ;;
+restore-session)
+  cat >"$PLUGIN_LOG"
+  echo '{}' ;;
+*)
+  echo "unknown operation $1" >&2
+  exit 1 ;;
+esac
+`
+
+func writePlugin(t *testing.T, dir, name, script string) string {
+	t.Helper()
+	path := filepath.Join(dir, Prefix+name)
+	if err := os.WriteFile(path, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func newTestAgent(t *testing.T) *Agent {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("test plugin is a shell script")
+	}
+	return New("test", writePlugin(t, t.TempDir(), "test", testPlugin))
+}
+
+func TestInfo(t *testing.T) {
+	a := newTestAgent(t)
+	if a.Name() != "test" {
+		t.Errorf("Name() = %q, want test", a.Name())
+	}
+	if a.DisplayName() != "Test Plugin" {
+		t.Errorf("DisplayName() = %q, want Test Plugin", a.DisplayName())
+	}
+	if a.ToolAliases()["bash"] != "Bash" {
+		t.Errorf("ToolAliases() = %v", a.ToolAliases())
+	}
+	if !a.IsCommitCommand("bash", "git commit -m x") || a.IsCommitCommand("edit", "git commit -m x") {
+		t.Error("IsCommitCommand() does not honour shell_tools")
+	}
+}
+
+func TestParseHookInput(t *testing.T) {
+	a := newTestAgent(t)
+	hook, err := a.ParseHookInput([]byte(`{}`))
+	if err != nil {
+		t.Fatalf("ParseHookInput() error: %v", err)
+	}
+	if hook.SessionID != "s1" || hook.ToolName != "bash" || hook.Command != "git commit -m x" {
+		t.Errorf("ParseHookInput() = %+v", hook)
+	}
+}
+
+func TestParseTranscript(t *testing.T) {
+	a := newTestAgent(t)
+	transcript, err := a.ParseTranscript(strings.NewReader("raw"))
+	if err != nil {
+		t.Fatalf("ParseTranscript() error: %v", err)
+	}
+	if len(transcript.Entries) != 2 || transcript.Turns != 1 {
+		t.Fatalf("got %d entries and %d turns, want 2 and 1", len(transcript.Entries), transcript.Turns)
+	}
+	if transcript.Model != "test-model" || transcript.Usage.TotalTokens() != 15 {
+		t.Errorf("Model = %q, Usage = %+v", transcript.Model, transcript.Usage)
+	}
+	if got := transcript.Entries[0].Message.Content[0].Text; got != "hello" {
+		t.Errorf("user text = %q", got)
+	}
+	if block := transcript.Entries[1].Message.Content[0]; block.Type != "tool_use" || block.Name != "bash" {
+		t.Errorf("assistant block = %+v", block)
+	}
+}
+
+func TestDiscoverSession(t *testing.T) {
+	a := newTestAgent(t)
+	info, err := a.DiscoverSession("/project")
+	if err != nil || info == nil {
+		t.Fatalf("DiscoverSession() = %v, %v", info, err)
+	}
+	if info.SessionID != "s1" || info.ProjectPath != "/project" {
+		t.Errorf("DiscoverSession() = %+v", info)
+	}
+
+	info, err = a.DiscoverSession("/none")
+	if err != nil || info != nil {
+		t.Errorf("DiscoverSession(/none) = %v, %v, want nil", info, err)
+	}
+}
+
+func TestRestoreAndResume(t *testing.T) {
+	a := newTestAgent(t)
+	log := filepath.Join(t.TempDir(), "restore.json")
+	t.Setenv("PLUGIN_LOG", log)
+
+	if err := a.RestoreSession("/project", "s1", "main", []byte("transcript"), 2, "summary"); err != nil {
+		t.Fatalf("RestoreSession() error: %v", err)
+	}
+	data, err := os.ReadFile(log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The transcript is base64-encoded
+	if !strings.Contains(string(data), `"transcript":"dHJhbnNjcmlwdA=="`) || !strings.Contains(string(data), `"session_id":"s1"`) {
+		t.Errorf("restore request = %s", data)
+	}
+
+	bin, args := a.ResumeCommand("s1")
+	if bin != "test-agent" || strings.Join(args, " ") != "--resume s1" {
+		t.Errorf("ResumeCommand() = %s %v", bin, args)
+	}
+
+	// Optional operations the plugin does not list are skipped
+	if err := a.ConfigureHooks("/project"); err != nil {
+		t.Errorf("ConfigureHooks() error: %v", err)
+	}
+	if checks := a.DiagnoseHooks("/project"); len(checks) != 1 || !checks[0].OK {
+		t.Errorf("DiagnoseHooks() = %+v", checks)
+	}
+}
+
+func TestFailingPlugin(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("test plugin is a shell script")
+	}
+	a := New("broken", writePlugin(t, t.TempDir(), "broken", "#!/bin/sh\necho 'no such session' >&2\nexit 1\n"))
+
+	if a.DisplayName() != "broken" {
+		t.Errorf("DisplayName() = %q, want the name when info fails", a.DisplayName())
+	}
+	if _, err := a.ParseTranscript(strings.NewReader("")); err == nil || !strings.Contains(err.Error(), "no such session") {
+		t.Errorf("ParseTranscript() error = %v, want the plugin's stderr", err)
+	}
+	if err := a.RestoreSession("/p", "s", "main", nil, 0, ""); err == nil {
+		t.Error("RestoreSession() succeeded for a plugin without restore-session")
+	}
+	if bin, _ := a.ResumeCommand("s"); bin != "" {
+		t.Errorf("ResumeCommand() = %q, want none", bin)
+	}
+}
+
+func TestFindAndLoad(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("test plugin is a shell script")
+	}
+	first, second := t.TempDir(), t.TempDir()
+	want := writePlugin(t, first, "findtest", testPlugin)
+	writePlugin(t, second, "findtest", testPlugin)
+	writePlugin(t, first, "Bad_Name", testPlugin)
+	if err := os.WriteFile(filepath.Join(first, Prefix+"noexec"), []byte(testPlugin), 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", first+string(os.PathListSeparator)+second)
+
+	plugins := Find()
+	if len(plugins) != 1 || plugins["findtest"] != want {
+		t.Fatalf("Find() = %v, want findtest at %s", plugins, want)
+	}
+
+	if errs := Load(); len(errs) != 0 {
+		t.Fatalf("Load() errors = %v", errs)
+	}
+	if _, err := agent.Get("findtest"); err != nil {
+		t.Errorf("plugin not registered: %v", err)
+	}
+
+	// A second load finds the name taken
+	if errs := Load(); len(errs) != 1 {
+		t.Errorf("second Load() errors = %v, want one", errs)
+	}
+}
@@ -0,0 +1,164 @@
+package agent
+
+import (
+	"encoding/json"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// editTools are the canonical tool names that modify files.
+var editTools = map[string]bool{
+	"Write":        true,
+	"Edit":         true,
+	"MultiEdit":    true,
+	"NotebookEdit": true,
+}
+
+// filePathKeys are the input keys agents use to name the file a tool operates on.
+var filePathKeys = []string{"file_path", "filePath", "notebook_path", "path", "absolute_path"}
+
+// newContentKeys are the input keys holding text an edit tool writes.
+var newContentKeys = []string{"content", "new_string", "newString", "new_str", "file_text"}
+
+// patchFileHeader matches the file headers of apply_patch style edits.
+var patchFileHeader = regexp.MustCompile(`^\*\*\* (?:Add|Update|Delete) File: (.+)$`)
+
+// FileEdit is a single file modification made by a tool call.
+type FileEdit struct {
+	Path  string   // path exactly as the agent recorded it
+	Lines []string // lines of text the edit wrote into the file
+}
+
+// FileEdits returns the file modifications made by a tool_use block.
+// Agent-specific tool names are mapped through aliases before matching.
+func FileEdits(block ContentBlock, aliases map[string]string) []FileEdit {
+	if block.Type != "tool_use" || len(block.Input) == 0 {
+		return nil
+	}
+
+	name := block.Name
+	if name == "" {
+		name = block.Text
+	}
+	if alias, ok := aliases[name]; ok {
+		name = alias
+	}
+
+	var input interface{}
+	if err := json.Unmarshal(block.Input, &input); err != nil {
+		return nil
+	}
+
+	var edits []FileEdit
+	if editTools[name] {
+		if obj, ok := input.(map[string]interface{}); ok {
+			for _, key := range filePathKeys {
+				if p, ok := obj[key].(string); ok && p != "" {
+					edits = append(edits, FileEdit{Path: p, Lines: newContentLines(obj)})
+					break
+				}
+			}
+		}
+	}
+
+	// Patch-based edits (e.g. Codex apply_patch) embed file names in the patch body.
+	for _, s := range inputStrings(input) {
+		edits = append(edits, patchEdits(s)...)
+	}
+
+	return edits
+}
+
+// newContentLines collects the lines written by an edit tool's input,
+// including each edit of a MultiEdit.
+func newContentLines(obj map[string]interface{}) []string {
+	var lines []string
+	for _, key := range newContentKeys {
+		if text, ok := obj[key].(string); ok && text != "" {
+			lines = append(lines, strings.Split(text, "\n")...)
+		}
+	}
+	if edits, ok := obj["edits"].([]interface{}); ok {
+		for _, e := range edits {
+			if m, ok := e.(map[string]interface{}); ok {
+				lines = append(lines, newContentLines(m)...)
+			}
+		}
+	}
+	return lines
+}
+
+// patchEdits parses apply_patch style text into per-file edits, taking the
+// added ("+") lines of each file section.
+func patchEdits(patch string) []FileEdit {
+	var edits []FileEdit
+	for _, line := range strings.Split(patch, "\n") {
+		if m := patchFileHeader.FindStringSubmatch(line); m != nil {
+			edits = append(edits, FileEdit{Path: strings.TrimSpace(m[1])})
+			continue
+		}
+		if len(edits) > 0 && strings.HasPrefix(line, "+") {
+			last := &edits[len(edits)-1]
+			last.Lines = append(last.Lines, line[1:])
+		}
+	}
+	return edits
+}
+
+// EditedFilePaths returns the paths of files modified by a tool_use block,
+// exactly as the agent recorded them.
+func EditedFilePaths(block ContentBlock, aliases map[string]string) []string {
+	var paths []string
+	for _, edit := range FileEdits(block, aliases) {
+		paths = append(paths, edit.Path)
+	}
+	return paths
+}
+
+// inputStrings collects every string value in a decoded JSON tool input.
+func inputStrings(v interface{}) []string {
+	switch val := v.(type) {
+	case string:
+		return []string{val}
+	case []interface{}:
+		var out []string
+		for _, item := range val {
+			out = append(out, inputStrings(item)...)
+		}
+		return out
+	case map[string]interface{}:
+		keys := make([]string, 0, len(val))
+		for k := range val {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		var out []string
+		for _, k := range keys {
+			out = append(out, inputStrings(val[k])...)
+		}
+		return out
+	}
+	return nil
+}
+
+// EditedFiles returns the paths of all files modified by tool calls in the
+// given entries, de-duplicated in first-seen order.
+func EditedFiles(entries []TranscriptEntry, aliases map[string]string) []string {
+	seen := make(map[string]bool)
+	var files []string
+	for _, entry := range entries {
+		if entry.Message == nil {
+			continue
+		}
+		for _, block := range entry.Message.Content {
+			for _, p := range EditedFilePaths(block, aliases) {
+				if !seen[p] {
+					seen[p] = true
+					files = append(files, p)
+				}
+			}
+		}
+	}
+	return files
+}
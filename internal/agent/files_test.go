@@ -0,0 +1,76 @@
+package agent
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestEditedFilePaths(t *testing.T) {
+	tests := []struct {
+		name    string
+		block   ContentBlock
+		aliases map[string]string
+		want    []string
+	}{
+		{
+			name:  "claude edit",
+			block: ContentBlock{Type: "tool_use", Name: "Edit", Input: json.RawMessage(`{"file_path":"/repo/main.go","old_string":"a","new_string":"b"}`)},
+			want:  []string{"/repo/main.go"},
+		},
+		{
+			name:  "claude notebook edit",
+			block: ContentBlock{Type: "tool_use", Name: "NotebookEdit", Input: json.RawMessage(`{"notebook_path":"nb.ipynb"}`)},
+			want:  []string{"nb.ipynb"},
+		},
+		{
+			name:    "aliased tool name",
+			block:   ContentBlock{Type: "tool_use", Name: "write", Input: json.RawMessage(`{"filePath":"src/app.ts","content":"x"}`)},
+			aliases: map[string]string{"write": "Write"},
+			want:    []string{"src/app.ts"},
+		},
+		{
+			name:  "read tool is not an edit",
+			block: ContentBlock{Type: "tool_use", Name: "Read", Input: json.RawMessage(`{"file_path":"main.go"}`)},
+			want:  nil,
+		},
+		{
+			name:  "apply_patch headers",
+			block: ContentBlock{Type: "tool_use", Text: "shell", Input: json.RawMessage(`{"command":["apply_patch","*** Begin Patch\n*** Update File: a.go\n@@\n*** Add File: b/c.go\n+x\n*** End Patch"]}`)},
+			want:  []string{"a.go", "b/c.go"},
+		},
+		{
+			name:  "not a tool_use block",
+			block: ContentBlock{Type: "text", Text: "*** Update File: a.go"},
+			want:  nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := EditedFilePaths(tt.block, tt.aliases)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("EditedFilePaths() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEditedFilesDeduplicates(t *testing.T) {
+	entries := []TranscriptEntry{
+		{Type: MessageTypeAssistant, Message: &Message{Content: []ContentBlock{
+			{Type: "tool_use", Name: "Write", Input: json.RawMessage(`{"file_path":"a.go"}`)},
+			{Type: "tool_use", Name: "Edit", Input: json.RawMessage(`{"file_path":"b.go"}`)},
+		}}},
+		{Type: MessageTypeUser, Message: nil},
+		{Type: MessageTypeAssistant, Message: &Message{Content: []ContentBlock{
+			{Type: "tool_use", Name: "Edit", Input: json.RawMessage(`{"file_path":"a.go"}`)},
+		}}},
+	}
+
+	got := EditedFiles(entries, nil)
+	want := []string{"a.go", "b.go"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("EditedFiles() = %v, want %v", got, want)
+	}
+}
@@ -155,6 +155,22 @@ func (a *Agent) DiscoverSession(projectPath string) (*agent.SessionInfo, error)
 	return scanForRecentSession(projectPath)
 }
 
+// ListSessions returns the Gemini CLI sessions in the project's session
+// directory and its legacy hash directory, newest first.
+func (a *Agent) ListSessions(projectPath string) ([]agent.SessionInfo, error) {
+	sessionDir, err := GetSessionDir(projectPath)
+	if err != nil {
+		return nil, err
+	}
+	skipFiles := []string{"sessions-index.json"}
+	sessions := agent.ListSessionFiles(sessionDir, ".json", skipFiles, projectPath)
+	if legacyDir, err := GetLegacySessionDir(projectPath); err == nil && legacyDir != sessionDir {
+		sessions = append(sessions, agent.ListSessionFiles(legacyDir, ".json", skipFiles, projectPath)...)
+		agent.SortSessions(sessions)
+	}
+	return sessions, nil
+}
+
 // RestoreSession writes a transcript to Gemini CLI's expected location.
 func (a *Agent) RestoreSession(projectPath, sessionID, gitBranch string,
 	transcriptData []byte, messageCount int, summary string) error {
@@ -193,11 +209,17 @@ func (a *Agent) ResumeCommand(sessionID string) (string, []string) {
 	return "gemini", []string{"--resume", sessionID}
 }
 
+// VersionCommand returns the command that prints the Gemini CLI version.
+func (a *Agent) VersionCommand() (string, []string) {
+	return "gemini", []string{"--version"}
+}
+
 // ToolAliases returns Gemini CLI's tool name mappings to canonical names.
 func (a *Agent) ToolAliases() map[string]string {
 	return map[string]string{
 		"run_shell_command": "Bash",
 		"replace":          "Edit",
+		"write_file":       "Write",
 		"grep_search":      "Grep",
 		"glob":             "Glob",
 		"list_directory":   "Glob",
@@ -0,0 +1,344 @@
+package goose
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/re-cinq/shift-log/internal/agent"
+)
+
+func init() {
+	agent.Register(&Agent{})
+}
+
+// Agent implements the agent.Agent interface for Goose, Block's open source
+// coding agent.
+type Agent struct{}
+
+func (a *Agent) Name() agent.Name    { return agent.Goose }
+func (a *Agent) DisplayName() string { return "Goose" }
+
+// ConfigureHooks is a no-op for Goose — it has no per-tool hook mechanism.
+// Conversation capture relies on the post-commit git hook.
+func (a *Agent) ConfigureHooks(repoRoot string) error {
+	return nil
+}
+
+// RemoveHooks is a no-op for Goose — it has no per-tool hook mechanism.
+func (a *Agent) RemoveHooks(repoRoot string) error {
+	return nil
+}
+
+// DiagnoseHooks checks that the goose binary is available.
+func (a *Agent) DiagnoseHooks(repoRoot string) []agent.DiagnosticCheck {
+	if _, err := LookupBinary(); err != nil {
+		return []agent.DiagnosticCheck{{
+			Name:    "Goose binary",
+			OK:      false,
+			Message: "goose not found in PATH. Install from https://block.github.io/goose",
+		}}
+	}
+	return []agent.DiagnosticCheck{{
+		Name:    "Goose binary",
+		OK:      true,
+		Message: "Found goose in PATH",
+	}}
+}
+
+// ParseHookInput parses hook JSON in the standard format. Goose has no
+// per-tool hooks, so this only serves manual store input.
+func (a *Agent) ParseHookInput(raw []byte) (*agent.HookData, error) {
+	return agent.ParseStandardHookInput(raw)
+}
+
+// shellTools are the Goose tool names that run shell commands.
+var shellTools = map[string]bool{
+	"developer__shell": true,
+	"shell":            true,
+}
+
+// IsCommitCommand checks if a tool invocation represents a git commit.
+func (a *Agent) IsCommitCommand(toolName, command string) bool {
+	if !shellTools[toolName] {
+		return false
+	}
+	return agent.IsGitCommitCommand(command)
+}
+
+// gooseMessage is a message line of a Goose session file.
+type gooseMessage struct {
+	ID       string         `json:"id"`
+	Role     string         `json:"role"`
+	Created  int64          `json:"created"` // Unix seconds
+	Content  []gooseContent `json:"content"`
+	Metadata *struct {
+		UserVisible *bool `json:"userVisible"`
+	} `json:"metadata"`
+}
+
+// gooseContent is a content item of a Goose message.
+type gooseContent struct {
+	Type       string      `json:"type"`
+	Text       string      `json:"text"`
+	Thinking   string      `json:"thinking"`
+	Omitted    string      `json:"omitted"` // thinking left out by shiftlog's thinking policy
+	ID         string      `json:"id"`
+	ToolCall   *toolResult `json:"toolCall"`
+	ToolResult *toolResult `json:"toolResult"`
+}
+
+// toolResult is Goose's serialized Result: {"status":"success","value":...}
+// or {"status":"error","error":"..."}.
+type toolResult struct {
+	Status string          `json:"status"`
+	Value  json.RawMessage `json:"value"`
+	Error  string          `json:"error"`
+}
+
+// toolCall is the value of a successful toolRequest.
+type toolCall struct {
+	Name      string          `json:"name"`
+	Arguments json.RawMessage `json:"arguments"`
+}
+
+// ParseTranscript parses a Goose session in the JSONL session file format:
+// a metadata line with the working directory and token counts, then one
+// message per line. Goose records no model in its sessions, so Model is left
+// empty.
+func (a *Agent) ParseTranscript(r io.Reader) (*agent.Transcript, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []agent.TranscriptEntry
+	var usage agent.UsageMetrics
+
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		if meta := parseMetadataLine([]byte(line)); meta != nil {
+			usage = metadataUsage(meta)
+			continue
+		}
+
+		var msg gooseMessage
+		if err := json.Unmarshal([]byte(line), &msg); err != nil || msg.Role == "" {
+			continue
+		}
+		if msg.Metadata != nil && msg.Metadata.UserVisible != nil && !*msg.Metadata.UserVisible {
+			// Internal messages, such as context summaries
+			continue
+		}
+
+		entry := parseGooseMessage(msg, []byte(line))
+		if entry.Message == nil || len(entry.Message.Content) == 0 {
+			continue
+		}
+		entry.UUID = fmt.Sprintf("goose-%d", len(entries))
+		if msg.ID != "" {
+			entry.UUID = msg.ID
+		}
+		entries = append(entries, entry)
+	}
+
+	t := &agent.Transcript{Entries: entries, Usage: usage}
+	t.Turns = t.CountTurns()
+	return t, nil
+}
+
+// metadataUsage returns the session's token usage, preferring the totals
+// accumulated over the whole session to those of the last exchange.
+func metadataUsage(meta *SessionMetadata) agent.UsageMetrics {
+	pick := func(accumulated, last *int64) int64 {
+		if accumulated != nil {
+			return *accumulated
+		}
+		if last != nil {
+			return *last
+		}
+		return 0
+	}
+	return agent.UsageMetrics{
+		InputTokens:  pick(meta.AccumulatedInputTokens, meta.InputTokens),
+		OutputTokens: pick(meta.AccumulatedOutputTokens, meta.OutputTokens),
+	}
+}
+
+// parseGooseMessage converts a Goose message into a TranscriptEntry. Tool
+// requests become tool_use blocks and tool responses tool_result blocks,
+// matched by the content item's id.
+func parseGooseMessage(msg gooseMessage, rawLine []byte) agent.TranscriptEntry {
+	entry := agent.TranscriptEntry{
+		Type: agent.NormalizeRole(msg.Role),
+		Raw:  json.RawMessage(append([]byte{}, rawLine...)),
+	}
+	if msg.Created > 0 {
+		entry.Timestamp = time.Unix(msg.Created, 0).UTC().Format(time.RFC3339)
+	}
+
+	m := &agent.Message{Role: msg.Role}
+	for _, c := range msg.Content {
+		switch c.Type {
+		case "text":
+			if c.Text != "" {
+				m.Content = append(m.Content, agent.ContentBlock{Type: "text", Text: c.Text})
+			}
+
+		case "thinking":
+			if c.Thinking != "" || c.Omitted != "" {
+				m.Content = append(m.Content, agent.ContentBlock{Type: "thinking", Thinking: c.Thinking, Omitted: c.Omitted})
+			}
+
+		case "toolRequest":
+			var call toolCall
+			if c.ToolCall == nil || c.ToolCall.Status != "success" || json.Unmarshal(c.ToolCall.Value, &call) != nil {
+				continue
+			}
+			m.Content = append(m.Content, agent.ContentBlock{
+				Type:      "tool_use",
+				ID:        c.ID,
+				ToolUseID: c.ID,
+				Name:      toolName(call),
+				Input:     call.Arguments,
+			})
+
+		case "toolResponse":
+			if c.ToolResult == nil {
+				continue
+			}
+			text := c.ToolResult.Error
+			if c.ToolResult.Status == "success" {
+				text = toolResultText(c.ToolResult.Value)
+			}
+			content, _ := json.Marshal(text)
+			m.Content = append(m.Content, agent.ContentBlock{
+				Type:      "tool_result",
+				ToolUseID: c.ID,
+				Content:   content,
+				IsError:   c.ToolResult.Status != "success",
+			})
+		}
+	}
+	entry.Message = m
+	return entry
+}
+
+// toolName returns the name a tool call is shown and aliased under. Viewing
+// a file with the text editor is reported as its own tool so that it does
+// not count as an edit.
+func toolName(call toolCall) string {
+	if call.Name != "developer__text_editor" {
+		return call.Name
+	}
+	var args struct {
+		Command string `json:"command"`
+	}
+	if json.Unmarshal(call.Arguments, &args) == nil && args.Command == "view" {
+		return "developer__text_editor__view"
+	}
+	return call.Name
+}
+
+// toolResultText joins the text items of a tool result. Goose records the
+// result either as a list of content items or as an object holding them in
+// "content".
+func toolResultText(value json.RawMessage) string {
+	type item struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	}
+	var items []item
+	if err := json.Unmarshal(value, &items); err != nil {
+		var wrapped struct {
+			Content []item `json:"content"`
+		}
+		if json.Unmarshal(value, &wrapped) != nil {
+			return ""
+		}
+		items = wrapped.Content
+	}
+
+	var parts []string
+	for _, it := range items {
+		if it.Type == "text" && it.Text != "" {
+			parts = append(parts, it.Text)
+		}
+	}
+	return strings.Join(parts, "\n")
+}
+
+// ParseTranscriptFile parses a Goose session file.
+func (a *Agent) ParseTranscriptFile(path string) (*agent.Transcript, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+	return a.ParseTranscript(f)
+}
+
+// DiscoverSession finds an active or recent Goose session for the project.
+// It first looks for JSONL session files, then falls back to the sessions
+// database of Goose 1.10 and later.
+func (a *Agent) DiscoverSession(projectPath string) (*agent.SessionInfo, error) {
+	path, sessionID, modTime := findRecentSessionFile(projectPath, agent.RecentSessionTimeout)
+	if path != "" {
+		return &agent.SessionInfo{
+			SessionID:      sessionID,
+			TranscriptPath: path,
+			StartedAt:      modTime.Format(time.RFC3339),
+			ProjectPath:    projectPath,
+		}, nil
+	}
+	return discoverFromSQLite(projectPath, agent.RecentSessionTimeout), nil
+}
+
+// RestoreSession writes a session to the Goose sessions directory.
+func (a *Agent) RestoreSession(projectPath, sessionID, gitBranch string,
+	transcriptData []byte, messageCount int, summary string) error {
+
+	_, err := WriteSessionFile(projectPath, sessionID, summary, transcriptData)
+	return err
+}
+
+// ResumeCommand returns the command to resume a Goose session.
+func (a *Agent) ResumeCommand(sessionID string) (string, []string) {
+	return "goose", []string{"session", "--resume", "--name", sessionID}
+}
+
+// VersionCommand returns the command that prints the Goose version.
+func (a *Agent) VersionCommand() (string, []string) {
+	return "goose", []string{"--version"}
+}
+
+// SummariseCommand returns the command to run Goose non-interactively. The
+// prompt is appended as the value of --text.
+func (a *Agent) SummariseCommand() (string, []string) {
+	return "goose", []string{"run", "--no-session", "--quiet", "--text"}
+}
+
+// ToolAliases returns Goose's tool name mappings to canonical names.
+func (a *Agent) ToolAliases() map[string]string {
+	return map[string]string{
+		"developer__shell":             "Bash",
+		"shell":                        "Bash",
+		"developer__text_editor":       "Edit",
+		"developer__text_editor__view": "Read",
+		"text_editor":                  "Edit",
+		"todo__todo_write":             "TodoWrite",
+	}
+}
+
+// LookupBinary checks if the goose binary is in PATH.
+func LookupBinary() (string, error) {
+	return exec.LookPath("goose")
+}
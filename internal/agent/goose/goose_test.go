@@ -0,0 +1,259 @@
+package goose
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/re-cinq/shift-log/internal/agent"
+)
+
+func TestAgentName(t *testing.T) {
+	a := &Agent{}
+	if a.Name() != agent.Goose {
+		t.Errorf("Name() = %q, want %q", a.Name(), agent.Goose)
+	}
+	if a.DisplayName() != "Goose" {
+		t.Errorf("DisplayName() = %q, want %q", a.DisplayName(), "Goose")
+	}
+}
+
+func TestConfigureHooksIsNoop(t *testing.T) {
+	a := &Agent{}
+	tmpDir := t.TempDir()
+
+	if err := a.ConfigureHooks(tmpDir); err != nil {
+		t.Fatalf("ConfigureHooks() error: %v", err)
+	}
+	entries, err := os.ReadDir(tmpDir)
+	if err != nil {
+		t.Fatalf("ReadDir() error: %v", err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("ConfigureHooks() created %d files, expected 0", len(entries))
+	}
+}
+
+func TestIsCommitCommand(t *testing.T) {
+	a := &Agent{}
+	tests := []struct {
+		tool, command string
+		want          bool
+	}{
+		{"developer__shell", "git commit -m 'test'", true},
+		{"shell", "git add . && git commit -m 'x'", true},
+		{"developer__shell", "git status", false},
+		{"developer__text_editor", "git commit -m 'test'", false},
+	}
+	for _, tt := range tests {
+		if got := a.IsCommitCommand(tt.tool, tt.command); got != tt.want {
+			t.Errorf("IsCommitCommand(%q, %q) = %v, want %v", tt.tool, tt.command, got, tt.want)
+		}
+	}
+}
+
+const sampleSession = `{"working_dir":"/test/project","description":"Fix the bug","message_count":4,"input_tokens":100,"output_tokens":20,"accumulated_input_tokens":1500,"accumulated_output_tokens":400}
+{"id":"msg_1","role":"user","created":1750000000,"content":[{"type":"text","text":"Fix the bug in main.go"}]}
+{"id":"msg_2","role":"assistant","created":1750000005,"content":[{"type":"thinking","thinking":"Look at the file first","signature":"sig"},{"type":"toolRequest","id":"call_1","toolCall":{"status":"success","value":{"name":"developer__text_editor","arguments":{"command":"view","path":"/test/project/main.go"}}}}]}
+{"id":"msg_3","role":"user","created":1750000006,"content":[{"type":"toolResponse","id":"call_1","toolResult":{"status":"success","value":[{"type":"text","text":"package main"}]}}]}
+{"id":"msg_4","role":"assistant","created":1750000010,"content":[{"type":"toolRequest","id":"call_2","toolCall":{"status":"success","value":{"name":"developer__text_editor","arguments":{"command":"write","path":"/test/project/main.go","file_text":"package main\n"}}}},{"type":"text","text":"Fixed."}]}
+{"id":"msg_5","role":"user","created":1750000011,"content":[{"type":"toolResponse","id":"call_2","toolResult":{"status":"error","error":"permission denied"}}]}
+{"id":"msg_6","role":"user","created":1750000012,"content":[{"type":"text","text":"summary"}],"metadata":{"userVisible":false}}
+`
+
+func TestParseTranscript(t *testing.T) {
+	a := &Agent{}
+	transcript, err := a.ParseTranscript(strings.NewReader(sampleSession))
+	if err != nil {
+		t.Fatalf("ParseTranscript() error: %v", err)
+	}
+
+	if len(transcript.Entries) != 5 {
+		t.Fatalf("got %d entries, want 5 (hidden message skipped)", len(transcript.Entries))
+	}
+	if transcript.Usage.InputTokens != 1500 || transcript.Usage.OutputTokens != 400 {
+		t.Errorf("Usage = %+v, want accumulated 1500/400", transcript.Usage)
+	}
+
+	first := transcript.Entries[0]
+	if first.Type != agent.MessageTypeUser || first.UUID != "msg_1" {
+		t.Errorf("first entry = %s/%s, want user/msg_1", first.Type, first.UUID)
+	}
+	if first.Timestamp != "2025-06-15T15:06:40Z" {
+		t.Errorf("Timestamp = %q", first.Timestamp)
+	}
+
+	blocks := transcript.Entries[1].Message.Content
+	if len(blocks) != 2 || blocks[0].Type != "thinking" || blocks[0].Thinking != "Look at the file first" {
+		t.Fatalf("unexpected assistant blocks: %+v", blocks)
+	}
+	if blocks[1].Type != "tool_use" || blocks[1].ID != "call_1" || blocks[1].Name != "developer__text_editor__view" {
+		t.Errorf("view call = %+v, want developer__text_editor__view tool_use", blocks[1])
+	}
+
+	result := transcript.Entries[2].Message.Content[0]
+	if result.Type != "tool_result" || result.ToolUseID != "call_1" || string(result.Content) != `"package main"` {
+		t.Errorf("tool result = %+v", result)
+	}
+
+	edit := transcript.Entries[3].Message.Content[0]
+	if edit.Name != "developer__text_editor" {
+		t.Errorf("write call Name = %q, want developer__text_editor", edit.Name)
+	}
+	failed := transcript.Entries[4].Message.Content[0]
+	if string(failed.Content) != `"permission denied"` {
+		t.Errorf("error result Content = %s", failed.Content)
+	}
+	if !failed.IsError || transcript.Entries[2].Message.Content[0].IsError {
+		t.Errorf("IsError = %v for the failed result, %v for the successful one, want true and false", failed.IsError, transcript.Entries[2].Message.Content[0].IsError)
+	}
+}
+
+func TestParseTranscriptEditedFiles(t *testing.T) {
+	a := &Agent{}
+	transcript, err := a.ParseTranscript(strings.NewReader(sampleSession))
+	if err != nil {
+		t.Fatalf("ParseTranscript() error: %v", err)
+	}
+
+	files := agent.EditedFiles(transcript.Entries, a.ToolAliases())
+	if len(files) != 1 || files[0] != "/test/project/main.go" {
+		t.Errorf("EditedFiles() = %v, want only the written file", files)
+	}
+}
+
+func TestParseTranscriptUsageFallback(t *testing.T) {
+	a := &Agent{}
+	session := `{"working_dir":"/p","description":"","input_tokens":100,"output_tokens":20}
+{"role":"user","created":1750000000,"content":[{"type":"text","text":"hi"}]}
+`
+	transcript, err := a.ParseTranscript(strings.NewReader(session))
+	if err != nil {
+		t.Fatalf("ParseTranscript() error: %v", err)
+	}
+	if transcript.Usage.InputTokens != 100 || transcript.Usage.OutputTokens != 20 {
+		t.Errorf("Usage = %+v, want 100/20", transcript.Usage)
+	}
+	if transcript.Entries[0].UUID != "goose-0" {
+		t.Errorf("UUID = %q, want goose-0", transcript.Entries[0].UUID)
+	}
+}
+
+func TestToolResultTextWrapped(t *testing.T) {
+	got := toolResultText([]byte(`{"content":[{"type":"text","text":"a"},{"type":"image","data":"x"},{"type":"text","text":"b"}]}`))
+	if got != "a\nb" {
+		t.Errorf("toolResultText() = %q, want %q", got, "a\nb")
+	}
+}
+
+func TestGetDataDir(t *testing.T) {
+	t.Setenv("XDG_DATA_HOME", "/custom/data")
+	dir, err := GetDataDir()
+	if err != nil {
+		t.Fatalf("GetDataDir() error: %v", err)
+	}
+	if dir != "/custom/data/goose" {
+		t.Errorf("GetDataDir() = %q, want /custom/data/goose", dir)
+	}
+
+	home := t.TempDir()
+	t.Setenv("XDG_DATA_HOME", "")
+	t.Setenv("HOME", home)
+	dir, err = GetDataDir()
+	if err != nil {
+		t.Fatalf("GetDataDir() error: %v", err)
+	}
+	if want := filepath.Join(home, ".local", "share", "goose"); dir != want {
+		t.Errorf("GetDataDir() = %q, want %q", dir, want)
+	}
+}
+
+func TestDiscoverSession(t *testing.T) {
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+	sessionsDir, _ := GetSessionsDir()
+	if err := os.MkdirAll(sessionsDir, 0700); err != nil {
+		t.Fatal(err)
+	}
+
+	other := `{"working_dir":"/other/project","description":""}` + "\n"
+	if err := os.WriteFile(filepath.Join(sessionsDir, "other.jsonl"), []byte(other), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(sessionsDir, "20250615_1.jsonl"), []byte(sampleSession), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	a := &Agent{}
+	info, err := a.DiscoverSession("/test/project")
+	if err != nil {
+		t.Fatalf("DiscoverSession() error: %v", err)
+	}
+	if info == nil {
+		t.Fatal("DiscoverSession() = nil, want session")
+	}
+	if info.SessionID != "20250615_1" {
+		t.Errorf("SessionID = %q, want 20250615_1", info.SessionID)
+	}
+	if info.TranscriptPath != filepath.Join(sessionsDir, "20250615_1.jsonl") {
+		t.Errorf("TranscriptPath = %q", info.TranscriptPath)
+	}
+
+	info, err = a.DiscoverSession("/unknown/project")
+	if err != nil || info != nil {
+		t.Errorf("DiscoverSession(unknown) = %v, %v; want nil, nil", info, err)
+	}
+}
+
+func TestWriteSessionFile(t *testing.T) {
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+
+	path, err := WriteSessionFile("/restored", "sess-1", "summary", []byte(sampleSession))
+	if err != nil {
+		t.Fatalf("WriteSessionFile() error: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != sampleSession {
+		t.Error("WriteSessionFile() changed a transcript that has metadata")
+	}
+
+	messages := `{"role":"user","created":1750000000,"content":[{"type":"text","text":"hi"}]}` + "\n"
+	path, err = WriteSessionFile("/restored", "sess-2", "Did things", []byte(messages))
+	if err != nil {
+		t.Fatalf("WriteSessionFile() error: %v", err)
+	}
+	if filepath.Base(path) != "sess-2.jsonl" {
+		t.Errorf("path = %q, want sess-2.jsonl", path)
+	}
+	meta, err := ParseSessionMetadata(path)
+	if err != nil || meta == nil {
+		t.Fatalf("ParseSessionMetadata() = %v, %v", meta, err)
+	}
+	if meta.WorkingDir != "/restored" || meta.Description != "Did things" {
+		t.Errorf("metadata = %+v", meta)
+	}
+}
+
+func TestResumeCommand(t *testing.T) {
+	a := &Agent{}
+	bin, args := a.ResumeCommand("sess-1")
+	if bin != "goose" || strings.Join(args, " ") != "session --resume --name sess-1" {
+		t.Errorf("ResumeCommand() = %s %v", bin, args)
+	}
+}
+
+func TestToolAliases(t *testing.T) {
+	aliases := (&Agent{}).ToolAliases()
+	for tool, want := range map[string]string{
+		"developer__shell":             "Bash",
+		"developer__text_editor":       "Edit",
+		"developer__text_editor__view": "Read",
+	} {
+		if aliases[tool] != want {
+			t.Errorf("ToolAliases()[%q] = %q, want %q", tool, aliases[tool], want)
+		}
+	}
+}
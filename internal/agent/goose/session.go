@@ -0,0 +1,216 @@
+package goose
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/re-cinq/shift-log/internal/agent"
+)
+
+// SessionMetadata is the first line of a Goose session JSONL file. The
+// remaining lines are the session's messages.
+type SessionMetadata struct {
+	WorkingDir              string `json:"working_dir"`
+	Description             string `json:"description"`
+	MessageCount            int    `json:"message_count,omitempty"`
+	InputTokens             *int64 `json:"input_tokens,omitempty"`
+	OutputTokens            *int64 `json:"output_tokens,omitempty"`
+	AccumulatedInputTokens  *int64 `json:"accumulated_input_tokens,omitempty"`
+	AccumulatedOutputTokens *int64 `json:"accumulated_output_tokens,omitempty"`
+}
+
+// maxSessionLineSize bounds a single line of a session file. Messages embed
+// whole tool outputs, which can be far longer than bufio's default limit.
+const maxSessionLineSize = 16 << 20
+
+// GetDataDir returns the Goose data directory. Goose follows XDG
+// conventions: $XDG_DATA_HOME/goose, defaulting to ~/.local/share/goose on
+// both Linux and macOS.
+func GetDataDir() (string, error) {
+	if xdg := os.Getenv("XDG_DATA_HOME"); xdg != "" {
+		return filepath.Join(xdg, "goose"), nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("could not determine home directory: %w", err)
+	}
+	return filepath.Join(home, ".local", "share", "goose"), nil
+}
+
+// GetSessionsDir returns the directory Goose keeps its sessions in.
+func GetSessionsDir() (string, error) {
+	dataDir, err := GetDataDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dataDir, "sessions"), nil
+}
+
+// ParseSessionMetadata reads the metadata line of a session JSONL file.
+// Returns nil (no error) if the file does not start with session metadata.
+func ParseSessionMetadata(path string) (*SessionMetadata, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxSessionLineSize)
+	if !scanner.Scan() {
+		return nil, scanner.Err()
+	}
+	return parseMetadataLine(scanner.Bytes()), nil
+}
+
+// parseMetadataLine decodes a session metadata line. Returns nil for any
+// other line, such as a message.
+func parseMetadataLine(line []byte) *SessionMetadata {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(line, &fields); err != nil {
+		return nil
+	}
+	if _, ok := fields["working_dir"]; !ok {
+		return nil
+	}
+	var meta SessionMetadata
+	if err := json.Unmarshal(line, &meta); err != nil {
+		return nil
+	}
+	return &meta
+}
+
+// findRecentSessionFile returns the most recently modified session file for
+// projectPath, if it was modified within timeout.
+func findRecentSessionFile(projectPath string, timeout time.Duration) (path, sessionID string, modTime time.Time) {
+	sessionsDir, err := GetSessionsDir()
+	if err != nil {
+		return "", "", time.Time{}
+	}
+	entries, err := os.ReadDir(sessionsDir)
+	if err != nil {
+		return "", "", time.Time{}
+	}
+
+	now := time.Now()
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".jsonl") {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil || now.Sub(info.ModTime()) > timeout {
+			continue
+		}
+		if path != "" && !info.ModTime().After(modTime) {
+			continue
+		}
+
+		p := filepath.Join(sessionsDir, entry.Name())
+		meta, err := ParseSessionMetadata(p)
+		if err != nil || meta == nil || !agent.PathsEqual(meta.WorkingDir, projectPath) {
+			continue
+		}
+		path = p
+		sessionID = strings.TrimSuffix(entry.Name(), ".jsonl")
+		modTime = info.ModTime()
+	}
+	return path, sessionID, modTime
+}
+
+// discoverFromSQLite finds the most recent session for projectPath in the
+// sessions database of Goose 1.10 and later, and returns it in the JSONL
+// session file format: a metadata line followed by one message per line.
+func discoverFromSQLite(projectPath string, timeout time.Duration) *agent.SessionInfo {
+	sessionsDir, err := GetSessionsDir()
+	if err != nil {
+		return nil
+	}
+	dbPath := filepath.Join(sessionsDir, "sessions.db")
+	if _, err := os.Stat(dbPath); err != nil {
+		return nil
+	}
+	if _, err := exec.LookPath("sqlite3"); err != nil {
+		return nil
+	}
+
+	dir := strings.ReplaceAll(projectPath, "'", "''")
+	sessionQuery := fmt.Sprintf(
+		`SELECT id, strftime('%%s', updated_at) FROM sessions WHERE working_dir='%s' ORDER BY updated_at DESC LIMIT 1;`,
+		dir,
+	)
+	out, err := exec.Command("sqlite3", "-separator", "\t", dbPath, sessionQuery).Output()
+	if err != nil {
+		return nil
+	}
+	fields := strings.Split(strings.TrimSpace(string(out)), "\t")
+	if len(fields) != 2 || fields[0] == "" {
+		return nil
+	}
+	sessionID := fields[0]
+	var updated int64
+	if _, err := fmt.Sscan(fields[1], &updated); err == nil && time.Since(time.Unix(updated, 0)) > timeout {
+		return nil
+	}
+
+	id := strings.ReplaceAll(sessionID, "'", "''")
+	metaQuery := fmt.Sprintf(
+		`SELECT json_object('working_dir', working_dir, 'description', description, 'accumulated_input_tokens', accumulated_input_tokens, 'accumulated_output_tokens', accumulated_output_tokens) FROM sessions WHERE id='%s';`,
+		id,
+	)
+	metaOut, err := exec.Command("sqlite3", dbPath, metaQuery).Output()
+	if err != nil {
+		return nil
+	}
+	msgQuery := fmt.Sprintf(
+		`SELECT json_object('id', message_id, 'role', role, 'created', created_timestamp, 'content', json(content_json)) FROM messages WHERE session_id='%s' ORDER BY id;`,
+		id,
+	)
+	msgOut, err := exec.Command("sqlite3", dbPath, msgQuery).Output()
+	if err != nil || strings.TrimSpace(string(msgOut)) == "" {
+		return nil
+	}
+
+	transcriptData := []byte(strings.TrimSpace(string(metaOut)) + "\n" + strings.TrimSpace(string(msgOut)) + "\n")
+	return &agent.SessionInfo{
+		SessionID:      sessionID,
+		StartedAt:      time.Unix(updated, 0).Format(time.RFC3339),
+		ProjectPath:    projectPath,
+		TranscriptData: transcriptData,
+	}
+}
+
+// WriteSessionFile writes a session to the Goose sessions directory as a
+// JSONL session file named after the session, so that Goose can resume it.
+// Transcripts that do not start with a metadata line get one for projectPath.
+func WriteSessionFile(projectPath, sessionID, summary string, transcriptData []byte) (string, error) {
+	sessionsDir, err := GetSessionsDir()
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(sessionsDir, 0700); err != nil {
+		return "", fmt.Errorf("could not create sessions directory: %w", err)
+	}
+
+	data := transcriptData
+	firstLine, _, _ := strings.Cut(string(transcriptData), "\n")
+	if parseMetadataLine([]byte(firstLine)) == nil {
+		description := summary
+		if description == "" {
+			description = "Restored session"
+		}
+		metaLine, err := json.Marshal(SessionMetadata{WorkingDir: projectPath, Description: description})
+		if err != nil {
+			return "", err
+		}
+		data = append(append(metaLine, '\n'), transcriptData...)
+	}
+
+	path := filepath.Join(sessionsDir, sessionID+".jsonl")
+	return path, os.WriteFile(path, data, 0600)
+}
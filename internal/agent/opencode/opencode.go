@@ -92,6 +92,16 @@ func (a *Agent) ParseHookInput(raw []byte) (*agent.HookData, error) {
 	}, nil
 }
 
+// MatchesHookInput claims payloads sent by the OpenCode plugin, which carry
+// its data directory or the transcript itself.
+func (a *Agent) MatchesHookInput(raw []byte) bool {
+	var hook struct {
+		DataDir        string `json:"data_dir"`
+		TranscriptData string `json:"transcript_data"`
+	}
+	return json.Unmarshal(raw, &hook) == nil && (hook.DataDir != "" || hook.TranscriptData != "")
+}
+
 // IsCommitCommand checks if a tool invocation represents a git commit.
 func (a *Agent) IsCommitCommand(toolName, command string) bool {
 	// OpenCode tool names for shell execution
@@ -387,11 +397,52 @@ func (a *Agent) RestoreSession(projectPath, sessionID, gitBranch string,
 	return err
 }
 
+// restoredMessage is a message of the combined transcript file written on
+// restore, in the form ParseTranscript reads back.
+type restoredMessage struct {
+	ID      string               `json:"id"`
+	Role    string               `json:"role"`
+	Time    *restoredTime        `json:"time,omitempty"`
+	Content []agent.ContentBlock `json:"content"`
+}
+
+type restoredTime struct {
+	Created string `json:"created"`
+}
+
+// EncodeTranscript writes a transcript as the JSONL message file that
+// RestoreSession stores with the session. Entries without a message are
+// skipped.
+func (a *Agent) EncodeTranscript(t *agent.Transcript, sessionID, projectPath string) ([]byte, error) {
+	var buf strings.Builder
+	for _, entry := range t.Entries {
+		if entry.Message == nil {
+			continue
+		}
+		msg := restoredMessage{ID: entry.UUID, Role: string(entry.Type), Content: entry.Message.Content}
+		if entry.Timestamp != "" {
+			msg.Time = &restoredTime{Created: entry.Timestamp}
+		}
+		data, err := json.Marshal(msg)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(data)
+		buf.WriteByte('\n')
+	}
+	return []byte(buf.String()), nil
+}
+
 // ResumeCommand returns the command to resume an OpenCode session.
 func (a *Agent) ResumeCommand(sessionID string) (string, []string) {
 	return "opencode", []string{"--session", sessionID}
 }
 
+// VersionCommand returns the command that prints the OpenCode version.
+func (a *Agent) VersionCommand() (string, []string) {
+	return "opencode", []string{"--version"}
+}
+
 // ToolAliases returns OpenCode's tool name mappings to canonical names.
 func (a *Agent) ToolAliases() map[string]string {
 	return map[string]string{
@@ -56,6 +56,16 @@ func TestParseHookInput(t *testing.T) {
 	}
 }
 
+func TestMatchesHookInput(t *testing.T) {
+	a := &Agent{}
+	if !a.MatchesHookInput([]byte(`{"session_id":"s","data_dir":"/d","tool_name":"bash"}`)) {
+		t.Error("MatchesHookInput() = false for plugin payload, want true")
+	}
+	if a.MatchesHookInput([]byte(`{"session_id":"s","transcript_path":"/t","tool_name":"Bash"}`)) {
+		t.Error("MatchesHookInput() = true for standard payload, want false")
+	}
+}
+
 func TestIsCommitCommand(t *testing.T) {
 	a := &Agent{}
 	tests := []struct {
@@ -448,3 +458,33 @@ func TestNormalizeOpenCodeRole(t *testing.T) {
 		}
 	}
 }
+
+func TestEncodeTranscriptRoundTrip(t *testing.T) {
+	a := &Agent{}
+	transcript := agent.ReplayTranscript(&agent.Transcript{Entries: []agent.TranscriptEntry{
+		{Type: agent.MessageTypeUser, Timestamp: "2026-01-01T10:00:00Z", Message: &agent.Message{Content: []agent.ContentBlock{{Type: "text", Text: "Hello"}}}},
+		{Type: agent.MessageTypeAssistant, Message: &agent.Message{Content: []agent.ContentBlock{{Type: "text", Text: "Hi there"}}}},
+	}}, "Claude Code")
+
+	data, err := a.EncodeTranscript(transcript, "session-1", "/repo")
+	if err != nil {
+		t.Fatalf("EncodeTranscript() error: %v", err)
+	}
+
+	parsed, err := a.ParseTranscript(strings.NewReader(string(data)))
+	if err != nil {
+		t.Fatalf("ParseTranscript() error: %v", err)
+	}
+	if len(parsed.Entries) != 3 {
+		t.Fatalf("Expected 3 entries, got %d", len(parsed.Entries))
+	}
+	if !strings.Contains(parsed.Entries[0].Message.Content[0].Text, "Claude Code") {
+		t.Errorf("Entry 0 should be the context message, got %q", parsed.Entries[0].Message.Content[0].Text)
+	}
+	if parsed.Entries[1].Timestamp != "2026-01-01T10:00:00Z" {
+		t.Errorf("Entry 1 timestamp = %q", parsed.Entries[1].Timestamp)
+	}
+	if parsed.Entries[2].Type != agent.MessageTypeAssistant || parsed.Entries[2].Message.Content[0].Text != "Hi there" {
+		t.Errorf("Entry 2 = %+v", parsed.Entries[2])
+	}
+}
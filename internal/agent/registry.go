@@ -47,3 +47,15 @@ func SupportedNames() string {
 	defer mu.RUnlock()
 	return supportedNames()
 }
+
+// All returns the registered agents sorted by name.
+func All() []Agent {
+	mu.RLock()
+	defer mu.RUnlock()
+	agents := make([]Agent, 0, len(registry))
+	for _, a := range registry {
+		agents = append(agents, a)
+	}
+	sort.Slice(agents, func(i, j int) bool { return agents[i].Name() < agents[j].Name() })
+	return agents
+}
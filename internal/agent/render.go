@@ -90,11 +90,17 @@ func (r *Renderer) renderMessageContent(msg *Message) {
 		case "text":
 			r.renderText(block.Text)
 		case "thinking":
+			if block.Omitted != "" {
+				_, _ = fmt.Fprintf(r.w, "  %s[thinking %s]%s\n", r.color(colorDim), block.Omitted, r.color(colorReset))
+				continue
+			}
 			r.renderThinking(block.Thinking)
 		case "tool_use":
 			r.renderToolUse(block)
 		case "tool_result":
 			r.renderToolResult(block)
+		case "image", "document":
+			r.renderAttachment(block)
 		}
 	}
 }
@@ -125,6 +131,14 @@ func (r *Renderer) renderThinking(thinking string) {
 	}
 }
 
+func (r *Renderer) renderAttachment(block ContentBlock) {
+	mediaType := block.Type
+	if block.Source != nil && block.Source.MediaType != "" {
+		mediaType = block.Source.MediaType
+	}
+	_, _ = fmt.Fprintf(r.w, "  %s[%s: %s]%s\n", r.color(colorDim), block.Type, mediaType, r.color(colorReset))
+}
+
 // resolveToolName maps an agent-specific tool name to its canonical display name.
 func (r *Renderer) resolveToolName(name string) string {
 	if r.toolAliases != nil {
@@ -388,3 +388,19 @@ func TestRendererToolAliases(t *testing.T) {
 		t.Errorf("Output should render Bash tool input after alias resolution, got: %s", output)
 	}
 }
+
+func TestRendererAttachment(t *testing.T) {
+	var buf bytes.Buffer
+	r := NewRenderer(&buf, nil)
+
+	r.RenderEntry(&TranscriptEntry{
+		Type: MessageTypeUser,
+		Message: &Message{Content: []ContentBlock{
+			{Type: "image", Source: &AttachmentSource{Type: SourceBase64, MediaType: "image/png", Data: "iVBORw=="}},
+		}},
+	})
+
+	if output := buf.String(); !strings.Contains(output, "[image: image/png]") || strings.Contains(output, "iVBORw") {
+		t.Errorf("Output should name the image without its data, got: %s", output)
+	}
+}
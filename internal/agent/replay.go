@@ -0,0 +1,86 @@
+package agent
+
+import (
+	"fmt"
+	"strings"
+)
+
+// TranscriptEncoder is an optional interface for agents that can write a
+// transcript in their native session format, so that conversations stored by
+// other agents can be resumed with them.
+// Checked via type assertion: if e, ok := ag.(TranscriptEncoder); ok { ... }
+type TranscriptEncoder interface {
+	// EncodeTranscript returns t as the session data RestoreSession expects
+	// for sessionID in the project at projectPath.
+	EncodeTranscript(t *Transcript, sessionID, projectPath string) ([]byte, error)
+}
+
+// replayContext opens a replayed transcript. %s is the display name of the
+// agent that recorded the conversation.
+const replayContext = `This session continues a conversation held with %s. Its messages follow,
+with tool calls reduced to their names and tool results left out. Files may
+have changed since; check the working tree before relying on them.`
+
+// ReplayTranscript converts a transcript recorded by another agent into one
+// that any agent can continue. Tool calls and their results cannot be
+// replayed across agents, so the conversation is kept as text: a context
+// message naming the source agent, then the user and assistant messages with
+// tool calls reduced to "Used tool: <name>". Thinking, tool results and
+// system entries are dropped.
+func ReplayTranscript(t *Transcript, source string) *Transcript {
+	replay := &Transcript{Model: t.Model, AgentVersion: t.AgentVersion}
+	add := func(msgType MessageType, timestamp, text string) {
+		entry := TranscriptEntry{
+			UUID:      fmt.Sprintf("replay-%d", len(replay.Entries)+1),
+			Type:      msgType,
+			Timestamp: timestamp,
+			Message: &Message{
+				Role:    string(msgType),
+				Content: []ContentBlock{{Type: "text", Text: text}},
+			},
+		}
+		if n := len(replay.Entries); n > 0 {
+			entry.ParentUUID = replay.Entries[n-1].UUID
+		}
+		replay.Entries = append(replay.Entries, entry)
+	}
+
+	var timestamp string
+	for _, entry := range t.Entries {
+		if entry.Timestamp != "" {
+			timestamp = entry.Timestamp
+			break
+		}
+	}
+	add(MessageTypeUser, timestamp, fmt.Sprintf(replayContext, source))
+
+	for _, entry := range t.Entries {
+		if entry.Message == nil || (entry.Type != MessageTypeUser && entry.Type != MessageTypeAssistant) {
+			continue
+		}
+		var parts []string
+		for _, block := range entry.Message.Content {
+			switch block.Type {
+			case "text":
+				if text := strings.TrimSpace(block.Text); text != "" {
+					parts = append(parts, text)
+				}
+			case "tool_use":
+				name := block.Name
+				if block.Text != "" && name == "" {
+					name = block.Text // Codex puts tool name in Text
+				}
+				if name != "" {
+					parts = append(parts, "Used tool: "+name)
+				}
+				// Skip: thinking, tool_result
+			}
+		}
+		if len(parts) > 0 {
+			add(entry.Type, entry.Timestamp, strings.Join(parts, "\n\n"))
+		}
+	}
+
+	replay.Turns = replay.CountTurns()
+	return replay
+}
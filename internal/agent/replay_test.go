@@ -0,0 +1,57 @@
+package agent
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestReplayTranscript(t *testing.T) {
+	transcript := &Transcript{
+		Model: "claude-sonnet-4-5",
+		Entries: []TranscriptEntry{
+			{Type: MessageTypeSystem, Message: &Message{Content: []ContentBlock{{Type: "text", Text: "system prompt"}}}},
+			{Type: MessageTypeUser, Timestamp: "2026-01-01T10:00:00Z", Message: &Message{Content: []ContentBlock{{Type: "text", Text: "Rename greet"}}}},
+			{Type: MessageTypeAssistant, Message: &Message{Content: []ContentBlock{
+				{Type: "thinking", Thinking: "let me look"},
+				{Type: "text", Text: "Renaming it now."},
+				{Type: "tool_use", ID: "t1", Name: "Edit"},
+			}}},
+			{Type: MessageTypeUser, Message: &Message{Content: []ContentBlock{{Type: "tool_result", ToolUseID: "t1"}}}},
+			{Type: MessageTypeAssistant, Message: &Message{Content: []ContentBlock{{Type: "text", Text: "Done."}}}},
+		},
+	}
+
+	replay := ReplayTranscript(transcript, "Claude Code")
+
+	var texts []string
+	for _, entry := range replay.Entries {
+		texts = append(texts, string(entry.Type)+": "+entry.Message.Content[0].Text)
+	}
+	if len(texts) != 4 {
+		t.Fatalf("got %d entries, want 4: %q", len(texts), texts)
+	}
+	if !strings.HasPrefix(texts[0], "user: ") || !strings.Contains(texts[0], "Claude Code") {
+		t.Errorf("first entry should be the context message, got %q", texts[0])
+	}
+	if texts[1] != "user: Rename greet" {
+		t.Errorf("entry 1 = %q", texts[1])
+	}
+	if texts[2] != "assistant: Renaming it now.\n\nUsed tool: Edit" {
+		t.Errorf("entry 2 = %q", texts[2])
+	}
+	if texts[3] != "assistant: Done." {
+		t.Errorf("entry 3 = %q", texts[3])
+	}
+
+	if replay.Entries[0].Timestamp != "2026-01-01T10:00:00Z" {
+		t.Errorf("context timestamp = %q, want the first recorded one", replay.Entries[0].Timestamp)
+	}
+	for i := 1; i < len(replay.Entries); i++ {
+		if replay.Entries[i].ParentUUID != replay.Entries[i-1].UUID {
+			t.Errorf("entry %d parent = %q, want %q", i, replay.Entries[i].ParentUUID, replay.Entries[i-1].UUID)
+		}
+	}
+	if replay.Model != "claude-sonnet-4-5" || replay.Turns != 2 {
+		t.Errorf("Model = %q, Turns = %d", replay.Model, replay.Turns)
+	}
+}
@@ -0,0 +1,76 @@
+package agent
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// SessionLister is an optional interface for agents that can enumerate all
+// sessions of a project, not only the active one. Checked via type
+// assertion like Summariser.
+type SessionLister interface {
+	// ListSessions returns the project's sessions, newest first.
+	ListSessions(projectPath string) ([]SessionInfo, error)
+}
+
+// ListSessions returns the sessions of the project known to the agent,
+// newest first. Agents that cannot enumerate their sessions report the one
+// DiscoverSession finds, if any.
+func ListSessions(ag Agent, projectPath string) ([]SessionInfo, error) {
+	if l, ok := ag.(SessionLister); ok {
+		return l.ListSessions(projectPath)
+	}
+	info, err := ag.DiscoverSession(projectPath)
+	if err != nil || info == nil {
+		return nil, err
+	}
+	return []SessionInfo{*info}, nil
+}
+
+// ListSessionFiles returns a session for every file with the extension in
+// sessionDir, newest first, regardless of its age. It is the listing
+// counterpart of ScanDirForRecentSession; StartedAt holds the file's
+// modification time.
+func ListSessionFiles(sessionDir, ext string, skipNames []string, projectPath string) []SessionInfo {
+	entries, err := os.ReadDir(sessionDir)
+	if err != nil {
+		return nil
+	}
+
+	skip := make(map[string]bool, len(skipNames))
+	for _, name := range skipNames {
+		skip[name] = true
+	}
+
+	var sessions []SessionInfo
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ext) || skip[entry.Name()] {
+			continue
+		}
+		fi, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		sessions = append(sessions, SessionInfo{
+			SessionID:      strings.TrimSuffix(entry.Name(), ext),
+			TranscriptPath: filepath.Join(sessionDir, entry.Name()),
+			StartedAt:      fi.ModTime().Format(time.RFC3339),
+			ProjectPath:    projectPath,
+		})
+	}
+	SortSessions(sessions)
+	return sessions
+}
+
+// SortSessions orders sessions by StartedAt, newest first. Sessions whose
+// StartedAt cannot be parsed sort last.
+func SortSessions(sessions []SessionInfo) {
+	sort.SliceStable(sessions, func(i, j int) bool {
+		ti, _ := time.Parse(time.RFC3339, sessions[i].StartedAt)
+		tj, _ := time.Parse(time.RFC3339, sessions[j].StartedAt)
+		return ti.After(tj)
+	})
+}
@@ -0,0 +1,45 @@
+package agent
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestListSessionFiles(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+	for name, age := range map[string]time.Duration{
+		"old.jsonl":            48 * time.Hour,
+		"new.jsonl":            time.Minute,
+		"skipped.jsonl":        0,
+		"notes.txt":            0,
+		"sessions-index.jsonl": 0,
+	} {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte("{}"), 0600); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, now.Add(-age), now.Add(-age)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	sessions := ListSessionFiles(dir, ".jsonl", []string{"skipped.jsonl", "sessions-index.jsonl"}, "/project")
+	if len(sessions) != 2 {
+		t.Fatalf("got %d sessions, want 2", len(sessions))
+	}
+	if sessions[0].SessionID != "new" || sessions[1].SessionID != "old" {
+		t.Errorf("got sessions %q, %q, want new, old", sessions[0].SessionID, sessions[1].SessionID)
+	}
+	if sessions[0].TranscriptPath != filepath.Join(dir, "new.jsonl") || sessions[0].ProjectPath != "/project" {
+		t.Errorf("unexpected session %+v", sessions[0])
+	}
+}
+
+func TestListSessionFilesMissingDir(t *testing.T) {
+	if sessions := ListSessionFiles(filepath.Join(t.TempDir(), "missing"), ".jsonl", nil, ""); sessions != nil {
+		t.Errorf("got %v, want nil", sessions)
+	}
+}
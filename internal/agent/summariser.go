@@ -2,7 +2,10 @@ package agent
 
 import (
 	"fmt"
+	"path"
 	"strings"
+	"unicode"
+	"unicode/utf8"
 )
 
 // Summariser is an optional interface for agents that support non-interactive
@@ -28,6 +31,21 @@ const (
 
 Be concise — aim for 5-15 bullet points. Use plain text, no markdown headers.
 
+--- TRANSCRIPT ---
+`
+
+	shortSummaryInstruction = `Summarise the following coding conversation transcript in 2-3 sentences
+of plain prose: what was asked for and what was changed. No bullet points, no
+markdown, no preamble.
+
+--- TRANSCRIPT ---
+`
+
+	commitMessageInstruction = `Write a git commit message for the changes made in the following coding
+conversation transcript: a subject line of at most 72 characters in the
+imperative mood, optionally followed by a blank line and a short body. Output
+only the commit message, no markdown, no preamble.
+
 --- TRANSCRIPT ---
 `
 )
@@ -54,6 +72,22 @@ func BuildSummaryPrompt(entries []TranscriptEntry, maxChars int) string {
 // BuildSummaryPromptWithFocus is like BuildSummaryPrompt but accepts an optional
 // focus string that hints the LLM about what to prioritise in the summary.
 func BuildSummaryPromptWithFocus(entries []TranscriptEntry, maxChars int, focus string) string {
+	return buildPrompt(entries, maxChars, buildSummaryInstruction(focus))
+}
+
+// BuildShortSummaryPrompt constructs a prompt asking for a 2-3 sentence
+// summary, suitable for storing alongside the conversation.
+func BuildShortSummaryPrompt(entries []TranscriptEntry, maxChars int) string {
+	return buildPrompt(entries, maxChars, shortSummaryInstruction)
+}
+
+// BuildCommitMessagePrompt constructs a prompt asking for a commit message
+// describing the changes made in the conversation.
+func BuildCommitMessagePrompt(entries []TranscriptEntry, maxChars int) string {
+	return buildPrompt(entries, maxChars, commitMessageInstruction)
+}
+
+func buildPrompt(entries []TranscriptEntry, maxChars int, instruction string) string {
 	if maxChars <= 0 {
 		maxChars = DefaultMaxPromptChars
 	}
@@ -93,8 +127,6 @@ func BuildSummaryPromptWithFocus(entries []TranscriptEntry, maxChars int, focus
 
 	transcript := strings.Join(lines, "\n")
 
-	instruction := buildSummaryInstruction(focus)
-
 	// Budget: maxChars minus the instruction prefix
 	budget := maxChars - len(instruction)
 	if budget < 1000 {
@@ -108,3 +140,101 @@ func BuildSummaryPromptWithFocus(entries []TranscriptEntry, maxChars int, focus
 
 	return instruction + transcript
 }
+
+// heuristicQuoteMaxRunes caps quoted text in heuristic summaries.
+const heuristicQuoteMaxRunes = 120
+
+// HeuristicSummary builds a short summary from the transcript without calling
+// an LLM: the first user request, the files edited and the agent's last reply.
+// Returns "" if the transcript has no user or assistant text.
+func HeuristicSummary(entries []TranscriptEntry, aliases map[string]string) string {
+	var request, reply string
+	toolCalls := 0
+	for _, entry := range entries {
+		if entry.Message == nil {
+			continue
+		}
+		for _, block := range entry.Message.Content {
+			switch {
+			case block.Type == "tool_use":
+				toolCalls++
+			case block.Type == "text" && block.Text != "" && entry.Type == MessageTypeUser && request == "":
+				request = block.Text
+			case block.Type == "text" && block.Text != "" && entry.Type == MessageTypeAssistant:
+				reply = block.Text
+			}
+		}
+	}
+	if request == "" && reply == "" {
+		return ""
+	}
+
+	var sentences []string
+	if request != "" {
+		sentences = append(sentences, fmt.Sprintf("The user asked: \"%s\".", quoteSnippet(request)))
+	}
+
+	files := EditedFiles(entries, aliases)
+	for i, f := range files {
+		files[i] = path.Base(f)
+	}
+	switch {
+	case len(files) > 3:
+		sentences = append(sentences, fmt.Sprintf("The agent edited %s and %d more files.", strings.Join(files[:3], ", "), len(files)-3))
+	case len(files) > 0:
+		sentences = append(sentences, fmt.Sprintf("The agent edited %s.", strings.Join(files, ", ")))
+	case toolCalls > 0:
+		sentences = append(sentences, fmt.Sprintf("The agent made %d tool calls without editing files.", toolCalls))
+	}
+
+	if reply != "" {
+		sentences = append(sentences, fmt.Sprintf("It concluded: \"%s\".", quoteSnippet(reply)))
+	}
+	return strings.Join(sentences, " ")
+}
+
+// quoteSnippet returns the first line of text without its final period,
+// truncated for quoting.
+func quoteSnippet(text string) string {
+	line := strings.TrimSpace(strings.SplitN(strings.TrimSpace(text), "\n", 2)[0])
+	line = strings.TrimSuffix(line, ".")
+	if utf8.RuneCountInString(line) > heuristicQuoteMaxRunes {
+		line = string([]rune(line)[:heuristicQuoteMaxRunes]) + "..."
+	}
+	return line
+}
+
+// commitSubjectMaxRunes caps the length of heuristic commit subjects.
+const commitSubjectMaxRunes = 72
+
+// HeuristicCommitMessage suggests a commit subject from the last user request
+// in the transcript, without calling an LLM. Returns "" if the transcript has
+// no user text.
+func HeuristicCommitMessage(entries []TranscriptEntry) string {
+	var request string
+	for _, entry := range entries {
+		if entry.Type != MessageTypeUser || entry.Message == nil {
+			continue
+		}
+		for _, block := range entry.Message.Content {
+			if block.Type == "text" && strings.TrimSpace(block.Text) != "" {
+				request = block.Text
+			}
+		}
+	}
+	if request == "" {
+		return ""
+	}
+
+	subject := strings.TrimSpace(strings.SplitN(strings.TrimSpace(request), "\n", 2)[0])
+	subject = strings.TrimRight(subject, ".!?")
+	if subject == "" {
+		return ""
+	}
+	runes := []rune(subject)
+	if len(runes) > commitSubjectMaxRunes {
+		runes = append(runes[:commitSubjectMaxRunes-3], []rune("...")...)
+	}
+	runes[0] = unicode.ToUpper(runes[0])
+	return string(runes)
+}
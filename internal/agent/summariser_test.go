@@ -1,8 +1,10 @@
 package agent
 
 import (
+	"encoding/json"
 	"strings"
 	"testing"
+	"unicode/utf8"
 )
 
 func TestBuildSummaryPrompt_Basic(t *testing.T) {
@@ -291,3 +293,81 @@ func TestBuildSummaryPrompt_NilMessage(t *testing.T) {
 		t.Error("prompt should skip nil messages and include valid ones")
 	}
 }
+
+func TestBuildShortSummaryPrompt(t *testing.T) {
+	entries := []TranscriptEntry{
+		{Type: MessageTypeUser, Message: &Message{Content: []ContentBlock{{Type: "text", Text: "Add a login page"}}}},
+	}
+
+	prompt := BuildShortSummaryPrompt(entries, DefaultMaxPromptChars)
+	if !strings.Contains(prompt, "2-3 sentences") {
+		t.Error("short prompt should ask for 2-3 sentences")
+	}
+	if !strings.Contains(prompt, "[user] Add a login page") {
+		t.Error("short prompt should include the transcript")
+	}
+	if BuildShortSummaryPrompt(nil, DefaultMaxPromptChars) != "" {
+		t.Error("empty transcript should produce an empty prompt")
+	}
+}
+
+func TestHeuristicSummary(t *testing.T) {
+	entries := []TranscriptEntry{
+		{Type: MessageTypeUser, Message: &Message{Content: []ContentBlock{{Type: "text", Text: "Rename the greeting function\nIt lives in app.go"}}}},
+		{Type: MessageTypeAssistant, Message: &Message{Content: []ContentBlock{
+			{Type: "text", Text: "Renaming it now."},
+			{Type: "tool_use", Name: "Edit", Input: json.RawMessage(`{"file_path":"/repo/src/app.go","new_string":"func greet()"}`)},
+		}}},
+		{Type: MessageTypeAssistant, Message: &Message{Content: []ContentBlock{{Type: "text", Text: "Done, greet() replaces hello()."}}}},
+	}
+
+	want := `The user asked: "Rename the greeting function". The agent edited app.go. It concluded: "Done, greet() replaces hello()".`
+	if got := HeuristicSummary(entries, nil); got != want {
+		t.Errorf("HeuristicSummary() = %q, want %q", got, want)
+	}
+
+	toolsOnly := []TranscriptEntry{
+		entries[0],
+		{Type: MessageTypeAssistant, Message: &Message{Content: []ContentBlock{{Type: "tool_use", Name: "Bash", Input: json.RawMessage(`{"command":"ls"}`)}}}},
+	}
+	if got := HeuristicSummary(toolsOnly, nil); !strings.Contains(got, "made 1 tool calls without editing files") {
+		t.Errorf("HeuristicSummary() = %q, want tool call count", got)
+	}
+
+	if got := HeuristicSummary(nil, nil); got != "" {
+		t.Errorf("HeuristicSummary(nil) = %q, want empty", got)
+	}
+}
+
+func TestBuildCommitMessagePrompt(t *testing.T) {
+	entries := []TranscriptEntry{
+		{Type: MessageTypeUser, Message: &Message{Content: []ContentBlock{{Type: "text", Text: "Fix the login bug"}}}},
+	}
+	prompt := BuildCommitMessagePrompt(entries, DefaultMaxPromptChars)
+	if !strings.Contains(prompt, "git commit message") || !strings.Contains(prompt, "[user] Fix the login bug") {
+		t.Errorf("BuildCommitMessagePrompt() = %q", prompt)
+	}
+}
+
+func TestHeuristicCommitMessage(t *testing.T) {
+	entries := []TranscriptEntry{
+		{Type: MessageTypeUser, Message: &Message{Content: []ContentBlock{{Type: "text", Text: "rename the greeting function"}}}},
+		{Type: MessageTypeAssistant, Message: &Message{Content: []ContentBlock{{Type: "text", Text: "Done."}}}},
+		{Type: MessageTypeUser, Message: &Message{Content: []ContentBlock{{Type: "text", Text: "now add a test for it.\nUse table tests"}}}},
+		{Type: MessageTypeUser, Message: &Message{Content: []ContentBlock{{Type: "tool_result", Text: "ok"}}}},
+	}
+	if got, want := HeuristicCommitMessage(entries), "Now add a test for it"; got != want {
+		t.Errorf("HeuristicCommitMessage() = %q, want %q", got, want)
+	}
+
+	long := []TranscriptEntry{
+		{Type: MessageTypeUser, Message: &Message{Content: []ContentBlock{{Type: "text", Text: strings.Repeat("word ", 30)}}}},
+	}
+	if got := HeuristicCommitMessage(long); utf8.RuneCountInString(got) != 72 || !strings.HasSuffix(got, "...") {
+		t.Errorf("HeuristicCommitMessage() = %q, want 72 runes ending in ...", got)
+	}
+
+	if got := HeuristicCommitMessage(nil); got != "" {
+		t.Errorf("HeuristicCommitMessage(nil) = %q, want empty", got)
+	}
+}
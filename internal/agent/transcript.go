@@ -13,16 +13,38 @@ const (
 	MessageTypeSystem    MessageType = "system"
 )
 
+// How the content of a thinking block was left out when it was stored, for
+// ContentBlock.Omitted.
+const (
+	// ThinkingStripped marks a thinking block whose text was dropped.
+	ThinkingStripped = "stripped"
+	// ThinkingHashed marks a thinking block whose text was replaced with
+	// "sha256:" and the hex SHA-256 of the text.
+	ThinkingHashed = "hashed"
+)
+
 // ContentBlock represents a content block in a message.
 type ContentBlock struct {
-	Type      string          `json:"type"`
-	Text      string          `json:"text,omitempty"`
-	Thinking  string          `json:"thinking,omitempty"`
-	ID        string          `json:"id,omitempty"`
-	Name      string          `json:"name,omitempty"`
-	Input     json.RawMessage `json:"input,omitempty"`
-	ToolUseID string          `json:"tool_use_id,omitempty"`
-	Content   json.RawMessage `json:"content,omitempty"`
+	Type      string            `json:"type"`
+	Text      string            `json:"text,omitempty"`
+	Thinking  string            `json:"thinking,omitempty"`
+	ID        string            `json:"id,omitempty"`
+	Name      string            `json:"name,omitempty"`
+	Input     json.RawMessage   `json:"input,omitempty"`
+	ToolUseID string            `json:"tool_use_id,omitempty"`
+	Content   json.RawMessage   `json:"content,omitempty"`
+	IsError   bool              `json:"is_error,omitempty"` // a tool_result reporting that the call failed
+	Omitted   string            `json:"omitted,omitempty"`  // ThinkingStripped or ThinkingHashed for thinking left out when stored
+	Source    *AttachmentSource `json:"source,omitempty"`   // image and document blocks
+	// AttachmentURL is where shiftlog serve serves an image or document
+	// block, whose data it leaves out of conversation responses. Only the
+	// server sets it and ContentURL; it drops those a stored transcript has.
+	AttachmentURL string `json:"attachment_url,omitempty"`
+	// Truncated marks a tool result whose content shiftlog serve shortened
+	// to a preview of its first lines; ContentURL serves the full entry.
+	Truncated    bool   `json:"truncated,omitempty"`
+	ContentURL   string `json:"content_url,omitempty"`
+	ContentLines int    `json:"content_lines,omitempty"`
 }
 
 // TranscriptEntry represents a single entry in a transcript.
@@ -31,6 +53,8 @@ type TranscriptEntry struct {
 	ParentUUID              string          `json:"parentUuid,omitempty"`
 	Type                    MessageType     `json:"type"`
 	Timestamp               string          `json:"timestamp,omitempty"`
+	Model                   string          `json:"model,omitempty"` // model that produced an assistant entry, when the transcript records it
+	Usage                   *UsageMetrics   `json:"usage,omitempty"` // token usage of the API call that produced an assistant entry, when recorded
 	Message                 *Message        `json:"message,omitempty"`
 	SourceToolAssistantUUID string          `json:"sourceToolAssistantUUID,omitempty"`
 	Raw                     json.RawMessage `json:"-"`
@@ -83,8 +107,44 @@ func (u UsageMetrics) TotalTokens() int64 {
 type Transcript struct {
 	Entries []TranscriptEntry
 	Model   string       // model identifier extracted from transcript (e.g. "claude-sonnet-4-5-20250514")
-	Usage   UsageMetrics // cumulative token usage (Claude Code only)
+	Usage   UsageMetrics // cumulative token usage (Claude Code, Codex CLI and Goose)
 	Turns   int          // number of user turns (all agents)
+
+	AgentVersion string // version of the agent CLI that wrote the transcript, when recorded (Claude Code and Codex CLI)
+}
+
+// ModelCount is the number of assistant messages produced by a model.
+type ModelCount struct {
+	Model    string
+	Messages int
+}
+
+// ModelCounts counts the assistant messages of each model, in order of first
+// use. Entries without a model of their own are attributed to t.Model.
+// Returns nil if the transcript records no model.
+func (t *Transcript) ModelCounts() []ModelCount {
+	var counts []ModelCount
+	index := make(map[string]int)
+	for _, entry := range t.Entries {
+		if entry.Type != MessageTypeAssistant {
+			continue
+		}
+		model := entry.Model
+		if model == "" {
+			model = t.Model
+		}
+		if model == "" {
+			continue
+		}
+		i, ok := index[model]
+		if !ok {
+			i = len(counts)
+			index[model] = i
+			counts = append(counts, ModelCount{Model: model})
+		}
+		counts[i].Messages++
+	}
+	return counts
 }
 
 // MessageCount returns the number of entries in the transcript.
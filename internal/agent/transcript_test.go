@@ -87,3 +87,27 @@ func TestUsageMetricsTotalTokens(t *testing.T) {
 		t.Errorf("zero UsageMetrics TotalTokens() = %d, want 0", u.TotalTokens())
 	}
 }
+
+func TestModelCounts(t *testing.T) {
+	tr := &Transcript{
+		Model: "sonnet",
+		Entries: []TranscriptEntry{
+			{Type: MessageTypeUser},
+			{Type: MessageTypeAssistant, Model: "opus"},
+			{Type: MessageTypeAssistant},
+			{Type: MessageTypeAssistant, Model: "opus"},
+		},
+	}
+
+	got := tr.ModelCounts()
+	want := []ModelCount{{Model: "opus", Messages: 2}, {Model: "sonnet", Messages: 1}}
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Errorf("ModelCounts() = %+v, want %+v", got, want)
+	}
+
+	tr.Model = ""
+	tr.Entries = tr.Entries[:1]
+	if got := tr.ModelCounts(); got != nil {
+		t.Errorf("ModelCounts() without models = %+v, want nil", got)
+	}
+}
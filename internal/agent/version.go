@@ -0,0 +1,53 @@
+package agent
+
+import (
+	"context"
+	"os/exec"
+	"regexp"
+	"time"
+)
+
+// VersionReporter is an optional interface for agents whose CLI can report
+// its version. Checked via type assertion like Summariser.
+type VersionReporter interface {
+	// VersionCommand returns the binary name and arguments that print the
+	// agent CLI's version.
+	VersionCommand() (binary string, args []string)
+}
+
+// VersionTimeout bounds running an agent's version command.
+const VersionTimeout = 5 * time.Second
+
+var versionPattern = regexp.MustCompile(`\d+\.\d+(\.\d+)?([-+][0-9A-Za-z.-]+)?`)
+
+// DetectVersion returns the version of the agent's installed CLI, or "" if
+// the agent cannot report it, its binary is not on PATH, or the output
+// contains no version number.
+func DetectVersion(ag Agent) string {
+	v, ok := ag.(VersionReporter)
+	if !ok {
+		return ""
+	}
+	binary, args := v.VersionCommand()
+	if binary == "" {
+		return ""
+	}
+	path, err := exec.LookPath(binary)
+	if err != nil {
+		return ""
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), VersionTimeout)
+	defer cancel()
+	out, err := exec.CommandContext(ctx, path, args...).Output()
+	if err != nil {
+		return ""
+	}
+	return ParseVersion(string(out))
+}
+
+// ParseVersion extracts the first version number from a version command's
+// output, e.g. "1.0.51" from "1.0.51 (Claude Code)".
+func ParseVersion(output string) string {
+	return versionPattern.FindString(output)
+}
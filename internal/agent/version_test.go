@@ -0,0 +1,27 @@
+package agent
+
+import "testing"
+
+func TestParseVersion(t *testing.T) {
+	tests := []struct {
+		output string
+		want   string
+	}{
+		{"1.0.51 (Claude Code)\n", "1.0.51"},
+		{"codex-cli 0.46.0\n", "0.46.0"},
+		{"Goose 1.9.0-beta.2", "1.9.0-beta.2"},
+		{"0.9", "0.9"},
+		{"no version here", ""},
+	}
+	for _, tt := range tests {
+		if got := ParseVersion(tt.output); got != tt.want {
+			t.Errorf("ParseVersion(%q) = %q, want %q", tt.output, got, tt.want)
+		}
+	}
+}
+
+func TestDetectVersionWithoutReporter(t *testing.T) {
+	if got := DetectVersion(nil); got != "" {
+		t.Errorf("DetectVersion(nil) = %q, want empty", got)
+	}
+}
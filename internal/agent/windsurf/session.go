@@ -0,0 +1,126 @@
+package windsurf
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/re-cinq/shift-log/internal/agent"
+)
+
+// exportHeading is the first line of a Cascade conversation exported as
+// Markdown.
+const exportHeading = "# Cascade Chat Conversation"
+
+// ExportDirs returns the directories searched for Cascade conversation
+// exports of a project: .windsurf/ and the project root.
+func ExportDirs(projectPath string) []string {
+	return []string{filepath.Join(projectPath, ".windsurf"), projectPath}
+}
+
+// GetDataDir returns the Windsurf data directory, ~/.codeium/windsurf.
+func GetDataDir() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("could not determine home directory: %w", err)
+	}
+	return filepath.Join(home, ".codeium", "windsurf"), nil
+}
+
+// GetRestoreDir returns the directory restored conversations are written to.
+// It lies outside the project so that a restored conversation is not
+// discovered as a new one on the next commit.
+func GetRestoreDir() (string, error) {
+	dataDir, err := GetDataDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dataDir, "shiftlog"), nil
+}
+
+// IsCascadeExport reports whether the file is a Cascade conversation export.
+func IsCascadeExport(path string) bool {
+	f, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer func() { _ = f.Close() }()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		return line == exportHeading
+	}
+	return false
+}
+
+// findRecentExport returns the most recently modified Cascade export of the
+// project, if it was modified within timeout.
+func findRecentExport(projectPath string, timeout time.Duration) *agent.SessionInfo {
+	var best string
+	var bestModTime time.Time
+
+	now := time.Now()
+	for _, dir := range ExportDirs(projectPath) {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".md") {
+				continue
+			}
+			info, err := entry.Info()
+			if err != nil || now.Sub(info.ModTime()) > timeout {
+				continue
+			}
+			if best != "" && !info.ModTime().After(bestModTime) {
+				continue
+			}
+			path := filepath.Join(dir, entry.Name())
+			if !IsCascadeExport(path) {
+				continue
+			}
+			best = path
+			bestModTime = info.ModTime()
+		}
+	}
+
+	if best == "" {
+		return nil
+	}
+	return &agent.SessionInfo{
+		SessionID:      strings.TrimSuffix(filepath.Base(best), ".md"),
+		TranscriptPath: best,
+		StartedAt:      bestModTime.Format(time.RFC3339),
+		ProjectPath:    projectPath,
+	}
+}
+
+// restoredPath returns the path a restored conversation is written to.
+func restoredPath(sessionID string) (string, error) {
+	dir, err := GetRestoreDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, sessionID+".md"), nil
+}
+
+// WriteRestoredFile writes a conversation to the restore directory and
+// returns its path.
+func WriteRestoredFile(sessionID string, data []byte) (string, error) {
+	path, err := restoredPath(sessionID)
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return "", fmt.Errorf("could not create restore directory: %w", err)
+	}
+	return path, os.WriteFile(path, data, 0600)
+}
@@ -0,0 +1,264 @@
+package windsurf
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/re-cinq/shift-log/internal/agent"
+)
+
+func init() {
+	agent.Register(&Agent{})
+}
+
+// Agent implements the agent.Agent interface for Windsurf's Cascade agent.
+//
+// Cascade has no hook API and keeps its conversations in an undocumented
+// binary format, so the integration works on conversations exported as
+// Markdown into the project: the post-commit git hook discovers the most
+// recent export and stores it.
+type Agent struct{}
+
+func (a *Agent) Name() agent.Name    { return agent.Windsurf }
+func (a *Agent) DisplayName() string { return "Windsurf Cascade" }
+
+// ConfigureHooks is a no-op for Windsurf — Cascade has no hook mechanism.
+// Conversation capture relies on the post-commit git hook.
+func (a *Agent) ConfigureHooks(repoRoot string) error {
+	return nil
+}
+
+// RemoveHooks is a no-op for Windsurf — Cascade has no hook mechanism.
+func (a *Agent) RemoveHooks(repoRoot string) error {
+	return nil
+}
+
+// DiagnoseHooks reports whether a Cascade export is waiting to be stored.
+func (a *Agent) DiagnoseHooks(repoRoot string) []agent.DiagnosticCheck {
+	if si := findRecentExport(repoRoot, agent.RecentSessionTimeout); si != nil {
+		return []agent.DiagnosticCheck{{
+			Name:    "Cascade conversation export",
+			OK:      true,
+			Message: fmt.Sprintf("Found recent export %s", si.TranscriptPath),
+		}}
+	}
+	return []agent.DiagnosticCheck{{
+		Name:    "Cascade conversation export",
+		OK:      true,
+		Message: "No recent export. Export the Cascade conversation as Markdown into .windsurf/ before committing.",
+	}}
+}
+
+// ParseHookInput parses hook JSON in the standard format. Cascade has no
+// hooks, so this only serves manual store input.
+func (a *Agent) ParseHookInput(raw []byte) (*agent.HookData, error) {
+	return agent.ParseStandardHookInput(raw)
+}
+
+// IsCommitCommand checks if a tool invocation represents a git commit.
+func (a *Agent) IsCommitCommand(toolName, command string) bool {
+	if toolName != "run_command" {
+		return false
+	}
+	return agent.IsGitCommitCommand(command)
+}
+
+// Section headings of a Cascade export.
+const (
+	userHeading      = "### User Input"
+	assistantHeading = "### Planner Response"
+)
+
+// action maps a Cascade step summary, an italic line such as
+// "*Viewed [main.go](file:///src/main.go) *", to the tool that performed it.
+type action struct {
+	prefix string
+	tool   string
+}
+
+var actions = []action{
+	{"User accepted the command", "run_command"},
+	{"Ran terminal command", "run_command"},
+	{"Viewed", "view_file"},
+	{"Analyzed", "view_code_item"},
+	{"Edited", "edit_file"},
+	{"Listed directory", "list_dir"},
+	{"Searched filesystem", "find_by_name"},
+	{"Grep searched codebase", "grep_search"},
+	{"Searched codebase", "codebase_search"},
+	{"Searched web", "search_web"},
+	{"Read URL", "read_url_content"},
+	{"Read page", "read_url_content"},
+	{"Updated todo list", "todo_list"},
+	{"Running MCP tool", "mcp_tool"},
+}
+
+var (
+	commandPattern = regexp.MustCompile("`([^`]+)`")
+	linkPattern    = regexp.MustCompile(`\[[^\]]*\]\(([^)]+)\)`)
+)
+
+// ParseTranscript parses a Cascade conversation exported as Markdown.
+// Exports carry no timestamps, model, token usage or tool results.
+func (a *Agent) ParseTranscript(r io.Reader) (*agent.Transcript, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []agent.TranscriptEntry
+	var current *agent.TranscriptEntry
+	var text []string
+
+	flushText := func() {
+		if current == nil {
+			text = nil
+			return
+		}
+		if s := strings.TrimSpace(strings.Join(text, "\n")); s != "" {
+			current.Message.Content = append(current.Message.Content, agent.ContentBlock{Type: "text", Text: s})
+		}
+		text = nil
+	}
+	flushEntry := func() {
+		flushText()
+		if current != nil && len(current.Message.Content) > 0 {
+			current.UUID = fmt.Sprintf("windsurf-%d", len(entries))
+			entries = append(entries, *current)
+		}
+		current = nil
+	}
+	start := func(msgType agent.MessageType, role string) {
+		flushEntry()
+		current = &agent.TranscriptEntry{Type: msgType, Message: &agent.Message{Role: role}}
+	}
+
+	inCode := false
+	for _, line := range strings.Split(string(data), "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "```") {
+			inCode = !inCode
+		}
+		switch {
+		case !inCode && trimmed == userHeading:
+			start(agent.MessageTypeUser, "user")
+		case !inCode && trimmed == assistantHeading:
+			start(agent.MessageTypeAssistant, "assistant")
+		case current == nil:
+			// Export heading and note before the first section
+		case !inCode && current.Type == agent.MessageTypeAssistant && isActionLine(trimmed):
+			if block, ok := actionBlock(trimmed, len(entries), len(current.Message.Content)); ok {
+				flushText()
+				current.Message.Content = append(current.Message.Content, block)
+			} else {
+				text = append(text, line)
+			}
+		default:
+			text = append(text, line)
+		}
+	}
+	flushEntry()
+
+	t := &agent.Transcript{Entries: entries}
+	t.Turns = t.CountTurns()
+	return t, nil
+}
+
+// isActionLine reports whether the line is an italic step summary.
+func isActionLine(line string) bool {
+	return len(line) > 2 && strings.HasPrefix(line, "*") && !strings.HasPrefix(line, "**") &&
+		strings.HasSuffix(line, "*") && !strings.HasSuffix(line, "**")
+}
+
+// actionBlock converts a step summary into a tool_use block. The command of
+// a terminal step and the file of a file step become its input.
+func actionBlock(line string, entryIndex, blockIndex int) (agent.ContentBlock, bool) {
+	summary := strings.TrimSpace(strings.Trim(line, "*"))
+	for _, act := range actions {
+		if !strings.HasPrefix(summary, act.prefix) {
+			continue
+		}
+		input := map[string]string{}
+		if act.tool == "run_command" {
+			if m := commandPattern.FindStringSubmatch(summary); m != nil {
+				input["command"] = m[1]
+			}
+		} else if m := linkPattern.FindStringSubmatch(summary); m != nil {
+			input["file_path"] = linkPath(m[1])
+		}
+		raw, _ := json.Marshal(input)
+		return agent.ContentBlock{
+			Type:  "tool_use",
+			ID:    fmt.Sprintf("windsurf-%d-%d", entryIndex, blockIndex),
+			Name:  act.tool,
+			Input: raw,
+		}, true
+	}
+	return agent.ContentBlock{}, false
+}
+
+// linkPath returns the local path of a file:// link target.
+func linkPath(target string) string {
+	u, err := url.Parse(target)
+	if err != nil || u.Scheme != "file" {
+		return target
+	}
+	return u.Path
+}
+
+// ParseTranscriptFile parses a Cascade export file.
+func (a *Agent) ParseTranscriptFile(path string) (*agent.Transcript, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+	return a.ParseTranscript(f)
+}
+
+// DiscoverSession finds the most recent Cascade export of the project.
+func (a *Agent) DiscoverSession(projectPath string) (*agent.SessionInfo, error) {
+	return findRecentExport(projectPath, agent.RecentSessionTimeout), nil
+}
+
+// RestoreSession writes the conversation to the restore directory. Cascade
+// cannot import conversations, so it is opened in Windsurf for reference.
+func (a *Agent) RestoreSession(projectPath, sessionID, gitBranch string,
+	transcriptData []byte, messageCount int, summary string) error {
+
+	_, err := WriteRestoredFile(sessionID, transcriptData)
+	return err
+}
+
+// ResumeCommand returns the command to open a restored conversation in
+// Windsurf.
+func (a *Agent) ResumeCommand(sessionID string) (string, []string) {
+	path, err := restoredPath(sessionID)
+	if err != nil {
+		return "windsurf", nil
+	}
+	return "windsurf", []string{path}
+}
+
+// ToolAliases returns Cascade's tool name mappings to canonical names.
+func (a *Agent) ToolAliases() map[string]string {
+	return map[string]string{
+		"run_command":          "Bash",
+		"view_file":            "Read",
+		"view_code_item":       "Read",
+		"edit_file":            "Edit",
+		"write_to_file":        "Write",
+		"replace_file_content": "Edit",
+		"list_dir":             "Glob",
+		"find_by_name":         "Glob",
+		"grep_search":          "Grep",
+		"search_web":           "WebSearch",
+		"read_url_content":     "WebFetch",
+		"todo_list":            "TodoWrite",
+	}
+}
@@ -0,0 +1,208 @@
+package windsurf
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/re-cinq/shift-log/internal/agent"
+)
+
+const sampleExport = "# Cascade Chat Conversation\n" +
+	"\n" +
+	"  Note: _This is purely the output of the chat conversation and does not contain any raw data, codebase snippets, etc. used to generate the output._\n" +
+	"\n" +
+	"### User Input\n" +
+	"\n" +
+	"Fix the greeting in main.go\n" +
+	"\n" +
+	"### Planner Response\n" +
+	"\n" +
+	"I'll look at the file first.\n" +
+	"\n" +
+	"*Viewed [main.go](file:///home/dev/my%20app/main.go) *\n" +
+	"\n" +
+	"*Edited relevant file*\n" +
+	"\n" +
+	"*User accepted the command `go test ./...`*\n" +
+	"\n" +
+	"### Planner Response\n" +
+	"\n" +
+	"**Done.** The greeting now reads:\n" +
+	"\n" +
+	"```go\n" +
+	"### User Input\n" +
+	"```\n" +
+	"\n" +
+	"### User Input\n" +
+	"\n" +
+	"Commit it\n" +
+	"\n" +
+	"### Planner Response\n" +
+	"\n" +
+	"*User accepted the command `git commit -am \"Fix greeting\"`*\n"
+
+func TestAgentName(t *testing.T) {
+	a := &Agent{}
+	if a.Name() != agent.Windsurf {
+		t.Errorf("Name() = %q, want %q", a.Name(), agent.Windsurf)
+	}
+	if a.DisplayName() != "Windsurf Cascade" {
+		t.Errorf("DisplayName() = %q, want %q", a.DisplayName(), "Windsurf Cascade")
+	}
+}
+
+func TestIsCommitCommand(t *testing.T) {
+	a := &Agent{}
+	if !a.IsCommitCommand("run_command", "git commit -m x") {
+		t.Error("IsCommitCommand(run_command, git commit) = false, want true")
+	}
+	if a.IsCommitCommand("view_file", "git commit -m x") {
+		t.Error("IsCommitCommand(view_file, git commit) = true, want false")
+	}
+}
+
+func TestParseTranscript(t *testing.T) {
+	a := &Agent{}
+	transcript, err := a.ParseTranscript(strings.NewReader(sampleExport))
+	if err != nil {
+		t.Fatalf("ParseTranscript() error: %v", err)
+	}
+
+	if len(transcript.Entries) != 5 {
+		t.Fatalf("got %d entries, want 5", len(transcript.Entries))
+	}
+	if transcript.Turns != 2 {
+		t.Errorf("Turns = %d, want 2", transcript.Turns)
+	}
+
+	user := transcript.Entries[0]
+	if user.Type != agent.MessageTypeUser || user.UUID != "windsurf-0" {
+		t.Errorf("first entry = %s/%s", user.Type, user.UUID)
+	}
+	if got := user.Message.Content[0].Text; got != "Fix the greeting in main.go" {
+		t.Errorf("user text = %q", got)
+	}
+
+	blocks := transcript.Entries[1].Message.Content
+	if len(blocks) != 4 {
+		t.Fatalf("got %d assistant blocks, want 4: %+v", len(blocks), blocks)
+	}
+	if blocks[0].Type != "text" || blocks[0].Text != "I'll look at the file first." {
+		t.Errorf("block 0 = %+v", blocks[0])
+	}
+	if blocks[1].Name != "view_file" || string(blocks[1].Input) != `{"file_path":"/home/dev/my app/main.go"}` {
+		t.Errorf("view block = %s %s", blocks[1].Name, blocks[1].Input)
+	}
+	if blocks[2].Name != "edit_file" {
+		t.Errorf("edit block = %s", blocks[2].Name)
+	}
+	if blocks[3].Name != "run_command" || string(blocks[3].Input) != `{"command":"go test ./..."}` {
+		t.Errorf("command block = %s %s", blocks[3].Name, blocks[3].Input)
+	}
+
+	// Headings inside code blocks and bold text stay part of the response
+	text := transcript.Entries[2].Message.Content[0].Text
+	if !strings.Contains(text, "**Done.**") || !strings.Contains(text, "```go\n### User Input\n```") {
+		t.Errorf("response text = %q", text)
+	}
+
+	commit := transcript.Entries[4].Message.Content[0]
+	if commit.Name != "run_command" || string(commit.Input) != `{"command":"git commit -am \"Fix greeting\""}` {
+		t.Errorf("commit block = %s %s", commit.Name, commit.Input)
+	}
+}
+
+func TestParseTranscriptEmpty(t *testing.T) {
+	a := &Agent{}
+	transcript, err := a.ParseTranscript(strings.NewReader(""))
+	if err != nil {
+		t.Fatalf("ParseTranscript() error: %v", err)
+	}
+	if len(transcript.Entries) != 0 {
+		t.Errorf("got %d entries, want 0", len(transcript.Entries))
+	}
+}
+
+func TestDiscoverSession(t *testing.T) {
+	project := t.TempDir()
+	exportDir := filepath.Join(project, ".windsurf")
+	if err := os.MkdirAll(exportDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	a := &Agent{}
+	if info, _ := a.DiscoverSession(project); info != nil {
+		t.Fatalf("DiscoverSession() = %+v, want nil without exports", info)
+	}
+
+	// Markdown that is not an export is ignored
+	if err := os.WriteFile(filepath.Join(project, "NOTES.md"), []byte("# Notes\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	older := filepath.Join(project, "older.md")
+	if err := os.WriteFile(older, []byte(sampleExport), 0644); err != nil {
+		t.Fatal(err)
+	}
+	past := time.Now().Add(-time.Minute)
+	if err := os.Chtimes(older, past, past); err != nil {
+		t.Fatal(err)
+	}
+	latest := filepath.Join(exportDir, "fix-greeting.md")
+	if err := os.WriteFile(latest, []byte(sampleExport), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := a.DiscoverSession(project)
+	if err != nil || info == nil {
+		t.Fatalf("DiscoverSession() = %v, %v", info, err)
+	}
+	if info.SessionID != "fix-greeting" || info.TranscriptPath != latest {
+		t.Errorf("DiscoverSession() = %s at %s, want fix-greeting at %s", info.SessionID, info.TranscriptPath, latest)
+	}
+
+	stale := time.Now().Add(-2 * agent.RecentSessionTimeout)
+	for _, p := range []string{older, latest} {
+		if err := os.Chtimes(p, stale, stale); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if info, _ := a.DiscoverSession(project); info != nil {
+		t.Errorf("DiscoverSession() = %+v, want nil for stale exports", info)
+	}
+}
+
+func TestRestoreSession(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	a := &Agent{}
+
+	if err := a.RestoreSession("/p", "fix-greeting", "main", []byte(sampleExport), 5, ""); err != nil {
+		t.Fatalf("RestoreSession() error: %v", err)
+	}
+
+	bin, args := a.ResumeCommand("fix-greeting")
+	if bin != "windsurf" || len(args) != 1 {
+		t.Fatalf("ResumeCommand() = %s %v", bin, args)
+	}
+	data, err := os.ReadFile(args[0])
+	if err != nil {
+		t.Fatalf("restored file not found: %v", err)
+	}
+	if string(data) != sampleExport {
+		t.Error("restored file differs from the stored conversation")
+	}
+	if strings.HasPrefix(args[0], "/p") {
+		t.Errorf("restored into the project (%s), where it would be discovered again", args[0])
+	}
+}
+
+func TestToolAliases(t *testing.T) {
+	aliases := (&Agent{}).ToolAliases()
+	for tool, want := range map[string]string{"run_command": "Bash", "view_file": "Read", "edit_file": "Edit"} {
+		if aliases[tool] != want {
+			t.Errorf("ToolAliases()[%q] = %q, want %q", tool, aliases[tool], want)
+		}
+	}
+}
@@ -0,0 +1,239 @@
+// Package anonymize removes what identifies people and machines from
+// conversations, so that conversations of private repositories can be
+// shared: names and email addresses, absolute paths, host and user names,
+// the values of environment variables printed by tools, and session IDs.
+package anonymize
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Placeholders of the anonymized values.
+const (
+	Person   = "Author"
+	Email    = "author@example.com"
+	Username = "user"
+	Hostname = "host"
+	Home     = "~"
+	Removed  = "[removed]"
+)
+
+var (
+	emailPattern = regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`)
+	// homePattern matches the home directories of any user on Linux and
+	// macOS.
+	homePattern = regexp.MustCompile(`/(?:home|Users)/[^/\s"'` + "`" + `]+`)
+	// envPattern matches an environment variable assignment on a line of
+	// its own, as printed by env, export or declare -x.
+	envPattern = regexp.MustCompile(`(?m)^(\s*(?:export\s+|declare -x\s+)?[A-Za-z_][A-Za-z0-9_]*=).*$`)
+)
+
+// Options names what identifies the people and machines of a repository.
+type Options struct {
+	ProjectPaths []string // absolute paths of the repository, rewritten to be repo-relative
+	HomeDir      string
+	People       []string // names of people, e.g. commit authors
+	Emails       []string // email addresses beyond those recognised by their form
+	Username     string
+	Hostname     string
+}
+
+// Anonymizer rewrites text and JSON documents.
+type Anonymizer struct {
+	projectPaths []string
+	homeDir      string
+	words        []*regexp.Regexp // people, user and host names
+	replacements []string         // of words
+	emails       []string
+	sessions     map[string]string
+}
+
+// New returns an Anonymizer for the repository described by opts.
+func New(opts Options) *Anonymizer {
+	a := &Anonymizer{homeDir: strings.TrimSuffix(opts.HomeDir, "/"), sessions: map[string]string{}}
+	for _, p := range opts.ProjectPaths {
+		if p = strings.TrimSuffix(p, "/"); p != "" {
+			a.projectPaths = append(a.projectPaths, p)
+		}
+	}
+	// Nested checkouts must be rewritten before the paths containing them
+	sort.Slice(a.projectPaths, func(i, j int) bool { return len(a.projectPaths[i]) > len(a.projectPaths[j]) })
+
+	addWord := func(word, suffix, replacement string) {
+		// Very short names would replace parts of ordinary words
+		if word = strings.TrimSpace(word); len(word) >= 3 && word != "localhost" {
+			a.words = append(a.words, regexp.MustCompile(`\b`+regexp.QuoteMeta(word+suffix)+`\b`))
+			a.replacements = append(a.replacements, replacement+suffix)
+		}
+	}
+	for _, name := range opts.People {
+		addWord(name, "", Person)
+	}
+	// User names are often ordinary words, like root, so only the user of
+	// prompts and ssh addresses is replaced
+	addWord(opts.Username, "@", Username)
+	addWord(opts.Hostname, "", Hostname)
+	for _, email := range opts.Emails {
+		if email = strings.TrimSpace(email); email != "" {
+			a.emails = append(a.emails, email)
+		}
+	}
+	return a
+}
+
+// SessionID returns a stable hash of a session ID, and replaces the ID in
+// everything anonymized after.
+func (a *Anonymizer) SessionID(id string) string {
+	if id == "" {
+		return ""
+	}
+	if hashed, ok := a.sessions[id]; ok {
+		return hashed
+	}
+	sum := sha256.Sum256([]byte(id))
+	hashed := fmt.Sprintf("session-%x", sum[:6])
+	a.sessions[id] = hashed
+	return hashed
+}
+
+// Text anonymizes text.
+func (a *Anonymizer) Text(s string) string {
+	for id, hashed := range a.sessions {
+		s = strings.ReplaceAll(s, id, hashed)
+	}
+	for _, p := range a.projectPaths {
+		s = replacePath(s, p, ".", true)
+	}
+	if a.homeDir != "" {
+		s = replacePath(s, a.homeDir, Home, false)
+	}
+	s = homePattern.ReplaceAllString(s, Home)
+	for _, email := range a.emails {
+		s = strings.ReplaceAll(s, email, Email)
+	}
+	s = emailPattern.ReplaceAllString(s, Email)
+	for i, word := range a.words {
+		s = word.ReplaceAllLiteralString(s, a.replacements[i])
+	}
+	return s
+}
+
+// Environment replaces the values of the environment variables assigned
+// on lines of s, as tools print them.
+func (a *Anonymizer) Environment(s string) string {
+	return envPattern.ReplaceAllString(s, "${1}"+Removed)
+}
+
+// replacePath rewrites the occurrences of the absolute path p in s. Paths
+// below p become relative to it when relative is set, or start with
+// replacement; p itself becomes replacement. Longer names that start like
+// p, such as p-old, are left alone.
+func replacePath(s, p, replacement string, relative bool) string {
+	var b strings.Builder
+	for {
+		i := strings.Index(s, p)
+		if i < 0 {
+			b.WriteString(s)
+			return b.String()
+		}
+		b.WriteString(s[:i])
+		rest := s[i+len(p):]
+		switch {
+		case strings.HasPrefix(rest, "/") && relative:
+			rest = rest[1:]
+		case strings.HasPrefix(rest, "/"):
+			b.WriteString(replacement)
+		case rest == "" || !isPathChar(rest[0]):
+			b.WriteString(replacement)
+		default:
+			b.WriteString(p)
+		}
+		s = rest
+	}
+}
+
+func isPathChar(c byte) bool {
+	return c == '.' || c == '-' || c == '_' ||
+		('0' <= c && c <= '9') || ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z')
+}
+
+// JSON anonymizes every string of a JSON document, and the environment
+// variables in the content of its tool_result blocks. The document is
+// returned indented.
+func (a *Anonymizer) JSON(data []byte) ([]byte, error) {
+	out, err := Strings(data, func(s string, toolOutput bool) string {
+		s = a.Text(s)
+		if toolOutput {
+			s = a.Environment(s)
+		}
+		return s
+	})
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, out, "", "  "); err != nil {
+		return nil, err
+	}
+	buf.WriteByte('\n')
+	return buf.Bytes(), nil
+}
+
+// Strings rewrites every string of a JSON document with rewrite, which is
+// told whether the string is part of a tool_result block. Object keys and
+// the data of base64 sources, such as images, are kept. The document is
+// returned compact, or unchanged when rewrite changed none of its strings.
+func Strings(data []byte, rewrite func(s string, toolOutput bool) string) ([]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var doc any
+	if err := dec.Decode(&doc); err != nil {
+		return nil, err
+	}
+	w := walker{rewrite: rewrite}
+	doc = w.walk(doc, false)
+	if !w.changed {
+		return data, nil
+	}
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(doc); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+type walker struct {
+	rewrite func(s string, toolOutput bool) string
+	changed bool
+}
+
+func (w *walker) walk(v any, toolOutput bool) any {
+	switch v := v.(type) {
+	case string:
+		s := w.rewrite(v, toolOutput)
+		w.changed = w.changed || s != v
+		return s
+	case []any:
+		for i := range v {
+			v[i] = w.walk(v[i], toolOutput)
+		}
+	case map[string]any:
+		output := toolOutput || v["type"] == "tool_result"
+		for key, value := range v {
+			if key == "data" && v["type"] == "base64" {
+				continue
+			}
+			v[key] = w.walk(value, output && key != "tool_use_id")
+		}
+	}
+	return v
+}
@@ -0,0 +1,108 @@
+package anonymize
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func testAnonymizer() *Anonymizer {
+	return New(Options{
+		ProjectPaths: []string{"/home/alice/src/app"},
+		HomeDir:      "/home/alice",
+		People:       []string{"Alice Liddell", "", "Al"},
+		Emails:       []string{"alice@corp"},
+		Username:     "alice",
+		Hostname:     "wonderland-laptop",
+	})
+}
+
+func TestText(t *testing.T) {
+	a := testAnonymizer()
+	tests := []struct {
+		in, want string
+	}{
+		{"edit /home/alice/src/app/main.go", "edit main.go"},
+		{"cd /home/alice/src/app && ls", "cd . && ls"},
+		{"/home/alice/src/app-old/main.go", "~/src/app-old/main.go"},
+		{"cat /home/alice/.bashrc", "cat ~/.bashrc"},
+		{"ls /Users/bob/Documents", "ls ~/Documents"},
+		{"Author: Alice Liddell <alice@corp>", "Author: Author <author@example.com>"},
+		{"mail bob.smith@example.org", "mail author@example.com"},
+		{"alice@wonderland-laptop:~$ ls", "user@host:~$ ls"},
+		{"the alice in the Algorithm", "the alice in the Algorithm"},
+	}
+	for _, tt := range tests {
+		if got := a.Text(tt.in); got != tt.want {
+			t.Errorf("Text(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestSessionID(t *testing.T) {
+	a := testAnonymizer()
+	hashed := a.SessionID("0f2c6a1e-session")
+	if !strings.HasPrefix(hashed, "session-") || hashed == "session-" {
+		t.Fatalf("SessionID() = %q", hashed)
+	}
+	if again := a.SessionID("0f2c6a1e-session"); again != hashed {
+		t.Errorf("SessionID() is not stable: %q, then %q", hashed, again)
+	}
+	if got := a.Text("resume 0f2c6a1e-session"); got != "resume "+hashed {
+		t.Errorf("Text() = %q, want the session ID replaced", got)
+	}
+	if a.SessionID("") != "" {
+		t.Error("SessionID(\"\") should stay empty")
+	}
+}
+
+func TestJSON(t *testing.T) {
+	a := testAnonymizer()
+	doc := `{
+		"home": "/home/alice/src/app/go.mod",
+		"entries": [
+			{"type": "text", "text": "PATH=/usr/bin"},
+			{"type": "tool_result", "tool_use_id": "toolu_1", "content": "HOME=/home/alice\nexport TOKEN=abc123\nno assignment"},
+			{"type": "tool_result", "content": [{"type": "text", "text": "USER=alice"}]},
+			{"type": "image", "source": {"type": "base64", "data": "/home/alice+="}}
+		],
+		"count": 12345678901234567890
+	}`
+	out, err := a.JSON([]byte(doc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got struct {
+		Home    string `json:"home"`
+		Entries []struct {
+			Text    string          `json:"text"`
+			Content json.RawMessage `json:"content"`
+			Source  struct {
+				Data string `json:"data"`
+			} `json:"source"`
+		} `json:"entries"`
+	}
+	if err := json.Unmarshal(out, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Home != "go.mod" {
+		t.Errorf("home = %q", got.Home)
+	}
+	if got.Entries[0].Text != "PATH=/usr/bin" {
+		t.Errorf("text outside tool results was changed: %q", got.Entries[0].Text)
+	}
+	var content string
+	_ = json.Unmarshal(got.Entries[1].Content, &content)
+	if content != "HOME=[removed]\nexport TOKEN=[removed]\nno assignment" {
+		t.Errorf("tool result = %q", content)
+	}
+	if !strings.Contains(string(got.Entries[2].Content), "USER=[removed]") {
+		t.Errorf("nested tool result = %s", got.Entries[2].Content)
+	}
+	if got.Entries[3].Source.Data != "/home/alice+=" {
+		t.Errorf("base64 data was changed: %q", got.Entries[3].Source.Data)
+	}
+	if !strings.Contains(string(out), "12345678901234567890") {
+		t.Errorf("large number was not kept: %s", out)
+	}
+}
@@ -0,0 +1,143 @@
+// Package audit keeps the log of what shiftlog changed in a repository:
+// which command stored, rewrote, synced or removed what, run by whom and
+// when. The log is .shiftlog/audit.jsonl, one JSON object per line, and is
+// only ever appended to.
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/re-cinq/shift-log/internal/cli"
+	"github.com/re-cinq/shift-log/internal/config"
+)
+
+// logFile is the name of the audit log in the .shiftlog directory.
+const logFile = "audit.jsonl"
+
+// Record is a run of a command that changed something.
+type Record struct {
+	Time string `json:"time"`
+	// User is the git user that ran the command, "name <email>".
+	User    string   `json:"user"`
+	Host    string   `json:"host,omitempty"`
+	Command string   `json:"command"`
+	Args    []string `json:"args,omitempty"`
+	// Hook is set when the command was run by a git or agent hook.
+	Hook bool `json:"hook,omitempty"`
+	// Status is "ok", or "error" when the command failed after changing
+	// something.
+	Status    string         `json:"status"`
+	Error     string         `json:"error,omitempty"`
+	Artifacts []cli.Artifact `json:"artifacts"`
+}
+
+// Path returns the path of the audit log.
+func Path() (string, error) {
+	dir, err := config.Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, logFile), nil
+}
+
+// Append adds a record to the audit log. Nothing is written in
+// repositories without a .shiftlog directory.
+func Append(r Record) error {
+	if exists, err := config.DirExists(); err != nil || !exists {
+		return err
+	}
+	path, err := Path()
+	if err != nil {
+		return err
+	}
+	var line bytes.Buffer
+	enc := json.NewEncoder(&line)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(r); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = f.Close() }()
+	_, err = f.Write(line.Bytes())
+	return err
+}
+
+// Filter selects audit records. Its zero value selects every record.
+type Filter struct {
+	// Since drops the records older than it.
+	Since time.Time
+	// User keeps the records of users whose name or email contains it,
+	// ignoring case.
+	User string
+	// Command keeps the records of the command and its subcommands, e.g.
+	// "sync" or "sync push".
+	Command string
+}
+
+// Match reports whether the filter selects r.
+func (f Filter) Match(r Record) bool {
+	if !f.Since.IsZero() {
+		t, err := time.Parse(time.RFC3339, r.Time)
+		if err != nil || t.Before(f.Since) {
+			return false
+		}
+	}
+	if f.User != "" && !strings.Contains(strings.ToLower(r.User), strings.ToLower(f.User)) {
+		return false
+	}
+	if f.Command != "" {
+		command := strings.TrimPrefix(r.Command, "shiftlog ")
+		if command != f.Command && !strings.HasPrefix(command, f.Command+" ") {
+			return false
+		}
+	}
+	return true
+}
+
+// Read returns the records of the audit log the filter selects, oldest
+// first, and the number of lines that could not be read, such as one cut
+// short by a crash. A missing log has no records.
+func Read(f Filter) ([]Record, int, error) {
+	path, err := Path()
+	if err != nil {
+		return nil, 0, err
+	}
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, 0, nil
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+	defer func() { _ = file.Close() }()
+
+	var records []Record
+	invalid := 0
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(nil, 1<<20)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var r Record
+		if err := json.Unmarshal(line, &r); err != nil {
+			invalid++
+			continue
+		}
+		if f.Match(r) {
+			records = append(records, r)
+		}
+	}
+	return records, invalid, scanner.Err()
+}
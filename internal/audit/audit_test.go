@@ -0,0 +1,86 @@
+package audit
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/re-cinq/shift-log/internal/cli"
+)
+
+func TestFilterMatch(t *testing.T) {
+	r := Record{
+		Time:    "2024-05-01T10:00:00Z",
+		User:    "Ada Lovelace <ada@example.com>",
+		Command: "shiftlog sync push",
+	}
+	tests := []struct {
+		name   string
+		filter Filter
+		want   bool
+	}{
+		{"empty filter", Filter{}, true},
+		{"since before", Filter{Since: time.Date(2024, 4, 30, 0, 0, 0, 0, time.UTC)}, true},
+		{"since after", Filter{Since: time.Date(2024, 5, 2, 0, 0, 0, 0, time.UTC)}, false},
+		{"user name", Filter{User: "ada"}, true},
+		{"user email", Filter{User: "ADA@EXAMPLE"}, true},
+		{"other user", Filter{User: "grace"}, false},
+		{"command", Filter{Command: "sync push"}, true},
+		{"parent command", Filter{Command: "sync"}, true},
+		{"command prefix", Filter{Command: "syn"}, false},
+		{"other command", Filter{Command: "store"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.filter.Match(r); got != tt.want {
+				t.Errorf("Match() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAppendAndRead(t *testing.T) {
+	dir := t.TempDir()
+	t.Chdir(dir)
+
+	// Without a .shiftlog directory nothing is written
+	if err := Append(Record{Command: "shiftlog store"}); err != nil {
+		t.Fatalf("Append() error = %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, ".shiftlog")); !os.IsNotExist(err) {
+		t.Fatalf("Append() created .shiftlog")
+	}
+
+	if err := os.Mkdir(".shiftlog", 0755); err != nil {
+		t.Fatal(err)
+	}
+	for _, command := range []string{"shiftlog store", "shiftlog sync push"} {
+		r := Record{
+			Time:      "2024-05-01T10:00:00Z",
+			Command:   command,
+			Status:    "ok",
+			Artifacts: []cli.Artifact{{Kind: "note", ID: "abc"}},
+		}
+		if err := Append(r); err != nil {
+			t.Fatalf("Append() error = %v", err)
+		}
+	}
+	f, err := os.OpenFile(filepath.Join(dir, ".shiftlog", logFile), os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = f.WriteString(`{"time": "2024-05`)
+	_ = f.Close()
+
+	records, invalid, err := Read(Filter{Command: "sync"})
+	if err != nil {
+		t.Fatalf("Read() error = %v", err)
+	}
+	if invalid != 1 {
+		t.Errorf("Read() invalid = %d, want 1", invalid)
+	}
+	if len(records) != 1 || records[0].Command != "shiftlog sync push" || records[0].Artifacts[0].ID != "abc" {
+		t.Errorf("Read() = %+v", records)
+	}
+}
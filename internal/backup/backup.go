@@ -0,0 +1,231 @@
+// Package backup archives the conversation notes ref and local shiftlog
+// config into a single file, and restores them from it.
+//
+// An archive is a tar file containing:
+//
+//	manifest.json  what the backup contains
+//	notes.bundle   git bundle of the notes ref with its full history
+//	config         .shiftlog/config, when present
+//
+// The archive is compressed according to the file name: .tar.gz or .tgz
+// use gzip, .tar.zst and .tzst zstd, and .tar is left uncompressed.
+package backup
+
+import (
+	"archive/tar"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/re-cinq/shift-log/internal/config"
+	"github.com/re-cinq/shift-log/internal/git"
+)
+
+// FormatVersion is the current version of the backup archive layout.
+const FormatVersion = 1
+
+const (
+	manifestName = "manifest.json"
+	bundleName   = "notes.bundle"
+	configName   = "config"
+)
+
+// Manifest describes the contents of a backup.
+type Manifest struct {
+	Version       int    `json:"version"`
+	CreatedAt     string `json:"created_at"` // RFC3339 UTC
+	NotesRef      string `json:"notes_ref"`
+	NotesCommit   string `json:"notes_commit"`
+	Conversations int    `json:"conversations"`
+	HasConfig     bool   `json:"has_config"`
+}
+
+// Create writes a backup of the notes ref and local config to path.
+func Create(path string) (*Manifest, error) {
+	notesCommit, err := git.GetNotesCommit()
+	if err != nil {
+		return nil, fmt.Errorf("could not read notes ref: %w", err)
+	}
+	if notesCommit == "" {
+		return nil, fmt.Errorf("no conversation notes to back up")
+	}
+
+	notes, err := git.ListAllCommitsWithNotes("")
+	if err != nil {
+		return nil, fmt.Errorf("could not list conversations: %w", err)
+	}
+
+	tmpDir, err := os.MkdirTemp("", "shiftlog-backup-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	bundlePath := filepath.Join(tmpDir, bundleName)
+	if err := git.CreateNotesBundle(bundlePath); err != nil {
+		return nil, fmt.Errorf("could not bundle notes: %w", err)
+	}
+
+	files := map[string]string{bundleName: bundlePath}
+	if configPath, err := config.Path(); err == nil {
+		if _, err := os.Stat(configPath); err == nil {
+			files[configName] = configPath
+		}
+	}
+
+	manifest := &Manifest{
+		Version:       FormatVersion,
+		CreatedAt:     time.Now().UTC().Format(time.RFC3339),
+		NotesRef:      git.NotesRef,
+		NotesCommit:   notesCommit,
+		Conversations: len(notes),
+		HasConfig:     files[configName] != "",
+	}
+
+	if err := writeArchive(path, manifest, files); err != nil {
+		os.Remove(path)
+		return nil, err
+	}
+	return manifest, nil
+}
+
+// Restore replaces the notes ref with the one in the backup at path. The
+// previous notes are kept in git.NotesPreRestoreRef. When restoreConfig is
+// true and the backup includes a config, .shiftlog/config is replaced too.
+func Restore(path string, restoreConfig bool) (*Manifest, error) {
+	tmpDir, err := os.MkdirTemp("", "shiftlog-restore-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	manifest, err := readArchive(path, tmpDir)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := git.RestoreNotesBundle(filepath.Join(tmpDir, bundleName)); err != nil {
+		return nil, fmt.Errorf("could not restore notes: %w", err)
+	}
+
+	if restoreConfig && manifest.HasConfig {
+		data, err := os.ReadFile(filepath.Join(tmpDir, configName))
+		if err != nil {
+			return nil, fmt.Errorf("backup is missing its config: %w", err)
+		}
+		configPath, err := config.Path()
+		if err != nil {
+			return nil, err
+		}
+		if err := os.MkdirAll(filepath.Dir(configPath), 0755); err != nil {
+			return nil, err
+		}
+		if err := os.WriteFile(configPath, data, 0644); err != nil {
+			return nil, fmt.Errorf("could not restore config: %w", err)
+		}
+	}
+	return manifest, nil
+}
+
+func writeArchive(path string, manifest *Manifest, files map[string]string) (err error) {
+	w, err := compressor(path)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if cerr := w.Close(); err == nil {
+			err = cerr
+		}
+	}()
+
+	tw := tar.NewWriter(w)
+	manifestData, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := writeEntry(tw, manifestName, manifestData); err != nil {
+		return err
+	}
+	for _, name := range []string{bundleName, configName} {
+		src, ok := files[name]
+		if !ok {
+			continue
+		}
+		data, err := os.ReadFile(src)
+		if err != nil {
+			return err
+		}
+		if err := writeEntry(tw, name, data); err != nil {
+			return err
+		}
+	}
+	return tw.Close()
+}
+
+func writeEntry(tw *tar.Writer, name string, data []byte) error {
+	hdr := &tar.Header{Name: name, Mode: 0644, Size: int64(len(data)), ModTime: time.Now()}
+	if err := tw.WriteHeader(hdr); err != nil {
+		return err
+	}
+	_, err := tw.Write(data)
+	return err
+}
+
+// readArchive extracts the known entries of the archive at path into dir
+// and returns its manifest.
+func readArchive(path, dir string) (*Manifest, error) {
+	r, err := decompressor(path)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	var manifest *Manifest
+	hasBundle := false
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("could not read backup: %w", err)
+		}
+
+		switch hdr.Name {
+		case manifestName:
+			manifest = &Manifest{}
+			if err := json.NewDecoder(tr).Decode(manifest); err != nil {
+				return nil, fmt.Errorf("invalid backup manifest: %w", err)
+			}
+		case bundleName, configName:
+			if err := extract(tr, filepath.Join(dir, hdr.Name)); err != nil {
+				return nil, err
+			}
+			hasBundle = hasBundle || hdr.Name == bundleName
+		}
+	}
+
+	if manifest == nil || !hasBundle {
+		return nil, fmt.Errorf("%s is not a shiftlog backup", path)
+	}
+	if manifest.Version > FormatVersion {
+		return nil, fmt.Errorf("backup format version %d is newer than supported version %d; upgrade shiftlog", manifest.Version, FormatVersion)
+	}
+	return manifest, nil
+}
+
+func extract(r io.Reader, dest string) error {
+	f, err := os.Create(dest)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
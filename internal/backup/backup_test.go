@@ -0,0 +1,86 @@
+package backup
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestArchiveRoundTrip(t *testing.T) {
+	names := []string{"backup.tar", "backup.tar.gz", "backup.tgz", "backup.tar.zst"}
+
+	src := t.TempDir()
+	bundle := filepath.Join(src, bundleName)
+	cfg := filepath.Join(src, configName)
+	if err := os.WriteFile(bundle, []byte("bundle data"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(cfg, []byte(`{"agent":"claude"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range names {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), name)
+			want := &Manifest{Version: FormatVersion, NotesRef: "refs/notes/shiftlog", NotesCommit: "abc", Conversations: 3, HasConfig: true}
+			if err := writeArchive(path, want, map[string]string{bundleName: bundle, configName: cfg}); err != nil {
+				t.Fatalf("writeArchive: %v", err)
+			}
+
+			dir := t.TempDir()
+			got, err := readArchive(path, dir)
+			if err != nil {
+				t.Fatalf("readArchive: %v", err)
+			}
+			if *got != *want {
+				t.Errorf("manifest = %+v, want %+v", got, want)
+			}
+			for file, content := range map[string]string{bundleName: "bundle data", configName: `{"agent":"claude"}`} {
+				data, err := os.ReadFile(filepath.Join(dir, file))
+				if err != nil || string(data) != content {
+					t.Errorf("%s = %q (%v), want %q", file, data, err, content)
+				}
+			}
+		})
+	}
+}
+
+func TestReadArchiveRejectsOtherFiles(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "other.tar")
+	if err := writeArchive(path, &Manifest{Version: FormatVersion}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := readArchive(path, t.TempDir()); err == nil {
+		t.Error("archive without a notes bundle should be rejected")
+	}
+
+	newer := filepath.Join(t.TempDir(), "newer.tar")
+	bundle := filepath.Join(t.TempDir(), bundleName)
+	if err := os.WriteFile(bundle, []byte("x"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeArchive(newer, &Manifest{Version: FormatVersion + 1}, map[string]string{bundleName: bundle}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := readArchive(newer, t.TempDir()); err == nil {
+		t.Error("archive from a newer format version should be rejected")
+	}
+}
+
+func TestCompression(t *testing.T) {
+	tests := map[string]string{
+		"b.tar.zst": "zstd",
+		"b.tzst":    "zstd",
+		"b.tar.gz":  "gzip",
+		"b.tgz":     "gzip",
+		"b.tar":     "",
+	}
+	for name, want := range tests {
+		if got, err := compression(name); err != nil || got != want {
+			t.Errorf("compression(%q) = %q, %v; want %q", name, got, err, want)
+		}
+	}
+	if _, err := compression("b.zip"); err == nil {
+		t.Error("compression(b.zip) should fail")
+	}
+}
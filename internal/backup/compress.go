@@ -0,0 +1,111 @@
+package backup
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// compression returns the compression for an archive file name:
+// "gzip", "zstd" or "" for none.
+func compression(path string) (string, error) {
+	switch {
+	case strings.HasSuffix(path, ".tar.gz"), strings.HasSuffix(path, ".tgz"):
+		return "gzip", nil
+	case strings.HasSuffix(path, ".tar.zst"), strings.HasSuffix(path, ".tzst"):
+		return "zstd", nil
+	case strings.HasSuffix(path, ".tar"):
+		return "", nil
+	}
+	return "", fmt.Errorf("unsupported backup file name %q: use .tar.zst, .tar.gz or .tar", path)
+}
+
+// compressor opens path for writing with the compression its name implies.
+func compressor(path string) (io.WriteCloser, error) {
+	kind, err := compression(path)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+
+	switch kind {
+	case "gzip":
+		return &chainCloser{WriteCloser: gzip.NewWriter(f), next: f}, nil
+	case "zstd":
+		zw, err := zstd.NewWriter(f)
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+		return &chainCloser{WriteCloser: zw, next: f}, nil
+	}
+	return f, nil
+}
+
+// decompressor opens path for reading, undoing the compression its name implies.
+func decompressor(path string) (io.ReadCloser, error) {
+	kind, err := compression(path)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	switch kind {
+	case "gzip":
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			f.Close()
+			return nil, fmt.Errorf("could not read backup: %w", err)
+		}
+		return &chainReadCloser{Reader: gz, closers: []io.Closer{gz, f}}, nil
+	case "zstd":
+		zr, err := zstd.NewReader(f)
+		if err != nil {
+			f.Close()
+			return nil, fmt.Errorf("could not read backup: %w", err)
+		}
+		return &chainReadCloser{Reader: zr, closers: []io.Closer{zr.IOReadCloser(), f}}, nil
+	}
+	return f, nil
+}
+
+// chainCloser closes the wrapped writer, then the underlying file.
+type chainCloser struct {
+	io.WriteCloser
+	next io.Closer
+}
+
+func (c *chainCloser) Close() error {
+	err := c.WriteCloser.Close()
+	if cerr := c.next.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+type chainReadCloser struct {
+	io.Reader
+	closers []io.Closer
+}
+
+func (c *chainReadCloser) Close() error {
+	var err error
+	for _, closer := range c.closers {
+		if cerr := closer.Close(); err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
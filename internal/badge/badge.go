@@ -0,0 +1,106 @@
+// Package badge renders shields.io-style SVG badges, such as the coverage
+// badge of 'shiftlog badge' and the /badge.svg endpoint of 'shiftlog serve'.
+package badge
+
+import (
+	"fmt"
+	"html"
+	"math"
+
+	"github.com/re-cinq/shift-log/internal/storage"
+)
+
+// DefaultLabel is the label of coverage badges.
+const DefaultLabel = "AI-logged"
+
+// Colors of coverage badges, from shields.io.
+const (
+	colorBrightGreen = "#4c1"
+	colorGreen       = "#97ca00"
+	colorYellow      = "#dfb317"
+	colorOrange      = "#fe7d37"
+	colorRed         = "#e05d44"
+	colorGrey        = "#9f9f9f"
+)
+
+// Coverage renders the badge of a coverage, e.g. "AI-logged | 87% of last
+// 100 commits".
+func Coverage(label string, cov storage.Coverage) []byte {
+	if label == "" {
+		label = DefaultLabel
+	}
+	return SVG(label, CoverageMessage(cov), CoverageColor(cov))
+}
+
+// CoverageMessage describes a coverage for a badge.
+func CoverageMessage(cov storage.Coverage) string {
+	switch {
+	case cov.Commits == 0:
+		return "no commits"
+	case cov.Commits == 1:
+		return fmt.Sprintf("%d%% of 1 commit", cov.Percent())
+	case cov.Limit > 0 && cov.Commits >= cov.Limit:
+		return fmt.Sprintf("%d%% of last %d commits", cov.Percent(), cov.Commits)
+	}
+	return fmt.Sprintf("%d%% of %d commits", cov.Percent(), cov.Commits)
+}
+
+// CoverageColor returns the color of a coverage badge: green for full
+// coverage through red for little.
+func CoverageColor(cov storage.Coverage) string {
+	if cov.Commits == 0 {
+		return colorGrey
+	}
+	switch p := cov.Percent(); {
+	case p >= 95:
+		return colorBrightGreen
+	case p >= 80:
+		return colorGreen
+	case p >= 60:
+		return colorYellow
+	case p >= 40:
+		return colorOrange
+	}
+	return colorRed
+}
+
+// SVG renders a flat badge with a grey label and a message on color.
+func SVG(label, message, color string) []byte {
+	labelWidth := textWidth(label) + 10
+	messageWidth := textWidth(message) + 10
+	width := labelWidth + messageWidth
+	title := html.EscapeString(label + ": " + message)
+	label, message, color = html.EscapeString(label), html.EscapeString(message), html.EscapeString(color)
+
+	return []byte(fmt.Sprintf(`<svg xmlns="http://www.w3.org/2000/svg" width="%[1]d" height="20" role="img" aria-label="%[2]s">`+
+		`<title>%[2]s</title>`+
+		`<linearGradient id="s" x2="0" y2="100%%"><stop offset="0" stop-color="#bbb" stop-opacity=".1"/><stop offset="1" stop-opacity=".1"/></linearGradient>`+
+		`<clipPath id="r"><rect width="%[1]d" height="20" rx="3" fill="#fff"/></clipPath>`+
+		`<g clip-path="url(#r)"><rect width="%[3]d" height="20" fill="#555"/><rect x="%[3]d" width="%[4]d" height="20" fill="%[5]s"/><rect width="%[1]d" height="20" fill="url(#s)"/></g>`+
+		`<g fill="#fff" text-anchor="middle" font-family="Verdana,Geneva,DejaVu Sans,sans-serif" font-size="11">`+
+		`<text x="%[6]d" y="15" fill="#010101" fill-opacity=".3">%[7]s</text><text x="%[6]d" y="14">%[7]s</text>`+
+		`<text x="%[8]d" y="15" fill="#010101" fill-opacity=".3">%[9]s</text><text x="%[8]d" y="14">%[9]s</text>`+
+		`</g></svg>`+"\n",
+		width, title, labelWidth, messageWidth, color,
+		labelWidth/2, label, labelWidth+messageWidth/2, message))
+}
+
+// textWidth estimates the width in pixels of s in 11px Verdana.
+func textWidth(s string) int {
+	w := 0.0
+	for _, r := range s {
+		switch {
+		case r == 'i' || r == 'l' || r == 'j' || r == '.' || r == ',' || r == ':' || r == ';' || r == '\'' || r == '|' || r == '!':
+			w += 3.5
+		case r == ' ' || r == 'f' || r == 't' || r == 'r' || r == 'I' || r == '(' || r == ')' || r == '-':
+			w += 4.5
+		case r == 'm' || r == 'w' || r == 'M' || r == 'W' || r == '%':
+			w += 10.5
+		case r >= 'A' && r <= 'Z':
+			w += 7.5
+		default:
+			w += 7
+		}
+	}
+	return int(math.Ceil(w))
+}
@@ -0,0 +1,62 @@
+package badge
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/re-cinq/shift-log/internal/storage"
+)
+
+func TestCoverageMessage(t *testing.T) {
+	tests := []struct {
+		cov  storage.Coverage
+		want string
+	}{
+		{storage.Coverage{Limit: 100, Commits: 100, WithConversation: 87}, "87% of last 100 commits"},
+		{storage.Coverage{Limit: 100, Commits: 3, WithConversation: 2}, "66% of 3 commits"},
+		{storage.Coverage{Limit: 100, Commits: 1, WithConversation: 1}, "100% of 1 commit"},
+		{storage.Coverage{Commits: 4, WithConversation: 4}, "100% of 4 commits"},
+		{storage.Coverage{Commits: 10, WithConversation: 6, OptedOut: 2}, "75% of 10 commits"},
+		{storage.Coverage{Limit: 100}, "no commits"},
+	}
+	for _, tt := range tests {
+		if got := CoverageMessage(tt.cov); got != tt.want {
+			t.Errorf("CoverageMessage(%+v) = %q, want %q", tt.cov, got, tt.want)
+		}
+	}
+}
+
+func TestCoverageColor(t *testing.T) {
+	tests := []struct {
+		with int
+		want string
+	}{
+		{100, colorBrightGreen},
+		{87, colorGreen},
+		{60, colorYellow},
+		{45, colorOrange},
+		{10, colorRed},
+	}
+	for _, tt := range tests {
+		cov := storage.Coverage{Commits: 100, WithConversation: tt.with}
+		if got := CoverageColor(cov); got != tt.want {
+			t.Errorf("CoverageColor(%d%%) = %q, want %q", tt.with, got, tt.want)
+		}
+	}
+	if got := CoverageColor(storage.Coverage{}); got != colorGrey {
+		t.Errorf("CoverageColor(no commits) = %q, want %q", got, colorGrey)
+	}
+}
+
+func TestSVGEscapes(t *testing.T) {
+	svg := string(SVG("a<b", "x & y", "#4c1"))
+	if !strings.HasPrefix(svg, "<svg ") {
+		t.Fatalf("SVG() does not start with <svg: %q", svg)
+	}
+	if strings.Contains(svg, "a<b") || strings.Contains(svg, "x & y") {
+		t.Errorf("SVG() did not escape its text: %q", svg)
+	}
+	if !strings.Contains(svg, "a&lt;b") || !strings.Contains(svg, "x &amp; y") {
+		t.Errorf("SVG() lost its text: %q", svg)
+	}
+}
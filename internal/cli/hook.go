@@ -16,6 +16,8 @@ func ReadHookInput(v interface{}) error {
 		return err
 	}
 
+	Trace("hook input", "input", string(input))
+
 	if err := json.Unmarshal(input, v); err != nil {
 		LogWarning("failed to parse hook JSON: %v", err)
 		return err
@@ -2,6 +2,7 @@ package cli
 
 import (
 	"fmt"
+	"log/slog"
 	"os"
 	"sync"
 
@@ -13,22 +14,34 @@ var (
 	debugEnabled bool
 )
 
-// LogWarning prints a warning message to stderr with the shiftlog prefix
+// LogWarning prints a warning message to stderr with the shiftlog prefix,
+// or records it in the Result with --output json
 func LogWarning(format string, args ...interface{}) {
+	trace(slog.LevelWarn, fmt.Sprintf(format, args...))
+	if recordLog(true, fmt.Sprintf(format, args...)) {
+		return
+	}
 	fmt.Fprintf(os.Stderr, "shiftlog: warning: "+format+"\n", args...)
 }
 
-// LogInfo prints an info message to stderr with the shiftlog prefix
+// LogInfo prints an info message to stderr with the shiftlog prefix, or
+// records it in the Result with --output json
 func LogInfo(format string, args ...interface{}) {
+	trace(slog.LevelInfo, fmt.Sprintf(format, args...))
+	if recordLog(false, fmt.Sprintf(format, args...)) {
+		return
+	}
 	fmt.Fprintf(os.Stderr, "shiftlog: "+format+"\n", args...)
 }
 
-// LogDebug prints a debug message to stderr if debug logging is enabled
-// in .shiftlog/config. The config is read once per process invocation.
+// LogDebug prints a debug message to stderr, and to the trace log, if debug
+// logging is enabled by --verbose, SHIFTLOG_DEBUG or .shiftlog/config. The
+// config is read once per process invocation.
 func LogDebug(format string, args ...interface{}) {
 	initDebug()
 	if debugEnabled {
 		fmt.Fprintf(os.Stderr, "shiftlog: debug: "+format+"\n", args...)
+		trace(slog.LevelDebug, fmt.Sprintf(format, args...))
 	}
 }
 
@@ -40,6 +53,10 @@ func IsDebugEnabled() bool {
 
 func initDebug() {
 	debugOnce.Do(func() {
+		if envDebug() {
+			debugEnabled = true
+			return
+		}
 		cfg, err := config.Read()
 		if err == nil {
 			debugEnabled = cfg.Debug
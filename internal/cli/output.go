@@ -0,0 +1,166 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+)
+
+// Output formats for the global --output flag.
+const (
+	OutputText = "text"
+	OutputJSON = "json"
+)
+
+// Result is the document a command prints on stdout with --output json,
+// in place of its human-readable output.
+type Result struct {
+	Command string `json:"command"`
+	// Status is "ok", or "error" when the command failed.
+	Status   string   `json:"status"`
+	Error    string   `json:"error,omitempty"`
+	Warnings []string `json:"warnings"`
+	// Messages are the lines the command would have printed, followed by
+	// its info logs.
+	Messages  []string   `json:"messages"`
+	Artifacts []Artifact `json:"artifacts"`
+	// Data holds the output of commands that print JSON themselves, such as
+	// stats and log with --format json.
+	Data json.RawMessage `json:"data,omitempty"`
+}
+
+// Artifact is something a command created, updated or removed: a note, a
+// hook, a file or a ref.
+type Artifact struct {
+	Kind string `json:"kind"`
+	ID   string `json:"id"`
+}
+
+var (
+	outputMu      sync.Mutex
+	result        *Result
+	realStdout    *os.File
+	captureWriter *os.File
+	captureDone   chan struct{}
+	captured      bytes.Buffer
+	artifacts     []Artifact
+)
+
+// IsJSONOutput returns whether the running command reports a Result
+// instead of printing human-readable output.
+func IsJSONOutput() bool {
+	outputMu.Lock()
+	defer outputMu.Unlock()
+	return result != nil
+}
+
+// StartJSONOutput captures everything the command prints on stdout, and its
+// info and warning logs, until FinishJSONOutput reports them as a Result.
+func StartJSONOutput(command string) error {
+	r, w, err := os.Pipe()
+	if err != nil {
+		return fmt.Errorf("could not capture output: %w", err)
+	}
+
+	outputMu.Lock()
+	defer outputMu.Unlock()
+	result = &Result{Command: command, Warnings: []string{}, Messages: []string{}, Artifacts: []Artifact{}}
+	realStdout = os.Stdout
+	captureWriter = w
+	captureDone = make(chan struct{})
+	captured.Reset()
+	os.Stdout = w
+
+	go func() {
+		_, _ = io.Copy(&captured, r)
+		_ = r.Close()
+		close(captureDone)
+	}()
+	return nil
+}
+
+// FinishJSONOutput restores stdout and prints the Result of the command,
+// which failed if cmdErr is non-nil. It returns cmdErr, so that the exit
+// status is unchanged.
+func FinishJSONOutput(cmdErr error) error {
+	outputMu.Lock()
+	res := result
+	outputMu.Unlock()
+	if res == nil {
+		return cmdErr
+	}
+
+	_ = captureWriter.Close()
+	<-captureDone
+	os.Stdout = realStdout
+
+	outputMu.Lock()
+	defer outputMu.Unlock()
+	result = nil
+
+	text := strings.TrimSpace(captured.String())
+	if text != "" && json.Valid([]byte(text)) {
+		res.Data = json.RawMessage(text)
+	} else if text != "" {
+		var lines []string
+		for _, line := range strings.Split(text, "\n") {
+			if strings.TrimSpace(line) != "" {
+				lines = append(lines, line)
+			}
+		}
+		res.Messages = append(lines, res.Messages...)
+	}
+
+	res.Status = "ok"
+	if cmdErr != nil {
+		res.Status = "error"
+		res.Error = cmdErr.Error()
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(res); err != nil {
+		return err
+	}
+	return cmdErr
+}
+
+// RecordArtifact notes something the command created, updated or removed,
+// for the Result and the audit log.
+func RecordArtifact(kind, id string) {
+	outputMu.Lock()
+	defer outputMu.Unlock()
+	artifact := Artifact{Kind: kind, ID: id}
+	artifacts = append(artifacts, artifact)
+	if result != nil {
+		result.Artifacts = append(result.Artifacts, artifact)
+	}
+}
+
+// Artifacts returns what the command recorded with RecordArtifact so far,
+// with any output format.
+func Artifacts() []Artifact {
+	outputMu.Lock()
+	defer outputMu.Unlock()
+	return append([]Artifact(nil), artifacts...)
+}
+
+// recordLog adds a warning or info log to the Result, returning false
+// without --output json.
+func recordLog(warning bool, msg string) bool {
+	outputMu.Lock()
+	defer outputMu.Unlock()
+	if result == nil {
+		return false
+	}
+	if warning {
+		result.Warnings = append(result.Warnings, msg)
+	} else {
+		result.Messages = append(result.Messages, msg)
+	}
+	return true
+}
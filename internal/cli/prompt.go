@@ -0,0 +1,28 @@
+package cli
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Confirm asks a yes/no question and reports whether the user answered yes.
+// The answer is read from the terminal rather than stdin, which git closes
+// for hooks. Without a terminal on stderr, e.g. when a coding agent runs
+// git commit, or with --output json, it returns false without asking.
+func Confirm(question string) bool {
+	if !isTerminal(os.Stderr) || IsJSONOutput() {
+		return false
+	}
+	tty, err := os.Open(ttyPath)
+	if err != nil {
+		return false
+	}
+	defer func() { _ = tty.Close() }()
+
+	fmt.Fprintf(os.Stderr, "%s [y/N] ", question)
+	response, _ := bufio.NewReader(tty).ReadString('\n')
+	response = strings.TrimSpace(strings.ToLower(response))
+	return response == "y" || response == "yes"
+}
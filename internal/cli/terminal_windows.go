@@ -9,6 +9,9 @@ import (
 var kernel32 = syscall.NewLazyDLL("kernel32.dll")
 var procGetConsoleMode = kernel32.NewProc("GetConsoleMode")
 
+// ttyPath is the terminal the user answers prompts on.
+const ttyPath = "CONIN$"
+
 func isTerminal(f *os.File) bool {
 	var mode uint32
 	r, _, _ := procGetConsoleMode.Call(f.Fd(), uintptr(unsafe.Pointer(&mode)))
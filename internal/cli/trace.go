@@ -0,0 +1,137 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/re-cinq/shift-log/internal/config"
+)
+
+// DebugEnv turns on debug logging in every shiftlog process started with it
+// set, including the ones started by hooks, e.g. SHIFTLOG_DEBUG=1.
+const DebugEnv = "SHIFTLOG_DEBUG"
+
+const (
+	logFileName = "shiftlog.log"
+	// maxLogSize is the size past which the log is rotated.
+	maxLogSize = 1 << 20
+	// maxLogFiles is the number of rotated logs kept, shiftlog.log.1 being
+	// the most recent.
+	maxLogFiles = 3
+)
+
+var (
+	traceMu    sync.Mutex
+	traceFile  *os.File
+	tracer     *slog.Logger
+	traceStart time.Time
+)
+
+// SetVerbose turns on debug logging for this process, as --verbose does.
+func SetVerbose() {
+	initDebug()
+	debugEnabled = true
+}
+
+// envDebug reports whether DebugEnv asks for debug logging.
+func envDebug() bool {
+	v := os.Getenv(DebugEnv)
+	return v != "" && v != "0" && v != "false"
+}
+
+// LogPath returns the path of the trace log in .shiftlog/logs.
+func LogPath() (string, error) {
+	dir, err := config.LogsDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, logFileName), nil
+}
+
+// StartTrace opens the trace log and records the invocation of command.
+// Hooks are always traced, so that their failures can be found afterwards;
+// other commands only with debug logging on. Nothing is written in
+// repositories without a .shiftlog directory.
+func StartTrace(command string, args []string, hook bool) {
+	if !hook && !IsDebugEnabled() {
+		return
+	}
+	if exists, err := config.DirExists(); err != nil || !exists {
+		return
+	}
+	path, err := LogPath()
+	if err != nil {
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return
+	}
+	rotateLog(path)
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return
+	}
+
+	traceMu.Lock()
+	traceFile = f
+	tracer = slog.New(slog.NewTextHandler(f, &slog.HandlerOptions{Level: slog.LevelDebug})).
+		With("pid", os.Getpid(), "cmd", command)
+	traceStart = time.Now()
+	traceMu.Unlock()
+
+	cwd, _ := os.Getwd()
+	trace(slog.LevelInfo, "invoked", "args", args, "cwd", cwd, "hook", hook)
+}
+
+// StopTrace records how the command ended and closes the trace log.
+func StopTrace(err error) {
+	traceMu.Lock()
+	defer traceMu.Unlock()
+	if tracer == nil {
+		return
+	}
+	duration := time.Since(traceStart).Round(time.Millisecond)
+	if err != nil {
+		tracer.Error("failed", "error", err.Error(), "duration", duration)
+	} else {
+		tracer.Info("finished", "duration", duration)
+	}
+	_ = traceFile.Close()
+	tracer = nil
+	traceFile = nil
+}
+
+// Trace records a debug event, with key-value attributes, in the trace log
+// only. It does nothing unless debug logging is on.
+func Trace(msg string, attrs ...any) {
+	if !IsDebugEnabled() {
+		return
+	}
+	trace(slog.LevelDebug, msg, attrs...)
+}
+
+func trace(level slog.Level, msg string, attrs ...any) {
+	traceMu.Lock()
+	defer traceMu.Unlock()
+	if tracer != nil {
+		tracer.Log(context.Background(), level, msg, attrs...)
+	}
+}
+
+// rotateLog moves the log aside once it has grown past maxLogSize, dropping
+// the oldest rotated log.
+func rotateLog(path string) {
+	info, err := os.Stat(path)
+	if err != nil || info.Size() < maxLogSize {
+		return
+	}
+	for i := maxLogFiles - 1; i >= 1; i-- {
+		_ = os.Rename(fmt.Sprintf("%s.%d", path, i), fmt.Sprintf("%s.%d", path, i+1))
+	}
+	_ = os.Rename(path, path+".1")
+}
@@ -2,20 +2,213 @@ package config
 
 import (
 	"encoding/json"
+	"fmt"
 	"os"
+	"path"
 	"path/filepath"
+	"regexp"
+	"time"
 
 	"github.com/re-cinq/shift-log/internal/util"
 )
 
 const configFile = "config"
 const shiftlogDir = ".shiftlog"
+const logsDir = "logs"
 
 // Config represents the shiftlog configuration stored in .shiftlog/config
 type Config struct {
 	NotesRef string `json:"notes_ref"`
 	Debug    bool   `json:"debug"`
 	Agent    string `json:"agent,omitempty"` // coding agent name (empty = "claude" for backward compat)
+	// Agents lists every configured coding agent when there are several,
+	// Agent being the first of them.
+	Agents []string `json:"agents,omitempty"`
+	// ExportTimezone is the IANA timezone (e.g. "Europe/Berlin") used when
+	// rendering dates for humans. Empty means the browser's local timezone
+	// in the web viewer, and UTC where shiftlog renders dates itself, as in
+	// HTML snapshots (see ExportLocation).
+	ExportTimezone string `json:"export_timezone,omitempty"`
+	// Summary selects how a short summary is generated when a conversation
+	// is stored: SummaryAgent, SummaryHeuristic, or empty for none.
+	Summary string `json:"summary,omitempty"`
+	// Backend names the storage backend holding conversations. Empty means
+	// git notes, the default.
+	Backend string `json:"backend,omitempty"`
+	// Sign makes store sign each conversation with the user's git signing
+	// key (user.signingkey, gpg.format).
+	Sign bool `json:"sign,omitempty"`
+	// ReadableNotes makes every conversation note written come with a
+	// plain-text description of the commit's conversations in
+	// git.ReadableRef, for git log to show without shiftlog.
+	ReadableNotes bool `json:"readable_notes,omitempty"`
+	// SessionGrace is how long after its last activity a session is still
+	// stored by the post-commit hook, as a Go duration (e.g. "12h"). Empty
+	// means agent.DefaultRecentSessionTimeout.
+	SessionGrace string `json:"session_grace,omitempty"`
+	// CommitSuggestion selects how the prepare-commit-msg hook suggests a
+	// commit message from the active conversation: SummaryAgent,
+	// SummaryHeuristic, or empty for no suggestion.
+	CommitSuggestion string `json:"commit_suggestion,omitempty"`
+	// Thinking is what store does with the thinking of the models in
+	// transcripts: ThinkingStrip drops it, ThinkingHash keeps only its
+	// SHA-256, and empty keeps it.
+	Thinking string `json:"thinking,omitempty"`
+	// AttachmentMaxBytes caps the size of the images and documents kept in
+	// stored transcripts; larger ones are replaced by a placeholder. 0 keeps
+	// every attachment.
+	AttachmentMaxBytes int64 `json:"attachment_max_bytes,omitempty"`
+	// Visibility is the visibility of stored conversations: VisibilityPrivate
+	// keeps them in this clone until they are published, empty shares them.
+	Visibility string `json:"visibility,omitempty"`
+	// PrivateBranches are patterns, as path.Match, of the branches whose
+	// conversations are stored as private, e.g. "spike/*".
+	PrivateBranches []string `json:"private_branches,omitempty"`
+	// Compression selects how stored transcripts are compressed:
+	// CompressionGzip, the default, or CompressionZstd. Transcripts are read
+	// back whichever compression they were stored with.
+	Compression string `json:"compression,omitempty"`
+	// CompressionDictionary is the ID of the zstd dictionary, trained by
+	// shiftlog compression train, that transcripts are compressed with. 0
+	// compresses without a dictionary.
+	CompressionDictionary uint32 `json:"compression_dictionary,omitempty"`
+	// TranscriptBlobThreshold is the compressed size, in bytes, over which a
+	// transcript is stored as a blob apart from its note, keeping the note
+	// small. 0 keeps every transcript in its note.
+	TranscriptBlobThreshold int `json:"transcript_blob_threshold,omitempty"`
+	// TranscriptChunks stores transcripts uncompressed, cut in
+	// content-defined chunks kept as blobs apart from their notes, so that
+	// the transcripts of consecutive commits of a session share the chunks
+	// of their common prefix. It takes precedence over
+	// TranscriptBlobThreshold.
+	TranscriptChunks bool `json:"transcript_chunks,omitempty"`
+	// BlobStore is the URL of an HTTP blob store, such as an S3 bucket
+	// behind a gateway, that sync uploads transcript blobs to instead of
+	// pushing them with the notes. Empty keeps them in git.
+	BlobStore string `json:"blob_store,omitempty"`
+	// ContributorRef is the notes ref, e.g. "refs/notes/shiftlog-alice",
+	// that sync pushes the conversations to in remotes, for contributors who
+	// cannot write to the shared notes ref of the repository they fork. Sync
+	// pulls it along with the shared one. Empty pushes to the shared ref.
+	ContributorRef string `json:"contributor_ref,omitempty"`
+	// ReadRefs are contributor notes refs whose conversations are shown
+	// with the shared ones, e.g. those pulled from forks before they are
+	// merged into the shared notes.
+	ReadRefs []string `json:"read_refs,omitempty"`
+	// GerritURL is the URL of the Gerrit server that shiftlog gerrit
+	// comment posts to, e.g. "https://review.example.com".
+	GerritURL string `json:"gerrit_url,omitempty"`
+	// ViewerURL is the URL where reviewers reach shiftlog serve, which
+	// links to conversations point to. Empty leaves the links out.
+	ViewerURL string `json:"viewer_url,omitempty"`
+	// IssuePattern is the regular expression matching the issue keys of
+	// commit messages recorded with their conversations. Empty means
+	// DefaultIssuePattern.
+	IssuePattern string `json:"issue_pattern,omitempty"`
+	// IssueTracker is the tracker that shiftlog issues --comment posts to:
+	// IssueTrackerJira or IssueTrackerLinear.
+	IssueTracker string `json:"issue_tracker,omitempty"`
+	// IssueTrackerURL is the URL of the tracker, e.g.
+	// "https://example.atlassian.net". Empty means Linear's API for Linear.
+	IssueTrackerURL string `json:"issue_tracker_url,omitempty"`
+}
+
+// DefaultIssuePattern matches Jira and Linear issue keys, such as PROJ-123.
+const DefaultIssuePattern = `\b[A-Z][A-Z0-9_]+-[1-9][0-9]*\b`
+
+// Issue trackers for Config.IssueTracker.
+const (
+	IssueTrackerJira   = "jira"
+	IssueTrackerLinear = "linear"
+)
+
+// VisibilityPrivate is the visibility of conversations kept in the clone
+// that stored them, for Config.Visibility and StoredConversation.Visibility.
+const VisibilityPrivate = "private"
+
+// Thinking policies for Config.Thinking.
+const (
+	ThinkingStrip = "strip"
+	ThinkingHash  = "hash"
+)
+
+// Compression algorithms for Config.Compression.
+const (
+	CompressionGzip = "gzip"
+	CompressionZstd = "zstd"
+)
+
+// Summary modes for Config.Summary and Config.CommitSuggestion.
+const (
+	// SummaryAgent asks the coding agent to summarise, falling back to
+	// SummaryHeuristic if it cannot.
+	SummaryAgent = "agent"
+	// SummaryHeuristic builds the summary locally from the transcript.
+	SummaryHeuristic = "heuristic"
+)
+
+// ExportLocation returns the timezone shiftlog renders dates in itself: the
+// configured export timezone, or UTC when unset. The web viewer renders
+// them in the browser's local timezone instead when unset.
+func (c *Config) ExportLocation() (*time.Location, error) {
+	if c.ExportTimezone == "" {
+		return time.UTC, nil
+	}
+	loc, err := time.LoadLocation(c.ExportTimezone)
+	if err != nil {
+		return nil, fmt.Errorf("invalid export_timezone %q: %w", c.ExportTimezone, err)
+	}
+	return loc, nil
+}
+
+// AgentNames returns the names of the configured coding agents, the first
+// being the default one.
+func (c *Config) AgentNames() []string {
+	if len(c.Agents) > 0 {
+		return c.Agents
+	}
+	if c.Agent != "" {
+		return []string{c.Agent}
+	}
+	return []string{"claude"}
+}
+
+// GraceWindow returns the configured session grace window, or 0 when unset.
+func (c *Config) GraceWindow() (time.Duration, error) {
+	if c.SessionGrace == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(c.SessionGrace)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid session_grace %q: want a duration like \"12h\"", c.SessionGrace)
+	}
+	return d, nil
+}
+
+// IssueRegexp returns the compiled issue_pattern, or DefaultIssuePattern
+// when unset.
+func (c *Config) IssueRegexp() (*regexp.Regexp, error) {
+	if c.IssuePattern == "" {
+		return regexp.MustCompile(DefaultIssuePattern), nil
+	}
+	re, err := regexp.Compile(c.IssuePattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid issue_pattern %q: %w", c.IssuePattern, err)
+	}
+	return re, nil
+}
+
+// IsPrivate reports whether conversations stored on the branch are private.
+func (c *Config) IsPrivate(branch string) bool {
+	if c.Visibility == VisibilityPrivate {
+		return true
+	}
+	for _, pattern := range c.PrivateBranches {
+		if ok, _ := path.Match(pattern, branch); ok {
+			return true
+		}
+	}
+	return false
 }
 
 // Read reads the config from .shiftlog/config in the project root.
@@ -70,6 +263,24 @@ func Path() (string, error) {
 	return filepath.Join(root, shiftlogDir, configFile), nil
 }
 
+// Dir returns the absolute path to the .shiftlog directory.
+func Dir() (string, error) {
+	root, err := util.GetProjectRoot()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(root, shiftlogDir), nil
+}
+
+// LogsDir returns the absolute path to the .shiftlog/logs directory.
+func LogsDir() (string, error) {
+	root, err := util.GetProjectRoot()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(root, shiftlogDir, logsDir), nil
+}
+
 // DirExists returns true if the .shiftlog directory exists in the project root.
 func DirExists() (bool, error) {
 	root, err := util.GetProjectRoot()
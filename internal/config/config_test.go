@@ -4,6 +4,7 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 )
 
 func TestReadWrite(t *testing.T) {
@@ -91,3 +92,69 @@ func TestDirExists(t *testing.T) {
 		t.Error("DirExists = false after creating .shiftlog")
 	}
 }
+
+func TestExportLocation(t *testing.T) {
+	loc, err := (&Config{}).ExportLocation()
+	if err != nil || loc.String() != "UTC" {
+		t.Errorf("empty ExportTimezone = (%v, %v), want UTC", loc, err)
+	}
+
+	loc, err = (&Config{ExportTimezone: "America/New_York"}).ExportLocation()
+	if err != nil || loc.String() != "America/New_York" {
+		t.Errorf("ExportLocation() = (%v, %v), want America/New_York", loc, err)
+	}
+
+	if _, err := (&Config{ExportTimezone: "Mars/Olympus"}).ExportLocation(); err == nil {
+		t.Error("invalid timezone should return an error")
+	}
+}
+
+func TestGraceWindow(t *testing.T) {
+	if d, err := (&Config{}).GraceWindow(); err != nil || d != 0 {
+		t.Errorf("empty SessionGrace = (%v, %v), want 0", d, err)
+	}
+
+	if d, err := (&Config{SessionGrace: "12h"}).GraceWindow(); err != nil || d != 12*time.Hour {
+		t.Errorf("GraceWindow() = (%v, %v), want 12h", d, err)
+	}
+
+	for _, bad := range []string{"tomorrow", "-1h"} {
+		if _, err := (&Config{SessionGrace: bad}).GraceWindow(); err == nil {
+			t.Errorf("SessionGrace %q should return an error", bad)
+		}
+	}
+}
+
+func TestIssueRegexp(t *testing.T) {
+	re, err := (&Config{}).IssueRegexp()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := re.FindAllString("PROJ-12: fix login (see ENG-7, utf-8, A-1)", -1); len(got) != 2 || got[0] != "PROJ-12" || got[1] != "ENG-7" {
+		t.Errorf("default pattern found %q", got)
+	}
+
+	re, err = (&Config{IssuePattern: `#[0-9]+`}).IssueRegexp()
+	if err != nil || !re.MatchString("fixes #42") {
+		t.Errorf("IssueRegexp() = (%v, %v)", re, err)
+	}
+	if _, err := (&Config{IssuePattern: "("}).IssueRegexp(); err == nil {
+		t.Error("an invalid pattern should return an error")
+	}
+}
+
+func TestIsPrivate(t *testing.T) {
+	if (&Config{}).IsPrivate("main") {
+		t.Error("empty config should share conversations")
+	}
+	if !(&Config{Visibility: VisibilityPrivate}).IsPrivate("main") {
+		t.Error("visibility private should keep every conversation private")
+	}
+
+	cfg := &Config{PrivateBranches: []string{"spike/*", "wip"}}
+	for branch, want := range map[string]bool{"spike/auth": true, "wip": true, "main": false, "spike": false} {
+		if got := cfg.IsPrivate(branch); got != want {
+			t.Errorf("IsPrivate(%q) = %v, want %v", branch, got, want)
+		}
+	}
+}
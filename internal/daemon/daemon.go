@@ -0,0 +1,381 @@
+// Package daemon is the long-lived process of 'shiftlog daemon', which
+// keeps the index of a worktree's conversations in memory and answers the
+// queries of editor plugins and CLI commands over a unix socket, sparing
+// each of them loading the index and reading notes cold.
+//
+// The socket carries JSON-RPC 1.0 calls of the methods of Service, named
+// "Shiftlog.<Method>", as net/rpc/jsonrpc encodes them, so that plugins in
+// any language can query it, e.g.
+//
+//	{"id": 1, "method": "Shiftlog.Context", "params": [{"file": "main.go", "line": 42}]}
+//
+// which is answered with the editor.LineContext of the context API. CLI
+// commands check the daemon's ProtocolVersion and shiftlog version with
+// Hello when they connect, and fall back to reading the notes themselves
+// when it differs or the daemon does not answer.
+package daemon
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"net/rpc"
+	"net/rpc/jsonrpc"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/re-cinq/shift-log/editor"
+	"github.com/re-cinq/shift-log/internal/cli"
+	"github.com/re-cinq/shift-log/internal/git"
+	"github.com/re-cinq/shift-log/internal/storage"
+	"github.com/re-cinq/shift-log/internal/web"
+)
+
+// ProtocolVersion is the version of the methods of Service. It changes
+// whenever one of them or their arguments do.
+const ProtocolVersion = 1
+
+// DisableEnv, when set to a non-empty value, keeps CLI commands from using
+// a running daemon.
+const DisableEnv = "SHIFTLOG_NO_DAEMON"
+
+// socketFile is the name of the socket in the worktree's git dir.
+const socketFile = "shiftlog-daemon.sock"
+
+// maxSocketPath is the longest socket path used; longer ones, which unix
+// sockets do not support everywhere, are replaced by one in the temporary
+// directory.
+const maxSocketPath = 100
+
+// dialTimeout bounds how long a command waits for the daemon before reading
+// the notes itself.
+const dialTimeout = 200 * time.Millisecond
+
+// serviceName is the name Service is registered under.
+const serviceName = "Shiftlog"
+
+// ErrRunning is returned by Serve when another daemon already answers on
+// the socket.
+var ErrRunning = errors.New("a daemon is already running")
+
+// SocketPath returns the path of the daemon's socket for the current
+// worktree. Each worktree has its own daemon, as queries such as search
+// depend on its HEAD.
+func SocketPath() (string, error) {
+	gitDir, err := git.GetGitDir()
+	if err != nil {
+		return "", err
+	}
+	path := filepath.Join(gitDir, socketFile)
+	if len(path) <= maxSocketPath {
+		return path, nil
+	}
+	sum := sha256.Sum256([]byte(gitDir))
+	return filepath.Join(os.TempDir(), "shiftlog-"+hex.EncodeToString(sum[:6])+".sock"), nil
+}
+
+// Hello describes a running daemon.
+type Hello struct {
+	Protocol int       `json:"protocol"`
+	Version  string    `json:"version"` // shiftlog version of the daemon
+	PID      int       `json:"pid"`
+	Started  time.Time `json:"started"`
+	Socket   string    `json:"socket"`
+}
+
+// CommitsArgs are the arguments of Service.Attribution.
+type CommitsArgs struct {
+	Commits []string `json:"commits"`
+}
+
+// ContextArgs are the arguments of Service.Context.
+type ContextArgs struct {
+	File string `json:"file"`
+	Line int    `json:"line"`
+}
+
+// Service is the RPC service of the daemon.
+type Service struct {
+	hello   Hello
+	repoDir string
+	stop    func()
+	seen    func()
+}
+
+// Hello returns the daemon's description.
+func (s *Service) Hello(_ struct{}, reply *Hello) error {
+	s.seen()
+	*reply = s.hello
+	return nil
+}
+
+// Conversations are the conversations of a commit with their transcripts,
+// and the contributor ref each was read from, which notes do not record.
+type Conversations struct {
+	Conversations   []*storage.StoredConversation `json:"conversations"`
+	ContributorRefs []string                      `json:"contributor_refs"`
+}
+
+// Conversations returns the conversations of a commit with their
+// transcripts, as storage.GetConversationsWithPrivate.
+func (s *Service) Conversations(commit string, reply *Conversations) error {
+	s.seen()
+	conversations, err := storage.GetConversationsWithPrivate(commit)
+	if err != nil {
+		return err
+	}
+	reply.Conversations = conversations
+	reply.ContributorRefs = make([]string, len(conversations))
+	for i, sc := range conversations {
+		reply.ContributorRefs[i] = sc.ContributorRef
+	}
+	return nil
+}
+
+// Attribution returns the first shared conversation of each of
+// args.Commits that has one, without its transcript: the one blame
+// attributes the commit's lines to.
+func (s *Service) Attribution(args CommitsArgs, reply *map[string]*storage.StoredConversation) error {
+	s.seen()
+	ix, err := storage.OpenIndex(context.Background())
+	if err != nil {
+		return err
+	}
+	conversations := make(map[string]*storage.StoredConversation)
+	for _, sha := range args.Commits {
+		if indexed := ix.Shared.Commits[sha]; len(indexed) > 0 {
+			conversations[sha] = indexed[0].Conversation
+		}
+	}
+	*reply = conversations
+	return nil
+}
+
+// Context returns the conversation behind a line of a file, as the
+// context API of 'shiftlog serve' does.
+func (s *Service) Context(args ContextArgs, reply *editor.LineContext) error {
+	s.seen()
+	lc, err := web.LineContext(s.repoDir, args.File, args.Line)
+	if err != nil {
+		return err
+	}
+	*reply = *lc
+	return nil
+}
+
+// Search runs storage.Search.
+func (s *Service) Search(params storage.SearchParams, reply *[]storage.SearchResult) error {
+	s.seen()
+	results, err := storage.Search(context.Background(), &params)
+	*reply = results
+	return err
+}
+
+// Stop stops the daemon once the call is answered.
+func (s *Service) Stop(_ struct{}, _ *struct{}) error {
+	go s.stop()
+	return nil
+}
+
+// Options configure Serve.
+type Options struct {
+	// Socket is the path of the socket to listen on.
+	Socket string
+	// RepoDir is the root of the worktree, or the git dir of a bare
+	// repository, whose lines Context blames.
+	RepoDir string
+	// Version is the shiftlog version reported by Hello.
+	Version string
+	// IdleTimeout, when positive, stops the daemon once no call came for
+	// that long.
+	IdleTimeout time.Duration
+}
+
+// Serve runs the daemon until ctx is done, Stop is called or it has been
+// idle for opts.IdleTimeout.
+func Serve(ctx context.Context, opts Options) error {
+	path, idleTimeout := opts.Socket, opts.IdleTimeout
+	if c, err := net.DialTimeout("unix", path, dialTimeout); err == nil {
+		_ = c.Close()
+		return ErrRunning
+	}
+	// A socket left by a daemon that did not stop cleanly
+	_ = os.Remove(path)
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return fmt.Errorf("could not listen on %s: %w", path, err)
+	}
+	defer func() { _ = os.Remove(path) }()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	activity := make(chan struct{}, 1)
+	service := &Service{
+		hello:   Hello{Protocol: ProtocolVersion, Version: opts.Version, PID: os.Getpid(), Started: time.Now(), Socket: path},
+		repoDir: opts.RepoDir,
+		stop:    cancel,
+		seen: func() {
+			select {
+			case activity <- struct{}{}:
+			default:
+			}
+		},
+	}
+	server := rpc.NewServer()
+	if err := server.RegisterName(serviceName, service); err != nil {
+		_ = listener.Close()
+		return err
+	}
+
+	// Load the index before the first query needs it
+	if _, err := storage.OpenIndex(ctx); err != nil {
+		cli.LogWarning("daemon: could not load the index: %v", err)
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				server.ServeCodec(jsonrpc.NewServerCodec(conn))
+			}()
+		}
+	}()
+
+	var idle <-chan time.Time
+	var timer *time.Timer
+	if idleTimeout > 0 {
+		timer = time.NewTimer(idleTimeout)
+		defer timer.Stop()
+		idle = timer.C
+	}
+loop:
+	for {
+		select {
+		case <-ctx.Done():
+			break loop
+		case <-activity:
+			if timer != nil {
+				if !timer.Stop() {
+					<-timer.C
+				}
+				timer.Reset(idleTimeout)
+			}
+		case <-idle:
+			cli.LogDebug("daemon: idle for %s, stopping", idleTimeout)
+			break loop
+		}
+	}
+	// Closing the listener ends the accept loop; open connections end when
+	// their clients close them, or with the process
+	_ = listener.Close()
+	return nil
+}
+
+// Client is a connection to a running daemon.
+type Client struct {
+	rpc   *rpc.Client
+	Hello Hello
+}
+
+// Connect returns a connection to the daemon of the current worktree, or
+// nil when none answers, it runs another protocol or shiftlog version than
+// version, or DisableEnv is set.
+func Connect(version string) *Client {
+	if os.Getenv(DisableEnv) != "" {
+		return nil
+	}
+	path, err := SocketPath()
+	if err != nil {
+		return nil
+	}
+	if _, err := os.Stat(path); err != nil {
+		return nil
+	}
+	c, err := Dial(path)
+	if err != nil {
+		cli.LogDebug("daemon: %v", err)
+		return nil
+	}
+	if c.Hello.Protocol != ProtocolVersion || c.Hello.Version != version {
+		cli.LogDebug("daemon: ignoring the daemon of shiftlog %s (protocol %d)", c.Hello.Version, c.Hello.Protocol)
+		_ = c.Close()
+		return nil
+	}
+	return c
+}
+
+// Dial connects to the daemon listening at path, whatever its version.
+func Dial(path string) (*Client, error) {
+	conn, err := net.DialTimeout("unix", path, dialTimeout)
+	if err != nil {
+		return nil, err
+	}
+	c := &Client{rpc: jsonrpc.NewClient(conn)}
+	if err := c.rpc.Call(serviceName+".Hello", struct{}{}, &c.Hello); err != nil {
+		_ = c.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+// Close closes the connection.
+func (c *Client) Close() error {
+	return c.rpc.Close()
+}
+
+// Conversations returns the conversations of a commit with their
+// transcripts, as storage.GetConversationsWithPrivate.
+func (c *Client) Conversations(commit string) ([]*storage.StoredConversation, error) {
+	var reply Conversations
+	if err := c.rpc.Call(serviceName+".Conversations", commit, &reply); err != nil {
+		return nil, err
+	}
+	for i, sc := range reply.Conversations {
+		if i < len(reply.ContributorRefs) {
+			sc.ContributorRef = reply.ContributorRefs[i]
+		}
+	}
+	return reply.Conversations, nil
+}
+
+// Attribution returns the first shared conversation of those of commits
+// that have one, without its transcript.
+func (c *Client) Attribution(commits []string) (map[string]*storage.StoredConversation, error) {
+	var conversations map[string]*storage.StoredConversation
+	err := c.rpc.Call(serviceName+".Attribution", CommitsArgs{Commits: commits}, &conversations)
+	return conversations, err
+}
+
+// Context returns the conversation behind a line of file.
+func (c *Client) Context(file string, line int) (*editor.LineContext, error) {
+	var lc editor.LineContext
+	if err := c.rpc.Call(serviceName+".Context", ContextArgs{File: file, Line: line}, &lc); err != nil {
+		return nil, err
+	}
+	return &lc, nil
+}
+
+// Search runs storage.Search in the daemon.
+func (c *Client) Search(params *storage.SearchParams) ([]storage.SearchResult, error) {
+	var results []storage.SearchResult
+	err := c.rpc.Call(serviceName+".Search", *params, &results)
+	return results, err
+}
+
+// Stop stops the daemon.
+func (c *Client) Stop() error {
+	return c.rpc.Call(serviceName+".Stop", struct{}{}, &struct{}{})
+}
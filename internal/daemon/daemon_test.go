@@ -0,0 +1,145 @@
+package daemon
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/re-cinq/shift-log/internal/git"
+	"github.com/re-cinq/shift-log/internal/storage"
+)
+
+// chdirScratchRepo changes to a new git repository for the rest of t, and
+// returns a function running git in it.
+func chdirScratchRepo(t *testing.T) (string, func(args ...string)) {
+	dir := t.TempDir()
+	t.Chdir(dir)
+	run := func(args ...string) {
+		t.Helper()
+		if out, err := exec.Command("git", args...).CombinedOutput(); err != nil {
+			t.Fatalf("git %s: %v: %s", args[0], err, out)
+		}
+	}
+	run("init", "-q")
+	run("config", "user.name", "Test")
+	run("config", "user.email", "test@example.com")
+	return dir, run
+}
+
+func TestServe(t *testing.T) {
+	dir, run := chdirScratchRepo(t)
+	if err := os.WriteFile(filepath.Join(dir, "a.go"), []byte("one\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	run("add", "a.go")
+	run("commit", "-q", "-m", "Add a.go")
+	sha, err := git.GetHeadCommit()
+	if err != nil {
+		t.Fatal(err)
+	}
+	transcript := `{"type":"user","uuid":"u1","timestamp":"2026-01-01T00:00:00Z","message":{"role":"user","content":"fix the retry logic"}}` + "\n"
+	sc, err := storage.NewStoredConversation("session-1", dir, "main", 1, []byte(transcript))
+	if err != nil {
+		t.Fatal(err)
+	}
+	content, err := storage.MarshalStoredConversations([]*storage.StoredConversation{sc})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := git.AddNote(sha, content); err != nil {
+		t.Fatal(err)
+	}
+
+	path, err := SocketPath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c := Connect("test"); c != nil {
+		t.Fatal("Connect() found a daemon before one was started")
+	}
+	done := make(chan error, 1)
+	go func() {
+		done <- Serve(context.Background(), Options{Socket: path, RepoDir: dir, Version: "test"})
+	}()
+	var c *Client
+	for deadline := time.Now().Add(5 * time.Second); c == nil && time.Now().Before(deadline); {
+		time.Sleep(20 * time.Millisecond)
+		c = Connect("test")
+	}
+	if c == nil {
+		t.Fatal("the daemon did not answer")
+	}
+	defer func() { _ = c.Close() }()
+	if c.Hello.PID != os.Getpid() || c.Hello.Protocol != ProtocolVersion {
+		t.Errorf("Hello = %+v", c.Hello)
+	}
+	if other := Connect("other"); other != nil {
+		t.Error("Connect() used the daemon of another version")
+	}
+	if err := Serve(context.Background(), Options{Socket: path, Version: "test"}); !errors.Is(err, ErrRunning) {
+		t.Errorf("a second Serve() = %v, want ErrRunning", err)
+	}
+
+	conversations, err := c.Conversations(sha)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(conversations) != 1 || conversations[0].SessionID != "session-1" || conversations[0].Transcript == "" {
+		t.Errorf("Conversations() = %+v", conversations)
+	}
+	attributed, err := c.Attribution([]string{sha, "0000000000000000000000000000000000000000"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(attributed) != 1 || attributed[sha].SessionID != "session-1" {
+		t.Errorf("Attribution() = %+v", attributed)
+	}
+	results, err := c.Search(&storage.SearchParams{Query: "retry"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || results[0].CommitSHA != sha {
+		t.Errorf("Search() = %+v", results)
+	}
+	lc, err := c.Context("a.go", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lc.Commit != sha || len(lc.Conversations) != 1 {
+		t.Errorf("Context() = %+v", lc)
+	}
+	if _, err := c.Context("a.go", 0); err == nil {
+		t.Error("Context() of line 0 did not fail")
+	}
+
+	if err := c.Stop(); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the daemon did not stop")
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Error("the socket was not removed")
+	}
+}
+
+func TestServeIdleTimeout(t *testing.T) {
+	chdirScratchRepo(t)
+	path := filepath.Join(t.TempDir(), "d.sock")
+	start := time.Now()
+	if err := Serve(context.Background(), Options{Socket: path, IdleTimeout: 100 * time.Millisecond}); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("the idle daemon ran for %s", elapsed)
+	}
+}
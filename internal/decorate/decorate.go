@@ -0,0 +1,105 @@
+// Package decorate marks the commits with a stored conversation in the
+// output of git log, for shiftlog git-log-format.
+package decorate
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// Marker is shown before the message count of commits with a conversation.
+const Marker = "✦"
+
+// minCountWidth is the narrowest the message count column gets, so that
+// lines keep their alignment up to 999 messages.
+const minCountWidth = 3
+
+const (
+	colorMarker = "\033[35m"
+	colorReset  = "\033[m"
+)
+
+// shaPattern matches the commit SHA at the start of a line of git log
+// output: after the graph drawn by --graph and the "commit " of the default
+// format, possibly wrapped in color codes.
+var shaPattern = regexp.MustCompile(`^((?:\x1b\[[0-9;]*m|[*|/\\_ .-])*(?:commit )?(?:\x1b\[[0-9;]*m)*)([0-9a-f]{7,40})((?:\x1b\[[0-9;]*m)*)(?:[^0-9A-Za-z_]|$)`)
+
+var ansiPattern = regexp.MustCompile(`\x1b\[[0-9;]*m`)
+
+// Decorator adds a column to git log lines: the marker and message count
+// for commits with a conversation, blank for the others.
+type Decorator struct {
+	shas   []string // sorted, for looking up abbreviated SHAs
+	counts map[string]int
+}
+
+// New returns a Decorator for the commits in counts, which maps full commit
+// SHAs to their number of messages.
+func New(counts map[string]int) *Decorator {
+	d := &Decorator{counts: counts}
+	for sha := range counts {
+		d.shas = append(d.shas, sha)
+	}
+	sort.Strings(d.shas)
+	return d
+}
+
+// lookup returns the message count of the commit sha abbreviates, if it
+// has a conversation and is the only such commit it abbreviates.
+func (d *Decorator) lookup(abbrev string) (int, bool) {
+	i := sort.SearchStrings(d.shas, abbrev)
+	if i == len(d.shas) || !strings.HasPrefix(d.shas[i], abbrev) {
+		return 0, false
+	}
+	if i+1 < len(d.shas) && strings.HasPrefix(d.shas[i+1], abbrev) {
+		return 0, false
+	}
+	return d.counts[d.shas[i]], true
+}
+
+// Line decorates a line of git log output. Lines that do not start with a
+// commit SHA, such as commit message bodies, are returned unchanged. The
+// marker is colored when the line is.
+func (d *Decorator) Line(line string) string {
+	m := shaPattern.FindStringSubmatchIndex(line)
+	// The graph separates its columns with single spaces; runs of spaces
+	// indent a commit message that merely starts with a hex word
+	if m == nil || strings.Contains(ansiPattern.ReplaceAllString(line[m[2]:m[3]], ""), "  ") {
+		return line
+	}
+	end := m[7] // after the SHA and the color codes closing it
+
+	count, ok := d.lookup(line[m[4]:m[5]])
+	if !ok {
+		if end == len(line) {
+			return line
+		}
+		return line[:end] + strings.Repeat(" ", utf8.RuneCountInString(Marker)+2+minCountWidth) + line[end:]
+	}
+	column := fmt.Sprintf("%s %*d", Marker, minCountWidth, count)
+	if strings.Contains(line, "\x1b[") {
+		column = colorMarker + column + colorReset
+	}
+	return line[:end] + " " + column + line[end:]
+}
+
+// Copy decorates the git log output read from r, writing it to w.
+func (d *Decorator) Copy(w io.Writer, r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	out := bufio.NewWriter(w)
+	for scanner.Scan() {
+		if _, err := out.WriteString(d.Line(scanner.Text()) + "\n"); err != nil {
+			return err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	return out.Flush()
+}
@@ -0,0 +1,62 @@
+package decorate
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+const (
+	noted  = "a1b2c3d4e5f60718293a4b5c6d7e8f9012345678"
+	noted2 = "a1b2c3d9999999999999999999999999999999aa"
+	plain  = "0123456789abcdef0123456789abcdef01234567"
+)
+
+func TestLine(t *testing.T) {
+	d := New(map[string]int{noted: 12, "ffff000011112222333344445555666677778888": 1400})
+
+	tests := []struct {
+		name, line, want string
+	}{
+		{"oneline", "a1b2c3d Fix parser", "a1b2c3d ✦  12 Fix parser"},
+		{"without conversation", "0123456 Add tests", "0123456       Add tests"},
+		{"wide count", "ffff000 Big session", "ffff000 ✦ 1400 Big session"},
+		{"graph", "| * a1b2c3d (HEAD -> main) Fix parser", "| * a1b2c3d ✦  12 (HEAD -> main) Fix parser"},
+		{"default format", "commit " + noted, "commit " + noted + " ✦  12"},
+		{"default format without conversation", "commit " + plain, "commit " + plain},
+		{"author line", "Author: Jane <jane@example.com>", "Author: Jane <jane@example.com>"},
+		{"indented message", "    deadbeef is a hex word", "    deadbeef is a hex word"},
+		{"short hex word", "cafe is not a sha", "cafe is not a sha"},
+		{
+			"colored",
+			"\x1b[33ma1b2c3d\x1b[m Fix parser",
+			"\x1b[33ma1b2c3d\x1b[m " + colorMarker + "✦  12" + colorReset + " Fix parser",
+		},
+	}
+	for _, tt := range tests {
+		if got := d.Line(tt.line); got != tt.want {
+			t.Errorf("%s: Line(%q) = %q, want %q", tt.name, tt.line, got, tt.want)
+		}
+	}
+}
+
+func TestLineAmbiguousAbbreviation(t *testing.T) {
+	d := New(map[string]int{noted: 12, noted2: 3})
+	if got := d.Line("a1b2c3d Fix parser"); strings.Contains(got, Marker) {
+		t.Errorf("ambiguous abbreviation was decorated: %q", got)
+	}
+	if got := d.Line("a1b2c3d4 Fix parser"); !strings.Contains(got, "✦  12") {
+		t.Errorf("longer abbreviation was not decorated: %q", got)
+	}
+}
+
+func TestCopy(t *testing.T) {
+	d := New(map[string]int{noted: 2})
+	var out bytes.Buffer
+	if err := d.Copy(&out, strings.NewReader("a1b2c3d One\n0123456 Two\n")); err != nil {
+		t.Fatal(err)
+	}
+	if want := "a1b2c3d ✦   2 One\n0123456       Two\n"; out.String() != want {
+		t.Errorf("Copy() = %q, want %q", out.String(), want)
+	}
+}
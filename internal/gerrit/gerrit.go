@@ -0,0 +1,157 @@
+// Package gerrit is a client of the parts of Gerrit's REST API that
+// 'shiftlog gerrit' uses: finding the change and patchset of a commit, and
+// reviewing it with a message.
+package gerrit
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// requestTimeout bounds each request to the Gerrit server.
+const requestTimeout = 30 * time.Second
+
+// ErrNotFound is returned for a commit that is no patchset of any change
+// the user can see.
+var ErrNotFound = errors.New("no Gerrit change has this commit")
+
+// Client talks to a Gerrit server. With a user, requests are authenticated
+// with their HTTP password, as Gerrit's /a/ endpoints require.
+type Client struct {
+	baseURL  string
+	user     string
+	password string
+	http     *http.Client
+}
+
+// NewClient returns a client of the Gerrit server at baseURL, e.g.
+// https://review.example.com, authenticated as user if it is not empty.
+func NewClient(baseURL, user, password string) *Client {
+	return &Client{
+		baseURL:  strings.TrimSuffix(baseURL, "/"),
+		user:     user,
+		password: password,
+		http:     &http.Client{Timeout: requestTimeout},
+	}
+}
+
+// Change is a Gerrit change, as much of its ChangeInfo as shiftlog reads.
+type Change struct {
+	// ID identifies the change in the API: project~branch~Change-Id.
+	ID       string `json:"id"`
+	ChangeID string `json:"change_id"`
+	Number   int    `json:"_number"`
+	Project  string `json:"project"`
+	// Revisions are the patchsets of the change, keyed by commit SHA.
+	Revisions map[string]Revision `json:"revisions"`
+	Messages  []Message           `json:"messages"`
+}
+
+// Revision is a patchset of a change.
+type Revision struct {
+	Number int `json:"_number"`
+}
+
+// Message is a message posted on a change.
+type Message struct {
+	Tag            string `json:"tag,omitempty"`
+	RevisionNumber int    `json:"_revision_number"`
+}
+
+// Patchset returns the number of the patchset of commitSHA, or 0.
+func (c *Change) Patchset(commitSHA string) int {
+	return c.Revisions[commitSHA].Number
+}
+
+// HasMessage reports whether a message with tag was posted on patchset.
+func (c *Change) HasMessage(tag string, patchset int) bool {
+	for _, m := range c.Messages {
+		if m.Tag == tag && m.RevisionNumber == patchset {
+			return true
+		}
+	}
+	return false
+}
+
+// ChangeOfCommit returns the change commitSHA is a patchset of, with every
+// patchset and message. Returns ErrNotFound if there is none.
+func (c *Client) ChangeOfCommit(ctx context.Context, commitSHA string) (*Change, error) {
+	query := url.Values{"q": {"commit:" + commitSHA}, "o": {"ALL_REVISIONS", "MESSAGES"}}
+	var changes []Change
+	if err := c.do(ctx, http.MethodGet, "/changes/?"+query.Encode(), nil, &changes); err != nil {
+		return nil, err
+	}
+	for i := range changes {
+		if changes[i].Patchset(commitSHA) > 0 {
+			return &changes[i], nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+// Review posts message on the patchset of commitSHA of change. tag groups
+// the messages of a tool; Gerrit can hide the ones whose tag starts with
+// "autogenerated:".
+func (c *Client) Review(ctx context.Context, change *Change, commitSHA, message, tag string) error {
+	body, err := json.Marshal(map[string]string{"message": message, "tag": tag})
+	if err != nil {
+		return err
+	}
+	path := "/changes/" + url.PathEscape(change.ID) + "/revisions/" + commitSHA + "/review"
+	return c.do(ctx, http.MethodPost, path, body, nil)
+}
+
+// do sends a request to the API and decodes its JSON response into result,
+// if it is not nil.
+func (c *Client) do(ctx context.Context, method, path string, body []byte, result any) error {
+	if c.user != "" {
+		path = "/a" + path
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.user != "" {
+		req.SetBasicAuth(c.user, c.password)
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode >= 300 {
+		// Gerrit explains errors in plain text
+		text, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("%s %s: %s: %s", method, path, resp.Status, strings.TrimSpace(string(text)))
+	}
+	if result == nil {
+		return nil
+	}
+	return decode(resp.Body, result)
+}
+
+// decode decodes a JSON response of Gerrit, which starts with a line of
+// )]}' against cross-site script inclusion.
+func decode(r io.Reader, result any) error {
+	br := bufio.NewReader(r)
+	if prefix, err := br.Peek(4); err == nil && string(prefix) == ")]}'" {
+		if _, err := br.ReadString('\n'); err != nil {
+			return err
+		}
+	}
+	return json.NewDecoder(br).Decode(result)
+}
@@ -0,0 +1,78 @@
+package gerrit
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+const sha = "0123456789abcdef0123456789abcdef01234567"
+
+func TestChangeOfCommitAndReview(t *testing.T) {
+	var review map[string]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, password, ok := r.BasicAuth(); !ok || user != "alice" || password != "secret" {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/a/changes/":
+			if got := r.URL.Query().Get("q"); got != "commit:"+sha {
+				t.Errorf("query: %q", got)
+			}
+			_, _ = io.WriteString(w, ")]}'\n"+`[{"id": "demo~main~I1", "change_id": "I1", "_number": 7,
+				"revisions": {"`+sha+`": {"_number": 2}},
+				"messages": [{"tag": "autogenerated:shiftlog", "_revision_number": 1}]}]`)
+		case r.Method == http.MethodPost && r.URL.EscapedPath() == "/a/changes/demo~main~I1/revisions/"+sha+"/review":
+			if err := json.NewDecoder(r.Body).Decode(&review); err != nil {
+				t.Error(err)
+			}
+			_, _ = io.WriteString(w, ")]}'\n{}")
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL+"/", "alice", "secret")
+	change, err := client.ChangeOfCommit(context.Background(), sha)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if change.Number != 7 || change.Patchset(sha) != 2 {
+		t.Errorf("change = %+v", change)
+	}
+	if !change.HasMessage("autogenerated:shiftlog", 1) || change.HasMessage("autogenerated:shiftlog", 2) {
+		t.Error("HasMessage() does not tell the patchsets apart")
+	}
+
+	if err := client.Review(context.Background(), change, sha, "hello", "autogenerated:shiftlog"); err != nil {
+		t.Fatal(err)
+	}
+	if review["message"] != "hello" || review["tag"] != "autogenerated:shiftlog" {
+		t.Errorf("review = %v", review)
+	}
+
+	if _, err := NewClient(server.URL, "alice", "wrong").ChangeOfCommit(context.Background(), sha); err == nil {
+		t.Error("a rejected password was not an error")
+	}
+}
+
+func TestChangeOfCommitNotFound(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/changes/" {
+			t.Errorf("anonymous request to %s", r.URL.Path)
+		}
+		_, _ = io.WriteString(w, ")]}'\n[]")
+	}))
+	defer server.Close()
+
+	_, err := NewClient(server.URL, "", "").ChangeOfCommit(context.Background(), sha)
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("err = %v, want ErrNotFound", err)
+	}
+}
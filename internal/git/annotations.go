@@ -0,0 +1,32 @@
+package git
+
+// AnnotationsRef is the git notes ref holding reviewer annotations on
+// conversations. Annotations live apart from the conversation notes so that
+// reviewing a conversation never rewrites it.
+const AnnotationsRef = "refs/notes/shiftlog-annotations"
+
+// AnnotationsTrackingRef holds fetched remote annotations before merging.
+const AnnotationsTrackingRef = "refs/notes/shiftlog-annotations-remote"
+
+// HasAnnotations reports whether any annotation has been recorded locally.
+func HasAnnotations() bool {
+	sha, err := refCommit(AnnotationsRef)
+	return err == nil && sha != ""
+}
+
+// PushAnnotations pushes the annotations ref to the remote.
+// Returns ErrNonFastForward if the remote has diverged.
+func PushAnnotations(remote string) error {
+	return pushNotesRef(remote, AnnotationsRef)
+}
+
+// FetchAnnotationsToTracking fetches remote annotations to the tracking ref.
+func FetchAnnotationsToTracking(remote string) error {
+	return fetchNotesRef(remote, AnnotationsRef, AnnotationsTrackingRef)
+}
+
+// MergeAnnotations merges fetched annotations into the local ref. Each
+// annotation is a single line, so cat_sort_uniq yields the union of both sides.
+func MergeAnnotations() error {
+	return mergeNotesRef(AnnotationsRef, AnnotationsTrackingRef)
+}
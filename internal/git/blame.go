@@ -0,0 +1,94 @@
+package git
+
+import (
+	"bufio"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// BlameLine is a single line of `git blame` output.
+type BlameLine struct {
+	Line      int    // final line number in the file (1-based)
+	CommitSHA string // commit that last changed the line (all zeros if uncommitted)
+	Content   string
+}
+
+// IsCommitted reports whether the line comes from a commit rather than the working tree.
+func (b BlameLine) IsCommitted() bool {
+	return strings.Trim(b.CommitSHA, "0") != ""
+}
+
+// Blame returns per-line attribution for path. When start and end are
+// positive, only that (inclusive) line range is blamed.
+func Blame(path string, start, end int) ([]BlameLine, error) {
+	return BlameIn("", "", path, start, end)
+}
+
+// BlameIn blames path as Blame does, running git in dir, or the current
+// directory when empty, at revision rev, or the working tree when empty.
+func BlameIn(dir, rev, path string, start, end int) ([]BlameLine, error) {
+	args := []string{"blame", "--porcelain"}
+	if start > 0 && end > 0 {
+		args = append(args, "-L", fmt.Sprintf("%d,%d", start, end))
+	}
+	if rev != "" {
+		args = append(args, rev)
+	}
+	args = append(args, "--", path)
+
+	cmd := CommandIn(dir, args...)
+	output, err := cmd.Output()
+	if err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok && len(exitErr.Stderr) > 0 {
+			return nil, fmt.Errorf("%s", strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return nil, err
+	}
+
+	return parseBlamePorcelain(string(output))
+}
+
+// parseBlamePorcelain parses `git blame --porcelain` output. Each line group
+// starts with a header "<sha> <orig-line> <final-line> [<count>]", followed by
+// optional commit metadata lines and the content line prefixed with a tab.
+func parseBlamePorcelain(output string) ([]BlameLine, error) {
+	var lines []BlameLine
+	var current BlameLine
+	inGroup := false
+
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	scanner.Buffer(make([]byte, 0, 64*1024), 10*1024*1024)
+	for scanner.Scan() {
+		text := scanner.Text()
+		if strings.HasPrefix(text, "\t") {
+			if !inGroup {
+				return nil, fmt.Errorf("unexpected blame content line")
+			}
+			current.Content = text[1:]
+			lines = append(lines, current)
+			inGroup = false
+			continue
+		}
+		if inGroup {
+			continue // commit metadata (author, summary, filename, ...)
+		}
+
+		fields := strings.Fields(text)
+		if len(fields) < 3 || len(fields[0]) < 40 {
+			return nil, fmt.Errorf("unexpected blame header: %q", text)
+		}
+		finalLine, err := strconv.Atoi(fields[2])
+		if err != nil {
+			return nil, fmt.Errorf("unexpected blame header: %q", text)
+		}
+		current = BlameLine{Line: finalLine, CommitSHA: fields[0]}
+		inGroup = true
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return lines, nil
+}
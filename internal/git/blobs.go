@@ -0,0 +1,222 @@
+package git
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// BlobsRef is the git notes ref keeping the transcripts stored apart from
+// their conversation notes. Each transcript blob is the note of itself,
+// which keeps it from being pruned and lets sync push and fetch it.
+const BlobsRef = "refs/notes/shiftlog-blobs"
+
+// BlobsTrackingRef holds fetched remote transcript blobs before merging.
+const BlobsTrackingRef = "refs/notes/shiftlog-blobs-remote"
+
+// HasBlobs reports whether any transcript blob has been saved locally.
+func HasBlobs() bool {
+	sha, err := refCommit(BlobsRef)
+	return err == nil && sha != ""
+}
+
+// PushBlobs pushes the transcript blobs ref to the remote.
+// Returns ErrNonFastForward if the remote has diverged.
+func PushBlobs(remote string) error {
+	return pushNotesRef(remote, BlobsRef)
+}
+
+// FetchBlobsToTracking fetches remote transcript blobs to the tracking ref.
+func FetchBlobsToTracking(remote string) error {
+	return fetchNotesRef(remote, BlobsRef, BlobsTrackingRef)
+}
+
+// MergeBlobs merges fetched transcript blobs into the local ref. A blob is
+// only ever the note of itself, so both sides agree on every note they
+// share.
+func MergeBlobs() error {
+	return mergeNotesRef(BlobsRef, BlobsTrackingRef)
+}
+
+// AddBlob saves data as the note of its own blob in BlobsRef and returns
+// the blob SHA.
+func AddBlob(data []byte) (string, error) {
+	return addBlobNote(BlobsRef, data)
+}
+
+// AddBlobs saves each of data as the note of its own blob in BlobsRef, like
+// AddBlob, and returns the blob SHAs in order. Blobs saved already are left
+// as they are, and the others are added in a single notes commit rather
+// than a git notes add each, as a transcript may be cut in hundreds of
+// chunks.
+func AddBlobs(data [][]byte) ([]string, error) {
+	shas, err := WriteBlobs(data)
+	if err != nil {
+		return nil, err
+	}
+
+	notesMu.Lock()
+	defer notesMu.Unlock()
+	if release, err := acquireNotesLock(); err == nil {
+		defer release()
+	}
+
+	saved, err := ListNoteBlobs(BlobsRef)
+	if err != nil {
+		return nil, err
+	}
+	var entries strings.Builder
+	for _, sha := range shas {
+		if _, ok := saved[sha]; ok {
+			continue
+		}
+		saved[sha] = sha
+		// git notes reads notes whether or not they are fanned out in
+		// subtrees, and fans them out again on its next write
+		fmt.Fprintf(&entries, "100644 blob %s\t%s\n", sha, sha)
+	}
+	if entries.Len() == 0 {
+		return shas, nil
+	}
+
+	parent, err := refCommit(BlobsRef)
+	if err != nil {
+		return nil, err
+	}
+	var tree strings.Builder
+	if parent != "" {
+		out, err := gitCommand("ls-tree", parent).Output()
+		if err != nil {
+			return nil, fmt.Errorf("could not list %s: %w", BlobsRef, err)
+		}
+		tree.Write(out)
+	}
+	tree.WriteString(entries.String())
+	mktree := gitCommand("mktree")
+	mktree.Stdin = strings.NewReader(tree.String())
+	out, err := mktree.Output()
+	if err != nil {
+		return nil, fmt.Errorf("could not write notes tree: %w", err)
+	}
+
+	args := []string{"commit-tree", strings.TrimSpace(string(out)), "-m", "Notes added by shiftlog"}
+	if parent != "" {
+		args = append(args, "-p", parent)
+	}
+	commit, err := RunGitCommand(args...)
+	if err != nil {
+		return nil, fmt.Errorf("could not commit notes tree: %w", err)
+	}
+	if _, err := RunGitCommand("update-ref", "-m", "notes: Notes added by shiftlog", BlobsRef, commit, parent); err != nil {
+		return nil, fmt.Errorf("could not update %s: %w", BlobsRef, err)
+	}
+	return shas, nil
+}
+
+// ListBlobs returns the SHAs of the blobs saved in BlobsRef.
+func ListBlobs() ([]string, error) {
+	notes, err := ListNoteBlobs(BlobsRef)
+	if err != nil {
+		return nil, err
+	}
+	blobs := make([]string, 0, len(notes))
+	for blob := range notes {
+		blobs = append(blobs, blob)
+	}
+	return blobs, nil
+}
+
+// WriteBlob writes data to the object database and returns its SHA.
+func WriteBlob(data []byte) (string, error) {
+	cmd := gitCommand("hash-object", "-w", "--stdin")
+	cmd.Stdin = bytes.NewReader(data)
+	out, err := cmd.Output()
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// WriteBlobs writes each of data to the object database with a single git
+// hash-object, and returns their SHAs in order.
+func WriteBlobs(data [][]byte) ([]string, error) {
+	if len(data) == 0 {
+		return nil, nil
+	}
+	dir, err := os.MkdirTemp("", "shiftlog-blobs-")
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = os.RemoveAll(dir) }()
+
+	var paths strings.Builder
+	for i, d := range data {
+		path := filepath.Join(dir, strconv.Itoa(i))
+		if err := os.WriteFile(path, d, 0600); err != nil {
+			return nil, err
+		}
+		paths.WriteString(path + "\n")
+	}
+	cmd := gitCommand("hash-object", "-w", "--no-filters", "--stdin-paths")
+	cmd.Stdin = strings.NewReader(paths.String())
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, err
+	}
+	shas := strings.Fields(string(out))
+	if len(shas) != len(data) {
+		return nil, fmt.Errorf("git hash-object wrote %d of %d blobs", len(shas), len(data))
+	}
+	return shas, nil
+}
+
+// BlobSizes returns the size of the given blobs, keyed by SHA, through a
+// single git cat-file --batch-check. Blobs missing from the object database
+// are left out.
+func BlobSizes(shas []string) (map[string]int, error) {
+	sizes := make(map[string]int, len(shas))
+	if len(shas) == 0 {
+		return sizes, nil
+	}
+	cmd := gitCommand("cat-file", "--batch-check")
+	cmd.Stdin = strings.NewReader(strings.Join(shas, "\n") + "\n")
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, err
+	}
+	// Each line is "<sha> blob <size>", or "<sha> missing"
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 3 {
+			continue
+		}
+		size, err := strconv.Atoi(fields[2])
+		if err != nil {
+			return nil, fmt.Errorf("bad size in %q", line)
+		}
+		sizes[fields[0]] = size
+	}
+	return sizes, nil
+}
+
+// HasObject reports whether an object is in the object database.
+func HasObject(sha string) bool {
+	return gitCommand("cat-file", "-e", sha).Run() == nil
+}
+
+// addBlobNote writes data as a blob and attaches it as is, as the note of
+// itself, in ref. git notes add -F would clean up binary content as if it
+// were a message.
+func addBlobNote(ref string, data []byte) (string, error) {
+	blob, err := WriteBlob(data)
+	if err != nil {
+		return "", err
+	}
+	if err := runNotesWrite(nil, "notes", "--ref", ref, "add", "-f", "-C", blob, blob); err != nil {
+		return "", err
+	}
+	return blob, nil
+}
@@ -0,0 +1,276 @@
+package git
+
+import (
+	"fmt"
+	"os/exec"
+	"sort"
+	"strings"
+)
+
+// NotesPreRestoreRef holds the notes ref as it was before the last backup
+// restore, so a restore can be undone.
+const NotesPreRestoreRef = "refs/notes/shiftlog-pre-restore"
+
+// Refs of a conversation bundle. The notes ref holds the bundled notes and
+// each commit ref one commit they belong to, so the bundle carries the
+// commits along with their history.
+const (
+	bundleNotesRef         = "refs/shiftlog/bundle/notes"
+	bundleCommitsRefPrefix = "refs/shiftlog/bundle/commits/"
+)
+
+// NotesBundleRef holds the notes of an imported conversation bundle before
+// they are merged into NotesRef.
+const NotesBundleRef = "refs/notes/shiftlog-bundle"
+
+// ImportedCommitsRefPrefix namespaces the refs that keep commits imported
+// from a conversation bundle alive while no local branch contains them.
+const ImportedCommitsRefPrefix = "refs/shiftlog/imported/"
+
+// GetNotesCommit returns the commit the notes ref points to, or "" if no
+// conversation has been stored yet.
+func GetNotesCommit() (string, error) {
+	return refCommit(NotesRef)
+}
+
+// refCommit returns the commit ref points to, or "" if it does not exist.
+func refCommit(ref string) (string, error) {
+	out, err := gitCommand("rev-parse", "-q", "--verify", ref).Output()
+	if err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok && exitErr.ExitCode() == 1 {
+			return "", nil
+		}
+		return "", err
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// CreateNotesBundle writes the full history of the notes ref to a git bundle.
+func CreateNotesBundle(path string) error {
+	cmd := gitCommand("bundle", "create", "-q", path, NotesRef)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("%w: %s", err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// RestoreNotesBundle replaces the notes ref with the one in a bundle created
+// by CreateNotesBundle. The previous notes commit, if any, is kept in
+// NotesPreRestoreRef.
+func RestoreNotesBundle(path string) error {
+	previous, err := GetNotesCommit()
+	if err != nil {
+		return err
+	}
+	if previous != "" {
+		if _, err := RunGitCommand("update-ref", NotesPreRestoreRef, previous); err != nil {
+			return fmt.Errorf("could not save current notes: %w", err)
+		}
+	}
+
+	cmd := gitCommand("fetch", "-q", path, "+"+NotesRef+":"+NotesRef)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("%w: %s", err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// ExistingCommitsSince returns those of commits the repository has,
+// newest first. With since set, a date in any format git log --since
+// accepts, only commits committed after it are returned.
+func ExistingCommitsSince(commits []string, since string) ([]string, error) {
+	if len(commits) == 0 {
+		return nil, nil
+	}
+	args := []string{"log", "--no-walk", "--stdin", "--ignore-missing", "--format=%H"}
+	if since != "" {
+		args = append(args, "--since="+since)
+	}
+	cmd := gitCommand(args...)
+	cmd.Stdin = strings.NewReader(strings.Join(commits, "\n") + "\n")
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, err
+	}
+	return strings.Fields(string(output)), nil
+}
+
+// CreateConversationBundle writes the conversation notes of commits, and
+// the commits themselves, to a git bundle at path for ImportConversationBundle
+// to read in another clone. With since set, history older than since is left
+// out, and the importing clone must already have the commits it builds on.
+func CreateConversationBundle(path string, commits []string, since string) error {
+	if len(commits) == 0 {
+		return fmt.Errorf("no conversations to bundle")
+	}
+	blobs, err := ListNoteBlobs(NotesRef)
+	if err != nil {
+		return fmt.Errorf("could not list notes: %w", err)
+	}
+
+	// A notes tree of just the selected commits, without fanout, which git
+	// notes reads like any other
+	var tree strings.Builder
+	for _, sha := range commits {
+		blob, ok := blobs[sha]
+		if !ok {
+			return fmt.Errorf("commit %s has no conversation", sha)
+		}
+		fmt.Fprintf(&tree, "100644 blob %s\t%s\n", blob, sha)
+	}
+	mktree := gitCommand("mktree")
+	mktree.Stdin = strings.NewReader(tree.String())
+	treeSHA, err := mktree.Output()
+	if err != nil {
+		return fmt.Errorf("could not write notes tree: %w", err)
+	}
+	notesCommit, err := RunGitCommand("commit-tree", strings.TrimSpace(string(treeSHA)), "-m", "Notes bundled by shiftlog")
+	if err != nil {
+		return fmt.Errorf("could not write notes commit: %w", err)
+	}
+
+	refs := []string{bundleNotesRef}
+	var create, remove strings.Builder
+	fmt.Fprintf(&create, "update %s %s\n", bundleNotesRef, notesCommit)
+	for _, sha := range commits {
+		ref := bundleCommitsRefPrefix + sha
+		refs = append(refs, ref)
+		fmt.Fprintf(&create, "update %s %s\n", ref, sha)
+	}
+	for _, ref := range refs {
+		fmt.Fprintf(&remove, "delete %s\n", ref)
+	}
+	if err := updateRefs(create.String()); err != nil {
+		return fmt.Errorf("could not create bundle refs: %w", err)
+	}
+	defer func() { _ = updateRefs(remove.String()) }()
+
+	args := []string{"bundle", "create", "-q", path}
+	if since != "" {
+		args = append(args, "--since="+since)
+	}
+	cmd := gitCommand(append(args, "--stdin")...)
+	cmd.Stdin = strings.NewReader(strings.Join(refs, "\n") + "\n")
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("%w: %s", err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// BundleImport describes the result of ImportConversationBundle.
+type BundleImport struct {
+	Conversations int      // conversations in the bundle
+	New           int      // conversations of commits that had none
+	Detached      []string // imported commits no local branch contains
+}
+
+// ImportConversationBundle fetches the commits and conversation notes of a
+// bundle created by CreateConversationBundle and merges the notes into
+// NotesRef as sync pull does. Imported commits that no local branch or tag
+// contains are kept alive under ImportedCommitsRefPrefix.
+func ImportConversationBundle(path string) (*BundleImport, error) {
+	if output, err := gitCommand("bundle", "verify", path).CombinedOutput(); err != nil {
+		var reasons []string
+		for _, line := range strings.Split(string(output), "\n") {
+			if reason, ok := strings.CutPrefix(line, "error: "); ok {
+				reasons = append(reasons, strings.TrimSpace(reason))
+			}
+		}
+		return nil, fmt.Errorf("cannot import %s: %s", path, strings.Join(reasons, " "))
+	}
+	heads, err := RunGitCommand("bundle", "list-heads", path, bundleNotesRef)
+	if err != nil || heads == "" {
+		return nil, fmt.Errorf("%s is not a shiftlog bundle", path)
+	}
+
+	local, err := ListNoteBlobs(NotesRef)
+	if err != nil {
+		return nil, fmt.Errorf("could not list notes: %w", err)
+	}
+
+	cmd := gitCommand("fetch", "-q", path,
+		"+"+bundleNotesRef+":"+NotesBundleRef,
+		"+"+bundleCommitsRefPrefix+"*:"+ImportedCommitsRefPrefix+"*")
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return nil, fmt.Errorf("%w: %s", err, strings.TrimSpace(string(output)))
+	}
+	defer func() { _ = DeleteRef(NotesBundleRef) }()
+
+	bundled, err := ListNoteBlobs(NotesBundleRef)
+	if err != nil {
+		return nil, fmt.Errorf("could not list bundled notes: %w", err)
+	}
+	result := &BundleImport{Conversations: len(bundled)}
+	var commits []string
+	for sha := range bundled {
+		commits = append(commits, sha)
+		if _, ok := local[sha]; !ok {
+			result.New++
+		}
+	}
+	sort.Strings(commits)
+
+	if err := mergeNotesRef(NotesRef, NotesBundleRef); err != nil {
+		return nil, fmt.Errorf("could not merge bundled notes: %w", err)
+	}
+
+	// Commits that are on a branch already need no ref of their own
+	detached, err := commitsOffBranches(commits)
+	if err != nil {
+		return nil, err
+	}
+	var remove strings.Builder
+	for _, sha := range commits {
+		if detached[sha] {
+			result.Detached = append(result.Detached, sha)
+		} else {
+			fmt.Fprintf(&remove, "delete %s%s\n", ImportedCommitsRefPrefix, sha)
+		}
+	}
+	if err := updateRefs(remove.String()); err != nil {
+		return nil, fmt.Errorf("could not clean up imported refs: %w", err)
+	}
+	return result, nil
+}
+
+// commitsOffBranches returns the commits no local branch, tag or remote
+// branch contains.
+func commitsOffBranches(commits []string) (map[string]bool, error) {
+	if len(commits) == 0 {
+		return nil, nil
+	}
+	tips, err := RunGitCommand("for-each-ref", "--format=^%(objectname)", "refs/heads/", "refs/tags/", "refs/remotes/")
+	if err != nil {
+		return nil, err
+	}
+	cmd := gitCommand("rev-list", "--stdin")
+	cmd.Stdin = strings.NewReader(strings.Join(commits, "\n") + "\n" + tips + "\n")
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, err
+	}
+	wanted := make(map[string]bool, len(commits))
+	for _, sha := range commits {
+		wanted[sha] = true
+	}
+	unreachable := make(map[string]bool)
+	for _, sha := range strings.Fields(string(output)) {
+		if wanted[sha] {
+			unreachable[sha] = true
+		}
+	}
+	return unreachable, nil
+}
+
+// updateRefs applies git update-ref --stdin instructions.
+func updateRefs(instructions string) error {
+	if instructions == "" {
+		return nil
+	}
+	cmd := gitCommand("update-ref", "--stdin")
+	cmd.Stdin = strings.NewReader(instructions)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("%w: %s", err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
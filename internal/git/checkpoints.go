@@ -0,0 +1,58 @@
+package git
+
+import (
+	"strings"
+)
+
+// CheckpointSnapshotsRefPrefix namespaces the refs that keep the snapshot
+// commits of shiftlog checkpoint from being garbage collected.
+const CheckpointSnapshotsRefPrefix = "refs/shiftlog/checkpoints/"
+
+// CreateSnapshot records the working tree and index in a commit like a
+// stash, without changing either, and keeps the commit alive under
+// CheckpointSnapshotsRefPrefix. It returns "" when there are no changes to
+// record.
+func CreateSnapshot(message string) (string, error) {
+	sha, err := RunGitCommand("stash", "create", message)
+	if err != nil || sha == "" {
+		return "", err
+	}
+	if _, err := RunGitCommand("update-ref", CheckpointSnapshotsRefPrefix+sha, sha); err != nil {
+		return "", err
+	}
+	return sha, nil
+}
+
+// ReleaseSnapshot deletes the ref keeping a snapshot commit alive. Commits
+// that are not snapshots are left alone.
+func ReleaseSnapshot(sha string) error {
+	ref := CheckpointSnapshotsRefPrefix + sha
+	if existing, err := refCommit(ref); err != nil || existing == "" {
+		return err
+	}
+	_, err := RunGitCommand("update-ref", "-d", ref)
+	return err
+}
+
+// IsSnapshot reports whether the commit was created by CreateSnapshot and
+// not yet released.
+func IsSnapshot(sha string) bool {
+	existing, err := refCommit(CheckpointSnapshotsRefPrefix + sha)
+	return err == nil && existing != ""
+}
+
+// StashNames returns the stash entries as a map of stash commit SHA to its
+// name, e.g. "stash@{0}".
+func StashNames() (map[string]string, error) {
+	out, err := RunGitCommand("stash", "list", "--format=%H %gd")
+	if err != nil {
+		return nil, err
+	}
+	names := make(map[string]string)
+	for _, line := range strings.Split(out, "\n") {
+		if sha, name, ok := strings.Cut(line, " "); ok {
+			names[sha] = name
+		}
+	}
+	return names, nil
+}
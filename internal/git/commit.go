@@ -0,0 +1,153 @@
+package git
+
+import (
+	"strings"
+)
+
+// Trailer is a "Key: value" line from the end of a commit message,
+// such as Co-Authored-By or Shiftlog-Session.
+type Trailer struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+// CommitDetails is the full context of a commit beyond its subject line.
+type CommitDetails struct {
+	Subject     string    `json:"subject"`
+	Body        string    `json:"body,omitempty"`
+	Author      string    `json:"author"`
+	AuthorEmail string    `json:"author_email"`
+	Date        string    `json:"date"`
+	Trailers    []Trailer `json:"trailers,omitempty"`
+	Signature   string    `json:"signature"`        // see signatureStatuses
+	Signer      string    `json:"signer,omitempty"` // signer name for signed commits
+}
+
+// signatureStatuses maps git's %G? codes to readable statuses.
+var signatureStatuses = map[string]string{
+	"G": "good",
+	"U": "good", // valid signature from a key of unknown trust
+	"B": "bad",
+	"X": "expired",
+	"Y": "expired-key",
+	"R": "revoked-key",
+	"E": "unverifiable",
+	"N": "unsigned",
+}
+
+// commitDetailsFormat separates fields with NUL so bodies may contain anything.
+const commitDetailsFormat = "%s%x00%b%x00%an%x00%ae%x00%aI%x00%G?%x00%GS%x00%(trailers:only,unfold)"
+
+// GetCommitDetails returns the full message, trailers and signature status of a commit.
+func GetCommitDetails(commitSHA string) (*CommitDetails, error) {
+	out, err := RunGitCommand("log", "-1", "--format="+commitDetailsFormat, commitSHA)
+	if err != nil {
+		return nil, err
+	}
+	return parseCommitDetails(out), nil
+}
+
+func parseCommitDetails(out string) *CommitDetails {
+	fields := strings.SplitN(out, "\x00", 8)
+	for len(fields) < 8 {
+		fields = append(fields, "")
+	}
+
+	trailers := parseTrailers(fields[7])
+	details := &CommitDetails{
+		Subject:     fields[0],
+		Body:        stripTrailers(strings.TrimSpace(fields[1]), trailers),
+		Author:      fields[2],
+		AuthorEmail: fields[3],
+		Date:        fields[4],
+		Trailers:    trailers,
+		Signature:   signatureStatuses[strings.TrimSpace(fields[5])],
+		Signer:      strings.TrimSpace(fields[6]),
+	}
+	if details.Signature == "" {
+		details.Signature = "unsigned"
+	}
+	return details
+}
+
+// parseTrailers parses the output of %(trailers:only,unfold).
+func parseTrailers(s string) []Trailer {
+	var trailers []Trailer
+	for _, line := range strings.Split(s, "\n") {
+		key, value, ok := strings.Cut(line, ":")
+		if !ok || strings.TrimSpace(key) == "" {
+			continue
+		}
+		trailers = append(trailers, Trailer{Key: strings.TrimSpace(key), Value: strings.TrimSpace(value)})
+	}
+	return trailers
+}
+
+// stripTrailers removes the trailer block from the end of a commit body so
+// trailers are not shown twice.
+func stripTrailers(body string, trailers []Trailer) string {
+	if len(trailers) == 0 {
+		return body
+	}
+	lines := strings.Split(body, "\n")
+	end := len(lines)
+	for end > 0 {
+		line := strings.TrimSpace(lines[end-1])
+		if line == "" {
+			break
+		}
+		// Continuation lines of folded trailers start with whitespace
+		if _, _, ok := strings.Cut(line, ":"); !ok && !strings.HasPrefix(lines[end-1], " ") && !strings.HasPrefix(lines[end-1], "\t") {
+			return body
+		}
+		end--
+	}
+	return strings.TrimSpace(strings.Join(lines[:end], "\n"))
+}
+
+// CommitMessage returns the full message of a commit, trailers included.
+func CommitMessage(commitSHA string) (string, error) {
+	return RunGitCommand("log", "-1", "--format=%B", commitSHA)
+}
+
+// ChangeIDTrailer is the trailer Gerrit identifies a change by, kept by
+// every patchset of the change however it is amended or rebased.
+const ChangeIDTrailer = "Change-Id"
+
+// ChangeIDs returns the Change-Id trailer of those of commits that have
+// one, keyed by commit SHA. Commits missing from the repository are left
+// out.
+func ChangeIDs(commits []string) (map[string]string, error) {
+	ids := make(map[string]string)
+	if len(commits) == 0 {
+		return ids, nil
+	}
+	cmd := gitCommand("log", "--no-walk=unsorted", "--ignore-missing", "--stdin",
+		"--format=%H %(trailers:key="+ChangeIDTrailer+",valueonly,separator=%x20)%x00")
+	cmd.Stdin = strings.NewReader(strings.Join(commits, "\n") + "\n")
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, err
+	}
+	for _, record := range strings.Split(string(out), "\x00") {
+		// A commit amended across changes may have several: Gerrit uses the last
+		if fields := strings.Fields(record); len(fields) >= 2 {
+			ids[fields[0]] = fields[len(fields)-1]
+		}
+	}
+	return ids, nil
+}
+
+// NewestCommit returns the most recently committed of commits.
+func NewestCommit(commits []string) (string, error) {
+	if len(commits) == 0 {
+		return "", nil
+	}
+	cmd := gitCommand("log", "--no-walk", "--ignore-missing", "--stdin", "-1", "--format=%H")
+	cmd.Stdin = strings.NewReader(strings.Join(commits, "\n") + "\n")
+	out, err := cmd.Output()
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(out)), nil
+}
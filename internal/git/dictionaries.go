@@ -0,0 +1,44 @@
+package git
+
+// DictionariesRef is the git notes ref holding the zstd dictionaries that
+// transcripts were compressed with. Each dictionary is the note of its own
+// blob, so clones that sync can read every note whatever dictionary it used.
+const DictionariesRef = "refs/notes/shiftlog-dictionaries"
+
+// DictionariesTrackingRef holds fetched remote dictionaries before merging.
+const DictionariesTrackingRef = "refs/notes/shiftlog-dictionaries-remote"
+
+// HasDictionaries reports whether any dictionary has been saved locally.
+func HasDictionaries() bool {
+	sha, err := refCommit(DictionariesRef)
+	return err == nil && sha != ""
+}
+
+// PushDictionaries pushes the dictionaries ref to the remote.
+// Returns ErrNonFastForward if the remote has diverged.
+func PushDictionaries(remote string) error {
+	return pushNotesRef(remote, DictionariesRef)
+}
+
+// FetchDictionariesToTracking fetches remote dictionaries to the tracking ref.
+func FetchDictionariesToTracking(remote string) error {
+	return fetchNotesRef(remote, DictionariesRef, DictionariesTrackingRef)
+}
+
+// MergeDictionaries merges fetched dictionaries into the local ref. A
+// dictionary is only ever the note of its own blob, so both sides agree on
+// every note they share.
+func MergeDictionaries() error {
+	return mergeNotesRef(DictionariesRef, DictionariesTrackingRef)
+}
+
+// AddDictionary saves a dictionary as the note of its own blob and returns
+// the blob SHA.
+func AddDictionary(data []byte) (string, error) {
+	return addBlobNote(DictionariesRef, data)
+}
+
+// ReadDictionaries returns every saved dictionary, keyed by blob SHA.
+func ReadDictionaries() (map[string][]byte, error) {
+	return ReadNotes(DictionariesRef)
+}
@@ -0,0 +1,106 @@
+package git
+
+import (
+	"bufio"
+	"strings"
+)
+
+// AddedLines returns the lines a commit adds, keyed by repo-relative path.
+// Merge commits and binary files contribute no lines.
+func AddedLines(commitSHA string) (map[string][]string, error) {
+	cmd := gitCommand("diff-tree", "-p", "-r", "--root", "-U0",
+		"--no-color", "--no-ext-diff", "--no-renames", "--format=", commitSHA)
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, err
+	}
+	return parseAddedLines(string(output)), nil
+}
+
+// parseAddedLines extracts added lines from unified diff output.
+func parseAddedLines(diff string) map[string][]string {
+	added := make(map[string][]string)
+	var current string
+
+	scanner := bufio.NewScanner(strings.NewReader(diff))
+	scanner.Buffer(make([]byte, 0, 64*1024), 10*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "diff --git "):
+			current = ""
+		case strings.HasPrefix(line, "+++ "):
+			current = ""
+			if path, ok := strings.CutPrefix(line, "+++ b/"); ok {
+				current = path
+			}
+		case strings.HasPrefix(line, "+") && current != "":
+			added[current] = append(added[current], line[1:])
+		}
+	}
+	return added
+}
+
+// FilePatch is the unified diff of one file in a commit.
+type FilePatch struct {
+	Path  string // repo-relative path, the old one for deleted files
+	Patch string // from the "diff --git" line on
+}
+
+// FilePatches returns the diff of each file a commit changes, in the order
+// git lists them. Merge commits change no files.
+func FilePatches(commitSHA string) ([]FilePatch, error) {
+	cmd := gitCommand("diff-tree", "-p", "-r", "--root",
+		"--no-color", "--no-ext-diff", "--no-renames", "--format=", commitSHA)
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, err
+	}
+	return parseFilePatches(string(output)), nil
+}
+
+// parseFilePatches splits unified diff output into the diffs of its files.
+func parseFilePatches(diff string) []FilePatch {
+	var patches []FilePatch
+	var current *strings.Builder
+	var path string
+	inHunk := false
+	previous := ""
+	flush := func() {
+		if current != nil {
+			patches = append(patches, FilePatch{Path: path, Patch: current.String()})
+		}
+	}
+	for _, line := range strings.SplitAfter(diff, "\n") {
+		trimmed := strings.TrimSuffix(line, "\n")
+		if header, ok := strings.CutPrefix(trimmed, "diff --git "); ok {
+			flush()
+			current = &strings.Builder{}
+			inHunk = false
+			// Paths with spaces are only known for sure from the ---/+++ lines
+			path = ""
+			if i := strings.LastIndex(header, " b/"); i >= 0 {
+				path = header[i+3:]
+			}
+		}
+		if current == nil {
+			continue
+		}
+		switch {
+		case inHunk:
+		case strings.HasPrefix(trimmed, "@@"):
+			inHunk = true
+		case strings.HasPrefix(trimmed, "+++ b/"):
+			path = trimmed[len("+++ b/"):]
+		case trimmed == "+++ /dev/null":
+			// A deleted file keeps its old path
+			if p, ok := strings.CutPrefix(previous, "--- a/"); ok {
+				path = p
+			}
+		}
+		previous = trimmed
+		current.WriteString(line)
+	}
+	flush()
+	return patches
+}
@@ -0,0 +1,30 @@
+package git
+
+import "testing"
+
+func TestParseFilePatches(t *testing.T) {
+	diff := "diff --git a/main.go b/main.go\n" +
+		"index 1111111..2222222 100644\n" +
+		"--- a/main.go\n" +
+		"+++ b/main.go\n" +
+		"@@ -1 +1 @@\n" +
+		"-func hello() {}\n" +
+		"++++ b/not a header\n" +
+		"diff --git a/old file.txt b/old file.txt\n" +
+		"deleted file mode 100644\n" +
+		"--- a/old file.txt\n" +
+		"+++ /dev/null\n" +
+		"@@ -1 +0,0 @@\n" +
+		"-gone\n"
+
+	patches := parseFilePatches(diff)
+	if len(patches) != 2 {
+		t.Fatalf("parseFilePatches() = %+v, want 2 files", patches)
+	}
+	if patches[0].Path != "main.go" || patches[1].Path != "old file.txt" {
+		t.Errorf("paths = %q, %q, want main.go and old file.txt", patches[0].Path, patches[1].Path)
+	}
+	if patches[0].Patch+patches[1].Patch != diff {
+		t.Errorf("patches do not add up to the diff: %+v", patches)
+	}
+}
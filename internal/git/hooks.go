@@ -1,9 +1,11 @@
 package git
 
 import (
+	"bytes"
 	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
 	"strings"
 )
 
@@ -15,14 +17,35 @@ const (
 	HookPostMerge    HookType = "post-merge"
 	HookPostCheckout HookType = "post-checkout"
 	HookPostCommit   HookType = "post-commit"
+
+	// HookPrepareCommitMsg is opt-in: it is installed by
+	// InstallCommitSuggestionHook, not InstallAllHooks.
+	HookPrepareCommitMsg HookType = "prepare-commit-msg"
 )
 
 // shiftlogMarker identifies shiftlog-managed hook sections
 const shiftlogMarker = "# shiftlog-managed"
 
-// InstallHook installs or updates a git hook with shiftlog commands
+// ChainedSuffix is appended to the name of a hook that shiftlog cannot add
+// its section to, such as a script in another language. The hook is moved
+// aside under that name and called first by the hook shiftlog writes.
+const ChainedSuffix = ".shiftlog-chained"
+
+// endsEarly matches the top-level exit and exec lines that would stop a
+// shell hook before a section appended to it.
+var endsEarly = regexp.MustCompile(`(?m)^(exit|exec)\b`)
+
+// InstallHook installs or updates a git hook in .git/hooks with shiftlog
+// commands
 func InstallHook(gitDir string, hookType HookType, command string) error {
-	hooksDir := filepath.Join(gitDir, "hooks")
+	return installHookFile(filepath.Join(gitDir, "hooks"), hookType, command)
+}
+
+// installHookFile installs or updates the hook script for hookType in
+// hooksDir with shiftlog commands. The shiftlog section is appended to an
+// existing shell script, while other hooks are chained: moved aside and
+// called before the section.
+func installHookFile(hooksDir string, hookType HookType, command string) error {
 	if err := os.MkdirAll(hooksDir, 0755); err != nil {
 		return err
 	}
@@ -50,6 +73,16 @@ func InstallHook(gitDir string, hookType HookType, command string) error {
 	} else if strings.Contains(existingContent, shiftlogMarker) {
 		// Update existing shiftlog section
 		newContent = replaceShiftlogSection(existingContent, shiftlogSection)
+	} else if !canAppendTo(existingContent) {
+		// Chain the existing hook, keeping its exit status
+		chained := hookPath + ChainedSuffix
+		if err := os.Rename(hookPath, chained); err != nil {
+			return fmt.Errorf("failed to move %s aside: %w", hookType, err)
+		}
+		if err := os.Chmod(chained, 0755); err != nil {
+			return err
+		}
+		newContent = chainedHookHeader(hookType) + shiftlogSection
 	} else {
 		// Append to existing hook
 		newContent = existingContent + "\n" + shiftlogSection
@@ -58,6 +91,37 @@ func InstallHook(gitDir string, hookType HookType, command string) error {
 	return os.WriteFile(hookPath, []byte(newContent), 0755)
 }
 
+// canAppendTo reports whether a section appended to an existing hook would
+// run: the hook must be a shell script that does not exit or exec first.
+func canAppendTo(content string) bool {
+	if bytes.IndexByte([]byte(content), 0) >= 0 {
+		return false
+	}
+	if strings.HasPrefix(content, "#!") {
+		shebang, _, _ := strings.Cut(content, "\n")
+		fields := strings.Fields(strings.TrimPrefix(shebang, "#!"))
+		if len(fields) == 0 {
+			return false
+		}
+		interpreter := filepath.Base(fields[0])
+		if interpreter == "env" && len(fields) > 1 {
+			interpreter = fields[1]
+		}
+		switch interpreter {
+		case "sh", "bash", "dash", "zsh", "ksh":
+		default:
+			return false
+		}
+	}
+	return !endsEarly.MatchString(content)
+}
+
+// chainedHookHeader is the start of a hook that calls the hook moved aside
+// to hookType + ChainedSuffix.
+func chainedHookHeader(hookType HookType) string {
+	return fmt.Sprintf("#!/bin/sh\n\"$(dirname \"$0\")/%s%s\" \"$@\" || exit $?\n", hookType, ChainedSuffix)
+}
+
 // replaceShiftlogSection replaces the shiftlog-managed section in hook content
 func replaceShiftlogSection(content, newSection string) string {
 	startMarker := shiftlogMarker + " start"
@@ -92,7 +156,13 @@ func replaceShiftlogSection(content, newSection string) string {
 // If the file reduces to just a shebang line (with optional whitespace),
 // it is deleted entirely. No-op if the hook file doesn't exist or has no shiftlog section.
 func RemoveHook(gitDir string, hookType HookType) error {
-	hookPath := filepath.Join(gitDir, "hooks", string(hookType))
+	return removeHookFile(filepath.Join(gitDir, "hooks"), hookType)
+}
+
+// removeHookFile removes the shiftlog-managed section from the hook script
+// for hookType in hooksDir, putting back a hook that was chained.
+func removeHookFile(hooksDir string, hookType HookType) error {
+	hookPath := filepath.Join(hooksDir, string(hookType))
 
 	data, err := os.ReadFile(hookPath)
 	if err != nil {
@@ -110,6 +180,13 @@ func RemoveHook(gitDir string, hookType HookType) error {
 	// Replace the shiftlog section with nothing
 	newContent := replaceShiftlogSection(content, "")
 
+	// Put back a chained hook once nothing else is left
+	chained := hookPath + ChainedSuffix
+	if _, err := os.Stat(chained); err == nil &&
+		strings.TrimSpace(newContent) == strings.TrimSpace(chainedHookHeader(hookType)) {
+		return os.Rename(chained, hookPath)
+	}
+
 	// If only a shebang line remains (with optional whitespace), delete the file
 	trimmed := strings.TrimSpace(newContent)
 	if trimmed == "" || trimmed == "#!/bin/sh" || trimmed == "#!/bin/bash" {
@@ -119,35 +196,51 @@ func RemoveHook(gitDir string, hookType HookType) error {
 	return os.WriteFile(hookPath, []byte(newContent), 0755)
 }
 
-// RemoveAllHooks removes shiftlog-managed sections from all git hooks.
+// RemoveAllHooks removes shiftlog-managed sections from all git hooks, both
+// in .git/hooks and wherever the repository's hook setup keeps them, and the
+// Lefthook configuration written by shiftlog.
 func RemoveAllHooks(gitDir string) error {
-	hookTypes := []HookType{HookPrePush, HookPostMerge, HookPostCheckout, HookPostCommit}
-	for _, ht := range hookTypes {
-		if err := RemoveHook(gitDir, ht); err != nil {
-			return fmt.Errorf("failed to remove %s hook: %w", ht, err)
+	dirs := []string{filepath.Join(gitDir, "hooks")}
+	if setup, err := DetectHookSetup(gitDir); err == nil {
+		if setup.Dir != dirs[0] {
+			dirs = append(dirs, setup.Dir)
+		}
+		if err := setup.removeLefthookConfig(); err != nil {
+			return fmt.Errorf("failed to remove %s: %w", LefthookLocalConfig, err)
+		}
+	}
+
+	hookTypes := []HookType{HookPrePush, HookPostMerge, HookPostCheckout, HookPostCommit, HookPrepareCommitMsg}
+	for _, dir := range dirs {
+		for _, ht := range hookTypes {
+			if err := removeHookFile(dir, ht); err != nil {
+				return fmt.Errorf("failed to remove %s hook: %w", ht, err)
+			}
 		}
 	}
 	return nil
 }
 
-// InstallAllHooks installs all shiftlog git hooks.
-// It resolves the absolute path to the running shiftlog binary so hooks work
-// even when the shell environment strips PATH (e.g. Codex CLI sandbox).
-func InstallAllHooks(gitDir string) error {
-	bin, err := resolveShiftlogBinary()
+// InstallAllHooks installs all shiftlog git hooks where setup says git runs
+// them from.
+func InstallAllHooks(setup *HookSetup) error {
+	bin, err := setup.shiftlogCommand()
 	if err != nil {
 		return fmt.Errorf("failed to resolve shiftlog binary path: %w", err)
 	}
 
 	hooks := map[HookType]string{
 		HookPrePush:      bin + " sync push",
-		HookPostMerge:    bin + " sync pull\n" + bin + " remap",
+		HookPostMerge:    bin + " sync pull\n" + bin + " remap\n" + bin + " store --merge",
 		HookPostCheckout: bin + " sync pull",
 		HookPostCommit:   bin + " store --manual",
 	}
 
-	for hookType, command := range hooks {
-		if err := InstallHook(gitDir, hookType, command); err != nil {
+	if setup.Manager == HookManagerLefthook {
+		return setup.installLefthookCommands(hooks)
+	}
+	for _, hookType := range []HookType{HookPrePush, HookPostMerge, HookPostCheckout, HookPostCommit} {
+		if err := setup.install(hookType, hooks[hookType]); err != nil {
 			return fmt.Errorf("failed to install %s hook: %w", hookType, err)
 		}
 	}
@@ -155,6 +248,24 @@ func InstallAllHooks(gitDir string) error {
 	return nil
 }
 
+// InstallCommitSuggestionHook installs the prepare-commit-msg hook that
+// suggests a commit message from the active conversation.
+func InstallCommitSuggestionHook(setup *HookSetup) error {
+	bin, err := setup.shiftlogCommand()
+	if err != nil {
+		return fmt.Errorf("failed to resolve shiftlog binary path: %w", err)
+	}
+	command := bin + ` suggest-commit-msg "$1" "$2"`
+	if setup.Manager == HookManagerLefthook {
+		// Lefthook passes the hook's arguments as {1} and {2}
+		command = bin + ` suggest-commit-msg {1} {2}`
+	}
+	if err := setup.install(HookPrepareCommitMsg, command); err != nil {
+		return fmt.Errorf("failed to install %s hook: %w", HookPrepareCommitMsg, err)
+	}
+	return nil
+}
+
 // resolveShiftlogBinary returns the absolute path to the running shiftlog binary.
 func resolveShiftlogBinary() (string, error) {
 	exe, err := os.Executable()
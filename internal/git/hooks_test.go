@@ -0,0 +1,79 @@
+package git
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCanAppendTo(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    bool
+	}{
+		{"sh script", "#!/bin/sh\necho hi\n", true},
+		{"env bash", "#!/usr/bin/env bash\necho hi\n", true},
+		{"no shebang", "npm test\n", true},
+		{"indented exit", "#!/bin/sh\nif false; then\n  exit 1\nfi\n", true},
+		{"python", "#!/usr/bin/env python3\nprint('hi')\n", false},
+		{"top-level exit", "#!/bin/sh\necho hi\nexit 0\n", false},
+		{"exec", "#!/bin/sh\nexec lint-staged\n", false},
+		{"binary", "\x7fELF\x00\x00", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := canAppendTo(tt.content); got != tt.want {
+				t.Errorf("canAppendTo() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestInstallHookFileChainsAndRestores(t *testing.T) {
+	dir := t.TempDir()
+	hookPath := filepath.Join(dir, "post-commit")
+	original := "#!/bin/sh\necho mine\nexit 0\n"
+	if err := os.WriteFile(hookPath, []byte(original), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := installHookFile(dir, HookPostCommit, "shiftlog store --manual"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	chained, err := os.ReadFile(hookPath + ChainedSuffix)
+	if err != nil {
+		t.Fatalf("chained hook missing: %v", err)
+	}
+	if string(chained) != original {
+		t.Errorf("chained hook = %q, want %q", chained, original)
+	}
+	data, err := os.ReadFile(hookPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(data), chainedHookHeader(HookPostCommit)) {
+		t.Errorf("hook does not call the chained hook first:\n%s", data)
+	}
+	if strings.Count(string(data), shiftlogMarker+" start") != 1 {
+		t.Errorf("expected one shiftlog section:\n%s", data)
+	}
+
+	if err := removeHookFile(dir, HookPostCommit); err != nil {
+		t.Fatal(err)
+	}
+	data, err = os.ReadFile(hookPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != original {
+		t.Errorf("restored hook = %q, want %q", data, original)
+	}
+	if _, err := os.Stat(hookPath + ChainedSuffix); !os.IsNotExist(err) {
+		t.Errorf("chained hook left behind: %v", err)
+	}
+}
@@ -0,0 +1,315 @@
+package git
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// HookManager identifies what decides which hook scripts git runs.
+type HookManager string
+
+const (
+	// HookManagerGit is plain git running the scripts in .git/hooks.
+	HookManagerGit HookManager = "git"
+	// HookManagerHooksPath is git running the scripts in core.hooksPath.
+	HookManagerHooksPath HookManager = "core.hooksPath"
+	// HookManagerHusky is Husky, whose hook scripts live in .husky/.
+	HookManagerHusky HookManager = "husky"
+	// HookManagerLefthook is Lefthook, which generates the scripts in
+	// .git/hooks from its YAML configuration.
+	HookManagerLefthook HookManager = "lefthook"
+)
+
+// LefthookLocalConfig is the file shiftlog configures Lefthook through. It
+// is Lefthook's per-developer configuration, kept out of version control.
+const LefthookLocalConfig = "lefthook-local.yml"
+
+// lefthookConfigs are the names Lefthook reads its main configuration from.
+var lefthookConfigs = []string{
+	"lefthook.yml", ".lefthook.yml", "lefthook.yaml", ".lefthook.yaml",
+	"lefthook.json", ".lefthook.json", "lefthook.toml", ".lefthook.toml",
+}
+
+// HookSetup describes where the git hooks of a repository come from.
+type HookSetup struct {
+	Manager HookManager
+	// Dir is the directory shiftlog writes hook scripts to. For Lefthook it
+	// is the directory of the scripts Lefthook generates.
+	Dir string
+	// HooksPath is core.hooksPath as configured, or "" when unset.
+	HooksPath string
+	// Shared is set when the hook scripts are part of the work tree, and so
+	// likely committed and run by people without shiftlog.
+	Shared bool
+
+	repoRoot string
+}
+
+// DetectHookSetup finds out how the current repository runs git hooks:
+// Lefthook when it has a Lefthook configuration, Husky when it has a .husky
+// directory, core.hooksPath when that is set, and .git/hooks otherwise.
+func DetectHookSetup(gitDir string) (*HookSetup, error) {
+	repoRoot, err := GetRepoRoot()
+	if err != nil {
+		return nil, err
+	}
+	setup := &HookSetup{
+		Manager:  HookManagerGit,
+		Dir:      filepath.Join(gitDir, "hooks"),
+		repoRoot: repoRoot,
+	}
+	setup.HooksPath, _ = RunGitCommand("config", "core.hooksPath")
+
+	huskyDir := filepath.Join(repoRoot, ".husky")
+	switch {
+	case lefthookConfigPath(repoRoot) != "":
+		setup.Manager = HookManagerLefthook
+	case isDir(huskyDir):
+		setup.Manager = HookManagerHusky
+		setup.Dir = huskyDir
+		setup.Shared = true
+	case setup.HooksPath != "":
+		setup.Manager = HookManagerHooksPath
+		setup.Dir = setup.HooksPathDir()
+		setup.Shared = isWithin(setup.Dir, repoRoot) && !isWithin(setup.Dir, gitDir)
+	}
+	return setup, nil
+}
+
+// HooksPathDir returns the directory core.hooksPath points to, or "" when
+// it is unset.
+func (s *HookSetup) HooksPathDir() string {
+	if s.HooksPath == "" {
+		return ""
+	}
+	return resolveHooksPath(s.repoRoot, s.HooksPath)
+}
+
+// HookPath returns the path of the script git runs for hookType.
+func (s *HookSetup) HookPath(hookType HookType) string {
+	return filepath.Join(s.Dir, string(hookType))
+}
+
+// Location describes where the hooks are installed, for messages.
+func (s *HookSetup) Location() string {
+	if s.Manager == HookManagerLefthook {
+		return LefthookLocalConfig + " (Lefthook)"
+	}
+	dir := s.Dir
+	if rel, err := filepath.Rel(s.repoRoot, s.Dir); err == nil && !strings.HasPrefix(rel, "..") {
+		dir = rel
+	}
+	switch s.Manager {
+	case HookManagerHusky:
+		return dir + " (Husky)"
+	case HookManagerHooksPath:
+		return dir + " (core.hooksPath)"
+	}
+	return dir
+}
+
+// LefthookConfigPath returns the path of the repository's main Lefthook
+// configuration, or "" when there is none.
+func (s *HookSetup) LefthookConfigPath() string {
+	return lefthookConfigPath(s.repoRoot)
+}
+
+// LefthookLocalConfigPath returns the path of LefthookLocalConfig.
+func (s *HookSetup) LefthookLocalConfigPath() string {
+	return filepath.Join(s.repoRoot, LefthookLocalConfig)
+}
+
+// shiftlogCommand returns how the installed hooks should run shiftlog: by
+// absolute path, so that hooks work even when the shell environment strips
+// PATH (e.g. Codex CLI sandbox), except in shared hook scripts, which other
+// people run from their own PATH.
+func (s *HookSetup) shiftlogCommand() (string, error) {
+	if s.Shared {
+		return "shiftlog", nil
+	}
+	return resolveShiftlogBinary()
+}
+
+// install installs command as the shiftlog part of hookType.
+func (s *HookSetup) install(hookType HookType, command string) error {
+	if s.Manager == HookManagerLefthook {
+		return s.installLefthookCommands(map[HookType]string{hookType: command})
+	}
+	if s.Shared {
+		// Teammates without shiftlog must still be able to commit
+		command = "if command -v shiftlog >/dev/null 2>&1; then\n" +
+			indent(command) + "\nfi"
+	}
+	return installHookFile(s.Dir, hookType, command)
+}
+
+// LefthookConfigError is returned when LefthookLocalConfig exists but was
+// not written by shiftlog, so the hooks could not be added to it.
+type LefthookConfigError struct {
+	Path string
+	// Snippet is the configuration to add to Path by hand.
+	Snippet string
+}
+
+func (e *LefthookConfigError) Error() string {
+	return fmt.Sprintf("%s is not managed by shiftlog; add the shiftlog commands to it by hand", e.Path)
+}
+
+// lefthookHook is the part of a Lefthook configuration for one hook.
+type lefthookHook struct {
+	Commands map[string]lefthookCommand `yaml:"commands"`
+}
+
+type lefthookCommand struct {
+	Run string `yaml:"run"`
+}
+
+// installLefthookCommands adds the commands to LefthookLocalConfig as the
+// shiftlog command of their hooks. The file is only written while shiftlog
+// owns it.
+func (s *HookSetup) installLefthookCommands(commands map[HookType]string) error {
+	path := s.LefthookLocalConfigPath()
+	hooks := map[string]lefthookHook{}
+	added := map[string]lefthookHook{}
+	for hookType, command := range commands {
+		added[string(hookType)] = lefthookHook{Commands: map[string]lefthookCommand{"shiftlog": {Run: command}}}
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err == nil {
+		if !strings.HasPrefix(string(data), shiftlogMarker) {
+			var snippet bytes.Buffer
+			_ = marshalLefthookYAML(&snippet, added)
+			return &LefthookConfigError{Path: path, Snippet: snippet.String()}
+		}
+		if err := yaml.Unmarshal(data, &hooks); err != nil {
+			return fmt.Errorf("could not parse %s: %w", path, err)
+		}
+	}
+
+	for name, hook := range added {
+		hooks[name] = hook
+	}
+	var out bytes.Buffer
+	out.WriteString(shiftlogMarker + ": written by 'shiftlog init', removed by 'shiftlog deinit'\n")
+	if err := marshalLefthookYAML(&out, hooks); err != nil {
+		return err
+	}
+	return os.WriteFile(path, out.Bytes(), 0644)
+}
+
+// marshalLefthookYAML writes hooks as YAML indented like Lefthook's own
+// examples.
+func marshalLefthookYAML(w io.Writer, hooks map[string]lefthookHook) error {
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(hooks); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+// LefthookHooks returns the hooks that run shiftlog according to the
+// repository's Lefthook configurations.
+func (s *HookSetup) LefthookHooks() []HookType {
+	found := map[HookType]bool{}
+	for _, path := range []string{s.LefthookConfigPath(), s.LefthookLocalConfigPath()} {
+		if path == "" {
+			continue
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		// Other top-level keys, such as min_version, are not hooks
+		var nodes map[string]yaml.Node
+		if yaml.Unmarshal(data, &nodes) != nil {
+			continue
+		}
+		for name, node := range nodes {
+			var hook lefthookHook
+			if node.Decode(&hook) != nil {
+				continue
+			}
+			for _, c := range hook.Commands {
+				if strings.Contains(c.Run, "shiftlog") {
+					found[HookType(name)] = true
+				}
+			}
+		}
+	}
+	var types []HookType
+	for t := range found {
+		types = append(types, t)
+	}
+	sort.Slice(types, func(i, j int) bool { return types[i] < types[j] })
+	return types
+}
+
+// removeLefthookConfig deletes LefthookLocalConfig if shiftlog wrote it.
+func (s *HookSetup) removeLefthookConfig() error {
+	path := s.LefthookLocalConfigPath()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	if !strings.HasPrefix(string(data), shiftlogMarker) {
+		return nil
+	}
+	return os.Remove(path)
+}
+
+// lefthookConfigPath returns the Lefthook configuration in repoRoot, or "".
+func lefthookConfigPath(repoRoot string) string {
+	for _, name := range lefthookConfigs {
+		path := filepath.Join(repoRoot, name)
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+	}
+	return ""
+}
+
+// resolveHooksPath returns the directory a core.hooksPath value points to.
+// Git resolves relative paths against the root of the work tree, where
+// hooks run.
+func resolveHooksPath(repoRoot, hooksPath string) string {
+	if strings.HasPrefix(hooksPath, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(home, hooksPath[2:])
+		}
+	}
+	if filepath.IsAbs(hooksPath) {
+		return filepath.Clean(hooksPath)
+	}
+	return filepath.Join(repoRoot, hooksPath)
+}
+
+func isDir(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
+
+// isWithin reports whether path is dir or inside it.
+func isWithin(path, dir string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// indent indents every line of s by two spaces.
+func indent(s string) string {
+	return "  " + strings.ReplaceAll(s, "\n", "\n  ")
+}
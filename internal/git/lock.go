@@ -0,0 +1,146 @@
+package git
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Parallel agents in one clone (several sessions, or one per worktree) store
+// notes at the same time. git updates a notes ref with a compare-and-swap on
+// its ref lock, so concurrent writers fail with "cannot lock ref" and the
+// conversation is dropped. Note writes therefore queue on a lock file in the
+// common git dir, shared by all worktrees, and retry with backoff if git
+// still reports lock contention, e.g. from a git process outside shiftlog.
+
+// notesLockName is the queue lock file, created in the common git dir.
+const notesLockName = "shiftlog-notes.lock"
+
+// Tunables for the write queue; variables so tests can shorten them.
+var (
+	notesLockTimeout   = 30 * time.Second       // give up queueing and write anyway
+	notesLockStale     = 2 * time.Minute        // remove locks left by crashed writers
+	notesWriteAttempts = 6                      // git invocations per write
+	notesRetryDelay    = 50 * time.Millisecond  // first backoff, doubled per attempt
+	notesLockPoll      = 10 * time.Millisecond  // first queue poll, doubled up to notesLockPollMax
+	notesLockPollMax   = 200 * time.Millisecond // longest wait between queue polls
+)
+
+// notesMu serializes writers within this process, e.g. web server requests.
+var notesMu sync.Mutex
+
+// errLockTimeout is returned when the queue lock could not be acquired.
+var errLockTimeout = errors.New("timed out waiting for notes lock")
+
+// runNotesWrite runs a git command that updates a notes ref, holding the
+// write queue lock and retrying on lock contention. stdin may be nil.
+func runNotesWrite(stdin []byte, args ...string) error {
+	defer lockNotesWrites()()
+	return runNotesWriteLocked(stdin, args...)
+}
+
+// lockNotesWrites takes the write queue lock, within this process and
+// across processes, and returns a function that releases it.
+func lockNotesWrites() func() {
+	notesMu.Lock()
+	// Without the queue lock, the retries of runNotesWriteLocked still
+	// guard against contention
+	release, err := acquireNotesLock()
+	if err != nil {
+		release = func() {}
+	}
+	return func() {
+		release()
+		notesMu.Unlock()
+	}
+}
+
+// runNotesWriteLocked runs a git command that updates a notes ref, retrying
+// on lock contention. The caller holds the write queue lock.
+func runNotesWriteLocked(stdin []byte, args ...string) error {
+	delay := notesRetryDelay
+	for attempt := 1; ; attempt++ {
+		cmd := gitCommand(args...)
+		if stdin != nil {
+			cmd.Stdin = bytes.NewReader(stdin)
+		}
+		var stderr bytes.Buffer
+		cmd.Stderr = &stderr
+
+		err := cmd.Run()
+		if err == nil {
+			return nil
+		}
+		msg := strings.TrimSpace(stderr.String())
+		if attempt >= notesWriteAttempts || !isLockContention(msg) {
+			if msg == "" {
+				return err
+			}
+			return fmt.Errorf("%w: %s", err, msg)
+		}
+
+		time.Sleep(delay + rand.N(delay))
+		delay *= 2
+	}
+}
+
+// isLockContention reports whether git failed because another process held
+// a ref lock it needed: its lock file existed, or the ref moved while git
+// updated it. Other failures to create a lock file, such as a permission
+// error, are not retried.
+func isLockContention(stderr string) bool {
+	if strings.Contains(stderr, "Unable to create '") {
+		return strings.Contains(stderr, "': File exists")
+	}
+	return strings.Contains(stderr, "cannot lock ref")
+}
+
+// acquireNotesLock waits for the queue lock file and returns a function that
+// releases it.
+func acquireNotesLock() (func(), error) {
+	commonDir, err := RunGitCommand("rev-parse", "--git-common-dir")
+	if err != nil {
+		return nil, err
+	}
+	path, err := filepath.Abs(filepath.Join(commonDir, notesLockName))
+	if err != nil {
+		return nil, err
+	}
+	return acquireLockFile(path)
+}
+
+// acquireLockFile creates path exclusively, waiting while another writer
+// holds it. A lock older than notesLockStale is assumed abandoned and taken
+// over.
+func acquireLockFile(path string) (func(), error) {
+	deadline := time.Now().Add(notesLockTimeout)
+	poll := notesLockPoll
+	for {
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+		if err == nil {
+			fmt.Fprintf(f, "%d\n", os.Getpid())
+			f.Close()
+			return func() { os.Remove(path) }, nil
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return nil, err
+		}
+
+		if info, statErr := os.Stat(path); statErr == nil && time.Since(info.ModTime()) > notesLockStale {
+			os.Remove(path)
+			continue
+		}
+		if time.Now().After(deadline) {
+			return nil, errLockTimeout
+		}
+
+		time.Sleep(poll)
+		poll = min(poll*2, notesLockPollMax)
+	}
+}
@@ -0,0 +1,98 @@
+package git
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestAcquireLockFileWaitsForRelease(t *testing.T) {
+	path := filepath.Join(t.TempDir(), notesLockName)
+
+	release, err := acquireLockFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	acquired := make(chan struct{})
+	go func() {
+		release2, err := acquireLockFile(path)
+		if err != nil {
+			t.Error(err)
+			close(acquired)
+			return
+		}
+		release2()
+		close(acquired)
+	}()
+
+	select {
+	case <-acquired:
+		t.Fatal("second writer acquired the lock while it was held")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	release()
+	select {
+	case <-acquired:
+	case <-time.After(5 * time.Second):
+		t.Fatal("second writer did not acquire the lock after release")
+	}
+}
+
+func TestAcquireLockFileTakesOverStaleLock(t *testing.T) {
+	path := filepath.Join(t.TempDir(), notesLockName)
+	if err := os.WriteFile(path, []byte("1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	old := time.Now().Add(-notesLockStale - time.Minute)
+	if err := os.Chtimes(path, old, old); err != nil {
+		t.Fatal(err)
+	}
+
+	release, err := acquireLockFile(path)
+	if err != nil {
+		t.Fatalf("acquireLockFile() with stale lock: %v", err)
+	}
+	release()
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("lock file still present after release: %v", err)
+	}
+}
+
+func TestAcquireLockFileTimesOut(t *testing.T) {
+	orig := notesLockTimeout
+	notesLockTimeout = 30 * time.Millisecond
+	t.Cleanup(func() { notesLockTimeout = orig })
+
+	path := filepath.Join(t.TempDir(), notesLockName)
+	if err := os.WriteFile(path, []byte("1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := acquireLockFile(path); err != errLockTimeout {
+		t.Errorf("acquireLockFile() error = %v, want %v", err, errLockTimeout)
+	}
+}
+
+func TestIsLockContention(t *testing.T) {
+	tests := []struct {
+		stderr string
+		want   bool
+	}{
+		{"error: cannot lock ref 'refs/notes/shiftlog': is at abc but expected def", true},
+		{"fatal: Unable to create '/repo/.git/refs/notes/shiftlog.lock': File exists.", true},
+		{"error: cannot lock ref 'refs/notes/shiftlog': Unable to create '/repo/.git/refs/notes/shiftlog.lock': File exists.", true},
+		{"error: cannot lock ref 'refs/notes/shiftlog': Unable to create '/repo/.git/refs/notes/shiftlog.lock': Permission denied", false},
+		{"fatal: Unable to create '/repo/.git/index.lock': Permission denied", false},
+		{"fatal: cannot open '/repo/.git/shallow.lock': Read-only file system", false},
+		{"error: failed to resolve 'nope' as a valid ref.", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if got := isLockContention(tt.stderr); got != tt.want {
+			t.Errorf("isLockContention(%q) = %v, want %v", tt.stderr, got, tt.want)
+		}
+	}
+}
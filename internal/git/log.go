@@ -0,0 +1,204 @@
+package git
+
+import (
+	"bufio"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// LogCommit is a commit listed by ListCommits. Author identities are
+// mapped through the repository's .mailmap, as in git shortlog.
+type LogCommit struct {
+	SHA         string
+	Subject     string
+	Author      string
+	Date        string // committer date, git's ISO format
+	AuthorEmail string
+	Parents     int
+	// Files are the files the commit changed, with LogOptions.Stat. Merge
+	// commits have none.
+	Files []FileStat
+}
+
+// FileStat is the number of lines a commit added to and deleted from a
+// file. Binary files count no lines.
+type FileStat struct {
+	Path    string
+	Added   int
+	Deleted int
+}
+
+// LogOptions selects the commits listed by ListCommits.
+type LogOptions struct {
+	Ref    string // branch, range such as main..HEAD, or other revision to list from; HEAD if empty
+	Since  string // only commits more recent than this date, in any format git log --since accepts
+	Author string // only commits whose author, after .mailmap, matches this pattern
+	Stat   bool   // list the files each commit changed, in LogCommit.Files
+}
+
+// logFormat is the git log format of the lines parseLogLine parses.
+const logFormat = "--format=%H%x00%s%x00%aN%x00%ci%x00%aE%x00%P"
+
+// parseLogLine parses a line of git log output in logFormat.
+func parseLogLine(line string) (LogCommit, bool) {
+	parts := strings.SplitN(line, "\x00", 6)
+	if len(parts) < 6 {
+		return LogCommit{}, false
+	}
+	c := LogCommit{SHA: parts[0], Subject: parts[1], Author: parts[2], Date: parts[3], AuthorEmail: parts[4]}
+	c.Parents = len(strings.Fields(parts[5]))
+	return c, true
+}
+
+// parseNumstatLine parses a line of git log --numstat output, such as
+// "12\t3\tpath".
+func parseNumstatLine(line string) (FileStat, bool) {
+	parts := strings.SplitN(line, "\t", 3)
+	if len(parts) < 3 || parts[2] == "" {
+		return FileStat{}, false
+	}
+	// Binary files are listed as "-\t-\tpath"
+	added, _ := strconv.Atoi(parts[0])
+	deleted, _ := strconv.Atoi(parts[1])
+	return FileStat{Path: parts[2], Added: added, Deleted: deleted}, true
+}
+
+// DescribeCommits returns the given commits as ListCommits lists them,
+// keyed by SHA, read through a single git log rather than a git log per
+// commit. Commits missing from the repository are left out.
+func DescribeCommits(shas []string) (map[string]LogCommit, error) {
+	commits := make(map[string]LogCommit, len(shas))
+	if len(shas) == 0 {
+		return commits, nil
+	}
+	cmd := gitCommand("log", "--no-walk=unsorted", "--ignore-missing", "--stdin", logFormat)
+	cmd.Stdin = strings.NewReader(strings.Join(shas, "\n") + "\n")
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, err
+	}
+	for _, line := range strings.Split(string(out), "\n") {
+		if c, ok := parseLogLine(line); ok {
+			commits[c.SHA] = c
+		}
+	}
+	return commits, nil
+}
+
+// ListCommits lists the commits reachable from opts.Ref, newest first,
+// calling fn for each until it returns false.
+func ListCommits(opts LogOptions, fn func(LogCommit) bool) error {
+	ref := opts.Ref
+	if ref == "" {
+		ref = "HEAD"
+	}
+	args := []string{"log", logFormat}
+	if opts.Since != "" {
+		args = append(args, "--since="+opts.Since)
+	}
+	if opts.Author != "" {
+		args = append(args, "--author="+opts.Author)
+	}
+	if opts.Stat {
+		// Renamed files are listed as a deletion and an addition, so that
+		// each path is a plain path
+		args = append(args, "--numstat", "--no-renames")
+	}
+	args = append(args, ref, "--")
+
+	cmd := gitCommand(args...)
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	scanner := bufio.NewScanner(stdout)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	stopped := false
+	// With Stat, a commit's files follow it, so it is passed to fn once the
+	// next commit starts
+	var pending *LogCommit
+	flush := func() bool {
+		if pending == nil {
+			return true
+		}
+		c := *pending
+		pending = nil
+		return fn(c)
+	}
+	for scanner.Scan() {
+		line := scanner.Text()
+		if c, ok := parseLogLine(line); ok {
+			if !flush() {
+				stopped = true
+				break
+			}
+			pending = &c
+			continue
+		}
+		if pending != nil && opts.Stat {
+			if f, ok := parseNumstatLine(line); ok {
+				pending.Files = append(pending.Files, f)
+			}
+		}
+	}
+	if !stopped && scanner.Err() == nil && !flush() {
+		stopped = true
+	}
+
+	if stopped {
+		// Stop git log early; its exit status is moot
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
+		return nil
+	}
+	if err := scanner.Err(); err != nil {
+		_ = cmd.Wait()
+		return err
+	}
+	if err := cmd.Wait(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return fmt.Errorf("git log: %s", msg)
+		}
+		return err
+	}
+	return nil
+}
+
+// revertedPattern matches the line git revert adds to the message of a
+// revert commit.
+var revertedPattern = regexp.MustCompile(`This reverts commit ([0-9a-f]{40})`)
+
+// ListReverts returns the commits reachable from ref, HEAD when empty, that
+// a commit also reachable from it reverted, mapped to the newest revert of
+// each. Reverts are recognized by the line git revert adds to their
+// message.
+func ListReverts(ref string) (map[string]string, error) {
+	if ref == "" {
+		ref = "HEAD"
+	}
+	out, err := gitCommand("log", "--grep=This reverts commit", "--format=%H%x1f%B%x1e", ref, "--").Output()
+	if err != nil {
+		return nil, err
+	}
+	reverts := make(map[string]string)
+	for _, record := range strings.Split(string(out), "\x1e") {
+		sha, body, ok := strings.Cut(strings.TrimSpace(record), "\x1f")
+		if !ok {
+			continue
+		}
+		for _, m := range revertedPattern.FindAllStringSubmatch(body, -1) {
+			if _, seen := reverts[m[1]]; !seen {
+				reverts[m[1]] = sha
+			}
+		}
+	}
+	return reverts, nil
+}
@@ -0,0 +1,67 @@
+package git
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+const (
+	// LogAliasKey is the git alias shiftlog git-log-format --install adds:
+	// git shiftlog shows git log --oneline with conversations marked.
+	LogAliasKey = "alias.shiftlog"
+	// LogPagerKey is the pager of git log, which --install --pager points
+	// at shiftlog git-log-format so that plain git log is marked too.
+	LogPagerKey = "pager.log"
+)
+
+// logFormatCommand is how shiftlog's git config values run the decorator.
+const logFormatCommand = " git-log-format"
+
+// InstallLogFormat adds the git shiftlog alias to the repository's config
+// and, with pager, pipes git log itself through shiftlog git-log-format
+// before the user's pager. An existing pager.log that shiftlog did not set
+// is an error, so that it is not silently replaced.
+func InstallLogFormat(pager bool) error {
+	bin, err := resolveShiftlogBinary()
+	if err != nil {
+		return fmt.Errorf("failed to resolve shiftlog binary path: %w", err)
+	}
+	// The user's pager is looked up when the log is shown, with the less
+	// options git itself sets
+	userPager := `LESS=${LESS:-FRX} $(git var GIT_PAGER)`
+
+	alias := `!f() { git log --oneline --decorate --color=always "$@" | ` + bin + logFormatCommand + ` | ` + userPager + `; }; f`
+	if err := gitCommand("config", LogAliasKey, alias).Run(); err != nil {
+		return fmt.Errorf("failed to set %s: %w", LogAliasKey, err)
+	}
+	if !pager {
+		return nil
+	}
+
+	if current, _ := RunGitCommand("config", LogPagerKey); current != "" && !strings.Contains(current, logFormatCommand) {
+		return fmt.Errorf("%s is already set to %q; unset it to let shiftlog decorate git log", LogPagerKey, current)
+	}
+	if err := gitCommand("config", LogPagerKey, bin+logFormatCommand+" | "+userPager).Run(); err != nil {
+		return fmt.Errorf("failed to set %s: %w", LogPagerKey, err)
+	}
+	return nil
+}
+
+// UninstallLogFormat removes the alias and pager InstallLogFormat set,
+// leaving values shiftlog did not set alone.
+func UninstallLogFormat() error {
+	for _, key := range []string{LogAliasKey, LogPagerKey} {
+		value, err := RunGitCommand("config", key)
+		if err != nil || !strings.Contains(value, logFormatCommand) {
+			continue
+		}
+		// Exit status 5 means the key was not set
+		if err := gitCommand("config", "--unset", key).Run(); err != nil {
+			if exitErr, ok := err.(*exec.ExitError); !ok || exitErr.ExitCode() != 5 {
+				return fmt.Errorf("failed to unset %s: %w", key, err)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+package git
+
+import (
+	"strings"
+)
+
+// MergesRef is the git notes ref recording, on each merge commit, the
+// conversations of the commits it merged. It keeps a feature branch's
+// sessions discoverable from the mainline after the branch is deleted.
+const MergesRef = "refs/notes/shiftlog-merges"
+
+// MergesTrackingRef holds fetched remote merge records before merging.
+const MergesTrackingRef = "refs/notes/shiftlog-merges-remote"
+
+// HasMergeRecords reports whether any merge has been recorded locally.
+func HasMergeRecords() bool {
+	sha, err := refCommit(MergesRef)
+	return err == nil && sha != ""
+}
+
+// PushMergeRecords pushes the merges ref to the remote.
+// Returns ErrNonFastForward if the remote has diverged.
+func PushMergeRecords(remote string) error {
+	return pushNotesRef(remote, MergesRef)
+}
+
+// FetchMergeRecordsToTracking fetches remote merge records to the tracking ref.
+func FetchMergeRecordsToTracking(remote string) error {
+	return fetchNotesRef(remote, MergesRef, MergesTrackingRef)
+}
+
+// MergeMergeRecords merges fetched merge records into the local ref. Each
+// record is a single line, so cat_sort_uniq yields the union of both sides.
+func MergeMergeRecords() error {
+	return mergeNotesRef(MergesRef, MergesTrackingRef)
+}
+
+// IsMergeCommit reports whether the commit has more than one parent.
+func IsMergeCommit(commitSHA string) bool {
+	parents, err := GetParentCommits(commitSHA)
+	return err == nil && len(parents) > 1
+}
+
+// IsHeadNewMerge reports whether HEAD is a merge commit made on top of the
+// previous HEAD, as git merge leaves it when it creates a merge commit. A
+// fast-forward onto someone else's merge commit does not count.
+func IsHeadNewMerge() bool {
+	parents, err := GetParentCommits("HEAD")
+	if err != nil || len(parents) < 2 {
+		return false
+	}
+	origHead, err := RunGitCommand("rev-parse", "-q", "--verify", "ORIG_HEAD")
+	return err == nil && origHead == parents[0]
+}
+
+// ListMergedCommits returns the commits a merge brought in: those reachable
+// from its second and later parents but not from its first parent, newest
+// first. Returns nil for a commit that is not a merge.
+func ListMergedCommits(mergeSHA string) ([]string, error) {
+	parents, err := GetParentCommits(mergeSHA)
+	if err != nil || len(parents) < 2 {
+		return nil, err
+	}
+
+	args := append([]string{"rev-list"}, parents[1:]...)
+	args = append(args, "--not", parents[0])
+	output, err := gitCommand(args...).Output()
+	if err != nil {
+		return nil, err
+	}
+
+	var commits []string
+	for _, line := range strings.Split(strings.TrimSpace(string(output)), "\n") {
+		if line != "" {
+			commits = append(commits, line)
+		}
+	}
+	return commits, nil
+}
@@ -1,8 +1,14 @@
 package git
 
 import (
+	"bufio"
+	"bytes"
 	"errors"
+	"fmt"
+	"io"
+	"os"
 	"os/exec"
+	"strconv"
 	"strings"
 )
 
@@ -13,6 +19,17 @@ const NotesRef = "refs/notes/shiftlog"
 // NotesTrackingRef is the ref used to hold fetched remote notes before merging.
 const NotesTrackingRef = "refs/notes/shiftlog-remote"
 
+// CheckpointsRef holds the conversation checkpoints taken by shiftlog watch
+// between commits, keyed by the commit HEAD pointed at. The next commit folds
+// them into its conversation note. The ref is local to the clone and not
+// synced.
+const CheckpointsRef = "refs/notes/shiftlog-wip"
+
+// PrivateRef holds the conversations stored as private, keyed by commit
+// like NotesRef. The ref is local to the clone and never synced; shiftlog
+// publish moves a conversation from it to NotesRef.
+const PrivateRef = "refs/notes/shiftlog-private"
+
 // LegacyNotesRef is the old ref name used before multi-agent support.
 // Used by the migrate command to upgrade existing repos.
 const LegacyNotesRef = "refs/notes/claude-conversations"
@@ -21,65 +38,48 @@ const LegacyNotesRef = "refs/notes/claude-conversations"
 var ErrNonFastForward = errors.New("non-fast-forward update: remote notes have diverged, run 'shiftlog sync pull' first")
 
 // AddNote adds a note to a commit.
-// Content is piped via stdin (-F -) to avoid ARG_MAX limits on large transcripts.
 func AddNote(commitSHA string, content []byte) error {
-	cmd := exec.Command("git", "notes", "--ref", NotesRef, "add", "-f", "-F", "-", commitSHA)
-	cmd.Stdin = strings.NewReader(string(content))
-	return cmd.Run()
+	return AddNoteToRef(NotesRef, commitSHA, content)
 }
 
 // GetNote retrieves a note from a commit
 func GetNote(commitSHA string) ([]byte, error) {
-	cmd := exec.Command("git", "notes", "--ref", NotesRef, "show", commitSHA)
+	cmd := gitCommand("notes", "--ref", NotesRef, "show", commitSHA)
 	return cmd.Output()
 }
 
 // HasNote checks if a commit has a conversation note
 func HasNote(commitSHA string) bool {
-	cmd := exec.Command("git", "notes", "--ref", NotesRef, "show", commitSHA)
+	cmd := gitCommand("notes", "--ref", NotesRef, "show", commitSHA)
 	return cmd.Run() == nil
 }
 
 // ListCommitsWithNotes returns a list of commit SHAs that have conversation notes
 // sorted in reverse chronological order (matching git log)
 func ListCommitsWithNotes() ([]string, error) {
-	cmd := exec.Command("git", "notes", "--ref", NotesRef, "list")
-	output, err := cmd.Output()
+	commitSet, err := ListAllCommitsWithNotes("")
 	if err != nil {
-		// No notes exist yet - this is not an error
-		if exitErr, ok := err.(*exec.ExitError); ok && exitErr.ExitCode() == 1 {
-			return nil, nil
-		}
 		return nil, err
 	}
+	return FilterReachableFromHead(commitSet)
+}
 
-	// Build a set of commits with notes
-	commitSet := make(map[string]bool)
-	lines := strings.Split(strings.TrimSpace(string(output)), "\n")
-	for _, line := range lines {
-		if line == "" {
-			continue
-		}
-		// Format: "note_sha commit_sha"
-		parts := strings.Fields(line)
-		if len(parts) >= 2 {
-			commitSet[parts[1]] = true
-		}
-	}
-
+// FilterReachableFromHead returns the commits in commitSet that are
+// reachable from HEAD, sorted in reverse chronological order (matching git log).
+func FilterReachableFromHead(commitSet map[string]bool) ([]string, error) {
 	if len(commitSet) == 0 {
 		return nil, nil
 	}
 
 	// Use git rev-list to sort commits in reverse chronological order
 	// HEAD scopes to the current branch, --topo-order maintains parent-child relationships
-	cmd = exec.Command("git", "rev-list", "HEAD", "--topo-order")
-	output, err = cmd.Output()
+	cmd := gitCommand("rev-list", "HEAD", "--topo-order")
+	output, err := cmd.Output()
 	if err != nil {
 		return nil, err
 	}
 
-	// Filter to only commits that have notes, preserving git's order
+	// Filter to only commits in the set, preserving git's order
 	var commits []string
 	for _, sha := range strings.Split(strings.TrimSpace(string(output)), "\n") {
 		if sha == "" {
@@ -98,7 +98,7 @@ func ListCommitsWithNotes() ([]string, error) {
 // not filter through `git rev-list HEAD`.
 // If repoDir is non-empty, the git command runs in that directory.
 func ListAllCommitsWithNotes(repoDir string) (map[string]bool, error) {
-	cmd := exec.Command("git", "notes", "--ref", NotesRef, "list")
+	cmd := gitCommand("notes", "--ref", NotesRef, "list")
 	if repoDir != "" {
 		cmd.Dir = repoDir
 	}
@@ -126,8 +126,19 @@ func ListAllCommitsWithNotes(repoDir string) (map[string]bool, error) {
 // PushNotes pushes notes to the remote.
 // Returns ErrNonFastForward if the remote has diverged.
 func PushNotes(remote string) error {
+	return pushNotesRef(remote, NotesRef)
+}
+
+// PushNotesTo pushes the local notes to remoteRef on the remote, such as a
+// contributor's own notes ref where the shared one is not writable.
+// Returns ErrNonFastForward if the remote has diverged.
+func PushNotesTo(remote, remoteRef string) error {
+	return pushNotesRef(remote, NotesRef+":"+remoteRef)
+}
+
+func pushNotesRef(remote, ref string) error {
 	// Use --no-verify to prevent pre-push hook from triggering recursively
-	cmd := exec.Command("git", "push", "--no-verify", remote, NotesRef)
+	cmd := gitCommand("push", "--no-verify", remote, ref)
 	output, err := cmd.CombinedOutput()
 	if err != nil {
 		if strings.Contains(string(output), "non-fast-forward") ||
@@ -144,7 +155,20 @@ func PushNotes(remote string) error {
 // touching the local notes ref. This is the first step of the
 // fetch-then-merge sync flow.
 func FetchNotesToTracking(remote string) error {
-	cmd := exec.Command("git", "fetch", remote, NotesRef+":"+NotesTrackingRef)
+	return fetchNotesRef(remote, NotesRef, NotesTrackingRef)
+}
+
+// FetchNotesFromToTracking fetches the remote's remoteRef, such as a
+// contributor's notes ref, to the tracking ref, to be merged like the
+// shared notes.
+func FetchNotesFromToTracking(remote, remoteRef string) error {
+	return fetchNotesRef(remote, remoteRef, NotesTrackingRef)
+}
+
+func fetchNotesRef(remote, ref, tracking string) error {
+	// Force the update: the tracking ref only stages the remote's notes for
+	// merging, and notes from another remote may not be its ancestors
+	cmd := gitCommand("fetch", remote, "+"+ref+":"+tracking)
 	return cmd.Run()
 }
 
@@ -152,22 +176,54 @@ func FetchNotesToTracking(remote string) error {
 // git notes merge. The cat_sort_uniq strategy concatenates notes when
 // two developers have annotated the same commit SHA.
 func MergeNotes() error {
-	cmd := exec.Command("git", "notes", "--ref", NotesRef, "merge", "--strategy=cat_sort_uniq", NotesTrackingRef)
-	return cmd.Run()
+	return mergeNotesRef(NotesRef, NotesTrackingRef)
+}
+
+// MergeNotesInto merges the tracking ref into ref instead of the local
+// notes ref, keeping the notes fetched from a contributor's ref apart.
+func MergeNotesInto(ref string) error {
+	return mergeNotesRef(ref, NotesTrackingRef)
+}
+
+func mergeNotesRef(ref, tracking string) error {
+	return runNotesWrite(nil, "notes", "--ref", ref, "merge", "--strategy=cat_sort_uniq", tracking)
+}
+
+// ValidateContributorRef checks that ref can hold a contributor's
+// conversations: a notes ref other than those shiftlog keeps its own data
+// in.
+func ValidateContributorRef(ref string) error {
+	if !strings.HasPrefix(ref, "refs/notes/") {
+		return fmt.Errorf("%s is not a notes ref: it must start with refs/notes/", ref)
+	}
+	if err := gitCommand("check-ref-format", ref).Run(); err != nil {
+		return fmt.Errorf("%s is not a valid ref name", ref)
+	}
+	switch ref {
+	case NotesRef, NotesTrackingRef, NotesPreRestoreRef, NotesBundleRef, LegacyNotesRef,
+		CheckpointsRef, PrivateRef,
+		AnnotationsRef, AnnotationsTrackingRef,
+		BlobsRef, BlobsTrackingRef,
+		DictionariesRef, DictionariesTrackingRef,
+		MergesRef, MergesTrackingRef,
+		OmissionsRef, OmissionsTrackingRef,
+		ReadableRef, ReadableTrackingRef:
+		return fmt.Errorf("%s holds shiftlog's own notes and cannot be a contributor ref", ref)
+	}
+	return nil
 }
 
 // CopyNote copies a note from one commit to another.
 // If the destination already has a note, the copy is forced (overwritten).
 func CopyNote(fromSHA, toSHA string) error {
-	cmd := exec.Command("git", "notes", "--ref", NotesRef, "copy", "-f", fromSHA, toSHA)
-	return cmd.Run()
+	return runNotesWrite(nil, "notes", "--ref", NotesRef, "copy", "-f", fromSHA, toSHA)
 }
 
 // FindOrphanedNotes returns notes whose commits are not reachable from any branch.
 // Returns a map of commit SHA → note blob SHA.
 func FindOrphanedNotes() (map[string]string, error) {
 	// List all notes
-	cmd := exec.Command("git", "notes", "--ref", NotesRef, "list")
+	cmd := gitCommand("notes", "--ref", NotesRef, "list")
 	output, err := cmd.Output()
 	if err != nil {
 		if exitErr, ok := err.(*exec.ExitError); ok && exitErr.ExitCode() == 1 {
@@ -190,11 +246,11 @@ func FindOrphanedNotes() (map[string]string, error) {
 		commitSHA := parts[1]
 
 		// Check if commit is reachable from any branch
-		cmd := exec.Command("git", "branch", "--contains", commitSHA)
+		cmd := gitCommand("branch", "--contains", commitSHA)
 		branchOutput, err := cmd.Output()
 		if err != nil || strings.TrimSpace(string(branchOutput)) == "" {
 			// Not on any branch — check the object still exists
-			checkCmd := exec.Command("git", "cat-file", "-t", commitSHA)
+			checkCmd := gitCommand("cat-file", "-t", commitSHA)
 			if checkCmd.Run() == nil {
 				orphaned[commitSHA] = noteSHA
 			}
@@ -207,8 +263,8 @@ func FindOrphanedNotes() (map[string]string, error) {
 // PatchID computes the git patch-id for a commit.
 // The patch-id is a stable hash of the commit's diff, independent of the SHA.
 func PatchID(commitSHA string) (string, error) {
-	diffCmd := exec.Command("git", "diff-tree", "-p", commitSHA)
-	patchCmd := exec.Command("git", "patch-id")
+	diffCmd := gitCommand("diff-tree", "-p", commitSHA)
+	patchCmd := gitCommand("patch-id")
 
 	pipe, err := diffCmd.StdoutPipe()
 	if err != nil {
@@ -250,7 +306,7 @@ func PatchID(commitSHA string) (string, error) {
 
 // ListCommitsInRange returns commit SHAs in the given range (e.g. "ORIG_HEAD..HEAD").
 func ListCommitsInRange(rangeSpec string) ([]string, error) {
-	cmd := exec.Command("git", "rev-list", rangeSpec)
+	cmd := gitCommand("rev-list", rangeSpec)
 	output, err := cmd.Output()
 	if err != nil {
 		return nil, err
@@ -267,7 +323,7 @@ func ListCommitsInRange(rangeSpec string) ([]string, error) {
 
 // ListAllBranchCommits returns all commit SHAs reachable from any branch.
 func ListAllBranchCommits() ([]string, error) {
-	cmd := exec.Command("git", "rev-list", "--all")
+	cmd := gitCommand("rev-list", "--all")
 	output, err := cmd.Output()
 	if err != nil {
 		return nil, err
@@ -281,3 +337,235 @@ func ListAllBranchCommits() ([]string, error) {
 	}
 	return commits, nil
 }
+
+// ListCommitsTouchingPath returns the SHAs of commits reachable from HEAD that
+// modify path, newest first, following renames. path is relative to the
+// repository root.
+func ListCommitsTouchingPath(path string) ([]string, error) {
+	cmd := gitCommand("log", "--follow", "--format=%H", "--", ":(top)"+path)
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, err
+	}
+
+	var commits []string
+	for _, line := range strings.Split(strings.TrimSpace(string(output)), "\n") {
+		if line != "" {
+			commits = append(commits, line)
+		}
+	}
+	return commits, nil
+}
+
+// ListNoteBlobs returns the notes under ref as a map of commit SHA to note
+// blob SHA. A missing ref yields an empty map.
+func ListNoteBlobs(ref string) (map[string]string, error) {
+	cmd := gitCommand("notes", "--ref", ref, "list")
+	output, err := cmd.Output()
+	if err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok && exitErr.ExitCode() == 1 {
+			return map[string]string{}, nil
+		}
+		return nil, err
+	}
+
+	blobs := make(map[string]string)
+	for _, line := range strings.Split(strings.TrimSpace(string(output)), "\n") {
+		parts := strings.Fields(line)
+		if len(parts) >= 2 {
+			blobs[parts[1]] = parts[0]
+		}
+	}
+	return blobs, nil
+}
+
+// NotesRefCommit returns the commit a notes ref points to, or "" if the
+// ref does not exist.
+func NotesRefCommit(ref string) (string, error) {
+	return refCommit(ref)
+}
+
+// ChangedNotes returns the commits whose note differs between two commits
+// of a notes ref: added, changed or removed from one to the other.
+func ChangedNotes(from, to string) ([]string, error) {
+	out, err := RunGitCommand("diff-tree", "-r", "--no-renames", "--name-only", from, to)
+	if err != nil {
+		return nil, err
+	}
+	var commits []string
+	for _, path := range strings.Fields(out) {
+		// Notes are fanned out in subtrees, e.g. ab/cdef... for abcdef...
+		if sha := strings.ReplaceAll(path, "/", ""); len(sha) == 40 {
+			commits = append(commits, sha)
+		}
+	}
+	return commits, nil
+}
+
+// ReadNotes returns the content of every note under ref, keyed by commit
+// SHA. The notes are listed once and read through a single git cat-file
+// --batch, rather than a git notes show per commit.
+func ReadNotes(ref string) (map[string][]byte, error) {
+	blobs, err := ListNoteBlobs(ref)
+	if err != nil || len(blobs) == 0 {
+		return map[string][]byte{}, err
+	}
+
+	shas := make([]string, 0, len(blobs))
+	for _, blob := range blobs {
+		shas = append(shas, blob)
+	}
+	contents, err := ReadBlobs(shas)
+	if err != nil {
+		return nil, err
+	}
+	notes := make(map[string][]byte, len(blobs))
+	for commit, blob := range blobs {
+		content, ok := contents[blob]
+		if !ok {
+			return nil, fmt.Errorf("reading note of %s: blob %s is missing", commit, blob)
+		}
+		notes[commit] = content
+	}
+	return notes, nil
+}
+
+// ReadBlobs returns the content of the given blobs, keyed by SHA, read
+// through a single git cat-file --batch. Blobs missing from the object
+// database are left out.
+func ReadBlobs(shas []string) (map[string][]byte, error) {
+	if len(shas) == 0 {
+		return map[string][]byte{}, nil
+	}
+	var input strings.Builder
+	for _, sha := range shas {
+		input.WriteString(sha + "\n")
+	}
+	cmd := gitCommand("cat-file", "--batch")
+	cmd.Stdin = strings.NewReader(input.String())
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, err
+	}
+
+	// Each object is "<sha> blob <size>\n<content>\n", or "<sha> missing\n",
+	// in input order
+	contents := make(map[string][]byte, len(shas))
+	r := bufio.NewReader(bytes.NewReader(output))
+	for _, sha := range shas {
+		header, err := r.ReadString('\n')
+		if err != nil {
+			return nil, fmt.Errorf("reading blob %s: %w", sha, err)
+		}
+		fields := strings.Fields(header)
+		if len(fields) == 2 && fields[1] == "missing" {
+			continue
+		}
+		if len(fields) != 3 {
+			return nil, fmt.Errorf("reading blob %s: %s", sha, strings.TrimSpace(header))
+		}
+		size, err := strconv.Atoi(fields[2])
+		if err != nil {
+			return nil, fmt.Errorf("reading blob %s: bad size %q", sha, fields[2])
+		}
+		content := make([]byte, size+1)
+		if _, err := io.ReadFull(r, content); err != nil {
+			return nil, fmt.Errorf("reading blob %s: %w", sha, err)
+		}
+		contents[sha] = content[:size]
+	}
+	return contents, nil
+}
+
+// GetNoteFromRef retrieves the note for a commit from the given notes ref.
+func GetNoteFromRef(ref, commitSHA string) ([]byte, error) {
+	cmd := gitCommand("notes", "--ref", ref, "show", commitSHA)
+	return cmd.Output()
+}
+
+// AddNoteToRef adds or replaces the note for a commit in the given notes ref.
+// Content is piped via stdin (-F -) to avoid ARG_MAX limits on large transcripts.
+// A note that already reads the same is left as it is.
+func AddNoteToRef(ref, commitSHA string, content []byte) error {
+	return UpdateNoteInRef(ref, commitSHA, func([]byte) ([]byte, error) {
+		return content, nil
+	})
+}
+
+// readNoteIfAny returns the note for a commit in the given notes ref, nil
+// if it has none. Unlike GetNoteFromRef, it tells a missing note from a
+// failure to read one.
+func readNoteIfAny(ref, commitSHA string) ([]byte, error) {
+	cmd := gitCommand("notes", "--ref", ref, "show", commitSHA)
+	// git's own messages, whatever the user's locale
+	cmd.Env = append(os.Environ(), "LC_ALL=C")
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err == nil {
+		return out, nil
+	}
+	msg := strings.TrimSpace(stderr.String())
+	if strings.HasPrefix(msg, "error: no note found for object") {
+		return nil, nil
+	}
+	if msg == "" {
+		return nil, fmt.Errorf("could not read the note of %s: %w", commitSHA, err)
+	}
+	return nil, fmt.Errorf("could not read the note of %s: %w: %s", commitSHA, err, msg)
+}
+
+// UpdateNoteInRef replaces the note for a commit in the given notes ref with
+// what update returns for its current content, nil if it has none. It
+// holds the write queue lock from the read to the write, so that writers
+// adding to the same note, such as an agent hook and the post-commit hook
+// of one commit, do not overwrite each other. Nothing is written when
+// update returns nil or the current content.
+func UpdateNoteInRef(ref, commitSHA string, update func(existing []byte) ([]byte, error)) error {
+	defer lockNotesWrites()()
+
+	// A note that cannot be read is not replaced as if there were none
+	existing, err := readNoteIfAny(ref, commitSHA)
+	if err != nil {
+		return err
+	}
+	content, err := update(existing)
+	if err != nil {
+		return err
+	}
+	// git strips the surrounding blank lines of what it stores
+	if content == nil || (existing != nil && bytes.Equal(bytes.TrimSpace(content), bytes.TrimSpace(existing))) {
+		return nil
+	}
+	return runNotesWriteLocked(content, "notes", "--ref", ref, "add", "-f", "-F", "-", commitSHA)
+}
+
+// RemoveNoteFromRef removes the note for a commit from the given notes ref.
+// A missing note is not an error.
+func RemoveNoteFromRef(ref, commitSHA string) error {
+	return runNotesWrite(nil, "notes", "--ref", ref, "remove", "--ignore-missing", commitSHA)
+}
+
+// ShiftlogRefs returns the local refs holding shiftlog data: the
+// conversation notes and their tracking, private, checkpoint, annotation,
+// merge, omission, pre-restore and bundle refs, and the refs keeping
+// checkpoint snapshots and imported commits alive.
+func ShiftlogRefs() ([]string, error) {
+	out, err := RunGitCommand("for-each-ref", "--format=%(refname)", "refs/notes/", "refs/shiftlog/")
+	if err != nil {
+		return nil, err
+	}
+	var refs []string
+	for _, ref := range strings.Split(out, "\n") {
+		if ref == NotesRef || strings.HasPrefix(ref, NotesRef+"-") || strings.HasPrefix(ref, "refs/shiftlog/") {
+			refs = append(refs, ref)
+		}
+	}
+	return refs, nil
+}
+
+// DeleteRef deletes a ref and its reflog.
+func DeleteRef(ref string) error {
+	_, err := RunGitCommand("update-ref", "-d", ref)
+	return err
+}
@@ -0,0 +1,52 @@
+package git
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// gitIn runs git in the current directory and returns its trimmed output.
+func gitIn(t *testing.T, args ...string) string {
+	t.Helper()
+	out, err := exec.Command("git", args...).CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %s: %v: %s", args[0], err, out)
+	}
+	return strings.TrimSpace(string(out))
+}
+
+func TestUpdateNoteInRefKeepsUnreadableNote(t *testing.T) {
+	t.Chdir(t.TempDir())
+	gitIn(t, "init", "-q")
+	gitIn(t, "config", "user.name", "Test")
+	gitIn(t, "config", "user.email", "test@example.com")
+	gitIn(t, "commit", "-q", "--allow-empty", "-m", "work")
+	commit := gitIn(t, "rev-parse", "HEAD")
+	const ref = "refs/notes/test"
+
+	update := func(existing []byte) ([]byte, error) {
+		return append(existing, "line\n"...), nil
+	}
+	if err := UpdateNoteInRef(ref, commit, update); err != nil {
+		t.Fatalf("UpdateNoteInRef without a note: %v", err)
+	}
+	if note := gitIn(t, "notes", "--ref", ref, "show", commit); note != "line" {
+		t.Fatalf("note = %q, want %q", note, "line")
+	}
+
+	// Lose the note's blob, as a broken clone would
+	blob := gitIn(t, "notes", "--ref", ref, "list", commit)
+	notesTree := gitIn(t, "rev-parse", ref)
+	if err := os.Remove(filepath.Join(".git", "objects", blob[:2], blob[2:])); err != nil {
+		t.Fatal(err)
+	}
+	if err := UpdateNoteInRef(ref, commit, update); err == nil {
+		t.Fatal("UpdateNoteInRef with an unreadable note succeeded, want an error")
+	}
+	if after := gitIn(t, "rev-parse", ref); after != notesTree {
+		t.Errorf("UpdateNoteInRef replaced an unreadable note")
+	}
+}
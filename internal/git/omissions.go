@@ -0,0 +1,85 @@
+package git
+
+import (
+	"os/exec"
+	"strings"
+)
+
+// OmissionsRef is the git notes ref recording, on commits whose author had
+// turned capture off, that a conversation was intentionally not stored. It
+// lets coverage reports tell opt-outs from conversations that were lost.
+const OmissionsRef = "refs/notes/shiftlog-omissions"
+
+// OmissionsTrackingRef holds fetched remote omissions before merging.
+const OmissionsTrackingRef = "refs/notes/shiftlog-omissions-remote"
+
+// OptOutKey is the git config key set by shiftlog optout, in the
+// repository's config or with --global in the user's.
+const OptOutKey = "shiftlog.optout"
+
+// HasOmissions reports whether any omission has been recorded locally.
+func HasOmissions() bool {
+	sha, err := refCommit(OmissionsRef)
+	return err == nil && sha != ""
+}
+
+// PushOmissions pushes the omissions ref to the remote.
+// Returns ErrNonFastForward if the remote has diverged.
+func PushOmissions(remote string) error {
+	return pushNotesRef(remote, OmissionsRef)
+}
+
+// FetchOmissionsToTracking fetches remote omissions to the tracking ref.
+func FetchOmissionsToTracking(remote string) error {
+	return fetchNotesRef(remote, OmissionsRef, OmissionsTrackingRef)
+}
+
+// MergeOmissions merges fetched omissions into the local ref. Each omission
+// is a single line, so cat_sort_uniq yields the union of both sides.
+func MergeOmissions() error {
+	return mergeNotesRef(OmissionsRef, OmissionsTrackingRef)
+}
+
+// ListOmittedCommits returns the commits with a recorded omission.
+func ListOmittedCommits() (map[string]bool, error) {
+	commits := make(map[string]bool)
+	if !HasOmissions() {
+		return commits, nil
+	}
+	out, err := gitCommand("notes", "--ref", OmissionsRef, "list").Output()
+	if err != nil {
+		return nil, err
+	}
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		if parts := strings.Fields(line); len(parts) == 2 {
+			commits[parts[1]] = true
+		}
+	}
+	return commits, nil
+}
+
+// IsOptedOut reports whether shiftlog optout is in effect, in the
+// repository's or the user's git config.
+func IsOptedOut() bool {
+	value, err := RunGitCommand("config", "--type=bool", OptOutKey)
+	return err == nil && value == "true"
+}
+
+// SetOptOut turns capture off for the current user, in the repository's git
+// config, or in the user's global config when global is set. Opting back in
+// removes the key.
+func SetOptOut(optOut, global bool) error {
+	args := []string{"config"}
+	if global {
+		args = append(args, "--global")
+	}
+	if optOut {
+		return gitCommand(append(args, "--type=bool", OptOutKey, "true")...).Run()
+	}
+	err := gitCommand(append(args, "--unset", OptOutKey)...).Run()
+	if exitErr, ok := err.(*exec.ExitError); ok && exitErr.ExitCode() == 5 {
+		// The key was not set
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,36 @@
+package git
+
+// ReadableRef is the git notes ref holding a plain-text description of
+// each commit's conversations, for git log --notes=shiftlog-readable to
+// show to those without shiftlog, where the conversation notes themselves
+// are JSON.
+const ReadableRef = "refs/notes/shiftlog-readable"
+
+// ReadableTrackingRef holds fetched remote readable notes before merging.
+const ReadableTrackingRef = "refs/notes/shiftlog-readable-remote"
+
+// HasReadableNotes reports whether any readable note has been written
+// locally.
+func HasReadableNotes() bool {
+	sha, err := refCommit(ReadableRef)
+	return err == nil && sha != ""
+}
+
+// PushReadableNotes pushes the readable notes ref to the remote.
+// Returns ErrNonFastForward if the remote has diverged.
+func PushReadableNotes(remote string) error {
+	return pushNotesRef(remote, ReadableRef)
+}
+
+// FetchReadableNotesToTracking fetches remote readable notes to the
+// tracking ref.
+func FetchReadableNotesToTracking(remote string) error {
+	return fetchNotesRef(remote, ReadableRef, ReadableTrackingRef)
+}
+
+// MergeReadableNotes merges fetched readable notes into the local ref. The
+// notes are rewritten from the conversations, so a commit described on
+// both sides keeps the local description rather than a concatenation.
+func MergeReadableNotes() error {
+	return runNotesWrite(nil, "notes", "--ref", ReadableRef, "merge", "--strategy=ours", ReadableTrackingRef)
+}
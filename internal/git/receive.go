@@ -0,0 +1,105 @@
+package git
+
+import (
+	"bufio"
+	"io"
+	"strings"
+)
+
+// RefUpdate is one line of the input git passes to a pre-receive hook.
+type RefUpdate struct {
+	OldRev string
+	NewRev string
+	Ref    string
+}
+
+// IsDelete reports whether the update deletes the ref.
+func (u RefUpdate) IsDelete() bool {
+	return isZeroRev(u.NewRev)
+}
+
+// isZeroRev reports whether rev is the all-zero object ID git uses for a
+// missing side of a ref update.
+func isZeroRev(rev string) bool {
+	return strings.Trim(rev, "0") == ""
+}
+
+// ParseRefUpdates reads "<old> <new> <ref>" lines as given to pre-receive.
+func ParseRefUpdates(r io.Reader) ([]RefUpdate, error) {
+	var updates []RefUpdate
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 3 {
+			continue
+		}
+		updates = append(updates, RefUpdate{OldRev: fields[0], NewRev: fields[1], Ref: fields[2]})
+	}
+	return updates, scanner.Err()
+}
+
+// PushedNote is a note blob brought to the repository by a push.
+type PushedNote struct {
+	Commit string // annotated commit SHA
+	Blob   string // note blob SHA
+}
+
+// PushedNotes returns the note blobs an update of a notes ref brings to the
+// repository. This covers every notes commit in the push, so a note that is
+// added and removed again within it is still reported; notes the repository
+// already has are left out, so old notes never block a push. Must run
+// before the refs are updated, as in a pre-receive hook.
+func PushedNotes(u RefUpdate) ([]PushedNote, error) {
+	if u.IsDelete() {
+		return nil, nil
+	}
+
+	out, err := gitCommand("rev-list", "--objects", u.NewRev, "--not", "--all").Output()
+	if err != nil {
+		return nil, err
+	}
+
+	var notes []PushedNote
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		// Format: "<sha> <path>" for trees and blobs, path fanned out as ab/cdef...
+		sha, path, ok := strings.Cut(line, " ")
+		if !ok {
+			continue
+		}
+		commit := strings.ReplaceAll(path, "/", "")
+		if !isObjectID(commit) {
+			// Fan-out directories and stray files are not notes
+			continue
+		}
+		notes = append(notes, PushedNote{Commit: commit, Blob: sha})
+	}
+	return notes, nil
+}
+
+// isObjectID reports whether s is a full SHA-1 or SHA-256 object ID.
+func isObjectID(s string) bool {
+	return (len(s) == 40 || len(s) == 64) && isHex(s)
+}
+
+// isHex reports whether s consists of lowercase hex digits only.
+func isHex(s string) bool {
+	for _, c := range s {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+// ReadBlob returns the content of a blob object.
+func ReadBlob(blobSHA string) ([]byte, error) {
+	return gitCommand("cat-file", "blob", blobSHA).Output()
+}
+
+// IsGitDir reports whether the current directory is inside a git repository,
+// bare or not. Server-side hooks run in bare repositories, where
+// RequireGitRepo does not apply.
+func IsGitDir() bool {
+	_, err := RunGitCommand("rev-parse", "--git-dir")
+	return err == nil
+}
@@ -0,0 +1,160 @@
+package git
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// NotesReflogPattern matches the shiftlog notes refs whose reflogs are kept
+// from expiring, so that 'shiftlog recover' can find lost notes.
+const NotesReflogPattern = "refs/notes/shiftlog*"
+
+// NotesGCSettings are the git config settings that keep old notes commits
+// from being pruned by git gc.
+var NotesGCSettings = map[string]string{
+	"gc." + NotesReflogPattern + ".reflogExpire":            "never",
+	"gc." + NotesReflogPattern + ".reflogExpireUnreachable": "never",
+}
+
+// NoteVersion is a note found in a notes commit that is no longer the tip
+// of the notes ref.
+type NoteVersion struct {
+	Commit      string // annotated commit SHA
+	Blob        string // note blob SHA
+	NotesCommit string // notes commit holding this version
+}
+
+// FindNoteVersions returns the notes recorded in notes commits from the
+// reflogs of the shiftlog notes refs and in unreachable notes commits,
+// newest notes commit first. These are the notes a force-push, a bad merge
+// or a reset of the notes ref can lose.
+func FindNoteVersions() ([]NoteVersion, error) {
+	// Reflog order, newest first, breaks ties between commits made in the
+	// same second
+	var candidates []string
+	seen := map[string]bool{}
+	add := func(sha string) {
+		if !seen[sha] {
+			seen[sha] = true
+			candidates = append(candidates, sha)
+		}
+	}
+	for _, ref := range []string{NotesRef, NotesTrackingRef, NotesPreRestoreRef} {
+		out, err := gitCommand("log", "-g", "--format=%H", ref, "--").Output()
+		if err != nil {
+			// No reflog for this ref
+			continue
+		}
+		for _, sha := range strings.Fields(string(out)) {
+			add(sha)
+		}
+	}
+
+	unreachable, err := unreachableCommits()
+	if err != nil {
+		return nil, err
+	}
+	for _, sha := range unreachable {
+		if isNotesCommit(sha) {
+			add(sha)
+		}
+	}
+
+	commits, err := sortByCommitDate(candidates)
+	if err != nil {
+		return nil, err
+	}
+
+	var versions []NoteVersion
+	for _, notesCommit := range commits {
+		blobs, err := noteBlobsAt(notesCommit)
+		if err != nil {
+			// Trees of a partly pruned commit may be gone
+			continue
+		}
+		for sha, blob := range blobs {
+			if !isObjectID(sha) {
+				continue
+			}
+			versions = append(versions, NoteVersion{Commit: sha, Blob: blob, NotesCommit: notesCommit})
+		}
+	}
+	return versions, nil
+}
+
+// unreachableCommits lists commits no ref or reflog points to.
+func unreachableCommits() ([]string, error) {
+	out, err := gitCommand("fsck", "--unreachable", "--no-reflogs", "--no-progress").Output()
+	if err != nil && len(out) == 0 {
+		return nil, err
+	}
+	var commits []string
+	for _, line := range strings.Split(string(out), "\n") {
+		// Format: "unreachable commit <sha>"
+		fields := strings.Fields(line)
+		if len(fields) == 3 && fields[0] == "unreachable" && fields[1] == "commit" {
+			commits = append(commits, fields[2])
+		}
+	}
+	return commits, nil
+}
+
+// isNotesCommit reports whether a commit's tree has the layout of a notes
+// tree: only object IDs and two-character fan-out directories.
+func isNotesCommit(sha string) bool {
+	out, err := gitCommand("ls-tree", "--name-only", sha).Output()
+	if err != nil {
+		return false
+	}
+	names := strings.Fields(string(out))
+	if len(names) == 0 {
+		return false
+	}
+	for _, name := range names {
+		if !isObjectID(name) && !(len(name) == 2 && isHex(name)) {
+			return false
+		}
+	}
+	return true
+}
+
+// sortByCommitDate returns the commits newest first by committer date,
+// keeping the given order for commits made in the same second.
+func sortByCommitDate(commits []string) ([]string, error) {
+	if len(commits) == 0 {
+		return nil, nil
+	}
+	cmd := gitCommand("log", "--no-walk=unsorted", "--stdin", "--format=%ct %H")
+	cmd.Stdin = strings.NewReader(strings.Join(commits, "\n") + "\n")
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, err
+	}
+
+	type dated struct {
+		sha  string
+		time int64
+	}
+	var list []dated
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		ts, sha, ok := strings.Cut(line, " ")
+		if !ok {
+			continue
+		}
+		t, _ := strconv.ParseInt(ts, 10, 64)
+		list = append(list, dated{sha, t})
+	}
+	sort.SliceStable(list, func(i, j int) bool { return list[i].time > list[j].time })
+
+	sorted := make([]string, len(list))
+	for i, d := range list {
+		sorted[i] = d.sha
+	}
+	return sorted, nil
+}
+
+// CommitExists reports whether the object database has the commit.
+func CommitExists(sha string) bool {
+	return gitCommand("cat-file", "-e", sha+"^{commit}").Run() == nil
+}
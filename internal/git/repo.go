@@ -2,19 +2,104 @@ package git
 
 import (
 	"errors"
+	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"time"
 )
 
 // ErrNotGitRepo is returned when an operation requires a git repository
 var ErrNotGitRepo = errors.New("not inside a git repository")
 
+// CommandTracer, when set, is called with the arguments of every git command
+// the package runs, e.g. to log them.
+var CommandTracer func(args []string)
+
+// CommandObserver, when set, is called with the arguments of every git
+// command the package runs once it has exited, with how long it ran and the
+// error it ended with, e.g. to measure them.
+var CommandObserver func(args []string, elapsed time.Duration, err error)
+
+// Cmd is a git command. Running it with Run, Output, CombinedOutput, or
+// Start and Wait reports it to CommandObserver.
+type Cmd struct {
+	*exec.Cmd
+	args  []string
+	start time.Time
+}
+
+// CommandIn returns the command running git with args in dir, or in the
+// current directory when dir is empty.
+func CommandIn(dir string, args ...string) *Cmd {
+	cmd := gitCommand(args...)
+	cmd.Dir = dir
+	return cmd
+}
+
+// gitCommand returns the command running git with args, passing them to
+// CommandTracer first.
+func gitCommand(args ...string) *Cmd {
+	if CommandTracer != nil {
+		CommandTracer(args)
+	}
+	return &Cmd{Cmd: exec.Command("git", args...), args: args}
+}
+
+// Start starts the command.
+func (c *Cmd) Start() error {
+	c.start = time.Now()
+	err := c.Cmd.Start()
+	if err != nil {
+		c.observe(err)
+	}
+	return err
+}
+
+// Wait waits for the command started with Start to exit.
+func (c *Cmd) Wait() error {
+	err := c.Cmd.Wait()
+	c.observe(err)
+	return err
+}
+
+// Run runs the command and waits for it to exit.
+func (c *Cmd) Run() error {
+	c.start = time.Now()
+	err := c.Cmd.Run()
+	c.observe(err)
+	return err
+}
+
+// Output runs the command and returns its standard output.
+func (c *Cmd) Output() ([]byte, error) {
+	c.start = time.Now()
+	out, err := c.Cmd.Output()
+	c.observe(err)
+	return out, err
+}
+
+// CombinedOutput runs the command and returns its standard output and
+// standard error combined.
+func (c *Cmd) CombinedOutput() ([]byte, error) {
+	c.start = time.Now()
+	out, err := c.Cmd.CombinedOutput()
+	c.observe(err)
+	return out, err
+}
+
+func (c *Cmd) observe(err error) {
+	if CommandObserver != nil {
+		CommandObserver(c.args, time.Since(c.start), err)
+	}
+}
+
 // RunGitCommand executes a git command and returns the trimmed output.
 // This is a helper to avoid repeating the exec.Command + TrimSpace pattern.
 func RunGitCommand(args ...string) (string, error) {
-	cmd := exec.Command("git", args...)
+	cmd := gitCommand(args...)
 	output, err := cmd.Output()
 	if err != nil {
 		return "", err
@@ -31,6 +116,19 @@ func IsInsideWorkTree() bool {
 	return output == "true"
 }
 
+// IsBareRepository returns true if the current directory is a bare
+// repository, which has no working tree
+func IsBareRepository() bool {
+	output, err := RunGitCommand("rev-parse", "--is-bare-repository")
+	return err == nil && output == "true"
+}
+
+// GetGitDir returns the absolute path of the git directory, which for a
+// bare repository is the repository itself
+func GetGitDir() (string, error) {
+	return RunGitCommand("rev-parse", "--absolute-git-dir")
+}
+
 // RequireGitRepo returns ErrNotGitRepo if not inside a git repository.
 // Use this at the start of commands that require a git repository.
 func RequireGitRepo() error {
@@ -45,6 +143,44 @@ func GetRepoRoot() (string, error) {
 	return RunGitCommand("rev-parse", "--show-toplevel")
 }
 
+// GetUserName returns the configured git user.name, or "" if unset.
+func GetUserName() string {
+	name, err := RunGitCommand("config", "user.name")
+	if err != nil {
+		return ""
+	}
+	return name
+}
+
+// GetUserEmail returns the configured git user.email, or "" if unset.
+func GetUserEmail() string {
+	email, err := RunGitCommand("config", "user.email")
+	if err != nil {
+		return ""
+	}
+	return email
+}
+
+// ParseDate parses a date the way git log --since does, e.g. "2.weeks",
+// "yesterday" or "2024-05-01".
+func ParseDate(date string) (time.Time, error) {
+	out, err := RunGitCommand("rev-parse", "--since="+date)
+	if err != nil {
+		return time.Time{}, err
+	}
+	secs, err := strconv.ParseInt(strings.TrimPrefix(out, "--max-age="), 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid date %q", date)
+	}
+	return time.Unix(secs, 0), nil
+}
+
+// GetPathPrefix returns the path of the current directory relative to the
+// repository root, with a trailing slash (empty at the root).
+func GetPathPrefix() (string, error) {
+	return RunGitCommand("rev-parse", "--show-prefix")
+}
+
 // GetCurrentBranch returns the name of the current branch
 func GetCurrentBranch() (string, error) {
 	return RunGitCommand("rev-parse", "--abbrev-ref", "HEAD")
@@ -100,10 +236,77 @@ func HasUncommittedChanges() (bool, error) {
 
 // Checkout checks out a commit or branch
 func Checkout(ref string) error {
-	cmd := exec.Command("git", "checkout", ref)
+	cmd := gitCommand("checkout", ref)
+	return cmd.Run()
+}
+
+// CheckoutNewBranch creates a branch at ref and checks it out
+func CheckoutNewBranch(branch, ref string) error {
+	cmd := gitCommand("checkout", "-b", branch, ref)
 	return cmd.Run()
 }
 
+// BranchExists returns true if a local branch with the given name exists
+func BranchExists(branch string) bool {
+	_, err := RunGitCommand("rev-parse", "--verify", "--quiet", "refs/heads/"+branch)
+	return err == nil
+}
+
+// ResumeBranchName returns an unused branch name for resuming a session from
+// a commit: resume/<short-sha>-<date>, with a numeric suffix when the
+// session was already resumed that day.
+func ResumeBranchName(commitSHA string, now time.Time) string {
+	short := commitSHA
+	if len(short) > 7 {
+		short = short[:7]
+	}
+	base := "resume/" + short + "-" + now.Format("20060102")
+	name := base
+	for i := 2; BranchExists(name); i++ {
+		name = base + "-" + strconv.Itoa(i)
+	}
+	return name
+}
+
+// StashChanges stashes uncommitted changes, untracked files included, and
+// returns the SHA of the stash commit
+func StashChanges(message string) (string, error) {
+	if _, err := RunGitCommand("stash", "push", "--include-untracked", "-m", message); err != nil {
+		return "", err
+	}
+	return RunGitCommand("rev-parse", "stash@{0}")
+}
+
+// AddWorktree checks out ref in a new worktree at path, on a new branch
+// unless branch is empty
+func AddWorktree(path, branch, ref string) error {
+	args := []string{"worktree", "add"}
+	if branch != "" {
+		args = append(args, "-b", branch)
+	} else {
+		args = append(args, "--detach")
+	}
+	_, err := RunGitCommand(append(args, path, ref)...)
+	return err
+}
+
+// ResumeWorktreePath returns an unused path next to the repository root for
+// a worktree resuming a session from a commit: <root>-resume-<short-sha>.
+func ResumeWorktreePath(repoRoot, commitSHA string) string {
+	short := commitSHA
+	if len(short) > 7 {
+		short = short[:7]
+	}
+	base := filepath.Clean(repoRoot) + "-resume-" + short
+	path := base
+	for i := 2; ; i++ {
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			return path
+		}
+		path = base + "-" + strconv.Itoa(i)
+	}
+}
+
 // GetParentCommits returns the parent commit SHA(s) for a given commit
 func GetParentCommits(commitSHA string) ([]string, error) {
 	output, err := RunGitCommand("rev-parse", commitSHA+"^@")
@@ -145,7 +348,7 @@ const branchFieldSep = ":::"
 // If repoDir is non-empty, the git command runs in that directory.
 func ListBranches(repoDir string) ([]BranchInfo, error) {
 	format := "%(refname:short)" + branchFieldSep + "%(objectname)" + branchFieldSep + "%(committerdate:iso8601)"
-	cmd := exec.Command("git", "for-each-ref", "--sort=-committerdate",
+	cmd := gitCommand("for-each-ref", "--sort=-committerdate",
 		"refs/heads/", "--format="+format)
 	if repoDir != "" {
 		cmd.Dir = repoDir
@@ -161,7 +364,7 @@ func ListBranches(repoDir string) ([]BranchInfo, error) {
 	}
 
 	// Determine current branch (in same dir context)
-	cbCmd := exec.Command("git", "rev-parse", "--abbrev-ref", "HEAD")
+	cbCmd := gitCommand("rev-parse", "--abbrev-ref", "HEAD")
 	if repoDir != "" {
 		cbCmd.Dir = repoDir
 	}
@@ -187,10 +390,22 @@ func ListBranches(repoDir string) ([]BranchInfo, error) {
 	return branches, nil
 }
 
+// ListRemotes returns the names of the configured remotes in git's order.
+func ListRemotes() ([]string, error) {
+	output, err := RunGitCommand("remote")
+	if err != nil {
+		return nil, err
+	}
+	if output == "" {
+		return nil, nil
+	}
+	return strings.Split(output, "\n"), nil
+}
+
 // MergeBase returns the best common ancestor (merge-base) of two refs.
 // If repoDir is non-empty, the git command runs in that directory.
 func MergeBase(repoDir, refA, refB string) (string, error) {
-	cmd := exec.Command("git", "merge-base", refA, refB)
+	cmd := gitCommand("merge-base", refA, refB)
 	if repoDir != "" {
 		cmd.Dir = repoDir
 	}
@@ -217,3 +432,33 @@ func GetCommitInfo(commitSHA string) (message string, date string, err error) {
 
 	return message, date, nil
 }
+
+// ShiftlogDir returns the directory in the common git dir, shared by all
+// worktrees, where shiftlog keeps what it derives from the notes of the
+// clone, such as the search index. It may not exist yet.
+func ShiftlogDir() (string, error) {
+	commonDir, err := RunGitCommand("rev-parse", "--git-common-dir")
+	if err != nil {
+		return "", err
+	}
+	return filepath.Abs(filepath.Join(commonDir, "shiftlog"))
+}
+
+// GetCommitAuthor returns the author name of a commit, mapped through the
+// repository's .mailmap.
+func GetCommitAuthor(commitSHA string) (string, error) {
+	return RunGitCommand("log", "-1", "--format=%aN", commitSHA)
+}
+
+// CountCommits returns the number of commits reachable from HEAD, 0 in a
+// repository without commits.
+func CountCommits() (int, error) {
+	if _, err := RunGitCommand("rev-parse", "--verify", "--quiet", "HEAD"); err != nil {
+		return 0, nil
+	}
+	out, err := RunGitCommand("rev-list", "--count", "HEAD")
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(out)
+}
@@ -0,0 +1,224 @@
+package git
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// Signature formats, as named by git's gpg.format.
+const (
+	SignFormatOpenPGP = "openpgp"
+	SignFormatSSH     = "ssh"
+)
+
+// sshSignatureNamespace scopes SSH signatures to shiftlog, so a conversation
+// signature cannot pass as a signature over anything else.
+const sshSignatureNamespace = "shiftlog"
+
+// gpgStatuses maps GnuPG status keywords to the signature statuses used for
+// commits in signatureStatuses.
+var gpgStatuses = map[string]string{
+	"GOODSIG":   "good",
+	"BADSIG":    "bad",
+	"EXPSIG":    "expired",
+	"EXPKEYSIG": "expired-key",
+	"REVKEYSIG": "revoked-key",
+	"ERRSIG":    "unverifiable",
+}
+
+// SignPayload signs payload with the user's git signing key, honouring
+// gpg.format, user.signingkey and the gpg programs configured for git.
+// Returns the format used and the ASCII-armored detached signature.
+func SignPayload(payload []byte) (format, signature string, err error) {
+	format = configValue("gpg.format", SignFormatOpenPGP)
+	key := configValue("user.signingkey", "")
+
+	var out []byte
+	switch format {
+	case SignFormatOpenPGP:
+		if key == "" {
+			// Like git, fall back to the committer identity
+			key = committerIdent()
+		}
+		out, err = runSigner(payload, gpgProgram(), "--status-fd=2", "-bsau", key)
+	case SignFormatSSH:
+		if key == "" {
+			return "", "", fmt.Errorf("user.signingkey is not set")
+		}
+		keyFile, cleanup, keyErr := sshKeyFile(key)
+		if keyErr != nil {
+			return "", "", keyErr
+		}
+		defer cleanup()
+		out, err = runSigner(payload, configValue("gpg.ssh.program", "ssh-keygen"),
+			"-Y", "sign", "-n", sshSignatureNamespace, "-f", keyFile)
+	default:
+		return "", "", fmt.Errorf("unsupported gpg.format %q (supported: openpgp, ssh)", format)
+	}
+	if err != nil {
+		return "", "", err
+	}
+	return format, string(out), nil
+}
+
+// VerifyPayload checks a signature made by SignPayload. The status is one
+// of the commit signature statuses ("good", "bad", "unverifiable", ...);
+// signer names the key owner when known.
+func VerifyPayload(payload []byte, format, signature string) (status, signer string) {
+	sigFile, err := writeTempFile("shiftlog-sig-*", []byte(signature))
+	if err != nil {
+		return "unverifiable", ""
+	}
+	defer func() { _ = os.Remove(sigFile) }()
+
+	switch format {
+	case SignFormatOpenPGP:
+		return verifyGPG(payload, sigFile)
+	case SignFormatSSH:
+		return verifySSH(payload, sigFile)
+	default:
+		return "unverifiable", ""
+	}
+}
+
+func verifyGPG(payload []byte, sigFile string) (status, signer string) {
+	cmd := exec.Command(gpgProgram(), "--status-fd=1", "--verify", sigFile, "-")
+	cmd.Stdin = bytes.NewReader(payload)
+	// A bad signature exits non-zero but still reports its status
+	out, _ := cmd.Output()
+
+	status = "unverifiable"
+	for _, line := range strings.Split(string(out), "\n") {
+		fields := strings.SplitN(strings.TrimPrefix(line, "[GNUPG:] "), " ", 3)
+		s, ok := gpgStatuses[fields[0]]
+		if !ok {
+			continue
+		}
+		status = s
+		if len(fields) == 3 && s != "unverifiable" {
+			signer = fields[2]
+		}
+	}
+	return status, signer
+}
+
+func verifySSH(payload []byte, sigFile string) (status, signer string) {
+	program := configValue("gpg.ssh.program", "ssh-keygen")
+	run := func(args ...string) (string, error) {
+		cmd := exec.Command(program, args...)
+		cmd.Stdin = bytes.NewReader(payload)
+		out, err := cmd.Output()
+		return strings.TrimSpace(string(out)), err
+	}
+
+	// Whether the signature matches the payload at all, whoever made it
+	if _, err := run("-Y", "check-novalidate", "-n", sshSignatureNamespace, "-s", sigFile); err != nil {
+		return "bad", ""
+	}
+
+	allowed := configValue("gpg.ssh.allowedSignersFile", "")
+	if allowed == "" {
+		return "unverifiable", ""
+	}
+	allowed = expandHome(allowed)
+	principals, err := run("-Y", "find-principals", "-s", sigFile, "-f", allowed)
+	if err != nil || principals == "" {
+		// Valid signature from a key not in the allowed signers file
+		return "unverifiable", ""
+	}
+	principal := strings.Split(principals, "\n")[0]
+	if _, err := run("-Y", "verify", "-f", allowed, "-I", principal, "-n", sshSignatureNamespace, "-s", sigFile); err != nil {
+		return "bad", principal
+	}
+	return "good", principal
+}
+
+// runSigner pipes payload through a signing program and returns its output.
+func runSigner(payload []byte, program string, args ...string) ([]byte, error) {
+	cmd := exec.Command(program, args...)
+	cmd.Stdin = bytes.NewReader(payload)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("%s failed to sign: %w: %s", filepath.Base(program), err, strings.TrimSpace(stderr.String()))
+	}
+	return out, nil
+}
+
+// gpgProgram returns the OpenPGP program git is configured to sign with.
+func gpgProgram() string {
+	if program := configValue("gpg.openpgp.program", ""); program != "" {
+		return program
+	}
+	return configValue("gpg.program", "gpg")
+}
+
+// sshKeyFile returns a file holding the SSH signing key. user.signingkey is
+// either a path or, with a "key::" prefix, a literal public key whose
+// private half is in the ssh-agent.
+func sshKeyFile(key string) (path string, cleanup func(), err error) {
+	literal, ok := strings.CutPrefix(key, "key::")
+	if !ok && !strings.HasPrefix(key, "ssh-") {
+		return expandHome(key), func() {}, nil
+	}
+	if !ok {
+		literal = key
+	}
+	path, err = writeTempFile("shiftlog-key-*.pub", []byte(literal+"\n"))
+	if err != nil {
+		return "", nil, err
+	}
+	return path, func() { _ = os.Remove(path) }, nil
+}
+
+func writeTempFile(pattern string, data []byte) (string, error) {
+	f, err := os.CreateTemp("", pattern)
+	if err != nil {
+		return "", err
+	}
+	if _, err := f.Write(data); err != nil {
+		_ = f.Close()
+		_ = os.Remove(f.Name())
+		return "", err
+	}
+	return f.Name(), f.Close()
+}
+
+// committerIdent returns the committer as "Name <email>".
+func committerIdent() string {
+	ident, err := RunGitCommand("var", "GIT_COMMITTER_IDENT")
+	if err != nil {
+		return ""
+	}
+	// Drop the trailing timestamp and timezone
+	if i := strings.LastIndex(ident, ">"); i >= 0 {
+		return ident[:i+1]
+	}
+	return ident
+}
+
+// configValue returns a git config value, or fallback when it is unset.
+func configValue(key, fallback string) string {
+	value, err := RunGitCommand("config", key)
+	if err != nil || value == "" {
+		return fallback
+	}
+	return value
+}
+
+func expandHome(path string) string {
+	rest, ok := strings.CutPrefix(path, "~/")
+	if !ok {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return filepath.Join(home, rest)
+}
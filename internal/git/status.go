@@ -0,0 +1,152 @@
+package git
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// NotesStatus describes how the local notes ref differs from a remote's.
+type NotesStatus struct {
+	Remote       string   `json:"remote"`
+	RemoteExists bool     `json:"remote_exists"` // remote has a notes ref
+	Ahead        int      `json:"ahead"`         // notes ref commits only in the local ref
+	Behind       int      `json:"behind"`        // notes ref commits only in the remote ref
+	LocalOnly    []string `json:"local_only"`    // commits with a note only locally
+	RemoteOnly   []string `json:"remote_only"`   // commits with a note only on the remote
+	Conflicting  []string `json:"conflicting"`   // commits whose notes differ
+	Shared       int      `json:"shared"`        // commits with identical notes on both sides
+}
+
+// InSync reports whether local and remote notes are identical.
+func (s *NotesStatus) InSync() bool {
+	return s.Ahead == 0 && s.Behind == 0
+}
+
+// CompareNotes compares the local notes ref with the one on remote without
+// changing any ref: the remote notes objects are fetched only if missing,
+// and nothing is written to refs or FETCH_HEAD.
+func CompareNotes(remote string) (*NotesStatus, error) {
+	status := &NotesStatus{Remote: remote, LocalOnly: []string{}, RemoteOnly: []string{}, Conflicting: []string{}}
+
+	out, err := gitCommand("ls-remote", remote, NotesRef).Output()
+	if err != nil {
+		return nil, fmt.Errorf("could not reach remote %s: %w", remote, err)
+	}
+	var remoteTip string
+	if fields := strings.Fields(string(out)); len(fields) > 0 {
+		remoteTip = fields[0]
+	}
+
+	localTip, err := GetNotesCommit()
+	if err != nil {
+		return nil, err
+	}
+
+	local, err := noteBlobsAt(localTip)
+	if err != nil {
+		return nil, err
+	}
+
+	remoteNotes := map[string]string{}
+	if remoteTip != "" {
+		status.RemoteExists = true
+		if gitCommand("cat-file", "-e", remoteTip+"^{commit}").Run() != nil {
+			fetch := gitCommand("fetch", "-q", "--no-write-fetch-head", remote, NotesRef)
+			if output, err := fetch.CombinedOutput(); err != nil {
+				return nil, fmt.Errorf("could not fetch remote notes: %w: %s", err, strings.TrimSpace(string(output)))
+			}
+		}
+		if remoteNotes, err = noteBlobsAt(remoteTip); err != nil {
+			return nil, err
+		}
+	}
+
+	status.Ahead, status.Behind, err = countDivergence(localTip, remoteTip)
+	if err != nil {
+		return nil, err
+	}
+
+	for sha, blob := range local {
+		remoteBlob, ok := remoteNotes[sha]
+		switch {
+		case !ok:
+			status.LocalOnly = append(status.LocalOnly, sha)
+		case remoteBlob != blob:
+			status.Conflicting = append(status.Conflicting, sha)
+		default:
+			status.Shared++
+		}
+	}
+	for sha := range remoteNotes {
+		if _, ok := local[sha]; !ok {
+			status.RemoteOnly = append(status.RemoteOnly, sha)
+		}
+	}
+	sort.Strings(status.LocalOnly)
+	sort.Strings(status.RemoteOnly)
+	sort.Strings(status.Conflicting)
+	return status, nil
+}
+
+// noteBlobsAt returns the notes in the notes commit rev as a map of
+// annotated commit SHA to note blob SHA. An empty rev yields an empty map.
+func noteBlobsAt(rev string) (map[string]string, error) {
+	blobs := map[string]string{}
+	if rev == "" {
+		return blobs, nil
+	}
+
+	out, err := gitCommand("ls-tree", "-r", rev).Output()
+	if err != nil {
+		return nil, fmt.Errorf("could not read notes tree %s: %w", rev, err)
+	}
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		// Format: "<mode> blob <blob_sha>\t<path>", path fanned out as ab/cdef...
+		meta, path, ok := strings.Cut(line, "\t")
+		fields := strings.Fields(meta)
+		if !ok || len(fields) < 3 || fields[1] != "blob" {
+			continue
+		}
+		blobs[strings.ReplaceAll(path, "/", "")] = fields[2]
+	}
+	return blobs, nil
+}
+
+// countDivergence returns how many commits each of two notes tips has that
+// the other lacks. An empty tip counts as having no commits.
+func countDivergence(localTip, remoteTip string) (ahead, behind int, err error) {
+	switch {
+	case localTip == "" && remoteTip == "":
+		return 0, 0, nil
+	case remoteTip == "":
+		ahead, err = countRevs(localTip)
+		return ahead, 0, err
+	case localTip == "":
+		behind, err = countRevs(remoteTip)
+		return 0, behind, err
+	}
+
+	out, err := gitCommand("rev-list", "--left-right", "--count", localTip+"..."+remoteTip).Output()
+	if err != nil {
+		return 0, 0, err
+	}
+	fields := strings.Fields(string(out))
+	if len(fields) != 2 {
+		return 0, 0, fmt.Errorf("unexpected rev-list output %q", out)
+	}
+	if ahead, err = strconv.Atoi(fields[0]); err != nil {
+		return 0, 0, err
+	}
+	behind, err = strconv.Atoi(fields[1])
+	return ahead, behind, err
+}
+
+func countRevs(rev string) (int, error) {
+	out, err := gitCommand("rev-list", "--count", rev).Output()
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(strings.TrimSpace(string(out)))
+}
@@ -0,0 +1,56 @@
+package git
+
+import (
+	"strings"
+)
+
+// TagInfo holds metadata about a tag.
+type TagInfo struct {
+	Name      string
+	CommitSHA string // the tagged commit, also for annotated tags
+	Date      string // tag date for annotated tags, commit date otherwise; ISO 8601
+}
+
+// ListTags returns the tags pointing at commits, newest first.
+// If repoDir is non-empty, the git command runs in that directory.
+func ListTags(repoDir string) ([]TagInfo, error) {
+	format := "%(refname:short)" + branchFieldSep + "%(objecttype)" + branchFieldSep + "%(objectname)" +
+		branchFieldSep + "%(*objecttype)" + branchFieldSep + "%(*objectname)" + branchFieldSep + "%(creatordate:iso8601)"
+	cmd := gitCommand("for-each-ref", "--sort=-creatordate", "refs/tags/", "--format="+format)
+	if repoDir != "" {
+		cmd.Dir = repoDir
+	}
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, err
+	}
+
+	var tags []TagInfo
+	for _, line := range strings.Split(strings.TrimSpace(string(output)), "\n") {
+		parts := strings.SplitN(line, branchFieldSep, 6)
+		if len(parts) < 6 {
+			continue
+		}
+		tag := TagInfo{Name: parts[0], Date: parts[5]}
+		switch {
+		case parts[1] == "commit":
+			tag.CommitSHA = parts[2]
+		case parts[3] == "commit":
+			tag.CommitSHA = parts[4]
+		default:
+			continue // tags of trees and blobs, or of other tags
+		}
+		tags = append(tags, tag)
+	}
+	return tags, nil
+}
+
+// PreviousTag returns the newest tag reachable from the parents of ref,
+// the tag a release at ref follows, or "" when there is none.
+func PreviousTag(ref string) string {
+	tag, err := RunGitCommand("describe", "--tags", "--abbrev=0", ref+"^")
+	if err != nil {
+		return ""
+	}
+	return tag
+}
@@ -0,0 +1,62 @@
+package importer
+
+import (
+	"strings"
+	"time"
+
+	"github.com/re-cinq/shift-log/internal/agent"
+)
+
+func init() {
+	register(&Format{
+		Name:        "aider",
+		Description: "aider's chat history, .aider.chat.history.md",
+		Agent:       "aider",
+		Parse:       parseAider,
+	})
+}
+
+// aiderStarted opens each session of an aider chat history.
+const aiderStarted = "# aider chat started at "
+
+// parseAider reads an aider chat history: user prompts are the lines
+// starting with "#### ", aider's own output the lines starting with "> ",
+// and everything else the model's replies. The sessions of the file are
+// imported one after another, timed by their start.
+func parseAider(data []byte) (*agent.Transcript, error) {
+	b := &builder{}
+	kind := agent.MessageType("")
+	var lines []string
+	flush := func() {
+		b.text(kind, "", strings.Join(lines, "\n"))
+		kind, lines = "", nil
+	}
+	part := func(k agent.MessageType, line string) {
+		if kind != k {
+			flush()
+			kind = k
+		}
+		lines = append(lines, line)
+	}
+
+	for _, line := range strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n") {
+		switch {
+		case strings.HasPrefix(line, aiderStarted):
+			flush()
+			started := strings.TrimSpace(strings.TrimPrefix(line, aiderStarted))
+			if t, err := time.ParseInLocation("2006-01-02 15:04:05", started, time.Local); err == nil {
+				b.timestamp = t.UTC().Format(time.RFC3339)
+			}
+		case line == "####" || strings.HasPrefix(line, "#### "):
+			part(agent.MessageTypeUser, strings.TrimPrefix(strings.TrimPrefix(line, "####"), " "))
+		case line == ">" || strings.HasPrefix(line, "> "):
+			part(agent.MessageTypeSystem, strings.TrimPrefix(strings.TrimPrefix(line, ">"), " "))
+		case strings.TrimSpace(line) == "" && kind != agent.MessageTypeAssistant:
+			// Blank lines separate the blocks of aider's output and prompts
+		default:
+			part(agent.MessageTypeAssistant, line)
+		}
+	}
+	flush()
+	return b.result("aider chat history")
+}
@@ -0,0 +1,81 @@
+package importer
+
+import (
+	"encoding/json"
+	"regexp"
+	"strings"
+
+	"github.com/re-cinq/shift-log/internal/agent"
+)
+
+func init() {
+	register(&Format{
+		Name:        "claude-export",
+		Description: "a conversation saved with Claude Code's /export command",
+		Agent:       "claude",
+		Parse:       parseClaudeExport,
+	})
+}
+
+// claudeToolCall matches the first line of a tool call in a Claude Code
+// export, e.g. "Bash(go test ./...)".
+var claudeToolCall = regexp.MustCompile(`^([A-Za-z][\w.:-]*)\((.*)\)$`)
+
+// parseClaudeExport reads the text Claude Code's /export writes: the
+// welcome box, then prompts starting with "> ", replies and tool calls
+// starting with "⏺ ", and tool output starting with "⎿" below its call.
+// Continuation lines are indented. The export has no timestamps.
+func parseClaudeExport(data []byte) (*agent.Transcript, error) {
+	b := &builder{}
+	kind := ""
+	var lines []string
+	flush := func() {
+		text := strings.Join(lines, "\n")
+		switch kind {
+		case "user":
+			b.text(agent.MessageTypeUser, "", text)
+		case "assistant":
+			first, rest, _ := strings.Cut(strings.TrimSpace(text), "\n")
+			if m := claudeToolCall.FindStringSubmatch(first); m != nil && rest == "" {
+				input, _ := json.Marshal(map[string]string{"input": m[2]})
+				b.tool("", m[1], input, nil)
+			} else {
+				b.text(agent.MessageTypeAssistant, "", text)
+			}
+		case "result":
+			output := strings.TrimSpace(text)
+			n := len(b.transcript.Entries)
+			if n > 0 && b.transcript.Entries[n-1].Message.Content[0].Type == "tool_use" {
+				call := b.transcript.Entries[n-1].Message.Content[0]
+				content, _ := json.Marshal(output)
+				b.add(agent.MessageTypeUser, "", agent.ContentBlock{Type: "tool_result", ToolUseID: call.ID, Content: content})
+			} else {
+				b.text(agent.MessageTypeSystem, "", output)
+			}
+		}
+		kind, lines = "", nil
+	}
+	start := func(k, line string) {
+		flush()
+		kind = k
+		lines = append(lines, line)
+	}
+
+	for _, line := range strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n") {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case kind == "" && (strings.HasPrefix(trimmed, "╭") || strings.HasPrefix(trimmed, "│") || strings.HasPrefix(trimmed, "╰")):
+			// The welcome box
+		case strings.HasPrefix(line, "> "):
+			start("user", strings.TrimPrefix(line, "> "))
+		case strings.HasPrefix(line, "⏺"):
+			start("assistant", strings.TrimSpace(strings.TrimPrefix(line, "⏺")))
+		case strings.HasPrefix(trimmed, "⎿"):
+			start("result", strings.TrimSpace(strings.TrimPrefix(trimmed, "⎿")))
+		case kind != "":
+			lines = append(lines, strings.TrimPrefix(strings.TrimPrefix(line, "  "), "   "))
+		}
+	}
+	flush()
+	return b.result("Claude Code export")
+}
@@ -0,0 +1,103 @@
+// Package importer converts the conversation logs of other tools into
+// transcripts, so that history recorded before a team adopted shiftlog can
+// be stored on its commits.
+package importer
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/re-cinq/shift-log/internal/agent"
+)
+
+// Format is a conversation log format shiftlog can import.
+type Format struct {
+	Name        string
+	Description string
+	// Agent is the tool that writes the format, recorded as the agent of
+	// imported conversations. Empty when the format does not tell.
+	Agent string
+	Parse func(data []byte) (*agent.Transcript, error)
+}
+
+var formats = map[string]*Format{}
+
+func register(f *Format) {
+	formats[f.Name] = f
+}
+
+// Get returns the format with the name.
+func Get(name string) (*Format, error) {
+	if f, ok := formats[name]; ok {
+		return f, nil
+	}
+	return nil, fmt.Errorf("unknown import format %q (supported: %s)", name, strings.Join(Names(), ", "))
+}
+
+// Names returns the names of the supported formats, sorted.
+func Names() []string {
+	names := make([]string, 0, len(formats))
+	for name := range formats {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// builder collects the entries of an imported transcript, chaining each to
+// the one before it.
+type builder struct {
+	transcript agent.Transcript
+	timestamp  string // of entries the format does not time
+	tools      int
+}
+
+func (b *builder) add(msgType agent.MessageType, timestamp string, blocks ...agent.ContentBlock) {
+	if timestamp == "" {
+		timestamp = b.timestamp
+	}
+	entry := agent.TranscriptEntry{
+		UUID:      fmt.Sprintf("import-%d", len(b.transcript.Entries)+1),
+		Type:      msgType,
+		Timestamp: timestamp,
+		Message:   &agent.Message{Role: string(msgType), Content: blocks},
+	}
+	if n := len(b.transcript.Entries); n > 0 {
+		entry.ParentUUID = b.transcript.Entries[n-1].UUID
+	}
+	b.transcript.Entries = append(b.transcript.Entries, entry)
+}
+
+// text adds a text message, unless it is blank.
+func (b *builder) text(msgType agent.MessageType, timestamp, text string) {
+	if text = strings.TrimSpace(text); text != "" {
+		b.add(msgType, timestamp, agent.ContentBlock{Type: "text", Text: text})
+	}
+}
+
+// tool adds an assistant tool call and, when output is not nil, the user
+// entry with its result.
+func (b *builder) tool(timestamp, name string, input json.RawMessage, output *string) {
+	b.tools++
+	id := fmt.Sprintf("import-tool-%d", b.tools)
+	if len(input) == 0 {
+		input = json.RawMessage("{}")
+	}
+	b.add(agent.MessageTypeAssistant, timestamp, agent.ContentBlock{Type: "tool_use", ID: id, Name: name, Input: input})
+	if output != nil {
+		content, _ := json.Marshal(*output)
+		b.add(agent.MessageTypeUser, timestamp, agent.ContentBlock{Type: "tool_result", ToolUseID: id, Content: content})
+	}
+}
+
+// result returns the transcript, or an error when nothing was imported.
+func (b *builder) result(format string) (*agent.Transcript, error) {
+	if len(b.transcript.Entries) == 0 {
+		return nil, fmt.Errorf("no messages found; is this a %s file?", format)
+	}
+	t := &b.transcript
+	t.Turns = t.CountTurns()
+	return t, nil
+}
@@ -0,0 +1,141 @@
+package importer
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/re-cinq/shift-log/internal/agent"
+)
+
+// summary describes the entries of a transcript as "type:text" or
+// "type:tool_use Name" lines, for comparing them.
+func summary(t *agent.Transcript) string {
+	var lines []string
+	for _, e := range t.Entries {
+		block := e.Message.Content[0]
+		switch block.Type {
+		case "text":
+			lines = append(lines, string(e.Type)+":"+block.Text)
+		case "tool_use":
+			lines = append(lines, string(e.Type)+":tool_use "+block.Name)
+		case "tool_result":
+			lines = append(lines, string(e.Type)+":tool_result "+string(block.Content))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+func TestParseAider(t *testing.T) {
+	history := `
+# aider chat started at 2024-05-01 10:00:00
+
+> Aider v0.50.0
+> Model: gpt-4o
+
+#### add a retry to the client
+#### with backoff
+
+I'll add a retry loop.
+
+` + "```go\nfor i := 0; i < 3; i++ {}\n```" + `
+
+> Applied edit to client.go
+
+#### thanks
+`
+	transcript, err := parseAider([]byte(history))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `system:Aider v0.50.0
+Model: gpt-4o
+user:add a retry to the client
+with backoff
+assistant:I'll add a retry loop.
+
+` + "```go\nfor i := 0; i < 3; i++ {}\n```" + `
+system:Applied edit to client.go
+user:thanks`
+	if got := summary(transcript); got != want {
+		t.Errorf("entries:\n%s\nwant:\n%s", got, want)
+	}
+	if ts := transcript.Entries[0].Timestamp; ts == "" {
+		t.Error("entries should be timed by the session start")
+	}
+	if transcript.Entries[1].ParentUUID != transcript.Entries[0].UUID {
+		t.Error("entries should be chained")
+	}
+	if transcript.Turns != 2 {
+		t.Errorf("Turns = %d, want 2", transcript.Turns)
+	}
+}
+
+func TestParseClaudeExport(t *testing.T) {
+	export := `╭───────────────────────────────────────╮
+│ ✻ Welcome to Claude Code!             │
+╰───────────────────────────────────────╯
+
+> fix the failing test
+  in the parser
+
+⏺ Let me run the tests first.
+
+⏺ Bash(go test ./...)
+  ⎿  FAIL parser
+     exit status 1
+
+⏺ The parser test fails; fixed.
+`
+	transcript, err := parseClaudeExport([]byte(export))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `user:fix the failing test
+in the parser
+assistant:Let me run the tests first.
+assistant:tool_use Bash
+user:tool_result "FAIL parser\nexit status 1"
+assistant:The parser test fails; fixed.`
+	if got := summary(transcript); got != want {
+		t.Errorf("entries:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestParseJSONL(t *testing.T) {
+	data := `{"role":"user","content":"list the files","timestamp":"2024-05-01T10:00:00Z"}
+{"role":"tool","name":"shell","input":{"command":"ls"},"output":"main.go"}
+{"role":"assistant","content":"There is one file.","model":"gpt-4o"}
+`
+	transcript, err := parseJSONL([]byte(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `user:list the files
+assistant:tool_use shell
+user:tool_result "main.go"
+assistant:There is one file.`
+	if got := summary(transcript); got != want {
+		t.Errorf("entries:\n%s\nwant:\n%s", got, want)
+	}
+	if transcript.Model != "gpt-4o" {
+		t.Errorf("Model = %q, want gpt-4o", transcript.Model)
+	}
+
+	if _, err := parseJSONL([]byte(`{"role":"narrator","content":"x"}`)); err == nil {
+		t.Error("unknown roles should be rejected")
+	}
+	if _, err := parseJSONL([]byte("\n")); err == nil {
+		t.Error("a file without messages should be rejected")
+	}
+}
+
+func TestGet(t *testing.T) {
+	for _, name := range []string{"aider", "claude-export", "jsonl"} {
+		if _, err := Get(name); err != nil {
+			t.Errorf("Get(%q): %v", name, err)
+		}
+	}
+	if _, err := Get("zip"); err == nil || !strings.Contains(err.Error(), "aider, claude-export, jsonl") {
+		t.Errorf("Get(zip) = %v, want the supported formats listed", err)
+	}
+}
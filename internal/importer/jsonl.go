@@ -0,0 +1,77 @@
+package importer
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/re-cinq/shift-log/internal/agent"
+)
+
+func init() {
+	register(&Format{
+		Name:        "jsonl",
+		Description: "one JSON message per line: role, content, and optional timestamp and model",
+		Parse:       parseJSONL,
+	})
+}
+
+// jsonlMessage is a line of the generic JSONL format. Role is user,
+// assistant or system, or tool for a tool call: Name and Input describe the
+// call and Output, when present, its result.
+type jsonlMessage struct {
+	Role      string          `json:"role"`
+	Content   string          `json:"content"`
+	Timestamp string          `json:"timestamp,omitempty"`
+	Model     string          `json:"model,omitempty"`
+	Name      string          `json:"name,omitempty"`
+	Input     json.RawMessage `json:"input,omitempty"`
+	Output    *string         `json:"output,omitempty"`
+}
+
+// parseJSONL reads the generic JSONL format, for tools shiftlog has no
+// importer of its own for.
+func parseJSONL(data []byte) (*agent.Transcript, error) {
+	b := &builder{}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), 10*1024*1024)
+	for n := 1; scanner.Scan(); n++ {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var m jsonlMessage
+		if err := json.Unmarshal(line, &m); err != nil {
+			return nil, fmt.Errorf("line %d: %w", n, err)
+		}
+
+		switch strings.ToLower(m.Role) {
+		case "tool":
+			if m.Name == "" {
+				return nil, fmt.Errorf("line %d: tool message without a name", n)
+			}
+			b.tool(m.Timestamp, m.Name, m.Input, m.Output)
+		case "user", "human":
+			b.text(agent.MessageTypeUser, m.Timestamp, m.Content)
+		case "assistant", "model":
+			before := len(b.transcript.Entries)
+			b.text(agent.MessageTypeAssistant, m.Timestamp, m.Content)
+			if m.Model != "" && len(b.transcript.Entries) > before {
+				b.transcript.Entries[before].Model = m.Model
+				if b.transcript.Model == "" {
+					b.transcript.Model = m.Model
+				}
+			}
+		case "system":
+			b.text(agent.MessageTypeSystem, m.Timestamp, m.Content)
+		default:
+			return nil, fmt.Errorf("line %d: unknown role %q", n, m.Role)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return b.result("JSONL")
+}
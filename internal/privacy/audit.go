@@ -0,0 +1,53 @@
+package privacy
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+
+	"github.com/re-cinq/shift-log/internal/config"
+)
+
+// auditLogFile is the log in .shiftlog/logs of what was scrubbed from the
+// conversations stored in the repository, one JSON object per line.
+const auditLogFile = "privacy-audit.jsonl"
+
+// AuditEntry records what was scrubbed from a stored conversation. It
+// counts the findings and never holds them.
+type AuditEntry struct {
+	Time      string    `json:"time"`
+	Commit    string    `json:"commit"`
+	SessionID string    `json:"session_id"`
+	Findings  []Finding `json:"findings"`
+}
+
+// AuditLogPath returns the path of the audit log.
+func AuditLogPath() (string, error) {
+	dir, err := config.LogsDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, auditLogFile), nil
+}
+
+// AppendAudit adds an entry to the audit log.
+func AppendAudit(entry AuditEntry) error {
+	path, err := AuditLogPath()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = f.Close() }()
+	_, err = f.Write(append(line, '\n'))
+	return err
+}
@@ -0,0 +1,93 @@
+package privacy
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Detector finds personal data in text.
+type Detector interface {
+	// Find returns the start and end offsets of each match in s, in order
+	// and without overlaps, like regexp.FindAllStringIndex.
+	Find(s string) [][]int
+}
+
+// DetectorFactory builds the detector of a policy rule, from the rule's
+// settings such as Values or Pattern.
+type DetectorFactory func(rule Rule) (Detector, error)
+
+var detectors = map[string]DetectorFactory{}
+
+// RegisterDetector makes a kind of detector available to policy rules.
+func RegisterDetector(kind string, factory DetectorFactory) {
+	detectors[kind] = factory
+}
+
+// DetectorKinds returns the registered kinds of detectors, sorted.
+func DetectorKinds() []string {
+	kinds := make([]string, 0, len(detectors))
+	for kind := range detectors {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+	return kinds
+}
+
+// Built-in detector kinds.
+const (
+	DetectorEmail   = "email"   // email addresses
+	DetectorPhone   = "phone"   // phone numbers written with separators or a country code
+	DetectorName    = "name"    // the names listed in the rule's values
+	DetectorPattern = "pattern" // matches of the rule's regular expression
+)
+
+var (
+	emailPattern = regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`)
+	// phonePattern requires a country code or groups separated by spaces
+	// or dashes, so that dates, versions and IP addresses do not match.
+	phonePattern = regexp.MustCompile(`(?:\+\d{7,15}\b|(?:\+\d{1,3}[ -]?)?(?:\(\d{1,4}\)[ -]?|\b\d{2,4}[ -])\d{3,4}[ -]\d{3,4}\b)`)
+)
+
+// regexpDetector is a Detector matching a regular expression.
+type regexpDetector struct {
+	re *regexp.Regexp
+}
+
+func (d regexpDetector) Find(s string) [][]int {
+	return d.re.FindAllStringIndex(s, -1)
+}
+
+func init() {
+	RegisterDetector(DetectorEmail, func(Rule) (Detector, error) {
+		return regexpDetector{emailPattern}, nil
+	})
+	RegisterDetector(DetectorPhone, func(Rule) (Detector, error) {
+		return regexpDetector{phonePattern}, nil
+	})
+	RegisterDetector(DetectorName, func(rule Rule) (Detector, error) {
+		var names []string
+		for _, v := range rule.Values {
+			if v = strings.TrimSpace(v); v != "" {
+				names = append(names, regexp.QuoteMeta(v))
+			}
+		}
+		if len(names) == 0 {
+			return nil, fmt.Errorf("the name detector needs values")
+		}
+		// Longer names first, so that "Ann Lee" wins over "Ann"
+		sort.Slice(names, func(i, j int) bool { return len(names[i]) > len(names[j]) })
+		return regexpDetector{regexp.MustCompile(`\b(?:` + strings.Join(names, "|") + `)\b`)}, nil
+	})
+	RegisterDetector(DetectorPattern, func(rule Rule) (Detector, error) {
+		if rule.Pattern == "" {
+			return nil, fmt.Errorf("the pattern detector needs a pattern")
+		}
+		re, err := regexp.Compile(rule.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern: %w", err)
+		}
+		return regexpDetector{re}, nil
+	})
+}
@@ -0,0 +1,273 @@
+// Package privacy scrubs personal data, such as email addresses, phone
+// numbers and names, from conversations before they are stored or
+// exported. What is detected and what happens to it is configured by a
+// Policy in .shiftlog/privacy.yaml.
+package privacy
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/re-cinq/shift-log/internal/anonymize"
+	"github.com/re-cinq/shift-log/internal/util"
+)
+
+// PolicyFile is the path of the policy in the project root.
+const PolicyFile = ".shiftlog/privacy.yaml"
+
+// Actions of a rule on what its detector finds.
+const (
+	ActionRedact = "redact" // replace with [redacted <rule>]
+	ActionHash   = "hash"   // replace with a hash, so that repeats stay recognisable
+	ActionAllow  = "allow"  // keep, but report it
+)
+
+// Policy is the content of .shiftlog/privacy.yaml:
+//
+//	scrub_on_store: true
+//	rules:
+//	  - detector: email
+//	    action: hash
+//	    allow: ["*@example.com"]
+//	  - detector: phone
+//	  - detector: name
+//	    values: ["Ada Lovelace"]
+//	  - detector: pattern
+//	    name: employee-id
+//	    pattern: 'EMP-[0-9]{6}'
+type Policy struct {
+	// ScrubOnStore scrubs conversations when they are stored, not only
+	// when they are exported with --scrub.
+	ScrubOnStore bool `yaml:"scrub_on_store"`
+	// Rules are applied in order. Without rules, email addresses and
+	// phone numbers are redacted.
+	Rules []Rule `yaml:"rules"`
+}
+
+// Rule applies an action to what a detector finds.
+type Rule struct {
+	Detector string `yaml:"detector"`
+	// Name names the rule in replacements and reports; the detector's
+	// kind by default.
+	Name string `yaml:"name"`
+	// Action is ActionRedact, ActionHash or ActionAllow; ActionRedact by
+	// default.
+	Action string `yaml:"action"`
+	// Allow lists matches that are kept, as case-insensitive glob
+	// patterns such as "*@example.com".
+	Allow []string `yaml:"allow"`
+	// Values are the names of the name detector.
+	Values []string `yaml:"values"`
+	// Pattern is the regular expression of the pattern detector.
+	Pattern string `yaml:"pattern"`
+}
+
+// DefaultRules are the rules of a policy without any.
+var DefaultRules = []Rule{
+	{Detector: DetectorEmail, Action: ActionRedact},
+	{Detector: DetectorPhone, Action: ActionRedact},
+}
+
+// LoadPolicy reads .shiftlog/privacy.yaml in the project root. Without the
+// file, it returns the default policy: nothing is scrubbed on store, and
+// DefaultRules apply to exports.
+func LoadPolicy() (*Policy, error) {
+	root, err := util.GetProjectRoot()
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(filepath.Join(root, PolicyFile))
+	if os.IsNotExist(err) {
+		return &Policy{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var p Policy
+	if err := yaml.Unmarshal(data, &p); err != nil {
+		return nil, fmt.Errorf("could not parse %s: %w", PolicyFile, err)
+	}
+	return &p, nil
+}
+
+// rule is a Rule with its detector built.
+type rule struct {
+	Rule
+	detector Detector
+}
+
+// Scrubber applies the rules of a policy and reports what they found.
+type Scrubber struct {
+	rules  []rule
+	report Report
+}
+
+// NewScrubber builds the detectors of the policy's rules.
+func NewScrubber(p *Policy) (*Scrubber, error) {
+	rules := p.Rules
+	if len(rules) == 0 {
+		rules = DefaultRules
+	}
+	s := &Scrubber{report: Report{}}
+	for i, r := range rules {
+		factory, ok := detectors[r.Detector]
+		if !ok {
+			return nil, fmt.Errorf("rule %d: unknown detector %q (supported: %s)", i+1, r.Detector, strings.Join(DetectorKinds(), ", "))
+		}
+		switch r.Action {
+		case "":
+			r.Action = ActionRedact
+		case ActionRedact, ActionHash, ActionAllow:
+		default:
+			return nil, fmt.Errorf("rule %d: unknown action %q (supported: redact, hash, allow)", i+1, r.Action)
+		}
+		for _, pattern := range r.Allow {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return nil, fmt.Errorf("rule %d: invalid allow pattern %q", i+1, pattern)
+			}
+		}
+		if r.Name == "" {
+			r.Name = r.Detector
+		}
+		d, err := factory(r)
+		if err != nil {
+			return nil, fmt.Errorf("rule %d: %w", i+1, err)
+		}
+		s.rules = append(s.rules, rule{Rule: r, detector: d})
+	}
+	return s, nil
+}
+
+// Text scrubs text, applying each rule to the result of the previous one.
+func (s *Scrubber) Text(text string) string {
+	for _, r := range s.rules {
+		matches := r.detector.Find(text)
+		if len(matches) == 0 {
+			continue
+		}
+		var b strings.Builder
+		last := 0
+		for _, m := range matches {
+			match := text[m[0]:m[1]]
+			action := r.Action
+			if r.allowed(match) {
+				action = ActionAllow
+			}
+			s.report.add(r.Name, action)
+			b.WriteString(text[last:m[0]])
+			switch action {
+			case ActionRedact:
+				b.WriteString("[redacted " + r.Name + "]")
+			case ActionHash:
+				sum := sha256.Sum256([]byte(match))
+				fmt.Fprintf(&b, "[%s:%x]", r.Name, sum[:6])
+			default:
+				b.WriteString(match)
+			}
+			last = m[1]
+		}
+		b.WriteString(text[last:])
+		text = b.String()
+	}
+	return text
+}
+
+func (r *rule) allowed(match string) bool {
+	for _, pattern := range r.Allow {
+		if ok, _ := path.Match(strings.ToLower(pattern), strings.ToLower(match)); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// JSON scrubs every string of a JSON document.
+func (s *Scrubber) JSON(data []byte) ([]byte, error) {
+	return anonymize.Strings(data, func(text string, _ bool) string { return s.Text(text) })
+}
+
+// Transcript scrubs a transcript: each line of a JSONL transcript, or the
+// whole of a transcript that is a single JSON document. Lines that are not
+// JSON are scrubbed as text, and lines without findings are kept as they
+// are.
+func (s *Scrubber) Transcript(data []byte) []byte {
+	if json.Valid(data) {
+		if out, err := s.JSON(data); err == nil {
+			return out
+		}
+	}
+	lines := bytes.Split(data, []byte("\n"))
+	for i, line := range lines {
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+		if out, err := s.JSON(line); err == nil {
+			lines[i] = out
+		} else {
+			lines[i] = []byte(s.Text(string(line)))
+		}
+	}
+	return bytes.Join(lines, []byte("\n"))
+}
+
+// Report returns what the scrubber found so far.
+func (s *Scrubber) Report() Report {
+	return s.report
+}
+
+// Report counts the findings of each rule by the action taken on them.
+type Report map[string]map[string]int
+
+func (r Report) add(name, action string) {
+	if r[name] == nil {
+		r[name] = map[string]int{}
+	}
+	r[name][action]++
+}
+
+// Finding is the number of matches of a rule that got the same action.
+type Finding struct {
+	Rule   string `json:"rule"`
+	Action string `json:"action"`
+	Count  int    `json:"count"`
+}
+
+// Findings returns the report's counts, sorted by rule and action.
+func (r Report) Findings() []Finding {
+	var findings []Finding
+	for name, actions := range r {
+		for action, count := range actions {
+			findings = append(findings, Finding{Rule: name, Action: action, Count: count})
+		}
+	}
+	sort.Slice(findings, func(i, j int) bool {
+		if findings[i].Rule != findings[j].Rule {
+			return findings[i].Rule < findings[j].Rule
+		}
+		return findings[i].Action < findings[j].Action
+	})
+	return findings
+}
+
+// String describes the report, e.g. "2 email redacted, 1 phone allowed".
+func (r Report) String() string {
+	findings := r.Findings()
+	if len(findings) == 0 {
+		return "nothing found"
+	}
+	past := map[string]string{ActionRedact: "redacted", ActionHash: "hashed", ActionAllow: "allowed"}
+	parts := make([]string, len(findings))
+	for i, f := range findings {
+		parts[i] = fmt.Sprintf("%d %s %s", f.Count, f.Rule, past[f.Action])
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,115 @@
+package privacy
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestScrubberDefaultRules(t *testing.T) {
+	s, err := NewScrubber(&Policy{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := s.Text("mail ada@corp.io or call +44 20 7946 0958 or 030 1234 5678")
+	want := "mail [redacted email] or call [redacted phone] or [redacted phone]"
+	if got != want {
+		t.Errorf("Text() = %q, want %q", got, want)
+	}
+	if report := s.Report().String(); report != "1 email redacted, 2 phone redacted" {
+		t.Errorf("Report() = %q", report)
+	}
+}
+
+func TestScrubberKeepsNumbersThatAreNotPhones(t *testing.T) {
+	s, err := NewScrubber(&Policy{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, text := range []string{
+		"2024-05-01 10:00:00",
+		"listening on 192.168.100.200:8080",
+		"go 1.22.3",
+		"exit status 127",
+	} {
+		if got := s.Text(text); got != text {
+			t.Errorf("Text(%q) = %q, want it unchanged", text, got)
+		}
+	}
+}
+
+func TestScrubberRules(t *testing.T) {
+	s, err := NewScrubber(&Policy{Rules: []Rule{
+		{Detector: DetectorEmail, Action: ActionHash, Allow: []string{"*@EXAMPLE.com"}},
+		{Detector: DetectorName, Values: []string{"Ada", "Ada Lovelace"}},
+		{Detector: DetectorPattern, Name: "employee-id", Pattern: `EMP-[0-9]{6}`, Action: ActionAllow},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := s.Text("Ada Lovelace <ada@corp.io>, Ada, bot@example.com, EMP-123456, Adam")
+	if !strings.HasPrefix(got, "[redacted name] <[email:") {
+		t.Errorf("Text() = %q, want the full name and hashed email replaced", got)
+	}
+	if !strings.HasSuffix(got, ">, [redacted name], bot@example.com, EMP-123456, Adam") {
+		t.Errorf("Text() = %q, want allowed matches kept", got)
+	}
+	if again := s.Text("ada@corp.io"); !strings.Contains(got, again) {
+		t.Errorf("hash %q is not stable in %q", again, got)
+	}
+
+	want := []Finding{
+		{Rule: "email", Action: ActionAllow, Count: 1},
+		{Rule: "email", Action: ActionHash, Count: 2},
+		{Rule: "employee-id", Action: ActionAllow, Count: 1},
+		{Rule: "name", Action: ActionRedact, Count: 2},
+	}
+	findings := s.Report().Findings()
+	if len(findings) != len(want) {
+		t.Fatalf("Findings() = %+v, want %+v", findings, want)
+	}
+	for i := range want {
+		if findings[i] != want[i] {
+			t.Errorf("Findings()[%d] = %+v, want %+v", i, findings[i], want[i])
+		}
+	}
+}
+
+func TestNewScrubberRejectsInvalidRules(t *testing.T) {
+	for _, r := range []Rule{
+		{Detector: "ssn"},
+		{Detector: DetectorEmail, Action: "encrypt"},
+		{Detector: DetectorName},
+		{Detector: DetectorPattern, Pattern: "("},
+		{Detector: DetectorEmail, Allow: []string{"["}},
+	} {
+		if _, err := NewScrubber(&Policy{Rules: []Rule{r}}); err == nil {
+			t.Errorf("NewScrubber(%+v) succeeded, want an error", r)
+		}
+	}
+}
+
+func TestScrubberTranscript(t *testing.T) {
+	s, err := NewScrubber(&Policy{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	transcript := `{"type":"user","message":{"content":"I am ada@corp.io"}}
+{"type":"assistant","message":{"content":"Hello"}}
+not json, ada@corp.io
+`
+	got := string(s.Transcript([]byte(transcript)))
+	lines := strings.Split(got, "\n")
+	if len(lines) != 4 || lines[3] != "" {
+		t.Fatalf("Transcript() = %q, want three lines", got)
+	}
+	if !strings.Contains(lines[0], `"I am [redacted email]"`) {
+		t.Errorf("line 1 = %q", lines[0])
+	}
+	if lines[1] != `{"type":"assistant","message":{"content":"Hello"}}` {
+		t.Errorf("line without findings was changed: %q", lines[1])
+	}
+	if lines[2] != "not json, [redacted email]" {
+		t.Errorf("line 3 = %q", lines[2])
+	}
+}
@@ -0,0 +1,114 @@
+// Package push sends the effort time series of 'shiftlog stats
+// --timeseries' to monitoring systems: a Prometheus Pushgateway or an
+// InfluxDB write endpoint.
+package push
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/re-cinq/shift-log/internal/storage"
+)
+
+// requestTimeout bounds each push.
+const requestTimeout = 30 * time.Second
+
+// Job is the job the series are pushed to a Pushgateway under.
+const Job = "shiftlog"
+
+var client = &http.Client{Timeout: requestTimeout}
+
+// Prometheus pushes the last point of series to the Pushgateway at
+// gatewayURL, as gauges grouped under Job and the repository repo.
+// A Pushgateway keeps only the latest value of each series, without
+// timestamps, so the history comes from Prometheus scraping it after each
+// push, such as from a scheduled CI job.
+func Prometheus(ctx context.Context, gatewayURL, repo, interval string, series []storage.EffortPoint) error {
+	if len(series) == 0 {
+		return nil
+	}
+	p := series[len(series)-1]
+	var body bytes.Buffer
+	gauge := func(name, help, value string) {
+		fmt.Fprintf(&body, "# HELP %s %s\n# TYPE %s gauge\n", name, help, name)
+		fmt.Fprintf(&body, "%s{interval=%q} %s\n", name, interval, value)
+	}
+	gauge("shiftlog_commits", "Commits made in the current interval.", strconv.Itoa(p.Commits))
+	gauge("shiftlog_conversations", "Conversations stored on the commits of the current interval.", strconv.Itoa(p.Conversations))
+	gauge("shiftlog_tokens", "Tokens used by the conversations of the current interval.", strconv.FormatInt(p.Tokens, 10))
+	gauge("shiftlog_cost_dollars", "List price of the tokens of the current interval.", strconv.FormatFloat(p.Cost, 'f', -1, 64))
+	gauge("shiftlog_interval_start_seconds", "Start of the current interval, as a Unix time.", strconv.FormatInt(p.Start.Unix(), 10))
+
+	endpoint := strings.TrimSuffix(gatewayURL, "/") + "/metrics/job/" + url.PathEscape(Job) + "/repo/" + url.PathEscape(repo)
+	// PUT replaces every metric of the group, so none are left from an
+	// older version
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, endpoint, &body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "text/plain; version=0.0.4")
+	return send(req)
+}
+
+// Influx writes series to the InfluxDB write endpoint writeURL, such as
+// http://localhost:8086/api/v2/write?org=acme&bucket=shiftlog, in line
+// protocol: a point of the shiftlog_effort measurement per interval, tagged
+// with the repository and the interval, at the interval's start. Writing
+// the series again overwrites its points. token, when set, authorizes the
+// write.
+func Influx(ctx context.Context, writeURL, token, repo, interval string, series []storage.EffortPoint) error {
+	if len(series) == 0 {
+		return nil
+	}
+	u, err := url.Parse(writeURL)
+	if err != nil {
+		return fmt.Errorf("invalid InfluxDB URL: %w", err)
+	}
+	// The timestamps are in seconds
+	q := u.Query()
+	q.Set("precision", "s")
+	u.RawQuery = q.Encode()
+
+	var body bytes.Buffer
+	for _, p := range series {
+		fmt.Fprintf(&body, "shiftlog_effort,repo=%s,interval=%s commits=%di,conversations=%di,tokens=%di,cost=%s %d\n",
+			escapeTag(repo), escapeTag(interval), p.Commits, p.Conversations, p.Tokens,
+			strconv.FormatFloat(p.Cost, 'f', -1, 64), p.Start.Unix())
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u.String(), &body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	if token != "" {
+		req.Header.Set("Authorization", "Token "+token)
+	}
+	return send(req)
+}
+
+// escapeTag escapes a tag value of the line protocol.
+func escapeTag(s string) string {
+	return strings.NewReplacer(",", `\,`, "=", `\=`, " ", `\ `).Replace(s)
+}
+
+// send sends req and fails unless the response is successful.
+func send(req *http.Request) error {
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode >= 300 {
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("%s %s: %s: %s", req.Method, req.URL.Host, resp.Status, strings.TrimSpace(string(data)))
+	}
+	return nil
+}
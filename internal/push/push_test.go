@@ -0,0 +1,79 @@
+package push
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/re-cinq/shift-log/internal/storage"
+)
+
+var series = []storage.EffortPoint{
+	{Start: time.Date(2025, 3, 3, 0, 0, 0, 0, time.UTC), Commits: 4, Conversations: 2, Tokens: 1200, Cost: 0.25},
+	{Start: time.Date(2025, 3, 10, 0, 0, 0, 0, time.UTC), Commits: 1},
+}
+
+func TestPrometheus(t *testing.T) {
+	var path, body string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPut {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		data, _ := io.ReadAll(r.Body)
+		path, body = r.URL.Path, string(data)
+	}))
+	defer server.Close()
+
+	if err := Prometheus(context.Background(), server.URL+"/", "my repo", storage.IntervalWeek, series); err != nil {
+		t.Fatal(err)
+	}
+	if path != "/metrics/job/shiftlog/repo/my repo" {
+		t.Errorf("path = %q", path)
+	}
+	// Only the last interval is pushed
+	for _, want := range []string{`shiftlog_commits{interval="week"} 1`, `shiftlog_tokens{interval="week"} 0`, "# TYPE shiftlog_cost_dollars gauge"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("body lacks %q:\n%s", want, body)
+		}
+	}
+}
+
+func TestInflux(t *testing.T) {
+	var query, auth, body string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("bucket") == "missing" {
+			http.Error(w, `{"message":"bucket not found"}`, http.StatusNotFound)
+			return
+		}
+		data, _ := io.ReadAll(r.Body)
+		query, auth, body = r.URL.RawQuery, r.Header.Get("Authorization"), string(data)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	if err := Influx(ctx, server.URL+"/api/v2/write?org=acme&bucket=shiftlog", "secret", "my repo", storage.IntervalWeek, series); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(query, "precision=s") || !strings.Contains(query, "bucket=shiftlog") {
+		t.Errorf("query = %q", query)
+	}
+	if auth != "Token secret" {
+		t.Errorf("Authorization = %q", auth)
+	}
+	want := "shiftlog_effort,repo=my\\ repo,interval=week commits=4i,conversations=2i,tokens=1200i,cost=0.25 1740960000\n" +
+		"shiftlog_effort,repo=my\\ repo,interval=week commits=1i,conversations=0i,tokens=0i,cost=0 1741564800\n"
+	if body != want {
+		t.Errorf("body = %q, want %q", body, want)
+	}
+
+	err := Influx(ctx, server.URL+"/api/v2/write?bucket=missing", "", "r", storage.IntervalDay, series)
+	if err == nil || !strings.Contains(err.Error(), "bucket not found") {
+		t.Errorf("a missing bucket: err = %v", err)
+	}
+}
@@ -0,0 +1,206 @@
+package render
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// language describes enough of a programming language's lexical syntax to
+// highlight its keywords, strings, comments and numbers.
+type language struct {
+	keywords      map[string]bool
+	lineComments  []string
+	blockComments [][2]string
+	quotes        string // characters that delimit strings
+	tripleQuotes  bool   // """ and ''' strings, as in Python
+	caseFold      bool   // keywords match in any case, as in SQL
+}
+
+func words(s string) map[string]bool {
+	m := make(map[string]bool)
+	for _, w := range strings.Fields(s) {
+		m[w] = true
+	}
+	return m
+}
+
+var (
+	cLike = [][2]string{{"/*", "*/"}}
+
+	goLang = &language{
+		keywords: words(`break case chan const continue default defer else fallthrough for func go goto if
+			import interface map package range return select struct switch type var
+			true false nil iota`),
+		lineComments: []string{"//"}, blockComments: cLike, quotes: "\"'`",
+	}
+	jsLang = &language{
+		keywords: words(`async await break case catch class const continue debugger default delete do else
+			export extends finally for from function if import in instanceof let new of return
+			static super switch this throw try typeof var void while with yield
+			true false null undefined interface type enum implements readonly as`),
+		lineComments: []string{"//"}, blockComments: cLike, quotes: "\"'`",
+	}
+	pythonLang = &language{
+		keywords: words(`and as assert async await break class continue def del elif else except finally
+			for from global if import in is lambda nonlocal not or pass raise return try while
+			with yield True False None self`),
+		lineComments: []string{"#"}, quotes: "\"'", tripleQuotes: true,
+	}
+	shellLang = &language{
+		keywords: words(`if then else elif fi for while until do done case esac in function return
+			local export set unset echo exit source`),
+		lineComments: []string{"#"}, quotes: "\"'",
+	}
+	rustLang = &language{
+		keywords: words(`as async await break const continue crate dyn else enum extern false fn for if
+			impl in let loop match mod move mut pub ref return self Self static struct super
+			trait true type unsafe use where while Some None Ok Err`),
+		lineComments: []string{"//"}, blockComments: cLike, quotes: "\"",
+	}
+	javaLang = &language{
+		keywords: words(`abstract boolean break byte case catch char class const continue default do
+			double else enum extends final finally float for if implements import instanceof int
+			interface long new package private protected public return short static super switch
+			this throw throws try void volatile while true false null var fun val when object`),
+		lineComments: []string{"//"}, blockComments: cLike, quotes: "\"'",
+	}
+	cLang = &language{
+		keywords: words(`auto break case char const continue default do double else enum extern float
+			for goto if inline int long register return short signed sizeof static struct switch
+			typedef union unsigned void volatile while class namespace template typename public
+			private protected virtual new delete this true false nullptr using`),
+		lineComments: []string{"//"}, blockComments: cLike, quotes: "\"'",
+	}
+	jsonLang = &language{keywords: words(`true false null`), quotes: "\""}
+	yamlLang = &language{keywords: words(`true false null yes no on off`), lineComments: []string{"#"}, quotes: "\"'"}
+	sqlLang  = &language{
+		keywords: words(`select from where and or not insert into values update set delete create
+			table index drop alter add join left right inner outer on group by order having
+			limit offset as distinct union all null is in like between case when then else end
+			primary key references default`),
+		lineComments: []string{"--"}, blockComments: cLike, quotes: "'\"", caseFold: true,
+	}
+)
+
+// languages maps the info strings of code fences to their languages.
+var languages = map[string]*language{
+	"go": goLang, "golang": goLang,
+	"js": jsLang, "javascript": jsLang, "jsx": jsLang, "ts": jsLang, "typescript": jsLang, "tsx": jsLang,
+	"py": pythonLang, "python": pythonLang,
+	"sh": shellLang, "bash": shellLang, "shell": shellLang, "zsh": shellLang, "console": shellLang,
+	"rs": rustLang, "rust": rustLang,
+	"java": javaLang, "kotlin": javaLang, "kt": javaLang,
+	"c": cLang, "h": cLang, "cpp": cLang, "c++": cLang, "cc": cLang,
+	"json": jsonLang,
+	"yaml": yamlLang, "yml": yamlLang,
+	"sql": sqlLang,
+}
+
+// Highlight returns code as HTML, with the keywords, strings, comments and
+// numbers of the given language (a code fence info string such as "go")
+// wrapped in hl-* spans. Code in other languages is only escaped.
+func Highlight(code, lang string) string {
+	l := languages[strings.ToLower(lang)]
+	if l == nil {
+		return EscapeHTML(code)
+	}
+
+	var b strings.Builder
+	span := func(class, text string) {
+		b.WriteString(`<span class="hl-` + class + `">` + EscapeHTML(text) + `</span>`)
+	}
+	for i := 0; i < len(code); {
+		rest := code[i:]
+		if n := l.comment(rest); n > 0 {
+			span("comment", rest[:n])
+			i += n
+			continue
+		}
+		if n := l.str(rest); n > 0 {
+			span("string", rest[:n])
+			i += n
+			continue
+		}
+
+		r, size := utf8.DecodeRuneInString(rest)
+		switch {
+		case unicode.IsDigit(r):
+			n := strings.IndexFunc(rest, func(r rune) bool {
+				return !unicode.IsDigit(r) && !unicode.IsLetter(r) && r != '.' && r != '_'
+			})
+			if n < 0 {
+				n = len(rest)
+			}
+			span("number", rest[:n])
+			i += n
+		case unicode.IsLetter(r) || r == '_':
+			n := strings.IndexFunc(rest, func(r rune) bool {
+				return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_'
+			})
+			if n < 0 {
+				n = len(rest)
+			}
+			word := rest[:n]
+			if l.keywords[word] || (l.caseFold && l.keywords[strings.ToLower(word)]) {
+				span("keyword", word)
+			} else {
+				b.WriteString(EscapeHTML(word))
+			}
+			i += n
+		default:
+			b.WriteString(EscapeHTML(rest[:size]))
+			i += size
+		}
+	}
+	return b.String()
+}
+
+// comment returns the length of the comment at the start of s, or 0.
+func (l *language) comment(s string) int {
+	for _, start := range l.lineComments {
+		if strings.HasPrefix(s, start) {
+			if end := strings.IndexByte(s, '\n'); end >= 0 {
+				return end
+			}
+			return len(s)
+		}
+	}
+	for _, delims := range l.blockComments {
+		if strings.HasPrefix(s, delims[0]) {
+			if end := strings.Index(s[len(delims[0]):], delims[1]); end >= 0 {
+				return len(delims[0]) + end + len(delims[1])
+			}
+			return len(s)
+		}
+	}
+	return 0
+}
+
+// str returns the length of the string literal at the start of s, or 0.
+// Unterminated strings end at the end of their line.
+func (l *language) str(s string) int {
+	if s == "" || !strings.ContainsRune(l.quotes, rune(s[0])) {
+		return 0
+	}
+	quote := s[0]
+	if l.tripleQuotes && len(s) >= 3 && s[1] == quote && s[2] == quote {
+		if end := strings.Index(s[3:], s[:3]); end >= 0 {
+			return end + 6
+		}
+		return len(s)
+	}
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case quote:
+			return i + 1
+		case '\n':
+			if quote != '`' {
+				return i
+			}
+		}
+	}
+	return len(s)
+}
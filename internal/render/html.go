@@ -0,0 +1,410 @@
+// Package render produces the canonical HTML rendering of conversation
+// transcripts. The markup and class names match the shiftlog web viewer, so
+// any page that includes Stylesheet renders conversations identically to it.
+// The package has no dependencies outside the standard library and the agent
+// transcript types, which lets it compile to WebAssembly (see render/wasm).
+package render
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"html"
+	"math"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/re-cinq/shift-log/internal/agent"
+)
+
+// Stylesheet is the CSS for the conversation markup produced by this package.
+//
+//go:embed conversation.css
+var Stylesheet string
+
+const (
+	// thinkingPreviewLines is the number of thinking lines shown collapsed.
+	thinkingPreviewLines = 3
+	// toolResultMaxLines is the number of lines of a tool result shown
+	// folded.
+	toolResultMaxLines = 20
+	// bashSummaryMaxLen caps the length of a Bash command summary.
+	bashSummaryMaxLen = 60
+)
+
+var htmlEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+var (
+	// servedURLPattern matches the URLs shiftlog serve points attachments
+	// and truncated tool results at.
+	servedURLPattern = regexp.MustCompile(`^/api/commits/[0-9a-f]+/`)
+	// imageDataURLPattern matches the data: URLs of embedded images.
+	imageDataURLPattern = regexp.MustCompile(`^data:image/[\w.+-]+;base64,[A-Za-z0-9+/=]*$`)
+)
+
+// RenderedEntry is the HTML of a transcript entry.
+type RenderedEntry struct {
+	UUID string `json:"uuid,omitempty"`
+	HTML string `json:"html"`
+}
+
+// Transcript renders transcript entries as HTML. User, assistant and system
+// entries are rendered; all other entry types are skipped.
+func Transcript(entries []agent.TranscriptEntry) string {
+	rendered := Entries(entries)
+	if len(rendered) == 0 {
+		return `<div class="empty-state"><div class="empty-state-icon">&#x1F4ED;</div><p>Conversation is empty</p></div>`
+	}
+	var b strings.Builder
+	for _, e := range rendered {
+		b.WriteString(e.HTML)
+	}
+	return b.String()
+}
+
+// Entries renders each transcript entry, leaving out those that render to
+// nothing, so callers can place their own markup between entries.
+func Entries(entries []agent.TranscriptEntry) []RenderedEntry {
+	rendered := []RenderedEntry{}
+	for i := range entries {
+		if html := Entry(&entries[i]); html != "" {
+			rendered = append(rendered, RenderedEntry{UUID: entries[i].UUID, HTML: html})
+		}
+	}
+	return rendered
+}
+
+// Entry renders a user, assistant or system entry, and returns "" for
+// other entries and entries without content.
+func Entry(entry *agent.TranscriptEntry) string {
+	switch entry.Type {
+	case agent.MessageTypeUser:
+		return userMessage(entry)
+	case agent.MessageTypeAssistant:
+		return assistantMessage(entry)
+	case agent.MessageTypeSystem:
+		return systemMessage(entry)
+	}
+	return ""
+}
+
+func content(entry *agent.TranscriptEntry) []agent.ContentBlock {
+	if entry.Message == nil {
+		return nil
+	}
+	return entry.Message.Content
+}
+
+func userMessage(entry *agent.TranscriptEntry) string {
+	blocks := content(entry)
+
+	// Tool results are carried in user entries but rendered on their own
+	for _, block := range blocks {
+		if block.Type == "tool_result" {
+			return toolResult(block)
+		}
+	}
+
+	text := firstText(blocks)
+	files := attachments(blocks)
+	if text == "" && files == "" {
+		return ""
+	}
+	out := `<div class="message user"><div class="message-role">User</div>`
+	if text != "" {
+		out += `<div class="message-content">` + Markdown(text) + `</div>`
+	}
+	return out + files + `</div>`
+}
+
+func assistantMessage(entry *agent.TranscriptEntry) string {
+	var b strings.Builder
+	b.WriteString(`<div class="message assistant"><div class="message-role">Assistant</div>`)
+	b.WriteString(usage(entry.Usage))
+	for _, block := range content(entry) {
+		switch {
+		case block.Type == "text" && block.Text != "":
+			b.WriteString(`<div class="message-content">` + Markdown(block.Text) + `</div>`)
+		case block.Type == "thinking" && block.Omitted != "":
+			b.WriteString(omittedThinking(block))
+		case block.Type == "thinking" && block.Thinking != "":
+			b.WriteString(thinking(block.Thinking))
+		case block.Type == "tool_use":
+			b.WriteString(toolUse(block))
+		}
+	}
+	b.WriteString(attachments(content(entry)))
+	b.WriteString(`</div>`)
+	return b.String()
+}
+
+// usage renders the token usage of an assistant entry, if recorded.
+func usage(u *agent.UsageMetrics) string {
+	if u == nil || u.TotalTokens() == 0 {
+		return ""
+	}
+	return fmt.Sprintf(`<div class="message-usage">%d in &middot; %d out tokens</div>`, u.InputTokens, u.OutputTokens)
+}
+
+func systemMessage(entry *agent.TranscriptEntry) string {
+	text := firstText(content(entry))
+	if text == "" {
+		return ""
+	}
+	return `<div class="message system"><div class="message-role">System</div>` +
+		`<div class="message-content">` + Markdown(text) + `</div></div>`
+}
+
+func firstText(blocks []agent.ContentBlock) string {
+	for _, block := range blocks {
+		if block.Type == "text" {
+			return block.Text
+		}
+	}
+	return ""
+}
+
+// omittedThinking renders a thinking block whose text the repository's
+// thinking policy left out: a placeholder, with the hash of the text when
+// it was hashed.
+func omittedThinking(block agent.ContentBlock) string {
+	note := "Not stored, as the repository's thinking policy says."
+	if block.Omitted == agent.ThinkingHashed && block.Thinking != "" {
+		note = "Stored as its hash, as the repository's thinking policy says: " + block.Thinking
+	}
+	return `<div class="thinking-block thinking-omitted"><div class="thinking-header">` +
+		`<span>&#x1F4AD; Thinking omitted</span></div>` +
+		`<div class="thinking-preview">` + EscapeHTML(note) + `</div></div>`
+}
+
+func thinking(text string) string {
+	lines := strings.Split(text, "\n")
+	hasMore := len(lines) > thinkingPreviewLines
+	preview := text
+	if hasMore {
+		preview = strings.Join(lines[:thinkingPreviewLines], "\n")
+	}
+
+	var b strings.Builder
+	b.WriteString(`<div class="thinking-block">`)
+	b.WriteString(`<div class="thinking-header" onclick="this.parentElement.classList.toggle('expanded')">`)
+	b.WriteString(`<span>&#x1F4AD; Thinking</span><span class="toggle-icon">`)
+	if hasMore {
+		b.WriteString("▶")
+	}
+	b.WriteString(`</span></div>`)
+	b.WriteString(`<div class="thinking-preview">` + EscapeHTML(preview))
+	if hasMore {
+		b.WriteString("...")
+	}
+	b.WriteString(`</div>`)
+	if hasMore {
+		b.WriteString(`<div class="thinking-full">` + EscapeHTML(text) + `</div>`)
+	}
+	b.WriteString(`</div>`)
+	return b.String()
+}
+
+func toolUse(block agent.ContentBlock) string {
+	summary, full := toolInput(block.Name, block.Input)
+	return `<div class="tool-use"><div class="tool-header">` +
+		`<span class="tool-name">&#x1F527; ` + EscapeHTML(block.Name) + `</span>` +
+		`<span class="tool-summary">` + EscapeHTML(summary) + `</span>` +
+		"<span class=\"toggle-icon\">▶</span></div>" +
+		`<div class="tool-content">` + EscapeHTML(full) + `</div></div>`
+}
+
+// toolInput returns a one-line summary of a tool's input and its full,
+// indented form. Key order of the original input is preserved.
+func toolInput(toolName string, raw json.RawMessage) (summary, full string) {
+	if len(raw) == 0 {
+		return "", "{}"
+	}
+
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return "", s
+	}
+
+	var indented bytes.Buffer
+	if err := json.Indent(&indented, raw, "", "  "); err != nil {
+		return "", string(raw)
+	}
+	full = indented.String()
+
+	var input map[string]interface{}
+	if err := json.Unmarshal(raw, &input); err != nil {
+		return "", full
+	}
+	str := func(key string) string {
+		v, _ := input[key].(string)
+		return v
+	}
+
+	switch toolName {
+	case "Bash":
+		firstLine := strings.SplitN(str("command"), "\n", 2)[0]
+		if utf8.RuneCountInString(firstLine) > bashSummaryMaxLen {
+			firstLine = string([]rune(firstLine)[:bashSummaryMaxLen]) + "..."
+		}
+		summary = firstLine
+	case "Write", "Read", "Edit":
+		summary = str("file_path")
+	case "Grep":
+		if p := str("pattern"); p != "" {
+			summary = "pattern: " + p
+		}
+	case "Glob":
+		summary = str("pattern")
+	}
+	return summary, full
+}
+
+// toolResult renders a tool result. Results longer than toolResultMaxLines
+// are folded to their first lines, with a button expanding them. Results
+// that shiftlog serve truncated leave the full output empty and point
+// data-url at it; the viewer loads it when expanded.
+func toolResult(block agent.ContentBlock) string {
+	text := block.Text
+	if text == "" {
+		text = toolResultText(block.Content)
+	}
+	// Tools such as Read return images as content blocks
+	var blocks []agent.ContentBlock
+	_ = json.Unmarshal(block.Content, &blocks)
+	files := attachments(blocks)
+	if text == "" && files == "" {
+		return ""
+	}
+
+	header := `<div class="tool-result"><div class="tool-result-header">&#x1F4E4; Tool Result</div>`
+	lines := strings.Split(text, "\n")
+	// Only shiftlog serve's own URLs are fetched for the full output
+	truncated := block.Truncated && servedURLPattern.MatchString(block.ContentURL)
+	switch {
+	case text == "":
+		return header + files + `</div>`
+	case !truncated && len(lines) <= toolResultMaxLines:
+		return header + `<div class="tool-result-content">` + EscapeHTML(text) + `</div>` + files + `</div>`
+	}
+
+	preview := strings.Join(lines[:min(len(lines), toolResultMaxLines)], "\n") + "\n..."
+	total := len(lines)
+	full := `<div class="tool-result-content tool-result-full">` + EscapeHTML(text) + `</div>`
+	if truncated {
+		total = block.ContentLines
+		full = `<div class="tool-result-content tool-result-full" data-url="` + html.EscapeString(block.ContentURL) +
+			`" data-tool-use-id="` + html.EscapeString(block.ToolUseID) + `"></div>`
+	}
+	label := fmt.Sprintf("Expand full output (%d lines)", total)
+	return header + `<div class="tool-result-content tool-result-preview">` + EscapeHTML(preview) + `</div>` + full +
+		`<button class="tool-result-expand" data-label="` + label + `">` + label + `</button>` + files + `</div>`
+}
+
+// attachments renders the image and document blocks of a message. Blocks
+// served by shiftlog serve link to their attachment_url; images that still
+// carry their data embed it as a data: URL. Any other URL is dropped, so a
+// transcript cannot point the viewer at a javascript: URL. Attachments left
+// out when the conversation was stored show their type and size instead.
+func attachments(blocks []agent.ContentBlock) string {
+	var b strings.Builder
+	for _, block := range blocks {
+		if (block.Type != "image" && block.Type != "document") || block.Source == nil {
+			continue
+		}
+		source := block.Source
+		mediaType := source.MediaType
+		if mediaType == "" {
+			mediaType = block.Type
+		}
+		url := block.AttachmentURL
+		if url == "" && source.Type == agent.SourceBase64 && source.Data != "" {
+			url = "data:" + source.MediaType + ";base64," + source.Data
+		}
+		if !servedURLPattern.MatchString(url) && !imageDataURLPattern.MatchString(url) {
+			url = ""
+		}
+		size := ""
+		if source.Size > 0 {
+			size = attachmentSize(source.Size)
+		}
+
+		switch {
+		case url == "":
+			if size != "" {
+				size = ", " + size
+			}
+			b.WriteString(`<span class="attachment-omitted">` + EscapeHTML(block.Type) + ` not stored (` + EscapeHTML(mediaType) + size + `)</span>`)
+		case block.Type == "image":
+			b.WriteString(`<a href="` + html.EscapeString(url) + `" target="_blank" rel="noopener">` +
+				`<img src="` + html.EscapeString(url) + `" alt="` + html.EscapeString(mediaType) + `" loading="lazy"></a>`)
+		default:
+			if size != "" {
+				size = " (" + size + ")"
+			}
+			b.WriteString(`<a href="` + html.EscapeString(url) + `" target="_blank" rel="noopener">&#x1F4CE; ` + EscapeHTML(mediaType) + size + `</a>`)
+		}
+	}
+	if b.Len() == 0 {
+		return ""
+	}
+	return `<div class="message-attachments">` + b.String() + `</div>`
+}
+
+// attachmentSize formats a size in bytes like the viewer does.
+func attachmentSize(bytes int64) string {
+	switch {
+	case bytes >= 1024*1024:
+		return fmt.Sprintf("%.1f MB", float64(bytes)/(1024*1024))
+	case bytes >= 1024:
+		return fmt.Sprintf("%d KB", int64(math.Round(float64(bytes)/1024)))
+	}
+	return fmt.Sprintf("%d B", bytes)
+}
+
+// toolResultText flattens tool result content, which agents record either as
+// a string, an array of content blocks, or an arbitrary JSON value.
+func toolResultText(raw json.RawMessage) string {
+	if len(raw) == 0 {
+		return ""
+	}
+
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return s
+	}
+
+	var blocks []struct {
+		Text string `json:"text"`
+	}
+	if err := json.Unmarshal(raw, &blocks); err == nil {
+		texts := make([]string, len(blocks))
+		for i, b := range blocks {
+			texts[i] = b.Text
+		}
+		return strings.Join(texts, "\n")
+	}
+
+	var indented bytes.Buffer
+	if err := json.Indent(&indented, raw, "", "  "); err != nil {
+		return string(raw)
+	}
+	if indented.String() == "null" {
+		return ""
+	}
+	return indented.String()
+}
+
+// EscapeHTML escapes text for use in HTML element content.
+func EscapeHTML(text string) string {
+	return htmlEscaper.Replace(text)
+}
+
+// Document wraps rendered conversation HTML in a standalone page that
+// includes Stylesheet.
+func Document(title, body string) string {
+	return fmt.Sprintf("<!DOCTYPE html>\n<html lang=\"en\">\n<head>\n<meta charset=\"UTF-8\">\n<title>%s</title>\n<style>\n%s</style>\n</head>\n<body>\n<div class=\"conversation-content\">%s</div>\n</body>\n</html>\n",
+		EscapeHTML(title), Stylesheet, body)
+}
@@ -0,0 +1,288 @@
+package render
+
+import (
+	"encoding/json"
+	"os"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/re-cinq/shift-log/internal/agent"
+)
+
+func entry(typ agent.MessageType, blocks ...agent.ContentBlock) agent.TranscriptEntry {
+	return agent.TranscriptEntry{Type: typ, Message: &agent.Message{Content: blocks}}
+}
+
+func TestTranscriptEmpty(t *testing.T) {
+	got := Transcript(nil)
+	if !strings.Contains(got, "Conversation is empty") {
+		t.Errorf("empty transcript should render empty state, got %q", got)
+	}
+}
+
+func TestTranscriptUserAndAssistant(t *testing.T) {
+	html := Transcript([]agent.TranscriptEntry{
+		entry(agent.MessageTypeUser, agent.ContentBlock{Type: "text", Text: "Fix <b>this</b> & that"}),
+		entry(agent.MessageTypeAssistant,
+			agent.ContentBlock{Type: "text", Text: "Run `go test`"},
+			agent.ContentBlock{Type: "tool_use", Name: "Bash", Input: json.RawMessage(`{"command":"go test ./...","description":"run"}`)},
+		),
+		{Type: "progress"},
+	})
+
+	wants := []string{
+		`<div class="message user"><div class="message-role">User</div><div class="message-content"><p>Fix &lt;b&gt;this&lt;/b&gt; &amp; that</p></div></div>`,
+		`<div class="message assistant"><div class="message-role">Assistant</div>`,
+		`<div class="message-content"><p>Run <code>go test</code></p></div>`,
+		`<span class="tool-name">&#x1F527; Bash</span>`,
+		`<span class="tool-summary">go test ./...</span>`,
+		"{\n  \"command\": \"go test ./...\",\n  \"description\": \"run\"\n}",
+	}
+	for _, want := range wants {
+		if !strings.Contains(html, want) {
+			t.Errorf("rendered HTML missing %q\ngot: %s", want, html)
+		}
+	}
+}
+
+func TestTranscriptToolResult(t *testing.T) {
+	lines := make([]string, 25)
+	for i := range lines {
+		lines[i] = "line"
+	}
+	content, _ := json.Marshal([]map[string]string{{"type": "text", "text": strings.Join(lines, "\n")}})
+
+	html := Transcript([]agent.TranscriptEntry{
+		entry(agent.MessageTypeUser, agent.ContentBlock{Type: "tool_result", ToolUseID: "t1", Content: content}),
+	})
+
+	if !strings.Contains(html, `<div class="tool-result-header">&#x1F4E4; Tool Result</div>`) {
+		t.Errorf("tool result header missing: %s", html)
+	}
+	preview := regexp.MustCompile(`<div class="tool-result-content tool-result-preview">([^<]*)</div>`).FindStringSubmatch(html)
+	if preview == nil || strings.Count(preview[1], "line") != toolResultMaxLines {
+		t.Errorf("tool result should be folded to %d lines: %s", toolResultMaxLines, html)
+	}
+	if !strings.Contains(html, `<button class="tool-result-expand" data-label="Expand full output (25 lines)">`) {
+		t.Errorf("folded tool result has no expand button: %s", html)
+	}
+	full := regexp.MustCompile(`<div class="tool-result-content tool-result-full">([^<]*)</div>`).FindStringSubmatch(html)
+	if full == nil || strings.Count(full[1], "line") != 25 {
+		t.Errorf("folded tool result should carry its full output: %s", html)
+	}
+	if strings.Contains(html, `class="message user"`) {
+		t.Error("tool result should not be rendered as a user message")
+	}
+}
+
+func TestTranscriptThinking(t *testing.T) {
+	html := Transcript([]agent.TranscriptEntry{
+		entry(agent.MessageTypeAssistant, agent.ContentBlock{Type: "thinking", Thinking: "a\nb\nc\nd"}),
+	})
+	if !strings.Contains(html, `<div class="thinking-preview">a
+b
+c...</div>`) {
+		t.Errorf("thinking preview missing: %s", html)
+	}
+	if !strings.Contains(html, `<div class="thinking-full">a
+b
+c
+d</div>`) {
+		t.Errorf("thinking full text missing: %s", html)
+	}
+}
+
+func TestTranscriptOmittedThinking(t *testing.T) {
+	html := Transcript([]agent.TranscriptEntry{
+		entry(agent.MessageTypeAssistant, agent.ContentBlock{Type: "thinking", Omitted: agent.ThinkingStripped}),
+		entry(agent.MessageTypeAssistant, agent.ContentBlock{Type: "thinking", Thinking: "sha256:abc", Omitted: agent.ThinkingHashed}),
+	})
+	if strings.Count(html, "Thinking omitted") != 2 {
+		t.Errorf("omitted thinking should be rendered as placeholders: %s", html)
+	}
+	if !strings.Contains(html, "sha256:abc") {
+		t.Errorf("hashed thinking should show its hash: %s", html)
+	}
+}
+
+func TestTranscriptTruncatedToolResult(t *testing.T) {
+	content, _ := json.Marshal("first\nsecond")
+	html := Transcript([]agent.TranscriptEntry{
+		entry(agent.MessageTypeUser, agent.ContentBlock{
+			Type: "tool_result", ToolUseID: "t1", Content: content,
+			Truncated: true, ContentLines: 5000, ContentURL: "/api/commits/abc/entries/u1/content?conversation=0",
+		}),
+	})
+
+	wants := []string{
+		`<div class="tool-result-content tool-result-preview">first` + "\nsecond\n" + `...</div>`,
+		`<div class="tool-result-content tool-result-full" data-url="/api/commits/abc/entries/u1/content?conversation=0" data-tool-use-id="t1"></div>`,
+		`Expand full output (5000 lines)</button>`,
+	}
+	for _, want := range wants {
+		if !strings.Contains(html, want) {
+			t.Errorf("rendered HTML missing %q\ngot: %s", want, html)
+		}
+	}
+}
+
+func TestMarkdown(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"code fence", "before\n```go\nx := <y>\n```\nafter",
+			`<p>before</p><pre><code class="language-go">x := &lt;y&gt;` + "\n" + `</code></pre><p>after</p>`},
+		{"paragraph lines", "one\ntwo\n\nthree", "<p>one\ntwo</p><p>three</p>"},
+		{"heading", "## Plan ##", "<h2>Plan</h2>"},
+		{"lists", "- a\n- **b**\n1. c\n2) d", "<ul><li>a</li><li><strong>b</strong></li></ul><ol><li>c</li><li>d</li></ol>"},
+		{"quote", "> note\n> *this*", "<blockquote><p>note\n<em>this</em></p></blockquote>"},
+		{"rule", "a\n\n---\nb", "<p>a</p><hr><p>b</p>"},
+		{"link", "[docs](https://example.com/a?b=1&c=\"2\")",
+			`<p><a href="https://example.com/a?b=1&amp;c=&#34;2&#34;" target="_blank" rel="noopener noreferrer">docs</a></p>`},
+		{"unsafe link", "[x](javascript:alert(1))", "<p>[x](javascript:alert(1))</p>"},
+		{"raw html", "<script>alert(1)</script>", "<p>&lt;script&gt;alert(1)&lt;/script&gt;</p>"},
+		{"code span keeps markup", "`**not bold** <b>`", "<p><code>**not bold** &lt;b&gt;</code></p>"},
+		{"multiplication is not italics", "2 * 3 * 4", "<p>2 * 3 * 4</p>"},
+		{"unterminated fence", "```\ncode", "<pre><code>code</code></pre>"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Markdown(tt.in); got != tt.want {
+				t.Errorf("Markdown(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHighlight(t *testing.T) {
+	got := Highlight("func f() string { return \"a<b\" } // done\nx := 42", "go")
+	want := `<span class="hl-keyword">func</span> f() string { <span class="hl-keyword">return</span> ` +
+		`<span class="hl-string">&#34;a&lt;b&#34;</span> } <span class="hl-comment">// done</span>` + "\n" +
+		`x := <span class="hl-number">42</span>`
+	want = strings.ReplaceAll(want, "&#34;", `"`)
+	if got != want {
+		t.Errorf("Highlight(go) = %q, want %q", got, want)
+	}
+
+	if got := Highlight(`def f(): """doc""" # x`, "python"); got != `<span class="hl-keyword">def</span> f(): <span class="hl-string">"""doc"""</span> <span class="hl-comment"># x</span>` {
+		t.Errorf("Highlight(python) = %q", got)
+	}
+	if got := Highlight("SELECT 1", "sql"); got != `<span class="hl-keyword">SELECT</span> <span class="hl-number">1</span>` {
+		t.Errorf("Highlight(sql) = %q", got)
+	}
+	if got := Highlight("if <x>", "unknown"); got != "if &lt;x&gt;" {
+		t.Errorf("Highlight(unknown) = %q", got)
+	}
+}
+
+func TestToolInputSummaries(t *testing.T) {
+	long := strings.Repeat("x", 70)
+	tests := []struct {
+		tool  string
+		input string
+		want  string
+	}{
+		{"Bash", `{"command":"echo hi\necho bye"}`, "echo hi"},
+		{"Bash", `{"command":"` + long + `"}`, strings.Repeat("x", 60) + "..."},
+		{"Edit", `{"file_path":"main.go"}`, "main.go"},
+		{"Grep", `{"pattern":"TODO"}`, "pattern: TODO"},
+		{"Glob", `{"pattern":"*.go"}`, "*.go"},
+		{"Unknown", `{"file_path":"main.go"}`, ""},
+		{"Bash", `"raw string input"`, ""},
+	}
+	for _, tt := range tests {
+		summary, _ := toolInput(tt.tool, json.RawMessage(tt.input))
+		if summary != tt.want {
+			t.Errorf("toolInput(%s, %s) summary = %q, want %q", tt.tool, tt.input, summary, tt.want)
+		}
+	}
+}
+
+// TestStylesheetMatchesViewer ensures every rule in the shared stylesheet is
+// identical to the web viewer's, so embedders render like the official UI.
+func TestStylesheetMatchesViewer(t *testing.T) {
+	viewer, err := os.ReadFile("../web/static/index.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	normalize := func(s string) string {
+		return strings.Join(strings.Fields(s), " ")
+	}
+	viewerCSS := normalize(string(viewer))
+
+	rules := regexp.MustCompile(`(?s)[^{}]+\{[^}]*\}`).FindAllString(Stylesheet, -1)
+	if len(rules) == 0 {
+		t.Fatal("stylesheet has no rules")
+	}
+	for _, rule := range rules {
+		rule = strings.TrimSpace(regexp.MustCompile(`(?s)/\*.*?\*/`).ReplaceAllString(rule, ""))
+		if !strings.Contains(viewerCSS, normalize(rule)) {
+			t.Errorf("stylesheet rule differs from viewer:\n%s", rule)
+		}
+	}
+}
+
+func TestTranscriptAssistantUsage(t *testing.T) {
+	withUsage := entry(agent.MessageTypeAssistant, agent.ContentBlock{Type: "text", Text: "Done"})
+	withUsage.Usage = &agent.UsageMetrics{InputTokens: 1200, OutputTokens: 34}
+
+	html := Transcript([]agent.TranscriptEntry{withUsage})
+	want := `<div class="message-role">Assistant</div><div class="message-usage">1200 in &middot; 34 out tokens</div>`
+	if !strings.Contains(html, want) {
+		t.Errorf("rendered HTML missing %q\ngot: %s", want, html)
+	}
+
+	html = Transcript([]agent.TranscriptEntry{entry(agent.MessageTypeAssistant, agent.ContentBlock{Type: "text", Text: "Done"})})
+	if strings.Contains(html, "message-usage") {
+		t.Errorf("entry without usage rendered a usage line: %s", html)
+	}
+}
+
+func TestTranscriptAttachments(t *testing.T) {
+	screenshot, _ := json.Marshal([]map[string]interface{}{
+		{"type": "image", "source": map[string]string{"type": "base64", "media_type": "image/png", "data": "iVBORw=="}},
+	})
+	html := Transcript([]agent.TranscriptEntry{
+		entry(agent.MessageTypeUser,
+			agent.ContentBlock{Type: "text", Text: "Why is this red?"},
+			agent.ContentBlock{Type: "image", Source: &agent.AttachmentSource{Type: agent.SourceBase64, MediaType: "image/png", Size: 3}, AttachmentURL: "/api/commits/abc/attachments/0123"},
+			agent.ContentBlock{Type: "document", Source: &agent.AttachmentSource{Type: agent.SourceOmitted, MediaType: "application/pdf", Size: 3 << 20}},
+		),
+		entry(agent.MessageTypeUser, agent.ContentBlock{Type: "tool_result", ToolUseID: "t1", Content: screenshot}),
+	})
+
+	wants := []string{
+		`<div class="message-content"><p>Why is this red?</p></div><div class="message-attachments">`,
+		`<a href="/api/commits/abc/attachments/0123" target="_blank" rel="noopener"><img src="/api/commits/abc/attachments/0123" alt="image/png" loading="lazy"></a>`,
+		`<span class="attachment-omitted">document not stored (application/pdf, 3.0 MB)</span>`,
+		`<div class="tool-result"><div class="tool-result-header">&#x1F4E4; Tool Result</div><div class="message-attachments"><a href="data:image/png;base64,iVBORw=="`,
+	}
+	for _, want := range wants {
+		if !strings.Contains(html, want) {
+			t.Errorf("rendered HTML missing %q\ngot: %s", want, html)
+		}
+	}
+}
+
+func TestTranscriptRejectsForeignURLs(t *testing.T) {
+	content, _ := json.Marshal("done")
+	html := Transcript([]agent.TranscriptEntry{
+		entry(agent.MessageTypeUser,
+			agent.ContentBlock{Type: "image", Source: &agent.AttachmentSource{Type: "url", MediaType: "image/png"}, AttachmentURL: "javascript:alert(1)"},
+			agent.ContentBlock{Type: "document", Source: &agent.AttachmentSource{Type: agent.SourceBase64, MediaType: "text/html", Data: "PHNjcmlwdD4="}},
+		),
+		entry(agent.MessageTypeUser, agent.ContentBlock{Type: "tool_result", ToolUseID: "t1", Content: content, Truncated: true, ContentURL: "javascript:alert(2)"}),
+	})
+
+	for _, unwanted := range []string{"javascript:", "data:text/html", "data-url="} {
+		if strings.Contains(html, unwanted) {
+			t.Errorf("rendered HTML contains %q: %s", unwanted, html)
+		}
+	}
+	if !strings.Contains(html, `<span class="attachment-omitted">image not stored (image/png)</span>`) {
+		t.Errorf("image with a foreign URL should render as not stored: %s", html)
+	}
+}
@@ -0,0 +1,154 @@
+package render
+
+import (
+	"html"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// The Markdown subset rendered in messages: fenced code blocks, headings,
+// bullet and numbered lists, block quotes, rules and paragraphs, with
+// inline code, links, bold and italics. Raw HTML is never passed through;
+// everything is escaped, and links only keep http, https and mailto URLs.
+
+var (
+	fencePattern    = regexp.MustCompile("^\\s*(```+|~~~+)\\s*([\\w+#.-]*)")
+	headingPattern  = regexp.MustCompile(`^(#{1,6})\s+(.*?)\s*#*\s*$`)
+	bulletPattern   = regexp.MustCompile(`^\s*[-*+]\s+(.*)$`)
+	orderedPattern  = regexp.MustCompile(`^\s*\d+[.)]\s+(.*)$`)
+	quotePattern    = regexp.MustCompile(`^\s*>\s?(.*)$`)
+	rulePattern     = regexp.MustCompile(`^\s*(?:(?:-\s*){3,}|(?:\*\s*){3,}|(?:_\s*){3,})$`)
+	linkPattern     = regexp.MustCompile(`\[([^\]\n]+)\]\(([^)\s]+)\)`)
+	boldPattern     = regexp.MustCompile(`\*\*([^*\n]+)\*\*|__([^_\n]+)__`)
+	italicPattern   = regexp.MustCompile(`(^|[^\w*])\*([^*\s](?:[^*\n]*[^*\s])?)\*`)
+	codeSpanPattern = regexp.MustCompile("`([^`\n]+)`")
+)
+
+// Markdown renders text as HTML. Lines of a paragraph keep their line
+// breaks, which the viewer's pre-wrap styling shows.
+func Markdown(text string) string {
+	lines := strings.Split(text, "\n")
+	var b strings.Builder
+	for i := 0; i < len(lines); {
+		line := lines[i]
+		switch {
+		case strings.TrimSpace(line) == "":
+			i++
+
+		case fencePattern.MatchString(line):
+			m := fencePattern.FindStringSubmatch(line)
+			fence, lang := m[1], m[2]
+			j := i + 1
+			for j < len(lines) && !strings.HasPrefix(strings.TrimSpace(lines[j]), fence) {
+				j++
+			}
+			code := strings.Join(lines[i+1:min(j, len(lines))], "\n")
+			if j < len(lines) {
+				code += "\n"
+			}
+			class := ""
+			if lang != "" {
+				class = ` class="language-` + html.EscapeString(strings.ToLower(lang)) + `"`
+			}
+			b.WriteString(`<pre><code` + class + `>` + Highlight(code, lang) + `</code></pre>`)
+			i = j + 1
+
+		case headingPattern.MatchString(line):
+			m := headingPattern.FindStringSubmatch(line)
+			tag := "h" + string(rune('0'+len(m[1])))
+			b.WriteString(`<` + tag + `>` + Inline(m[2]) + `</` + tag + `>`)
+			i++
+
+		case rulePattern.MatchString(line):
+			b.WriteString(`<hr>`)
+			i++
+
+		case bulletPattern.MatchString(line), orderedPattern.MatchString(line):
+			pattern, tag := bulletPattern, "ul"
+			if !bulletPattern.MatchString(line) {
+				pattern, tag = orderedPattern, "ol"
+			}
+			b.WriteString(`<` + tag + `>`)
+			for ; i < len(lines) && pattern.MatchString(lines[i]) && !rulePattern.MatchString(lines[i]); i++ {
+				b.WriteString(`<li>` + Inline(pattern.FindStringSubmatch(lines[i])[1]) + `</li>`)
+			}
+			b.WriteString(`</` + tag + `>`)
+
+		case quotePattern.MatchString(line):
+			var quoted []string
+			for ; i < len(lines) && quotePattern.MatchString(lines[i]); i++ {
+				quoted = append(quoted, quotePattern.FindStringSubmatch(lines[i])[1])
+			}
+			b.WriteString(`<blockquote>` + Markdown(strings.Join(quoted, "\n")) + `</blockquote>`)
+
+		default:
+			var para []string
+			for ; i < len(lines) && strings.TrimSpace(lines[i]) != "" && !startsBlock(lines[i]); i++ {
+				para = append(para, lines[i])
+			}
+			b.WriteString(`<p>` + Inline(strings.Join(para, "\n")) + `</p>`)
+		}
+	}
+	return b.String()
+}
+
+// startsBlock reports whether line starts a block other than a paragraph.
+func startsBlock(line string) bool {
+	return fencePattern.MatchString(line) || headingPattern.MatchString(line) ||
+		bulletPattern.MatchString(line) || orderedPattern.MatchString(line) ||
+		quotePattern.MatchString(line) || rulePattern.MatchString(line)
+}
+
+// Inline renders the inline Markdown of text: code spans, links, bold and
+// italics.
+func Inline(text string) string {
+	var b strings.Builder
+	last := 0
+	for _, m := range codeSpanPattern.FindAllStringSubmatchIndex(text, -1) {
+		b.WriteString(links(text[last:m[0]]))
+		b.WriteString(`<code>` + EscapeHTML(text[m[2]:m[3]]) + `</code>`)
+		last = m[1]
+	}
+	b.WriteString(links(text[last:]))
+	return b.String()
+}
+
+func links(text string) string {
+	var b strings.Builder
+	last := 0
+	for _, m := range linkPattern.FindAllStringSubmatchIndex(text, -1) {
+		b.WriteString(emphasis(EscapeHTML(text[last:m[0]])))
+		label, target := text[m[2]:m[3]], text[m[4]:m[5]]
+		if safeURL(target) {
+			b.WriteString(`<a href="` + html.EscapeString(target) + `" target="_blank" rel="noopener noreferrer">` +
+				emphasis(EscapeHTML(label)) + `</a>`)
+		} else {
+			b.WriteString(emphasis(EscapeHTML(text[m[0]:m[1]])))
+		}
+		last = m[1]
+	}
+	b.WriteString(emphasis(EscapeHTML(text[last:])))
+	return b.String()
+}
+
+// emphasis renders bold and italics in escaped text.
+func emphasis(escaped string) string {
+	escaped = boldPattern.ReplaceAllString(escaped, "<strong>$1$2</strong>")
+	return italicPattern.ReplaceAllString(escaped, "$1<em>$2</em>")
+}
+
+// safeURL reports whether a link target may be rendered as a link.
+func safeURL(target string) bool {
+	u, err := url.Parse(target)
+	if err != nil {
+		return false
+	}
+	switch strings.ToLower(u.Scheme) {
+	case "http", "https":
+		return u.Host != ""
+	case "mailto":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,66 @@
+//go:build js && wasm
+
+// Command wasm exposes the canonical transcript renderer to JavaScript.
+//
+// Build with `make wasm`, then load dist/shiftlog-render.wasm with Go's
+// wasm_exec.js. Once started it defines a global `shiftlog` object:
+//
+//	shiftlog.render(json)  // HTML for a /api/commits/<sha> response or a transcript array
+//	shiftlog.stylesheet    // CSS for the rendered markup
+package main
+
+import (
+	"encoding/json"
+	"syscall/js"
+
+	"github.com/re-cinq/shift-log/internal/agent"
+	"github.com/re-cinq/shift-log/internal/render"
+)
+
+func main() {
+	js.Global().Set("shiftlog", js.ValueOf(map[string]interface{}{
+		"render":     js.FuncOf(renderFunc),
+		"stylesheet": render.Stylesheet,
+	}))
+
+	// Keep the Go runtime alive so the exported functions stay callable.
+	select {}
+}
+
+// renderFunc renders its single JSON string argument. It returns an object
+// with either an `html` or an `error` property.
+func renderFunc(this js.Value, args []js.Value) interface{} {
+	if len(args) != 1 || args[0].Type() != js.TypeString {
+		return result("", "render expects one JSON string argument")
+	}
+
+	entries, err := parseEntries([]byte(args[0].String()))
+	if err != nil {
+		return result("", err.Error())
+	}
+	return result(render.Transcript(entries), "")
+}
+
+// parseEntries accepts either a conversation response (an object with a
+// "transcript" field) or a bare array of transcript entries.
+func parseEntries(data []byte) ([]agent.TranscriptEntry, error) {
+	var entries []agent.TranscriptEntry
+	if err := json.Unmarshal(data, &entries); err == nil {
+		return entries, nil
+	}
+
+	var conv struct {
+		Transcript []agent.TranscriptEntry `json:"transcript"`
+	}
+	if err := json.Unmarshal(data, &conv); err != nil {
+		return nil, err
+	}
+	return conv.Transcript, nil
+}
+
+func result(html, errMsg string) interface{} {
+	if errMsg != "" {
+		return map[string]interface{}{"error": errMsg}
+	}
+	return map[string]interface{}{"html": html}
+}
@@ -0,0 +1,164 @@
+// Package report renders reports of stored conversations as Markdown, such
+// as the release report of 'shiftlog release-report' and of the
+// /api/releases/report endpoint of 'shiftlog serve', and the prompt library
+// of 'shiftlog prompts'.
+package report
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/re-cinq/shift-log/internal/storage"
+)
+
+// Release writes a release report as Markdown: totals, a breakdown by
+// agent and a table of the conversations. commitURL, when set, is the URL
+// commits are linked to, followed by their SHA.
+func Release(w io.Writer, r *storage.ReleaseReport, commitURL string) {
+	title := r.To
+	if r.From != "" {
+		title = r.From + " → " + r.To
+	}
+	fmt.Fprintf(w, "# Release report: %s\n\n", title)
+	if r.Commits == 0 {
+		fmt.Fprintln(w, "No commits in this release.")
+		return
+	}
+
+	s := r.Stats
+	fmt.Fprintf(w, "- **Commits:** %d, %d with a conversation (%.0f%%)\n",
+		r.Commits, r.WithConversation, float64(r.WithConversation)*100/float64(r.Commits))
+	fmt.Fprintf(w, "- **Conversations:** %d, %d AI-assisted\n", s.Conversations, s.AIAssisted)
+	if s.Turns > 0 || s.Tokens > 0 {
+		fmt.Fprintf(w, "- **Effort:** %d turns, %d tokens", s.Turns, s.Tokens)
+		if s.DurationSeconds > 0 {
+			fmt.Fprintf(w, ", %s", time.Duration(s.DurationSeconds)*time.Second)
+		}
+		fmt.Fprintln(w)
+	}
+	if r.Cost > 0 {
+		fmt.Fprintf(w, "- **Estimated cost:** %s\n", FormatCost(r.Cost))
+	}
+	if s.Measured > 0 {
+		fmt.Fprintf(w, "- **AI authorship:** %d of %d added lines (%.0f%%)\n", s.AILines, s.TotalLines, s.AIRatio*100)
+	}
+	fmt.Fprintln(w)
+
+	if len(s.Agents) > 1 {
+		fmt.Fprintln(w, "## By agent")
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "| Agent | Conversations | AI lines | Tokens |")
+		fmt.Fprintln(w, "| --- | ---: | ---: | ---: |")
+		for _, name := range s.AgentNames() {
+			a := s.Agents[name]
+			fmt.Fprintf(w, "| %s | %d | %d/%d | %d |\n", MarkdownCell(name), a.Conversations, a.AILines, a.TotalLines, a.Tokens)
+		}
+		fmt.Fprintln(w)
+	}
+
+	if len(r.Conversations) == 0 {
+		return
+	}
+	fmt.Fprintln(w, "## Conversations")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "| Commit | Subject | Agent | Tokens | Cost | Summary | Tags |")
+	fmt.Fprintln(w, "| --- | --- | --- | ---: | ---: | --- | --- |")
+	for _, c := range r.Conversations {
+		sha := "`" + c.CommitSHA[:7] + "`"
+		if commitURL != "" {
+			sha = "[" + sha + "](" + commitURL + c.CommitSHA + ")"
+		}
+		agent := c.Agent
+		if c.Model != "" {
+			agent += " (" + c.Model + ")"
+		}
+		tokens, cost := "", ""
+		if t := c.Effort.TotalTokens(); t > 0 {
+			tokens = fmt.Sprintf("%d", t)
+		}
+		if c.Cost != nil {
+			cost = FormatCost(*c.Cost)
+		}
+		row := []string{sha, MarkdownCell(c.CommitMsg), MarkdownCell(agent), tokens, cost,
+			MarkdownCell(Truncate(c.Summary, 200)), MarkdownCell(strings.Join(c.Tags, ", "))}
+		fmt.Fprintln(w, "| "+strings.Join(row, " | ")+" |")
+	}
+	fmt.Fprintln(w)
+	if r.Cost > 0 {
+		fmt.Fprintln(w, "Costs are estimates at list prices, from the tokens recorded in the conversations.")
+		fmt.Fprintln(w)
+	}
+}
+
+// Prompts writes a prompt library as Markdown: each prompt as a quote,
+// followed by the commits of the sessions it was sent in. commitURL, when
+// set, is the URL commits are linked to, followed by their SHA.
+func Prompts(w io.Writer, library []*storage.Prompt, commitURL string) {
+	fmt.Fprintln(w, "# Prompt library")
+	fmt.Fprintln(w)
+	if len(library) == 0 {
+		fmt.Fprintln(w, "No prompts found.")
+		return
+	}
+	sessions := 0
+	for _, p := range library {
+		sessions += len(p.Uses)
+	}
+	fmt.Fprintf(w, "%s from %s, most used first.\n\n", plural(len(library), "prompt"), plural(sessions, "session"))
+
+	for i, p := range library {
+		title, _, _ := strings.Cut(p.Text, "\n")
+		fmt.Fprintf(w, "## %d. %s\n\n", i+1, Truncate(strings.Join(strings.Fields(title), " "), 80))
+		for _, line := range strings.Split(p.Text, "\n") {
+			fmt.Fprintln(w, strings.TrimRight("> "+line, " "))
+		}
+		fmt.Fprintln(w)
+		fmt.Fprintf(w, "Used in %s:\n\n", plural(len(p.Uses), "session"))
+		for _, u := range p.Uses {
+			sha := "`" + u.CommitSHA[:7] + "`"
+			if commitURL != "" {
+				sha = "[" + sha + "](" + commitURL + u.CommitSHA + ")"
+			}
+			agent := u.Agent
+			if u.Model != "" {
+				agent += " (" + u.Model + ")"
+			}
+			date, _, _ := strings.Cut(u.CommitDate, " ")
+			fmt.Fprintf(w, "- %s %s, %s, %s\n", sha, strings.Join(strings.Fields(u.CommitMsg), " "), agent, date)
+		}
+		fmt.Fprintln(w)
+	}
+}
+
+// plural formats a count of things, such as "1 session" or "2 sessions".
+func plural(n int, thing string) string {
+	if n == 1 {
+		return fmt.Sprintf("%d %s", n, thing)
+	}
+	return fmt.Sprintf("%d %ss", n, thing)
+}
+
+// MarkdownCell makes s fit in a cell of a Markdown table.
+func MarkdownCell(s string) string {
+	s = strings.Join(strings.Fields(s), " ")
+	return strings.ReplaceAll(s, "|", `\|`)
+}
+
+// Truncate shortens s to at most n runes, marking the cut with "…".
+func Truncate(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n-1]) + "…"
+}
+
+// FormatCost formats an amount of US dollars.
+func FormatCost(dollars float64) string {
+	if dollars < 0.01 {
+		return "<$0.01"
+	}
+	return fmt.Sprintf("$%.2f", dollars)
+}
@@ -0,0 +1,82 @@
+package report
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/re-cinq/shift-log/internal/storage"
+)
+
+func TestRelease(t *testing.T) {
+	cost := 0.42
+	conversations := []storage.ReleaseConversation{
+		{
+			AuthorshipRecord: storage.AuthorshipRecord{
+				CommitSHA: "0123456789abcdef", CommitMsg: "Add | pipes", Agent: "claude", Model: "claude-sonnet-4",
+				Effort: &storage.Effort{Turns: 3, InputTokens: 1000, OutputTokens: 200},
+			},
+			Summary: "Added the parser", Tags: []string{"parser", "review"}, Cost: &cost,
+		},
+		{AuthorshipRecord: storage.AuthorshipRecord{CommitSHA: "fedcba9876543210", CommitMsg: "Fix typo", Agent: "gemini"}},
+	}
+	var records []storage.AuthorshipRecord
+	for _, c := range conversations {
+		records = append(records, c.AuthorshipRecord)
+	}
+	r := &storage.ReleaseReport{
+		From: "v1.0.0", To: "v1.1.0", Commits: 4, WithConversation: 2,
+		Stats: storage.Summarize(records, 4), Cost: cost, Conversations: conversations,
+	}
+
+	var buf bytes.Buffer
+	Release(&buf, r, "https://example.com/commit/")
+	got := buf.String()
+	for _, want := range []string{
+		"# Release report: v1.0.0 → v1.1.0",
+		"- **Commits:** 4, 2 with a conversation (50%)",
+		"- **Effort:** 3 turns, 1200 tokens",
+		"- **Estimated cost:** $0.42",
+		"## By agent",
+		"| [`0123456`](https://example.com/commit/0123456789abcdef) | Add \\| pipes | claude (claude-sonnet-4) | 1200 | $0.42 | Added the parser | parser, review |",
+		"| [`fedcba9`](https://example.com/commit/fedcba9876543210) | Fix typo | gemini |  |  |  |  |",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("report missing %q:\n%s", want, got)
+		}
+	}
+}
+
+func TestReleaseWithoutCommits(t *testing.T) {
+	var buf bytes.Buffer
+	Release(&buf, &storage.ReleaseReport{To: "v0.1.0", Stats: storage.Summarize(nil, 0)}, "")
+	if got := buf.String(); got != "# Release report: v0.1.0\n\nNo commits in this release.\n" {
+		t.Errorf("report = %q", got)
+	}
+}
+
+func TestPrompts(t *testing.T) {
+	library := []*storage.Prompt{
+		{Text: "Add a parser\nfor the config file", Uses: []storage.PromptUse{
+			{CommitSHA: "0123456789abcdef", CommitDate: "2025-03-04 10:00:00 +0100", CommitMsg: "Add parser", Agent: "claude", Model: "claude-sonnet-4"},
+			{CommitSHA: "fedcba9876543210", CommitDate: "2025-03-01 09:00:00 +0100", CommitMsg: "Start config", Agent: "codex"},
+		}},
+		{Text: "Fix the typo", Uses: []storage.PromptUse{{CommitSHA: "aaaaaaa000000000", CommitDate: "2025-02-01 09:00:00 +0000", CommitMsg: "Fix typo", Agent: "gemini"}}},
+	}
+
+	var buf bytes.Buffer
+	Prompts(&buf, library, "https://example.com/commit/")
+	got := buf.String()
+	for _, want := range []string{
+		"2 prompts from 3 sessions, most used first.",
+		"## 1. Add a parser\n\n> Add a parser\n> for the config file\n\nUsed in 2 sessions:",
+		"- [`0123456`](https://example.com/commit/0123456789abcdef) Add parser, claude (claude-sonnet-4), 2025-03-04",
+		"## 2. Fix the typo",
+		"Used in 1 session:",
+		"- [`aaaaaaa`](https://example.com/commit/aaaaaaa000000000) Fix typo, gemini, 2025-02-01",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("library missing %q:\n%s", want, got)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package storage
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/re-cinq/shift-log/internal/git"
+)
+
+// Annotation is a reviewer comment on a transcript entry of a stored
+// conversation.
+//
+// The annotations of a commit are stored in git.AnnotationsRef as one JSON
+// object per line, so that merging two clones with cat_sort_uniq yields the
+// union of their annotations.
+type Annotation struct {
+	CreatedAt string `json:"created_at"` // annotationTimeFormat; first so sorted lines are chronological
+	ID        string `json:"id"`
+	EntryUUID string `json:"entry_uuid"`
+	Author    string `json:"author,omitempty"`
+	Body      string `json:"body"`
+}
+
+// GetAnnotations returns the annotations recorded for a commit, oldest
+// first. Returns nil if the commit has none.
+func GetAnnotations(commitSHA string) ([]Annotation, error) {
+	data, err := git.GetNoteFromRef(git.AnnotationsRef, commitSHA)
+	if err != nil {
+		// No note for this commit (or no annotations ref yet)
+		return nil, nil
+	}
+	return parseAnnotations(data), nil
+}
+
+// annotationTimeFormat is RFC3339 in UTC with fixed-width nanoseconds, so
+// that timestamps sort lexically in creation order.
+const annotationTimeFormat = "2006-01-02T15:04:05.000000000Z07:00"
+
+// AddAnnotation records a new annotation on entryUUID of the commit's
+// conversation and returns it.
+func AddAnnotation(commitSHA, entryUUID, author, body string) (*Annotation, error) {
+	body = strings.TrimSpace(body)
+	if body == "" {
+		return nil, fmt.Errorf("annotation body is empty")
+	}
+	if entryUUID == "" {
+		return nil, fmt.Errorf("annotation entry is required")
+	}
+
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
+	a := Annotation{
+		CreatedAt: time.Now().UTC().Format(annotationTimeFormat),
+		ID:        hex.EncodeToString(id),
+		EntryUUID: entryUUID,
+		Author:    author,
+		Body:      body,
+	}
+
+	err := git.UpdateNoteInRef(git.AnnotationsRef, commitSHA, func(existing []byte) ([]byte, error) {
+		return marshalAnnotations(append(parseAnnotations(existing), a))
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to store annotation: %w", err)
+	}
+	return &a, nil
+}
+
+// parseAnnotations decodes one annotation per line, skipping lines that are
+// not annotations, and sorts them chronologically.
+func parseAnnotations(data []byte) []Annotation {
+	var annotations []Annotation
+	for _, line := range bytes.Split(data, []byte("\n")) {
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 {
+			continue
+		}
+		var a Annotation
+		if err := json.Unmarshal(line, &a); err != nil || a.ID == "" {
+			continue
+		}
+		annotations = append(annotations, a)
+	}
+	sort.SliceStable(annotations, func(i, j int) bool {
+		if annotations[i].CreatedAt != annotations[j].CreatedAt {
+			return annotations[i].CreatedAt < annotations[j].CreatedAt
+		}
+		return annotations[i].ID < annotations[j].ID
+	})
+	return annotations
+}
+
+func marshalAnnotations(annotations []Annotation) ([]byte, error) {
+	var buf bytes.Buffer
+	for _, a := range annotations {
+		line, err := json.Marshal(a)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(line)
+		buf.WriteByte('\n')
+	}
+	return buf.Bytes(), nil
+}
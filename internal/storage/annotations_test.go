@@ -0,0 +1,33 @@
+package storage
+
+import "testing"
+
+func TestParseAnnotationsMergedLines(t *testing.T) {
+	// Two clones' annotations merged line-wise by cat_sort_uniq, plus a
+	// line that is not an annotation
+	data := []byte(`{"created_at":"2025-01-02T00:00:00Z","id":"b","entry_uuid":"u1","body":"second"}
+garbage
+{"created_at":"2025-01-01T00:00:00Z","id":"a","entry_uuid":"u2","body":"first"}
+`)
+
+	got := parseAnnotations(data)
+	if len(got) != 2 {
+		t.Fatalf("got %d annotations, want 2", len(got))
+	}
+	if got[0].ID != "a" || got[1].ID != "b" {
+		t.Errorf("order = %s, %s; want a, b", got[0].ID, got[1].ID)
+	}
+}
+
+func TestMarshalAnnotationsRoundTrip(t *testing.T) {
+	in := []Annotation{{CreatedAt: "2025-01-01T00:00:00Z", ID: "a", EntryUUID: "u1", Body: "multi\nline"}}
+
+	data, err := marshalAnnotations(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := parseAnnotations(data)
+	if len(out) != 1 || out[0] != in[0] {
+		t.Errorf("round trip = %+v, want %+v", out, in)
+	}
+}
@@ -0,0 +1,53 @@
+package storage
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+
+	"github.com/re-cinq/shift-log/internal/agent"
+)
+
+// OmitLargeAttachments replaces the data of the transcript's attachments
+// larger than maxBytes with a placeholder recording their media type and
+// size, to keep notes small. It returns the transcript and the number of
+// attachments omitted. Transcripts are JSON documents or JSONL; lines
+// without a large attachment are kept byte for byte.
+func OmitLargeAttachments(transcriptData []byte, maxBytes int64) ([]byte, int) {
+	if maxBytes <= 0 || !bytes.Contains(transcriptData, []byte(`"source"`)) {
+		return transcriptData, 0
+	}
+
+	omitted := 0
+	omit := func(block map[string]any) bool {
+		source, _ := block["source"].(map[string]any)
+		if source["type"] != agent.SourceBase64 {
+			return false
+		}
+		encoded, _ := source["data"].(string)
+		padding := len(encoded) - len(strings.TrimRight(encoded, "="))
+		size := int64(base64.StdEncoding.DecodedLen(len(encoded)) - padding)
+		if size <= maxBytes {
+			return false
+		}
+		block["source"] = map[string]any{
+			"type":       agent.SourceOmitted,
+			"media_type": source["media_type"],
+			"size":       size,
+		}
+		omitted++
+		return true
+	}
+
+	if json.Valid(transcriptData) {
+		return agent.RewriteAttachments(transcriptData, omit), omitted
+	}
+	lines := bytes.Split(transcriptData, []byte("\n"))
+	for i, line := range lines {
+		if bytes.Contains(line, []byte(`"source"`)) {
+			lines[i] = agent.RewriteAttachments(line, omit)
+		}
+	}
+	return bytes.Join(lines, []byte("\n")), omitted
+}
@@ -0,0 +1,46 @@
+package storage
+
+import (
+	"bytes"
+	"encoding/base64"
+	"strings"
+	"testing"
+)
+
+func TestOmitLargeAttachments(t *testing.T) {
+	small := base64.StdEncoding.EncodeToString([]byte("tiny"))
+	large := base64.StdEncoding.EncodeToString(bytes.Repeat([]byte("x"), 2048))
+	text := `{"uuid":"u1","type":"user","message":{"role":"user","content":"Look at these"}}`
+	smallLine := `{"uuid":"u2","type":"user","message":{"content":[{"type":"image","source":{"type":"base64","media_type":"image/png","data":"` + small + `"}}]}}`
+	largeLine := `{"uuid":"u3","type":"user","message":{"content":[{"type":"image","source":{"type":"base64","media_type":"image/png","data":"` + large + `"}}]}}`
+	transcript := []byte(strings.Join([]string{text, smallLine, largeLine, ""}, "\n"))
+
+	t.Run("omits attachments over the cap", func(t *testing.T) {
+		got, omitted := OmitLargeAttachments(transcript, 1024)
+		if omitted != 1 {
+			t.Errorf("omitted = %d, want 1", omitted)
+		}
+		lines := strings.Split(string(got), "\n")
+		if len(lines) != 4 || lines[0] != text || lines[1] != smallLine || lines[3] != "" {
+			t.Errorf("other lines changed:\n%s", got)
+		}
+		want := `{"message":{"content":[{"source":{"media_type":"image/png","size":2048,"type":"omitted"},"type":"image"}]},"type":"user","uuid":"u3"}`
+		if lines[2] != want {
+			t.Errorf("large attachment line = %s, want %s", lines[2], want)
+		}
+	})
+
+	t.Run("keeps everything without a cap", func(t *testing.T) {
+		if got, omitted := OmitLargeAttachments(transcript, 0); omitted != 0 || !bytes.Equal(got, transcript) {
+			t.Errorf("transcript changed without a cap: %d omitted", omitted)
+		}
+	})
+
+	t.Run("handles JSON documents", func(t *testing.T) {
+		doc := []byte("[\n" + smallLine + ",\n" + largeLine + "\n]")
+		got, omitted := OmitLargeAttachments(doc, 1024)
+		if omitted != 1 || strings.Contains(string(got), large) || !strings.Contains(string(got), small) {
+			t.Errorf("OmitLargeAttachments(doc) = %s, %d omitted", got, omitted)
+		}
+	})
+}
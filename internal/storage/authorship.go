@@ -0,0 +1,84 @@
+package storage
+
+import (
+	"strings"
+
+	"github.com/re-cinq/shift-log/internal/agent"
+	"github.com/re-cinq/shift-log/internal/git"
+)
+
+// Authorship records how much of a commit's diff was written by the agent.
+// Only non-blank added lines are counted.
+type Authorship struct {
+	AILines    int     `json:"ai_lines"`
+	TotalLines int     `json:"total_lines"`
+	Ratio      float64 `json:"ratio"` // AILines / TotalLines, 0 when the commit adds no lines
+}
+
+// IsAIAssisted reports whether any of the commit's lines came from the agent, nil-safe.
+func (a *Authorship) IsAIAssisted() bool {
+	return a != nil && a.AILines > 0
+}
+
+// ComputeAuthorship attributes the lines added by commitSHA to the agent when
+// they match text written by edit tool calls in entries for the same file.
+// Remaining lines are counted as manual edits.
+func ComputeAuthorship(commitSHA string, entries []agent.TranscriptEntry, aliases map[string]string, repoRoot string) (*Authorship, error) {
+	added, err := git.AddedLines(commitSHA)
+	if err != nil {
+		return nil, err
+	}
+	return attributeLines(added, agentLines(entries, aliases, repoRoot)), nil
+}
+
+// agentLines returns, per repo-relative file, a multiset of the non-blank
+// trimmed lines written by edit tool calls.
+func agentLines(entries []agent.TranscriptEntry, aliases map[string]string, repoRoot string) map[string]map[string]int {
+	written := make(map[string]map[string]int)
+	for _, entry := range entries {
+		if entry.Message == nil {
+			continue
+		}
+		for _, block := range entry.Message.Content {
+			for _, edit := range agent.FileEdits(block, aliases) {
+				file := RepoRelativePath(edit.Path, repoRoot)
+				if file == "" {
+					continue
+				}
+				if written[file] == nil {
+					written[file] = make(map[string]int)
+				}
+				for _, line := range edit.Lines {
+					if line = strings.TrimSpace(line); line != "" {
+						written[file][line]++
+					}
+				}
+			}
+		}
+	}
+	return written
+}
+
+// attributeLines matches added lines against agent-written lines. Each
+// agent-written line can account for at most one added line.
+func attributeLines(added map[string][]string, written map[string]map[string]int) *Authorship {
+	a := &Authorship{}
+	for file, lines := range added {
+		available := written[file]
+		for _, line := range lines {
+			line = strings.TrimSpace(line)
+			if line == "" {
+				continue
+			}
+			a.TotalLines++
+			if available[line] > 0 {
+				available[line]--
+				a.AILines++
+			}
+		}
+	}
+	if a.TotalLines > 0 {
+		a.Ratio = float64(a.AILines) / float64(a.TotalLines)
+	}
+	return a
+}
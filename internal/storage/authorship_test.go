@@ -0,0 +1,101 @@
+package storage
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/re-cinq/shift-log/internal/agent"
+)
+
+func TestAttributeAuthorship(t *testing.T) {
+	entries := []agent.TranscriptEntry{
+		{Type: agent.MessageTypeAssistant, Message: &agent.Message{Content: []agent.ContentBlock{
+			{Type: "tool_use", Name: "Write", Input: json.RawMessage(`{"file_path":"/repo/a.go","content":"package a\n\nfunc A() {}\n"}`)},
+			{Type: "tool_use", Name: "Edit", Input: json.RawMessage(`{"file_path":"/repo/b.go","old_string":"x","new_string":"  return 1"}`)},
+		}}},
+	}
+	added := map[string][]string{
+		"a.go": {"package a", "", "func A() {}", "// manual comment"},
+		"b.go": {"\treturn 1", "return 1"},
+		"c.go": {"package c"},
+	}
+
+	got := attributeLines(added, agentLines(entries, nil, "/repo"))
+
+	// a.go: 2 of 3 non-blank lines; b.go: one agent line matches once; c.go: manual
+	if got.AILines != 3 || got.TotalLines != 6 {
+		t.Errorf("authorship = %d/%d, want 3/6", got.AILines, got.TotalLines)
+	}
+	if got.Ratio != 0.5 {
+		t.Errorf("Ratio = %v, want 0.5", got.Ratio)
+	}
+	if !got.IsAIAssisted() {
+		t.Error("IsAIAssisted() = false, want true")
+	}
+}
+
+func TestAttributeAuthorshipNoLines(t *testing.T) {
+	got := attributeLines(map[string][]string{}, nil)
+	if got.TotalLines != 0 || got.Ratio != 0 || got.IsAIAssisted() {
+		t.Errorf("empty diff authorship = %+v, want zero", got)
+	}
+
+	var nilAuthorship *Authorship
+	if nilAuthorship.IsAIAssisted() {
+		t.Error("nil Authorship should not be AI-assisted")
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	records := []AuthorshipRecord{
+		{Agent: "claude", AIAssisted: true, Authorship: &Authorship{AILines: 8, TotalLines: 10}, Effort: &Effort{Turns: 2, InputTokens: 10, OutputTokens: 5, DurationSeconds: 90, ToolSeconds: 30}},
+		{Agent: "claude", Authorship: &Authorship{TotalLines: 10}, Effort: &Effort{DurationSeconds: 10}},
+		{Agent: "gemini"}, // stored before authorship was recorded
+	}
+
+	s := Summarize(records, 5)
+	if s.Commits != 5 || s.Conversations != 3 || s.AIAssisted != 1 || s.Measured != 2 {
+		t.Errorf("commits/conversations/ai_assisted/measured = %d/%d/%d/%d, want 5/3/1/2",
+			s.Commits, s.Conversations, s.AIAssisted, s.Measured)
+	}
+	if s.AILines != 8 || s.TotalLines != 20 || s.AIRatio != 0.4 {
+		t.Errorf("lines = %d/%d ratio %v, want 8/20 ratio 0.4", s.AILines, s.TotalLines, s.AIRatio)
+	}
+	if s.Turns != 2 || s.Tokens != 15 {
+		t.Errorf("turns/tokens = %d/%d, want 2/15", s.Turns, s.Tokens)
+	}
+	if s.DurationSeconds != 100 || s.ToolSeconds != 30 || s.Agents["claude"].DurationSeconds != 100 {
+		t.Errorf("duration/tool seconds = %d/%d, want 100/30", s.DurationSeconds, s.ToolSeconds)
+	}
+	if got := s.AgentNames(); len(got) != 2 || got[0] != "claude" || got[1] != "gemini" {
+		t.Errorf("AgentNames() = %v, want [claude gemini]", got)
+	}
+	if s.Agents["claude"].Conversations != 2 || s.Agents["gemini"].TotalLines != 0 {
+		t.Errorf("per-agent stats wrong: claude=%+v gemini=%+v", s.Agents["claude"], s.Agents["gemini"])
+	}
+}
+
+func TestSummarizeByModel(t *testing.T) {
+	records := []AuthorshipRecord{
+		{Agent: "claude", Model: "sonnet", Authorship: &Authorship{AILines: 4, TotalLines: 4}, Provenance: &Provenance{
+			Agent:  "claude",
+			Models: []ModelUsage{{Model: "sonnet", Messages: 3}, {Model: "haiku", Messages: 1}},
+		}},
+		{Agent: "codex", Model: "gpt-5-codex"}, // stored before provenance was recorded
+		{Agent: "goose"},                       // records no model
+	}
+
+	s := Summarize(records, 3)
+	if got := s.ModelNames(); len(got) != 3 || got[0] != "gpt-5-codex" || got[1] != "haiku" || got[2] != "sonnet" {
+		t.Fatalf("ModelNames() = %v, want [gpt-5-codex haiku sonnet]", got)
+	}
+	if m := s.Models["sonnet"]; m.Conversations != 1 || m.AILines != 4 || m.Messages != 3 {
+		t.Errorf("sonnet = %+v, want the conversation and 3 messages", m)
+	}
+	if m := s.Models["haiku"]; m.Conversations != 0 || m.Messages != 1 {
+		t.Errorf("haiku = %+v, want only 1 message", m)
+	}
+	if m := s.Models["gpt-5-codex"]; m.Conversations != 1 {
+		t.Errorf("gpt-5-codex = %+v, want the conversation", m)
+	}
+}
@@ -0,0 +1,346 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/re-cinq/shift-log/internal/config"
+	"github.com/re-cinq/shift-log/internal/git"
+)
+
+// Backend persists stored conversations, keyed by commit SHA. Content is
+// the marshalled StoredConversation, or an array of them (see
+// MarshalStoredConversations); backends do not interpret it.
+type Backend interface {
+	// Name is the identifier used for the backend in .shiftlog/config.
+	Name() string
+	// Location describes where conversations are kept, for diagnostics.
+	Location() string
+	// Read returns the conversation stored for a commit, or nil, nil if none.
+	Read(commitSHA string) ([]byte, error)
+	// Write stores content for a commit, replacing any existing conversation.
+	Write(commitSHA string, content []byte) error
+	// List returns every commit with a stored conversation, reachable or not.
+	List() (map[string]bool, error)
+}
+
+// BulkReader is implemented by backends that can read every stored
+// conversation at once faster than with a Read per commit.
+type BulkReader interface {
+	// ReadAll returns the content stored for every commit, keyed by SHA.
+	ReadAll() (map[string][]byte, error)
+}
+
+// Updater is implemented by backends that can change the content stored
+// for a commit without another writer changing it in between.
+type Updater interface {
+	// Update replaces the content stored for a commit with what update
+	// returns for the current content, nil if none. Nothing is written when
+	// update returns nil or the current content.
+	Update(commitSHA string, update func(existing []byte) ([]byte, error)) error
+}
+
+// BackendFactory creates a backend from the repository config.
+type BackendFactory func(cfg *config.Config) (Backend, error)
+
+// DefaultBackend is the backend used when none is configured.
+const DefaultBackend = "git-notes"
+
+var (
+	backendMu     sync.Mutex
+	backends      = make(map[string]BackendFactory)
+	activeBackend Backend
+)
+
+func init() {
+	RegisterBackend(DefaultBackend, func(*config.Config) (Backend, error) {
+		return GitNotesBackend{}, nil
+	})
+}
+
+// RegisterBackend makes a backend available under name.
+// This is typically called from an init() function.
+func RegisterBackend(name string, factory BackendFactory) {
+	backendMu.Lock()
+	defer backendMu.Unlock()
+	backends[name] = factory
+}
+
+// BackendNames returns a comma-separated list of registered backend names.
+func BackendNames() string {
+	backendMu.Lock()
+	defer backendMu.Unlock()
+	names := make([]string, 0, len(backends))
+	for name := range backends {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+// NewBackend creates the backend selected by cfg.
+func NewBackend(cfg *config.Config) (Backend, error) {
+	name := cfg.Backend
+	if name == "" {
+		name = DefaultBackend
+	}
+	backendMu.Lock()
+	factory, ok := backends[name]
+	backendMu.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown storage backend %q (supported: %s)", name, BackendNames())
+	}
+	return factory(cfg)
+}
+
+// ActiveBackend returns the backend configured for the current repository.
+// It is created on first use and reused for the rest of the process.
+func ActiveBackend() (Backend, error) {
+	backendMu.Lock()
+	b := activeBackend
+	backendMu.Unlock()
+	if b != nil {
+		return b, nil
+	}
+
+	cfg, err := config.Read()
+	if err != nil {
+		return nil, fmt.Errorf("could not read config: %w", err)
+	}
+	b, err = NewBackend(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	backendMu.Lock()
+	defer backendMu.Unlock()
+	if activeBackend == nil {
+		activeBackend = b
+	}
+	return activeBackend, nil
+}
+
+// GitNotesBackend stores each conversation as a git note on its commit,
+// under git.NotesRef. It is the default backend, and the one sync, remap
+// and backup operate on.
+type GitNotesBackend struct{}
+
+// Name implements Backend.
+func (GitNotesBackend) Name() string { return DefaultBackend }
+
+// Location implements Backend.
+func (GitNotesBackend) Location() string { return git.NotesRef }
+
+// Read implements Backend.
+func (GitNotesBackend) Read(commitSHA string) ([]byte, error) {
+	if !git.HasNote(commitSHA) {
+		return nil, nil
+	}
+	return git.GetNote(commitSHA)
+}
+
+// Write implements Backend. With readable_notes set, it also describes
+// the conversations in git.ReadableRef.
+func (GitNotesBackend) Write(commitSHA string, content []byte) error {
+	if err := git.AddNote(commitSHA, content); err != nil {
+		return err
+	}
+	updateReadableNote(commitSHA, content)
+	return nil
+}
+
+// Update implements Updater.
+func (GitNotesBackend) Update(commitSHA string, update func(existing []byte) ([]byte, error)) error {
+	var written []byte
+	err := git.UpdateNoteInRef(git.NotesRef, commitSHA, func(existing []byte) ([]byte, error) {
+		content, err := update(existing)
+		written = content
+		return content, err
+	})
+	if err != nil || written == nil {
+		return err
+	}
+	updateReadableNote(commitSHA, written)
+	return nil
+}
+
+// List implements Backend.
+func (GitNotesBackend) List() (map[string]bool, error) {
+	return git.ListAllCommitsWithNotes("")
+}
+
+// ReadAll implements BulkReader.
+func (GitNotesBackend) ReadAll() (map[string][]byte, error) {
+	return git.ReadNotes(git.NotesRef)
+}
+
+// SaveStoredConversation stores sc for a commit in the active backend. It
+// replaces the stored conversation of the same agent session and keeps
+// those of other sessions, adding sc after them if it is new.
+func SaveStoredConversation(commitSHA string, sc *StoredConversation) error {
+	return updateStoredConversations(commitSHA, true, func(conversations []*StoredConversation) []*StoredConversation {
+		if i := IndexOfSession(conversations, sc); i >= 0 {
+			conversations[i] = sc
+			return conversations
+		}
+		return append(conversations, sc)
+	})
+}
+
+// SetStoredSummary sets the summary of the conversation of sc's agent
+// session stored for a commit, in the active backend or, when private, in
+// its private note. It leaves the note as it is when the session is not
+// stored there.
+func SetStoredSummary(commitSHA string, sc *StoredConversation, summary string, private bool) error {
+	setSummary := func(conversations []*StoredConversation) []*StoredConversation {
+		i := IndexOfSession(conversations, sc)
+		if i < 0 {
+			return nil
+		}
+		conversations[i].Summary = summary
+		return conversations
+	}
+	if private {
+		return git.UpdateNoteInRef(git.PrivateRef, commitSHA, conversationsUpdate(true, setSummary))
+	}
+	return updateStoredConversations(commitSHA, true, setSummary)
+}
+
+// AddStoredConversation adds sc to the conversations stored for a commit in
+// the active backend, unless its agent session is already among them, and
+// reports whether it did. With a backend that is an Updater, a concurrent
+// store of another session on the same commit cannot overwrite it. A note
+// that cannot be parsed is overwritten, unless strict makes it an error.
+func AddStoredConversation(commitSHA string, sc *StoredConversation, strict bool) (bool, error) {
+	added := false
+	err := updateStoredConversations(commitSHA, strict, addConversation(sc, &added))
+	return added && err == nil, err
+}
+
+// addConversation returns the update of a commit's conversations that adds
+// sc unless its agent session is among them, setting added when it does.
+func addConversation(sc *StoredConversation, added *bool) func([]*StoredConversation) []*StoredConversation {
+	return func(conversations []*StoredConversation) []*StoredConversation {
+		if IndexOfSession(conversations, sc) >= 0 {
+			return nil
+		}
+		*added = true
+		return append(conversations, sc)
+	}
+}
+
+// updateStoredConversations replaces the conversations stored for a commit
+// with what update returns for them, leaving them as they are when it
+// returns nil. With strict, a note that cannot be parsed is an error
+// instead of holding no conversations.
+func updateStoredConversations(commitSHA string, strict bool, update func([]*StoredConversation) []*StoredConversation) error {
+	b, err := ActiveBackend()
+	if err != nil {
+		return err
+	}
+	apply := conversationsUpdate(strict, update)
+	if updater, ok := b.(Updater); ok {
+		return updater.Update(commitSHA, apply)
+	}
+	existing, err := b.Read(commitSHA)
+	if err != nil {
+		return fmt.Errorf("could not read conversation: %w", err)
+	}
+	content, err := apply(existing)
+	if err != nil || content == nil {
+		return err
+	}
+	return b.Write(commitSHA, content)
+}
+
+// conversationsUpdate returns the update of a note's content that applies
+// update to the conversations it holds.
+func conversationsUpdate(strict bool, update func([]*StoredConversation) []*StoredConversation) func(existing []byte) ([]byte, error) {
+	return func(existing []byte) ([]byte, error) {
+		var conversations []*StoredConversation
+		if existing != nil {
+			parsed, err := UnmarshalStoredConversations(existing)
+			if err != nil && strict {
+				return nil, fmt.Errorf("could not parse conversation: %w", err)
+			}
+			conversations = parsed
+		}
+		conversations = update(conversations)
+		if conversations == nil {
+			return nil, nil
+		}
+		content, err := MarshalStoredConversations(conversations)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal conversation: %w", err)
+		}
+		return content, nil
+	}
+}
+
+// ListConversationCommits returns the commits reachable from HEAD that have
+// a stored conversation, in reverse chronological order (matching git log).
+func ListConversationCommits() ([]string, error) {
+	stored, err := ListAllConversationCommits()
+	if err != nil {
+		return nil, err
+	}
+	return git.FilterReachableFromHead(stored)
+}
+
+// ListAllConversationCommits returns the set of commits with a stored
+// conversation, regardless of branch reachability.
+func ListAllConversationCommits() (map[string]bool, error) {
+	b, err := ActiveBackend()
+	if err != nil {
+		return nil, err
+	}
+	return b.List()
+}
+
+// ReadAllConversations returns the content stored for every commit in the
+// active backend, keyed by commit SHA, in one pass when the backend is a
+// BulkReader.
+func ReadAllConversations() (map[string][]byte, error) {
+	b, err := ActiveBackend()
+	if err != nil {
+		return nil, err
+	}
+	if bulk, ok := b.(BulkReader); ok {
+		return bulk.ReadAll()
+	}
+	commits, err := b.List()
+	if err != nil {
+		return nil, err
+	}
+	contents := make(map[string][]byte, len(commits))
+	for commit := range commits {
+		content, err := b.Read(commit)
+		if err != nil {
+			return nil, err
+		}
+		if content != nil {
+			contents[commit] = content
+		}
+	}
+	return contents, nil
+}
+
+// MessageCounts returns the number of messages stored for every commit
+// with a conversation, summed over its agent sessions. It reads them from
+// the index rather than from every note.
+func MessageCounts() (map[string]int, error) {
+	ix, err := OpenIndex(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	counts := make(map[string]int, len(ix.Shared.Commits))
+	for commit, indexed := range ix.Shared.Commits {
+		for _, ic := range indexed {
+			counts[commit] += ic.Conversation.MessageCount
+		}
+	}
+	return counts, nil
+}
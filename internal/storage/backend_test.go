@@ -0,0 +1,198 @@
+package storage
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/re-cinq/shift-log/internal/config"
+)
+
+func TestNewBackend(t *testing.T) {
+	b, err := NewBackend(&config.Config{})
+	if err != nil {
+		t.Fatalf("NewBackend(default) error: %v", err)
+	}
+	if b.Name() != DefaultBackend {
+		t.Errorf("default backend = %q, want %q", b.Name(), DefaultBackend)
+	}
+
+	if _, err := NewBackend(&config.Config{Backend: "floppy"}); err == nil || !strings.Contains(err.Error(), DefaultBackend) {
+		t.Errorf("NewBackend(unknown) error = %v, want error listing %q", err, DefaultBackend)
+	}
+}
+
+type memoryBackend struct{ notes map[string][]byte }
+
+func (m *memoryBackend) Name() string     { return "memory" }
+func (m *memoryBackend) Location() string { return "process memory" }
+func (m *memoryBackend) Read(sha string) ([]byte, error) {
+	return m.notes[sha], nil
+}
+func (m *memoryBackend) Write(sha string, content []byte) error {
+	m.notes[sha] = content
+	return nil
+}
+func (m *memoryBackend) List() (map[string]bool, error) {
+	set := make(map[string]bool, len(m.notes))
+	for sha := range m.notes {
+		set[sha] = true
+	}
+	return set, nil
+}
+
+func TestRegisterBackend(t *testing.T) {
+	mem := &memoryBackend{notes: map[string][]byte{}}
+	RegisterBackend("memory", func(*config.Config) (Backend, error) { return mem, nil })
+
+	b, err := NewBackend(&config.Config{Backend: "memory"})
+	if err != nil {
+		t.Fatalf("NewBackend(memory) error: %v", err)
+	}
+	if !strings.Contains(BackendNames(), "memory") {
+		t.Errorf("BackendNames() = %q, want it to include memory", BackendNames())
+	}
+
+	sc := &StoredConversation{Version: NoteFormatVersion, SessionID: "s1"}
+	content, err := sc.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Write("abc", content); err != nil {
+		t.Fatal(err)
+	}
+	data, err := b.Read("abc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := UnmarshalStoredConversation(data)
+	if err != nil || got.SessionID != "s1" {
+		t.Errorf("round trip = %+v, %v", got, err)
+	}
+}
+
+func TestMessageCounts(t *testing.T) {
+	// The counts are read from the index, saved in a scratch repository
+	chdirScratchRepo(t)
+	mem := &memoryBackend{notes: map[string][]byte{}}
+	useBackend(t, mem)
+
+	two, err := MarshalStoredConversations([]*StoredConversation{
+		{Version: NoteFormatVersion, SessionID: "s1", MessageCount: 4},
+		{Version: NoteFormatVersion, SessionID: "s2", MessageCount: 3},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	one, err := (&StoredConversation{Version: NoteFormatVersion, SessionID: "s3", MessageCount: 9}).Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	mem.notes["abc"] = two
+	mem.notes["def"] = one
+
+	counts, err := MessageCounts()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(counts) != 2 || counts["abc"] != 7 || counts["def"] != 9 {
+		t.Errorf("MessageCounts() = %v, want abc: 7, def: 9", counts)
+	}
+}
+
+// useBackend makes b the active backend for the rest of t.
+func useBackend(t *testing.T, b Backend) {
+	backendMu.Lock()
+	previous := activeBackend
+	activeBackend = b
+	backendMu.Unlock()
+	t.Cleanup(func() {
+		backendMu.Lock()
+		activeBackend = previous
+		backendMu.Unlock()
+	})
+}
+
+func TestAddStoredConversation(t *testing.T) {
+	mem := &memoryBackend{notes: map[string][]byte{}}
+	useBackend(t, mem)
+
+	for _, tt := range []struct {
+		session string
+		added   bool
+	}{{"s1", true}, {"s1", false}, {"s2", true}} {
+		added, err := AddStoredConversation("abc", &StoredConversation{Version: NoteFormatVersion, SessionID: tt.session}, false)
+		if err != nil || added != tt.added {
+			t.Errorf("AddStoredConversation(%s) = %v, %v, want %v", tt.session, added, err, tt.added)
+		}
+	}
+	conversations, err := UnmarshalStoredConversations(mem.notes["abc"])
+	if err != nil || len(conversations) != 2 {
+		t.Errorf("stored %d conversations, %v, want s1 and s2", len(conversations), err)
+	}
+
+	mem.notes["abc"] = []byte("not a note")
+	sc := &StoredConversation{Version: NoteFormatVersion, SessionID: "s3"}
+	if added, err := AddStoredConversation("abc", sc, true); err == nil || added {
+		t.Errorf("strict AddStoredConversation on an unparseable note = %v, %v, want an error", added, err)
+	}
+	if string(mem.notes["abc"]) != "not a note" {
+		t.Errorf("strict AddStoredConversation overwrote an unparseable note")
+	}
+	if added, err := AddStoredConversation("abc", sc, false); err != nil || !added {
+		t.Errorf("AddStoredConversation on an unparseable note = %v, %v, want it overwritten", added, err)
+	}
+}
+
+func TestAddStoredConversationConcurrently(t *testing.T) {
+	chdirScratchRepo(t)
+	if out, err := exec.Command("git", "commit", "-q", "--allow-empty", "-m", "work").CombinedOutput(); err != nil {
+		t.Fatalf("git commit: %v: %s", err, out)
+	}
+	head, err := exec.Command("git", "rev-parse", "HEAD").Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	commit := strings.TrimSpace(string(head))
+	useBackend(t, GitNotesBackend{})
+
+	const writers = 6
+	var wg sync.WaitGroup
+	for i := range writers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sc := &StoredConversation{Version: NoteFormatVersion, SessionID: fmt.Sprintf("s%d", i)}
+			if _, err := AddStoredConversation(commit, sc, false); err != nil {
+				t.Error(err)
+			}
+			if _, err := AddPrivateConversation(commit, sc, false); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	conversations, err := GetStoredConversations(commit)
+	if err != nil || len(conversations) != writers {
+		t.Fatalf("stored %d conversations, %v, want %d", len(conversations), err, writers)
+	}
+	private, err := GetPrivateConversations(commit)
+	if err != nil || len(private) != writers {
+		t.Fatalf("stored %d private conversations, %v, want %d", len(private), err, writers)
+	}
+
+	notesRef := func() string {
+		out, _ := exec.Command("git", "rev-parse", "refs/notes/shiftlog").Output()
+		return string(out)
+	}
+	before := notesRef()
+	if err := SaveStoredConversation(commit, conversations[0]); err != nil {
+		t.Fatal(err)
+	}
+	if after := notesRef(); after != before {
+		t.Errorf("saving an unchanged conversation rewrote the note")
+	}
+}
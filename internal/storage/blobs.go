@@ -0,0 +1,243 @@
+package storage
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/re-cinq/shift-log/internal/git"
+)
+
+// BlobStoreTokenEnv names the environment variable holding the bearer
+// token sent to the configured HTTP blob store, if it needs one.
+const BlobStoreTokenEnv = "SHIFTLOG_BLOB_STORE_TOKEN"
+
+// blobStoreTimeout bounds each request to the HTTP blob store.
+const blobStoreTimeout = 60 * time.Second
+
+// errBlobNotFound is returned by the blob store for a missing blob.
+var errBlobNotFound = errors.New("blob not found")
+
+// CompressedTranscript returns the compressed transcript of sc, from the
+// note or, when it is stored apart, from its blob. Transcripts stored in
+// chunks are not compressed: read them with GetTranscript.
+func (sc *StoredConversation) CompressedTranscript() ([]byte, error) {
+	if sc.IsChunked() {
+		return nil, errors.New("transcript is stored uncompressed, in chunks")
+	}
+	if sc.TranscriptBlob == "" {
+		return Decode(sc.Transcript)
+	}
+	return readTranscriptBlob(sc.TranscriptBlob)
+}
+
+// SetCompressedTranscript replaces the transcript of sc with compressed
+// data, storing it apart from the note when it is over the configured
+// transcript_blob_threshold. The checksum is left to the caller.
+func (sc *StoredConversation) SetCompressedTranscript(compressed []byte) error {
+	sc.TranscriptChunks = nil
+	threshold := storeConfig().TranscriptBlobThreshold
+	if threshold <= 0 || len(compressed) <= threshold || sc.IsPrivate() {
+		sc.Transcript = Encode(compressed)
+		sc.TranscriptBlob = ""
+		return nil
+	}
+	blob, err := git.AddBlob(compressed)
+	if err != nil {
+		return fmt.Errorf("could not store transcript blob: %w", err)
+	}
+	sc.Transcript = ""
+	sc.TranscriptBlob = blob
+	return nil
+}
+
+// StoreTranscriptApart stores the transcript of sc apart from its note as
+// configured: in content-defined chunks with transcript_chunks, or else as
+// a blob when it is over transcript_blob_threshold. Private conversations
+// keep theirs in the note: the blobs are shared by sync.
+func (sc *StoredConversation) StoreTranscriptApart() error {
+	if sc.Transcript == "" || sc.IsPrivate() {
+		return nil
+	}
+	cfg := storeConfig()
+	if cfg.TranscriptChunks {
+		transcript, err := sc.GetTranscript()
+		if err != nil || len(transcript) == 0 {
+			return err
+		}
+		return sc.SetTranscriptChunks(transcript)
+	}
+	if cfg.TranscriptBlobThreshold <= 0 || base64DecodedLen(sc.Transcript) <= cfg.TranscriptBlobThreshold {
+		return nil
+	}
+	compressed, err := Decode(sc.Transcript)
+	if err != nil {
+		return err
+	}
+	return sc.SetCompressedTranscript(compressed)
+}
+
+// base64DecodedLen returns the length of the data s encodes.
+func base64DecodedLen(s string) int {
+	return len(s) / 4 * 3
+}
+
+// readTranscriptBlob returns a transcript blob from the object database,
+// or else from the configured HTTP blob store, keeping a copy of it.
+func readTranscriptBlob(sha string) ([]byte, error) {
+	if git.HasObject(sha) {
+		return git.ReadBlob(sha)
+	}
+	store := newBlobStore()
+	if store == nil {
+		return nil, fmt.Errorf("transcript blob %s is not available; run 'shiftlog sync pull' to fetch it", shortSHA(sha))
+	}
+	data, err := store.get(sha)
+	if err != nil {
+		return nil, fmt.Errorf("could not fetch transcript blob %s from %s: %w", shortSHA(sha), store.url, err)
+	}
+	if got := blobSHA(data); got != sha {
+		return nil, fmt.Errorf("transcript blob %s fetched from %s has the content of %s", shortSHA(sha), store.url, shortSHA(got))
+	}
+	// The copy saves fetching it again until git gc prunes it
+	_, _ = git.WriteBlob(data)
+	return data, nil
+}
+
+// UploadTranscriptBlobs uploads the transcript blobs of git.BlobsRef that
+// the configured HTTP blob store does not have yet, and returns how many it
+// uploaded.
+func UploadTranscriptBlobs() (int, error) {
+	store := newBlobStore()
+	if store == nil {
+		return 0, errors.New("no blob_store configured")
+	}
+	blobs, err := git.ListBlobs()
+	if err != nil {
+		return 0, fmt.Errorf("could not list transcript blobs: %w", err)
+	}
+	uploaded := 0
+	for _, sha := range blobs {
+		exists, err := store.has(sha)
+		if err != nil {
+			return uploaded, fmt.Errorf("could not check transcript blob %s in %s: %w", shortSHA(sha), store.url, err)
+		}
+		if exists {
+			continue
+		}
+		data, err := git.ReadBlob(sha)
+		if err != nil {
+			return uploaded, fmt.Errorf("could not read transcript blob %s: %w", shortSHA(sha), err)
+		}
+		if err := store.put(sha, data); err != nil {
+			return uploaded, fmt.Errorf("could not upload transcript blob %s to %s: %w", shortSHA(sha), store.url, err)
+		}
+		uploaded++
+	}
+	return uploaded, nil
+}
+
+// UsesBlobStore reports whether transcript blobs are kept in an HTTP blob
+// store rather than pushed with the notes.
+func UsesBlobStore() bool {
+	return newBlobStore() != nil
+}
+
+// blobSHA returns the git blob SHA of data, as git hash-object computes it.
+func blobSHA(data []byte) string {
+	h := sha1.New()
+	fmt.Fprintf(h, "blob %d\x00", len(data))
+	h.Write(data)
+	return fmt.Sprintf("%x", h.Sum(nil))
+}
+
+func shortSHA(sha string) string {
+	if len(sha) > 7 {
+		return sha[:7]
+	}
+	return sha
+}
+
+// blobStore is an HTTP blob store keeping each blob at <url>/<sha>: GET
+// reads it, HEAD checks for it and PUT writes it.
+type blobStore struct {
+	url    string
+	token  string
+	client *http.Client
+}
+
+// newBlobStore returns the configured HTTP blob store, or nil if there is
+// none.
+func newBlobStore() *blobStore {
+	url := strings.TrimSuffix(storeConfig().BlobStore, "/")
+	if url == "" {
+		return nil
+	}
+	return &blobStore{url: url, token: os.Getenv(BlobStoreTokenEnv), client: &http.Client{Timeout: blobStoreTimeout}}
+}
+
+func (s *blobStore) do(method, sha string, body []byte) (*http.Response, error) {
+	var r io.Reader
+	if body != nil {
+		r = bytes.NewReader(body)
+	}
+	req, err := http.NewRequest(method, s.url+"/"+sha, r)
+	if err != nil {
+		return nil, err
+	}
+	if s.token != "" {
+		req.Header.Set("Authorization", "Bearer "+s.token)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/octet-stream")
+	}
+	return s.client.Do(req)
+}
+
+func (s *blobStore) get(sha string) ([]byte, error) {
+	resp, err := s.do(http.MethodGet, sha, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, errBlobNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+func (s *blobStore) has(sha string) (bool, error) {
+	resp, err := s.do(http.MethodHead, sha, nil)
+	if err != nil {
+		return false, err
+	}
+	_ = resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return true, nil
+	case http.StatusNotFound:
+		return false, nil
+	}
+	return false, fmt.Errorf("unexpected status %s", resp.Status)
+}
+
+func (s *blobStore) put(sha string, data []byte) error {
+	resp, err := s.do(http.MethodPut, sha, data)
+	if err != nil {
+		return err
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package storage
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestBlobSHA(t *testing.T) {
+	// git hash-object of "hello\n"
+	if got := blobSHA([]byte("hello\n")); got != "ce013625030ba8dba906f756967f9e9ca394464a" {
+		t.Errorf("blobSHA() = %s", got)
+	}
+}
+
+func TestBlobStoreRoundTrip(t *testing.T) {
+	var mu sync.Mutex
+	blobs := map[string][]byte{}
+	var auth string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		auth = r.Header.Get("Authorization")
+		sha := strings.TrimPrefix(r.URL.Path, "/blobs/")
+		switch r.Method {
+		case http.MethodPut:
+			data, _ := io.ReadAll(r.Body)
+			blobs[sha] = data
+			w.WriteHeader(http.StatusCreated)
+		case http.MethodGet, http.MethodHead:
+			data, ok := blobs[sha]
+			if !ok {
+				http.NotFound(w, r)
+				return
+			}
+			_, _ = w.Write(data)
+		}
+	}))
+	defer srv.Close()
+
+	store := &blobStore{url: srv.URL + "/blobs", token: "secret", client: srv.Client()}
+	data := []byte("compressed transcript")
+	sha := blobSHA(data)
+
+	if ok, err := store.has(sha); err != nil || ok {
+		t.Fatalf("has() before put = %v, %v", ok, err)
+	}
+	if _, err := store.get(sha); err != errBlobNotFound {
+		t.Errorf("get() before put error = %v, want errBlobNotFound", err)
+	}
+	if err := store.put(sha, data); err != nil {
+		t.Fatalf("put() error: %v", err)
+	}
+	if auth != "Bearer secret" {
+		t.Errorf("Authorization = %q", auth)
+	}
+	if ok, err := store.has(sha); err != nil || !ok {
+		t.Errorf("has() after put = %v, %v", ok, err)
+	}
+	got, err := store.get(sha)
+	if err != nil || string(got) != string(data) {
+		t.Errorf("get() = %q, %v", got, err)
+	}
+}
@@ -0,0 +1,132 @@
+package storage
+
+import (
+	"fmt"
+
+	"github.com/re-cinq/shift-log/internal/git"
+)
+
+// GetCheckpoints returns the conversation checkpoints taken while HEAD was
+// at the commit, one per agent session. Returns nil if there are none.
+func GetCheckpoints(commitSHA string) ([]*StoredConversation, error) {
+	data, err := git.GetNoteFromRef(git.CheckpointsRef, commitSHA)
+	if err != nil {
+		// No checkpoint for this commit (or no checkpoints ref yet)
+		return nil, nil
+	}
+	return UnmarshalStoredConversations(data)
+}
+
+// SaveCheckpoint records sc as the latest checkpoint of its session taken
+// while HEAD was at the commit, replacing the session's previous one.
+func SaveCheckpoint(commitSHA string, sc *StoredConversation) error {
+	checkpoints, err := GetCheckpoints(commitSHA)
+	if err != nil {
+		checkpoints = nil
+	}
+	if i := IndexOfSession(checkpoints, sc); i >= 0 {
+		checkpoints[i] = sc
+	} else {
+		checkpoints = append(checkpoints, sc)
+	}
+	content, err := MarshalStoredConversations(checkpoints)
+	if err != nil {
+		return fmt.Errorf("failed to marshal checkpoint: %w", err)
+	}
+	return git.AddNoteToRef(git.CheckpointsRef, commitSHA, content)
+}
+
+// ListCheckpoints returns every object with checkpoints, a commit or a
+// snapshot, mapped to its checkpoints.
+func ListCheckpoints() (map[string][]*StoredConversation, error) {
+	blobs, err := git.ListNoteBlobs(git.CheckpointsRef)
+	if err != nil {
+		return nil, fmt.Errorf("could not list checkpoints: %w", err)
+	}
+	checkpoints := make(map[string][]*StoredConversation, len(blobs))
+	for sha := range blobs {
+		conversations, err := GetCheckpoints(sha)
+		if err != nil {
+			return nil, fmt.Errorf("could not read checkpoints of %s: %w", sha, err)
+		}
+		checkpoints[sha] = conversations
+	}
+	return checkpoints, nil
+}
+
+// FoldCheckpoints moves the checkpoints taken on the parents of a commit
+// into the commit's conversation note, and returns the ones added. A session
+// already stored on the commit keeps its stored conversation, which is at
+// least as recent as the checkpoint.
+func FoldCheckpoints(commitSHA string) ([]*StoredConversation, error) {
+	parents, err := git.GetParentCommits(commitSHA)
+	if err != nil {
+		return nil, fmt.Errorf("could not list parents of %s: %w", commitSHA, err)
+	}
+	return moveCheckpoints(parents, commitSHA)
+}
+
+// PromoteCheckpoints moves the checkpoints of an object into a commit's
+// conversation note like FoldCheckpoints, and returns the ones added. A
+// snapshot object is released. It fails if the object has no checkpoints.
+func PromoteCheckpoints(objectSHA, commitSHA string) ([]*StoredConversation, error) {
+	checkpoints, err := GetCheckpoints(objectSHA)
+	if err != nil {
+		return nil, err
+	}
+	if checkpoints == nil {
+		return nil, fmt.Errorf("no checkpoint found for %s", objectSHA)
+	}
+	added, err := moveCheckpoints([]string{objectSHA}, commitSHA)
+	if err != nil {
+		return added, err
+	}
+	return added, git.ReleaseSnapshot(objectSHA)
+}
+
+// moveCheckpoints adds the checkpoints of the sources to the commit's
+// conversation note, skipping sessions stored on it already, and removes
+// them from the sources.
+func moveCheckpoints(sources []string, commitSHA string) ([]*StoredConversation, error) {
+	var pending []*StoredConversation
+	var moved []string
+	for _, source := range sources {
+		checkpoints, err := GetCheckpoints(source)
+		if err != nil {
+			return nil, fmt.Errorf("could not read checkpoints of %s: %w", source, err)
+		}
+		if checkpoints != nil {
+			pending = append(pending, checkpoints...)
+			moved = append(moved, source)
+		}
+	}
+	if len(pending) == 0 {
+		return nil, nil
+	}
+
+	var added []*StoredConversation
+	err := updateStoredConversations(commitSHA, true, func(conversations []*StoredConversation) []*StoredConversation {
+		added = nil
+		for _, sc := range pending {
+			if IndexOfSession(conversations, sc) >= 0 {
+				continue
+			}
+			conversations = append(conversations, sc)
+			added = append(added, sc)
+		}
+		if len(added) == 0 {
+			return nil
+		}
+		return conversations
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, source := range moved {
+		if err := git.RemoveNoteFromRef(git.CheckpointsRef, source); err != nil {
+			return added, fmt.Errorf("could not remove checkpoints of %s: %w", source, err)
+		}
+	}
+	return added, nil
+}
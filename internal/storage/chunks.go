@@ -0,0 +1,112 @@
+package storage
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/re-cinq/shift-log/internal/git"
+)
+
+// Consecutive commits of a session store transcripts that share a growing
+// prefix. Compressed, they share no bytes git could deduplicate or delta
+// against each other, so every commit pays for the whole session again.
+// With transcript_chunks, transcripts are instead stored uncompressed, cut
+// into chunks where their content, not their offset, says so: a rolling gear
+// hash over the bytes cuts wherever its low bits are zero. Transcripts with
+// a common prefix are then cut at the same places and share all but their
+// last chunks, each a blob that git stores, compresses and syncs once.
+
+// Chunk sizes: with chunkMask's 14 bits, chunks average 16 KiB past the
+// minimum.
+const (
+	chunkMinSize = 4 << 10
+	chunkMaxSize = 64 << 10
+	chunkMask    = 1<<14 - 1
+)
+
+// gearTable maps each byte to a pseudo-random value for the rolling hash.
+// It is derived from a fixed seed: every clone must cut transcripts at the
+// same places for their chunks to be shared.
+var gearTable = func() (table [256]uint64) {
+	// splitmix64
+	state := uint64(0x5368696674C06)
+	for i := range table {
+		state += 0x9e3779b97f4a7c15
+		z := state
+		z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+		z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+		table[i] = z ^ (z >> 31)
+	}
+	return table
+}()
+
+// splitChunks cuts data into content-defined chunks of chunkMinSize to
+// chunkMaxSize bytes, the last one possibly shorter.
+func splitChunks(data []byte) [][]byte {
+	var chunks [][]byte
+	for len(data) > 0 {
+		n := chunkBoundary(data)
+		chunks = append(chunks, data[:n])
+		data = data[n:]
+	}
+	return chunks
+}
+
+// chunkBoundary returns the length of the first chunk of data.
+func chunkBoundary(data []byte) int {
+	if len(data) <= chunkMinSize {
+		return len(data)
+	}
+	limit := min(len(data), chunkMaxSize)
+	var hash uint64
+	for i := chunkMinSize; i < limit; i++ {
+		hash = hash<<1 + gearTable[data[i]]
+		if hash&chunkMask == 0 {
+			return i + 1
+		}
+	}
+	return limit
+}
+
+// IsChunked reports whether the transcript of sc is stored in
+// content-defined chunks.
+func (sc *StoredConversation) IsChunked() bool {
+	return len(sc.TranscriptChunks) > 0
+}
+
+// SetTranscriptChunks replaces the transcript of sc with transcript, stored
+// uncompressed in content-defined chunks saved in git.BlobsRef. The
+// checksum is left to the caller.
+func (sc *StoredConversation) SetTranscriptChunks(transcript []byte) error {
+	if len(transcript) == 0 {
+		return fmt.Errorf("cannot store an empty transcript in chunks")
+	}
+	chunks, err := git.AddBlobs(splitChunks(transcript))
+	if err != nil {
+		return fmt.Errorf("could not store transcript chunks: %w", err)
+	}
+	sc.Transcript = ""
+	sc.TranscriptBlob = ""
+	sc.TranscriptChunks = chunks
+	return nil
+}
+
+// readTranscriptChunks reassembles a transcript from its chunks, reading
+// those the object database does not have from the HTTP blob store.
+func readTranscriptChunks(shas []string) ([]byte, error) {
+	local, err := git.ReadBlobs(shas)
+	if err != nil {
+		return nil, err
+	}
+	var transcript bytes.Buffer
+	for _, sha := range shas {
+		chunk, ok := local[sha]
+		if !ok {
+			if chunk, err = readTranscriptBlob(sha); err != nil {
+				return nil, err
+			}
+		}
+		transcript.Write(chunk)
+	}
+	return transcript.Bytes(), nil
+}
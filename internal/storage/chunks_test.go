@@ -0,0 +1,108 @@
+package storage
+
+import (
+	"bytes"
+	"os/exec"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/re-cinq/shift-log/internal/git"
+)
+
+func TestSplitChunks(t *testing.T) {
+	transcript := bytes.Join(benchmarkTranscripts(10), nil)
+	chunks := splitChunks(transcript)
+	if len(chunks) < 2 {
+		t.Fatalf("splitChunks() returned %d chunks of a %d byte transcript", len(chunks), len(transcript))
+	}
+	for i, chunk := range chunks {
+		if len(chunk) > chunkMaxSize || (len(chunk) < chunkMinSize && i < len(chunks)-1) {
+			t.Errorf("chunk %d is %d bytes, want %d to %d", i, len(chunk), chunkMinSize, chunkMaxSize)
+		}
+	}
+	if got := bytes.Join(chunks, nil); !bytes.Equal(got, transcript) {
+		t.Error("chunks do not reassemble into the transcript")
+	}
+	if len(splitChunks(nil)) != 0 {
+		t.Error("splitChunks(nil) returned chunks")
+	}
+}
+
+func TestSplitChunksSharesPrefix(t *testing.T) {
+	// A session's transcript on a later commit extends the earlier one
+	sessions := benchmarkTranscripts(12)
+	earlier := splitChunks(bytes.Join(sessions[:10], nil))
+	later := splitChunks(bytes.Join(sessions, nil))
+
+	// Only the last chunk of the earlier transcript may differ
+	for i, chunk := range earlier[:len(earlier)-1] {
+		if !bytes.Equal(chunk, later[i]) {
+			t.Fatalf("chunk %d of %d differs between the transcripts", i, len(earlier))
+		}
+	}
+}
+
+// BenchmarkRepoSize stores the transcripts a session leaves on 40
+// consecutive commits, each extending the previous one, and reports the
+// size of the repository's pack once git gc has compressed them.
+func BenchmarkRepoSize(b *testing.B) {
+	sessions := benchmarkTranscripts(40)
+	transcripts := make([][]byte, len(sessions))
+	for i := range sessions {
+		transcripts[i] = bytes.Join(sessions[:i+1], nil)
+	}
+
+	b.Run("compressed", func(b *testing.B) {
+		chdirScratchRepo(b)
+		for b.Loop() {
+			for _, transcript := range transcripts {
+				compressed, err := Compress(transcript)
+				if err != nil {
+					b.Fatalf("Compress() error: %v", err)
+				}
+				if _, err := git.AddBlob(compressed); err != nil {
+					b.Fatalf("AddBlob() error: %v", err)
+				}
+			}
+		}
+		reportPackSize(b)
+	})
+	b.Run("chunks", func(b *testing.B) {
+		chdirScratchRepo(b)
+		for b.Loop() {
+			for _, transcript := range transcripts {
+				if _, err := git.AddBlobs(splitChunks(transcript)); err != nil {
+					b.Fatalf("AddBlobs() error: %v", err)
+				}
+			}
+		}
+		reportPackSize(b)
+	})
+}
+
+// reportPackSize reports the size of the loose objects of the scratch
+// repository, then packs it and reports the size of the pack.
+func reportPackSize(b *testing.B) {
+	b.ReportMetric(float64(countObjects(b, "size")), "loose-KiB")
+	if out, err := exec.Command("git", "gc", "-q").CombinedOutput(); err != nil {
+		b.Fatalf("git gc: %v: %s", err, out)
+	}
+	b.ReportMetric(float64(countObjects(b, "size-pack")), "pack-KiB")
+}
+
+// countObjects returns a size, in KiB, that git count-objects reports.
+func countObjects(b *testing.B, field string) int {
+	out, err := exec.Command("git", "count-objects", "-v").Output()
+	if err != nil {
+		b.Fatalf("git count-objects: %v", err)
+	}
+	for _, line := range strings.Split(string(out), "\n") {
+		if kib, ok := strings.CutPrefix(line, field+": "); ok {
+			size, _ := strconv.Atoi(kib)
+			return size
+		}
+	}
+	b.Fatalf("git count-objects reported no %s", field)
+	return 0
+}
@@ -7,8 +7,29 @@ import (
 	"encoding/base64"
 	"fmt"
 	"io"
+	"sync"
+
+	"github.com/re-cinq/shift-log/internal/config"
+)
+
+var (
+	storeConfigOnce sync.Once
+	storeCfg        *config.Config
 )
 
+// storeConfig returns the config new transcripts are stored with, read on
+// first use. An unreadable config stores them with the defaults.
+func storeConfig() *config.Config {
+	storeConfigOnce.Do(func() {
+		cfg, err := config.Read()
+		if err != nil {
+			cfg = &config.Config{}
+		}
+		storeCfg = cfg
+	})
+	return storeCfg
+}
+
 // Compress compresses data using gzip
 func Compress(data []byte) ([]byte, error) {
 	var buf bytes.Buffer
@@ -25,8 +46,36 @@ func Compress(data []byte) ([]byte, error) {
 	return buf.Bytes(), nil
 }
 
-// Decompress decompresses gzip data
+// CompressWith compresses data with a compression algorithm,
+// config.CompressionGzip or config.CompressionZstd, using the saved zstd
+// dictionary dictID unless it is 0.
+func CompressWith(algorithm string, dictID uint32, data []byte) ([]byte, error) {
+	switch algorithm {
+	case "", config.CompressionGzip:
+		return Compress(data)
+	case config.CompressionZstd:
+		return zstdCompress(data, dictID)
+	}
+	return nil, fmt.Errorf("unsupported compression %q: use %s or %s", algorithm, config.CompressionGzip, config.CompressionZstd)
+}
+
+// Compression returns the compression algorithm of data, as
+// config.Compression names it, and the ID of the zstd dictionary it needs,
+// if any.
+func Compression(data []byte) (algorithm string, dictID uint32) {
+	if isZstd(data) {
+		return config.CompressionZstd, frameDictionaryID(data)
+	}
+	return config.CompressionGzip, 0
+}
+
+// Decompress decompresses gzip or zstd data, telling them apart by their
+// magic number, so transcripts stored before a change of compression are
+// still read.
 func Decompress(data []byte) (result []byte, err error) {
+	if isZstd(data) {
+		return zstdDecompress(data)
+	}
 	r, err := gzip.NewReader(bytes.NewReader(data))
 	if err != nil {
 		return nil, err
@@ -61,9 +110,11 @@ func VerifyChecksum(data []byte, expected string) bool {
 	return Checksum(data) == expected
 }
 
-// CompressAndEncode compresses and base64 encodes data
+// CompressAndEncode compresses data with the configured compression and
+// base64 encodes it
 func CompressAndEncode(data []byte) (string, error) {
-	compressed, err := Compress(data)
+	cfg := storeConfig()
+	compressed, err := CompressWith(cfg.Compression, cfg.CompressionDictionary, data)
 	if err != nil {
 		return "", err
 	}
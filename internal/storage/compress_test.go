@@ -1,8 +1,16 @@
 package storage
 
 import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/rand/v2"
+	"os/exec"
 	"strings"
 	"testing"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/re-cinq/shift-log/internal/config"
 )
 
 func TestCompressDecompressRoundTrip(t *testing.T) {
@@ -131,3 +139,250 @@ func TestDecodeAndDecompressValidBase64InvalidGzip(t *testing.T) {
 		t.Error("DecodeAndDecompress() should fail when base64 decodes to non-gzip data")
 	}
 }
+
+func TestDecompressDetectsZstd(t *testing.T) {
+	original := []byte(`{"uuid":"1","type":"user","message":{"content":[{"type":"text","text":"Hello"}]}}`)
+
+	compressed, err := CompressWith(config.CompressionZstd, 0, original)
+	if err != nil {
+		t.Fatalf("CompressWith() error: %v", err)
+	}
+	if algorithm, dictID := Compression(compressed); algorithm != config.CompressionZstd || dictID != 0 {
+		t.Errorf("Compression() = %q, %d, want zstd without dictionary", algorithm, dictID)
+	}
+	decompressed, err := Decompress(compressed)
+	if err != nil {
+		t.Fatalf("Decompress() error: %v", err)
+	}
+	if string(decompressed) != string(original) {
+		t.Error("zstd round-trip failed")
+	}
+
+	gzipped, err := Compress(original)
+	if err != nil {
+		t.Fatalf("Compress() error: %v", err)
+	}
+	if algorithm, _ := Compression(gzipped); algorithm != config.CompressionGzip {
+		t.Errorf("Compression() of gzip data = %q", algorithm)
+	}
+}
+
+func TestCompressWithUnsupported(t *testing.T) {
+	if _, err := CompressWith("lz4", 0, []byte("data")); err == nil {
+		t.Error("CompressWith() should fail on an unsupported compression")
+	}
+}
+
+func TestFrameDictionaryID(t *testing.T) {
+	tests := []struct {
+		name  string
+		frame []byte
+		want  uint32
+	}{
+		// Single segment, 4-byte dictionary ID
+		{"4-byte ID", []byte{0x28, 0xb5, 0x2f, 0xfd, 0x23, 0x78, 0x56, 0x34, 0x12}, 0x12345678},
+		// Window descriptor, then a 1-byte dictionary ID
+		{"1-byte ID after window", []byte{0x28, 0xb5, 0x2f, 0xfd, 0x01, 0x58, 0x2a}, 0x2a},
+		{"no dictionary", []byte{0x28, 0xb5, 0x2f, 0xfd, 0x20, 0x00}, 0},
+		{"truncated", []byte{0x28, 0xb5, 0x2f, 0xfd, 0x23, 0x78}, 0},
+		{"gzip", []byte{0x1f, 0x8b, 0x08, 0x00}, 0},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := frameDictionaryID(tc.frame); got != tc.want {
+				t.Errorf("frameDictionaryID() = %#x, want %#x", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestDictionaryID(t *testing.T) {
+	id, err := DictionaryID([]byte{0x37, 0xa4, 0x30, 0xec, 0x2a, 0x00, 0x00, 0x00, 0xff})
+	if err != nil || id != 42 {
+		t.Errorf("DictionaryID() = %d, %v, want 42", id, err)
+	}
+	if _, err := DictionaryID([]byte("not a dictionary")); err == nil {
+		t.Error("DictionaryID() should fail on data that is not a dictionary")
+	}
+}
+
+// benchmarkTranscripts returns n transcripts shaped like Claude Code's JSONL,
+// of 20 to 200 messages each.
+func benchmarkTranscripts(n int) [][]byte {
+	words := strings.Fields("the function returns an error when the config file is missing so " +
+		"we should add retry logic to the upload client and cover it with table driven tests " +
+		"refactor the parser to handle edge cases in internal storage and cmd packages")
+	rng := rand.New(rand.NewPCG(1, 2))
+	transcripts := make([][]byte, n)
+	for t := range transcripts {
+		var b strings.Builder
+		parent := ""
+		for i := range 20 + rng.IntN(180) {
+			uuid := fmt.Sprintf("%08x-%04x-4%03x-8%03x-%012x", rng.Uint32(), rng.IntN(1<<16), rng.IntN(1<<12), rng.IntN(1<<12), rng.Uint64()>>16)
+			text := make([]string, 5+rng.IntN(80))
+			for j := range text {
+				text[j] = words[rng.IntN(len(words))]
+			}
+			entry := map[string]any{
+				"parentUuid": parent, "isSidechain": false, "userType": "external",
+				"cwd": "/home/dev/project", "sessionId": fmt.Sprintf("session-%d", t),
+				"version": "2.0.14", "gitBranch": "main", "uuid": uuid,
+				"timestamp": fmt.Sprintf("2025-05-01T10:%02d:%02d.000Z", i/60%60, i%60),
+			}
+			if i%2 == 0 {
+				entry["type"] = "user"
+				entry["message"] = map[string]any{"role": "user", "content": strings.Join(text, " ")}
+			} else {
+				entry["type"] = "assistant"
+				entry["message"] = map[string]any{
+					"id": "msg_" + uuid[:24], "type": "message", "role": "assistant",
+					"model": "claude-sonnet-4-5-20250929",
+					"content": []map[string]any{
+						{"type": "text", "text": strings.Join(text, " ")},
+						{"type": "tool_use", "id": "toolu_" + uuid[:24], "name": "Bash",
+							"input": map[string]any{"command": "go test ./internal/...", "description": "Run the tests"}},
+					},
+					"usage": map[string]any{"input_tokens": rng.IntN(5000), "output_tokens": rng.IntN(2000), "cache_read_input_tokens": rng.IntN(90000)},
+				}
+			}
+			line, _ := json.Marshal(entry)
+			b.Write(line)
+			b.WriteByte('\n')
+			parent = uuid
+		}
+		transcripts[t] = []byte(b.String())
+	}
+	return transcripts
+}
+
+// chdirScratchRepo changes to a new git repository for the rest of tb.
+func chdirScratchRepo(tb testing.TB) {
+	tb.Chdir(tb.TempDir())
+	for _, args := range [][]string{
+		{"init", "-q"},
+		{"config", "user.name", "Bench"},
+		{"config", "user.email", "bench@example.com"},
+	} {
+		if out, err := exec.Command("git", args...).CombinedOutput(); err != nil {
+			tb.Fatalf("git %s: %v: %s", args[0], err, out)
+		}
+	}
+}
+
+// benchmarkCompression compresses every transcript per iteration and
+// reports their compressed size as a percentage of the original.
+func benchmarkCompression(b *testing.B, algorithm string, dictID uint32, transcripts [][]byte) {
+	original, compressed := 0, 0
+	for _, transcript := range transcripts {
+		out, err := CompressWith(algorithm, dictID, transcript)
+		if err != nil {
+			b.Fatalf("CompressWith() error: %v", err)
+		}
+		original += len(transcript)
+		compressed += len(out)
+	}
+	b.SetBytes(int64(original))
+	for b.Loop() {
+		for _, transcript := range transcripts {
+			if _, err := CompressWith(algorithm, dictID, transcript); err != nil {
+				b.Fatalf("CompressWith() error: %v", err)
+			}
+		}
+	}
+	b.ReportMetric(100*float64(compressed)/float64(original), "%size")
+}
+
+func TestZstdDictionaryRoundTrip(t *testing.T) {
+	// The dictionary is saved in a scratch repository
+	chdirScratchRepo(t)
+	t.Cleanup(resetDictionaries)
+
+	transcripts := benchmarkTranscripts(60)
+	dictionary, err := TrainDictionary(transcripts[10:], DefaultDictionarySize)
+	if err != nil {
+		t.Fatalf("TrainDictionary() error: %v", err)
+	}
+	id, err := SaveDictionary(dictionary)
+	if err != nil {
+		t.Fatalf("SaveDictionary() error: %v", err)
+	}
+
+	compressed, err := CompressWith(config.CompressionZstd, id, transcripts[0])
+	if err != nil {
+		t.Fatalf("CompressWith() error: %v", err)
+	}
+	if algorithm, dictID := Compression(compressed); algorithm != config.CompressionZstd || dictID != id {
+		t.Errorf("Compression() = %q, %d, want zstd with dictionary %d", algorithm, dictID, id)
+	}
+	resetDictionaries()
+	decompressed, err := Decompress(compressed)
+	if err != nil {
+		t.Fatalf("Decompress() error: %v", err)
+	}
+	if !bytes.Equal(decompressed, transcripts[0]) {
+		t.Error("zstd round-trip with a dictionary failed")
+	}
+}
+
+// resetDictionaries forgets the dictionaries and coders loaded so far.
+func resetDictionaries() {
+	dictionaryMu.Lock()
+	defer dictionaryMu.Unlock()
+	dictionaryData = nil
+	zstdEncoders = map[uint32]*zstd.Encoder{}
+	zstdDecoders = map[uint32]*zstd.Decoder{}
+}
+
+func BenchmarkCompressGzip(b *testing.B) {
+	benchmarkCompression(b, config.CompressionGzip, 0, benchmarkTranscripts(50))
+}
+
+func BenchmarkCompressZstd(b *testing.B) {
+	benchmarkCompression(b, config.CompressionZstd, 0, benchmarkTranscripts(50))
+}
+
+func BenchmarkCompressZstdDictionary(b *testing.B) {
+	// The dictionary is saved in a scratch repository
+	chdirScratchRepo(b)
+	b.Cleanup(resetDictionaries)
+
+	// Train over some transcripts and measure on others
+	transcripts := benchmarkTranscripts(250)
+	dictionary, err := TrainDictionary(transcripts[50:], DefaultDictionarySize)
+	if err != nil {
+		b.Fatalf("TrainDictionary() error: %v", err)
+	}
+	id, err := SaveDictionary(dictionary)
+	if err != nil {
+		b.Fatalf("SaveDictionary() error: %v", err)
+	}
+	benchmarkCompression(b, config.CompressionZstd, id, transcripts[:50])
+}
+
+func BenchmarkDecompressGzip(b *testing.B) {
+	transcript := benchmarkTranscripts(1)[0]
+	compressed, err := Compress(transcript)
+	if err != nil {
+		b.Fatalf("Compress() error: %v", err)
+	}
+	b.SetBytes(int64(len(transcript)))
+	for b.Loop() {
+		if _, err := Decompress(compressed); err != nil {
+			b.Fatalf("Decompress() error: %v", err)
+		}
+	}
+}
+
+func BenchmarkDecompressZstd(b *testing.B) {
+	transcript := benchmarkTranscripts(1)[0]
+	compressed, err := CompressWith(config.CompressionZstd, 0, transcript)
+	if err != nil {
+		b.Fatalf("CompressWith() error: %v", err)
+	}
+	b.SetBytes(int64(len(transcript)))
+	for b.Loop() {
+		if _, err := Decompress(compressed); err != nil {
+			b.Fatalf("Decompress() error: %v", err)
+		}
+	}
+}
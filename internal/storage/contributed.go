@@ -0,0 +1,88 @@
+package storage
+
+import (
+	"fmt"
+
+	"github.com/re-cinq/shift-log/internal/config"
+	"github.com/re-cinq/shift-log/internal/git"
+)
+
+// Contributors who cannot write to a repository's shared notes ref, such as
+// those working from a fork, push their conversations to a notes ref of
+// their own instead (config.ContributorRef). Maintainers read them from the
+// refs listed in read_refs, where they are shown with the shared
+// conversations of the same commits until they are merged into them.
+
+// ContributorRefs returns the contributor notes refs that the views read
+// along with the shared one, as .shiftlog/config lists them in read_refs.
+func ContributorRefs() ([]string, error) {
+	cfg, err := config.Read()
+	if err != nil {
+		return nil, fmt.Errorf("could not read config: %w", err)
+	}
+	for _, ref := range cfg.ReadRefs {
+		if err := git.ValidateContributorRef(ref); err != nil {
+			return nil, fmt.Errorf("invalid read_refs: %w", err)
+		}
+	}
+	return cfg.ReadRefs, nil
+}
+
+// GetContributedConversations returns the conversations stored for a commit
+// in the contributor refs, in the order of read_refs, leaving out those of
+// the sessions in shared and the repeated ones.
+func GetContributedConversations(commitSHA string, shared []*StoredConversation) ([]*StoredConversation, error) {
+	refs, err := ContributorRefs()
+	if err != nil {
+		return nil, err
+	}
+	var contributed []*StoredConversation
+	for _, ref := range refs {
+		data, err := git.GetNoteFromRef(ref, commitSHA)
+		if err != nil {
+			// No conversation for this commit in the ref, or no such ref yet
+			continue
+		}
+		conversations, err := UnmarshalStoredConversations(data)
+		if err != nil {
+			return nil, fmt.Errorf("could not parse conversation in %s: %w", ref, err)
+		}
+		for _, sc := range conversations {
+			sc.ContributorRef = ref
+		}
+		contributed = appendNewSessions(contributed, shared, conversations)
+	}
+	return contributed, nil
+}
+
+// ListContributedConversationCommits returns the set of commits with a
+// conversation in one of the contributor refs.
+func ListContributedConversationCommits() (map[string]bool, error) {
+	refs, err := ContributorRefs()
+	if err != nil {
+		return nil, err
+	}
+	commits := make(map[string]bool)
+	for _, ref := range refs {
+		blobs, err := git.ListNoteBlobs(ref)
+		if err != nil {
+			return nil, fmt.Errorf("could not list %s: %w", ref, err)
+		}
+		for sha := range blobs {
+			commits[sha] = true
+		}
+	}
+	return commits, nil
+}
+
+// appendNewSessions appends to contributed the conversations of sessions
+// found neither in shared nor in contributed: once a contributor's
+// conversation is merged into the shared notes, the shared one is shown.
+func appendNewSessions(contributed, shared, conversations []*StoredConversation) []*StoredConversation {
+	for _, sc := range conversations {
+		if IndexOfSession(shared, sc) < 0 && IndexOfSession(contributed, sc) < 0 {
+			contributed = append(contributed, sc)
+		}
+	}
+	return contributed
+}
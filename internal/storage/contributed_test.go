@@ -0,0 +1,115 @@
+package storage
+
+import (
+	"context"
+	"os/exec"
+	"testing"
+
+	"github.com/re-cinq/shift-log/internal/config"
+	"github.com/re-cinq/shift-log/internal/git"
+)
+
+func TestContributedConversations(t *testing.T) {
+	chdirScratchRepo(t)
+	backendMu.Lock()
+	previous := activeBackend
+	activeBackend = GitNotesBackend{}
+	backendMu.Unlock()
+	t.Cleanup(func() {
+		backendMu.Lock()
+		activeBackend = previous
+		backendMu.Unlock()
+		openIndex = nil
+	})
+
+	if out, err := exec.Command("git", "commit", "-q", "--allow-empty", "-m", "first").CombinedOutput(); err != nil {
+		t.Fatalf("git commit: %v: %s", err, out)
+	}
+	commit, err := git.GetHeadCommit()
+	if err != nil {
+		t.Fatal(err)
+	}
+	const contributorRef = "refs/notes/shiftlog-alice"
+	store := func(ref string, sessionIDs ...string) {
+		t.Helper()
+		var conversations []*StoredConversation
+		for _, sessionID := range sessionIDs {
+			sc, err := NewStoredConversation(sessionID, "/project", "main", 1, []byte(`{"type":"user","uuid":"u1"}`+"\n"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			conversations = append(conversations, sc)
+		}
+		content, err := MarshalStoredConversations(conversations)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := git.AddNoteToRef(ref, commit, content); err != nil {
+			t.Fatal(err)
+		}
+	}
+	store(git.NotesRef, "shared")
+	store(contributorRef, "shared", "alice")
+
+	// Until read_refs names it, the contributor ref is not read
+	if got, err := GetConversationsWithPrivate(commit); err != nil || len(got) != 1 {
+		t.Fatalf("GetConversationsWithPrivate() without read_refs = %d conversations, %v", len(got), err)
+	}
+	if err := config.Write(&config.Config{ReadRefs: []string{contributorRef}}); err != nil {
+		t.Fatal(err)
+	}
+
+	check := func(name string, got []*StoredConversation) {
+		t.Helper()
+		if len(got) != 2 || got[0].SessionID != "shared" || got[1].SessionID != "alice" {
+			t.Fatalf("%s = %+v, want the shared conversation then alice's", name, got)
+		}
+		if got[0].ContributorRef != "" || got[1].ContributorRef != contributorRef {
+			t.Errorf("%s: contributor refs = %q, %q", name, got[0].ContributorRef, got[1].ContributorRef)
+		}
+	}
+	conversations, err := GetConversationsWithPrivate(commit)
+	if err != nil {
+		t.Fatal(err)
+	}
+	check("GetConversationsWithPrivate()", conversations)
+
+	openIndex = nil
+	ix, err := OpenIndex(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	check("Index.Conversations()", ix.Conversations(commit))
+
+	commits, err := ListContributedConversationCommits()
+	if err != nil || !commits[commit] {
+		t.Errorf("ListContributedConversationCommits() = %v, %v", commits, err)
+	}
+
+	// Removing the ref from read_refs drops it from the index
+	if err := config.Write(&config.Config{}); err != nil {
+		t.Fatal(err)
+	}
+	if ix, err = OpenIndex(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if got := ix.Conversations(commit); len(got) != 1 {
+		t.Errorf("Index.Conversations() without read_refs = %d conversations, want 1", len(got))
+	}
+}
+
+func TestContributorRefs(t *testing.T) {
+	chdirScratchRepo(t)
+	if err := config.Write(&config.Config{ReadRefs: []string{git.PrivateRef}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ContributorRefs(); err == nil {
+		t.Error("the private ref was accepted as a contributor ref")
+	}
+	if err := config.Write(&config.Config{ReadRefs: []string{"refs/heads/alice"}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ContributorRefs(); err == nil {
+		t.Error("a branch was accepted as a contributor ref")
+	}
+}
@@ -1,34 +1,61 @@
 package storage
 
 import (
+	"errors"
 	"fmt"
 	"strings"
 
 	"github.com/re-cinq/shift-log/internal/agent"
+	_ "github.com/re-cinq/shift-log/internal/agent/amazonq" // register Amazon Q agent
 	agentclaude "github.com/re-cinq/shift-log/internal/agent/claude"
 	_ "github.com/re-cinq/shift-log/internal/agent/gemini"   // register Gemini agent
+	_ "github.com/re-cinq/shift-log/internal/agent/goose"    // register Goose agent
 	_ "github.com/re-cinq/shift-log/internal/agent/opencode" // register OpenCode agent
+	_ "github.com/re-cinq/shift-log/internal/agent/windsurf" // register Windsurf agent
 	"github.com/re-cinq/shift-log/internal/git"
 )
 
-// GetStoredConversation retrieves and parses a stored conversation from a commit's git note.
-// Returns nil, nil if no note exists for the commit.
+// NoteObserver, when set, is called with the size of every conversation
+// note read, e.g. to measure them.
+var NoteObserver func(size int)
+
+// GetStoredConversation retrieves and parses the conversation stored for a
+// commit in the active backend. Returns nil, nil if none is stored. When
+// several agent sessions contributed to the commit, it returns the first
+// stored; see GetStoredConversations.
 func GetStoredConversation(commitSHA string) (*StoredConversation, error) {
-	if !git.HasNote(commitSHA) {
-		return nil, nil
+	conversations, err := GetStoredConversations(commitSHA)
+	if err != nil || conversations == nil {
+		return nil, err
 	}
+	return conversations[0], nil
+}
 
-	noteContent, err := git.GetNote(commitSHA)
+// GetStoredConversations retrieves every conversation stored for a commit,
+// in the order they were stored. Returns nil, nil if none is stored.
+func GetStoredConversations(commitSHA string) ([]*StoredConversation, error) {
+	b, err := ActiveBackend()
+	if err != nil {
+		return nil, err
+	}
+
+	noteContent, err := b.Read(commitSHA)
 	if err != nil {
 		return nil, fmt.Errorf("could not read conversation: %w", err)
 	}
+	if noteContent == nil {
+		return nil, nil
+	}
+	if NoteObserver != nil {
+		NoteObserver(len(noteContent))
+	}
 
-	stored, err := UnmarshalStoredConversation(noteContent)
+	conversations, err := UnmarshalStoredConversations(noteContent)
 	if err != nil {
 		return nil, fmt.Errorf("could not parse conversation: %w", err)
 	}
 
-	return stored, nil
+	return conversations, nil
 }
 
 // ParseTranscript decompresses the stored transcript and parses it into a Transcript.
@@ -61,21 +88,19 @@ func FindParentConversationBoundary(commitSHA, currentSessionID string) (parentS
 	}
 
 	for _, parent := range parents {
-		if !git.HasNote(parent) {
+		conversations, err := GetStoredConversations(parent)
+		if err != nil || conversations == nil {
 			continue
 		}
 
-		noteContent, err := git.GetNote(parent)
-		if err != nil {
-			continue
-		}
-
-		stored, err := UnmarshalStoredConversation(noteContent)
-		if err != nil {
-			continue
+		var stored *StoredConversation
+		for _, sc := range conversations {
+			if sc.SessionID == currentSessionID {
+				stored = sc
+				break
+			}
 		}
-
-		if stored.SessionID != currentSessionID {
+		if stored == nil {
 			return "", ""
 		}
 
@@ -92,3 +117,76 @@ func FindParentConversationBoundary(commitSHA, currentSessionID string) (parentS
 
 	return "", ""
 }
+
+// ErrNoSessionToDiff is wrapped by DiffConversations' errors when the two
+// commits have no conversation of a common session.
+var ErrNoSessionToDiff = errors.New("no session to diff")
+
+// ConversationDiff is the difference between the transcripts of a session
+// stored on two commits.
+type ConversationDiff struct {
+	From        string `json:"from"`
+	To          string `json:"to"`
+	SessionID   string `json:"session_id"`
+	Agent       string `json:"agent"`
+	FromEntries int    `json:"from_entries"`
+	ToEntries   int    `json:"to_entries"`
+	*agent.TranscriptDiff
+}
+
+// DiffConversations compares the transcripts of a session stored on two
+// commits, fromSHA being the older, keeping context unchanged entries
+// around each change (all of them when negative). With an empty sessionID
+// it diffs the first session of toSHA that is also stored on fromSHA.
+func DiffConversations(fromSHA, toSHA, sessionID string, context int) (*ConversationDiff, error) {
+	fromConversations, err := GetStoredConversations(fromSHA)
+	if err != nil {
+		return nil, err
+	}
+	toConversations, err := GetStoredConversations(toSHA)
+	if err != nil {
+		return nil, err
+	}
+	if fromConversations == nil {
+		return nil, fmt.Errorf("%w: no conversation found for commit %s", ErrNoSessionToDiff, fromSHA[:7])
+	}
+	if toConversations == nil {
+		return nil, fmt.Errorf("%w: no conversation found for commit %s", ErrNoSessionToDiff, toSHA[:7])
+	}
+
+	var from, to *StoredConversation
+	for _, t := range toConversations {
+		if sessionID != "" && t.SessionID != sessionID {
+			continue
+		}
+		if i := IndexOfSession(fromConversations, t); i >= 0 {
+			from, to = fromConversations[i], t
+			break
+		}
+	}
+	if to == nil {
+		if sessionID != "" {
+			return nil, fmt.Errorf("%w: session %s is not stored on both %s and %s", ErrNoSessionToDiff, sessionID, fromSHA[:7], toSHA[:7])
+		}
+		return nil, fmt.Errorf("%w: commits %s and %s have no session in common", ErrNoSessionToDiff, fromSHA[:7], toSHA[:7])
+	}
+
+	fromTranscript, err := from.ParseTranscript()
+	if err != nil {
+		return nil, fmt.Errorf("could not parse transcript of %s: %w", fromSHA[:7], err)
+	}
+	toTranscript, err := to.ParseTranscript()
+	if err != nil {
+		return nil, fmt.Errorf("could not parse transcript of %s: %w", toSHA[:7], err)
+	}
+
+	return &ConversationDiff{
+		From:           fromSHA,
+		To:             toSHA,
+		SessionID:      to.SessionID,
+		Agent:          to.AgentName(),
+		FromEntries:    len(fromTranscript.Entries),
+		ToEntries:      len(toTranscript.Entries),
+		TranscriptDiff: agent.DiffTranscripts(fromTranscript, toTranscript, context),
+	}, nil
+}
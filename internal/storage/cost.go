@@ -0,0 +1,92 @@
+package storage
+
+import "strings"
+
+// ModelPrice is the list price of a model, in US dollars per million
+// tokens.
+type ModelPrice struct {
+	Input      float64
+	Output     float64
+	CacheWrite float64
+	CacheRead  float64
+}
+
+// modelPrice is the price of the models whose identifier contains match.
+type modelPrice struct {
+	match string
+	price ModelPrice
+}
+
+// anthropicPrice prices cache writes at 1.25 and cache reads at 0.1 times
+// the input price, as Anthropic does.
+func anthropicPrice(input, output float64) ModelPrice {
+	return ModelPrice{Input: input, Output: output, CacheWrite: input * 1.25, CacheRead: input * 0.1}
+}
+
+// cachedInputPrice prices cache reads at a share of the input price and
+// cache writes like any input, as OpenAI and Google do.
+func cachedInputPrice(input, output, readShare float64) ModelPrice {
+	return ModelPrice{Input: input, Output: output, CacheWrite: input, CacheRead: input * readShare}
+}
+
+// modelPrices are checked in order, so more specific matches come first.
+// Prices change; the table only serves estimates.
+var modelPrices = []modelPrice{
+	{"claude-opus-4-1", anthropicPrice(15, 75)},
+	{"claude-opus-4-2025", anthropicPrice(15, 75)},
+	{"claude-opus-4", anthropicPrice(5, 25)},
+	{"claude-3-opus", anthropicPrice(15, 75)},
+	{"sonnet", anthropicPrice(3, 15)},
+	{"haiku-4", anthropicPrice(1, 5)},
+	{"3-5-haiku", anthropicPrice(0.8, 4)},
+	{"haiku", anthropicPrice(0.25, 1.25)},
+	{"gpt-5-nano", cachedInputPrice(0.05, 0.4, 0.1)},
+	{"gpt-5-mini", cachedInputPrice(0.25, 2, 0.1)},
+	{"gpt-5", cachedInputPrice(1.25, 10, 0.1)},
+	{"gpt-4.1-nano", cachedInputPrice(0.1, 0.4, 0.25)},
+	{"gpt-4.1-mini", cachedInputPrice(0.4, 1.6, 0.25)},
+	{"gpt-4.1", cachedInputPrice(2, 8, 0.25)},
+	{"gpt-4o-mini", cachedInputPrice(0.15, 0.6, 0.5)},
+	{"gpt-4o", cachedInputPrice(2.5, 10, 0.5)},
+	{"o4-mini", cachedInputPrice(1.1, 4.4, 0.25)},
+	{"o3", cachedInputPrice(2, 8, 0.25)},
+	{"gemini-2.5-pro", cachedInputPrice(1.25, 10, 0.25)},
+	{"gemini-2.5-flash-lite", cachedInputPrice(0.1, 0.4, 0.25)},
+	{"gemini-2.5-flash", cachedInputPrice(0.3, 2.5, 0.25)},
+}
+
+// PriceOf returns the list price of a model, and false when it is not
+// known.
+func PriceOf(model string) (ModelPrice, bool) {
+	model = strings.ToLower(model)
+	// Bedrock and Vertex prefix the model, e.g. "us.anthropic.claude-..."
+	if i := strings.LastIndex(model, "/"); i >= 0 {
+		model = model[i+1:]
+	}
+	if model == "" {
+		return ModelPrice{}, false
+	}
+	for _, p := range modelPrices {
+		if strings.Contains(model, p.match) {
+			return p.price, true
+		}
+	}
+	return ModelPrice{}, false
+}
+
+// EstimateCost returns what the tokens of effort cost at the list price of
+// model, in US dollars, and false when the model's price is not known.
+func EstimateCost(model string, effort *Effort) (float64, bool) {
+	price, ok := PriceOf(model)
+	if !ok {
+		return 0, false
+	}
+	if effort == nil {
+		return 0, true
+	}
+	cost := float64(effort.InputTokens)*price.Input +
+		float64(effort.OutputTokens)*price.Output +
+		float64(effort.CacheCreationInputTokens)*price.CacheWrite +
+		float64(effort.CacheReadInputTokens)*price.CacheRead
+	return cost / 1e6, true
+}
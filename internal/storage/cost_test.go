@@ -0,0 +1,53 @@
+package storage
+
+import (
+	"math"
+	"testing"
+)
+
+func TestPriceOf(t *testing.T) {
+	tests := []struct {
+		model     string
+		wantInput float64
+		wantOK    bool
+	}{
+		{"claude-sonnet-4-5-20250514", 3, true},
+		{"claude-opus-4-20250514", 15, true},
+		{"claude-opus-4-1-20250805", 15, true},
+		{"claude-opus-4-5-20251101", 5, true},
+		{"claude-3-5-haiku-20241022", 0.8, true},
+		{"us.anthropic.claude-sonnet-4-20250514-v1:0", 3, true},
+		{"openai/gpt-5-mini", 0.25, true},
+		{"gpt-4o-mini", 0.15, true},
+		{"Gemini-2.5-Pro", 1.25, true},
+		{"llama-3-70b", 0, false},
+		{"", 0, false},
+	}
+	for _, tt := range tests {
+		price, ok := PriceOf(tt.model)
+		if ok != tt.wantOK || price.Input != tt.wantInput {
+			t.Errorf("PriceOf(%q) = %v, %v, want input %v, %v", tt.model, price, ok, tt.wantInput, tt.wantOK)
+		}
+	}
+}
+
+func TestEstimateCost(t *testing.T) {
+	effort := &Effort{
+		InputTokens:              1_000_000,
+		OutputTokens:             100_000,
+		CacheCreationInputTokens: 200_000,
+		CacheReadInputTokens:     1_000_000,
+	}
+	// 3 + 1.5 + 0.75 + 0.3
+	cost, ok := EstimateCost("claude-sonnet-4-5", effort)
+	if !ok || math.Abs(cost-5.55) > 1e-9 {
+		t.Errorf("EstimateCost() = %v, %v, want 5.55, true", cost, ok)
+	}
+
+	if cost, ok := EstimateCost("claude-sonnet-4-5", nil); !ok || cost != 0 {
+		t.Errorf("EstimateCost(nil effort) = %v, %v, want 0, true", cost, ok)
+	}
+	if _, ok := EstimateCost("unknown-model", effort); ok {
+		t.Error("EstimateCost(unknown model) ok = true, want false")
+	}
+}
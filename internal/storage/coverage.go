@@ -0,0 +1,180 @@
+package storage
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/re-cinq/shift-log/internal/git"
+)
+
+// CoverageGroup is how many of a group of commits, and of the lines they
+// changed, have a stored conversation. Changed lines are the lines added
+// plus the lines deleted.
+type CoverageGroup struct {
+	Commits       int     `json:"commits"`
+	Covered       int     `json:"covered"`
+	OptedOut      int     `json:"opted_out"` // commits without a conversation whose author opted out of capture
+	Lines         int     `json:"lines"`
+	CoveredLines  int     `json:"covered_lines"`
+	OptedOutLines int     `json:"opted_out_lines"`
+	CommitRatio   float64 `json:"commit_ratio"`
+	LineRatio     float64 `json:"line_ratio"`
+}
+
+func (g *CoverageGroup) add(lines int, covered, optedOut bool) {
+	g.Commits++
+	g.Lines += lines
+	if covered {
+		g.Covered++
+		g.CoveredLines += lines
+	} else if optedOut {
+		g.OptedOut++
+		g.OptedOutLines += lines
+	}
+}
+
+// finish computes the ratios. As for the badge, commits whose author opted
+// out are not expected to have a conversation and are left out.
+func (g *CoverageGroup) finish() {
+	if expected := g.Commits - g.OptedOut; expected > 0 {
+		g.CommitRatio = float64(g.Covered) / float64(expected)
+	}
+	if expected := g.Lines - g.OptedOutLines; expected > 0 {
+		g.LineRatio = float64(g.CoveredLines) / float64(expected)
+	}
+}
+
+// CoverageReport is the conversation coverage of the history of a ref,
+// broken down by author and by directory.
+type CoverageReport struct {
+	Ref         string                    `json:"ref"`
+	Since       string                    `json:"since,omitempty"`
+	Depth       int                       `json:"depth"`
+	Total       CoverageGroup             `json:"total"`
+	Authors     map[string]*CoverageGroup `json:"authors"`
+	Directories map[string]*CoverageGroup `json:"directories"`
+}
+
+// AuthorNames returns the report's authors, most commits first.
+func (r *CoverageReport) AuthorNames() []string {
+	names := make([]string, 0, len(r.Authors))
+	for name := range r.Authors {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		a, b := r.Authors[names[i]], r.Authors[names[j]]
+		if a.Commits != b.Commits {
+			return a.Commits > b.Commits
+		}
+		return names[i] < names[j]
+	})
+	return names
+}
+
+// DirectoryNames returns the report's directories, most changed lines
+// first.
+func (r *CoverageReport) DirectoryNames() []string {
+	names := make([]string, 0, len(r.Directories))
+	for name := range r.Directories {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		a, b := r.Directories[names[i]], r.Directories[names[j]]
+		if a.Lines != b.Lines {
+			return a.Lines > b.Lines
+		}
+		return names[i] < names[j]
+	})
+	return names
+}
+
+// addCommit counts a commit, with whether it has a conversation or its
+// author opted out, towards the total, its author and the directories of
+// the files it changed.
+func (r *CoverageReport) addCommit(c git.LogCommit, covered, optedOut bool) {
+	lines := 0
+	dirLines := make(map[string]int)
+	for _, f := range c.Files {
+		lines += f.Added + f.Deleted
+		dirLines[coverageDirectory(f.Path, r.Depth)] += f.Added + f.Deleted
+	}
+
+	r.Total.add(lines, covered, optedOut)
+	author := r.Authors[c.Author]
+	if author == nil {
+		author = &CoverageGroup{}
+		r.Authors[c.Author] = author
+	}
+	author.add(lines, covered, optedOut)
+	for dir, n := range dirLines {
+		group := r.Directories[dir]
+		if group == nil {
+			group = &CoverageGroup{}
+			r.Directories[dir] = group
+		}
+		group.add(n, covered, optedOut)
+	}
+}
+
+func (r *CoverageReport) finish() {
+	r.Total.finish()
+	for _, g := range r.Authors {
+		g.finish()
+	}
+	for _, g := range r.Directories {
+		g.finish()
+	}
+}
+
+// coverageDirectory returns the directory a file is counted under: its
+// first depth path components, or "." for a file at the root.
+func coverageDirectory(file string, depth int) string {
+	dir := path.Dir(file)
+	if dir == "." || depth <= 0 {
+		return "."
+	}
+	parts := strings.Split(dir, "/")
+	if len(parts) > depth {
+		parts = parts[:depth]
+	}
+	return strings.Join(parts, "/")
+}
+
+// ConversationCoverage reports which of the commits reachable from ref,
+// HEAD when empty, and committed since since when set, have a stored
+// conversation. Directories group files by their first depth path
+// components. Merge commits are left out: they are not made by an agent
+// session.
+func ConversationCoverage(ref, since string, depth int) (*CoverageReport, error) {
+	if ref == "" {
+		ref = "HEAD"
+	}
+	report := &CoverageReport{
+		Ref:         ref,
+		Since:       since,
+		Depth:       depth,
+		Authors:     make(map[string]*CoverageGroup),
+		Directories: make(map[string]*CoverageGroup),
+	}
+	noted, err := ListAllConversationCommits()
+	if err != nil {
+		return nil, fmt.Errorf("could not list conversations: %w", err)
+	}
+	omitted, err := git.ListOmittedCommits()
+	if err != nil {
+		return nil, fmt.Errorf("could not list omitted conversations: %w", err)
+	}
+	err = git.ListCommits(git.LogOptions{Ref: ref, Since: since, Stat: true}, func(c git.LogCommit) bool {
+		if c.Parents <= 1 {
+			report.addCommit(c, noted[c.SHA], omitted[c.SHA])
+		}
+		return true
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not list commits of %s: %w", ref, err)
+	}
+	report.finish()
+	return report, nil
+}
@@ -0,0 +1,70 @@
+package storage
+
+import (
+	"testing"
+
+	"github.com/re-cinq/shift-log/internal/git"
+)
+
+func TestCoverageDirectory(t *testing.T) {
+	tests := []struct {
+		file  string
+		depth int
+		want  string
+	}{
+		{"README.md", 1, "."},
+		{"cmd/root.go", 1, "cmd"},
+		{"internal/web/server.go", 1, "internal"},
+		{"internal/web/server.go", 2, "internal/web"},
+		{"internal/web/server.go", 5, "internal/web"},
+		{"internal/web/server.go", 0, "."},
+	}
+	for _, tt := range tests {
+		if got := coverageDirectory(tt.file, tt.depth); got != tt.want {
+			t.Errorf("coverageDirectory(%q, %d) = %q, want %q", tt.file, tt.depth, got, tt.want)
+		}
+	}
+}
+
+func TestCoverageReportAddCommit(t *testing.T) {
+	r := &CoverageReport{Depth: 1, Authors: make(map[string]*CoverageGroup), Directories: make(map[string]*CoverageGroup)}
+	r.addCommit(git.LogCommit{Author: "Ada", Files: []git.FileStat{
+		{Path: "cmd/a.go", Added: 10, Deleted: 2},
+		{Path: "internal/b.go", Added: 8},
+	}}, true, false)
+	r.addCommit(git.LogCommit{Author: "Ada", Files: []git.FileStat{
+		{Path: "cmd/a.go", Added: 5, Deleted: 5},
+	}}, false, false)
+	r.addCommit(git.LogCommit{Author: "Bob", Files: []git.FileStat{
+		{Path: "README.md", Added: 30},
+	}}, false, true)
+	r.finish()
+
+	if r.Total.Commits != 3 || r.Total.Covered != 1 || r.Total.OptedOut != 1 {
+		t.Errorf("total = %+v", r.Total)
+	}
+	if r.Total.CommitRatio != 0.5 {
+		t.Errorf("commit ratio = %v, want 0.5 without the opted out commit", r.Total.CommitRatio)
+	}
+	if r.Total.Lines != 60 || r.Total.CoveredLines != 20 || r.Total.LineRatio != 20.0/30 {
+		t.Errorf("lines = %d, covered %d, ratio %v", r.Total.Lines, r.Total.CoveredLines, r.Total.LineRatio)
+	}
+
+	if ada := r.Authors["Ada"]; ada.Commits != 2 || ada.Covered != 1 {
+		t.Errorf("Ada = %+v", ada)
+	}
+	if names := r.AuthorNames(); len(names) != 2 || names[0] != "Ada" {
+		t.Errorf("AuthorNames() = %v", names)
+	}
+
+	cmd := r.Directories["cmd"]
+	if cmd.Commits != 2 || cmd.Lines != 22 || cmd.CoveredLines != 12 {
+		t.Errorf("cmd = %+v", cmd)
+	}
+	if internal := r.Directories["internal"]; internal.Commits != 1 || internal.LineRatio != 1 {
+		t.Errorf("internal = %+v", internal)
+	}
+	if names := r.DirectoryNames(); len(names) != 3 || names[0] != "." || names[1] != "cmd" {
+		t.Errorf("DirectoryNames() = %v", names)
+	}
+}
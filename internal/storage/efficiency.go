@@ -0,0 +1,137 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/re-cinq/shift-log/internal/git"
+)
+
+// Groupings of an efficiency comparison.
+const (
+	CompareByModel = "model"
+	CompareByAgent = "agent"
+)
+
+// Efficiency compares the effort a model, or an agent, spent on the commits
+// of its conversations: turns, tokens and cost per commit, and per line the
+// commits changed. The averages only count the commits whose conversation
+// records its effort.
+type Efficiency struct {
+	Name            string  `json:"name"`
+	Commits         int     `json:"commits"`
+	Measured        int     `json:"measured"` // commits whose conversation records its effort
+	Turns           int     `json:"turns"`
+	Tokens          int64   `json:"tokens"`
+	Cost            float64 `json:"cost"`  // list price of the tokens, 0 for models without a known price
+	Lines           int     `json:"lines"` // lines added and deleted by the measured commits
+	TurnsPerCommit  float64 `json:"turns_per_commit"`
+	TokensPerCommit float64 `json:"tokens_per_commit"`
+	CostPerCommit   float64 `json:"cost_per_commit"`
+	TokensPerLine   float64 `json:"tokens_per_line"`
+	CostPerLine     float64 `json:"cost_per_line"`
+}
+
+// primaryModel returns the model that wrote a conversation's first
+// assistant message, the one it counts towards in Stats.
+func primaryModel(sc *StoredConversation) string {
+	if p := sc.GetProvenance(); p != nil && len(p.Models) > 0 {
+		return p.Models[0].Model
+	}
+	return sc.Model
+}
+
+// add counts a commit, the first of whose conversations is sc, that
+// changed lines lines.
+func (e *Efficiency) add(sc *StoredConversation, lines int) {
+	e.Commits++
+	if sc.Effort == nil {
+		return
+	}
+	e.Measured++
+	e.Turns += sc.Effort.Turns
+	e.Tokens += sc.Effort.TotalTokens()
+	if cost, ok := EstimateCost(sc.Model, sc.Effort); ok {
+		e.Cost += cost
+	}
+	e.Lines += lines
+}
+
+func (e *Efficiency) finish() {
+	if e.Measured > 0 {
+		e.TurnsPerCommit = float64(e.Turns) / float64(e.Measured)
+		e.TokensPerCommit = float64(e.Tokens) / float64(e.Measured)
+		e.CostPerCommit = e.Cost / float64(e.Measured)
+	}
+	if e.Lines > 0 {
+		e.TokensPerLine = float64(e.Tokens) / float64(e.Lines)
+		e.CostPerLine = e.Cost / float64(e.Lines)
+	}
+}
+
+// CompareEfficiency compares the effort of the models, or of the agents
+// with CompareByAgent, behind the commits on the current branch, most
+// commits first. As in Stats, a commit counts towards the first of its
+// conversations, and a conversation towards its primary model; those that
+// record no model are left out of a comparison by model. Merge commits are
+// left out. ctx bounds bringing the index up to date.
+func CompareEfficiency(ctx context.Context, by string) ([]Efficiency, error) {
+	if by != CompareByModel && by != CompareByAgent {
+		return nil, fmt.Errorf("invalid grouping %q: must be %s or %s", by, CompareByModel, CompareByAgent)
+	}
+	count, err := git.CountCommits()
+	if err != nil {
+		return nil, fmt.Errorf("could not count commits: %w", err)
+	}
+	if count == 0 {
+		return nil, nil
+	}
+	ix, err := OpenIndex(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("could not read the index: %w", err)
+	}
+
+	groups := make(map[string]*Efficiency)
+	err = git.ListCommits(git.LogOptions{Stat: true}, func(c git.LogCommit) bool {
+		conversations := ix.Conversations(c.SHA)
+		if c.Parents > 1 || len(conversations) == 0 {
+			return true
+		}
+		sc := conversations[0]
+		name := sc.AgentName()
+		if by == CompareByModel {
+			name = primaryModel(sc)
+		}
+		if name == "" {
+			return true
+		}
+		lines := 0
+		for _, f := range c.Files {
+			lines += f.Added + f.Deleted
+		}
+		e := groups[name]
+		if e == nil {
+			e = &Efficiency{Name: name}
+			groups[name] = e
+		}
+		e.add(sc, lines)
+		return true
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not list commits: %w", err)
+	}
+
+	comparison := make([]Efficiency, 0, len(groups))
+	for _, e := range groups {
+		e.finish()
+		comparison = append(comparison, *e)
+	}
+	sort.Slice(comparison, func(i, j int) bool {
+		if comparison[i].Commits != comparison[j].Commits {
+			return comparison[i].Commits > comparison[j].Commits
+		}
+		return comparison[i].Name < comparison[j].Name
+	})
+	return comparison, nil
+}
@@ -0,0 +1,35 @@
+package storage
+
+import "testing"
+
+func TestEfficiency(t *testing.T) {
+	e := &Efficiency{Name: "claude-sonnet-4-5-20250514"}
+	e.add(&StoredConversation{Model: e.Name, Effort: &Effort{Turns: 4, InputTokens: 1000, OutputTokens: 200}}, 20)
+	e.add(&StoredConversation{Model: e.Name, Effort: &Effort{Turns: 2, InputTokens: 500, OutputTokens: 100}}, 10)
+	// Stored before effort was recorded: counted, but not in the averages
+	e.add(&StoredConversation{Model: e.Name}, 100)
+	e.finish()
+
+	if e.Commits != 3 || e.Measured != 2 || e.Lines != 30 {
+		t.Fatalf("commits = %d, measured = %d, lines = %d", e.Commits, e.Measured, e.Lines)
+	}
+	if e.TurnsPerCommit != 3 || e.TokensPerCommit != 900 || e.TokensPerLine != 60 {
+		t.Errorf("per commit = %v turns, %v tokens; per line = %v tokens", e.TurnsPerCommit, e.TokensPerCommit, e.TokensPerLine)
+	}
+	if e.Cost <= 0 || e.CostPerLine != e.Cost/30 {
+		t.Errorf("cost = %v, per line = %v", e.Cost, e.CostPerLine)
+	}
+}
+
+func TestPrimaryModel(t *testing.T) {
+	sc := &StoredConversation{Model: "claude-opus-4-5-20251101", Provenance: &Provenance{Models: []ModelUsage{
+		{Model: "claude-haiku-4-5-20251001", Messages: 3},
+		{Model: "claude-opus-4-5-20251101", Messages: 10},
+	}}}
+	if got := primaryModel(sc); got != "claude-haiku-4-5-20251001" {
+		t.Errorf("primaryModel() = %q, want the first model used", got)
+	}
+	if got := primaryModel(&StoredConversation{Model: "gpt-5"}); got != "gpt-5" {
+		t.Errorf("primaryModel() without provenance = %q", got)
+	}
+}
@@ -0,0 +1,180 @@
+package storage
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/re-cinq/shift-log/internal/agent"
+	"github.com/re-cinq/shift-log/internal/git"
+)
+
+// DefaultExcerptContext is the number of entries shown on either side of a
+// tool call that edited a file.
+const DefaultExcerptContext = 2
+
+// FileHistoryEntry is a commit touching a file together with the part of its
+// conversation in which the file was edited.
+type FileHistoryEntry struct {
+	CommitSHA  string
+	CommitDate string
+	CommitMsg  string
+	Agent      string
+	Model      string
+	Tags       []string
+	Excerpt    []agent.TranscriptEntry
+}
+
+// RepoRelativePath converts a path recorded by an agent into a slash-separated
+// path relative to repoRoot. Returns "" for paths outside the repository.
+func RepoRelativePath(p, repoRoot string) string {
+	if p == "" {
+		return ""
+	}
+	if filepath.IsAbs(p) {
+		if repoRoot == "" {
+			return ""
+		}
+		rel, err := filepath.Rel(repoRoot, p)
+		if err != nil {
+			return ""
+		}
+		p = rel
+	}
+	p = path.Clean(filepath.ToSlash(p))
+	if p == "." || p == ".." || strings.HasPrefix(p, "../") {
+		return ""
+	}
+	return p
+}
+
+// FilesTouched returns the repo-relative paths of files edited by tool calls
+// in the given entries.
+func FilesTouched(entries []agent.TranscriptEntry, aliases map[string]string, repoRoot string) []string {
+	seen := make(map[string]bool)
+	var files []string
+	for _, p := range agent.EditedFiles(entries, aliases) {
+		rel := RepoRelativePath(p, repoRoot)
+		if rel == "" || seen[rel] {
+			continue
+		}
+		seen[rel] = true
+		files = append(files, rel)
+	}
+	return files
+}
+
+// FileExcerpt returns the entries surrounding each tool call that edited
+// file, with contextEntries entries on either side. file is repo-relative.
+func FileExcerpt(entries []agent.TranscriptEntry, aliases map[string]string, repoRoot, file string, contextEntries int) []agent.TranscriptEntry {
+	include := make([]bool, len(entries))
+	found := false
+
+	for i, entry := range entries {
+		if entry.Message == nil {
+			continue
+		}
+		for _, block := range entry.Message.Content {
+			for _, p := range agent.EditedFilePaths(block, aliases) {
+				if RepoRelativePath(p, repoRoot) != file {
+					continue
+				}
+				found = true
+				for j := i - contextEntries; j <= i+contextEntries; j++ {
+					if j >= 0 && j < len(entries) {
+						include[j] = true
+					}
+				}
+			}
+		}
+	}
+
+	if !found {
+		return nil
+	}
+
+	var excerpt []agent.TranscriptEntry
+	for i, entry := range entries {
+		if include[i] {
+			excerpt = append(excerpt, entry)
+		}
+	}
+	return excerpt
+}
+
+// CommitFileExcerpt returns the excerpt, as FileExcerpt, of the increment of
+// a conversation stored for a commit: the part of the session that led to
+// the commit. Returns nil if the transcript cannot be read.
+func CommitFileExcerpt(commitSHA string, stored *StoredConversation, file string, contextEntries int) []agent.TranscriptEntry {
+	transcript, err := stored.ParseTranscript()
+	if err != nil {
+		return nil
+	}
+	_, lastUUID := FindParentConversationBoundary(commitSHA, stored.SessionID)
+	entries := transcript.GetEntriesSince(lastUUID)
+	return FileExcerpt(entries, stored.ToolAliases(), stored.ProjectPath, file, contextEntries)
+}
+
+// ToolAliases returns the tool name aliases of the agent that recorded the conversation.
+func (sc *StoredConversation) ToolAliases() map[string]string {
+	name := sc.Agent
+	if name == "" {
+		name = "claude"
+	}
+	ag, err := agent.Get(agent.Name(name))
+	if err != nil {
+		return nil
+	}
+	return ag.ToolAliases()
+}
+
+// FileHistory returns every commit reachable from HEAD that touches file and
+// has a stored conversation, newest first. file is relative to the repository
+// root. Each entry carries the excerpt of the commit's own conversation
+// increment in which the file was edited, if the agent edited it. A non-empty
+// tag limits the history to conversations carrying that tag.
+func FileHistory(file, tag string, contextEntries, limit int) ([]FileHistoryEntry, error) {
+	commits, err := git.ListCommitsTouchingPath(file)
+	if err != nil {
+		return nil, fmt.Errorf("could not list commits for %s: %w", file, err)
+	}
+
+	var history []FileHistoryEntry
+	for _, sha := range commits {
+		if limit > 0 && len(history) >= limit {
+			break
+		}
+
+		stored, err := GetStoredConversation(sha)
+		if err != nil || stored == nil {
+			continue
+		}
+		if tag != "" && !stored.HasTag(tag) {
+			continue
+		}
+
+		message, date, err := git.GetCommitInfo(sha)
+		if err != nil {
+			continue
+		}
+
+		entry := FileHistoryEntry{
+			CommitSHA:  sha,
+			CommitDate: date,
+			CommitMsg:  message,
+			Agent:      stored.Agent,
+			Model:      stored.Model,
+			Tags:       stored.Tags,
+		}
+		if entry.Agent == "" {
+			entry.Agent = "claude"
+		}
+
+		entry.Excerpt = CommitFileExcerpt(sha, stored, file, contextEntries)
+
+		history = append(history, entry)
+	}
+
+	return history, nil
+}
@@ -0,0 +1,85 @@
+package storage
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/re-cinq/shift-log/internal/agent"
+)
+
+func TestRepoRelativePath(t *testing.T) {
+	tests := []struct {
+		path string
+		root string
+		want string
+	}{
+		{"/repo/src/main.go", "/repo", "src/main.go"},
+		{"src/./main.go", "/repo", "src/main.go"},
+		{"/elsewhere/main.go", "/repo", ""},
+		{"../main.go", "/repo", ""},
+		{"/repo/main.go", "", ""},
+		{"", "/repo", ""},
+	}
+
+	for _, tt := range tests {
+		if got := RepoRelativePath(tt.path, tt.root); got != tt.want {
+			t.Errorf("RepoRelativePath(%q, %q) = %q, want %q", tt.path, tt.root, got, tt.want)
+		}
+	}
+}
+
+func TestFilesTouched(t *testing.T) {
+	entries := []agent.TranscriptEntry{
+		{Type: agent.MessageTypeAssistant, Message: &agent.Message{Content: []agent.ContentBlock{
+			{Type: "tool_use", Name: "Write", Input: json.RawMessage(`{"file_path":"/repo/a.go"}`)},
+			{Type: "tool_use", Name: "Edit", Input: json.RawMessage(`{"file_path":"a.go"}`)},
+			{Type: "tool_use", Name: "Edit", Input: json.RawMessage(`{"file_path":"/tmp/scratch.txt"}`)},
+		}}},
+	}
+
+	got := FilesTouched(entries, nil, "/repo")
+	want := []string{"a.go"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("FilesTouched() = %v, want %v", got, want)
+	}
+}
+
+func TestFileExcerpt(t *testing.T) {
+	text := func(uuid string, typ agent.MessageType, s string) agent.TranscriptEntry {
+		return agent.TranscriptEntry{UUID: uuid, Type: typ, Message: &agent.Message{Content: []agent.ContentBlock{{Type: "text", Text: s}}}}
+	}
+	edit := func(uuid, file string) agent.TranscriptEntry {
+		return agent.TranscriptEntry{UUID: uuid, Type: agent.MessageTypeAssistant, Message: &agent.Message{Content: []agent.ContentBlock{
+			{Type: "tool_use", Name: "Edit", Input: json.RawMessage(`{"file_path":"` + file + `"}`)},
+		}}}
+	}
+
+	entries := []agent.TranscriptEntry{
+		text("u1", agent.MessageTypeUser, "first"),
+		text("a1", agent.MessageTypeAssistant, "ok"),
+		text("u2", agent.MessageTypeUser, "change a.go"),
+		edit("e1", "/repo/a.go"),
+		text("a2", agent.MessageTypeAssistant, "done"),
+		text("u3", agent.MessageTypeUser, "now b.go"),
+		edit("e2", "/repo/b.go"),
+	}
+
+	uuids := func(es []agent.TranscriptEntry) []string {
+		var out []string
+		for _, e := range es {
+			out = append(out, e.UUID)
+		}
+		return out
+	}
+
+	if got, want := uuids(FileExcerpt(entries, nil, "/repo", "a.go", 1)), []string{"u2", "e1", "a2"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("FileExcerpt(a.go, 1) = %v, want %v", got, want)
+	}
+	if got, want := uuids(FileExcerpt(entries, nil, "/repo", "b.go", 2)), []string{"a2", "u3", "e2"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("FileExcerpt(b.go, 2) = %v, want %v", got, want)
+	}
+	if got := FileExcerpt(entries, nil, "/repo", "c.go", 2); got != nil {
+		t.Errorf("FileExcerpt(c.go) = %v, want nil", uuids(got))
+	}
+}
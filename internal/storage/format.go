@@ -1,7 +1,9 @@
 package storage
 
 import (
+	"bytes"
 	"encoding/json"
+	"fmt"
 	"time"
 )
 
@@ -10,7 +12,23 @@ import (
 //   - 1: initial format (agent field added later with omitempty for compat)
 //   - 2: added model field for tracking the AI model used
 //   - 3: added effort field for tracking turns and token usage
-const NoteFormatVersion = 3
+//   - 4: added files_touched field listing files edited by the agent
+//   - 5: added ai_assisted and authorship fields for AI-authored line ratios
+//   - 6: added summary field with a short human-readable summary
+//   - 7: added tags field with user-assigned labels
+//   - 8: added signature field with an optional signature by the storing user
+//   - 9: a note may hold an array of conversations, one per agent session
+//     that contributed to the commit
+//   - 10: added provenance field with the agent CLI version, the models
+//     used and what triggered storing the conversation
+//   - 11: added duration_seconds and tool_seconds to the effort field
+//   - 12: the transcript may be left out of the note, transcript then being
+//     empty: transcript_blob names a git blob holding it compressed, or
+//     transcript_chunks the git blobs of its content-defined chunks. It may
+//     also be zstd compressed, with a dictionary from
+//     refs/notes/shiftlog-dictionaries. Added the visibility, issues and
+//     thinking fields
+const NoteFormatVersion = 12
 
 // Effort captures quantified AI effort metrics for a commit.
 type Effort struct {
@@ -19,6 +37,8 @@ type Effort struct {
 	OutputTokens             int64 `json:"output_tokens,omitempty"`
 	CacheCreationInputTokens int64 `json:"cache_creation_input_tokens,omitempty"`
 	CacheReadInputTokens     int64 `json:"cache_read_input_tokens,omitempty"`
+	DurationSeconds          int64 `json:"duration_seconds,omitempty"` // wall-clock time of the session since the previous commit
+	ToolSeconds              int64 `json:"tool_seconds,omitempty"`     // part of DurationSeconds spent running tools
 }
 
 // TotalTokens returns the sum of input and output tokens, nil-safe.
@@ -29,19 +49,68 @@ func (e *Effort) TotalTokens() int64 {
 	return e.InputTokens + e.OutputTokens
 }
 
+// Triggers of storing a conversation, recorded in Provenance.Trigger.
+const (
+	TriggerAgentHook  = "agent-hook"  // the agent's post-tool hook saw it run git commit
+	TriggerPostCommit = "post-commit" // the git post-commit hook discovered the active session
+	TriggerAttach     = "attach"      // a user attached the session with shiftlog attach
+	TriggerWatch      = "watch"       // shiftlog watch checkpointed the session before the commit
+	TriggerCheckpoint = "checkpoint"  // a user checkpointed the session and promoted it to the commit
+	TriggerAPI        = "api"         // a client posted the conversation to the HTTP API of shiftlog serve
+	TriggerBackfill   = "backfill"    // shiftlog backfill matched an old session to the commit
+	TriggerImport     = "import"      // shiftlog import converted another tool's log of the conversation
+)
+
+// Provenance records which agent, agent version and models produced a
+// conversation, and how it came to be stored.
+type Provenance struct {
+	Agent        string       `json:"agent"`
+	AgentVersion string       `json:"agent_version,omitempty"` // agent CLI version, from the transcript or detected at store time
+	Models       []ModelUsage `json:"models,omitempty"`        // models of the assistant messages, in order of first use
+	Trigger      string       `json:"trigger,omitempty"`       // one of the Trigger constants
+}
+
+// ModelUsage is the number of assistant messages produced by a model.
+type ModelUsage struct {
+	Model    string `json:"model"`
+	Messages int    `json:"messages"`
+}
+
 // StoredConversation represents the format stored in git notes
 type StoredConversation struct {
-	Version      int    `json:"version"`
-	SessionID    string `json:"session_id"`
-	Timestamp    string `json:"timestamp"`
-	ProjectPath  string `json:"project_path"`
-	GitBranch    string `json:"git_branch"`
-	MessageCount int    `json:"message_count"`
-	Checksum     string `json:"checksum"`
-	Transcript   string `json:"transcript"`        // base64-encoded gzipped JSONL
-	Agent        string  `json:"agent,omitempty"`    // coding agent name (empty = "claude" for backward compat)
-	Model        string  `json:"model,omitempty"`    // AI model identifier (e.g. "claude-sonnet-4-5-20250514")
-	Effort       *Effort `json:"effort,omitempty"`   // AI effort metrics (turns, tokens)
+	Version      int         `json:"version"`
+	SessionID    string      `json:"session_id"`
+	Timestamp    string      `json:"timestamp"`
+	ProjectPath  string      `json:"project_path"`
+	GitBranch    string      `json:"git_branch"`
+	MessageCount int         `json:"message_count"`
+	Checksum     string      `json:"checksum"`
+	Transcript   string      `json:"transcript"`              // base64-encoded JSONL, gzip or zstd compressed
+	Agent        string      `json:"agent,omitempty"`         // coding agent name (empty = "claude" for backward compat)
+	Model        string      `json:"model,omitempty"`         // AI model identifier (e.g. "claude-sonnet-4-5-20250514")
+	Effort       *Effort     `json:"effort,omitempty"`        // AI effort metrics (turns, tokens)
+	FilesTouched []string    `json:"files_touched,omitempty"` // repo-relative paths edited by the agent since the previous commit
+	AIAssisted   bool        `json:"ai_assisted,omitempty"`   // true when any line of the commit was written by the agent
+	Authorship   *Authorship `json:"authorship,omitempty"`    // agent vs manual share of the commit's added lines
+	Summary      string      `json:"summary,omitempty"`       // 2-3 sentence summary, when enabled or backfilled
+	Tags         []string    `json:"tags,omitempty"`          // user-assigned labels, sorted and unique
+	Issues       []string    `json:"issues,omitempty"`        // issue keys of the commit message, sorted and unique
+	Signature    *Signature  `json:"signature,omitempty"`     // signature over SigningPayload, when signing is enabled
+	Provenance   *Provenance `json:"provenance,omitempty"`    // agent version, models and store trigger
+	Visibility   string      `json:"visibility,omitempty"`    // config.VisibilityPrivate when kept in git.PrivateRef, empty when shared
+	Thinking     string      `json:"thinking,omitempty"`      // config.ThinkingStrip or ThinkingHash when the thinking policy left thinking out
+
+	// TranscriptBlob is the git blob of the compressed transcript when it is
+	// stored apart from the note, Transcript then being empty.
+	TranscriptBlob string `json:"transcript_blob,omitempty"`
+	// TranscriptChunks are the git blobs of the uncompressed transcript, in
+	// order, when it is stored in content-defined chunks, Transcript then
+	// being empty.
+	TranscriptChunks []string `json:"transcript_chunks,omitempty"`
+
+	// ContributorRef is the contributor notes ref the conversation was read
+	// from, empty for shared and private conversations. It is not stored.
+	ContributorRef string `json:"-"`
 }
 
 // NewStoredConversation creates a new StoredConversation from transcript data
@@ -70,18 +139,120 @@ func (sc *StoredConversation) Marshal() ([]byte, error) {
 	return json.MarshalIndent(sc, "", "  ")
 }
 
-// UnmarshalStoredConversation deserializes a stored conversation from JSON
+// UnmarshalStoredConversation deserializes a stored conversation from JSON.
+// For a note holding several conversations it returns the first stored.
 func UnmarshalStoredConversation(data []byte) (*StoredConversation, error) {
+	conversations, err := UnmarshalStoredConversations(data)
+	if err != nil {
+		return nil, err
+	}
+	return conversations[0], nil
+}
+
+// UnmarshalStoredConversations deserializes every conversation of a note: a
+// single conversation object, or an array of them when several agent
+// sessions contributed to the commit.
+func UnmarshalStoredConversations(data []byte) ([]*StoredConversation, error) {
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '[' {
+		var conversations []*StoredConversation
+		if err := json.Unmarshal(trimmed, &conversations); err != nil {
+			return nil, err
+		}
+		if len(conversations) == 0 {
+			return nil, fmt.Errorf("note holds no conversations")
+		}
+		for _, sc := range conversations {
+			if sc == nil {
+				return nil, fmt.Errorf("note holds a null conversation")
+			}
+		}
+		return conversations, nil
+	}
+
 	var sc StoredConversation
 	if err := json.Unmarshal(data, &sc); err != nil {
 		return nil, err
 	}
-	return &sc, nil
+	return []*StoredConversation{&sc}, nil
+}
+
+// MarshalStoredConversations serializes the conversations of a note. A
+// single conversation is written as an object, as before format version 9.
+func MarshalStoredConversations(conversations []*StoredConversation) ([]byte, error) {
+	switch len(conversations) {
+	case 0:
+		return nil, fmt.Errorf("no conversations to store")
+	case 1:
+		return conversations[0].Marshal()
+	}
+	return json.MarshalIndent(conversations, "", "  ")
+}
+
+// AgentName returns the name of the agent the conversation was stored by.
+func (sc *StoredConversation) AgentName() string {
+	if sc.Agent == "" {
+		return "claude"
+	}
+	return sc.Agent
+}
+
+// GetProvenance returns the conversation's provenance. Conversations stored
+// before provenance was recorded get one derived from their agent and model
+// fields.
+func (sc *StoredConversation) GetProvenance() *Provenance {
+	if sc.Provenance != nil {
+		return sc.Provenance
+	}
+	p := &Provenance{Agent: sc.AgentName()}
+	if sc.Model != "" {
+		p.Models = []ModelUsage{{Model: sc.Model}}
+	}
+	return p
+}
+
+// SameSession reports whether two stored conversations are of the same
+// agent session.
+func (sc *StoredConversation) SameSession(other *StoredConversation) bool {
+	return sc.SessionID == other.SessionID && sc.AgentName() == other.AgentName()
 }
 
-// GetTranscript decompresses and returns the original transcript data
+// IndexOfSession returns the index of the conversation of the given agent
+// session, or -1.
+func IndexOfSession(conversations []*StoredConversation, sc *StoredConversation) int {
+	for i, c := range conversations {
+		if c.SameSession(sc) {
+			return i
+		}
+	}
+	return -1
+}
+
+// GetTranscript decompresses and returns the original transcript data,
+// reading it from its blob or chunks when it is stored apart from the note
 func (sc *StoredConversation) GetTranscript() ([]byte, error) {
-	return DecodeAndDecompress(sc.Transcript)
+	if sc.IsChunked() {
+		return readTranscriptChunks(sc.TranscriptChunks)
+	}
+	compressed, err := sc.CompressedTranscript()
+	if err != nil {
+		return nil, err
+	}
+	return Decompress(compressed)
+}
+
+// SetTranscript replaces the transcript of sc and its checksum, storing it
+// as configured: compressed in the note, or apart from it. A signature of
+// sc, which covers the old checksum, no longer verifies.
+func (sc *StoredConversation) SetTranscript(transcript []byte) error {
+	encoded, err := CompressAndEncode(transcript)
+	if err != nil {
+		return err
+	}
+	sc.Transcript = encoded
+	sc.TranscriptBlob = ""
+	sc.TranscriptChunks = nil
+	sc.Checksum = Checksum(transcript)
+	return sc.StoreTranscriptApart()
 }
 
 // VerifyIntegrity checks if the transcript matches the stored checksum
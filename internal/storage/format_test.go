@@ -381,3 +381,85 @@ func TestEffortOmittedWhenNil(t *testing.T) {
 		t.Error("JSON should not contain 'effort' key when Effort is nil")
 	}
 }
+
+func TestMarshalUnmarshalConversations(t *testing.T) {
+	first, err := NewStoredConversation("session-1", "/test", "main", 1, []byte(`{"uuid":"1","type":"user"}`))
+	if err != nil {
+		t.Fatalf("NewStoredConversation() error: %v", err)
+	}
+	second, err := NewStoredConversation("session-2", "/test", "main", 1, []byte(`{"uuid":"2","type":"user"}`))
+	if err != nil {
+		t.Fatalf("NewStoredConversation() error: %v", err)
+	}
+	second.Agent = "codex"
+
+	// A single conversation is still stored as an object
+	data, err := MarshalStoredConversations([]*StoredConversation{first})
+	if err != nil {
+		t.Fatalf("MarshalStoredConversations() error: %v", err)
+	}
+	if !strings.HasPrefix(string(data), "{") {
+		t.Errorf("single conversation marshaled as %q, want an object", data[:1])
+	}
+
+	data, err = MarshalStoredConversations([]*StoredConversation{first, second})
+	if err != nil {
+		t.Fatalf("MarshalStoredConversations() error: %v", err)
+	}
+	list, err := UnmarshalStoredConversations(data)
+	if err != nil {
+		t.Fatalf("UnmarshalStoredConversations() error: %v", err)
+	}
+	if len(list) != 2 || list[0].SessionID != "session-1" || list[1].AgentName() != "codex" {
+		t.Fatalf("UnmarshalStoredConversations() = %+v", list)
+	}
+
+	// Legacy readers get the first conversation
+	sc, err := UnmarshalStoredConversation(data)
+	if err != nil {
+		t.Fatalf("UnmarshalStoredConversation() error: %v", err)
+	}
+	if sc.SessionID != "session-1" {
+		t.Errorf("SessionID = %q, want session-1", sc.SessionID)
+	}
+
+	if _, err := UnmarshalStoredConversations([]byte(`[]`)); err == nil {
+		t.Error("UnmarshalStoredConversations([]) should fail")
+	}
+	if _, err := MarshalStoredConversations(nil); err == nil {
+		t.Error("MarshalStoredConversations(nil) should fail")
+	}
+}
+
+func TestIndexOfSession(t *testing.T) {
+	list := []*StoredConversation{
+		{SessionID: "s1"},
+		{SessionID: "s1", Agent: "codex"},
+	}
+	if i := IndexOfSession(list, &StoredConversation{SessionID: "s1", Agent: "claude"}); i != 0 {
+		t.Errorf("IndexOfSession(claude s1) = %d, want 0", i)
+	}
+	if i := IndexOfSession(list, &StoredConversation{SessionID: "s1", Agent: "codex"}); i != 1 {
+		t.Errorf("IndexOfSession(codex s1) = %d, want 1", i)
+	}
+	if i := IndexOfSession(list, &StoredConversation{SessionID: "s2"}); i != -1 {
+		t.Errorf("IndexOfSession(s2) = %d, want -1", i)
+	}
+}
+
+func TestGetProvenance(t *testing.T) {
+	recorded := &Provenance{Agent: "codex", AgentVersion: "0.46.0", Trigger: TriggerAgentHook}
+	sc := &StoredConversation{Agent: "codex", Model: "gpt-5-codex", Provenance: recorded}
+	if sc.GetProvenance() != recorded {
+		t.Errorf("GetProvenance() = %+v, want the recorded provenance", sc.GetProvenance())
+	}
+
+	// Notes stored before format version 10
+	legacy := (&StoredConversation{Model: "claude-sonnet-4-5-20250514"}).GetProvenance()
+	if legacy.Agent != "claude" || len(legacy.Models) != 1 || legacy.Models[0].Model != "claude-sonnet-4-5-20250514" {
+		t.Errorf("legacy GetProvenance() = %+v", legacy)
+	}
+	if p := (&StoredConversation{Agent: "goose"}).GetProvenance(); p.Agent != "goose" || p.Models != nil {
+		t.Errorf("GetProvenance() without model = %+v", p)
+	}
+}
@@ -0,0 +1,630 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/re-cinq/shift-log/internal/agent"
+	"github.com/re-cinq/shift-log/internal/git"
+	bolt "go.etcd.io/bbolt"
+)
+
+// The index keeps what search, stats, log and the web server read of every
+// stored conversation, so that they need not read, decompress and parse
+// every note each time: the conversations' metadata without their
+// transcripts, the number of entries of each transcript, the terms of its
+// searchable text and the errors its tools reported. It is saved in a bolt
+// database in git.ShiftlogDir, shared by the worktrees of the clone, with
+// the tip of each notes ref it was built at. When a tip has moved, by a
+// store, a sync or any other note write, only the notes that changed since
+// are read again, and only their records are written.
+//
+// The database has a bucket per notes ref indexed, named indexShared,
+// indexPrivate or after the contributor ref, holding the tip under
+// indexTipKey and, in its indexCommitsBucket, the gob-encoded
+// conversations of each commit keyed by its SHA. indexMetaBucket holds the
+// version and the order of the contributor refs.
+
+// indexVersion is the version of the saved index. An index saved with
+// another version is rebuilt.
+const indexVersion = 4
+
+// indexFile is the name of the saved index in git.ShiftlogDir.
+const indexFile = "index"
+
+// indexLockTimeout is how long a command waits for another to finish
+// writing the index before leaving the update to the next reader.
+const indexLockTimeout = 2 * time.Second
+
+var (
+	indexMetaBucket    = []byte("meta")
+	indexVersionKey    = []byte("version")
+	indexContributed   = []byte("contributed")
+	indexShared        = []byte("shared")
+	indexPrivate       = []byte("private")
+	indexTipKey        = []byte("tip")
+	indexCommitsBucket = []byte("commits")
+)
+
+// indexMaxTerm is the longest term recorded. A transcript with a longer
+// word, such as base64 data, is searched without the help of its terms.
+const indexMaxTerm = 64
+
+// Index indexes the shared and private conversations of the clone, and
+// those of the contributor refs it reads.
+type Index struct {
+	Version int
+	Shared  RefIndex
+	Private RefIndex
+	// Contributed index the contributor refs, in the order of read_refs.
+	Contributed []RefIndex
+}
+
+// RefIndex indexes the conversations stored in one notes ref.
+type RefIndex struct {
+	// Ref is the notes ref indexed, set for contributor refs.
+	Ref string
+	// Tip is the commit of the notes ref indexed, empty when the
+	// conversations are kept by another backend than git notes.
+	Tip string
+	// Commits are the conversations of each commit, in their note's order.
+	Commits map[string][]*IndexedConversation
+}
+
+// IndexedConversation is a stored conversation as the index records it.
+type IndexedConversation struct {
+	// Conversation is the stored conversation without its transcript. It
+	// is shared by every reader of the index and must not be modified.
+	Conversation *StoredConversation
+	// Entries is the number of entries of the transcript.
+	Entries int
+	// Terms are the distinct words of the transcript's searchable text,
+	// lowercased and sorted. They are only complete when FullText is set.
+	Terms    []string
+	FullText bool
+	// Errors are the lines of the transcript's tool results that report
+	// an error, as toolErrors returns them. They are only recorded when
+	// Entries is.
+	Errors []string
+}
+
+// IndexObserver, when set, is called whenever the index is opened, with
+// whether it was up to date or had notes to read again, e.g. to measure
+// how often it is.
+var IndexObserver func(upToDate bool)
+
+var (
+	indexMu       sync.Mutex
+	openIndex     *Index
+	openIndexPath string
+)
+
+// OpenIndex returns the index of the clone's conversations, brought up to
+// date with the notes refs and saved again if they moved. The index is kept
+// in memory between calls, which only check the tips of the notes refs.
+// The notes that changed are parsed Concurrency at a time, until ctx is
+// done.
+func OpenIndex(ctx context.Context) (*Index, error) {
+	path, err := indexPath()
+	if err != nil {
+		return nil, err
+	}
+	indexMu.Lock()
+	defer indexMu.Unlock()
+
+	ix := openIndex
+	if ix == nil || openIndexPath != path {
+		// An unreadable index is rebuilt
+		ix = loadIndex(path)
+	}
+	updated, changed, err := ix.refresh(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if IndexObserver != nil {
+		IndexObserver(!changed)
+	}
+	if changed {
+		err := updated.save(path, ix)
+		if errors.Is(err, bolt.ErrTimeout) {
+			// Another command is writing it; the next reader catches up
+			err = nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("could not save the index: %w", err)
+		}
+	}
+	openIndex, openIndexPath = updated, path
+	return updated, nil
+}
+
+// UpdateIndex brings the saved index up to date, as after storing or
+// syncing conversations, so that the next command reading it need not. An
+// index never saved is left for the first reader to build: building it
+// reads every note, which a git hook should not wait for.
+func UpdateIndex() error {
+	path, err := indexPath()
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(path); err != nil {
+		return nil
+	}
+	_, err = OpenIndex(context.Background())
+	return err
+}
+
+// Conversations returns the shared conversations of a commit followed by
+// those of the contributor refs, without their transcripts, or nil if it
+// has none.
+func (ix *Index) Conversations(commitSHA string) []*StoredConversation {
+	shared := conversationsOf(ix.Shared.Commits[commitSHA])
+	var contributed []*StoredConversation
+	for _, ri := range ix.Contributed {
+		contributed = appendNewSessions(contributed, shared, conversationsOf(ri.Commits[commitSHA]))
+	}
+	return append(shared, contributed...)
+}
+
+// ConversationsWithPrivate returns the conversations of a commit, as
+// Conversations does, followed by its private ones, without their
+// transcripts.
+func (ix *Index) ConversationsWithPrivate(commitSHA string) []*StoredConversation {
+	return append(ix.Conversations(commitSHA), conversationsOf(ix.Private.Commits[commitSHA])...)
+}
+
+func conversationsOf(indexed []*IndexedConversation) []*StoredConversation {
+	if len(indexed) == 0 {
+		return nil
+	}
+	conversations := make([]*StoredConversation, len(indexed))
+	for i, ic := range indexed {
+		conversations[i] = ic.Conversation
+	}
+	return conversations
+}
+
+// MayContain reports whether the transcript may contain every one of
+// words, as searchTerms returns them: it does not when one of them is part
+// of none of its terms.
+func (ic *IndexedConversation) MayContain(words []string) bool {
+	if !ic.FullText {
+		return true
+	}
+	for _, word := range words {
+		found := false
+		for _, term := range ic.Terms {
+			if strings.Contains(term, word) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// searchTerms returns the words of text the way the index records them.
+func searchTerms(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+func indexPath() (string, error) {
+	dir, err := git.ShiftlogDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, indexFile), nil
+}
+
+// loadIndex reads the index saved at path, returning an empty one if it is
+// missing, unreadable or of another version.
+func loadIndex(path string) *Index {
+	empty := &Index{Version: indexVersion}
+	if _, err := os.Stat(path); err != nil {
+		return empty
+	}
+	db, err := bolt.Open(path, 0644, &bolt.Options{ReadOnly: true, Timeout: indexLockTimeout})
+	if err != nil {
+		return empty
+	}
+	defer func() { _ = db.Close() }()
+
+	ix := &Index{Version: indexVersion}
+	err = db.View(func(tx *bolt.Tx) error {
+		meta := tx.Bucket(indexMetaBucket)
+		if meta == nil || string(meta.Get(indexVersionKey)) != strconv.Itoa(indexVersion) {
+			return errors.New("index of another version")
+		}
+		var err error
+		if ix.Shared, err = readRefIndex(tx.Bucket(indexShared)); err != nil {
+			return err
+		}
+		if ix.Private, err = readRefIndex(tx.Bucket(indexPrivate)); err != nil {
+			return err
+		}
+		for _, ref := range contributedRefs(meta) {
+			ri, err := readRefIndex(tx.Bucket([]byte(ref)))
+			if err != nil {
+				return err
+			}
+			ri.Ref = ref
+			ix.Contributed = append(ix.Contributed, ri)
+		}
+		return nil
+	})
+	if err != nil {
+		return empty
+	}
+	return ix
+}
+
+// readRefIndex reads the index of a notes ref from its bucket, which may be
+// missing.
+func readRefIndex(b *bolt.Bucket) (RefIndex, error) {
+	ri := RefIndex{Commits: make(map[string][]*IndexedConversation)}
+	if b == nil {
+		return ri, nil
+	}
+	ri.Tip = string(b.Get(indexTipKey))
+	commits := b.Bucket(indexCommitsBucket)
+	if commits == nil {
+		return ri, nil
+	}
+	err := commits.ForEach(func(k, v []byte) error {
+		var indexed []*IndexedConversation
+		if err := gob.NewDecoder(bytes.NewReader(v)).Decode(&indexed); err != nil {
+			return err
+		}
+		ri.Commits[string(k)] = indexed
+		return nil
+	})
+	return ri, err
+}
+
+// contributedRefs returns the contributor refs of the saved index, in the
+// order of read_refs.
+func contributedRefs(meta *bolt.Bucket) []string {
+	if list := string(meta.Get(indexContributed)); list != "" {
+		return strings.Split(list, "\n")
+	}
+	return nil
+}
+
+// save writes the index to path, as refreshed from previous: only the
+// commits whose conversations changed since are written, in a single
+// transaction so that concurrent readers see either index whole. A database
+// that cannot be opened is replaced.
+func (ix *Index) save(path string, previous *Index) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	db, err := bolt.Open(path, 0644, &bolt.Options{Timeout: indexLockTimeout})
+	if errors.Is(err, bolt.ErrInvalid) || errors.Is(err, bolt.ErrVersionMismatch) || errors.Is(err, bolt.ErrChecksum) {
+		// Not an index this version can write, e.g. one saved before
+		// the index was a database
+		if err := os.Remove(path); err != nil {
+			return err
+		}
+		db, err = bolt.Open(path, 0644, &bolt.Options{Timeout: indexLockTimeout})
+	}
+	if err != nil {
+		return err
+	}
+	defer func() { _ = db.Close() }()
+
+	return db.Update(func(tx *bolt.Tx) error {
+		meta := tx.Bucket(indexMetaBucket)
+		if meta != nil && string(meta.Get(indexVersionKey)) != strconv.Itoa(indexVersion) {
+			// Written by another version: start over
+			var names [][]byte
+			if err := tx.ForEach(func(name []byte, _ *bolt.Bucket) error {
+				names = append(names, append([]byte(nil), name...))
+				return nil
+			}); err != nil {
+				return err
+			}
+			for _, name := range names {
+				if err := tx.DeleteBucket(name); err != nil {
+					return err
+				}
+			}
+			meta = nil
+		}
+		if meta == nil {
+			var err error
+			if meta, err = tx.CreateBucket(indexMetaBucket); err != nil {
+				return err
+			}
+		}
+
+		if err := writeRefIndex(tx, indexShared, previous.Shared, ix.Shared); err != nil {
+			return err
+		}
+		if err := writeRefIndex(tx, indexPrivate, previous.Private, ix.Private); err != nil {
+			return err
+		}
+		kept := make(map[string]bool, len(ix.Contributed))
+		refs := make([]string, 0, len(ix.Contributed))
+		for _, ri := range ix.Contributed {
+			var old RefIndex
+			for _, p := range previous.Contributed {
+				if p.Ref == ri.Ref {
+					old = p
+				}
+			}
+			if err := writeRefIndex(tx, []byte(ri.Ref), old, ri); err != nil {
+				return err
+			}
+			kept[ri.Ref] = true
+			refs = append(refs, ri.Ref)
+		}
+		for _, ref := range contributedRefs(meta) {
+			if kept[ref] || tx.Bucket([]byte(ref)) == nil {
+				continue
+			}
+			if err := tx.DeleteBucket([]byte(ref)); err != nil {
+				return err
+			}
+		}
+		if err := meta.Put(indexContributed, []byte(strings.Join(refs, "\n"))); err != nil {
+			return err
+		}
+		return meta.Put(indexVersionKey, []byte(strconv.Itoa(indexVersion)))
+	})
+}
+
+// writeRefIndex writes the index of a notes ref to its bucket, as refreshed
+// from old: the commits whose conversations are not those of old are
+// written and those no longer indexed deleted. The bucket is written whole
+// when it was not saved from old, e.g. when another command updated it
+// since old was read.
+func writeRefIndex(tx *bolt.Tx, name []byte, old, ri RefIndex) error {
+	b, err := tx.CreateBucketIfNotExists(name)
+	if err != nil {
+		return err
+	}
+	if string(b.Get(indexTipKey)) != old.Tip || b.Bucket(indexCommitsBucket) == nil {
+		if b.Bucket(indexCommitsBucket) != nil {
+			if err := b.DeleteBucket(indexCommitsBucket); err != nil {
+				return err
+			}
+		}
+		if _, err := b.CreateBucket(indexCommitsBucket); err != nil {
+			return err
+		}
+		old = RefIndex{}
+	}
+	commits := b.Bucket(indexCommitsBucket)
+
+	for commit := range old.Commits {
+		if _, ok := ri.Commits[commit]; !ok {
+			if err := commits.Delete([]byte(commit)); err != nil {
+				return err
+			}
+		}
+	}
+	for commit, indexed := range ri.Commits {
+		if sameIndexed(old.Commits[commit], indexed) {
+			continue
+		}
+		var buf bytes.Buffer
+		if err := gob.NewEncoder(&buf).Encode(indexed); err != nil {
+			return err
+		}
+		if err := commits.Put([]byte(commit), buf.Bytes()); err != nil {
+			return err
+		}
+	}
+	return b.Put(indexTipKey, []byte(ri.Tip))
+}
+
+// sameIndexed reports whether a and b are the same records of a commit's
+// conversations, as refresh carries over those of the notes that did not
+// change.
+func sameIndexed(a, b []*IndexedConversation) bool {
+	return len(a) > 0 && len(a) == len(b) && a[0] == b[0]
+}
+
+// refresh returns the index brought up to date with the notes refs, and
+// whether that changed it. ix itself is left as it is for its readers.
+func (ix *Index) refresh(ctx context.Context) (*Index, bool, error) {
+	b, err := ActiveBackend()
+	if err != nil {
+		return nil, false, err
+	}
+	updated := &Index{Version: indexVersion}
+	changed := false
+	if b.Name() == DefaultBackend {
+		var sharedChanged bool
+		if updated.Shared, sharedChanged, err = refreshRefIndex(ctx, ix.Shared, git.NotesRef); err != nil {
+			return nil, false, err
+		}
+		changed = sharedChanged
+	} else {
+		// Other backends have no tip to tell what changed: their
+		// conversations are indexed anew, without their terms
+		contents, err := ReadAllConversations()
+		if err != nil {
+			return nil, false, err
+		}
+		updated.Shared = RefIndex{Commits: make(map[string][]*IndexedConversation, len(contents))}
+		for commit, content := range contents {
+			if conversations, err := UnmarshalStoredConversations(content); err == nil {
+				updated.Shared.Commits[commit] = indexConversations(conversations, false)
+			}
+		}
+	}
+	privateChanged := false
+	if updated.Private, privateChanged, err = refreshRefIndex(ctx, ix.Private, git.PrivateRef); err != nil {
+		return nil, false, err
+	}
+	changed = changed || privateChanged
+
+	refs, err := ContributorRefs()
+	if err != nil {
+		return nil, false, err
+	}
+	// Refs added to or removed from read_refs change the index too
+	changed = changed || len(refs) != len(ix.Contributed)
+	for i, ref := range refs {
+		var old RefIndex
+		for _, ri := range ix.Contributed {
+			if ri.Ref == ref {
+				old = ri
+			}
+		}
+		changed = changed || i >= len(ix.Contributed) || ix.Contributed[i].Ref != ref
+		contributed, refChanged, err := refreshRefIndex(ctx, old, ref)
+		if err != nil {
+			return nil, false, err
+		}
+		contributed.Ref = ref
+		updated.Contributed = append(updated.Contributed, contributed)
+		changed = changed || refChanged
+	}
+	return updated, changed, nil
+}
+
+// refreshRefIndex returns old brought up to date with the notes of ref,
+// and whether it changed, reading only the notes that changed since the
+// tip old was built at.
+func refreshRefIndex(ctx context.Context, old RefIndex, ref string) (RefIndex, bool, error) {
+	tip, err := git.NotesRefCommit(ref)
+	if err != nil {
+		return old, false, fmt.Errorf("could not resolve %s: %w", ref, err)
+	}
+	if tip == old.Tip {
+		return old, false, nil
+	}
+	updated := RefIndex{Tip: tip, Commits: make(map[string][]*IndexedConversation)}
+	if tip == "" {
+		return updated, true, nil
+	}
+
+	blobs, err := git.ListNoteBlobs(ref)
+	if err != nil {
+		return old, false, fmt.Errorf("could not list %s: %w", ref, err)
+	}
+	var changed []string
+	full := old.Tip == "" || !git.HasObject(old.Tip)
+	if !full {
+		changed, err = git.ChangedNotes(old.Tip, tip)
+		full = err != nil
+	}
+	if full {
+		// Built at a tip that is gone, or never built: index every note
+		changed = make([]string, 0, len(blobs))
+		for commit := range blobs {
+			changed = append(changed, commit)
+		}
+	} else {
+		for commit, conversations := range old.Commits {
+			updated.Commits[commit] = conversations
+		}
+	}
+
+	var read []string
+	for _, commit := range changed {
+		if blob, ok := blobs[commit]; ok {
+			read = append(read, blob)
+		}
+	}
+	contents, err := git.ReadBlobs(read)
+	if err != nil {
+		return old, false, fmt.Errorf("could not read %s: %w", ref, err)
+	}
+	indexed := make([][]*IndexedConversation, len(changed))
+	err = ForEachParallel(ctx, len(changed), func(i int) error {
+		// A note removed, or not a conversation note, is left out
+		if conversations, err := UnmarshalStoredConversations(contents[blobs[changed[i]]]); err == nil {
+			if ref != git.NotesRef && ref != git.PrivateRef {
+				for _, sc := range conversations {
+					sc.ContributorRef = ref
+				}
+			}
+			indexed[i] = indexConversations(conversations, true)
+		}
+		return nil
+	})
+	if err != nil {
+		return old, false, err
+	}
+	if NoteObserver != nil {
+		for _, content := range contents {
+			NoteObserver(len(content))
+		}
+	}
+	for i, commit := range changed {
+		delete(updated.Commits, commit)
+		if indexed[i] != nil {
+			updated.Commits[commit] = indexed[i]
+		}
+	}
+	return updated, true, nil
+}
+
+// indexConversations indexes the conversations of a note, with the terms
+// and tool errors of their transcripts when fullText is set.
+func indexConversations(conversations []*StoredConversation, fullText bool) []*IndexedConversation {
+	indexed := make([]*IndexedConversation, len(conversations))
+	for i, sc := range conversations {
+		ic := &IndexedConversation{}
+		if fullText {
+			if transcript, err := sc.ParseTranscript(); err == nil {
+				ic.Entries = len(transcript.Entries)
+				ic.Terms, ic.FullText = transcriptTerms(transcript.Entries)
+				ic.Errors = toolErrors(transcript.Entries)
+			}
+		}
+		meta := *sc
+		meta.Transcript, meta.TranscriptBlob, meta.TranscriptChunks = "", "", nil
+		ic.Conversation = &meta
+		indexed[i] = ic
+	}
+	return indexed
+}
+
+// transcriptTerms returns the distinct terms of the text search looks into
+// in entries, sorted, and whether none was left out for being longer than
+// indexMaxTerm.
+func transcriptTerms(entries []agent.TranscriptEntry) ([]string, bool) {
+	seen := make(map[string]bool)
+	complete := true
+	for _, entry := range entries {
+		if entry.Message == nil {
+			continue
+		}
+		for _, block := range entry.Message.Content {
+			text, _, _ := blockSearchText(block)
+			for _, term := range searchTerms(text) {
+				if len(term) > indexMaxTerm {
+					complete = false
+					continue
+				}
+				seen[term] = true
+			}
+		}
+	}
+	terms := make([]string, 0, len(seen))
+	for term := range seen {
+		terms = append(terms, term)
+	}
+	sort.Strings(terms)
+	return terms, complete
+}
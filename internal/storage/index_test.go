@@ -0,0 +1,239 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"encoding/gob"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/re-cinq/shift-log/internal/agent"
+	"github.com/re-cinq/shift-log/internal/git"
+	bolt "go.etcd.io/bbolt"
+)
+
+func TestSearchTerms(t *testing.T) {
+	got := searchTerms("Run `go test ./...` in the Upload-Client, 2 times")
+	want := []string{"run", "go", "test", "in", "the", "upload", "client", "2", "times"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("searchTerms() = %q, want %q", got, want)
+	}
+}
+
+func TestTranscriptTerms(t *testing.T) {
+	entries := []agent.TranscriptEntry{
+		{Type: agent.MessageTypeUser, Message: &agent.Message{Content: []agent.ContentBlock{
+			{Type: "text", Text: "Fix the retry logic"},
+		}}},
+		{Type: agent.MessageTypeAssistant, Message: &agent.Message{Content: []agent.ContentBlock{
+			{Type: "tool_use", Name: "Bash", Text: "go test"},
+		}}},
+		{Type: agent.MessageTypeSystem},
+	}
+	terms, complete := transcriptTerms(entries)
+	if !complete {
+		t.Error("transcriptTerms() left terms out")
+	}
+	ic := &IndexedConversation{Terms: terms, FullText: complete}
+	for _, query := range []string{"retry", "Retry Logic", "bash", "etr", "go test"} {
+		if !ic.MayContain(searchTerms(query)) {
+			t.Errorf("MayContain(%q) = false, want true", query)
+		}
+	}
+	for _, query := range []string{"upload", "retry upload"} {
+		if ic.MayContain(searchTerms(query)) {
+			t.Errorf("MayContain(%q) = true, want false", query)
+		}
+	}
+
+	long := []agent.TranscriptEntry{{Type: agent.MessageTypeUser, Message: &agent.Message{Content: []agent.ContentBlock{
+		{Type: "text", Text: strings.Repeat("a", indexMaxTerm+1)},
+	}}}}
+	if _, complete := transcriptTerms(long); complete {
+		t.Error("transcriptTerms() of an overlong word reported complete terms")
+	}
+}
+
+func TestIndexRefresh(t *testing.T) {
+	chdirScratchRepo(t)
+	backendMu.Lock()
+	previous := activeBackend
+	activeBackend = GitNotesBackend{}
+	backendMu.Unlock()
+	t.Cleanup(func() {
+		backendMu.Lock()
+		activeBackend = previous
+		backendMu.Unlock()
+	})
+
+	var commits []string
+	for _, message := range []string{"first", "second"} {
+		if out, err := exec.Command("git", "commit", "-q", "--allow-empty", "-m", message).CombinedOutput(); err != nil {
+			t.Fatalf("git commit: %v: %s", err, out)
+		}
+		sha, err := git.GetHeadCommit()
+		if err != nil {
+			t.Fatal(err)
+		}
+		commits = append(commits, sha)
+	}
+	store := func(commit, sessionID, text string) {
+		t.Helper()
+		transcript := `{"type":"user","uuid":"u1","message":{"role":"user","content":"` + text + `"}}` + "\n"
+		sc, err := NewStoredConversation(sessionID, "/project", "main", 1, []byte(transcript))
+		if err != nil {
+			t.Fatal(err)
+		}
+		content, err := MarshalStoredConversations([]*StoredConversation{sc})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := git.AddNote(commit, content); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	store(commits[0], "s1", "fix the retry logic")
+	ix, err := OpenIndex(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	indexed := ix.Shared.Commits[commits[0]]
+	if len(indexed) != 1 || indexed[0].Conversation.SessionID != "s1" || indexed[0].Entries != 1 {
+		t.Fatalf("index of %s = %+v", commits[0], indexed)
+	}
+	if indexed[0].Conversation.Transcript != "" {
+		t.Error("the index kept the transcript")
+	}
+	if !indexed[0].MayContain(searchTerms("retry")) || indexed[0].MayContain(searchTerms("upload")) {
+		t.Errorf("terms of %s = %q", commits[0], indexed[0].Terms)
+	}
+
+	// Only the note that changed is read again; the saved index is reused
+	store(commits[1], "s2", "add the upload client")
+	openIndex = nil
+	updated, err := OpenIndex(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(updated.Shared.Commits) != 2 {
+		t.Fatalf("index has %d commits, want 2", len(updated.Shared.Commits))
+	}
+	if got := updated.Conversations(commits[1]); len(got) != 1 || got[0].SessionID != "s2" {
+		t.Errorf("Conversations(%s) = %+v", commits[1], got)
+	}
+	if updated.Shared.Commits[commits[0]][0].Conversation.SessionID != "s1" {
+		t.Error("the unchanged note's conversations were lost")
+	}
+	if ix.Shared.Commits[commits[1]] != nil {
+		t.Error("refreshing the index modified the one returned before")
+	}
+
+	if out, err := exec.Command("git", "notes", "--ref", git.NotesRef, "remove", commits[0]).CombinedOutput(); err != nil {
+		t.Fatalf("git notes remove: %v: %s", err, out)
+	}
+	updated, err = OpenIndex(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.Conversations(commits[0]) != nil {
+		t.Error("the removed note's conversations are still indexed")
+	}
+}
+
+func TestIndexSavesOnlyChangedNotes(t *testing.T) {
+	chdirScratchRepo(t)
+	useBackend(t, GitNotesBackend{})
+	t.Cleanup(func() { openIndex = nil })
+
+	path, err := indexPath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// An index saved before it was a database is replaced
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte("gob index"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	note := func(message, sessionID string) string {
+		t.Helper()
+		if out, err := exec.Command("git", "commit", "-q", "--allow-empty", "-m", message).CombinedOutput(); err != nil {
+			t.Fatalf("git commit: %v: %s", err, out)
+		}
+		sha, err := git.GetHeadCommit()
+		if err != nil {
+			t.Fatal(err)
+		}
+		content, err := MarshalStoredConversations([]*StoredConversation{{Version: NoteFormatVersion, SessionID: sessionID}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := git.AddNote(sha, content); err != nil {
+			t.Fatal(err)
+		}
+		return sha
+	}
+	record := func(commit string) []byte {
+		t.Helper()
+		db, err := bolt.Open(path, 0644, &bolt.Options{ReadOnly: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer func() { _ = db.Close() }()
+		var v []byte
+		_ = db.View(func(tx *bolt.Tx) error {
+			v = append(v, tx.Bucket(indexShared).Bucket(indexCommitsBucket).Get([]byte(commit))...)
+			return nil
+		})
+		return v
+	}
+
+	first := note("first", "s1")
+	if _, err := OpenIndex(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if len(record(first)) == 0 {
+		t.Fatalf("the index saved no record of %s", first)
+	}
+
+	// Mark the saved record, which a rewrite of the whole index would undo
+	db, err := bolt.Open(path, 0644, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var marked bytes.Buffer
+	if err := gob.NewEncoder(&marked).Encode([]*IndexedConversation{{Conversation: &StoredConversation{SessionID: "marked"}}}); err != nil {
+		t.Fatal(err)
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(indexShared).Bucket(indexCommitsBucket).Put([]byte(first), marked.Bytes())
+	})
+	_ = db.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The index kept in memory since is refreshed and saved
+	second := note("second", "s2")
+	if _, err := OpenIndex(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if len(record(second)) == 0 {
+		t.Errorf("the index saved no record of %s", second)
+	}
+	openIndex = nil
+	ix, err := OpenIndex(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := ix.Conversations(first); len(got) != 1 || got[0].SessionID != "marked" {
+		t.Errorf("Conversations(%s) = %+v, want the record left as saved", first, got)
+	}
+}
@@ -0,0 +1,95 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/re-cinq/shift-log/internal/git"
+	"github.com/re-cinq/shift-log/internal/util"
+)
+
+// IssueCommit is a commit whose conversations refer to an issue.
+type IssueCommit struct {
+	SHA     string
+	Subject string
+	Date    string // committer date, git's ISO format
+	// Conversations are every conversation of the commit, as
+	// Index.ConversationsWithPrivate returns them, without their
+	// transcripts.
+	Conversations []*StoredConversation
+}
+
+// IssueKeys returns the issue keys that pattern matches in a commit
+// message, sorted and unique.
+func IssueKeys(message string, pattern *regexp.Regexp) []string {
+	keys := pattern.FindAllString(message, -1)
+	if len(keys) == 0 {
+		return nil
+	}
+	slices.Sort(keys)
+	return slices.Compact(keys)
+}
+
+// HasIssue reports whether the conversation refers to the issue key
+// (case-insensitive).
+func (sc *StoredConversation) HasIssue(key string) bool {
+	return slices.ContainsFunc(sc.Issues, func(k string) bool { return strings.EqualFold(k, key) })
+}
+
+// IssueCommits returns the commits with a conversation referring to the
+// issue key, shared, contributed or private, newest first. It reads the
+// index, bringing it up to date.
+func IssueCommits(ctx context.Context, key string) ([]IssueCommit, error) {
+	ix, err := OpenIndex(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("could not read the index: %w", err)
+	}
+
+	matches := make(map[string][]*StoredConversation)
+	refs := append([]RefIndex{ix.Shared, ix.Private}, ix.Contributed...)
+	for _, ri := range refs {
+		for sha := range ri.Commits {
+			if _, seen := matches[sha]; seen {
+				continue
+			}
+			// One copied from a commit whose message was reworded since
+			// may not refer to it; the commit's are shown together
+			conversations := ix.ConversationsWithPrivate(sha)
+			if slices.ContainsFunc(conversations, func(sc *StoredConversation) bool { return sc.HasIssue(key) }) {
+				matches[sha] = conversations
+			}
+		}
+	}
+
+	shas := make([]string, 0, len(matches))
+	for sha := range matches {
+		shas = append(shas, sha)
+	}
+	infos, err := git.DescribeCommits(shas)
+	if err != nil {
+		return nil, fmt.Errorf("could not read commits: %w", err)
+	}
+
+	commits := make([]IssueCommit, 0, len(infos))
+	for _, sha := range shas {
+		info, ok := infos[sha]
+		if !ok {
+			continue
+		}
+		commits = append(commits, IssueCommit{SHA: sha, Subject: info.Subject, Date: info.Date, Conversations: matches[sha]})
+	}
+	// Ties by SHA keep the order stable
+	sort.Slice(commits, func(i, j int) bool {
+		ti, _ := util.ParseTimestamp(commits[i].Date)
+		tj, _ := util.ParseTimestamp(commits[j].Date)
+		if !ti.Equal(tj) {
+			return ti.After(tj)
+		}
+		return commits[i].SHA < commits[j].SHA
+	})
+	return commits, nil
+}
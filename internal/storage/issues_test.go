@@ -0,0 +1,26 @@
+package storage
+
+import (
+	"reflect"
+	"regexp"
+	"testing"
+
+	"github.com/re-cinq/shift-log/internal/config"
+)
+
+func TestIssueKeys(t *testing.T) {
+	pattern := regexp.MustCompile(config.DefaultIssuePattern)
+	got := IssueKeys("PROJ-12: retry uploads\n\nAlso fixes ENG-3 and PROJ-12.\n\nRefs: ENG-10", pattern)
+	want := []string{"ENG-10", "ENG-3", "PROJ-12"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("IssueKeys() = %q, want %q", got, want)
+	}
+	if got := IssueKeys("Fix the retry logic", pattern); got != nil {
+		t.Errorf("IssueKeys() without keys = %q, want nil", got)
+	}
+
+	sc := &StoredConversation{Issues: want}
+	if !sc.HasIssue("eng-3") || sc.HasIssue("ENG-1") {
+		t.Error("HasIssue() does not match keys case-insensitively and exactly")
+	}
+}
@@ -0,0 +1,137 @@
+package storage
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/re-cinq/shift-log/internal/git"
+)
+
+// MergedConversation references a conversation stored on a commit that a
+// merge brought in from another branch.
+//
+// The references of a merge commit are stored in git.MergesRef as one JSON
+// object per line, so that merging two clones with cat_sort_uniq yields the
+// union of their records.
+type MergedConversation struct {
+	Timestamp string `json:"timestamp"` // when the conversation was stored; first so sorted lines are chronological
+	Commit    string `json:"commit"`
+	SessionID string `json:"session_id"`
+	Agent     string `json:"agent,omitempty"`
+	Branch    string `json:"branch,omitempty"` // branch the conversation was stored on
+	Summary   string `json:"summary,omitempty"`
+}
+
+// GetMergedConversations returns the conversations recorded for a merge
+// commit, oldest first. Returns nil if none were recorded.
+func GetMergedConversations(mergeSHA string) ([]MergedConversation, error) {
+	data, err := git.GetNoteFromRef(git.MergesRef, mergeSHA)
+	if err != nil {
+		// No record for this commit (or no merges ref yet)
+		return nil, nil
+	}
+	return parseMergedConversations(data), nil
+}
+
+// RecordMergedConversations records on a merge commit the conversations of
+// the commits it merged, and returns them. Recording is idempotent; a commit
+// that is not a merge, or that merged no conversations, records nothing.
+func RecordMergedConversations(mergeSHA string) ([]MergedConversation, error) {
+	merged, err := git.ListMergedCommits(mergeSHA)
+	if err != nil {
+		return nil, fmt.Errorf("could not list merged commits: %w", err)
+	}
+	if len(merged) == 0 {
+		return nil, nil
+	}
+
+	backend, err := ActiveBackend()
+	if err != nil {
+		return nil, err
+	}
+	withConversation, err := backend.List()
+	if err != nil {
+		return nil, fmt.Errorf("could not list conversations: %w", err)
+	}
+
+	var refs []MergedConversation
+	for _, sha := range merged {
+		if !withConversation[sha] {
+			continue
+		}
+		conversations, err := GetStoredConversations(sha)
+		if err != nil {
+			continue
+		}
+		for _, sc := range conversations {
+			refs = append(refs, MergedConversation{
+				Timestamp: sc.Timestamp,
+				Commit:    sha,
+				SessionID: sc.SessionID,
+				Agent:     sc.Agent,
+				Branch:    sc.GitBranch,
+				Summary:   sc.Summary,
+			})
+		}
+	}
+	if len(refs) == 0 {
+		return nil, nil
+	}
+
+	content, err := marshalMergedConversations(refs)
+	if err != nil {
+		return nil, err
+	}
+	if err := git.AddNoteToRef(git.MergesRef, mergeSHA, content); err != nil {
+		return nil, fmt.Errorf("failed to record merged conversations: %w", err)
+	}
+	return parseMergedConversations(content), nil
+}
+
+// parseMergedConversations decodes one reference per line, skipping lines
+// that are not references, and sorts them chronologically.
+func parseMergedConversations(data []byte) []MergedConversation {
+	seen := make(map[string]bool)
+	var refs []MergedConversation
+	for _, line := range bytes.Split(data, []byte("\n")) {
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 {
+			continue
+		}
+		var m MergedConversation
+		if err := json.Unmarshal(line, &m); err != nil || m.Commit == "" {
+			continue
+		}
+		key := m.Commit + " " + m.SessionID
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		refs = append(refs, m)
+	}
+	sort.SliceStable(refs, func(i, j int) bool {
+		if refs[i].Timestamp != refs[j].Timestamp {
+			return refs[i].Timestamp < refs[j].Timestamp
+		}
+		if refs[i].Commit != refs[j].Commit {
+			return refs[i].Commit < refs[j].Commit
+		}
+		return refs[i].SessionID < refs[j].SessionID
+	})
+	return refs
+}
+
+func marshalMergedConversations(refs []MergedConversation) ([]byte, error) {
+	var buf bytes.Buffer
+	for _, m := range refs {
+		line, err := json.Marshal(m)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(line)
+		buf.WriteByte('\n')
+	}
+	return buf.Bytes(), nil
+}
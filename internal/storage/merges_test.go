@@ -0,0 +1,35 @@
+package storage
+
+import "testing"
+
+func TestParseMergedConversationsMergedLines(t *testing.T) {
+	// Two clones' records merged line-wise by cat_sort_uniq, one commit
+	// recorded by both with a different summary, plus a line that is not a
+	// record
+	data := []byte(`{"timestamp":"2025-01-01T00:00:00Z","commit":"aaa","session_id":"s1","summary":"old"}
+{"timestamp":"2025-01-01T00:00:00Z","commit":"aaa","session_id":"s1","summary":"new"}
+garbage
+{"timestamp":"2024-12-31T00:00:00Z","commit":"bbb","session_id":"s2"}
+`)
+
+	got := parseMergedConversations(data)
+	if len(got) != 2 {
+		t.Fatalf("got %d records, want 2", len(got))
+	}
+	if got[0].Commit != "bbb" || got[1].Commit != "aaa" {
+		t.Errorf("order = %s, %s; want bbb, aaa", got[0].Commit, got[1].Commit)
+	}
+}
+
+func TestMarshalMergedConversationsRoundTrip(t *testing.T) {
+	in := []MergedConversation{{Timestamp: "2025-01-01T00:00:00Z", Commit: "aaa", SessionID: "s1", Agent: "claude", Branch: "feature"}}
+
+	data, err := marshalMergedConversations(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := parseMergedConversations(data)
+	if len(out) != 1 || out[0] != in[0] {
+		t.Errorf("round trip = %+v, want %+v", out, in)
+	}
+}
@@ -0,0 +1,85 @@
+package storage
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/re-cinq/shift-log/internal/git"
+)
+
+// Reasons a conversation was intentionally not stored.
+const (
+	OmissionOptOut   = "opt-out"  // the author ran shiftlog optout
+	OmissionDisabled = "disabled" // SHIFTLOG_DISABLE was set in the hook's environment
+)
+
+// Omission records that the conversation of a commit was intentionally not
+// stored, instead of lost. It holds no detail of the session left out.
+//
+// The omissions of a commit are stored in git.OmissionsRef as one JSON
+// object per line, so that merging two clones with cat_sort_uniq yields the
+// union of their records.
+type Omission struct {
+	Timestamp string `json:"timestamp"`
+	Reason    string `json:"reason"` // one of the Omission constants
+	Agent     string `json:"agent,omitempty"`
+	Trigger   string `json:"trigger,omitempty"` // one of the Trigger constants
+}
+
+// GetOmissions returns the omissions recorded for a commit. Returns nil if
+// none were recorded.
+func GetOmissions(commitSHA string) []Omission {
+	data, err := git.GetNoteFromRef(git.OmissionsRef, commitSHA)
+	if err != nil {
+		return nil
+	}
+	return parseOmissions(data)
+}
+
+// parseOmissions decodes one omission per line, skipping lines that are not
+// omissions.
+func parseOmissions(data []byte) []Omission {
+	var omissions []Omission
+	for _, line := range bytes.Split(data, []byte("\n")) {
+		var o Omission
+		if json.Unmarshal(bytes.TrimSpace(line), &o) == nil && o.Reason != "" {
+			omissions = append(omissions, o)
+		}
+	}
+	return omissions
+}
+
+// RecordOmission records on a commit that a conversation of the agent was
+// intentionally not stored. A commit records each reason once.
+func RecordOmission(commitSHA, reason, agentName, trigger string) error {
+	o := Omission{
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+		Reason:    reason,
+		Agent:     agentName,
+		Trigger:   trigger,
+	}
+	err := git.UpdateNoteInRef(git.OmissionsRef, commitSHA, func(data []byte) ([]byte, error) {
+		existing := parseOmissions(data)
+		for _, e := range existing {
+			if e.Reason == reason {
+				return nil, nil
+			}
+		}
+		var buf bytes.Buffer
+		for _, o := range append(existing, o) {
+			line, err := json.Marshal(o)
+			if err != nil {
+				return nil, err
+			}
+			buf.Write(line)
+			buf.WriteByte('\n')
+		}
+		return buf.Bytes(), nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to record omitted conversation: %w", err)
+	}
+	return nil
+}
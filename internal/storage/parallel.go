@@ -0,0 +1,69 @@
+package storage
+
+import (
+	"context"
+	"runtime"
+	"sync"
+	"sync/atomic"
+)
+
+// concurrency is how many conversations are read, decompressed and parsed
+// at once, set by SetConcurrency; 0 uses every CPU.
+var concurrency atomic.Int64
+
+// SetConcurrency sets how many conversations are loaded at once. n <= 0
+// uses every CPU.
+func SetConcurrency(n int) {
+	concurrency.Store(int64(max(n, 0)))
+}
+
+// Concurrency returns how many conversations are loaded at once.
+func Concurrency() int {
+	if n := concurrency.Load(); n > 0 {
+		return int(n)
+	}
+	return runtime.GOMAXPROCS(0)
+}
+
+// ForEachParallel calls fn with every index below n, Concurrency of them at
+// once, and returns the first error one returns. Once ctx is done, or fn
+// has failed, the indices not started yet are skipped; if ctx skipped any,
+// its error is returned.
+func ForEachParallel(ctx context.Context, n int, fn func(i int) error) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		next     atomic.Int64
+		done     atomic.Int64
+		wg       sync.WaitGroup
+		errOnce  sync.Once
+		firstErr error
+	)
+	for range min(Concurrency(), n) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for ctx.Err() == nil {
+				i := int(next.Add(1) - 1)
+				if i >= n {
+					return
+				}
+				if err := fn(i); err != nil {
+					errOnce.Do(func() { firstErr = err })
+					cancel()
+					return
+				}
+				done.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+	if firstErr != nil {
+		return firstErr
+	}
+	if int(done.Load()) < n {
+		return context.Cause(ctx)
+	}
+	return nil
+}
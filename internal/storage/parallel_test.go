@@ -0,0 +1,81 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+)
+
+func TestForEachParallel(t *testing.T) {
+	SetConcurrency(3)
+	t.Cleanup(func() { SetConcurrency(0) })
+
+	var running, peak atomic.Int64
+	seen := make([]atomic.Int64, 50)
+	err := ForEachParallel(context.Background(), len(seen), func(i int) error {
+		n := running.Add(1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		seen[i].Add(1)
+		running.Add(-1)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("ForEachParallel() error: %v", err)
+	}
+	for i := range seen {
+		if got := seen[i].Load(); got != 1 {
+			t.Errorf("index %d was visited %d times, want 1", i, got)
+		}
+	}
+	if peak.Load() > 3 {
+		t.Errorf("%d calls ran at once, want at most 3", peak.Load())
+	}
+}
+
+func TestForEachParallelError(t *testing.T) {
+	SetConcurrency(1)
+	t.Cleanup(func() { SetConcurrency(0) })
+
+	failed := errors.New("failed")
+	calls := 0
+	err := ForEachParallel(context.Background(), 10, func(i int) error {
+		calls++
+		if i == 2 {
+			return failed
+		}
+		return nil
+	})
+	if !errors.Is(err, failed) {
+		t.Errorf("ForEachParallel() error = %v, want %v", err, failed)
+	}
+	if calls != 3 {
+		t.Errorf("fn was called %d times, want 3", calls)
+	}
+}
+
+func TestForEachParallelCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	var calls atomic.Int64
+	err := ForEachParallel(ctx, 1000, func(i int) error {
+		if calls.Add(1) == 1 {
+			cancel()
+		}
+		return nil
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("ForEachParallel() error = %v, want %v", err, context.Canceled)
+	}
+	if calls.Load() == 1000 {
+		t.Error("every index was visited after cancellation")
+	}
+
+	if err := ForEachParallel(ctx, 0, func(int) error { return nil }); err != nil {
+		t.Errorf("ForEachParallel() of nothing error = %v", err)
+	}
+}
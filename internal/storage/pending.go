@@ -0,0 +1,150 @@
+package storage
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/re-cinq/shift-log/internal/config"
+	"github.com/re-cinq/shift-log/internal/util"
+)
+
+// pendingDir is the directory in .shiftlog holding the queued stores.
+const pendingDir = "pending"
+
+// PendingStore is a conversation whose store failed, e.g. on git lock
+// contention or a transient filesystem error, queued in .shiftlog/pending
+// to be attached to its commit by a later store or shiftlog flush.
+type PendingStore struct {
+	// Commit is the commit the conversation belongs to.
+	Commit string `json:"commit"`
+	// Private is set when the conversation goes to the private notes ref.
+	Private bool `json:"private,omitempty"`
+	// QueuedAt is when the store first failed, in RFC 3339.
+	QueuedAt string `json:"queued_at"`
+	// Attempts counts the failed stores, the first included.
+	Attempts int `json:"attempts"`
+	// Error is the error of the last failed store.
+	Error        string              `json:"error"`
+	Conversation *StoredConversation `json:"conversation"`
+
+	path string
+}
+
+// PendingDir returns the path of the directory holding the queued stores.
+func PendingDir() (string, error) {
+	dir, err := config.Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, pendingDir), nil
+}
+
+// QueuePendingStore queues a conversation whose store on commitSHA failed
+// with cause. Queueing the same session of a commit again replaces its
+// entry and counts the attempt.
+func QueuePendingStore(commitSHA string, sc *StoredConversation, private bool, cause error) (*PendingStore, error) {
+	dir, err := PendingDir()
+	if err != nil {
+		return nil, err
+	}
+	if err := util.EnsureDir(dir); err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256([]byte(commitSHA + "\x00" + sc.AgentName() + "\x00" + sc.SessionID))
+	p := &PendingStore{
+		Commit:       commitSHA,
+		Private:      private,
+		QueuedAt:     time.Now().UTC().Format(time.RFC3339),
+		Conversation: sc,
+		path:         filepath.Join(dir, shortSHA(commitSHA)+"-"+hex.EncodeToString(sum[:6])+".json"),
+	}
+	if previous, err := readPendingStore(p.path); err == nil {
+		p.QueuedAt = previous.QueuedAt
+		p.Attempts = previous.Attempts
+	}
+	return p, p.Retried(cause)
+}
+
+// Retried records another failed attempt to store p, with its cause.
+func (p *PendingStore) Retried(cause error) error {
+	p.Attempts++
+	p.Error = cause.Error()
+	data, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return err
+	}
+	// Written aside and renamed, so that a crash never leaves half an entry
+	tmp := p.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, p.path)
+}
+
+// Remove drops p from the queue, once it is stored.
+func (p *PendingStore) Remove() error {
+	if err := os.Remove(p.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// PendingStores returns the queued stores, oldest first. Entries that
+// cannot be read are skipped with their error in the second result, so
+// one corrupt file does not hold up the others.
+func PendingStores() ([]*PendingStore, []error) {
+	dir, err := PendingDir()
+	if err != nil {
+		return nil, []error{err}
+	}
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, []error{err}
+	}
+	var stores []*PendingStore
+	var errs []error
+	for _, path := range paths {
+		p, err := readPendingStore(path)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", filepath.Base(path), err))
+			continue
+		}
+		stores = append(stores, p)
+	}
+	sort.SliceStable(stores, func(i, j int) bool { return stores[i].QueuedAt < stores[j].QueuedAt })
+	return stores, errs
+}
+
+// CountPendingStores returns the number of entries in the queue.
+func CountPendingStores() int {
+	dir, err := PendingDir()
+	if err != nil {
+		return 0
+	}
+	paths, _ := filepath.Glob(filepath.Join(dir, "*.json"))
+	return len(paths)
+}
+
+// readPendingStore reads a queue entry.
+func readPendingStore(path string) (*PendingStore, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var p PendingStore
+	if err := json.Unmarshal(data, &p); err != nil {
+		return nil, err
+	}
+	if p.Commit == "" || p.Conversation == nil {
+		return nil, fmt.Errorf("entry has no commit or conversation")
+	}
+	p.path = path
+	return &p, nil
+}
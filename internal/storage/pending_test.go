@@ -0,0 +1,62 @@
+package storage
+
+import (
+	"errors"
+	"os"
+	"testing"
+)
+
+func TestPendingStores(t *testing.T) {
+	chdirScratchRepo(t)
+	commit := "0123456789abcdef0123456789abcdef01234567"
+	sc := &StoredConversation{Agent: "claude", SessionID: "s1", MessageCount: 2}
+
+	if n := CountPendingStores(); n != 0 {
+		t.Fatalf("CountPendingStores() = %d before queueing", n)
+	}
+	if _, err := QueuePendingStore(commit, sc, false, errors.New("cannot lock ref")); err != nil {
+		t.Fatal(err)
+	}
+	again, err := QueuePendingStore(commit, sc, true, errors.New("disk full"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := QueuePendingStore(commit, &StoredConversation{Agent: "codex", SessionID: "s1"}, false, errors.New("disk full")); err != nil {
+		t.Fatal(err)
+	}
+
+	pending, errs := PendingStores()
+	if len(errs) != 0 || len(pending) != 2 {
+		t.Fatalf("PendingStores() = %d entries, errors %v, want 2 entries", len(pending), errs)
+	}
+	var p *PendingStore
+	for _, candidate := range pending {
+		if candidate.Conversation.AgentName() == "claude" {
+			p = candidate
+		}
+	}
+	if p == nil || p.Attempts != 2 || p.Error != "disk full" || !p.Private || p.Conversation.MessageCount != 2 {
+		t.Fatalf("requeued entry = %+v, want 2 attempts of a private conversation", p)
+	}
+	if p.QueuedAt != again.QueuedAt {
+		t.Errorf("QueuedAt = %s, want the first failure's", p.QueuedAt)
+	}
+
+	dir, _ := PendingDir()
+	if err := os.WriteFile(dir+"/corrupt.json", []byte("{"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, errs := PendingStores(); len(errs) != 1 {
+		t.Errorf("PendingStores() errors = %v, want the corrupt entry", errs)
+	}
+
+	if err := p.Remove(); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Remove(); err != nil {
+		t.Errorf("removing twice: %v", err)
+	}
+	if n := CountPendingStores(); n != 2 {
+		t.Errorf("CountPendingStores() = %d, want 2", n)
+	}
+}
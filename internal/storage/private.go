@@ -0,0 +1,132 @@
+package storage
+
+import (
+	"fmt"
+
+	"github.com/re-cinq/shift-log/internal/config"
+	"github.com/re-cinq/shift-log/internal/git"
+)
+
+// IsPrivate reports whether the conversation is kept in this clone only.
+func (sc *StoredConversation) IsPrivate() bool {
+	return sc.Visibility == config.VisibilityPrivate
+}
+
+// GetPrivateConversations returns the private conversations stored for a
+// commit, one per agent session. Returns nil if there are none.
+func GetPrivateConversations(commitSHA string) ([]*StoredConversation, error) {
+	data, err := git.GetNoteFromRef(git.PrivateRef, commitSHA)
+	if err != nil {
+		// No private conversation for this commit (or no private ref yet)
+		return nil, nil
+	}
+	return UnmarshalStoredConversations(data)
+}
+
+// SavePrivateConversations replaces the private conversations of a commit,
+// removing its private note when there are none left.
+func SavePrivateConversations(commitSHA string, conversations []*StoredConversation) error {
+	if len(conversations) == 0 {
+		return git.RemoveNoteFromRef(git.PrivateRef, commitSHA)
+	}
+	content, err := MarshalStoredConversations(conversations)
+	if err != nil {
+		return fmt.Errorf("failed to marshal conversation: %w", err)
+	}
+	return git.AddNoteToRef(git.PrivateRef, commitSHA, content)
+}
+
+// AddPrivateConversation adds sc to the private conversations of a commit,
+// unless its agent session is already among them, and reports whether it
+// did. Like AddStoredConversation, it reads and writes the private note
+// under the notes write lock, and overwrites a note that cannot be parsed
+// unless strict makes it an error.
+func AddPrivateConversation(commitSHA string, sc *StoredConversation, strict bool) (bool, error) {
+	added := false
+	err := git.UpdateNoteInRef(git.PrivateRef, commitSHA, conversationsUpdate(strict, addConversation(sc, &added)))
+	return added && err == nil, err
+}
+
+// ListPrivateConversationCommits returns the set of commits with a private
+// conversation.
+func ListPrivateConversationCommits() (map[string]bool, error) {
+	blobs, err := git.ListNoteBlobs(git.PrivateRef)
+	if err != nil {
+		return nil, fmt.Errorf("could not list private conversations: %w", err)
+	}
+	commits := make(map[string]bool, len(blobs))
+	for sha := range blobs {
+		commits[sha] = true
+	}
+	return commits, nil
+}
+
+// GetConversationsWithPrivate returns the conversations stored for a commit
+// followed by those of the contributor refs and its private ones, for the
+// views of the clone that stored them. Returns nil if there are none.
+func GetConversationsWithPrivate(commitSHA string) ([]*StoredConversation, error) {
+	shared, err := GetStoredConversations(commitSHA)
+	if err != nil {
+		return nil, err
+	}
+	contributed, err := GetContributedConversations(commitSHA, shared)
+	if err != nil {
+		return nil, err
+	}
+	shared = append(shared, contributed...)
+	private, err := GetPrivateConversations(commitSHA)
+	if err != nil {
+		return nil, fmt.Errorf("could not read private conversations: %w", err)
+	}
+	return append(shared, private...), nil
+}
+
+// PublishConversations moves the private conversations of a commit, or
+// only the one of sessionID when it is not empty, into the commit's shared
+// conversation note, and returns them. A published conversation replaces
+// the shared one of the same session.
+func PublishConversations(commitSHA, sessionID string) ([]*StoredConversation, error) {
+	private, err := GetPrivateConversations(commitSHA)
+	if err != nil {
+		return nil, fmt.Errorf("could not read private conversations: %w", err)
+	}
+	var published, kept []*StoredConversation
+	for _, sc := range private {
+		if sessionID == "" || sc.SessionID == sessionID {
+			published = append(published, sc)
+		} else {
+			kept = append(kept, sc)
+		}
+	}
+	if len(published) == 0 {
+		if sessionID != "" {
+			return nil, fmt.Errorf("no private conversation of session %s found for commit %s", sessionID, commitSHA[:7])
+		}
+		return nil, fmt.Errorf("no private conversation found for commit %s", commitSHA[:7])
+	}
+
+	for _, sc := range published {
+		sc.Visibility = ""
+		if err := sc.StoreTranscriptApart(); err != nil {
+			return nil, err
+		}
+	}
+	err = updateStoredConversations(commitSHA, true, func(conversations []*StoredConversation) []*StoredConversation {
+		for _, sc := range published {
+			if i := IndexOfSession(conversations, sc); i >= 0 {
+				conversations[i] = sc
+			} else {
+				conversations = append(conversations, sc)
+			}
+		}
+		return conversations
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := SavePrivateConversations(commitSHA, kept); err != nil {
+		return published, fmt.Errorf("could not remove published conversations from %s: %w", git.PrivateRef, err)
+	}
+	return published, nil
+}
@@ -0,0 +1,179 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/re-cinq/shift-log/internal/agent"
+	"github.com/re-cinq/shift-log/internal/git"
+)
+
+// promptSimilarity is the share of their words two prompts must have in
+// common to count as one.
+const promptSimilarity = 0.8
+
+// PromptUse is a session a prompt was sent in, and the newest commit it is
+// stored on.
+type PromptUse struct {
+	CommitSHA  string `json:"commit"`
+	CommitDate string `json:"date"`
+	CommitMsg  string `json:"message"`
+	SessionID  string `json:"session_id"`
+	Agent      string `json:"agent"`
+	Model      string `json:"model,omitempty"`
+}
+
+// Prompt is an entry of the prompt library: a prompt, and every session
+// it, or a prompt like it, was sent in, newest first.
+type Prompt struct {
+	Text string      `json:"text"` // the most recent wording
+	Uses []PromptUse `json:"uses"`
+
+	words map[string]bool
+}
+
+// promptWords returns the set of the lowercased words of a prompt, for
+// comparing it with others.
+func promptWords(text string) map[string]bool {
+	words := make(map[string]bool)
+	for _, w := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	}) {
+		words[w] = true
+	}
+	return words
+}
+
+// similarPrompts reports whether two prompts share enough of their words,
+// by the Jaccard index of their word sets, to be one entry of the library.
+func similarPrompts(a, b map[string]bool) bool {
+	if len(a) == 0 || len(b) == 0 {
+		return len(a) == len(b)
+	}
+	common := 0
+	for w := range a {
+		if b[w] {
+			common++
+		}
+	}
+	return float64(common)/float64(len(a)+len(b)-common) >= promptSimilarity
+}
+
+// userPrompts returns the text of the user messages of a transcript, in
+// order: the messages the user typed, not the tool results the agent
+// reported as user entries, nor the command output and reminders agents
+// such as Claude Code wrap in tags.
+func userPrompts(transcript *agent.Transcript) []string {
+	var prompts []string
+	for _, entry := range transcript.Entries {
+		if entry.Type != agent.MessageTypeUser || entry.Message == nil {
+			continue
+		}
+		var parts []string
+		for _, block := range entry.Message.Content {
+			if block.Type == "text" && strings.TrimSpace(block.Text) != "" {
+				parts = append(parts, strings.TrimSpace(block.Text))
+			}
+		}
+		text := strings.Join(parts, "\n\n")
+		if text == "" || strings.HasPrefix(text, "<") {
+			continue
+		}
+		prompts = append(prompts, text)
+	}
+	return prompts
+}
+
+// PromptLibrary collects the prompts of the conversations stored on the
+// current branch: the first user message of each, or every user message
+// with all. Prompts whose words are mostly the same are merged into one
+// entry. Entries are ordered by how often they were used, then by the most
+// recent use. ctx bounds loading the transcripts.
+func PromptLibrary(ctx context.Context, all bool) ([]*Prompt, error) {
+	commits, err := ListConversationCommits()
+	if err != nil {
+		return nil, fmt.Errorf("could not list conversations: %w", err)
+	}
+	infos, err := git.DescribeCommits(commits)
+	if err != nil {
+		return nil, fmt.Errorf("could not read commits: %w", err)
+	}
+
+	type sent struct {
+		text string
+		use  PromptUse
+	}
+	perCommit := make([][]sent, len(commits))
+	err = ForEachParallel(ctx, len(commits), func(i int) error {
+		sha := commits[i]
+		info, ok := infos[sha]
+		if !ok {
+			return nil
+		}
+		conversations, err := GetStoredConversations(sha)
+		if err != nil {
+			return nil
+		}
+		for _, sc := range conversations {
+			transcript, err := sc.ParseTranscript()
+			if err != nil {
+				continue
+			}
+			prompts := userPrompts(transcript)
+			if !all && len(prompts) > 1 {
+				prompts = prompts[:1]
+			}
+			for _, text := range prompts {
+				perCommit[i] = append(perCommit[i], sent{text: text, use: PromptUse{
+					CommitSHA:  sha,
+					CommitDate: info.Date,
+					CommitMsg:  info.Subject,
+					SessionID:  sc.SessionID,
+					Agent:      sc.AgentName(),
+					Model:      sc.Model,
+				}})
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Commits are newest first, so each entry keeps its most recent wording
+	var library []*Prompt
+	exact := make(map[string]*Prompt)
+	for _, commitPrompts := range perCommit {
+		for _, s := range commitPrompts {
+			key := strings.Join(strings.Fields(strings.ToLower(s.text)), " ")
+			p := exact[key]
+			if p == nil {
+				words := promptWords(s.text)
+				for _, other := range library {
+					if similarPrompts(words, other.words) {
+						p = other
+						break
+					}
+				}
+				if p == nil {
+					p = &Prompt{Text: s.text, words: words}
+					library = append(library, p)
+				}
+				exact[key] = p
+			}
+			// A session stored on several commits, or sending the prompt
+			// again, uses it once, on its newest commit
+			if !slices.ContainsFunc(p.Uses, func(u PromptUse) bool { return u.SessionID == s.use.SessionID }) {
+				p.Uses = append(p.Uses, s.use)
+			}
+		}
+	}
+	sort.SliceStable(library, func(i, j int) bool {
+		return len(library[i].Uses) > len(library[j].Uses)
+	})
+	return library, nil
+}
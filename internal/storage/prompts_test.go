@@ -0,0 +1,41 @@
+package storage
+
+import (
+	"testing"
+
+	"github.com/re-cinq/shift-log/internal/agent"
+)
+
+func TestUserPrompts(t *testing.T) {
+	user := func(blocks ...agent.ContentBlock) agent.TranscriptEntry {
+		return agent.TranscriptEntry{Type: agent.MessageTypeUser, Message: &agent.Message{Content: blocks}}
+	}
+	transcript := &agent.Transcript{Entries: []agent.TranscriptEntry{
+		user(agent.ContentBlock{Type: "text", Text: "<command-name>/clear</command-name>"}),
+		user(agent.ContentBlock{Type: "text", Text: "  Add a parser  "}),
+		{Type: agent.MessageTypeAssistant, Message: &agent.Message{Content: []agent.ContentBlock{{Type: "text", Text: "Sure"}}}},
+		user(agent.ContentBlock{Type: "tool_result", ToolUseID: "t1"}),
+		user(agent.ContentBlock{Type: "text", Text: "Now test it"}, agent.ContentBlock{Type: "image"}),
+	}}
+	got := userPrompts(transcript)
+	if len(got) != 2 || got[0] != "Add a parser" || got[1] != "Now test it" {
+		t.Errorf("userPrompts() = %q", got)
+	}
+}
+
+func TestSimilarPrompts(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{"Write tests for the parser package", "write tests for the parser package!", true},
+		{"Write unit tests for the parser package please", "Write unit tests for the parser package", true},
+		{"Write tests for the parser package", "Fix the flaky test in the server", false},
+		{"", "", true},
+	}
+	for _, tt := range tests {
+		if got := similarPrompts(promptWords(tt.a), promptWords(tt.b)); got != tt.want {
+			t.Errorf("similarPrompts(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
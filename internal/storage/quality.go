@@ -0,0 +1,198 @@
+package storage
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"time"
+
+	"github.com/re-cinq/shift-log/internal/git"
+	"github.com/re-cinq/shift-log/internal/util"
+)
+
+// Reasons a commit's agent work is likely problematic.
+const (
+	SignalReverted    = "reverted"      // a later commit reverted it
+	SignalFollowUpFix = "follow-up-fix" // a fix soon after it changed the same files
+)
+
+// FollowUpWindow is how soon after a commit a fix touching the same files
+// counts as fixing it.
+const FollowUpWindow = 48 * time.Hour
+
+// fixPattern matches the subjects of commits that fix something.
+var fixPattern = regexp.MustCompile(`(?i)\b(fix|fixes|fixed|fixup|hotfix|bugfix)\b`)
+
+// QualitySignal is a sign that the agent work of a commit with a
+// conversation was problematic: the later commit that reverted or fixed it.
+type QualitySignal struct {
+	Reason  string `json:"reason"` // SignalReverted or SignalFollowUpFix
+	Commit  string `json:"commit"` // the revert or the fix
+	Message string `json:"message"`
+}
+
+// signalCommit is a commit of the history QualitySignals reads.
+type signalCommit struct {
+	sha     string
+	subject string
+	date    time.Time
+	files   map[string]bool
+}
+
+// QualitySignals flags the commits with a stored conversation, among those
+// reachable from ref, HEAD when empty, whose agent work was likely
+// problematic: a commit also reachable from ref reverted it, or a commit
+// whose subject mentions a fix changed one of its files within
+// FollowUpWindow. It returns the signals of each flagged commit, the revert
+// first.
+func QualitySignals(ref string) (map[string][]QualitySignal, error) {
+	if ref == "" {
+		ref = "HEAD"
+	}
+	noted, err := ListAllConversationCommits()
+	if err != nil {
+		return nil, fmt.Errorf("could not list conversations: %w", err)
+	}
+	signals := make(map[string][]QualitySignal)
+	if len(noted) == 0 {
+		return signals, nil
+	}
+
+	// Only the history since the oldest commit with a conversation is read
+	// with its files, which git has to diff
+	var oldest string
+	err = git.ListCommits(git.LogOptions{Ref: ref}, func(c git.LogCommit) bool {
+		if noted[c.SHA] {
+			oldest = c.Date
+		}
+		return true
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not list commits of %s: %w", ref, err)
+	}
+	if oldest == "" {
+		return signals, nil
+	}
+
+	// Newest first
+	var history []signalCommit
+	err = git.ListCommits(git.LogOptions{Ref: ref, Since: oldest, Stat: true}, func(c git.LogCommit) bool {
+		date, err := util.ParseTimestamp(c.Date)
+		if err != nil || c.Parents > 1 {
+			return true
+		}
+		files := make(map[string]bool, len(c.Files))
+		for _, f := range c.Files {
+			files[f.Path] = true
+		}
+		history = append(history, signalCommit{sha: c.SHA, subject: c.Subject, date: date, files: files})
+		return true
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not list commits of %s: %w", ref, err)
+	}
+	reverts, err := git.ListReverts(ref)
+	if err != nil {
+		return nil, fmt.Errorf("could not list reverts: %w", err)
+	}
+	return flagCommits(history, noted, reverts), nil
+}
+
+// flagCommits returns the signals of the commits of history, newest first,
+// that have a conversation in noted, given the reverts of ListReverts.
+func flagCommits(history []signalCommit, noted map[string]bool, reverts map[string]string) map[string][]QualitySignal {
+	subjects := make(map[string]string, len(history))
+	for _, c := range history {
+		subjects[c.sha] = c.subject
+	}
+
+	signals := make(map[string][]QualitySignal)
+	for i, c := range history {
+		if !noted[c.sha] {
+			continue
+		}
+		revert, reverted := reverts[c.sha]
+		if reverted {
+			signals[c.sha] = append(signals[c.sha], QualitySignal{Reason: SignalReverted, Commit: revert, Message: subjects[revert]})
+		}
+		// The commits after c are before it in history
+		for j := i - 1; j >= 0; j-- {
+			later := history[j]
+			if later.date.Sub(c.date) > FollowUpWindow {
+				break
+			}
+			if later.sha == revert || !fixPattern.MatchString(later.subject) || !sharesFile(c.files, later.files) {
+				continue
+			}
+			signals[c.sha] = append(signals[c.sha], QualitySignal{Reason: SignalFollowUpFix, Commit: later.sha, Message: later.subject})
+		}
+	}
+	return signals
+}
+
+// sharesFile reports whether two sets of files have one in common.
+func sharesFile(a, b map[string]bool) bool {
+	for f := range a {
+		if b[f] {
+			return true
+		}
+	}
+	return false
+}
+
+// ProblematicCommit is a commit with a conversation whose agent work was
+// likely problematic, for shiftlog stats --problematic.
+type ProblematicCommit struct {
+	CommitSHA  string          `json:"commit"`
+	CommitDate string          `json:"date"`
+	CommitMsg  string          `json:"message"`
+	Agent      string          `json:"agent"`
+	Model      string          `json:"model,omitempty"`
+	SessionID  string          `json:"session_id"`
+	Signals    []QualitySignal `json:"signals"`
+}
+
+// ProblematicCommits lists the commits on the current branch flagged by
+// QualitySignals, newest first, with their first conversation.
+func ProblematicCommits() ([]ProblematicCommit, error) {
+	signals, err := QualitySignals("HEAD")
+	if err != nil {
+		return nil, err
+	}
+	shas := make([]string, 0, len(signals))
+	for sha := range signals {
+		shas = append(shas, sha)
+	}
+	infos, err := git.DescribeCommits(shas)
+	if err != nil {
+		return nil, fmt.Errorf("could not read commits: %w", err)
+	}
+
+	var commits []ProblematicCommit
+	for _, sha := range shas {
+		conversations, err := GetStoredConversations(sha)
+		if err != nil || len(conversations) == 0 {
+			continue
+		}
+		sc := conversations[0]
+		info := infos[sha]
+		commits = append(commits, ProblematicCommit{
+			CommitSHA:  sha,
+			CommitDate: info.Date,
+			CommitMsg:  info.Subject,
+			Agent:      sc.AgentName(),
+			Model:      sc.Model,
+			SessionID:  sc.SessionID,
+			Signals:    signals[sha],
+		})
+	}
+	sort.Slice(commits, func(i, j int) bool {
+		a, _ := util.ParseTimestamp(commits[i].CommitDate)
+		b, _ := util.ParseTimestamp(commits[j].CommitDate)
+		if !a.Equal(b) {
+			return a.After(b)
+		}
+		return commits[i].CommitSHA < commits[j].CommitSHA
+	})
+	return commits, nil
+}
@@ -0,0 +1,44 @@
+package storage
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFlagCommits(t *testing.T) {
+	day := time.Date(2025, 3, 3, 9, 0, 0, 0, time.UTC)
+	files := func(paths ...string) map[string]bool {
+		set := make(map[string]bool)
+		for _, p := range paths {
+			set[p] = true
+		}
+		return set
+	}
+	// Newest first
+	history := []signalCommit{
+		{sha: "fix-late", subject: "Fix the lexer", date: day.Add(5 * 24 * time.Hour), files: files("lexer.go")},
+		{sha: "revert", subject: `Revert "Add cache"`, date: day.Add(30 * time.Hour), files: files("cache.go")},
+		{sha: "fix", subject: "fix: handle empty input in the parser", date: day.Add(3 * time.Hour), files: files("parser.go", "parser_test.go")},
+		{sha: "docs", subject: "Update docs", date: day.Add(2 * time.Hour), files: files("parser.go")},
+		{sha: "cache", subject: "Add cache", date: day.Add(time.Hour), files: files("cache.go")},
+		{sha: "parser", subject: "Add parser", date: day, files: files("parser.go")},
+		{sha: "lexer", subject: "Add lexer", date: day, files: files("lexer.go")},
+	}
+	noted := map[string]bool{"parser": true, "cache": true, "lexer": true, "fix": true}
+	reverts := map[string]string{"cache": "revert"}
+
+	signals := flagCommits(history, noted, reverts)
+	if len(signals) != 2 {
+		t.Fatalf("flagged %v, want parser and cache", signals)
+	}
+	if got := signals["cache"]; len(got) != 1 || got[0].Reason != SignalReverted || got[0].Commit != "revert" || got[0].Message != `Revert "Add cache"` {
+		t.Errorf("cache = %+v", got)
+	}
+	if got := signals["parser"]; len(got) != 1 || got[0].Reason != SignalFollowUpFix || got[0].Commit != "fix" {
+		t.Errorf("parser = %+v", got)
+	}
+	// The lexer's fix came after FollowUpWindow
+	if got := signals["lexer"]; got != nil {
+		t.Errorf("lexer = %+v", got)
+	}
+}
@@ -0,0 +1,94 @@
+package storage
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/re-cinq/shift-log/internal/cli"
+	"github.com/re-cinq/shift-log/internal/config"
+	"github.com/re-cinq/shift-log/internal/git"
+)
+
+// readableWidth is the column readable notes wrap summaries at.
+const readableWidth = 72
+
+// ReadableNote describes the conversations of a commit in plain text, as
+// written to git.ReadableRef: the agent, model, branch and message count
+// of each, with its summary and tags, and how to see it in full.
+func ReadableNote(commitSHA string, conversations []*StoredConversation) []byte {
+	var b strings.Builder
+	if len(conversations) == 1 {
+		b.WriteString("shiftlog: 1 agent conversation\n")
+	} else {
+		fmt.Fprintf(&b, "shiftlog: %d agent conversations\n", len(conversations))
+	}
+	for _, sc := range conversations {
+		b.WriteString("\n")
+		b.WriteString(sc.AgentName())
+		if sc.Model != "" {
+			fmt.Fprintf(&b, " (%s)", sc.Model)
+		}
+		if sc.GitBranch != "" {
+			fmt.Fprintf(&b, " on %s", sc.GitBranch)
+		}
+		fmt.Fprintf(&b, ": %d messages, session %s\n", sc.MessageCount, sc.SessionID)
+		for _, line := range wrapWords(sc.Summary, readableWidth-4) {
+			b.WriteString("    " + line + "\n")
+		}
+		if len(sc.Tags) > 0 {
+			fmt.Fprintf(&b, "    Tags: %s\n", strings.Join(sc.Tags, ", "))
+		}
+	}
+	short := commitSHA
+	if len(short) > 7 {
+		short = short[:7]
+	}
+	fmt.Fprintf(&b, "\nFull transcript: shiftlog show %s\n", short)
+	return []byte(b.String())
+}
+
+// wrapWords breaks text into lines of at most width characters, between
+// words.
+func wrapWords(text string, width int) []string {
+	var lines []string
+	line := ""
+	for _, word := range strings.Fields(text) {
+		switch {
+		case line == "":
+			line = word
+		case len(line)+1+len(word) > width:
+			lines = append(lines, line)
+			line = word
+		default:
+			line += " " + word
+		}
+	}
+	if line != "" {
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// WriteReadableNote writes the readable note of a commit from the content
+// of its conversation note.
+func WriteReadableNote(commitSHA string, content []byte) error {
+	conversations, err := UnmarshalStoredConversations(content)
+	if err != nil {
+		return err
+	}
+	return git.AddNoteToRef(git.ReadableRef, commitSHA, ReadableNote(commitSHA, conversations))
+}
+
+// updateReadableNote writes the readable note of a commit whose
+// conversation note was written, when the repository asks for readable
+// notes. It does not fail the write: a missing readable note is only a
+// missing convenience, which shiftlog readable-notes writes again.
+func updateReadableNote(commitSHA string, content []byte) {
+	cfg, err := config.Read()
+	if err != nil || !cfg.ReadableNotes {
+		return
+	}
+	if err := WriteReadableNote(commitSHA, content); err != nil {
+		cli.LogDebug("could not write the readable note of %s: %v", commitSHA, err)
+	}
+}
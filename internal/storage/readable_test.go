@@ -0,0 +1,49 @@
+package storage
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestReadableNote(t *testing.T) {
+	conversations := []*StoredConversation{
+		{
+			Agent:        "claude",
+			Model:        "claude-sonnet-4-5",
+			GitBranch:    "main",
+			SessionID:    "s1",
+			MessageCount: 12,
+			Summary:      strings.Repeat("Fixed the retry backoff. ", 6),
+			Tags:         []string{"bugfix", "retry"},
+		},
+		{Agent: "codex", SessionID: "s2", MessageCount: 3},
+	}
+
+	note := string(ReadableNote("0123456789abcdef", conversations))
+	for _, want := range []string{
+		"shiftlog: 2 agent conversations\n",
+		"claude (claude-sonnet-4-5) on main: 12 messages, session s1\n",
+		"    Tags: bugfix, retry\n",
+		"\ncodex: 3 messages, session s2\n",
+		"\nFull transcript: shiftlog show 0123456\n",
+	} {
+		if !strings.Contains(note, want) {
+			t.Errorf("note is missing %q:\n%s", want, note)
+		}
+	}
+	for _, line := range strings.Split(note, "\n") {
+		if len(line) > readableWidth {
+			t.Errorf("line is %d characters, over %d: %q", len(line), readableWidth, line)
+		}
+	}
+}
+
+func TestWrapWords(t *testing.T) {
+	lines := wrapWords("one two  three\nfour", 9)
+	if strings.Join(lines, "|") != "one two|three|four" {
+		t.Errorf("wrapWords() = %q", lines)
+	}
+	if lines := wrapWords("", 9); lines != nil {
+		t.Errorf("wrapWords(\"\") = %q, want none", lines)
+	}
+}
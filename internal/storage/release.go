@@ -0,0 +1,95 @@
+package storage
+
+import (
+	"fmt"
+
+	"github.com/re-cinq/shift-log/internal/git"
+)
+
+// ReleaseConversation is a conversation of a release report.
+type ReleaseConversation struct {
+	AuthorshipRecord
+	SessionID string   `json:"session_id"`
+	Summary   string   `json:"summary,omitempty"`
+	Tags      []string `json:"tags,omitempty"`
+	// Cost is the estimated list price of the tokens used, when the model's
+	// price is known.
+	Cost *float64 `json:"estimated_cost_usd,omitempty"`
+}
+
+// ReleaseReport summarizes the conversations of the commits of a release,
+// for release retrospectives.
+type ReleaseReport struct {
+	From             string `json:"from,omitempty"` // empty for a first release
+	To               string `json:"to"`
+	Commits          int    `json:"commits"`
+	WithConversation int    `json:"with_conversation"`
+	// Stats counts each conversation of a commit, not only the first.
+	Stats         *Stats                `json:"stats"`
+	Cost          float64               `json:"estimated_cost_usd"` // of the conversations with a known price
+	Conversations []ReleaseConversation `json:"conversations"`
+}
+
+// BuildReleaseReport reports on the conversations of the commits reachable
+// from to but not from from, newest first. An empty from is the tag
+// before to, or the whole history of to when there is none.
+func BuildReleaseReport(from, to string) (*ReleaseReport, error) {
+	if from == "" {
+		from = git.PreviousTag(to)
+	}
+	report := &ReleaseReport{From: from, To: to, Conversations: []ReleaseConversation{}}
+
+	noted, err := ListAllConversationCommits()
+	if err != nil {
+		return nil, fmt.Errorf("could not list conversations: %w", err)
+	}
+
+	ref := to
+	if from != "" {
+		ref = from + ".." + to
+	}
+	var records []AuthorshipRecord
+	err = git.ListCommits(git.LogOptions{Ref: ref}, func(c git.LogCommit) bool {
+		report.Commits++
+		if !noted[c.SHA] {
+			return true
+		}
+		conversations, err := GetStoredConversations(c.SHA)
+		if err != nil || len(conversations) == 0 {
+			return true
+		}
+		report.WithConversation++
+		for _, sc := range conversations {
+			rc := ReleaseConversation{
+				AuthorshipRecord: AuthorshipRecord{
+					CommitSHA:  c.SHA,
+					CommitDate: c.Date,
+					CommitMsg:  c.Subject,
+					Author:     c.Author,
+					Agent:      sc.AgentName(),
+					Model:      sc.Model,
+					AIAssisted: sc.AIAssisted,
+					Authorship: sc.Authorship,
+					Effort:     sc.Effort,
+					Provenance: sc.GetProvenance(),
+				},
+				SessionID: sc.SessionID,
+				Summary:   sc.Summary,
+				Tags:      sc.Tags,
+			}
+			if cost, ok := EstimateCost(sc.Model, sc.Effort); ok && sc.Effort != nil {
+				rc.Cost = &cost
+				report.Cost += cost
+			}
+			report.Conversations = append(report.Conversations, rc)
+			records = append(records, rc.AuthorshipRecord)
+		}
+		return true
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not list commits of %s: %w", ref, err)
+	}
+
+	report.Stats = Summarize(records, report.Commits)
+	return report, nil
+}
@@ -1,6 +1,7 @@
 package storage
 
 import (
+	"context"
 	"fmt"
 	"regexp"
 	"strings"
@@ -8,6 +9,7 @@ import (
 
 	"github.com/re-cinq/shift-log/internal/agent"
 	"github.com/re-cinq/shift-log/internal/git"
+	"github.com/re-cinq/shift-log/internal/util"
 )
 
 // SearchParams defines the parameters for searching conversations.
@@ -16,6 +18,7 @@ type SearchParams struct {
 	Agent         string
 	Branch        string
 	Model         string
+	Tag           string
 	Before        time.Time
 	After         time.Time
 	Limit         int
@@ -23,6 +26,10 @@ type SearchParams struct {
 	MetadataOnly  bool
 	CaseSensitive bool
 	Regex         bool
+	// Errors searches the lines of tool results that report an error
+	// instead of the whole transcript; every conversation with one
+	// matches an empty query.
+	Errors bool
 }
 
 // SearchMatch represents a single text match within a conversation.
@@ -42,6 +49,8 @@ type SearchResult struct {
 	Branch     string
 	Model      string
 	MsgCount   int
+	Summary    string
+	Tags       []string
 	Matches    []SearchMatch
 }
 
@@ -173,6 +182,32 @@ func findMatches(text string, match matchFunc, contextLines int, maxMatches int)
 	return snippets
 }
 
+// blockSearchText returns the text of a content block that search looks
+// into, empty for blocks it skips, with the block's type and tool name.
+func blockSearchText(block agent.ContentBlock) (text, blockType, toolName string) {
+	switch block.Type {
+	case "text":
+		return block.Text, "text", ""
+	case "thinking":
+		return block.Thinking, "thinking", ""
+	case "tool_use":
+		// Search tool name + text content
+		text = block.Name
+		if block.Text != "" {
+			text += " " + block.Text
+		}
+		return text, "tool_use", block.Name
+	case "tool_result":
+		// Try to extract text from tool result content
+		text = block.Text
+		if text == "" && len(block.Content) > 0 {
+			text = string(block.Content)
+		}
+		return text, "tool_result", ""
+	}
+	return "", "", ""
+}
+
 // searchTranscript searches a parsed transcript for matches and returns SearchMatch entries.
 func searchTranscript(transcript *agent.Transcript, match matchFunc, contextLines int) []SearchMatch {
 	var matches []SearchMatch
@@ -193,36 +228,7 @@ func searchTranscript(transcript *agent.Transcript, match matchFunc, contextLine
 				break
 			}
 
-			var text string
-			var blockType string
-			var toolName string
-
-			switch block.Type {
-			case "text":
-				text = block.Text
-				blockType = "text"
-			case "thinking":
-				text = block.Thinking
-				blockType = "thinking"
-			case "tool_use":
-				blockType = "tool_use"
-				toolName = block.Name
-				// Search tool name + text content
-				text = block.Name
-				if block.Text != "" {
-					text += " " + block.Text
-				}
-			case "tool_result":
-				blockType = "tool_result"
-				// Try to extract text from tool result content
-				text = block.Text
-				if text == "" && len(block.Content) > 0 {
-					text = string(block.Content)
-				}
-			default:
-				continue
-			}
-
+			text, blockType, toolName := blockSearchText(block)
 			if text == "" {
 				continue
 			}
@@ -266,6 +272,10 @@ func matchesMetadata(stored *StoredConversation, commitDate string, params *Sear
 		}
 	}
 
+	if params.Tag != "" && !stored.HasTag(params.Tag) {
+		return false
+	}
+
 	if !params.Before.IsZero() || !params.After.IsZero() {
 		// Try parsing the commit date
 		t, err := parseDate(commitDate)
@@ -285,96 +295,135 @@ func matchesMetadata(stored *StoredConversation, commitDate string, params *Sear
 
 // parseDate tries common date formats from git.
 func parseDate(s string) (time.Time, error) {
-	formats := []string{
-		time.RFC3339,
-		"2006-01-02 15:04:05 -0700",
-		"2006-01-02T15:04:05Z",
-		"2006-01-02",
-	}
-	for _, f := range formats {
-		if t, err := time.Parse(f, strings.TrimSpace(s)); err == nil {
-			return t, nil
-		}
-	}
-	return time.Time{}, fmt.Errorf("could not parse date: %s", s)
+	return util.ParseTimestamp(s)
 }
 
-// Search searches conversations stored as git notes.
-func Search(params *SearchParams) ([]SearchResult, error) {
-	commits, err := git.ListCommitsWithNotes()
+// Search searches the stored conversations on the current branch. Their
+// metadata is read from the index, and only the transcripts whose terms
+// may match the query are read from their notes, Concurrency at a time,
+// until ctx is done. The tool errors searched with params.Errors are read
+// from the index, or from the transcripts the index has no entries of.
+func Search(ctx context.Context, params *SearchParams) ([]SearchResult, error) {
+	commits, err := ListConversationCommits()
 	if err != nil {
 		return nil, fmt.Errorf("could not list conversations: %w", err)
 	}
+	ix, err := OpenIndex(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("could not read the index: %w", err)
+	}
+	infos, err := git.DescribeCommits(commits)
+	if err != nil {
+		return nil, fmt.Errorf("could not read commits: %w", err)
+	}
 
 	var match matchFunc
+	var words []string
+	textSearch := params.Errors || (params.Query != "" && !params.MetadataOnly)
 	if params.Query != "" {
 		match, err = newMatcher(params)
 		if err != nil {
 			return nil, err
 		}
+		if !params.Regex && !params.Errors {
+			words = searchTerms(params.Query)
+		}
 	}
 
-	var results []SearchResult
-
+	// Every agent session that contributed to a commit is a candidate
+	type candidate struct {
+		result SearchResult
+		index  int  // of the conversation in its note
+		done   bool // matched from the index alone
+	}
+	var candidates []candidate
 	for _, sha := range commits {
-		if params.Limit > 0 && len(results) >= params.Limit {
-			break
-		}
-
-		// Get commit info
-		message, date, err := git.GetCommitInfo(sha)
-		if err != nil {
-			continue
-		}
-
-		// Get conversation metadata (cheap JSON parse, no decompression)
-		noteContent, err := git.GetNote(sha)
-		if err != nil {
-			continue
-		}
-		stored, err := UnmarshalStoredConversation(noteContent)
-		if err != nil {
+		info, ok := infos[sha]
+		if !ok {
 			continue
 		}
-
-		// Apply metadata filters
-		if !matchesMetadata(stored, date, params) {
-			continue
-		}
-
-		result := SearchResult{
-			CommitSHA:  sha,
-			CommitDate: date,
-			CommitMsg:  message,
-			Agent:      stored.Agent,
-			Branch:     stored.GitBranch,
-			Model:      stored.Model,
-			MsgCount:   stored.MessageCount,
-		}
-		if result.Agent == "" {
-			result.Agent = "claude"
+		for i, indexed := range ix.Shared.Commits[sha] {
+			stored := indexed.Conversation
+			if !matchesMetadata(stored, info.Date, params) {
+				continue
+			}
+			if textSearch && !indexed.MayContain(words) {
+				continue
+			}
+			var errorMatched []SearchMatch
+			if params.Errors && indexed.Entries > 0 {
+				if errorMatched = errorMatches(indexed.Errors, match); len(errorMatched) == 0 {
+					continue
+				}
+			}
+			candidates = append(candidates, candidate{index: i, done: errorMatched != nil, result: SearchResult{
+				CommitSHA:  sha,
+				CommitDate: info.Date,
+				CommitMsg:  info.Subject,
+				Agent:      stored.AgentName(),
+				Branch:     stored.GitBranch,
+				Model:      stored.Model,
+				MsgCount:   stored.MessageCount,
+				Summary:    stored.Summary,
+				Tags:       stored.Tags,
+				Matches:    errorMatched,
+			}})
 		}
+	}
 
-		// If no text query or metadata-only, emit result without matches
-		if params.Query == "" || params.MetadataOnly {
-			results = append(results, result)
-			continue
+	var results []SearchResult
+	full := func() bool { return params.Limit > 0 && len(results) >= params.Limit }
+	if !textSearch {
+		// Without a text query, every candidate is a result
+		for _, c := range candidates {
+			if full() {
+				break
+			}
+			results = append(results, c.result)
 		}
+		return results, nil
+	}
 
-		// Decompress and search transcript
-		transcript, err := stored.ParseTranscript()
+	// Transcripts are searched a batch at a time, in order, until enough
+	// of them match
+	batch := Concurrency() * 4
+	for start := 0; start < len(candidates) && !full(); start += batch {
+		window := candidates[start:min(start+batch, len(candidates))]
+		matches := make([][]SearchMatch, len(window))
+		err := ForEachParallel(ctx, len(window), func(i int) error {
+			c := window[i]
+			if c.done {
+				matches[i] = c.result.Matches
+				return nil
+			}
+			notes, err := GetStoredConversations(c.result.CommitSHA)
+			if err != nil || c.index >= len(notes) {
+				return nil
+			}
+			transcript, err := notes[c.index].ParseTranscript()
+			if err != nil {
+				return nil
+			}
+			if params.Errors {
+				matches[i] = errorMatches(toolErrors(transcript.Entries), match)
+			} else {
+				matches[i] = searchTranscript(transcript, match, params.ContextLines)
+			}
+			return nil
+		})
 		if err != nil {
-			continue
+			return nil, err
 		}
-
-		matches := searchTranscript(transcript, match, params.ContextLines)
-		if len(matches) == 0 {
-			continue
+		for i, c := range window {
+			if full() {
+				break
+			}
+			if len(matches[i]) == 0 {
+				continue
+			}
+			c.result.Matches = matches[i]
+			results = append(results, c.result)
 		}
-
-		result.Matches = matches
-		results = append(results, result)
 	}
-
 	return results, nil
 }
@@ -0,0 +1,62 @@
+package storage
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/re-cinq/shift-log/internal/git"
+)
+
+// Signature is a detached signature over a conversation's SigningPayload,
+// made with the storing user's git signing key.
+type Signature struct {
+	Format string `json:"format"` // gpg.format used to sign: openpgp or ssh
+	Value  string `json:"value"`  // ASCII-armored signature
+}
+
+// SignatureStatus is the outcome of checking a conversation's signature.
+type SignatureStatus struct {
+	Status string `json:"status"`           // "unsigned" or a commit signature status such as "good" or "bad"
+	Signer string `json:"signer,omitempty"` // key owner, when known
+	Format string `json:"format,omitempty"`
+}
+
+// SigningPayload returns the text a conversation signature covers: the
+// transcript checksum and the metadata recorded at store time. Fields that
+// later commands change, such as tags and summaries, are left out so they
+// do not invalidate the signature; neither is the commit, so notes that
+// follow a rebase stay verifiable.
+func (sc *StoredConversation) SigningPayload() []byte {
+	var b strings.Builder
+	b.WriteString("shiftlog conversation signature v1\n")
+	fmt.Fprintf(&b, "session: %s\n", sc.SessionID)
+	fmt.Fprintf(&b, "timestamp: %s\n", sc.Timestamp)
+	fmt.Fprintf(&b, "agent: %s\n", sc.Agent)
+	fmt.Fprintf(&b, "model: %s\n", sc.Model)
+	fmt.Fprintf(&b, "project: %s\n", sc.ProjectPath)
+	fmt.Fprintf(&b, "branch: %s\n", sc.GitBranch)
+	fmt.Fprintf(&b, "messages: %d\n", sc.MessageCount)
+	fmt.Fprintf(&b, "checksum: %s\n", sc.Checksum)
+	return []byte(b.String())
+}
+
+// Sign signs the conversation with the user's git signing key.
+func (sc *StoredConversation) Sign() error {
+	format, value, err := git.SignPayload(sc.SigningPayload())
+	if err != nil {
+		return err
+	}
+	sc.Signature = &Signature{Format: format, Value: value}
+	return nil
+}
+
+// VerifySignature checks the conversation's signature against its current
+// content. It does not check the transcript against the checksum; see
+// VerifyIntegrity.
+func (sc *StoredConversation) VerifySignature() SignatureStatus {
+	if sc.Signature == nil {
+		return SignatureStatus{Status: "unsigned"}
+	}
+	status, signer := git.VerifyPayload(sc.SigningPayload(), sc.Signature.Format, sc.Signature.Value)
+	return SignatureStatus{Status: status, Signer: signer, Format: sc.Signature.Format}
+}
@@ -0,0 +1,34 @@
+package storage
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestSigningPayload(t *testing.T) {
+	sc, err := NewStoredConversation("session-1", "/test/project", "main", 5, []byte(`{"type":"user"}`))
+	if err != nil {
+		t.Fatalf("NewStoredConversation() error: %v", err)
+	}
+	payload := sc.SigningPayload()
+
+	sc.Tags = []string{"reviewed"}
+	if !bytes.Equal(sc.SigningPayload(), payload) {
+		t.Error("SigningPayload() changed when tags were added")
+	}
+
+	sc.Checksum = "sha256:0000"
+	if bytes.Equal(sc.SigningPayload(), payload) {
+		t.Error("SigningPayload() did not change with the checksum")
+	}
+}
+
+func TestVerifySignatureUnsigned(t *testing.T) {
+	sc, err := NewStoredConversation("session-1", "/test/project", "main", 1, []byte(`{"type":"user"}`))
+	if err != nil {
+		t.Fatalf("NewStoredConversation() error: %v", err)
+	}
+	if got := sc.VerifySignature(); got.Status != "unsigned" {
+		t.Errorf("VerifySignature().Status = %q, want %q", got.Status, "unsigned")
+	}
+}
@@ -0,0 +1,479 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/re-cinq/shift-log/internal/agent"
+	"github.com/re-cinq/shift-log/internal/git"
+)
+
+// AuthorshipRecord is one row of the AI authorship report: a commit with a
+// stored conversation and the agent's share of its added lines.
+type AuthorshipRecord struct {
+	CommitSHA  string      `json:"commit"`
+	CommitDate string      `json:"date"`
+	CommitMsg  string      `json:"message"`
+	Author     string      `json:"author,omitempty"` // after .mailmap
+	Agent      string      `json:"agent"`
+	Model      string      `json:"model,omitempty"`
+	AIAssisted bool        `json:"ai_assisted"`
+	Authorship *Authorship `json:"authorship,omitempty"` // nil for notes stored before format version 5
+	Effort     *Effort     `json:"effort,omitempty"`
+	Provenance *Provenance `json:"provenance"` // derived from agent and model for notes stored before format version 10
+}
+
+// AgentStats aggregates conversations for a single agent, or another
+// grouping such as a model or an author.
+type AgentStats struct {
+	Conversations   int   `json:"conversations"`
+	AIAssisted      int   `json:"ai_assisted"`
+	AILines         int   `json:"ai_lines"`
+	TotalLines      int   `json:"total_lines"`
+	Turns           int   `json:"turns"`
+	Tokens          int64 `json:"tokens"`
+	DurationSeconds int64 `json:"duration_seconds"`
+}
+
+// add counts a conversation in the aggregate.
+func (a *AgentStats) add(r AuthorshipRecord) {
+	a.Conversations++
+	if r.AIAssisted {
+		a.AIAssisted++
+	}
+	if r.Authorship != nil {
+		a.AILines += r.Authorship.AILines
+		a.TotalLines += r.Authorship.TotalLines
+	}
+	if r.Effort != nil {
+		a.Turns += r.Effort.Turns
+		a.Tokens += r.Effort.TotalTokens()
+		a.DurationSeconds += r.Effort.DurationSeconds
+	}
+}
+
+// ModelStats aggregates conversations for a single model. A conversation
+// that used several models counts towards its primary model, the first one
+// it used; Messages counts the assistant messages of every model.
+type ModelStats struct {
+	AgentStats
+	Messages int `json:"messages"`
+}
+
+// Stats summarizes the conversations stored on the current branch.
+type Stats struct {
+	Commits         int                    `json:"commits"`       // commits reachable from HEAD
+	Conversations   int                    `json:"conversations"` // commits with a stored conversation
+	OptedOut        int                    `json:"opted_out"`     // commits without one whose author opted out of capture
+	Problematic     int                    `json:"problematic"`   // commits whose agent work was later reverted or quickly fixed
+	AIAssisted      int                    `json:"ai_assisted"`   // commits with at least one agent-written line
+	Measured        int                    `json:"measured"`      // conversations that record authorship
+	AILines         int                    `json:"ai_lines"`
+	TotalLines      int                    `json:"total_lines"`
+	AIRatio         float64                `json:"ai_ratio"` // AILines / TotalLines over measured commits
+	Turns           int                    `json:"turns"`
+	Tokens          int64                  `json:"tokens"`
+	DurationSeconds int64                  `json:"duration_seconds"` // wall-clock time of the sessions behind the commits
+	ToolSeconds     int64                  `json:"tool_seconds"`
+	Agents          map[string]*AgentStats `json:"agents"`
+	Models          map[string]*ModelStats `json:"models"` // conversations that record no model are left out
+	// Authors aggregates by commit author, whose names and emails are
+	// mapped through the repository's .mailmap.
+	Authors map[string]*AgentStats `json:"authors"`
+}
+
+// AgentNames returns the agents in Stats sorted by name.
+func (s *Stats) AgentNames() []string {
+	names := make([]string, 0, len(s.Agents))
+	for name := range s.Agents {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// AuthorNames returns the authors in Stats, most conversations first.
+func (s *Stats) AuthorNames() []string {
+	names := make([]string, 0, len(s.Authors))
+	for name := range s.Authors {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		a, b := s.Authors[names[i]], s.Authors[names[j]]
+		if a.Conversations != b.Conversations {
+			return a.Conversations > b.Conversations
+		}
+		return names[i] < names[j]
+	})
+	return names
+}
+
+// ModelNames returns the models in Stats sorted by name.
+func (s *Stats) ModelNames() []string {
+	names := make([]string, 0, len(s.Models))
+	for name := range s.Models {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// AuthorshipReport returns an authorship record for every commit on the
+// current branch that has a stored conversation, newest first. ctx bounds
+// bringing the index up to date.
+func AuthorshipReport(ctx context.Context) ([]AuthorshipRecord, error) {
+	commits, err := ListConversationCommits()
+	if err != nil {
+		return nil, fmt.Errorf("could not list conversations: %w", err)
+	}
+	ix, err := OpenIndex(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("could not read the index: %w", err)
+	}
+	infos, err := git.DescribeCommits(commits)
+	if err != nil {
+		return nil, fmt.Errorf("could not read commits: %w", err)
+	}
+
+	var records []AuthorshipRecord
+	for _, sha := range commits {
+		info, ok := infos[sha]
+		if !ok {
+			continue
+		}
+		conversations := ix.Conversations(sha)
+		if len(conversations) == 0 {
+			continue
+		}
+		stored := conversations[0]
+
+		record := AuthorshipRecord{
+			CommitSHA:  sha,
+			CommitDate: info.Date,
+			CommitMsg:  info.Subject,
+			Author:     info.Author,
+			Agent:      stored.Agent,
+			Model:      stored.Model,
+			AIAssisted: stored.AIAssisted,
+			Authorship: stored.Authorship,
+			Effort:     stored.Effort,
+			Provenance: stored.GetProvenance(),
+		}
+		if record.Agent == "" {
+			record.Agent = "claude"
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+// ComputeStats summarizes the conversations stored on the current branch.
+func ComputeStats(ctx context.Context) (*Stats, error) {
+	commits, err := git.CountCommits()
+	if err != nil {
+		return nil, fmt.Errorf("could not count commits: %w", err)
+	}
+	records, err := AuthorshipReport(ctx)
+	if err != nil {
+		return nil, err
+	}
+	s := Summarize(records, commits)
+	if s.OptedOut, err = countOptedOut(records); err != nil {
+		return nil, err
+	}
+	if s.Conversations > 0 {
+		signals, err := QualitySignals("HEAD")
+		if err != nil {
+			return nil, err
+		}
+		s.Problematic = len(signals)
+	}
+	return s, nil
+}
+
+// countOptedOut counts the commits reachable from HEAD that have an
+// omission and none of the conversations of records.
+func countOptedOut(records []AuthorshipRecord) (int, error) {
+	omitted, err := git.ListOmittedCommits()
+	if err != nil || len(omitted) == 0 {
+		return 0, err
+	}
+	for _, r := range records {
+		delete(omitted, r.CommitSHA)
+	}
+	commits, err := git.ListCommitsInRange("HEAD")
+	if err != nil {
+		return 0, fmt.Errorf("could not list commits: %w", err)
+	}
+	count := 0
+	for _, sha := range commits {
+		if omitted[sha] {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// Coverage is how many of a set of commits have a stored conversation.
+type Coverage struct {
+	Ref              string `json:"ref"`
+	Limit            int    `json:"limit"`
+	Commits          int    `json:"commits"`
+	WithConversation int    `json:"with_conversation"`
+	OptedOut         int    `json:"opted_out"` // commits without a conversation whose author opted out of capture
+}
+
+// Percent returns the share of the commits with a conversation, rounded
+// down to a whole percentage. Commits whose author opted out are not
+// expected to have one and are left out.
+func (c Coverage) Percent() int {
+	expected := c.Commits - c.OptedOut
+	if expected <= 0 {
+		return 0
+	}
+	return c.WithConversation * 100 / expected
+}
+
+// CommitCoverage counts the conversations of the last limit commits
+// reachable from ref, HEAD when empty. Merge commits are left out: they
+// are not made by an agent session.
+func CommitCoverage(ref string, limit int) (Coverage, error) {
+	if ref == "" {
+		ref = "HEAD"
+	}
+	cov := Coverage{Ref: ref, Limit: limit}
+	noted, err := ListAllConversationCommits()
+	if err != nil {
+		return cov, fmt.Errorf("could not list conversations: %w", err)
+	}
+	omitted, err := git.ListOmittedCommits()
+	if err != nil {
+		return cov, fmt.Errorf("could not list omitted conversations: %w", err)
+	}
+	err = git.ListCommits(git.LogOptions{Ref: ref}, func(c git.LogCommit) bool {
+		if c.Parents > 1 {
+			return true
+		}
+		cov.Commits++
+		if noted[c.SHA] {
+			cov.WithConversation++
+		} else if omitted[c.SHA] {
+			cov.OptedOut++
+		}
+		return limit <= 0 || cov.Commits < limit
+	})
+	if err != nil {
+		return cov, fmt.Errorf("could not list commits of %s: %w", ref, err)
+	}
+	return cov, nil
+}
+
+// Summarize aggregates authorship records into Stats.
+func Summarize(records []AuthorshipRecord, commits int) *Stats {
+	s := &Stats{Commits: commits, Agents: make(map[string]*AgentStats), Models: make(map[string]*ModelStats), Authors: make(map[string]*AgentStats)}
+	for _, r := range records {
+		agentStats := s.Agents[r.Agent]
+		if agentStats == nil {
+			agentStats = &AgentStats{}
+			s.Agents[r.Agent] = agentStats
+		}
+		agentStats.add(r)
+		s.addModels(r)
+		if r.Author != "" {
+			authorStats := s.Authors[r.Author]
+			if authorStats == nil {
+				authorStats = &AgentStats{}
+				s.Authors[r.Author] = authorStats
+			}
+			authorStats.add(r)
+		}
+
+		s.Conversations++
+		if r.AIAssisted {
+			s.AIAssisted++
+		}
+		if r.Authorship != nil {
+			s.Measured++
+			s.AILines += r.Authorship.AILines
+			s.TotalLines += r.Authorship.TotalLines
+		}
+		if r.Effort != nil {
+			s.Turns += r.Effort.Turns
+			s.Tokens += r.Effort.TotalTokens()
+			s.DurationSeconds += r.Effort.DurationSeconds
+			s.ToolSeconds += r.Effort.ToolSeconds
+		}
+	}
+	if s.TotalLines > 0 {
+		s.AIRatio = float64(s.AILines) / float64(s.TotalLines)
+	}
+	return s
+}
+
+// addModels counts a conversation towards its primary model and its
+// assistant messages towards every model it used.
+func (s *Stats) addModels(r AuthorshipRecord) {
+	models := []ModelUsage{{Model: r.Model}}
+	if r.Provenance != nil && len(r.Provenance.Models) > 0 {
+		models = r.Provenance.Models
+	}
+	for i, m := range models {
+		if m.Model == "" {
+			continue
+		}
+		modelStats := s.Models[m.Model]
+		if modelStats == nil {
+			modelStats = &ModelStats{}
+			s.Models[m.Model] = modelStats
+		}
+		if i == 0 {
+			modelStats.add(r)
+		}
+		modelStats.Messages += m.Messages
+	}
+}
+
+// TurnCost is the token usage of one assistant entry of a stored
+// conversation.
+type TurnCost struct {
+	CommitSHA string             `json:"commit"`
+	CommitMsg string             `json:"message"`
+	SessionID string             `json:"session_id"`
+	Agent     string             `json:"agent"`
+	Model     string             `json:"model,omitempty"`
+	EntryUUID string             `json:"uuid"`
+	Timestamp string             `json:"timestamp,omitempty"`
+	Preview   string             `json:"preview,omitempty"` // start of the entry's text
+	Usage     agent.UsageMetrics `json:"usage"`
+	Tokens    int64              `json:"tokens"` // input plus output tokens
+}
+
+// turnPreviewRunes caps TurnCost.Preview.
+const turnPreviewRunes = 80
+
+// ExpensiveTurns returns the assistant entries with the highest token usage
+// in the conversations stored on the current branch, at most limit of them.
+// A session stored on several commits repeats its earlier entries, so each
+// entry is reported once, for the commit that introduced it. Transcripts
+// that record no per-entry usage are skipped. The transcripts are parsed
+// Concurrency at a time, until ctx is done.
+func ExpensiveTurns(ctx context.Context, limit int) ([]TurnCost, error) {
+	commits, err := ListConversationCommits()
+	if err != nil {
+		return nil, fmt.Errorf("could not list conversations: %w", err)
+	}
+	infos, err := git.DescribeCommits(commits)
+	if err != nil {
+		return nil, fmt.Errorf("could not read commits: %w", err)
+	}
+
+	keyed := make([][]keyedTurn, len(commits))
+	err = ForEachParallel(ctx, len(commits), func(i int) error {
+		keyed[i] = commitTurns(commits[i], infos[commits[i]].Subject)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Commits are newest first, so older commits overwrite the entries
+	// they share with newer ones
+	turns := make(map[string]TurnCost)
+	for _, commitTurns := range keyed {
+		for _, turn := range commitTurns {
+			turns[turn.key] = turn.TurnCost
+		}
+	}
+
+	result := make([]TurnCost, 0, len(turns))
+	for _, turn := range turns {
+		result = append(result, turn)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Tokens != result[j].Tokens {
+			return result[i].Tokens > result[j].Tokens
+		}
+		return result[i].CommitSHA+result[i].EntryUUID < result[j].CommitSHA+result[j].EntryUUID
+	})
+	if limit > 0 && len(result) > limit {
+		result = result[:limit]
+	}
+	return result, nil
+}
+
+// keyedTurn is a TurnCost with the key that identifies its entry across
+// the commits a session is stored on.
+type keyedTurn struct {
+	TurnCost
+	key string
+}
+
+// commitTurns returns the assistant entries of the conversations stored for
+// sha that record their token usage.
+func commitTurns(sha, message string) []keyedTurn {
+	conversations, err := GetStoredConversations(sha)
+	if err != nil || conversations == nil {
+		return nil
+	}
+	var turns []keyedTurn
+	for _, stored := range conversations {
+		transcript, err := stored.ParseTranscript()
+		if err != nil {
+			continue
+		}
+		for i, entry := range transcript.Entries {
+			if entry.Type != agent.MessageTypeAssistant || entry.Usage == nil || entry.Usage.TotalTokens() == 0 {
+				continue
+			}
+			model := entry.Model
+			if model == "" {
+				model = transcript.Model
+			}
+			key := stored.AgentName() + "\x00" + stored.SessionID + "\x00" + entry.UUID
+			if entry.UUID == "" {
+				key += fmt.Sprintf("\x00%s\x00%d", sha, i)
+			}
+			turns = append(turns, keyedTurn{key: key, TurnCost: TurnCost{
+				CommitSHA: sha,
+				CommitMsg: message,
+				SessionID: stored.SessionID,
+				Agent:     stored.AgentName(),
+				Model:     model,
+				EntryUUID: entry.UUID,
+				Timestamp: entry.Timestamp,
+				Preview:   turnPreview(entry),
+				Usage:     *entry.Usage,
+				Tokens:    entry.Usage.TotalTokens(),
+			}})
+		}
+	}
+	return turns
+}
+
+// turnPreview returns the start of an assistant entry's text, or the name
+// of its first tool call when it has no text.
+func turnPreview(entry agent.TranscriptEntry) string {
+	if entry.Message == nil {
+		return ""
+	}
+	var tool string
+	for _, block := range entry.Message.Content {
+		if block.Type == "text" && strings.TrimSpace(block.Text) != "" {
+			text := strings.Join(strings.Fields(block.Text), " ")
+			if utf8.RuneCountInString(text) > turnPreviewRunes {
+				text = string([]rune(text)[:turnPreviewRunes]) + "..."
+			}
+			return text
+		}
+		if block.Type == "tool_use" && tool == "" {
+			tool = block.Name
+		}
+	}
+	if tool != "" {
+		return "Used tool: " + tool
+	}
+	return ""
+}
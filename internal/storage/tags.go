@@ -0,0 +1,160 @@
+package storage
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/re-cinq/shift-log/internal/cli"
+	"github.com/re-cinq/shift-log/internal/git"
+)
+
+var validTag = regexp.MustCompile(`^[a-z0-9][a-z0-9._-]*$`)
+
+// NormalizeTag lowercases and trims a tag and checks that it only contains
+// letters, digits, '.', '_' and '-'.
+func NormalizeTag(tag string) (string, error) {
+	t := strings.ToLower(strings.TrimSpace(tag))
+	if !validTag.MatchString(t) {
+		return "", fmt.Errorf("invalid tag %q: use letters, digits, '.', '_' or '-'", tag)
+	}
+	return t, nil
+}
+
+// AddTags adds tags to the conversation, keeping the list sorted and unique.
+// Returns true if the list changed.
+func (sc *StoredConversation) AddTags(tags ...string) bool {
+	merged := unionTags(sc.Tags, tags)
+	if slices.Equal(merged, sc.Tags) {
+		return false
+	}
+	sc.Tags = merged
+	return true
+}
+
+// RemoveTags removes tags from the conversation. Returns true if the list changed.
+func (sc *StoredConversation) RemoveTags(tags ...string) bool {
+	kept := slices.DeleteFunc(slices.Clone(sc.Tags), func(t string) bool {
+		return slices.Contains(tags, t)
+	})
+	if len(kept) == len(sc.Tags) {
+		return false
+	}
+	if len(kept) == 0 {
+		kept = nil
+	}
+	sc.Tags = kept
+	return true
+}
+
+// HasTag reports whether the conversation carries tag (case-insensitive).
+func (sc *StoredConversation) HasTag(tag string) bool {
+	return slices.Contains(sc.Tags, strings.ToLower(tag))
+}
+
+func unionTags(a, b []string) []string {
+	if len(a)+len(b) == 0 {
+		return nil
+	}
+	merged := append(slices.Clone(a), b...)
+	slices.Sort(merged)
+	return slices.Compact(merged)
+}
+
+// mergeConversations combines two copies of the same conversation that were
+// annotated independently in different clones. Tags are unioned and a
+// summary present on either side is kept. Returns nil if the notes are not
+// copies of the same conversation.
+func mergeConversations(local, remote *StoredConversation) *StoredConversation {
+	if local.SessionID != remote.SessionID || local.Checksum != remote.Checksum {
+		return nil
+	}
+	merged := *local
+	merged.Tags = unionTags(local.Tags, remote.Tags)
+	if merged.Summary == "" {
+		merged.Summary = remote.Summary
+	}
+	return &merged
+}
+
+// mergeConversationLists combines the conversations of a note stored
+// independently in two clones: copies of the same session are merged with
+// mergeConversations and sessions stored in only one clone are kept, local
+// ones first. Returns nil if a session has different transcripts.
+func mergeConversationLists(local, remote []*StoredConversation) []*StoredConversation {
+	merged := append([]*StoredConversation(nil), local...)
+	for _, r := range remote {
+		i := IndexOfSession(merged, r)
+		if i < 0 {
+			merged = append(merged, r)
+			continue
+		}
+		m := mergeConversations(merged[i], r)
+		if m == nil {
+			return nil
+		}
+		merged[i] = m
+	}
+	return merged
+}
+
+// ReconcileTrackingNotes prepares the fetched remote notes for merging.
+// Notes are JSON documents, so the line-based cat_sort_uniq merge would
+// interleave two copies of a note that differ only in metadata such as
+// tags, or in the agent sessions stored on the commit. For each such note
+// the merged conversations are written to both the local and tracking
+// refs, so git sees identical changes and keeps a single copy. Notes with
+// different transcripts of the same session are left for cat_sort_uniq as
+// before.
+func ReconcileTrackingNotes() error {
+	local, err := git.ListNoteBlobs(git.NotesRef)
+	if err != nil {
+		return err
+	}
+	remote, err := git.ListNoteBlobs(git.NotesTrackingRef)
+	if err != nil {
+		return err
+	}
+
+	for sha, remoteBlob := range remote {
+		localBlob, ok := local[sha]
+		if !ok || localBlob == remoteBlob {
+			continue
+		}
+
+		localConv, err := readConversations(git.NotesRef, sha)
+		if err != nil {
+			continue
+		}
+		remoteConv, err := readConversations(git.NotesTrackingRef, sha)
+		if err != nil {
+			continue
+		}
+		merged := mergeConversationLists(localConv, remoteConv)
+		if merged == nil {
+			cli.LogDebug("sync pull: %s has different conversations locally and remotely", sha[:8])
+			continue
+		}
+
+		content, err := MarshalStoredConversations(merged)
+		if err != nil {
+			return err
+		}
+		for _, ref := range []string{git.NotesRef, git.NotesTrackingRef} {
+			if err := git.AddNoteToRef(ref, sha, content); err != nil {
+				return fmt.Errorf("could not update note for %s in %s: %w", sha[:7], ref, err)
+			}
+		}
+		cli.LogDebug("sync pull: merged metadata for %s", sha[:8])
+	}
+	return nil
+}
+
+func readConversations(ref, sha string) ([]*StoredConversation, error) {
+	data, err := git.GetNoteFromRef(ref, sha)
+	if err != nil {
+		return nil, err
+	}
+	return UnmarshalStoredConversations(data)
+}
@@ -0,0 +1,107 @@
+package storage
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestNormalizeTag(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    string
+		wantErr bool
+	}{
+		{"bugfix", "bugfix", false},
+		{" Prompt-Engineering ", "prompt-engineering", false},
+		{"v1.2_rc", "v1.2_rc", false},
+		{"", "", true},
+		{"has space", "", true},
+		{"-leading", "", true},
+		{"a,b", "", true},
+	}
+	for _, tt := range tests {
+		got, err := NormalizeTag(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("NormalizeTag(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("NormalizeTag(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestAddRemoveTags(t *testing.T) {
+	sc := &StoredConversation{}
+
+	if !sc.AddTags("review", "bugfix", "review") {
+		t.Error("AddTags() = false, want true")
+	}
+	if want := []string{"bugfix", "review"}; !slices.Equal(sc.Tags, want) {
+		t.Errorf("Tags = %v, want %v", sc.Tags, want)
+	}
+	if sc.AddTags("bugfix") {
+		t.Error("AddTags() of existing tag = true, want false")
+	}
+	if !sc.HasTag("Review") {
+		t.Error("HasTag(Review) = false, want true")
+	}
+
+	if sc.RemoveTags("missing") {
+		t.Error("RemoveTags() of missing tag = true, want false")
+	}
+	if !sc.RemoveTags("bugfix", "review") {
+		t.Error("RemoveTags() = false, want true")
+	}
+	if sc.Tags != nil {
+		t.Errorf("Tags = %v, want nil", sc.Tags)
+	}
+}
+
+func TestMergeConversations(t *testing.T) {
+	local := &StoredConversation{SessionID: "s1", Checksum: "sha256:a", Tags: []string{"bugfix"}}
+	remote := &StoredConversation{SessionID: "s1", Checksum: "sha256:a", Tags: []string{"training"}, Summary: "Fixed it."}
+
+	merged := mergeConversations(local, remote)
+	if merged == nil {
+		t.Fatal("mergeConversations() = nil, want merged conversation")
+	}
+	if want := []string{"bugfix", "training"}; !slices.Equal(merged.Tags, want) {
+		t.Errorf("Tags = %v, want %v", merged.Tags, want)
+	}
+	if merged.Summary != "Fixed it." {
+		t.Errorf("Summary = %q, want %q", merged.Summary, "Fixed it.")
+	}
+	if len(local.Tags) != 1 {
+		t.Errorf("local.Tags modified: %v", local.Tags)
+	}
+
+	other := &StoredConversation{SessionID: "s2", Checksum: "sha256:b"}
+	if mergeConversations(local, other) != nil {
+		t.Error("mergeConversations() of different conversations should return nil")
+	}
+}
+
+func TestMergeConversationLists(t *testing.T) {
+	local := []*StoredConversation{{SessionID: "s1", Checksum: "sha256:a", Tags: []string{"bugfix"}}}
+	remote := []*StoredConversation{
+		{SessionID: "s1", Checksum: "sha256:a", Tags: []string{"training"}},
+		{SessionID: "s2", Agent: "codex", Checksum: "sha256:b"},
+	}
+
+	merged := mergeConversationLists(local, remote)
+	if len(merged) != 2 {
+		t.Fatalf("mergeConversationLists() returned %d conversations, want 2", len(merged))
+	}
+	if want := []string{"bugfix", "training"}; !slices.Equal(merged[0].Tags, want) {
+		t.Errorf("Tags = %v, want %v", merged[0].Tags, want)
+	}
+	if merged[1].SessionID != "s2" {
+		t.Errorf("second conversation = %q, want s2", merged[1].SessionID)
+	}
+
+	conflict := []*StoredConversation{{SessionID: "s1", Checksum: "sha256:c"}}
+	if mergeConversationLists(local, conflict) != nil {
+		t.Error("mergeConversationLists() with different transcripts of a session should return nil")
+	}
+}
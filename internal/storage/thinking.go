@@ -0,0 +1,172 @@
+package storage
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/re-cinq/shift-log/internal/agent"
+	"github.com/re-cinq/shift-log/internal/config"
+)
+
+// OmitThinking leaves the thinking of the models out of transcript data as
+// policy says: config.ThinkingStrip drops its text, config.ThinkingHash
+// replaces it with "sha256:" and its hex SHA-256, and an empty policy keeps
+// it. It returns the transcript and the number of blocks rewritten, each
+// marked with how its thinking was omitted so that viewers can say so.
+//
+// It rewrites Claude Code and Goose thinking blocks, Claude Code's
+// redacted_thinking blocks and Codex reasoning items, dropping their
+// signatures and encrypted content in both modes. Blocks omitted by the
+// same policy before are left as they are. Transcripts are JSON documents
+// or JSONL; lines without thinking are kept byte for byte.
+func OmitThinking(transcriptData []byte, policy string) ([]byte, int, error) {
+	var mark string
+	switch policy {
+	case "":
+		return transcriptData, 0, nil
+	case config.ThinkingStrip:
+		mark = agent.ThinkingStripped
+	case config.ThinkingHash:
+		mark = agent.ThinkingHashed
+	default:
+		return nil, 0, fmt.Errorf("invalid thinking %q: must be %s or %s", policy, config.ThinkingStrip, config.ThinkingHash)
+	}
+	if !mayHaveThinking(transcriptData) {
+		return transcriptData, 0, nil
+	}
+
+	omitted := 0
+	rewrite := func(raw []byte) []byte {
+		dec := json.NewDecoder(bytes.NewReader(raw))
+		dec.UseNumber()
+		var v any
+		if err := dec.Decode(&v); err != nil {
+			return raw
+		}
+		n := omitThinking(v, mark)
+		if n == 0 {
+			return raw
+		}
+		var buf bytes.Buffer
+		enc := json.NewEncoder(&buf)
+		enc.SetEscapeHTML(false)
+		if err := enc.Encode(v); err != nil {
+			return raw
+		}
+		omitted += n
+		return bytes.TrimSuffix(buf.Bytes(), []byte("\n"))
+	}
+
+	if json.Valid(transcriptData) {
+		return rewrite(transcriptData), omitted, nil
+	}
+	lines := bytes.Split(transcriptData, []byte("\n"))
+	for i, line := range lines {
+		if mayHaveThinking(line) {
+			lines[i] = rewrite(line)
+		}
+	}
+	return bytes.Join(lines, []byte("\n")), omitted, nil
+}
+
+// mayHaveThinking reports whether data names a block type OmitThinking
+// rewrites.
+func mayHaveThinking(data []byte) bool {
+	return bytes.Contains(data, []byte(`"thinking"`)) ||
+		bytes.Contains(data, []byte(`"redacted_thinking"`)) ||
+		bytes.Contains(data, []byte(`"reasoning"`))
+}
+
+// hashThinking returns the replacement of a thinking text under
+// config.ThinkingHash.
+func hashThinking(text string) string {
+	sum := sha256.Sum256([]byte(text))
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// omitThinking rewrites the thinking blocks of the decoded JSON value v, at
+// any depth, marking them with mark, and returns how many it rewrote.
+func omitThinking(v any, mark string) int {
+	switch v := v.(type) {
+	case map[string]any:
+		switch v["type"] {
+		case "thinking":
+			return omitThinkingBlock(v, mark)
+		case "redacted_thinking":
+			// Encrypted by the provider, with no text to hash
+			delete(v, "data")
+			v["type"] = "thinking"
+			v["thinking"] = ""
+			v["omitted"] = agent.ThinkingStripped
+			return 1
+		case "reasoning":
+			return omitReasoning(v, mark)
+		}
+		n := 0
+		for _, child := range v {
+			n += omitThinking(child, mark)
+		}
+		return n
+	case []any:
+		n := 0
+		for _, child := range v {
+			n += omitThinking(child, mark)
+		}
+		return n
+	}
+	return 0
+}
+
+// omitThinkingBlock rewrites a Claude Code or Goose thinking block.
+func omitThinkingBlock(block map[string]any, mark string) int {
+	text, _ := block["thinking"].(string)
+	_, signed := block["signature"]
+	previous, _ := block["omitted"].(string)
+	switch {
+	case previous == mark || previous == agent.ThinkingStripped:
+		return 0
+	case previous == "" && text == "" && !signed:
+		return 0
+	}
+	delete(block, "signature")
+	if mark == agent.ThinkingHashed {
+		block["thinking"] = hashThinking(text)
+	} else {
+		block["thinking"] = ""
+	}
+	block["omitted"] = mark
+	return 1
+}
+
+// omitReasoning rewrites a Codex reasoning item, whose readable part is the
+// text of its summary.
+func omitReasoning(item map[string]any, mark string) int {
+	previous, _ := item["omitted"].(string)
+	if previous == mark || previous == agent.ThinkingStripped {
+		return 0
+	}
+	var text []string
+	summary, _ := item["summary"].([]any)
+	for _, part := range summary {
+		if p, ok := part.(map[string]any); ok {
+			if t, ok := p["text"].(string); ok && t != "" {
+				text = append(text, t)
+			}
+		}
+	}
+	if previous == "" && len(text) == 0 && item["encrypted_content"] == nil && item["content"] == nil {
+		return 0
+	}
+	delete(item, "encrypted_content")
+	delete(item, "content")
+	item["summary"] = []any{}
+	if mark == agent.ThinkingHashed && len(text) > 0 {
+		item["summary"] = []any{map[string]any{"type": "summary_text", "text": hashThinking(strings.Join(text, "\n\n"))}}
+	}
+	item["omitted"] = mark
+	return 1
+}
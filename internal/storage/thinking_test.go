@@ -0,0 +1,88 @@
+package storage
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/re-cinq/shift-log/internal/agent/claude"
+	"github.com/re-cinq/shift-log/internal/agent/codex"
+	"github.com/re-cinq/shift-log/internal/config"
+)
+
+func TestOmitThinking(t *testing.T) {
+	user := `{"type":"user","uuid":"u1","message":{"role":"user","content":"Think <hard>"}}`
+	transcript := []byte(user + "\n" +
+		`{"type":"assistant","uuid":"a1","message":{"role":"assistant","content":[` +
+		`{"type":"thinking","thinking":"secret plan","signature":"sig"},` +
+		`{"type":"redacted_thinking","data":"opaque"},` +
+		`{"type":"text","text":"Done"}]}}`)
+
+	stripped, n, err := OmitThinking(transcript, config.ThinkingStrip)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 {
+		t.Errorf("stripped %d blocks, want 2", n)
+	}
+	if !bytes.HasPrefix(stripped, []byte(user+"\n")) {
+		t.Errorf("line without thinking should be kept byte for byte: %s", stripped)
+	}
+	for _, gone := range []string{"secret plan", "sig", "opaque"} {
+		if bytes.Contains(stripped, []byte(gone)) {
+			t.Errorf("stripped transcript still holds %q: %s", gone, stripped)
+		}
+	}
+	parsed, err := claude.ParseJSONLTranscript(bytes.NewReader(stripped))
+	if err != nil {
+		t.Fatal(err)
+	}
+	blocks := parsed.Entries[1].Message.Content
+	if blocks[0].Omitted != "stripped" || blocks[1].Omitted != "stripped" || blocks[2].Text != "Done" {
+		t.Errorf("parsed blocks = %+v, want two omitted thinking blocks and the text", blocks)
+	}
+	if again, n, _ := OmitThinking(stripped, config.ThinkingStrip); n != 0 || !bytes.Equal(again, stripped) {
+		t.Errorf("stripping again rewrote %d blocks", n)
+	}
+
+	hashed, n, err := OmitThinking(transcript, config.ThinkingHash)
+	if err != nil || n != 2 {
+		t.Fatalf("OmitThinking(hash) = %d, %v, want 2 blocks", n, err)
+	}
+	if !bytes.Contains(hashed, []byte(`"thinking":"`+hashThinking("secret plan")+`"`)) || bytes.Contains(hashed, []byte("secret plan")) {
+		t.Errorf("hashed transcript = %s", hashed)
+	}
+
+	if _, _, err := OmitThinking(transcript, "shred"); err == nil {
+		t.Error("OmitThinking should reject an unknown policy")
+	}
+	if kept, n, _ := OmitThinking(transcript, ""); n != 0 || !bytes.Equal(kept, transcript) {
+		t.Error("an empty policy should keep the thinking")
+	}
+}
+
+func TestOmitThinkingCodex(t *testing.T) {
+	transcript := []byte(`{"timestamp":"2025-01-01T00:00:00Z","type":"response_item","payload":{"type":"reasoning",` +
+		`"summary":[{"type":"summary_text","text":"**Planning** the fix"}],"encrypted_content":"gAAAA"}}`)
+
+	hashed, n, err := OmitThinking(transcript, config.ThinkingHash)
+	if err != nil || n != 1 {
+		t.Fatalf("OmitThinking(hash) = %d, %v, want 1 item", n, err)
+	}
+	if bytes.Contains(hashed, []byte("Planning")) || bytes.Contains(hashed, []byte("gAAAA")) {
+		t.Errorf("hashed reasoning still holds its text: %s", hashed)
+	}
+	parsed, err := (&codex.Agent{}).ParseTranscript(bytes.NewReader(hashed))
+	if err != nil {
+		t.Fatal(err)
+	}
+	block := parsed.Entries[0].Message.Content[0]
+	if block.Omitted != "hashed" || !strings.HasPrefix(block.Thinking, "sha256:") {
+		t.Errorf("parsed reasoning = %+v, want its hash", block)
+	}
+
+	stripped, n, _ := OmitThinking(hashed, config.ThinkingStrip)
+	if n != 1 || bytes.Contains(stripped, []byte("sha256:")) {
+		t.Errorf("stripping hashed reasoning = %d blocks: %s", n, stripped)
+	}
+}
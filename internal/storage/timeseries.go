@@ -0,0 +1,115 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/re-cinq/shift-log/internal/git"
+)
+
+// Intervals of an effort time series.
+const (
+	IntervalDay  = "day"
+	IntervalWeek = "week"
+)
+
+// EffortPoint is the effort of the commits of one interval of a time
+// series: the commits made in it, the conversations stored on them, and
+// the tokens those used with their cost at list price. Conversations of
+// models without a known price add no cost.
+type EffortPoint struct {
+	Start         time.Time `json:"start"` // midnight UTC of the first day of the interval
+	Commits       int       `json:"commits"`
+	Conversations int       `json:"conversations"`
+	Tokens        int64     `json:"tokens"`
+	Cost          float64   `json:"cost"`
+}
+
+// intervalStart returns the start of the interval t falls in: its day, or
+// the Monday of its week, at midnight UTC.
+func intervalStart(t time.Time, interval string) time.Time {
+	t = t.UTC()
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	if interval == IntervalWeek {
+		// Weeks start on Monday, as ISO weeks do
+		offset := (int(day.Weekday()) + 6) % 7
+		day = day.AddDate(0, 0, -offset)
+	}
+	return day
+}
+
+// nextInterval returns the start of the interval after the one starting at
+// start.
+func nextInterval(start time.Time, interval string) time.Time {
+	if interval == IntervalWeek {
+		return start.AddDate(0, 0, 7)
+	}
+	return start.AddDate(0, 0, 1)
+}
+
+// EffortTimeSeries returns the effort of the commits on the current branch
+// per interval, IntervalDay or IntervalWeek, oldest first. Commits are
+// placed by their commit date in UTC, and intervals without commits between
+// the first and the last are included with zeros, so that the series can be
+// plotted as is. ctx bounds bringing the index up to date.
+func EffortTimeSeries(ctx context.Context, interval string) ([]EffortPoint, error) {
+	if interval != IntervalDay && interval != IntervalWeek {
+		return nil, fmt.Errorf("invalid interval %q: must be %s or %s", interval, IntervalDay, IntervalWeek)
+	}
+	count, err := git.CountCommits()
+	if err != nil {
+		return nil, fmt.Errorf("could not count commits: %w", err)
+	}
+	if count == 0 {
+		return nil, nil
+	}
+	ix, err := OpenIndex(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("could not read the index: %w", err)
+	}
+
+	points := make(map[time.Time]*EffortPoint)
+	var first, last time.Time
+	err = git.ListCommits(git.LogOptions{}, func(c git.LogCommit) bool {
+		date, err := time.Parse("2006-01-02 15:04:05 -0700", c.Date)
+		if err != nil {
+			return true
+		}
+		start := intervalStart(date, interval)
+		p := points[start]
+		if p == nil {
+			p = &EffortPoint{Start: start}
+			points[start] = p
+		}
+		if first.IsZero() || start.Before(first) {
+			first = start
+		}
+		if start.After(last) {
+			last = start
+		}
+
+		p.Commits++
+		for _, sc := range ix.Conversations(c.SHA) {
+			p.Conversations++
+			p.Tokens += sc.Effort.TotalTokens()
+			if cost, ok := EstimateCost(sc.Model, sc.Effort); ok && sc.Effort != nil {
+				p.Cost += cost
+			}
+		}
+		return true
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not list commits: %w", err)
+	}
+
+	var series []EffortPoint
+	for start := first; len(points) > 0 && !start.After(last); start = nextInterval(start, interval) {
+		if p := points[start]; p != nil {
+			series = append(series, *p)
+		} else {
+			series = append(series, EffortPoint{Start: start})
+		}
+	}
+	return series, nil
+}
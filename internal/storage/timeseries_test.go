@@ -0,0 +1,24 @@
+package storage
+
+import (
+	"testing"
+	"time"
+)
+
+func TestIntervalStart(t *testing.T) {
+	// A Wednesday evening in UTC-5, Thursday in UTC
+	date := time.Date(2025, 3, 5, 21, 30, 0, 0, time.FixedZone("", -5*3600))
+	if got, want := intervalStart(date, IntervalDay), time.Date(2025, 3, 6, 0, 0, 0, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("day = %v, want %v", got, want)
+	}
+	if got, want := intervalStart(date, IntervalWeek), time.Date(2025, 3, 3, 0, 0, 0, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("week = %v, want %v", got, want)
+	}
+	sunday := time.Date(2025, 3, 9, 12, 0, 0, 0, time.UTC)
+	if got, want := intervalStart(sunday, IntervalWeek), time.Date(2025, 3, 3, 0, 0, 0, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("week of a Sunday = %v, want %v", got, want)
+	}
+	if got, want := nextInterval(time.Date(2025, 3, 3, 0, 0, 0, 0, time.UTC), IntervalWeek), time.Date(2025, 3, 10, 0, 0, 0, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("next week = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,130 @@
+package storage
+
+import (
+	"encoding/json"
+	"regexp"
+	"strings"
+
+	"github.com/re-cinq/shift-log/internal/agent"
+)
+
+const (
+	// maxToolErrors caps the error lines recorded per conversation.
+	maxToolErrors = 50
+	// maxToolErrorLength caps the characters recorded of an error line.
+	maxToolErrorLength = 200
+)
+
+// errorLinePatterns match the lines of a tool result that report a failure:
+// a non-zero exit, an exception or error with its type, the start of a
+// stack trace, a panic, or a failed test.
+var errorLinePatterns = []*regexp.Regexp{
+	regexp.MustCompile(`(?i)\b(exit code|exit status|exited with code|exit_code)[:=]?\s*-?[1-9][0-9]*\b`),
+	regexp.MustCompile(`\b[A-Z][A-Za-z0-9_.]*(Error|Exception)\b(:|$)`),
+	regexp.MustCompile(`^Traceback \(most recent call last\)`),
+	regexp.MustCompile(`(?i)^(error|fatal|panic)(\[[^\]]*\])?:`),
+	regexp.MustCompile(`^(--- )?FAIL\b`),
+	regexp.MustCompile(`^npm ERR!`),
+}
+
+// toolErrors returns the lines of the tool results of entries that report
+// an error, deduplicated, in transcript order: those matching
+// errorLinePatterns, and the first line of a result its agent marked as an
+// error when none of its lines match.
+func toolErrors(entries []agent.TranscriptEntry) []string {
+	var errors []string
+	seen := make(map[string]bool)
+	add := func(line string) {
+		if len(errors) >= maxToolErrors || seen[line] {
+			return
+		}
+		seen[line] = true
+		errors = append(errors, line)
+	}
+	for _, entry := range entries {
+		if entry.Message == nil {
+			continue
+		}
+		for _, block := range entry.Message.Content {
+			if block.Type != "tool_result" {
+				continue
+			}
+			first, found := "", false
+			for _, line := range strings.Split(toolResultText(block), "\n") {
+				line = strings.TrimSpace(line)
+				if line == "" {
+					continue
+				}
+				if r := []rune(line); len(r) > maxToolErrorLength {
+					line = string(r[:maxToolErrorLength])
+				}
+				if first == "" {
+					first = line
+				}
+				if isErrorLine(line) {
+					found = true
+					add(line)
+				}
+			}
+			if block.IsError && !found && first != "" {
+				add(first)
+			}
+		}
+	}
+	return errors
+}
+
+// isErrorLine reports whether a line of a tool result reports a failure.
+func isErrorLine(line string) bool {
+	for _, re := range errorLinePatterns {
+		if re.MatchString(line) {
+			return true
+		}
+	}
+	return false
+}
+
+// toolResultText returns the text of a tool_result block, whose content
+// agents record as a string or as text blocks.
+func toolResultText(block agent.ContentBlock) string {
+	if block.Text != "" || len(block.Content) == 0 {
+		return block.Text
+	}
+	var s string
+	if err := json.Unmarshal(block.Content, &s); err == nil {
+		return s
+	}
+	var blocks []struct {
+		Text string `json:"text"`
+	}
+	if err := json.Unmarshal(block.Content, &blocks); err == nil {
+		texts := make([]string, len(blocks))
+		for i, b := range blocks {
+			texts[i] = b.Text
+		}
+		return strings.Join(texts, "\n")
+	}
+	return string(block.Content)
+}
+
+// errorMatches returns the error lines that match, all of them when match
+// is nil, as search matches in the tool results they were found in.
+func errorMatches(errors []string, match matchFunc) []SearchMatch {
+	var matches []SearchMatch
+	for _, line := range errors {
+		if len(matches) >= maxMatchesPerConversation {
+			break
+		}
+		if match != nil {
+			if idx, _ := match(line); idx < 0 {
+				continue
+			}
+		}
+		matches = append(matches, SearchMatch{
+			EntryType: string(agent.MessageTypeUser),
+			BlockType: "tool_result",
+			Snippet:   line,
+		})
+	}
+	return matches
+}
@@ -0,0 +1,62 @@
+package storage
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/re-cinq/shift-log/internal/agent"
+)
+
+func TestToolErrors(t *testing.T) {
+	result := func(content string, isError bool) agent.TranscriptEntry {
+		return agent.TranscriptEntry{Type: agent.MessageTypeUser, Message: &agent.Message{Content: []agent.ContentBlock{
+			{Type: "tool_result", Content: json.RawMessage(content), IsError: isError},
+		}}}
+	}
+	entries := []agent.TranscriptEntry{
+		result(`"ok\nall tests passed"`, false),
+		result(`"Traceback (most recent call last):\n  File \"app.py\", line 3\nTypeError: 'NoneType' object is not subscriptable"`, false),
+		result(`[{"type":"text","text":"npm test\nExit code 1"}]`, false),
+		result(`"permission denied"`, true),
+		result(`"TypeError: 'NoneType' object is not subscriptable"`, false),
+		{Type: agent.MessageTypeAssistant, Message: &agent.Message{Content: []agent.ContentBlock{
+			{Type: "text", Text: "Error: this is the assistant talking"},
+		}}},
+	}
+
+	got := toolErrors(entries)
+	want := []string{
+		"Traceback (most recent call last):",
+		"TypeError: 'NoneType' object is not subscriptable",
+		"Exit code 1",
+		"permission denied",
+	}
+	if len(got) != len(want) {
+		t.Fatalf("toolErrors() = %q, want %q", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("toolErrors()[%d] = %q, want %q", i, got[i], want[i])
+		}
+	}
+
+	for _, line := range []string{"exit status 0", "Exit code 0", "no errors found", "ErrorHandler registered"} {
+		if isErrorLine(line) {
+			t.Errorf("isErrorLine(%q) = true, want false", line)
+		}
+	}
+	for _, line := range []string{"exit status 2", "panic: runtime error: index out of range", "--- FAIL: TestParse (0.00s)", "error: could not compile"} {
+		if !isErrorLine(line) {
+			t.Errorf("isErrorLine(%q) = false, want true", line)
+		}
+	}
+
+	match, _ := newMatcher(&SearchParams{Query: "typeerror"})
+	matches := errorMatches(got, match)
+	if len(matches) != 1 || matches[0].BlockType != "tool_result" || matches[0].Snippet != want[1] {
+		t.Errorf("errorMatches() = %+v, want the TypeError", matches)
+	}
+	if all := errorMatches(got, nil); len(all) != len(want) {
+		t.Errorf("errorMatches(nil) = %d matches, want %d", len(all), len(want))
+	}
+}
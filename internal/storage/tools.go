@@ -0,0 +1,275 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/re-cinq/shift-log/internal/agent"
+)
+
+// toolReportFiles caps the most edited files listed per agent.
+const toolReportFiles = 10
+
+// ToolCount is how often an agent called a tool, and how many of the calls
+// failed.
+type ToolCount struct {
+	Name        string  `json:"name"` // canonical name, such as Bash or Edit
+	Calls       int     `json:"calls"`
+	Failures    int     `json:"failures"`
+	FailureRate float64 `json:"failure_rate"`
+}
+
+// FileEditCount is how many tool calls of an agent edited a file.
+type FileEditCount struct {
+	Path  string `json:"path"` // relative to the repository
+	Edits int    `json:"edits"`
+}
+
+// ToolUsage is the tool use of one agent across the conversations stored
+// on the current branch. Failures are the calls whose result the agent
+// marked as an error, which only Claude Code and Goose record; the calls of
+// other agents never count as failed.
+type ToolUsage struct {
+	Agent         string          `json:"agent"`
+	Sessions      int             `json:"sessions"`
+	Calls         int             `json:"calls"`
+	Failures      int             `json:"failures"`
+	FailureRate   float64         `json:"failure_rate"`
+	BashCommands  int             `json:"bash_commands"`
+	AvgBashLength float64         `json:"avg_bash_length"` // characters per command
+	Tools         []ToolCount     `json:"tools"`           // most called first
+	Files         []FileEditCount `json:"files"`           // most edited first
+}
+
+// toolCall is a tool_use block of a stored conversation, with the key that
+// identifies it across the commits its session is stored on.
+type toolCall struct {
+	key     string
+	agent   string
+	session string
+	name    string
+	command string // the command line of a Bash call
+	failed  bool
+	files   []string
+}
+
+// transcriptToolCalls returns the tool calls of a transcript, with their
+// names mapped through aliases and the files they edited made relative to
+// repoRoot. A call failed when a tool_result for it is marked as an error.
+func transcriptToolCalls(transcript *agent.Transcript, aliases map[string]string, repoRoot string) []toolCall {
+	failed := make(map[string]bool)
+	for _, entry := range transcript.Entries {
+		if entry.Message == nil {
+			continue
+		}
+		for _, block := range entry.Message.Content {
+			if block.Type == "tool_result" && block.IsError && block.ToolUseID != "" {
+				failed[block.ToolUseID] = true
+			}
+		}
+	}
+
+	var calls []toolCall
+	for i, entry := range transcript.Entries {
+		if entry.Message == nil {
+			continue
+		}
+		for j, block := range entry.Message.Content {
+			if block.Type != "tool_use" {
+				continue
+			}
+			name := block.Name
+			if name == "" {
+				name = block.Text
+			}
+			if alias, ok := aliases[name]; ok {
+				name = alias
+			}
+			if name == "" {
+				continue
+			}
+			id := block.ID
+			if id == "" {
+				id = block.ToolUseID
+			}
+			key := id
+			if key == "" {
+				key = fmt.Sprintf("%s\x00%d\x00%d", entry.UUID, i, j)
+			}
+			call := toolCall{key: key, name: name, failed: id != "" && failed[id]}
+			if name == "Bash" {
+				call.command = bashCommand(block.Input)
+			}
+			for _, edit := range agent.FileEdits(block, aliases) {
+				if rel := RepoRelativePath(edit.Path, repoRoot); rel != "" {
+					call.files = append(call.files, rel)
+				}
+			}
+			calls = append(calls, call)
+		}
+	}
+	return calls
+}
+
+// bashCommand returns the command line of a Bash tool call's input, which
+// agents record as a string or as the words of an argv.
+func bashCommand(input json.RawMessage) string {
+	var obj map[string]interface{}
+	if json.Unmarshal(input, &obj) != nil {
+		return ""
+	}
+	for _, key := range []string{"command", "cmd"} {
+		switch v := obj[key].(type) {
+		case string:
+			return v
+		case []interface{}:
+			words := make([]string, 0, len(v))
+			for _, w := range v {
+				if s, ok := w.(string); ok {
+					words = append(words, s)
+				}
+			}
+			return strings.Join(words, " ")
+		}
+	}
+	return ""
+}
+
+// aggregateToolCalls groups tool calls by agent, most calls first.
+func aggregateToolCalls(calls []toolCall) []ToolUsage {
+	type group struct {
+		usage    ToolUsage
+		sessions map[string]bool
+		tools    map[string]*ToolCount
+		files    map[string]int
+		bashLen  int
+	}
+	groups := make(map[string]*group)
+	for _, c := range calls {
+		g := groups[c.agent]
+		if g == nil {
+			g = &group{
+				usage:    ToolUsage{Agent: c.agent},
+				sessions: make(map[string]bool),
+				tools:    make(map[string]*ToolCount),
+				files:    make(map[string]int),
+			}
+			groups[c.agent] = g
+		}
+		g.sessions[c.session] = true
+		g.usage.Calls++
+		tool := g.tools[c.name]
+		if tool == nil {
+			tool = &ToolCount{Name: c.name}
+			g.tools[c.name] = tool
+		}
+		tool.Calls++
+		if c.failed {
+			g.usage.Failures++
+			tool.Failures++
+		}
+		if c.name == "Bash" && c.command != "" {
+			g.usage.BashCommands++
+			g.bashLen += len([]rune(c.command))
+		}
+		for _, f := range c.files {
+			g.files[f]++
+		}
+	}
+
+	usage := make([]ToolUsage, 0, len(groups))
+	for _, g := range groups {
+		u := g.usage
+		u.Sessions = len(g.sessions)
+		u.FailureRate = float64(u.Failures) / float64(u.Calls)
+		if u.BashCommands > 0 {
+			u.AvgBashLength = float64(g.bashLen) / float64(u.BashCommands)
+		}
+		u.Tools = make([]ToolCount, 0, len(g.tools))
+		for _, tool := range g.tools {
+			tool.FailureRate = float64(tool.Failures) / float64(tool.Calls)
+			u.Tools = append(u.Tools, *tool)
+		}
+		sort.Slice(u.Tools, func(i, j int) bool {
+			if u.Tools[i].Calls != u.Tools[j].Calls {
+				return u.Tools[i].Calls > u.Tools[j].Calls
+			}
+			return u.Tools[i].Name < u.Tools[j].Name
+		})
+		u.Files = make([]FileEditCount, 0, len(g.files))
+		for f, edits := range g.files {
+			u.Files = append(u.Files, FileEditCount{Path: f, Edits: edits})
+		}
+		sort.Slice(u.Files, func(i, j int) bool {
+			if u.Files[i].Edits != u.Files[j].Edits {
+				return u.Files[i].Edits > u.Files[j].Edits
+			}
+			return u.Files[i].Path < u.Files[j].Path
+		})
+		if len(u.Files) > toolReportFiles {
+			u.Files = u.Files[:toolReportFiles]
+		}
+		usage = append(usage, u)
+	}
+	sort.Slice(usage, func(i, j int) bool {
+		if usage[i].Calls != usage[j].Calls {
+			return usage[i].Calls > usage[j].Calls
+		}
+		return usage[i].Agent < usage[j].Agent
+	})
+	return usage
+}
+
+// ToolUsageReport reports the tool use of each agent in the conversations
+// stored on the current branch: how often each tool was called and failed,
+// the length of Bash commands, and the files edited most. A session stored
+// on several commits repeats its earlier calls, so each call is counted
+// once. The transcripts are parsed Concurrency at a time, until ctx is done.
+func ToolUsageReport(ctx context.Context) ([]ToolUsage, error) {
+	commits, err := ListConversationCommits()
+	if err != nil {
+		return nil, fmt.Errorf("could not list conversations: %w", err)
+	}
+
+	perCommit := make([][]toolCall, len(commits))
+	err = ForEachParallel(ctx, len(commits), func(i int) error {
+		conversations, err := GetStoredConversations(commits[i])
+		if err != nil {
+			return nil
+		}
+		for _, sc := range conversations {
+			transcript, err := sc.ParseTranscript()
+			if err != nil {
+				continue
+			}
+			for _, call := range transcriptToolCalls(transcript, sc.ToolAliases(), sc.ProjectPath) {
+				call.agent = sc.AgentName()
+				call.session = sc.SessionID
+				call.key = call.agent + "\x00" + sc.SessionID + "\x00" + call.key
+				perCommit[i] = append(perCommit[i], call)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// A call's result may only be stored with a later commit than the call
+	seen := make(map[string]int)
+	var calls []toolCall
+	for _, commitCalls := range perCommit {
+		for _, call := range commitCalls {
+			if i, ok := seen[call.key]; ok {
+				calls[i].failed = calls[i].failed || call.failed
+				continue
+			}
+			seen[call.key] = len(calls)
+			calls = append(calls, call)
+		}
+	}
+	return aggregateToolCalls(calls), nil
+}
@@ -0,0 +1,60 @@
+package storage
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/re-cinq/shift-log/internal/agent"
+)
+
+func TestToolUsage(t *testing.T) {
+	call := func(id, name, input string) agent.ContentBlock {
+		return agent.ContentBlock{Type: "tool_use", ID: id, Name: name, Input: json.RawMessage(input)}
+	}
+	assistant := func(blocks ...agent.ContentBlock) agent.TranscriptEntry {
+		return agent.TranscriptEntry{Type: agent.MessageTypeAssistant, Message: &agent.Message{Content: blocks}}
+	}
+	transcript := &agent.Transcript{Entries: []agent.TranscriptEntry{
+		assistant(call("t1", "Bash", `{"command":"go test ./..."}`)),
+		{Type: agent.MessageTypeUser, Message: &agent.Message{Content: []agent.ContentBlock{
+			{Type: "tool_result", ToolUseID: "t1", IsError: true},
+		}}},
+		assistant(call("t2", "Edit", `{"file_path":"/repo/main.go","new_string":"x"}`)),
+		assistant(call("t3", "shell", `{"command":["ls","-la"]}`)),
+		assistant(call("t4", "Write", `{"file_path":"/repo/main.go","content":"y"}`)),
+	}}
+
+	calls := transcriptToolCalls(transcript, map[string]string{"shell": "Bash"}, "/repo")
+	if len(calls) != 4 {
+		t.Fatalf("transcriptToolCalls() = %+v, want 4 calls", calls)
+	}
+	if !calls[0].failed || calls[1].failed {
+		t.Errorf("failed = %v, %v, want only the first call", calls[0].failed, calls[1].failed)
+	}
+	if calls[2].name != "Bash" || calls[2].command != "ls -la" {
+		t.Errorf("aliased call = %+v, want Bash running ls -la", calls[2])
+	}
+	for i := range calls {
+		calls[i].agent = "claude"
+		calls[i].session = "s1"
+	}
+	calls = append(calls, toolCall{agent: "codex", session: "s2", name: "Read"})
+
+	usage := aggregateToolCalls(calls)
+	if len(usage) != 2 || usage[0].Agent != "claude" {
+		t.Fatalf("aggregateToolCalls() = %+v, want claude first", usage)
+	}
+	claude := usage[0]
+	if claude.Calls != 4 || claude.Failures != 1 || claude.FailureRate != 0.25 || claude.Sessions != 1 {
+		t.Errorf("claude = %+v, want 4 calls in 1 session, 1 failed", claude)
+	}
+	if claude.BashCommands != 2 || claude.AvgBashLength != 9.5 {
+		t.Errorf("bash = %d commands of %v characters, want 2 of 9.5", claude.BashCommands, claude.AvgBashLength)
+	}
+	if claude.Tools[0].Name != "Bash" || claude.Tools[0].Calls != 2 || claude.Tools[0].FailureRate != 0.5 {
+		t.Errorf("Tools[0] = %+v, want Bash with 2 calls, half failed", claude.Tools[0])
+	}
+	if len(claude.Files) != 1 || claude.Files[0] != (FileEditCount{Path: "main.go", Edits: 2}) {
+		t.Errorf("Files = %+v, want main.go edited twice", claude.Files)
+	}
+}
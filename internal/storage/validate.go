@@ -0,0 +1,240 @@
+package storage
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/re-cinq/shift-log/internal/git"
+)
+
+// DefaultMaxNoteSize is the largest note accepted by the validators unless
+// a caller sets its own limit.
+const DefaultMaxNoteSize = 10 << 20
+
+// secretPattern recognises one kind of credential in stored text.
+type secretPattern struct {
+	name string
+	re   *regexp.Regexp
+}
+
+// secretPatterns are credential formats distinctive enough to be flagged
+// without false positives from ordinary code and prose. More specific
+// formats come first, as the first matching pattern names a secret.
+var secretPatterns = []secretPattern{
+	{"private key", regexp.MustCompile(`-----BEGIN (?:[A-Z]+ )?PRIVATE KEY(?: BLOCK)?-----`)},
+	{"AWS access key", regexp.MustCompile(`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`)},
+	{"GitHub token", regexp.MustCompile(`\b(?:gh[pousr]_[A-Za-z0-9]{36,}|github_pat_[A-Za-z0-9_]{22,})`)},
+	{"Anthropic API key", regexp.MustCompile(`\bsk-ant-[A-Za-z0-9_-]{20,}`)},
+	{"OpenAI API key", regexp.MustCompile(`\bsk-(?:proj-)?[A-Za-z0-9_-]{32,}`)},
+	{"Slack token", regexp.MustCompile(`\bxox[abposr]-[A-Za-z0-9-]{10,}`)},
+	{"Google API key", regexp.MustCompile(`\bAIza[0-9A-Za-z_-]{35}\b`)},
+	{"Stripe secret key", regexp.MustCompile(`\b[rs]k_live_[0-9A-Za-z]{24,}`)},
+}
+
+// FindSecrets returns a description of each distinct credential found in
+// data. Secrets are masked to a short prefix so reports do not leak them.
+func FindSecrets(data []byte) []string {
+	var found []string
+	seen := map[string]bool{}
+	for _, p := range secretPatterns {
+		for _, match := range p.re.FindAll(data, -1) {
+			masked := maskSecret(match)
+			if seen[masked] {
+				continue
+			}
+			seen[masked] = true
+			found = append(found, fmt.Sprintf("%s (%s)", p.name, masked))
+		}
+	}
+	return found
+}
+
+func maskSecret(secret []byte) string {
+	const shown = 8
+	if len(secret) <= shown {
+		return "…"
+	}
+	return string(secret[:shown]) + "…"
+}
+
+// ValidateConversationNote checks a conversation note before it is
+// accepted, for example by a server-side pre-receive hook. It returns one
+// message per problem; an empty result means the note is valid.
+func ValidateConversationNote(data []byte, maxSize int) []string {
+	if len(data) > maxSize {
+		return []string{fmt.Sprintf("note is %d bytes, over the limit of %d bytes", len(data), maxSize)}
+	}
+
+	conversations, err := UnmarshalStoredConversations(data)
+	if err != nil {
+		return []string{fmt.Sprintf("note is not a valid conversation: %v", err)}
+	}
+	if len(conversations) == 1 {
+		return validateConversation(conversations[0])
+	}
+
+	var problems []string
+	for i, sc := range conversations {
+		for _, p := range validateConversation(sc) {
+			problems = append(problems, fmt.Sprintf("conversation %d: %s", i+1, p))
+		}
+	}
+	return problems
+}
+
+// validateConversation checks one conversation of a note.
+func validateConversation(sc *StoredConversation) []string {
+	var problems []string
+	if sc.Version < 1 || sc.Version > NoteFormatVersion {
+		problems = append(problems, fmt.Sprintf("unsupported note format version %d (expected 1 to %d)", sc.Version, NoteFormatVersion))
+	}
+	if sc.SessionID == "" {
+		problems = append(problems, "session_id is missing")
+	}
+	if _, err := time.Parse(time.RFC3339, sc.Timestamp); err != nil {
+		problems = append(problems, fmt.Sprintf("timestamp %q is not RFC3339", sc.Timestamp))
+	}
+	if sc.Transcript == "" && sc.TranscriptBlob == "" && !sc.IsChunked() {
+		return append(problems, "transcript is missing")
+	}
+	if sc.TranscriptBlob != "" && !git.HasObject(sc.TranscriptBlob) {
+		// Kept in an HTTP blob store, out of reach of the server
+		return problems
+	}
+	if sc.IsChunked() {
+		sizes, err := git.BlobSizes(sc.TranscriptChunks)
+		if err != nil {
+			return append(problems, fmt.Sprintf("transcript chunks cannot be read: %v", err))
+		}
+		for _, sha := range sc.TranscriptChunks {
+			if _, ok := sizes[sha]; !ok {
+				return problems
+			}
+		}
+	}
+
+	transcript, err := sc.GetTranscript()
+	if err != nil {
+		return append(problems, fmt.Sprintf("transcript cannot be decoded: %v", err))
+	}
+	if !VerifyChecksum(transcript, sc.Checksum) {
+		problems = append(problems, "transcript does not match its checksum")
+	}
+
+	for _, secret := range FindSecrets(transcript) {
+		problems = append(problems, "transcript contains an unredacted "+secret)
+	}
+	for _, secret := range FindSecrets([]byte(sc.Summary)) {
+		problems = append(problems, "summary contains an unredacted "+secret)
+	}
+	return problems
+}
+
+// ValidateAnnotationsNote checks a note of the annotations ref the way
+// ValidateConversationNote checks conversation notes.
+func ValidateAnnotationsNote(data []byte, maxSize int) []string {
+	if len(data) > maxSize {
+		return []string{fmt.Sprintf("note is %d bytes, over the limit of %d bytes", len(data), maxSize)}
+	}
+
+	var problems []string
+	for i, line := range bytes.Split(data, []byte("\n")) {
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 {
+			continue
+		}
+		var a Annotation
+		if err := json.Unmarshal(line, &a); err != nil {
+			problems = append(problems, fmt.Sprintf("line %d is not a valid annotation: %v", i+1, err))
+			continue
+		}
+		if a.ID == "" || a.EntryUUID == "" || a.Body == "" {
+			problems = append(problems, fmt.Sprintf("line %d is missing id, entry_uuid or body", i+1))
+		}
+		for _, secret := range FindSecrets([]byte(a.Body)) {
+			problems = append(problems, fmt.Sprintf("annotation %s contains an unredacted %s", a.ID, secret))
+		}
+	}
+	return problems
+}
+
+// ValidateMergesNote checks a note of the merges ref the way
+// ValidateConversationNote checks conversation notes.
+func ValidateMergesNote(data []byte, maxSize int) []string {
+	if len(data) > maxSize {
+		return []string{fmt.Sprintf("note is %d bytes, over the limit of %d bytes", len(data), maxSize)}
+	}
+
+	var problems []string
+	for i, line := range bytes.Split(data, []byte("\n")) {
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 {
+			continue
+		}
+		var m MergedConversation
+		if err := json.Unmarshal(line, &m); err != nil {
+			problems = append(problems, fmt.Sprintf("line %d is not a valid merged conversation: %v", i+1, err))
+			continue
+		}
+		if m.Commit == "" || m.SessionID == "" {
+			problems = append(problems, fmt.Sprintf("line %d is missing commit or session_id", i+1))
+		}
+		for _, secret := range FindSecrets([]byte(m.Summary)) {
+			problems = append(problems, fmt.Sprintf("summary of %s contains an unredacted %s", m.Commit, secret))
+		}
+	}
+	return problems
+}
+
+// ValidateOmissionsNote checks a note of the omissions ref the way
+// ValidateConversationNote checks conversation notes.
+func ValidateOmissionsNote(data []byte, maxSize int) []string {
+	if len(data) > maxSize {
+		return []string{fmt.Sprintf("note is %d bytes, over the limit of %d bytes", len(data), maxSize)}
+	}
+
+	var problems []string
+	for i, line := range bytes.Split(data, []byte("\n")) {
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 {
+			continue
+		}
+		var o Omission
+		if err := json.Unmarshal(line, &o); err != nil {
+			problems = append(problems, fmt.Sprintf("line %d is not a valid omission: %v", i+1, err))
+			continue
+		}
+		if o.Reason == "" {
+			problems = append(problems, fmt.Sprintf("line %d is missing reason", i+1))
+		}
+	}
+	return problems
+}
+
+// ValidateDictionaryNote checks a note of the dictionaries ref the way
+// ValidateConversationNote checks conversation notes.
+func ValidateDictionaryNote(data []byte, maxSize int) []string {
+	if len(data) > maxSize {
+		return []string{fmt.Sprintf("note is %d bytes, over the limit of %d bytes", len(data), maxSize)}
+	}
+	if _, err := DictionaryID(data); err != nil {
+		return []string{err.Error()}
+	}
+	return nil
+}
+
+// ValidateReadableNote checks a note of the readable notes ref the way
+// ValidateConversationNote checks conversation notes.
+func ValidateReadableNote(data []byte, maxSize int) []string {
+	if len(data) > maxSize {
+		return []string{fmt.Sprintf("note is %d bytes, over the limit of %d bytes", len(data), maxSize)}
+	}
+	var problems []string
+	for _, secret := range FindSecrets(data) {
+		problems = append(problems, fmt.Sprintf("contains an unredacted %s", secret))
+	}
+	return problems
+}
@@ -0,0 +1,236 @@
+package storage
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/klauspost/compress/dict"
+	"github.com/klauspost/compress/zstd"
+	"github.com/re-cinq/shift-log/internal/git"
+)
+
+// zstdMagic starts every zstd frame; gzip streams start with 0x1f 0x8b.
+var zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+
+// dictionaryMagic starts every zstd dictionary, followed by its ID.
+var dictionaryMagic = []byte{0x37, 0xa4, 0x30, 0xec}
+
+// DefaultDictionarySize is the size of trained dictionaries, in bytes: the
+// zstd command's default.
+const DefaultDictionarySize = 112640
+
+// trainingChunkSize is the size of the pieces transcripts are cut into for
+// training, so that the dictionary learns the JSON of single messages
+// rather than of whole conversations.
+const trainingChunkSize = 16 << 10
+
+var (
+	dictionaryMu sync.Mutex
+	// dictionaryData maps the ID of each saved dictionary to its content,
+	// nil until the dictionaries are first needed.
+	dictionaryData map[uint32][]byte
+
+	// zstdEncoders and zstdDecoders hold a coder per dictionary ID, 0 for
+	// none, as they are costly to set up. Both are safe for concurrent use.
+	zstdEncoders = map[uint32]*zstd.Encoder{}
+	zstdDecoders = map[uint32]*zstd.Decoder{}
+)
+
+// isZstd reports whether data is a zstd frame.
+func isZstd(data []byte) bool {
+	return bytes.HasPrefix(data, zstdMagic)
+}
+
+// frameDictionaryID returns the ID of the dictionary a zstd frame was
+// compressed with, or 0 if it needs none.
+func frameDictionaryID(frame []byte) uint32 {
+	if len(frame) < 5 || !isZstd(frame) {
+		return 0
+	}
+	descriptor := frame[4]
+	pos := 5
+	if descriptor&0x20 == 0 {
+		// Window descriptor of frames that are not single segment
+		pos++
+	}
+	size := []int{0, 1, 2, 4}[descriptor&0x03]
+	if len(frame) < pos+size {
+		return 0
+	}
+	var id uint32
+	for i := 0; i < size; i++ {
+		id |= uint32(frame[pos+i]) << (8 * i)
+	}
+	return id
+}
+
+// DictionaryID returns the ID of a zstd dictionary, or an error if data is
+// not one.
+func DictionaryID(data []byte) (uint32, error) {
+	if len(data) < 8 || !bytes.HasPrefix(data, dictionaryMagic) {
+		return 0, errors.New("not a zstd dictionary")
+	}
+	return binary.LittleEndian.Uint32(data[4:8]), nil
+}
+
+// zstdCompress compresses data with zstd, using the saved dictionary dictID
+// unless it is 0.
+func zstdCompress(data []byte, dictID uint32) ([]byte, error) {
+	enc, err := zstdEncoder(dictID)
+	if err != nil {
+		return nil, err
+	}
+	return enc.EncodeAll(data, nil), nil
+}
+
+// zstdDecompress decompresses a zstd frame with the dictionary it was
+// compressed with.
+func zstdDecompress(data []byte) ([]byte, error) {
+	dec, err := zstdDecoder(frameDictionaryID(data))
+	if err != nil {
+		return nil, err
+	}
+	out, err := dec.DecodeAll(data, nil)
+	if err != nil {
+		return nil, fmt.Errorf("zstd failed: %w", err)
+	}
+	return out, nil
+}
+
+// zstdEncoder returns the encoder for the saved dictionary id, or without
+// a dictionary when id is 0.
+func zstdEncoder(id uint32) (*zstd.Encoder, error) {
+	dictionaryMu.Lock()
+	defer dictionaryMu.Unlock()
+	if enc, ok := zstdEncoders[id]; ok {
+		return enc, nil
+	}
+	var opts []zstd.EOption
+	if id != 0 {
+		data, err := dictionary(id)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, zstd.WithEncoderDict(data))
+	}
+	enc, err := zstd.NewWriter(nil, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("could not set up zstd: %w", err)
+	}
+	zstdEncoders[id] = enc
+	return enc, nil
+}
+
+// zstdDecoder returns the decoder for the saved dictionary id, or without
+// a dictionary when id is 0.
+func zstdDecoder(id uint32) (*zstd.Decoder, error) {
+	dictionaryMu.Lock()
+	defer dictionaryMu.Unlock()
+	if dec, ok := zstdDecoders[id]; ok {
+		return dec, nil
+	}
+	var opts []zstd.DOption
+	if id != 0 {
+		data, err := dictionary(id)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, zstd.WithDecoderDicts(data))
+	}
+	dec, err := zstd.NewReader(nil, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("could not set up zstd: %w", err)
+	}
+	zstdDecoders[id] = dec
+	return dec, nil
+}
+
+// dictionary returns the saved dictionary id. The caller holds
+// dictionaryMu.
+func dictionary(id uint32) ([]byte, error) {
+	if _, ok := dictionaryData[id]; !ok {
+		// Not loaded yet, or saved or fetched since
+		if err := loadDictionaries(); err != nil {
+			return nil, fmt.Errorf("could not read dictionaries: %w", err)
+		}
+	}
+	data, ok := dictionaryData[id]
+	if !ok {
+		return nil, fmt.Errorf("zstd dictionary %d not found in %s; run 'shiftlog sync pull' to fetch it", id, git.DictionariesRef)
+	}
+	return data, nil
+}
+
+// loadDictionaries reads every saved dictionary. The caller holds
+// dictionaryMu.
+func loadDictionaries() error {
+	notes, err := git.ReadDictionaries()
+	if err != nil {
+		return err
+	}
+	dictionaryData = make(map[uint32][]byte, len(notes))
+	for _, data := range notes {
+		id, err := DictionaryID(data)
+		if err != nil {
+			continue
+		}
+		dictionaryData[id] = data
+	}
+	return nil
+}
+
+// Dictionaries returns the IDs of the saved dictionaries with their size in
+// bytes.
+func Dictionaries() (map[uint32]int, error) {
+	dictionaryMu.Lock()
+	defer dictionaryMu.Unlock()
+	if err := loadDictionaries(); err != nil {
+		return nil, err
+	}
+	sizes := make(map[uint32]int, len(dictionaryData))
+	for id, data := range dictionaryData {
+		sizes[id] = len(data)
+	}
+	return sizes, nil
+}
+
+// TrainDictionary trains a zstd dictionary of at most maxSize bytes over
+// samples of transcript JSONL.
+func TrainDictionary(samples [][]byte, maxSize int) ([]byte, error) {
+	var chunks [][]byte
+	for _, sample := range samples {
+		for len(sample) > trainingChunkSize {
+			chunks = append(chunks, sample[:trainingChunkSize])
+			sample = sample[trainingChunkSize:]
+		}
+		if len(sample) > 0 {
+			chunks = append(chunks, sample)
+		}
+	}
+	data, err := dict.BuildZstdDict(chunks, dict.Options{MaxDictSize: maxSize, HashBytes: 6})
+	if err != nil {
+		return nil, fmt.Errorf("could not train dictionary: %w", err)
+	}
+	return data, nil
+}
+
+// SaveDictionary saves a trained dictionary in git.DictionariesRef, where
+// sync shares it with the clones that will read what it compressed, and
+// returns its ID.
+func SaveDictionary(data []byte) (uint32, error) {
+	id, err := DictionaryID(data)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := git.AddDictionary(data); err != nil {
+		return 0, fmt.Errorf("could not save dictionary: %w", err)
+	}
+
+	dictionaryMu.Lock()
+	dictionaryData = nil
+	dictionaryMu.Unlock()
+	return id, nil
+}
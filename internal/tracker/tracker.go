@@ -0,0 +1,174 @@
+// Package tracker posts comments on the issues of Jira and Linear, for
+// 'shiftlog issues --comment'.
+package tracker
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/re-cinq/shift-log/internal/config"
+)
+
+// requestTimeout bounds each request to the tracker.
+const requestTimeout = 30 * time.Second
+
+// linearAPI is the URL of Linear's API, used when no other is configured.
+const linearAPI = "https://api.linear.app"
+
+// Tracker comments on issues.
+type Tracker interface {
+	// Comment posts body, in Markdown, on the issue key.
+	Comment(ctx context.Context, key, body string) error
+}
+
+// New returns the tracker of kind, config.IssueTrackerJira or
+// config.IssueTrackerLinear, at baseURL. Jira authenticates with user and
+// token, an API token, or with token alone as a personal access token;
+// Linear with token, an API key.
+func New(kind, baseURL, user, token string) (Tracker, error) {
+	client := &http.Client{Timeout: requestTimeout}
+	switch kind {
+	case config.IssueTrackerJira:
+		if baseURL == "" {
+			return nil, fmt.Errorf("no Jira server: set issue_tracker_url in .shiftlog/config")
+		}
+		return &jira{baseURL: strings.TrimSuffix(baseURL, "/"), user: user, token: token, http: client}, nil
+	case config.IssueTrackerLinear:
+		if baseURL == "" {
+			baseURL = linearAPI
+		}
+		return &linear{baseURL: strings.TrimSuffix(baseURL, "/"), token: token, http: client}, nil
+	case "":
+		return nil, fmt.Errorf("no issue tracker: set issue_tracker in .shiftlog/config to %s or %s", config.IssueTrackerJira, config.IssueTrackerLinear)
+	default:
+		return nil, fmt.Errorf("unknown issue_tracker %q: must be %s or %s", kind, config.IssueTrackerJira, config.IssueTrackerLinear)
+	}
+}
+
+// jira comments through Jira's REST API, version 2 taking plain text bodies
+// where version 3 wants them in its document format.
+type jira struct {
+	baseURL string
+	user    string
+	token   string
+	http    *http.Client
+}
+
+func (j *jira) Comment(ctx context.Context, key, body string) error {
+	payload, err := json.Marshal(map[string]string{"body": body})
+	if err != nil {
+		return err
+	}
+	endpoint := j.baseURL + "/rest/api/2/issue/" + url.PathEscape(key) + "/comment"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	if j.user != "" {
+		req.SetBasicAuth(j.user, j.token)
+	} else if j.token != "" {
+		req.Header.Set("Authorization", "Bearer "+j.token)
+	}
+	_, err = send(j.http, req)
+	return err
+}
+
+// linear comments through Linear's GraphQL API.
+type linear struct {
+	baseURL string
+	token   string
+	http    *http.Client
+}
+
+func (l *linear) Comment(ctx context.Context, key, body string) error {
+	// Comments are created on the issue's ID, which its key looks up
+	var issue struct {
+		Issue *struct {
+			ID string `json:"id"`
+		} `json:"issue"`
+	}
+	if err := l.query(ctx, `query($id: String!) { issue(id: $id) { id } }`, map[string]any{"id": key}, &issue); err != nil {
+		return err
+	}
+	if issue.Issue == nil {
+		return fmt.Errorf("no issue %s", key)
+	}
+
+	var created struct {
+		CommentCreate struct {
+			Success bool `json:"success"`
+		} `json:"commentCreate"`
+	}
+	mutation := `mutation($issueId: String!, $body: String!) { commentCreate(input: {issueId: $issueId, body: $body}) { success } }`
+	if err := l.query(ctx, mutation, map[string]any{"issueId": issue.Issue.ID, "body": body}, &created); err != nil {
+		return err
+	}
+	if !created.CommentCreate.Success {
+		return fmt.Errorf("linear did not create the comment on %s", key)
+	}
+	return nil
+}
+
+// query runs a GraphQL query and decodes its data into result.
+func (l *linear) query(ctx context.Context, query string, variables map[string]any, result any) error {
+	payload, err := json.Marshal(map[string]any{"query": query, "variables": variables})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, l.baseURL+"/graphql", bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	// Personal API keys are sent as they are, without Bearer
+	req.Header.Set("Authorization", l.token)
+	data, err := send(l.http, req)
+	if err != nil {
+		return err
+	}
+
+	var response struct {
+		Data   json.RawMessage `json:"data"`
+		Errors []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	if err := json.Unmarshal(data, &response); err != nil {
+		return fmt.Errorf("invalid response from Linear: %w", err)
+	}
+	if len(response.Errors) > 0 {
+		return fmt.Errorf("linear: %s", response.Errors[0].Message)
+	}
+	return json.Unmarshal(response.Data, result)
+}
+
+// send sends req and returns the body of a successful response.
+func send(client *http.Client, req *http.Request) ([]byte, error) {
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 300 {
+		text := strings.TrimSpace(string(data))
+		if len(text) > 1024 {
+			text = text[:1024]
+		}
+		return nil, fmt.Errorf("%s %s: %s: %s", req.Method, req.URL.Path, resp.Status, text)
+	}
+	return data, nil
+}
@@ -0,0 +1,99 @@
+package tracker
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestJiraComment(t *testing.T) {
+	var comment map[string]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, token, ok := r.BasicAuth(); !ok || user != "ada@example.com" || token != "secret" {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		if r.Method != http.MethodPost || r.URL.Path != "/rest/api/2/issue/PROJ-12/comment" {
+			http.NotFound(w, r)
+			return
+		}
+		if err := json.NewDecoder(r.Body).Decode(&comment); err != nil {
+			t.Error(err)
+		}
+		w.WriteHeader(http.StatusCreated)
+		_, _ = io.WriteString(w, `{"id": "10000"}`)
+	}))
+	defer server.Close()
+
+	tr, err := New("jira", server.URL+"/", "ada@example.com", "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tr.Comment(context.Background(), "PROJ-12", "hello"); err != nil {
+		t.Fatal(err)
+	}
+	if comment["body"] != "hello" {
+		t.Errorf("comment = %v", comment)
+	}
+	if err := tr.Comment(context.Background(), "PROJ-13", "hello"); err == nil || !strings.Contains(err.Error(), "404") {
+		t.Errorf("an unknown issue: err = %v", err)
+	}
+}
+
+func TestLinearComment(t *testing.T) {
+	var body string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "lin_api_key" || r.URL.Path != "/graphql" {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		var request struct {
+			Query     string         `json:"query"`
+			Variables map[string]any `json:"variables"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			t.Error(err)
+		}
+		switch {
+		case strings.Contains(request.Query, "issue(id:") && request.Variables["id"] == "ENG-7":
+			_, _ = io.WriteString(w, `{"data": {"issue": {"id": "uuid-7"}}}`)
+		case strings.Contains(request.Query, "issue(id:"):
+			_, _ = io.WriteString(w, `{"data": null, "errors": [{"message": "Entity not found"}]}`)
+		case strings.Contains(request.Query, "commentCreate") && request.Variables["issueId"] == "uuid-7":
+			body, _ = request.Variables["body"].(string)
+			_, _ = io.WriteString(w, `{"data": {"commentCreate": {"success": true}}}`)
+		default:
+			t.Errorf("unexpected query %q", request.Query)
+		}
+	}))
+	defer server.Close()
+
+	tr, err := New("linear", server.URL, "", "lin_api_key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tr.Comment(context.Background(), "ENG-7", "hello"); err != nil {
+		t.Fatal(err)
+	}
+	if body != "hello" {
+		t.Errorf("body = %q", body)
+	}
+	if err := tr.Comment(context.Background(), "ENG-8", "hello"); err == nil || !strings.Contains(err.Error(), "Entity not found") {
+		t.Errorf("an unknown issue: err = %v", err)
+	}
+}
+
+func TestNew(t *testing.T) {
+	for _, kind := range []string{"", "github"} {
+		if _, err := New(kind, "https://example.com", "", ""); err == nil {
+			t.Errorf("New(%q) did not fail", kind)
+		}
+	}
+	if _, err := New("jira", "", "", ""); err == nil {
+		t.Error("jira without a URL did not fail")
+	}
+}
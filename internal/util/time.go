@@ -0,0 +1,61 @@
+package util
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// timestampFormats are the layouts produced by git and the coding agents.
+var timestampFormats = []string{
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05 -0700", // git %ci / committerdate:iso8601
+	"2006-01-02T15:04:05Z",
+	"2006-01-02",
+}
+
+// ParseTimestamp parses a timestamp in any of the formats git and the
+// supported agents emit.
+func ParseTimestamp(s string) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	for _, f := range timestampFormats {
+		if t, err := time.Parse(f, s); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("could not parse date: %s", s)
+}
+
+// NormalizeTimestamp converts a timestamp to RFC3339 in UTC. Values that
+// cannot be parsed are returned unchanged.
+func NormalizeTimestamp(s string) string {
+	t, err := ParseTimestamp(s)
+	if err != nil {
+		return s
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+// NormalizeEntryTime converts the timestamp of a transcript entry to
+// RFC3339 in UTC with milliseconds, so that entries of any agent can be
+// ordered and spaced in time. It accepts the formats of ParseTimestamp and
+// Unix times in seconds or milliseconds, which some agents record, and
+// returns "" for values it cannot parse.
+func NormalizeEntryTime(s string) string {
+	s = strings.TrimSpace(s)
+	t, err := ParseTimestamp(s)
+	if err != nil {
+		n, err := strconv.ParseInt(s, 10, 64)
+		if err != nil || n <= 0 {
+			return ""
+		}
+		// Seconds would not reach 1e11 until the year 5138
+		if n >= 1e11 {
+			t = time.UnixMilli(n)
+		} else {
+			t = time.Unix(n, 0)
+		}
+	}
+	return t.UTC().Format("2006-01-02T15:04:05.000Z")
+}
@@ -0,0 +1,44 @@
+package util
+
+import "testing"
+
+func TestNormalizeTimestamp(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"2024-01-15 10:30:00 +0100", "2024-01-15T09:30:00Z"},
+		{"2024-01-15 10:30:00 -0500", "2024-01-15T15:30:00Z"},
+		{"2024-01-15T10:30:00+02:00", "2024-01-15T08:30:00Z"},
+		{"2024-01-15T10:30:00.123Z", "2024-01-15T10:30:00Z"},
+		{"2024-01-15", "2024-01-15T00:00:00Z"},
+		{"not a date", "not a date"},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		if got := NormalizeTimestamp(tt.in); got != tt.want {
+			t.Errorf("NormalizeTimestamp(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestNormalizeEntryTime(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"2024-01-15T10:30:00.123456+02:00", "2024-01-15T08:30:00.123Z"},
+		{"2024-01-15T10:30:00Z", "2024-01-15T10:30:00.000Z"},
+		{"1705314600", "2024-01-15T10:30:00.000Z"},
+		{"1705314600250", "2024-01-15T10:30:00.250Z"},
+		{"not a date", ""},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		if got := NormalizeEntryTime(tt.in); got != tt.want {
+			t.Errorf("NormalizeEntryTime(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
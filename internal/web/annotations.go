@@ -0,0 +1,80 @@
+package web
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/re-cinq/shift-log/internal/git"
+	"github.com/re-cinq/shift-log/internal/storage"
+)
+
+// maxAnnotationBytes caps the size of an annotation request body.
+const maxAnnotationBytes = 64 << 10
+
+// AnnotationRequest is the body of POST /api/commits/<sha>/annotations.
+type AnnotationRequest struct {
+	EntryUUID string `json:"entry_uuid"`
+	Body      string `json:"body"`
+	Author    string `json:"author,omitempty"` // defaults to git user.name
+}
+
+// handleAnnotations lists (GET) or adds (POST) annotations on the
+// conversation stored for ref.
+func (s *Server) handleAnnotations(w http.ResponseWriter, r *http.Request, ref string) {
+	if r.Method != http.MethodGet && r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	fullSHA, err := git.ResolveRef(ref)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, "invalid commit reference")
+		return
+	}
+	if getStoredOrWriteError(w, fullSHA) == nil {
+		return
+	}
+
+	if r.Method == http.MethodGet {
+		annotations, err := storage.GetAnnotations(fullSHA)
+		if err != nil {
+			writeJSONError(w, http.StatusInternalServerError, "failed to read annotations")
+			return
+		}
+		if annotations == nil {
+			annotations = []storage.Annotation{}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(annotations)
+		return
+	}
+
+	if !s.mayWrite(r) {
+		writeJSONError(w, http.StatusForbidden, remoteWriteDenied)
+		return
+	}
+	var req AnnotationRequest
+	if err := json.NewDecoder(io.LimitReader(r.Body, maxAnnotationBytes)).Decode(&req); err != nil {
+		writeJSONError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if req.EntryUUID == "" || strings.TrimSpace(req.Body) == "" {
+		writeJSONError(w, http.StatusBadRequest, "entry_uuid and body are required")
+		return
+	}
+	if req.Author == "" {
+		req.Author = git.GetUserName()
+	}
+
+	annotation, err := storage.AddAnnotation(fullSHA, req.EntryUUID, req.Author, req.Body)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "failed to store annotation")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(annotation)
+}
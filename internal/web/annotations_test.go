@@ -0,0 +1,90 @@
+package web
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/re-cinq/shift-log/internal/storage"
+)
+
+func TestHandleAnnotations(t *testing.T) {
+	repo := newTestRepo(t)
+	chdir(t, repo.path)
+	repo.writeFile("a.txt", "a")
+	sha := repo.commit("First commit")
+	repo.addConversation(sha, "session-1", sampleTranscript(), 2)
+
+	srv := NewServer(0, repo.path)
+	path := "/api/commits/" + sha + "/annotations"
+
+	req := httptest.NewRequest("GET", path, nil)
+	w := httptest.NewRecorder()
+	srv.mux.ServeHTTP(w, req)
+	var annotations []storage.Annotation
+	decodeJSON(t, w, &annotations)
+	if len(annotations) != 0 {
+		t.Fatalf("annotations = %+v, want none", annotations)
+	}
+
+	for _, body := range []string{"this prompt caused the regression", "agreed"} {
+		req = httptest.NewRequest("POST", path, strings.NewReader(`{"entry_uuid":"u1","body":"`+body+`"}`))
+		w = httptest.NewRecorder()
+		srv.mux.ServeHTTP(w, req)
+		if w.Code != http.StatusCreated {
+			t.Fatalf("POST status = %d, want %d: %s", w.Code, http.StatusCreated, w.Body.String())
+		}
+	}
+
+	req = httptest.NewRequest("GET", path, nil)
+	w = httptest.NewRecorder()
+	srv.mux.ServeHTTP(w, req)
+	decodeJSON(t, w, &annotations)
+	if len(annotations) != 2 {
+		t.Fatalf("got %d annotations, want 2", len(annotations))
+	}
+	a := annotations[0]
+	if a.EntryUUID != "u1" || a.Body != "this prompt caused the regression" || a.Author != "Test User" || a.ID == "" {
+		t.Errorf("annotation = %+v", a)
+	}
+
+	// The conversation note itself is untouched
+	if stored, err := storage.GetStoredConversation(sha); err != nil || stored == nil || stored.SessionID != "session-1" {
+		t.Errorf("conversation note changed: %+v, %v", stored, err)
+	}
+}
+
+func TestHandleAnnotationsErrors(t *testing.T) {
+	repo := newTestRepo(t)
+	chdir(t, repo.path)
+	repo.writeFile("a.txt", "a")
+	sha := repo.commit("First commit")
+	repo.addConversation(sha, "session-1", sampleTranscript(), 2)
+	repo.writeFile("b.txt", "b")
+	bare := repo.commit("No conversation")
+
+	srv := NewServer(0, repo.path)
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+		want   int
+	}{
+		{"missing body", "POST", "/api/commits/" + sha + "/annotations", `{"entry_uuid":"u1"}`, http.StatusBadRequest},
+		{"missing entry", "POST", "/api/commits/" + sha + "/annotations", `{"body":"hi"}`, http.StatusBadRequest},
+		{"invalid json", "POST", "/api/commits/" + sha + "/annotations", `{`, http.StatusBadRequest},
+		{"no conversation", "GET", "/api/commits/" + bare + "/annotations", "", http.StatusNotFound},
+		{"bad method", "DELETE", "/api/commits/" + sha + "/annotations", "", http.StatusMethodNotAllowed},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+		w := httptest.NewRecorder()
+		srv.mux.ServeHTTP(w, req)
+		if w.Code != tt.want {
+			t.Errorf("%s: status = %d, want %d", tt.name, w.Code, tt.want)
+		}
+	}
+}
@@ -0,0 +1,153 @@
+package web
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/re-cinq/shift-log/internal/agent"
+	"github.com/re-cinq/shift-log/internal/git"
+	"github.com/re-cinq/shift-log/internal/storage"
+)
+
+// handleAttachment serves an image or document of the conversations of a
+// commit, /api/commits/<sha>/attachments/<id>.
+func (s *Server) handleAttachment(w http.ResponseWriter, r *http.Request, ref, id string) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	fullSHA, err := git.ResolveRef(ref)
+	if err != nil {
+		http.Error(w, "Invalid commit reference", http.StatusBadRequest)
+		return
+	}
+	conversations, err := storage.GetConversationsWithPrivate(fullSHA)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "failed to read conversation")
+		return
+	}
+
+	// IDs are content hashes, so any conversation of the commit will do
+	var attachment agent.Attachment
+	found := false
+	for _, sc := range conversations {
+		if transcript, err := sc.ParseTranscript(); err == nil {
+			if attachment, found = transcript.Attachment(id); found {
+				break
+			}
+		}
+	}
+	if !found {
+		writeJSONError(w, http.StatusNotFound, "attachment not found")
+		return
+	}
+
+	// Images and PDFs keep their type; anything else is served as bytes to
+	// download. The sandbox keeps scripts in SVGs from running when opened
+	// directly.
+	contentType := attachment.MediaType
+	if !strings.HasPrefix(contentType, "image/") && contentType != "application/pdf" {
+		contentType = "application/octet-stream"
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Content-Security-Policy", "sandbox")
+	// Attachments are addressed by their content, so they never change
+	w.Header().Set("Cache-Control", "private, max-age=31536000, immutable")
+	_, _ = w.Write(attachment.Data)
+}
+
+// referenceAttachments replaces the data of the attachments of an entry of
+// a conversation of commit sha with their attachment_id and attachment_url,
+// for the viewer to load them from handleAttachment instead of receiving
+// them inline.
+func referenceAttachments(entry *agent.TranscriptEntry, sha string) {
+	clearServedURLs(entry)
+	msg := entry.Message
+	if msg == nil || !bytes.Contains(msg.RawContent, []byte(`"source"`)) {
+		return
+	}
+	referenced := *msg
+	referenced.RawContent = agent.RewriteAttachments(msg.RawContent, func(block map[string]any) bool {
+		source, _ := block["source"].(map[string]any)
+		if source["type"] != agent.SourceBase64 {
+			return false
+		}
+		encoded, _ := source["data"].(string)
+		data, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return false
+		}
+		id := agent.AttachmentID(data)
+		block["attachment_id"] = id
+		block["attachment_url"] = "/api/commits/" + sha + "/attachments/" + id
+		delete(source, "data")
+		source["size"] = len(data)
+		return true
+	})
+	if !bytes.Equal(referenced.RawContent, msg.RawContent) {
+		referenced.Content = nil
+		_ = json.Unmarshal(referenced.RawContent, &referenced.Content)
+	}
+	entry.Message = &referenced
+}
+
+// servedURLKeys are the content block fields that only this server sets,
+// pointing the viewer at its attachment and tool output endpoints.
+var servedURLKeys = []string{"attachment_url", "content_url"}
+
+// clearServedURLs removes the attachment_url and content_url fields a stored
+// transcript carries, so only the URLs this server sets reach the viewer.
+func clearServedURLs(entry *agent.TranscriptEntry) {
+	msg := entry.Message
+	if msg == nil || !bytes.Contains(msg.RawContent, []byte(`_url"`)) {
+		return
+	}
+	dec := json.NewDecoder(bytes.NewReader(msg.RawContent))
+	dec.UseNumber()
+	var content any
+	if err := dec.Decode(&content); err != nil || !deleteServedURLs(content) {
+		return
+	}
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(content); err != nil {
+		return
+	}
+	cleared := *msg
+	cleared.RawContent = bytes.TrimSuffix(buf.Bytes(), []byte("\n"))
+	cleared.Content = nil
+	_ = json.Unmarshal(cleared.RawContent, &cleared.Content)
+	entry.Message = &cleared
+}
+
+func deleteServedURLs(v any) bool {
+	changed := false
+	switch v := v.(type) {
+	case map[string]any:
+		for _, key := range servedURLKeys {
+			if _, ok := v[key]; ok {
+				delete(v, key)
+				changed = true
+			}
+		}
+		for _, child := range v {
+			if deleteServedURLs(child) {
+				changed = true
+			}
+		}
+	case []any:
+		for _, child := range v {
+			if deleteServedURLs(child) {
+				changed = true
+			}
+		}
+	}
+	return changed
+}
@@ -0,0 +1,113 @@
+package web
+
+import (
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/re-cinq/shift-log/internal/agent"
+)
+
+func TestHandleAttachments(t *testing.T) {
+	repo := newTestRepo(t)
+	chdir(t, repo.path)
+
+	png := []byte("\x89PNG screenshot")
+	transcript := marshalTranscript([]map[string]interface{}{
+		{
+			"uuid": "user-1", "type": "user",
+			"message": map[string]interface{}{
+				"role": "user",
+				"content": []map[string]interface{}{
+					{"type": "text", "text": "Why is this red?"},
+					{"type": "image", "source": map[string]interface{}{
+						"type": "base64", "media_type": "image/png", "data": base64.StdEncoding.EncodeToString(png),
+					}},
+				},
+			},
+		},
+		{
+			"uuid": "user-2", "type": "user",
+			"message": map[string]interface{}{
+				"role": "user",
+				"content": []map[string]interface{}{
+					{"type": "image", "source": map[string]interface{}{"type": "url", "media_type": "image/png"}, "attachment_url": "javascript:alert(1)"},
+					{"type": "tool_result", "tool_use_id": "t1", "content": "done", "truncated": true, "content_url": "javascript:alert(2)"},
+				},
+			},
+		},
+	})
+	repo.writeFile("a.txt", "a")
+	sha := repo.commit("Fix colours")
+	repo.addConversation(sha, "session-1", transcript, 1)
+	srv := NewServer(0, repo.path)
+	id := agent.AttachmentID(png)
+
+	t.Run("conversation references attachments", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/api/commits/"+sha, nil)
+		w := httptest.NewRecorder()
+		srv.mux.ServeHTTP(w, req)
+
+		body := w.Body.String()
+		if strings.Contains(body, base64.StdEncoding.EncodeToString(png)) {
+			t.Error("conversation still embeds the image data")
+		}
+		want := `"attachment_url":"/api/commits/` + sha + `/attachments/` + id + `"`
+		if !strings.Contains(body, want) {
+			t.Errorf("conversation missing %s: %s", want, body)
+		}
+	})
+
+	t.Run("conversation drops stored urls", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/api/commits/"+sha, nil)
+		w := httptest.NewRecorder()
+		srv.mux.ServeHTTP(w, req)
+
+		if body := w.Body.String(); strings.Contains(body, "javascript:") {
+			t.Errorf("conversation serves a URL from the stored transcript: %s", body)
+		}
+	})
+
+	t.Run("serves the attachment", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/api/commits/"+sha+"/attachments/"+id, nil)
+		w := httptest.NewRecorder()
+		srv.mux.ServeHTTP(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("status: want 200, got %d: %s", w.Code, w.Body.String())
+		}
+		if got := w.Header().Get("Content-Type"); got != "image/png" {
+			t.Errorf("Content-Type = %q, want image/png", got)
+		}
+		if w.Body.String() != string(png) {
+			t.Errorf("body = %q, want the image", w.Body.String())
+		}
+	})
+
+	t.Run("unknown attachment", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/api/commits/"+sha+"/attachments/0123456789abcdef", nil)
+		w := httptest.NewRecorder()
+		srv.mux.ServeHTTP(w, req)
+		if w.Code != http.StatusNotFound {
+			t.Errorf("status: want 404, got %d", w.Code)
+		}
+	})
+}
+
+func TestHTMLContainsAttachments(t *testing.T) {
+	repo := newTestRepo(t)
+	srv := NewServer(0, repo.path)
+
+	req := httptest.NewRequest("GET", "/", nil)
+	w := httptest.NewRecorder()
+	srv.mux.ServeHTTP(w, req)
+
+	body := w.Body.String()
+	for _, elem := range []string{".message-attachments", ".message-attachments img", ".attachment-omitted"} {
+		if !strings.Contains(body, elem) {
+			t.Errorf("index.html missing attachment element: %s", elem)
+		}
+	}
+}
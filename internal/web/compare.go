@@ -0,0 +1,160 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/re-cinq/shift-log/internal/git"
+	"github.com/re-cinq/shift-log/internal/storage"
+)
+
+// EffortTotals adds up the conversations of a set of commits.
+type EffortTotals struct {
+	Commits          int   `json:"commits"`
+	WithConversation int   `json:"with_conversation"`
+	Conversations    int   `json:"conversations"`
+	Messages         int   `json:"messages"`
+	Turns            int   `json:"turns"`
+	Tokens           int64 `json:"tokens"`
+	DurationSeconds  int64 `json:"duration_seconds"`
+	AILines          int   `json:"ai_lines"`
+	TotalLines       int   `json:"total_lines"`
+}
+
+// add counts a commit and its conversations in the totals.
+func (t *EffortTotals) add(conversations []*storage.StoredConversation) {
+	t.Commits++
+	if len(conversations) > 0 {
+		t.WithConversation++
+	}
+	for _, sc := range conversations {
+		t.Conversations++
+		t.Messages += sc.MessageCount
+		if sc.Effort != nil {
+			t.Turns += sc.Effort.Turns
+			t.Tokens += sc.Effort.TotalTokens()
+			t.DurationSeconds += sc.Effort.DurationSeconds
+		}
+		if sc.Authorship != nil {
+			t.AILines += sc.Authorship.AILines
+			t.TotalLines += sc.Authorship.TotalLines
+		}
+	}
+}
+
+// BranchCompareSide is one side of a branch comparison: the commits only
+// on a branch, or the history both branches share.
+type BranchCompareSide struct {
+	Name    string       `json:"name,omitempty"`
+	Commits []CommitInfo `json:"commits"`
+	Totals  EffortTotals `json:"totals"`
+	// Truncated is set when the side has more commits than were listed;
+	// the totals only cover the listed ones.
+	Truncated bool `json:"truncated,omitempty"`
+}
+
+// BranchCompareData is the response of /api/branches/compare.
+type BranchCompareData struct {
+	// MergeBase is the newest commit of the shared history, or empty when
+	// the branches have none.
+	MergeBase string            `json:"merge_base,omitempty"`
+	A         BranchCompareSide `json:"a"`
+	B         BranchCompareSide `json:"b"`
+	Shared    BranchCompareSide `json:"shared"`
+}
+
+// handleBranchCompare compares the branches ?a= and ?b=: the commits only on
+// each of them, and the history they share from their merge base, at most
+// ?limit= commits each (default 100), with the effort of their
+// conversations.
+func (s *Server) handleBranchCompare(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+	a, b := query.Get("a"), query.Get("b")
+	if a == "" || b == "" {
+		writeJSONError(w, http.StatusBadRequest, "a and b are required")
+		return
+	}
+	limit := 100
+	if l := query.Get("limit"); l != "" {
+		if val, err := strconv.Atoi(l); err == nil && val > 0 {
+			limit = val
+		}
+	}
+
+	// Only compare known branches, which also keeps options out of git log
+	branches, err := git.ListBranches(s.repoDir)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "failed to list branches")
+		return
+	}
+	known := make(map[string]bool, len(branches))
+	for _, br := range branches {
+		known[br.Name] = true
+	}
+	for _, name := range []string{a, b} {
+		if !known[name] {
+			writeJSONError(w, http.StatusNotFound, "unknown branch "+name)
+			return
+		}
+	}
+
+	noteSet, err := buildAllNoteSet()
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "failed to list notes")
+		return
+	}
+	ix, err := storage.OpenIndex(r.Context())
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "failed to read conversations")
+		return
+	}
+
+	result := BranchCompareData{A: BranchCompareSide{Name: a}, B: BranchCompareSide{Name: b}}
+	result.MergeBase, _ = git.MergeBase(s.repoDir, a, b)
+
+	refs := map[*BranchCompareSide]string{
+		&result.A: b + ".." + a,
+		&result.B: a + ".." + b,
+	}
+	if result.MergeBase != "" {
+		refs[&result.Shared] = result.MergeBase
+	}
+	for side, ref := range refs {
+		if err := fillCompareSide(ix, side, ref, limit, noteSet, s.repoDir); err != nil {
+			writeJSONError(w, http.StatusInternalServerError, "failed to list commits")
+			return
+		}
+	}
+	if result.Shared.Commits == nil {
+		result.Shared.Commits = []CommitInfo{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(result)
+}
+
+// fillCompareSide lists the commits of ref, at most limit of them, and adds
+// up their conversations.
+func fillCompareSide(ix *storage.Index, side *BranchCompareSide, ref string, limit int, noteSet map[string]bool, repoDir string) error {
+	commits, err := getCommitListForRef(ref, limit+1, repoDir)
+	if err != nil {
+		return err
+	}
+	if len(commits) > limit {
+		commits = commits[:limit]
+		side.Truncated = true
+	}
+	side.Commits = make([]CommitInfo, 0, len(commits))
+	for _, c := range commits {
+		info, conversations := commitInfo(ix, c, noteSet[c.SHA])
+		side.Commits = append(side.Commits, info)
+		side.Totals.add(conversations)
+	}
+	return nil
+}
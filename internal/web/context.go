@@ -0,0 +1,165 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/re-cinq/shift-log/editor"
+	"github.com/re-cinq/shift-log/internal/agent"
+	"github.com/re-cinq/shift-log/internal/git"
+	"github.com/re-cinq/shift-log/internal/storage"
+	"github.com/re-cinq/shift-log/internal/util"
+)
+
+// contextExcerptEntries is the number of entries kept on either side of an
+// edit in the excerpts of the context API, fewer than the file history's
+// for a hover to stay short.
+const contextExcerptEntries = 1
+
+// handleContext returns the conversation behind a line of a file, for
+// editor plugins: ?file=<path>&line=<n>. The contract is documented, and
+// kept stable, in package editor. Responses are not cached: a line's blame
+// changes with the working tree, which the ETags do not cover.
+func (s *Server) handleContext(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	line, err := strconv.Atoi(r.URL.Query().Get("line"))
+	if err != nil {
+		line = 0
+	}
+	lc, err := LineContext(s.repoDir, r.URL.Query().Get("file"), line)
+	if err != nil {
+		status := http.StatusInternalServerError
+		var ce *ContextError
+		if errors.As(err, &ce) {
+			status = ce.Status
+		}
+		writeJSONError(w, status, err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	_ = json.NewEncoder(w).Encode(lc)
+}
+
+// ContextError is an error of LineContext, with the HTTP status the context
+// API answers it with.
+type ContextError struct {
+	Status  int
+	Message string
+}
+
+func (e *ContextError) Error() string {
+	return e.Message
+}
+
+// LineContext returns the conversation behind a line of file in the
+// repository at repoDir, as the context API does. file is relative to the
+// repository root, or absolute within it; line starts at 1.
+func LineContext(repoDir, file string, line int) (*editor.LineContext, error) {
+	file = storage.RepoRelativePath(file, repoDir)
+	if file == "" {
+		return nil, &ContextError{http.StatusBadRequest, "file must be a path within the repository"}
+	}
+	if line < 1 {
+		return nil, &ContextError{http.StatusBadRequest, "line must be a line number, starting at 1"}
+	}
+
+	// A bare repository has no working tree to blame
+	rev := ""
+	if git.IsBareRepository() {
+		rev = "HEAD"
+	}
+	blamed, err := git.BlameIn(repoDir, rev, file, line, line)
+	if err != nil || len(blamed) == 0 {
+		return nil, &ContextError{http.StatusNotFound, fmt.Sprintf("could not blame line %d of %s", line, file)}
+	}
+
+	lc := &editor.LineContext{Version: editor.Version, File: file, Line: line, Conversations: []editor.Conversation{}}
+	if blamed[0].IsCommitted() {
+		sha := blamed[0].CommitSHA
+		lc.Commit = sha
+		lc.URL = "/#/commit/" + sha
+		if infos, err := git.DescribeCommits([]string{sha}); err == nil {
+			info := infos[sha]
+			lc.Subject, lc.Author, lc.Date = info.Subject, info.Author, util.NormalizeTimestamp(info.Date)
+		}
+		conversations, err := storage.GetConversationsWithPrivate(sha)
+		if err != nil {
+			return nil, &ContextError{http.StatusInternalServerError, "failed to read the conversations of " + sha[:7]}
+		}
+		for i, sc := range conversations {
+			lc.Conversations = append(lc.Conversations, editor.Conversation{
+				Index:        i,
+				SessionID:    sc.SessionID,
+				Agent:        sc.AgentName(),
+				Model:        sc.Model,
+				MessageCount: sc.MessageCount,
+				Summary:      sc.Summary,
+				Excerpt:      excerptMessages(storage.CommitFileExcerpt(sha, sc, file, contextExcerptEntries)),
+			})
+		}
+	}
+	lc.Markdown = contextMarkdown(lc)
+	return lc, nil
+}
+
+// excerptMessages converts transcript entries to the messages of the
+// context API, leaving out those with neither text nor tool calls, such as
+// tool results.
+func excerptMessages(entries []agent.TranscriptEntry) []editor.Message {
+	messages := []editor.Message{}
+	for _, entry := range entries {
+		if entry.Message == nil || (entry.Type != agent.MessageTypeUser && entry.Type != agent.MessageTypeAssistant) {
+			continue
+		}
+		var texts, tools []string
+		for _, block := range entry.Message.Content {
+			switch block.Type {
+			case "text":
+				if text := strings.TrimSpace(block.Text); text != "" {
+					texts = append(texts, text)
+				}
+			case "tool_use":
+				tools = append(tools, block.Name)
+			}
+		}
+		if len(texts) == 0 && len(tools) == 0 {
+			continue
+		}
+		messages = append(messages, editor.Message{Role: string(entry.Type), Text: strings.Join(texts, "\n\n"), Tools: tools})
+	}
+	return messages
+}
+
+// contextMarkdown summarises a line's context for a hover.
+func contextMarkdown(lc *editor.LineContext) string {
+	if lc.Commit == "" {
+		return "Not committed yet"
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "`%s` %s", lc.Commit[:7], lc.Subject)
+	if len(lc.Conversations) == 0 {
+		b.WriteString("\n\nNo conversation stored for this commit")
+		return b.String()
+	}
+	for _, c := range lc.Conversations {
+		fmt.Fprintf(&b, "\n\n**%s**", c.Agent)
+		if c.Model != "" {
+			fmt.Fprintf(&b, " (%s)", c.Model)
+		}
+		fmt.Fprintf(&b, ", %d messages", c.MessageCount)
+		if c.Summary != "" {
+			fmt.Fprintf(&b, "\n\n%s", c.Summary)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,80 @@
+package web
+
+import (
+	"context"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/re-cinq/shift-log/editor"
+)
+
+func TestHandleContext(t *testing.T) {
+	repo := newTestRepo(t)
+	chdir(t, repo.path)
+
+	repo.writeFile("src/a.go", "one\ntwo\n")
+	sha1 := repo.commit("Add a.go")
+	repo.addConversation(sha1, "session-1", editTranscript(filepath.Join(repo.path, "src", "a.go")), 2)
+	repo.writeFile("src/a.go", "one\ntwo\nthree\n")
+	sha2 := repo.commit("Add a line by hand")
+	repo.writeFile("src/a.go", "one\ntwo\nthree\nfour\n")
+
+	server := httptest.NewServer(NewServer(0, repo.path).Handler())
+	defer server.Close()
+	client := editor.NewClient(server.URL)
+	ctx := context.Background()
+
+	lc, err := client.Context(ctx, "src/a.go", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lc.Commit != sha1 || lc.Subject != "Add a.go" || lc.URL != "/#/commit/"+sha1 {
+		t.Errorf("line 2: %+v", lc)
+	}
+	if len(lc.Conversations) != 1 {
+		t.Fatalf("line 2: want 1 conversation, got %d", len(lc.Conversations))
+	}
+	c := lc.Conversations[0]
+	if c.SessionID != "session-1" || c.Agent != "claude" || len(c.Excerpt) != 2 {
+		t.Errorf("conversation: %+v", c)
+	}
+	if c.Excerpt[0].Role != "user" || c.Excerpt[0].Text != "Please update the file" || c.Excerpt[1].Tools[0] != "Edit" {
+		t.Errorf("excerpt: %+v", c.Excerpt)
+	}
+	if !strings.Contains(lc.Markdown, "**claude**, 2 messages") {
+		t.Errorf("markdown: %q", lc.Markdown)
+	}
+
+	// An absolute path, a commit without a conversation and an uncommitted line
+	lc, err = client.Context(ctx, filepath.Join(repo.path, "src", "a.go"), 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lc.File != "src/a.go" || lc.Commit != sha2 || len(lc.Conversations) != 0 {
+		t.Errorf("line 3: %+v", lc)
+	}
+	lc, err = client.Context(ctx, "src/a.go", 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lc.Commit != "" || lc.Markdown != "Not committed yet" {
+		t.Errorf("line 4: %+v", lc)
+	}
+
+	for _, tc := range []struct {
+		file string
+		line int
+		want string
+	}{
+		{"src/a.go", 0, "400 Bad Request: line must be"},
+		{"../outside.go", 1, "400 Bad Request: file must be"},
+		{"src/a.go", 10, "404 Not Found"},
+		{"missing.go", 1, "404 Not Found"},
+	} {
+		if _, err := client.Context(ctx, tc.file, tc.line); err == nil || !strings.HasPrefix(err.Error(), tc.want) {
+			t.Errorf("Context(%q, %d): err = %v, want %q", tc.file, tc.line, err, tc.want)
+		}
+	}
+}
@@ -0,0 +1,183 @@
+package web
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/re-cinq/shift-log/internal/agent"
+	"github.com/re-cinq/shift-log/internal/git"
+	"github.com/re-cinq/shift-log/internal/render"
+	"github.com/re-cinq/shift-log/internal/storage"
+)
+
+// maxConversationBytes caps the size of a posted conversation, transcript
+// included.
+const maxConversationBytes = 64 << 20
+
+// ErrInvalidConversation is wrapped by a ConversationStorer's errors that
+// are the client's fault, such as an unknown agent or commit. They are
+// reported with status 400.
+var ErrInvalidConversation = errors.New("invalid conversation")
+
+// StoreRequest is the body of POST /api/conversations: a coding agent's hook
+// payload and the transcript it refers to, to store on a commit of the
+// server's repository.
+type StoreRequest struct {
+	// Commit is the commit to store the conversation on. It must already
+	// have been pushed to the server's repository.
+	Commit string `json:"commit"`
+	// Agent is the coding agent that produced the hook payload and the
+	// transcript, "claude" by default.
+	Agent string `json:"agent,omitempty"`
+	// Hook is the agent's hook JSON, as passed to 'shiftlog store'. Only
+	// its session_id is taken from it.
+	Hook json.RawMessage `json:"hook"`
+	// Transcript is the content of the session's transcript file. It is
+	// required: the server never looks for the session's transcript itself.
+	Transcript string `json:"transcript"`
+	// ProjectPath is the repository root on the client, used to make the
+	// paths of touched files relative.
+	ProjectPath string `json:"project_path,omitempty"`
+	// Branch is the branch the commit was made on.
+	Branch string `json:"branch,omitempty"`
+}
+
+// StoreResponse reports what POST /api/conversations did.
+type StoreResponse struct {
+	Commit    string `json:"commit"`
+	SessionID string `json:"session_id"`
+	// Status is "stored", or "exists" when the session was already stored
+	// on the commit.
+	Status       string `json:"status"`
+	MessageCount int    `json:"message_count"`
+}
+
+// ConversationStorer stores a posted conversation in the server's
+// repository.
+type ConversationStorer func(req StoreRequest) (*StoreResponse, error)
+
+// EnableConversationAPI turns on POST /api/conversations, for requests
+// carrying token as a bearer token, and has store write the conversations.
+func (s *Server) EnableConversationAPI(token string, store ConversationStorer) {
+	s.apiToken = token
+	s.storeConversation = store
+}
+
+// handleConversations stores a conversation posted by a client, e.g. a CI
+// agent without the notes ref locally.
+func (s *Server) handleConversations(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if s.apiToken == "" || s.storeConversation == nil {
+		writeJSONError(w, http.StatusForbidden, "the conversation API is disabled; start shiftlog serve with an API token")
+		return
+	}
+	if !s.authorized(r) {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="shiftlog"`)
+		writeJSONError(w, http.StatusUnauthorized, "missing or invalid API token")
+		return
+	}
+
+	var req StoreRequest
+	if err := json.NewDecoder(io.LimitReader(r.Body, maxConversationBytes)).Decode(&req); err != nil {
+		writeJSONError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if req.Commit == "" || len(req.Hook) == 0 || req.Transcript == "" {
+		writeJSONError(w, http.StatusBadRequest, "commit, hook and transcript are required")
+		return
+	}
+
+	resp, err := s.storeConversation(req)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, ErrInvalidConversation) {
+			status = http.StatusBadRequest
+		}
+		writeJSONError(w, status, err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if resp.Status == "stored" {
+		w.WriteHeader(http.StatusCreated)
+	}
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+// authorized reports whether r carries the API token as a bearer token.
+func (s *Server) authorized(r *http.Request) bool {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return ok && subtle.ConstantTimeCompare([]byte(strings.TrimSpace(token)), []byte(s.apiToken)) == 1
+}
+
+// handleConversationDiff returns the entries of a session's transcript that
+// changed between the commits ?from= and ?to=, with ?context= unchanged
+// entries around each change (3 by default, all when negative). ?session=
+// picks the session when the commits share several.
+func (s *Server) handleConversationDiff(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+	from, to := query.Get("from"), query.Get("to")
+	if from == "" || to == "" {
+		writeJSONError(w, http.StatusBadRequest, "from and to are required")
+		return
+	}
+	context := 3
+	if c := query.Get("context"); c != "" {
+		val, err := strconv.Atoi(c)
+		if err != nil {
+			writeJSONError(w, http.StatusBadRequest, "invalid context")
+			return
+		}
+		context = val
+	}
+
+	var shas [2]string
+	for i, ref := range []string{from, to} {
+		if strings.HasPrefix(ref, "-") {
+			writeJSONError(w, http.StatusBadRequest, "invalid commit reference "+ref)
+			return
+		}
+		sha, err := git.ResolveRef(ref + "^{commit}")
+		if err != nil {
+			writeJSONError(w, http.StatusBadRequest, "invalid commit reference "+ref)
+			return
+		}
+		shas[i] = sha
+	}
+
+	diff, err := storage.DiffConversations(shas[0], shas[1], query.Get("session"), context)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, storage.ErrNoSessionToDiff) {
+			status = http.StatusNotFound
+		}
+		writeJSONError(w, status, err.Error())
+		return
+	}
+	for _, hunk := range diff.Hunks {
+		for i, line := range hunk.Lines {
+			sha := diff.To
+			if line.Op == agent.DiffRemoved {
+				sha = diff.From
+			}
+			referenceAttachments(&hunk.Lines[i].Entry, sha)
+			hunk.Lines[i].HTML = render.Entry(&hunk.Lines[i].Entry)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(diff)
+}
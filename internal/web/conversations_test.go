@@ -0,0 +1,143 @@
+package web
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/re-cinq/shift-log/internal/agent"
+	"github.com/re-cinq/shift-log/internal/storage"
+)
+
+func TestHandleConversations(t *testing.T) {
+	body := `{"commit":"abc123","hook":{"session_id":"s1"},"transcript":"{}"}`
+	post := func(srv *Server, token, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/api/conversations", strings.NewReader(body))
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		srv.mux.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := post(NewServer(0, ""), "secret", body); w.Code != http.StatusForbidden {
+		t.Errorf("without an API token: status = %d, want 403", w.Code)
+	}
+
+	var got StoreRequest
+	srv := NewServer(0, "")
+	srv.EnableConversationAPI("secret", func(req StoreRequest) (*StoreResponse, error) {
+		got = req
+		switch req.Commit {
+		case "bad":
+			return nil, fmt.Errorf("%w: unknown commit", ErrInvalidConversation)
+		case "broken":
+			return nil, fmt.Errorf("disk full")
+		}
+		return &StoreResponse{Commit: req.Commit, SessionID: "s1", Status: "stored"}, nil
+	})
+
+	tests := []struct {
+		name   string
+		token  string
+		body   string
+		status int
+	}{
+		{"no token", "", body, http.StatusUnauthorized},
+		{"wrong token", "guess", body, http.StatusUnauthorized},
+		{"invalid body", "secret", "not json", http.StatusBadRequest},
+		{"missing hook", "secret", `{"commit":"abc123","transcript":"{}"}`, http.StatusBadRequest},
+		{"missing transcript", "secret", `{"commit":"abc123","hook":{"session_id":"s1"}}`, http.StatusBadRequest},
+		{"client error", "secret", `{"commit":"bad","hook":{},"transcript":"{}"}`, http.StatusBadRequest},
+		{"server error", "secret", `{"commit":"broken","hook":{},"transcript":"{}"}`, http.StatusInternalServerError},
+		{"stored", "secret", body, http.StatusCreated},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if w := post(srv, tt.token, tt.body); w.Code != tt.status {
+				t.Errorf("status = %d, want %d: %s", w.Code, tt.status, w.Body.String())
+			}
+		})
+	}
+
+	if got.Commit != "abc123" || got.Transcript != "{}" || string(got.Hook) != `{"session_id":"s1"}` {
+		t.Errorf("storer got %+v", got)
+	}
+
+	req := httptest.NewRequest("GET", "/api/conversations", nil)
+	w := httptest.NewRecorder()
+	srv.mux.ServeHTTP(w, req)
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("GET status = %d, want 405", w.Code)
+	}
+}
+
+func TestHandleConversationDiff(t *testing.T) {
+	repo := newTestRepo(t)
+	chdir(t, repo.path)
+
+	repo.writeFile("a.txt", "a")
+	sha1 := repo.commit("First commit")
+	repo.addConversation(sha1, "session-1", sampleTranscript(), 2)
+	repo.writeFile("b.txt", "b")
+	sha2 := repo.commit("Second commit")
+	repo.addConversation(sha2, "session-1", extendedTranscript(), 4)
+	repo.writeFile("c.txt", "c")
+	sha3 := repo.commit("Third commit")
+	repo.addConversation(sha3, "session-2", sampleTranscript(), 2)
+
+	srv := NewServer(0, repo.path)
+	get := func(url string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", url, nil)
+		w := httptest.NewRecorder()
+		srv.mux.ServeHTTP(w, req)
+		return w
+	}
+
+	w := get("/api/conversations/diff?from=" + sha1 + "&to=" + sha2 + "&context=1")
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200: %s", w.Code, w.Body.String())
+	}
+	var diff storage.ConversationDiff
+	decodeJSON(t, w, &diff)
+	if diff.SessionID != "session-1" || diff.Added != 2 || diff.Removed != 0 {
+		t.Errorf("diff = session %q, %d added, %d removed; want session-1, 2 added, 0 removed", diff.SessionID, diff.Added, diff.Removed)
+	}
+	if len(diff.Hunks) != 1 || len(diff.Hunks[0].Lines) != 3 || diff.Hunks[0].Lines[0].Op != agent.DiffContext {
+		t.Errorf("hunks = %+v, want one hunk of one context and two added entries", diff.Hunks)
+	}
+	for _, line := range diff.Hunks[0].Lines {
+		if !strings.Contains(line.HTML, `class="message`) {
+			t.Errorf("line %s of entry %s has no rendered HTML: %q", line.Op, line.Entry.UUID, line.HTML)
+		}
+	}
+
+	if w := get("/api/conversations/diff?from=" + sha1 + "&to=" + sha3); w.Code != http.StatusNotFound {
+		t.Errorf("without a common session: status = %d, want 404", w.Code)
+	}
+	if w := get("/api/conversations/diff?from=" + sha1); w.Code != http.StatusBadRequest {
+		t.Errorf("without to: status = %d, want 400", w.Code)
+	}
+	if w := get("/api/conversations/diff?from=nope&to=" + sha2); w.Code != http.StatusBadRequest {
+		t.Errorf("unknown commit: status = %d, want 400", w.Code)
+	}
+}
+
+func TestHTMLContainsConversationDiff(t *testing.T) {
+	repo := newTestRepo(t)
+	srv := NewServer(0, repo.path)
+
+	req := httptest.NewRequest("GET", "/", nil)
+	w := httptest.NewRecorder()
+	srv.mux.ServeHTTP(w, req)
+
+	body := w.Body.String()
+	for _, elem := range []string{`id="compare-select"`, "/api/conversations/diff?", "function renderConversationDiff("} {
+		if !strings.Contains(body, elem) {
+			t.Errorf("index.html missing conversation diff element: %s", elem)
+		}
+	}
+}
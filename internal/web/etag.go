@@ -0,0 +1,114 @@
+package web
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/re-cinq/shift-log/internal/config"
+	"github.com/re-cinq/shift-log/internal/git"
+)
+
+// The API's responses only change when a ref does, a branch or tag moving
+// or a notes ref getting a conversation, or the configuration. Responses to
+// GET carry an ETag hashing every ref, HEAD, the configuration, the request
+// and the server process, and a client sending it back in If-None-Match
+// gets 304 Not Modified without the server doing the work again.
+// Cache-Control: no-cache has browsers revalidate their copy on every
+// request, so the viewer needs no code of its own for it.
+
+// cached wraps handler to serve its GET responses with an ETag and answer
+// requests whose If-None-Match has it with 304 Not Modified.
+func (s *Server) cached(handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			handler(w, r)
+			return
+		}
+		tag, err := s.etag(r)
+		if err != nil {
+			handler(w, r)
+			return
+		}
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Add("Vary", "Accept")
+		if etagMatches(r.Header.Get("If-None-Match"), tag) {
+			w.Header().Set("ETag", tag)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		handler(&etagWriter{ResponseWriter: w, tag: tag}, r)
+	}
+}
+
+// etag returns the entity tag of the response to r in the repository's
+// current state.
+func (s *Server) etag(r *http.Request) (string, error) {
+	refs, err := git.CommandIn(s.repoDir, "for-each-ref", "--format=%(HEAD)%(objectname) %(refname)").Output()
+	if err != nil {
+		return "", err
+	}
+	// Detached, HEAD is none of the refs; missing in a repository without commits
+	head, _ := git.CommandIn(s.repoDir, "rev-parse", "-q", "--verify", "HEAD").Output()
+	// The config selects, among others, the contributor refs shown
+	var cfg []byte
+	if path, err := config.Path(); err == nil {
+		cfg, _ = os.ReadFile(path)
+	}
+
+	h := sha256.New()
+	fmt.Fprintf(h, "%d\x00%s\x00%s\x00%s\x00", s.started.UnixNano(), r.URL.RequestURI(), r.Header.Get("Accept"), head)
+	_, _ = h.Write(refs)
+	_, _ = h.Write(cfg)
+	return `"` + hex.EncodeToString(h.Sum(nil)[:16]) + `"`, nil
+}
+
+// etagMatches reports whether an If-None-Match header matches tag. Weak
+// tags match by their value, as RFC 9110 has GET compare them.
+func etagMatches(header, tag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == tag || candidate == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+// etagWriter sets the ETag of successful responses only: errors are not
+// to be cached.
+type etagWriter struct {
+	http.ResponseWriter
+	tag         string
+	wroteHeader bool
+}
+
+func (w *etagWriter) WriteHeader(status int) {
+	if !w.wroteHeader {
+		w.wroteHeader = true
+		if status == http.StatusOK {
+			w.Header().Set("ETag", w.tag)
+		}
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *etagWriter) Write(p []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.ResponseWriter.Write(p)
+}
+
+// Flush keeps streamed responses working through the writer.
+func (w *etagWriter) Flush() {
+	_ = http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (w *etagWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
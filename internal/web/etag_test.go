@@ -0,0 +1,85 @@
+package web
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestETag(t *testing.T) {
+	repo := newTestRepo(t)
+	chdir(t, repo.path)
+	repo.writeFile("a.txt", "a")
+	sha := repo.commit("First commit")
+	repo.addConversation(sha, "session-1", sampleTranscript(), 2)
+
+	srv := NewServer(0, repo.path)
+	get := func(path, ifNoneMatch string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest("GET", path, nil)
+		if ifNoneMatch != "" {
+			req.Header.Set("If-None-Match", ifNoneMatch)
+		}
+		w := httptest.NewRecorder()
+		srv.mux.ServeHTTP(w, req)
+		return w
+	}
+
+	first := get("/api/commits", "")
+	tag := first.Header().Get("ETag")
+	if first.Code != http.StatusOK || tag == "" {
+		t.Fatalf("first request: want 200 with an ETag, got %d, %q", first.Code, tag)
+	}
+	if cc := first.Header().Get("Cache-Control"); cc != "no-cache" {
+		t.Errorf("Cache-Control: want no-cache, got %q", cc)
+	}
+
+	again := get("/api/commits", tag)
+	if again.Code != http.StatusNotModified {
+		t.Fatalf("revalidation: want 304, got %d", again.Code)
+	}
+	if again.Body.Len() != 0 {
+		t.Errorf("304 has a body: %q", again.Body.String())
+	}
+	if get("/api/commits", `"other", W/`+tag).Code != http.StatusNotModified {
+		t.Error("a weak tag in a list did not match")
+	}
+
+	if other := get("/api/commits?limit=1", "").Header().Get("ETag"); other == tag {
+		t.Error("another query has the same ETag")
+	}
+
+	// A new conversation changes the notes ref, and so the tag
+	repo.writeFile("b.txt", "b")
+	sha2 := repo.commit("Second commit")
+	repo.addConversation(sha2, "session-2", sampleTranscript(), 2)
+	changed := get("/api/commits", tag)
+	if changed.Code != http.StatusOK {
+		t.Fatalf("after a new conversation: want 200, got %d", changed.Code)
+	}
+	if changed.Header().Get("ETag") == tag {
+		t.Error("the ETag did not change with the refs")
+	}
+
+	// Errors are not tagged
+	if missing := get("/api/commits/unknown", ""); missing.Header().Get("ETag") != "" {
+		t.Errorf("a %d response has an ETag", missing.Code)
+	}
+}
+
+func TestETagMatches(t *testing.T) {
+	tests := map[string]bool{
+		`"abc"`:      true,
+		`W/"abc"`:    true,
+		`"x", "abc"`: true,
+		`*`:          true,
+		`"abcd"`:     false,
+		``:           false,
+		`"x",W/"y"`:  false,
+	}
+	for header, want := range tests {
+		if got := etagMatches(header, `"abc"`); got != want {
+			t.Errorf("etagMatches(%q) = %v, want %v", header, got, want)
+		}
+	}
+}
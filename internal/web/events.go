@@ -0,0 +1,94 @@
+package web
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/re-cinq/shift-log/internal/git"
+)
+
+// eventPollInterval is how often the event stream checks for new commits and
+// conversations. A variable so tests can shorten it.
+var eventPollInterval = 2 * time.Second
+
+// HeadEvent is sent on the event stream whenever HEAD moves or a
+// conversation is stored, so the UI can follow an agent's work.
+type HeadEvent struct {
+	SHA             string `json:"sha"`
+	Branch          string `json:"branch,omitempty"` // empty when HEAD is detached
+	HasConversation bool   `json:"has_conversation"`
+}
+
+// handleEvents streams HeadEvents as server-sent events. The current HEAD is
+// sent on connect and again whenever it or the notes ref changes, until the
+// client goes away or the server shuts down.
+func (s *Server) handleEvents(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	// The stream outlives the server's write timeout
+	_ = http.NewResponseController(w).SetWriteDeadline(time.Time{})
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+
+	ticker := time.NewTicker(eventPollInterval)
+	defer ticker.Stop()
+
+	var last string
+	for {
+		event, state, err := s.headState()
+		if err == nil && state != last {
+			last = state
+			data, _ := json.Marshal(event)
+			if _, err := fmt.Fprintf(w, "event: head\ndata: %s\n\n", data); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+
+		select {
+		case <-r.Context().Done():
+			return
+		case <-s.shuttingDown:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// headState returns the current HEAD event and a fingerprint that changes
+// when HEAD moves or any conversation note is added.
+func (s *Server) headState() (HeadEvent, string, error) {
+	head, err := s.gitOutput("rev-parse", "HEAD")
+	if err != nil {
+		return HeadEvent{}, "", err
+	}
+	branch, _ := s.gitOutput("symbolic-ref", "--short", "-q", "HEAD")
+	// Missing until the first conversation is stored
+	notes, _ := s.gitOutput("rev-parse", "-q", "--verify", git.NotesRef)
+	_, noteErr := s.gitOutput("notes", "--ref", git.NotesRef, "show", head)
+
+	event := HeadEvent{SHA: head, Branch: branch, HasConversation: noteErr == nil}
+	return event, head + " " + branch + " " + notes, nil
+}
+
+func (s *Server) gitOutput(args ...string) (string, error) {
+	output, err := git.CommandIn(s.repoDir, args...).Output()
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(output)), nil
+}
@@ -0,0 +1,78 @@
+package web
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHandleEvents(t *testing.T) {
+	orig := eventPollInterval
+	eventPollInterval = 20 * time.Millisecond
+	t.Cleanup(func() { eventPollInterval = orig })
+
+	repo := newTestRepo(t)
+	chdir(t, repo.path)
+	repo.writeFile("a.txt", "a")
+	sha1 := repo.commit("First commit")
+	branch := strings.TrimSpace(repo.git("symbolic-ref", "--short", "HEAD"))
+
+	ts := httptest.NewServer(NewServer(0, repo.path).mux)
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL + "/api/events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("Content-Type = %q, want text/event-stream", ct)
+	}
+
+	events := make(chan HeadEvent)
+	go func() {
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			if data, ok := strings.CutPrefix(scanner.Text(), "data: "); ok {
+				var event HeadEvent
+				if json.Unmarshal([]byte(data), &event) == nil {
+					events <- event
+				}
+			}
+		}
+		close(events)
+	}()
+
+	next := func() HeadEvent {
+		t.Helper()
+		select {
+		case event, ok := <-events:
+			if !ok {
+				t.Fatal("event stream closed")
+			}
+			return event
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for event")
+		}
+		return HeadEvent{}
+	}
+
+	if got := next(); got.SHA != sha1 || got.Branch != branch || got.HasConversation {
+		t.Errorf("initial event = %+v, want HEAD %s on %s without conversation", got, sha1, branch)
+	}
+
+	repo.writeFile("b.txt", "b")
+	sha2 := repo.commit("Second commit")
+	if got := next(); got.SHA != sha2 || got.HasConversation {
+		t.Errorf("commit event = %+v, want HEAD %s without conversation", got, sha2)
+	}
+
+	repo.addConversation(sha2, "session-1", sampleTranscript(), 2)
+	if got := next(); got.SHA != sha2 || !got.HasConversation {
+		t.Errorf("note event = %+v, want HEAD %s with conversation", got, sha2)
+	}
+}
@@ -0,0 +1,124 @@
+package web
+
+import (
+	"slices"
+)
+
+// Graph layout
+//
+// Graph nodes are returned in rows, newest first with every commit above
+// its parents, and each node is given a lane (a column), as in
+// git log --graph:
+//
+//   - A commit takes the leftmost lane waiting for it, or the leftmost free
+//     lane when none is, as a branch tip does. Other lanes waiting for it,
+//     from branches forked off it, end at its row.
+//   - Its first parent continues in its lane.
+//   - Each other parent of a merge continues in a lane already waiting for
+//     it, or in the leftmost free lane.
+//   - A root commit frees its lane.
+//
+// ParentLanes[i] is the lane the edge to Parents[i] runs down in, from the
+// row below the commit to the row above the parent: the edge leaves the
+// commit's lane at its top end and joins the parent's lane at its bottom
+// end. Parents beyond the listed commits keep their lane to the last row.
+
+// sortGraphNodes orders nodes newest first with every commit above its
+// parents. Commits that do not depend on each other are ordered by date.
+func sortGraphNodes(nodes []GraphNode) []GraphNode {
+	index := make(map[string]int, len(nodes))
+	for i, n := range nodes {
+		index[n.SHA] = i
+	}
+	// children[i] counts the listed commits above node i
+	children := make([]int, len(nodes))
+	for _, n := range nodes {
+		for _, p := range n.Parents {
+			if i, ok := index[p]; ok {
+				children[i]++
+			}
+		}
+	}
+
+	var ready []int
+	for i := range nodes {
+		if children[i] == 0 {
+			ready = append(ready, i)
+		}
+	}
+	sorted := make([]GraphNode, 0, len(nodes))
+	for len(ready) > 0 {
+		// Take the newest ready commit, the first listed on a tie
+		next := 0
+		for k, i := range ready {
+			if nodes[i].Date > nodes[ready[next]].Date || (nodes[i].Date == nodes[ready[next]].Date && i < ready[next]) {
+				next = k
+			}
+		}
+		i := ready[next]
+		ready = slices.Delete(ready, next, next+1)
+		sorted = append(sorted, nodes[i])
+		for _, p := range nodes[i].Parents {
+			if j, ok := index[p]; ok {
+				if children[j]--; children[j] == 0 {
+					ready = append(ready, j)
+				}
+			}
+		}
+	}
+	return sorted
+}
+
+// assignLanes sets the lane and parent lanes of nodes, which must be sorted
+// with every commit above its parents, and returns the number of lanes.
+func assignLanes(nodes []GraphNode) int {
+	var lanes []string // the commit each lane waits for, "" when free
+	take := func(sha string) int {
+		if i := slices.Index(lanes, ""); i >= 0 {
+			lanes[i] = sha
+			return i
+		}
+		lanes = append(lanes, sha)
+		return len(lanes) - 1
+	}
+
+	width := 0
+	for i := range nodes {
+		n := &nodes[i]
+		n.Lane = -1
+		for l, waiting := range lanes {
+			if waiting != n.SHA {
+				continue
+			}
+			if n.Lane < 0 {
+				n.Lane = l
+			} else {
+				lanes[l] = ""
+			}
+		}
+		if n.Lane < 0 {
+			n.Lane = take(n.SHA)
+		}
+		lanes[n.Lane] = ""
+
+		n.ParentLanes = make([]int, len(n.Parents))
+		for p, parent := range n.Parents {
+			if p == 0 {
+				lanes[n.Lane] = parent
+				n.ParentLanes[p] = n.Lane
+				continue
+			}
+			l := slices.Index(lanes, parent)
+			if l < 0 {
+				l = take(parent)
+			}
+			n.ParentLanes[p] = l
+		}
+
+		width = max(width, len(lanes))
+		for len(lanes) > 0 && lanes[len(lanes)-1] == "" {
+			lanes = lanes[:len(lanes)-1]
+		}
+	}
+	return width
+}
@@ -0,0 +1,158 @@
+package web
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+)
+
+// graphNode builds a node with the given parents.
+func graphNode(sha string, parents ...string) GraphNode {
+	return GraphNode{SHA: sha, Parents: parents}
+}
+
+func TestAssignLanes(t *testing.T) {
+	tests := []struct {
+		name        string
+		nodes       []GraphNode
+		wantLanes   map[string]int
+		wantParents map[string][]int
+		wantWidth   int
+	}{
+		{
+			name:        "linear history",
+			nodes:       []GraphNode{graphNode("c", "b"), graphNode("b", "a"), graphNode("a")},
+			wantLanes:   map[string]int{"c": 0, "b": 0, "a": 0},
+			wantParents: map[string][]int{"c": {0}, "b": {0}},
+			wantWidth:   1,
+		},
+		{
+			name:        "fork",
+			nodes:       []GraphNode{graphNode("x", "base"), graphNode("y", "base"), graphNode("base")},
+			wantLanes:   map[string]int{"x": 0, "y": 1, "base": 0},
+			wantParents: map[string][]int{"x": {0}, "y": {1}},
+			wantWidth:   2,
+		},
+		{
+			name: "octopus merge",
+			nodes: []GraphNode{
+				graphNode("m", "a", "b", "c"),
+				graphNode("a", "base"), graphNode("b", "base"), graphNode("c", "base"),
+				graphNode("base"),
+			},
+			wantLanes:   map[string]int{"m": 0, "a": 0, "b": 1, "c": 2, "base": 0},
+			wantParents: map[string][]int{"m": {0, 1, 2}, "a": {0}, "b": {1}, "c": {2}},
+			wantWidth:   3,
+		},
+		{
+			name: "octopus merge of a branch already drawn",
+			nodes: []GraphNode{
+				graphNode("tip", "b"),
+				graphNode("m", "a", "b", "c"),
+				graphNode("a", "base"), graphNode("b", "base"), graphNode("c", "base"),
+				graphNode("base"),
+			},
+			wantLanes:   map[string]int{"tip": 0, "m": 1, "b": 0, "a": 1, "c": 2, "base": 0},
+			wantParents: map[string][]int{"tip": {0}, "m": {1, 0, 2}},
+			wantWidth:   3,
+		},
+		{
+			name: "lanes are reused",
+			nodes: []GraphNode{
+				graphNode("x", "r"), graphNode("r"),
+				graphNode("y", "s"), graphNode("s"),
+			},
+			wantLanes: map[string]int{"x": 0, "r": 0, "y": 0, "s": 0},
+			wantWidth: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			width := assignLanes(tt.nodes)
+			if width != tt.wantWidth {
+				t.Errorf("width = %d, want %d", width, tt.wantWidth)
+			}
+			for _, n := range tt.nodes {
+				if want, ok := tt.wantLanes[n.SHA]; ok && n.Lane != want {
+					t.Errorf("lane of %s = %d, want %d", n.SHA, n.Lane, want)
+				}
+				if want, ok := tt.wantParents[n.SHA]; ok && !slices.Equal(n.ParentLanes, want) {
+					t.Errorf("parent lanes of %s = %v, want %v", n.SHA, n.ParentLanes, want)
+				}
+			}
+		})
+	}
+}
+
+func TestSortGraphNodes(t *testing.T) {
+	// The parent's date is after its child's, as with a skewed clock
+	nodes := []GraphNode{
+		{SHA: "base", Date: "2025-01-05T00:00:00Z"},
+		{SHA: "old", Parents: []string{"base"}, Date: "2025-01-01T00:00:00Z"},
+		{SHA: "new", Parents: []string{"base"}, Date: "2025-01-03T00:00:00Z"},
+		{SHA: "merge", Parents: []string{"old", "new"}, Date: "2025-01-02T00:00:00Z"},
+	}
+
+	var got []string
+	for _, n := range sortGraphNodes(nodes) {
+		got = append(got, n.SHA)
+	}
+	if want := []string{"merge", "new", "old", "base"}; !slices.Equal(got, want) {
+		t.Errorf("order = %v, want %v", got, want)
+	}
+}
+
+func TestHandleGraphOctopusMerge(t *testing.T) {
+	repo := newTestRepo(t)
+	chdir(t, repo.path)
+
+	repo.writeFile("base.txt", "base")
+	repo.commit("Base")
+	for _, b := range []string{"one", "two", "three"} {
+		repo.git("checkout", "-q", "-b", b, "master")
+		repo.writeFile(b+".txt", b)
+		repo.commit("Add " + b)
+	}
+	repo.git("checkout", "-q", "master")
+	repo.git("merge", "--no-gpg-sign", "-q", "--no-ff", "-m", "Octopus", "one", "two", "three")
+
+	srv := NewServer(0, repo.path)
+	req := httptest.NewRequest("GET", "/api/graph", nil)
+	w := httptest.NewRecorder()
+	srv.mux.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status: want 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var nodes []GraphNode
+	decodeJSON(t, w, &nodes)
+	if len(nodes) != 5 {
+		t.Fatalf("expected 5 nodes, got %d", len(nodes))
+	}
+
+	merge := nodes[0]
+	if len(merge.Parents) != 4 {
+		t.Fatalf("expected the octopus merge first with 4 parents, got %v", merge.Parents)
+	}
+	// Every parent of the merge gets its own lane, and is drawn in it
+	if lanes := slices.Compact(slices.Sorted(slices.Values(merge.ParentLanes))); len(lanes) != 4 {
+		t.Errorf("parent lanes of the merge = %v, want 4 different lanes", merge.ParentLanes)
+	}
+	row := make(map[string]int)
+	for i, n := range nodes {
+		row[n.SHA] = i
+	}
+	for i, p := range merge.Parents {
+		if got := nodes[row[p]].Lane; got != merge.ParentLanes[i] {
+			t.Errorf("lane of parent %d = %d, want %d", i, got, merge.ParentLanes[i])
+		}
+	}
+	for _, n := range nodes {
+		for _, p := range n.Parents {
+			if row[p] <= row[n.SHA] {
+				t.Errorf("parent %s is not below %s", p[:7], n.SHA[:7])
+			}
+		}
+	}
+}
@@ -0,0 +1,235 @@
+package web
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	shiftlogv1 "github.com/re-cinq/shift-log/api/shiftlog/v1"
+	"github.com/re-cinq/shift-log/internal/agent"
+	"github.com/re-cinq/shift-log/internal/git"
+	"github.com/re-cinq/shift-log/internal/storage"
+	"github.com/re-cinq/shift-log/internal/util"
+)
+
+// Defaults of the gRPC requests' limits, those of GET /api/commits and
+// shiftlog search.
+const (
+	grpcCommitsLimit = 100
+	grpcSearchLimit  = 20
+)
+
+// grpcService implements the gRPC API, served through the mux under the
+// service's path: gRPC requests are HTTP/2 POSTs to /<service>/<method>.
+type grpcService struct {
+	shiftlogv1.UnimplementedShiftlogServer
+	s *Server
+}
+
+// newGRPCServer returns the gRPC server of s.
+func newGRPCServer(s *Server) *grpc.Server {
+	server := grpc.NewServer()
+	shiftlogv1.RegisterShiftlogServer(server, &grpcService{s: s})
+	return server
+}
+
+// grpcPath is the path the gRPC API is served under.
+var grpcPath = "/" + shiftlogv1.Shiftlog_ServiceDesc.ServiceName + "/"
+
+func (g *grpcService) ListCommits(req *shiftlogv1.ListCommitsRequest, stream grpc.ServerStreamingServer[shiftlogv1.Commit]) error {
+	limit := int(req.Limit)
+	if limit <= 0 {
+		limit = grpcCommitsLimit
+	}
+	tag := strings.ToLower(req.Tag)
+
+	var noteSet map[string]bool
+	var err error
+	if req.Revision != "" {
+		noteSet, err = buildAllNoteSet()
+	} else {
+		noteSet, err = buildNoteSet()
+	}
+	if err != nil {
+		return status.Error(codes.Internal, "failed to list conversations")
+	}
+	ix, err := storage.OpenIndex(stream.Context())
+	if err != nil {
+		return status.Error(codes.Internal, "failed to read conversations")
+	}
+
+	args := []string{fmt.Sprintf("--max-count=%d", limit)}
+	if req.Revision != "" {
+		if strings.HasPrefix(req.Revision, "-") {
+			return status.Error(codes.InvalidArgument, "invalid revision")
+		}
+		args = append([]string{req.Revision}, args...)
+	}
+	if req.Author != "" {
+		args = append(args, "--fixed-strings", "--author="+req.Author)
+	}
+	commits, err := listCommits(g.s.repoDir, args...)
+	if err != nil {
+		return status.Errorf(codes.InvalidArgument, "could not list the commits of %q", req.Revision)
+	}
+	tags, _ := git.ListTags(g.s.repoDir)
+	gitTags := tagsByCommit(tags)
+
+	for _, commit := range commits {
+		hasConv := noteSet[commit.SHA]
+		if (req.HasConversation || tag != "") && !hasConv {
+			continue
+		}
+		info, conversations := commitInfo(ix, commit, hasConv)
+		if tag != "" && !slices.Contains(info.Tags, tag) {
+			continue
+		}
+		c := &shiftlogv1.Commit{
+			Sha:     commit.SHA,
+			Message: commit.Message,
+			Author:  commit.Author,
+			Date:    commit.Date,
+			GitTags: gitTags[commit.SHA],
+		}
+		for i, sc := range conversations {
+			c.Conversations = append(c.Conversations, conversationProto(i, sc))
+		}
+		if err := stream.Send(c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (g *grpcService) GetConversations(ctx context.Context, req *shiftlogv1.GetConversationsRequest) (*shiftlogv1.GetConversationsResponse, error) {
+	if req.Commit == "" || strings.HasPrefix(req.Commit, "-") {
+		return nil, status.Error(codes.InvalidArgument, "commit is required")
+	}
+	sha, err := git.ResolveRef(req.Commit + "^{commit}")
+	if err != nil {
+		return nil, status.Errorf(codes.NotFound, "unknown commit %s", req.Commit)
+	}
+	conversations, err := storage.GetConversationsWithPrivate(sha)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to read conversation")
+	}
+	if conversations == nil {
+		return nil, status.Errorf(codes.NotFound, "no conversation stored for %s", sha[:7])
+	}
+
+	response := &shiftlogv1.GetConversationsResponse{Sha: sha}
+	for i, sc := range conversations {
+		c := conversationProto(i, sc)
+		if !req.MetadataOnly {
+			transcript, err := sc.ParseTranscript()
+			if err != nil {
+				return nil, status.Error(codes.Internal, "failed to parse transcript")
+			}
+			for _, entry := range transcript.Entries {
+				c.Transcript = append(c.Transcript, entryProto(entry))
+			}
+		}
+		response.Conversations = append(response.Conversations, c)
+	}
+	return response, nil
+}
+
+func (g *grpcService) Search(req *shiftlogv1.SearchRequest, stream grpc.ServerStreamingServer[shiftlogv1.SearchResult]) error {
+	if req.Query == "" && req.Agent == "" && req.Branch == "" && req.Model == "" && req.Tag == "" {
+		return status.Error(codes.InvalidArgument, "a query or a filter is required")
+	}
+	limit := int(req.Limit)
+	if limit <= 0 {
+		limit = grpcSearchLimit
+	}
+	results, err := storage.Search(stream.Context(), &storage.SearchParams{
+		Query:         req.Query,
+		Agent:         req.Agent,
+		Branch:        req.Branch,
+		Model:         req.Model,
+		Tag:           req.Tag,
+		Limit:         limit,
+		MetadataOnly:  req.MetadataOnly,
+		CaseSensitive: req.CaseSensitive,
+		Regex:         req.Regex,
+	})
+	if err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	for _, r := range results {
+		result := &shiftlogv1.SearchResult{
+			Sha:           r.CommitSHA,
+			CommitDate:    r.CommitDate,
+			CommitMessage: r.CommitMsg,
+			Agent:         r.Agent,
+			Branch:        r.Branch,
+			Model:         r.Model,
+			MessageCount:  int32(r.MsgCount),
+			Summary:       r.Summary,
+			Tags:          r.Tags,
+		}
+		for _, m := range r.Matches {
+			result.Matches = append(result.Matches, &shiftlogv1.SearchMatch{
+				EntryType: m.EntryType,
+				BlockType: m.BlockType,
+				ToolName:  m.ToolName,
+				Snippet:   m.Snippet,
+			})
+		}
+		if err := stream.Send(result); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// conversationProto returns the metadata of the index-th conversation of a
+// commit.
+func conversationProto(index int, sc *storage.StoredConversation) *shiftlogv1.Conversation {
+	return &shiftlogv1.Conversation{
+		Index:        int32(index),
+		SessionId:    sc.SessionID,
+		Agent:        sc.AgentName(),
+		Model:        sc.Model,
+		Timestamp:    util.NormalizeTimestamp(sc.Timestamp),
+		MessageCount: int32(sc.MessageCount),
+		Summary:      sc.Summary,
+		Tags:         sc.Tags,
+		Private:      sc.IsPrivate(),
+		NotesRef:     sc.ContributorRef,
+	}
+}
+
+// entryProto converts a transcript entry. The data of images and
+// documents is left out, as in the conversation responses of the HTTP API.
+func entryProto(entry agent.TranscriptEntry) *shiftlogv1.TranscriptEntry {
+	e := &shiftlogv1.TranscriptEntry{
+		Uuid:       entry.UUID,
+		ParentUuid: entry.ParentUUID,
+		Type:       string(entry.Type),
+		Timestamp:  entry.Timestamp,
+		Model:      entry.Model,
+	}
+	if entry.Message == nil {
+		return e
+	}
+	for _, block := range entry.Message.Content {
+		b := &shiftlogv1.ContentBlock{Type: block.Type, Text: block.Text, Name: block.Name, ToolUseId: block.ToolUseID}
+		switch block.Type {
+		case "thinking":
+			b.Text = block.Thinking
+		case "tool_use":
+			b.ToolUseId = block.ID
+			b.Json = string(block.Input)
+		case "tool_result":
+			b.Json = string(block.Content)
+		}
+		e.Content = append(e.Content, b)
+	}
+	return e
+}
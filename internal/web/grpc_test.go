@@ -0,0 +1,104 @@
+package web
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+
+	shiftlogv1 "github.com/re-cinq/shift-log/api/shiftlog/v1"
+)
+
+func TestGRPC(t *testing.T) {
+	repo := newTestRepo(t)
+	chdir(t, repo.path)
+
+	repo.writeFile("a.txt", "one")
+	sha1 := repo.commit("First commit")
+	repo.addConversation(sha1, "session-1", sampleTranscript(), 2)
+	repo.writeFile("a.txt", "two")
+	sha2 := repo.commit("Second commit")
+
+	// gRPC shares the port of the HTTP API
+	server := httptest.NewUnstartedServer(NewServer(0, repo.path).Handler())
+	server.Config.Protocols = serverProtocols()
+	server.Start()
+	defer server.Close()
+	resp, err := http.Get(server.URL + "/api/commits")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("GET /api/commits = %d", resp.StatusCode)
+	}
+
+	conn, err := grpc.NewClient(strings.TrimPrefix(server.URL, "http://"), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = conn.Close() }()
+	client := shiftlogv1.NewShiftlogClient(conn)
+	ctx := context.Background()
+
+	stream, err := client.ListCommits(ctx, &shiftlogv1.ListCommitsRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var commits []*shiftlogv1.Commit
+	for {
+		c, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		commits = append(commits, c)
+	}
+	if len(commits) != 2 || commits[0].Sha != sha2 || commits[1].Sha != sha1 {
+		t.Fatalf("ListCommits() = %v", commits)
+	}
+	if len(commits[0].Conversations) != 0 || len(commits[1].Conversations) != 1 || commits[1].Conversations[0].SessionId != "session-1" {
+		t.Errorf("conversations of ListCommits() = %v, %v", commits[0].Conversations, commits[1].Conversations)
+	}
+
+	got, err := client.GetConversations(ctx, &shiftlogv1.GetConversationsRequest{Commit: sha1[:7]})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Sha != sha1 || len(got.Conversations) != 1 {
+		t.Fatalf("GetConversations() = %v", got)
+	}
+	transcript := got.Conversations[0].Transcript
+	if len(transcript) != 2 || transcript[1].Type != "assistant" || transcript[1].Content[0].Text != "Of course! What do you need?" {
+		t.Errorf("transcript = %v", transcript)
+	}
+	_, err = client.GetConversations(ctx, &shiftlogv1.GetConversationsRequest{Commit: sha2})
+	if status.Code(err) != codes.NotFound {
+		t.Errorf("GetConversations() of a commit without conversation: %v, want NotFound", err)
+	}
+
+	results, err := client.Search(ctx, &shiftlogv1.SearchRequest{Query: "help"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := results.Recv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Sha != sha1 || len(result.Matches) == 0 {
+		t.Errorf("Search() = %v", result)
+	}
+	if _, err := results.Recv(); !errors.Is(err, io.EOF) {
+		t.Errorf("Search() returned more than one result: %v", err)
+	}
+}
@@ -3,26 +3,53 @@ package web
 import (
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
 	"os/exec"
+	"slices"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/re-cinq/shift-log/internal/agent"
 	agentclaude "github.com/re-cinq/shift-log/internal/agent/claude"
+	"github.com/re-cinq/shift-log/internal/badge"
+	"github.com/re-cinq/shift-log/internal/config"
 	"github.com/re-cinq/shift-log/internal/git"
 	"github.com/re-cinq/shift-log/internal/storage"
+	"github.com/re-cinq/shift-log/internal/util"
 )
 
 // CommitInfo represents commit data for the API
 type CommitInfo struct {
-	SHA             string          `json:"sha"`
-	Message         string          `json:"message"`
-	Author          string          `json:"author"`
-	Date            string          `json:"date"`
-	HasConversation bool            `json:"has_conversation"`
-	MessageCount    int             `json:"message_count,omitempty"`
-	Effort          *storage.Effort `json:"effort,omitempty"`
+	SHA             string              `json:"sha"`
+	Message         string              `json:"message"`
+	Author          string              `json:"author"`
+	Date            string              `json:"date"`
+	HasConversation bool                `json:"has_conversation"`
+	MessageCount    int                 `json:"message_count,omitempty"`
+	Effort          *storage.Effort     `json:"effort,omitempty"`
+	AIAssisted      bool                `json:"ai_assisted,omitempty"`
+	Authorship      *storage.Authorship `json:"authorship,omitempty"`
+	Summary         string              `json:"summary,omitempty"`
+	Tags            []string            `json:"tags,omitempty"`
+	Agents          []string            `json:"agents,omitempty"`   // agents of each conversation, when several are stored
+	GitTags         []string            `json:"git_tags,omitempty"` // git tags of the commit; tags holds the conversation's labels
+	Private         bool                `json:"private,omitempty"`  // a conversation of the commit is private, kept in this clone only
+	// Signals are the later reverts and fixes of the commit's agent work,
+	// listed with ?problematic=true.
+	Signals []storage.QualitySignal `json:"signals,omitempty"`
+}
+
+// ConversationRef identifies one of the conversations stored for a commit.
+type ConversationRef struct {
+	Index        int    `json:"index"`
+	SessionID    string `json:"session_id"`
+	Agent        string `json:"agent"`
+	Model        string `json:"model,omitempty"`
+	MessageCount int    `json:"message_count"`
+	Private      bool   `json:"private,omitempty"`
+	NotesRef     string `json:"notes_ref,omitempty"` // contributor ref the conversation was read from
 }
 
 // ConversationResponse represents the full conversation data
@@ -33,20 +60,40 @@ type ConversationResponse struct {
 	MessageCount     int                      `json:"message_count"`
 	Agent            string                   `json:"agent,omitempty"`
 	Model            string                   `json:"model,omitempty"`
+	Provenance       *storage.Provenance      `json:"provenance"` // agent version, models used and store trigger
 	Effort           *storage.Effort          `json:"effort,omitempty"`
-	Transcript       []agent.TranscriptEntry `json:"transcript"`
+	Summary          string                   `json:"summary,omitempty"`
+	Tags             []string                 `json:"tags,omitempty"`
+	Transcript       []agent.TranscriptEntry  `json:"transcript"`
 	IsIncremental    bool                     `json:"is_incremental"`
 	ParentCommitSHA  string                   `json:"parent_commit_sha,omitempty"`
 	IncrementalCount int                      `json:"incremental_count,omitempty"`
+	Offset           int                      `json:"offset"`                  // index of the first transcript entry, with ?offset=
+	TotalEntries     int                      `json:"total_entries"`           // entries on all pages
+	DisplayedCount   int                      `json:"displayed_count"`         // user and assistant messages on all pages
+	Commit           *git.CommitDetails       `json:"commit,omitempty"`        // full message, trailers and signature status
+	Signature        *storage.SignatureStatus `json:"signature,omitempty"`     // status of the conversation's own signature, when signed
+	Index            int                      `json:"index"`                   // index of this conversation among the commit's conversations
+	Conversations    []ConversationRef        `json:"conversations,omitempty"` // every conversation of the commit, when several agent sessions contributed
+	Private          bool                     `json:"private,omitempty"`       // kept in this clone only, until shiftlog publish shares it
+	NotesRef         string                   `json:"notes_ref,omitempty"`     // contributor ref the conversation was read from, empty for the shared notes
+	Thinking         string                   `json:"thinking,omitempty"`      // config.ThinkingStrip or ThinkingHash when the thinking policy left thinking out
 }
 
-// GraphNode represents a node in the commit graph
+// GraphNode represents a node in the commit graph. Lane and ParentLanes
+// place it in the graph; see graph.go for the layout.
 type GraphNode struct {
-	SHA             string   `json:"sha"`
-	Parents         []string `json:"parents"`
-	HasConversation bool     `json:"has_conversation"`
-	Message         string   `json:"message"`
-	Date            string   `json:"date,omitempty"`
+	SHA             string              `json:"sha"`
+	Parents         []string            `json:"parents"`
+	Lane            int                 `json:"lane"`
+	ParentLanes     []int               `json:"parent_lanes,omitempty"`
+	HasConversation bool                `json:"has_conversation"`
+	Message         string              `json:"message"`
+	Date            string              `json:"date,omitempty"`
+	AIAssisted      bool                `json:"ai_assisted,omitempty"`
+	Authorship      *storage.Authorship `json:"authorship,omitempty"`
+	// Merged lists the conversations a merge commit brought in from other branches
+	Merged []storage.MergedConversation `json:"merged,omitempty"`
 }
 
 // BranchSummary represents a branch in the branches API response.
@@ -61,6 +108,9 @@ type BranchSummary struct {
 // BranchGraphData is the top-level response for the branch graph endpoint.
 type BranchGraphData struct {
 	Branches []BranchGraphEntry `json:"branches"`
+	// Rows lists the commits of all branches newest first, each above its
+	// parents, giving the row of each commit in the branch overview.
+	Rows []string `json:"rows"`
 }
 
 // ForkPoint describes where a branch diverged from another branch.
@@ -69,12 +119,35 @@ type ForkPoint struct {
 	CommitSHA    string `json:"commit_sha"`
 }
 
-// BranchGraphEntry holds graph nodes for a single branch.
+// BranchGraphEntry holds graph nodes for a single branch, laid out in lanes
+// of their own.
 type BranchGraphEntry struct {
-	Name      string     `json:"name"`
-	IsCurrent bool       `json:"is_current"`
+	Name      string      `json:"name"`
+	IsCurrent bool        `json:"is_current"`
 	Nodes     []GraphNode `json:"nodes"`
-	ForkPoint *ForkPoint `json:"fork_point,omitempty"`
+	ForkPoint *ForkPoint  `json:"fork_point,omitempty"`
+}
+
+// FileConversation is a commit touching a file together with the
+// conversation excerpt in which the file was edited.
+type FileConversation struct {
+	SHA     string                  `json:"sha"`
+	Message string                  `json:"message"`
+	Date    string                  `json:"date"`
+	Agent   string                  `json:"agent"`
+	Model   string                  `json:"model,omitempty"`
+	Tags    []string                `json:"tags,omitempty"`
+	Excerpt []agent.TranscriptEntry `json:"excerpt"`
+}
+
+// Settings holds server-side display settings for the web UI.
+type Settings struct {
+	// ExportTimezone is the IANA timezone dates are rendered in.
+	// Empty means the browser's local timezone.
+	ExportTimezone string `json:"export_timezone"`
+	// ResumeDisabled is why sessions cannot be resumed from this server.
+	// Empty means resuming is available.
+	ResumeDisabled string `json:"resume_disabled,omitempty"`
 }
 
 // writeJSONError writes a JSON error response with the given status code.
@@ -84,22 +157,55 @@ func writeJSONError(w http.ResponseWriter, status int, msg string) {
 	_ = json.NewEncoder(w).Encode(map[string]string{"error": msg})
 }
 
-// buildNoteSet returns a set of commit SHAs that have conversation notes.
+// buildNoteSet returns a set of commit SHAs that have conversation notes,
+// shared, private or in a contributor ref.
 func buildNoteSet() (map[string]bool, error) {
-	commitsWithNotes, err := git.ListCommitsWithNotes()
+	commitsWithNotes, err := storage.ListConversationCommits()
+	if err != nil {
+		return nil, err
+	}
+	noteSet, err := buildOtherNoteSet()
 	if err != nil {
 		return nil, err
 	}
-	noteSet := make(map[string]bool, len(commitsWithNotes))
 	for _, sha := range commitsWithNotes {
 		noteSet[sha] = true
 	}
 	return noteSet, nil
 }
 
-// buildAllNoteSet returns the set of all commit SHAs with notes (cross-branch).
-func buildAllNoteSet(repoDir string) (map[string]bool, error) {
-	return git.ListAllCommitsWithNotes(repoDir)
+// buildAllNoteSet returns the set of all commit SHAs with notes (cross-branch),
+// shared, private or in a contributor ref.
+func buildAllNoteSet() (map[string]bool, error) {
+	noteSet, err := storage.ListAllConversationCommits()
+	if err != nil {
+		return nil, err
+	}
+	other, err := buildOtherNoteSet()
+	if err != nil {
+		return nil, err
+	}
+	for sha := range other {
+		noteSet[sha] = true
+	}
+	return noteSet, nil
+}
+
+// buildOtherNoteSet returns the set of commit SHAs with a private
+// conversation or one in a contributor ref.
+func buildOtherNoteSet() (map[string]bool, error) {
+	noteSet, err := storage.ListPrivateConversationCommits()
+	if err != nil {
+		return nil, err
+	}
+	contributed, err := storage.ListContributedConversationCommits()
+	if err != nil {
+		return nil, err
+	}
+	for sha := range contributed {
+		noteSet[sha] = true
+	}
+	return noteSet, nil
 }
 
 // getStoredOrWriteError retrieves a stored conversation for the given SHA,
@@ -117,6 +223,108 @@ func getStoredOrWriteError(w http.ResponseWriter, commitSHA string) *storage.Sto
 	return stored
 }
 
+// getConversationOrWriteError retrieves the conversation selected by the
+// "conversation" query parameter, an index defaulting to the first, among
+// those stored for the given SHA. Like getStoredOrWriteError it writes an
+// error response and returns nil if there is none.
+func getConversationOrWriteError(w http.ResponseWriter, r *http.Request, commitSHA string) (*storage.StoredConversation, int, []*storage.StoredConversation) {
+	conversations, err := storage.GetConversationsWithPrivate(commitSHA)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "failed to read conversation")
+		return nil, 0, nil
+	}
+	if conversations == nil {
+		writeJSONError(w, http.StatusNotFound, "no conversation found")
+		return nil, 0, nil
+	}
+	index := 0
+	if c := r.URL.Query().Get("conversation"); c != "" {
+		index, err = strconv.Atoi(c)
+		if err != nil || index < 0 || index >= len(conversations) {
+			writeJSONError(w, http.StatusNotFound, "no such conversation")
+			return nil, 0, nil
+		}
+	}
+	return conversations[index], index, conversations
+}
+
+// conversationPage is the part of a commit's conversation a response
+// carries.
+type conversationPage struct {
+	Entries        []agent.TranscriptEntry
+	ParentSHA      string
+	IsIncremental  bool
+	Offset         int // index of the first entry among those shown
+	Total          int // number of entries shown, on all pages
+	DisplayedCount int // user and assistant messages shown, on all pages
+}
+
+// conversationEntries returns the entries of a commit's conversation to
+// show: with ?incremental=true, those since the session was stored on a
+// parent commit. ?offset= and ?limit= select a page of them; without a
+// limit every entry is returned, and ?limit=0 returns none, for callers
+// that only need the conversation's metadata. Attachments are referenced
+// and, unless ?full=true, large tool results truncated.
+func conversationEntries(r *http.Request, sha string, stored *storage.StoredConversation, index int, transcript *agent.Transcript) conversationPage {
+	var page conversationPage
+	entries := transcript.Entries
+	if r.URL.Query().Get("incremental") == "true" {
+		var lastEntryUUID string
+		page.ParentSHA, lastEntryUUID = storage.FindParentConversationBoundary(sha, stored.SessionID)
+		if lastEntryUUID != "" {
+			entries = transcript.GetEntriesSince(lastEntryUUID)
+			page.IsIncremental = true
+		}
+	}
+	page.Total = len(entries)
+	page.DisplayedCount = countDisplayedMessages(entries)
+
+	end := len(entries)
+	if o, err := strconv.Atoi(r.URL.Query().Get("offset")); err == nil && o > 0 {
+		page.Offset = min(o, len(entries))
+	}
+	if l, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && l >= 0 {
+		end = min(page.Offset+l, len(entries))
+	}
+	page.Entries = entries[page.Offset:end]
+
+	full := r.URL.Query().Get("full") == "true"
+	for i := range page.Entries {
+		referenceAttachments(&page.Entries[i], sha)
+		if !full {
+			truncateToolResults(&page.Entries[i], sha, index)
+		}
+	}
+	return page
+}
+
+// countDisplayedMessages counts the assistant messages and the user
+// messages other than tool results among entries.
+func countDisplayedMessages(entries []agent.TranscriptEntry) int {
+	count := 0
+	for _, entry := range entries {
+		switch entry.Type {
+		case agent.MessageTypeAssistant:
+			count++
+		case agent.MessageTypeUser:
+			if entry.Message == nil {
+				continue
+			}
+			toolResult := false
+			for _, block := range entry.Message.Content {
+				if block.Type == "tool_result" {
+					toolResult = true
+					break
+				}
+			}
+			if !toolResult {
+				count++
+			}
+		}
+	}
+	return count
+}
+
 // handleCommits returns a list of commits with conversation metadata
 func (s *Server) handleCommits(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
@@ -144,11 +352,27 @@ func (s *Server) handleCommits(w http.ResponseWriter, r *http.Request) {
 	}
 
 	branchParam := r.URL.Query().Get("branch")
+	tagParam := strings.ToLower(r.URL.Query().Get("tag"))
+	problematicFilter := r.URL.Query().Get("problematic") == "true"
+
+	// ?release= lists the commits of a release: since the tag before it
+	tags, _ := git.ListTags(s.repoDir)
+	gitTags := tagsByCommit(tags)
+	if release := r.URL.Query().Get("release"); release != "" {
+		if !hasTag(tags, release) {
+			writeJSONError(w, http.StatusNotFound, "unknown tag "+release)
+			return
+		}
+		branchParam = release
+		if previous := git.PreviousTag(release); previous != "" {
+			branchParam = previous + ".." + release
+		}
+	}
 
 	var noteSet map[string]bool
 	var err error
 	if branchParam != "" {
-		noteSet, err = buildAllNoteSet(s.repoDir)
+		noteSet, err = buildAllNoteSet()
 	} else {
 		noteSet, err = buildNoteSet()
 	}
@@ -156,42 +380,52 @@ func (s *Server) handleCommits(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Failed to list conversations", http.StatusInternalServerError)
 		return
 	}
+	ix, err := storage.OpenIndex(r.Context())
+	if err != nil {
+		http.Error(w, "Failed to read conversations", http.StatusInternalServerError)
+		return
+	}
 
 	// Get all commits
-	var commits []CommitData
+	args := []string{fmt.Sprintf("--max-count=%d", limit+offset)}
 	if branchParam != "" {
-		commits, err = getCommitListForRef(branchParam, limit+offset, s.repoDir)
-	} else {
-		commits, err = getCommitList(limit+offset, s.repoDir)
+		args = append([]string{branchParam}, args...)
+	}
+	if author := r.URL.Query().Get("author"); author != "" {
+		// Part of the author's name or email, after .mailmap
+		args = append(args, "--fixed-strings", "--author="+author)
 	}
+	commits, err := listCommits(s.repoDir, args...)
 	if err != nil {
 		http.Error(w, "Failed to get commits", http.StatusInternalServerError)
 		return
 	}
+	var signals map[string][]storage.QualitySignal
+	if problematicFilter {
+		if signals, err = storage.QualitySignals(branchParam); err != nil {
+			http.Error(w, "Failed to find reverted agent work", http.StatusInternalServerError)
+			return
+		}
+	}
 
 	var result []CommitInfo
 
 	for _, commit := range commits {
 		hasConv := noteSet[commit.SHA]
 
-		if hasConversationFilter && !hasConv {
+		if (hasConversationFilter || tagParam != "" || problematicFilter) && !hasConv {
 			continue
 		}
-
-		info := CommitInfo{
-			SHA:             commit.SHA,
-			Message:         commit.Message,
-			Author:          commit.Author,
-			Date:            commit.Date,
-			HasConversation: hasConv,
+		if problematicFilter && signals[commit.SHA] == nil {
+			continue
 		}
 
-		// Get message count and effort if has conversation
-		if hasConv {
-			if stored, err := storage.GetStoredConversation(commit.SHA); err == nil && stored != nil {
-				info.MessageCount = stored.MessageCount
-				info.Effort = stored.Effort
-			}
+		info, _ := commitInfo(ix, commit, hasConv)
+		info.GitTags = gitTags[commit.SHA]
+		info.Signals = signals[commit.SHA]
+
+		if tagParam != "" && !slices.Contains(info.Tags, tagParam) {
+			continue
 		}
 
 		result = append(result, info)
@@ -210,25 +444,86 @@ func (s *Server) handleCommits(w http.ResponseWriter, r *http.Request) {
 	_ = json.NewEncoder(w).Encode(result)
 }
 
+// commitInfo returns the API view of a commit, and the conversations
+// stored for it, as ix has them, when hasConv is set.
+func commitInfo(ix *storage.Index, commit CommitData, hasConv bool) (CommitInfo, []*storage.StoredConversation) {
+	info := CommitInfo{
+		SHA:             commit.SHA,
+		Message:         commit.Message,
+		Author:          commit.Author,
+		Date:            commit.Date,
+		HasConversation: hasConv,
+	}
+	if !hasConv {
+		return info, nil
+	}
+
+	// Get message count and effort if has conversation
+	conversations := ix.ConversationsWithPrivate(commit.SHA)
+	if conversations == nil {
+		return info, nil
+	}
+	stored := conversations[0]
+	info.MessageCount = stored.MessageCount
+	info.Effort = stored.Effort
+	info.AIAssisted = stored.AIAssisted
+	info.Authorship = stored.Authorship
+	info.Summary = stored.Summary
+	info.Tags = stored.Tags
+	if len(conversations) > 1 {
+		for _, sc := range conversations {
+			info.Agents = append(info.Agents, sc.AgentName())
+		}
+	}
+	for _, sc := range conversations {
+		info.Private = info.Private || sc.IsPrivate()
+	}
+	return info, conversations
+}
+
 // handleCommitDetail returns the full conversation for a specific commit
 func (s *Server) handleCommitDetail(w http.ResponseWriter, r *http.Request) {
+	// Extract SHA from path
+	path := strings.TrimPrefix(r.URL.Path, "/api/commits/")
+	sha := strings.TrimSuffix(path, "/")
+
+	if ref, ok := strings.CutSuffix(sha, "/annotations"); ok {
+		s.handleAnnotations(w, r, ref)
+		return
+	}
+	if ref, ok := strings.CutSuffix(sha, "/rendered"); ok {
+		s.handleRendered(w, r, ref)
+		return
+	}
+	if ref, ok := strings.CutSuffix(sha, "/replay"); ok {
+		s.handleReplay(w, r, ref)
+		return
+	}
+	if ref, ok := strings.CutSuffix(sha, "/snapshot"); ok {
+		s.handleSnapshot(w, r, ref)
+		return
+	}
+	if ref, id, ok := strings.Cut(sha, "/attachments/"); ok {
+		s.handleAttachment(w, r, ref, id)
+		return
+	}
+	if ref, rest, ok := strings.Cut(sha, "/entries/"); ok {
+		if uuid, ok := strings.CutSuffix(rest, "/content"); ok {
+			s.handleEntryContent(w, r, ref, uuid)
+			return
+		}
+	}
+
 	if r.Method != http.MethodGet {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
-	// Extract SHA from path
-	path := strings.TrimPrefix(r.URL.Path, "/api/commits/")
-	sha := strings.TrimSuffix(path, "/")
-
 	if sha == "" {
 		http.Error(w, "Commit SHA required", http.StatusBadRequest)
 		return
 	}
 
-	// Check if incremental mode is requested
-	incremental := r.URL.Query().Get("incremental") == "true"
-
 	// Resolve the reference
 	fullSHA, err := git.ResolveRef(sha)
 	if err != nil {
@@ -236,7 +531,7 @@ func (s *Server) handleCommitDetail(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	stored := getStoredOrWriteError(w, fullSHA)
+	stored, index, conversations := getConversationOrWriteError(w, r, fullSHA)
 	if stored == nil {
 		return
 	}
@@ -247,41 +542,56 @@ func (s *Server) handleCommitDetail(w http.ResponseWriter, r *http.Request) {
 		writeJSONError(w, http.StatusInternalServerError, "failed to parse transcript")
 		return
 	}
-
-	// Determine which entries to return
-	var entries []agent.TranscriptEntry
-	var parentSHA string
-	var isIncremental bool
-
-	if incremental {
-		var lastEntryUUID string
-		parentSHA, lastEntryUUID = storage.FindParentConversationBoundary(fullSHA, stored.SessionID)
-		if lastEntryUUID != "" {
-			entries = transcript.GetEntriesSince(lastEntryUUID)
-			isIncremental = true
-		} else {
-			entries = transcript.Entries
-		}
-	} else {
-		entries = transcript.Entries
-	}
+	page := conversationEntries(r, fullSHA, stored, index, transcript)
 
 	response := ConversationResponse{
 		SHA:              fullSHA,
 		SessionID:        stored.SessionID,
-		Timestamp:        stored.Timestamp,
+		Timestamp:        util.NormalizeTimestamp(stored.Timestamp),
 		MessageCount:     stored.MessageCount,
 		Agent:            stored.Agent,
 		Model:            stored.Model,
+		Provenance:       stored.GetProvenance(),
 		Effort:           stored.Effort,
-		Transcript:       entries,
-		IsIncremental:    isIncremental,
-		ParentCommitSHA:  parentSHA,
-		IncrementalCount: len(entries),
+		Summary:          stored.Summary,
+		Tags:             stored.Tags,
+		Transcript:       page.Entries,
+		IsIncremental:    page.IsIncremental,
+		ParentCommitSHA:  page.ParentSHA,
+		IncrementalCount: page.Total,
+		Offset:           page.Offset,
+		TotalEntries:     page.Total,
+		DisplayedCount:   page.DisplayedCount,
+		Index:            index,
+		Private:          stored.IsPrivate(),
+		NotesRef:         stored.ContributorRef,
+		Thinking:         stored.Thinking,
+	}
+	if len(conversations) > 1 {
+		for i, sc := range conversations {
+			response.Conversations = append(response.Conversations, ConversationRef{
+				Index:        i,
+				SessionID:    sc.SessionID,
+				Agent:        sc.AgentName(),
+				Model:        sc.Model,
+				MessageCount: sc.MessageCount,
+				Private:      sc.IsPrivate(),
+				NotesRef:     sc.ContributorRef,
+			})
+		}
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	_ = json.NewEncoder(w).Encode(response)
+	if details, err := git.GetCommitDetails(fullSHA); err == nil {
+		details.Date = util.NormalizeTimestamp(details.Date)
+		response.Commit = details
+	}
+
+	if stored.Signature != nil {
+		sig := stored.VerifySignature()
+		response.Signature = &sig
+	}
+
+	writeConversation(w, r, response)
 }
 
 // handleGraph returns the commit graph data
@@ -296,6 +606,11 @@ func (s *Server) handleGraph(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Failed to list conversations", http.StatusInternalServerError)
 		return
 	}
+	ix, err := storage.OpenIndex(r.Context())
+	if err != nil {
+		http.Error(w, "Failed to read conversations", http.StatusInternalServerError)
+		return
+	}
 
 	// Get graph data
 	nodes, err := getGraphData(50, s.repoDir)
@@ -304,12 +619,54 @@ func (s *Server) handleGraph(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	annotateGraphNodes(ix, nodes, noteSet)
+	assignLanes(nodes)
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(nodes)
+}
+
+// annotateGraphNodes marks nodes that have a stored conversation and copies
+// their AI authorship from ix. Merge commits also get the conversations
+// recorded as merged from other branches.
+func annotateGraphNodes(ix *storage.Index, nodes []GraphNode, noteSet map[string]bool) {
 	for i := range nodes {
+		if len(nodes[i].Parents) > 1 {
+			nodes[i].Merged, _ = storage.GetMergedConversations(nodes[i].SHA)
+		}
 		nodes[i].HasConversation = noteSet[nodes[i].SHA]
+		if !nodes[i].HasConversation {
+			continue
+		}
+		if conversations := ix.Conversations(nodes[i].SHA); conversations != nil {
+			nodes[i].AIAssisted = conversations[0].AIAssisted
+			nodes[i].Authorship = conversations[0].Authorship
+		}
 	}
+}
 
-	w.Header().Set("Content-Type", "application/json")
-	_ = json.NewEncoder(w).Encode(nodes)
+// Strategies for resuming when the working tree has uncommitted changes.
+// Without one, resume is rejected with 409 Conflict.
+const (
+	DirtyStash      = "stash"       // stash the changes, then check out the commit
+	DirtyNoCheckout = "no-checkout" // resume on the current working tree
+	DirtyWorktree   = "worktree"    // check out the commit in a new worktree next to the repository
+)
+
+// ResumeRequest is the optional body of POST /api/resume/<sha>.
+type ResumeRequest struct {
+	CreateBranch bool   `json:"create_branch"` // check out resume/<short-sha>-<date> instead of a detached HEAD
+	OnDirty      string `json:"on_dirty"`      // one of the Dirty* strategies; only used with uncommitted changes
+	Worktree     bool   `json:"worktree"`      // always check out the commit in a new worktree, leaving the current checkout alone
+}
+
+// ResumeResponse is the response of POST /api/resume/<sha>.
+type ResumeResponse struct {
+	Status    string `json:"status"`
+	SessionID string `json:"session_id"`
+	Branch    string `json:"branch,omitempty"`   // set when a branch was created
+	Stash     string `json:"stash,omitempty"`    // SHA of the stash holding the uncommitted changes
+	Worktree  string `json:"worktree,omitempty"` // path of the worktree the session was resumed in
 }
 
 // handleResume triggers a session resume
@@ -319,6 +676,15 @@ func (s *Server) handleResume(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if s.resumeDisabled != "" {
+		writeJSONError(w, http.StatusForbidden, s.resumeDisabled)
+		return
+	}
+	if !s.mayWrite(r) {
+		writeJSONError(w, http.StatusForbidden, remoteWriteDenied)
+		return
+	}
+
 	// Extract SHA from path
 	path := strings.TrimPrefix(r.URL.Path, "/api/resume/")
 	sha := strings.TrimSuffix(path, "/")
@@ -328,6 +694,19 @@ func (s *Server) handleResume(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Options are optional; an empty body resumes on a detached HEAD
+	var req ResumeRequest
+	if err := json.NewDecoder(io.LimitReader(r.Body, maxAnnotationBytes)).Decode(&req); err != nil && err != io.EOF {
+		writeJSONError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	switch req.OnDirty {
+	case "", DirtyStash, DirtyNoCheckout, DirtyWorktree:
+	default:
+		writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("invalid on_dirty %q: must be %s, %s or %s", req.OnDirty, DirtyStash, DirtyNoCheckout, DirtyWorktree))
+		return
+	}
+
 	// Check for uncommitted changes
 	hasChanges, err := git.HasUncommittedChanges()
 	if err != nil {
@@ -335,10 +714,14 @@ func (s *Server) handleResume(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if hasChanges {
+	if hasChanges && req.OnDirty == "" && !req.Worktree {
 		writeJSONError(w, http.StatusConflict, "uncommitted changes in working directory")
 		return
 	}
+	onDirty := ""
+	if hasChanges {
+		onDirty = req.OnDirty
+	}
 
 	// Resolve the reference
 	fullSHA, err := git.ResolveRef(sha)
@@ -347,7 +730,7 @@ func (s *Server) handleResume(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	stored := getStoredOrWriteError(w, fullSHA)
+	stored, _, _ := getConversationOrWriteError(w, r, fullSHA)
 	if stored == nil {
 		return
 	}
@@ -359,6 +742,23 @@ func (s *Server) handleResume(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Check out the commit in a new worktree first, so the session is
+	// restored for the worktree's path
+	resp := ResumeResponse{Status: "success", SessionID: stored.SessionID}
+	projectPath := s.repoDir
+	useWorktree := req.Worktree || onDirty == DirtyWorktree
+	if useWorktree {
+		if req.CreateBranch {
+			resp.Branch = git.ResumeBranchName(fullSHA, time.Now())
+		}
+		resp.Worktree = git.ResumeWorktreePath(s.repoDir, fullSHA)
+		if err := git.AddWorktree(resp.Worktree, resp.Branch, fullSHA); err != nil {
+			writeJSONError(w, http.StatusInternalServerError, fmt.Sprintf("failed to create worktree: %v", err))
+			return
+		}
+		projectPath = resp.Worktree
+	}
+
 	// Resolve agent for session restoration
 	agentName := stored.Agent
 	if agentName == "" {
@@ -370,7 +770,7 @@ func (s *Server) handleResume(w http.ResponseWriter, r *http.Request) {
 	var restoreErr error
 	if agErr == nil {
 		restoreErr = ag.RestoreSession(
-			s.repoDir,
+			projectPath,
 			stored.SessionID,
 			stored.GitBranch,
 			transcriptData,
@@ -381,7 +781,7 @@ func (s *Server) handleResume(w http.ResponseWriter, r *http.Request) {
 		// Fallback to Claude agent directly
 		var claudeAgent agentclaude.Agent
 		restoreErr = claudeAgent.RestoreSession(
-			s.repoDir,
+			projectPath,
 			stored.SessionID,
 			stored.GitBranch,
 			transcriptData,
@@ -395,25 +795,39 @@ func (s *Server) handleResume(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Checkout commit
-	if err := git.Checkout(fullSHA); err != nil {
-		writeJSONError(w, http.StatusInternalServerError, fmt.Sprintf("failed to checkout: %v", err))
-		return
+	// Stash uncommitted changes out of the checkout's way
+	if onDirty == DirtyStash && !useWorktree {
+		resp.Stash, err = git.StashChanges("shiftlog resume " + fullSHA[:7])
+		if err != nil {
+			writeJSONError(w, http.StatusInternalServerError, fmt.Sprintf("failed to stash changes: %v", err))
+			return
+		}
+	}
+
+	// Checkout commit, on a new branch if requested
+	if !useWorktree && onDirty != DirtyNoCheckout {
+		if req.CreateBranch {
+			resp.Branch = git.ResumeBranchName(fullSHA, time.Now())
+			err = git.CheckoutNewBranch(resp.Branch, fullSHA)
+		} else {
+			err = git.Checkout(fullSHA)
+		}
+		if err != nil {
+			writeJSONError(w, http.StatusInternalServerError, fmt.Sprintf("failed to checkout: %v", err))
+			return
+		}
 	}
 
 	// Launch Claude in background
 	claudeCmd := exec.Command("claude", "--resume", stored.SessionID)
-	claudeCmd.Dir = s.repoDir
+	claudeCmd.Dir = projectPath
 	if err := claudeCmd.Start(); err != nil {
 		writeJSONError(w, http.StatusInternalServerError, fmt.Sprintf("failed to launch claude: %v", err))
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	_ = json.NewEncoder(w).Encode(map[string]string{
-		"status":     "success",
-		"session_id": stored.SessionID,
-	})
+	_ = json.NewEncoder(w).Encode(resp)
 }
 
 // CommitData holds basic commit information
@@ -431,41 +845,13 @@ const fieldSep = "\x00"
 
 // getCommitList returns a list of commits
 func getCommitList(limit int, repoDir string) ([]CommitData, error) {
-	cmd := exec.Command("git", "log", fmt.Sprintf("--max-count=%d", limit),
-		"--format=%H%x00%s%x00%an%x00%ci")
-	cmd.Dir = repoDir
-	output, err := cmd.Output()
-	if err != nil {
-		return nil, err
-	}
-
-	var commits []CommitData
-	lines := strings.Split(strings.TrimSpace(string(output)), "\n")
-	for _, line := range lines {
-		if line == "" {
-			continue
-		}
-		parts := strings.SplitN(line, fieldSep, 4)
-		if len(parts) < 4 {
-			continue
-		}
-		commits = append(commits, CommitData{
-			SHA:     parts[0],
-			Message: parts[1],
-			Author:  parts[2],
-			Date:    parts[3],
-		})
-	}
-
-	return commits, nil
+	return listCommits(repoDir, fmt.Sprintf("--max-count=%d", limit))
 }
 
 // getGraphData returns commit graph data
 func getGraphData(limit int, repoDir string) ([]GraphNode, error) {
-	cmd := exec.Command("git", "log", fmt.Sprintf("--max-count=%d", limit),
-		"--format=%H%x00%P%x00%s%x00%ci")
-	cmd.Dir = repoDir
-	output, err := cmd.Output()
+	output, err := git.CommandIn(repoDir, "log", fmt.Sprintf("--max-count=%d", limit),
+		"--topo-order", "--format=%H%x00%P%x00%s%x00%ci").Output()
 	if err != nil {
 		return nil, err
 	}
@@ -475,10 +861,13 @@ func getGraphData(limit int, repoDir string) ([]GraphNode, error) {
 
 // getCommitListForRef returns commits reachable from a specific ref.
 func getCommitListForRef(ref string, limit int, repoDir string) ([]CommitData, error) {
-	cmd := exec.Command("git", "log", ref, fmt.Sprintf("--max-count=%d", limit),
-		"--format=%H%x00%s%x00%an%x00%ci")
-	cmd.Dir = repoDir
-	output, err := cmd.Output()
+	return listCommits(repoDir, ref, fmt.Sprintf("--max-count=%d", limit))
+}
+
+// listCommits runs git log with args and parses the commits it lists.
+// Author names are mapped through the repository's .mailmap.
+func listCommits(repoDir string, args ...string) ([]CommitData, error) {
+	output, err := git.CommandIn(repoDir, append([]string{"log", "--format=%H%x00%s%x00%aN%x00%ci"}, args...)...).Output()
 	if err != nil {
 		return nil, err
 	}
@@ -497,7 +886,7 @@ func getCommitListForRef(ref string, limit int, repoDir string) ([]CommitData, e
 			SHA:     parts[0],
 			Message: parts[1],
 			Author:  parts[2],
-			Date:    parts[3],
+			Date:    util.NormalizeTimestamp(parts[3]),
 		})
 	}
 
@@ -506,10 +895,8 @@ func getCommitListForRef(ref string, limit int, repoDir string) ([]CommitData, e
 
 // getGraphDataForRef returns commit graph data for a specific ref.
 func getGraphDataForRef(ref string, limit int, repoDir string) ([]GraphNode, error) {
-	cmd := exec.Command("git", "log", ref, fmt.Sprintf("--max-count=%d", limit),
-		"--format=%H%x00%P%x00%s%x00%ci")
-	cmd.Dir = repoDir
-	output, err := cmd.Output()
+	output, err := git.CommandIn(repoDir, "log", ref, fmt.Sprintf("--max-count=%d", limit),
+		"--topo-order", "--format=%H%x00%P%x00%s%x00%ci").Output()
 	if err != nil {
 		return nil, err
 	}
@@ -541,7 +928,7 @@ func parseGraphNodes(output []byte) []GraphNode {
 			Message: parts[2],
 		}
 		if len(parts) >= 4 {
-			node.Date = parts[3]
+			node.Date = util.NormalizeTimestamp(parts[3])
 		}
 
 		nodes = append(nodes, node)
@@ -563,14 +950,16 @@ func (s *Server) handleBranches(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	noteSet, err := buildAllNoteSet(s.repoDir)
+	noteSet, err := buildAllNoteSet()
 	if err != nil {
 		writeJSONError(w, http.StatusInternalServerError, "failed to list notes")
 		return
 	}
 
-	var result []BranchSummary
-	for _, b := range branches {
+	// Each branch runs its own git log: several at once
+	result := make([]BranchSummary, len(branches))
+	err = storage.ForEachParallel(r.Context(), len(branches), func(i int) error {
+		b := branches[i]
 		convCount := 0
 		commits, err := getCommitListForRef(b.Name, 100, s.repoDir)
 		if err == nil {
@@ -580,13 +969,18 @@ func (s *Server) handleBranches(w http.ResponseWriter, r *http.Request) {
 				}
 			}
 		}
-		result = append(result, BranchSummary{
+		result[i] = BranchSummary{
 			Name:              b.Name,
 			HeadSHA:           b.HeadSHA,
 			IsCurrent:         b.IsCurrent,
-			CommitDate:        b.CommitDate,
+			CommitDate:        util.NormalizeTimestamp(b.CommitDate),
 			ConversationCount: convCount,
-		})
+		}
+		return nil
+	})
+	if err != nil {
+		// The client has gone
+		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -613,11 +1007,16 @@ func (s *Server) handleBranchGraph(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	noteSet, err := buildAllNoteSet(s.repoDir)
+	noteSet, err := buildAllNoteSet()
 	if err != nil {
 		writeJSONError(w, http.StatusInternalServerError, "failed to list notes")
 		return
 	}
+	ix, err := storage.OpenIndex(r.Context())
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "failed to read conversations")
+		return
+	}
 
 	var entries []BranchGraphEntry
 	for _, b := range branches {
@@ -625,9 +1024,8 @@ func (s *Server) handleBranchGraph(w http.ResponseWriter, r *http.Request) {
 		if err != nil {
 			continue
 		}
-		for i := range nodes {
-			nodes[i].HasConversation = noteSet[nodes[i].SHA]
-		}
+		annotateGraphNodes(ix, nodes, noteSet)
+		assignLanes(nodes)
 		entries = append(entries, BranchGraphEntry{
 			Name:      b.Name,
 			IsCurrent: b.IsCurrent,
@@ -662,8 +1060,7 @@ func (s *Server) handleBranchGraph(w http.ResponseWriter, r *http.Request) {
 					continue
 				}
 				// If mb2 is a descendant of mb, it's a closer fork point
-				chk := exec.Command("git", "merge-base", "--is-ancestor", mb, mb2)
-				chk.Dir = s.repoDir
+				chk := git.CommandIn(s.repoDir, "merge-base", "--is-ancestor", mb, mb2)
 				if chk.Run() == nil && mb2 != mb {
 					mb = mb2
 					parent = entries[j].Name
@@ -676,6 +1073,245 @@ func (s *Server) handleBranchGraph(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	// Lay out the commits of all branches in one timeline
+	var all []GraphNode
+	seen := make(map[string]bool)
+	for _, e := range entries {
+		for _, n := range e.Nodes {
+			if !seen[n.SHA] {
+				seen[n.SHA] = true
+				all = append(all, n)
+			}
+		}
+	}
+	rows := make([]string, 0, len(all))
+	for _, n := range sortGraphNodes(all) {
+		rows = append(rows, n.SHA)
+	}
+
 	w.Header().Set("Content-Type", "application/json")
-	_ = json.NewEncoder(w).Encode(BranchGraphData{Branches: entries})
+	_ = json.NewEncoder(w).Encode(BranchGraphData{Branches: entries, Rows: rows})
+}
+
+// handleFileConversations returns the conversation history of a single file:
+// every commit touching it that has a conversation, with the excerpt around
+// each edit. Path format: /api/files/<path>/conversations
+func (s *Server) handleFileConversations(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	path := strings.TrimPrefix(r.URL.Path, "/api/files/")
+	file, ok := strings.CutSuffix(strings.TrimSuffix(path, "/"), "/conversations")
+	file = strings.Trim(file, "/")
+	if !ok || file == "" {
+		writeJSONError(w, http.StatusBadRequest, "file path required")
+		return
+	}
+	if strings.HasPrefix(file, "../") || strings.Contains(file, "/../") || file == ".." {
+		writeJSONError(w, http.StatusBadRequest, "invalid file path")
+		return
+	}
+
+	contextEntries := storage.DefaultExcerptContext
+	if c := r.URL.Query().Get("context"); c != "" {
+		if val, err := strconv.Atoi(c); err == nil && val >= 0 {
+			contextEntries = val
+		}
+	}
+	limit := 50
+	if l := r.URL.Query().Get("limit"); l != "" {
+		if val, err := strconv.Atoi(l); err == nil && val > 0 {
+			limit = val
+		}
+	}
+
+	history, err := storage.FileHistory(file, r.URL.Query().Get("tag"), contextEntries, limit)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "failed to get file history")
+		return
+	}
+
+	result := make([]FileConversation, 0, len(history))
+	for _, h := range history {
+		excerpt := h.Excerpt
+		if excerpt == nil {
+			excerpt = []agent.TranscriptEntry{}
+		}
+		for i := range excerpt {
+			referenceAttachments(&excerpt[i], h.CommitSHA)
+		}
+		result = append(result, FileConversation{
+			SHA:     h.CommitSHA,
+			Message: h.CommitMsg,
+			Date:    util.NormalizeTimestamp(h.CommitDate),
+			Agent:   h.Agent,
+			Model:   h.Model,
+			Tags:    h.Tags,
+			Excerpt: excerpt,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(result)
+}
+
+// handleSettings returns display settings from the shiftlog config.
+// All API dates are RFC3339 UTC; the UI renders them in the browser's locale,
+// using the configured export timezone when one is set.
+func (s *Server) handleSettings(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	settings := Settings{ResumeDisabled: s.resumeDisabled}
+	if settings.ResumeDisabled == "" && !s.mayWrite(r) {
+		settings.ResumeDisabled = remoteWriteDenied
+	}
+	if cfg, err := config.Read(); err == nil {
+		// Ignore invalid timezones so the UI falls back to the browser's
+		if _, err := cfg.ExportLocation(); err == nil {
+			settings.ExportTimezone = cfg.ExportTimezone
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(settings)
+}
+
+// handleStats returns the conversation and AI authorship summary for the current branch.
+func (s *Server) handleStats(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	stats, err := storage.ComputeStats(r.Context())
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "failed to compute stats")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(stats)
+}
+
+// handleAuthorshipReport returns the per-commit AI authorship report.
+func (s *Server) handleAuthorshipReport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	records, err := storage.AuthorshipReport(r.Context())
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "failed to build authorship report")
+		return
+	}
+	if records == nil {
+		records = []storage.AuthorshipRecord{}
+	}
+	for i := range records {
+		records[i].CommitDate = util.NormalizeTimestamp(records[i].CommitDate)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(records)
+}
+
+// handleExpensiveTurns returns the assistant turns with the highest token
+// usage, at most ?limit= of them (default 10).
+func (s *Server) handleExpensiveTurns(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	limit := 10
+	if l := r.URL.Query().Get("limit"); l != "" {
+		if val, err := strconv.Atoi(l); err == nil && val > 0 {
+			limit = val
+		}
+	}
+
+	turns, err := storage.ExpensiveTurns(r.Context(), limit)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "failed to compute turn costs")
+		return
+	}
+	for i := range turns {
+		turns[i].Timestamp = util.NormalizeTimestamp(turns[i].Timestamp)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(turns)
+}
+
+// handleToolUsage returns the tool use of each agent: calls and failures per
+// tool, Bash command length and the files edited most.
+func (s *Server) handleToolUsage(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	usage, err := storage.ToolUsageReport(r.Context())
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "failed to compute tool usage")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(usage)
+}
+
+// maxBadgeCommits caps the ?limit= of /badge.svg.
+const maxBadgeCommits = 10000
+
+// handleBadge renders a badge of the share of the last ?limit= commits
+// (default 100) of ?ref= (default HEAD) that have a conversation, for
+// embedding in READMEs.
+func (s *Server) handleBadge(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+	limit := 100
+	if l := query.Get("limit"); l != "" {
+		val, err := strconv.Atoi(l)
+		if err != nil || val <= 0 || val > maxBadgeCommits {
+			http.Error(w, fmt.Sprintf("limit must be between 1 and %d", maxBadgeCommits), http.StatusBadRequest)
+			return
+		}
+		limit = val
+	}
+	ref := "HEAD"
+	if v := query.Get("ref"); v != "" {
+		if strings.HasPrefix(v, "-") {
+			http.Error(w, "invalid ref", http.StatusBadRequest)
+			return
+		}
+		ref = v
+	}
+	sha, err := git.ResolveRef(ref + "^{commit}")
+	if err != nil {
+		http.Error(w, "unknown ref", http.StatusNotFound)
+		return
+	}
+
+	cov, err := storage.CommitCoverage(sha, limit)
+	if err != nil {
+		http.Error(w, "failed to compute coverage", http.StatusInternalServerError)
+		return
+	}
+	cov.Ref = ref
+
+	w.Header().Set("Content-Type", "image/svg+xml")
+	// Keep image proxies such as GitHub's camo from serving a stale badge
+	w.Header().Set("Cache-Control", "no-cache, max-age=0")
+	_, _ = w.Write(badge.Coverage(query.Get("label"), cov))
 }
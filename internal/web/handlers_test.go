@@ -10,7 +10,9 @@ import (
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/re-cinq/shift-log/internal/config"
 	"github.com/re-cinq/shift-log/internal/git"
 	"github.com/re-cinq/shift-log/internal/storage"
 )
@@ -438,6 +440,10 @@ func TestHandleCommitDetail(t *testing.T) {
 		if resp.MessageCount != 2 {
 			t.Errorf("MessageCount: want 2, got %d", resp.MessageCount)
 		}
+		// Notes stored before provenance get one derived from the agent
+		if resp.Provenance == nil || resp.Provenance.Agent != "claude" {
+			t.Errorf("Provenance: want agent claude, got %+v", resp.Provenance)
+		}
 	})
 
 	t.Run("short SHA resolves", func(t *testing.T) {
@@ -738,6 +744,184 @@ func TestHandleResume(t *testing.T) {
 			t.Errorf("status: want 409, got %d: %s", w.Code, w.Body.String())
 		}
 	})
+
+	t.Run("invalid body returns 400", func(t *testing.T) {
+		req := httptest.NewRequest("POST", "/api/resume/"+sha2, strings.NewReader("{not json"))
+		w := httptest.NewRecorder()
+		srv.mux.ServeHTTP(w, req)
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("status: want 400, got %d: %s", w.Code, w.Body.String())
+		}
+	})
+
+	t.Run("create_branch checks out a resume branch", func(t *testing.T) {
+		stubClaude(t)
+
+		req := httptest.NewRequest("POST", "/api/resume/"+sha2, strings.NewReader(`{"create_branch": true}`))
+		w := httptest.NewRecorder()
+		srv.mux.ServeHTTP(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("status: want 200, got %d: %s", w.Code, w.Body.String())
+		}
+		var resp ResumeResponse
+		decodeJSON(t, w, &resp)
+		want := "resume/" + sha2[:7] + "-" + time.Now().Format("20060102")
+		if resp.Branch != want {
+			t.Errorf("branch: want %q, got %q", want, resp.Branch)
+		}
+		if resp.SessionID != "session-1" {
+			t.Errorf("session_id: want session-1, got %q", resp.SessionID)
+		}
+		if branch := repo.git("rev-parse", "--abbrev-ref", "HEAD"); branch != want {
+			t.Errorf("HEAD: want branch %q, got %q", want, branch)
+		}
+
+		// Resuming again the same day picks a fresh name
+		repo.git("checkout", "--detach", sha1)
+		w = httptest.NewRecorder()
+		srv.mux.ServeHTTP(w, httptest.NewRequest("POST", "/api/resume/"+sha2, strings.NewReader(`{"create_branch": true}`)))
+		decodeJSON(t, w, &resp)
+		if resp.Branch != want+"-2" {
+			t.Errorf("second branch: want %q, got %q", want+"-2", resp.Branch)
+		}
+	})
+
+	t.Run("invalid on_dirty returns 400", func(t *testing.T) {
+		req := httptest.NewRequest("POST", "/api/resume/"+sha2, strings.NewReader(`{"on_dirty": "discard"}`))
+		w := httptest.NewRecorder()
+		srv.mux.ServeHTTP(w, req)
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("status: want 400, got %d: %s", w.Code, w.Body.String())
+		}
+	})
+}
+
+func TestHandleResumeDirtyStrategies(t *testing.T) {
+	stubClaude(t)
+
+	setup := func(t *testing.T) (*testRepo, *Server, string, string) {
+		repo := newTestRepo(t)
+		chdir(t, repo.path)
+		repo.writeFile("a.txt", "a")
+		sha1 := repo.commit("First commit")
+		repo.addConversation(sha1, "session-1", sampleTranscript(), 2)
+		repo.writeFile("b.txt", "b")
+		repo.commit("Second commit")
+		repo.writeFile("a.txt", "local edit")
+		return repo, NewServer(0, repo.path), sha1, repo.git("rev-parse", "HEAD")
+	}
+	resume := func(t *testing.T, srv *Server, sha, body string) ResumeResponse {
+		t.Helper()
+		w := httptest.NewRecorder()
+		srv.mux.ServeHTTP(w, httptest.NewRequest("POST", "/api/resume/"+sha, strings.NewReader(body)))
+		if w.Code != http.StatusOK {
+			t.Fatalf("status: want 200, got %d: %s", w.Code, w.Body.String())
+		}
+		var resp ResumeResponse
+		decodeJSON(t, w, &resp)
+		return resp
+	}
+
+	t.Run("stash", func(t *testing.T) {
+		repo, srv, sha1, _ := setup(t)
+
+		resp := resume(t, srv, sha1, `{"on_dirty": "stash"}`)
+
+		if resp.Stash == "" || resp.Stash != repo.git("rev-parse", "stash@{0}") {
+			t.Errorf("stash: want the stash commit, got %q", resp.Stash)
+		}
+		if head := repo.git("rev-parse", "HEAD"); head != sha1 {
+			t.Errorf("HEAD: want %s, got %s", sha1, head)
+		}
+		if status := repo.git("status", "--porcelain"); status != "" {
+			t.Errorf("working tree should be clean, got %q", status)
+		}
+	})
+
+	t.Run("no-checkout", func(t *testing.T) {
+		repo, srv, sha1, head := setup(t)
+
+		resp := resume(t, srv, sha1, `{"on_dirty": "no-checkout", "create_branch": true}`)
+
+		if resp.Branch != "" || resp.Stash != "" || resp.Worktree != "" {
+			t.Errorf("unexpected response: %+v", resp)
+		}
+		if got := repo.git("rev-parse", "HEAD"); got != head {
+			t.Errorf("HEAD: want %s, got %s", head, got)
+		}
+		if status := repo.git("status", "--porcelain"); !strings.Contains(status, "a.txt") {
+			t.Errorf("local edit should be kept, got status %q", status)
+		}
+	})
+
+	t.Run("worktree", func(t *testing.T) {
+		repo, srv, sha1, head := setup(t)
+
+		resp := resume(t, srv, sha1, `{"on_dirty": "worktree", "create_branch": true}`)
+		t.Cleanup(func() { _ = os.RemoveAll(resp.Worktree) })
+
+		if resp.Worktree == "" || !strings.Contains(resp.Worktree, "-resume-"+sha1[:7]) {
+			t.Fatalf("worktree: got %q", resp.Worktree)
+		}
+		if resp.Branch == "" {
+			t.Error("branch: want the worktree's branch")
+		}
+		if got := repo.git("-C", resp.Worktree, "rev-parse", "HEAD"); got != sha1 {
+			t.Errorf("worktree HEAD: want %s, got %s", sha1, got)
+		}
+		if got := repo.git("rev-parse", "HEAD"); got != head {
+			t.Errorf("main HEAD: want %s, got %s", head, got)
+		}
+	})
+
+	t.Run("worktree option", func(t *testing.T) {
+		repo, srv, sha1, head := setup(t)
+
+		resp := resume(t, srv, sha1, `{"worktree": true}`)
+		t.Cleanup(func() { _ = os.RemoveAll(resp.Worktree) })
+
+		if resp.Worktree == "" || resp.Branch != "" {
+			t.Fatalf("want a detached worktree, got %+v", resp)
+		}
+		if got := repo.git("-C", resp.Worktree, "rev-parse", "HEAD"); got != sha1 {
+			t.Errorf("worktree HEAD: want %s, got %s", sha1, got)
+		}
+		if got := repo.git("rev-parse", "HEAD"); got != head {
+			t.Errorf("main HEAD: want %s, got %s", head, got)
+		}
+		if status := repo.git("status", "--porcelain"); !strings.Contains(status, "a.txt") {
+			t.Errorf("local edit should be kept, got status %q", status)
+		}
+	})
+
+	t.Run("clean tree ignores the strategy", func(t *testing.T) {
+		repo, srv, sha1, _ := setup(t)
+		repo.git("checkout", "--", "a.txt")
+
+		resp := resume(t, srv, sha1, `{"on_dirty": "stash"}`)
+
+		if resp.Stash != "" {
+			t.Errorf("stash: want none, got %q", resp.Stash)
+		}
+		if head := repo.git("rev-parse", "HEAD"); head != sha1 {
+			t.Errorf("HEAD: want %s, got %s", sha1, head)
+		}
+	})
+}
+
+// stubClaude puts a no-op claude binary on PATH and points HOME at a
+// temporary directory, so resume can launch the agent in tests.
+func stubClaude(t *testing.T) {
+	t.Helper()
+	binDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(binDir, "claude"), []byte("#!/bin/sh\nexit 0\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	t.Setenv("HOME", t.TempDir())
 }
 
 // --- Static file / embedded HTML tests ---
@@ -778,16 +962,8 @@ func TestStaticFileServing(t *testing.T) {
 		body := w.Body.String()
 		functions := []string{
 			"function escapeHtml(",
-			"function formatContent(",
 			"function renderConversation(",
-			"function renderUserMessage(",
-			"function renderAssistantMessage(",
-			"function renderSystemMessage(",
-			"function renderThinking(",
-			"function renderToolUse(",
-			"function renderToolResult(",
-			"function formatToolInput(",
-			"function countDisplayedMessages(",
+			"function attachTranscriptHandlers(",
 			"function formatDate(",
 			"function showStatus(",
 			"function setViewMode(",
@@ -1013,6 +1189,33 @@ func TestHandleBranchGraph(t *testing.T) {
 		}
 	})
 
+	t.Run("lists every node in the rows", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/api/graph/branches?per_branch=10", nil)
+		w := httptest.NewRecorder()
+		srv.mux.ServeHTTP(w, req)
+
+		var data BranchGraphData
+		decodeJSON(t, w, &data)
+
+		row := make(map[string]int)
+		for i, sha := range data.Rows {
+			row[sha] = i
+		}
+		for _, b := range data.Branches {
+			for _, n := range b.Nodes {
+				r, ok := row[n.SHA]
+				if !ok {
+					t.Errorf("node %s of %s has no row", n.SHA[:7], b.Name)
+				}
+				for _, p := range n.Parents {
+					if pr, ok := row[p]; ok && pr <= r {
+						t.Errorf("parent %s is not below %s", p[:7], n.SHA[:7])
+					}
+				}
+			}
+		}
+	})
+
 	t.Run("marks has_conversation correctly", func(t *testing.T) {
 		req := httptest.NewRequest("GET", "/api/graph/branches", nil)
 		w := httptest.NewRecorder()
@@ -1362,7 +1565,10 @@ func TestHTMLContainsEffortElements(t *testing.T) {
 		`id="meta-turns"`,
 		`id="meta-input-tokens"`,
 		`id="meta-output-tokens"`,
+		`id="meta-duration"`,
 		"function formatTokenCount(",
+		"function formatSeconds(",
+		"function formatMergedConversation(",
 	}
 	for _, elem := range elements {
 		if !strings.Contains(body, elem) {
@@ -1370,3 +1576,1089 @@ func TestHTMLContainsEffortElements(t *testing.T) {
 		}
 	}
 }
+
+func editTranscript(filePath string) []byte {
+	entries := []map[string]interface{}{
+		{
+			"uuid": "user-1", "type": "user",
+			"message": map[string]interface{}{
+				"role": "user",
+				"content": []map[string]interface{}{
+					{"type": "text", "text": "Please update the file"},
+				},
+			},
+		},
+		{
+			"uuid": "assistant-1", "parentUuid": "user-1", "type": "assistant",
+			"message": map[string]interface{}{
+				"role": "assistant",
+				"content": []map[string]interface{}{
+					{
+						"type": "tool_use", "id": "tool-1", "name": "Edit",
+						"input": map[string]interface{}{"file_path": filePath, "old_string": "a", "new_string": "b"},
+					},
+				},
+			},
+		},
+	}
+	return marshalTranscript(entries)
+}
+
+func TestHandleFileConversations(t *testing.T) {
+	repo := newTestRepo(t)
+	chdir(t, repo.path)
+
+	repo.writeFile("src/a.go", "a")
+	sha1 := repo.commit("Edit a.go")
+	repo.addConversation(sha1, "session-1", editTranscript(filepath.Join(repo.path, "src", "a.go")), 2)
+
+	repo.writeFile("b.txt", "b")
+	sha2 := repo.commit("Add b.txt")
+	repo.addConversation(sha2, "session-2", sampleTranscript(), 2)
+
+	repo.writeFile("src/a.go", "b")
+	repo.commit("Edit a.go by hand")
+
+	srv := NewServer(0, repo.path)
+
+	t.Run("returns commits touching the file with excerpts", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/api/files/src/a.go/conversations", nil)
+		w := httptest.NewRecorder()
+		srv.mux.ServeHTTP(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("status: want 200, got %d: %s", w.Code, w.Body.String())
+		}
+
+		var resp []FileConversation
+		decodeJSON(t, w, &resp)
+		if len(resp) != 1 {
+			t.Fatalf("want 1 conversation, got %d", len(resp))
+		}
+		if resp[0].SHA != sha1 {
+			t.Errorf("SHA = %s, want %s", resp[0].SHA, sha1)
+		}
+		if resp[0].Agent != "claude" {
+			t.Errorf("Agent = %q, want claude", resp[0].Agent)
+		}
+		if len(resp[0].Excerpt) != 2 {
+			t.Errorf("want 2 excerpt entries, got %d", len(resp[0].Excerpt))
+		}
+	})
+
+	t.Run("file without conversations returns empty list", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/api/files/missing.go/conversations", nil)
+		w := httptest.NewRecorder()
+		srv.mux.ServeHTTP(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("status: want 200, got %d: %s", w.Code, w.Body.String())
+		}
+		var resp []FileConversation
+		decodeJSON(t, w, &resp)
+		if len(resp) != 0 {
+			t.Errorf("want 0 conversations, got %d", len(resp))
+		}
+	})
+
+	t.Run("missing conversations suffix is rejected", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/api/files/src/a.go", nil)
+		w := httptest.NewRecorder()
+		srv.mux.ServeHTTP(w, req)
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("status: want 400, got %d", w.Code)
+		}
+	})
+
+	t.Run("rejects POST", func(t *testing.T) {
+		req := httptest.NewRequest("POST", "/api/files/src/a.go/conversations", nil)
+		w := httptest.NewRecorder()
+		srv.mux.ServeHTTP(w, req)
+
+		if w.Code != http.StatusMethodNotAllowed {
+			t.Errorf("status: want 405, got %d", w.Code)
+		}
+	})
+}
+
+func TestAPIDatesAreRFC3339UTC(t *testing.T) {
+	repo := newTestRepo(t)
+	chdir(t, repo.path)
+
+	repo.writeFile("a.txt", "a")
+	cmd := exec.Command("git", "commit", "--no-gpg-sign", "-m", "Dated commit")
+	cmd.Dir = repo.path
+	cmd.Env = append(os.Environ(), "GIT_COMMITTER_DATE=2024-01-15T10:30:00+0100", "GIT_AUTHOR_DATE=2024-01-15T10:30:00+0100")
+	repo.git("add", "-A")
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("commit failed: %v\n%s", err, out)
+	}
+	sha := repo.git("rev-parse", "HEAD")
+	repo.addConversation(sha, "session-1", sampleTranscript(), 2)
+
+	srv := NewServer(0, repo.path)
+	const want = "2024-01-15T09:30:00Z"
+
+	t.Run("commits", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/api/commits", nil)
+		w := httptest.NewRecorder()
+		srv.mux.ServeHTTP(w, req)
+
+		var commits []CommitInfo
+		decodeJSON(t, w, &commits)
+		if len(commits) != 1 || commits[0].Date != want {
+			t.Errorf("commit date = %v, want %s", commits, want)
+		}
+	})
+
+	t.Run("graph", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/api/graph", nil)
+		w := httptest.NewRecorder()
+		srv.mux.ServeHTTP(w, req)
+
+		var nodes []GraphNode
+		decodeJSON(t, w, &nodes)
+		if len(nodes) != 1 || nodes[0].Date != want {
+			t.Errorf("graph date = %v, want %s", nodes, want)
+		}
+	})
+
+	t.Run("branches", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/api/branches", nil)
+		w := httptest.NewRecorder()
+		srv.mux.ServeHTTP(w, req)
+
+		var branches []BranchSummary
+		decodeJSON(t, w, &branches)
+		if len(branches) != 1 || branches[0].CommitDate != want {
+			t.Errorf("branch date = %v, want %s", branches, want)
+		}
+	})
+}
+
+func TestHandleSettings(t *testing.T) {
+	repo := newTestRepo(t)
+	chdir(t, repo.path)
+	srv := NewServer(0, repo.path)
+
+	get := func() Settings {
+		t.Helper()
+		req := httptest.NewRequest("GET", "/api/settings", nil)
+		w := httptest.NewRecorder()
+		srv.mux.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("status: want 200, got %d", w.Code)
+		}
+		var settings Settings
+		decodeJSON(t, w, &settings)
+		return settings
+	}
+
+	if got := get(); got.ExportTimezone != "" {
+		t.Errorf("default ExportTimezone = %q, want empty", got.ExportTimezone)
+	}
+
+	repo.writeFile(".shiftlog/config", `{"export_timezone": "Asia/Tokyo"}`)
+	if got := get(); got.ExportTimezone != "Asia/Tokyo" {
+		t.Errorf("ExportTimezone = %q, want Asia/Tokyo", got.ExportTimezone)
+	}
+
+	repo.writeFile(".shiftlog/config", `{"export_timezone": "Not/AZone"}`)
+	if got := get(); got.ExportTimezone != "" {
+		t.Errorf("invalid ExportTimezone should be dropped, got %q", got.ExportTimezone)
+	}
+}
+
+func TestResumeDisabled(t *testing.T) {
+	repo := newTestRepo(t)
+	chdir(t, repo.path)
+
+	repo.writeFile("a.txt", "a")
+	sha := repo.commit("First commit")
+	repo.addConversation(sha, "session-1", sampleTranscript(), 2)
+
+	srv := NewServer(0, repo.path)
+	srv.DisableResume("no working tree")
+
+	req := httptest.NewRequest("POST", "/api/resume/"+sha, nil)
+	w := httptest.NewRecorder()
+	srv.mux.ServeHTTP(w, req)
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("status: want 403, got %d", w.Code)
+	}
+	if head := repo.git("rev-parse", "--abbrev-ref", "HEAD"); head != "master" {
+		t.Errorf("HEAD moved to %q", head)
+	}
+
+	req = httptest.NewRequest("GET", "/api/settings", nil)
+	w = httptest.NewRecorder()
+	srv.mux.ServeHTTP(w, req)
+	var settings Settings
+	decodeJSON(t, w, &settings)
+	if settings.ResumeDisabled != "no working tree" {
+		t.Errorf("ResumeDisabled = %q, want the reason", settings.ResumeDisabled)
+	}
+}
+
+func TestResumeBeyondLoopback(t *testing.T) {
+	stubClaude(t)
+	repo := newTestRepo(t)
+	chdir(t, repo.path)
+
+	repo.writeFile("a.txt", "a")
+	sha := repo.commit("First commit")
+	repo.addConversation(sha, "session-1", sampleTranscript(), 2)
+	srv := NewServer(0, repo.path)
+	srv.SetHost("0.0.0.0")
+
+	req := httptest.NewRequest("POST", "/api/resume/"+sha, nil)
+	w := httptest.NewRecorder()
+	srv.mux.ServeHTTP(w, req)
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("status: want 403, got %d: %s", w.Code, w.Body.String())
+	}
+	if head := repo.git("rev-parse", "--abbrev-ref", "HEAD"); head != "master" {
+		t.Errorf("HEAD moved to %q", head)
+	}
+
+	req = httptest.NewRequest("POST", "/api/commits/"+sha+"/annotations", strings.NewReader(`{"entry_uuid": "u1", "body": "note"}`))
+	w = httptest.NewRecorder()
+	srv.mux.ServeHTTP(w, req)
+	if w.Code != http.StatusForbidden {
+		t.Errorf("annotation status: want 403, got %d: %s", w.Code, w.Body.String())
+	}
+
+	req = httptest.NewRequest("GET", "/api/settings", nil)
+	w = httptest.NewRecorder()
+	srv.mux.ServeHTTP(w, req)
+	var settings Settings
+	decodeJSON(t, w, &settings)
+	if settings.ResumeDisabled == "" {
+		t.Error("settings should report resume as disabled")
+	}
+
+	// The API token authorizes it
+	srv.EnableConversationAPI("secret", nil)
+	req = httptest.NewRequest("POST", "/api/resume/"+sha, nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	w = httptest.NewRecorder()
+	srv.mux.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Errorf("status with the token: want 200, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestAuthorshipSurfaced(t *testing.T) {
+	repo := newTestRepo(t)
+	chdir(t, repo.path)
+
+	repo.writeFile("a.txt", "a")
+	sha1 := repo.commit("AI commit")
+	stored, err := storage.NewStoredConversation("session-1", repo.path, "master", 2, sampleTranscript())
+	if err != nil {
+		t.Fatal(err)
+	}
+	stored.AIAssisted = true
+	stored.Authorship = &storage.Authorship{AILines: 3, TotalLines: 4, Ratio: 0.75}
+	stored.Effort = &storage.Effort{Turns: 2, InputTokens: 100, OutputTokens: 50}
+	data, err := stored.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo.git("notes", "--ref", git.NotesRef, "add", "-f", "-m", string(data), sha1)
+
+	repo.writeFile("b.txt", "b")
+	repo.commit("Manual commit")
+
+	srv := NewServer(0, repo.path)
+	get := func(path string, v interface{}) {
+		t.Helper()
+		req := httptest.NewRequest("GET", path, nil)
+		w := httptest.NewRecorder()
+		srv.mux.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s status: want 200, got %d", path, w.Code)
+		}
+		decodeJSON(t, w, v)
+	}
+
+	t.Run("commit list", func(t *testing.T) {
+		var commits []CommitInfo
+		get("/api/commits", &commits)
+		for _, c := range commits {
+			if c.SHA == sha1 && (!c.AIAssisted || c.Authorship == nil || c.Authorship.Ratio != 0.75) {
+				t.Errorf("AI commit authorship = %v/%+v, want ai_assisted with ratio 0.75", c.AIAssisted, c.Authorship)
+			}
+			if c.SHA != sha1 && (c.AIAssisted || c.Authorship != nil) {
+				t.Errorf("manual commit should have no authorship, got %+v", c.Authorship)
+			}
+		}
+	})
+
+	t.Run("graph", func(t *testing.T) {
+		var nodes []GraphNode
+		get("/api/graph", &nodes)
+		for _, n := range nodes {
+			if n.SHA == sha1 && (!n.AIAssisted || n.Authorship == nil || n.Authorship.AILines != 3) {
+				t.Errorf("graph node authorship = %v/%+v, want 3 AI lines", n.AIAssisted, n.Authorship)
+			}
+		}
+	})
+
+	t.Run("stats", func(t *testing.T) {
+		var stats storage.Stats
+		get("/api/stats", &stats)
+		if stats.Commits != 2 || stats.Conversations != 1 || stats.AIAssisted != 1 {
+			t.Errorf("stats commits/conversations/ai_assisted = %d/%d/%d, want 2/1/1", stats.Commits, stats.Conversations, stats.AIAssisted)
+		}
+		if stats.AILines != 3 || stats.TotalLines != 4 || stats.Tokens != 150 {
+			t.Errorf("stats lines/tokens = %d/%d/%d, want 3/4/150", stats.AILines, stats.TotalLines, stats.Tokens)
+		}
+		if a := stats.Agents["claude"]; a == nil || a.Conversations != 1 {
+			t.Errorf("stats.Agents[claude] = %+v, want 1 conversation", a)
+		}
+	})
+
+	t.Run("authorship report", func(t *testing.T) {
+		var records []storage.AuthorshipRecord
+		get("/api/stats/authorship", &records)
+		if len(records) != 1 || records[0].CommitSHA != sha1 {
+			t.Fatalf("report = %+v, want one record for %s", records, sha1)
+		}
+		if _, err := time.Parse(time.RFC3339, records[0].CommitDate); err != nil || !strings.HasSuffix(records[0].CommitDate, "Z") {
+			t.Errorf("report date %q is not RFC3339 UTC", records[0].CommitDate)
+		}
+	})
+}
+
+func TestHandleCommitDetailIncludesCommitContext(t *testing.T) {
+	repo := newTestRepo(t)
+	chdir(t, repo.path)
+
+	repo.writeFile("a.txt", "a")
+	repo.git("add", "-A")
+	repo.git("commit", "--no-gpg-sign", "-m", "Add a\n\nExplain why a is needed.\nSecond line.\n\nCo-Authored-By: Pat <pat@example.com>\nShiftlog-Session: session-1")
+	sha := repo.git("rev-parse", "HEAD")
+	repo.addConversation(sha, "session-1", sampleTranscript(), 2)
+
+	srv := NewServer(0, repo.path)
+	req := httptest.NewRequest("GET", "/api/commits/"+sha, nil)
+	w := httptest.NewRecorder()
+	srv.mux.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status: want 200, got %d", w.Code)
+	}
+
+	var resp ConversationResponse
+	decodeJSON(t, w, &resp)
+	c := resp.Commit
+	if c == nil {
+		t.Fatal("Commit should not be nil")
+	}
+	if c.Subject != "Add a" {
+		t.Errorf("Subject = %q, want %q", c.Subject, "Add a")
+	}
+	if c.Body != "Explain why a is needed.\nSecond line." {
+		t.Errorf("Body = %q, want trailers stripped", c.Body)
+	}
+	want := []git.Trailer{
+		{Key: "Co-Authored-By", Value: "Pat <pat@example.com>"},
+		{Key: "Shiftlog-Session", Value: "session-1"},
+	}
+	if len(c.Trailers) != len(want) || c.Trailers[0] != want[0] || c.Trailers[1] != want[1] {
+		t.Errorf("Trailers = %+v, want %+v", c.Trailers, want)
+	}
+	if c.Signature != "unsigned" {
+		t.Errorf("Signature = %q, want unsigned", c.Signature)
+	}
+	if c.Author == "" || !strings.HasSuffix(c.Date, "Z") {
+		t.Errorf("Author/Date = %q/%q, want author and RFC3339 UTC date", c.Author, c.Date)
+	}
+}
+
+func TestHandleCommitsWithSummary(t *testing.T) {
+	repo := newTestRepo(t)
+	chdir(t, repo.path)
+
+	repo.writeFile("a.txt", "a")
+	sha := repo.commit("First commit")
+	stored, err := storage.NewStoredConversation("session-1", repo.path, "master", 2, sampleTranscript())
+	if err != nil {
+		t.Fatal(err)
+	}
+	stored.Summary = "Added a.txt."
+	data, err := stored.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo.git("notes", "--ref", git.NotesRef, "add", "-f", "-m", string(data), sha)
+
+	srv := NewServer(0, repo.path)
+
+	req := httptest.NewRequest("GET", "/api/commits", nil)
+	w := httptest.NewRecorder()
+	srv.mux.ServeHTTP(w, req)
+	var commits []CommitInfo
+	decodeJSON(t, w, &commits)
+	if len(commits) != 1 || commits[0].Summary != "Added a.txt." {
+		t.Errorf("commit list summary = %+v, want %q", commits, "Added a.txt.")
+	}
+
+	req = httptest.NewRequest("GET", "/api/commits/"+sha, nil)
+	w = httptest.NewRecorder()
+	srv.mux.ServeHTTP(w, req)
+	var resp ConversationResponse
+	decodeJSON(t, w, &resp)
+	if resp.Summary != "Added a.txt." {
+		t.Errorf("detail summary = %q, want %q", resp.Summary, "Added a.txt.")
+	}
+}
+
+func TestHandleCommitsTagFilter(t *testing.T) {
+	repo := newTestRepo(t)
+	chdir(t, repo.path)
+
+	repo.writeFile("a.txt", "a")
+	tagged := repo.commit("Tagged commit")
+	stored, err := storage.NewStoredConversation("session-1", repo.path, "master", 2, sampleTranscript())
+	if err != nil {
+		t.Fatal(err)
+	}
+	stored.AddTags("training")
+	data, err := stored.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo.git("notes", "--ref", git.NotesRef, "add", "-f", "-m", string(data), tagged)
+
+	repo.writeFile("b.txt", "b")
+	untagged := repo.commit("Untagged commit")
+	repo.addConversation(untagged, "session-2", sampleTranscript(), 2)
+
+	srv := NewServer(0, repo.path)
+
+	req := httptest.NewRequest("GET", "/api/commits?tag=Training", nil)
+	w := httptest.NewRecorder()
+	srv.mux.ServeHTTP(w, req)
+	var commits []CommitInfo
+	decodeJSON(t, w, &commits)
+	if len(commits) != 1 || commits[0].SHA != tagged {
+		t.Fatalf("tag filter returned %+v, want only %s", commits, tagged)
+	}
+	if len(commits[0].Tags) != 1 || commits[0].Tags[0] != "training" {
+		t.Errorf("Tags = %v, want [training]", commits[0].Tags)
+	}
+
+	req = httptest.NewRequest("GET", "/api/commits/"+tagged, nil)
+	w = httptest.NewRecorder()
+	srv.mux.ServeHTTP(w, req)
+	var resp ConversationResponse
+	decodeJSON(t, w, &resp)
+	if len(resp.Tags) != 1 || resp.Tags[0] != "training" {
+		t.Errorf("detail Tags = %v, want [training]", resp.Tags)
+	}
+}
+
+func TestHandleCommitsProblematicFilter(t *testing.T) {
+	repo := newTestRepo(t)
+	chdir(t, repo.path)
+
+	repo.writeFile("a.txt", "a")
+	reverted := repo.commit("Add a")
+	repo.addConversation(reverted, "session-1", sampleTranscript(), 2)
+
+	repo.writeFile("b.txt", "b")
+	kept := repo.commit("Add b")
+	repo.addConversation(kept, "session-2", sampleTranscript(), 2)
+
+	repo.git("revert", "--no-edit", reverted)
+	revert := repo.git("rev-parse", "HEAD")
+
+	srv := NewServer(0, repo.path)
+
+	req := httptest.NewRequest("GET", "/api/commits?problematic=true", nil)
+	w := httptest.NewRecorder()
+	srv.mux.ServeHTTP(w, req)
+	var commits []CommitInfo
+	decodeJSON(t, w, &commits)
+	if len(commits) != 1 || commits[0].SHA != reverted {
+		t.Fatalf("problematic filter returned %+v, want only %s", commits, reverted)
+	}
+	signals := commits[0].Signals
+	if len(signals) != 1 || signals[0].Reason != storage.SignalReverted || signals[0].Commit != revert {
+		t.Errorf("Signals = %+v, want reverted by %s", signals, revert)
+	}
+
+	req = httptest.NewRequest("GET", "/api/commits", nil)
+	w = httptest.NewRecorder()
+	srv.mux.ServeHTTP(w, req)
+	commits = nil
+	decodeJSON(t, w, &commits)
+	for _, c := range commits {
+		if c.Signals != nil {
+			t.Errorf("commit %s has signals without the filter: %+v", c.SHA, c.Signals)
+		}
+	}
+}
+
+func TestHandleCommitDetailSignature(t *testing.T) {
+	if _, err := exec.LookPath("ssh-keygen"); err != nil {
+		t.Skip("ssh-keygen not available")
+	}
+	repo := newTestRepo(t)
+	chdir(t, repo.path)
+
+	keyDir := t.TempDir()
+	key := filepath.Join(keyDir, "key")
+	if out, err := exec.Command("ssh-keygen", "-q", "-t", "ed25519", "-N", "", "-f", key).CombinedOutput(); err != nil {
+		t.Fatalf("ssh-keygen: %v\n%s", err, out)
+	}
+	pub, err := os.ReadFile(key + ".pub")
+	if err != nil {
+		t.Fatal(err)
+	}
+	allowed := filepath.Join(keyDir, "allowed_signers")
+	if err := os.WriteFile(allowed, []byte("test@example.com "+string(pub)), 0644); err != nil {
+		t.Fatal(err)
+	}
+	repo.git("config", "gpg.format", "ssh")
+	repo.git("config", "user.signingkey", key)
+	repo.git("config", "gpg.ssh.allowedSignersFile", allowed)
+
+	repo.writeFile("a.txt", "a")
+	unsigned := repo.commit("Unsigned conversation")
+	repo.addConversation(unsigned, "session-1", sampleTranscript(), 2)
+
+	repo.writeFile("b.txt", "b")
+	signed := repo.commit("Signed conversation")
+	stored, err := storage.NewStoredConversation("session-2", repo.path, "master", 2, sampleTranscript())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := stored.Sign(); err != nil {
+		t.Fatal(err)
+	}
+	data, err := stored.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo.git("notes", "--ref", git.NotesRef, "add", "-f", "-m", string(data), signed)
+
+	srv := NewServer(0, repo.path)
+	get := func(sha string) ConversationResponse {
+		req := httptest.NewRequest("GET", "/api/commits/"+sha, nil)
+		w := httptest.NewRecorder()
+		srv.mux.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("status: want 200, got %d: %s", w.Code, w.Body.String())
+		}
+		var resp ConversationResponse
+		decodeJSON(t, w, &resp)
+		return resp
+	}
+
+	if resp := get(unsigned); resp.Signature != nil {
+		t.Errorf("unsigned conversation: Signature = %+v, want nil", resp.Signature)
+	}
+	resp := get(signed)
+	if resp.Signature == nil || resp.Signature.Status != "good" || resp.Signature.Signer != "test@example.com" || resp.Signature.Format != "ssh" {
+		t.Errorf("signed conversation: Signature = %+v, want good ssh signature by test@example.com", resp.Signature)
+	}
+}
+
+func TestHandleBranchGraphMergedConversations(t *testing.T) {
+	repo := newTestRepo(t)
+	chdir(t, repo.path)
+
+	repo.writeFile("a.txt", "a")
+	repo.commit("First commit")
+
+	repo.git("checkout", "-b", "feature-x")
+	repo.writeFile("b.txt", "b")
+	featureSHA := repo.commit("Feature commit")
+	repo.addConversation(featureSHA, "feature-session", sampleTranscript(), 2)
+
+	repo.git("checkout", "master")
+	repo.writeFile("c.txt", "c")
+	repo.commit("Mainline commit")
+	repo.git("merge", "--no-ff", "--no-edit", "feature-x")
+	mergeSHA := repo.git("rev-parse", "HEAD")
+	repo.git("branch", "-D", "feature-x")
+
+	if _, err := storage.RecordMergedConversations(mergeSHA); err != nil {
+		t.Fatal(err)
+	}
+
+	srv := NewServer(0, repo.path)
+	req := httptest.NewRequest("GET", "/api/graph/branches", nil)
+	w := httptest.NewRecorder()
+	srv.mux.ServeHTTP(w, req)
+
+	var data BranchGraphData
+	decodeJSON(t, w, &data)
+
+	var merge *GraphNode
+	for _, n := range data.Branches[0].Nodes {
+		if n.SHA == mergeSHA {
+			merge = &n
+		} else if len(n.Merged) > 0 {
+			t.Errorf("node %s is not a merge but has merged conversations", n.SHA[:7])
+		}
+	}
+	if merge == nil {
+		t.Fatal("merge commit missing from graph")
+	}
+	if len(merge.Merged) != 1 || merge.Merged[0].Commit != featureSHA || merge.Merged[0].SessionID != "feature-session" {
+		t.Errorf("merged = %+v, want the feature-session conversation on %s", merge.Merged, featureSHA[:7])
+	}
+}
+
+func TestHandleCommitDetailMultipleConversations(t *testing.T) {
+	repo := newTestRepo(t)
+	chdir(t, repo.path)
+
+	repo.writeFile("a.txt", "a")
+	sha := repo.commit("Shared commit")
+
+	first, err := storage.NewStoredConversation("session-1", repo.path, "master", 2, sampleTranscript())
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := storage.NewStoredConversation("session-2", repo.path, "master", 2, sampleTranscript())
+	if err != nil {
+		t.Fatal(err)
+	}
+	second.Agent = "codex"
+	data, err := storage.MarshalStoredConversations([]*storage.StoredConversation{first, second})
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo.git("notes", "--ref", git.NotesRef, "add", "-f", "-m", string(data), sha)
+
+	srv := NewServer(0, repo.path)
+
+	t.Run("commit list names the agents", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/api/commits", nil)
+		w := httptest.NewRecorder()
+		srv.mux.ServeHTTP(w, req)
+
+		var commits []CommitInfo
+		decodeJSON(t, w, &commits)
+		if len(commits) != 1 || len(commits[0].Agents) != 2 {
+			t.Fatalf("commits = %+v, want one commit with two agents", commits)
+		}
+	})
+
+	t.Run("selects a conversation", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/api/commits/"+sha+"?conversation=1", nil)
+		w := httptest.NewRecorder()
+		srv.mux.ServeHTTP(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("status: want 200, got %d: %s", w.Code, w.Body.String())
+		}
+		var resp ConversationResponse
+		decodeJSON(t, w, &resp)
+		if resp.SessionID != "session-2" || resp.Index != 1 {
+			t.Errorf("got session %q at index %d, want session-2 at 1", resp.SessionID, resp.Index)
+		}
+		if len(resp.Conversations) != 2 || resp.Conversations[1].Agent != "codex" {
+			t.Errorf("Conversations = %+v", resp.Conversations)
+		}
+	})
+
+	t.Run("unknown conversation", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/api/commits/"+sha+"?conversation=2", nil)
+		w := httptest.NewRecorder()
+		srv.mux.ServeHTTP(w, req)
+
+		if w.Code != http.StatusNotFound {
+			t.Errorf("status: want 404, got %d", w.Code)
+		}
+	})
+}
+
+func TestHandleExpensiveTurns(t *testing.T) {
+	repo := newTestRepo(t)
+	chdir(t, repo.path)
+
+	assistant := func(uuid, text string, input, output int) map[string]interface{} {
+		return map[string]interface{}{
+			"uuid": uuid, "type": "assistant",
+			"message": map[string]interface{}{
+				"role":    "assistant",
+				"content": []map[string]interface{}{{"type": "text", "text": text}},
+				"usage":   map[string]interface{}{"input_tokens": input, "output_tokens": output},
+			},
+		}
+	}
+	first := []map[string]interface{}{assistant("a1", "Small reply", 100, 10)}
+	second := append(first, assistant("a2", "Big reply", 5000, 500))
+
+	repo.writeFile("a.txt", "a")
+	sha1 := repo.commit("First commit")
+	repo.addConversation(sha1, "session-1", marshalTranscript(first), 1)
+	repo.writeFile("b.txt", "b")
+	sha2 := repo.commit("Second commit")
+	repo.addConversation(sha2, "session-1", marshalTranscript(second), 2)
+
+	srv := NewServer(0, repo.path)
+	req := httptest.NewRequest("GET", "/api/stats/turns", nil)
+	w := httptest.NewRecorder()
+	srv.mux.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("status: want 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var turns []storage.TurnCost
+	decodeJSON(t, w, &turns)
+
+	// a1 is stored with both commits but reported once, for the first
+	if len(turns) != 2 {
+		t.Fatalf("turns: want 2, got %+v", turns)
+	}
+	if turns[0].EntryUUID != "a2" || turns[0].Tokens != 5500 || turns[0].Preview != "Big reply" {
+		t.Errorf("most expensive turn = %+v, want a2 with 5500 tokens", turns[0])
+	}
+	if turns[1].EntryUUID != "a1" || turns[1].CommitSHA != sha1 {
+		t.Errorf("second turn = %+v, want a1 on %s", turns[1], sha1)
+	}
+
+	req = httptest.NewRequest("GET", "/api/stats/turns?limit=1", nil)
+	w = httptest.NewRecorder()
+	srv.mux.ServeHTTP(w, req)
+	decodeJSON(t, w, &turns)
+	if len(turns) != 1 {
+		t.Errorf("turns with limit=1: want 1, got %d", len(turns))
+	}
+}
+
+func TestHandleToolUsage(t *testing.T) {
+	repo := newTestRepo(t)
+	chdir(t, repo.path)
+
+	first := []map[string]interface{}{{
+		"uuid": "a1", "type": "assistant",
+		"message": map[string]interface{}{
+			"role": "assistant",
+			"content": []map[string]interface{}{
+				{"type": "tool_use", "id": "t1", "name": "Bash", "input": map[string]interface{}{"command": "make"}},
+			},
+		},
+	}}
+	second := append(first, map[string]interface{}{
+		"uuid": "u1", "type": "user",
+		"message": map[string]interface{}{
+			"role": "user",
+			"content": []map[string]interface{}{
+				{"type": "tool_result", "tool_use_id": "t1", "is_error": true, "content": "make: *** No targets."},
+			},
+		},
+	})
+
+	repo.writeFile("a.txt", "a")
+	sha1 := repo.commit("First commit")
+	repo.addConversation(sha1, "session-1", marshalTranscript(first), 1)
+	repo.writeFile("b.txt", "b")
+	sha2 := repo.commit("Second commit")
+	repo.addConversation(sha2, "session-1", marshalTranscript(second), 2)
+
+	srv := NewServer(0, repo.path)
+	req := httptest.NewRequest("GET", "/api/stats/tools", nil)
+	w := httptest.NewRecorder()
+	srv.mux.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("status: want 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var usage []storage.ToolUsage
+	decodeJSON(t, w, &usage)
+
+	// The call is stored with both commits, its failed result only with the second
+	if len(usage) != 1 || usage[0].Calls != 1 || usage[0].Failures != 1 {
+		t.Fatalf("usage = %+v, want one failed call", usage)
+	}
+	if len(usage[0].Tools) != 1 || usage[0].Tools[0].Name != "Bash" || usage[0].BashCommands != 1 {
+		t.Errorf("usage = %+v, want one Bash command", usage[0])
+	}
+}
+
+func TestHTMLContainsResumeBranchOption(t *testing.T) {
+	repo := newTestRepo(t)
+	srv := NewServer(0, repo.path)
+
+	req := httptest.NewRequest("GET", "/", nil)
+	w := httptest.NewRecorder()
+	srv.mux.ServeHTTP(w, req)
+
+	body := w.Body.String()
+	for _, elem := range []string{`id="resume-branch"`, "create_branch: createBranch", "data.branch"} {
+		if !strings.Contains(body, elem) {
+			t.Errorf("index.html missing resume branch element: %s", elem)
+		}
+	}
+}
+
+func TestHTMLContainsDirtyResumeDialog(t *testing.T) {
+	repo := newTestRepo(t)
+	srv := NewServer(0, repo.path)
+
+	req := httptest.NewRequest("GET", "/", nil)
+	w := httptest.NewRecorder()
+	srv.mux.ServeHTTP(w, req)
+
+	body := w.Body.String()
+	for _, elem := range []string{
+		`id="dirty-dialog"`,
+		`value="` + DirtyStash + `"`,
+		`value="` + DirtyNoCheckout + `"`,
+		`value="` + DirtyWorktree + `"`,
+		"function chooseDirtyStrategy(",
+		"on_dirty: onDirty",
+		`id="resume-worktree"`,
+	} {
+		if !strings.Contains(body, elem) {
+			t.Errorf("index.html missing dirty resume element: %s", elem)
+		}
+	}
+}
+
+func TestHandleBadge(t *testing.T) {
+	repo := newTestRepo(t)
+	chdir(t, repo.path)
+
+	repo.writeFile("a.txt", "a")
+	sha := repo.commit("AI commit")
+	repo.addConversation(sha, "session-1", sampleTranscript(), 2)
+	repo.writeFile("b.txt", "b")
+	repo.commit("Manual commit")
+
+	srv := NewServer(0, repo.path)
+	get := func(url string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", url, nil)
+		w := httptest.NewRecorder()
+		srv.mux.ServeHTTP(w, req)
+		return w
+	}
+
+	w := get("/badge.svg")
+	if w.Code != http.StatusOK {
+		t.Fatalf("status: want 200, got %d", w.Code)
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "image/svg+xml" {
+		t.Errorf("Content-Type = %q, want image/svg+xml", ct)
+	}
+	if body := w.Body.String(); !strings.Contains(body, "AI-logged") || !strings.Contains(body, "50% of 2 commits") {
+		t.Errorf("badge does not show the coverage: %s", body)
+	}
+
+	w = get("/badge.svg?limit=1&label=audited")
+	if body := w.Body.String(); !strings.Contains(body, "audited") || !strings.Contains(body, "0% of 1 commit") {
+		t.Errorf("badge ignores limit and label: %s", body)
+	}
+
+	if w := get("/badge.svg?limit=0"); w.Code != http.StatusBadRequest {
+		t.Errorf("limit=0: want 400, got %d", w.Code)
+	}
+	if w := get("/badge.svg?ref=--all"); w.Code != http.StatusBadRequest {
+		t.Errorf("ref=--all: want 400, got %d", w.Code)
+	}
+	if w := get("/badge.svg?ref=nope"); w.Code != http.StatusNotFound {
+		t.Errorf("unknown ref: want 404, got %d", w.Code)
+	}
+}
+
+func TestHandleBranchCompare(t *testing.T) {
+	repo := newTestRepo(t)
+	chdir(t, repo.path)
+
+	repo.writeFile("a.txt", "a")
+	base := repo.commit("Shared commit")
+	repo.addConversationWithEffort(base, "s1", sampleTranscript(), 2, &storage.Effort{Turns: 1, InputTokens: 10})
+
+	repo.git("checkout", "-b", "feature-x")
+	repo.writeFile("b.txt", "b")
+	feature := repo.commit("Feature commit")
+	repo.addConversationWithEffort(feature, "s2", extendedTranscript(), 4, &storage.Effort{Turns: 2, InputTokens: 100, OutputTokens: 50})
+	repo.writeFile("c.txt", "c")
+	repo.commit("Manual feature commit")
+
+	repo.git("checkout", "master")
+	repo.writeFile("d.txt", "d")
+	repo.commit("Master commit")
+
+	srv := NewServer(0, repo.path)
+	get := func(url string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", url, nil)
+		w := httptest.NewRecorder()
+		srv.mux.ServeHTTP(w, req)
+		return w
+	}
+
+	w := get("/api/branches/compare?a=master&b=feature-x")
+	if w.Code != http.StatusOK {
+		t.Fatalf("status: want 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var data BranchCompareData
+	decodeJSON(t, w, &data)
+
+	if data.MergeBase != base {
+		t.Errorf("merge base = %s, want %s", data.MergeBase, base)
+	}
+	if len(data.A.Commits) != 1 || data.A.Commits[0].Message != "Master commit" {
+		t.Errorf("master-only commits = %+v, want the master commit", data.A.Commits)
+	}
+	want := EffortTotals{Commits: 2, WithConversation: 1, Conversations: 1, Messages: 4, Turns: 2, Tokens: 150}
+	if data.B.Totals != want {
+		t.Errorf("feature totals = %+v, want %+v", data.B.Totals, want)
+	}
+	if len(data.Shared.Commits) != 1 || data.Shared.Totals.Tokens != 10 {
+		t.Errorf("shared = %+v, want the shared commit and its tokens", data.Shared)
+	}
+
+	if w := get("/api/branches/compare?a=master"); w.Code != http.StatusBadRequest {
+		t.Errorf("without b: want 400, got %d", w.Code)
+	}
+	if w := get("/api/branches/compare?a=master&b=--all"); w.Code != http.StatusNotFound {
+		t.Errorf("unknown branch: want 404, got %d", w.Code)
+	}
+}
+
+func TestHTMLContainsBranchCompare(t *testing.T) {
+	repo := newTestRepo(t)
+	srv := NewServer(0, repo.path)
+
+	req := httptest.NewRequest("GET", "/", nil)
+	w := httptest.NewRecorder()
+	srv.mux.ServeHTTP(w, req)
+
+	body := w.Body.String()
+	for _, elem := range []string{`id="compare-dialog"`, "/api/branches/compare?", "function renderBranchCompare("} {
+		if !strings.Contains(body, elem) {
+			t.Errorf("index.html missing branch compare element: %s", elem)
+		}
+	}
+}
+
+func TestHandleCommitsAuthor(t *testing.T) {
+	repo := newTestRepo(t)
+	chdir(t, repo.path)
+
+	repo.writeFile("a.txt", "a")
+	repo.git("add", "-A")
+	repo.git("commit", "-m", "From work", "--author", "Alice Smith <alice@corp.example>")
+	work := repo.git("rev-parse", "HEAD")
+	repo.writeFile("b.txt", "b")
+	repo.git("add", "-A")
+	repo.git("commit", "-m", "From home", "--author", "alice <alice@home.example>")
+	home := repo.git("rev-parse", "HEAD")
+	repo.writeFile("c.txt", "c")
+	repo.commit("Someone else")
+	repo.writeFile(".mailmap", "Alice Smith <alice@corp.example> <alice@home.example>\n")
+
+	repo.addConversation(work, "session-work", sampleTranscript(), 2)
+	repo.addConversation(home, "session-home", sampleTranscript(), 2)
+
+	srv := NewServer(0, repo.path)
+
+	t.Run("filters by the mapped author", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/api/commits?author=Alice+Smith", nil)
+		w := httptest.NewRecorder()
+		srv.mux.ServeHTTP(w, req)
+
+		var commits []CommitInfo
+		decodeJSON(t, w, &commits)
+		if len(commits) != 2 {
+			t.Fatalf("expected Alice's 2 commits, got %+v", commits)
+		}
+		for _, c := range commits {
+			if c.Author != "Alice Smith" {
+				t.Errorf("author of %q = %q, want Alice Smith", c.Message, c.Author)
+			}
+		}
+	})
+
+	t.Run("aggregates stats across emails", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/api/stats", nil)
+		w := httptest.NewRecorder()
+		srv.mux.ServeHTTP(w, req)
+
+		var stats storage.Stats
+		decodeJSON(t, w, &stats)
+		if len(stats.Authors) != 1 || stats.Authors["Alice Smith"] == nil || stats.Authors["Alice Smith"].Conversations != 2 {
+			t.Errorf("authors = %+v, want Alice Smith with 2 conversations", stats.Authors)
+		}
+	})
+}
+
+func TestHTMLContainsAuthors(t *testing.T) {
+	repo := newTestRepo(t)
+	srv := NewServer(0, repo.path)
+
+	req := httptest.NewRequest("GET", "/", nil)
+	w := httptest.NewRecorder()
+	srv.mux.ServeHTTP(w, req)
+
+	body := w.Body.String()
+	for _, elem := range []string{`id="author-dialog"`, `id="nav-authors"`, "function openAuthor("} {
+		if !strings.Contains(body, elem) {
+			t.Errorf("index.html missing author element: %s", elem)
+		}
+	}
+}
+
+func TestHTMLContainsDeepLinks(t *testing.T) {
+	repo := newTestRepo(t)
+	srv := NewServer(0, repo.path)
+
+	req := httptest.NewRequest("GET", "/", nil)
+	w := httptest.NewRecorder()
+	srv.mux.ServeHTTP(w, req)
+
+	body := w.Body.String()
+	for _, elem := range []string{"function parseRoute(", "function applyRoute(", "'hashchange'", "function handleKeydown(", "?entry=", "entry-link"} {
+		if !strings.Contains(body, elem) {
+			t.Errorf("index.html missing deep link element: %s", elem)
+		}
+	}
+}
+
+func TestHandleCommitsWithPrivateConversation(t *testing.T) {
+	repo := newTestRepo(t)
+	chdir(t, repo.path)
+
+	repo.writeFile("a.txt", "a")
+	sha := repo.commit("First commit")
+	stored, err := storage.NewStoredConversation("session-private", repo.path, "master", 2, sampleTranscript())
+	if err != nil {
+		t.Fatal(err)
+	}
+	stored.Visibility = config.VisibilityPrivate
+	if err := storage.SavePrivateConversations(sha, []*storage.StoredConversation{stored}); err != nil {
+		t.Fatal(err)
+	}
+
+	srv := NewServer(0, repo.path)
+
+	req := httptest.NewRequest("GET", "/api/commits", nil)
+	w := httptest.NewRecorder()
+	srv.mux.ServeHTTP(w, req)
+	var commits []CommitInfo
+	decodeJSON(t, w, &commits)
+	if len(commits) != 1 || !commits[0].HasConversation || !commits[0].Private {
+		t.Fatalf("commits = %+v, want one with a private conversation", commits)
+	}
+
+	req = httptest.NewRequest("GET", "/api/commits/"+sha, nil)
+	w = httptest.NewRecorder()
+	srv.mux.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status: want 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var resp ConversationResponse
+	decodeJSON(t, w, &resp)
+	if resp.SessionID != "session-private" || !resp.Private {
+		t.Errorf("conversation = %s, private %v; want session-private, private", resp.SessionID, resp.Private)
+	}
+}
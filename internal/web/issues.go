@@ -0,0 +1,70 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/re-cinq/shift-log/internal/storage"
+	"github.com/re-cinq/shift-log/internal/util"
+)
+
+// IssueResponse is the response of the issues API: the commits whose
+// conversations refer to an issue, newest first.
+type IssueResponse struct {
+	Key     string       `json:"key"`
+	Commits []IssueEntry `json:"commits"`
+}
+
+// IssueEntry is a commit of an issue with its conversations.
+type IssueEntry struct {
+	SHA           string            `json:"sha"`
+	Message       string            `json:"message"`
+	Date          string            `json:"date"`
+	Summary       string            `json:"summary,omitempty"`
+	Conversations []ConversationRef `json:"conversations"`
+}
+
+// handleIssue returns the commits whose conversations refer to an issue.
+// Path format: /api/issues/<key>
+func (s *Server) handleIssue(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	key := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/issues/"), "/")
+	if key == "" || strings.Contains(key, "/") {
+		writeJSONError(w, http.StatusBadRequest, "issue key required")
+		return
+	}
+
+	commits, err := storage.IssueCommits(r.Context(), key)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "failed to list the conversations of the issue")
+		return
+	}
+
+	response := IssueResponse{Key: key, Commits: make([]IssueEntry, 0, len(commits))}
+	for _, c := range commits {
+		entry := IssueEntry{SHA: c.SHA, Message: c.Subject, Date: util.NormalizeTimestamp(c.Date)}
+		for i, sc := range c.Conversations {
+			if entry.Summary == "" {
+				entry.Summary = sc.Summary
+			}
+			entry.Conversations = append(entry.Conversations, ConversationRef{
+				Index:        i,
+				SessionID:    sc.SessionID,
+				Agent:        sc.AgentName(),
+				Model:        sc.Model,
+				MessageCount: sc.MessageCount,
+				Private:      sc.IsPrivate(),
+				NotesRef:     sc.ContributorRef,
+			})
+		}
+		response.Commits = append(response.Commits, entry)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,75 @@
+package web
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/re-cinq/shift-log/internal/git"
+	"github.com/re-cinq/shift-log/internal/storage"
+)
+
+func TestHandleIssue(t *testing.T) {
+	repo := newTestRepo(t)
+	chdir(t, repo.path)
+	addWithIssues := func(sha, sessionID string, issues ...string) {
+		t.Helper()
+		stored, err := storage.NewStoredConversation(sessionID, repo.path, "master", 2, sampleTranscript())
+		if err != nil {
+			t.Fatal(err)
+		}
+		stored.Issues = issues
+		stored.Summary = "Worked on " + sessionID
+		data, err := stored.Marshal()
+		if err != nil {
+			t.Fatal(err)
+		}
+		repo.git("notes", "--ref", git.NotesRef, "add", "-f", "-m", string(data), sha)
+	}
+
+	repo.writeFile("a.txt", "a")
+	first := repo.commit("PROJ-1: first")
+	addWithIssues(first, "session-1", "PROJ-1")
+	repo.writeFile("b.txt", "b")
+	second := repo.commit("PROJ-1, PROJ-2: second")
+	addWithIssues(second, "session-2", "PROJ-1", "PROJ-2")
+	repo.writeFile("c.txt", "c")
+	repo.addConversation(repo.commit("Unrelated"), "session-3", sampleTranscript(), 2)
+
+	srv := NewServer(0, repo.path)
+	get := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		srv.mux.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		return w
+	}
+
+	w := get("/api/issues/proj-1")
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", w.Code, w.Body.String())
+	}
+	var resp IssueResponse
+	decodeJSON(t, w, &resp)
+	// Committed within the same second, the commits come in either order
+	bySHA := make(map[string]IssueEntry)
+	for _, c := range resp.Commits {
+		bySHA[c.SHA] = c
+	}
+	if _, ok := bySHA[first]; len(resp.Commits) != 2 || !ok {
+		t.Fatalf("commits = %+v, want the first and second commits", resp.Commits)
+	}
+	c := bySHA[second]
+	if c.Message != "PROJ-1, PROJ-2: second" || c.Summary != "Worked on session-2" || len(c.Conversations) != 1 || c.Conversations[0].SessionID != "session-2" {
+		t.Errorf("commit = %+v", c)
+	}
+
+	w = get("/api/issues/PROJ-3")
+	resp = IssueResponse{}
+	decodeJSON(t, w, &resp)
+	if w.Code != http.StatusOK || resp.Commits == nil || len(resp.Commits) != 0 {
+		t.Errorf("an issue without conversations: %d %+v", w.Code, resp)
+	}
+
+	if w := get("/api/issues/"); w.Code != http.StatusBadRequest {
+		t.Errorf("no key: status %d, want 400", w.Code)
+	}
+}
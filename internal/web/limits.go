@@ -0,0 +1,121 @@
+package web
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Some endpoints run a git command per branch, or per pair of branches, or
+// read every conversation of the branch: a dashboard refreshing them in a
+// loop would keep the server's CPUs busy. Each of them serves a few requests
+// at once, and each client a few requests a second; the requests past
+// either limit get 429 Too Many Requests with a Retry-After.
+
+// endpointLimits are the limits of one expensive endpoint.
+type endpointLimits struct {
+	concurrent int     // requests served at once, from every client
+	rate       float64 // requests per second of each client
+	burst      int     // requests a client may make at once before rate applies
+}
+
+// expensiveLimits are the limits of the expensive endpoints.
+var expensiveLimits = endpointLimits{concurrent: 2, rate: 1, burst: 10}
+
+// limiterPruneSize is the number of clients tracked past which those that
+// have not made a request for a while are forgotten.
+const limiterPruneSize = 1024
+
+// limiter enforces endpointLimits.
+type limiter struct {
+	limits endpointLimits
+	slots  chan struct{}
+
+	mu      sync.Mutex
+	buckets map[string]*tokenBucket // by client address
+	now     func() time.Time
+}
+
+func newLimiter(limits endpointLimits) *limiter {
+	return &limiter{
+		limits:  limits,
+		slots:   make(chan struct{}, limits.concurrent),
+		buckets: make(map[string]*tokenBucket),
+		now:     time.Now,
+	}
+}
+
+// tokenBucket holds a client's tokens, one spent per request and refilled
+// at the endpoint's rate up to its burst.
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// allow takes a token of client's bucket, or returns how long until there
+// is one.
+func (l *limiter) allow(client string) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := l.now()
+	if len(l.buckets) >= limiterPruneSize {
+		l.prune(now)
+	}
+	b := l.buckets[client]
+	if b == nil {
+		b = &tokenBucket{tokens: float64(l.limits.burst), last: now}
+		l.buckets[client] = b
+	}
+	b.tokens = math.Min(float64(l.limits.burst), b.tokens+now.Sub(b.last).Seconds()*l.limits.rate)
+	b.last = now
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+	return false, time.Duration((1 - b.tokens) / l.limits.rate * float64(time.Second))
+}
+
+// prune forgets the clients whose buckets have refilled: they start again
+// with a full one anyway.
+func (l *limiter) prune(now time.Time) {
+	full := time.Duration(float64(l.limits.burst) / l.limits.rate * float64(time.Second))
+	for client, b := range l.buckets {
+		if now.Sub(b.last) >= full {
+			delete(l.buckets, client)
+		}
+	}
+}
+
+// limit wraps an expensive endpoint's handler to enforce limits.
+func limit(limits endpointLimits, handler http.HandlerFunc) http.HandlerFunc {
+	l := newLimiter(limits)
+	return func(w http.ResponseWriter, r *http.Request) {
+		client, _, err := net.SplitHostPort(r.RemoteAddr)
+		if err != nil {
+			client = r.RemoteAddr
+		}
+		if ok, wait := l.allow(client); !ok {
+			tooManyRequests(w, wait, "too many requests, slow down")
+			return
+		}
+		select {
+		case l.slots <- struct{}{}:
+			defer func() { <-l.slots }()
+		default:
+			tooManyRequests(w, time.Second, "server busy, try again")
+			return
+		}
+		handler(w, r)
+	}
+}
+
+// tooManyRequests rejects a request with 429, asking the client to retry
+// after wait, rounded up to whole seconds.
+func tooManyRequests(w http.ResponseWriter, wait time.Duration, message string) {
+	seconds := max(1, int(math.Ceil(wait.Seconds())))
+	w.Header().Set("Retry-After", strconv.Itoa(seconds))
+	writeJSONError(w, http.StatusTooManyRequests, message)
+}
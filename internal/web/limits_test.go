@@ -0,0 +1,102 @@
+package web
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestLimiterRate(t *testing.T) {
+	l := newLimiter(endpointLimits{concurrent: 1, rate: 2, burst: 3})
+	now := time.Unix(1700000000, 0)
+	l.now = func() time.Time { return now }
+
+	for i := range 3 {
+		if ok, _ := l.allow("client"); !ok {
+			t.Fatalf("request %d of the burst was refused", i+1)
+		}
+	}
+	ok, wait := l.allow("client")
+	if ok {
+		t.Fatal("request past the burst was allowed")
+	}
+	if wait != 500*time.Millisecond {
+		t.Errorf("wait: want 500ms, got %v", wait)
+	}
+	if ok, _ := l.allow("other"); !ok {
+		t.Error("another client was refused")
+	}
+
+	now = now.Add(500 * time.Millisecond)
+	if ok, _ := l.allow("client"); !ok {
+		t.Error("request after the wait was refused")
+	}
+	if ok, _ := l.allow("client"); ok {
+		t.Error("second request after the wait was allowed")
+	}
+}
+
+func TestLimiterPrune(t *testing.T) {
+	l := newLimiter(endpointLimits{concurrent: 1, rate: 1, burst: 2})
+	now := time.Unix(1700000000, 0)
+	l.now = func() time.Time { return now }
+	for i := range limiterPruneSize {
+		l.allow(string(rune('a' + i)))
+	}
+	now = now.Add(2 * time.Second)
+	l.allow("new")
+	if len(l.buckets) != 1 {
+		t.Errorf("clients tracked after pruning: want 1, got %d", len(l.buckets))
+	}
+}
+
+func TestLimitRejectsWithRetryAfter(t *testing.T) {
+	handler := limit(endpointLimits{concurrent: 1, rate: 0.5, burst: 1}, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	w := httptest.NewRecorder()
+	handler(w, httptest.NewRequest("GET", "/api/stats", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("first request: want 200, got %d", w.Code)
+	}
+	w = httptest.NewRecorder()
+	handler(w, httptest.NewRequest("GET", "/api/stats", nil))
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("second request: want 429, got %d", w.Code)
+	}
+	if got := w.Header().Get("Retry-After"); got != "2" {
+		t.Errorf("Retry-After: want 2, got %q", got)
+	}
+}
+
+func TestLimitConcurrency(t *testing.T) {
+	started, release := make(chan struct{}), make(chan struct{})
+	handler := limit(endpointLimits{concurrent: 1, rate: 100, burst: 100}, func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+	})
+
+	done := make(chan int)
+	go func() {
+		w := httptest.NewRecorder()
+		handler(w, httptest.NewRequest("GET", "/api/graph/branches", nil))
+		done <- w.Code
+	}()
+	<-started
+
+	w := httptest.NewRecorder()
+	handler(w, httptest.NewRequest("GET", "/api/graph/branches", nil))
+	if w.Code != http.StatusTooManyRequests {
+		t.Errorf("request while busy: want 429, got %d", w.Code)
+	}
+	if w.Header().Get("Retry-After") == "" {
+		t.Error("request while busy: no Retry-After")
+	}
+
+	close(release)
+	if code := <-done; code != http.StatusOK {
+		t.Errorf("request in progress: want 200, got %d", code)
+	}
+}
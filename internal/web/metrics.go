@@ -0,0 +1,301 @@
+package web
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/re-cinq/shift-log/internal/git"
+	"github.com/re-cinq/shift-log/internal/storage"
+)
+
+// The server counts its requests, the git commands it runs, how often the
+// conversation index was up to date and the size of the notes it read, and
+// serves them at /metrics in the Prometheus text format. The git commands
+// and notes are counted through hooks of their packages, so the counts are
+// the process's, shared by every Server.
+
+// durationBuckets are the upper bounds, in seconds, of the buckets of the
+// request and git command duration histograms.
+var durationBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// noteSizeBuckets are the upper bounds, in bytes, of the buckets of the note
+// size histogram.
+var noteSizeBuckets = []float64{1 << 10, 4 << 10, 16 << 10, 64 << 10, 256 << 10, 1 << 20, 4 << 20, 16 << 20}
+
+// histogram counts observations in buckets, as a Prometheus histogram.
+type histogram struct {
+	bounds []float64
+	counts []uint64 // per bucket, the last one past every bound
+	sum    float64
+	count  uint64
+}
+
+func newHistogram(bounds []float64) *histogram {
+	return &histogram{bounds: bounds, counts: make([]uint64, len(bounds)+1)}
+}
+
+func (h *histogram) observe(v float64) {
+	i := sort.SearchFloat64s(h.bounds, v)
+	h.counts[i]++
+	h.sum += v
+	h.count++
+}
+
+// write writes the histogram's series, with labels, a comma-separated list
+// of name="value" pairs, added to each.
+func (h *histogram) write(w io.Writer, name, labels string) {
+	prefix := labels
+	if prefix != "" {
+		prefix += ","
+	}
+	cumulative := uint64(0)
+	for i, bound := range h.bounds {
+		cumulative += h.counts[i]
+		fmt.Fprintf(w, "%s_bucket{%sle=\"%s\"} %d\n", name, prefix, formatFloat(bound), cumulative)
+	}
+	fmt.Fprintf(w, "%s_bucket{%sle=\"+Inf\"} %d\n", name, prefix, h.count)
+	if labels != "" {
+		labels = "{" + labels + "}"
+	}
+	fmt.Fprintf(w, "%s_sum%s %s\n", name, labels, formatFloat(h.sum))
+	fmt.Fprintf(w, "%s_count%s %d\n", name, labels, h.count)
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// requestKey identifies the requests counted together: route is the
+// pattern of the handler that served them.
+type requestKey struct {
+	route  string
+	method string
+	code   int
+}
+
+// serverMetrics are the metrics served at /metrics.
+type serverMetrics struct {
+	inFlight atomic.Int64
+
+	mu          sync.Mutex
+	requests    map[requestKey]*histogram
+	gitCommands map[string]*histogram // by git subcommand
+	gitErrors   map[string]uint64
+	indexOpens  map[bool]uint64 // by whether the index was up to date
+	noteSizes   *histogram
+}
+
+var (
+	metrics = &serverMetrics{
+		requests:    make(map[requestKey]*histogram),
+		gitCommands: make(map[string]*histogram),
+		gitErrors:   make(map[string]uint64),
+		indexOpens:  make(map[bool]uint64),
+		noteSizes:   newHistogram(noteSizeBuckets),
+	}
+	metricsHooks sync.Once
+)
+
+// installMetricsHooks has the git and storage packages report to metrics.
+func installMetricsHooks() {
+	metricsHooks.Do(func() {
+		git.CommandObserver = metrics.observeGitCommand
+		storage.IndexObserver = metrics.observeIndexOpen
+		storage.NoteObserver = metrics.observeNote
+	})
+}
+
+func (m *serverMetrics) observeRequest(key requestKey, elapsed time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	h := m.requests[key]
+	if h == nil {
+		h = newHistogram(durationBuckets)
+		m.requests[key] = h
+	}
+	h.observe(elapsed.Seconds())
+}
+
+func (m *serverMetrics) observeGitCommand(args []string, elapsed time.Duration, err error) {
+	command := gitSubcommand(args)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	h := m.gitCommands[command]
+	if h == nil {
+		h = newHistogram(durationBuckets)
+		m.gitCommands[command] = h
+	}
+	h.observe(elapsed.Seconds())
+	if err != nil {
+		m.gitErrors[command]++
+	}
+}
+
+func (m *serverMetrics) observeIndexOpen(upToDate bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.indexOpens[upToDate]++
+}
+
+func (m *serverMetrics) observeNote(size int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.noteSizes.observe(float64(size))
+}
+
+// gitSubcommand returns the subcommand of git's args, past the options
+// given to git itself.
+func gitSubcommand(args []string) string {
+	for i := 0; i < len(args); i++ {
+		switch {
+		case args[i] == "-c" || args[i] == "-C":
+			i++
+		case !strings.HasPrefix(args[i], "-"):
+			return args[i]
+		}
+	}
+	return "git"
+}
+
+// write writes the metrics in the Prometheus text format.
+func (m *serverMetrics) write(w io.Writer) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	fmt.Fprintln(w, "# HELP shiftlog_http_requests_in_flight Requests being served.")
+	fmt.Fprintln(w, "# TYPE shiftlog_http_requests_in_flight gauge")
+	fmt.Fprintf(w, "shiftlog_http_requests_in_flight %d\n", m.inFlight.Load())
+
+	fmt.Fprintln(w, "# HELP shiftlog_http_request_duration_seconds Time taken to serve requests, by route, method and status code.")
+	fmt.Fprintln(w, "# TYPE shiftlog_http_request_duration_seconds histogram")
+	keys := make([]requestKey, 0, len(m.requests))
+	for key := range m.requests {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		a, b := keys[i], keys[j]
+		if a.route != b.route {
+			return a.route < b.route
+		}
+		if a.method != b.method {
+			return a.method < b.method
+		}
+		return a.code < b.code
+	})
+	for _, key := range keys {
+		labels := fmt.Sprintf("route=%s,method=%s,code=\"%d\"", labelValue(key.route), labelValue(key.method), key.code)
+		m.requests[key].write(w, "shiftlog_http_request_duration_seconds", labels)
+	}
+
+	commands := make([]string, 0, len(m.gitCommands))
+	for command := range m.gitCommands {
+		commands = append(commands, command)
+	}
+	sort.Strings(commands)
+	fmt.Fprintln(w, "# HELP shiftlog_git_command_duration_seconds Time taken by git subprocesses, by subcommand.")
+	fmt.Fprintln(w, "# TYPE shiftlog_git_command_duration_seconds histogram")
+	for _, command := range commands {
+		m.gitCommands[command].write(w, "shiftlog_git_command_duration_seconds", "command="+labelValue(command))
+	}
+	fmt.Fprintln(w, "# HELP shiftlog_git_command_errors_total Git subprocesses that failed, by subcommand.")
+	fmt.Fprintln(w, "# TYPE shiftlog_git_command_errors_total counter")
+	for _, command := range commands {
+		fmt.Fprintf(w, "shiftlog_git_command_errors_total{command=%s} %d\n", labelValue(command), m.gitErrors[command])
+	}
+
+	fmt.Fprintln(w, "# HELP shiftlog_index_opens_total Opens of the conversation index, by whether it was up to date (hit) or had notes to read again (miss).")
+	fmt.Fprintln(w, "# TYPE shiftlog_index_opens_total counter")
+	fmt.Fprintf(w, "shiftlog_index_opens_total{result=\"hit\"} %d\n", m.indexOpens[true])
+	fmt.Fprintf(w, "shiftlog_index_opens_total{result=\"miss\"} %d\n", m.indexOpens[false])
+
+	fmt.Fprintln(w, "# HELP shiftlog_note_size_bytes Size of the conversation notes read.")
+	fmt.Fprintln(w, "# TYPE shiftlog_note_size_bytes histogram")
+	m.noteSizes.write(w, "shiftlog_note_size_bytes", "")
+}
+
+// labelValue quotes a label value as the text format requires.
+func labelValue(v string) string {
+	v = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(v)
+	return `"` + v + `"`
+}
+
+// handleMetrics serves the metrics in the Prometheus text format.
+func (s *Server) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	metrics.write(w)
+}
+
+// statusRecorder records the status code and size of a response.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *statusRecorder) Write(p []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	n, err := r.ResponseWriter.Write(p)
+	r.bytes += n
+	return n, err
+}
+
+// Flush keeps the event stream working through the recorder.
+func (r *statusRecorder) Flush() {
+	_ = http.NewResponseController(r.ResponseWriter).Flush()
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (r *statusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+// instrument wraps next to count its requests in metrics and, when the
+// server has an access log, log each of them once served.
+func (s *Server) instrument(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		metrics.inFlight.Add(1)
+		defer metrics.inFlight.Add(-1)
+
+		rec := &statusRecorder{ResponseWriter: w}
+		next.ServeHTTP(rec, r)
+		if rec.status == 0 {
+			rec.status = http.StatusOK
+		}
+		elapsed := time.Since(start)
+
+		_, route := s.mux.Handler(r)
+		metrics.observeRequest(requestKey{route: route, method: r.Method, code: rec.status}, elapsed)
+		if s.accessLog != nil {
+			s.accessLog.LogAttrs(r.Context(), slog.LevelInfo, "request",
+				slog.String("method", r.Method),
+				slog.String("path", r.URL.RequestURI()),
+				slog.Int("status", rec.status),
+				slog.Int("bytes", rec.bytes),
+				slog.Duration("duration", elapsed),
+				slog.String("remote", r.RemoteAddr),
+			)
+		}
+	})
+}
@@ -0,0 +1,85 @@
+package web
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestMetrics(t *testing.T) {
+	repo := newTestRepo(t)
+	chdir(t, repo.path)
+	repo.writeFile("a.txt", "a")
+	sha := repo.commit("First commit")
+	repo.addConversation(sha, "session-1", sampleTranscript(), 2)
+
+	srv := NewServer(0, repo.path)
+	var logs bytes.Buffer
+	srv.SetAccessLog(slog.New(slog.NewJSONHandler(&logs, nil)))
+	handler := srv.Handler()
+
+	for _, path := range []string{"/api/commits", "/api/commits/" + sha, "/api/commits/unknown"} {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", path, nil))
+	}
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status: want 200, got %d", w.Code)
+	}
+	if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain; version=0.0.4") {
+		t.Errorf("Content-Type: want the Prometheus text format, got %q", ct)
+	}
+
+	body := w.Body.String()
+	for _, want := range []string{
+		`shiftlog_http_request_duration_seconds_count{route="/api/commits",method="GET",code="200"}`,
+		`shiftlog_http_request_duration_seconds_count{route="/api/commits/",method="GET",code="400"}`,
+		`shiftlog_http_request_duration_seconds_bucket{route="/api/commits/",method="GET",code="200",le="+Inf"}`,
+		`shiftlog_git_command_duration_seconds_count{command="log"}`,
+		`shiftlog_git_command_errors_total{command="rev-parse"}`,
+		`shiftlog_index_opens_total{result="hit"}`,
+		`shiftlog_note_size_bytes_count`,
+		`shiftlog_http_requests_in_flight 1`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("metrics do not contain %s:\n%s", want, body)
+		}
+	}
+
+	// One access log line per request
+	lines := strings.Split(strings.TrimSpace(logs.String()), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("access log lines: want 4, got %d:\n%s", len(lines), logs.String())
+	}
+	var entry struct {
+		Msg    string `json:"msg"`
+		Method string `json:"method"`
+		Path   string `json:"path"`
+		Status int    `json:"status"`
+		Bytes  int    `json:"bytes"`
+	}
+	if err := json.Unmarshal([]byte(lines[1]), &entry); err != nil {
+		t.Fatal(err)
+	}
+	if entry.Msg != "request" || entry.Method != "GET" || entry.Path != "/api/commits/"+sha || entry.Status != 200 || entry.Bytes == 0 {
+		t.Errorf("access log entry: got %+v", entry)
+	}
+}
+
+func TestGitSubcommand(t *testing.T) {
+	tests := map[string][]string{
+		"log":   {"log", "--format=%H"},
+		"notes": {"-c", "core.notesRef=refs/notes/x", "notes", "show"},
+		"show":  {"--no-pager", "-C", "/repo", "show"},
+		"git":   {"--version"},
+	}
+	for want, args := range tests {
+		if got := gitSubcommand(args); got != want {
+			t.Errorf("gitSubcommand(%q) = %q, want %q", args, got, want)
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package web
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/re-cinq/shift-log/internal/git"
+	"github.com/re-cinq/shift-log/internal/report"
+	"github.com/re-cinq/shift-log/internal/storage"
+	"github.com/re-cinq/shift-log/internal/util"
+)
+
+// TagSummary represents a tag in the tags API response.
+type TagSummary struct {
+	Name string `json:"name"`
+	SHA  string `json:"sha"` // the tagged commit
+	Date string `json:"date"`
+}
+
+// handleTags lists the tags of commits, newest first.
+func (s *Server) handleTags(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	tags, err := git.ListTags(s.repoDir)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "failed to list tags")
+		return
+	}
+	result := make([]TagSummary, 0, len(tags))
+	for _, t := range tags {
+		result = append(result, TagSummary{Name: t.Name, SHA: t.CommitSHA, Date: util.NormalizeTimestamp(t.Date)})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(result)
+}
+
+// handleReleaseReport reports on the conversations of a release, the
+// commits from the tag ?from= to the tag ?to=. from defaults to the tag
+// before to. ?format=markdown returns the report as a Markdown download
+// instead of JSON.
+func (s *Server) handleReleaseReport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+	from, to := query.Get("from"), query.Get("to")
+	if to == "" {
+		writeJSONError(w, http.StatusBadRequest, "to is required")
+		return
+	}
+	format := query.Get("format")
+	if format != "" && format != "json" && format != "markdown" {
+		writeJSONError(w, http.StatusBadRequest, "format must be json or markdown")
+		return
+	}
+
+	// Only report on known tags, which also keeps options out of git log
+	tags, err := git.ListTags(s.repoDir)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "failed to list tags")
+		return
+	}
+	for _, name := range []string{from, to} {
+		if name != "" && !hasTag(tags, name) {
+			writeJSONError(w, http.StatusNotFound, "unknown tag "+name)
+			return
+		}
+	}
+
+	rep, err := storage.BuildReleaseReport(from, to)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "failed to build the release report")
+		return
+	}
+
+	if format == "markdown" {
+		var buf bytes.Buffer
+		report.Release(&buf, rep, "")
+		name := strings.NewReplacer("/", "-", `"`, "").Replace(to)
+		w.Header().Set("Content-Type", "text/markdown; charset=utf-8")
+		w.Header().Set("Content-Disposition", `attachment; filename="release-`+name+`.md"`)
+		_, _ = w.Write(buf.Bytes())
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(rep)
+}
+
+// hasTag reports whether tags has a tag called name.
+func hasTag(tags []git.TagInfo, name string) bool {
+	return slices.ContainsFunc(tags, func(t git.TagInfo) bool { return t.Name == name })
+}
+
+// tagsByCommit maps commits to the names of their tags.
+func tagsByCommit(tags []git.TagInfo) map[string][]string {
+	byCommit := make(map[string][]string, len(tags))
+	for _, t := range tags {
+		byCommit[t.CommitSHA] = append(byCommit[t.CommitSHA], t.Name)
+	}
+	return byCommit
+}
@@ -0,0 +1,178 @@
+package web
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/re-cinq/shift-log/internal/storage"
+)
+
+// releaseRepo makes a repository with the releases v1.0.0 (lightweight) and
+// v1.1.0 (annotated) of one commit each, plus an untagged commit; the
+// commit of v1.1.0 has a conversation. It returns the commits oldest first.
+func releaseRepo(t *testing.T) (*testRepo, []string) {
+	repo := newTestRepo(t)
+	chdir(t, repo.path)
+
+	repo.writeFile("a.txt", "a")
+	first := repo.commit("First")
+	repo.git("tag", "v1.0.0")
+	repo.writeFile("b.txt", "b")
+	second := repo.commit("Second")
+	repo.git("tag", "-a", "-m", "Release 1.1", "v1.1.0")
+	repo.writeFile("c.txt", "c")
+	third := repo.commit("Third")
+
+	repo.addConversation(second, "session-1", sampleTranscript(), 2)
+	return repo, []string{first, second, third}
+}
+
+func TestHandleTags(t *testing.T) {
+	repo, shas := releaseRepo(t)
+	srv := NewServer(0, repo.path)
+
+	req := httptest.NewRequest("GET", "/api/tags", nil)
+	w := httptest.NewRecorder()
+	srv.mux.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status: want 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var tags []TagSummary
+	decodeJSON(t, w, &tags)
+	got := map[string]string{}
+	for _, tag := range tags {
+		got[tag.Name] = tag.SHA
+	}
+	if len(tags) != 2 || got["v1.0.0"] != shas[0] || got["v1.1.0"] != shas[1] {
+		t.Errorf("tags = %+v, want v1.0.0 on %s and v1.1.0 on %s", tags, shas[0][:7], shas[1][:7])
+	}
+}
+
+func TestHandleCommitsGitTags(t *testing.T) {
+	repo, shas := releaseRepo(t)
+	srv := NewServer(0, repo.path)
+
+	t.Run("lists the git tags of commits", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/api/commits", nil)
+		w := httptest.NewRecorder()
+		srv.mux.ServeHTTP(w, req)
+
+		var commits []CommitInfo
+		decodeJSON(t, w, &commits)
+		for _, c := range commits {
+			want := map[string]string{shas[0]: "v1.0.0", shas[1]: "v1.1.0"}[c.SHA]
+			if got := strings.Join(c.GitTags, ","); got != want {
+				t.Errorf("git tags of %s = %q, want %q", c.Message, got, want)
+			}
+		}
+	})
+
+	t.Run("lists the commits of a release", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/api/commits?release=v1.1.0", nil)
+		w := httptest.NewRecorder()
+		srv.mux.ServeHTTP(w, req)
+
+		var commits []CommitInfo
+		decodeJSON(t, w, &commits)
+		if len(commits) != 1 || commits[0].SHA != shas[1] || !commits[0].HasConversation {
+			t.Errorf("commits = %+v, want only the commit of v1.1.0, with its conversation", commits)
+		}
+	})
+
+	t.Run("a first release starts at the root", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/api/commits?release=v1.0.0", nil)
+		w := httptest.NewRecorder()
+		srv.mux.ServeHTTP(w, req)
+
+		var commits []CommitInfo
+		decodeJSON(t, w, &commits)
+		if len(commits) != 1 || commits[0].SHA != shas[0] {
+			t.Errorf("commits = %+v, want only the first commit", commits)
+		}
+	})
+
+	t.Run("rejects unknown releases", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/api/commits?release=--all", nil)
+		w := httptest.NewRecorder()
+		srv.mux.ServeHTTP(w, req)
+		if w.Code != http.StatusNotFound {
+			t.Errorf("status: want 404, got %d", w.Code)
+		}
+	})
+}
+
+func TestHandleReleaseReport(t *testing.T) {
+	repo, shas := releaseRepo(t)
+	srv := NewServer(0, repo.path)
+
+	t.Run("reports since the previous tag", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/api/releases/report?to=v1.1.0", nil)
+		w := httptest.NewRecorder()
+		srv.mux.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("status: want 200, got %d: %s", w.Code, w.Body.String())
+		}
+
+		var rep storage.ReleaseReport
+		decodeJSON(t, w, &rep)
+		if rep.From != "v1.0.0" || rep.Commits != 1 || rep.WithConversation != 1 {
+			t.Errorf("report = %+v, want 1 commit since v1.0.0 with a conversation", rep)
+		}
+		if len(rep.Conversations) != 1 || rep.Conversations[0].CommitSHA != shas[1] || rep.Conversations[0].SessionID != "session-1" {
+			t.Errorf("conversations = %+v, want session-1 on %s", rep.Conversations, shas[1][:7])
+		}
+	})
+
+	t.Run("exports Markdown", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/api/releases/report?from=v1.0.0&to=v1.1.0&format=markdown", nil)
+		w := httptest.NewRecorder()
+		srv.mux.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("status: want 200, got %d: %s", w.Code, w.Body.String())
+		}
+		if got := w.Header().Get("Content-Disposition"); got != `attachment; filename="release-v1.1.0.md"` {
+			t.Errorf("Content-Disposition = %q", got)
+		}
+		if body := w.Body.String(); !strings.HasPrefix(body, "# Release report: v1.0.0 → v1.1.0") || !strings.Contains(body, shas[1][:7]) {
+			t.Errorf("report = %q", body)
+		}
+	})
+
+	for name, tc := range map[string]struct {
+		query string
+		code  int
+	}{
+		"missing to":     {"", http.StatusBadRequest},
+		"unknown tag":    {"to=v9.9.9", http.StatusNotFound},
+		"not a tag":      {"to=master", http.StatusNotFound},
+		"unknown format": {"to=v1.1.0&format=csv", http.StatusBadRequest},
+	} {
+		t.Run(name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/api/releases/report?"+tc.query, nil)
+			w := httptest.NewRecorder()
+			srv.mux.ServeHTTP(w, req)
+			if w.Code != tc.code {
+				t.Errorf("status: want %d, got %d", tc.code, w.Code)
+			}
+		})
+	}
+}
+
+func TestHTMLContainsReleases(t *testing.T) {
+	repo := newTestRepo(t)
+	srv := NewServer(0, repo.path)
+
+	req := httptest.NewRequest("GET", "/", nil)
+	w := httptest.NewRecorder()
+	srv.mux.ServeHTTP(w, req)
+
+	body := w.Body.String()
+	for _, elem := range []string{`id="release-select"`, "/api/tags", "/api/releases/report?", "function renderGitTags("} {
+		if !strings.Contains(body, elem) {
+			t.Errorf("index.html missing release element: %s", elem)
+		}
+	}
+}
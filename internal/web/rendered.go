@@ -0,0 +1,62 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/re-cinq/shift-log/internal/git"
+	"github.com/re-cinq/shift-log/internal/render"
+)
+
+// RenderedConversation is a commit's conversation rendered as HTML by the
+// render package, one entry at a time.
+type RenderedConversation struct {
+	SHA             string                 `json:"sha"`
+	Index           int                    `json:"index"`
+	IsIncremental   bool                   `json:"is_incremental"`
+	ParentCommitSHA string                 `json:"parent_commit_sha,omitempty"`
+	Offset          int                    `json:"offset"`        // index of the page's first transcript entry
+	TotalEntries    int                    `json:"total_entries"` // transcript entries on all pages
+	Entries         []render.RenderedEntry `json:"entries"`
+}
+
+// handleRendered returns the HTML of a commit's conversation,
+// /api/commits/<sha>/rendered, taking the query parameters of the
+// conversation itself, including ?offset= and ?limit=, which page the
+// transcript entries. Entries that render to nothing are left out, so a
+// page can hold fewer entries than its limit.
+func (s *Server) handleRendered(w http.ResponseWriter, r *http.Request, ref string) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	fullSHA, err := git.ResolveRef(ref)
+	if err != nil {
+		http.Error(w, "Invalid commit reference", http.StatusBadRequest)
+		return
+	}
+	stored, index, _ := getConversationOrWriteError(w, r, fullSHA)
+	if stored == nil {
+		return
+	}
+	transcript, err := stored.ParseTranscript()
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "failed to parse transcript")
+		return
+	}
+
+	page := conversationEntries(r, fullSHA, stored, index, transcript)
+	response := RenderedConversation{
+		SHA:             fullSHA,
+		Index:           index,
+		IsIncremental:   page.IsIncremental,
+		ParentCommitSHA: page.ParentSHA,
+		Offset:          page.Offset,
+		TotalEntries:    page.Total,
+		Entries:         render.Entries(page.Entries),
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,144 @@
+package web
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHandleRendered(t *testing.T) {
+	repo := newTestRepo(t)
+	chdir(t, repo.path)
+
+	transcript := marshalTranscript([]map[string]interface{}{
+		{
+			"uuid": "user-1", "type": "user",
+			"message": map[string]interface{}{
+				"role":    "user",
+				"content": "Why does **this** fail? <script>alert(1)</script>",
+			},
+		},
+		{
+			"uuid": "assistant-1", "parentUuid": "user-1", "type": "assistant",
+			"message": map[string]interface{}{
+				"role": "assistant",
+				"content": []map[string]interface{}{
+					{"type": "text", "text": "Return early:\n\n```go\nreturn nil\n```"},
+				},
+			},
+		},
+		{"uuid": "summary-1", "type": "summary"},
+	})
+	repo.writeFile("a.txt", "a")
+	sha := repo.commit("Fix the failure")
+	repo.addConversation(sha, "session-1", transcript, 2)
+	srv := NewServer(0, repo.path)
+
+	req := httptest.NewRequest("GET", "/api/commits/"+sha+"/rendered", nil)
+	w := httptest.NewRecorder()
+	srv.mux.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200: %s", w.Code, w.Body.String())
+	}
+
+	var resp RenderedConversation
+	decodeJSON(t, w, &resp)
+	if resp.SHA != sha || len(resp.Entries) != 2 {
+		t.Fatalf("response = %+v, want two entries of %s", resp, sha)
+	}
+	if e := resp.Entries[0]; e.UUID != "user-1" || !strings.Contains(e.HTML, "<strong>this</strong>") ||
+		strings.Contains(e.HTML, "<script>") {
+		t.Errorf("user entry = %+v, want rendered Markdown with the script escaped", e)
+	}
+	if e := resp.Entries[1]; !strings.Contains(e.HTML, `<code class="language-go"><span class="hl-keyword">return</span>`) {
+		t.Errorf("assistant entry = %+v, want a highlighted code block", e)
+	}
+
+	req = httptest.NewRequest("GET", "/api/commits/"+sha+"/rendered?conversation=3", nil)
+	w = httptest.NewRecorder()
+	srv.mux.ServeHTTP(w, req)
+	if w.Code == http.StatusOK {
+		t.Error("unknown conversation: want an error status")
+	}
+}
+
+func TestConversationPaging(t *testing.T) {
+	repo := newTestRepo(t)
+	chdir(t, repo.path)
+
+	var entries []map[string]interface{}
+	for i := 0; i < 10; i++ {
+		entries = append(entries, map[string]interface{}{
+			"uuid": fmt.Sprintf("entry-%d", i), "type": "user",
+			"message": map[string]interface{}{"role": "user", "content": fmt.Sprintf("Message %d", i)},
+		})
+	}
+	repo.writeFile("a.txt", "a")
+	sha := repo.commit("Long conversation")
+	repo.addConversation(sha, "session-1", marshalTranscript(entries), 10)
+	srv := NewServer(0, repo.path)
+
+	get := func(url string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", url, nil)
+		w := httptest.NewRecorder()
+		srv.mux.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("rendered page", func(t *testing.T) {
+		var resp RenderedConversation
+		decodeJSON(t, get("/api/commits/"+sha+"/rendered?offset=4&limit=3"), &resp)
+		if resp.Offset != 4 || resp.TotalEntries != 10 || len(resp.Entries) != 3 {
+			t.Fatalf("page = offset %d of %d with %d entries, want offset 4 of 10 with 3", resp.Offset, resp.TotalEntries, len(resp.Entries))
+		}
+		if resp.Entries[0].UUID != "entry-4" || resp.Entries[2].UUID != "entry-6" {
+			t.Errorf("entries = %s..%s, want entry-4..entry-6", resp.Entries[0].UUID, resp.Entries[2].UUID)
+		}
+	})
+
+	t.Run("page past the end", func(t *testing.T) {
+		var resp RenderedConversation
+		decodeJSON(t, get("/api/commits/"+sha+"/rendered?offset=8&limit=5"), &resp)
+		if len(resp.Entries) != 2 {
+			t.Errorf("got %d entries, want the last 2", len(resp.Entries))
+		}
+		decodeJSON(t, get("/api/commits/"+sha+"/rendered?offset=50"), &resp)
+		if resp.Offset != 10 || len(resp.Entries) != 0 {
+			t.Errorf("page = offset %d with %d entries, want offset 10 with none", resp.Offset, len(resp.Entries))
+		}
+	})
+
+	t.Run("conversation metadata without entries", func(t *testing.T) {
+		var resp ConversationResponse
+		decodeJSON(t, get("/api/commits/"+sha+"?limit=0"), &resp)
+		if len(resp.Transcript) != 0 || resp.TotalEntries != 10 || resp.DisplayedCount != 10 {
+			t.Errorf("response = %d entries of %d, %d displayed; want 0 of 10, 10 displayed", len(resp.Transcript), resp.TotalEntries, resp.DisplayedCount)
+		}
+	})
+
+	t.Run("no limit returns every entry", func(t *testing.T) {
+		var resp ConversationResponse
+		decodeJSON(t, get("/api/commits/"+sha), &resp)
+		if len(resp.Transcript) != 10 || resp.Offset != 0 {
+			t.Errorf("got %d entries from offset %d, want 10 from 0", len(resp.Transcript), resp.Offset)
+		}
+	})
+}
+
+func TestHTMLContainsWindowedTranscript(t *testing.T) {
+	repo := newTestRepo(t)
+	srv := NewServer(0, repo.path)
+
+	req := httptest.NewRequest("GET", "/", nil)
+	w := httptest.NewRecorder()
+	srv.mux.ServeHTTP(w, req)
+
+	body := w.Body.String()
+	for _, elem := range []string{"function renderWindow(", "function loadMoreEntries(", "ENTRY_PAGE_SIZE", `id="virtual-window"`, "displayed_count"} {
+		if !strings.Contains(body, elem) {
+			t.Errorf("index.html missing windowed transcript element: %s", elem)
+		}
+	}
+}
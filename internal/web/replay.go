@@ -0,0 +1,119 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/re-cinq/shift-log/internal/agent"
+	"github.com/re-cinq/shift-log/internal/git"
+	"github.com/re-cinq/shift-log/internal/render"
+	"github.com/re-cinq/shift-log/internal/storage"
+	"github.com/re-cinq/shift-log/internal/util"
+)
+
+// replayPatchMaxBytes caps the diff of a file sent for replay.
+const replayPatchMaxBytes = 64 << 10
+
+// ReplayEntry is a rendered transcript entry with what the replay needs
+// to play it back: when it happened, and the files its tool calls edited.
+type ReplayEntry struct {
+	UUID      string   `json:"uuid,omitempty"`
+	HTML      string   `json:"html"`
+	Timestamp string   `json:"timestamp,omitempty"` // RFC3339 in UTC, empty when unknown
+	Files     []string `json:"files,omitempty"`     // repo-relative
+}
+
+// ReplayFile is the diff of a file changed by the commit.
+type ReplayFile struct {
+	Path      string `json:"path"`
+	Patch     string `json:"patch"`
+	Truncated bool   `json:"truncated,omitempty"`
+}
+
+// ReplayConversation is a commit's conversation prepared for replay, with
+// the commit's diff.
+type ReplayConversation struct {
+	SHA             string        `json:"sha"`
+	Index           int           `json:"index"`
+	IsIncremental   bool          `json:"is_incremental"`
+	ParentCommitSHA string        `json:"parent_commit_sha,omitempty"`
+	Entries         []ReplayEntry `json:"entries"`
+	Files           []ReplayFile  `json:"files"`
+}
+
+// handleReplay returns every entry of a commit's conversation rendered as
+// HTML, with its timestamp and edited files, and the diff of the files the
+// commit changed, /api/commits/<sha>/replay, for the viewer to play the
+// conversation back. It takes the query parameters of the conversation.
+func (s *Server) handleReplay(w http.ResponseWriter, r *http.Request, ref string) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	fullSHA, err := git.ResolveRef(ref)
+	if err != nil {
+		http.Error(w, "Invalid commit reference", http.StatusBadRequest)
+		return
+	}
+	stored, index, _ := getConversationOrWriteError(w, r, fullSHA)
+	if stored == nil {
+		return
+	}
+	transcript, err := stored.ParseTranscript()
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "failed to parse transcript")
+		return
+	}
+	patches, err := git.FilePatches(fullSHA)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "failed to read the commit's diff")
+		return
+	}
+
+	// Every entry is played, whatever page the viewer shows
+	query := r.URL.Query()
+	query.Del("offset")
+	query.Del("limit")
+	r.URL.RawQuery = query.Encode()
+	page := conversationEntries(r, fullSHA, stored, index, transcript)
+
+	response := ReplayConversation{
+		SHA:             fullSHA,
+		Index:           index,
+		IsIncremental:   page.IsIncremental,
+		ParentCommitSHA: page.ParentSHA,
+		Entries:         replayEntries(page.Entries, stored.ToolAliases(), stored.ProjectPath),
+		Files:           make([]ReplayFile, len(patches)),
+	}
+	for i, p := range patches {
+		response.Files[i] = ReplayFile{Path: p.Path, Patch: p.Patch}
+		if len(p.Patch) > replayPatchMaxBytes {
+			response.Files[i].Patch = p.Patch[:replayPatchMaxBytes]
+			response.Files[i].Truncated = true
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(response)
+}
+
+// replayEntries renders entries as render.Entries does, leaving out those
+// that render to nothing, with their timestamps normalized across agents
+// and the files their tool calls edited relative to repoRoot.
+func replayEntries(entries []agent.TranscriptEntry, aliases map[string]string, repoRoot string) []ReplayEntry {
+	replay := []ReplayEntry{}
+	for i := range entries {
+		html := render.Entry(&entries[i])
+		if html == "" {
+			continue
+		}
+		replay = append(replay, ReplayEntry{
+			UUID:      entries[i].UUID,
+			HTML:      html,
+			Timestamp: util.NormalizeEntryTime(entries[i].Timestamp),
+			Files:     storage.FilesTouched(entries[i:i+1], aliases, repoRoot),
+		})
+	}
+	return replay
+}
@@ -0,0 +1,65 @@
+package web
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestHandleReplay(t *testing.T) {
+	repo := newTestRepo(t)
+	chdir(t, repo.path)
+
+	transcript := marshalTranscript([]map[string]interface{}{
+		{
+			"uuid": "user-1", "type": "user", "timestamp": "2025-03-01T10:00:00+01:00",
+			"message": map[string]interface{}{"role": "user", "content": "Rename hello"},
+		},
+		{
+			"uuid": "assistant-1", "parentUuid": "user-1", "type": "assistant", "timestamp": "2025-03-01T09:00:04.5Z",
+			"message": map[string]interface{}{
+				"role": "assistant",
+				"content": []map[string]interface{}{
+					{"type": "tool_use", "id": "t1", "name": "Edit", "input": map[string]interface{}{
+						"file_path": filepath.Join(repo.path, "main.go"), "old_string": "hello", "new_string": "greet",
+					}},
+				},
+			},
+		},
+		{"uuid": "summary-1", "type": "summary"},
+	})
+	repo.writeFile("main.go", "func greet() {}\n")
+	repo.writeFile("README.md", "# Greeter\n")
+	sha := repo.commit("Rename hello")
+	repo.addConversation(sha, "session-1", transcript, 2)
+	srv := NewServer(0, repo.path)
+
+	req := httptest.NewRequest("GET", "/api/commits/"+sha+"/replay?limit=1", nil)
+	w := httptest.NewRecorder()
+	srv.mux.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200: %s", w.Code, w.Body.String())
+	}
+
+	var resp ReplayConversation
+	decodeJSON(t, w, &resp)
+	if len(resp.Entries) != 2 {
+		t.Fatalf("entries = %+v, want both rendered entries despite the limit", resp.Entries)
+	}
+	if resp.Entries[0].Timestamp != "2025-03-01T09:00:00.000Z" || resp.Entries[1].Timestamp != "2025-03-01T09:00:04.500Z" {
+		t.Errorf("timestamps = %q, %q, want them in UTC", resp.Entries[0].Timestamp, resp.Entries[1].Timestamp)
+	}
+	if files := resp.Entries[1].Files; len(files) != 1 || files[0] != "main.go" {
+		t.Errorf("edited files = %v, want main.go", files)
+	}
+	if len(resp.Files) != 2 {
+		t.Fatalf("files = %+v, want the commit's two files", resp.Files)
+	}
+	for _, f := range resp.Files {
+		if f.Path == "main.go" && !strings.Contains(f.Patch, "+func greet() {}") {
+			t.Errorf("main.go patch = %q", f.Patch)
+		}
+	}
+}
@@ -1,31 +1,77 @@
 package web
 
 import (
+	"context"
 	"embed"
+	"errors"
 	"fmt"
 	"io/fs"
+	"log/slog"
+	"net"
 	"net/http"
+	"os"
 	"os/exec"
+	"os/signal"
 	"runtime"
+	"strconv"
+	"syscall"
+	"time"
 )
 
 //go:embed static
 var staticFiles embed.FS
 
+// Timeouts of the HTTP server. Reading a request may take a while for the
+// transcripts posted to /api/conversations; writing a response, for a
+// conversation of tens of megabytes. The event stream has no write
+// deadline: it stays open for as long as the UI does.
+const (
+	readHeaderTimeout = 10 * time.Second
+	readTimeout       = time.Minute
+	writeTimeout      = 5 * time.Minute
+	idleTimeout       = 2 * time.Minute
+)
+
+// shutdownTimeout bounds how long a stopping server waits for the requests
+// in progress, such as a resume writing notes, to finish.
+const shutdownTimeout = 30 * time.Second
+
 // Server represents the shiftlog web server
 type Server struct {
 	port    int
+	host    string
 	repoDir string
 	mux     *http.ServeMux
+	// resumeDisabled, when set, is why sessions cannot be resumed from
+	// this server.
+	resumeDisabled string
+	// apiToken authorizes POST /api/conversations, which is disabled while
+	// it is empty.
+	apiToken          string
+	storeConversation ConversationStorer
+	// accessLog, when set, logs every request served.
+	accessLog *slog.Logger
+	// started is when the server was created, which the ETags depend on:
+	// another version of the server may respond differently.
+	started time.Time
+
+	// shuttingDown is closed when the server starts shutting down, ending
+	// the event streams, which would otherwise hold the shutdown up.
+	shuttingDown chan struct{}
 }
 
 // NewServer creates a new web server instance
 func NewServer(port int, repoDir string) *Server {
 	s := &Server{
 		port:    port,
+		host:    "127.0.0.1",
 		repoDir: repoDir,
 		mux:     http.NewServeMux(),
+
+		shuttingDown: make(chan struct{}),
+		started:      time.Now(),
 	}
+	installMetricsHooks()
 	s.setupRoutes()
 	return s
 }
@@ -36,21 +82,87 @@ func (s *Server) setupRoutes() {
 	s.mux.Handle("/", http.FileServer(http.FS(staticFS)))
 
 	// API endpoints
-	s.mux.HandleFunc("/api/commits", s.handleCommits)
-	s.mux.HandleFunc("/api/commits/", s.handleCommitDetail)
-	s.mux.HandleFunc("/api/graph", s.handleGraph)
+	s.mux.HandleFunc("/api/commits", s.cached(s.handleCommits))
+	s.mux.HandleFunc("/api/commits/", s.cached(s.handleCommitDetail))
+	s.mux.HandleFunc("/api/graph", s.cached(s.handleGraph))
 	s.mux.HandleFunc("/api/resume/", s.handleResume)
-	s.mux.HandleFunc("/api/branches", s.handleBranches)
-	s.mux.HandleFunc("/api/graph/branches", s.handleBranchGraph)
+	s.mux.HandleFunc("/api/branches", s.cached(limit(expensiveLimits, s.handleBranches)))
+	s.mux.HandleFunc("/api/branches/compare", s.cached(limit(expensiveLimits, s.handleBranchCompare)))
+	s.mux.HandleFunc("/api/graph/branches", s.cached(limit(expensiveLimits, s.handleBranchGraph)))
+	s.mux.HandleFunc("/api/files/", s.cached(limit(expensiveLimits, s.handleFileConversations)))
+	s.mux.HandleFunc("/api/settings", s.handleSettings)
+	s.mux.HandleFunc("/api/stats", s.cached(limit(expensiveLimits, s.handleStats)))
+	s.mux.HandleFunc("/api/stats/authorship", s.cached(limit(expensiveLimits, s.handleAuthorshipReport)))
+	s.mux.HandleFunc("/api/stats/turns", s.cached(limit(expensiveLimits, s.handleExpensiveTurns)))
+	s.mux.HandleFunc("/api/stats/tools", s.cached(limit(expensiveLimits, s.handleToolUsage)))
+	s.mux.HandleFunc("/api/events", s.handleEvents)
+	s.mux.HandleFunc("/api/conversations", s.handleConversations)
+	s.mux.HandleFunc("/api/conversations/diff", s.cached(s.handleConversationDiff))
+	s.mux.HandleFunc("/api/tags", s.cached(s.handleTags))
+	s.mux.HandleFunc("/api/issues/", s.cached(s.handleIssue))
+	s.mux.HandleFunc("/api/context", s.handleContext)
+	s.mux.HandleFunc("/api/releases/report", s.cached(limit(expensiveLimits, s.handleReleaseReport)))
+	s.mux.HandleFunc("/badge.svg", s.cached(s.handleBadge))
+	s.mux.HandleFunc("/metrics", s.handleMetrics)
+
+	// The gRPC API, over HTTP/2
+	s.mux.Handle(grpcPath, newGRPCServer(s))
+}
+
+// SetHost sets the address the server listens on, 127.0.0.1 by default.
+func (s *Server) SetHost(host string) { s.host = host }
+
+// remoteWriteDenied is why a request may not change the repository: the
+// server listens beyond loopback and the request lacks the API token.
+const remoteWriteDenied = "Changing the repository needs the API token: the server listens beyond localhost"
+
+// loopback reports whether the server listens on a loopback address only,
+// where the browser on the same machine is the only client.
+func (s *Server) loopback() bool {
+	if s.host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(s.host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// mayWrite reports whether r may change the repository, by resuming a
+// session or adding an annotation. Listening beyond loopback, anyone who can
+// reach the server could otherwise check out commits and launch the agent,
+// so only requests carrying the API token may.
+func (s *Server) mayWrite(r *http.Request) bool {
+	return s.loopback() || (s.apiToken != "" && s.authorized(r))
 }
 
-// Handler returns the HTTP handler for the server.
-func (s *Server) Handler() http.Handler { return s.mux }
+// DisableResume turns off resuming sessions, e.g. in a bare repository,
+// which has no working tree to resume in. reason is reported to the UI.
+func (s *Server) DisableResume(reason string) { s.resumeDisabled = reason }
 
-// Start starts the web server
+// SetAccessLog logs every request the server serves to logger.
+func (s *Server) SetAccessLog(logger *slog.Logger) { s.accessLog = logger }
+
+// Handler returns the HTTP handler for the server, counting its requests
+// in the metrics and logging them to the access log.
+func (s *Server) Handler() http.Handler { return s.instrument(s.mux) }
+
+// serverProtocols returns the protocols the server speaks: HTTP/1 and, for
+// gRPC clients, HTTP/2 without TLS.
+func serverProtocols() *http.Protocols {
+	protocols := new(http.Protocols)
+	protocols.SetHTTP1(true)
+	protocols.SetUnencryptedHTTP2(true)
+	return protocols
+}
+
+// Start starts the web server and runs it until SIGINT or SIGTERM, then
+// shuts it down gracefully.
 func (s *Server) Start(openBrowser bool) error {
-	addr := fmt.Sprintf("127.0.0.1:%d", s.port)
-	url := fmt.Sprintf("http://%s", addr)
+	addr := net.JoinHostPort(s.host, strconv.Itoa(s.port))
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	url := fmt.Sprintf("http://%s", ln.Addr())
 
 	fmt.Printf("Starting server at %s\n", url)
 	fmt.Println("Press Ctrl+C to stop")
@@ -59,7 +171,45 @@ func (s *Server) Start(openBrowser bool) error {
 		go openURL(url) //nolint:errcheck // Fire and forget
 	}
 
-	return http.ListenAndServe(addr, s.mux)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	// A second Ctrl+C stops at once
+	context.AfterFunc(ctx, stop)
+	return s.serve(ctx, ln)
+}
+
+// serve serves HTTP on ln until ctx is done, then stops accepting
+// connections and waits up to shutdownTimeout for the requests in progress.
+func (s *Server) serve(ctx context.Context, ln net.Listener) error {
+	srv := &http.Server{
+		Handler:           s.Handler(),
+		ReadHeaderTimeout: readHeaderTimeout,
+		ReadTimeout:       readTimeout,
+		WriteTimeout:      writeTimeout,
+		IdleTimeout:       idleTimeout,
+		Protocols:         serverProtocols(),
+	}
+	srv.RegisterOnShutdown(func() { close(s.shuttingDown) })
+
+	served := make(chan error, 1)
+	go func() { served <- srv.Serve(ln) }()
+	select {
+	case err := <-served:
+		return err
+	case <-ctx.Done():
+	}
+
+	fmt.Println("Shutting down: waiting for requests in progress")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		return fmt.Errorf("could not shut down gracefully: %w", err)
+	}
+	if err := <-served; err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	fmt.Println("Server stopped")
+	return nil
 }
 
 // openURL opens the given URL in the default browser
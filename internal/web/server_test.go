@@ -0,0 +1,84 @@
+package web
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestServeShutsDownGracefully(t *testing.T) {
+	repo := newTestRepo(t)
+	chdir(t, repo.path)
+	repo.writeFile("a.txt", "a")
+	repo.commit("First commit")
+
+	srv := NewServer(0, repo.path)
+	started, release := make(chan struct{}), make(chan struct{})
+	srv.mux.HandleFunc("/slow", func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+		_, _ = io.WriteString(w, "done")
+	})
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	served := make(chan error, 1)
+	go func() { served <- srv.serve(ctx, ln) }()
+	url := "http://" + ln.Addr().String()
+
+	// An open event stream does not hold the shutdown up
+	events, err := http.Get(url + "/api/events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer events.Body.Close()
+
+	type result struct {
+		body string
+		err  error
+	}
+	slow := make(chan result, 1)
+	go func() {
+		resp, err := http.Get(url + "/slow")
+		if err != nil {
+			slow <- result{err: err}
+			return
+		}
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		slow <- result{string(body), err}
+	}()
+	<-started
+
+	cancel()
+	select {
+	case err := <-served:
+		t.Fatalf("serve returned %v with a request in progress", err)
+	case <-time.After(100 * time.Millisecond):
+	}
+	if _, err := http.Get(url + "/api/settings"); err == nil {
+		t.Error("the stopping server accepted a new connection")
+	}
+
+	close(release)
+	if r := <-slow; r.err != nil || r.body != "done" {
+		t.Errorf("request in progress: got %q, %v", r.body, r.err)
+	}
+	select {
+	case err := <-served:
+		if err != nil {
+			t.Errorf("serve() error: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("serve did not return after the request finished")
+	}
+	if _, err := io.ReadAll(events.Body); err != nil {
+		t.Errorf("event stream did not end cleanly: %v", err)
+	}
+}
@@ -0,0 +1,110 @@
+package web
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/re-cinq/shift-log/internal/config"
+	"github.com/re-cinq/shift-log/internal/git"
+	"github.com/re-cinq/shift-log/internal/render"
+	"github.com/re-cinq/shift-log/internal/storage"
+	"github.com/re-cinq/shift-log/internal/util"
+)
+
+// handleSnapshot returns one of a commit's conversations as a standalone
+// HTML page, /api/commits/<sha>/snapshot, rendered like shiftlog export
+// --format html with the commit's metadata above it. Tool results are
+// whole and attachments embedded, so the page needs no server, e.g. to be
+// attached to an incident ticket. It is served as a download and takes
+// ?conversation= and ?incremental=true like the conversation itself.
+func (s *Server) handleSnapshot(w http.ResponseWriter, r *http.Request, ref string) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	fullSHA, err := git.ResolveRef(ref)
+	if err != nil {
+		http.Error(w, "Invalid commit reference", http.StatusBadRequest)
+		return
+	}
+	stored, _, _ := getConversationOrWriteError(w, r, fullSHA)
+	if stored == nil {
+		return
+	}
+	transcript, err := stored.ParseTranscript()
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "failed to parse transcript")
+		return
+	}
+	details, err := git.GetCommitDetails(fullSHA)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "failed to read commit")
+		return
+	}
+
+	entries := transcript.Entries
+	since := ""
+	if r.URL.Query().Get("incremental") == "true" {
+		parentSHA, lastEntryUUID := storage.FindParentConversationBoundary(fullSHA, stored.SessionID)
+		if lastEntryUUID != "" {
+			entries = transcript.GetEntriesSince(lastEntryUUID)
+			since = parentSHA
+		}
+	}
+
+	var body strings.Builder
+	body.WriteString(snapshotHeader(fullSHA, details, stored, since))
+	body.WriteString(render.Transcript(entries))
+	title := fmt.Sprintf("Conversation for %s: %s", fullSHA[:7], details.Subject)
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="shiftlog-%s.html"`, fullSHA[:7]))
+	_, _ = fmt.Fprint(w, render.Document(title, body.String()))
+}
+
+// snapshotHeader renders the commit and conversation a snapshot shows,
+// with the commit the conversation is shown since, if any.
+func snapshotHeader(sha string, details *git.CommitDetails, stored *storage.StoredConversation, since string) string {
+	date := details.Date
+	if t, err := util.ParseTimestamp(date); err == nil {
+		loc := time.UTC
+		if cfg, err := config.Read(); err == nil {
+			if l, err := cfg.ExportLocation(); err == nil {
+				loc = l
+			}
+		}
+		date = t.In(loc).Format("2006-01-02 15:04 MST")
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "<h1>%s</h1>\n", render.EscapeHTML(details.Subject))
+	if details.Body != "" {
+		fmt.Fprintf(&b, "<pre>%s</pre>\n", render.EscapeHTML(details.Body))
+	}
+	fmt.Fprintf(&b, "<p>Commit <code>%s</code> by %s on %s</p>\n",
+		sha, render.EscapeHTML(details.Author), render.EscapeHTML(date))
+
+	about := []string{render.EscapeHTML(stored.AgentName())}
+	if stored.Model != "" {
+		about = append(about, render.EscapeHTML(stored.Model))
+	}
+	if stored.GitBranch != "" {
+		about = append(about, "branch "+render.EscapeHTML(stored.GitBranch))
+	}
+	about = append(about, fmt.Sprintf("%d messages", stored.MessageCount))
+	fmt.Fprintf(&b, "<p>Session <code>%s</code>: %s</p>\n", render.EscapeHTML(stored.SessionID), strings.Join(about, ", "))
+	if stored.IsPrivate() {
+		b.WriteString("<p>Private: not shared with the repository</p>\n")
+	}
+	if since != "" {
+		fmt.Fprintf(&b, "<p>Showing the messages since commit <code>%s</code></p>\n", since[:7])
+	}
+	if stored.Summary != "" {
+		fmt.Fprintf(&b, "<p>%s</p>\n", render.EscapeHTML(stored.Summary))
+	}
+	b.WriteString("<hr>\n")
+	return b.String()
+}
@@ -0,0 +1,68 @@
+package web
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHandleSnapshot(t *testing.T) {
+	repo := newTestRepo(t)
+	chdir(t, repo.path)
+
+	transcript := marshalTranscript([]map[string]interface{}{
+		{
+			"uuid": "user-1", "type": "user",
+			"message": map[string]interface{}{"role": "user", "content": "Why is the <queue> stuck?"},
+		},
+		{
+			"uuid": "assistant-1", "parentUuid": "user-1", "type": "assistant",
+			"message": map[string]interface{}{
+				"role":    "assistant",
+				"content": []map[string]interface{}{{"type": "text", "text": "The worker **deadlocks**."}},
+			},
+		},
+	})
+	repo.writeFile("a.txt", "a")
+	sha := repo.commit("Fix the stuck queue")
+	repo.addConversation(sha, "session-1", transcript, 2)
+	srv := NewServer(0, repo.path)
+
+	req := httptest.NewRequest("GET", "/api/commits/"+sha+"/snapshot", nil)
+	w := httptest.NewRecorder()
+	srv.mux.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200: %s", w.Code, w.Body.String())
+	}
+	if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/html") {
+		t.Errorf("Content-Type = %q, want HTML", ct)
+	}
+	if cd := w.Header().Get("Content-Disposition"); !strings.Contains(cd, "shiftlog-"+sha[:7]+".html") {
+		t.Errorf("Content-Disposition = %q, want a download named after the commit", cd)
+	}
+	page := w.Body.String()
+	for _, want := range []string{
+		"<!DOCTYPE html>",
+		"<style>",
+		"<h1>Fix the stuck queue</h1>",
+		"<code>" + sha + "</code>",
+		"Session <code>session-1</code>",
+		"Why is the &lt;queue&gt; stuck?",
+		"<strong>deadlocks</strong>",
+	} {
+		if !strings.Contains(page, want) {
+			t.Errorf("snapshot missing %q", want)
+		}
+	}
+	if strings.Contains(page, "/api/") {
+		t.Error("snapshot should not refer to the server")
+	}
+
+	req = httptest.NewRequest("GET", "/api/commits/"+sha+"/snapshot?conversation=2", nil)
+	w = httptest.NewRecorder()
+	srv.mux.ServeHTTP(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("unknown conversation: status = %d, want 404", w.Code)
+	}
+}
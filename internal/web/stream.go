@@ -0,0 +1,97 @@
+package web
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/re-cinq/shift-log/internal/agent"
+)
+
+// ndjsonContentType is newline-delimited JSON, which a client asks for with
+// its Accept header to read a conversation an entry per line.
+const ndjsonContentType = "application/x-ndjson"
+
+// streamFlushEntries is how many transcript entries are written between
+// flushes, so that the client starts reading a long transcript before the
+// server is done writing it.
+const streamFlushEntries = 64
+
+// transcriptField is the transcript of a ConversationResponse marshaled
+// without entries. A JSON string holds its quotes escaped, so only the
+// field itself matches.
+var transcriptField = []byte(`"transcript":null`)
+
+// writeConversation writes response as JSON without marshaling its
+// transcript at once: the entries are written one at a time, flushing as
+// they go, and writing stops when the client goes away. Clients that accept
+// application/x-ndjson get the response without its entries on the first
+// line, then an entry per line.
+func writeConversation(w http.ResponseWriter, r *http.Request, response ConversationResponse) {
+	entries := response.Transcript
+	ndjson := strings.Contains(r.Header.Get("Accept"), ndjsonContentType)
+	if ndjson {
+		response.Transcript = []agent.TranscriptEntry{}
+	} else {
+		response.Transcript = nil
+	}
+	head, err := json.Marshal(response)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "failed to encode conversation")
+		return
+	}
+
+	if ndjson {
+		w.Header().Set("Content-Type", ndjsonContentType)
+		_, _ = w.Write(append(head, '\n'))
+		if writeEntries(w, r, entries, []byte("\n")) && len(entries) > 0 {
+			_, _ = w.Write([]byte("\n"))
+		}
+		return
+	}
+
+	prefix, suffix, ok := bytes.Cut(head, transcriptField)
+	if !ok {
+		writeJSONError(w, http.StatusInternalServerError, "failed to encode conversation")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(prefix)
+	if entries == nil {
+		_, _ = w.Write(transcriptField)
+	} else {
+		_, _ = w.Write([]byte(`"transcript":[`))
+		if !writeEntries(w, r, entries, []byte(",")) {
+			return
+		}
+		_, _ = w.Write([]byte("]"))
+	}
+	_, _ = w.Write(append(suffix, '\n'))
+}
+
+// writeEntries writes entries as JSON, separated by sep, and reports
+// whether it wrote them all.
+func writeEntries(w http.ResponseWriter, r *http.Request, entries []agent.TranscriptEntry, sep []byte) bool {
+	rc := http.NewResponseController(w)
+	for i := range entries {
+		if i%streamFlushEntries == 0 && i > 0 {
+			if r.Context().Err() != nil {
+				return false
+			}
+			_ = rc.Flush()
+		}
+		data, err := json.Marshal(&entries[i])
+		if err != nil {
+			// Too late for an error status: the response ends short
+			return false
+		}
+		if i > 0 {
+			_, _ = w.Write(sep)
+		}
+		if _, err := w.Write(data); err != nil {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,90 @@
+package web
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/re-cinq/shift-log/internal/agent"
+)
+
+func streamedResponse(n int) ConversationResponse {
+	response := ConversationResponse{SHA: "abc123", SessionID: "session-1", Summary: `mentions "transcript":null`, TotalEntries: n}
+	for i := range n {
+		response.Transcript = append(response.Transcript, agent.TranscriptEntry{
+			Type: agent.MessageTypeUser,
+			UUID: fmt.Sprintf("entry-%d", i),
+			Message: &agent.Message{Role: "user", Content: []agent.ContentBlock{
+				{Type: "text", Text: fmt.Sprintf("message <%d>", i)},
+			}},
+		})
+	}
+	return response
+}
+
+func TestWriteConversation(t *testing.T) {
+	for _, n := range []int{0, 1, streamFlushEntries*2 + 5} {
+		response := streamedResponse(n)
+		var want bytes.Buffer
+		if err := json.NewEncoder(&want).Encode(response); err != nil {
+			t.Fatal(err)
+		}
+
+		w := httptest.NewRecorder()
+		writeConversation(w, httptest.NewRequest("GET", "/api/commits/abc123", nil), response)
+		if w.Body.String() != want.String() {
+			t.Errorf("%d entries: streamed\n%s\nwant\n%s", n, w.Body, &want)
+		}
+		if ct := w.Header().Get("Content-Type"); ct != "application/json" {
+			t.Errorf("Content-Type: want application/json, got %q", ct)
+		}
+	}
+}
+
+func TestWriteConversationNDJSON(t *testing.T) {
+	response := streamedResponse(3)
+	req := httptest.NewRequest("GET", "/api/commits/abc123", nil)
+	req.Header.Set("Accept", ndjsonContentType)
+	w := httptest.NewRecorder()
+	writeConversation(w, req, response)
+
+	if ct := w.Header().Get("Content-Type"); ct != ndjsonContentType {
+		t.Errorf("Content-Type: want %s, got %q", ndjsonContentType, ct)
+	}
+	lines := strings.Split(strings.TrimSuffix(w.Body.String(), "\n"), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("lines: want 4, got %d:\n%s", len(lines), w.Body)
+	}
+	var head ConversationResponse
+	if err := json.Unmarshal([]byte(lines[0]), &head); err != nil {
+		t.Fatal(err)
+	}
+	if head.SessionID != "session-1" || head.Transcript == nil || len(head.Transcript) != 0 {
+		t.Errorf("first line: want the response without entries, got %s", lines[0])
+	}
+	for i, line := range lines[1:] {
+		var entry agent.TranscriptEntry
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatal(err)
+		}
+		if entry.UUID != fmt.Sprintf("entry-%d", i) {
+			t.Errorf("line %d: want entry-%d, got %s", i+1, i, entry.UUID)
+		}
+	}
+}
+
+func TestWriteConversationClientGone(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	req := httptest.NewRequest("GET", "/api/commits/abc123", nil).WithContext(ctx)
+	w := httptest.NewRecorder()
+	writeConversation(w, req, streamedResponse(streamFlushEntries*3))
+
+	if strings.Contains(w.Body.String(), fmt.Sprintf("entry-%d", streamFlushEntries)) {
+		t.Error("entries were written after the client went away")
+	}
+}
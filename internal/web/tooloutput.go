@@ -0,0 +1,162 @@
+package web
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/re-cinq/shift-log/internal/agent"
+	"github.com/re-cinq/shift-log/internal/git"
+)
+
+const (
+	// toolResultMaxBytes is the size of tool result content above which
+	// conversation responses carry a preview instead of the full output.
+	toolResultMaxBytes = 16 << 10
+	// toolResultPreviewLines is the number of lines of a truncated tool
+	// result's preview, as many as the viewer shows.
+	toolResultPreviewLines = 20
+	// toolResultPreviewBytes caps the preview of outputs with long lines.
+	toolResultPreviewBytes = 4 << 10
+)
+
+// EntryContentResponse is the full content of a transcript entry.
+type EntryContentResponse struct {
+	UUID    string          `json:"uuid"`
+	Content json.RawMessage `json:"content"`
+}
+
+// handleEntryContent returns the full content of an entry of a commit's
+// conversation, /api/commits/<sha>/entries/<uuid>/content, for the viewer
+// to load tool results truncated in the conversation response. The
+// conversation is selected with ?conversation= like the conversation itself.
+func (s *Server) handleEntryContent(w http.ResponseWriter, r *http.Request, ref, uuid string) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	fullSHA, err := git.ResolveRef(ref)
+	if err != nil {
+		http.Error(w, "Invalid commit reference", http.StatusBadRequest)
+		return
+	}
+	stored, _, _ := getConversationOrWriteError(w, r, fullSHA)
+	if stored == nil {
+		return
+	}
+	transcript, err := stored.ParseTranscript()
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "failed to parse transcript")
+		return
+	}
+
+	i := transcript.FindEntryIndex(uuid)
+	if i < 0 || uuid == "" {
+		writeJSONError(w, http.StatusNotFound, "entry not found")
+		return
+	}
+	entry := &transcript.Entries[i]
+	referenceAttachments(entry, fullSHA)
+	response := EntryContentResponse{UUID: uuid, Content: json.RawMessage("null")}
+	if entry.Message != nil && len(entry.Message.RawContent) > 0 {
+		response.Content = entry.Message.RawContent
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(response)
+}
+
+// truncateToolResults replaces the content of the entry's tool results
+// larger than toolResultMaxBytes with a preview of their first lines,
+// marking them truncated and pointing content_url at the full output.
+// Images and other non-text blocks of the output are kept.
+func truncateToolResults(entry *agent.TranscriptEntry, sha string, conversation int) {
+	msg := entry.Message
+	if msg == nil || len(msg.RawContent) <= toolResultMaxBytes || entry.UUID == "" {
+		return
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(msg.RawContent))
+	dec.UseNumber()
+	var blocks []any
+	if err := dec.Decode(&blocks); err != nil {
+		return
+	}
+
+	changed := false
+	for _, b := range blocks {
+		block, _ := b.(map[string]any)
+		if block["type"] != "tool_result" {
+			continue
+		}
+		raw, err := json.Marshal(block["content"])
+		if err != nil || len(raw) <= toolResultMaxBytes {
+			continue
+		}
+
+		var text []string
+		var kept []any
+		switch content := block["content"].(type) {
+		case string:
+			text = append(text, content)
+		case []any:
+			for _, c := range content {
+				if cb, _ := c.(map[string]any); cb["type"] == "text" {
+					s, _ := cb["text"].(string)
+					text = append(text, s)
+				} else {
+					kept = append(kept, c)
+				}
+			}
+		default:
+			text = append(text, string(raw))
+		}
+		full := strings.Join(text, "\n")
+		preview := previewLines(full)
+		if kept != nil {
+			block["content"] = append([]any{map[string]any{"type": "text", "text": preview}}, kept...)
+		} else {
+			block["content"] = preview
+		}
+		block["truncated"] = true
+		block["content_lines"] = strings.Count(full, "\n") + 1
+		block["content_url"] = "/api/commits/" + sha + "/entries/" + entry.UUID + "/content?conversation=" + strconv.Itoa(conversation)
+		changed = true
+	}
+	if !changed {
+		return
+	}
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(blocks); err != nil {
+		return
+	}
+	truncated := *msg
+	truncated.RawContent = bytes.TrimSuffix(buf.Bytes(), []byte("\n"))
+	truncated.Content = nil
+	_ = json.Unmarshal(truncated.RawContent, &truncated.Content)
+	entry.Message = &truncated
+}
+
+// previewLines returns the first toolResultPreviewLines lines of text, at
+// most toolResultPreviewBytes of them.
+func previewLines(text string) string {
+	lines := strings.SplitN(text, "\n", toolResultPreviewLines+1)
+	if len(lines) > toolResultPreviewLines {
+		lines = lines[:toolResultPreviewLines]
+	}
+	preview := strings.Join(lines, "\n")
+	if len(preview) > toolResultPreviewBytes {
+		preview = preview[:toolResultPreviewBytes]
+		for !utf8.ValidString(preview) {
+			preview = preview[:len(preview)-1]
+		}
+	}
+	return preview
+}
@@ -0,0 +1,136 @@
+package web
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestHandleCommitDetailTruncatesToolOutput(t *testing.T) {
+	repo := newTestRepo(t)
+	chdir(t, repo.path)
+
+	lines := make([]string, 5000)
+	for i := range lines {
+		lines[i] = fmt.Sprintf("line %d", i+1)
+	}
+	output := strings.Join(lines, "\n")
+	transcript := marshalTranscript([]map[string]interface{}{
+		{
+			"uuid": "assistant-1", "type": "assistant",
+			"message": map[string]interface{}{
+				"role": "assistant",
+				"content": []map[string]interface{}{
+					{"type": "tool_use", "id": "tool-1", "name": "Bash", "input": map[string]string{"command": "make test"}},
+				},
+			},
+		},
+		{
+			"uuid": "result-1", "parentUuid": "assistant-1", "type": "user",
+			"message": map[string]interface{}{
+				"role": "user",
+				"content": []map[string]interface{}{
+					{"type": "tool_result", "tool_use_id": "tool-1", "content": output},
+				},
+			},
+		},
+	})
+	repo.writeFile("a.txt", "a")
+	sha := repo.commit("Run the tests")
+	repo.addConversation(sha, "session-1", transcript, 2)
+	srv := NewServer(0, repo.path)
+
+	t.Run("truncates large tool results", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/api/commits/"+sha, nil)
+		w := httptest.NewRecorder()
+		srv.mux.ServeHTTP(w, req)
+
+		var resp struct {
+			Transcript []struct {
+				Message struct {
+					Content []map[string]interface{} `json:"content"`
+				} `json:"message"`
+			} `json:"transcript"`
+		}
+		decodeJSON(t, w, &resp)
+		block := resp.Transcript[1].Message.Content[0]
+		if block["truncated"] != true || block["content_lines"] != float64(5000) {
+			t.Errorf("block = %v, want truncated with 5000 lines", block)
+		}
+		if want := "/api/commits/" + sha + "/entries/result-1/content?conversation=0"; block["content_url"] != want {
+			t.Errorf("content_url = %v, want %s", block["content_url"], want)
+		}
+		if preview, _ := block["content"].(string); preview != strings.Join(lines[:toolResultPreviewLines], "\n") {
+			t.Errorf("preview = %q", preview)
+		}
+	})
+
+	t.Run("full returns untruncated output", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/api/commits/"+sha+"?full=true", nil)
+		w := httptest.NewRecorder()
+		srv.mux.ServeHTTP(w, req)
+		if body := w.Body.String(); strings.Contains(body, "truncated") || !strings.Contains(body, "line 5000") {
+			t.Error("full conversation was truncated")
+		}
+	})
+
+	t.Run("serves the full entry content", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/api/commits/"+sha+"/entries/result-1/content?conversation=0", nil)
+		w := httptest.NewRecorder()
+		srv.mux.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("status: want 200, got %d: %s", w.Code, w.Body.String())
+		}
+
+		var resp EntryContentResponse
+		decodeJSON(t, w, &resp)
+		var blocks []struct {
+			Content string `json:"content"`
+		}
+		if err := json.Unmarshal(resp.Content, &blocks); err != nil {
+			t.Fatal(err)
+		}
+		if resp.UUID != "result-1" || len(blocks) != 1 || blocks[0].Content != output {
+			t.Errorf("entry content = %s", resp.Content)
+		}
+	})
+
+	t.Run("unknown entry", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/api/commits/"+sha+"/entries/missing/content", nil)
+		w := httptest.NewRecorder()
+		srv.mux.ServeHTTP(w, req)
+		if w.Code != http.StatusNotFound {
+			t.Errorf("status: want 404, got %d", w.Code)
+		}
+	})
+}
+
+func TestPreviewLines(t *testing.T) {
+	if got := previewLines("a\nb"); got != "a\nb" {
+		t.Errorf("short text = %q", got)
+	}
+	long := strings.Repeat("é", toolResultPreviewBytes)
+	if got := previewLines(long); len(got) > toolResultPreviewBytes || !utf8.ValidString(got) {
+		t.Errorf("long line preview is %d bytes, valid UTF-8: %v", len(got), utf8.ValidString(got))
+	}
+}
+
+func TestHTMLContainsToolOutputExpansion(t *testing.T) {
+	repo := newTestRepo(t)
+	srv := NewServer(0, repo.path)
+
+	req := httptest.NewRequest("GET", "/", nil)
+	w := httptest.NewRecorder()
+	srv.mux.ServeHTTP(w, req)
+
+	body := w.Body.String()
+	for _, elem := range []string{"function expandToolResult(", "dataset.url", "tool-result-expand"} {
+		if !strings.Contains(body, elem) {
+			t.Errorf("index.html missing tool output element: %s", elem)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package acceptance_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/re-cinq/shift-log/tests/acceptance/testutil"
+)
+
+// agentPlugin is a shiftlog-agent-<name> plugin whose transcripts already
+// are in the common format, one entry per line.
+const agentPlugin = `#!/bin/sh
+case "$1" in
+info)
+  echo '{"display_name":"Plug Agent","shell_tools":["run"]}' ;;
+parse-hook)
+  sed 's/"tool_input":{"command":\([^}]*\)}/"command":\1/' ;;
+parse-transcript)
+  printf '{"model":"plug-1","entries":['
+  sep=''
+  while IFS= read -r line; do printf '%s%s' "$sep" "$line"; sep=','; done
+  printf ']}' ;;
+discover-session)
+  echo null ;;
+resume-command)
+  echo '{"command":"echo","args":["resuming"]}' ;;
+*)
+  exit 1 ;;
+esac
+`
+
+var _ = Describe("Agent plugins", func() {
+	var repo *testutil.GitRepo
+	var env []string
+
+	BeforeEach(func() {
+		if runtime.GOOS == "windows" {
+			Skip("test plugin is a shell script")
+		}
+
+		var err error
+		repo, err = testutil.NewGitRepo()
+		Expect(err).NotTo(HaveOccurred())
+		repo.SetBinaryPath(testutil.BinaryPath())
+
+		Expect(repo.WriteFile("README.md", "# Test")).To(Succeed())
+		Expect(repo.Commit("Initial commit")).To(Succeed())
+
+		binDir := GinkgoT().TempDir()
+		Expect(os.WriteFile(filepath.Join(binDir, "shiftlog-agent-plug"), []byte(agentPlugin), 0755)).To(Succeed())
+		env = []string{"PATH=" + binDir + string(os.PathListSeparator) + os.Getenv("PATH")}
+	})
+
+	AfterEach(func() {
+		if repo != nil {
+			repo.Cleanup()
+		}
+	})
+
+	It("registers a plugin on PATH as an agent", func() {
+		stdout, _, err := testutil.RunShiftlogInDirWithEnv(repo.Path, env, "init", "--agent=plug")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stdout).To(ContainSubstring("Plug Agent"))
+	})
+
+	It("stores a conversation through the plugin", func() {
+		transcriptPath := filepath.Join(repo.Path, "plug.jsonl")
+		transcript := `{"uuid":"u1","type":"user","message":{"role":"user","content":"Commit it"}}` + "\n" +
+			`{"uuid":"a1","type":"assistant","message":{"role":"assistant","content":[{"type":"text","text":"Done."}]}}` + "\n"
+		Expect(os.WriteFile(transcriptPath, []byte(transcript), 0644)).To(Succeed())
+
+		hookInput := fmt.Sprintf(`{"session_id":"plug-1","transcript_path":%q,"tool_name":"run","tool_input":{"command":"git commit -m 'test'"}}`, transcriptPath)
+		_, _, err := testutil.RunShiftlogInDirWithEnvAndStdin(repo.Path, env, hookInput, "store", "--agent=plug")
+		Expect(err).NotTo(HaveOccurred())
+
+		head, err := repo.GetHead()
+		Expect(err).NotTo(HaveOccurred())
+		note, err := repo.GetNote("refs/notes/shiftlog", head)
+		Expect(err).NotTo(HaveOccurred())
+
+		var stored map[string]interface{}
+		Expect(json.Unmarshal([]byte(note), &stored)).To(Succeed())
+		Expect(stored["agent"]).To(Equal("plug"))
+		Expect(stored["session_id"]).To(Equal("plug-1"))
+		Expect(stored["model"]).To(Equal("plug-1"))
+	})
+
+	It("is unknown without the plugin on PATH", func() {
+		_, _, err := testutil.RunShiftlogInDir(repo.Path, "init", "--agent=plug")
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -0,0 +1,157 @@
+package acceptance_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/re-cinq/shift-log/tests/acceptance/testutil"
+)
+
+// sampleAmazonQConversation returns an Amazon Q CLI conversation in the form
+// it keeps in its database.
+func sampleAmazonQConversation(id string) string {
+	now := time.Now().Format(time.RFC3339Nano)
+	return fmt.Sprintf(`{"conversation_id":%q,"history":[`+
+		`{"user":{"content":{"Prompt":{"prompt":"Create a file called test.txt"}},"timestamp":%q},`+
+		`"assistant":{"ToolUse":{"message_id":"msg-1","content":"Creating it.","tool_uses":[{"id":"tooluse_1","name":"execute_bash","args":{"command":"echo test > test.txt"}}]}}},`+
+		`{"user":{"content":{"ToolUseResults":{"tool_use_results":[{"tool_use_id":"tooluse_1","content":[{"Text":""}],"status":"Success"}]}},"timestamp":%q},`+
+		`"assistant":{"Response":{"message_id":"msg-2","content":"Done."}}}],`+
+		`"model_info":{"model_id":"claude-sonnet-4"}}`, id, now, now)
+}
+
+var _ = Describe("Amazon Q CLI agent", func() {
+	var repo *testutil.GitRepo
+
+	BeforeEach(func() {
+		var err error
+		repo, err = testutil.NewGitRepo()
+		Expect(err).NotTo(HaveOccurred())
+		repo.SetBinaryPath(testutil.BinaryPath())
+
+		Expect(repo.WriteFile("README.md", "# Test")).To(Succeed())
+		Expect(repo.Commit("Initial commit")).To(Succeed())
+	})
+
+	AfterEach(func() {
+		if repo != nil {
+			repo.Cleanup()
+		}
+	})
+
+	It("configures and removes the shiftlog agent hook", func() {
+		_, _, err := testutil.RunShiftlogInDir(repo.Path, "init", "--agent=amazonq")
+		Expect(err).NotTo(HaveOccurred())
+
+		content, err := repo.ReadFile(".amazonq/cli-agents/shiftlog.json")
+		Expect(err).NotTo(HaveOccurred())
+
+		var cfg map[string]interface{}
+		Expect(json.Unmarshal([]byte(content), &cfg)).To(Succeed())
+		Expect(cfg["name"]).To(Equal("shiftlog"))
+		hooks := cfg["hooks"].(map[string]interface{})
+		postToolUse := hooks["postToolUse"].([]interface{})
+		Expect(postToolUse).To(HaveLen(1))
+		hook := postToolUse[0].(map[string]interface{})
+		Expect(hook["matcher"]).To(Equal("execute_bash"))
+		Expect(hook["command"]).To(Equal("shiftlog store --agent=amazonq"))
+
+		_, _, err = testutil.RunShiftlogInDir(repo.Path, "deinit")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(repo.FileExists(".amazonq/cli-agents/shiftlog.json")).To(BeFalse())
+	})
+
+	It("stores a conversation from a saved transcript", func() {
+		transcriptPath := filepath.Join(repo.Path, "conversation.json")
+		Expect(os.WriteFile(transcriptPath, []byte(sampleAmazonQConversation("conv-file")), 0644)).To(Succeed())
+
+		hookInput := fmt.Sprintf(`{"session_id":"conv-file","transcript_path":%q,"tool_name":"execute_bash","tool_input":{"command":"git commit -m 'test'"}}`, transcriptPath)
+		_, _, err := testutil.RunShiftlogInDirWithStdin(repo.Path, hookInput, "store", "--agent=amazonq")
+		Expect(err).NotTo(HaveOccurred())
+
+		head, err := repo.GetHead()
+		Expect(err).NotTo(HaveOccurred())
+		note, err := repo.GetNote("refs/notes/shiftlog", head)
+		Expect(err).NotTo(HaveOccurred())
+
+		var stored map[string]interface{}
+		Expect(json.Unmarshal([]byte(note), &stored)).To(Succeed())
+		Expect(stored["agent"]).To(Equal("amazonq"))
+		Expect(stored["session_id"]).To(Equal("conv-file"))
+		Expect(stored["model"]).To(Equal("claude-sonnet-4"))
+	})
+
+	Describe("with the Amazon Q CLI database", func() {
+		var env []string
+		var dbPath string
+		var projectPath string
+
+		BeforeEach(func() {
+			if _, err := exec.LookPath("sqlite3"); err != nil {
+				Skip("sqlite3 not available")
+			}
+
+			home := GinkgoT().TempDir()
+			dataHome := filepath.Join(home, ".local", "share")
+			env = []string{"HOME=" + home, "XDG_DATA_HOME=" + dataHome}
+			dataDir := filepath.Join(dataHome, "amazon-q")
+			if runtime.GOOS == "darwin" {
+				dataDir = filepath.Join(home, "Library", "Application Support", "amazon-q")
+			}
+			Expect(os.MkdirAll(dataDir, 0700)).To(Succeed())
+			dbPath = filepath.Join(dataDir, "data.sqlite3")
+
+			schema := `CREATE TABLE conversations_v2 (key TEXT NOT NULL, conversation_id TEXT NOT NULL, value TEXT NOT NULL, created_at INTEGER NOT NULL, updated_at INTEGER NOT NULL, PRIMARY KEY (key, conversation_id));`
+			Expect(exec.Command("sqlite3", dbPath, schema).Run()).To(Succeed())
+
+			var err error
+			projectPath, err = filepath.EvalSymlinks(repo.Path)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("stores the directory's conversation from the postToolUse hook", func() {
+			now := time.Now().UnixMilli()
+			insert := fmt.Sprintf("INSERT INTO conversations_v2 VALUES ('%s', 'conv-db', '%s', %d, %d);",
+				projectPath, strings.ReplaceAll(sampleAmazonQConversation("conv-db"), "'", "''"), now, now)
+			Expect(exec.Command("sqlite3", dbPath, insert).Run()).To(Succeed())
+
+			hookInput := fmt.Sprintf(`{"hook_event_name":"postToolUse","cwd":%q,"tool_name":"execute_bash","tool_input":{"command":"git commit -m 'test'"}}`, projectPath)
+			_, _, err := testutil.RunShiftlogInDirWithEnvAndStdin(repo.Path, env, hookInput, "store", "--agent=amazonq")
+			Expect(err).NotTo(HaveOccurred())
+
+			head, err := repo.GetHead()
+			Expect(err).NotTo(HaveOccurred())
+			note, err := repo.GetNote("refs/notes/shiftlog", head)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(note).To(ContainSubstring("conv-db"))
+		})
+
+		It("restores a stored conversation for resume", func() {
+			transcriptPath := filepath.Join(repo.Path, "conversation.json")
+			conversation := sampleAmazonQConversation("conv-resume")
+			Expect(os.WriteFile(transcriptPath, []byte(conversation), 0644)).To(Succeed())
+
+			hookInput := fmt.Sprintf(`{"session_id":"conv-resume","transcript_path":%q,"tool_name":"execute_bash","tool_input":{"command":"git commit -m 'test'"}}`, transcriptPath)
+			_, _, err := testutil.RunShiftlogInDirWithStdin(repo.Path, hookInput, "store", "--agent=amazonq")
+			Expect(err).NotTo(HaveOccurred())
+
+			head, err := repo.GetHead()
+			Expect(err).NotTo(HaveOccurred())
+			stdout, _, _ := testutil.RunShiftlogInDirWithEnv(repo.Path, env, "resume", head, "--force")
+			Expect(stdout).To(ContainSubstring("restored session"))
+
+			query := fmt.Sprintf("SELECT value FROM conversations_v2 WHERE key='%s' AND conversation_id='conv-resume';", projectPath)
+			out, err := exec.Command("sqlite3", dbPath, query).Output()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(strings.TrimSpace(string(out))).To(Equal(conversation))
+		})
+	})
+})
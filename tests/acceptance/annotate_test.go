@@ -0,0 +1,64 @@
+package acceptance_test
+
+import (
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/re-cinq/shift-log/tests/acceptance/testutil"
+)
+
+var _ = Describe("Annotate Command", func() {
+	var repo *testutil.GitRepo
+
+	BeforeEach(func() {
+		var err error
+		repo, err = testutil.NewGitRepo()
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(repo.WriteFile("README.md", "# Test")).To(Succeed())
+		Expect(repo.Commit("Initial commit")).To(Succeed())
+		Expect(repo.Run("git", "tag", "base")).To(Succeed())
+
+		_, _, err = testutil.RunShiftlogInDir(repo.Path, "init")
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(repo.WriteFile("a.txt", "a")).To(Succeed())
+		Expect(repo.Commit("Add a")).To(Succeed())
+		transcriptPath := filepath.Join(repo.Path, "transcript.jsonl")
+		Expect(os.WriteFile(transcriptPath, []byte(testutil.SampleTranscript()), 0644)).To(Succeed())
+		hookInput := testutil.SampleHookInput("session-a", transcriptPath, "git commit -m 'test'")
+		_, _, err = testutil.RunShiftlogInDirWithStdin(repo.Path, hookInput, "store")
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(repo.WriteFile("b.txt", "b")).To(Succeed())
+		Expect(repo.Commit("Add b")).To(Succeed())
+	})
+
+	AfterEach(func() {
+		repo.Cleanup()
+	})
+
+	It("emits workflow commands and appends the job summary", func() {
+		summaryPath := filepath.Join(repo.Path, "summary.md")
+		stdout, _, err := testutil.RunShiftlogInDir(repo.Path, "annotate", "--range", "base..HEAD", "--summary-file", summaryPath)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stdout).To(ContainSubstring("::warning title=No shiftlog conversation::"))
+		Expect(stdout).To(ContainSubstring("Add b"))
+		Expect(stdout).To(ContainSubstring("::notice title=shiftlog::1 of 2 commit(s) in base..HEAD have a conversation"))
+
+		summary, err := os.ReadFile(summaryPath)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(summary)).To(ContainSubstring("| Commit | Subject | Conversation | Tokens | Cost | Summary |"))
+		Expect(string(summary)).To(ContainSubstring("| Add a | ✅ claude"))
+		Expect(string(summary)).To(ContainSubstring("| Add b | ❌ missing"))
+	})
+
+	It("prints the table without a job summary", func() {
+		stdout, _, err := testutil.RunShiftlogInDir(repo.Path, "annotate", "--range", "base..HEAD", "--summary-file", "-")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stdout).To(ContainSubstring("### shiftlog conversations"))
+	})
+})
@@ -0,0 +1,105 @@
+package acceptance_test
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/re-cinq/shift-log/tests/acceptance/testutil"
+)
+
+var _ = Describe("Audit log", func() {
+	var repo *testutil.GitRepo
+	var hookInput string
+
+	BeforeEach(func() {
+		var err error
+		repo, err = testutil.NewGitRepo()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(repo.WriteFile("README.md", "# Test")).To(Succeed())
+		Expect(repo.Commit("Initial commit")).To(Succeed())
+
+		_, _, err = testutil.RunShiftlogInDir(repo.Path, "init")
+		Expect(err).NotTo(HaveOccurred())
+
+		transcriptPath := filepath.Join(GinkgoT().TempDir(), "transcript.jsonl")
+		Expect(os.WriteFile(transcriptPath, []byte(testutil.SampleTranscript()), 0644)).To(Succeed())
+		hookInput = testutil.SampleHookInput("session-audit", transcriptPath, "git commit -m 'test'")
+	})
+
+	AfterEach(func() {
+		repo.Cleanup()
+	})
+
+	It("records the operations that changed something", func() {
+		_, _, err := testutil.RunShiftlogInDirWithStdin(repo.Path, hookInput, "store")
+		Expect(err).NotTo(HaveOccurred())
+		_, _, err = testutil.RunShiftlogInDir(repo.Path, "tag", "HEAD", "reviewed")
+		Expect(err).NotTo(HaveOccurred())
+		// Read-only commands are not recorded
+		_, _, err = testutil.RunShiftlogInDir(repo.Path, "list")
+		Expect(err).NotTo(HaveOccurred())
+
+		head, err := repo.RunOutput("git", "rev-parse", "HEAD")
+		Expect(err).NotTo(HaveOccurred())
+
+		stdout, _, err := testutil.RunShiftlogInDir(repo.Path, "audit", "log", "--format", "json")
+		Expect(err).NotTo(HaveOccurred())
+		var records []struct {
+			User      string   `json:"user"`
+			Command   string   `json:"command"`
+			Args      []string `json:"args"`
+			Status    string   `json:"status"`
+			Artifacts []struct {
+				Kind string `json:"kind"`
+				ID   string `json:"id"`
+			} `json:"artifacts"`
+		}
+		Expect(json.Unmarshal([]byte(stdout), &records)).To(Succeed())
+
+		var commands []string
+		for _, r := range records {
+			commands = append(commands, r.Command)
+		}
+		Expect(commands).To(Equal([]string{"shiftlog init", "shiftlog store", "shiftlog tag"}))
+
+		tag := records[2]
+		Expect(tag.User).To(Equal("Test User <test@example.com>"))
+		Expect(tag.Args).To(Equal([]string{"tag", "HEAD", "reviewed"}))
+		Expect(tag.Status).To(Equal("ok"))
+		Expect(tag.Artifacts).To(HaveLen(1))
+		Expect(tag.Artifacts[0].Kind).To(Equal("note"))
+		Expect(tag.Artifacts[0].ID).To(Equal(strings.TrimSpace(head)))
+	})
+
+	It("filters the listed operations", func() {
+		_, _, err := testutil.RunShiftlogInDirWithStdin(repo.Path, hookInput, "store")
+		Expect(err).NotTo(HaveOccurred())
+
+		stdout, _, err := testutil.RunShiftlogInDir(repo.Path, "audit", "log", "--command", "store")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stdout).To(ContainSubstring("Test User <test@example.com>  store (hook)  note "))
+		Expect(stdout).NotTo(ContainSubstring("init"))
+
+		stdout, _, err = testutil.RunShiftlogInDir(repo.Path, "audit", "log", "--user", "someone-else")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stdout).To(ContainSubstring("no recorded operations"))
+
+		stdout, _, err = testutil.RunShiftlogInDir(repo.Path, "audit", "log", "--since", "2090-01-01")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stdout).To(ContainSubstring("no recorded operations"))
+	})
+
+	It("does not record failed operations that changed nothing", func() {
+		_, _, err := testutil.RunShiftlogInDir(repo.Path, "tag", "HEAD", "reviewed")
+		Expect(err).To(HaveOccurred())
+
+		stdout, _, err := testutil.RunShiftlogInDir(repo.Path, "audit", "log", "--command", "tag")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stdout).To(ContainSubstring("no recorded operations"))
+	})
+})
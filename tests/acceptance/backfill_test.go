@@ -0,0 +1,129 @@
+package acceptance_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/re-cinq/shift-log/tests/acceptance/testutil"
+)
+
+// backfillMessage is a user message of a backfillTranscript.
+type backfillMessage struct {
+	at   time.Time
+	text string
+}
+
+// backfillTranscript returns a Claude Code transcript of user messages
+// recorded on branch.
+func backfillTranscript(sessionID, branch string, messages ...backfillMessage) string {
+	var b strings.Builder
+	for i, m := range messages {
+		data, _ := json.Marshal(map[string]interface{}{
+			"uuid":      fmt.Sprintf("%s-%d", sessionID, i),
+			"type":      "user",
+			"sessionId": sessionID,
+			"gitBranch": branch,
+			"timestamp": m.at.UTC().Format(time.RFC3339),
+			"message":   map[string]interface{}{"role": "user", "content": m.text},
+		})
+		b.Write(data)
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+var _ = Describe("Backfill Command", func() {
+	var repo *testutil.GitRepo
+	var agentEnv *testutil.AgentEnv
+	var unrelated, early, late string
+
+	// commitAt makes a commit dated at
+	commitAt := func(message string, at time.Time) string {
+		repo.ExtraEnv = []string{"GIT_COMMITTER_DATE=" + at.Format(time.RFC3339), "GIT_AUTHOR_DATE=" + at.Format(time.RFC3339)}
+		defer func() { repo.ExtraEnv = nil }()
+		Expect(repo.WriteFile(message+".txt", message)).To(Succeed())
+		Expect(repo.Commit(message)).To(Succeed())
+		sha, err := repo.GetHead()
+		Expect(err).NotTo(HaveOccurred())
+		return sha
+	}
+
+	BeforeEach(func() {
+		var err error
+		repo, err = testutil.NewGitRepo()
+		Expect(err).NotTo(HaveOccurred())
+		agentEnv, err = testutil.NewAgentEnv(testutil.ClaudeTestConfig())
+		Expect(err).NotTo(HaveOccurred())
+
+		now := time.Now().Truncate(time.Second)
+		unrelated = commitAt("unrelated", now.Add(-5*time.Hour))
+		early = commitAt("early", now.Add(-2*time.Hour))
+		late = commitAt("late", now.Add(-10*time.Minute))
+		branch, err := repo.RunOutput("git", "rev-parse", "--abbrev-ref", "HEAD")
+		Expect(err).NotTo(HaveOccurred())
+		branch = strings.TrimSpace(branch)
+
+		_, err = agentEnv.WriteSessionFile(repo.Path, "early-session", []byte(backfillTranscript("early-session", branch,
+			backfillMessage{now.Add(-2*time.Hour - 5*time.Minute), "Write the early file"},
+			backfillMessage{now.Add(-2*time.Hour + 5*time.Minute), "Now something after the commit"},
+		)))
+		Expect(err).NotTo(HaveOccurred())
+		_, err = agentEnv.WriteSessionFile(repo.Path, "late-session", []byte(backfillTranscript("late-session", branch,
+			backfillMessage{now.Add(-15 * time.Minute), "Write the late file"},
+			backfillMessage{now.Add(-11 * time.Minute), "Commit it"},
+		)))
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		repo.Cleanup()
+		agentEnv.Cleanup()
+	})
+
+	It("lists the matches without storing them with --dry-run", func() {
+		stdout, _, err := testutil.RunShiftlogInDirWithEnv(repo.Path, agentEnv.GetEnvVars(), "backfill", "--agent", "claude", "--dry-run")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stdout).To(MatchRegexp(early[:7] + `\s+\S+ \S+\s+early-session\s+early`))
+		Expect(stdout).To(MatchRegexp(late[:7] + `\s+\S+ \S+\s+late-session\s+late`))
+		Expect(stdout).NotTo(ContainSubstring(unrelated[:7]))
+		Expect(repo.HasNote("refs/notes/shiftlog", early)).To(BeFalse())
+	})
+
+	It("does not store without confirmation", func() {
+		_, stderr, err := testutil.RunShiftlogInDirWithEnv(repo.Path, agentEnv.GetEnvVars(), "backfill", "--agent", "claude")
+		Expect(err).To(HaveOccurred())
+		Expect(stderr).To(ContainSubstring("pass --yes"))
+		Expect(repo.HasNote("refs/notes/shiftlog", early)).To(BeFalse())
+	})
+
+	It("stores each session on the commits made while it was active", func() {
+		stdout, _, err := testutil.RunShiftlogInDirWithEnv(repo.Path, agentEnv.GetEnvVars(), "backfill", "--agent", "claude", "--yes")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stdout).To(ContainSubstring("Stored 2 of 2 conversations"))
+
+		note, err := repo.GetNote("refs/notes/shiftlog", early)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(note).To(ContainSubstring("early-session"))
+		Expect(note).To(ContainSubstring(`"trigger": "backfill"`))
+
+		// The transcript ends at the commit
+		stdout, _, err = testutil.RunShiftlogInDir(repo.Path, "show", early)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stdout).To(ContainSubstring("Write the early file"))
+		Expect(stdout).NotTo(ContainSubstring("Now something after the commit"))
+
+		note, err = repo.GetNote("refs/notes/shiftlog", late)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(note).To(ContainSubstring("late-session"))
+		Expect(repo.HasNote("refs/notes/shiftlog", unrelated)).To(BeFalse())
+
+		stdout, _, err = testutil.RunShiftlogInDirWithEnv(repo.Path, agentEnv.GetEnvVars(), "backfill", "--agent", "claude", "--yes")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stdout).To(ContainSubstring("no commits without a conversation match a session"))
+	})
+})
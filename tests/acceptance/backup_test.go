@@ -0,0 +1,114 @@
+package acceptance_test
+
+import (
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/re-cinq/shift-log/tests/acceptance/testutil"
+)
+
+var _ = Describe("Backup Command", func() {
+	var repo *testutil.GitRepo
+	var head, backupDir string
+
+	BeforeEach(func() {
+		var err error
+		repo, err = testutil.NewGitRepo()
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(repo.WriteFile("README.md", "# Test")).To(Succeed())
+		Expect(repo.Commit("Initial commit")).To(Succeed())
+		head, err = repo.GetHead()
+		Expect(err).NotTo(HaveOccurred())
+
+		transcriptPath := filepath.Join(os.TempDir(), "session-backup.jsonl")
+		Expect(os.WriteFile(transcriptPath, []byte(testutil.SampleTranscript()), 0644)).To(Succeed())
+		DeferCleanup(os.Remove, transcriptPath)
+		hookInput := testutil.SampleHookInput("session-backup", transcriptPath, "git commit -m 'test'")
+		_, _, err = testutil.RunShiftlogInDirWithStdin(repo.Path, hookInput, "store")
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(repo.WriteFile(".shiftlog/config", `{"agent": "claude"}`)).To(Succeed())
+
+		backupDir, err = os.MkdirTemp("", "shiftlog-backup-*")
+		Expect(err).NotTo(HaveOccurred())
+		DeferCleanup(os.RemoveAll, backupDir)
+	})
+
+	AfterEach(func() {
+		if repo != nil {
+			repo.Cleanup()
+		}
+	})
+
+	It("restores notes and config deleted after a backup", func() {
+		backupFile := filepath.Join(backupDir, "notes.tar.gz")
+		stdout, _, err := testutil.RunShiftlogInDir(repo.Path, "backup", "create", backupFile)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stdout).To(ContainSubstring("Backed up 1 conversations"))
+
+		Expect(repo.Run("git", "update-ref", "-d", "refs/notes/shiftlog")).To(Succeed())
+		Expect(os.Remove(filepath.Join(repo.Path, ".shiftlog", "config"))).To(Succeed())
+		Expect(repo.HasNote("refs/notes/shiftlog", head)).To(BeFalse())
+
+		stdout, _, err = testutil.RunShiftlogInDir(repo.Path, "backup", "restore", backupFile)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stdout).To(ContainSubstring("Restored 1 conversations"))
+		Expect(repo.HasNote("refs/notes/shiftlog", head)).To(BeTrue())
+
+		config, err := os.ReadFile(filepath.Join(repo.Path, ".shiftlog", "config"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(config)).To(ContainSubstring(`"agent": "claude"`))
+
+		stdout, _, err = testutil.RunShiftlogInDir(repo.Path, "show", head)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stdout).NotTo(BeEmpty())
+	})
+
+	It("keeps the replaced notes in the pre-restore ref", func() {
+		backupFile := filepath.Join(backupDir, "notes.tar")
+		_, _, err := testutil.RunShiftlogInDir(repo.Path, "backup", "create", backupFile)
+		Expect(err).NotTo(HaveOccurred())
+
+		// Diverge from the backup by adding a note on a new commit
+		Expect(repo.WriteFile("b.txt", "b")).To(Succeed())
+		Expect(repo.Commit("Second commit")).To(Succeed())
+		second, err := repo.GetHead()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(repo.Run("git", "notes", "--ref", "refs/notes/shiftlog", "add", "-m", "{}", second)).To(Succeed())
+
+		stdout, _, err := testutil.RunShiftlogInDir(repo.Path, "backup", "restore", "--no-config", backupFile)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stdout).To(ContainSubstring("Previous notes saved to refs/notes/shiftlog-pre-restore"))
+		Expect(stdout).NotTo(ContainSubstring("Restored .shiftlog/config"))
+		Expect(repo.HasNote("refs/notes/shiftlog", second)).To(BeFalse())
+		Expect(repo.HasNote("refs/notes/shiftlog-pre-restore", second)).To(BeTrue())
+	})
+
+	It("writes zstd-compressed backups", func() {
+		backupFile := filepath.Join(backupDir, "notes.tar.zst")
+		_, _, err := testutil.RunShiftlogInDir(repo.Path, "backup", "create", backupFile)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(repo.Run("git", "update-ref", "-d", "refs/notes/shiftlog")).To(Succeed())
+		_, _, err = testutil.RunShiftlogInDir(repo.Path, "backup", "restore", backupFile)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(repo.HasNote("refs/notes/shiftlog", head)).To(BeTrue())
+	})
+
+	It("fails when there are no notes to back up", func() {
+		Expect(repo.Run("git", "update-ref", "-d", "refs/notes/shiftlog")).To(Succeed())
+		_, stderr, err := testutil.RunShiftlogInDir(repo.Path, "backup", "create", filepath.Join(backupDir, "empty.tar.gz"))
+		Expect(err).To(HaveOccurred())
+		Expect(stderr).To(ContainSubstring("no conversation notes to back up"))
+	})
+
+	It("rejects unsupported file names", func() {
+		_, stderr, err := testutil.RunShiftlogInDir(repo.Path, "backup", "create", filepath.Join(backupDir, "notes.zip"))
+		Expect(err).To(HaveOccurred())
+		Expect(stderr).To(ContainSubstring("unsupported backup file name"))
+	})
+})
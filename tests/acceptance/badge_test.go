@@ -0,0 +1,54 @@
+package acceptance_test
+
+import (
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/re-cinq/shift-log/tests/acceptance/testutil"
+)
+
+var _ = Describe("Badge Command", func() {
+	var repo *testutil.GitRepo
+
+	BeforeEach(func() {
+		var err error
+		repo, err = testutil.NewGitRepo()
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(repo.WriteFile("README.md", "# Test")).To(Succeed())
+		Expect(repo.Commit("Initial commit")).To(Succeed())
+
+		_, _, err = testutil.RunShiftlogInDir(repo.Path, "init")
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(repo.WriteFile("a.txt", "a")).To(Succeed())
+		Expect(repo.Commit("Add a")).To(Succeed())
+		transcriptPath := filepath.Join(repo.Path, "transcript.jsonl")
+		Expect(os.WriteFile(transcriptPath, []byte(testutil.SampleTranscript()), 0644)).To(Succeed())
+		hookInput := testutil.SampleHookInput("session-a", transcriptPath, "git commit -m 'test'")
+		_, _, err = testutil.RunShiftlogInDirWithStdin(repo.Path, hookInput, "store")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		repo.Cleanup()
+	})
+
+	It("prints the coverage badge", func() {
+		stdout, _, err := testutil.RunShiftlogInDir(repo.Path, "badge")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stdout).To(HavePrefix("<svg "))
+		Expect(stdout).To(ContainSubstring("AI-logged: 50% of 2 commits"))
+	})
+
+	It("writes the badge to --out", func() {
+		out := filepath.Join(repo.Path, "badge.svg")
+		stdout, _, err := testutil.RunShiftlogInDir(repo.Path, "badge", "--limit", "1", "--out", out)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stdout).To(ContainSubstring("100% of 1 commit"))
+		Expect(out).To(BeAnExistingFile())
+	})
+})
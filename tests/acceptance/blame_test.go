@@ -0,0 +1,106 @@
+package acceptance_test
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/re-cinq/shift-log/tests/acceptance/testutil"
+)
+
+var _ = Describe("Blame Command", func() {
+	var repo *testutil.GitRepo
+	var agentSHA, manualSHA string
+
+	type blameEntry struct {
+		Line            int    `json:"line"`
+		Commit          string `json:"commit"`
+		HasConversation bool   `json:"has_conversation"`
+		Agent           string `json:"agent"`
+		Model           string `json:"model"`
+		Content         string `json:"content"`
+	}
+
+	BeforeEach(func() {
+		var err error
+		repo, err = testutil.NewGitRepo()
+		Expect(err).NotTo(HaveOccurred())
+
+		// First commit is made during an agent session and gets a conversation
+		Expect(repo.WriteFile("app.go", "package app\nfunc A() {}\nfunc B() {}\n")).To(Succeed())
+		Expect(repo.Commit("Add app")).To(Succeed())
+		agentSHA, err = repo.GetHead()
+		Expect(err).NotTo(HaveOccurred())
+
+		transcriptPath := filepath.Join(repo.Path, ".transcript.jsonl")
+		Expect(os.WriteFile(transcriptPath, []byte(testutil.SampleTranscript()), 0644)).To(Succeed())
+		hookInput := testutil.SampleHookInput("session-blame", transcriptPath, "git commit -m 'Add app'")
+		_, _, err = testutil.RunShiftlogInDirWithStdin(repo.Path, hookInput, "store")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(os.Remove(transcriptPath)).To(Succeed())
+
+		// Second commit is a manual edit with no conversation
+		Expect(repo.WriteFile("app.go", "package app\nfunc A() {}\nfunc C() {}\n")).To(Succeed())
+		Expect(repo.Commit("Rename B")).To(Succeed())
+		manualSHA, err = repo.GetHead()
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		if repo != nil {
+			repo.Cleanup()
+		}
+	})
+
+	It("attributes each line in JSON output", func() {
+		stdout, _, err := testutil.RunShiftlogInDir(repo.Path, "blame", "app.go", "--format", "json")
+		Expect(err).NotTo(HaveOccurred())
+
+		var entries []blameEntry
+		Expect(json.Unmarshal([]byte(stdout), &entries)).To(Succeed())
+		Expect(entries).To(HaveLen(3))
+
+		Expect(entries[1].Commit).To(Equal(agentSHA))
+		Expect(entries[1].HasConversation).To(BeTrue())
+		Expect(entries[1].Agent).To(Equal("claude"))
+		Expect(entries[1].Model).To(Equal("claude-sonnet-4-5-20250514"))
+		Expect(entries[1].Content).To(Equal("func A() {}"))
+
+		Expect(entries[2].Commit).To(Equal(manualSHA))
+		Expect(entries[2].HasConversation).To(BeFalse())
+		Expect(entries[2].Agent).To(BeEmpty())
+	})
+
+	It("restricts output to a line range", func() {
+		stdout, _, err := testutil.RunShiftlogInDir(repo.Path, "blame", "app.go", "-L", "3,3", "--format", "json")
+		Expect(err).NotTo(HaveOccurred())
+
+		var entries []blameEntry
+		Expect(json.Unmarshal([]byte(stdout), &entries)).To(Succeed())
+		Expect(entries).To(HaveLen(1))
+		Expect(entries[0].Line).To(Equal(3))
+		Expect(entries[0].Content).To(Equal("func C() {}"))
+	})
+
+	It("prints a table by default", func() {
+		stdout, _, err := testutil.RunShiftlogInDirWithEnv(repo.Path, []string{"NO_COLOR=1"}, "blame", "app.go")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stdout).To(ContainSubstring(agentSHA[:7] + " * claude"))
+		Expect(stdout).To(ContainSubstring(manualSHA[:7] + "   -"))
+		Expect(stdout).To(ContainSubstring("| func C() {}"))
+	})
+
+	It("rejects an invalid line range", func() {
+		_, stderr, err := testutil.RunShiftlogInDir(repo.Path, "blame", "app.go", "-L", "5")
+		Expect(err).To(HaveOccurred())
+		Expect(stderr).To(ContainSubstring("invalid line range"))
+	})
+
+	It("fails for a file that is not tracked", func() {
+		_, _, err := testutil.RunShiftlogInDir(repo.Path, "blame", "missing.go")
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -0,0 +1,187 @@
+package acceptance_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/re-cinq/shift-log/tests/acceptance/testutil"
+)
+
+var _ = Describe("Transcripts stored apart", func() {
+	var repo, remote *testutil.GitRepo
+	var hookInput string
+
+	BeforeEach(func() {
+		var err error
+		repo, remote, err = testutil.NewGitRepoWithRemote()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(repo.WriteFile("README.md", "# Test")).To(Succeed())
+		Expect(repo.Commit("Initial commit")).To(Succeed())
+		Expect(repo.Run("git", "push", "-q", "origin", "HEAD:refs/heads/master")).To(Succeed())
+
+		_, _, err = testutil.RunShiftlogInDir(repo.Path, "init")
+		Expect(err).NotTo(HaveOccurred())
+
+		transcriptPath := filepath.Join(GinkgoT().TempDir(), "transcript.jsonl")
+		Expect(os.WriteFile(transcriptPath, []byte(testutil.SampleTranscript()), 0644)).To(Succeed())
+		hookInput = testutil.SampleHookInput("session-large", transcriptPath, "git commit -m 'test'")
+	})
+
+	AfterEach(func() {
+		repo.Cleanup()
+		remote.Cleanup()
+	})
+
+	// storedNote returns the conversation note of HEAD.
+	storedNote := func(r *testutil.GitRepo) map[string]any {
+		note, err := r.GetNote("refs/notes/shiftlog", "HEAD")
+		Expect(err).NotTo(HaveOccurred())
+		var sc map[string]any
+		Expect(json.Unmarshal([]byte(note), &sc)).To(Succeed())
+		return sc
+	}
+
+	// cloneRemote clones the remote into a new repository.
+	cloneRemote := func() *testutil.GitRepo {
+		clone := &testutil.GitRepo{Path: filepath.Join(GinkgoT().TempDir(), "clone")}
+		Expect(remote.Run("git", "clone", "-q", remote.Path, clone.Path)).To(Succeed())
+		Expect(clone.Run("git", "config", "user.name", "Other User")).To(Succeed())
+		Expect(clone.Run("git", "config", "user.email", "other@example.com")).To(Succeed())
+		return clone
+	}
+
+	It("keeps oversized transcripts out of the note and syncs them", func() {
+		Expect(repo.WriteFile(".shiftlog/config", `{"agent": "claude", "transcript_blob_threshold": 64}`)).To(Succeed())
+		_, _, err := testutil.RunShiftlogInDirWithStdin(repo.Path, hookInput, "store")
+		Expect(err).NotTo(HaveOccurred())
+
+		sc := storedNote(repo)
+		Expect(sc["transcript"]).To(BeEmpty())
+		Expect(sc["transcript_blob"]).NotTo(BeEmpty())
+
+		stdout, _, err := testutil.RunShiftlogInDir(repo.Path, "show")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stdout).To(ContainSubstring("Hello, can you help me with a task?"))
+
+		_, _, err = testutil.RunShiftlogInDir(repo.Path, "sync", "push")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(remote.RunOutput("git", "for-each-ref", "refs/notes/")).To(ContainSubstring("refs/notes/shiftlog-blobs"))
+
+		clone := cloneRemote()
+		_, _, err = testutil.RunShiftlogInDir(clone.Path, "sync", "pull")
+		Expect(err).NotTo(HaveOccurred())
+		stdout, _, err = testutil.RunShiftlogInDir(clone.Path, "show", "HEAD")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stdout).To(ContainSubstring("Hello, can you help me with a task?"))
+	})
+
+	It("keeps small transcripts in the note", func() {
+		Expect(repo.WriteFile(".shiftlog/config", `{"agent": "claude", "transcript_blob_threshold": 1000000}`)).To(Succeed())
+		_, _, err := testutil.RunShiftlogInDirWithStdin(repo.Path, hookInput, "store")
+		Expect(err).NotTo(HaveOccurred())
+
+		sc := storedNote(repo)
+		Expect(sc["transcript"]).NotTo(BeEmpty())
+		Expect(sc).NotTo(HaveKey("transcript_blob"))
+	})
+
+	It("stores transcripts in chunks shared between commits and syncs them", func() {
+		Expect(repo.WriteFile(".shiftlog/config", `{"agent": "claude", "transcript_chunks": true}`)).To(Succeed())
+
+		// The session's transcript grows between its two commits
+		uuids := make([]string, 320)
+		messages := make([]string, len(uuids))
+		for i := range uuids {
+			uuids[i] = fmt.Sprintf("chunked-%d", i)
+			messages[i] = fmt.Sprintf("Step %d: run the tests of the upload client and fix the retry logic", i)
+		}
+		lines := strings.SplitAfter(testutil.SampleTranscriptWithIDs(uuids, messages), "\n")
+		var chunks [2][]any
+		for i, n := range []int{300, 320} {
+			transcriptPath := filepath.Join(GinkgoT().TempDir(), "transcript.jsonl")
+			Expect(os.WriteFile(transcriptPath, []byte(strings.Join(lines[:n], "")), 0644)).To(Succeed())
+			Expect(repo.WriteFile(fmt.Sprintf("file%d.txt", i), "change")).To(Succeed())
+			Expect(repo.Commit(fmt.Sprintf("Change %d", i))).To(Succeed())
+			_, _, err := testutil.RunShiftlogInDirWithStdin(repo.Path, testutil.SampleHookInput("session-chunked", transcriptPath, "git commit -m 'test'"), "store")
+			Expect(err).NotTo(HaveOccurred())
+
+			sc := storedNote(repo)
+			Expect(sc["transcript"]).To(BeEmpty())
+			Expect(sc["transcript_chunks"]).NotTo(BeEmpty())
+			chunks[i] = sc["transcript_chunks"].([]any)
+		}
+		Expect(len(chunks[0])).To(BeNumerically(">", 1))
+		Expect(chunks[1][0]).To(Equal(chunks[0][0]))
+
+		stdout, _, err := testutil.RunShiftlogInDir(repo.Path, "show", "HEAD")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stdout).To(ContainSubstring("Step 319: run the tests"))
+
+		Expect(repo.Run("git", "push", "-q", "origin", "HEAD:refs/heads/master")).To(Succeed())
+		_, _, err = testutil.RunShiftlogInDir(repo.Path, "sync", "push")
+		Expect(err).NotTo(HaveOccurred())
+		clone := cloneRemote()
+		_, _, err = testutil.RunShiftlogInDir(clone.Path, "sync", "pull")
+		Expect(err).NotTo(HaveOccurred())
+		stdout, _, err = testutil.RunShiftlogInDir(clone.Path, "show", "HEAD~1")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stdout).To(ContainSubstring("Step 299: run the tests"))
+		_, _, err = testutil.RunShiftlogInDir(clone.Path, "verify")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("uploads transcripts to an HTTP blob store instead of pushing them", func() {
+		var mu sync.Mutex
+		blobs := map[string][]byte{}
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			mu.Lock()
+			defer mu.Unlock()
+			sha := strings.TrimPrefix(r.URL.Path, "/")
+			switch r.Method {
+			case http.MethodPut:
+				data, _ := io.ReadAll(r.Body)
+				blobs[sha] = data
+			case http.MethodGet, http.MethodHead:
+				data, ok := blobs[sha]
+				if !ok {
+					http.NotFound(w, r)
+					return
+				}
+				_, _ = w.Write(data)
+			}
+		}))
+		defer srv.Close()
+
+		config := `{"agent": "claude", "transcript_blob_threshold": 64, "blob_store": "` + srv.URL + `"}`
+		Expect(repo.WriteFile(".shiftlog/config", config)).To(Succeed())
+		_, _, err := testutil.RunShiftlogInDirWithStdin(repo.Path, hookInput, "store")
+		Expect(err).NotTo(HaveOccurred())
+		blob := storedNote(repo)["transcript_blob"]
+
+		stdout, _, err := testutil.RunShiftlogInDir(repo.Path, "sync", "push")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stdout).To(ContainSubstring("Uploaded 1 transcript blobs"))
+		Expect(remote.RunOutput("git", "for-each-ref", "refs/notes/")).NotTo(ContainSubstring("shiftlog-blobs"))
+		mu.Lock()
+		Expect(blobs).To(HaveKey(blob))
+		mu.Unlock()
+
+		clone := cloneRemote()
+		Expect(clone.WriteFile(".shiftlog/config", config)).To(Succeed())
+		_, _, err = testutil.RunShiftlogInDir(clone.Path, "sync", "pull")
+		Expect(err).NotTo(HaveOccurred())
+		stdout, _, err = testutil.RunShiftlogInDir(clone.Path, "show", "HEAD")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stdout).To(ContainSubstring("Hello, can you help me with a task?"))
+	})
+})
@@ -0,0 +1,105 @@
+package acceptance_test
+
+import (
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/re-cinq/shift-log/tests/acceptance/testutil"
+)
+
+var _ = Describe("Bundle Command", func() {
+	var source, target *testutil.GitRepo
+	var head, bundleFile string
+
+	BeforeEach(func() {
+		var err error
+		source, err = testutil.NewGitRepo()
+		Expect(err).NotTo(HaveOccurred())
+		target, err = testutil.NewGitRepo()
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(source.WriteFile("README.md", "# Test")).To(Succeed())
+		Expect(source.Commit("Initial commit")).To(Succeed())
+		head, err = source.GetHead()
+		Expect(err).NotTo(HaveOccurred())
+
+		transcriptPath := filepath.Join(os.TempDir(), "session-bundle.jsonl")
+		Expect(os.WriteFile(transcriptPath, []byte(testutil.SampleTranscript()), 0644)).To(Succeed())
+		DeferCleanup(os.Remove, transcriptPath)
+		hookInput := testutil.SampleHookInput("session-bundle", transcriptPath, "git commit -m 'test'")
+		_, _, err = testutil.RunShiftlogInDirWithStdin(source.Path, hookInput, "store")
+		Expect(err).NotTo(HaveOccurred())
+
+		bundleDir, err := os.MkdirTemp("", "shiftlog-bundle-*")
+		Expect(err).NotTo(HaveOccurred())
+		DeferCleanup(os.RemoveAll, bundleDir)
+		bundleFile = filepath.Join(bundleDir, "conversations.bundle")
+	})
+
+	AfterEach(func() {
+		source.Cleanup()
+		target.Cleanup()
+	})
+
+	It("carries conversations and their commits to another clone", func() {
+		stdout, _, err := testutil.RunShiftlogInDir(source.Path, "bundle", "create", bundleFile)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stdout).To(ContainSubstring("Bundled 1 conversations"))
+
+		stdout, _, err = testutil.RunShiftlogInDir(target.Path, "bundle", "import", bundleFile)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stdout).To(ContainSubstring("Imported 1 conversations"))
+		Expect(stdout).To(ContainSubstring("(1 new)"))
+		Expect(target.HasNote("refs/notes/shiftlog", head)).To(BeTrue())
+
+		// The commit is on no branch of the target, so a ref keeps it
+		ref, err := target.RunOutput("git", "rev-parse", "refs/shiftlog/imported/"+head)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ref).To(ContainSubstring(head))
+
+		stdout, _, err = testutil.RunShiftlogInDir(target.Path, "show", head)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stdout).NotTo(BeEmpty())
+
+		// No temporary refs are left behind on either side
+		refs, err := source.RunOutput("git", "for-each-ref", "refs/shiftlog/")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(refs).To(BeEmpty())
+		refs, err = target.RunOutput("git", "for-each-ref", "refs/notes/shiftlog-bundle")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(refs).To(BeEmpty())
+	})
+
+	It("merges into the conversations the clone already has", func() {
+		_, _, err := testutil.RunShiftlogInDir(source.Path, "bundle", "create", bundleFile)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(target.WriteFile("other.txt", "other")).To(Succeed())
+		Expect(target.Commit("Other commit")).To(Succeed())
+		other, err := target.GetHead()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(target.AddNote("refs/notes/shiftlog", other, "{}")).To(Succeed())
+
+		stdout, _, err := testutil.RunShiftlogInDir(target.Path, "bundle", "import", bundleFile)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stdout).To(ContainSubstring("Imported 1 conversations"))
+		Expect(target.HasNote("refs/notes/shiftlog", head)).To(BeTrue())
+		Expect(target.HasNote("refs/notes/shiftlog", other)).To(BeTrue())
+	})
+
+	It("fails when no conversation is recent enough", func() {
+		_, stderr, err := testutil.RunShiftlogInDir(source.Path, "bundle", "create", "--since", "2099-01-01", bundleFile)
+		Expect(err).To(HaveOccurred())
+		Expect(stderr).To(ContainSubstring("no conversations since 2099-01-01"))
+	})
+
+	It("rejects files that are not shiftlog bundles", func() {
+		Expect(source.Run("git", "bundle", "create", bundleFile, "HEAD")).To(Succeed())
+		_, stderr, err := testutil.RunShiftlogInDir(target.Path, "bundle", "import", bundleFile)
+		Expect(err).To(HaveOccurred())
+		Expect(stderr).To(ContainSubstring("not a shiftlog bundle"))
+	})
+})
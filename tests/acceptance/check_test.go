@@ -0,0 +1,81 @@
+package acceptance_test
+
+import (
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/re-cinq/shift-log/tests/acceptance/testutil"
+)
+
+var _ = Describe("Check Command", func() {
+	var repo *testutil.GitRepo
+
+	BeforeEach(func() {
+		var err error
+		repo, err = testutil.NewGitRepo()
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(repo.WriteFile("README.md", "# Test")).To(Succeed())
+		Expect(repo.Commit("Initial commit")).To(Succeed())
+		Expect(repo.Run("git", "tag", "base")).To(Succeed())
+
+		_, _, err = testutil.RunShiftlogInDir(repo.Path, "init")
+		Expect(err).NotTo(HaveOccurred())
+
+		// One commit with a conversation
+		Expect(repo.WriteFile("a.txt", "a")).To(Succeed())
+		Expect(repo.Commit("Add a")).To(Succeed())
+		transcriptPath := filepath.Join(repo.Path, "transcript.jsonl")
+		Expect(os.WriteFile(transcriptPath, []byte(testutil.SampleTranscript()), 0644)).To(Succeed())
+		hookInput := testutil.SampleHookInput("session-a", transcriptPath, "git commit -m 'test'")
+		_, _, err = testutil.RunShiftlogInDirWithStdin(repo.Path, hookInput, "store")
+		Expect(err).NotTo(HaveOccurred())
+
+		// Allowlisted by default
+		Expect(repo.Run("git", "commit", "--allow-empty", "-m", `Revert "Add a"`)).To(Succeed())
+		Expect(repo.Run("git", "-c", "user.name=renovate[bot]", "commit", "--allow-empty", "-m", "Bump deps")).To(Succeed())
+
+		// Missing a conversation
+		Expect(repo.WriteFile("b.txt", "b")).To(Succeed())
+		Expect(repo.Commit("chore: add b")).To(Succeed())
+	})
+
+	AfterEach(func() {
+		repo.Cleanup()
+	})
+
+	It("reports commits without a conversation", func() {
+		stdout, _, err := testutil.RunShiftlogInDir(repo.Path, "check", "--range", "base..HEAD")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stdout).To(ContainSubstring("chore: add b"))
+		Expect(stdout).NotTo(ContainSubstring("Bump deps"))
+		Expect(stdout).To(ContainSubstring("4 commit(s) in base..HEAD: 1 with a conversation, 2 allowlisted, 1 missing"))
+	})
+
+	It("fails with --require-conversation", func() {
+		_, stderr, err := testutil.RunShiftlogInDir(repo.Path, "check", "--range", "base..HEAD", "--require-conversation")
+		Expect(err).To(HaveOccurred())
+		Expect(stderr).To(ContainSubstring("1 commit(s) without a conversation"))
+	})
+
+	It("passes within --max-missing", func() {
+		_, _, err := testutil.RunShiftlogInDir(repo.Path, "check", "--range", "base..HEAD", "--max-missing", "1")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("skips commits matching --allow-message", func() {
+		stdout, _, err := testutil.RunShiftlogInDir(repo.Path, "check", "--range", "base..HEAD", "--require-conversation", "--allow-message", "^chore:")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stdout).To(ContainSubstring("3 allowlisted, 0 missing"))
+	})
+
+	It("counts bots and reverts with --no-default-allowlist", func() {
+		stdout, _, err := testutil.RunShiftlogInDir(repo.Path, "check", "--range", "base..HEAD", "--no-default-allowlist")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stdout).To(ContainSubstring("Bump deps"))
+		Expect(stdout).To(ContainSubstring("3 missing"))
+	})
+})
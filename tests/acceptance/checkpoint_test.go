@@ -0,0 +1,134 @@
+package acceptance_test
+
+import (
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/re-cinq/shift-log/tests/acceptance/testutil"
+)
+
+var _ = Describe("Checkpoint Commands", func() {
+	const checkpointsRef = "refs/notes/shiftlog-wip"
+
+	var repo *testutil.GitRepo
+	var agentEnv *testutil.AgentEnv
+
+	BeforeEach(func() {
+		var err error
+		repo, err = testutil.NewGitRepo()
+		Expect(err).NotTo(HaveOccurred())
+
+		agentEnv, err = testutil.NewAgentEnv(testutil.ClaudeTestConfig())
+		Expect(err).NotTo(HaveOccurred())
+
+		_, _, err = testutil.RunShiftlogInDir(repo.Path, "init")
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(repo.WriteFile("README.md", "# Test")).To(Succeed())
+		Expect(repo.Commit("Initial commit")).To(Succeed())
+
+		_, err = agentEnv.WriteSessionFile(repo.Path, "paused-session", []byte(testutil.SampleTranscript()))
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		repo.Cleanup()
+		agentEnv.Cleanup()
+	})
+
+	checkpointObjects := func() []string {
+		out, err := repo.RunOutput("git", "notes", "--ref", checkpointsRef, "list")
+		Expect(err).NotTo(HaveOccurred())
+		var objects []string
+		for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+			if fields := strings.Fields(line); len(fields) == 2 {
+				objects = append(objects, fields[1])
+			}
+		}
+		return objects
+	}
+
+	Describe("shiftlog checkpoint", func() {
+		It("stores the checkpoint against a snapshot of uncommitted changes", func() {
+			Expect(repo.WriteFile("README.md", "# Changed")).To(Succeed())
+
+			stdout, _, err := testutil.RunShiftlogInDirWithEnv(repo.Path, agentEnv.GetEnvVars(), "checkpoint")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(stdout).To(ContainSubstring("checkpointed session paused-session"))
+			Expect(stdout).To(ContainSubstring("on snapshot"))
+
+			// The working tree is left alone
+			content, err := repo.ReadFile("README.md")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(content).To(Equal("# Changed"))
+
+			objects := checkpointObjects()
+			Expect(objects).To(HaveLen(1))
+			snapshot, err := repo.RunOutput("git", "show", objects[0]+":README.md")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(snapshot).To(Equal("# Changed"))
+		})
+
+		It("stashes changes with --stash", func() {
+			Expect(repo.WriteFile("README.md", "# Changed")).To(Succeed())
+
+			stdout, _, err := testutil.RunShiftlogInDirWithEnv(repo.Path, agentEnv.GetEnvVars(), "checkpoint", "--stash")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(stdout).To(ContainSubstring("on stash@{0}"))
+
+			stash, err := repo.RunOutput("git", "rev-parse", "stash@{0}")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(checkpointObjects()).To(ConsistOf(strings.TrimSpace(stash)))
+
+			content, err := repo.ReadFile("README.md")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(content).To(Equal("# Test"))
+		})
+
+		It("fails with --stash when there is nothing to stash", func() {
+			_, _, err := testutil.RunShiftlogInDirWithEnv(repo.Path, agentEnv.GetEnvVars(), "checkpoint", "--stash")
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("fails for an unknown session", func() {
+			_, _, err := testutil.RunShiftlogInDirWithEnv(repo.Path, agentEnv.GetEnvVars(), "checkpoint", "--session", "unknown-session")
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Describe("shiftlog checkpoints", func() {
+		It("reports when there are no checkpoints", func() {
+			stdout, _, err := testutil.RunShiftlogInDir(repo.Path, "checkpoints")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(stdout).To(ContainSubstring("no checkpoints found"))
+		})
+
+		It("lists checkpoints and promotes one to a commit note", func() {
+			Expect(repo.WriteFile("README.md", "# Changed")).To(Succeed())
+			_, _, err := testutil.RunShiftlogInDirWithEnv(repo.Path, agentEnv.GetEnvVars(), "checkpoint", "--stash")
+			Expect(err).NotTo(HaveOccurred())
+
+			stdout, _, err := testutil.RunShiftlogInDir(repo.Path, "checkpoints")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(stdout).To(ContainSubstring("stash@{0}"))
+			Expect(stdout).To(ContainSubstring("paused-session"))
+
+			stdout, _, err = testutil.RunShiftlogInDir(repo.Path, "checkpoints", "promote", "stash@{0}")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(stdout).To(ContainSubstring("stored checkpoint of session paused-session"))
+
+			note, err := repo.GetNote("refs/notes/shiftlog", "HEAD")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(note).To(ContainSubstring("paused-session"))
+			Expect(note).To(ContainSubstring(`"trigger": "checkpoint"`))
+			Expect(checkpointObjects()).To(BeEmpty())
+		})
+
+		It("fails to promote an unknown checkpoint", func() {
+			_, _, err := testutil.RunShiftlogInDir(repo.Path, "checkpoints", "promote", "deadbeef")
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
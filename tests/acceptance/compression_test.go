@@ -0,0 +1,127 @@
+package acceptance_test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/re-cinq/shift-log/tests/acceptance/testutil"
+)
+
+var _ = Describe("Transcript compression", func() {
+	var repo *testutil.GitRepo
+
+	// storeSession commits a change and stores a transcript of n messages
+	// on it.
+	storeSession := func(sessionID string, n int) {
+		Expect(repo.WriteFile(sessionID+".txt", sessionID)).To(Succeed())
+		Expect(repo.Commit("Work of " + sessionID)).To(Succeed())
+
+		uuids := make([]string, n)
+		messages := make([]string, n)
+		for i := range uuids {
+			uuids[i] = fmt.Sprintf("%s-%d", sessionID, i)
+			messages[i] = fmt.Sprintf("Step %d: run the tests of the upload client and fix the retry logic", i)
+		}
+		transcriptPath := filepath.Join(GinkgoT().TempDir(), "transcript.jsonl")
+		Expect(os.WriteFile(transcriptPath, []byte(testutil.SampleTranscriptWithIDs(uuids, messages)), 0644)).To(Succeed())
+		hookInput := testutil.SampleHookInput(sessionID, transcriptPath, "git commit -m 'test'")
+		_, _, err := testutil.RunShiftlogInDirWithStdin(repo.Path, hookInput, "store")
+		Expect(err).NotTo(HaveOccurred())
+	}
+
+	BeforeEach(func() {
+		var err error
+		repo, err = testutil.NewGitRepo()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(repo.WriteFile("README.md", "# Test")).To(Succeed())
+		Expect(repo.Commit("Initial commit")).To(Succeed())
+
+		_, _, err = testutil.RunShiftlogInDir(repo.Path, "init")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		if repo != nil {
+			repo.Cleanup()
+		}
+	})
+
+	It("reads gzip transcripts after switching to zstd", func() {
+		storeSession("session-gzip", 4)
+		Expect(repo.WriteFile(".shiftlog/config", `{"agent": "claude", "compression": "zstd"}`)).To(Succeed())
+		storeSession("session-zstd", 4)
+
+		note, err := repo.GetNote("refs/notes/shiftlog", "HEAD")
+		Expect(err).NotTo(HaveOccurred())
+		// Base64 of the zstd magic number
+		Expect(note).To(ContainSubstring(`"transcript": "KLUv`))
+
+		for _, ref := range []string{"HEAD", "HEAD~1"} {
+			stdout, _, err := testutil.RunShiftlogInDir(repo.Path, "show", ref)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(stdout).To(ContainSubstring("Step 3: run the tests"))
+		}
+
+		stdout, _, err := testutil.RunShiftlogInDir(repo.Path, "compression", "status")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stdout).To(ContainSubstring("New transcripts: zstd"))
+		Expect(stdout).To(MatchRegexp(`gzip\s+1 conversations`))
+		Expect(stdout).To(MatchRegexp(`zstd\s+1 conversations`))
+	})
+
+	It("trains a dictionary and recompresses stored transcripts with it", func() {
+		for i := 0; i < 5; i++ {
+			storeSession(fmt.Sprintf("session-%d", i), 200)
+		}
+
+		stdout, _, err := testutil.RunShiftlogInDir(repo.Path, "compression", "train")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stdout).To(ContainSubstring("over 5 transcripts"))
+		Expect(repo.RunOutput("git", "notes", "--ref", "refs/notes/shiftlog-dictionaries", "list")).NotTo(BeEmpty())
+		config, err := os.ReadFile(filepath.Join(repo.Path, ".shiftlog", "config"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(config)).To(ContainSubstring(`"compression": "zstd"`))
+		Expect(string(config)).To(ContainSubstring(`"compression_dictionary"`))
+
+		stdout, _, err = testutil.RunShiftlogInDir(repo.Path, "compression", "recompress", "--dry-run")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stdout).To(ContainSubstring("Would recompress 5 transcripts with zstd, dictionary"))
+
+		stdout, _, err = testutil.RunShiftlogInDir(repo.Path, "compression", "recompress")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stdout).To(ContainSubstring("Recompressed 5 transcripts"))
+
+		stdout, _, err = testutil.RunShiftlogInDir(repo.Path, "show", "HEAD~2")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stdout).To(ContainSubstring("Step 199: run the tests"))
+
+		_, _, err = testutil.RunShiftlogInDir(repo.Path, "verify")
+		Expect(err).NotTo(HaveOccurred())
+
+		// Nothing is left to rewrite
+		stdout, _, err = testutil.RunShiftlogInDir(repo.Path, "compression", "recompress")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stdout).To(ContainSubstring("Recompressed 0 transcripts"))
+	})
+
+	It("shares dictionaries with sync", func() {
+		for i := 0; i < 5; i++ {
+			storeSession(fmt.Sprintf("session-%d", i), 200)
+		}
+		_, _, err := testutil.RunShiftlogInDir(repo.Path, "compression", "train")
+		Expect(err).NotTo(HaveOccurred())
+		storeSession("session-new", 10)
+
+		remote, err := testutil.NewGitRepoAsBare()
+		Expect(err).NotTo(HaveOccurred())
+		defer remote.Cleanup()
+		Expect(repo.AddRemote("origin", remote.Path)).To(Succeed())
+		_, _, err = testutil.RunShiftlogInDir(repo.Path, "sync", "push")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(remote.RunOutput("git", "for-each-ref", "refs/notes/")).To(ContainSubstring("refs/notes/shiftlog-dictionaries"))
+	})
+})
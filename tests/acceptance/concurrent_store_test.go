@@ -0,0 +1,121 @@
+package acceptance_test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/re-cinq/shift-log/tests/acceptance/testutil"
+)
+
+var _ = Describe("Concurrent store", func() {
+	const agents = 8
+
+	var repo *testutil.GitRepo
+	var worktrees []string
+
+	BeforeEach(func() {
+		var err error
+		repo, err = testutil.NewGitRepo()
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(repo.WriteFile("README.md", "# Test")).To(Succeed())
+		Expect(repo.Commit("Initial commit")).To(Succeed())
+
+		// One worktree per agent, each with its own commit to store for
+		worktrees = nil
+		for i := range agents {
+			dir, err := os.MkdirTemp("", "shiftlog-concurrent-*")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(os.RemoveAll(dir)).To(Succeed())
+			Expect(repo.Run("git", "worktree", "add", "-b", fmt.Sprintf("agent-%d", i), dir)).To(Succeed())
+			worktrees = append(worktrees, dir)
+
+			wt := &testutil.GitRepo{Path: dir}
+			Expect(wt.WriteFile(fmt.Sprintf("agent-%d.txt", i), "work")).To(Succeed())
+			Expect(wt.Commit(fmt.Sprintf("Agent %d commit", i))).To(Succeed())
+		}
+	})
+
+	AfterEach(func() {
+		for _, dir := range worktrees {
+			if repo != nil {
+				_ = repo.Run("git", "worktree", "remove", "--force", dir)
+			}
+			_ = os.RemoveAll(dir)
+		}
+		if repo != nil {
+			repo.Cleanup()
+		}
+	})
+
+	It("stores every conversation when agents commit at the same time", func() {
+		heads := make([]string, agents)
+		inputs := make([]string, agents)
+		for i, dir := range worktrees {
+			transcriptPath := filepath.Join(dir, "transcript.jsonl")
+			Expect(os.WriteFile(transcriptPath, []byte(testutil.SampleTranscript()), 0644)).To(Succeed())
+
+			head, err := (&testutil.GitRepo{Path: dir}).GetHead()
+			Expect(err).NotTo(HaveOccurred())
+			heads[i] = head
+			inputs[i] = testutil.SampleHookInput(fmt.Sprintf("session-agent-%d", i), transcriptPath, "git commit -m 'test'")
+		}
+
+		var wg sync.WaitGroup
+		errs := make([]error, agents)
+		start := make(chan struct{})
+		for i, dir := range worktrees {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				<-start
+				_, _, errs[i] = testutil.RunShiftlogInDirWithStdin(dir, inputs[i], "store")
+			}()
+		}
+		close(start)
+		wg.Wait()
+
+		for i, head := range heads {
+			Expect(errs[i]).NotTo(HaveOccurred())
+			note, err := repo.GetNote("refs/notes/shiftlog", head)
+			Expect(err).NotTo(HaveOccurred(), "conversation for agent %d was dropped", i)
+			Expect(note).To(ContainSubstring(fmt.Sprintf("session-agent-%d", i)))
+		}
+	})
+
+	It("keeps every conversation when hooks store on the same commit at once", func() {
+		Expect(repo.WriteFile("retry.go", "package retry\n")).To(Succeed())
+		Expect(repo.Commit("Fix retry")).To(Succeed())
+		head, err := repo.GetHead()
+		Expect(err).NotTo(HaveOccurred())
+		transcriptPath := filepath.Join(GinkgoT().TempDir(), "transcript.jsonl")
+		Expect(os.WriteFile(transcriptPath, []byte(testutil.SampleTranscript()), 0644)).To(Succeed())
+
+		var wg sync.WaitGroup
+		errs := make([]error, agents)
+		start := make(chan struct{})
+		for i := range agents {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				input := testutil.SampleHookInput(fmt.Sprintf("session-hook-%d", i), transcriptPath, "git commit -m 'Fix retry'")
+				<-start
+				_, _, errs[i] = testutil.RunShiftlogInDirWithStdin(repo.Path, input, "store")
+			}()
+		}
+		close(start)
+		wg.Wait()
+
+		note, err := repo.GetNote("refs/notes/shiftlog", head)
+		Expect(err).NotTo(HaveOccurred())
+		for i := range agents {
+			Expect(errs[i]).NotTo(HaveOccurred())
+			Expect(note).To(ContainSubstring(fmt.Sprintf("session-hook-%d", i)), "conversation of hook %d was overwritten", i)
+		}
+	})
+})
@@ -0,0 +1,83 @@
+package acceptance_test
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/re-cinq/shift-log/tests/acceptance/testutil"
+)
+
+var _ = Describe("Coverage Command", func() {
+	var repo *testutil.GitRepo
+
+	BeforeEach(func() {
+		var err error
+		repo, err = testutil.NewGitRepo()
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(repo.WriteFile("README.md", "# Test\n")).To(Succeed())
+		Expect(repo.Commit("Initial commit")).To(Succeed())
+
+		_, _, err = testutil.RunShiftlogInDir(repo.Path, "init")
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(os.MkdirAll(filepath.Join(repo.Path, "src"), 0755)).To(Succeed())
+		Expect(repo.WriteFile("src/a.txt", "one\ntwo\nthree\n")).To(Succeed())
+		Expect(repo.Commit("Add a")).To(Succeed())
+		transcriptPath := filepath.Join(repo.Path, "transcript.jsonl")
+		Expect(os.WriteFile(transcriptPath, []byte(testutil.SampleTranscript()), 0644)).To(Succeed())
+		hookInput := testutil.SampleHookInput("session-a", transcriptPath, "git commit -m 'test'")
+		_, _, err = testutil.RunShiftlogInDirWithStdin(repo.Path, hookInput, "store")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		repo.Cleanup()
+	})
+
+	It("reports the coverage of commits and lines as JSON", func() {
+		stdout, _, err := testutil.RunShiftlogInDir(repo.Path, "coverage", "--format", "json")
+		Expect(err).NotTo(HaveOccurred())
+
+		var report struct {
+			Total struct {
+				Commits      int     `json:"commits"`
+				Covered      int     `json:"covered"`
+				Lines        int     `json:"lines"`
+				CoveredLines int     `json:"covered_lines"`
+				CommitRatio  float64 `json:"commit_ratio"`
+			} `json:"total"`
+			Directories map[string]struct {
+				Covered int `json:"covered"`
+			} `json:"directories"`
+		}
+		Expect(json.Unmarshal([]byte(stdout), &report)).To(Succeed())
+		Expect(report.Total.Commits).To(Equal(2))
+		Expect(report.Total.Covered).To(Equal(1))
+		Expect(report.Total.CommitRatio).To(Equal(0.5))
+		// The initial commit holds only README.md: init's files come with
+		// the second
+		Expect(report.Total.Lines - report.Total.CoveredLines).To(Equal(1))
+		Expect(report.Directories).To(HaveKey("src"))
+		Expect(report.Directories["src"].Covered).To(Equal(1))
+	})
+
+	It("prints Markdown tables by author and directory", func() {
+		stdout, _, err := testutil.RunShiftlogInDir(repo.Path, "coverage", "--format", "markdown")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stdout).To(ContainSubstring("1/2 (50%) commits"))
+		Expect(stdout).To(ContainSubstring("| author | commits | lines |"))
+		Expect(stdout).To(ContainSubstring("| directory | commits | lines |"))
+		Expect(stdout).To(ContainSubstring("| src | 1/1 (100%) | 3/3 (100%) |"))
+	})
+
+	It("only counts commits since --since", func() {
+		stdout, _, err := testutil.RunShiftlogInDir(repo.Path, "coverage", "--since", "2090-01-01")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stdout).To(ContainSubstring("No commits found."))
+	})
+})
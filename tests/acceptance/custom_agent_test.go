@@ -0,0 +1,111 @@
+package acceptance_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/re-cinq/shift-log/tests/acceptance/testutil"
+)
+
+// customAgentManifest describes an agent writing OpenAI-style chat messages
+// as JSONL into a sessions directory.
+const customAgentManifest = `name: acme
+display_name: Acme Agent
+sessions:
+  dir: "%s"
+transcript:
+  id: .id
+  role: .role
+  text: .content
+  model: .model
+  tool_calls: .tool_calls
+  tool_name: .function.name
+  tool_input: .function.arguments
+  tool_id: .id
+shell_tools: [shell]
+tool_aliases:
+  shell: Bash
+resume:
+  command: echo
+  args: ["resuming", "{session_id}"]
+`
+
+const customAgentTranscript = `{"id":"m1","role":"user","content":"Commit the fix"}
+{"id":"m2","role":"assistant","content":"Committing.","model":"acme-1","tool_calls":[{"id":"call_1","function":{"name":"shell","arguments":"{\"command\":\"git commit -m fix\"}"}}]}
+`
+
+var _ = Describe("Custom agent manifests", func() {
+	var repo *testutil.GitRepo
+	var sessionsDir string
+	var env []string
+
+	BeforeEach(func() {
+		var err error
+		repo, err = testutil.NewGitRepo()
+		Expect(err).NotTo(HaveOccurred())
+		repo.SetBinaryPath(testutil.BinaryPath())
+
+		Expect(repo.WriteFile("README.md", "# Test")).To(Succeed())
+		Expect(repo.Commit("Initial commit")).To(Succeed())
+
+		home := GinkgoT().TempDir()
+		env = []string{"HOME=" + home, "XDG_CONFIG_HOME=" + filepath.Join(home, ".config")}
+		sessionsDir = filepath.Join(home, "acme", "sessions")
+		Expect(os.MkdirAll(sessionsDir, 0755)).To(Succeed())
+		Expect(repo.WriteFile(".shiftlog/agents/acme.yaml", fmt.Sprintf(customAgentManifest, sessionsDir))).To(Succeed())
+	})
+
+	AfterEach(func() {
+		if repo != nil {
+			repo.Cleanup()
+		}
+	})
+
+	It("initializes with the custom agent", func() {
+		stdout, _, err := testutil.RunShiftlogInDirWithEnv(repo.Path, env, "init", "--agent=acme")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stdout).To(ContainSubstring("Acme Agent"))
+	})
+
+	It("stores and resumes a conversation of the custom agent", func() {
+		transcriptPath := filepath.Join(sessionsDir, "sess-1.jsonl")
+		Expect(os.WriteFile(transcriptPath, []byte(customAgentTranscript), 0644)).To(Succeed())
+
+		hookInput := fmt.Sprintf(`{"session_id":"sess-1","transcript_path":%q,"tool_name":"shell","tool_input":{"command":"git commit -m 'test'"}}`, transcriptPath)
+		_, _, err := testutil.RunShiftlogInDirWithEnvAndStdin(repo.Path, env, hookInput, "store", "--agent=acme")
+		Expect(err).NotTo(HaveOccurred())
+
+		head, err := repo.GetHead()
+		Expect(err).NotTo(HaveOccurred())
+		note, err := repo.GetNote("refs/notes/shiftlog", head)
+		Expect(err).NotTo(HaveOccurred())
+
+		var stored map[string]interface{}
+		Expect(json.Unmarshal([]byte(note), &stored)).To(Succeed())
+		Expect(stored["agent"]).To(Equal("acme"))
+		Expect(stored["session_id"]).To(Equal("sess-1"))
+		Expect(stored["model"]).To(Equal("acme-1"))
+
+		Expect(os.Remove(transcriptPath)).To(Succeed())
+		stdout, _, err := testutil.RunShiftlogInDirWithEnv(repo.Path, env, "resume", head, "--force")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stdout).To(ContainSubstring("resuming sess-1"))
+
+		restored, err := os.ReadFile(transcriptPath)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(restored)).To(Equal(customAgentTranscript))
+	})
+
+	It("warns about an invalid manifest without failing", func() {
+		Expect(repo.WriteFile(".shiftlog/agents/broken.yaml", "name: Broken\n")).To(Succeed())
+
+		_, stderr, err := testutil.RunShiftlogInDirWithEnv(repo.Path, env, "init", "--agent=acme")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stderr).To(ContainSubstring("skipping agent manifest"))
+	})
+})
@@ -0,0 +1,107 @@
+package acceptance_test
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/re-cinq/shift-log/tests/acceptance/testutil"
+)
+
+var _ = Describe("Daemon Command", func() {
+	var repo *testutil.GitRepo
+	var daemon *exec.Cmd
+
+	BeforeEach(func() {
+		var err error
+		repo, err = testutil.NewGitRepo()
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(repo.WriteFile("main.go", "package main\n")).To(Succeed())
+		Expect(repo.Commit("Initial commit")).To(Succeed())
+
+		transcriptPath := filepath.Join(repo.Path, "transcript.jsonl")
+		Expect(os.WriteFile(transcriptPath, []byte(testutil.SampleTranscript()), 0644)).To(Succeed())
+		hookInput := testutil.SampleHookInput("daemon-session", transcriptPath, "git commit -m 'test'")
+		_, _, err = testutil.RunShiftlogInDirWithStdin(repo.Path, hookInput, "store")
+		Expect(err).NotTo(HaveOccurred())
+		daemon = nil
+	})
+
+	AfterEach(func() {
+		if daemon != nil && daemon.ProcessState == nil {
+			_ = daemon.Process.Kill()
+			_ = daemon.Wait()
+		}
+		repo.Cleanup()
+	})
+
+	startDaemon := func() {
+		daemon = exec.Command(testutil.BinaryPath(), "daemon")
+		daemon.Dir = repo.Path
+		Expect(daemon.Start()).To(Succeed())
+		Eventually(func() string {
+			stdout, _, _ := testutil.RunShiftlogInDir(repo.Path, "daemon", "status")
+			return stdout
+		}, 10*time.Second, 50*time.Millisecond).Should(ContainSubstring("daemon running"))
+	}
+
+	It("reports when no daemon runs", func() {
+		stdout, _, err := testutil.RunShiftlogInDir(repo.Path, "daemon", "status")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stdout).To(ContainSubstring("no daemon running"))
+
+		stdout, _, err = testutil.RunShiftlogInDir(repo.Path, "daemon", "stop")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stdout).To(ContainSubstring("no daemon running"))
+	})
+
+	It("answers search, show and blame with the same output as without it", func() {
+		queries := [][]string{
+			{"search", "Hello"},
+			{"show", "HEAD"},
+			{"blame", "main.go"},
+		}
+		var expected []string
+		for _, args := range queries {
+			stdout, _, err := testutil.RunShiftlogInDirWithEnv(repo.Path, []string{"NO_COLOR=1"}, args...)
+			Expect(err).NotTo(HaveOccurred())
+			expected = append(expected, stdout)
+		}
+
+		startDaemon()
+		debug := []string{"SHIFTLOG_DEBUG=1", "NO_COLOR=1"}
+		for i, args := range queries {
+			stdout, stderr, err := testutil.RunShiftlogInDirWithEnv(repo.Path, debug, args...)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(stderr).To(ContainSubstring("using the daemon"))
+			Expect(stdout).To(Equal(expected[i]), "shiftlog %v", args)
+		}
+
+		// Commands can be kept from using it
+		_, stderr, err := testutil.RunShiftlogInDirWithEnv(repo.Path, append(debug, "SHIFTLOG_NO_DAEMON=1"), "search", "Hello")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stderr).NotTo(ContainSubstring("using the daemon"))
+	})
+
+	It("refuses to start twice and stops on request", func() {
+		startDaemon()
+
+		_, stderr, err := testutil.RunShiftlogInDir(repo.Path, "daemon")
+		Expect(err).To(HaveOccurred())
+		Expect(stderr).To(ContainSubstring("a daemon is already running"))
+
+		stdout, _, err := testutil.RunShiftlogInDir(repo.Path, "daemon", "stop")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stdout).To(ContainSubstring("stopped daemon"))
+		Expect(daemon.Wait()).To(Succeed())
+
+		stdout, _, err = testutil.RunShiftlogInDir(repo.Path, "daemon", "status")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stdout).To(ContainSubstring("no daemon running"))
+	})
+})
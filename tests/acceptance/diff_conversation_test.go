@@ -0,0 +1,64 @@
+package acceptance_test
+
+import (
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/re-cinq/shift-log/tests/acceptance/testutil"
+)
+
+var _ = Describe("Diff Conversation Command", func() {
+	var repo *testutil.GitRepo
+
+	BeforeEach(func() {
+		var err error
+		repo, err = testutil.NewGitRepo()
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(repo.WriteFile("README.md", "# Test")).To(Succeed())
+		Expect(repo.Commit("Initial commit")).To(Succeed())
+
+		_, _, err = testutil.RunShiftlogInDir(repo.Path, "init")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		repo.Cleanup()
+	})
+
+	storeOn := func(message, transcript string) {
+		Expect(repo.WriteFile(message+".txt", message)).To(Succeed())
+		Expect(repo.Commit(message)).To(Succeed())
+		transcriptPath := filepath.Join(repo.Path, "transcript.jsonl")
+		Expect(os.WriteFile(transcriptPath, []byte(transcript), 0644)).To(Succeed())
+		hookInput := testutil.SampleHookInput("session-diff", transcriptPath, "git commit -m 'test'")
+		_, _, err := testutil.RunShiftlogInDirWithStdin(repo.Path, hookInput, "store")
+		Expect(err).NotTo(HaveOccurred())
+	}
+
+	It("shows the entries added between two commits", func() {
+		first := `{"uuid":"u1","type":"user","message":{"role":"user","content":"Add a"}}
+{"uuid":"a1","type":"assistant","message":{"role":"assistant","content":[{"type":"text","text":"Added a"}]}}
+`
+		storeOn("a", first)
+		storeOn("b", first+`{"uuid":"u2","type":"user","message":{"role":"user","content":"Add b"}}
+{"uuid":"a2","type":"assistant","message":{"role":"assistant","content":[{"type":"text","text":"Added b"}]}}
+`)
+
+		stdout, _, err := testutil.RunShiftlogInDir(repo.Path, "diff-conversation", "HEAD~1", "HEAD", "--context", "0")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stdout).To(ContainSubstring("Session session-diff (claude): 2 added, 0 removed"))
+		Expect(stdout).To(ContainSubstring("@@ -2,0 +3,2 @@"))
+		Expect(stdout).To(ContainSubstring("+   Added b"))
+		Expect(stdout).NotTo(ContainSubstring("Added a"))
+	})
+
+	It("fails when the commits share no session", func() {
+		_, stderr, err := testutil.RunShiftlogInDir(repo.Path, "diff-conversation", "HEAD", "HEAD")
+		Expect(err).To(HaveOccurred())
+		Expect(stderr).To(ContainSubstring("no conversation found"))
+	})
+})
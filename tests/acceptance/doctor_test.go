@@ -46,6 +46,21 @@ var _ = Describe("Doctor Command", func() {
 			Expect(stdout).To(ContainSubstring("All git hooks installed"))
 		})
 
+		It("reports the default storage backend", func() {
+			stdout, _, _ := testutil.RunShiftlogInDir(repo.Path, "doctor")
+			Expect(stdout).To(ContainSubstring("Checking storage backend... OK"))
+			Expect(stdout).To(ContainSubstring("Backend: git-notes (refs/notes/shiftlog)"))
+		})
+
+		It("fails on an unknown storage backend", func() {
+			Expect(repo.WriteFile(".shiftlog/config", `{"backend": "floppy"}`)).To(Succeed())
+
+			stdout, _, err := testutil.RunShiftlogInDir(repo.Path, "doctor")
+			Expect(err).To(HaveOccurred())
+			Expect(stdout).To(ContainSubstring("Checking storage backend... FAIL"))
+			Expect(stdout).To(ContainSubstring(`unknown storage backend "floppy"`))
+		})
+
 		It("fails outside git repository", func() {
 			tmpDir, err := os.MkdirTemp("", "not-a-repo-*")
 			Expect(err).NotTo(HaveOccurred())
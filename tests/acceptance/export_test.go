@@ -0,0 +1,99 @@
+package acceptance_test
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/re-cinq/shift-log/tests/acceptance/testutil"
+)
+
+var _ = Describe("Export Command", func() {
+	var repo *testutil.GitRepo
+
+	BeforeEach(func() {
+		var err error
+		repo, err = testutil.NewGitRepo()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(repo.WriteFile("README.md", "# Test")).To(Succeed())
+		Expect(repo.Commit("Initial commit")).To(Succeed())
+
+		// A conversation whose tool output shows the repository's path, the
+		// environment and the author's email
+		lines := []map[string]any{
+			{"role": "user", "content": "Test User here, why does the build fail?"},
+			{"role": "tool", "name": "Bash", "input": map[string]any{"command": "env"},
+				"output": "PWD=" + repo.Path + "/src\nAPI_TOKEN=hunter2"},
+			{"role": "assistant", "content": "Ask test@example.com about " + repo.Path + "/src/main.go"},
+		}
+		var data []byte
+		for _, l := range lines {
+			line, err := json.Marshal(l)
+			Expect(err).NotTo(HaveOccurred())
+			data = append(append(data, line...), '\n')
+		}
+		chatPath := filepath.Join(GinkgoT().TempDir(), "chat.jsonl")
+		Expect(os.WriteFile(chatPath, data, 0644)).To(Succeed())
+		_, _, err = testutil.RunShiftlogInDir(repo.Path, "import", "--format", "jsonl", "--session", "private-session-42", chatPath)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		repo.Cleanup()
+	})
+
+	It("exports the conversations of a commit as JSON", func() {
+		stdout, _, err := testutil.RunShiftlogInDir(repo.Path, "export")
+		Expect(err).NotTo(HaveOccurred())
+
+		var doc struct {
+			Subject       string `json:"subject"`
+			Author        string `json:"author"`
+			Conversations []struct {
+				SessionID string            `json:"session_id"`
+				Entries   []json.RawMessage `json:"entries"`
+			} `json:"conversations"`
+		}
+		Expect(json.Unmarshal([]byte(stdout), &doc)).To(Succeed())
+		Expect(doc.Subject).To(Equal("Initial commit"))
+		Expect(doc.Author).To(Equal("Test User"))
+		Expect(doc.Conversations).To(HaveLen(1))
+		Expect(doc.Conversations[0].SessionID).To(Equal("private-session-42"))
+		Expect(doc.Conversations[0].Entries).NotTo(BeEmpty())
+	})
+
+	It("removes names, emails, paths, environment values and session IDs with --anonymize", func() {
+		stdout, _, err := testutil.RunShiftlogInDir(repo.Path, "export", "--anonymize")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(json.Valid([]byte(stdout))).To(BeTrue())
+
+		Expect(stdout).NotTo(ContainSubstring(repo.Path))
+		Expect(stdout).NotTo(ContainSubstring("private-session-42"))
+		Expect(stdout).NotTo(ContainSubstring("Test User"))
+		Expect(stdout).NotTo(ContainSubstring("test@example.com"))
+		Expect(stdout).NotTo(ContainSubstring("hunter2"))
+		Expect(stdout).To(ContainSubstring("src/main.go"))
+		Expect(stdout).To(ContainSubstring("API_TOKEN=[removed]"))
+		Expect(stdout).To(ContainSubstring("why does the build fail?"))
+	})
+
+	It("writes an anonymized HTML page", func() {
+		stdout, _, err := testutil.RunShiftlogInDir(repo.Path, "export", "--anonymize", "--format", "html")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stdout).To(HavePrefix("<!DOCTYPE html>"))
+		Expect(stdout).To(ContainSubstring("why does the build fail?"))
+		Expect(stdout).NotTo(ContainSubstring(repo.Path))
+		Expect(stdout).NotTo(ContainSubstring("hunter2"))
+	})
+
+	It("fails for a commit without conversations", func() {
+		Expect(repo.WriteFile("other.txt", "x")).To(Succeed())
+		Expect(repo.Commit("Second commit")).To(Succeed())
+		_, stderr, err := testutil.RunShiftlogInDir(repo.Path, "export")
+		Expect(err).To(HaveOccurred())
+		Expect(stderr).To(ContainSubstring("no conversation found"))
+	})
+})
@@ -0,0 +1,171 @@
+package acceptance_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/re-cinq/shift-log/tests/acceptance/testutil"
+)
+
+const gerritChangeID = "I0123456789abcdef0123456789abcdef01234567"
+
+// fakeGerrit is a Gerrit server with one change, whose patchsets are the
+// commits added to it, recording the messages posted on them.
+type fakeGerrit struct {
+	mu        sync.Mutex
+	patchsets []string
+	messages  []map[string]any
+	posted    []string
+}
+
+func (g *fakeGerrit) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if user, password, ok := r.BasicAuth(); !ok || user != "bot" || password != "secret" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	revisions := make(map[string]any)
+	for i, sha := range g.patchsets {
+		revisions[sha] = map[string]int{"_number": i + 1}
+	}
+	change := map[string]any{
+		"id":        "demo~master~" + gerritChangeID,
+		"change_id": gerritChangeID,
+		"_number":   1,
+		"revisions": revisions,
+		"messages":  g.messages,
+	}
+
+	switch {
+	case r.Method == http.MethodGet && r.URL.Path == "/a/changes/":
+		sha := strings.TrimPrefix(r.URL.Query().Get("q"), "commit:")
+		var changes []any
+		if revisions[sha] != nil {
+			changes = append(changes, change)
+		}
+		body, _ := json.Marshal(changes)
+		_, _ = fmt.Fprintf(w, ")]}'\n%s", body)
+	case r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/review"):
+		var review map[string]string
+		if err := json.NewDecoder(r.Body).Decode(&review); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		sha := strings.Split(r.URL.Path, "/")[5]
+		g.posted = append(g.posted, review["message"])
+		g.messages = append(g.messages, map[string]any{"tag": review["tag"], "_revision_number": revisions[sha].(map[string]int)["_number"]})
+		_, _ = io.WriteString(w, ")]}'\n{}")
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+var _ = Describe("shiftlog gerrit comment", func() {
+	var repo *testutil.GitRepo
+	var gerrit *fakeGerrit
+	var server *httptest.Server
+	var sha string
+	env := []string{"SHIFTLOG_GERRIT_USER=bot", "SHIFTLOG_GERRIT_PASSWORD=secret"}
+
+	BeforeEach(func() {
+		var err error
+		repo, err = testutil.NewGitRepo()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(repo.WriteFile("README.md", "# Test")).To(Succeed())
+		Expect(repo.Commit("Initial commit")).To(Succeed())
+
+		_, _, err = testutil.RunShiftlogInDir(repo.Path, "init")
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(repo.WriteFile("change.txt", "first patchset\n")).To(Succeed())
+		Expect(repo.Commit("Add a change\n\nChange-Id: " + gerritChangeID)).To(Succeed())
+		sha, err = repo.GetHead()
+		Expect(err).NotTo(HaveOccurred())
+
+		transcriptPath := filepath.Join(GinkgoT().TempDir(), "transcript.jsonl")
+		Expect(os.WriteFile(transcriptPath, []byte(testutil.SampleTranscript()), 0644)).To(Succeed())
+		hookInput := testutil.SampleHookInput("session-gerrit", transcriptPath, "git commit -m 'test'")
+		_, _, err = testutil.RunShiftlogInDirWithStdin(repo.Path, hookInput, "store")
+		Expect(err).NotTo(HaveOccurred())
+
+		gerrit = &fakeGerrit{patchsets: []string{sha}}
+		server = httptest.NewServer(gerrit)
+		Expect(repo.WriteFile(".shiftlog/config", fmt.Sprintf(`{"agent": "claude", "gerrit_url": %q, "viewer_url": "http://localhost:8080"}`, server.URL))).To(Succeed())
+	})
+
+	AfterEach(func() {
+		server.Close()
+		repo.Cleanup()
+	})
+
+	It("comments on the patchset once", func() {
+		stdout, _, err := testutil.RunShiftlogInDirWithEnv(repo.Path, env, "gerrit", "comment", "HEAD")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stdout).To(ContainSubstring("commented on change 1, patchset 1"))
+		Expect(gerrit.posted).To(HaveLen(1))
+		Expect(gerrit.posted[0]).To(HavePrefix("shiftlog: 1 conversation with"))
+		Expect(gerrit.posted[0]).To(ContainSubstring("http://localhost:8080/#/commit/" + sha))
+
+		stdout, _, err = testutil.RunShiftlogInDirWithEnv(repo.Path, env, "gerrit", "comment", "HEAD")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stdout).To(ContainSubstring("already commented on change 1, patchset 1"))
+		Expect(gerrit.posted).To(HaveLen(1))
+
+		_, _, err = testutil.RunShiftlogInDirWithEnv(repo.Path, env, "gerrit", "comment", "HEAD", "--force")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(gerrit.posted).To(HaveLen(2))
+	})
+
+	It("prints the message on a dry run", func() {
+		stdout, _, err := testutil.RunShiftlogInDir(repo.Path, "gerrit", "comment", "HEAD", "--dry-run")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stdout).To(ContainSubstring("  shiftlog: 1 conversation with"))
+		Expect(gerrit.posted).To(BeEmpty())
+	})
+
+	It("reports commits not uploaded and fails on a rejected password", func() {
+		Expect(repo.WriteFile("other.txt", "other\n")).To(Succeed())
+		Expect(repo.Commit("Not uploaded")).To(Succeed())
+		Expect(repo.Run("git", "notes", "--ref", "refs/notes/shiftlog", "copy", "HEAD~1", "HEAD")).To(Succeed())
+
+		stdout, _, err := testutil.RunShiftlogInDirWithEnv(repo.Path, env, "gerrit", "comment", "HEAD")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stdout).To(ContainSubstring("not uploaded to Gerrit"))
+
+		_, stderr, err := testutil.RunShiftlogInDirWithEnv(repo.Path, []string{"SHIFTLOG_GERRIT_USER=bot", "SHIFTLOG_GERRIT_PASSWORD=wrong"}, "gerrit", "comment", "HEAD~1")
+		Expect(err).To(HaveOccurred())
+		Expect(stderr).To(ContainSubstring("401"))
+	})
+
+	It("copies the conversation of an earlier patchset to an amended one", func() {
+		Expect(repo.WriteFile("change.txt", "second patchset\n")).To(Succeed())
+		Expect(repo.Run("git", "add", "-A")).To(Succeed())
+		Expect(repo.Run("git", "-c", "notes.rewrite.amend=false", "commit", "-q", "--amend", "--no-edit")).To(Succeed())
+		amended, err := repo.GetHead()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(repo.HasNote("refs/notes/shiftlog", amended)).To(BeFalse())
+		gerrit.patchsets = append(gerrit.patchsets, amended)
+
+		stdout, _, err := testutil.RunShiftlogInDir(repo.Path, "gerrit", "comment", "HEAD", "--dry-run")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stdout).To(ContainSubstring("would copy the conversation of " + sha[:7]))
+		Expect(repo.HasNote("refs/notes/shiftlog", amended)).To(BeFalse())
+
+		stdout, _, err = testutil.RunShiftlogInDirWithEnv(repo.Path, env, "gerrit", "comment", "HEAD")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stdout).To(ContainSubstring("copied the conversation of " + sha[:7]))
+		Expect(stdout).To(ContainSubstring("commented on change 1, patchset 2"))
+		Expect(repo.HasNote("refs/notes/shiftlog", amended)).To(BeTrue())
+	})
+})
@@ -0,0 +1,81 @@
+package acceptance_test
+
+import (
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/re-cinq/shift-log/tests/acceptance/testutil"
+)
+
+var _ = Describe("Git Log Format Command", func() {
+	var repo *testutil.GitRepo
+
+	BeforeEach(func() {
+		var err error
+		repo, err = testutil.NewGitRepo()
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(repo.WriteFile("README.md", "# Test")).To(Succeed())
+		Expect(repo.Commit("Initial commit")).To(Succeed())
+
+		_, _, err = testutil.RunShiftlogInDir(repo.Path, "init")
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(repo.WriteFile("a.txt", "a")).To(Succeed())
+		Expect(repo.Commit("Add a")).To(Succeed())
+		transcriptPath := filepath.Join(repo.Path, "transcript.jsonl")
+		Expect(os.WriteFile(transcriptPath, []byte(testutil.SampleTranscript()), 0644)).To(Succeed())
+		hookInput := testutil.SampleHookInput("session-a", transcriptPath, "git commit -m 'test'")
+		_, _, err = testutil.RunShiftlogInDirWithStdin(repo.Path, hookInput, "store")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		repo.Cleanup()
+	})
+
+	It("marks the commits with a conversation in piped git log output", func() {
+		log, err := repo.RunOutput("git", "log", "--oneline")
+		Expect(err).NotTo(HaveOccurred())
+
+		stdout, _, err := testutil.RunShiftlogInDirWithStdin(repo.Path, log, "git-log-format")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stdout).To(MatchRegexp(`(?m)^[0-9a-f]{7,} ✦ +[1-9]\d* Add a$`))
+		Expect(stdout).To(MatchRegexp(`(?m)^[0-9a-f]{7,} {7}Initial commit$`))
+	})
+
+	It("runs git log itself with the arguments after --", func() {
+		stdout, _, err := testutil.RunShiftlogInDir(repo.Path, "git-log-format", "--", "-1")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stdout).To(ContainSubstring("✦"))
+		Expect(stdout).NotTo(ContainSubstring("Initial commit"))
+	})
+
+	It("installs and removes the git shiftlog alias and log pager", func() {
+		_, _, err := testutil.RunShiftlogInDir(repo.Path, "git-log-format", "--install", "--pager")
+		Expect(err).NotTo(HaveOccurred())
+		alias, err := repo.RunOutput("git", "config", "alias.shiftlog")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(alias).To(ContainSubstring("git-log-format"))
+		pager, err := repo.RunOutput("git", "config", "pager.log")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pager).To(ContainSubstring("git-log-format"))
+
+		_, _, err = testutil.RunShiftlogInDir(repo.Path, "git-log-format", "--uninstall")
+		Expect(err).NotTo(HaveOccurred())
+		_, err = repo.RunOutput("git", "config", "alias.shiftlog")
+		Expect(err).To(HaveOccurred())
+		_, err = repo.RunOutput("git", "config", "pager.log")
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("keeps a pager.log it did not set", func() {
+		Expect(repo.Run("git", "config", "pager.log", "less")).To(Succeed())
+		_, stderr, err := testutil.RunShiftlogInDir(repo.Path, "git-log-format", "--install", "--pager")
+		Expect(err).To(HaveOccurred())
+		Expect(stderr).To(ContainSubstring("pager.log is already set"))
+	})
+})
@@ -0,0 +1,140 @@
+package acceptance_test
+
+import (
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/re-cinq/shift-log/tests/acceptance/testutil"
+)
+
+var _ = Describe("Git Hook Setups", func() {
+	var repo *testutil.GitRepo
+
+	BeforeEach(func() {
+		var err error
+		repo, err = testutil.NewGitRepo()
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		repo.Cleanup()
+	})
+
+	Describe("core.hooksPath", func() {
+		BeforeEach(func() {
+			Expect(repo.Run("git", "config", "core.hooksPath", ".githooks")).To(Succeed())
+		})
+
+		It("installs the hooks in core.hooksPath", func() {
+			stdout, _, err := testutil.RunShiftlogInDir(repo.Path, "init")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(stdout).To(ContainSubstring(".githooks (core.hooksPath)"))
+
+			for _, hook := range []string{"pre-push", "post-merge", "post-checkout", "post-commit"} {
+				content, err := repo.ReadFile(".githooks/" + hook)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(content).To(ContainSubstring("shiftlog-managed"))
+				Expect(content).To(ContainSubstring("command -v shiftlog"))
+			}
+			Expect(repo.FileExists(".git/hooks/post-commit")).To(BeFalse())
+
+			stdout, _, err = testutil.RunShiftlogInDir(repo.Path, "doctor")
+			Expect(stdout).To(ContainSubstring("All git hooks installed in .githooks"))
+		})
+
+		It("chains hooks it cannot append to and restores them on deinit", func() {
+			original := "#!/usr/bin/env python3\nprint('custom')\n"
+			Expect(os.MkdirAll(filepath.Join(repo.Path, ".githooks"), 0755)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(repo.Path, ".githooks", "post-commit"), []byte(original), 0755)).To(Succeed())
+
+			_, _, err := testutil.RunShiftlogInDir(repo.Path, "init")
+			Expect(err).NotTo(HaveOccurred())
+
+			content, err := repo.ReadFile(".githooks/post-commit")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(content).To(ContainSubstring("post-commit.shiftlog-chained"))
+			Expect(content).To(ContainSubstring("shiftlog-managed"))
+			chained, err := repo.ReadFile(".githooks/post-commit.shiftlog-chained")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(chained).To(Equal(original))
+
+			_, _, err = testutil.RunShiftlogInDir(repo.Path, "deinit")
+			Expect(err).NotTo(HaveOccurred())
+
+			content, err = repo.ReadFile(".githooks/post-commit")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(content).To(Equal(original))
+			Expect(repo.FileExists(".githooks/post-commit.shiftlog-chained")).To(BeFalse())
+		})
+	})
+
+	Describe("Husky", func() {
+		BeforeEach(func() {
+			Expect(os.MkdirAll(filepath.Join(repo.Path, ".husky", "_"), 0755)).To(Succeed())
+			Expect(repo.WriteFile(".husky/pre-push", "npm test\n")).To(Succeed())
+		})
+
+		It("adds the hooks to the scripts in .husky", func() {
+			Expect(repo.Run("git", "config", "core.hooksPath", ".husky/_")).To(Succeed())
+
+			_, _, err := testutil.RunShiftlogInDir(repo.Path, "init")
+			Expect(err).NotTo(HaveOccurred())
+
+			content, err := repo.ReadFile(".husky/pre-push")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(content).To(HavePrefix("npm test\n"))
+			Expect(content).To(ContainSubstring("shiftlog sync push"))
+			Expect(repo.FileExists(".husky/post-commit")).To(BeTrue())
+
+			stdout, _, err := testutil.RunShiftlogInDir(repo.Path, "doctor")
+			Expect(stdout).To(ContainSubstring("All git hooks installed in .husky (Husky)"))
+		})
+
+		It("reports in doctor when Husky is not installed", func() {
+			_, _, err := testutil.RunShiftlogInDir(repo.Path, "init")
+			Expect(err).NotTo(HaveOccurred())
+
+			stdout, _, err := testutil.RunShiftlogInDir(repo.Path, "doctor")
+			Expect(err).To(HaveOccurred())
+			Expect(stdout).To(ContainSubstring("Husky's hooks do not run"))
+		})
+	})
+
+	Describe("Lefthook", func() {
+		BeforeEach(func() {
+			Expect(repo.WriteFile("lefthook.yml", "pre-commit:\n  commands:\n    lint:\n      run: echo lint\n")).To(Succeed())
+		})
+
+		It("writes the commands to lefthook-local.yml", func() {
+			_, _, err := testutil.RunShiftlogInDir(repo.Path, "init")
+			Expect(err).NotTo(HaveOccurred())
+
+			content, err := repo.ReadFile("lefthook-local.yml")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(content).To(HavePrefix("# shiftlog-managed"))
+			Expect(content).To(ContainSubstring("post-commit:"))
+			Expect(content).To(ContainSubstring("store --manual"))
+
+			_, _, err = testutil.RunShiftlogInDir(repo.Path, "deinit")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(repo.FileExists("lefthook-local.yml")).To(BeFalse())
+			Expect(repo.FileExists("lefthook.yml")).To(BeTrue())
+		})
+
+		It("prints a snippet instead of changing a lefthook-local.yml it does not own", func() {
+			Expect(repo.WriteFile("lefthook-local.yml", "pre-commit: {}\n")).To(Succeed())
+
+			_, stderr, err := testutil.RunShiftlogInDir(repo.Path, "init")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(stderr).To(ContainSubstring("not managed by shiftlog"))
+			Expect(stderr).To(ContainSubstring("store --manual"))
+
+			content, err := repo.ReadFile("lefthook-local.yml")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(content).To(Equal("pre-commit: {}\n"))
+		})
+	})
+})
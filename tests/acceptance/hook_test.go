@@ -0,0 +1,86 @@
+package acceptance_test
+
+import (
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/re-cinq/shift-log/tests/acceptance/testutil"
+)
+
+var _ = Describe("Hook Run Command", func() {
+	var repo *testutil.GitRepo
+	var transcriptPath string
+
+	BeforeEach(func() {
+		var err error
+		repo, err = testutil.NewGitRepo()
+		Expect(err).NotTo(HaveOccurred())
+
+		_, _, err = testutil.RunShiftlogInDir(repo.Path, "init")
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(repo.WriteFile("README.md", "# Test")).To(Succeed())
+		Expect(repo.Commit("Initial commit")).To(Succeed())
+
+		transcriptPath = filepath.Join(repo.Path, "transcript.jsonl")
+		Expect(repo.WriteFile("transcript.jsonl", testutil.SampleTranscript())).To(Succeed())
+	})
+
+	AfterEach(func() {
+		repo.Cleanup()
+	})
+
+	Describe("--dry-run", func() {
+		It("reports the note it would write without writing it", func() {
+			input := testutil.SampleHookInput("dry-session", transcriptPath, "git commit -m 'test'")
+			stdout, _, err := testutil.RunShiftlogInDirWithStdin(repo.Path, input, "hook", "run", "--dry-run")
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(stdout).To(MatchRegexp(`Parsers:\s+.*claude \(commit\)`))
+			Expect(stdout).To(MatchRegexp(`Session:\s+dry-session`))
+			Expect(stdout).To(MatchRegexp(`Commit:\s+yes`))
+			Expect(stdout).To(ContainSubstring("would write a"))
+
+			head, err := repo.GetHead()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(repo.HasNote("refs/notes/shiftlog", head)).To(BeFalse())
+		})
+
+		It("reports payloads that are not commits", func() {
+			input := testutil.SampleHookInputNonBash("dry-session")
+			stdout, _, err := testutil.RunShiftlogInDirWithStdin(repo.Path, input, "hook", "run", "--dry-run")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(stdout).To(MatchRegexp(`Commit:\s+no`))
+			Expect(stdout).To(ContainSubstring("would skip: not a git commit command"))
+		})
+
+		It("reports payloads that cannot be parsed", func() {
+			stdout, _, err := testutil.RunShiftlogInDirWithStdin(repo.Path, "not json", "hook", "run", "--dry-run")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(stdout).To(ContainSubstring("none recognise the payload"))
+			Expect(stdout).To(ContainSubstring("cannot parse the payload"))
+		})
+
+		It("reports conversations already stored", func() {
+			input := testutil.SampleHookInput("dry-session", transcriptPath, "git commit -m 'test'")
+			_, _, err := testutil.RunShiftlogInDirWithStdin(repo.Path, input, "store")
+			Expect(err).NotTo(HaveOccurred())
+
+			stdout, _, err := testutil.RunShiftlogInDirWithStdin(repo.Path, input, "hook", "run", "--dry-run")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(stdout).To(ContainSubstring("would skip: conversation already stored"))
+		})
+	})
+
+	It("stores the conversation without --dry-run", func() {
+		input := testutil.SampleHookInput("run-session", transcriptPath, "git commit -m 'test'")
+		_, _, err := testutil.RunShiftlogInDirWithStdin(repo.Path, input, "hook", "run")
+		Expect(err).NotTo(HaveOccurred())
+
+		head, err := repo.GetHead()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(repo.HasNote("refs/notes/shiftlog", head)).To(BeTrue())
+	})
+})
@@ -0,0 +1,82 @@
+package acceptance_test
+
+import (
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/re-cinq/shift-log/tests/acceptance/testutil"
+)
+
+const aiderHistory = `
+# aider chat started at 2024-05-01 10:00:00
+
+#### add a retry to the upload client
+
+I'll wrap the upload in a retry loop with backoff.
+
+> Applied edit to client.go
+`
+
+var _ = Describe("Import Command", func() {
+	var repo *testutil.GitRepo
+	var historyPath string
+
+	BeforeEach(func() {
+		var err error
+		repo, err = testutil.NewGitRepo()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(repo.WriteFile("README.md", "# Test")).To(Succeed())
+		Expect(repo.Commit("Initial commit")).To(Succeed())
+
+		historyPath = filepath.Join(GinkgoT().TempDir(), ".aider.chat.history.md")
+		Expect(os.WriteFile(historyPath, []byte(aiderHistory), 0644)).To(Succeed())
+	})
+
+	AfterEach(func() {
+		repo.Cleanup()
+	})
+
+	It("stores an aider chat history on the commit", func() {
+		_, stderr, err := testutil.RunShiftlogInDir(repo.Path, "import", "--format", "aider", historyPath)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stderr).To(ContainSubstring("stored conversation"))
+
+		note, err := repo.GetNote("refs/notes/shiftlog", "HEAD")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(note).To(ContainSubstring(`"agent": "aider"`))
+		Expect(note).To(ContainSubstring(`"trigger": "import"`))
+
+		stdout, _, err := testutil.RunShiftlogInDir(repo.Path, "show")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stdout).To(ContainSubstring("add a retry to the upload client"))
+		Expect(stdout).To(ContainSubstring("retry loop with backoff"))
+	})
+
+	It("stores the same file only once", func() {
+		_, _, err := testutil.RunShiftlogInDir(repo.Path, "import", "--format", "aider", historyPath)
+		Expect(err).NotTo(HaveOccurred())
+		_, stderr, err := testutil.RunShiftlogInDir(repo.Path, "import", "--format", "aider", historyPath)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stderr).To(ContainSubstring("already stored"))
+	})
+
+	It("records the agent given for generic JSONL", func() {
+		jsonlPath := filepath.Join(GinkgoT().TempDir(), "chat.jsonl")
+		Expect(os.WriteFile(jsonlPath, []byte(`{"role":"user","content":"hello from jsonl"}`+"\n"), 0644)).To(Succeed())
+
+		_, _, err := testutil.RunShiftlogInDir(repo.Path, "import", "--format", "jsonl", "--agent", "cursor", jsonlPath)
+		Expect(err).NotTo(HaveOccurred())
+		note, err := repo.GetNote("refs/notes/shiftlog", "HEAD")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(note).To(ContainSubstring(`"agent": "cursor"`))
+	})
+
+	It("rejects unknown formats", func() {
+		_, stderr, err := testutil.RunShiftlogInDir(repo.Path, "import", "--format", "zip", historyPath)
+		Expect(err).To(HaveOccurred())
+		Expect(stderr).To(ContainSubstring("unknown import format"))
+	})
+})
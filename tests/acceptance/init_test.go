@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"os"
 	"path/filepath"
+	"strings"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
@@ -46,7 +47,7 @@ var _ = Describe("Init Command", func() {
 
 					var cfg map[string]interface{}
 					Expect(json.Unmarshal([]byte(content), &cfg)).To(Succeed())
-					Expect(cfg["agent"]).To(Equal("codex"))
+					Expect(cfg["agent"]).To(Equal(strings.TrimPrefix(config.InitArgs[1], "--agent=")))
 				})
 			}
 
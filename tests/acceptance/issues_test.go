@@ -0,0 +1,118 @@
+package acceptance_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/re-cinq/shift-log/tests/acceptance/testutil"
+)
+
+var _ = Describe("Issue linking", func() {
+	var repo *testutil.GitRepo
+	var hookInput string
+
+	BeforeEach(func() {
+		var err error
+		repo, err = testutil.NewGitRepo()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(repo.WriteFile("README.md", "# Test")).To(Succeed())
+		Expect(repo.Commit("Initial commit")).To(Succeed())
+
+		_, _, err = testutil.RunShiftlogInDir(repo.Path, "init")
+		Expect(err).NotTo(HaveOccurred())
+
+		transcriptPath := filepath.Join(GinkgoT().TempDir(), "transcript.jsonl")
+		Expect(os.WriteFile(transcriptPath, []byte(testutil.SampleTranscript()), 0644)).To(Succeed())
+		hookInput = testutil.SampleHookInput("session-issue", transcriptPath, "git commit -m 'test'")
+	})
+
+	AfterEach(func() {
+		repo.Cleanup()
+	})
+
+	// commitAndStore commits with message and stores the conversation on it.
+	commitAndStore := func(file, message string) string {
+		Expect(repo.WriteFile(file, message)).To(Succeed())
+		Expect(repo.Commit(message)).To(Succeed())
+		_, _, err := testutil.RunShiftlogInDirWithStdin(repo.Path, hookInput, "store")
+		Expect(err).NotTo(HaveOccurred())
+		sha, err := repo.GetHead()
+		Expect(err).NotTo(HaveOccurred())
+		return sha
+	}
+
+	It("records the issue keys of the commit message and lists their conversations", func() {
+		sha := commitAndStore("a.txt", "PROJ-12: retry uploads\n\nRefs: ENG-3")
+		commitAndStore("b.txt", "Unrelated change")
+
+		note, err := repo.GetNote("refs/notes/shiftlog", sha)
+		Expect(err).NotTo(HaveOccurred())
+		var sc map[string]any
+		Expect(json.Unmarshal([]byte(note), &sc)).To(Succeed())
+		Expect(sc["issues"]).To(Equal([]any{"ENG-3", "PROJ-12"}))
+
+		stdout, _, err := testutil.RunShiftlogInDir(repo.Path, "issues", "PROJ-12")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stdout).To(ContainSubstring(sha[:7]))
+		Expect(stdout).To(ContainSubstring("PROJ-12: retry uploads"))
+		Expect(stdout).NotTo(ContainSubstring("Unrelated"))
+
+		stdout, _, err = testutil.RunShiftlogInDir(repo.Path, "issues", "PROJ-99")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stdout).To(ContainSubstring("no conversations refer to PROJ-99"))
+	})
+
+	It("matches the configured issue pattern", func() {
+		Expect(repo.WriteFile(".shiftlog/config", `{"agent": "claude", "issue_pattern": "#[0-9]+"}`)).To(Succeed())
+		sha := commitAndStore("a.txt", "Fix the login (#42)")
+
+		stdout, _, err := testutil.RunShiftlogInDir(repo.Path, "issues", "#42")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stdout).To(ContainSubstring(sha[:7]))
+	})
+
+	It("comments on the issue in Jira", func() {
+		var comments []string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if user, token, ok := r.BasicAuth(); !ok || user != "ada@example.com" || token != "secret" {
+				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+				return
+			}
+			if r.URL.Path != "/rest/api/2/issue/PROJ-12/comment" {
+				http.NotFound(w, r)
+				return
+			}
+			var body map[string]string
+			Expect(json.NewDecoder(r.Body).Decode(&body)).To(Succeed())
+			comments = append(comments, body["body"])
+			w.WriteHeader(http.StatusCreated)
+		}))
+		defer server.Close()
+		Expect(repo.WriteFile(".shiftlog/config", fmt.Sprintf(`{"agent": "claude", "issue_tracker": "jira", "issue_tracker_url": %q, "viewer_url": "http://localhost:8080"}`, server.URL))).To(Succeed())
+		sha := commitAndStore("a.txt", "PROJ-12: retry uploads")
+
+		stdout, _, err := testutil.RunShiftlogInDir(repo.Path, "issues", "PROJ-12", "--comment", "--dry-run")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stdout).To(ContainSubstring("shiftlog: 1 commit with AI conversations"))
+		Expect(stdout).To(ContainSubstring("http://localhost:8080/#/commit/" + sha))
+		Expect(comments).To(BeEmpty())
+
+		env := []string{"SHIFTLOG_ISSUE_USER=ada@example.com", "SHIFTLOG_ISSUE_TOKEN=secret"}
+		stdout, _, err = testutil.RunShiftlogInDirWithEnv(repo.Path, env, "issues", "PROJ-12", "--comment")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stdout).To(ContainSubstring("commented on PROJ-12"))
+		Expect(comments).To(HaveLen(1))
+		Expect(comments[0]).To(ContainSubstring(sha[:7] + " PROJ-12: retry uploads"))
+
+		_, stderr, err := testutil.RunShiftlogInDirWithEnv(repo.Path, []string{"SHIFTLOG_ISSUE_USER=ada@example.com", "SHIFTLOG_ISSUE_TOKEN=wrong"}, "issues", "PROJ-12", "--comment")
+		Expect(err).To(HaveOccurred())
+		Expect(stderr).To(ContainSubstring("401"))
+	})
+})
@@ -0,0 +1,157 @@
+package acceptance_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/re-cinq/shift-log/tests/acceptance/testutil"
+)
+
+var _ = Describe("Log Command", func() {
+	var repo *testutil.GitRepo
+
+	BeforeEach(func() {
+		var err error
+		repo, err = testutil.NewGitRepo()
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(repo.WriteFile("README.md", "# Test")).To(Succeed())
+		Expect(repo.Commit("Initial commit")).To(Succeed())
+	})
+
+	AfterEach(func() {
+		if repo != nil {
+			repo.Cleanup()
+		}
+	})
+
+	// Helper to store a transcript on the current commit via the hook
+	storeTranscript := func(sessionID, transcript string) {
+		transcriptPath := filepath.Join(os.TempDir(), sessionID+".jsonl")
+		Expect(os.WriteFile(transcriptPath, []byte(transcript), 0644)).To(Succeed())
+		DeferCleanup(os.Remove, transcriptPath)
+
+		hookInput := testutil.SampleHookInput(sessionID, transcriptPath, "git commit -m 'test'")
+		_, _, err := testutil.RunShiftlogInDirWithStdin(repo.Path, hookInput, "store")
+		Expect(err).NotTo(HaveOccurred())
+	}
+
+	// repoFile returns the absolute path of a file as git reports the repo root
+	repoFile := func(name string) string {
+		root, err := repo.RunOutput("git", "rev-parse", "--show-toplevel")
+		Expect(err).NotTo(HaveOccurred())
+		return filepath.Join(strings.TrimSpace(root), name)
+	}
+
+	Describe("--file", func() {
+		BeforeEach(func() {
+			Expect(repo.WriteFile("src/app.go", "package app\n\nfunc greet() {}\n")).To(Succeed())
+			Expect(repo.Commit("Rename greeting")).To(Succeed())
+			storeTranscript("session-log-1", testutil.SampleEditTranscript(repoFile("src/app.go")))
+		})
+
+		It("records the edited files in the note", func() {
+			head, err := repo.GetHead()
+			Expect(err).NotTo(HaveOccurred())
+
+			note, err := repo.GetNote("refs/notes/shiftlog", head)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(note).To(ContainSubstring(`"files_touched"`))
+			Expect(note).To(ContainSubstring(`"src/app.go"`))
+		})
+
+		It("shows commits touching the file with the edit excerpt", func() {
+			stdout, _, err := testutil.RunShiftlogInDir(repo.Path, "log", "--file", "src/app.go")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(stdout).To(ContainSubstring("Rename greeting"))
+			Expect(stdout).To(ContainSubstring("Please rename the greeting function"))
+			Expect(stdout).To(ContainSubstring("[tool: Edit]"))
+		})
+
+		It("resolves paths relative to the current directory", func() {
+			stdout, _, err := testutil.RunShiftlogInDir(filepath.Join(repo.Path, "src"), "log", "--file", "app.go")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(stdout).To(ContainSubstring("Rename greeting"))
+		})
+
+		It("lists commits that touched the file without an agent edit", func() {
+			Expect(repo.WriteFile("src/app.go", "package app\n\nfunc greet() { println() }\n")).To(Succeed())
+			Expect(repo.Commit("Manual tweak")).To(Succeed())
+			storeTranscript("session-log-2", testutil.SampleTranscript())
+
+			stdout, _, err := testutil.RunShiftlogInDir(repo.Path, "log", "--file", "src/app.go")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(stdout).To(ContainSubstring("Manual tweak"))
+			Expect(stdout).To(ContainSubstring("file not edited in this conversation"))
+			Expect(strings.Index(stdout, "Manual tweak")).To(BeNumerically("<", strings.Index(stdout, "Rename greeting")))
+		})
+
+		It("reports when no conversations touch the file", func() {
+			stdout, _, err := testutil.RunShiftlogInDir(repo.Path, "log", "--file", "README.md")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(stdout).To(ContainSubstring("no conversations found"))
+		})
+	})
+
+	Describe("commit listing", func() {
+		BeforeEach(func() {
+			Expect(repo.WriteFile("app.go", "package app\n")).To(Succeed())
+			Expect(repo.Commit("Add app")).To(Succeed())
+			storeTranscript("session-log-list", testutil.SampleTranscript())
+			Expect(repo.WriteFile("app.go", "package app\n\n// tweak\n")).To(Succeed())
+			Expect(repo.Commit("Manual tweak")).To(Succeed())
+		})
+
+		It("lists commits with conversation columns", func() {
+			stdout, _, err := testutil.RunShiftlogInDir(repo.Path, "log")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(stdout).To(ContainSubstring("Manual tweak"))
+			Expect(stdout).To(ContainSubstring("Initial commit"))
+			Expect(stdout).To(MatchRegexp(`\* +\d+ msgs .*claude +Add app`))
+			Expect(strings.Index(stdout, "Manual tweak")).To(BeNumerically("<", strings.Index(stdout, "Add app")))
+		})
+
+		It("only lists commits with a conversation with --has-conversation", func() {
+			stdout, _, err := testutil.RunShiftlogInDir(repo.Path, "log", "--has-conversation")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(stdout).To(ContainSubstring("Add app"))
+			Expect(stdout).NotTo(ContainSubstring("Manual tweak"))
+			Expect(stdout).NotTo(ContainSubstring("Initial commit"))
+		})
+
+		It("honours --limit", func() {
+			stdout, _, err := testutil.RunShiftlogInDir(repo.Path, "log", "--limit", "1")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(stdout).To(ContainSubstring("Manual tweak"))
+			Expect(stdout).NotTo(ContainSubstring("Add app"))
+		})
+
+		It("outputs JSON with --format json", func() {
+			stdout, _, err := testutil.RunShiftlogInDir(repo.Path, "log", "--format", "json")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(stdout).To(ContainSubstring(`"message": "Add app"`))
+			Expect(stdout).To(ContainSubstring(`"has_conversation": true`))
+			Expect(stdout).To(ContainSubstring(`"agents": [`))
+		})
+
+		It("outputs tab-separated values with --format tsv", func() {
+			stdout, _, err := testutil.RunShiftlogInDir(repo.Path, "log", "--format", "tsv")
+			Expect(err).NotTo(HaveOccurred())
+			lines := strings.Split(strings.TrimSpace(stdout), "\n")
+			Expect(lines).To(HaveLen(4))
+			Expect(lines[0]).To(HavePrefix("sha\tmessage\t"))
+			Expect(lines[2]).To(ContainSubstring("\tAdd app\t"))
+			Expect(lines[2]).To(ContainSubstring("\ttrue\t"))
+		})
+
+		It("rejects an unknown format", func() {
+			_, stderr, err := testutil.RunShiftlogInDir(repo.Path, "log", "--format", "xml")
+			Expect(err).To(HaveOccurred())
+			Expect(stderr).To(ContainSubstring("invalid --format"))
+		})
+	})
+})
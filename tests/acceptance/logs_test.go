@@ -0,0 +1,74 @@
+package acceptance_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/re-cinq/shift-log/tests/acceptance/testutil"
+)
+
+var _ = Describe("Logs Command", func() {
+	var repo *testutil.GitRepo
+
+	BeforeEach(func() {
+		var err error
+		repo, err = testutil.NewGitRepo()
+		Expect(err).NotTo(HaveOccurred())
+
+		_, _, err = testutil.RunShiftlogInDir(repo.Path, "init")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		repo.Cleanup()
+	})
+
+	It("reports an empty log", func() {
+		stdout, _, err := testutil.RunShiftlogInDir(repo.Path, "logs")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stdout).To(ContainSubstring("no log entries yet"))
+	})
+
+	It("records hook runs without debug logging", func() {
+		_, _, err := testutil.RunShiftlogInDir(repo.Path, "store", "--manual")
+		Expect(err).NotTo(HaveOccurred())
+
+		stdout, _, err := testutil.RunShiftlogInDir(repo.Path, "logs")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stdout).To(ContainSubstring(`msg=invoked`))
+		Expect(stdout).To(ContainSubstring(`cmd="shiftlog store"`))
+		Expect(stdout).To(ContainSubstring(`msg=finished`))
+		Expect(stdout).NotTo(ContainSubstring("level=DEBUG"))
+	})
+
+	It("does not record other commands without debug logging", func() {
+		_, _, err := testutil.RunShiftlogInDir(repo.Path, "list")
+		Expect(err).NotTo(HaveOccurred())
+
+		stdout, _, err := testutil.RunShiftlogInDir(repo.Path, "logs")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stdout).NotTo(ContainSubstring(`cmd="shiftlog list"`))
+	})
+
+	It("records every command and the git commands run with --verbose", func() {
+		_, _, err := testutil.RunShiftlogInDir(repo.Path, "list", "--verbose")
+		Expect(err).NotTo(HaveOccurred())
+
+		stdout, _, err := testutil.RunShiftlogInDir(repo.Path, "logs", "-n", "0")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stdout).To(ContainSubstring(`cmd="shiftlog list"`))
+		Expect(stdout).To(ContainSubstring("msg=git"))
+	})
+
+	It("turns on debug logging with SHIFTLOG_DEBUG", func() {
+		_, stderr, err := testutil.RunShiftlogInDirWithEnv(repo.Path, []string{"SHIFTLOG_DEBUG=1"}, "store", "--manual")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stderr).To(ContainSubstring("shiftlog: debug:"))
+	})
+
+	It("prints the log path", func() {
+		stdout, _, err := testutil.RunShiftlogInDir(repo.Path, "logs", "--path")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stdout).To(ContainSubstring(".shiftlog/logs/shiftlog.log"))
+	})
+})
@@ -82,6 +82,7 @@ var _ = Describe("Manual Store Command", func() {
 			// Verify note was created (this is the key assertion)
 			noteOutput, _ := repo.RunOutput("git", "notes", "--ref=refs/notes/shiftlog", "show", "HEAD")
 			Expect(noteOutput).To(ContainSubstring("test-session-123"))
+			Expect(noteOutput).To(ContainSubstring(`"trigger": "post-commit"`))
 		})
 
 		It("skips storage when same session already stored (idempotent)", func() {
@@ -121,7 +122,7 @@ var _ = Describe("Manual Store Command", func() {
 			Expect(stderr2).To(ContainSubstring("already stored"))
 		})
 
-		It("overwrites when different session stored", func() {
+		It("adds a different session to the existing note", func() {
 			// Create first transcript file
 			transcriptPath1 := filepath.Join(os.TempDir(), "overwrite-test1.jsonl")
 			transcriptContent1 := `{"type":"user","message":{"role":"user","content":[{"type":"text","text":"first session"}]}}`
@@ -170,15 +171,18 @@ var _ = Describe("Manual Store Command", func() {
 			sessionData2, _ := json.MarshalIndent(activeSession2, "", "  ")
 			os.WriteFile(filepath.Join(shiftlogDir, "active-session.json"), sessionData2, 0644)
 
-			// Second store should overwrite
+			// Second store should add the session to the note
 			_, stderr2, err := testutil.RunShiftlogInDir(repo.Path, "store", "--manual")
 			Expect(err).NotTo(HaveOccurred())
 			Expect(stderr2).To(ContainSubstring("stored conversation"))
 
-			// Verify second session stored
+			// Verify both sessions stored, first one first
 			noteOutput2, _ := repo.RunOutput("git", "notes", "--ref=refs/notes/shiftlog", "show", "HEAD")
-			Expect(noteOutput2).To(ContainSubstring("second-session"))
-			Expect(noteOutput2).NotTo(ContainSubstring("first-session"))
+			var stored []map[string]interface{}
+			Expect(json.Unmarshal([]byte(noteOutput2), &stored)).To(Succeed())
+			Expect(stored).To(HaveLen(2))
+			Expect(stored[0]["session_id"]).To(Equal("first-session"))
+			Expect(stored[1]["session_id"]).To(Equal("second-session"))
 		})
 
 		It("skips when project path doesn't match", func() {
@@ -0,0 +1,141 @@
+package acceptance_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/re-cinq/shift-log/tests/acceptance/testutil"
+)
+
+var _ = Describe("Merge Commits", func() {
+	var repo *testutil.GitRepo
+
+	BeforeEach(func() {
+		var err error
+		repo, err = testutil.NewGitRepo()
+		Expect(err).NotTo(HaveOccurred())
+		repo.SetBinaryPath(testutil.BinaryPath())
+
+		Expect(repo.WriteFile("README.md", "# Test")).To(Succeed())
+		Expect(repo.Commit("Initial commit")).To(Succeed())
+
+		_, _, err = testutil.RunShiftlogInDir(repo.Path, "init")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		if repo != nil {
+			repo.Cleanup()
+		}
+	})
+
+	// storeVia runs the store hook for a shell command in session sessionID.
+	storeVia := func(sessionID, command string) {
+		transcriptPath := filepath.Join(repo.Path, ".git", "transcript.jsonl")
+		Expect(os.WriteFile(transcriptPath, []byte(testutil.SampleTranscript()), 0644)).To(Succeed())
+		hookInput := testutil.SampleHookInput(sessionID, transcriptPath, command)
+		_, _, err := testutil.RunShiftlogInDirWithStdin(repo.Path, hookInput, "store")
+		Expect(err).NotTo(HaveOccurred())
+	}
+
+	// featureWithConversation commits on a feature branch with a stored
+	// conversation, then returns to master with a diverging commit.
+	featureWithConversation := func(file string) string {
+		Expect(repo.Run("git", "checkout", "-b", "feature")).To(Succeed())
+		Expect(repo.WriteFile(file, "feature\n")).To(Succeed())
+		Expect(repo.Commit("Feature commit")).To(Succeed())
+		storeVia("feature-session", "git commit -m 'Feature commit'")
+		featureSHA, err := repo.GetHead()
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(repo.Run("git", "checkout", "master")).To(Succeed())
+		Expect(repo.WriteFile("main.txt", "main\n")).To(Succeed())
+		Expect(repo.Commit("Mainline commit")).To(Succeed())
+		return featureSHA
+	}
+
+	mergeRecord := func(sha string) string {
+		out, err := repo.RunOutput("git", "notes", "--ref", "refs/notes/shiftlog-merges", "show", sha)
+		Expect(err).NotTo(HaveOccurred())
+		return out
+	}
+
+	It("records the merged branch's conversations on the merge commit", func() {
+		featureSHA := featureWithConversation("feature.txt")
+
+		// The post-merge hook records the merge
+		Expect(repo.Run("git", "merge", "--no-ff", "--no-edit", "feature")).To(Succeed())
+		Expect(repo.Run("git", "branch", "-D", "feature")).To(Succeed())
+		mergeSHA, err := repo.GetHead()
+		Expect(err).NotTo(HaveOccurred())
+
+		record := mergeRecord(mergeSHA)
+		Expect(record).To(ContainSubstring(`"commit":"` + featureSHA + `"`))
+		Expect(record).To(ContainSubstring(`"session_id":"feature-session"`))
+		Expect(record).To(ContainSubstring(`"branch":"feature"`))
+	})
+
+	It("records a merge concluded with git commit after a conflict", func() {
+		featureSHA := featureWithConversation("main.txt")
+
+		Expect(repo.Run("git", "merge", "--no-ff", "feature")).NotTo(Succeed())
+		Expect(repo.WriteFile("main.txt", "resolved\n")).To(Succeed())
+		// The post-commit hook records the merge
+		Expect(repo.Commit("Merge feature")).To(Succeed())
+		mergeSHA, err := repo.GetHead()
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(mergeRecord(mergeSHA)).To(ContainSubstring(`"commit":"` + featureSHA + `"`))
+	})
+
+	It("stores the agent's conversation on a merge commit it created", func() {
+		featureWithConversation("feature.txt")
+
+		Expect(repo.Run("git", "merge", "--no-ff", "--no-edit", "feature")).To(Succeed())
+		storeVia("merge-session", "git merge --no-ff feature")
+		mergeSHA, err := repo.GetHead()
+		Expect(err).NotTo(HaveOccurred())
+
+		note, err := repo.GetNote("refs/notes/shiftlog", mergeSHA)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(note).To(ContainSubstring(`"merge-session"`))
+	})
+
+	It("does not store a conversation for a fast-forward merge", func() {
+		Expect(repo.Run("git", "checkout", "-b", "feature")).To(Succeed())
+		Expect(repo.WriteFile("feature.txt", "feature\n")).To(Succeed())
+		Expect(repo.Commit("Feature commit")).To(Succeed())
+		featureSHA, err := repo.GetHead()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(repo.Run("git", "checkout", "master")).To(Succeed())
+
+		Expect(repo.Run("git", "merge", "feature")).To(Succeed())
+		storeVia("ff-session", "git merge feature")
+
+		Expect(repo.HasNote("refs/notes/shiftlog", featureSHA)).To(BeFalse())
+	})
+
+	It("pushes and pulls merge records with the notes", func() {
+		remote, err := testutil.NewGitRepoAsBare()
+		Expect(err).NotTo(HaveOccurred())
+		defer remote.Cleanup()
+		Expect(repo.AddRemote("origin", remote.Path)).To(Succeed())
+
+		featureWithConversation("feature.txt")
+		Expect(repo.Run("git", "merge", "--no-ff", "--no-edit", "feature")).To(Succeed())
+		mergeSHA, err := repo.GetHead()
+		Expect(err).NotTo(HaveOccurred())
+
+		stdout, _, err := testutil.RunShiftlogInDir(repo.Path, "sync", "push")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stdout).To(ContainSubstring("Pushed merge records to origin"))
+
+		out, err := remote.RunOutput("git", "notes", "--ref", "refs/notes/shiftlog-merges", "list")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(strings.Fields(out)).To(ContainElement(mergeSHA))
+	})
+})
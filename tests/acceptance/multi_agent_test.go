@@ -0,0 +1,80 @@
+package acceptance_test
+
+import (
+	"encoding/json"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/re-cinq/shift-log/tests/acceptance/testutil"
+)
+
+var _ = Describe("Multiple Agents", func() {
+	var repo *testutil.GitRepo
+
+	BeforeEach(func() {
+		var err error
+		repo, err = testutil.NewGitRepo()
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(repo.WriteFile("README.md", "# Test")).To(Succeed())
+		Expect(repo.Commit("Initial commit")).To(Succeed())
+	})
+
+	AfterEach(func() {
+		repo.Cleanup()
+	})
+
+	It("configures every agent given to --agent", func() {
+		stdout, _, err := testutil.RunShiftlogInDir(repo.Path, "init", "--agent=claude,gemini")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stdout).To(ContainSubstring("Configured Claude Code hooks"))
+		Expect(stdout).To(ContainSubstring("Configured Gemini CLI hooks"))
+
+		Expect(repo.FileExists(".claude/settings.local.json")).To(BeTrue())
+		Expect(repo.FileExists(".gemini/settings.json")).To(BeTrue())
+
+		content, err := repo.ReadFile(".shiftlog/config")
+		Expect(err).NotTo(HaveOccurred())
+		var cfg map[string]interface{}
+		Expect(json.Unmarshal([]byte(content), &cfg)).To(Succeed())
+		Expect(cfg["agent"]).To(Equal("claude"))
+		Expect(cfg["agents"]).To(Equal([]interface{}{"claude", "gemini"}))
+	})
+
+	It("configures every supported agent with --all-agents", func() {
+		_, _, err := testutil.RunShiftlogInDir(repo.Path, "init", "--all-agents")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(repo.FileExists(".gemini/settings.json")).To(BeTrue())
+		Expect(repo.FileExists(".opencode/plugins/shiftlog.js")).To(BeTrue())
+	})
+
+	It("rejects unknown agents", func() {
+		_, stderr, err := testutil.RunShiftlogInDir(repo.Path, "init", "--agent=claude,nope")
+		Expect(err).To(HaveOccurred())
+		Expect(stderr).To(ContainSubstring(`unsupported agent "nope"`))
+	})
+
+	It("stores a hook payload under the agent that sent it", func() {
+		_, _, err := testutil.RunShiftlogInDir(repo.Path, "init", "--agent=claude,gemini")
+		Expect(err).NotTo(HaveOccurred())
+
+		gemini := testutil.GeminiTestConfig()
+		hookParam, err := gemini.PrepareTranscript(repo.Path, "gemini-session", gemini.SampleTranscript())
+		Expect(err).NotTo(HaveOccurred())
+
+		head, err := repo.GetHead()
+		Expect(err).NotTo(HaveOccurred())
+
+		input := gemini.SampleHookInput("gemini-session", hookParam, "git commit -m 'test'")
+		_, _, err = testutil.RunShiftlogInDirWithStdin(repo.Path, input, "store")
+		Expect(err).NotTo(HaveOccurred())
+
+		noteContent, err := repo.GetNote("refs/notes/shiftlog", head)
+		Expect(err).NotTo(HaveOccurred())
+		var stored map[string]interface{}
+		Expect(json.Unmarshal([]byte(noteContent), &stored)).To(Succeed())
+		Expect(stored["agent"]).To(Equal("gemini"))
+		Expect(stored["session_id"]).To(Equal("gemini-session"))
+	})
+})
@@ -0,0 +1,110 @@
+package acceptance_test
+
+import (
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/re-cinq/shift-log/tests/acceptance/testutil"
+)
+
+var _ = Describe("Opt-out", func() {
+	var repo *testutil.GitRepo
+	var hookInput string
+
+	BeforeEach(func() {
+		var err error
+		repo, err = testutil.NewGitRepo()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(repo.WriteFile("README.md", "# Test")).To(Succeed())
+		Expect(repo.Commit("Initial commit")).To(Succeed())
+		Expect(repo.Run("git", "tag", "base")).To(Succeed())
+
+		_, _, err = testutil.RunShiftlogInDir(repo.Path, "init")
+		Expect(err).NotTo(HaveOccurred())
+
+		transcriptPath := filepath.Join(GinkgoT().TempDir(), "transcript.jsonl")
+		Expect(os.WriteFile(transcriptPath, []byte(testutil.SampleTranscript()), 0644)).To(Succeed())
+		hookInput = testutil.SampleHookInput("session-optout", transcriptPath, "git commit -m 'test'")
+
+		Expect(repo.WriteFile("a.txt", "a")).To(Succeed())
+		Expect(repo.Commit("Add a")).To(Succeed())
+	})
+
+	AfterEach(func() {
+		repo.Cleanup()
+	})
+
+	It("records the conversation as omitted after shiftlog optout", func() {
+		stdout, _, err := testutil.RunShiftlogInDir(repo.Path, "optout")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stdout).To(ContainSubstring("off in this repository"))
+
+		_, stderr, err := testutil.RunShiftlogInDirWithStdin(repo.Path, hookInput, "store")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stderr).To(ContainSubstring("capture is off (opt-out)"))
+
+		Expect(repo.HasNote("refs/notes/shiftlog", "HEAD")).To(BeFalse())
+		omission, err := repo.GetNote("refs/notes/shiftlog-omissions", "HEAD")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(omission).To(ContainSubstring(`"reason":"opt-out"`))
+		Expect(omission).To(ContainSubstring(`"trigger":"agent-hook"`))
+		Expect(omission).NotTo(ContainSubstring("session-optout"))
+	})
+
+	It("honors SHIFTLOG_DISABLE", func() {
+		_, _, err := testutil.RunShiftlogInDirWithEnvAndStdin(repo.Path, []string{"SHIFTLOG_DISABLE=1"}, hookInput, "store")
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(repo.HasNote("refs/notes/shiftlog", "HEAD")).To(BeFalse())
+		omission, err := repo.GetNote("refs/notes/shiftlog-omissions", "HEAD")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(omission).To(ContainSubstring(`"reason":"disabled"`))
+	})
+
+	It("counts opted-out commits apart from missing ones", func() {
+		_, _, err := testutil.RunShiftlogInDir(repo.Path, "optout")
+		Expect(err).NotTo(HaveOccurred())
+		_, _, err = testutil.RunShiftlogInDirWithStdin(repo.Path, hookInput, "store")
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(repo.WriteFile("b.txt", "b")).To(Succeed())
+		Expect(repo.Commit("Add b")).To(Succeed())
+
+		stdout, _, err := testutil.RunShiftlogInDir(repo.Path, "check", "--range", "base..HEAD", "--max-missing", "1")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stdout).To(ContainSubstring("2 commit(s) in base..HEAD: 0 with a conversation, 1 opted out, 0 allowlisted, 1 missing"))
+		Expect(stdout).NotTo(ContainSubstring("Add a"))
+
+		stdout, _, err = testutil.RunShiftlogInDir(repo.Path, "stats")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stdout).To(ContainSubstring("Opted out:      1"))
+	})
+
+	It("stores again after --undo", func() {
+		_, _, err := testutil.RunShiftlogInDir(repo.Path, "optout")
+		Expect(err).NotTo(HaveOccurred())
+		stdout, _, err := testutil.RunShiftlogInDir(repo.Path, "optout", "--undo")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stdout).To(ContainSubstring("back on"))
+
+		_, _, err = testutil.RunShiftlogInDirWithStdin(repo.Path, hookInput, "store")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(repo.HasNote("refs/notes/shiftlog", "HEAD")).To(BeTrue())
+	})
+
+	It("opts out in every repository with --global", func() {
+		env := []string{"HOME=" + GinkgoT().TempDir()}
+		_, _, err := testutil.RunShiftlogInDirWithEnv(repo.Path, env, "optout", "--global")
+		Expect(err).NotTo(HaveOccurred())
+		out, err := repo.RunOutput("git", "config", "--local", "shiftlog.optout")
+		Expect(err).To(HaveOccurred(), out)
+
+		_, _, err = testutil.RunShiftlogInDirWithEnvAndStdin(repo.Path, env, hookInput, "store")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(repo.HasNote("refs/notes/shiftlog", "HEAD")).To(BeFalse())
+		Expect(repo.HasNote("refs/notes/shiftlog-omissions", "HEAD")).To(BeTrue())
+	})
+})
@@ -0,0 +1,90 @@
+package acceptance_test
+
+import (
+	"encoding/json"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/re-cinq/shift-log/tests/acceptance/testutil"
+)
+
+// jsonResult mirrors the document printed with --output json.
+type jsonResult struct {
+	Command   string          `json:"command"`
+	Status    string          `json:"status"`
+	Error     string          `json:"error"`
+	Warnings  []string        `json:"warnings"`
+	Messages  []string        `json:"messages"`
+	Artifacts []jsonArtifact  `json:"artifacts"`
+	Data      json.RawMessage `json:"data"`
+}
+
+type jsonArtifact struct {
+	Kind string `json:"kind"`
+	ID   string `json:"id"`
+}
+
+var _ = Describe("JSON Output", func() {
+	var repo *testutil.GitRepo
+
+	BeforeEach(func() {
+		var err error
+		repo, err = testutil.NewGitRepo()
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		repo.Cleanup()
+	})
+
+	parse := func(stdout string) jsonResult {
+		var result jsonResult
+		Expect(json.Unmarshal([]byte(stdout), &result)).To(Succeed(), stdout)
+		return result
+	}
+
+	It("reports init's messages and the artifacts it created", func() {
+		stdout, _, err := testutil.RunShiftlogInDir(repo.Path, "init", "--output", "json")
+		Expect(err).NotTo(HaveOccurred())
+
+		result := parse(stdout)
+		Expect(result.Command).To(Equal("shiftlog init"))
+		Expect(result.Status).To(Equal("ok"))
+		Expect(result.Messages).To(ContainElement(ContainSubstring("Configured notes ref")))
+		Expect(result.Artifacts).To(ContainElement(HaveField("Kind", "hook")))
+		Expect(result.Artifacts).To(ContainElement(HaveField("Kind", "config")))
+	})
+
+	It("embeds the JSON of commands with a --format flag as data", func() {
+		_, _, err := testutil.RunShiftlogInDir(repo.Path, "init")
+		Expect(err).NotTo(HaveOccurred())
+
+		stdout, _, err := testutil.RunShiftlogInDir(repo.Path, "stats", "--output", "json")
+		Expect(err).NotTo(HaveOccurred())
+
+		result := parse(stdout)
+		Expect(result.Status).To(Equal("ok"))
+		Expect(string(result.Data)).To(ContainSubstring(`"conversations"`))
+	})
+
+	It("reports failures with a non-zero exit status", func() {
+		stdout, _, err := testutil.RunShiftlogInDir(repo.Path, "show", "does-not-exist", "--output", "json")
+		Expect(err).To(HaveOccurred())
+
+		result := parse(stdout)
+		Expect(result.Status).To(Equal("error"))
+		Expect(result.Error).To(ContainSubstring("could not resolve reference"))
+	})
+
+	It("rejects an unknown output format", func() {
+		_, _, err := testutil.RunShiftlogInDir(repo.Path, "list", "--output", "yaml")
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("rejects --output json for long-running commands", func() {
+		_, stderr, err := testutil.RunShiftlogInDir(repo.Path, "serve", "--output", "json")
+		Expect(err).To(HaveOccurred())
+		Expect(stderr).To(ContainSubstring("does not support --output json"))
+	})
+})
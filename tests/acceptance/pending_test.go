@@ -0,0 +1,95 @@
+package acceptance_test
+
+import (
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/re-cinq/shift-log/tests/acceptance/testutil"
+)
+
+var _ = Describe("Queued stores", func() {
+	var repo *testutil.GitRepo
+	var lockPath string
+
+	// storeSession commits a change and stores a conversation on it.
+	storeSession := func(file, sessionID string) (string, string) {
+		Expect(repo.WriteFile(file, "package retry\n")).To(Succeed())
+		Expect(repo.Commit("Add " + file)).To(Succeed())
+		transcriptPath := filepath.Join(GinkgoT().TempDir(), "transcript.jsonl")
+		Expect(os.WriteFile(transcriptPath, []byte(testutil.SampleTranscript()), 0644)).To(Succeed())
+		hookInput := testutil.SampleHookInput(sessionID, transcriptPath, "git commit -m 'Add "+file+"'")
+		_, stderr, err := testutil.RunShiftlogInDirWithStdin(repo.Path, hookInput, "store")
+		Expect(err).NotTo(HaveOccurred(), stderr)
+		head, err := repo.GetHead()
+		Expect(err).NotTo(HaveOccurred())
+		return head, stderr
+	}
+
+	pending := func() []string {
+		entries, _ := filepath.Glob(filepath.Join(repo.Path, ".shiftlog", "pending", "*.json"))
+		return entries
+	}
+
+	BeforeEach(func() {
+		var err error
+		repo, err = testutil.NewGitRepo()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(repo.WriteFile("README.md", "# Test")).To(Succeed())
+		Expect(repo.Commit("Initial commit")).To(Succeed())
+
+		_, _, err = testutil.RunShiftlogInDir(repo.Path, "init")
+		Expect(err).NotTo(HaveOccurred())
+
+		// Another git process holding the notes ref's lock
+		lockPath = filepath.Join(repo.Path, ".git", "refs", "notes", "shiftlog.lock")
+		Expect(os.MkdirAll(filepath.Dir(lockPath), 0755)).To(Succeed())
+		Expect(os.WriteFile(lockPath, nil, 0644)).To(Succeed())
+	})
+
+	AfterEach(func() {
+		if repo != nil {
+			repo.Cleanup()
+		}
+	})
+
+	It("queues a conversation it cannot store and flush stores it", func() {
+		head, stderr := storeSession("retry.go", "session-queued")
+		Expect(stderr).To(ContainSubstring("queued the conversation"))
+		Expect(repo.HasNote("refs/notes/shiftlog", head)).To(BeFalse())
+		Expect(pending()).To(HaveLen(1))
+
+		stdout, _, _ := testutil.RunShiftlogInDir(repo.Path, "doctor")
+		Expect(stdout).To(ContainSubstring("Checking queued conversations... WARN"))
+		Expect(stdout).To(ContainSubstring("shiftlog flush"))
+
+		_, _, err := testutil.RunShiftlogInDir(repo.Path, "flush")
+		Expect(err).To(HaveOccurred())
+		Expect(pending()).To(HaveLen(1))
+
+		Expect(os.Remove(lockPath)).To(Succeed())
+		stdout, _, err = testutil.RunShiftlogInDir(repo.Path, "flush")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stdout).To(ContainSubstring("Stored 1 queued conversations"))
+		Expect(repo.HasNote("refs/notes/shiftlog", head)).To(BeTrue())
+		Expect(pending()).To(BeEmpty())
+
+		stdout, _, err = testutil.RunShiftlogInDir(repo.Path, "flush")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stdout).To(ContainSubstring("No queued conversations"))
+	})
+
+	It("stores queued conversations on their commits with the next store", func() {
+		first, _ := storeSession("retry.go", "session-first")
+		Expect(pending()).To(HaveLen(1))
+
+		Expect(os.Remove(lockPath)).To(Succeed())
+		second, _ := storeSession("backoff.go", "session-second")
+
+		Expect(repo.HasNote("refs/notes/shiftlog", first)).To(BeTrue())
+		Expect(repo.HasNote("refs/notes/shiftlog", second)).To(BeTrue())
+		Expect(pending()).To(BeEmpty())
+	})
+})
@@ -0,0 +1,149 @@
+package acceptance_test
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/re-cinq/shift-log/tests/acceptance/testutil"
+)
+
+const privacyChat = `{"role":"user","content":"Ask Ada Lovelace (ada@corp.io, +44 20 7946 0958) or bot@example.com"}
+{"role":"assistant","content":"I will write to her."}
+`
+
+var _ = Describe("Privacy Policy", func() {
+	var repo *testutil.GitRepo
+	var chatPath string
+
+	BeforeEach(func() {
+		var err error
+		repo, err = testutil.NewGitRepo()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(repo.WriteFile("README.md", "# Test")).To(Succeed())
+		Expect(repo.Commit("Initial commit")).To(Succeed())
+
+		chatPath = filepath.Join(GinkgoT().TempDir(), "chat.jsonl")
+		Expect(os.WriteFile(chatPath, []byte(privacyChat), 0644)).To(Succeed())
+	})
+
+	AfterEach(func() {
+		repo.Cleanup()
+	})
+
+	It("stores conversations unscrubbed without a policy asking for it", func() {
+		_, _, err := testutil.RunShiftlogInDir(repo.Path, "import", "--format", "jsonl", chatPath)
+		Expect(err).NotTo(HaveOccurred())
+
+		stdout, _, err := testutil.RunShiftlogInDir(repo.Path, "show")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stdout).To(ContainSubstring("ada@corp.io"))
+	})
+
+	It("scrubs conversations on store and records an audit log", func() {
+		Expect(repo.WriteFile(".shiftlog/privacy.yaml", `scrub_on_store: true
+rules:
+  - detector: email
+    action: hash
+    allow: ["*@example.com"]
+  - detector: phone
+  - detector: name
+    values: ["Ada Lovelace"]
+`)).To(Succeed())
+
+		_, stderr, err := testutil.RunShiftlogInDir(repo.Path, "import", "--format", "jsonl", chatPath)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stderr).To(ContainSubstring("1 email allowed, 1 email hashed, 1 name redacted, 1 phone redacted"))
+
+		stdout, _, err := testutil.RunShiftlogInDir(repo.Path, "show")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stdout).To(ContainSubstring("Ask [redacted name] ([email:"))
+		Expect(stdout).To(ContainSubstring("[redacted phone]) or bot@example.com"))
+		Expect(stdout).NotTo(ContainSubstring("ada@corp.io"))
+
+		audit, err := repo.ReadFile(".shiftlog/logs/privacy-audit.jsonl")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(audit).To(ContainSubstring(`{"rule":"email","action":"hash","count":1}`))
+		Expect(audit).NotTo(ContainSubstring("ada@corp.io"))
+
+		// The scrubbed note is still valid
+		_, _, err = testutil.RunShiftlogInDir(repo.Path, "verify")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("scrubs conversations posted to the server's API", func() {
+		Expect(repo.WriteFile(".shiftlog/privacy.yaml", "scrub_on_store: true\nrules:\n  - detector: email\n")).To(Succeed())
+		head, err := repo.GetHead()
+		Expect(err).NotTo(HaveOccurred())
+
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		Expect(err).NotTo(HaveOccurred())
+		port := listener.Addr().(*net.TCPAddr).Port
+		Expect(listener.Close()).To(Succeed())
+
+		server := exec.Command(testutil.BinaryPath(), "serve", "--port", fmt.Sprint(port), "--no-browser")
+		server.Dir = repo.Path
+		server.Env = append(os.Environ(), "SHIFTLOG_API_TOKEN=secret")
+		Expect(server.Start()).To(Succeed())
+		defer func() {
+			_ = server.Process.Kill()
+			_ = server.Wait()
+		}()
+		base := fmt.Sprintf("http://127.0.0.1:%d", port)
+		Eventually(func() error {
+			resp, err := http.Get(base + "/api/settings")
+			if err == nil {
+				resp.Body.Close()
+			}
+			return err
+		}, 10*time.Second, 50*time.Millisecond).Should(Succeed())
+
+		transcript := testutil.SampleTranscriptWithIDs([]string{"u1", "a1"}, []string{"Write to ada@corp.io", "Done."})
+		body := fmt.Sprintf(`{"commit": %q, "hook": {"session_id": "api-session"}, "transcript": %q}`, head, transcript)
+		req, err := http.NewRequest("POST", base+"/api/conversations", strings.NewReader(body))
+		Expect(err).NotTo(HaveOccurred())
+		req.Header.Set("Authorization", "Bearer secret")
+		resp, err := http.DefaultClient.Do(req)
+		Expect(err).NotTo(HaveOccurred())
+		resp.Body.Close()
+		Expect(resp.StatusCode).To(Equal(http.StatusCreated))
+
+		stdout, _, err := testutil.RunShiftlogInDir(repo.Path, "show")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stdout).To(ContainSubstring("Write to [redacted email]"))
+		Expect(stdout).NotTo(ContainSubstring("ada@corp.io"))
+
+		audit, err := repo.ReadFile(".shiftlog/logs/privacy-audit.jsonl")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(audit).To(ContainSubstring(`"session_id":"api-session"`))
+	})
+
+	It("refuses to store with an invalid policy", func() {
+		Expect(repo.WriteFile(".shiftlog/privacy.yaml", "scrub_on_store: true\nrules:\n  - detector: ssn\n")).To(Succeed())
+
+		_, stderr, err := testutil.RunShiftlogInDir(repo.Path, "import", "--format", "jsonl", chatPath)
+		Expect(err).To(HaveOccurred())
+		Expect(stderr).To(ContainSubstring(`unknown detector "ssn"`))
+		Expect(repo.HasNote("refs/notes/shiftlog", "HEAD")).To(BeFalse())
+	})
+
+	It("scrubs exports with --scrub and reports what it found", func() {
+		_, _, err := testutil.RunShiftlogInDir(repo.Path, "import", "--format", "jsonl", chatPath)
+		Expect(err).NotTo(HaveOccurred())
+
+		stdout, stderr, err := testutil.RunShiftlogInDir(repo.Path, "export", "--scrub")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stderr).To(ContainSubstring("scrubbed: 3 email redacted, 1 phone redacted"))
+		Expect(stdout).To(ContainSubstring("[redacted email]"))
+		Expect(stdout).NotTo(ContainSubstring("ada@corp.io"))
+		Expect(stdout).NotTo(ContainSubstring("7946"))
+	})
+})
@@ -0,0 +1,101 @@
+package acceptance_test
+
+import (
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/re-cinq/shift-log/tests/acceptance/testutil"
+)
+
+var _ = Describe("Private conversations", func() {
+	var repo, remote *testutil.GitRepo
+	var hookInput string
+
+	BeforeEach(func() {
+		var err error
+		repo, remote, err = testutil.NewGitRepoWithRemote()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(repo.WriteFile("README.md", "# Test")).To(Succeed())
+		Expect(repo.Commit("Initial commit")).To(Succeed())
+
+		_, _, err = testutil.RunShiftlogInDir(repo.Path, "init")
+		Expect(err).NotTo(HaveOccurred())
+
+		transcriptPath := filepath.Join(GinkgoT().TempDir(), "transcript.jsonl")
+		Expect(os.WriteFile(transcriptPath, []byte(testutil.SampleTranscript()), 0644)).To(Succeed())
+		hookInput = testutil.SampleHookInput("session-private", transcriptPath, "git commit -m 'test'")
+	})
+
+	AfterEach(func() {
+		repo.Cleanup()
+		remote.Cleanup()
+	})
+
+	It("keeps a private conversation out of sync until it is published", func() {
+		_, stderr, err := testutil.RunShiftlogInDirWithStdin(repo.Path, hookInput, "store", "--private")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stderr).To(ContainSubstring("stored private conversation"))
+		Expect(repo.HasNote("refs/notes/shiftlog", "HEAD")).To(BeFalse())
+		Expect(repo.HasNote("refs/notes/shiftlog-private", "HEAD")).To(BeTrue())
+
+		stdout, _, err := testutil.RunShiftlogInDir(repo.Path, "show")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stdout).To(ContainSubstring("Private: kept in this clone"))
+
+		_, _, err = testutil.RunShiftlogInDir(repo.Path, "sync", "push")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(remote.RunOutput("git", "for-each-ref", "refs/notes/")).NotTo(ContainSubstring("shiftlog-private"))
+
+		stdout, _, err = testutil.RunShiftlogInDir(repo.Path, "publish", "HEAD")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stdout).To(ContainSubstring("published conversation of session session-private"))
+		Expect(repo.HasNote("refs/notes/shiftlog-private", "HEAD")).To(BeFalse())
+		note, err := repo.GetNote("refs/notes/shiftlog", "HEAD")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(note).To(ContainSubstring("session-private"))
+		Expect(note).NotTo(ContainSubstring(`"visibility"`))
+
+		_, _, err = testutil.RunShiftlogInDir(repo.Path, "sync", "push")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(remote.RunOutput("git", "for-each-ref", "refs/notes/shiftlog")).To(ContainSubstring("refs/notes/shiftlog"))
+	})
+
+	It("stores the conversations of private branches as private", func() {
+		Expect(repo.WriteFile(".shiftlog/config", `{"notes_ref": "refs/notes/shiftlog", "private_branches": ["spike/*"]}`)).To(Succeed())
+		Expect(repo.Run("git", "checkout", "-q", "-b", "spike/idea")).To(Succeed())
+		Expect(repo.WriteFile("a.txt", "a")).To(Succeed())
+		Expect(repo.Commit("Try an idea")).To(Succeed())
+
+		_, _, err := testutil.RunShiftlogInDirWithStdin(repo.Path, hookInput, "store")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(repo.HasNote("refs/notes/shiftlog", "HEAD")).To(BeFalse())
+		Expect(repo.HasNote("refs/notes/shiftlog-private", "HEAD")).To(BeTrue())
+
+		// Storing the session again does not duplicate it
+		_, stderr, err := testutil.RunShiftlogInDirWithStdin(repo.Path, hookInput, "store")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stderr).To(ContainSubstring("already stored"))
+	})
+
+	It("marks private conversations in exports", func() {
+		_, _, err := testutil.RunShiftlogInDirWithStdin(repo.Path, hookInput, "store", "--private")
+		Expect(err).NotTo(HaveOccurred())
+
+		stdout, _, err := testutil.RunShiftlogInDir(repo.Path, "export")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stdout).To(ContainSubstring(`"visibility": "private"`))
+
+		stdout, _, err = testutil.RunShiftlogInDir(repo.Path, "export", "--format", "html")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stdout).To(ContainSubstring("private: not shared with the repository"))
+	})
+
+	It("fails to publish a commit without private conversations", func() {
+		_, stderr, err := testutil.RunShiftlogInDir(repo.Path, "publish", "HEAD")
+		Expect(err).To(HaveOccurred())
+		Expect(stderr).To(ContainSubstring("no private conversation found"))
+	})
+})
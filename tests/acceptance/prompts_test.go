@@ -0,0 +1,71 @@
+package acceptance_test
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/re-cinq/shift-log/tests/acceptance/testutil"
+)
+
+var _ = Describe("Prompts Command", func() {
+	var repo *testutil.GitRepo
+
+	BeforeEach(func() {
+		var err error
+		repo, err = testutil.NewGitRepo()
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(repo.WriteFile("README.md", "# Test")).To(Succeed())
+		Expect(repo.Commit("Initial commit")).To(Succeed())
+
+		_, _, err = testutil.RunShiftlogInDir(repo.Path, "init")
+		Expect(err).NotTo(HaveOccurred())
+
+		// Two sessions starting with the same prompt
+		transcriptPath := filepath.Join(repo.Path, "transcript.jsonl")
+		Expect(os.WriteFile(transcriptPath, []byte(testutil.SampleTranscript()), 0644)).To(Succeed())
+		for _, session := range []string{"session-a", "session-b"} {
+			Expect(repo.WriteFile(session+".txt", session)).To(Succeed())
+			Expect(repo.Commit("Add " + session)).To(Succeed())
+			hookInput := testutil.SampleHookInput(session, transcriptPath, "git commit -m 'test'")
+			_, _, err = testutil.RunShiftlogInDirWithStdin(repo.Path, hookInput, "store")
+			Expect(err).NotTo(HaveOccurred())
+		}
+	})
+
+	AfterEach(func() {
+		repo.Cleanup()
+	})
+
+	It("exports the prompts as a Markdown library", func() {
+		stdout, _, err := testutil.RunShiftlogInDir(repo.Path, "prompts", "--commit-url", "https://example.com/commit/")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stdout).To(ContainSubstring("# Prompt library"))
+		Expect(stdout).To(ContainSubstring("1 prompt from 2 sessions"))
+		Expect(stdout).To(ContainSubstring("> Hello, can you help me with a task?"))
+		Expect(stdout).To(ContainSubstring("Used in 2 sessions:"))
+		Expect(stdout).To(ContainSubstring("](https://example.com/commit/"))
+		Expect(stdout).To(ContainSubstring("Add session-b, claude"))
+	})
+
+	It("exports the prompts as JSON", func() {
+		stdout, _, err := testutil.RunShiftlogInDir(repo.Path, "prompts", "--all", "--format", "json")
+		Expect(err).NotTo(HaveOccurred())
+
+		var library []struct {
+			Text string `json:"text"`
+			Uses []struct {
+				SessionID string `json:"session_id"`
+			} `json:"uses"`
+		}
+		Expect(json.Unmarshal([]byte(stdout), &library)).To(Succeed())
+		Expect(library).NotTo(BeEmpty())
+		Expect(library[0].Text).To(Equal("Hello, can you help me with a task?"))
+		Expect(library[0].Uses).To(HaveLen(2))
+		Expect(library[0].Uses[0].SessionID).To(Equal("session-b"))
+	})
+})
@@ -0,0 +1,77 @@
+package acceptance_test
+
+import (
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/re-cinq/shift-log/tests/acceptance/testutil"
+)
+
+var _ = Describe("Readable notes", func() {
+	var repo *testutil.GitRepo
+
+	// storeSession commits a change and stores a conversation on it.
+	storeSession := func() {
+		Expect(repo.WriteFile("retry.go", "package retry\n")).To(Succeed())
+		Expect(repo.Commit("Fix retry")).To(Succeed())
+		transcriptPath := filepath.Join(GinkgoT().TempDir(), "transcript.jsonl")
+		Expect(os.WriteFile(transcriptPath, []byte(testutil.SampleTranscript()), 0644)).To(Succeed())
+		hookInput := testutil.SampleHookInput("session-readable", transcriptPath, "git commit -m 'Fix retry'")
+		_, _, err := testutil.RunShiftlogInDirWithStdin(repo.Path, hookInput, "store")
+		Expect(err).NotTo(HaveOccurred())
+	}
+
+	BeforeEach(func() {
+		var err error
+		repo, err = testutil.NewGitRepo()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(repo.WriteFile("README.md", "# Test")).To(Succeed())
+		Expect(repo.Commit("Initial commit")).To(Succeed())
+
+		_, _, err = testutil.RunShiftlogInDir(repo.Path, "init")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		if repo != nil {
+			repo.Cleanup()
+		}
+	})
+
+	It("describes conversations at store time when configured", func() {
+		Expect(repo.WriteFile(".shiftlog/config", `{"agent": "claude", "readable_notes": true}`)).To(Succeed())
+		storeSession()
+
+		note, err := repo.GetNote("refs/notes/shiftlog-readable", "HEAD")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(note).To(ContainSubstring("shiftlog: 1 agent conversation"))
+		Expect(note).To(ContainSubstring("messages, session "))
+		Expect(note).To(ContainSubstring("Full transcript: shiftlog show"))
+
+		log, err := repo.RunOutput("git", "log", "-1", "--notes=shiftlog-readable")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(log).To(ContainSubstring("Notes (shiftlog-readable):"))
+	})
+
+	It("leaves them out unless configured", func() {
+		storeSession()
+
+		_, err := repo.GetNote("refs/notes/shiftlog-readable", "HEAD")
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("describes conversations already stored", func() {
+		storeSession()
+
+		stdout, _, err := testutil.RunShiftlogInDir(repo.Path, "readable-notes")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stdout).To(ContainSubstring("Described the conversations of 1 commits"))
+
+		note, err := repo.GetNote("refs/notes/shiftlog-readable", "HEAD")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(note).To(ContainSubstring("shiftlog: 1 agent conversation"))
+	})
+})
@@ -0,0 +1,127 @@
+package acceptance_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/re-cinq/shift-log/tests/acceptance/testutil"
+)
+
+var _ = Describe("Recover Command", func() {
+	var repo *testutil.GitRepo
+
+	BeforeEach(func() {
+		var err error
+		repo, err = testutil.NewGitRepo()
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(repo.WriteFile("README.md", "# Test")).To(Succeed())
+		Expect(repo.Commit("Initial commit")).To(Succeed())
+
+		_, _, err = testutil.RunShiftlogInDir(repo.Path, "init")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		if repo != nil {
+			repo.Cleanup()
+		}
+	})
+
+	// storeOnNewCommit commits a file and stores a conversation for it.
+	storeOnNewCommit := func(name string) string {
+		Expect(repo.WriteFile(name+".txt", name)).To(Succeed())
+		Expect(repo.Commit("Add " + name)).To(Succeed())
+
+		transcriptPath := filepath.Join(repo.Path, "transcript.jsonl")
+		Expect(os.WriteFile(transcriptPath, []byte(testutil.SampleTranscript()), 0644)).To(Succeed())
+		hookInput := testutil.SampleHookInput("session-"+name, transcriptPath, "git commit -m 'test'")
+		_, _, err := testutil.RunShiftlogInDirWithStdin(repo.Path, hookInput, "store")
+		Expect(err).NotTo(HaveOccurred())
+
+		head, err := repo.GetHead()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(repo.HasNote("refs/notes/shiftlog", head)).To(BeTrue())
+		return head
+	}
+
+	It("configures reflog retention for the notes refs on init", func() {
+		for _, key := range []string{"reflogExpire", "reflogExpireUnreachable"} {
+			out, err := repo.RunOutput("git", "config", "gc.refs/notes/shiftlog*."+key)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(strings.TrimSpace(out)).To(Equal("never"))
+		}
+
+		_, _, err := testutil.RunShiftlogInDir(repo.Path, "deinit")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(repo.Run("git", "config", "gc.refs/notes/shiftlog*.reflogExpire")).NotTo(Succeed())
+	})
+
+	It("restores notes dropped by a reset of the notes ref, even after gc", func() {
+		first := storeOnNewCommit("first")
+		second := storeOnNewCommit("second")
+
+		// Simulate a force-push pulled over the local notes
+		Expect(repo.Run("git", "update-ref", "refs/notes/shiftlog", "refs/notes/shiftlog~1")).To(Succeed())
+		Expect(repo.HasNote("refs/notes/shiftlog", second)).To(BeFalse())
+		Expect(repo.Run("git", "-c", "gc.reflogExpire=now", "-c", "gc.reflogExpireUnreachable=now", "gc", "--prune=now", "-q")).To(Succeed())
+
+		stdout, _, err := testutil.RunShiftlogInDir(repo.Path, "recover")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stdout).To(ContainSubstring("Restored note for " + second[:7]))
+		Expect(stdout).To(ContainSubstring("Recovered 1 note(s)"))
+
+		Expect(repo.HasNote("refs/notes/shiftlog", first)).To(BeTrue())
+		Expect(repo.HasNote("refs/notes/shiftlog", second)).To(BeTrue())
+		stdout, _, err = testutil.RunShiftlogInDir(repo.Path, "show", second)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stdout).To(ContainSubstring("Hello, can you help me with a task?"))
+	})
+
+	It("restores notes from unreachable commits after the notes ref is deleted", func() {
+		head := storeOnNewCommit("lost")
+
+		// Deleting the ref drops its reflog too
+		Expect(repo.Run("git", "update-ref", "-d", "refs/notes/shiftlog")).To(Succeed())
+
+		stdout, _, err := testutil.RunShiftlogInDir(repo.Path, "recover")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stdout).To(ContainSubstring("Restored note for " + head[:7]))
+		Expect(repo.HasNote("refs/notes/shiftlog", head)).To(BeTrue())
+	})
+
+	It("repairs a corrupt note with its last intact version", func() {
+		head := storeOnNewCommit("corrupt")
+		Expect(repo.AddNote("refs/notes/shiftlog", head, "{ truncated")).To(Succeed())
+
+		stdout, _, err := testutil.RunShiftlogInDir(repo.Path, "recover")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stdout).To(ContainSubstring("Repaired note for " + head[:7]))
+
+		stdout, _, err = testutil.RunShiftlogInDir(repo.Path, "show", head)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stdout).To(ContainSubstring("Hello, can you help me with a task?"))
+	})
+
+	It("changes nothing with --dry-run", func() {
+		head := storeOnNewCommit("dry")
+		Expect(repo.Run("git", "update-ref", "-d", "refs/notes/shiftlog")).To(Succeed())
+
+		stdout, _, err := testutil.RunShiftlogInDir(repo.Path, "recover", "--dry-run")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stdout).To(ContainSubstring("Would restore note for " + head[:7]))
+		Expect(repo.HasNote("refs/notes/shiftlog", head)).To(BeFalse())
+	})
+
+	It("reports when nothing was lost", func() {
+		storeOnNewCommit("intact")
+
+		stdout, _, err := testutil.RunShiftlogInDir(repo.Path, "recover")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stdout).To(ContainSubstring("No lost notes found"))
+	})
+})
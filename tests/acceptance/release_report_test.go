@@ -0,0 +1,71 @@
+package acceptance_test
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/re-cinq/shift-log/tests/acceptance/testutil"
+)
+
+var _ = Describe("Release Report Command", func() {
+	var repo *testutil.GitRepo
+
+	BeforeEach(func() {
+		var err error
+		repo, err = testutil.NewGitRepo()
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(repo.WriteFile("README.md", "# Test")).To(Succeed())
+		Expect(repo.Commit("Initial commit")).To(Succeed())
+		Expect(repo.Run("git", "tag", "v1.0.0")).To(Succeed())
+
+		_, _, err = testutil.RunShiftlogInDir(repo.Path, "init")
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(repo.WriteFile("feature.txt", "feature")).To(Succeed())
+		Expect(repo.Commit("Add feature")).To(Succeed())
+		transcriptPath := filepath.Join(repo.Path, "transcript.jsonl")
+		Expect(os.WriteFile(transcriptPath, []byte(testutil.SampleTranscript()), 0644)).To(Succeed())
+		hookInput := testutil.SampleHookInput("session-release", transcriptPath, "git commit -m 'test'")
+		_, _, err = testutil.RunShiftlogInDirWithStdin(repo.Path, hookInput, "store")
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(repo.WriteFile("fix.txt", "fix")).To(Succeed())
+		Expect(repo.Commit("Manual fix")).To(Succeed())
+		Expect(repo.Run("git", "tag", "v1.1.0")).To(Succeed())
+	})
+
+	AfterEach(func() {
+		repo.Cleanup()
+	})
+
+	It("reports the conversations since the previous tag as Markdown", func() {
+		stdout, _, err := testutil.RunShiftlogInDir(repo.Path, "release-report", "v1.1.0")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stdout).To(ContainSubstring("# Release report: v1.0.0 → v1.1.0"))
+		Expect(stdout).To(ContainSubstring("- **Commits:** 2, 1 with a conversation (50%)"))
+		Expect(stdout).To(ContainSubstring("| Add feature |"))
+		Expect(stdout).NotTo(ContainSubstring("Manual fix"))
+	})
+
+	It("reports as JSON", func() {
+		stdout, _, err := testutil.RunShiftlogInDir(repo.Path, "release-report", "v1.0.0", "v1.1.0", "--format", "json")
+		Expect(err).NotTo(HaveOccurred())
+
+		var report map[string]interface{}
+		Expect(json.Unmarshal([]byte(stdout), &report)).To(Succeed())
+		Expect(report["from"]).To(Equal("v1.0.0"))
+		Expect(report["commits"]).To(BeNumerically("==", 2))
+		Expect(report["conversations"]).To(HaveLen(1))
+	})
+
+	It("rejects unknown references", func() {
+		_, stderr, err := testutil.RunShiftlogInDir(repo.Path, "release-report", "v9.9.9")
+		Expect(err).To(HaveOccurred())
+		Expect(stderr).To(ContainSubstring("could not resolve reference 'v9.9.9'"))
+	})
+})
@@ -123,6 +123,36 @@ var _ = Describe("Remote Rebase Notes Remap", func() {
 		})
 	})
 
+	Describe("shiftlog remap matches amended patchsets by Change-Id", func() {
+		It("copies the note of an amended commit to its new patchset", func() {
+			Expect(repo.WriteFile("base.txt", "base\n")).To(Succeed())
+			Expect(repo.Commit("base")).To(Succeed())
+
+			Expect(repo.WriteFile("change.txt", "first patchset\n")).To(Succeed())
+			Expect(repo.Commit("change\n\nChange-Id: I0123456789abcdef0123456789abcdef01234567")).To(Succeed())
+			oldSHA, err := repo.GetHead()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(repo.AddNote("refs/notes/shiftlog", oldSHA, `{"session_id":"patchset-1"}`)).To(Succeed())
+
+			// Amend the diff, as a reviewer's edit would, without git copying the note
+			Expect(repo.WriteFile("change.txt", "second patchset\n")).To(Succeed())
+			Expect(repo.Run("git", "add", "-A")).To(Succeed())
+			Expect(repo.Run("git", "-c", "notes.rewrite.amend=false", "commit", "-q", "--amend", "--no-edit")).To(Succeed())
+			newSHA, err := repo.GetHead()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(repo.HasNote("refs/notes/shiftlog", newSHA)).To(BeFalse())
+
+			stdout, _, err := testutil.RunShiftlogInDir(repo.Path, "remap")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(stdout).To(ContainSubstring("Remapped 1 note(s)"))
+			Expect(stdout).NotTo(ContainSubstring("could not be matched"))
+
+			note, err := repo.GetNote("refs/notes/shiftlog", newSHA)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(note).To(ContainSubstring("patchset-1"))
+		})
+	})
+
 	Describe("orphaned notes with no patch-id match are reported but not deleted", func() {
 		It("reports unmatched notes without removing them", func() {
 			// Create a commit with a note, then make it orphaned
@@ -3,6 +3,9 @@ package acceptance_test
 import (
 	"encoding/json"
 	"os"
+	"path/filepath"
+	"strings"
+	"time"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
@@ -311,6 +314,119 @@ var _ = Describe("Resume Command", func() {
 				})
 			})
 
+			Describe("resuming without checkout", func() {
+				It("keeps HEAD and uncommitted changes with --no-checkout", func() {
+					commitSHA := storeConversation("session-no-checkout")
+
+					Expect(repo.WriteFile("second.txt", "content")).To(Succeed())
+					Expect(repo.Commit("Second commit")).To(Succeed())
+					head, err := repo.GetHead()
+					Expect(err).NotTo(HaveOccurred())
+					Expect(repo.WriteFile("uncommitted.txt", "changes")).To(Succeed())
+
+					stdout, stderr, _ := testutil.RunShiftlogInDirWithEnv(
+						repo.Path,
+						agentEnv.GetEnvVars(),
+						"resume", commitSHA, "--no-checkout",
+					)
+
+					Expect(stdout).To(ContainSubstring("restored session"))
+					Expect(stdout).NotTo(ContainSubstring("checked out"))
+					Expect(stderr).NotTo(ContainSubstring("you have uncommitted changes"))
+					Expect(repo.GetHead()).To(Equal(head))
+					Expect(agentEnv.SessionFileExists(repo.Path, "session-no-checkout")).To(BeTrue())
+				})
+			})
+
+			Describe("resuming on a new branch", func() {
+				It("checks out a resume branch with --branch", func() {
+					commitSHA := storeConversation("session-branch")
+
+					stdout, _, _ := testutil.RunShiftlogInDirWithEnv(
+						repo.Path,
+						agentEnv.GetEnvVars(),
+						"resume", commitSHA, "--force", "--branch",
+					)
+
+					branch := "resume/" + commitSHA[:7] + "-" + time.Now().Format("20060102")
+					Expect(stdout).To(ContainSubstring("on new branch " + branch))
+					head, err := repo.RunOutput("git", "rev-parse", "--abbrev-ref", "HEAD")
+					Expect(err).NotTo(HaveOccurred())
+					Expect(strings.TrimSpace(head)).To(Equal(branch))
+				})
+
+				It("rejects --branch with --no-checkout", func() {
+					commitSHA := storeConversation("session-branch-conflict")
+
+					_, stderr, err := testutil.RunShiftlogInDirWithEnv(
+						repo.Path,
+						agentEnv.GetEnvVars(),
+						"resume", commitSHA, "--branch", "--no-checkout",
+					)
+
+					Expect(err).To(HaveOccurred())
+					Expect(stderr).To(ContainSubstring("cannot be used with --branch"))
+				})
+			})
+
+			Describe("resuming in a worktree", func() {
+				It("checks out the commit in a new worktree with --worktree", func() {
+					commitSHA := storeConversation("session-worktree")
+
+					Expect(repo.WriteFile("second.txt", "content")).To(Succeed())
+					Expect(repo.Commit("Second commit")).To(Succeed())
+					head, err := repo.GetHead()
+					Expect(err).NotTo(HaveOccurred())
+					Expect(repo.WriteFile("uncommitted.txt", "changes")).To(Succeed())
+
+					root, err := repo.RunOutput("git", "rev-parse", "--show-toplevel")
+					Expect(err).NotTo(HaveOccurred())
+					worktree := strings.TrimSpace(root) + "-resume-" + commitSHA[:7]
+					DeferCleanup(os.RemoveAll, worktree)
+
+					stdout, stderr, _ := testutil.RunShiftlogInDirWithEnv(
+						repo.Path,
+						agentEnv.GetEnvVars(),
+						"resume", commitSHA, "--worktree",
+					)
+
+					Expect(stdout).To(ContainSubstring("in worktree " + worktree))
+					Expect(stdout).To(ContainSubstring("restored session"))
+					Expect(stderr).NotTo(ContainSubstring("you have uncommitted changes"))
+					Expect(filepath.Join(worktree, "README.md")).To(BeAnExistingFile())
+					Expect(filepath.Join(worktree, "second.txt")).NotTo(BeAnExistingFile())
+					Expect(repo.GetHead()).To(Equal(head))
+					Expect(agentEnv.SessionFileExists(worktree, "session-worktree")).To(BeTrue())
+				})
+			})
+
+			Describe("overriding the agent", func() {
+				It("fails for an unknown agent", func() {
+					commitSHA := storeConversation("session-bad-agent")
+
+					_, stderr, err := testutil.RunShiftlogInDirWithEnv(
+						repo.Path,
+						agentEnv.GetEnvVars(),
+						"resume", commitSHA, "--force", "--agent", "nonexistent",
+					)
+
+					Expect(err).To(HaveOccurred())
+					Expect(stderr).To(ContainSubstring("unknown agent"))
+				})
+
+				It("resumes with the named agent", func() {
+					commitSHA := storeConversation("session-agent-flag")
+
+					stdout, _, _ := testutil.RunShiftlogInDirWithEnv(
+						repo.Path,
+						agentEnv.GetEnvVars(),
+						"resume", commitSHA, "--force", "--agent", strings.ToLower(config.Name),
+					)
+
+					Expect(stdout).To(ContainSubstring("restored session session-agent-flag"))
+				})
+			})
+
 			Describe("session content verification", func() {
 				It("restores the original transcript content", func() {
 					originalTranscript := config.SampleTranscript()
@@ -355,6 +471,72 @@ var _ = Describe("Resume Command", func() {
 		})
 	}
 
+	Describe("replaying into another agent", func() {
+		var repo *testutil.GitRepo
+		var commitSHA string
+
+		BeforeEach(func() {
+			var err error
+			repo, err = testutil.NewGitRepo()
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(repo.WriteFile("README.md", "# Test")).To(Succeed())
+			Expect(repo.Commit("Initial commit")).To(Succeed())
+
+			claude := testutil.ClaudeTestConfig()
+			hookParam, err := claude.PrepareTranscript(repo.Path, "session-cross", claude.SampleTranscript())
+			Expect(err).NotTo(HaveOccurred())
+			hookInput := claude.SampleHookInput("session-cross", hookParam, "git commit -m 'test'")
+			_, _, err = testutil.RunShiftlogInDirWithStdin(repo.Path, hookInput, claude.StoreArgs...)
+			Expect(err).NotTo(HaveOccurred())
+
+			commitSHA, err = repo.GetHead()
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		AfterEach(func() {
+			if repo != nil {
+				repo.Cleanup()
+			}
+		})
+
+		It("converts a Claude conversation into a Codex session", func() {
+			codexEnv, err := testutil.NewAgentEnv(testutil.CodexTestConfig())
+			Expect(err).NotTo(HaveOccurred())
+			DeferCleanup(codexEnv.Cleanup)
+
+			stdout, _, _ := testutil.RunShiftlogInDirWithEnv(
+				repo.Path,
+				codexEnv.GetEnvVars(),
+				"resume", commitSHA, "--no-checkout", "--agent", "codex",
+			)
+
+			Expect(stdout).To(ContainSubstring("replaying claude conversation into Codex CLI"))
+			Expect(stdout).To(ContainSubstring("restored session session-cross"))
+
+			content, err := codexEnv.ReadRestoredTranscript(repo.Path, "session-cross")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(content)).To(ContainSubstring(`"type":"session_meta"`))
+			Expect(string(content)).To(ContainSubstring("continues a conversation held with Claude Code"))
+			Expect(string(content)).To(ContainSubstring("Hello, can you help me with a task?"))
+		})
+
+		It("fails for agents that cannot import conversations", func() {
+			geminiEnv, err := testutil.NewAgentEnv(testutil.GeminiTestConfig())
+			Expect(err).NotTo(HaveOccurred())
+			DeferCleanup(geminiEnv.Cleanup)
+
+			_, stderr, err := testutil.RunShiftlogInDirWithEnv(
+				repo.Path,
+				geminiEnv.GetEnvVars(),
+				"resume", commitSHA, "--no-checkout", "--agent", "gemini",
+			)
+
+			Expect(err).To(HaveOccurred())
+			Expect(stderr).To(ContainSubstring("cannot resume conversations of other agents"))
+		})
+	})
+
 	// Shared test that runs once (not per-agent)
 	Describe("requires arguments", func() {
 		var repo *testutil.GitRepo
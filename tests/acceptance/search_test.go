@@ -152,6 +152,41 @@ var _ = Describe("Search Command", func() {
 		})
 	})
 
+	Describe("errors flag", func() {
+		It("finds the sessions where a tool reported an error", func() {
+			storeConversation("session-clean")
+
+			Expect(repo.WriteFile("app.py", "print(1)")).To(Succeed())
+			Expect(repo.Commit("Add app")).To(Succeed())
+			transcript := `{"type":"user","uuid":"u1","message":{"role":"user","content":"Run the app"}}
+{"type":"assistant","uuid":"a1","message":{"role":"assistant","content":[{"type":"tool_use","id":"t1","name":"Bash","input":{"command":"python app.py"}}]}}
+{"type":"user","uuid":"u2","message":{"role":"user","content":[{"type":"tool_result","tool_use_id":"t1","is_error":true,"content":"Traceback (most recent call last):\n  File \"app.py\", line 1\nTypeError: unsupported operand"}]}}
+{"type":"assistant","uuid":"a2","message":{"role":"assistant","content":[{"type":"text","text":"Mentioning a TypeError in prose is not an error"}]}}`
+			transcriptPath := filepath.Join(repo.Path, "transcript.jsonl")
+			Expect(os.WriteFile(transcriptPath, []byte(transcript), 0644)).To(Succeed())
+			hookInput := testutil.SampleHookInput("session-failing", transcriptPath, "git commit -m 'test'")
+			_, _, err := testutil.RunShiftlogInDirWithStdin(repo.Path, hookInput, "store")
+			Expect(err).NotTo(HaveOccurred())
+
+			stdout, _, err := testutil.RunShiftlogInDir(repo.Path, "search", "--errors", "TypeError")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(stdout).To(ContainSubstring("Add app"))
+			Expect(stdout).To(ContainSubstring("[user/tool_result]"))
+			Expect(stdout).To(ContainSubstring("TypeError: unsupported operand"))
+			Expect(stdout).NotTo(ContainSubstring("in prose"))
+
+			// Without a query, every session with an error is listed
+			stdout, _, err = testutil.RunShiftlogInDir(repo.Path, "search", "--errors")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(stdout).To(ContainSubstring("Add app"))
+			Expect(stdout).NotTo(ContainSubstring("Initial commit"))
+
+			stdout, _, err = testutil.RunShiftlogInDir(repo.Path, "search", "--errors", "help me with a task")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(stdout).To(ContainSubstring("no matching conversations found"))
+		})
+	})
+
 	Describe("error cases", func() {
 		It("shows error when no query and no filters", func() {
 			_, stderr, err := testutil.RunShiftlogInDir(repo.Path, "search")
@@ -177,4 +212,36 @@ var _ = Describe("Search Command", func() {
 			Expect(stdout).To(ContainSubstring("no matching conversations found"))
 		})
 	})
+
+	Describe("index", func() {
+		It("keeps the index up to date as conversations are stored", func() {
+			storeConversation("session-index-1")
+
+			stdout, _, err := testutil.RunShiftlogInDir(repo.Path, "search", "help me with a task")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(stdout).To(ContainSubstring("help me with a task"))
+			Expect(filepath.Join(repo.Path, ".git", "shiftlog", "index")).To(BeAnExistingFile())
+
+			// The second conversation is found through the updated index
+			Expect(repo.WriteFile("file.txt", "change")).To(Succeed())
+			Expect(repo.Commit("Second commit")).To(Succeed())
+			transcriptPath := filepath.Join(repo.Path, "transcript.jsonl")
+			transcript := testutil.SampleTranscriptWithIDs([]string{"index-1"}, []string{"Rename the upload client"})
+			Expect(os.WriteFile(transcriptPath, []byte(transcript), 0644)).To(Succeed())
+			hookInput := testutil.SampleHookInput("session-index-2", transcriptPath, "git commit -m 'test'")
+			_, _, err = testutil.RunShiftlogInDirWithStdin(repo.Path, hookInput, "store")
+			Expect(err).NotTo(HaveOccurred())
+
+			stdout, _, err = testutil.RunShiftlogInDir(repo.Path, "search", "upload client")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(stdout).To(ContainSubstring("Second commit"))
+			Expect(stdout).NotTo(ContainSubstring("Initial commit"))
+
+			// A removed index is rebuilt
+			Expect(os.Remove(filepath.Join(repo.Path, ".git", "shiftlog", "index"))).To(Succeed())
+			stdout, _, err = testutil.RunShiftlogInDir(repo.Path, "search", "help me with a task")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(stdout).To(ContainSubstring("Initial commit"))
+		})
+	})
 })
@@ -0,0 +1,54 @@
+package acceptance_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/re-cinq/shift-log/tests/acceptance/testutil"
+)
+
+var _ = Describe("Selftest Command", func() {
+	var repo *testutil.GitRepo
+
+	BeforeEach(func() {
+		var err error
+		repo, err = testutil.NewGitRepo()
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(repo.WriteFile("README.md", "# Test")).To(Succeed())
+		Expect(repo.Commit("Initial commit")).To(Succeed())
+	})
+
+	AfterEach(func() {
+		if repo != nil {
+			repo.Cleanup()
+		}
+	})
+
+	It("stores and reads back a conversation without touching the repository", func() {
+		head, err := repo.GetHead()
+		Expect(err).NotTo(HaveOccurred())
+
+		stdout, _, err := testutil.RunShiftlogInDir(repo.Path, "selftest")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stdout).To(ContainSubstring("Storing conversation from hook payload... OK"))
+		Expect(stdout).To(ContainSubstring("Reading conversation from web API... OK"))
+		Expect(stdout).To(ContainSubstring("All checks passed!"))
+
+		after, err := repo.GetHead()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(after).To(Equal(head))
+		branches, err := repo.RunOutput("git", "branch", "--list", "shiftlog-selftest")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(branches).To(BeEmpty())
+		Expect(repo.Run("git", "rev-parse", "--verify", "-q", "refs/notes/shiftlog")).NotTo(Succeed())
+	})
+
+	It("exits non-zero when conversations cannot be stored", func() {
+		Expect(repo.WriteFile(".shiftlog/config", `{"backend": "nope"}`)).To(Succeed())
+
+		stdout, _, err := testutil.RunShiftlogInDir(repo.Path, "selftest")
+		Expect(err).To(HaveOccurred())
+		Expect(stdout).To(ContainSubstring("FAIL"))
+	})
+})
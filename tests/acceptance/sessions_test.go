@@ -0,0 +1,172 @@
+package acceptance_test
+
+import (
+	"os"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/re-cinq/shift-log/tests/acceptance/testutil"
+)
+
+var _ = Describe("Sessions and Attach Commands", func() {
+	var repo *testutil.GitRepo
+	var agentEnv *testutil.AgentEnv
+
+	BeforeEach(func() {
+		var err error
+		repo, err = testutil.NewGitRepo()
+		Expect(err).NotTo(HaveOccurred())
+
+		agentEnv, err = testutil.NewAgentEnv(testutil.ClaudeTestConfig())
+		Expect(err).NotTo(HaveOccurred())
+
+		_, _, err = testutil.RunShiftlogInDir(repo.Path, "init")
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(repo.WriteFile("README.md", "# Test")).To(Succeed())
+		Expect(repo.Commit("Initial commit")).To(Succeed())
+	})
+
+	AfterEach(func() {
+		repo.Cleanup()
+		agentEnv.Cleanup()
+	})
+
+	Describe("shiftlog sessions", func() {
+		It("reports when no sessions exist", func() {
+			stdout, _, err := testutil.RunShiftlogInDirWithEnv(repo.Path, agentEnv.GetEnvVars(), "sessions")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(stdout).To(ContainSubstring("no sessions found"))
+		})
+
+		It("lists sessions with agent and message count", func() {
+			_, err := agentEnv.WriteSessionFile(repo.Path, "listed-session", []byte(testutil.SampleTranscript()))
+			Expect(err).NotTo(HaveOccurred())
+
+			stdout, _, err := testutil.RunShiftlogInDirWithEnv(repo.Path, agentEnv.GetEnvVars(), "sessions")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(stdout).To(ContainSubstring("SESSION"))
+			Expect(stdout).To(MatchRegexp(`listed-session\s+claude\s+just now\s+\d+`))
+		})
+
+		It("rejects an unknown agent", func() {
+			_, _, err := testutil.RunShiftlogInDirWithEnv(repo.Path, agentEnv.GetEnvVars(), "sessions", "--agent", "nonexistent")
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Describe("shiftlog attach", func() {
+		It("stores the session on HEAD", func() {
+			_, err := agentEnv.WriteSessionFile(repo.Path, "attached-session", []byte(testutil.SampleTranscript()))
+			Expect(err).NotTo(HaveOccurred())
+
+			_, stderr, err := testutil.RunShiftlogInDirWithEnv(repo.Path, agentEnv.GetEnvVars(), "attach", "attached-session")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(stderr).To(ContainSubstring("stored conversation"))
+
+			note, err := repo.GetNote("refs/notes/shiftlog", "HEAD")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(note).To(ContainSubstring("attached-session"))
+			Expect(note).To(ContainSubstring(`"trigger": "attach"`))
+		})
+
+		It("stores the session on the given commit", func() {
+			first, err := repo.GetHead()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(repo.WriteFile("second.txt", "second")).To(Succeed())
+			Expect(repo.Commit("Second commit")).To(Succeed())
+
+			_, err = agentEnv.WriteSessionFile(repo.Path, "older-session", []byte(testutil.SampleTranscript()))
+			Expect(err).NotTo(HaveOccurred())
+
+			_, _, err = testutil.RunShiftlogInDirWithEnv(repo.Path, agentEnv.GetEnvVars(), "attach", "older-session", "--commit", first[:7])
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(repo.HasNote("refs/notes/shiftlog", first)).To(BeTrue())
+			Expect(repo.HasNote("refs/notes/shiftlog", "HEAD")).To(BeFalse())
+		})
+
+		It("replaces the stored conversation with --replace", func() {
+			_, err := agentEnv.WriteSessionFile(repo.Path, "wrong-session", []byte(testutil.SampleTranscript()))
+			Expect(err).NotTo(HaveOccurred())
+			_, err = agentEnv.WriteSessionFile(repo.Path, "right-session", []byte(testutil.SampleTranscript()))
+			Expect(err).NotTo(HaveOccurred())
+
+			_, _, err = testutil.RunShiftlogInDirWithEnv(repo.Path, agentEnv.GetEnvVars(), "attach", "wrong-session")
+			Expect(err).NotTo(HaveOccurred())
+			_, _, err = testutil.RunShiftlogInDirWithEnv(repo.Path, agentEnv.GetEnvVars(), "attach", "right-session", "--replace")
+			Expect(err).NotTo(HaveOccurred())
+
+			note, err := repo.GetNote("refs/notes/shiftlog", "HEAD")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(note).To(ContainSubstring("right-session"))
+			Expect(note).NotTo(ContainSubstring("wrong-session"))
+		})
+
+		It("fails for an unknown session", func() {
+			_, _, err := testutil.RunShiftlogInDirWithEnv(repo.Path, agentEnv.GetEnvVars(), "attach", "missing-session")
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("stores the most recent session with --last, however old", func() {
+			yesterday := time.Now().Add(-20 * time.Hour)
+			older, err := agentEnv.WriteSessionFile(repo.Path, "older-session", []byte(testutil.SampleTranscript()))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(os.Chtimes(older, yesterday.Add(-time.Hour), yesterday.Add(-time.Hour))).To(Succeed())
+			latest, err := agentEnv.WriteSessionFile(repo.Path, "latest-session", []byte(testutil.SampleTranscript()))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(os.Chtimes(latest, yesterday, yesterday)).To(Succeed())
+
+			_, stderr, err := testutil.RunShiftlogInDirWithEnv(repo.Path, agentEnv.GetEnvVars(), "attach", "--last")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(stderr).To(ContainSubstring("latest-session from 20h ago"))
+
+			note, err := repo.GetNote("refs/notes/shiftlog", "HEAD")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(note).To(ContainSubstring("latest-session"))
+			Expect(note).NotTo(ContainSubstring("older-session"))
+		})
+
+		It("requires either a session ID or --last", func() {
+			_, _, err := testutil.RunShiftlogInDirWithEnv(repo.Path, agentEnv.GetEnvVars(), "attach")
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Describe("shiftlog store --manual grace window", func() {
+		var sessionPath string
+
+		BeforeEach(func() {
+			var err error
+			sessionPath, err = agentEnv.WriteSessionFile(repo.Path, "overnight-session", []byte(testutil.SampleTranscript()))
+			Expect(err).NotTo(HaveOccurred())
+			lastNight := time.Now().Add(-9 * time.Hour)
+			Expect(os.Chtimes(sessionPath, lastNight, lastNight)).To(Succeed())
+		})
+
+		It("skips sessions older than the default window without a terminal", func() {
+			_, _, err := testutil.RunShiftlogInDirWithEnv(repo.Path, agentEnv.GetEnvVars(), "store", "--manual")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(repo.HasNote("refs/notes/shiftlog", "HEAD")).To(BeFalse())
+		})
+
+		It("stores sessions within --grace", func() {
+			_, _, err := testutil.RunShiftlogInDirWithEnv(repo.Path, agentEnv.GetEnvVars(), "store", "--manual", "--grace", "12h")
+			Expect(err).NotTo(HaveOccurred())
+
+			note, err := repo.GetNote("refs/notes/shiftlog", "HEAD")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(note).To(ContainSubstring("overnight-session"))
+		})
+
+		It("stores sessions within the session_grace config", func() {
+			Expect(repo.WriteFile(".shiftlog/config", `{"session_grace": "12h"}`)).To(Succeed())
+
+			_, _, err := testutil.RunShiftlogInDirWithEnv(repo.Path, agentEnv.GetEnvVars(), "store", "--manual")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(repo.HasNote("refs/notes/shiftlog", "HEAD")).To(BeTrue())
+		})
+	})
+})
@@ -94,6 +94,26 @@ var _ = Describe("Show Command", func() {
 			Expect(stdout).To(ContainSubstring("Hello, can you help me with a task?"))
 			Expect(stdout).To(ContainSubstring("Of course! What would you like help with?"))
 		})
+
+		It("writes the conversation as HTML with --format html", func() {
+			storeConversation("session-show-html")
+
+			stdout, _, err := testutil.RunShiftlogInDir(repo.Path, "show", "--format", "html")
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(stdout).To(HavePrefix("<!DOCTYPE html>"))
+			Expect(stdout).To(ContainSubstring(`<div class="message user">`))
+			Expect(stdout).To(ContainSubstring("<p>Hello, can you help me with a task?</p>"))
+			Expect(stdout).NotTo(ContainSubstring("Showing:"))
+		})
+
+		It("rejects an unknown format", func() {
+			storeConversation("session-show-format")
+
+			_, stderr, err := testutil.RunShiftlogInDir(repo.Path, "show", "--format", "pdf")
+			Expect(err).To(HaveOccurred())
+			Expect(stderr).To(ContainSubstring("invalid --format"))
+		})
 	})
 
 	Describe("without conversation", func() {
@@ -0,0 +1,231 @@
+package acceptance_test
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/re-cinq/shift-log/tests/acceptance/testutil"
+)
+
+var _ = Describe("Stats Command", func() {
+	var repo *testutil.GitRepo
+	var agentSHA string
+
+	BeforeEach(func() {
+		var err error
+		repo, err = testutil.NewGitRepo()
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(repo.WriteFile("README.md", "# Test")).To(Succeed())
+		Expect(repo.Commit("Initial commit")).To(Succeed())
+
+		// One line written by the agent's Edit call, two written by hand
+		Expect(repo.WriteFile("app.go", "package app\n\nfunc greet()\n// manual note\n")).To(Succeed())
+		Expect(repo.Commit("Rename greeting")).To(Succeed())
+		agentSHA, err = repo.GetHead()
+		Expect(err).NotTo(HaveOccurred())
+
+		root, err := repo.RunOutput("git", "rev-parse", "--show-toplevel")
+		Expect(err).NotTo(HaveOccurred())
+		transcriptPath := filepath.Join(os.TempDir(), "session-stats.jsonl")
+		transcript := testutil.SampleEditTranscript(filepath.Join(strings.TrimSpace(root), "app.go"))
+		Expect(os.WriteFile(transcriptPath, []byte(transcript), 0644)).To(Succeed())
+		DeferCleanup(os.Remove, transcriptPath)
+
+		hookInput := testutil.SampleHookInput("session-stats", transcriptPath, "git commit -m 'Rename greeting'")
+		_, _, err = testutil.RunShiftlogInDirWithStdin(repo.Path, hookInput, "store")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		if repo != nil {
+			repo.Cleanup()
+		}
+	})
+
+	It("records the AI authorship ratio in the note", func() {
+		note, err := repo.GetNote("refs/notes/shiftlog", agentSHA)
+		Expect(err).NotTo(HaveOccurred())
+
+		var stored map[string]interface{}
+		Expect(json.Unmarshal([]byte(note), &stored)).To(Succeed())
+		Expect(stored["ai_assisted"]).To(BeTrue())
+
+		authorship, ok := stored["authorship"].(map[string]interface{})
+		Expect(ok).To(BeTrue())
+		Expect(authorship["ai_lines"]).To(BeEquivalentTo(1))
+		Expect(authorship["total_lines"]).To(BeEquivalentTo(3))
+	})
+
+	It("summarizes conversations as JSON", func() {
+		stdout, _, err := testutil.RunShiftlogInDir(repo.Path, "stats", "--format", "json")
+		Expect(err).NotTo(HaveOccurred())
+
+		var stats map[string]interface{}
+		Expect(json.Unmarshal([]byte(stdout), &stats)).To(Succeed())
+		Expect(stats["commits"]).To(BeEquivalentTo(2))
+		Expect(stats["conversations"]).To(BeEquivalentTo(1))
+		Expect(stats["ai_assisted"]).To(BeEquivalentTo(1))
+		Expect(stats["ai_lines"]).To(BeEquivalentTo(1))
+		Expect(stats["total_lines"]).To(BeEquivalentTo(3))
+
+		models, ok := stats["models"].(map[string]interface{})
+		Expect(ok).To(BeTrue())
+		model, ok := models["claude-sonnet-4-5-20250514"].(map[string]interface{})
+		Expect(ok).To(BeTrue())
+		Expect(model["conversations"]).To(BeEquivalentTo(1))
+		Expect(model["messages"]).To(BeEquivalentTo(2))
+	})
+
+	It("groups authors by the repository's .mailmap", func() {
+		Expect(repo.WriteFile("other.go", "package app\n")).To(Succeed())
+		Expect(repo.Run("git", "add", "-A")).To(Succeed())
+		Expect(repo.Run("git", "commit", "-m", "From home", "--author", "Tester <tester@home.example>")).To(Succeed())
+		transcriptPath := filepath.Join(repo.Path, "transcript.jsonl")
+		Expect(os.WriteFile(transcriptPath, []byte(testutil.SampleTranscript()), 0644)).To(Succeed())
+		hookInput := testutil.SampleHookInput("session-home", transcriptPath, "git commit -m 'From home'")
+		_, _, err := testutil.RunShiftlogInDirWithStdin(repo.Path, hookInput, "store")
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(repo.WriteFile(".mailmap", "Test User <test@example.com> <tester@home.example>\n")).To(Succeed())
+
+		stdout, _, err := testutil.RunShiftlogInDir(repo.Path, "stats", "--format", "json")
+		Expect(err).NotTo(HaveOccurred())
+		var stats map[string]interface{}
+		Expect(json.Unmarshal([]byte(stdout), &stats)).To(Succeed())
+		Expect(stats["authors"]).To(HaveLen(1))
+		Expect(stats["authors"]).To(HaveKeyWithValue("Test User", HaveKeyWithValue("conversations", BeEquivalentTo(2))))
+
+		stdout, _, err = testutil.RunShiftlogInDir(repo.Path, "log", "--author", "Test User")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stdout).To(ContainSubstring("From home"))
+	})
+
+	It("prints a readable summary", func() {
+		stdout, _, err := testutil.RunShiftlogInDir(repo.Path, "stats")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stdout).To(ContainSubstring("AI-assisted:    1"))
+		Expect(stdout).To(ContainSubstring("By agent:       1 (33%)"))
+		Expect(stdout).To(ContainSubstring("claude"))
+		Expect(stdout).To(ContainSubstring("By model"))
+	})
+
+	It("exports the authorship report as CSV", func() {
+		stdout, _, err := testutil.RunShiftlogInDir(repo.Path, "stats", "--authorship", "--format", "csv")
+		Expect(err).NotTo(HaveOccurred())
+
+		lines := strings.Split(strings.TrimSpace(stdout), "\n")
+		Expect(lines).To(HaveLen(2))
+		Expect(lines[0]).To(Equal("commit,date,agent,model,ai_assisted,ai_lines,total_lines,ratio,message"))
+		Expect(lines[1]).To(HavePrefix(agentSHA + ","))
+		Expect(lines[1]).To(ContainSubstring(",true,1,3,0.33,Rename greeting"))
+	})
+
+	It("exports the authorship report as Markdown", func() {
+		stdout, _, err := testutil.RunShiftlogInDir(repo.Path, "stats", "--authorship", "--format", "markdown")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stdout).To(ContainSubstring("| commit | date |"))
+		Expect(stdout).To(ContainSubstring("| " + agentSHA + " |"))
+	})
+
+	It("lists no expensive turns when transcripts record no per-turn usage", func() {
+		stdout, _, err := testutil.RunShiftlogInDir(repo.Path, "stats", "--turns")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stdout).To(ContainSubstring("No per-turn token usage recorded."))
+
+		stdout, _, err = testutil.RunShiftlogInDir(repo.Path, "stats", "--turns", "--format", "json")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(strings.TrimSpace(stdout)).To(Equal("[]"))
+	})
+
+	It("reports the tools each agent used", func() {
+		stdout, _, err := testutil.RunShiftlogInDir(repo.Path, "stats", "--tools", "--format", "json")
+		Expect(err).NotTo(HaveOccurred())
+
+		var usage []map[string]interface{}
+		Expect(json.Unmarshal([]byte(stdout), &usage)).To(Succeed())
+		Expect(usage).To(HaveLen(1))
+		Expect(usage[0]["agent"]).To(Equal("claude"))
+		Expect(usage[0]["calls"]).To(BeEquivalentTo(1))
+		Expect(usage[0]["tools"]).To(ConsistOf(HaveKeyWithValue("name", "Edit")))
+		Expect(usage[0]["files"]).To(ConsistOf(HaveKeyWithValue("path", "app.go")))
+
+		stdout, _, err = testutil.RunShiftlogInDir(repo.Path, "stats", "--tools")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stdout).To(ContainSubstring("Sessions: 1  Calls: 1  Failed: 0%"))
+		Expect(stdout).To(ContainSubstring("app.go"))
+	})
+
+	It("compares the efficiency of models", func() {
+		stdout, _, err := testutil.RunShiftlogInDir(repo.Path, "stats", "--by", "model", "--format", "json")
+		Expect(err).NotTo(HaveOccurred())
+
+		var comparison []map[string]interface{}
+		Expect(json.Unmarshal([]byte(stdout), &comparison)).To(Succeed())
+		Expect(comparison).To(HaveLen(1))
+		Expect(comparison[0]["name"]).To(Equal("claude-sonnet-4-5-20250514"))
+		Expect(comparison[0]["commits"]).To(BeEquivalentTo(1))
+
+		stdout, _, err = testutil.RunShiftlogInDir(repo.Path, "stats", "--by", "model")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stdout).To(ContainSubstring("tokens/line"))
+		Expect(stdout).To(ContainSubstring("claude-sonnet-4-5-20250514"))
+	})
+
+	It("exports the effort per interval as CSV", func() {
+		stdout, _, err := testutil.RunShiftlogInDir(repo.Path, "stats", "--timeseries", "--interval", "week", "--format", "csv")
+		Expect(err).NotTo(HaveOccurred())
+
+		lines := strings.Split(strings.TrimSpace(stdout), "\n")
+		Expect(lines).To(HaveLen(2))
+		Expect(lines[0]).To(Equal("start,commits,conversations,tokens,cost"))
+		Expect(lines[1]).To(MatchRegexp(`^\d{4}-\d{2}-\d{2},2,1,\d+,`))
+	})
+
+	It("pushes the effort per interval to a Pushgateway", func() {
+		var body string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			data, _ := io.ReadAll(r.Body)
+			body = string(data)
+		}))
+		defer server.Close()
+
+		stdout, _, err := testutil.RunShiftlogInDir(repo.Path, "stats", "--timeseries", "--interval", "week", "--push-gateway", server.URL)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stdout).To(ContainSubstring("Pushed the current week"))
+		Expect(body).To(ContainSubstring(`shiftlog_commits{interval="week"} 2`))
+		Expect(body).To(ContainSubstring(`shiftlog_conversations{interval="week"} 1`))
+	})
+
+	It("flags agent work that was later reverted", func() {
+		stdout, _, err := testutil.RunShiftlogInDir(repo.Path, "stats", "--problematic")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stdout).To(ContainSubstring("No reverted or quickly fixed agent work found."))
+
+		Expect(repo.Run("git", "revert", "--no-edit", agentSHA)).To(Succeed())
+
+		stdout, _, err = testutil.RunShiftlogInDir(repo.Path, "stats", "--problematic")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stdout).To(ContainSubstring(agentSHA[:7]))
+		Expect(stdout).To(ContainSubstring("reverted by"))
+
+		stdout, _, err = testutil.RunShiftlogInDir(repo.Path, "stats", "--format", "json")
+		Expect(err).NotTo(HaveOccurred())
+		var stats map[string]interface{}
+		Expect(json.Unmarshal([]byte(stdout), &stats)).To(Succeed())
+		Expect(stats["problematic"]).To(BeEquivalentTo(1))
+	})
+
+	It("rejects summary-only formats it cannot produce", func() {
+		_, _, err := testutil.RunShiftlogInDir(repo.Path, "stats", "--format", "csv")
+		Expect(err).To(HaveOccurred())
+	})
+})
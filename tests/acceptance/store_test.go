@@ -73,11 +73,16 @@ var _ = Describe("Store Command", func() {
 				var stored map[string]interface{}
 				Expect(json.Unmarshal([]byte(noteContent), &stored)).To(Succeed())
 
-				Expect(stored["version"]).To(BeEquivalentTo(3))
+				Expect(stored["version"]).To(BeEquivalentTo(12))
 				Expect(stored["session_id"]).To(Equal("session-456"))
 				Expect(stored["checksum"]).To(HavePrefix("sha256:"))
 				Expect(stored["transcript"]).NotTo(BeEmpty())
 				Expect(stored["agent"]).NotTo(BeEmpty())
+
+				provenance, ok := stored["provenance"].(map[string]interface{})
+				Expect(ok).To(BeTrue())
+				Expect(provenance["agent"]).To(Equal(stored["agent"]))
+				Expect(provenance["trigger"]).To(Equal("agent-hook"))
 			})
 
 			It("transcript can be decompressed from note", func() {
@@ -0,0 +1,93 @@
+package acceptance_test
+
+import (
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/re-cinq/shift-log/tests/acceptance/testutil"
+)
+
+var _ = Describe("Commit Message Suggestions", func() {
+	var repo *testutil.GitRepo
+	var agentEnv *testutil.AgentEnv
+
+	BeforeEach(func() {
+		var err error
+		repo, err = testutil.NewGitRepo()
+		Expect(err).NotTo(HaveOccurred())
+
+		agentEnv, err = testutil.NewAgentEnv(testutil.ClaudeTestConfig())
+		Expect(err).NotTo(HaveOccurred())
+
+		_, _, err = testutil.RunShiftlogInDir(repo.Path, "init", "--suggest-commit-msg", "heuristic")
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(repo.WriteFile("README.md", "# Test")).To(Succeed())
+		Expect(repo.Commit("Initial commit")).To(Succeed())
+	})
+
+	AfterEach(func() {
+		repo.Cleanup()
+		agentEnv.Cleanup()
+	})
+
+	writeMessageFile := func(content string) string {
+		path := filepath.Join(repo.Path, ".git", "COMMIT_EDITMSG")
+		Expect(os.WriteFile(path, []byte(content), 0644)).To(Succeed())
+		return path
+	}
+
+	It("installs the prepare-commit-msg hook and saves the mode", func() {
+		hook, err := os.ReadFile(filepath.Join(repo.Path, ".git", "hooks", "prepare-commit-msg"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(hook)).To(ContainSubstring("suggest-commit-msg"))
+
+		config, err := repo.ReadFile(".shiftlog/config")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(config).To(ContainSubstring(`"commit_suggestion": "heuristic"`))
+	})
+
+	It("rejects an unknown mode", func() {
+		_, _, err := testutil.RunShiftlogInDir(repo.Path, "init", "--suggest-commit-msg", "magic")
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("comments the last user request into the commit template", func() {
+		_, err := agentEnv.WriteSessionFile(repo.Path, "suggest-session", []byte(testutil.SampleTranscript()))
+		Expect(err).NotTo(HaveOccurred())
+
+		path := writeMessageFile("\n# Please enter the commit message for your changes.\n")
+		_, _, err = testutil.RunShiftlogInDirWithEnv(repo.Path, agentEnv.GetEnvVars(), "suggest-commit-msg", path, "template")
+		Expect(err).NotTo(HaveOccurred())
+
+		content, err := os.ReadFile(path)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(content)).To(MatchRegexp(`(?s)# Suggested by shiftlog from Claude Code session suggest-session:\n#\n# \S.*\n#\n# Please enter`))
+	})
+
+	It("leaves messages given with -m alone", func() {
+		_, err := agentEnv.WriteSessionFile(repo.Path, "suggest-session", []byte(testutil.SampleTranscript()))
+		Expect(err).NotTo(HaveOccurred())
+
+		path := writeMessageFile("Fix the bug\n")
+		_, _, err = testutil.RunShiftlogInDirWithEnv(repo.Path, agentEnv.GetEnvVars(), "suggest-commit-msg", path, "message")
+		Expect(err).NotTo(HaveOccurred())
+
+		content, err := os.ReadFile(path)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(content)).To(Equal("Fix the bug\n"))
+	})
+
+	It("suggests nothing without an active session", func() {
+		path := writeMessageFile("\n# Please enter the commit message for your changes.\n")
+		_, _, err := testutil.RunShiftlogInDirWithEnv(repo.Path, agentEnv.GetEnvVars(), "suggest-commit-msg", path)
+		Expect(err).NotTo(HaveOccurred())
+
+		content, err := os.ReadFile(path)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(content)).NotTo(ContainSubstring("Suggested by shiftlog"))
+	})
+})
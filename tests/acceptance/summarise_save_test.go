@@ -0,0 +1,134 @@
+package acceptance_test
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/re-cinq/shift-log/tests/acceptance/testutil"
+)
+
+var _ = Describe("Summarise --save", func() {
+	var repo *testutil.GitRepo
+
+	BeforeEach(func() {
+		var err error
+		repo, err = testutil.NewGitRepo()
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(repo.WriteFile("README.md", "# Test")).To(Succeed())
+		Expect(repo.Commit("Initial commit")).To(Succeed())
+	})
+
+	AfterEach(func() {
+		if repo != nil {
+			repo.Cleanup()
+		}
+	})
+
+	storeConversation := func(sessionID string, env []string) string {
+		transcriptPath := filepath.Join(os.TempDir(), sessionID+".jsonl")
+		Expect(os.WriteFile(transcriptPath, []byte(testutil.SampleTranscript()), 0644)).To(Succeed())
+		DeferCleanup(os.Remove, transcriptPath)
+
+		head, err := repo.GetHead()
+		Expect(err).NotTo(HaveOccurred())
+
+		hookInput := testutil.SampleHookInput(sessionID, transcriptPath, "git commit -m 'test'")
+		_, _, err = testutil.RunShiftlogInDirWithEnvAndStdin(repo.Path, env, hookInput, "store")
+		Expect(err).NotTo(HaveOccurred())
+		return head
+	}
+
+	noteSummary := func(sha string) string {
+		note, err := repo.GetNote("refs/notes/shiftlog", sha)
+		Expect(err).NotTo(HaveOccurred())
+		var stored map[string]interface{}
+		Expect(json.Unmarshal([]byte(note), &stored)).To(Succeed())
+		summary, _ := stored["summary"].(string)
+		return summary
+	}
+
+	// mockAgent puts a "claude" binary on PATH that prints a fixed summary
+	mockAgent := func() []string {
+		mockDir, err := os.MkdirTemp("", "shiftlog-mock-summary-*")
+		Expect(err).NotTo(HaveOccurred())
+		DeferCleanup(os.RemoveAll, mockDir)
+
+		script := "#!/bin/sh\necho 'Created a test file with the Bash tool.'\n"
+		Expect(os.WriteFile(filepath.Join(mockDir, "claude"), []byte(script), 0755)).To(Succeed())
+		return []string{"PATH=" + mockDir + ":" + os.Getenv("PATH")}
+	}
+
+	Describe("at store time", func() {
+		It("stores no summary by default", func() {
+			sha := storeConversation("session-summary-default", nil)
+			Expect(noteSummary(sha)).To(BeEmpty())
+		})
+
+		It("stores a heuristic summary when configured", func() {
+			Expect(repo.WriteFile(".shiftlog/config", `{"summary": "heuristic"}`)).To(Succeed())
+			sha := storeConversation("session-summary-heuristic", nil)
+			Expect(noteSummary(sha)).To(HavePrefix("The user asked: "))
+		})
+
+		It("stores the agent's summary when configured", func() {
+			Expect(repo.WriteFile(".shiftlog/config", `{"summary": "agent"}`)).To(Succeed())
+			sha := storeConversation("session-summary-agent", mockAgent())
+			Expect(noteSummary(sha)).To(Equal("Created a test file with the Bash tool."))
+		})
+
+		It("keeps the note and its heuristic summary when the agent fails", func() {
+			mockDir, err := os.MkdirTemp("", "shiftlog-mock-summary-*")
+			Expect(err).NotTo(HaveOccurred())
+			DeferCleanup(os.RemoveAll, mockDir)
+			Expect(os.WriteFile(filepath.Join(mockDir, "claude"), []byte("#!/bin/sh\nexit 1\n"), 0755)).To(Succeed())
+
+			Expect(repo.WriteFile(".shiftlog/config", `{"summary": "agent"}`)).To(Succeed())
+			sha := storeConversation("session-summary-agent-fails", []string{"PATH=" + mockDir + ":" + os.Getenv("PATH")})
+			Expect(noteSummary(sha)).To(HavePrefix("The user asked: "))
+		})
+	})
+
+	Describe("backfill", func() {
+		It("summarises every conversation without a summary", func() {
+			sha := storeConversation("session-summary-backfill", nil)
+
+			stdout, _, err := testutil.RunShiftlogInDir(repo.Path, "summarise", "--all", "--heuristic")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(stdout).To(ContainSubstring(sha[:7] + " The user asked: "))
+			Expect(noteSummary(sha)).To(HavePrefix("The user asked: "))
+
+			stdout, _, err = testutil.RunShiftlogInDir(repo.Path, "summarise", "--all", "--heuristic")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(stdout).To(ContainSubstring("no conversations need a summary"))
+
+			stdout, _, err = testutil.RunShiftlogInDir(repo.Path, "list")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(stdout).To(ContainSubstring("  The user asked: "))
+		})
+
+		It("summarises a single commit with the agent", func() {
+			sha := storeConversation("session-summary-ref", nil)
+
+			_, _, err := testutil.RunShiftlogInDirWithEnv(repo.Path, mockAgent(), "summarise", "--save", "HEAD")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(noteSummary(sha)).To(Equal("Created a test file with the Bash tool."))
+		})
+
+		It("rejects commits with --all", func() {
+			_, stderr, err := testutil.RunShiftlogInDir(repo.Path, "summarise", "--all", "HEAD")
+			Expect(err).To(HaveOccurred())
+			Expect(stderr).To(ContainSubstring("specify commits to summarise or --all, not both"))
+		})
+
+		It("saves only with --save or --all", func() {
+			_, stderr, err := testutil.RunShiftlogInDir(repo.Path, "summarise", "--heuristic")
+			Expect(err).To(HaveOccurred())
+			Expect(stderr).To(ContainSubstring("--heuristic and --force apply with --save or --all"))
+		})
+	})
+})
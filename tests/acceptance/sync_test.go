@@ -227,6 +227,198 @@ var _ = Describe("Sync Command", func() {
 		})
 	})
 
+	Describe("shiftlog sync with multiple remotes", func() {
+		var backup *testutil.GitRepo
+
+		BeforeEach(func() {
+			var err error
+			backup, err = testutil.NewGitRepoAsBare()
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(local.AddRemote("backup", backup.Path)).To(Succeed())
+			Expect(local.Run("git", "push", "backup", "master")).To(Succeed())
+
+			transcriptPath := filepath.Join(local.Path, "transcript.jsonl")
+			Expect(os.WriteFile(transcriptPath, []byte(testutil.SampleTranscript()), 0644)).To(Succeed())
+
+			hookInput := testutil.SampleHookInput("session-multi-remote", transcriptPath, "git commit -m 'test'")
+			_, _, err = testutil.RunShiftlogInDirWithStdin(local.Path, hookInput, "store")
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		AfterEach(func() {
+			if backup != nil {
+				backup.Cleanup()
+			}
+		})
+
+		It("pushes notes to each remote given with --remote", func() {
+			head, err := local.GetHead()
+			Expect(err).NotTo(HaveOccurred())
+
+			stdout, _, err := testutil.RunShiftlogInDir(local.Path, "sync", "push", "--remote", "origin", "--remote", "backup")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(stdout).To(ContainSubstring("Pushed conversation notes to origin"))
+			Expect(stdout).To(ContainSubstring("Pushed conversation notes to backup"))
+
+			Expect(remote.HasNote("refs/notes/shiftlog", head)).To(BeTrue())
+			Expect(backup.HasNote("refs/notes/shiftlog", head)).To(BeTrue())
+		})
+
+		It("pushes notes to every remote with --all-remotes", func() {
+			head, err := local.GetHead()
+			Expect(err).NotTo(HaveOccurred())
+
+			stdout, _, err := testutil.RunShiftlogInDir(local.Path, "sync", "push", "--all-remotes")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(stdout).To(ContainSubstring("Pushed conversation notes to origin"))
+			Expect(stdout).To(ContainSubstring("Pushed conversation notes to backup"))
+
+			Expect(remote.HasNote("refs/notes/shiftlog", head)).To(BeTrue())
+			Expect(backup.HasNote("refs/notes/shiftlog", head)).To(BeTrue())
+		})
+
+		It("merges notes pulled from every remote with --all-remotes", func() {
+			head, err := local.GetHead()
+			Expect(err).NotTo(HaveOccurred())
+
+			// Each remote holds a note the other lacks
+			_, _, err = testutil.RunShiftlogInDir(local.Path, "sync", "push", "--remote", "backup")
+			Expect(err).NotTo(HaveOccurred())
+
+			clone, err := testutil.NewGitRepo()
+			Expect(err).NotTo(HaveOccurred())
+			defer clone.Cleanup()
+
+			Expect(clone.AddRemote("origin", remote.Path)).To(Succeed())
+			Expect(clone.AddRemote("backup", backup.Path)).To(Succeed())
+			Expect(clone.Run("git", "fetch", "origin")).To(Succeed())
+			Expect(clone.Run("git", "checkout", "-b", "master", "origin/master")).To(Succeed())
+
+			Expect(clone.WriteFile("second.txt", "content")).To(Succeed())
+			Expect(clone.Commit("second commit")).To(Succeed())
+			second, err := clone.GetHead()
+			Expect(err).NotTo(HaveOccurred())
+			clone.AddNote("refs/notes/shiftlog", second, "origin-note")
+			Expect(clone.Run("git", "push", "origin", "master", "refs/notes/shiftlog")).To(Succeed())
+			Expect(clone.Run("git", "update-ref", "-d", "refs/notes/shiftlog")).To(Succeed())
+
+			stdout, _, err := testutil.RunShiftlogInDir(clone.Path, "sync", "pull", "--all-remotes")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(stdout).To(ContainSubstring("Fetched and merged conversation notes from origin"))
+			Expect(stdout).To(ContainSubstring("Fetched and merged conversation notes from backup"))
+
+			Expect(clone.HasNote("refs/notes/shiftlog", head)).To(BeTrue())
+			Expect(clone.HasNote("refs/notes/shiftlog", second)).To(BeTrue())
+		})
+
+		It("reports each remote's result and fails if any push is rejected", func() {
+			head, err := local.GetHead()
+			Expect(err).NotTo(HaveOccurred())
+
+			// Give origin notes that diverge from the local ones
+			clone, err := testutil.NewGitRepo()
+			Expect(err).NotTo(HaveOccurred())
+			defer clone.Cleanup()
+
+			Expect(clone.AddRemote("origin", remote.Path)).To(Succeed())
+			Expect(clone.Run("git", "fetch", "origin")).To(Succeed())
+			Expect(clone.Run("git", "checkout", "-b", "master", "origin/master")).To(Succeed())
+			clone.AddNote("refs/notes/shiftlog", head, "other-note")
+			_, _, err = testutil.RunShiftlogInDir(clone.Path, "sync", "push")
+			Expect(err).NotTo(HaveOccurred())
+
+			stdout, _, err := testutil.RunShiftlogInDir(local.Path, "sync", "push", "--all-remotes")
+			Expect(err).To(HaveOccurred())
+			Expect(stdout).To(ContainSubstring("Push failed for origin"))
+			Expect(stdout).To(ContainSubstring("Pushed conversation notes to backup"))
+
+			Expect(backup.HasNote("refs/notes/shiftlog", head)).To(BeTrue())
+		})
+
+		It("rejects --remote combined with --all-remotes", func() {
+			_, _, err := testutil.RunShiftlogInDir(local.Path, "sync", "push", "--all-remotes", "--remote", "backup")
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Describe("shiftlog sync with a contributor notes ref", func() {
+		const contributorRef = "refs/notes/shiftlog-alice"
+		var head string
+		var maintainer *testutil.GitRepo
+
+		BeforeEach(func() {
+			transcriptPath := filepath.Join(local.Path, "transcript.jsonl")
+			Expect(os.WriteFile(transcriptPath, []byte(testutil.SampleTranscript()), 0644)).To(Succeed())
+
+			hookInput := testutil.SampleHookInput("session-contributor", transcriptPath, "git commit -m 'test'")
+			_, _, err := testutil.RunShiftlogInDirWithStdin(local.Path, hookInput, "store")
+			Expect(err).NotTo(HaveOccurred())
+			head, err = local.GetHead()
+			Expect(err).NotTo(HaveOccurred())
+
+			maintainer, err = testutil.NewGitRepo()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(maintainer.AddRemote("origin", remote.Path)).To(Succeed())
+			Expect(maintainer.Run("git", "fetch", "origin")).To(Succeed())
+			Expect(maintainer.Run("git", "checkout", "-b", "master", "origin/master")).To(Succeed())
+		})
+
+		AfterEach(func() {
+			if maintainer != nil {
+				maintainer.Cleanup()
+			}
+		})
+
+		It("pushes to the contributor_ref of the config instead of the shared ref", func() {
+			Expect(local.WriteFile(".shiftlog/config", `{"agent": "claude", "contributor_ref": "`+contributorRef+`"}`)).To(Succeed())
+
+			stdout, _, err := testutil.RunShiftlogInDir(local.Path, "sync", "push")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(stdout).To(ContainSubstring("Pushed conversation notes to " + contributorRef + " of origin"))
+
+			Expect(remote.HasNote(contributorRef, head)).To(BeTrue())
+			Expect(remote.HasNote("refs/notes/shiftlog", head)).To(BeFalse())
+		})
+
+		It("shows a contributor ref listed in read_refs with the shared conversations", func() {
+			_, _, err := testutil.RunShiftlogInDir(local.Path, "sync", "push", "--notes-ref", contributorRef)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(maintainer.WriteFile(".shiftlog/config", `{"agent": "claude", "read_refs": ["`+contributorRef+`"]}`)).To(Succeed())
+			stdout, _, err := testutil.RunShiftlogInDir(maintainer.Path, "sync", "pull", "--notes-ref", contributorRef)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(stdout).To(ContainSubstring("Fetched and merged conversation notes of " + contributorRef + " from origin"))
+
+			Expect(maintainer.HasNote(contributorRef, head)).To(BeTrue())
+			Expect(maintainer.HasNote("refs/notes/shiftlog", head)).To(BeFalse())
+
+			stdout, _, err = testutil.RunShiftlogInDir(maintainer.Path, "show", head)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(stdout).To(ContainSubstring("Contributed: read from " + contributorRef))
+		})
+
+		It("merges a contributor ref not in read_refs into the shared notes", func() {
+			_, _, err := testutil.RunShiftlogInDir(local.Path, "sync", "push", "--notes-ref", contributorRef)
+			Expect(err).NotTo(HaveOccurred())
+
+			_, _, err = testutil.RunShiftlogInDir(maintainer.Path, "sync", "pull", "--notes-ref", contributorRef)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(maintainer.HasNote("refs/notes/shiftlog", head)).To(BeTrue())
+
+			// Pushed on, the contributor's conversation is shared
+			stdout, _, err := testutil.RunShiftlogInDir(maintainer.Path, "sync", "push")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(stdout).To(ContainSubstring("Pushed conversation notes to origin"))
+			Expect(remote.HasNote("refs/notes/shiftlog", head)).To(BeTrue())
+		})
+
+		It("rejects a ref shiftlog keeps its own notes in", func() {
+			_, _, err := testutil.RunShiftlogInDir(local.Path, "sync", "push", "--notes-ref", "refs/notes/shiftlog-private")
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
 	Describe("shiftlog sync push", func() {
 		It("pushes notes to remote", func() {
 			// Create a note on the commit
@@ -403,6 +595,144 @@ var _ = Describe("Sync Command", func() {
 		})
 	})
 
+	Describe("shiftlog sync status", func() {
+		It("reports notes that have not been pushed yet", func() {
+			head, err := local.GetHead()
+			Expect(err).NotTo(HaveOccurred())
+			local.AddNote("refs/notes/shiftlog", head, "dev1-note")
+
+			stdout, _, err := testutil.RunShiftlogInDir(local.Path, "sync", "status")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(stdout).To(ContainSubstring("origin has no conversation notes yet"))
+			Expect(stdout).To(ContainSubstring("Only local:   1"))
+
+			// Nothing was pushed
+			Expect(remote.HasNote("refs/notes/shiftlog", head)).To(BeFalse())
+		})
+
+		It("reports in sync after a push", func() {
+			head, err := local.GetHead()
+			Expect(err).NotTo(HaveOccurred())
+			local.AddNote("refs/notes/shiftlog", head, "dev1-note")
+			_, _, err = testutil.RunShiftlogInDir(local.Path, "sync", "push")
+			Expect(err).NotTo(HaveOccurred())
+
+			stdout, _, err := testutil.RunShiftlogInDir(local.Path, "sync", "status")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(stdout).To(ContainSubstring("in sync with origin"))
+			Expect(stdout).To(ContainSubstring("Identical:    1"))
+		})
+
+		It("reports divergence and conflicting notes without changing refs", func() {
+			head, err := local.GetHead()
+			Expect(err).NotTo(HaveOccurred())
+			local.AddNote("refs/notes/shiftlog", head, "dev1-note")
+			Expect(local.WriteFile("second.txt", "content")).To(Succeed())
+			Expect(local.Commit("second commit")).To(Succeed())
+			secondHead, err := local.GetHead()
+			Expect(err).NotTo(HaveOccurred())
+			local.AddNote("refs/notes/shiftlog", secondHead, "dev1-second")
+			Expect(local.Run("git", "push", "origin", "master")).To(Succeed())
+			_, _, err = testutil.RunShiftlogInDir(local.Path, "sync", "push")
+			Expect(err).NotTo(HaveOccurred())
+
+			clone, err := testutil.NewGitRepo()
+			Expect(err).NotTo(HaveOccurred())
+			defer clone.Cleanup()
+			Expect(clone.Run("git", "remote", "add", "origin", remote.Path)).To(Succeed())
+			Expect(clone.Run("git", "fetch", "origin")).To(Succeed())
+			Expect(clone.Run("git", "checkout", "-b", "master", "origin/master")).To(Succeed())
+
+			// Same commit, different note; plus a note the remote lacks
+			clone.AddNote("refs/notes/shiftlog", head, "dev2-note")
+			Expect(clone.WriteFile("third.txt", "content")).To(Succeed())
+			Expect(clone.Commit("third commit")).To(Succeed())
+			thirdHead, err := clone.GetHead()
+			Expect(err).NotTo(HaveOccurred())
+			clone.AddNote("refs/notes/shiftlog", thirdHead, "dev2-third")
+
+			before, err := clone.RunOutput("git", "for-each-ref")
+			Expect(err).NotTo(HaveOccurred())
+
+			stdout, _, err := testutil.RunShiftlogInDir(clone.Path, "sync", "status", "--verbose")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(stdout).To(ContainSubstring("Diverged from origin"))
+			Expect(stdout).To(ContainSubstring("Only local:   1"))
+			Expect(stdout).To(ContainSubstring("Only remote:  1"))
+			Expect(stdout).To(ContainSubstring("Conflicting:  1"))
+			Expect(stdout).To(ContainSubstring(thirdHead[:7] + " third commit"))
+			Expect(stdout).To(ContainSubstring(head[:7] + " Initial commit"))
+
+			after, err := clone.RunOutput("git", "for-each-ref")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(after).To(Equal(before))
+			note, err := clone.GetNote("refs/notes/shiftlog", head)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(note).To(ContainSubstring("dev2-note"))
+			Expect(note).NotTo(ContainSubstring("dev1-note"))
+		})
+
+		It("prints JSON", func() {
+			head, err := local.GetHead()
+			Expect(err).NotTo(HaveOccurred())
+			local.AddNote("refs/notes/shiftlog", head, "dev1-note")
+
+			stdout, _, err := testutil.RunShiftlogInDir(local.Path, "sync", "status", "--format", "json")
+			Expect(err).NotTo(HaveOccurred())
+			var status map[string]interface{}
+			Expect(json.Unmarshal([]byte(stdout), &status)).To(Succeed())
+			Expect(status["remote_exists"]).To(BeFalse())
+			Expect(status["local_only"]).To(ConsistOf(head))
+			Expect(status["conflicting"]).To(BeEmpty())
+		})
+	})
+
+	Describe("annotations", func() {
+		const annotationsRef = "refs/notes/shiftlog-annotations"
+
+		It("merges annotations made in two clones", func() {
+			head, err := local.GetHead()
+			Expect(err).NotTo(HaveOccurred())
+
+			local.AddNote("refs/notes/shiftlog", head, "conversation")
+			local.AddNote(annotationsRef, head, `{"created_at":"2025-01-01T00:00:00Z","id":"a1","entry_uuid":"u1","body":"dev1 comment"}`)
+			stdout, _, err := testutil.RunShiftlogInDir(local.Path, "sync", "push")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(stdout).To(ContainSubstring("Pushed annotations"))
+			Expect(remote.HasNote(annotationsRef, head)).To(BeTrue())
+
+			clone, err := testutil.NewGitRepo()
+			Expect(err).NotTo(HaveOccurred())
+			defer clone.Cleanup()
+
+			Expect(clone.Run("git", "remote", "add", "origin", remote.Path)).To(Succeed())
+			Expect(clone.Run("git", "fetch", "origin")).To(Succeed())
+			Expect(clone.Run("git", "checkout", "-b", "master", "origin/master")).To(Succeed())
+			clone.AddNote(annotationsRef, head, `{"created_at":"2025-01-02T00:00:00Z","id":"b1","entry_uuid":"u1","body":"dev2 comment"}`)
+
+			_, _, err = testutil.RunShiftlogInDir(clone.Path, "sync", "pull")
+			Expect(err).NotTo(HaveOccurred())
+
+			note, err := clone.GetNote(annotationsRef, head)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(note).To(ContainSubstring("dev1 comment"))
+			Expect(note).To(ContainSubstring("dev2 comment"))
+		})
+
+		It("pulls when the remote has no annotations", func() {
+			head, err := local.GetHead()
+			Expect(err).NotTo(HaveOccurred())
+
+			local.AddNote("refs/notes/shiftlog", head, "conversation")
+			stdout, _, err := testutil.RunShiftlogInDir(local.Path, "sync", "push")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(stdout).NotTo(ContainSubstring("annotations"))
+
+			_, _, err = testutil.RunShiftlogInDir(local.Path, "sync", "pull")
+			Expect(err).NotTo(HaveOccurred())
+		})
+	})
+
 	Describe("notes round-trip", func() {
 		It("preserves conversation through push/pull", func() {
 			// Store conversation locally
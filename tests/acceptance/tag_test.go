@@ -0,0 +1,160 @@
+package acceptance_test
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/re-cinq/shift-log/tests/acceptance/testutil"
+)
+
+var _ = Describe("Tag Command", func() {
+	var repo *testutil.GitRepo
+
+	BeforeEach(func() {
+		var err error
+		repo, err = testutil.NewGitRepo()
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(repo.WriteFile("README.md", "# Test")).To(Succeed())
+		Expect(repo.Commit("Initial commit")).To(Succeed())
+	})
+
+	AfterEach(func() {
+		if repo != nil {
+			repo.Cleanup()
+		}
+	})
+
+	storeConversation := func(r *testutil.GitRepo, sessionID string) string {
+		transcriptPath := filepath.Join(os.TempDir(), sessionID+".jsonl")
+		Expect(os.WriteFile(transcriptPath, []byte(testutil.SampleTranscript()), 0644)).To(Succeed())
+		DeferCleanup(os.Remove, transcriptPath)
+
+		head, err := r.GetHead()
+		Expect(err).NotTo(HaveOccurred())
+
+		hookInput := testutil.SampleHookInput(sessionID, transcriptPath, "git commit -m 'test'")
+		_, _, err = testutil.RunShiftlogInDirWithStdin(r.Path, hookInput, "store")
+		Expect(err).NotTo(HaveOccurred())
+		return head
+	}
+
+	noteTags := func(r *testutil.GitRepo, sha string) []string {
+		note, err := r.GetNote("refs/notes/shiftlog", sha)
+		Expect(err).NotTo(HaveOccurred())
+		var stored struct {
+			Tags []string `json:"tags"`
+		}
+		Expect(json.Unmarshal([]byte(note), &stored)).To(Succeed())
+		return stored.Tags
+	}
+
+	It("adds, lists and removes tags", func() {
+		sha := storeConversation(repo, "session-tag-basic")
+
+		stdout, _, err := testutil.RunShiftlogInDir(repo.Path, "tag", "HEAD", "bugfix", "Prompt-Engineering", "bugfix")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stdout).To(ContainSubstring("bugfix prompt-engineering"))
+		Expect(noteTags(repo, sha)).To(Equal([]string{"bugfix", "prompt-engineering"}))
+
+		stdout, _, err = testutil.RunShiftlogInDir(repo.Path, "tag", sha[:7])
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stdout).To(Equal("bugfix prompt-engineering\n"))
+
+		_, _, err = testutil.RunShiftlogInDir(repo.Path, "tag", "--remove", "HEAD", "bugfix")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(noteTags(repo, sha)).To(Equal([]string{"prompt-engineering"}))
+	})
+
+	It("rejects invalid tags", func() {
+		storeConversation(repo, "session-tag-invalid")
+
+		_, stderr, err := testutil.RunShiftlogInDir(repo.Path, "tag", "HEAD", "has space")
+		Expect(err).To(HaveOccurred())
+		Expect(stderr).To(ContainSubstring("invalid tag"))
+	})
+
+	It("fails for a commit without a conversation", func() {
+		_, stderr, err := testutil.RunShiftlogInDir(repo.Path, "tag", "HEAD", "bugfix")
+		Expect(err).To(HaveOccurred())
+		Expect(stderr).To(ContainSubstring("no conversation found"))
+	})
+
+	It("filters search results by tag", func() {
+		tagged := storeConversation(repo, "session-tag-search-1")
+		_, _, err := testutil.RunShiftlogInDir(repo.Path, "tag", "HEAD", "training")
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(repo.WriteFile("other.txt", "other")).To(Succeed())
+		Expect(repo.Commit("Second commit")).To(Succeed())
+		untagged := storeConversation(repo, "session-tag-search-2")
+
+		stdout, _, err := testutil.RunShiftlogInDir(repo.Path, "search", "--tag", "training")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stdout).To(ContainSubstring(tagged[:7]))
+		Expect(stdout).To(ContainSubstring("tags: training"))
+		Expect(stdout).NotTo(ContainSubstring(untagged[:7]))
+	})
+
+	It("filters file history by tag", func() {
+		Expect(repo.WriteFile("app.go", "package main")).To(Succeed())
+		Expect(repo.Commit("Add app")).To(Succeed())
+		sha := storeConversation(repo, "session-tag-log")
+
+		stdout, _, err := testutil.RunShiftlogInDir(repo.Path, "log", "--file", "app.go", "--tag", "review")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stdout).To(ContainSubstring("no conversations found"))
+
+		_, _, err = testutil.RunShiftlogInDir(repo.Path, "tag", "HEAD", "review")
+		Expect(err).NotTo(HaveOccurred())
+
+		stdout, _, err = testutil.RunShiftlogInDir(repo.Path, "log", "--file", "app.go", "--tag", "review")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stdout).To(ContainSubstring(sha[:7]))
+		Expect(stdout).To(ContainSubstring("tags: review"))
+	})
+
+	It("merges tags added in different clones on sync pull", func() {
+		local, remote, err := testutil.NewGitRepoWithRemote()
+		Expect(err).NotTo(HaveOccurred())
+		defer local.Cleanup()
+		defer remote.Cleanup()
+
+		Expect(local.WriteFile("README.md", "# Test")).To(Succeed())
+		Expect(local.Commit("Initial commit")).To(Succeed())
+		Expect(local.Run("git", "push", "-u", "origin", "master")).To(Succeed())
+		sha := storeConversation(local, "session-tag-sync")
+		_, _, err = testutil.RunShiftlogInDir(local.Path, "sync", "push")
+		Expect(err).NotTo(HaveOccurred())
+
+		clone, err := testutil.NewGitRepo()
+		Expect(err).NotTo(HaveOccurred())
+		defer clone.Cleanup()
+		Expect(clone.Run("git", "remote", "add", "origin", remote.Path)).To(Succeed())
+		Expect(clone.Run("git", "fetch", "origin")).To(Succeed())
+		Expect(clone.Run("git", "checkout", "-b", "master", "origin/master")).To(Succeed())
+		_, _, err = testutil.RunShiftlogInDir(clone.Path, "sync", "pull")
+		Expect(err).NotTo(HaveOccurred())
+
+		// Each clone tags the same conversation differently
+		_, _, err = testutil.RunShiftlogInDir(local.Path, "tag", sha, "bugfix")
+		Expect(err).NotTo(HaveOccurred())
+		_, _, err = testutil.RunShiftlogInDir(local.Path, "sync", "push")
+		Expect(err).NotTo(HaveOccurred())
+
+		_, _, err = testutil.RunShiftlogInDir(clone.Path, "tag", sha, "training")
+		Expect(err).NotTo(HaveOccurred())
+		_, _, err = testutil.RunShiftlogInDir(clone.Path, "sync", "pull")
+		Expect(err).NotTo(HaveOccurred())
+
+		// The note is still a single valid conversation carrying both tags
+		Expect(noteTags(clone, sha)).To(Equal([]string{"bugfix", "training"}))
+		stdout, _, err := testutil.RunShiftlogInDir(clone.Path, "show", sha)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stdout).NotTo(BeEmpty())
+	})
+})
@@ -223,9 +223,44 @@ func CopilotTestConfig() AgentTestConfig {
 	}
 }
 
+// GooseTestConfig returns the test configuration for Goose agent.
+func GooseTestConfig() AgentTestConfig {
+	return AgentTestConfig{
+		Name:      "Goose",
+		InitArgs:  []string{"init", "--agent=goose"},
+		StoreArgs: []string{"store", "--agent=goose"},
+
+		SampleTranscript:   SampleGooseTranscript,
+		SampleHookInput:    SampleGooseHookInput,
+		SampleNonToolInput: SampleGooseHookInputNonShell,
+
+		SettingsFile:    "", // no settings file — hookless agent
+		HookKey:         "", // no hook key
+		ToolMatcher:     "", // no tool matcher
+		StoreCommand:    "shiftlog store --agent=goose",
+		Timeout:         0,
+		HasSessionHooks: false,
+		IsPluginBased:   false,
+		IsHookless:      true,
+
+		SessionFileExt:         ".jsonl",
+		TranscriptFileExt:      ".jsonl",
+		GetSessionDir:          gooseSessionDir,
+		NeedsBinaryPath:        true,
+		HasSessionsIndex:       false,
+		ReadRestoredTranscript: nil,
+		PrepareTranscript:      goosePrepareTranscript,
+
+		ExpectedTurns:     2,
+		ExpectedHasTokens: true,
+		ExpectedInputTok:  1200,
+		ExpectedOutputTok: 300,
+	}
+}
+
 // AllAgentConfigs returns test configs for all agents.
 func AllAgentConfigs() []AgentTestConfig {
-	return []AgentTestConfig{ClaudeTestConfig(), GeminiTestConfig(), OpenCodeTestConfig(), CodexTestConfig(), CopilotTestConfig()}
+	return []AgentTestConfig{ClaudeTestConfig(), GeminiTestConfig(), OpenCodeTestConfig(), CodexTestConfig(), CopilotTestConfig(), GooseTestConfig()}
 }
 
 // claudeSessionDir computes the Claude projects session directory path.
@@ -158,3 +158,77 @@ func SampleTranscriptWithIDs(uuids []string, messages []string) string {
 	}
 	return result
 }
+
+// SampleEditTranscript returns a JSONL transcript in which the assistant
+// edits filePath with the Edit tool.
+func SampleEditTranscript(filePath string) string {
+	entries := []map[string]interface{}{
+		{
+			"uuid":       "user-1",
+			"parentUuid": "",
+			"type":       "user",
+			"timestamp":  time.Now().Format(time.RFC3339),
+			"message": map[string]interface{}{
+				"role": "user",
+				"content": []map[string]interface{}{
+					{"type": "text", "text": "Please rename the greeting function"},
+				},
+			},
+		},
+		{
+			"uuid":       "assistant-1",
+			"parentUuid": "user-1",
+			"type":       "assistant",
+			"message": map[string]interface{}{
+				"role":  "assistant",
+				"model": "claude-sonnet-4-5-20250514",
+				"content": []map[string]interface{}{
+					{"type": "text", "text": "Renaming it now."},
+					{
+						"type": "tool_use",
+						"id":   "tool-1",
+						"name": "Edit",
+						"input": map[string]interface{}{
+							"file_path":  filePath,
+							"old_string": "func hello()",
+							"new_string": "func greet()",
+						},
+					},
+				},
+			},
+		},
+		{
+			"uuid":       "user-2",
+			"parentUuid": "assistant-1",
+			"type":       "user",
+			"message": map[string]interface{}{
+				"role": "user",
+				"content": []map[string]interface{}{
+					{"type": "tool_result", "tool_use_id": "tool-1", "content": "ok"},
+				},
+			},
+		},
+		{
+			"uuid":       "assistant-2",
+			"parentUuid": "user-2",
+			"type":       "assistant",
+			"message": map[string]interface{}{
+				"role":  "assistant",
+				"model": "claude-sonnet-4-5-20250514",
+				"content": []map[string]interface{}{
+					{"type": "text", "text": "The function is now called greet."},
+				},
+			},
+		},
+	}
+
+	var result string
+	for i, entry := range entries {
+		data, _ := json.Marshal(entry)
+		if i > 0 {
+			result += "\n"
+		}
+		result += string(data)
+	}
+	return result
+}
@@ -0,0 +1,118 @@
+package testutil
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// SampleGooseTranscript returns a sample Goose session JSONL transcript for testing.
+// Goose session files start with a metadata line (working directory and token
+// counts) followed by one message per line.
+func SampleGooseTranscript() string {
+	now := time.Now().Unix()
+
+	lines := []map[string]interface{}{
+		{
+			"working_dir":               "/test/project",
+			"description":               "Create a test file",
+			"message_count":             4,
+			"accumulated_input_tokens":  1200,
+			"accumulated_output_tokens": 300,
+		},
+		{
+			"id":      "msg_1",
+			"role":    "user",
+			"created": now,
+			"content": []map[string]interface{}{
+				{"type": "text", "text": "Hello, can you help me with a task?"},
+			},
+		},
+		{
+			"id":      "msg_2",
+			"role":    "assistant",
+			"created": now,
+			"content": []map[string]interface{}{
+				{"type": "text", "text": "Of course! What would you like help with?"},
+			},
+		},
+		{
+			"id":      "msg_3",
+			"role":    "user",
+			"created": now,
+			"content": []map[string]interface{}{
+				{"type": "text", "text": "Please create a file called test.txt"},
+			},
+		},
+		{
+			"id":      "msg_4",
+			"role":    "assistant",
+			"created": now,
+			"content": []map[string]interface{}{
+				{
+					"type": "toolRequest",
+					"id":   "call_1",
+					"toolCall": map[string]interface{}{
+						"status": "success",
+						"value": map[string]interface{}{
+							"name":      "developer__shell",
+							"arguments": map[string]interface{}{"command": "echo 'test content' > test.txt"},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	var parts []string
+	for _, line := range lines {
+		data, _ := json.Marshal(line)
+		parts = append(parts, string(data))
+	}
+	return strings.Join(parts, "\n")
+}
+
+// SampleGooseHookInput returns sample hook JSON for Goose testing.
+// Goose has no per-tool hooks, so this is used for the hook-based store path
+// that receives JSON via stdin.
+func SampleGooseHookInput(sessionID, transcriptPath, command string) string {
+	input := map[string]interface{}{
+		"session_id":      sessionID,
+		"transcript_path": transcriptPath,
+		"tool_name":       "developer__shell",
+		"tool_input": map[string]interface{}{
+			"command": command,
+		},
+	}
+	data, _ := json.Marshal(input)
+	return string(data)
+}
+
+// SampleGooseHookInputNonShell returns hook JSON for a non-shell Goose tool.
+func SampleGooseHookInputNonShell(sessionID string) string {
+	input := map[string]interface{}{
+		"session_id": sessionID,
+		"tool_name":  "developer__text_editor",
+		"tool_input": map[string]interface{}{
+			"command": "view",
+			"path":    "/some/file.txt",
+		},
+	}
+	data, _ := json.Marshal(input)
+	return string(data)
+}
+
+// goosePrepareTranscript writes a Goose JSONL session file.
+func goosePrepareTranscript(baseDir, sessionID, transcript string) (string, error) {
+	path := filepath.Join(baseDir, sessionID+".jsonl")
+	return path, os.WriteFile(path, []byte(transcript), 0644)
+}
+
+// gooseSessionDir computes the Goose sessions directory path.
+// Goose: ~/.local/share/goose/sessions, mirroring GetSessionsDir() in
+// internal/agent/goose/session.go.
+func gooseSessionDir(homeDir, projectPath string) string {
+	return filepath.Join(homeDir, ".local", "share", "goose", "sessions")
+}
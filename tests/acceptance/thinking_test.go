@@ -0,0 +1,93 @@
+package acceptance_test
+
+import (
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/re-cinq/shift-log/tests/acceptance/testutil"
+)
+
+var _ = Describe("Thinking policy", func() {
+	var repo *testutil.GitRepo
+
+	const transcript = `{"type":"user","uuid":"u1","sessionId":"session-thinking","timestamp":"2025-01-01T00:00:00Z","message":{"role":"user","content":"Fix the retry logic"}}
+{"type":"assistant","uuid":"a1","parentUuid":"u1","sessionId":"session-thinking","timestamp":"2025-01-01T00:00:05Z","message":{"role":"assistant","model":"claude-sonnet-4-5-20250514","content":[{"type":"thinking","thinking":"The backoff never resets","signature":"c2ln"},{"type":"text","text":"Fixed the backoff."}]}}
+`
+
+	// storeSession commits a change and stores the transcript on it.
+	storeSession := func() error {
+		Expect(repo.WriteFile("retry.go", "package retry\n")).To(Succeed())
+		Expect(repo.Commit("Fix retry")).To(Succeed())
+		transcriptPath := filepath.Join(GinkgoT().TempDir(), "transcript.jsonl")
+		Expect(os.WriteFile(transcriptPath, []byte(transcript), 0644)).To(Succeed())
+		hookInput := testutil.SampleHookInput("session-thinking", transcriptPath, "git commit -m 'Fix retry'")
+		_, _, err := testutil.RunShiftlogInDirWithStdin(repo.Path, hookInput, "store")
+		return err
+	}
+
+	BeforeEach(func() {
+		var err error
+		repo, err = testutil.NewGitRepo()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(repo.WriteFile("README.md", "# Test")).To(Succeed())
+		Expect(repo.Commit("Initial commit")).To(Succeed())
+
+		_, _, err = testutil.RunShiftlogInDir(repo.Path, "init")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		if repo != nil {
+			repo.Cleanup()
+		}
+	})
+
+	It("strips thinking at store time when configured", func() {
+		Expect(repo.WriteFile(".shiftlog/config", `{"agent": "claude", "thinking": "strip"}`)).To(Succeed())
+		Expect(storeSession()).To(Succeed())
+
+		note, err := repo.GetNote("refs/notes/shiftlog", "HEAD")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(note).To(ContainSubstring(`"thinking": "strip"`))
+
+		stdout, _, err := testutil.RunShiftlogInDir(repo.Path, "show", "HEAD")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stdout).To(ContainSubstring("[thinking stripped]"))
+		Expect(stdout).To(ContainSubstring("Fixed the backoff."))
+		Expect(stdout).NotTo(ContainSubstring("The backoff never resets"))
+	})
+
+	It("refuses to store under a policy it does not know", func() {
+		Expect(repo.WriteFile(".shiftlog/config", `{"agent": "claude", "thinking": "shred"}`)).To(Succeed())
+		Expect(storeSession()).NotTo(Succeed())
+
+		_, err := repo.GetNote("refs/notes/shiftlog", "HEAD")
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("strips thinking from conversations already stored", func() {
+		Expect(storeSession()).To(Succeed())
+		stdout, _, err := testutil.RunShiftlogInDir(repo.Path, "show", "HEAD")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stdout).To(ContainSubstring("The backoff never resets"))
+
+		stdout, _, err = testutil.RunShiftlogInDir(repo.Path, "strip-thinking", "--dry-run")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stdout).To(ContainSubstring("Would strip the thinking of 1 blocks in 1 conversations"))
+
+		stdout, _, err = testutil.RunShiftlogInDir(repo.Path, "strip-thinking", "--hash")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stdout).To(ContainSubstring("Hashed the thinking of 1 blocks in 1 conversations"))
+
+		stdout, _, err = testutil.RunShiftlogInDir(repo.Path, "show", "HEAD")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stdout).To(ContainSubstring("[thinking hashed]"))
+		Expect(stdout).NotTo(ContainSubstring("The backoff never resets"))
+
+		stdout, _, err = testutil.RunShiftlogInDir(repo.Path, "verify", "HEAD")
+		Expect(err).NotTo(HaveOccurred(), stdout)
+	})
+})
@@ -0,0 +1,92 @@
+package acceptance_test
+
+import (
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/re-cinq/shift-log/tests/acceptance/testutil"
+)
+
+var _ = Describe("Uninstall Command", func() {
+	var repo *testutil.GitRepo
+
+	BeforeEach(func() {
+		var err error
+		repo, err = testutil.NewGitRepo()
+		Expect(err).NotTo(HaveOccurred())
+
+		_, _, err = testutil.RunShiftlogInDir(repo.Path, "init", "--agent=gemini")
+		Expect(err).NotTo(HaveOccurred())
+		_, _, err = testutil.RunShiftlogInDir(repo.Path, "init")
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(repo.Commit("Initial commit")).To(Succeed())
+		head, err := repo.GetHead()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(repo.AddNote("refs/notes/shiftlog", head, "{}")).To(Succeed())
+	})
+
+	AfterEach(func() {
+		repo.Cleanup()
+	})
+
+	It("removes the hooks of every agent and keeps the notes", func() {
+		stdout, _, err := testutil.RunShiftlogInDir(repo.Path, "uninstall")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stdout).To(ContainSubstring("Git notes data has been preserved"))
+
+		Expect(repo.FileExists(".claude/settings.local.json")).To(BeFalse())
+		settings, err := repo.ReadFile(".gemini/settings.json")
+		if err == nil {
+			Expect(settings).NotTo(ContainSubstring("shiftlog"))
+		}
+		Expect(repo.FileExists(".git/hooks/post-commit")).To(BeFalse())
+		Expect(repo.FileExists(".shiftlog/config")).To(BeTrue())
+
+		head, err := repo.GetHead()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(repo.HasNote("refs/notes/shiftlog", head)).To(BeTrue())
+	})
+
+	It("keeps user content in shared hook files", func() {
+		hookPath := filepath.Join(repo.Path, ".git", "hooks", "pre-push")
+		content, err := os.ReadFile(hookPath)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(os.WriteFile(hookPath, append(content, []byte("\necho user-hook\n")...), 0755)).To(Succeed())
+
+		_, _, err = testutil.RunShiftlogInDir(repo.Path, "uninstall")
+		Expect(err).NotTo(HaveOccurred())
+
+		content, err = os.ReadFile(hookPath)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(content)).To(ContainSubstring("echo user-hook"))
+		Expect(string(content)).NotTo(ContainSubstring("shiftlog-managed"))
+	})
+
+	It("refuses to delete notes without confirmation", func() {
+		_, _, err := testutil.RunShiftlogInDir(repo.Path, "uninstall", "--delete-notes")
+		Expect(err).To(HaveOccurred())
+
+		head, err := repo.GetHead()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(repo.HasNote("refs/notes/shiftlog", head)).To(BeTrue())
+		Expect(repo.FileExists(".git/hooks/post-commit")).To(BeTrue())
+	})
+
+	It("deletes notes and .shiftlog with --delete-notes --purge --yes", func() {
+		_, _, err := testutil.RunShiftlogInDir(repo.Path, "uninstall", "--delete-notes", "--purge", "--yes")
+		Expect(err).NotTo(HaveOccurred())
+
+		refs, err := repo.RunOutput("git", "for-each-ref", "refs/notes/")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(refs).To(BeEmpty())
+
+		Expect(repo.FileExists(".shiftlog")).To(BeFalse())
+		gitignore, err := repo.ReadFile(".gitignore")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(gitignore).NotTo(ContainSubstring(".shiftlog/"))
+	})
+})
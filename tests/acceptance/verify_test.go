@@ -0,0 +1,151 @@
+package acceptance_test
+
+import (
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/re-cinq/shift-log/tests/acceptance/testutil"
+)
+
+var _ = Describe("Verify Command", func() {
+	var repo *testutil.GitRepo
+
+	BeforeEach(func() {
+		var err error
+		repo, err = testutil.NewGitRepo()
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(repo.WriteFile("README.md", "# Test")).To(Succeed())
+		Expect(repo.Commit("Initial commit")).To(Succeed())
+
+		_, _, err = testutil.RunShiftlogInDir(repo.Path, "init")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		if repo != nil {
+			repo.Cleanup()
+		}
+	})
+
+	// storeOnNewCommit commits a file and stores a conversation for it.
+	storeOnNewCommit := func(name string) string {
+		Expect(repo.WriteFile(name+".txt", name)).To(Succeed())
+		Expect(repo.Commit("Add " + name)).To(Succeed())
+
+		transcriptPath := filepath.Join(repo.Path, "transcript.jsonl")
+		Expect(os.WriteFile(transcriptPath, []byte(testutil.SampleTranscript()), 0644)).To(Succeed())
+		hookInput := testutil.SampleHookInput("session-"+name, transcriptPath, "git commit -m 'test'")
+		_, _, err := testutil.RunShiftlogInDirWithStdin(repo.Path, hookInput, "store")
+		Expect(err).NotTo(HaveOccurred())
+
+		head, err := repo.GetHead()
+		Expect(err).NotTo(HaveOccurred())
+		return head
+	}
+
+	// rewriteNote applies edit to the JSON note on sha.
+	rewriteNote := func(sha string, edit func(note map[string]interface{})) {
+		out, err := repo.RunOutput("git", "notes", "--ref", "refs/notes/shiftlog", "show", sha)
+		Expect(err).NotTo(HaveOccurred())
+		var note map[string]interface{}
+		Expect(json.Unmarshal([]byte(out), &note)).To(Succeed())
+		edit(note)
+		data, err := json.Marshal(note)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(repo.AddNote("refs/notes/shiftlog", sha, string(data))).To(Succeed())
+	}
+
+	It("verifies transcripts against their checksums", func() {
+		head := storeOnNewCommit("plain")
+
+		stdout, _, err := testutil.RunShiftlogInDir(repo.Path, "verify")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stdout).To(ContainSubstring(head[:7] + "  OK"))
+		Expect(stdout).To(ContainSubstring("All 1 conversation(s) verified"))
+
+		stdout, _, err = testutil.RunShiftlogInDir(repo.Path, "verify", "--signatures")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stdout).To(ContainSubstring("unsigned"))
+	})
+
+	It("fails when a transcript was modified", func() {
+		head := storeOnNewCommit("tampered")
+		rewriteNote(head, func(note map[string]interface{}) {
+			note["transcript"] = "dGFtcGVyZWQ="
+		})
+
+		stdout, _, err := testutil.RunShiftlogInDir(repo.Path, "verify", "HEAD")
+		Expect(err).To(HaveOccurred())
+		Expect(stdout).To(ContainSubstring(head[:7] + "  FAIL  transcript does not match its checksum"))
+		Expect(stdout).To(ContainSubstring("1 of 1 conversation(s) failed verification"))
+	})
+
+	Context("with an SSH signing key", func() {
+		var allowedSigners string
+
+		BeforeEach(func() {
+			if _, err := exec.LookPath("ssh-keygen"); err != nil {
+				Skip("ssh-keygen not available")
+			}
+			keyDir := GinkgoT().TempDir()
+			key := filepath.Join(keyDir, "key")
+			Expect(exec.Command("ssh-keygen", "-q", "-t", "ed25519", "-N", "", "-f", key).Run()).To(Succeed())
+			pub, err := os.ReadFile(key + ".pub")
+			Expect(err).NotTo(HaveOccurred())
+			allowedSigners = filepath.Join(keyDir, "allowed_signers")
+			Expect(os.WriteFile(allowedSigners, []byte("test@example.com "+string(pub)), 0644)).To(Succeed())
+
+			Expect(repo.Run("git", "config", "gpg.format", "ssh")).To(Succeed())
+			Expect(repo.Run("git", "config", "user.signingkey", key)).To(Succeed())
+			Expect(repo.WriteFile(".shiftlog/config", `{"sign": true}`)).To(Succeed())
+		})
+
+		It("reports a good signature by the allowed signer", func() {
+			Expect(repo.Run("git", "config", "gpg.ssh.allowedSignersFile", allowedSigners)).To(Succeed())
+			head := storeOnNewCommit("signed")
+
+			stdout, _, err := testutil.RunShiftlogInDir(repo.Path, "verify", "--signatures")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(stdout).To(ContainSubstring(head[:7] + "  OK    good ssh signature by test@example.com"))
+		})
+
+		It("reports a valid signature from a key it cannot check", func() {
+			head := storeOnNewCommit("unknown")
+
+			stdout, _, err := testutil.RunShiftlogInDir(repo.Path, "verify", "--signatures")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(stdout).To(ContainSubstring(head[:7] + "  OK    ssh signature from an unknown key"))
+		})
+
+		It("fails when signed metadata was changed", func() {
+			Expect(repo.Run("git", "config", "gpg.ssh.allowedSignersFile", allowedSigners)).To(Succeed())
+			head := storeOnNewCommit("forged")
+			rewriteNote(head, func(note map[string]interface{}) {
+				note["model"] = "forged-model"
+			})
+
+			stdout, _, err := testutil.RunShiftlogInDir(repo.Path, "verify", "--signatures")
+			Expect(err).To(HaveOccurred())
+			Expect(stdout).To(ContainSubstring(head[:7] + "  FAIL  bad ssh signature"))
+		})
+
+		It("keeps the signature valid when tags change", func() {
+			Expect(repo.Run("git", "config", "gpg.ssh.allowedSignersFile", allowedSigners)).To(Succeed())
+			head := storeOnNewCommit("tagged")
+			rewriteNote(head, func(note map[string]interface{}) {
+				note["tags"] = []string{"reviewed"}
+			})
+
+			stdout, _, err := testutil.RunShiftlogInDir(repo.Path, "verify", "--signatures")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(strings.Count(stdout, "good ssh signature")).To(Equal(1))
+		})
+	})
+})
@@ -0,0 +1,81 @@
+package acceptance_test
+
+import (
+	"os"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/re-cinq/shift-log/tests/acceptance/testutil"
+)
+
+var _ = Describe("Watch Command", func() {
+	const checkpointsRef = "refs/notes/shiftlog-wip"
+
+	var repo *testutil.GitRepo
+	var agentEnv *testutil.AgentEnv
+
+	BeforeEach(func() {
+		var err error
+		repo, err = testutil.NewGitRepo()
+		Expect(err).NotTo(HaveOccurred())
+
+		agentEnv, err = testutil.NewAgentEnv(testutil.ClaudeTestConfig())
+		Expect(err).NotTo(HaveOccurred())
+
+		_, _, err = testutil.RunShiftlogInDir(repo.Path, "init")
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(repo.WriteFile("README.md", "# Test")).To(Succeed())
+		Expect(repo.Commit("Initial commit")).To(Succeed())
+	})
+
+	AfterEach(func() {
+		repo.Cleanup()
+		agentEnv.Cleanup()
+	})
+
+	It("reports when there is no active session", func() {
+		_, stderr, err := testutil.RunShiftlogInDirWithEnv(repo.Path, agentEnv.GetEnvVars(), "watch", "--once")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stderr).To(ContainSubstring("no active Claude Code session found"))
+	})
+
+	It("checkpoints the active session on HEAD", func() {
+		_, err := agentEnv.WriteSessionFile(repo.Path, "watched-session", []byte(testutil.SampleTranscript()))
+		Expect(err).NotTo(HaveOccurred())
+
+		_, stderr, err := testutil.RunShiftlogInDirWithEnv(repo.Path, agentEnv.GetEnvVars(), "watch", "--once")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stderr).To(ContainSubstring("checkpointed session watched-session"))
+
+		checkpoint, err := repo.GetNote(checkpointsRef, "HEAD")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(checkpoint).To(ContainSubstring("watched-session"))
+		Expect(repo.HasNote("refs/notes/shiftlog", "HEAD")).To(BeFalse())
+	})
+
+	It("folds the checkpoint into the next commit when the session is gone", func() {
+		sessionPath, err := agentEnv.WriteSessionFile(repo.Path, "crashed-session", []byte(testutil.SampleTranscript()))
+		Expect(err).NotTo(HaveOccurred())
+
+		_, _, err = testutil.RunShiftlogInDirWithEnv(repo.Path, agentEnv.GetEnvVars(), "watch", "--once")
+		Expect(err).NotTo(HaveOccurred())
+		base, err := repo.GetHead()
+		Expect(err).NotTo(HaveOccurred())
+
+		// The agent crashed and its transcript is gone
+		Expect(os.Remove(sessionPath)).To(Succeed())
+
+		Expect(repo.WriteFile("work.txt", "work")).To(Succeed())
+		Expect(repo.Commit("Work done by the agent")).To(Succeed())
+		_, _, err = testutil.RunShiftlogInDirWithEnv(repo.Path, agentEnv.GetEnvVars(), "store", "--manual")
+		Expect(err).NotTo(HaveOccurred())
+
+		note, err := repo.GetNote("refs/notes/shiftlog", "HEAD")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(note).To(ContainSubstring("crashed-session"))
+		Expect(note).To(ContainSubstring(`"trigger": "watch"`))
+		Expect(repo.HasNote(checkpointsRef, base)).To(BeFalse())
+	})
+})
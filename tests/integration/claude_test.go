@@ -11,6 +11,8 @@ import (
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+
+	"github.com/re-cinq/shift-log/internal/storage"
 )
 
 var _ = Describe("Claude Code Integration", func() {
@@ -126,9 +128,9 @@ var _ = Describe("Claude Code Integration", func() {
 				Expect(noteData).To(HaveKey(field), "Note missing required field '%s'", field)
 			}
 
-			// Verify version is 3 (current format version)
-			if v, ok := noteData["version"].(float64); !ok || int(v) != 3 {
-				GinkgoWriter.Printf("Note: expected version=3, got %v\n", noteData["version"])
+			// Verify version is the current format version
+			if v, ok := noteData["version"].(float64); !ok || int(v) != storage.NoteFormatVersion {
+				GinkgoWriter.Printf("Note: expected version=%d, got %v\n", storage.NoteFormatVersion, noteData["version"])
 			}
 
 			// Verify agent field is "claude"